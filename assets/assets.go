@@ -0,0 +1,25 @@
+// Package assets embeds the text files shipped with the binaries, so they
+// work wherever the binaries are installed.
+package assets
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// ClientHelp is the help of all client commands
+//
+//go:embed client/help.txt
+var ClientHelp string
+
+//go:embed web
+var web embed.FS
+
+// WebUI returns the files of the web interface the server serves
+func WebUI() fs.FS {
+	files, err := fs.Sub(web, "web")
+	if err != nil {
+		panic(err)
+	}
+	return files
+}
@@ -0,0 +1,840 @@
+// GophKeeper web interface. Items are encrypted and decrypted in the browser
+// in the format of internal/crypto: PBKDF2-SHA256 derives the vault key from
+// the master password and the account salt, and each item is sealed with
+// AES-256-GCM under its own data key, stored wrapped by the vault key. The
+// master password and keys only live in this page and are gone on reload.
+
+const PBKDF2_ITERATIONS = 100000;
+const FORMAT_VAULT_KEY = 1;
+const FORMAT_DATA_KEY = 2;
+const NONCE_SIZE = 12;
+const SALT_SIZE = 32;
+// Records are written in the binary envelope of internal/crypto; JSON records
+// written before it start with '{'
+const ENVELOPE_MAGIC = new Uint8Array([0x47, 0x4b, 0x45]); // "GKE"
+const ENVELOPE_VERSION = 1;
+const DATA_KEY_SIZE = 32;
+const VERIFIER_PLAINTEXT = 'gophkeeper master password verifier v1';
+const CHECKSUM_LABEL = 'gophkeeper-checksum';
+const DEVICE_NAME = 'Web UI';
+
+// Payload fields of each editable type, as the command-line client edits them
+const FIELDS = {
+  login_password: [
+    { name: 'login', required: true },
+    { name: 'password', secret: true, required: true },
+    { name: 'url' },
+    { name: 'notes', multiline: true },
+  ],
+  bank_card: [
+    { name: 'card_number', secret: true, required: true },
+    { name: 'expiry_date', required: true },
+    { name: 'cvv', secret: true, required: true },
+    { name: 'cardholder', required: true },
+    { name: 'bank' },
+    { name: 'notes', multiline: true },
+  ],
+  text: [
+    { name: 'content', required: true, multiline: true },
+    { name: 'notes', multiline: true },
+  ],
+  otp: [
+    { name: 'secret', secret: true, required: true },
+    { name: 'issuer' },
+    { name: 'account' },
+    { name: 'notes', multiline: true },
+  ],
+};
+
+const TYPE_NAMES = {
+  login_password: 'Login',
+  text: 'Text',
+  bank_card: 'Bank card',
+  otp: 'One-time password',
+  binary: 'File',
+};
+
+const OTP_HASHES = { SHA1: 'SHA-1', SHA256: 'SHA-256', SHA512: 'SHA-512' };
+
+const encoder = new TextEncoder();
+const decoder = new TextDecoder();
+const $ = (id) => document.getElementById(id);
+
+// session is the open vault: the token, the salt of the account with its
+// PBKDF2 iteration count and the master password as a key that only derives
+// vault keys
+let session = null;
+let items = [];
+// opened is the item shown or edited, with its decrypted payload
+let opened = null;
+let otpTimer = null;
+// iconURLs holds object URLs of the decrypted icons of logins, by item ID
+let iconURLs = new Map();
+
+class WrongMasterPassword extends Error {
+  constructor() {
+    super('Wrong master password');
+  }
+}
+
+// Encoding
+
+function bytesToBase64(bytes) {
+  let binary = '';
+  for (let i = 0; i < bytes.length; i += 0x8000) {
+    binary += String.fromCharCode.apply(null, bytes.subarray(i, i + 0x8000));
+  }
+  return btoa(binary);
+}
+
+function base64ToBytes(text) {
+  const binary = atob(text);
+  const bytes = new Uint8Array(binary.length);
+  for (let i = 0; i < binary.length; i++) {
+    bytes[i] = binary.charCodeAt(i);
+  }
+  return bytes;
+}
+
+function concat(...parts) {
+  const joined = new Uint8Array(parts.reduce((length, part) => length + part.length, 0));
+  let offset = 0;
+  for (const part of parts) {
+    joined.set(part, offset);
+    offset += part.length;
+  }
+  return joined;
+}
+
+// uvarint encodes an unsigned integer as a Go uvarint
+function uvarint(value) {
+  const bytes = [];
+  while (value >= 0x80) {
+    bytes.push((value % 0x80) | 0x80);
+    value = Math.floor(value / 0x80);
+  }
+  bytes.push(value);
+  return new Uint8Array(bytes);
+}
+
+// readUvarint decodes a Go uvarint at offset, returning it and the offset after it
+function readUvarint(bytes, offset) {
+  let value = 0;
+  for (let shift = 0; shift < 35 && offset < bytes.length; shift += 7) {
+    const b = bytes[offset++];
+    value += (b & 0x7f) * 2 ** shift;
+    if (b < 0x80) {
+      return [value, offset];
+    }
+  }
+  throw new Error('Invalid encrypted data envelope');
+}
+
+function base32ToBytes(text) {
+  const alphabet = 'ABCDEFGHIJKLMNOPQRSTUVWXYZ234567';
+  const bytes = [];
+  let bits = 0;
+  let value = 0;
+  for (const char of text.replace(/=+$/, '')) {
+    const index = alphabet.indexOf(char);
+    if (index < 0) {
+      return null;
+    }
+    value = (value << 5) | index;
+    bits += 5;
+    if (bits >= 8) {
+      bytes.push((value >>> (bits - 8)) & 0xff);
+      bits -= 8;
+    }
+  }
+  return new Uint8Array(bytes);
+}
+
+// Cryptography, mirroring internal/crypto
+
+// vaultKey derives the vault key for a salt and PBKDF2 iteration count (0 for
+// the default), once per salt: items written before the master password was
+// rotated may still use an older one
+function vaultKey(salt, iterations) {
+  const count = iterations || PBKDF2_ITERATIONS;
+  const id = `${count}:${salt}`;
+  if (!session.vaultKeys.has(id)) {
+    session.vaultKeys.set(id, crypto.subtle.deriveKey(
+      { name: 'PBKDF2', hash: 'SHA-256', salt: base64ToBytes(salt), iterations: count },
+      session.passwordKey,
+      { name: 'AES-GCM', length: 256 },
+      false,
+      ['encrypt', 'decrypt'],
+    ));
+  }
+  return session.vaultKeys.get(id);
+}
+
+function importAESKey(raw) {
+  return crypto.subtle.importKey('raw', raw, 'AES-GCM', false, ['encrypt', 'decrypt']);
+}
+
+// seal encrypts with AES-256-GCM under a random nonce, returning both
+async function seal(key, plaintext) {
+  const nonce = crypto.getRandomValues(new Uint8Array(NONCE_SIZE));
+  const data = new Uint8Array(await crypto.subtle.encrypt({ name: 'AES-GCM', iv: nonce }, key, plaintext));
+  return { nonce, data };
+}
+
+async function open(key, nonce, data) {
+  return new Uint8Array(await crypto.subtle.decrypt({ name: 'AES-GCM', iv: nonce }, key, data));
+}
+
+// parseRecord decodes the encrypted record of an item, from its binary
+// envelope or EncryptedData JSON, into the fields of the JSON
+function parseRecord(bytes) {
+  const record = isEnvelope(bytes) ? parseEnvelope(bytes) : JSON.parse(decoder.decode(bytes));
+  if (!record.salt || base64ToBytes(record.salt).length !== SALT_SIZE) {
+    throw new Error('Invalid salt length in encrypted data');
+  }
+  return record;
+}
+
+function isEnvelope(bytes) {
+  return bytes.length >= ENVELOPE_MAGIC.length && ENVELOPE_MAGIC.every((b, i) => bytes[i] === b);
+}
+
+// parseEnvelope decodes a binary envelope: magic, version, salt, iterations,
+// wrapped key length, wrapped key, nonce and ciphertext
+function parseEnvelope(bytes) {
+  let offset = ENVELOPE_MAGIC.length;
+  if (bytes[offset] !== ENVELOPE_VERSION) {
+    throw new Error(`Unsupported encrypted data envelope version ${bytes[offset]}`);
+  }
+  offset++;
+  const salt = bytes.subarray(offset, offset + SALT_SIZE);
+  let iterations;
+  let wrappedLength;
+  [iterations, offset] = readUvarint(bytes, offset + SALT_SIZE);
+  [wrappedLength, offset] = readUvarint(bytes, offset);
+  if (wrappedLength === 0 || offset + wrappedLength + NONCE_SIZE > bytes.length) {
+    throw new Error('Invalid encrypted data envelope');
+  }
+  const wrappedKey = bytes.subarray(offset, offset + wrappedLength);
+  offset += wrappedLength;
+  return {
+    version: FORMAT_DATA_KEY,
+    salt: bytesToBase64(salt),
+    iterations,
+    wrapped_key: bytesToBase64(wrappedKey),
+    nonce: bytesToBase64(bytes.subarray(offset, offset + NONCE_SIZE)),
+    data: bytesToBase64(bytes.subarray(offset + NONCE_SIZE)),
+  };
+}
+
+// dataKeyOf unwraps the data key of a record with the vault key
+async function dataKeyOf(record) {
+  const wrapped = base64ToBytes(record.wrapped_key);
+  return open(await vaultKey(record.salt, record.iterations), wrapped.subarray(0, NONCE_SIZE), wrapped.subarray(NONCE_SIZE));
+}
+
+// decryptRecord decrypts an item's data in any format version
+async function decryptRecord(bytes) {
+  const record = parseRecord(bytes);
+  let key;
+  switch (record.version || FORMAT_VAULT_KEY) {
+    case FORMAT_VAULT_KEY:
+      key = await vaultKey(record.salt, record.iterations);
+      break;
+    case FORMAT_DATA_KEY:
+      key = await importAESKey(await dataKeyOf(record));
+      break;
+    default:
+      throw new Error(`Unsupported encrypted data version ${record.version}`);
+  }
+  return open(key, base64ToBytes(record.nonce), base64ToBytes(record.data));
+}
+
+// encryptRecord encrypts plaintext under a fresh data key wrapped by the
+// vault key. Replacing an item keeps its data key, as the command-line client
+// does, so whoever it is shared with can still read it. It returns the
+// record with the checksum of the plaintext.
+async function encryptRecord(plaintext, currentBytes) {
+  const current = currentBytes ? parseRecord(currentBytes) : null;
+  let dataKey;
+  let salt;
+  let iterations;
+  let wrappedKey;
+  if (current && current.version === FORMAT_DATA_KEY) {
+    dataKey = await dataKeyOf(current);
+    salt = current.salt;
+    iterations = current.iterations;
+    wrappedKey = current.wrapped_key;
+  } else {
+    dataKey = crypto.getRandomValues(new Uint8Array(DATA_KEY_SIZE));
+    salt = session.salt;
+    iterations = session.iterations;
+    const wrapped = await seal(await vaultKey(salt, iterations), dataKey);
+    wrappedKey = bytesToBase64(concat(wrapped.nonce, wrapped.data));
+  }
+
+  const sealed = await seal(await importAESKey(dataKey), plaintext);
+  const wrapped = base64ToBytes(wrappedKey);
+  const data = concat(ENVELOPE_MAGIC, [ENVELOPE_VERSION], base64ToBytes(salt), uvarint(iterations || 0),
+    uvarint(wrapped.length), wrapped, sealed.nonce, sealed.data);
+  return { data, checksum: await checksum(dataKey, plaintext) };
+}
+
+async function hmac(hash, rawKey, message) {
+  const key = await crypto.subtle.importKey('raw', rawKey, { name: 'HMAC', hash }, false, ['sign']);
+  return new Uint8Array(await crypto.subtle.sign('HMAC', key, message));
+}
+
+// checksum is the HMAC-SHA256 of plaintext under a key derived from the data key
+async function checksum(dataKey, plaintext) {
+  const checksumKey = await hmac('SHA-256', dataKey, encoder.encode(CHECKSUM_LABEL));
+  return hmac('SHA-256', checksumKey, plaintext);
+}
+
+// totp returns the current RFC 6238 code of an otp item and its seconds left
+async function totp(otp) {
+  const period = otp.period || 30;
+  const digits = otp.digits || 6;
+  const hash = OTP_HASHES[(otp.algorithm || 'SHA1').toUpperCase()];
+  const key = base32ToBytes(otp.secret || '');
+  if (!hash || !key || key.length === 0) {
+    throw new Error('Invalid one-time password secret');
+  }
+
+  const now = Math.floor(Date.now() / 1000);
+  const counter = Math.floor(now / period);
+  const message = new Uint8Array(8);
+  const view = new DataView(message.buffer);
+  view.setUint32(0, Math.floor(counter / 2 ** 32));
+  view.setUint32(4, counter >>> 0);
+  const sum = await hmac(hash, key, message);
+
+  const offset = sum[sum.length - 1] & 0x0f;
+  const value = new DataView(sum.buffer).getUint32(offset) & 0x7fffffff;
+  return { code: String(value % 10 ** digits).padStart(digits, '0'), remaining: period - (now % period) };
+}
+
+// Server API
+
+class APIError extends Error {
+  constructor(status, message, code) {
+    super(message);
+    this.status = status;
+    this.code = code;
+  }
+}
+
+// api calls the server relative to this page, so a server behind a path
+// prefix works too
+async function api(method, path, body, headers = {}) {
+  const init = { method, headers: { ...headers } };
+  if (session) {
+    init.headers.Authorization = `Bearer ${session.token}`;
+  }
+  if (body !== undefined) {
+    init.headers['Content-Type'] = 'application/json';
+    init.body = JSON.stringify(body);
+  }
+
+  const resp = await fetch(new URL(`..${path}`, window.location.href), init);
+  const text = await resp.text();
+  let json = null;
+  try {
+    json = text ? JSON.parse(text) : null;
+  } catch {
+    // error pages of proxies are not JSON
+  }
+  if (!resp.ok) {
+    const message = (json && (json.message || json.error)) || `${resp.status} ${resp.statusText}`;
+    if (resp.status === 401 && session) {
+      lock();
+      throw new APIError(resp.status, 'Your session expired, log in again', json && json.code);
+    }
+    throw new APIError(resp.status, message, json && json.code);
+  }
+  return json;
+}
+
+// checkMasterPassword checks the derived key against the verifier stored with
+// the account. Accounts from before verifiers are checked against their
+// first item instead, and an empty vault without one cannot be checked.
+async function checkMasterPassword() {
+  let verifier = null;
+  try {
+    verifier = (await api('GET', '/api/v1/verifier')).verifier;
+  } catch {
+    // servers without verifiers fall back to the first item
+  }
+
+  if (verifier) {
+    const bytes = base64ToBytes(verifier);
+    if (parseRecord(bytes).salt !== session.salt) {
+      throw new WrongMasterPassword();
+    }
+    try {
+      if (decoder.decode(await decryptRecord(bytes)) === VERIFIER_PLAINTEXT) {
+        return;
+      }
+    } catch {
+      // a wrong key fails authentication
+    }
+    throw new WrongMasterPassword();
+  }
+
+  await loadItems();
+  if (items.length > 0) {
+    try {
+      await decryptRecord(base64ToBytes(items[0].data));
+    } catch {
+      throw new WrongMasterPassword();
+    }
+  }
+}
+
+async function loadItems() {
+  items = (await api('GET', '/api/v1/data')).data || [];
+  items.sort((a, b) => a.name.localeCompare(b.name));
+  await loadIcons();
+}
+
+// loadIcons decrypts the favicons the command-line client stores with logins
+async function loadIcons() {
+  clearIcons();
+  await Promise.all(items.filter((item) => item.icon).map(async (item) => {
+    try {
+      const icon = await decryptRecord(base64ToBytes(item.icon));
+      iconURLs.set(item.id, URL.createObjectURL(new Blob([icon])));
+    } catch {
+      // an icon that does not decrypt is left out
+    }
+  }));
+}
+
+function clearIcons() {
+  for (const url of iconURLs.values()) {
+    URL.revokeObjectURL(url);
+  }
+  iconURLs = new Map();
+}
+
+// decryptPayload decrypts an item into its payload: the fields of the type,
+// or for files the content as base64 with the file details. Files uploaded
+// in chunks are left to the command-line client.
+async function decryptPayload(item) {
+  if (item.type === 'binary') {
+    const details = JSON.parse(item.metadata || '{}');
+    if (details.chunks) {
+      return details;
+    }
+    return { ...details, content: decoder.decode(await decryptRecord(base64ToBytes(item.data))) };
+  }
+  return JSON.parse(decoder.decode(await decryptRecord(base64ToBytes(item.data))));
+}
+
+// itemMetadata mirrors the summaries the command-line client stores
+function itemMetadata(type, payload) {
+  const field = (name) => payload[name] || '';
+  switch (type) {
+    case 'login_password':
+      return `Login: ${field('login')}, URL: ${field('url')}`;
+    case 'bank_card':
+      return `Card: ${field('card_number')}, Bank: ${field('bank')}`;
+    case 'text':
+      return `Length: ${encoder.encode(field('content')).length} characters`;
+    case 'otp':
+      return `Issuer: ${field('issuer')}, Account: ${field('account')}`;
+    default:
+      return '';
+  }
+}
+
+// otpPayload builds an otp payload in the field order of models.OTPData,
+// normalizing the secret as the command-line client does
+function otpPayload(current, fields) {
+  const secret = fields.secret.toUpperCase().replace(/[\s-]/g, '');
+  const key = base32ToBytes(secret);
+  if (!key || key.length === 0) {
+    throw new Error('The secret must be base32 encoded');
+  }
+  const payload = { secret };
+  for (const [name, value] of [
+    ['issuer', fields.issuer],
+    ['account', fields.account],
+    ['algorithm', (current.algorithm || 'SHA1').toUpperCase()],
+    ['digits', current.digits || 6],
+    ['period', current.period || 30],
+    ['notes', fields.notes],
+  ]) {
+    if (value) {
+      payload[name] = value;
+    }
+  }
+  return payload;
+}
+
+// Views
+
+function showMessage(text, isError = false) {
+  const message = $('message');
+  message.textContent = text;
+  message.className = isError ? 'error' : '';
+  message.hidden = !text;
+}
+
+function showView(id) {
+  for (const view of ['login-view', 'list-view', 'item-view', 'edit-view']) {
+    $(view).hidden = view !== id;
+  }
+  if (id !== 'item-view') {
+    clearInterval(otpTimer);
+    otpTimer = null;
+  }
+}
+
+function lock() {
+  session = null;
+  items = [];
+  opened = null;
+  clearIcons();
+  $('items').replaceChildren();
+  $('item-fields').replaceChildren();
+  $('edit-fields').replaceChildren();
+  $('user').textContent = '';
+  $('lock').hidden = true;
+  showView('login-view');
+}
+
+function renderList() {
+  const query = $('search').value.trim().toLowerCase();
+  const type = $('type-filter').value;
+  const rows = items
+    .filter((item) => !type || item.type === type)
+    .filter((item) => !query || [item.name, item.description, item.environment, ...(item.tags || [])]
+      .some((text) => (text || '').toLowerCase().includes(query)))
+    .map((item) => {
+      const row = document.createElement('tr');
+      for (const text of [item.name, TYPE_NAMES[item.type] || item.type, item.description,
+        new Date(item.updated_at).toLocaleString()]) {
+        const cell = document.createElement('td');
+        cell.textContent = text || '';
+        row.append(cell);
+      }
+      if (iconURLs.has(item.id)) {
+        const icon = document.createElement('img');
+        icon.className = 'icon';
+        icon.alt = '';
+        icon.src = iconURLs.get(item.id);
+        row.firstChild.prepend(icon);
+      }
+      row.addEventListener('click', () => run(() => showItem(item)));
+      return row;
+    });
+  $('items').replaceChildren(...rows);
+  $('empty').hidden = rows.length > 0;
+  showView('list-view');
+}
+
+function addField(list, label, value, { secret = false, copy = true } = {}) {
+  const term = document.createElement('dt');
+  term.textContent = label;
+  const definition = document.createElement('dd');
+  const text = document.createElement('span');
+  text.textContent = secret ? '••••••••' : value;
+  definition.append(text);
+  if (secret) {
+    const reveal = document.createElement('button');
+    reveal.type = 'button';
+    reveal.textContent = 'Show';
+    reveal.addEventListener('click', () => {
+      const hidden = reveal.textContent === 'Show';
+      text.textContent = hidden ? value : '••••••••';
+      reveal.textContent = hidden ? 'Hide' : 'Show';
+    });
+    definition.append(reveal);
+  }
+  if (copy) {
+    const copyButton = document.createElement('button');
+    copyButton.type = 'button';
+    copyButton.textContent = 'Copy';
+    copyButton.addEventListener('click', () => run(async () => {
+      await navigator.clipboard.writeText(value);
+      showMessage(`${label} copied`);
+    }));
+    definition.append(copyButton);
+  }
+  list.append(term, definition);
+  return text;
+}
+
+function fieldLabel(name) {
+  const label = name.replace(/_/g, ' ');
+  return label.charAt(0).toUpperCase() + label.slice(1);
+}
+
+async function showItem(item) {
+  const payload = await decryptPayload(item);
+  opened = { item, payload };
+  showMessage('');
+  $('item-name').textContent = item.name;
+  $('item-description').textContent = [item.description, item.environment, (item.tags || []).join(', ')]
+    .filter(Boolean).join(' · ');
+  $('edit-item').hidden = !FIELDS[item.type];
+
+  const list = document.createElement('dl');
+  list.id = 'item-fields';
+  let refreshCode = null;
+  if (item.type === 'binary') {
+    addField(list, 'File name', payload.file_name || '', { copy: false });
+    addField(list, 'Size', `${payload.size || 0} bytes`, { copy: false });
+    if (payload.notes) {
+      addField(list, 'Notes', payload.notes);
+    }
+    const download = document.createElement('button');
+    download.type = 'button';
+    if (payload.chunks) {
+      download.textContent = 'Download large files with the command-line client';
+      download.disabled = true;
+    } else {
+      download.textContent = 'Download';
+      download.addEventListener('click', () => {
+        const blob = new Blob([base64ToBytes(payload.content)], { type: payload.mime_type || 'application/octet-stream' });
+        const link = document.createElement('a');
+        link.href = URL.createObjectURL(blob);
+        link.download = payload.file_name || 'download';
+        link.click();
+        URL.revokeObjectURL(link.href);
+      });
+    }
+    const definition = document.createElement('dd');
+    definition.append(download);
+    list.append(document.createElement('dt'), definition);
+  } else {
+    const known = (FIELDS[item.type] || []).map((field) => field.name);
+    for (const field of FIELDS[item.type] || []) {
+      if (payload[field.name]) {
+        addField(list, fieldLabel(field.name), String(payload[field.name]), { secret: field.secret });
+      }
+    }
+    for (const [name, value] of Object.entries(payload)) {
+      if (!known.includes(name) && value !== '' && value !== null && typeof value !== 'object') {
+        addField(list, fieldLabel(name), String(value), { copy: false });
+      }
+    }
+    if (item.type === 'otp') {
+      const code = addField(list, 'Code', '', { copy: false });
+      refreshCode = async () => {
+        try {
+          const { code: value, remaining } = await totp(payload);
+          code.textContent = `${value} (${remaining}s)`;
+        } catch (err) {
+          code.textContent = err.message;
+        }
+      };
+      await refreshCode();
+    }
+  }
+  $('item-fields').replaceWith(list);
+  showView('item-view');
+  if (refreshCode) {
+    otpTimer = setInterval(refreshCode, 1000);
+  }
+}
+
+function renderEditFields(type, payload) {
+  const fields = (FIELDS[type] || []).map((field) => {
+    const label = document.createElement('label');
+    label.textContent = fieldLabel(field.name);
+    const input = document.createElement(field.multiline ? 'textarea' : 'input');
+    input.name = `field-${field.name}`;
+    input.required = Boolean(field.required);
+    if (field.secret) {
+      input.type = 'password';
+      input.autocomplete = 'new-password';
+    }
+    input.value = payload[field.name] ? String(payload[field.name]) : '';
+    label.append(input);
+    return label;
+  });
+  $('edit-fields').replaceChildren(...fields);
+}
+
+function showEditor(item, payload) {
+  const form = $('edit-form');
+  form.reset();
+  form.elements.type.disabled = Boolean(item);
+  form.elements.type.value = item ? item.type : 'login_password';
+  form.elements.name.value = item ? item.name : '';
+  form.elements.description.value = item ? item.description : '';
+  form.elements.environment.value = item ? item.environment || '' : '';
+  form.elements.tags.value = item ? (item.tags || []).join(', ') : '';
+  $('edit-title').textContent = item ? `Edit ${item.name}` : 'New item';
+  renderEditFields(form.elements.type.value, payload || {});
+  showMessage('');
+  showView('edit-view');
+}
+
+async function saveItem(form) {
+  const item = opened ? opened.item : null;
+  const type = form.elements.type.value;
+  const current = item ? opened.payload : {};
+  const fields = {};
+  for (const field of FIELDS[type]) {
+    fields[field.name] = form.elements[`field-${field.name}`].value;
+  }
+
+  let payload;
+  if (type === 'otp') {
+    payload = otpPayload(current, fields);
+  } else {
+    // fields this page does not know are kept
+    payload = { ...current };
+    for (const [name, value] of Object.entries(fields)) {
+      if (value) {
+        payload[name] = value;
+      } else {
+        delete payload[name];
+      }
+    }
+  }
+
+  const plaintext = encoder.encode(JSON.stringify(payload));
+  const encrypted = await encryptRecord(plaintext, item ? base64ToBytes(item.data) : null);
+  const tags = form.elements.tags.value.split(',').map((tag) => tag.trim()).filter(Boolean);
+  const body = {
+    type,
+    name: form.elements.name.value.trim(),
+    description: form.elements.description.value,
+    data: bytesToBase64(encrypted.data),
+    metadata: itemMetadata(type, payload),
+    environment: form.elements.environment.value.trim() || undefined,
+    tags: item || tags.length > 0 ? tags : undefined,
+    checksum: bytesToBase64(encrypted.checksum),
+  };
+  // Browsers have no public suffix list to derive the registrable domain of a
+  // URL, so a changed URL clears the domain index of a login and the
+  // command-line client indexes it again on its next lookup that misses. The
+  // icon of the old site goes too.
+  if (type === 'login_password' && (!item || (current.url || '') !== (payload.url || ''))) {
+    body.domains = [];
+    if (item && item.icon) {
+      body.icon = '';
+    }
+  }
+
+  let saved;
+  try {
+    if (item) {
+      body.base_revision = item.revision;
+      saved = await api('PUT', `/api/v1/data/${item.id}`, body, { 'If-Match': `"${item.revision}"` });
+    } else {
+      saved = await api('POST', '/api/v1/data', body);
+    }
+  } catch (err) {
+    if (err.status === 409 || err.status === 412) {
+      throw new Error('The item was changed on another device; open it again to see the changes');
+    }
+    throw err;
+  }
+
+  await loadItems();
+  await showItem(saved.data);
+  showMessage(item ? 'Item updated' : 'Item created');
+}
+
+async function login(form) {
+  const username = form.elements.username.value.trim();
+  const resp = await api('POST', '/api/v1/login', { username, password: form.elements.password.value },
+    { 'X-Device-Name': DEVICE_NAME });
+  if (!resp.salt) {
+    throw new Error('This account has no vault yet; log in once with the command-line client to set it up');
+  }
+
+  session = {
+    token: resp.token,
+    username: resp.user.username,
+    salt: resp.salt,
+    iterations: resp.kdf_iterations || 0,
+    vaultKeys: new Map(),
+    passwordKey: await crypto.subtle.importKey('raw', encoder.encode(form.elements.master.value), 'PBKDF2',
+      false, ['deriveKey']),
+  };
+  try {
+    await checkMasterPassword();
+    await loadItems();
+  } catch (err) {
+    await revokeSession();
+    session = null;
+    throw err;
+  }
+
+  form.reset();
+  $('user').textContent = session.username;
+  $('lock').hidden = false;
+  showMessage('');
+  renderList();
+}
+
+// revokeSession ends the login of this page on the server, so the token is
+// useless once the vault is locked
+async function revokeSession() {
+  try {
+    const { sessions } = await api('GET', '/api/v1/sessions');
+    const current = (sessions || []).find((s) => s.current);
+    if (current) {
+      await api('DELETE', `/api/v1/sessions/${current.id}`);
+    }
+  } catch {
+    // servers without sessions let the token expire
+  }
+}
+
+// run reports the failure of an action instead of leaving it unhandled
+async function run(action) {
+  try {
+    await action();
+  } catch (err) {
+    showMessage(err.message || String(err), true);
+  }
+}
+
+function init() {
+  if (!window.crypto || !window.crypto.subtle) {
+    showMessage('This browser cannot encrypt here: open the page over HTTPS or on localhost', true);
+    $('login-form').querySelector('button').disabled = true;
+  }
+
+  $('login-form').addEventListener('submit', (event) => {
+    event.preventDefault();
+    showMessage('Unlocking…');
+    run(() => login(event.target));
+  });
+  $('lock').addEventListener('click', () => run(async () => {
+    await revokeSession();
+    lock();
+    showMessage('Vault locked');
+  }));
+  $('search').addEventListener('input', renderList);
+  $('type-filter').addEventListener('change', renderList);
+  $('refresh').addEventListener('click', () => run(async () => {
+    await loadItems();
+    renderList();
+  }));
+  $('new-item').addEventListener('click', () => {
+    opened = null;
+    showEditor(null, null);
+  });
+  $('back').addEventListener('click', () => {
+    opened = null;
+    showMessage('');
+    renderList();
+  });
+  $('edit-item').addEventListener('click', () => showEditor(opened.item, opened.payload));
+  $('cancel-edit').addEventListener('click', () => (opened ? run(() => showItem(opened.item)) : renderList()));
+  $('edit-form').elements.type.addEventListener('change', (event) => renderEditFields(event.target.value, {}));
+  $('edit-form').addEventListener('submit', (event) => {
+    event.preventDefault();
+    run(() => saveItem(event.target));
+  });
+}
+
+init();
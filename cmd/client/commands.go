@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/assets"
+	"github.com/a2sh3r/gophkeeper/internal/cli"
+	"github.com/a2sh3r/gophkeeper/internal/client"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// newCommandSet returns the commands of the client, run by h. A nil h is
+// enough to look commands up and generate completion scripts.
+func newCommandSet(h *CommandHandler) *cli.Set {
+	dataTypes := []string{
+		string(models.DataTypeLoginPassword), string(models.DataTypeText), string(models.DataTypeBinary),
+		string(models.DataTypeBankCard), string(models.DataTypeOTP),
+	}
+	itemFlagNames := []string{"--name", "--description", "--env", "--tag", "--field", "--json", "--from-file", "--output"}
+
+	return cli.NewSet(
+		&cli.Command{Name: "status", Summary: "Show server version, whether registration is open and supported features",
+			Run: func(ctx context.Context, args []string) bool {
+				if err := h.session.StatusCommand(ctx); err != nil {
+					fmt.Println(err)
+				}
+				return false
+			}},
+		&cli.Command{Name: "setup", Summary: "Guided first-time setup (server, account, master password, first item)",
+			Run: func(ctx context.Context, args []string) bool { return h.handleSetup(ctx) }},
+		&cli.Command{Name: "register", Usage: "<username> <password>", Summary: "Register a new user (requires master password)",
+			Run: h.handleRegister},
+		&cli.Command{Name: "login", Usage: "<username> <password>", Summary: "Login with existing user (requires master password)",
+			Run: h.handleLogin},
+		&cli.Command{Name: "sso", Usage: "[link]", Summary: "Login with the server's identity provider, or link it to your account",
+			Args: []string{"link"}, Run: h.handleSSO},
+		&cli.Command{Name: "unlock", Summary: "Open the vault of the saved login with the master password",
+			Run: func(ctx context.Context, args []string) bool { return h.handleUnlock(ctx) }},
+		&cli.Command{Name: "lock", Summary: "Drop the master password and keys from memory until 'unlock'",
+			Run: func(ctx context.Context, args []string) bool {
+				if h.session.IsAuthenticated() {
+					h.session.Lock()
+					fmt.Println("Vault locked. Use 'unlock' to open it again.")
+				} else {
+					fmt.Println("Vault is not unlocked")
+				}
+				return false
+			}},
+		&cli.Command{Name: "autolock", Usage: "[<minutes> | off]", Summary: "Show or set how long the vault stays unlocked without input",
+			Args: []string{"off"},
+			Run: func(ctx context.Context, args []string) bool {
+				if err := client.AutoLockCommand(h.config, args); err != nil {
+					fmt.Println(err)
+					fmt.Println("Usage: autolock [<minutes> | off]")
+				}
+				h.idle.SetTimeout(h.config.IdleTimeout())
+				return false
+			}},
+		&cli.Command{Name: "list", Usage: "[--env <env> | --all] [--flat] [--output json]", Summary: "List encrypted data grouped by type",
+			Flags: []string{"--env", "--all", "--flat", "--output"}, CommandLine: true, Run: h.handleList},
+		&cli.Command{Name: "search", Aliases: []string{"find"}, Usage: "<query>",
+			Summary: "List items whose name, description or metadata contain the query", Run: h.handleSearch},
+		&cli.Command{Name: "find-login", Usage: "<url> [--match domain|host|exact] [--output json]",
+			Summary: "List the logins saved for a site, matching subdomains by the given rule",
+			Flags:   []string{"--match", "--output"}, CommandLine: true, Run: h.handleFindLogin},
+		&cli.Command{Name: "get", Aliases: []string{"show"}, Usage: "<id> [--reveal [--force]] [--output json]",
+			Summary: "Get and decrypt data by ID or unique ID prefix, with secrets masked unless revealed",
+			Flags:   []string{"--reveal", "--force", "--output"}, CommandLine: true, Run: h.handleGet},
+		&cli.Command{Name: "peek", Usage: "<id> [seconds]", Summary: "Show decrypted data briefly, then clear it from the screen",
+			Run: h.handlePeek},
+		&cli.Command{Name: "copy", Aliases: []string{"cp"}, Usage: "<id> [field] [seconds]",
+			Summary: "Copy the password, card number or another field to the clipboard and clear it later", Run: h.handleCopy},
+		&cli.Command{Name: "totp", Usage: "<id>", Summary: "Show the current one-time code of an otp item", Run: h.handleTOTP},
+		&cli.Command{Name: "history", Usage: "<id> [restore <version>]", Summary: "List previous versions of an item, or revert to one",
+			Run: h.handleHistory},
+		&cli.Command{Name: "create", Aliases: []string{"new", "add"}, Usage: "<type> [name] [description] [flags]",
+			Summary: "Create new encrypted data, prompting for its fields unless they are given by flags or JSON",
+			Flags:   append(itemFlagNames, "--file"), Args: dataTypes, CommandLine: true, Run: h.handleCreate},
+		&cli.Command{Name: "update", Aliases: []string{"edit"}, Usage: "[--raw] <id> [flags]",
+			Summary: "Edit an item field by field, or change it from flags or JSON",
+			Flags:   append([]string{"--raw"}, itemFlagNames...), CommandLine: true, Run: h.handleUpdate},
+		&cli.Command{Name: "rename", Usage: "<id> <new name>", Summary: "Rename an item without re-entering or re-encrypting its secret",
+			Run: h.handleRename},
+		&cli.Command{Name: "describe", Usage: "<id> [description]", Summary: "Set or clear the description of an item without touching its secret",
+			Run: h.handleDescribe},
+		&cli.Command{Name: "delete", Aliases: []string{"rm"}, Usage: "<id>...", Summary: "Delete encrypted data; several items are deleted together, all or none",
+			Run: h.handleDelete},
+		&cli.Command{Name: "save", Usage: "<id> [path]", Summary: "Save decrypted binary data to file", Run: h.handleSave},
+		&cli.Command{Name: "comment", Usage: "<id> <text>", Summary: "Append an encrypted, timestamped comment to an item",
+			Run: h.handleComment},
+		&cli.Command{Name: "attach", Usage: "<id> <file>", Summary: "Attach an encrypted file of up to 10 MiB to an item",
+			Run: h.handleAttach},
+		&cli.Command{Name: "attachments", Usage: "<id>", Summary: "List the files attached to an item", Run: h.handleAttachments},
+		&cli.Command{Name: "download", Usage: "<id> <attachment> [path]", Summary: "Save a decrypted attachment, by ID or file name, to a file",
+			Run: h.handleDownload},
+		&cli.Command{Name: "detach", Usage: "<id> <attachment>", Summary: "Remove an attachment from an item", Run: h.handleDetach},
+		&cli.Command{Name: "tag", Usage: "add|remove <id> <tag>... | list [tag]", Summary: "Add or remove tags of an item, or list the tags in use",
+			Args: []string{"add", "remove", "list"}, Run: h.handleTag},
+		&cli.Command{Name: "ls", Usage: "[path]", Summary: "Show the collections and their items as a tree", Run: h.handleLs},
+		&cli.Command{Name: "mkdir", Usage: "<path>", Summary: "Create a collection such as Work/Servers, with any missing parents",
+			Run: h.handleMkdir},
+		&cli.Command{Name: "rmdir", Usage: "<path>", Summary: "Delete an empty collection", Run: h.handleRmdir},
+		&cli.Command{Name: "mv", Usage: "<id|/collection> <path|/>", Summary: "Move an item or a collection into a collection",
+			Run: h.handleMv},
+		&cli.Command{Name: "import", Usage: "[format] <file>", Summary: "Import items from NDJSON or another password manager's export",
+			Args: client.ImportFormats, CommandLine: true, Run: h.handleImport},
+		&cli.Command{Name: "export", Usage: "[path]", Summary: "Write all items, decrypted, to a file encrypted with a separate export password",
+			Run: h.handleExport},
+		&cli.Command{Name: "audit", Usage: "[count]", Summary: "Show the latest reads and changes of your items and logins",
+			Run: h.handleAudit},
+		&cli.Command{Name: "audit-passwords", Usage: "[--breach]", Summary: "Report weak, reused and breached login passwords",
+			Flags: []string{"--breach"},
+			Run: func(ctx context.Context, args []string) bool {
+				if err := h.session.AuditPasswordsCommand(ctx, args); err != nil {
+					if err == client.ErrNotAuthenticated {
+						fmt.Println("Please login first to audit your passwords")
+					} else {
+						fmt.Printf("Password audit failed: %v\n", err)
+					}
+				}
+				return false
+			}},
+		&cli.Command{Name: "verify", Usage: "<id> | --all", Summary: "Decrypt items and check them against their checksums to detect corruption",
+			Flags: []string{"--all"},
+			Run: func(ctx context.Context, args []string) bool {
+				if len(args) != 1 {
+					fmt.Println("Usage: verify <id> | --all")
+					return false
+				}
+				if err := h.session.VerifyCommand(ctx, args[0]); err != nil {
+					if err == client.ErrNotAuthenticated {
+						fmt.Println("Please login first to verify your items")
+					} else {
+						fmt.Printf("Verification failed: %v\n", err)
+					}
+				}
+				return false
+			}},
+		&cli.Command{Name: "breach-check", Usage: "[on|off]", Summary: "Check new login passwords against known breaches when they are created",
+			Args: []string{"on", "off"},
+			Run: func(ctx context.Context, args []string) bool {
+				if err := h.session.BreachCheckCommand(h.config, args); err != nil {
+					fmt.Printf("Breach check: %v\n", err)
+				}
+				return false
+			}},
+		&cli.Command{Name: "icon", Usage: "[on|off] | fetch <id>|--all | remove <id>",
+			Summary: "Fetch the favicons of the sites of logins, for clients that show them",
+			Args:    []string{"on", "off", "fetch", "remove"},
+			Run: func(ctx context.Context, args []string) bool {
+				if err := h.session.IconCommand(ctx, h.config, args); err != nil {
+					printCommandError(err, "Please login first to fetch icons", "Icon")
+				}
+				return false
+			}},
+		&cli.Command{Name: "unused", Usage: "--older-than <age>", Summary: "List items not used via get/peek/save for e.g. 90d, 6m or 1y",
+			Flags: []string{"--older-than"},
+			Run: func(ctx context.Context, args []string) bool {
+				if err := h.session.UnusedCommand(ctx, args); err != nil {
+					if err == client.ErrNotAuthenticated {
+						fmt.Println("Please login first to access encrypted data")
+					} else {
+						fmt.Println(err)
+					}
+				}
+				return false
+			}},
+		&cli.Command{Name: "expiring", Usage: "[<period>] [--output json]",
+			Summary: "List items that expired or expire within e.g. 30d (the default), soonest first",
+			Flags:   []string{"--output"}, CommandLine: true, Run: h.handleExpiring},
+		&cli.Command{Name: "expire", Usage: "<id> <date>|never", Summary: "Set when an item expires, e.g. 2030-12-31 or 12/30, or clear it",
+			Args: []string{"never"},
+			Run: func(ctx context.Context, args []string) bool {
+				if err := h.session.ExpireCommand(ctx, args); err != nil {
+					printCommandError(err, "Please login first to access encrypted data", "Expire")
+				}
+				return false
+			}},
+		&cli.Command{Name: "env", Usage: "[<env> | clear]", Summary: "Show, set or clear the default environment",
+			Args: []string{"clear"}, Run: func(ctx context.Context, args []string) bool { return h.handleEnv(args) }},
+		&cli.Command{Name: "snapshot", Usage: "diff <from> <to> [--details]", Summary: "Show items added, changed or removed between two times",
+			Args: []string{"diff"}, Flags: []string{"--details"}, Run: h.handleSnapshot},
+		&cli.Command{Name: "assert", Usage: "exists <name> | field <name> <field> --matches <regex>",
+			Summary: "Check that an item exists or a field matches, without printing it (exit code 0/1/2 as argument)",
+			Args:    []string{"exists", "field"}, Flags: []string{"--matches"}, CommandLine: true,
+			Run: func(ctx context.Context, args []string) bool {
+				h.session.AssertCommand(ctx, args)
+				return false
+			}},
+		&cli.Command{Name: "scan", Usage: "<path> [path...]", Summary: "Check files for passwords, card numbers or keys stored in the vault",
+			CommandLine: true,
+			Run: func(ctx context.Context, args []string) bool {
+				if !h.session.IsAuthenticated() {
+					fmt.Println("Please login first to scan for stored secrets")
+					return false
+				}
+				h.session.ScanCommand(ctx, args)
+				return false
+			}},
+		&cli.Command{Name: "publish-field", Usage: "<id> <field> [days]", Summary: "Publish one field for machine consumers",
+			Run: h.handlePublishField},
+		&cli.Command{Name: "hint", Usage: "[show|set|remove]", Summary: "Manage an optional master password hint",
+			Args: []string{"show", "set", "remove"},
+			Run: func(ctx context.Context, args []string) bool {
+				if err := h.session.HintCommand(ctx, args); err != nil {
+					if err == client.ErrNotAuthenticated {
+						fmt.Println("Please login first to manage your hint")
+					} else {
+						fmt.Printf("Hint failed: %v\n", err)
+					}
+				}
+				return false
+			}},
+		&cli.Command{Name: "escrow", Usage: "[status|enable|disable]", Summary: "Manage opt-in organization key escrow",
+			Args: []string{"status", "enable", "disable"}, Run: h.handleEscrow},
+		&cli.Command{Name: "rotate-master", Summary: "Change the master password, re-wrapping the data keys of all items",
+			Run: func(ctx context.Context, args []string) bool { return h.handleRotateMaster(ctx) }},
+		&cli.Command{Name: "conflicts", Usage: "[resolve [<id>]]", Summary: "List conflict copies from concurrent edits, or merge them",
+			Args: []string{"resolve"}, Run: h.handleConflicts},
+		&cli.Command{Name: "sync", Summary: "Send changes made offline to the server and refresh the local copy of the vault",
+			Run: func(ctx context.Context, args []string) bool { return h.handleSync(ctx) }},
+		&cli.Command{Name: "share", Usage: "<id> <username> [read|write]", Summary: "Share an item with another user",
+			Run: h.sharingCommand("Please login first to share items", func(ctx context.Context, args []string) error {
+				return h.session.ShareCommand(ctx, args)
+			})},
+		&cli.Command{Name: "shares", Usage: "<id>", Summary: "List the users an item is shared with",
+			Run: h.sharingCommand("Please login first to list who an item is shared with", func(ctx context.Context, args []string) error {
+				return h.session.SharesCommand(ctx, args)
+			})},
+		&cli.Command{Name: "unshare", Usage: "<id> <username>", Summary: "Stop sharing an item with a user and re-encrypt it",
+			Run: h.sharingCommand("Please login first to stop sharing items", func(ctx context.Context, args []string) error {
+				return h.session.UnshareCommand(ctx, args)
+			})},
+		&cli.Command{Name: "shared", Usage: "[show <share-id> | edit <share-id> <field>=<value>...]", Summary: "List, show or change the items shared with you",
+			Args: []string{"show", "edit"},
+			Run: h.sharingCommand("Please login first to see items shared with you", func(ctx context.Context, args []string) error {
+				return h.session.SharedCommand(ctx, args)
+			})},
+		&cli.Command{Name: "sessions", Usage: "[revoke <id>]", Summary: "List your logins, or log one out",
+			Args: []string{"revoke"},
+			Run: func(ctx context.Context, args []string) bool {
+				if err := h.session.SessionsCommand(ctx, args); err != nil {
+					if err == client.ErrNotAuthenticated {
+						fmt.Println("Please login first to see your sessions")
+					} else {
+						fmt.Printf("Sessions: %v\n", err)
+					}
+				}
+				return false
+			}},
+		&cli.Command{Name: "keychain", Usage: "[status|remember|forget]", Summary: "Manage what is kept in the OS keychain",
+			Args: []string{"status", "remember", "forget"},
+			Run: func(ctx context.Context, args []string) bool {
+				if err := h.session.KeychainCommand(h.config, args); err != nil {
+					if err == client.ErrNotAuthenticated {
+						fmt.Println("Please login or unlock first to remember the vault key")
+					} else {
+						fmt.Printf("Keychain: %v\n", err)
+					}
+				}
+				return false
+			}},
+		&cli.Command{Name: "notify", Usage: "[on|off|test]", Summary: "Show a desktop notification when scans, syncs or recoveries finish",
+			Args: []string{"on", "off", "test"},
+			Run: func(ctx context.Context, args []string) bool {
+				if err := h.session.NotifyCommand(h.config, args); err != nil {
+					fmt.Printf("Notifications: %v\n", err)
+				}
+				return false
+			}},
+		&cli.Command{Name: "security-log", Usage: "[verify]", Summary: "Show local security events or verify their hash chain",
+			Args: []string{"verify"}, Run: func(ctx context.Context, args []string) bool { return h.handleSecurityLog(args) }},
+		&cli.Command{Name: "sync-k8s", Usage: "-tag <tag> [-namespace <ns>] [-interval 5m] [-once]",
+			Summary: "Sync the items with one tag to Kubernetes Secrets",
+			Flags:   []string{"-tag", "-namespace", "-api-server", "-token-file", "-interval", "-once"}, CommandLine: true},
+		&cli.Command{Name: "fetch-field", Usage: "[-json] <id> <field>", Summary: "Fetch one published field with a scoped token",
+			Flags: []string{"-json"}, CommandLine: true},
+		&cli.Command{Name: "recovery-keygen", Usage: "<private-key-file>", Summary: "Generate the organization recovery key pair for key escrow",
+			CommandLine: true},
+		&cli.Command{Name: "escrow-recover", Usage: "-private-key-file <file> -out <file> <username>",
+			Summary: "Recover the escrowed items of a user with the organization recovery key",
+			Flags:   []string{"-private-key-file", "-out"}, CommandLine: true},
+		&cli.Command{Name: "completion", Usage: strings.Join(cli.Shells, "|"), Summary: "Print the shell completion script",
+			Args: cli.Shells, CommandLine: true},
+		&cli.Command{Name: "help", Aliases: []string{"?"}, Usage: "[command]", Summary: "Show all commands, or the help of one",
+			Run: func(ctx context.Context, args []string) bool {
+				h.showHelp(args)
+				return false
+			}},
+		&cli.Command{Name: "exit", Aliases: []string{"quit", "q"}, Summary: "Exit the program",
+			Run: func(ctx context.Context, args []string) bool {
+				fmt.Println("Goodbye!")
+				return true
+			}},
+	)
+}
+
+// sharingCommand runs a sharing command, printing loginHint when the vault is locked
+func (h *CommandHandler) sharingCommand(loginHint string, run func(context.Context, []string) error) func(context.Context, []string) bool {
+	return func(ctx context.Context, args []string) bool {
+		if err := run(ctx, args); err != nil {
+			if err == client.ErrNotAuthenticated {
+				fmt.Println(loginHint)
+			} else {
+				fmt.Printf("Sharing: %v\n", err)
+			}
+		}
+		return false
+	}
+}
+
+// lookupCommand finds the command called name, printing why if there is none
+func (h *CommandHandler) lookupCommand(name string) (*cli.Command, bool) {
+	command, err := h.commands.Lookup(name)
+	var ambiguous *cli.AmbiguousError
+	switch {
+	case errors.As(err, &ambiguous):
+		fmt.Printf("Ambiguous command %q, could be: %s\n", name, strings.Join(ambiguous.Candidates, ", "))
+		return nil, false
+	case err != nil:
+		fmt.Printf("Unknown command: %s. Type 'help' for available commands.\n", name)
+		return nil, false
+	}
+	return command, true
+}
+
+// showHelp shows the help of the command in args, or the help of all commands
+func (h *CommandHandler) showHelp(args []string) {
+	if len(args) > 0 {
+		if command, ok := h.lookupCommand(args[0]); ok {
+			fmt.Print(command.Help())
+		}
+		return
+	}
+
+	fmt.Print(assets.ClientHelp)
+}
+
+// runCompletion prints the completion script for the shell in args
+func runCompletion(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: completion %s\n", strings.Join(cli.Shells, "|"))
+		return client.AssertExitError
+	}
+
+	var flags []cli.Flag
+	flag.VisitAll(func(f *flag.Flag) {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, cli.Flag{Name: f.Name, Usage: f.Usage, TakesValue: !ok || !boolFlag.IsBoolFlag()})
+	})
+	program := filepath.Base(os.Args[0])
+	if err := cli.WriteCompletion(os.Stdout, args[0], program, newCommandSet(nil), flags); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return client.AssertExitError
+	}
+	return client.AssertExitPassed
+}
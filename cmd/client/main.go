@@ -3,33 +3,107 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/a2sh3r/gophkeeper/internal/cli"
 	"github.com/a2sh3r/gophkeeper/internal/client"
+	"github.com/a2sh3r/gophkeeper/internal/client/tui"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
 	"github.com/a2sh3r/gophkeeper/pkg/version"
 )
 
+// Environment variables for non-interactive use
+const (
+	masterPasswordEnv = "GOPHKEEPER_MASTER_PASSWORD"
+	fieldTokenEnv     = "GOPHKEEPER_FIELD_TOKEN"
+	dataKeyEnv        = "GOPHKEEPER_DATA_KEY"
+	adminTokenEnv     = "GOPHKEEPER_ADMIN_TOKEN"
+)
+
 // CommandHandler handles CLI commands
 type CommandHandler struct {
 	session *client.ClientSession
 	config  *client.Config
+	// mutex serializes commands with automatic locks from the system lock watcher
+	mutex sync.Mutex
+	// idle locks the vault after a period without input
+	idle *client.IdleWatcher
+	// commands are the commands of the prompt and the command line
+	commands *cli.Set
 }
 
 // NewCommandHandler creates a new command handler
 func NewCommandHandler(session *client.ClientSession, config *client.Config) *CommandHandler {
-	return &CommandHandler{
+	h := &CommandHandler{
 		session: session,
 		config:  config,
+		idle:    client.NewIdleWatcher(config.IdleTimeout(), clock.System{}),
+	}
+	h.commands = newCommandSet(h)
+	return h
+}
+
+// newClient creates a client for the configured server with the configured
+// timeouts and proxy, verifying its certificate with the configured CA bundle
+// and retrying transient failures. With the gRPC protocol the vault calls go
+// to the gRPC API, over TLS when the server URL is HTTPS.
+func newClient(config *client.Config) (*client.Client, error) {
+	cli := client.NewClient(config.ServerURL)
+	cli.SetRetryPolicy(config.RetryPolicy())
+	if err := cli.SetNetworkOptions(config.NetworkOptions()); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := client.NewTLSConfig(config.CACertFile, config.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	if config.CACertFile != "" || config.InsecureSkipVerify {
+		cli.SetTLSConfig(tlsConfig)
+	}
+
+	switch config.Protocol {
+	case "", client.ProtocolHTTP:
+		return cli, nil
+	case client.ProtocolGRPC:
+	default:
+		return nil, fmt.Errorf("unknown protocol %q, use %s or %s", config.Protocol, client.ProtocolHTTP, client.ProtocolGRPC)
+	}
+	target, secure, err := config.GRPCTarget()
+	if err != nil {
+		return nil, err
+	}
+	if !secure {
+		tlsConfig = nil
 	}
+	if err := cli.UseGRPC(target, tlsConfig); err != nil {
+		return nil, err
+	}
+	return cli, nil
 }
 
 func main() {
 	var (
 		serverURL   = flag.String("server", "http://localhost:8080", "Server URL")
 		showVersion = flag.Bool("version", false, "Show version information")
+		demo        = flag.Bool("demo", false, "Explore with an ephemeral in-memory demo vault (no server, nothing persisted)")
+		useTUI      = flag.Bool("tui", false, "Browse and edit the vault in a full-screen terminal UI")
+		caCert      = flag.String("ca-cert", "", "PEM bundle of CA certificates to trust for the server")
+		insecure    = flag.Bool("insecure", false, "Skip verification of the server certificate (testing only)")
+		timeout     = flag.Duration("timeout", 0, "Time limit for a request to the server, e.g. 1m (remembered)")
+		protocol    = flag.String("protocol", "", "API to send vault calls over: http or grpc (remembered)")
+		grpcServer  = flag.String("grpc-server", "", "host:port of the gRPC API, the server host on port "+client.DefaultGRPCPort+" by default (remembered)")
 	)
 	flag.Parse()
 
@@ -38,24 +112,494 @@ func main() {
 		os.Exit(0)
 	}
 
+	if flag.Arg(0) == "completion" {
+		os.Exit(runCompletion(flag.Args()[1:]))
+	}
+
+	if *demo {
+		runDemo(flag.Args(), *useTUI)
+		return
+	}
+
 	config := client.NewConfig()
 	if config.ServerURL == "" {
 		config.ServerURL = *serverURL
 	}
+	if *caCert != "" {
+		config.CACertFile = *caCert
+	}
+	if *timeout > 0 {
+		config.TimeoutSeconds = int(max(*timeout, time.Second) / time.Second)
+	}
+	if *protocol != "" {
+		config.Protocol = *protocol
+	}
+	if *grpcServer != "" {
+		config.GRPCAddress = *grpcServer
+	}
+	config.InsecureSkipVerify = *insecure
+	if config.InsecureSkipVerify {
+		fmt.Println("Warning: the server certificate is not verified")
+	}
 
-	cli := client.NewClient(config.ServerURL)
+	cli, err := newClient(config)
+	if err != nil {
+		fmt.Printf("Failed to configure the client: %v\n", err)
+		os.Exit(1)
+	}
 	if config.Token != "" {
 		cli.SetToken(config.Token)
 	}
 
 	session := client.NewClientSession(cli)
+	if err := session.SetKDFIterations(config.KDFIterations); err != nil {
+		fmt.Printf("Invalid kdf_iterations in %s: %v\n", client.GetConfigPath(), err)
+		os.Exit(1)
+	}
+	if securityLog, err := client.NewSecurityLog(client.GetSecurityLogPath()); err != nil {
+		fmt.Printf("Warning: security log disabled: %v\n", err)
+	} else {
+		session.SetSecurityLog(securityLog)
+	}
+	if config.Notifications {
+		session.SetNotifier(client.DesktopNotifier{})
+	}
+	if config.BreachCheck {
+		session.SetPwnedChecker(client.NewPwnedChecker())
+	}
+	if config.FetchIcons {
+		session.SetIconFetcher(client.NewIconFetcher())
+	}
+	session.SetClipboard(client.SystemClipboard{})
+	session.SetUsagePath(client.GetUsagePath())
+	session.SetIndexPath(client.GetIndexPath())
+	session.SetLocalStorePath(client.GetLocalStorePath())
 	handler := NewCommandHandler(session, config)
 
+	if *useTUI {
+		os.Exit(handler.runTUI())
+	}
+
+	if flag.NArg() > 0 {
+		os.Exit(handler.runOnce(flag.Args()))
+	}
+
+	if config.Token == "" {
+		fmt.Println("Welcome to GophKeeper! Type 'setup' for a guided first-time setup or 'help' for all commands.")
+	} else {
+		fmt.Printf("Logged in as %s. Type 'unlock' to open your vault.\n", config.Username)
+	}
+
 	runCLI(handler)
 }
 
+// runDemo runs the CLI or the TUI against an in-memory demo vault
+func runDemo(args []string, useTUI bool) {
+	session, config, err := client.NewDemoSession(context.Background())
+	if err != nil {
+		fmt.Printf("Failed to start demo mode: %v\n", err)
+		os.Exit(1)
+	}
+	session.SetClipboard(client.SystemClipboard{})
+
+	if useTUI {
+		os.Exit(NewCommandHandler(session, config).runTUI())
+	}
+
+	if len(args) > 0 {
+		os.Exit(NewCommandHandler(session, config).runOnce(args))
+	}
+
+	fmt.Println("Demo mode: using an in-memory vault with sample items. Nothing is sent to a server or saved.")
+	fmt.Printf("Demo credentials: login %s %s (master password: %s)\n",
+		client.DemoUsername, client.DemoMasterPassword, client.DemoMasterPassword)
+	fmt.Println("Try 'list' to get started.")
+
+	runCLI(NewCommandHandler(session, config))
+}
+
+// runTUI unlocks the vault if needed and shows the terminal UI until the user
+// quits. The UI closes, and the vault is locked, when the system sleeps or the
+// screen is locked.
+func (h *CommandHandler) runTUI() int {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if !h.session.IsAuthenticated() {
+		if err := h.session.UnlockCommand(ctx, h.config); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return 1
+		}
+	}
+
+	// the session is locked here rather than in the watcher, which runs
+	// concurrently with the UI
+	var lockReason string
+	var lockMutex sync.Mutex
+	onLock := func(reason string) {
+		lockMutex.Lock()
+		lockReason = reason
+		lockMutex.Unlock()
+		cancel()
+	}
+	client.WatchSystemLock(ctx, onLock)
+	h.idle.Touch()
+	h.idle.Watch(ctx, onLock)
+
+	err := tui.Run(ctx, h.session, os.Stdin, os.Stdout, h.idle.Touch)
+	lockMutex.Lock()
+	reason := lockReason
+	lockMutex.Unlock()
+	if reason != "" {
+		h.session.AutoLock(reason)
+		fmt.Printf("Vault locked (%s).\n", reason)
+	} else {
+		h.session.Lock()
+	}
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runOnce executes a single command given on the command line and returns the process exit code
+func (h *CommandHandler) runOnce(args []string) int {
+	ctx := context.Background()
+
+	command, err := h.commands.Lookup(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v: %s\n", err, args[0])
+		return client.AssertExitError
+	}
+	if cli.WantsHelp(args[1:]) {
+		fmt.Print(command.Help())
+		return client.AssertExitPassed
+	}
+	args = append([]string{command.Name}, args[1:]...)
+
+	switch args[0] {
+	case "assert":
+		if err := h.unlockFromEnv(); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return client.AssertExitError
+		}
+		return h.session.AssertCommand(ctx, args[1:])
+	case "scan":
+		if err := h.unlockFromEnv(); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return client.AssertExitError
+		}
+		return h.session.ScanCommand(ctx, args[1:])
+	case "sync-k8s":
+		if err := h.unlockFromEnv(); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return client.AssertExitError
+		}
+		if err := h.runK8sSync(ctx, args[1:]); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return client.AssertExitError
+		}
+		return client.AssertExitPassed
+	case "import":
+		if err := h.unlockFromEnv(); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return client.AssertExitError
+		}
+		if len(args) < 2 || len(args) > 3 {
+			fmt.Println(importUsage)
+			return client.AssertExitError
+		}
+		if err := importFile(ctx, h.session, args[1:]); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			if errors.Is(err, client.ErrImportFailures) {
+				return client.AssertExitFailed
+			}
+			return client.AssertExitError
+		}
+		return client.AssertExitPassed
+	case "get", "list", "find-login", "expiring", "create", "update":
+		if err := h.unlockFromEnv(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return client.AssertExitError
+		}
+		if err := h.runScripted(ctx, args[0], args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return client.AssertExitError
+		}
+		return client.AssertExitPassed
+	case "fetch-field":
+		if err := h.fetchField(ctx, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return client.AssertExitError
+		}
+		return client.AssertExitPassed
+	case "recovery-keygen":
+		if err := recoveryKeygen(args[1:]); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return client.AssertExitError
+		}
+		return client.AssertExitPassed
+	case "escrow-recover":
+		if err := h.escrowRecover(ctx, args[1:]); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return client.AssertExitError
+		}
+		return client.AssertExitPassed
+	default:
+		fmt.Printf("Command %s is only available in interactive mode\n", args[0])
+		return client.AssertExitError
+	}
+}
+
+// runK8sSync syncs vault items with one tag to Kubernetes Secrets
+func (h *CommandHandler) runK8sSync(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("sync-k8s", flag.ContinueOnError)
+	namespace := flags.String("namespace", "", "Target namespace (defaults to the pod namespace in-cluster)")
+	tag := flags.String("tag", "", "Tag of the vault items to sync")
+	apiServer := flags.String("api-server", "", "Kubernetes API server URL (defaults to in-cluster config)")
+	tokenFile := flags.String("token-file", "", "File with the Kubernetes API token (required with -api-server)")
+	interval := flags.Duration("interval", client.DefaultK8sSyncInterval, "Interval of syncs without a change event")
+	once := flags.Bool("once", false, "Sync once and exit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*tag) == "" {
+		return fmt.Errorf("-tag is required")
+	}
+
+	var k8s *client.K8sClient
+	if *apiServer != "" {
+		if *tokenFile == "" || *namespace == "" {
+			return fmt.Errorf("-token-file and -namespace are required with -api-server")
+		}
+		token, err := os.ReadFile(*tokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read token file: %w", err)
+		}
+		k8s = client.NewK8sClient(*apiServer, strings.TrimSpace(string(token)), *namespace, nil)
+	} else {
+		inCluster, err := client.NewInClusterK8sClient(*namespace)
+		if err != nil {
+			return err
+		}
+		k8s = inCluster
+	}
+
+	syncer := client.NewK8sSyncer(h.session, k8s, *tag)
+	if *once {
+		applied, err := syncer.SyncOnce(ctx)
+		h.session.NotifyResult("Kubernetes sync", fmt.Sprintf("Synced %d secret(s)", applied), err)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Synced %d secret(s)\n", applied)
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return syncer.Run(ctx, *interval)
+}
+
+// fetchField prints one published field value using a scoped token and data key from the environment
+func (h *CommandHandler) fetchField(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("fetch-field", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "Print {\"value\": ...} for tools such as the Terraform external data source")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("usage: fetch-field [-json] <id> <field>")
+	}
+
+	token, dataKey := os.Getenv(fieldTokenEnv), os.Getenv(dataKeyEnv)
+	if token == "" || dataKey == "" {
+		return fmt.Errorf("%s and %s must be set", fieldTokenEnv, dataKeyEnv)
+	}
+
+	cli, err := newClient(h.config)
+	if err != nil {
+		return err
+	}
+	cli.SetToken(token)
+	value, err := client.FetchField(ctx, cli, flags.Arg(0), flags.Arg(1), dataKey)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(map[string]string{"value": value})
+	}
+	fmt.Println(value)
+	return nil
+}
+
+// recoveryKeygen generates an organization recovery key pair, saving the private key to a file
+func recoveryKeygen(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: recovery-keygen <private-key-file>")
+	}
+
+	publicKey, privateKey, err := crypto.GenerateRecoveryKeyPair()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create private key file: %w", err)
+	}
+	if _, err := fmt.Fprintln(file, base64.StdEncoding.EncodeToString(privateKey)); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+
+	fmt.Printf("Private key saved to %s - keep it offline; it can decrypt every escrowed vault.\n", args[0])
+	fmt.Printf("Recovery key ID: %s\n", crypto.RecoveryKeyID(publicKey))
+	fmt.Printf("Configure the server with:\n  ESCROW_RECOVERY_PUBLIC_KEY=%s\n", base64.StdEncoding.EncodeToString(publicKey))
+	return nil
+}
+
+// escrowRecover decrypts a consenting user's escrowed vault into a JSON file
+func (h *CommandHandler) escrowRecover(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("escrow-recover", flag.ContinueOnError)
+	privateKeyFile := flags.String("private-key-file", "", "File with the organization recovery private key")
+	out := flags.String("out", "", "File to write the recovered items to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *privateKeyFile == "" || *out == "" {
+		return fmt.Errorf("usage: escrow-recover -private-key-file <file> -out <file> <username>")
+	}
+
+	adminToken := os.Getenv(adminTokenEnv)
+	if adminToken == "" {
+		return fmt.Errorf("%s must be set", adminTokenEnv)
+	}
+
+	encodedKey, err := os.ReadFile(*privateKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+	privateKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedKey)))
+	if err != nil {
+		return fmt.Errorf("invalid private key file: %w", err)
+	}
+
+	cli, err := newClient(h.config)
+	if err != nil {
+		return err
+	}
+	recovery, err := cli.GetEscrowRecovery(ctx, adminToken, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	items, err := client.RecoverEscrow(recovery, privateKey)
+	h.session.NotifyResult("Escrow recovery", fmt.Sprintf("Recovered %d item(s) of %s", len(items), flags.Arg(0)), err)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal recovered items: %w", err)
+	}
+	if err := os.WriteFile(*out, payload, 0600); err != nil {
+		return fmt.Errorf("failed to write recovered items: %w", err)
+	}
+
+	fmt.Printf("Recovered %d item(s) of %s to %s\n", len(items), recovery.Username, *out)
+	return nil
+}
+
+// unlockFromEnv unlocks the session with the master password from the environment
+func (h *CommandHandler) unlockFromEnv() error {
+	if h.session.IsAuthenticated() {
+		return nil
+	}
+
+	masterPassword := os.Getenv(masterPasswordEnv)
+	if masterPassword == "" {
+		return fmt.Errorf("%s must be set for non-interactive use", masterPasswordEnv)
+	}
+	if h.config.Token == "" || h.config.Salt == "" {
+		return fmt.Errorf("no saved session - login interactively first")
+	}
+	return h.session.UnlockWithIterations(masterPassword, h.config.Salt, h.config.SaltIterations)
+}
+
+// commandInterrupter cancels the running interactive command on Ctrl+C, so a
+// long import or download returns to the prompt instead of ending the session
+type commandInterrupter struct {
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+}
+
+// start returns the context of a new command and the function ending it
+func (c *commandInterrupter) start(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	c.mutex.Lock()
+	c.cancel = cancel
+	c.mutex.Unlock()
+
+	return ctx, func() {
+		c.mutex.Lock()
+		c.cancel = nil
+		c.mutex.Unlock()
+		cancel()
+	}
+}
+
+// interrupt cancels the running command and reports whether there was one
+func (c *commandInterrupter) interrupt() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.cancel == nil {
+		return false
+	}
+	c.cancel()
+	c.cancel = nil
+	return true
+}
+
+// watch handles Ctrl+C until ctx is done
+func (c *commandInterrupter) watch(ctx context.Context) {
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		defer signal.Stop(interrupts)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-interrupts:
+				if c.interrupt() {
+					fmt.Println("\nCancelling...")
+				} else {
+					fmt.Print("\nType 'exit' to quit\ngophkeeper> ")
+				}
+			}
+		}
+	}()
+}
+
 // runCLI runs the main CLI loop
 func runCLI(handler *CommandHandler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.WatchSystemLock(ctx, handler.autoLock)
+	handler.idle.Watch(ctx, handler.idleLock)
+	handler.session.WatchChanges(ctx, &handler.mutex, handler.refreshAfterChange)
+
+	var interrupter commandInterrupter
+	interrupter.watch(ctx)
+
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
 		fmt.Print("gophkeeper> ")
@@ -63,6 +607,7 @@ func runCLI(handler *CommandHandler) {
 			break
 		}
 
+		handler.idle.Touch()
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
 			continue
@@ -76,43 +621,82 @@ func runCLI(handler *CommandHandler) {
 		command := parts[0]
 		args := parts[1:]
 
-		if handler.handleCommand(command, args) {
+		commandCtx, done := interrupter.start(ctx)
+		handler.mutex.Lock()
+		exit := handler.handleCommand(commandCtx, command, args)
+		handler.mutex.Unlock()
+		if errors.Is(commandCtx.Err(), context.Canceled) && ctx.Err() == nil {
+			fmt.Printf("%s cancelled\n", command)
+		}
+		done()
+		if exit {
 			break
 		}
 	}
+
+	handler.mutex.Lock()
+	handler.session.Lock()
+	handler.mutex.Unlock()
 }
 
-// handleCommand processes a single command and returns true if exit was requested
-func (h *CommandHandler) handleCommand(command string, args []string) bool {
-	ctx := context.Background()
+// autoLock drops the in-memory key when the system sleeps or the screen is locked
+func (h *CommandHandler) autoLock(reason string) {
+	h.mutex.Lock()
+	locked := h.session.AutoLock(reason)
+	h.mutex.Unlock()
 
-	switch command {
-	case "register":
-		return h.handleRegister(ctx, args)
-	case "login":
-		return h.handleLogin(ctx, args)
-	case "list":
-		return h.handleList(ctx)
-	case "get":
-		return h.handleGet(ctx, args)
-	case "create":
-		return h.handleCreate(ctx, args)
-	case "update":
-		return h.handleUpdate(ctx, args)
-	case "delete":
-		return h.handleDelete(ctx, args)
-	case "save":
-		return h.handleSave(ctx, args)
-	case "help":
-		h.showHelp()
-		return false
-	case "exit", "quit":
-		fmt.Println("Goodbye!")
-		return true
-	default:
-		fmt.Printf("Unknown command: %s. Type 'help' for available commands.\n", command)
+	if locked {
+		fmt.Printf("\nVault locked (%s). Use 'unlock' to open it again.\ngophkeeper> ", reason)
+	}
+}
+
+// idleLock drops the in-memory key after a period without input. A running
+// command counts as activity, so the vault is never locked under one.
+func (h *CommandHandler) idleLock(reason string) {
+	if !h.mutex.TryLock() {
+		h.idle.Touch()
+		return
+	}
+	locked := h.session.AutoLock(reason)
+	h.mutex.Unlock()
+
+	if locked {
+		fmt.Printf("\nVault locked after %d minutes without input. Use 'unlock' to open it again.\ngophkeeper> ",
+			int(h.config.IdleTimeout().Minutes()))
+	}
+}
+
+// refreshAfterChange refreshes the local cache after the vault changed, e.g. from another device
+func (h *CommandHandler) refreshAfterChange(ctx context.Context) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.session.RefreshCache(ctx)
+}
+
+// handleCommand processes a single command and returns true if exit was requested.
+// Commands are found by name, alias or unambiguous prefix. Commands stop early,
+// cleaning up partial work, when ctx is cancelled.
+func (h *CommandHandler) handleCommand(ctx context.Context, name string, args []string) bool {
+	command, ok := h.lookupCommand(name)
+	switch {
+	case !ok:
+		return false
+	case cli.WantsHelp(args):
+		fmt.Print(command.Help())
+		return false
+	case command.Run == nil:
+		fmt.Printf("Command %s only runs as a command-line argument, e.g. gophkeeper-client %s\n", command.Name, command.Name)
 		return false
 	}
+	return command.Run(ctx, args)
+}
+
+// handleSetup processes the setup command
+func (h *CommandHandler) handleSetup(ctx context.Context) bool {
+	if err := h.session.SetupCommand(ctx, h.config); err != nil {
+		fmt.Printf("Setup failed: %v\n", err)
+	}
+	return false
 }
 
 // handleRegister processes the register command
@@ -137,17 +721,79 @@ func (h *CommandHandler) handleLogin(ctx context.Context, args []string) bool {
 	}
 	if err := h.session.LoginCommand(ctx, args[0], args[1], h.config); err != nil {
 		fmt.Printf("Login failed: %v\n", err)
+		return false
+	}
+	h.session.RefreshIndexInBackground(ctx)
+	return false
+}
+
+// handleSSO processes the sso command
+func (h *CommandHandler) handleSSO(ctx context.Context, args []string) bool {
+	if len(args) > 0 && args[0] == "link" {
+		if err := h.session.SSOLinkCommand(ctx); err != nil {
+			if err == client.ErrNotAuthenticated {
+				fmt.Println("Please login first to link your identity")
+			} else {
+				fmt.Printf("Linking failed: %v\n", err)
+			}
+		}
+		return false
+	}
+	if len(args) > 0 {
+		fmt.Println("Usage: sso [link]")
+		return false
+	}
+	if err := h.session.SSOLoginCommand(ctx, h.config); err != nil {
+		fmt.Printf("Login failed: %v\n", err)
+		return false
+	}
+	h.session.RefreshIndexInBackground(ctx)
+	return false
+}
+
+// handleUnlock processes the unlock command
+func (h *CommandHandler) handleUnlock(ctx context.Context) bool {
+	if err := h.session.UnlockCommand(ctx, h.config); err != nil {
+		fmt.Printf("Unlock failed: %v\n", err)
 	}
 	return false
 }
 
 // handleList processes the list command
-func (h *CommandHandler) handleList(ctx context.Context) bool {
-	if err := h.session.ListCommand(ctx); err != nil {
+func (h *CommandHandler) handleList(ctx context.Context, args []string) bool {
+	if err := h.list(ctx, args); err != nil {
+		printCommandError(err, "Please login first to access encrypted data", "Failed to list data")
+	}
+	return false
+}
+
+// handleFindLogin processes the find-login command
+func (h *CommandHandler) handleFindLogin(ctx context.Context, args []string) bool {
+	if err := h.findLogin(ctx, args); err != nil {
+		printCommandError(err, "Please login first to access encrypted data", "Failed to find logins")
+	}
+	return false
+}
+
+// handleExpiring processes the expiring command
+func (h *CommandHandler) handleExpiring(ctx context.Context, args []string) bool {
+	if err := h.expiring(ctx, args); err != nil {
+		printCommandError(err, "Please login first to access encrypted data", "Failed to list expiring items")
+	}
+	return false
+}
+
+// handleSearch processes the search command
+func (h *CommandHandler) handleSearch(ctx context.Context, args []string) bool {
+	if len(args) < 1 {
+		fmt.Println("Usage: search <query>")
+		return false
+	}
+	if err := h.session.SearchCommand(ctx, strings.Join(args, " ")); err != nil {
 		if err == client.ErrNotAuthenticated {
 			fmt.Println("Please login first to access encrypted data")
 		} else {
-			fmt.Printf("Failed to list data: %v\n", err)
+			fmt.Printf("Failed to search data: %v\n", err)
 		}
 	}
 	return false
@@ -155,15 +801,111 @@ func (h *CommandHandler) handleList(ctx context.Context) bool {
 
 // handleGet processes the get command
 func (h *CommandHandler) handleGet(ctx context.Context, args []string) bool {
+	if err := h.get(ctx, args); err != nil {
+		printCommandError(err, "Please login first to access encrypted data", "Failed to get data")
+	}
+	return false
+}
+
+// handlePeek processes the peek command
+func (h *CommandHandler) handlePeek(ctx context.Context, args []string) bool {
 	if len(args) < 1 {
-		fmt.Println("Usage: get <id>")
+		fmt.Println("Usage: peek <id> [seconds]")
+		return false
+	}
+	duration := client.DefaultPeekDuration
+	if len(args) > 1 {
+		seconds, err := strconv.Atoi(args[1])
+		if err != nil || seconds <= 0 {
+			fmt.Println("Seconds must be a positive number")
+			return false
+		}
+		duration = time.Duration(seconds) * time.Second
+	}
+	if err := h.session.PeekCommand(ctx, args[0], duration); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Printf("Failed to peek data: %v\n", err)
+		}
+	}
+	return false
+}
+
+// handleCopy processes the copy command
+func (h *CommandHandler) handleCopy(ctx context.Context, args []string) bool {
+	if len(args) < 1 || len(args) > 3 {
+		fmt.Println("Usage: copy <id> [field] [seconds]")
+		return false
+	}
+	field := ""
+	duration := client.DefaultClipboardDuration
+	rest := args[1:]
+	if n := len(rest); n > 0 {
+		if seconds, err := strconv.Atoi(rest[n-1]); err == nil {
+			if seconds <= 0 {
+				fmt.Println("Seconds must be a positive number")
+				return false
+			}
+			duration = time.Duration(seconds) * time.Second
+			rest = rest[:n-1]
+		}
+	}
+	if len(rest) > 1 {
+		fmt.Println("Usage: copy <id> [field] [seconds]")
+		return false
+	}
+	if len(rest) == 1 {
+		field = rest[0]
+	}
+	if err := h.session.CopyCommand(ctx, args[0], field, duration); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Printf("Failed to copy data: %v\n", err)
+		}
+	}
+	return false
+}
+
+// handleTOTP processes the totp command
+func (h *CommandHandler) handleTOTP(ctx context.Context, args []string) bool {
+	if len(args) != 1 {
+		fmt.Println("Usage: totp <id>")
 		return false
 	}
-	if err := h.session.GetCommand(ctx, args[0]); err != nil {
+	if err := h.session.TOTPCommand(ctx, args[0]); err != nil {
 		if err == client.ErrNotAuthenticated {
 			fmt.Println("Please login first to access encrypted data")
 		} else {
-			fmt.Printf("Failed to get data: %v\n", err)
+			fmt.Printf("Failed to generate code: %v\n", err)
+		}
+	}
+	return false
+}
+
+// handleHistory processes the history command
+func (h *CommandHandler) handleHistory(ctx context.Context, args []string) bool {
+	var err error
+	switch {
+	case len(args) == 1:
+		err = h.session.HistoryCommand(ctx, args[0])
+	case len(args) == 3 && args[1] == "restore":
+		version, convErr := strconv.Atoi(args[2])
+		if convErr != nil || version < 1 {
+			fmt.Println("Version must be a positive number")
+			return false
+		}
+		err = h.session.RestoreVersionCommand(ctx, args[0], version)
+	default:
+		fmt.Println("Usage: history <id> [restore <version>]")
+		return false
+	}
+	if err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Println(err)
 		}
 	}
 	return false
@@ -171,49 +913,125 @@ func (h *CommandHandler) handleGet(ctx context.Context, args []string) bool {
 
 // handleCreate processes the create command
 func (h *CommandHandler) handleCreate(ctx context.Context, args []string) bool {
+	if err := h.create(ctx, args, nil); err != nil {
+		printCommandError(err, "Please login first to create encrypted data", "Failed to create data")
+	}
+	return false
+}
+
+// handlePublishField processes the publish-field command
+func (h *CommandHandler) handlePublishField(ctx context.Context, args []string) bool {
 	if len(args) < 2 {
-		fmt.Println("Usage: create <type> <name> [description]")
-		fmt.Println("Types: login_password, text, binary, bank_card")
-		fmt.Println("Note: Use quotes around names with spaces: create text \"My Shopping List\" \"Description\"")
+		fmt.Println("Usage: publish-field <id> <field> [days]")
 		return false
 	}
-	description := ""
+	ttl := 30 * 24 * time.Hour
 	if len(args) > 2 {
-		description = client.CleanQuotes(strings.Join(args[2:], " "))
+		days, err := strconv.Atoi(args[2])
+		if err != nil || days <= 0 {
+			fmt.Println("Days must be a positive number")
+			return false
+		}
+		ttl = time.Duration(days) * 24 * time.Hour
 	}
-	if err := h.session.CreateCommand(ctx, args[0], client.CleanQuotes(args[1]), description); err != nil {
+	if err := h.session.PublishFieldCommand(ctx, args[0], args[1], ttl); err != nil {
 		if err == client.ErrNotAuthenticated {
-			fmt.Println("Please login first to create encrypted data")
+			fmt.Println("Please login first to access encrypted data")
 		} else {
-			fmt.Printf("Failed to create data: %v\n", err)
+			fmt.Printf("Failed to publish field: %v\n", err)
 		}
 	}
 	return false
 }
 
-// handleUpdate processes the update command
-func (h *CommandHandler) handleUpdate(ctx context.Context, args []string) bool {
-	if len(args) < 1 {
-		fmt.Println("Usage: update <id>")
-		return false
+// handleEscrow processes the escrow command
+func (h *CommandHandler) handleEscrow(ctx context.Context, args []string) bool {
+	if err := h.session.EscrowCommand(ctx, args); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to manage key escrow")
+		} else {
+			fmt.Printf("Escrow failed: %v\n", err)
+		}
 	}
-	if err := h.session.UpdateCommand(ctx, args[0]); err != nil {
+	return false
+}
+
+// handleConflicts processes the conflicts command
+func (h *CommandHandler) handleConflicts(ctx context.Context, args []string) bool {
+	if err := h.session.ConflictsCommand(ctx, args); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to review conflicts")
+		} else {
+			fmt.Printf("Conflict resolution failed: %v\n", err)
+		}
+	}
+	return false
+}
+
+// handleSync processes the sync command
+func (h *CommandHandler) handleSync(ctx context.Context) bool {
+	if err := h.session.SyncCommand(ctx); err != nil {
 		if err == client.ErrNotAuthenticated {
-			fmt.Println("Please login first to update encrypted data")
+			fmt.Println("Please login first to sync your vault")
 		} else {
-			fmt.Printf("Failed to update data: %v\n", err)
+			fmt.Printf("Sync failed: %v\n", err)
 		}
 	}
 	return false
 }
 
+// handleEnv processes the env command
+func (h *CommandHandler) handleEnv(args []string) bool {
+	if err := client.EnvCommand(h.config, args); err != nil {
+		fmt.Printf("Failed to update environment: %v\n", err)
+		fmt.Println("Usage: env [<environment> | clear]")
+	}
+	return false
+}
+
+// parseEnvFlag extracts an --env flag from args, falling back to the configured default environment
+func (h *CommandHandler) parseEnvFlag(args []string) (string, []string, error) {
+	environment := h.config.DefaultEnvironment
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--env" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			return "", nil, fmt.Errorf("--env requires an environment name")
+		}
+		normalized, err := client.NormalizeEnvironment(args[i+1])
+		if err != nil {
+			return "", nil, err
+		}
+		environment = normalized
+		i++
+	}
+	return environment, rest, nil
+}
+
+// handleUpdate processes the update command
+func (h *CommandHandler) handleUpdate(ctx context.Context, args []string) bool {
+	if err := h.update(ctx, args, nil); err != nil {
+		printCommandError(err, "Please login first to update encrypted data", "Failed to update data")
+	}
+	return false
+}
+
 // handleDelete processes the delete command
 func (h *CommandHandler) handleDelete(ctx context.Context, args []string) bool {
 	if len(args) < 1 {
-		fmt.Println("Usage: delete <id>")
+		fmt.Println("Usage: delete <id>...")
 		return false
 	}
-	if err := h.session.DeleteCommand(ctx, args[0]); err != nil {
+	var err error
+	if len(args) == 1 {
+		err = h.session.DeleteCommand(ctx, args[0])
+	} else {
+		err = h.session.DeleteManyCommand(ctx, args)
+	}
+	if err != nil {
 		if err == client.ErrNotAuthenticated {
 			fmt.Println("Please login first to delete encrypted data")
 		} else {
@@ -223,6 +1041,270 @@ func (h *CommandHandler) handleDelete(ctx context.Context, args []string) bool {
 	return false
 }
 
+// handleRename processes the rename command
+func (h *CommandHandler) handleRename(ctx context.Context, args []string) bool {
+	if len(args) < 2 {
+		fmt.Println("Usage: rename <id> <new name>")
+		return false
+	}
+	if err := h.session.RenameCommand(ctx, args[0], strings.Join(args[1:], " ")); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Println(err)
+		}
+	}
+	return false
+}
+
+// handleDescribe processes the describe command
+func (h *CommandHandler) handleDescribe(ctx context.Context, args []string) bool {
+	if len(args) < 1 {
+		fmt.Println("Usage: describe <id> [description]")
+		return false
+	}
+	if err := h.session.DescribeCommand(ctx, args[0], strings.Join(args[1:], " ")); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Println(err)
+		}
+	}
+	return false
+}
+
+// handleComment processes the comment command
+func (h *CommandHandler) handleComment(ctx context.Context, args []string) bool {
+	if len(args) < 2 {
+		fmt.Println("Usage: comment <id> <text>")
+		return false
+	}
+	if err := h.session.CommentCommand(ctx, args[0], strings.Join(args[1:], " ")); err != nil {
+		fmt.Printf("Failed to add comment: %v\n", err)
+	}
+	return false
+}
+
+// handleAttach processes the attach command
+func (h *CommandHandler) handleAttach(ctx context.Context, args []string) bool {
+	if len(args) != 2 {
+		fmt.Println("Usage: attach <id> <file>")
+		return false
+	}
+	if err := h.session.AttachCommand(ctx, args[0], args[1]); err != nil {
+		printCommandError(err, "Please login first to attach files", "Failed to attach file")
+	}
+	return false
+}
+
+// handleAttachments processes the attachments command
+func (h *CommandHandler) handleAttachments(ctx context.Context, args []string) bool {
+	if len(args) != 1 {
+		fmt.Println("Usage: attachments <id>")
+		return false
+	}
+	if err := h.session.AttachmentsCommand(ctx, args[0]); err != nil {
+		printCommandError(err, "Please login first to list attachments", "Failed to list attachments")
+	}
+	return false
+}
+
+// handleDownload processes the download command
+func (h *CommandHandler) handleDownload(ctx context.Context, args []string) bool {
+	if len(args) < 2 || len(args) > 3 {
+		fmt.Println("Usage: download <id> <attachment> [path]")
+		return false
+	}
+	outputPath := ""
+	if len(args) == 3 {
+		outputPath = args[2]
+	}
+	if err := h.session.DownloadCommand(ctx, args[0], args[1], outputPath); err != nil {
+		printCommandError(err, "Please login first to download attachments", "Failed to download attachment")
+	}
+	return false
+}
+
+// handleDetach processes the detach command
+func (h *CommandHandler) handleDetach(ctx context.Context, args []string) bool {
+	if len(args) != 2 {
+		fmt.Println("Usage: detach <id> <attachment>")
+		return false
+	}
+	if err := h.session.DetachCommand(ctx, args[0], args[1]); err != nil {
+		printCommandError(err, "Please login first to remove attachments", "Failed to remove attachment")
+	}
+	return false
+}
+
+// handleTag processes the tag command
+func (h *CommandHandler) handleTag(ctx context.Context, args []string) bool {
+	const usage = "Usage: tag add <id> <tag>... | tag remove <id> <tag>... | tag list [tag]"
+	if len(args) < 1 {
+		fmt.Println(usage)
+		return false
+	}
+
+	var err error
+	switch {
+	case args[0] == "add" && len(args) >= 3:
+		err = h.session.TagAddCommand(ctx, args[1], args[2:])
+	case args[0] == "remove" && len(args) >= 3:
+		err = h.session.TagRemoveCommand(ctx, args[1], args[2:])
+	case args[0] == "list" && len(args) <= 2:
+		tag := ""
+		if len(args) == 2 {
+			tag = args[1]
+		}
+		err = h.session.TagListCommand(ctx, tag)
+	default:
+		fmt.Println(usage)
+		return false
+	}
+	if err == client.ErrNotAuthenticated {
+		fmt.Println("Please login first to access encrypted data")
+	} else if err != nil {
+		fmt.Println(err)
+	}
+	return false
+}
+
+// handleLs processes the ls command
+func (h *CommandHandler) handleLs(ctx context.Context, args []string) bool {
+	path := "/"
+	if len(args) > 0 {
+		path = strings.Join(args, " ")
+	}
+	if err := h.session.TreeCommand(ctx, path); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Printf("Failed to list collection: %v\n", err)
+		}
+	}
+	return false
+}
+
+// handleMkdir processes the mkdir command
+func (h *CommandHandler) handleMkdir(ctx context.Context, args []string) bool {
+	if len(args) < 1 {
+		fmt.Println("Usage: mkdir <path>")
+		return false
+	}
+	if err := h.session.MkdirCommand(ctx, strings.Join(args, " ")); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Printf("Failed to create collection: %v\n", err)
+		}
+	}
+	return false
+}
+
+// handleRmdir processes the rmdir command
+func (h *CommandHandler) handleRmdir(ctx context.Context, args []string) bool {
+	if len(args) < 1 {
+		fmt.Println("Usage: rmdir <path>")
+		return false
+	}
+	if err := h.session.RmdirCommand(ctx, strings.Join(args, " ")); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Printf("Failed to delete collection: %v\n", err)
+		}
+	}
+	return false
+}
+
+// handleMv processes the mv command
+func (h *CommandHandler) handleMv(ctx context.Context, args []string) bool {
+	if len(args) < 2 {
+		fmt.Println("Usage: mv <id|/collection> <path|/>")
+		return false
+	}
+	if err := h.session.MoveCommand(ctx, args[0], strings.Join(args[1:], " ")); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Printf("Failed to move: %v\n", err)
+		}
+	}
+	return false
+}
+
+// handleRotateMaster processes the rotate-master command
+func (h *CommandHandler) handleRotateMaster(ctx context.Context) bool {
+	if err := h.session.RotateMasterCommand(ctx, h.config); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to change your master password")
+		} else {
+			fmt.Printf("Master password not changed: %v\n", err)
+		}
+	}
+	return false
+}
+
+// handleExport processes the export command
+func (h *CommandHandler) handleExport(ctx context.Context, args []string) bool {
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if err := h.session.ExportCommand(ctx, path); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Printf("Failed to export data: %v\n", err)
+		}
+	}
+	return false
+}
+
+// importUsage is the usage of the import command
+var importUsage = "Usage: import [format] <file>, format: " + strings.Join(client.ImportFormats, ", ") + " (default ndjson)"
+
+// importFile imports the file of the import arguments, [format] <file>
+func importFile(ctx context.Context, session *client.ClientSession, args []string) error {
+	if len(args) == 2 {
+		return session.ImportFromCommand(ctx, args[0], args[1])
+	}
+	return session.ImportCommand(ctx, args[0])
+}
+
+// handleImport processes the import command
+func (h *CommandHandler) handleImport(ctx context.Context, args []string) bool {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println(importUsage)
+		return false
+	}
+	if err := importFile(ctx, h.session, args); err != nil {
+		fmt.Printf("Failed to import data: %v\n", err)
+	}
+	return false
+}
+
+// handleAudit processes the audit command
+func (h *CommandHandler) handleAudit(ctx context.Context, args []string) bool {
+	limit := 0
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			fmt.Println("Usage: audit [count]")
+			return false
+		}
+		limit = n
+	}
+	if err := h.session.AuditCommand(ctx, limit); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to read the audit log")
+		} else {
+			fmt.Printf("Failed to get audit log: %v\n", err)
+		}
+	}
+	return false
+}
+
 // handleSave processes the save command
 func (h *CommandHandler) handleSave(ctx context.Context, args []string) bool {
 	if len(args) < 1 {
@@ -244,13 +1326,38 @@ func (h *CommandHandler) handleSave(ctx context.Context, args []string) bool {
 	return false
 }
 
-// showHelp displays help information from file
-func (h *CommandHandler) showHelp() {
-	content, err := os.ReadFile("assets/client/help.txt")
-	if err != nil {
-		fmt.Println("Error reading help file:", err)
-		return
+// handleSnapshot processes the snapshot command
+func (h *CommandHandler) handleSnapshot(ctx context.Context, args []string) bool {
+	details := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "--details" {
+			details = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	if len(rest) < 3 || rest[0] != "diff" {
+		fmt.Println("Usage: snapshot diff <from> <to> [--details]")
+		fmt.Println("Times: YYYY-MM-DD, \"YYYY-MM-DD HH:MM:SS\" or RFC3339")
+		return false
+	}
+	if err := h.session.SnapshotDiffCommand(ctx, client.CleanQuotes(rest[1]), client.CleanQuotes(rest[2]), details); err != nil {
+		if err == client.ErrNotAuthenticated {
+			fmt.Println("Please login first to access encrypted data")
+		} else {
+			fmt.Printf("Failed to diff snapshots: %v\n", err)
+		}
 	}
+	return false
+}
 
-	fmt.Print(string(content))
+// handleSecurityLog processes the security-log command
+func (h *CommandHandler) handleSecurityLog(args []string) bool {
+	verify := len(args) > 0 && args[0] == "verify"
+	if err := h.session.SecurityLogCommand(verify); err != nil {
+		fmt.Printf("Security log: %v\n", err)
+	}
+	return false
 }
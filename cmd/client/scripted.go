@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/client"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// Usage of the commands that can run without prompts
+const (
+	getUsage    = "Usage: get <id> [--reveal [--force]] [--output json]"
+	listUsage   = "Usage: list [--env <environment> | --all] [--flat] [--output json]"
+	findUsage   = "Usage: find-login <url> [--match domain|host|exact] [--output json]"
+	expiryUsage = "Usage: expiring [<period>] [--output json], e.g. expiring 90d"
+	createUsage = "Usage: create <type> <name> [description] [--env <environment>]\n" +
+		"   or: create <type> [name] [--name <name>] [--description <text>] [--env <environment>] [--tag <tag>]...\n" +
+		"                 [--field <name>=<value>]... [--file <path>] [--json <path>|- | --from-file <path>] [--output json]"
+	updateUsage = "Usage: update [--raw] <id>\n" +
+		"   or: update <id> --field <name>...\n" +
+		"   or: update <id> [--name <name>] [--description <text>] [--env <environment>] [--tag <tag>]...\n" +
+		"                   [--field <name>=<value>]... [--json <path>|- | --from-file <path>] [--output json]"
+)
+
+// usageError reports invalid arguments; its text is the usage of the command
+type usageError string
+
+// Error implements error
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// printCommandError prints the error of a command: the usage for invalid
+// arguments, loginHint when the vault is locked and failure otherwise
+func printCommandError(err error, loginHint, failure string) {
+	var usage usageError
+	switch {
+	case errors.As(err, &usage):
+		fmt.Println(usage)
+	case err == client.ErrNotAuthenticated:
+		fmt.Println(loginHint)
+	default:
+		fmt.Printf("%s: %v\n", failure, err)
+	}
+}
+
+// itemFlags are the flags that give an item on the command line instead of
+// answering prompts; --env alone still prompts for the fields
+var itemFlags = map[string]bool{
+	"--name": true, "--description": true, "--tag": true, "--field": true,
+	"--file": true, "--json": true, "--from-file": true,
+}
+
+// hasItemFlags reports whether args give the item without prompts
+func hasItemFlags(args []string) bool {
+	for _, arg := range args {
+		if itemFlags[arg] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFieldNames extracts the --field flags naming a field without a value,
+// which are edited by prompting for the new value
+func parseFieldNames(args []string) ([]string, []string) {
+	var names []string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--field" && i+1 < len(args) && !strings.Contains(args[i+1], "=") {
+			names = append(names, args[i+1])
+			i++
+			continue
+		}
+		rest = append(rest, args[i])
+	}
+	return names, rest
+}
+
+// parseOutputFlag extracts an --output flag from args
+func parseOutputFlag(args []string) (string, []string, error) {
+	output := client.OutputText
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--output" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if i+1 >= len(args) || (args[i+1] != client.OutputText && args[i+1] != client.OutputJSON) {
+			return "", nil, fmt.Errorf("--output must be %s or %s", client.OutputText, client.OutputJSON)
+		}
+		output = args[i+1]
+		i++
+	}
+	return output, rest, nil
+}
+
+// parseItemFlags extracts the item flags from args. JSON given with --json or
+// --from-file is read first and the other flags override it. stdin is read
+// for "--json -"; it is nil when standard input is the interactive prompt.
+func parseItemFlags(args []string, stdin io.Reader) (client.ItemInput, []string, error) {
+	var flags, fromJSON client.ItemInput
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		if !itemFlags[flag] && flag != "--env" {
+			rest = append(rest, flag)
+			continue
+		}
+		if i+1 >= len(args) {
+			return client.ItemInput{}, nil, fmt.Errorf("%s requires a value", flag)
+		}
+		value := args[i+1]
+		i++
+
+		switch flag {
+		case "--name":
+			flags.Name = value
+		case "--description":
+			flags.Description = value
+		case "--env":
+			flags.Environment = value
+		case "--tag":
+			flags.Tags = append(flags.Tags, value)
+		case "--file":
+			flags.File = value
+		case "--field":
+			name, fieldValue, ok := strings.Cut(value, "=")
+			if !ok || name == "" {
+				return client.ItemInput{}, nil, fmt.Errorf("--field must be <name>=<value>, got %q", value)
+			}
+			if flags.Fields == nil {
+				flags.Fields = make(map[string]string)
+			}
+			flags.Fields[name] = fieldValue
+		case "--json", "--from-file":
+			input, err := readItemJSON(value, stdin)
+			if err != nil {
+				return client.ItemInput{}, nil, err
+			}
+			fromJSON = input
+		}
+	}
+	return fromJSON.Merge(flags), rest, nil
+}
+
+// readItemJSON reads an item as JSON from path, or from stdin for "-"
+func readItemJSON(path string, stdin io.Reader) (client.ItemInput, error) {
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return client.ItemInput{}, fmt.Errorf("failed to open item file: %w", err)
+		}
+		defer file.Close()
+		return client.ReadItemInput(file)
+	}
+	if stdin == nil {
+		return client.ItemInput{}, fmt.Errorf("reading the item from standard input only works when run as a command-line argument")
+	}
+	return client.ReadItemInput(stdin)
+}
+
+// printItem reports a created or updated item as text or JSON
+func printItem(output string, data *models.Data, message string) error {
+	if output == client.OutputJSON {
+		return client.WriteJSON(os.Stdout, client.NewItemOutput(data))
+	}
+	fmt.Printf("%s %s\n", message, data.ID)
+	return nil
+}
+
+// runScripted runs get, list, find-login, expiring, create or update as a
+// command-line argument, reading the item from flags or JSON instead of prompts
+func (h *CommandHandler) runScripted(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "get":
+		return h.get(ctx, args)
+	case "list":
+		return h.list(ctx, args)
+	case "find-login":
+		return h.findLogin(ctx, args)
+	case "expiring":
+		return h.expiring(ctx, args)
+	case "create":
+		if !hasItemFlags(args) {
+			return fmt.Errorf("create needs --field, --json or --from-file when run as a command-line argument")
+		}
+		return h.create(ctx, args, os.Stdin)
+	case "update":
+		if !hasItemFlags(args) {
+			return fmt.Errorf("update needs --field, --json or --from-file when run as a command-line argument")
+		}
+		return h.update(ctx, args, os.Stdin)
+	default:
+		return fmt.Errorf("unknown command %s", command)
+	}
+}
+
+// get shows an item as text or JSON. Text output masks secrets unless --reveal
+// is given, and --force is needed as well when it is not going to a terminal.
+func (h *CommandHandler) get(ctx context.Context, args []string) error {
+	output, args, err := parseOutputFlag(args)
+	if err != nil {
+		return err
+	}
+	var id string
+	reveal, force := false, false
+	for _, arg := range args {
+		switch {
+		case arg == "--reveal":
+			reveal = true
+		case arg == "--force":
+			force = true
+		case id == "" && !strings.HasPrefix(arg, "--"):
+			id = arg
+		default:
+			return usageError(getUsage)
+		}
+	}
+	if id == "" {
+		return usageError(getUsage)
+	}
+	if output == client.OutputJSON {
+		return h.session.GetJSONCommand(ctx, os.Stdout, id)
+	}
+	if reveal && !force && !client.StdoutIsTerminal() {
+		fmt.Fprintln(os.Stderr, "Output is not a terminal, secrets stay hidden; add --force to print them")
+		reveal = false
+	}
+	return h.session.GetCommand(ctx, id, reveal)
+}
+
+// list lists the items of an environment as text or JSON
+func (h *CommandHandler) list(ctx context.Context, args []string) error {
+	output, args, err := parseOutputFlag(args)
+	if err != nil {
+		return err
+	}
+	environment, args, err := h.parseEnvFlag(args)
+	if err != nil {
+		return err
+	}
+	flat := false
+	for _, arg := range args {
+		switch arg {
+		case "--all":
+			environment = ""
+		case "--flat":
+			flat = true
+		default:
+			return usageError(listUsage)
+		}
+	}
+	if output == client.OutputJSON {
+		return h.session.ListJSONCommand(ctx, os.Stdout, environment)
+	}
+	return h.session.ListCommand(ctx, environment, flat)
+}
+
+// findLogin lists the logins whose URL matches a target URL, as text or JSON
+func (h *CommandHandler) findLogin(ctx context.Context, args []string) error {
+	output, args, err := parseOutputFlag(args)
+	if err != nil {
+		return err
+	}
+	var target, match string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--match" && i+1 < len(args):
+			match = args[i+1]
+			i++
+		case target == "" && !strings.HasPrefix(args[i], "--"):
+			target = args[i]
+		default:
+			return usageError(findUsage)
+		}
+	}
+	if target == "" {
+		return usageError(findUsage)
+	}
+	rule, err := client.ParseMatchRule(match)
+	if err != nil {
+		return err
+	}
+	return h.session.FindLoginCommand(ctx, os.Stdout, target, rule, output)
+}
+
+// expiring lists the items expiring within a period, as text or JSON
+func (h *CommandHandler) expiring(ctx context.Context, args []string) error {
+	output, args, err := parseOutputFlag(args)
+	if err != nil {
+		return err
+	}
+	window := client.DefaultExpiryWindow
+	switch {
+	case len(args) == 1 && !strings.HasPrefix(args[0], "--"):
+		window = args[0]
+	case len(args) > 0:
+		return usageError(expiryUsage)
+	}
+	return h.session.ExpiringCommand(ctx, os.Stdout, window, output)
+}
+
+// create creates an item from flags or JSON, or by prompting for its fields
+// when none are given
+func (h *CommandHandler) create(ctx context.Context, args []string, stdin io.Reader) error {
+	if !hasItemFlags(args) {
+		return h.createInteractive(ctx, args)
+	}
+	input, args, err := parseItemFlags(args, stdin)
+	if err != nil {
+		return err
+	}
+	output, args, err := parseOutputFlag(args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return usageError(createUsage)
+	}
+	if len(args) == 2 && input.Name == "" {
+		input.Name = client.CleanQuotes(args[1])
+	}
+	if input.Environment == "" {
+		input.Environment = h.config.DefaultEnvironment
+	}
+
+	data, err := h.session.CreateItem(ctx, models.DataType(args[0]), input)
+	if err != nil {
+		return err
+	}
+	return printItem(output, data, "Successfully created encrypted data with ID:")
+}
+
+// createInteractive creates an item by prompting for its fields
+func (h *CommandHandler) createInteractive(ctx context.Context, args []string) error {
+	environment, args, err := h.parseEnvFlag(args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return usageError(createUsage + "\nTypes: login_password, text, binary, bank_card, otp\n" +
+			"Note: Use quotes around names with spaces: create text \"My Shopping List\" \"Description\"")
+	}
+	description := ""
+	if len(args) > 2 {
+		description = client.CleanQuotes(strings.Join(args[2:], " "))
+	}
+	return h.session.CreateCommand(ctx, args[0], client.CleanQuotes(args[1]), description, environment)
+}
+
+// update changes an item from flags or JSON, or with the field by field
+// editor when none are given; --field <name> without a value edits that field only
+func (h *CommandHandler) update(ctx context.Context, args []string, stdin io.Reader) error {
+	if names, rest := parseFieldNames(args); len(names) > 0 {
+		if len(rest) != 1 {
+			return usageError(updateUsage)
+		}
+		return h.session.UpdateFieldsCommand(ctx, rest[0], names)
+	}
+
+	raw := len(args) > 0 && args[0] == "--raw"
+	if raw || !hasItemFlags(args) {
+		if raw {
+			args = args[1:]
+		}
+		if len(args) < 1 {
+			return usageError(updateUsage)
+		}
+		if raw {
+			return h.session.UpdateRawCommand(ctx, args[0])
+		}
+		return h.session.UpdateCommand(ctx, args[0])
+	}
+
+	input, args, err := parseItemFlags(args, stdin)
+	if err != nil {
+		return err
+	}
+	output, args, err := parseOutputFlag(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError(updateUsage)
+	}
+	data, err := h.session.UpdateItem(ctx, args[0], input)
+	if err != nil {
+		return err
+	}
+	return printItem(output, data, "Successfully updated encrypted data:")
+}
@@ -0,0 +1,186 @@
+// Command loadtest drives concurrent register, login and item CRUD traffic
+// against a GophKeeper server and reports the latency percentiles of each
+// operation, to track performance regressions.
+//
+// Each simulated user registers, logs in and then creates, reads, lists,
+// updates and deletes an item in a loop until the duration is over. Run it
+// against a server with a raised AUTH_RATE_LIMIT, or registrations of more
+// users than the limit allows fail.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/client"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// Operations whose latency is recorded
+const (
+	opRegister = "register"
+	opLogin    = "login"
+	opCreate   = "create"
+	opGet      = "get"
+	opList     = "list"
+	opUpdate   = "update"
+	opDelete   = "delete"
+)
+
+// operations are the recorded operations in the order they are reported
+var operations = []string{opRegister, opLogin, opCreate, opGet, opList, opUpdate, opDelete}
+
+// options configure a load test
+type options struct {
+	server   string
+	users    int
+	duration time.Duration
+	payload  int
+	timeout  time.Duration
+}
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.server, "server", "http://localhost:8080", "base URL of the server")
+	flag.IntVar(&opts.users, "users", 10, "number of concurrent users")
+	flag.DurationVar(&opts.duration, "duration", 30*time.Second, "how long the load test runs, including registration")
+	flag.IntVar(&opts.payload, "payload", 1024, "size in bytes of the content of each item")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "timeout of each request")
+	flag.Parse()
+
+	if opts.users <= 0 || opts.duration <= 0 || opts.payload <= 0 {
+		fmt.Fprintln(os.Stderr, "users, duration and payload must be positive")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Running %d users against %s for %s...\n", opts.users, opts.server, opts.duration)
+	stats := newRecorder()
+	started := time.Now()
+	run(ctx, opts, stats)
+	elapsed := time.Since(started)
+
+	stats.report(os.Stdout, operations, elapsed)
+	if stats.failed() > 0 {
+		os.Exit(1)
+	}
+}
+
+// run starts the users and waits for them to finish
+func run(ctx context.Context, opts options, stats *recorder) {
+	// runID keeps usernames of repeated runs against one server apart
+	runID := time.Now().UnixNano()
+	deadline := time.Now().Add(opts.duration)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.users; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u := &user{
+				client:   client.NewClient(opts.server),
+				username: fmt.Sprintf("loadtest-%d-%d", runID, i),
+				password: "loadtest-password-1",
+				stats:    stats,
+				timeout:  opts.timeout,
+			}
+			u.run(ctx, deadline, opts.payload)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// user is one simulated user of a load test
+type user struct {
+	client   *client.Client
+	username string
+	password string
+	stats    *recorder
+	timeout  time.Duration
+}
+
+// run registers and logs in, then runs item operations until deadline
+func (u *user) run(ctx context.Context, deadline time.Time, payload int) {
+	if err := u.do(ctx, opRegister, func(ctx context.Context) error {
+		_, err := u.client.Register(ctx, u.username, u.password, u.password)
+		return err
+	}); err != nil {
+		return
+	}
+	if err := u.do(ctx, opLogin, func(ctx context.Context) error {
+		resp, err := u.client.Login(ctx, u.username, u.password)
+		if err == nil {
+			u.client.SetToken(resp.Token)
+		}
+		return err
+	}); err != nil {
+		return
+	}
+
+	content := make([]byte, payload)
+	for ctx.Err() == nil && time.Now().Before(deadline) {
+		if _, err := rand.Read(content); err != nil {
+			return
+		}
+		u.cycle(ctx, content)
+	}
+}
+
+// cycle creates an item, reads it alone and in the list, updates it and
+// deletes it
+func (u *user) cycle(ctx context.Context, content []byte) {
+	req := models.DataRequest{Type: models.DataTypeText, Name: "load test item", Data: content}
+	var data *models.Data
+	if err := u.do(ctx, opCreate, func(ctx context.Context) (err error) {
+		data, err = u.client.CreateData(ctx, req)
+		return err
+	}); err != nil {
+		return
+	}
+	id := data.ID.String()
+
+	_ = u.do(ctx, opGet, func(ctx context.Context) error {
+		_, err := u.client.GetDataByID(ctx, id)
+		return err
+	})
+	_ = u.do(ctx, opList, func(ctx context.Context) error {
+		_, err := u.client.GetData(ctx)
+		return err
+	})
+	req.Name = "updated load test item"
+	req.BaseRevision = &data.Revision
+	_ = u.do(ctx, opUpdate, func(ctx context.Context) error {
+		_, err := u.client.UpdateData(ctx, id, req)
+		return err
+	})
+	_ = u.do(ctx, opDelete, func(ctx context.Context) error {
+		return u.client.DeleteData(ctx, id)
+	})
+}
+
+// do runs one operation with the request timeout and records its latency,
+// or its failure unless the load test was interrupted
+func (u *user) do(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	opCtx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	started := time.Now()
+	err := fn(opCtx)
+	if err != nil {
+		if ctx.Err() == nil {
+			u.stats.fail(op, err)
+		}
+		return err
+	}
+	u.stats.record(op, time.Since(started))
+	return nil
+}
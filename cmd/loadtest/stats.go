@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// recorder collects the latencies and failures of operations. It is safe for
+// concurrent use.
+type recorder struct {
+	mutex     sync.Mutex
+	latencies map[string][]time.Duration
+	failures  map[string]int
+	// lastErrors are the last failure of each operation, to tell why it fails
+	lastErrors map[string]error
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		latencies:  make(map[string][]time.Duration),
+		failures:   make(map[string]int),
+		lastErrors: make(map[string]error),
+	}
+}
+
+// record records a succeeded operation
+func (r *recorder) record(op string, latency time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.latencies[op] = append(r.latencies[op], latency)
+}
+
+// fail records a failed operation
+func (r *recorder) fail(op string, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.failures[op]++
+	r.lastErrors[op] = err
+}
+
+// failed returns the number of failed operations
+func (r *recorder) failed() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	failed := 0
+	for _, n := range r.failures {
+		failed += n
+	}
+	return failed
+}
+
+// percentile returns the latency that p percent of sorted are at or below
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+// report writes a table of the operations in ops, with their throughput over
+// elapsed and latency percentiles, followed by the last error of each failing
+// operation
+func (r *recorder) report(w io.Writer, ops []string, elapsed time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\tok\tfailed\treq/s\tp50\tp90\tp99\tmax\t")
+	for _, op := range ops {
+		sorted := slices.Clone(r.latencies[op])
+		if len(sorted) == 0 && r.failures[op] == 0 {
+			continue
+		}
+		slices.Sort(sorted)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", op, len(sorted), r.failures[op],
+			float64(len(sorted))/elapsed.Seconds(),
+			round(percentile(sorted, 50)), round(percentile(sorted, 90)), round(percentile(sorted, 99)),
+			round(percentile(sorted, 100)))
+	}
+	_ = tw.Flush()
+
+	for _, op := range ops {
+		if err := r.lastErrors[op]; err != nil {
+			fmt.Fprintf(w, "%s failed %d times, last with: %v\n", op, r.failures[op], err)
+		}
+	}
+}
+
+// round rounds a latency for display
+func round(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(time.Millisecond)
+	}
+	if d >= time.Millisecond {
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
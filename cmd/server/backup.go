@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/a2sh3r/gophkeeper/internal/backup"
+	"github.com/a2sh3r/gophkeeper/internal/config"
+	"github.com/a2sh3r/gophkeeper/internal/db"
+	"github.com/a2sh3r/gophkeeper/internal/s3"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+)
+
+// Exit codes of the backup and restore commands
+const (
+	backupExitOK    = 0
+	backupExitError = 1
+)
+
+// newBackupManager creates the manager of the backups configured in cfg
+func newBackupManager(cfg config.BackupConfig, dumper storage.Dumper) (*backup.Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if !cfg.Configured() {
+		return nil, fmt.Errorf("no backup directory or S3 bucket is configured")
+	}
+
+	var key []byte
+	if cfg.Key != "" {
+		var err error
+		if key, err = base64.StdEncoding.DecodeString(cfg.Key); err != nil {
+			return nil, err
+		}
+	}
+
+	var target backup.Target
+	if cfg.Dir != "" {
+		dir, err := backup.NewDirTarget(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		target = dir
+	} else {
+		client, err := s3.New(s3.Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		target = backup.NewS3Target(client, cfg.S3Prefix)
+	}
+	return backup.NewManager(dumper, target, backup.Options{Key: key, Retention: cfg.Retention}), nil
+}
+
+// openBackupStorage connects to the database backed up, which must be PostgreSQL
+func openBackupStorage(cfg *config.Config) (*db.DB, *storage.PostgresStorage, error) {
+	if cfg.Database.Type != "postgres" {
+		return nil, nil, fmt.Errorf("backups need the postgres database type, not %q", cfg.Database.Type)
+	}
+	// backups read from and restore into the primary only
+	primary := *cfg
+	primary.Database.ReplicaDSNs = nil
+	database, err := openDatabase(&primary)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	return database, storage.NewPostgresStorage(database.Conn()), nil
+}
+
+// runBackup takes a backup to the configured directory or bucket, or lists
+// the stored backups, and returns 0 on success and 1 otherwise
+func runBackup(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	list := flags.Bool("list", false, "List the stored backups instead of taking one")
+	if err := flags.Parse(args); err != nil {
+		return backupExitError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	database, postgres, err := openBackupStorage(cfg)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return backupExitError
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+	manager, err := newBackupManager(cfg.Backup, postgres)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return backupExitError
+	}
+
+	if *list {
+		names, err := manager.List(ctx)
+		if err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return backupExitError
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return backupExitOK
+	}
+
+	name, err := manager.Backup(ctx)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return backupExitError
+	}
+	fmt.Printf("Backup %s taken\n", name)
+	return backupExitOK
+}
+
+// runRestore restores a stored backup by name, the newest one for latest, or
+// a backup file, into the database, and returns 0 on success and 1 otherwise.
+// The schema is migrated first, so an empty database can be restored into.
+func runRestore(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	force := flags.Bool("force", false, "Delete all users and items before restoring into a database that has some")
+	if err := flags.Parse(args); err != nil {
+		return backupExitError
+	}
+	if flags.NArg() != 1 {
+		fmt.Println("usage: restore [-force] <name>|latest|<file>")
+		return backupExitError
+	}
+	source := flags.Arg(0)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	database, postgres, err := openBackupStorage(cfg)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return backupExitError
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+	if err := runMigrations(database.Conn()); err != nil {
+		fmt.Printf("ERROR: failed to migrate database: %v\n", err)
+		return backupExitError
+	}
+
+	var header *backup.Header
+	var rows int
+	if file, openErr := os.Open(source); openErr == nil {
+		defer func() {
+			_ = file.Close()
+		}()
+		var key []byte
+		if cfg.Backup.Key != "" {
+			if key, err = base64.StdEncoding.DecodeString(cfg.Backup.Key); err != nil {
+				fmt.Printf("ERROR: invalid backup key: %v\n", err)
+				return backupExitError
+			}
+		}
+		header, rows, err = backup.Read(ctx, file, postgres, key, *force)
+	} else {
+		manager, managerErr := newBackupManager(cfg.Backup, postgres)
+		if managerErr != nil {
+			fmt.Printf("ERROR: %v\n", managerErr)
+			return backupExitError
+		}
+		header, rows, err = manager.Restore(ctx, source, *force)
+	}
+	if errors.Is(err, storage.ErrNotEmpty) {
+		fmt.Println("ERROR: the database has users already; restore with -force to replace them")
+		return backupExitError
+	}
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return backupExitError
+	}
+	fmt.Printf("Restored %d rows of the backup taken %s\n", rows, header.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	return backupExitOK
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/config"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+)
+
+// Exit codes of the check command
+const (
+	checkExitOK       = 0
+	checkExitDegraded = 1
+	checkExitError    = 2
+)
+
+// runCheck calls the readiness endpoint of a running server, prints every
+// dependency and returns 0 when all are ok, 1 when any is slow or down and 2
+// when the server cannot be asked, for use in deployment pipelines
+func runCheck(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	url := flags.String("url", "http://"+cfg.GetServerAddr(), "Base URL of the server to check")
+	timeout := flags.Duration("timeout", 10*time.Second, "Time to wait for the readiness response")
+	if err := flags.Parse(args); err != nil {
+		return checkExitError
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *url+"/readyz", nil)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return checkExitError
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Printf("ERROR: server unreachable: %v\n", err)
+		return checkExitError
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var readiness models.ReadinessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&readiness); err != nil {
+		fmt.Printf("ERROR: invalid readiness response (HTTP %d): %v\n", resp.StatusCode, err)
+		return checkExitError
+	}
+
+	for _, health := range readiness.Dependencies {
+		fmt.Printf("%-10s %-5s %8.1fms", health.Name, health.Status, health.LatencyMS)
+		if health.Error != "" {
+			fmt.Printf("  %s", health.Error)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Server is %s\n", readiness.Status)
+
+	if readiness.Status != server.HealthOK {
+		return checkExitDegraded
+	}
+	return checkExitOK
+}
@@ -1,27 +1,45 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base64"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/a2sh3r/gophkeeper/assets"
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/atrest"
 	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/backup"
 	"github.com/a2sh3r/gophkeeper/internal/config"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
 	"github.com/a2sh3r/gophkeeper/internal/db"
+	"github.com/a2sh3r/gophkeeper/internal/db/migrations"
+	"github.com/a2sh3r/gophkeeper/internal/grpcserver"
+	"github.com/a2sh3r/gophkeeper/internal/idgen"
 	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/s3"
 	"github.com/a2sh3r/gophkeeper/internal/server"
 	"github.com/a2sh3r/gophkeeper/internal/storage"
 	"github.com/a2sh3r/gophkeeper/pkg/version"
 	"github.com/gorilla/mux"
 	"github.com/urfave/negroni"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 func main() {
 	var (
 		showVersion = flag.Bool("version", false, "Show version information")
+		migrateOnly = flag.Bool("migrate", false, "Apply pending database migrations and exit")
 	)
 	flag.Parse()
 
@@ -32,17 +50,49 @@ func main() {
 
 	cfg := config.Load()
 
+	if flag.Arg(0) == "check" {
+		os.Exit(runCheck(cfg, flag.Args()[1:]))
+	}
+
 	if err := logger.Initialize(cfg.Server.LogLevel); err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 
+	switch flag.Arg(0) {
+	case "backup":
+		os.Exit(runBackup(cfg, flag.Args()[1:]))
+	case "restore":
+		os.Exit(runRestore(cfg, flag.Args()[1:]))
+	}
+
 	var userStore server.UserStorage
 	var dataStore server.DataStorage
+	var escrowStore server.EscrowStorage
+	var hintStore server.HintStorage
+	var commentStore server.CommentStorage
+	var chunkStore server.ChunkStorage
+	var attachmentStore server.AttachmentStorage
+	var versionStore server.VersionStorage
+	var auditStore server.AuditStorage
+	var collectionStore server.CollectionStorage
+	var rotationStore server.RotationStorage
+	var verifierStore server.VerifierStorage
+	var shareStore server.ShareStorage
+	var sessionStore server.SessionStorage
+	var identityStore server.IdentityStorage
+	var expiryStore server.ExpiryStorage
+	var blobStore server.BlobChunkStorage
+	var blobSweeper server.BlobSweeper
+	var presigner server.Presigner
+	var blobPinger func(ctx context.Context) error
+	var dumper storage.Dumper
+	var selfTester storage.SelfTester
+	var pinger storage.Pinger
 
 	switch cfg.Database.Type {
 	case "postgres":
 		logger.Log.Info("Using PostgreSQL database", zap.String("host", cfg.Database.Host))
-		database, err := db.New(cfg.GetDSN())
+		database, err := openDatabase(cfg)
 		if err != nil {
 			logger.Log.Fatal("Failed to connect to PostgreSQL", zap.Error(err))
 		}
@@ -51,33 +101,480 @@ func main() {
 				logger.Log.Error("Failed to close database", zap.Error(err))
 			}
 		}()
-		userStore = storage.NewPostgresStorage(database.Conn())
-		dataStore = storage.NewPostgresStorage(database.Conn())
+		if *migrateOnly || cfg.Database.AutoMigrate {
+			if err := runMigrations(database.Conn()); err != nil {
+				logger.Log.Fatal("Failed to migrate database", zap.Error(err))
+			}
+		}
+		// one store serves every role, so items are sealed at rest the same way
+		// whichever route writes or reads them
+		postgres := storage.NewPostgresStorage(database.Conn())
+		if len(cfg.Database.ReplicaDSNs) > 0 {
+			postgres.SetReplicas(database)
+			logger.Log.Info("Reads served by replicas", zap.Int("replicas", len(cfg.Database.ReplicaDSNs)))
+		}
+		if cfg.AtRest.Enabled() {
+			envelope, err := newAtRestEnvelope(cfg.AtRest)
+			if err != nil {
+				logger.Log.Fatal("Invalid encryption at rest configuration", zap.Error(err))
+			}
+			postgres.SetEnvelope(envelope)
+			logger.Log.Info("Encryption at rest enabled", zap.Bool("kms", cfg.AtRest.VaultAddr != ""))
+		}
+		if cfg.Blob.Enabled() {
+			blobs, blobPresigner, err := newBlobClients(cfg.Blob)
+			if err != nil {
+				logger.Log.Fatal("Invalid blob storage configuration", zap.Error(err))
+			}
+			postgres.SetBlobs(blobs)
+			blobSweeper = postgres
+			blobPinger = blobs.Ping
+			// chunks sealed at rest only pass through the server, as clients
+			// could neither open them nor seal the ones they upload
+			if !cfg.AtRest.Enabled() {
+				blobStore = postgres
+				presigner = blobPresigner
+			}
+			logger.Log.Info("Binary content stored in S3", zap.String("endpoint", cfg.Blob.S3Endpoint),
+				zap.String("bucket", cfg.Blob.S3Bucket), zap.Bool("presigned", presigner != nil))
+		}
+		userStore = postgres
+		dataStore = postgres
+		escrowStore = postgres
+		hintStore = postgres
+		commentStore = postgres
+		chunkStore = postgres
+		attachmentStore = postgres
+		versionStore = postgres
+		auditStore = postgres
+		collectionStore = postgres
+		rotationStore = postgres
+		verifierStore = postgres
+		shareStore = postgres
+		sessionStore = postgres
+		identityStore = postgres
+		expiryStore = postgres
+		dumper = postgres
+		selfTester = postgres
+		pinger = postgres
 	case "memory":
 		logger.Log.Info("Using in-memory storage")
-		userStore = storage.NewMemoryStorage()
-		dataStore = storage.NewMemoryStorage()
+		// users and items share one in-memory store, as a rotation replaces the
+		// salt of a user together with every ciphertext of their items
+		if cfg.Blob.Enabled() {
+			logger.Log.Fatal("Blob storage needs the postgres database type")
+		}
+		if len(cfg.Database.ReplicaDSNs) > 0 {
+			logger.Log.Fatal("Read replicas need the postgres database type")
+		}
+		memory := storage.NewMemoryStorage()
+		userStore = memory
+		dataStore = memory
+		escrowStore = memory
+		hintStore = memory
+		commentStore = memory
+		chunkStore = memory
+		attachmentStore = memory
+		versionStore = memory
+		collectionStore = memory
+		rotationStore = memory
+		verifierStore = memory
+		shareStore = memory
+		sessionStore = memory
+		identityStore = memory
+		expiryStore = memory
+		auditStore = memory
+		selfTester = memory
+		pinger = memory
 	default:
 		logger.Log.Fatal("Unsupported database type", zap.String("type", cfg.Database.Type))
 	}
 
+	if *migrateOnly {
+		return
+	}
+
+	if err := runSelfTest(selfTester); err != nil {
+		if !cfg.Server.AllowDegraded {
+			logger.Log.Fatal("Storage self-test failed, refusing to start", zap.Error(err))
+		}
+		logger.Log.Error("STORAGE SELF-TEST FAILED, STARTING DEGRADED IN READ-ONLY MODE", zap.Error(err))
+		cfg.Maintenance.Enabled = true
+		cfg.Maintenance.Message = "The server storage is degraded; your data can be read but changes are disabled."
+	}
+
+	idGenerator, err := idgen.New(cfg.Server.IDFormat)
+	if err != nil {
+		logger.Log.Fatal("Invalid ID format", zap.Error(err))
+	}
+
+	credentialPolicy := models.CredentialPolicy{
+		UsernameMinLength:  cfg.Policy.UsernameMinLength,
+		UsernameMaxLength:  cfg.Policy.UsernameMaxLength,
+		UsernameCharset:    cfg.Policy.UsernameCharset,
+		PasswordMinLength:  cfg.Policy.PasswordMinLength,
+		PasswordMaxLength:  cfg.Policy.PasswordMaxLength,
+		PasswordMinClasses: cfg.Policy.PasswordMinClasses,
+	}
+	if err := credentialPolicy.Validate(); err != nil {
+		logger.Log.Fatal("Invalid credential policy", zap.Error(err))
+	}
+	routeOptions := server.Options{
+		CredentialPolicy: &credentialPolicy,
+		ExpiryWarning:    cfg.Server.ExpiryWarning,
+		DataIDs:          idGenerator,
+	}
+	if err := cfg.OIDC.Validate(); err != nil {
+		logger.Log.Fatal("Invalid OIDC configuration", zap.Error(err))
+	}
+	if err := cfg.CORS.Validate(); err != nil {
+		logger.Log.Fatal("Invalid CORS configuration", zap.Error(err))
+	}
+	if err := cfg.Cache.Validate(); err != nil {
+		logger.Log.Fatal("Invalid cache configuration", zap.Error(err))
+	}
+	if cfg.Cache.Enabled() {
+		storageCache := server.NewStorageCache(cfg.Cache.Size, cfg.Cache.TTL)
+		dataStore = server.NewCachingDataStorage(dataStore, storageCache)
+		userStore = server.NewCachingUserStorage(userStore, storageCache)
+		rotationStore = server.NewCachingRotationStorage(rotationStore, storageCache)
+		collectionStore = server.NewCachingCollectionStorage(collectionStore, storageCache)
+		expiryStore = server.NewCachingExpiryStorage(expiryStore, storageCache)
+		logger.Log.Info("Items and users cached", zap.Int("size", cfg.Cache.Size), zap.Duration("ttl", cfg.Cache.TTL))
+	}
+	var backups *backup.Manager
+	if cfg.Backup.Interval > 0 {
+		if dumper == nil {
+			logger.Log.Fatal("Scheduled backups need the postgres database type")
+		}
+		if backups, err = newBackupManager(cfg.Backup, dumper); err != nil {
+			logger.Log.Fatal("Invalid backup configuration", zap.Error(err))
+		}
+	}
+
 	jwtManager := auth.NewJWTManager(cfg.JWT.Secret, cfg.JWT.TokenExpiry)
+	sessions := server.NewSessions(sessionStore, routeOptions)
+	jwtManager.SetSessions(sessions)
 
 	router := mux.NewRouter()
-	server.RegisterRoutes(router, userStore, dataStore, jwtManager)
+	router.NotFoundHandler = apierror.NotFoundHandler()
+	router.MethodNotAllowedHandler = apierror.MethodNotAllowedHandler()
+	if len(cfg.Database.ReplicaDSNs) > 0 || cfg.Cache.Enabled() {
+		router.Use(middleware.PrimaryReadsForWrites)
+	}
+	if !cfg.Server.RegistrationOpen {
+		server.CloseRegistration(router)
+	}
+	if cfg.OIDC.Only {
+		server.DisablePasswordLogin(router)
+	}
+	auditOptions := server.AuditOptions{RecordIP: cfg.Audit.RecordIP, AdminToken: cfg.Admin.Token}
+	server.RegisterAuditRoutes(router, auditStore, jwtManager, auditOptions)
+	vaultLocks := server.NewVaultLocks(routeOptions)
+	server.RegisterVaultLockRoutes(router, vaultLocks, jwtManager)
+	events := server.NewEventHub()
+	server.RegisterEventRoutes(router, events, jwtManager)
+	dataStore = server.NewAuditedDataStorage(dataStore, auditStore, auditOptions)
+	dataStore = server.NewNotifyingDataStorage(dataStore, events, routeOptions)
+	userStore = server.NewAuditedUserStorage(userStore, auditStore, auditOptions)
+	server.RegisterRoutes(router, userStore, dataStore, jwtManager, routeOptions)
+
+	server.RegisterHintRoutes(router, hintStore, userStore, jwtManager)
+	server.RegisterVerifierRoutes(router, verifierStore, jwtManager)
+	server.RegisterCommentRoutes(router, commentStore, dataStore, jwtManager, routeOptions)
+	server.RegisterChunkRoutes(router, chunkStore, dataStore, jwtManager)
+	if blobStore != nil {
+		server.RegisterBlobRoutes(router, blobStore, presigner, dataStore, jwtManager, server.BlobOptions{Expiry: cfg.Blob.PresignExpiry})
+	}
+	server.RegisterAttachmentRoutes(router, attachmentStore, dataStore, jwtManager, routeOptions)
+	server.RegisterVersionRoutes(router, versionStore, dataStore, jwtManager, routeOptions)
+	server.RegisterCollectionRoutes(router, collectionStore, dataStore, jwtManager, routeOptions)
+	server.RegisterRotationRoutes(router, rotationStore, jwtManager)
+	server.RegisterShareRoutes(router, shareStore, userStore, dataStore, jwtManager, routeOptions)
+	server.RegisterSessionRoutes(router, sessions, jwtManager)
+	if cfg.OIDC.Enabled() {
+		provider, err := auth.NewOIDCProvider(context.Background(), auth.OIDCConfig{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+		}, nil)
+		if err != nil {
+			logger.Log.Fatal("Failed to set up single sign-on", zap.Error(err))
+		}
+		server.RegisterOIDCRoutes(router, provider, identityStore, userStore, jwtManager, server.OIDCOptions{
+			UsernameClaim:    cfg.OIDC.UsernameClaim,
+			AutoCreate:       cfg.OIDC.AutoCreate,
+			CredentialPolicy: &credentialPolicy,
+		})
+		logger.Log.Info("Single sign-on enabled", zap.String("issuer", provider.Issuer()), zap.Bool("only", cfg.OIDC.Only))
+	}
+
+	var recoveryPublicKey []byte
+	if cfg.Escrow.RecoveryPublicKey != "" {
+		key, err := base64.StdEncoding.DecodeString(cfg.Escrow.RecoveryPublicKey)
+		if err != nil || len(key) != 32 {
+			logger.Log.Fatal("Invalid escrow recovery public key", zap.Error(err))
+		}
+		recoveryPublicKey = key
+		logger.Log.Info("Key escrow enabled", zap.String("recovery_key_id", crypto.RecoveryKeyID(key)))
+	}
+	server.RegisterEscrowRoutes(router, escrowStore, userStore, dataStore, jwtManager, server.EscrowOptions{
+		RecoveryPublicKey: recoveryPublicKey,
+		AdminToken:        cfg.Admin.Token,
+	})
+	if cfg.Server.WebUI {
+		server.RegisterWebUIRoutes(router, assets.WebUI())
+	}
+
+	var bulk *middleware.ConcurrencyLimiter
+	if cfg.Limits.BulkMaxInFlight > 0 {
+		bulk = middleware.NewConcurrencyLimiter("bulk", cfg.Limits.BulkMaxInFlight, cfg.Limits.BulkMaxQueue,
+			cfg.Limits.BulkQueueTimeout)
+		limiters := make(map[string]*middleware.ConcurrencyLimiter)
+		for _, name := range server.BulkRoutes {
+			limiters[name] = bulk
+		}
+		router.Use(middleware.LimitRoutes(limiters))
+	}
+	var authLimiter *middleware.RateLimiter
+	if cfg.Limits.AuthRateLimit > 0 && cfg.Limits.AuthRateWindow > 0 {
+		limit := middleware.RateLimit{Burst: cfg.Limits.AuthRateLimit, Per: cfg.Limits.AuthRateWindow}
+		authLimiter = middleware.NewRateLimiter("auth", limit, middleware.NewMemoryRateLimitStore())
+		router.Use(authLimiter.Routes(server.AuthRoutes...))
+	}
+
+	features := []string{server.FeatureEnvironments, server.FeatureFieldPublishing, server.FeatureConflictDetection,
+		server.FeatureComments, server.FeatureStreamingImport, server.FeatureAuditLog, server.FeatureVaultLock,
+		server.FeatureChunkedBinary, server.FeatureVersions, server.FeatureSearch, server.FeatureCollections,
+		server.FeatureVaultRotation, server.FeatureSharing, server.FeatureChangeEvents, server.FeatureBatch,
+		server.FeatureMetadataPatch, server.FeatureSessions, server.FeatureDomainIndex, server.FeatureIcons,
+		server.FeatureAttachments, server.FeatureExpiry}
+	if len(recoveryPublicKey) > 0 {
+		features = append(features, server.FeatureKeyEscrow)
+	}
+	if cfg.Admin.Token != "" {
+		features = append(features, server.FeatureAdmin)
+	}
+	if _, ok := idGenerator.(*idgen.ULID); ok {
+		features = append(features, server.FeatureULIDs)
+	}
+	if cfg.OIDC.Enabled() {
+		features = append(features, server.FeatureOIDC)
+	}
+	if cfg.Server.Compression {
+		features = append(features, server.FeatureCompression)
+	}
+	if cfg.Server.WebUI {
+		features = append(features, server.FeatureWebUI)
+	}
+	if blobStore != nil {
+		features = append(features, server.FeaturePresignedChunks)
+	}
+	payloadLimits := server.PayloadLimits(cfg.Server.MaxAuthPayloadBytes, cfg.Server.RoutePayloadLimits)
+	if err := server.CheckPayloadLimits(router, cfg.Server.RoutePayloadLimits); err != nil {
+		logger.Log.Fatal("Invalid route payload limits", zap.Error(err))
+	}
+	server.RegisterStatusRoutes(router, server.StatusOptions{
+		RegistrationOpen: cfg.Server.RegistrationOpen,
+		Features:         features,
+		MaxPayloadBytes:  cfg.Server.MaxPayloadBytes,
+		PayloadLimits:    payloadLimits,
+		CredentialPolicy: &credentialPolicy,
+	})
+	router.Use(middleware.MaxBodySizeByRoute(cfg.Server.MaxPayloadBytes, payloadLimits, server.ImportPath, server.RotatePath))
+
+	checks := []server.DependencyCheck{{Name: "database", Check: pinger.Ping}}
+	if blobPinger != nil {
+		checks = append(checks, server.DependencyCheck{Name: "blob storage", Check: blobPinger})
+	}
+	server.RegisterReadinessRoutes(router, server.ReadinessOptions{
+		Checks:      checks,
+		SlowLatency: storage.SlowLatency,
+	})
+
+	maintenance := middleware.NewMaintenance(cfg.Maintenance.Enabled, cfg.Maintenance.Message, server.MaintenanceExemptPaths...)
+	server.RegisterMaintenanceRoutes(router, maintenance, cfg.Admin.Token)
+
+	// the gRPC API serves the same storage under the same policies
+	grpcOptions := grpcserver.Options{
+		Routes:                routeOptions,
+		Maintenance:           maintenance,
+		VaultLocks:            vaultLocks,
+		AuthLimiter:           authLimiter,
+		BulkLimiter:           bulk,
+		MaxPayloadBytes:       cfg.Server.MaxPayloadBytes,
+		PayloadLimits:         payloadLimits,
+		RegistrationClosed:    !cfg.Server.RegistrationOpen,
+		PasswordLoginDisabled: cfg.OIDC.Only,
+	}
+	newGRPC := func(opts ...grpc.ServerOption) *grpc.Server {
+		return grpcserver.New(userStore, dataStore, chunkStore, jwtManager, grpcOptions, opts...)
+	}
 
 	n := negroni.New()
-	n.Use(negroni.NewLogger())
+	accessLog := negroni.NewLogger()
+	accessLog.SetFormat(negroni.LoggerDefaultFormat + ` | {{.Request.Header.Get "` + middleware.RequestIDHeader + `"}}`)
+	n.Use(accessLog)
 	n.Use(negroni.NewRecovery())
-	n.UseHandler(router)
+	handler := maintenance.Handler(router)
+	if cfg.Server.Compression {
+		// Outside the router, so request bodies are decompressed before the body size limits
+		handler = middleware.Compression(middleware.DefaultCompressionMinSize)(handler)
+	}
+	if cfg.CORS.Enabled() {
+		// Outside the router too, as routes only match their own methods and not preflight OPTIONS
+		handler = middleware.CORS(middleware.CORSOptions{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		})(handler)
+		logger.Log.Info("Cross-origin requests allowed", zap.Strings("origins", cfg.CORS.AllowedOrigins))
+	}
+	n.UseHandler(handler)
+
+	if err := cfg.Server.TLS.Validate(); err != nil {
+		logger.Log.Fatal("Invalid TLS configuration", zap.Error(err))
+	}
 
 	addr := cfg.GetServerAddr()
 	logger.Log.Info("Starting GophKeeper server",
 		zap.String("address", addr),
 		zap.String("version", version.ShortInfo()),
-		zap.String("database", cfg.Database.Type))
+		zap.String("database", cfg.Database.Type),
+		zap.Bool("tls", cfg.Server.TLS.Enabled()))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// event streams never finish on their own, so end them before draining requests
+	go func() {
+		<-ctx.Done()
+		events.Close()
+	}()
+	// flags are writes, so a read-only server leaves them as they are
+	if cfg.Server.ExpiryCheckInterval > 0 && !cfg.Maintenance.Enabled {
+		go server.RunExpiryChecks(ctx, expiryStore, events, cfg.Server.ExpiryCheckInterval, routeOptions)
+	}
+	// deleting objects of deleted chunks is a write too
+	if blobSweeper != nil && !cfg.Maintenance.Enabled {
+		go server.RunBlobSweeps(ctx, blobSweeper, cfg.Blob.SweepInterval)
+	}
+	// backups only read, so a read-only server takes them as well
+	if backups != nil {
+		go backups.Run(ctx, cfg.Backup.Interval)
+		logger.Log.Info("Scheduled backups enabled", zap.Duration("interval", cfg.Backup.Interval),
+			zap.Int("retention", cfg.Backup.Retention), zap.Bool("encrypted", cfg.Backup.Key != ""))
+	}
 
-	if err := http.ListenAndServe(addr, n); err != nil {
+	if err := listenAndServe(ctx, cfg, middleware.RequestID(n), newGRPC); err != nil {
 		logger.Log.Fatal("Server failed to start", zap.Error(err))
 	}
+	logger.Log.Info("Server stopped")
+}
+
+// newAtRestEnvelope creates the envelope sealing item data at rest and checks
+// that the master key or KMS works before serving
+func newAtRestEnvelope(cfg config.AtRestConfig) (*atrest.Envelope, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var wrapper atrest.KeyWrapper
+	if cfg.Key != "" {
+		key, err := base64.StdEncoding.DecodeString(cfg.Key)
+		if err != nil {
+			return nil, err
+		}
+		if wrapper, err = atrest.NewLocalKey(key); err != nil {
+			return nil, err
+		}
+	} else {
+		wrapper = atrest.NewVaultTransit(cfg.VaultAddr, cfg.VaultToken, cfg.VaultKey, nil)
+	}
+
+	envelope := atrest.NewEnvelope(wrapper)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := envelope.Check(ctx); err != nil {
+		return nil, err
+	}
+	return envelope, nil
+}
+
+// newBlobClients creates the client storing binary content in the bucket of
+// cfg and the one presigning URLs for clients, which differs when clients
+// reach the bucket at a public endpoint
+func newBlobClients(cfg config.BlobConfig) (*s3.Client, *s3.Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	s3cfg := s3.Config{
+		Endpoint:  cfg.S3Endpoint,
+		Region:    cfg.S3Region,
+		Bucket:    cfg.S3Bucket,
+		AccessKey: cfg.S3AccessKey,
+		SecretKey: cfg.S3SecretKey,
+		PathStyle: cfg.S3PathStyle,
+	}
+	blobs, err := s3.New(s3cfg, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.S3PublicEndpoint == "" {
+		return blobs, blobs, nil
+	}
+	s3cfg.Endpoint = cfg.S3PublicEndpoint
+	presigner, err := s3.New(s3cfg, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return blobs, presigner, nil
+}
+
+// openDatabase connects to the PostgreSQL database of cfg
+func openDatabase(cfg *config.Config) (*db.DB, error) {
+	return db.NewWithOptions(cfg.GetDSN(), db.Options{
+		MaxOpenConns:     cfg.Database.MaxOpenConns,
+		MaxIdleConns:     cfg.Database.MaxIdleConns,
+		ConnMaxLifetime:  cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime:  cfg.Database.ConnMaxIdleTime,
+		StatementTimeout: cfg.Database.StatementTimeout,
+		NativePool:       cfg.Database.NativePool,
+		ReplicaDSNs:      cfg.Database.ReplicaDSNs,
+	})
+}
+
+// runMigrations brings the database schema up to date with the migrations
+// embedded in this build
+func runMigrations(conn *sql.DB) error {
+	version, err := migrations.Up(context.Background(), conn)
+	if err != nil {
+		return err
+	}
+	logger.Log.Info("Database schema is up to date", zap.Int("schema_version", version))
+	return nil
+}
+
+// runSelfTest checks the storage before serving so failures surface at boot
+// rather than as opaque 500s on the first user request
+func runSelfTest(tester storage.SelfTester) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	report, err := tester.SelfTest(ctx)
+	if err != nil {
+		return err
+	}
+
+	if report.Slow() {
+		logger.Log.Warn("Storage latency is high", zap.Duration("latency", report.Latency),
+			zap.Duration("threshold", storage.SlowLatency))
+	}
+	logger.Log.Info("Storage self-test passed", zap.Int("schema_version", report.SchemaVersion),
+		zap.Duration("latency", report.Latency))
+	return nil
 }
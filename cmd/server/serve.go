@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/config"
+	"github.com/a2sh3r/gophkeeper/internal/grpcserver"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// listenAndServe serves handler over HTTPS when TLS is configured, along with
+// the optional listener redirecting plain HTTP to it, and over HTTP otherwise.
+// The gRPC API, when its port is set, is served by the server newGRPC returns
+// and uses the same certificates. When ctx is done it stops accepting
+// connections and drains in-flight requests before returning.
+func listenAndServe(ctx context.Context, cfg *config.Config, handler http.Handler, newGRPC newGRPCServer) error {
+	srv := &http.Server{
+		Addr:              cfg.GetServerAddr(),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	tlsConfig := cfg.Server.TLS
+	if !tlsConfig.Enabled() {
+		servers, err := withGRPC(cfg, newGRPC, nil, srv)
+		if err != nil {
+			return err
+		}
+		return serveUntilDone(ctx, cfg.Server.ShutdownTimeout, srv.ListenAndServe, servers...)
+	}
+
+	srv.Handler = middleware.HSTS(handler)
+	redirect := middleware.RedirectToHTTPS(strconv.Itoa(cfg.Server.Port))
+	certFile, keyFile := tlsConfig.CertFile, tlsConfig.KeyFile
+
+	if tlsConfig.Autocert() {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(tlsConfig.AutocertDomains...),
+			Cache:      autocert.DirCache(tlsConfig.AutocertCacheDir),
+			Email:      tlsConfig.AutocertEmail,
+		}
+		srv.TLSConfig = manager.TLSConfig()
+		// Let's Encrypt validates domains over plain HTTP on port 80
+		redirect = manager.HTTPHandler(redirect)
+		certFile, keyFile = "", ""
+		logger.Log.Info("Obtaining certificates from Let's Encrypt",
+			zap.Strings("domains", tlsConfig.AutocertDomains),
+			zap.String("cache", tlsConfig.AutocertCacheDir))
+	} else {
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	grpcTLS := srv.TLSConfig.Clone()
+	if cfg.Server.GRPCPort != 0 && certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		grpcTLS.Certificates = []tls.Certificate{cert}
+	}
+	servers, err := withGRPC(cfg, newGRPC, credentials.NewTLS(grpcTLS), srv)
+	if err != nil {
+		return err
+	}
+	if tlsConfig.RedirectAddr != "" {
+		redirectServer := &http.Server{Addr: tlsConfig.RedirectAddr, Handler: redirect, ReadHeaderTimeout: 10 * time.Second}
+		servers = append(servers, redirectServer)
+		go func() {
+			logger.Log.Info("Redirecting HTTP to HTTPS", zap.String("address", tlsConfig.RedirectAddr))
+			if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Log.Error("HTTP redirect listener failed", zap.Error(err))
+			}
+		}()
+	}
+
+	return serveUntilDone(ctx, cfg.Server.ShutdownTimeout, func() error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	}, servers...)
+}
+
+// newGRPCServer returns the server of the gRPC API with the given options
+type newGRPCServer func(opts ...grpc.ServerOption) *grpc.Server
+
+// withGRPC starts serving the gRPC API on its port when it is set, and
+// returns the servers to stop on shutdown. creds nil serves plain text.
+func withGRPC(cfg *config.Config, newGRPC newGRPCServer, creds credentials.TransportCredentials, servers ...gracefulServer) ([]gracefulServer, error) {
+	if cfg.Server.GRPCPort == 0 {
+		return servers, nil
+	}
+	listener, err := net.Listen("tcp", cfg.GetGRPCAddr())
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for gRPC: %w", err)
+	}
+
+	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(grpcserver.MaxMessageSize(cfg.Server.MaxPayloadBytes))}
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+	srv := newGRPC(opts...)
+	go func() {
+		logger.Log.Info("Serving the gRPC API", zap.String("address", cfg.GetGRPCAddr()), zap.Bool("tls", creds != nil))
+		if err := srv.Serve(listener); err != nil {
+			logger.Log.Error("gRPC listener failed", zap.Error(err))
+		}
+	}()
+	return append(servers, grpcServer{srv}), nil
+}
+
+// gracefulServer is a server draining its requests on Shutdown, like http.Server
+type gracefulServer interface {
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+// grpcServer stops a gRPC server like an http.Server
+type grpcServer struct {
+	*grpc.Server
+}
+
+// Shutdown stops accepting calls and waits for the running ones until ctx is done
+func (s grpcServer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close cancels the running calls and closes the connections
+func (s grpcServer) Close() error {
+	s.Stop()
+	return nil
+}
+
+// serveUntilDone runs serve until it fails or ctx is done. Then the servers
+// stop accepting connections and get up to timeout to finish the requests in
+// flight, so a restart does not cut responses or writes short; connections
+// still open after that are closed.
+func serveUntilDone(ctx context.Context, timeout time.Duration, serve func() error, servers ...gracefulServer) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- serve()
+	}()
+
+	select {
+	case err := <-errs:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Log.Info("Shutting down, draining in-flight requests", zap.Duration("timeout", timeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Log.Warn("Requests still in flight after the shutdown timeout, closing connections", zap.Error(err))
+			if err := srv.Close(); err != nil {
+				logger.Log.Error("Failed to close server", zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
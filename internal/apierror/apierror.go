@@ -0,0 +1,120 @@
+// Package apierror writes the JSON error responses of the API.
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// Error replies to the request with message and the error code of status.
+// It is the JSON counterpart of http.Error and takes the same arguments.
+func Error(w http.ResponseWriter, message string, status int) {
+	Write(w, status, models.ErrorResponse{Error: message})
+}
+
+// WithCode replies to the request with message and a specific error code
+func WithCode(w http.ResponseWriter, status int, code, message string) {
+	Write(w, status, models.ErrorResponse{Error: message, Code: code})
+}
+
+// Invalid replies to a request whose fields break validation rules
+func Invalid(w http.ResponseWriter, fields []models.FieldError) {
+	Reply(w, NewInvalid(fields))
+}
+
+// Write replies to the request with response, filling in the error code of
+// status when it has none
+func Write(w http.ResponseWriter, status int, response models.ErrorResponse) {
+	if response.Code == "" {
+		response.Code = models.ErrorCodeForStatus(status)
+	}
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Log.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// NotFoundHandler replies to requests for unknown routes
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, "Not found", http.StatusNotFound)
+	})
+}
+
+// MethodNotAllowedHandler replies to requests with a method a route does not serve
+func MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+}
+
+// Problem is an error response as an error, returned by the operations both
+// the REST and the gRPC API run, for each to reply with in its own way
+type Problem struct {
+	Status   int
+	Response models.ErrorResponse
+	// Header holds headers of the reply, such as Retry-After or ETag
+	Header http.Header
+}
+
+// New returns the problem Error replies with
+func New(message string, status int) *Problem {
+	return &Problem{Status: status, Response: models.ErrorResponse{Error: message}}
+}
+
+// NewWithCode returns the problem WithCode replies with
+func NewWithCode(status int, code, message string) *Problem {
+	return &Problem{Status: status, Response: models.ErrorResponse{Error: message, Code: code}}
+}
+
+// NewInvalid returns the problem Invalid replies with
+func NewInvalid(fields []models.FieldError) *Problem {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	return &Problem{Status: http.StatusBadRequest, Response: models.ErrorResponse{
+		Error:   "Invalid request",
+		Message: strings.Join(messages, "; "),
+		Fields:  fields,
+	}}
+}
+
+// Error implements error
+func (p *Problem) Error() string {
+	if p.Response.Message != "" {
+		return p.Response.Error + ": " + p.Response.Message
+	}
+	return p.Response.Error
+}
+
+// WithHeader sets a header of the reply and returns p
+func (p *Problem) WithHeader(key, value string) *Problem {
+	if p.Header == nil {
+		p.Header = make(http.Header)
+	}
+	p.Header.Set(key, value)
+	return p
+}
+
+// Reply replies to the request with err, a Problem or any other error as 500
+func Reply(w http.ResponseWriter, err error) {
+	problem, ok := err.(*Problem)
+	if !ok {
+		logger.Log.Error("Request failed", zap.Error(err))
+		Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for key, values := range problem.Header {
+		w.Header()[key] = values
+	}
+	Write(w, problem.Status, problem.Response)
+}
@@ -0,0 +1,99 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		write  func(w http.ResponseWriter)
+		status int
+		want   models.ErrorResponse
+	}{
+		{
+			name:   "code from status",
+			write:  func(w http.ResponseWriter) { Error(w, "Data not found", http.StatusNotFound) },
+			status: http.StatusNotFound,
+			want:   models.ErrorResponse{Error: "Data not found", Code: models.ErrorCodeNotFound},
+		},
+		{
+			name:   "unauthorized",
+			write:  func(w http.ResponseWriter) { Error(w, "Unauthorized", http.StatusUnauthorized) },
+			status: http.StatusUnauthorized,
+			want:   models.ErrorResponse{Error: "Unauthorized", Code: models.ErrorCodeUnauthorized},
+		},
+		{
+			name:   "forbidden",
+			write:  func(w http.ResponseWriter) { Error(w, "Forbidden", http.StatusForbidden) },
+			status: http.StatusForbidden,
+			want:   models.ErrorResponse{Error: "Forbidden", Code: models.ErrorCodeForbidden},
+		},
+		{
+			name: "specific code",
+			write: func(w http.ResponseWriter) {
+				WithCode(w, http.StatusConflict, models.ErrorCodeUserExists, "User already exists")
+			},
+			status: http.StatusConflict,
+			want:   models.ErrorResponse{Error: "User already exists", Code: models.ErrorCodeUserExists},
+		},
+		{
+			name: "detail",
+			write: func(w http.ResponseWriter) {
+				Write(w, http.StatusServiceUnavailable, models.ErrorResponse{Error: "maintenance", Message: "Nightly backup"})
+			},
+			status: http.StatusServiceUnavailable,
+			want:   models.ErrorResponse{Error: "maintenance", Message: "Nightly backup", Code: models.ErrorCodeUnavailable},
+		},
+		{
+			name:   "unknown route",
+			write:  func(w http.ResponseWriter) { NotFoundHandler().ServeHTTP(w, httptest.NewRequest("GET", "/nope", nil)) },
+			status: http.StatusNotFound,
+			want:   models.ErrorResponse{Error: "Not found", Code: models.ErrorCodeNotFound},
+		},
+		{
+			name: "invalid fields",
+			write: func(w http.ResponseWriter) {
+				Invalid(w, []models.FieldError{
+					{Field: "name", Rule: "required", Message: "name is required"},
+					{Field: "type", Rule: "oneof", Message: "type must be one of text, binary"},
+				})
+			},
+			status: http.StatusBadRequest,
+			want: models.ErrorResponse{Error: "Invalid request", Message: "name is required; type must be one of text, binary",
+				Code: models.ErrorCodeInvalidRequest, Fields: []models.FieldError{
+					{Field: "name", Rule: "required", Message: "name is required"},
+					{Field: "type", Rule: "oneof", Message: "type must be one of text, binary"},
+				}},
+		},
+		{
+			name:   "server error",
+			write:  func(w http.ResponseWriter) { Error(w, "Failed to get data", http.StatusInternalServerError) },
+			status: http.StatusInternalServerError,
+			want:   models.ErrorResponse{Error: "Failed to get data", Code: models.ErrorCodeInternal},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.write(w)
+
+			if w.Code != tt.status || w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Expected status %d with JSON, got %d %s", tt.status, w.Code, w.Header().Get("Content-Type"))
+			}
+			var got models.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
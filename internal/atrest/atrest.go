@@ -0,0 +1,153 @@
+// Package atrest encrypts values on the server before they are stored, so
+// database dumps and backups expose neither the client-side ciphertext nor its
+// salt metadata. Each value is sealed under a fresh data key, and the data key
+// is wrapped by a KeyWrapper: a local master key or a key held by a KMS.
+package atrest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+)
+
+// magic starts every sealed value. Stored client ciphertext is JSON and starts
+// with '{', so values written before encryption at rest was enabled are told
+// apart and read unchanged.
+var magic = []byte("GKAR")
+
+// formatVersion is the version of the sealed value layout
+const formatVersion = 1
+
+// headerSize is the size of the magic, the version and the wrapped key length
+const headerSize = 4 + 1 + 2
+
+// maxCachedKeys bounds the unwrapped data keys kept in memory, so listing a
+// vault does not call the KMS once per item
+const maxCachedKeys = 1024
+
+// KeyWrapper wraps and unwraps the data keys of sealed values
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// Envelope seals values under data keys wrapped by a KeyWrapper
+type Envelope struct {
+	wrapper KeyWrapper
+
+	mu    sync.Mutex
+	cache map[string][]byte
+}
+
+// NewEnvelope creates an envelope wrapping its data keys with wrapper
+func NewEnvelope(wrapper KeyWrapper) *Envelope {
+	return &Envelope{wrapper: wrapper, cache: make(map[string][]byte)}
+}
+
+// Seal encrypts value under a new data key. The result holds the wrapped key
+// in front of the ciphertext, so it can be opened on its own.
+func (e *Envelope) Seal(ctx context.Context, value []byte) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	key, err := crypto.GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := e.wrapper.WrapKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	if len(wrapped) > 0xffff {
+		return nil, fmt.Errorf("wrapped data key is too long: %d bytes", len(wrapped))
+	}
+	sealed, err := crypto.SealWithKey(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, headerSize+len(wrapped)+len(sealed))
+	out = append(out, magic...)
+	out = append(out, formatVersion)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(wrapped)))
+	out = append(out, wrapped...)
+	return append(out, sealed...), nil
+}
+
+// Open decrypts a value produced by Seal. Values without the header were
+// stored before encryption at rest was enabled and are returned unchanged.
+func (e *Envelope) Open(ctx context.Context, value []byte) ([]byte, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+	if len(value) < headerSize {
+		return nil, fmt.Errorf("sealed value is too short")
+	}
+	if value[len(magic)] != formatVersion {
+		return nil, fmt.Errorf("unsupported sealed value version %d", value[len(magic)])
+	}
+
+	size := int(binary.BigEndian.Uint16(value[len(magic)+1:]))
+	if len(value) < headerSize+size {
+		return nil, fmt.Errorf("sealed value is too short")
+	}
+	wrapped, sealed := value[headerSize:headerSize+size], value[headerSize+size:]
+
+	key, err := e.unwrap(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.OpenWithKey(key, sealed)
+}
+
+// IsSealed reports whether value was produced by Seal
+func IsSealed(value []byte) bool {
+	return bytes.HasPrefix(value, magic)
+}
+
+// Check seals and opens a value, so a misconfigured key or an unreachable KMS
+// is found at startup rather than on the first write
+func (e *Envelope) Check(ctx context.Context) error {
+	probe := []byte("gophkeeper at-rest check")
+	sealed, err := e.Seal(ctx, probe)
+	if err != nil {
+		return err
+	}
+	opened, err := e.Open(ctx, sealed)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(opened, probe) {
+		return fmt.Errorf("sealed value did not round-trip")
+	}
+	return nil
+}
+
+// unwrap unwraps a data key, caching the result. The cache is cleared when
+// full, which is simpler than tracking use and rarely happens.
+func (e *Envelope) unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	e.mu.Lock()
+	key, ok := e.cache[string(wrapped)]
+	e.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	key, err := e.wrapper.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	e.mu.Lock()
+	if len(e.cache) >= maxCachedKeys {
+		e.cache = make(map[string][]byte)
+	}
+	e.cache[string(wrapped)] = key
+	e.mu.Unlock()
+	return key, nil
+}
@@ -0,0 +1,152 @@
+package atrest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newLocalEnvelope(t *testing.T) *Envelope {
+	t.Helper()
+	wrapper, err := NewLocalKey(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("NewLocalKey() error = %v", err)
+	}
+	return NewEnvelope(wrapper)
+}
+
+func TestEnvelope_SealOpen(t *testing.T) {
+	ctx := context.Background()
+	envelope := newLocalEnvelope(t)
+	value := []byte(`{"version":2,"salt":"c2FsdA==","data":"Y2lwaGVy"}`)
+
+	sealed, err := envelope.Seal(ctx, value)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("salt")) {
+		t.Fatalf("Expected the value to be sealed, got %q", sealed)
+	}
+	opened, err := envelope.Open(ctx, sealed)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !bytes.Equal(opened, value) {
+		t.Errorf("Expected %q, got %q", value, opened)
+	}
+
+	legacy, err := envelope.Open(ctx, value)
+	if err != nil || !bytes.Equal(legacy, value) {
+		t.Errorf("Expected an unsealed value to be returned unchanged, got %q, %v", legacy, err)
+	}
+	if empty, err := envelope.Seal(ctx, nil); err != nil || empty != nil {
+		t.Errorf("Expected nil to stay nil, got %q, %v", empty, err)
+	}
+
+	damaged := append([]byte(nil), sealed...)
+	damaged[len(damaged)-1] ^= 1
+	if _, err := envelope.Open(ctx, damaged); err == nil {
+		t.Error("Expected a damaged value to fail")
+	}
+	if _, err := envelope.Open(ctx, sealed[:headerSize+2]); err == nil {
+		t.Error("Expected a truncated value to fail")
+	}
+	if _, err := newLocalEnvelope(t).Open(ctx, sealed); err != nil {
+		t.Errorf("Expected another envelope with the same key to open the value, got %v", err)
+	}
+
+	other, err := NewLocalKey(bytes.Repeat([]byte{8}, 32))
+	if err != nil {
+		t.Fatalf("NewLocalKey() error = %v", err)
+	}
+	if _, err := NewEnvelope(other).Open(ctx, sealed); err == nil {
+		t.Error("Expected another master key to fail")
+	}
+}
+
+func TestNewLocalKey_InvalidLength(t *testing.T) {
+	if _, err := NewLocalKey([]byte("short")); err == nil {
+		t.Error("Expected a short master key to be rejected")
+	}
+}
+
+// fakeTransit implements the transit encrypt and decrypt operations by
+// prefixing the plaintext, and counts the decrypt calls
+func fakeTransit(t *testing.T, decrypts *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		var request map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var data map[string]string
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/gophkeeper":
+			data = map[string]string{"ciphertext": "vault:v1:" + request["plaintext"]}
+		case "/v1/transit/decrypt/gophkeeper":
+			*decrypts++
+			data = map[string]string{"plaintext": strings.TrimPrefix(request["ciphertext"], "vault:v1:")}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+}
+
+func TestVaultTransit(t *testing.T) {
+	ctx := context.Background()
+	var decrypts int
+	srv := fakeTransit(t, &decrypts)
+	defer srv.Close()
+
+	envelope := NewEnvelope(NewVaultTransit(srv.URL+"/", "root", "gophkeeper", srv.Client()))
+	if err := envelope.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	sealed, err := envelope.Seal(ctx, []byte("ciphertext"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !bytes.Contains(sealed, []byte("vault:v1:")) {
+		t.Errorf("Expected the wrapped key of the KMS in the sealed value")
+	}
+	before := decrypts
+	for i := 0; i < 2; i++ {
+		opened, err := envelope.Open(ctx, sealed)
+		if err != nil || string(opened) != "ciphertext" {
+			t.Fatalf("Open() = %q, %v", opened, err)
+		}
+	}
+	if decrypts-before != 1 {
+		t.Errorf("Expected the unwrapped key to be cached, got %d decrypt calls", decrypts-before)
+	}
+
+	denied := NewEnvelope(NewVaultTransit(srv.URL, "wrong", "gophkeeper", srv.Client()))
+	if err := denied.Check(ctx); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected a permission error, got %v", err)
+	}
+}
+
+func TestVaultTransit_InvalidPlaintext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": "%%%"}})
+	}))
+	defer srv.Close()
+
+	wrapper := NewVaultTransit(srv.URL, "root", "gophkeeper", srv.Client())
+	if _, err := wrapper.UnwrapKey(context.Background(), []byte("vault:v1:"+base64.StdEncoding.EncodeToString([]byte("k")))); err == nil {
+		t.Error("Expected an invalid plaintext to fail")
+	}
+}
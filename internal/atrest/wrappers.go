@@ -0,0 +1,129 @@
+package atrest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+)
+
+// LocalKey wraps data keys with a master key held by the server
+type LocalKey struct {
+	key []byte
+}
+
+// NewLocalKey creates a wrapper for a 32-byte master key
+func NewLocalKey(key []byte) (*LocalKey, error) {
+	if len(key) != crypto.DataKeySize {
+		return nil, fmt.Errorf("invalid master key length: expected %d bytes, got %d", crypto.DataKeySize, len(key))
+	}
+	return &LocalKey{key: append([]byte(nil), key...)}, nil
+}
+
+// WrapKey encrypts key under the master key
+func (l *LocalKey) WrapKey(_ context.Context, key []byte) ([]byte, error) {
+	return crypto.SealWithKey(l.key, key)
+}
+
+// UnwrapKey decrypts a key wrapped by WrapKey
+func (l *LocalKey) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	return crypto.OpenWithKey(l.key, wrapped)
+}
+
+// VaultTransit wraps data keys with a named key of the transit secrets engine
+// of HashiCorp Vault or OpenBao, so the master key never leaves the KMS
+type VaultTransit struct {
+	addr       string
+	token      string
+	keyName    string
+	httpClient *http.Client
+}
+
+// NewVaultTransit creates a wrapper using the transit key keyName of the KMS
+// at addr. A nil httpClient uses one with a 10 second timeout.
+func NewVaultTransit(addr, token, keyName string, httpClient *http.Client) *VaultTransit {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &VaultTransit{
+		addr:       strings.TrimRight(addr, "/"),
+		token:      token,
+		keyName:    keyName,
+		httpClient: httpClient,
+	}
+}
+
+// WrapKey encrypts key with the transit key. The wrapped key is the
+// ciphertext the KMS returns, such as vault:v1:...
+func (v *VaultTransit) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	var response struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	request := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(key)}
+	if err := v.call(ctx, "encrypt", request, &response); err != nil {
+		return nil, err
+	}
+	if response.Data.Ciphertext == "" {
+		return nil, fmt.Errorf("transit encrypt returned no ciphertext")
+	}
+	return []byte(response.Data.Ciphertext), nil
+}
+
+// UnwrapKey decrypts a key wrapped by WrapKey
+func (v *VaultTransit) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var response struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	request := map[string]string{"ciphertext": string(wrapped)}
+	if err := v.call(ctx, "decrypt", request, &response); err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(response.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transit plaintext: %w", err)
+	}
+	return key, nil
+}
+
+// call posts request to the transit operation and decodes the response
+func (v *VaultTransit) call(ctx context.Context, operation string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transit request: %w", err)
+	}
+
+	endpoint := v.addr + "/v1/transit/" + operation + "/" + url.PathEscape(v.keyName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create transit request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", v.token)
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("transit %s failed: %w", operation, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("transit %s failed with status %d: %s", operation, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("invalid transit response: %w", err)
+	}
+	return nil
+}
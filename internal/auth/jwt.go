@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/a2sh3r/gophkeeper/internal/clock"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
@@ -11,19 +14,42 @@ import (
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrTokenExpired = errors.New("token expired")
+	// ErrSessionRevoked is returned for tokens of a session that was revoked or has ended
+	ErrSessionRevoked = errors.New("session revoked")
 )
 
-// Claims represents JWT token claims
+// Sessions tracks the logins full account tokens are issued for, so they can
+// be listed and revoked
+type Sessions interface {
+	// Start records a new session of the user lasting until expiresAt and returns its ID
+	Start(ctx context.Context, userID uuid.UUID, device, userAgent string, expiresAt time.Time) (uuid.UUID, error)
+	// Check returns ErrSessionRevoked if the session of the user has ended.
+	// For a scoped token only revoking the session ends it, as the token
+	// may outlive the expiry of the login that issued it.
+	Check(ctx context.Context, userID, sessionID uuid.UUID, scoped bool) error
+}
+
+// Claims represents JWT token claims. The token ID is the session ID of
+// tokens issued with IssueToken, and of the session that issued a scoped token.
 type Claims struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Username string    `json:"username"`
+	// Scope restricts the token to a single resource; empty means full account access
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// FieldScope returns the scope granting read access to one published field of an item
+func FieldScope(dataID uuid.UUID, field string) string {
+	return fmt.Sprintf("field:read:%s/%s", dataID, field)
+}
+
 // JWTManager manages JWT tokens
 type JWTManager struct {
 	secretKey     string
 	tokenDuration time.Duration
+	clock         clock.Clock
+	sessions      Sessions
 }
 
 // NewJWTManager creates new JWT token manager
@@ -31,20 +57,76 @@ func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 	return &JWTManager{
 		secretKey:     secretKey,
 		tokenDuration: tokenDuration,
+		clock:         clock.System{},
 	}
 }
 
+// SetClock sets the clock tokens are issued and checked for expiry against
+func (m *JWTManager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetSessions tracks the sessions of tokens issued with IssueToken and
+// refuses the tokens of revoked ones. Call it before serving requests.
+func (m *JWTManager) SetSessions(s Sessions) {
+	m.sessions = s
+}
+
 // GenerateToken generates JWT token for user
 func (m *JWTManager) GenerateToken(userID uuid.UUID, username string) (string, error) {
+	return m.generateToken(userID, username, "", "", m.tokenDuration)
+}
+
+// IssueToken generates a token for a login of the user, starting a session
+// for it if sessions are tracked
+func (m *JWTManager) IssueToken(ctx context.Context, userID uuid.UUID, username, device, userAgent string) (string, error) {
+	if m.sessions == nil {
+		return m.GenerateToken(userID, username)
+	}
+	sessionID, err := m.sessions.Start(ctx, userID, device, userAgent, m.clock.Now().Add(m.tokenDuration))
+	if err != nil {
+		return "", fmt.Errorf("failed to start session: %w", err)
+	}
+	return m.generateToken(userID, username, "", sessionID.String(), m.tokenDuration)
+}
+
+// CheckSession returns ErrSessionRevoked if the token belongs to a session
+// that has ended, or a scoped token to one that was revoked. Tokens issued
+// without a session pass.
+func (m *JWTManager) CheckSession(ctx context.Context, claims *Claims) error {
+	if m.sessions == nil || claims.ID == "" {
+		return nil
+	}
+	sessionID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	return m.sessions.Check(ctx, claims.UserID, sessionID, claims.Scope != "")
+}
+
+// GenerateScopedToken generates a JWT token restricted to scope with its own
+// lifetime. A token issued from a session, sessionID not empty, is refused
+// once that session is revoked, but not when it merely expires.
+func (m *JWTManager) GenerateScopedToken(userID uuid.UUID, username, scope, sessionID string, duration time.Duration) (string, error) {
+	if scope == "" {
+		return "", fmt.Errorf("scope cannot be empty")
+	}
+	return m.generateToken(userID, username, scope, sessionID, duration)
+}
+
+func (m *JWTManager) generateToken(userID uuid.UUID, username, scope, sessionID string, duration time.Duration) (string, error) {
+	now := m.clock.Now()
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
+		Scope:    scope,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.tokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "gophkeeper",
 			Subject:   userID.String(),
+			ID:        sessionID,
 		},
 	}
 
@@ -59,7 +141,7 @@ func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 			return nil, ErrInvalidToken
 		}
 		return []byte(m.secretKey), nil
-	})
+	}, jwt.WithTimeFunc(m.clock.Now))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -1,9 +1,11 @@
 package auth
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/a2sh3r/gophkeeper/internal/clock"
 	"github.com/google/uuid"
 )
 
@@ -116,6 +118,33 @@ func TestJWTManager_ValidateToken_Expired(t *testing.T) {
 	}
 }
 
+func TestJWTManager_ValidateToken_Clock(t *testing.T) {
+	now := clock.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := NewJWTManager("test-secret", time.Hour)
+	manager.SetClock(now)
+
+	token, err := manager.GenerateToken(uuid.New(), "testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	claims, err := manager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if !claims.IssuedAt.Time.Equal(now.Now()) || !claims.ExpiresAt.Time.Equal(now.Now().Add(time.Hour)) {
+		t.Errorf("Expected the token to be stamped by the clock, got issued %v, expires %v", claims.IssuedAt, claims.ExpiresAt)
+	}
+
+	now.Advance(time.Hour - time.Second)
+	if _, err := manager.ValidateToken(token); err != nil {
+		t.Errorf("Expected the token to be valid a second before expiry, got %v", err)
+	}
+	now.Advance(time.Second)
+	if _, err := manager.ValidateToken(token); err != ErrTokenExpired {
+		t.Errorf("Expected ErrTokenExpired at expiry, got %v", err)
+	}
+}
+
 func TestJWTManager_ValidateToken_WrongSecret(t *testing.T) {
 	manager1 := NewJWTManager("secret1", time.Hour)
 	manager2 := NewJWTManager("secret2", time.Hour)
@@ -132,3 +161,96 @@ func TestJWTManager_ValidateToken_WrongSecret(t *testing.T) {
 		t.Errorf("Expected ErrInvalidToken, got %v", err)
 	}
 }
+
+func TestJWTManager_GenerateScopedToken(t *testing.T) {
+	manager := NewJWTManager("test-secret", time.Hour)
+	userID := uuid.New()
+	scope := FieldScope(uuid.New(), "password")
+
+	token, err := manager.GenerateScopedToken(userID, "testuser", scope, "", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateScopedToken() error = %v", err)
+	}
+
+	claims, err := manager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.Scope != scope {
+		t.Errorf("Expected scope %s, got %s", scope, claims.Scope)
+	}
+	if claims.UserID != userID {
+		t.Errorf("Expected user ID %s, got %s", userID, claims.UserID)
+	}
+
+	if _, err := manager.GenerateScopedToken(userID, "testuser", "", "", time.Minute); err == nil {
+		t.Error("Expected error for empty scope")
+	}
+}
+
+// fakeSessions is a Sessions keeping the started sessions in a map
+type fakeSessions map[uuid.UUID]string
+
+func (s fakeSessions) Start(ctx context.Context, userID uuid.UUID, device, userAgent string, expiresAt time.Time) (uuid.UUID, error) {
+	id := uuid.New()
+	s[id] = device
+	return id, nil
+}
+
+func (s fakeSessions) Check(ctx context.Context, userID, sessionID uuid.UUID, scoped bool) error {
+	if _, ok := s[sessionID]; !ok {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+func TestJWTManager_IssueToken(t *testing.T) {
+	ctx := context.Background()
+	manager := NewJWTManager("test-secret", time.Hour)
+	userID := uuid.New()
+
+	token, err := manager.IssueToken(ctx, userID, "testuser", "laptop", "agent")
+	if err != nil {
+		t.Fatalf("IssueToken() error = %v", err)
+	}
+	claims, err := manager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.ID != "" || manager.CheckSession(ctx, claims) != nil {
+		t.Errorf("Expected a token without a session when sessions are not tracked, got ID %q", claims.ID)
+	}
+
+	sessions := fakeSessions{}
+	manager.SetSessions(sessions)
+	token, err = manager.IssueToken(ctx, userID, "testuser", "laptop", "agent")
+	if err != nil {
+		t.Fatalf("IssueToken() error = %v", err)
+	}
+	claims, err = manager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	sessionID, err := uuid.Parse(claims.ID)
+	if err != nil || sessions[sessionID] != "laptop" {
+		t.Fatalf("Expected the token ID to be the started session, got %q", claims.ID)
+	}
+	if err := manager.CheckSession(ctx, claims); err != nil {
+		t.Errorf("CheckSession() error = %v", err)
+	}
+
+	delete(sessions, sessionID)
+	if err := manager.CheckSession(ctx, claims); err != ErrSessionRevoked {
+		t.Errorf("CheckSession() error = %v, want %v", err, ErrSessionRevoked)
+	}
+
+	scoped, _ := manager.GenerateScopedToken(userID, "testuser", FieldScope(uuid.New(), "password"), "", time.Minute)
+	if claims, _ := manager.ValidateToken(scoped); manager.CheckSession(ctx, claims) != nil {
+		t.Error("Expected scoped tokens to pass without a session")
+	}
+
+	scoped, _ = manager.GenerateScopedToken(userID, "testuser", FieldScope(uuid.New(), "password"), sessionID.String(), time.Hour)
+	if claims, _ := manager.ValidateToken(scoped); manager.CheckSession(ctx, claims) != ErrSessionRevoked {
+		t.Error("Expected scoped tokens of a revoked session to be revoked too")
+	}
+}
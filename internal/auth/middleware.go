@@ -1,36 +1,42 @@
 package auth
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 	"strings"
 
-	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
 	"github.com/urfave/negroni"
-	"go.uber.org/zap"
 )
 
-// AuthMiddleware creates authentication middleware
+// AuthMiddleware creates authentication middleware that only accepts full account tokens
 func AuthMiddleware(jwtManager *JWTManager) negroni.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
-			return
-		}
+	return authenticate(jwtManager, false)
+}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
-			return
-		}
+// ScopedAuthMiddleware creates authentication middleware that also accepts scoped tokens.
+// The token scope is passed to handlers in the X-Token-Scope header, and the
+// session of a full account token in X-Session-ID.
+func ScopedAuthMiddleware(jwtManager *JWTManager) negroni.HandlerFunc {
+	return authenticate(jwtManager, true)
+}
 
-		token := parts[1]
-		claims, err := jwtManager.ValidateToken(token)
+func authenticate(jwtManager *JWTManager, allowScoped bool) negroni.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		r.Header.Del("X-Token-Scope")
+		r.Header.Del("X-Session-ID")
+
+		claims, err := Authenticate(r.Context(), jwtManager, r.Header.Get("Authorization"), allowScoped)
 		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			apierror.Reply(w, err)
 			return
 		}
+		if claims.ID != "" && claims.Scope == "" {
+			r.Header.Set("X-Session-ID", claims.ID)
+		}
+		if claims.Scope != "" {
+			r.Header.Set("X-Token-Scope", claims.Scope)
+		}
 
 		r.Header.Set("X-User-ID", claims.UserID.String())
 		r.Header.Set("X-Username", claims.Username)
@@ -39,16 +45,36 @@ func AuthMiddleware(jwtManager *JWTManager) negroni.HandlerFunc {
 	}
 }
 
-// ErrorResponse represents error response
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
+// Authenticate returns the claims of the bearer token in authorization, the
+// value of an Authorization header, once its session is checked. A request
+// it refuses gets the problem to reply with.
+func Authenticate(ctx context.Context, jwtManager *JWTManager, authorization string, allowScoped bool) (*Claims, error) {
+	if authorization == "" {
+		return nil, apierror.New("Authorization header required", http.StatusUnauthorized)
+	}
+
+	parts := strings.Split(authorization, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, apierror.New("Invalid authorization header format", http.StatusUnauthorized)
+	}
+
+	claims, err := jwtManager.ValidateToken(parts[1])
+	if err != nil {
+		return nil, apierror.New("Invalid token", http.StatusUnauthorized)
+	}
+
+	switch err := jwtManager.CheckSession(ctx, claims); err {
+	case nil:
+	case ErrSessionRevoked:
+		return nil, apierror.New("Session revoked", http.StatusUnauthorized)
+	case ErrInvalidToken:
+		return nil, apierror.New("Invalid token", http.StatusUnauthorized)
+	default:
+		return nil, apierror.New("Failed to check session", http.StatusInternalServerError)
+	}
 
-// writeError writes error to response
-func writeError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(ErrorResponse{Error: message}); err != nil {
-		logger.Log.Error("Failed to encode data", zap.Error(err))
+	if claims.Scope != "" && !allowScoped {
+		return nil, apierror.New("Token scope does not allow this request", http.StatusForbidden)
 	}
+	return claims, nil
 }
@@ -94,41 +94,60 @@ func TestAuthMiddleware(t *testing.T) {
 	}
 }
 
-func TestWriteError(t *testing.T) {
+func TestAuthMiddleware_ScopedTokens(t *testing.T) {
+	jwtManager := NewJWTManager("test-secret", time.Hour)
+	userID := uuid.New()
+	scope := FieldScope(uuid.New(), "password")
+
+	fullToken, err := jwtManager.GenerateToken(userID, "testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	scopedToken, err := jwtManager.GenerateScopedToken(userID, "testuser", scope, "", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to generate scoped token: %v", err)
+	}
+
 	tests := []struct {
-		name    string
-		status  int
-		message string
+		name           string
+		scoped         bool
+		token          string
+		spoofScope     string
+		expectedStatus int
+		expectedScope  string
 	}{
-		{
-			name:    "unauthorized error",
-			status:  http.StatusUnauthorized,
-			message: "Unauthorized",
-		},
-		{
-			name:    "forbidden error",
-			status:  http.StatusForbidden,
-			message: "Forbidden",
-		},
-		{
-			name:    "internal server error",
-			status:  http.StatusInternalServerError,
-			message: "Internal Server Error",
-		},
+		{name: "full token on full route", token: fullToken, expectedStatus: http.StatusOK},
+		{name: "scoped token on full route", token: scopedToken, expectedStatus: http.StatusForbidden},
+		{name: "scoped token on scoped route", scoped: true, token: scopedToken, expectedStatus: http.StatusOK, expectedScope: scope},
+		{name: "full token on scoped route", scoped: true, token: fullToken, expectedStatus: http.StatusOK},
+		{name: "spoofed scope header is dropped", scoped: true, token: fullToken, spoofScope: "anything", expectedStatus: http.StatusOK},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			writeError(w, tt.status, tt.message)
+			middleware := AuthMiddleware(jwtManager)
+			if tt.scoped {
+				middleware = ScopedAuthMiddleware(jwtManager)
+			}
 
-			if w.Code != tt.status {
-				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("X-Token-Scope"); got != tt.expectedScope {
+					t.Errorf("Expected X-Token-Scope %q, got %q", tt.expectedScope, got)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			if tt.spoofScope != "" {
+				req.Header.Set("X-Token-Scope", tt.spoofScope)
 			}
 
-			contentType := w.Header().Get("Content-Type")
-			if contentType != "application/json" {
-				t.Errorf("Expected Content-Type application/json, got %s", contentType)
+			w := httptest.NewRecorder()
+			middleware(w, req, handler.ServeHTTP)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
 			}
 		})
 	}
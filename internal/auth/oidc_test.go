@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeIdP is an identity provider issuing ID tokens signed with one RSA key
+type fakeIdP struct {
+	server     *httptest.Server
+	key        *rsa.PrivateKey
+	kid        string
+	idToken    string
+	form       url.Values
+	keyFetches int
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	idp := &fakeIdP{key: key, kid: "key-1"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		idp.keyFetches++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": idp.kid, "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		idp.form = r.PostForm
+		if r.PostForm.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *fakeIdP) sign(t *testing.T, kid string, claims OIDCClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func TestOIDCProvider_Verify(t *testing.T) {
+	ctx := context.Background()
+	idp := newFakeIdP(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	provider, err := NewOIDCProvider(ctx, OIDCConfig{Issuer: idp.server.URL + "/", ClientID: "gophkeeper",
+		RedirectURL: "https://vault.example.com/api/v1/oidc/callback", Scopes: []string{"email"}}, idp.server.Client())
+	if err != nil {
+		t.Fatalf("NewOIDCProvider() error = %v", err)
+	}
+	provider.SetClock(clock.NewManual(now))
+
+	valid := func() OIDCClaims {
+		return OIDCClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    idp.server.URL,
+				Subject:   "user-42",
+				Audience:  jwt.ClaimStrings{"gophkeeper"},
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+			},
+			Nonce:             "nonce-1",
+			PreferredUsername: "alice",
+		}
+	}
+	tests := []struct {
+		name    string
+		kid     string
+		claims  func() OIDCClaims
+		nonce   string
+		wantErr bool
+	}{
+		{name: "valid", kid: "key-1", claims: valid, nonce: "nonce-1"},
+		{name: "wrong nonce", kid: "key-1", claims: valid, nonce: "nonce-2", wantErr: true},
+		{name: "wrong audience", kid: "key-1", nonce: "nonce-1", wantErr: true, claims: func() OIDCClaims {
+			c := valid()
+			c.Audience = jwt.ClaimStrings{"another-app"}
+			return c
+		}},
+		{name: "other issuer", kid: "key-1", nonce: "nonce-1", wantErr: true, claims: func() OIDCClaims {
+			c := valid()
+			c.Issuer = "https://evil.example.com"
+			return c
+		}},
+		{name: "expired", kid: "key-1", nonce: "nonce-1", wantErr: true, claims: func() OIDCClaims {
+			c := valid()
+			c.ExpiresAt = jwt.NewNumericDate(now.Add(-2 * time.Minute))
+			return c
+		}},
+		{name: "no expiry", kid: "key-1", nonce: "nonce-1", wantErr: true, claims: func() OIDCClaims {
+			c := valid()
+			c.ExpiresAt = nil
+			return c
+		}},
+		{name: "several audiences without azp", kid: "key-1", nonce: "nonce-1", wantErr: true, claims: func() OIDCClaims {
+			c := valid()
+			c.Audience = jwt.ClaimStrings{"gophkeeper", "another-app"}
+			return c
+		}},
+		{name: "unknown key", kid: "key-2", claims: valid, nonce: "nonce-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.Verify(ctx, idp.sign(t, tt.kid, tt.claims()), tt.nonce)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Errorf("Verify() error = %v, want ErrInvalidIDToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.Subject != "user-42" || claims.PreferredUsername != "alice" {
+				t.Errorf("Unexpected claims %+v", claims)
+			}
+		})
+	}
+	if idp.keyFetches != 1 {
+		t.Errorf("Expected the key set to be fetched once within a minute, got %d", idp.keyFetches)
+	}
+
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte(""))
+	if _, err := provider.Verify(ctx, hmacToken, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("Expected symmetric tokens to be refused, got %v", err)
+	}
+}
+
+func TestOIDCProvider_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	idp := newFakeIdP(t)
+	provider, err := NewOIDCProvider(ctx, OIDCConfig{Issuer: idp.server.URL, ClientID: "gophkeeper",
+		RedirectURL: "https://vault.example.com/api/v1/oidc/callback"}, idp.server.Client())
+	if err != nil {
+		t.Fatalf("NewOIDCProvider() error = %v", err)
+	}
+
+	authURL, err := url.Parse(provider.AuthCodeURL("state-1", "nonce-1", "verifier-1"))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() is not a URL: %v", err)
+	}
+	query := authURL.Query()
+	challenge := sha256.Sum256([]byte("verifier-1"))
+	if query.Get("state") != "state-1" || query.Get("nonce") != "nonce-1" || query.Get("scope") != "openid" ||
+		query.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(challenge[:]) || query.Get("code_challenge_method") != "S256" {
+		t.Errorf("Unexpected authorization URL %s", authURL)
+	}
+
+	now := time.Now()
+	idp.idToken = idp.sign(t, "key-1", OIDCClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Issuer: idp.server.URL, Subject: "user-42", Audience: jwt.ClaimStrings{"gophkeeper"},
+			IssuedAt: jwt.NewNumericDate(now), ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute))},
+		Nonce: "nonce-1",
+	})
+	claims, err := provider.Authenticate(ctx, "good-code", "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if claims.Subject != "user-42" {
+		t.Errorf("Expected subject user-42, got %q", claims.Subject)
+	}
+	if idp.form.Get("code_verifier") != "verifier-1" || idp.form.Get("client_id") != "gophkeeper" {
+		t.Errorf("Expected the verifier and client ID of a public client to be sent, got %v", idp.form)
+	}
+
+	if _, err := provider.Authenticate(ctx, "bad-code", "verifier-1", "nonce-1"); err == nil {
+		t.Error("Expected a refused code to fail")
+	}
+}
+
+func TestNewOIDCProvider_IssuerMismatch(t *testing.T) {
+	idp := newFakeIdP(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, idp.server.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer server.Close()
+
+	if _, err := NewOIDCProvider(context.Background(), OIDCConfig{Issuer: server.URL, ClientID: "gophkeeper"}, server.Client()); err == nil {
+		t.Error("Expected a discovery document of another issuer to be refused")
+	}
+}
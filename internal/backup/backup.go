@@ -0,0 +1,351 @@
+// Package backup dumps the database of the server to compressed and
+// optionally encrypted backups in a directory or an S3-compatible bucket,
+// keeps the newest of them and restores them.
+//
+// A backup is a gzip stream of JSON values, one per line: a header, a record
+// for every row in restore order and an end record with the number of rows.
+// Encrypted backups wrap that stream in an encrypted stream of the crypto
+// package. Item data is already encrypted by clients and, with encryption at
+// rest, sealed again by the server; it is backed up as stored, so restoring
+// sealed items needs the same at-rest key.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/a2sh3r/gophkeeper/pkg/version"
+	"go.uber.org/zap"
+)
+
+// Format identifies backups in their header
+const Format = "gophkeeper-backup"
+
+// FormatVersion is the version of the backup format written
+const FormatVersion = 1
+
+// Backup names are the prefix, the UTC time taken and an extension telling
+// whether the backup is encrypted, so they sort by age
+const (
+	namePrefix    = "gophkeeper-"
+	nameTime      = "20060102T150405Z"
+	extCompressed = ".jsonl.gz"
+	extEncrypted  = ".jsonl.gz.enc"
+)
+
+// Header starts every backup
+type Header struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SchemaVersion int       `json:"schema_version"`
+	ServerVersion string    `json:"server_version,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// record is a row of a table or, with End set, the end of a backup
+type record struct {
+	Table string          `json:"table,omitempty"`
+	Row   json.RawMessage `json:"row,omitempty"`
+	End   bool            `json:"end,omitempty"`
+	Rows  int             `json:"rows,omitempty"`
+}
+
+// Write dumps every row of dumper to w as a backup taken at now, encrypted
+// with key unless it is nil, and returns the number of rows
+func Write(ctx context.Context, w io.Writer, dumper storage.Dumper, key []byte, now time.Time) (int, error) {
+	var encrypted io.WriteCloser
+	if key != nil {
+		cm, err := streamCrypto(key)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := w.Write(encryptedMagic); err != nil {
+			return 0, fmt.Errorf("failed to write backup: %w", err)
+		}
+		if encrypted, err = cm.EncryptWriter(w); err != nil {
+			return 0, err
+		}
+		w = encrypted
+	}
+	compressed := gzip.NewWriter(w)
+	encoder := json.NewEncoder(compressed)
+
+	err := encoder.Encode(Header{
+		Format:        Format,
+		Version:       FormatVersion,
+		SchemaVersion: storage.SchemaVersion,
+		ServerVersion: version.ShortInfo(),
+		CreatedAt:     now.UTC(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to write backup: %w", err)
+	}
+	rows := 0
+	err = dumper.Dump(ctx, func(table string, row json.RawMessage) error {
+		rows++
+		return encoder.Encode(record{Table: table, Row: row})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to dump database: %w", err)
+	}
+	if err := encoder.Encode(record{End: true, Rows: rows}); err != nil {
+		return 0, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	if err := compressed.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if encrypted != nil {
+		if err := encrypted.Close(); err != nil {
+			return 0, fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	return rows, nil
+}
+
+// Read restores the backup in r into dumper, decrypting it with key if it is
+// encrypted, and returns its header and number of rows. The backup must be of
+// the schema version of this build. Rows are only kept if the whole backup
+// is read; see storage.Dumper for replace.
+func Read(ctx context.Context, r io.Reader, dumper storage.Dumper, key []byte, replace bool) (*Header, int, error) {
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(len(encryptedMagic)); bytes.Equal(magic, encryptedMagic) {
+		if key == nil {
+			return nil, 0, ErrKeyRequired
+		}
+		cm, err := streamCrypto(key)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err := buffered.Discard(len(encryptedMagic)); err != nil {
+			return nil, 0, err
+		}
+		decrypted, err := cm.DecryptReader(buffered)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decrypt backup, is the key right? %w", err)
+		}
+		r = decrypted
+	} else {
+		r = buffered
+	}
+	decompressed, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("not a backup: %w", err)
+	}
+	decoder := json.NewDecoder(decompressed)
+
+	var header Header
+	if err := decoder.Decode(&header); err != nil || header.Format != Format {
+		return nil, 0, fmt.Errorf("not a backup")
+	}
+	if header.Version != FormatVersion {
+		return nil, 0, fmt.Errorf("unsupported backup format version %d", header.Version)
+	}
+	if header.SchemaVersion != storage.SchemaVersion {
+		return nil, 0, fmt.Errorf("backup is of schema version %d, this server needs %d; restore it with the release that took it",
+			header.SchemaVersion, storage.SchemaVersion)
+	}
+
+	rows := 0
+	err = dumper.Restore(ctx, replace, func(insert func(table string, row json.RawMessage) error) error {
+		for {
+			var rec record
+			if err := decoder.Decode(&rec); err != nil {
+				if errors.Is(err, io.EOF) {
+					return fmt.Errorf("backup is incomplete after %d rows", rows)
+				}
+				return fmt.Errorf("invalid backup: %w", err)
+			}
+			if rec.End {
+				if rec.Rows != rows {
+					return fmt.Errorf("backup holds %d rows, expected %d", rows, rec.Rows)
+				}
+				return nil
+			}
+			if err := insert(rec.Table, rec.Row); err != nil {
+				return err
+			}
+			rows++
+		}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return &header, rows, nil
+}
+
+// Name returns the name of a backup taken at t
+func Name(t time.Time, encrypted bool) string {
+	name := namePrefix + t.UTC().Format(nameTime)
+	if encrypted {
+		return name + extEncrypted
+	}
+	return name + extCompressed
+}
+
+// nameTimeOf returns when the backup of name was taken
+func nameTimeOf(name string) (time.Time, bool) {
+	stamp := strings.TrimPrefix(name, namePrefix)
+	stamp, _, _ = strings.Cut(stamp, ".")
+	t, err := time.Parse(nameTime, stamp)
+	return t, err == nil
+}
+
+func isBackupName(name string) bool {
+	if !strings.HasSuffix(name, extCompressed) && !strings.HasSuffix(name, extEncrypted) {
+		return false
+	}
+	_, ok := nameTimeOf(name)
+	return ok && strings.HasPrefix(name, namePrefix)
+}
+
+// Options configure a Manager
+type Options struct {
+	// Key encrypts backups; they are only compressed if it is nil
+	Key []byte
+	// Retention is how many of the newest backups are kept; 0 keeps all
+	Retention int
+}
+
+// Manager takes backups of a storage to a target and restores them
+type Manager struct {
+	dumper storage.Dumper
+	target Target
+	opts   Options
+	clock  clock.Clock
+}
+
+// NewManager creates a manager backing up dumper to target
+func NewManager(dumper storage.Dumper, target Target, opts Options) *Manager {
+	return &Manager{dumper: dumper, target: target, opts: opts, clock: clock.System{}}
+}
+
+// List returns the names of the stored backups, oldest first
+func (m *Manager) List(ctx context.Context) ([]string, error) {
+	names, err := m.target.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Backup takes a backup, stores it and deletes the backups beyond the
+// retention, and returns its name. The backup is written to a temporary
+// file first, as object storage needs to know its size.
+func (m *Manager) Backup(ctx context.Context) (string, error) {
+	tmp, err := os.CreateTemp("", "gophkeeper-backup-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	now := m.clock.Now()
+	rows, err := Write(ctx, tmp, m.dumper, m.opts.Key, now)
+	if err != nil {
+		return "", err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	name := Name(now, m.opts.Key != nil)
+	if err := m.target.Put(ctx, name, tmp, size); err != nil {
+		return "", fmt.Errorf("failed to store backup: %w", err)
+	}
+	logger.Log.Info("Backup taken", zap.String("name", name), zap.Int("rows", rows), zap.Int64("bytes", size))
+
+	if err := m.prune(ctx); err != nil {
+		logger.Log.Warn("Failed to delete old backups", zap.Error(err))
+	}
+	return name, nil
+}
+
+// prune deletes the oldest backups beyond the retention
+func (m *Manager) prune(ctx context.Context) error {
+	if m.opts.Retention <= 0 {
+		return nil
+	}
+	names, err := m.List(ctx)
+	if err != nil {
+		return err
+	}
+	for len(names) > m.opts.Retention {
+		if err := m.target.Delete(ctx, names[0]); err != nil {
+			return err
+		}
+		logger.Log.Info("Old backup deleted", zap.String("name", names[0]))
+		names = names[1:]
+	}
+	return nil
+}
+
+// Restore restores the stored backup of name, or the newest one for latest
+func (m *Manager) Restore(ctx context.Context, name string, replace bool) (*Header, int, error) {
+	if name == "latest" {
+		names, err := m.List(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(names) == 0 {
+			return nil, 0, ErrNotFound
+		}
+		name = names[len(names)-1]
+	}
+	body, err := m.target.Open(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	return Read(ctx, body, m.dumper, m.opts.Key, replace)
+}
+
+// Run takes a backup every interval until ctx is done. The first one is due
+// an interval after the newest stored backup, so restarts do not delay
+// backups nor take extra ones.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	next := m.clock.Now()
+	if names, err := m.List(ctx); err != nil {
+		logger.Log.Warn("Failed to list backups", zap.Error(err))
+	} else if len(names) > 0 {
+		if last, ok := nameTimeOf(names[len(names)-1]); ok {
+			next = last.Add(interval)
+		}
+	}
+
+	timer := time.NewTimer(next.Sub(m.clock.Now()))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		if _, err := m.Backup(ctx); err != nil {
+			logger.Log.Error("Scheduled backup failed", zap.Error(err))
+		}
+		timer.Reset(interval)
+	}
+}
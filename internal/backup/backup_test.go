@@ -0,0 +1,250 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+)
+
+// fakeDumper holds rows as a database would, in restore order
+type fakeDumper struct {
+	rows []string
+}
+
+func (f *fakeDumper) Dump(_ context.Context, fn func(table string, row json.RawMessage) error) error {
+	for _, row := range f.rows {
+		table, value, _ := strings.Cut(row, " ")
+		if err := fn(table, json.RawMessage(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeDumper) Restore(_ context.Context, replace bool, read func(insert func(table string, row json.RawMessage) error) error) error {
+	if len(f.rows) > 0 && !replace {
+		return storage.ErrNotEmpty
+	}
+	var rows []string
+	err := read(func(table string, row json.RawMessage) error {
+		rows = append(rows, table+" "+string(row))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	f.rows = rows
+	return nil
+}
+
+// testRows returns a user and n items of random, incompressible data
+func testRows(n int) []string {
+	rows := []string{`users {"id":"u1","username":"alice"}`}
+	for i := 0; i < n; i++ {
+		data := make([]byte, 64)
+		_, _ = rand.Read(data)
+		rows = append(rows, fmt.Sprintf(`data {"id":"d%d","user_id":"u1","data":"\\x%x"}`, i, data))
+	}
+	return rows
+}
+
+func TestWriteRead(t *testing.T) {
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{7}, 32)
+	source := &fakeDumper{rows: testRows(3000)}
+
+	for _, tt := range []struct {
+		name string
+		key  []byte
+	}{
+		{name: "compressed"},
+		{name: "encrypted", key: key},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			rows, err := Write(ctx, &buf, source, tt.key, time.Now())
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if rows != len(source.rows) {
+				t.Errorf("Write() = %d rows, want %d", rows, len(source.rows))
+			}
+			if tt.key != nil && bytes.Contains(buf.Bytes(), []byte("alice")) {
+				t.Error("Encrypted backup holds plaintext")
+			}
+
+			restored := &fakeDumper{}
+			header, rows, err := Read(ctx, bytes.NewReader(buf.Bytes()), restored, tt.key, false)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if header.SchemaVersion != storage.SchemaVersion || rows != len(source.rows) {
+				t.Errorf("Read() = schema %d, %d rows", header.SchemaVersion, rows)
+			}
+			if strings.Join(restored.rows, "\n") != strings.Join(source.rows, "\n") {
+				t.Error("Restored rows differ from the dumped ones")
+			}
+		})
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{7}, 32)
+	source := &fakeDumper{rows: testRows(3000)}
+	var encrypted, compressed bytes.Buffer
+	if _, err := Write(ctx, &encrypted, source, key, time.Now()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := Write(ctx, &compressed, source, nil, time.Now()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	tampered := append([]byte(nil), encrypted.Bytes()...)
+	tampered[len(tampered)/2] ^= 1
+	// the stream header is as long as that of an empty stream, less its tag
+	cm, err := streamCrypto(key)
+	if err != nil {
+		t.Fatalf("streamCrypto() error = %v", err)
+	}
+	var empty bytes.Buffer
+	if _, err := cm.EncryptStream(&empty, bytes.NewReader(nil)); err != nil {
+		t.Fatalf("EncryptStream() error = %v", err)
+	}
+	header := len(encryptedMagic) + empty.Len() - 16
+
+	tests := []struct {
+		name   string
+		backup []byte
+		key    []byte
+	}{
+		{name: "no key", backup: encrypted.Bytes()},
+		{name: "wrong key", backup: encrypted.Bytes(), key: bytes.Repeat([]byte{8}, 32)},
+		{name: "tampered", backup: tampered, key: key},
+		{name: "truncated encrypted", backup: encrypted.Bytes()[:encrypted.Len()-100], key: key},
+		// cut after a whole chunk, which only the nonce of the last tells apart
+		{name: "chunk dropped", backup: encrypted.Bytes()[:header+crypto.StreamChunkSize+16], key: key},
+		{name: "truncated compressed", backup: compressed.Bytes()[:compressed.Len()/2]},
+		{name: "not a backup", backup: []byte(`{"users":[]}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restored := &fakeDumper{}
+			if _, _, err := Read(ctx, bytes.NewReader(tt.backup), restored, tt.key, false); err == nil {
+				t.Fatal("Read() succeeded, want an error")
+			}
+			if len(restored.rows) != 0 {
+				t.Errorf("Read() kept %d rows of a failed restore", len(restored.rows))
+			}
+		})
+	}
+
+	if _, _, err := Read(ctx, bytes.NewReader(encrypted.Bytes()), &fakeDumper{}, nil, false); !errors.Is(err, ErrKeyRequired) {
+		t.Errorf("Read() without a key error = %v, want ErrKeyRequired", err)
+	}
+	if _, _, err := Read(ctx, bytes.NewReader(compressed.Bytes()), source, nil, false); !errors.Is(err, storage.ErrNotEmpty) {
+		t.Errorf("Read() into a database with rows error = %v, want ErrNotEmpty", err)
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	target, err := NewDirTarget(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirTarget() error = %v", err)
+	}
+	source := &fakeDumper{rows: testRows(10)}
+	manager := NewManager(source, target, Options{Key: bytes.Repeat([]byte{7}, 32), Retention: 2})
+	manual := clock.NewManual(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	manager.clock = manual
+
+	var taken []string
+	for i := 0; i < 3; i++ {
+		name, err := manager.Backup(ctx)
+		if err != nil {
+			t.Fatalf("Backup() error = %v", err)
+		}
+		taken = append(taken, name)
+		source.rows = append(source.rows, fmt.Sprintf(`data {"id":"new%d"}`, i))
+		manual.Advance(time.Hour)
+	}
+	if taken[0] != "gophkeeper-20240501T120000Z.jsonl.gz.enc" {
+		t.Errorf("Unexpected backup name %s", taken[0])
+	}
+
+	names, err := manager.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(names) != 2 || names[0] != taken[1] || names[1] != taken[2] {
+		t.Fatalf("List() = %v, want the 2 newest backups", names)
+	}
+
+	restored := NewManager(&fakeDumper{}, target, Options{Key: bytes.Repeat([]byte{7}, 32)})
+	_, rows, err := restored.Restore(ctx, "latest", false)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if rows != 13 {
+		t.Errorf("Restore(latest) = %d rows, want 13", rows)
+	}
+	if _, _, err := restored.Restore(ctx, taken[1], true); err != nil {
+		t.Fatalf("Restore() with replace error = %v", err)
+	}
+	if _, _, err := restored.Restore(ctx, taken[0], true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Restore() of a pruned backup error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestManager_Run(t *testing.T) {
+	target, err := NewDirTarget(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirTarget() error = %v", err)
+	}
+	manager := NewManager(&fakeDumper{rows: testRows(1)}, target, Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	// without backups the first one is due at once
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		names, _ := manager.List(context.Background())
+		if len(names) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Run() took no backup")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestIsBackupName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"gophkeeper-20240501T120000Z.jsonl.gz":     true,
+		"gophkeeper-20240501T120000Z.jsonl.gz.enc": true,
+		"gophkeeper-latest.jsonl.gz":               false,
+		"notes-20240501T120000Z.jsonl.gz":          false,
+		".partial-123":                             false,
+	} {
+		if got := isBackupName(name); got != want {
+			t.Errorf("isBackupName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+)
+
+// encryptedMagic starts encrypted backups, followed by an encrypted stream of
+// the crypto package; compressed ones start with the gzip magic instead
+var encryptedMagic = []byte("GKBAKENC")
+
+// backupSalt stands in for the salt in the stream header, as backup keys are
+// not derived from a password
+var backupSalt = make([]byte, 32)
+
+// ErrKeyRequired is returned when opening an encrypted backup without a key
+var ErrKeyRequired = errors.New("backup is encrypted, a backup key is required")
+
+// streamCrypto returns the crypto manager encrypting the streams of backups
+// with key
+func streamCrypto(key []byte) (*crypto.CryptoManager, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("backup key must be 32 bytes, got %d", len(key))
+	}
+	return crypto.NewCryptoManagerWithKey(key, backupSalt)
+}
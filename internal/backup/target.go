@@ -0,0 +1,140 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/s3"
+)
+
+// ErrNotFound is returned for backups that do not exist
+var ErrNotFound = errors.New("backup not found")
+
+// Target stores backups by name
+type Target interface {
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names of the stored backups
+	List(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// DirTarget stores backups as files of a local directory, which may be a
+// mounted network share
+type DirTarget struct {
+	dir string
+}
+
+// NewDirTarget creates a target storing backups in dir, creating it if needed
+func NewDirTarget(dir string) (*DirTarget, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return &DirTarget{dir: dir}, nil
+}
+
+// Put writes a backup to a temporary file and renames it into place, so a
+// failed write never leaves a partial backup under the name
+func (d *DirTarget) Put(_ context.Context, name string, r io.Reader, _ int64) error {
+	tmp, err := os.CreateTemp(d.dir, ".partial-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(d.dir, filepath.Base(name)))
+}
+
+// Open opens a backup file
+func (d *DirTarget) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(d.dir, filepath.Base(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// List returns the names of the backup files
+func (d *DirTarget) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && isBackupName(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// Delete removes a backup file
+func (d *DirTarget) Delete(_ context.Context, name string) error {
+	err := os.Remove(filepath.Join(d.dir, filepath.Base(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// S3Target stores backups as objects of an S3-compatible bucket
+type S3Target struct {
+	client *s3.Client
+	prefix string
+}
+
+// NewS3Target creates a target storing backups in the bucket of client, under
+// keys starting with prefix
+func NewS3Target(client *s3.Client, prefix string) *S3Target {
+	return &S3Target{client: client, prefix: prefix}
+}
+
+// Put uploads a backup
+func (t *S3Target) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	return t.client.Put(ctx, t.prefix+name, r, size)
+}
+
+// Open downloads a backup
+func (t *S3Target) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	body, err := t.client.Get(ctx, t.prefix+name)
+	if errors.Is(err, s3.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return body, err
+}
+
+// List returns the names of the backups under the prefix
+func (t *S3Target) List(ctx context.Context) ([]string, error) {
+	objects, err := t.client.List(ctx, t.prefix)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, object := range objects {
+		name := strings.TrimPrefix(object.Key, t.prefix)
+		if isBackupName(name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Delete removes a backup
+func (t *S3Target) Delete(ctx context.Context, name string) error {
+	return t.client.Delete(ctx, t.prefix+name)
+}
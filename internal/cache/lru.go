@@ -0,0 +1,139 @@
+// Package cache keeps recently used values in memory for a limited time.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+)
+
+// LRU holds up to a fixed number of values, evicting the least recently used
+// one to make room, and forgets values older than its TTL. It is safe for
+// concurrent use.
+type LRU[K comparable, V any] struct {
+	mutex   sync.Mutex
+	size    int
+	ttl     time.Duration
+	clock   clock.Clock
+	order   *list.List
+	entries map[K]*list.Element
+}
+
+// entry is a value of an LRU and when it expires
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// NewLRU creates a cache of at most size values, each kept for ttl or until
+// evicted. A ttl of 0 keeps values until they are evicted.
+func NewLRU[K comparable, V any](size int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		size:    size,
+		ttl:     ttl,
+		clock:   clock.System{},
+		order:   list.New(),
+		entries: make(map[K]*list.Element, size),
+	}
+}
+
+// SetClock sets the clock that values expire by. Call it before use.
+func (c *LRU[K, V]) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// Get returns the value of key and whether there is one that has not expired
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	e := element.Value.(*entry[K, V])
+	if c.ttl > 0 && !c.clock.Now().Before(e.expires) {
+		c.remove(element)
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+// Add sets the value of key, evicting the least recently used value if the
+// cache is full
+func (c *LRU[K, V]) Add(key K, value V) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.size <= 0 {
+		return
+	}
+	expires := c.clock.Now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		e := element.Value.(*entry[K, V])
+		e.value = value
+		e.expires = expires
+		c.order.MoveToFront(element)
+		return
+	}
+	for c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+	}
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+}
+
+// Remove forgets the value of key
+func (c *LRU[K, V]) Remove(key K) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// RemoveFunc forgets every value that match returns true for, and returns how
+// many it forgot
+func (c *LRU[K, V]) RemoveFunc(match func(key K, value V) bool) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	removed := 0
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		e := element.Value.(*entry[K, V])
+		if match(e.key, e.value) {
+			c.remove(element)
+			removed++
+		}
+		element = next
+	}
+	return removed
+}
+
+// Purge forgets every value
+func (c *LRU[K, V]) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[K]*list.Element, c.size)
+}
+
+// Len returns the number of values held, including expired ones not yet
+// forgotten
+func (c *LRU[K, V]) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+)
+
+func TestLRU_Evicts(t *testing.T) {
+	c := NewLRU[string, int](2, 0)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected the least recently used value to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v, want 1", v, ok)
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Get(c) = %d, %v, want 3", v, ok)
+	}
+
+	c.Add("a", 10)
+	if v, _ := c.Get("a"); v != 10 || c.Len() != 2 {
+		t.Errorf("Expected a replaced in place, got %d with %d values", v, c.Len())
+	}
+}
+
+func TestLRU_Expires(t *testing.T) {
+	clk := clock.NewManual(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := NewLRU[string, int](10, time.Minute)
+	c.SetClock(clk)
+
+	c.Add("a", 1)
+	clk.Advance(30 * time.Second)
+	c.Add("b", 2)
+	clk.Advance(30 * time.Second)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected a to expire after the TTL")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("Expected b to be kept within the TTL")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected the expired value forgotten, %d left", c.Len())
+	}
+}
+
+func TestLRU_Remove(t *testing.T) {
+	c := NewLRU[string, int](10, 0)
+	for i, key := range []string{"a", "b", "c", "d"} {
+		c.Add(key, i)
+	}
+
+	c.Remove("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected a removed")
+	}
+	if removed := c.RemoveFunc(func(_ string, v int) bool { return v%2 == 1 }); removed != 2 {
+		t.Errorf("RemoveFunc() = %d, want 2", removed)
+	}
+	if _, ok := c.Get("c"); !ok || c.Len() != 1 {
+		t.Errorf("Expected only c left, got %d values", c.Len())
+	}
+
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Expected an empty cache after Purge, got %d values", c.Len())
+	}
+}
+
+func TestLRU_Disabled(t *testing.T) {
+	c := NewLRU[string, int](0, time.Minute)
+	c.Add("a", 1)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected a cache of size 0 to hold nothing")
+	}
+}
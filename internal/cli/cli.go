@@ -0,0 +1,126 @@
+// Package cli looks up the commands of a command-line program by name, alias
+// or abbreviation, formats their help and generates shell completion scripts
+// for them.
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownCommand is returned by Lookup for a name that matches no command
+var ErrUnknownCommand = errors.New("unknown command")
+
+// Command is a command of the program
+type Command struct {
+	Name string
+	// Aliases are other names the command is run by
+	Aliases []string
+	// Usage shows the arguments that follow the name
+	Usage string
+	// Summary is a one-line description
+	Summary string
+	// Flags are the flags the command accepts, such as "--env"
+	Flags []string
+	// Args are the words completed as the first argument, e.g. subcommands
+	Args []string
+	// CommandLine marks commands that also run as arguments of the program
+	// rather than only at its prompt; only these are completed by the shell
+	CommandLine bool
+	// Run runs the command at the prompt and reports whether the program
+	// should exit; nil for commands that only run as arguments
+	Run func(ctx context.Context, args []string) bool
+}
+
+// Help returns the usage, description, aliases and flags of the command
+func (c *Command) Help() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: %s", c.Name)
+	if c.Usage != "" {
+		fmt.Fprintf(&b, " %s", c.Usage)
+	}
+	fmt.Fprintf(&b, "\n\n%s\n", c.Summary)
+	if len(c.Aliases) > 0 {
+		fmt.Fprintf(&b, "\nAliases: %s\n", strings.Join(c.Aliases, ", "))
+	}
+	if len(c.Flags) > 0 {
+		fmt.Fprintf(&b, "Flags: %s\n", strings.Join(c.Flags, " "))
+	}
+	return b.String()
+}
+
+// AmbiguousError is returned by Lookup for an abbreviation of several commands
+type AmbiguousError struct {
+	Name       string
+	Candidates []string
+}
+
+// Error implements error
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("ambiguous command %q: %s", e.Name, strings.Join(e.Candidates, ", "))
+}
+
+// Set is the commands of a program
+type Set struct {
+	commands []*Command
+	byName   map[string]*Command
+}
+
+// NewSet returns a set of commands. It panics if two commands share a name or
+// alias, which is a programming error.
+func NewSet(commands ...*Command) *Set {
+	s := &Set{commands: commands, byName: make(map[string]*Command)}
+	for _, command := range commands {
+		for _, name := range append([]string{command.Name}, command.Aliases...) {
+			if _, ok := s.byName[name]; ok {
+				panic(fmt.Sprintf("cli: duplicate command name %q", name))
+			}
+			s.byName[name] = command
+		}
+	}
+	return s
+}
+
+// Commands returns the commands in the order they were given
+func (s *Set) Commands() []*Command {
+	return s.commands
+}
+
+// Lookup returns the command with the given name or alias, or the only one
+// whose name starts with it. Aliases are not abbreviated.
+func (s *Set) Lookup(name string) (*Command, error) {
+	if command, ok := s.byName[name]; ok {
+		return command, nil
+	}
+	if name == "" {
+		return nil, ErrUnknownCommand
+	}
+
+	var matches []*Command
+	for _, command := range s.commands {
+		if strings.HasPrefix(command.Name, name) {
+			matches = append(matches, command)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, ErrUnknownCommand
+	case 1:
+		return matches[0], nil
+	default:
+		candidates := make([]string, 0, len(matches))
+		for _, command := range matches {
+			candidates = append(candidates, command.Name)
+		}
+		sort.Strings(candidates)
+		return nil, &AmbiguousError{Name: name, Candidates: candidates}
+	}
+}
+
+// WantsHelp reports whether args ask for the help of a command
+func WantsHelp(args []string) bool {
+	return len(args) == 1 && (args[0] == "--help" || args[0] == "-h")
+}
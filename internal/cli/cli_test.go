@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestSet() *Set {
+	return NewSet(
+		&Command{Name: "list", Summary: "List items", Flags: []string{"--all"}},
+		&Command{Name: "login", Usage: "<username>", Summary: "Sign in"},
+		&Command{Name: "delete", Aliases: []string{"rm"}, Usage: "<id>...", Summary: "Delete items"},
+		&Command{Name: "lock", Summary: "Lock the vault"},
+	)
+}
+
+func TestSet_Lookup(t *testing.T) {
+	set := newTestSet()
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "list", want: "list"},
+		{name: "rm", want: "delete"},
+		{name: "del", want: "delete"},
+		{name: "lis", want: "list"},
+		{name: "loc", want: "lock"},
+		{name: "r", wantErr: ErrUnknownCommand},
+		{name: "", wantErr: ErrUnknownCommand},
+		{name: "sync", wantErr: ErrUnknownCommand},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, err := set.Lookup(tt.name)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Lookup(%q) error = %v, want %v", tt.name, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || command.Name != tt.want {
+				t.Fatalf("Lookup(%q) = %v, %v, want %s", tt.name, command, err, tt.want)
+			}
+		})
+	}
+
+	_, err := set.Lookup("lo")
+	var ambiguous *AmbiguousError
+	if !errors.As(err, &ambiguous) || strings.Join(ambiguous.Candidates, ",") != "lock,login" {
+		t.Errorf("Expected lo to be ambiguous between lock and login, got %v", err)
+	}
+}
+
+func TestNewSet_DuplicateName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a duplicate name")
+		}
+	}()
+	NewSet(&Command{Name: "list"}, &Command{Name: "ls", Aliases: []string{"list"}})
+}
+
+func TestCommand_Help(t *testing.T) {
+	command, _ := newTestSet().Lookup("delete")
+	help := command.Help()
+	for _, want := range []string{"Usage: delete <id>...", "Delete items", "Aliases: rm"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("Expected help to contain %q, got:\n%s", want, help)
+		}
+	}
+}
+
+func TestWantsHelp(t *testing.T) {
+	for args, want := range map[string]bool{"--help": true, "-h": true, "": false, "3f2a --help": false} {
+		if got := WantsHelp(strings.Fields(args)); got != want {
+			t.Errorf("WantsHelp(%q) = %v, want %v", args, got, want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Shells that completion scripts are generated for
+var Shells = []string{"bash", "zsh", "fish"}
+
+// Flag is a flag of the program itself, given before the command
+type Flag struct {
+	Name  string
+	Usage string
+	// TakesValue is set for flags followed by a value, e.g. -server <url>
+	TakesValue bool
+}
+
+// WriteCompletion writes the completion script of the program for shell. It
+// completes the commands that run as arguments of the program, their flags
+// and first arguments, and the flags of the program.
+func WriteCompletion(w io.Writer, shell, program string, set *Set, flags []Flag) error {
+	var commands []*Command
+	for _, command := range set.Commands() {
+		if command.CommandLine {
+			commands = append(commands, command)
+		}
+	}
+
+	switch shell {
+	case "bash":
+		return writeBashCompletion(w, program, commands, flags)
+	case "zsh":
+		// zsh runs bash completion functions through bashcompinit
+		if _, err := fmt.Fprintf(w, "#compdef %s\n\nautoload -U +X bashcompinit && bashcompinit\n\n", program); err != nil {
+			return err
+		}
+		return writeBashCompletion(w, program, commands, flags)
+	case "fish":
+		return writeFishCompletion(w, program, commands, flags)
+	default:
+		return fmt.Errorf("unsupported shell %q (use %s)", shell, strings.Join(Shells, ", "))
+	}
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func writeBashCompletion(w io.Writer, program string, commands []*Command, flags []Flag) error {
+	function := "_" + nonIdentifier.ReplaceAllString(program, "_")
+
+	var valueFlags, globalWords, commandWords []string
+	for _, flag := range flags {
+		globalWords = append(globalWords, "-"+flag.Name)
+		if flag.TakesValue {
+			valueFlags = append(valueFlags, "-"+flag.Name)
+		}
+	}
+	for _, command := range commands {
+		commandWords = append(commandWords, command.Name)
+		commandWords = append(commandWords, command.Aliases...)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# bash completion for %s\n", program)
+	fmt.Fprintf(&b, "%s() {\n", function)
+	b.WriteString("    local cur=${COMP_WORDS[COMP_CWORD]} command=\"\" args=0 i words\n")
+	b.WriteString("    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	b.WriteString("        case ${COMP_WORDS[i]} in\n")
+	if len(valueFlags) > 0 {
+		fmt.Fprintf(&b, "        %s) [[ -z $command ]] && ((i++)) ;;\n", strings.Join(valueFlags, "|"))
+	}
+	b.WriteString("        -*) ;;\n")
+	b.WriteString("        *) if [[ -z $command ]]; then command=${COMP_WORDS[i]}; else ((args++)); fi ;;\n")
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n")
+	b.WriteString("    case $command in\n")
+	fmt.Fprintf(&b, "    \"\") words=%q ;;\n", strings.Join(append(commandWords, globalWords...), " "))
+	for _, command := range commands {
+		names := strings.Join(append([]string{command.Name}, command.Aliases...), "|")
+		fmt.Fprintf(&b, "    %s)\n", names)
+		fmt.Fprintf(&b, "        words=%q\n", strings.Join(command.Flags, " "))
+		if len(command.Args) > 0 {
+			fmt.Fprintf(&b, "        ((args == 0)) && words=\"$words %s\"\n", strings.Join(command.Args, " "))
+		}
+		b.WriteString("        ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("    COMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -o default -F %s %s\n", function, program)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFishCompletion(w io.Writer, program string, commands []*Command, flags []Flag) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s\n", program)
+	for _, flag := range flags {
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -o %s", program, flag.Name)
+		if flag.TakesValue {
+			b.WriteString(" -r")
+		}
+		fmt.Fprintf(&b, " -d %s\n", fishQuote(flag.Usage))
+	}
+	for _, command := range commands {
+		for _, name := range append([]string{command.Name}, command.Aliases...) {
+			fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -f -a %s -d %s\n", program, name, fishQuote(command.Summary))
+		}
+		condition := fishQuote("__fish_seen_subcommand_from " + strings.Join(append([]string{command.Name}, command.Aliases...), " "))
+		for _, flag := range command.Flags {
+			fmt.Fprintf(&b, "complete -c %s -n %s -l %s\n", program, condition, strings.TrimLeft(flag, "-"))
+		}
+		if len(command.Args) > 0 {
+			fmt.Fprintf(&b, "complete -c %s -n %s -f -a %s\n", program, condition, fishQuote(strings.Join(command.Args, " ")))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fishQuote quotes s as a single fish word
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func newCompletionSet() *Set {
+	return NewSet(
+		&Command{Name: "get", Aliases: []string{"show"}, Summary: "Show an item", Flags: []string{"--output"}, CommandLine: true},
+		&Command{Name: "create", Summary: "Create an item", Args: []string{"text", "otp"}, Flags: []string{"--name"}, CommandLine: true},
+		&Command{Name: "peek", Summary: "Prompt only"},
+	)
+}
+
+var completionFlags = []Flag{{Name: "server", Usage: "Server URL", TakesValue: true}, {Name: "demo", Usage: "Demo mode"}}
+
+func TestWriteCompletion(t *testing.T) {
+	for _, shell := range Shells {
+		t.Run(shell, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := WriteCompletion(&out, shell, "gophkeeper-client", newCompletionSet(), completionFlags); err != nil {
+				t.Fatalf("WriteCompletion() error = %v", err)
+			}
+			script := out.String()
+			for _, want := range []string{"gophkeeper-client", "get", "show", "create", "otp", "server"} {
+				if !strings.Contains(script, want) {
+					t.Errorf("Expected the script to mention %q:\n%s", want, script)
+				}
+			}
+			if strings.Contains(script, "peek") {
+				t.Errorf("Expected prompt-only commands to be left out:\n%s", script)
+			}
+		})
+	}
+
+	if err := WriteCompletion(&bytes.Buffer{}, "powershell", "gophkeeper-client", newCompletionSet(), nil); err == nil {
+		t.Error("Expected an unsupported shell to be rejected")
+	}
+}
+
+func TestWriteCompletion_Bash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not installed")
+	}
+	var script bytes.Buffer
+	if err := WriteCompletion(&script, "bash", "gophkeeper-client", newCompletionSet(), completionFlags); err != nil {
+		t.Fatalf("WriteCompletion() error = %v", err)
+	}
+
+	complete := func(line string) string {
+		t.Helper()
+		words := strings.Fields(line)
+		if strings.HasSuffix(line, " ") {
+			words = append(words, "")
+		}
+		cmd := exec.Command(bash, "--norc", "--noprofile", "-c", script.String()+`
+COMP_WORDS=(`+strings.Join(quoteAll(words), " ")+`)
+COMP_CWORD=$((${#COMP_WORDS[@]} - 1))
+_gophkeeper_client
+echo "${COMPREPLY[*]}"`)
+		cmd.Env = os.Environ()
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("bash error = %v: %s", err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	tests := map[string]string{
+		"gophkeeper-client cr":                  "create",
+		"gophkeeper-client -server http://x cr": "create",
+		"gophkeeper-client create ":             "--name text otp",
+		"gophkeeper-client create text --n":     "--name",
+		"gophkeeper-client show --o":            "--output",
+		"gophkeeper-client -se":                 "-server",
+		"gophkeeper-client create text ":        "--name",
+	}
+	for line, want := range tests {
+		if got := complete(line); got != want {
+			t.Errorf("Completing %q = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func quoteAll(words []string) []string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = "'" + word + "'"
+	}
+	return quoted
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// Exit codes of the assert command
+const (
+	AssertExitPassed = 0
+	AssertExitFailed = 1
+	AssertExitError  = 2
+)
+
+// ErrAssertionFailed is returned when an assertion does not hold
+var ErrAssertionFailed = errors.New("assertion failed")
+
+// AssertExists checks that an item with the given name exists
+func (s *ClientSession) AssertExists(ctx context.Context, name string) error {
+	_, err := s.findByName(ctx, name)
+	return err
+}
+
+// AssertFieldMatches checks that a field of the named item matches pattern,
+// without ever printing the field value
+func (s *ClientSession) AssertFieldMatches(ctx context.Context, name, field, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	item, err := s.findByName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	value, err := s.itemField(item, field)
+	if err != nil {
+		return err
+	}
+
+	if !re.MatchString(value) {
+		return fmt.Errorf("%w: field %q of %q does not match %q", ErrAssertionFailed, field, name, pattern)
+	}
+	return nil
+}
+
+// AssertCommand runs an assertion and returns the process exit code
+func (s *ClientSession) AssertCommand(ctx context.Context, args []string) int {
+	var err error
+	switch {
+	case len(args) == 2 && args[0] == "exists":
+		err = s.AssertExists(ctx, CleanQuotes(args[1]))
+	case len(args) == 5 && args[0] == "field" && args[3] == "--matches":
+		err = s.AssertFieldMatches(ctx, CleanQuotes(args[1]), args[2], CleanQuotes(args[4]))
+	default:
+		fmt.Println("Usage: assert exists <name>")
+		fmt.Println("       assert field <name> <field> --matches <regex>")
+		return AssertExitError
+	}
+
+	switch {
+	case err == nil:
+		fmt.Println("PASS")
+		return AssertExitPassed
+	case errors.Is(err, ErrAssertionFailed):
+		fmt.Printf("FAIL: %v\n", err)
+		return AssertExitFailed
+	default:
+		fmt.Printf("ERROR: %v\n", err)
+		return AssertExitError
+	}
+}
+
+// findByName returns the single item with the given name
+func (s *ClientSession) findByName(ctx context.Context, name string) (*models.Data, error) {
+	items, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []models.Data
+	for _, item := range items {
+		if CleanQuotes(item.Name) == name {
+			found = append(found, item)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("%w: no item named %q", ErrAssertionFailed, name)
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, fmt.Errorf("%d items are named %q", len(found), name)
+	}
+}
+
+// itemField extracts a field from the item or its decrypted payload
+func (s *ClientSession) itemField(item *models.Data, field string) (string, error) {
+	switch field {
+	case "name":
+		return CleanQuotes(item.Name), nil
+	case "description":
+		return CleanQuotes(item.Description), nil
+	}
+
+	decrypted, err := s.cryptoManager.Decrypt(item.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt data: %w", err)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(decrypted, &payload); err != nil {
+		return "", fmt.Errorf("item %q has no structured fields", item.Name)
+	}
+
+	value, ok := payload[field]
+	if !ok {
+		return "", fmt.Errorf("%w: item %q has no field %q", ErrAssertionFailed, item.Name, field)
+	}
+	return fmt.Sprint(value), nil
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestClientSession_AssertExists(t *testing.T) {
+	session, _, err := NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	if err := session.AssertExists(context.Background(), "Demo Email"); err != nil {
+		t.Errorf("AssertExists() error = %v", err)
+	}
+
+	err = session.AssertExists(context.Background(), "Missing")
+	if !errors.Is(err, ErrAssertionFailed) {
+		t.Errorf("AssertExists() error = %v, want ErrAssertionFailed", err)
+	}
+}
+
+func TestClientSession_AssertFieldMatches(t *testing.T) {
+	session, _, err := NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		item       string
+		field      string
+		pattern    string
+		wantFailed bool
+		wantErr    bool
+	}{
+		{name: "payload field matches", item: "Demo Visa", field: "card_number", pattern: `^4\d{15}$`},
+		{name: "item field matches", item: "Demo Email", field: "description", pattern: "webmail"},
+		{name: "payload field mismatch", item: "Demo Email", field: "url", pattern: "^http://", wantFailed: true},
+		{name: "missing field", item: "Demo Email", field: "cvv", pattern: ".*", wantFailed: true},
+		{name: "invalid pattern", item: "Demo Email", field: "login", pattern: "(", wantErr: true},
+		{name: "unstructured payload", item: "hello.txt", field: "content", pattern: ".*", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := session.AssertFieldMatches(context.Background(), tt.item, tt.field, tt.pattern)
+			if failed := errors.Is(err, ErrAssertionFailed); failed != tt.wantFailed {
+				t.Errorf("AssertFieldMatches() error = %v, wantFailed %v", err, tt.wantFailed)
+			}
+			if hasErr := err != nil && !errors.Is(err, ErrAssertionFailed); hasErr != tt.wantErr {
+				t.Errorf("AssertFieldMatches() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientSession_AssertCommand(t *testing.T) {
+	session, _, err := NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "exists passes", args: []string{"exists", "Demo Visa"}, want: AssertExitPassed},
+		{name: "exists fails", args: []string{"exists", "Nope"}, want: AssertExitFailed},
+		{name: "field passes", args: []string{"field", "Demo Visa", "cvv", "--matches", `^\d{3}$`}, want: AssertExitPassed},
+		{name: "usage error", args: []string{"field", "Demo Visa"}, want: AssertExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := session.AssertCommand(context.Background(), tt.args); got != tt.want {
+				t.Errorf("AssertCommand() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
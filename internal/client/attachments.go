@@ -0,0 +1,366 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// MaxAttachmentBytes is the largest file that can be attached to an item; the
+// encrypted file must fit the server's 16 MiB limit. Larger files are stored
+// as binary items, which are uploaded in chunks.
+const MaxAttachmentBytes = 10 << 20
+
+// ItemAttachment is an attachment of an item with its decrypted description
+type ItemAttachment struct {
+	ID        string
+	FileName  string
+	MimeType  string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// AddDataAttachment attaches an encrypted file to an item
+func (c *Client) AddDataAttachment(ctx context.Context, id string, attachment models.DataAttachmentRequest) (*models.DataAttachment, error) {
+	var resp models.DataAttachmentResponse
+	path := "/api/v1/data/" + url.PathEscape(id) + "/attachments"
+	if err := c.doJSON(ctx, http.MethodPost, path, attachment, &resp, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &resp.Attachment, nil
+}
+
+// GetDataAttachments gets the attachments of an item without their content,
+// oldest first
+func (c *Client) GetDataAttachments(ctx context.Context, id string) ([]models.DataAttachment, error) {
+	var resp models.DataAttachmentsResponse
+	path := "/api/v1/data/" + url.PathEscape(id) + "/attachments"
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Attachments, nil
+}
+
+// GetDataAttachment gets an attachment of an item with its encrypted content
+func (c *Client) GetDataAttachment(ctx context.Context, id, attachmentID string) (*models.DataAttachment, error) {
+	var resp models.DataAttachmentResponse
+	path := "/api/v1/data/" + url.PathEscape(id) + "/attachments/" + url.PathEscape(attachmentID)
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &resp.Attachment, nil
+}
+
+// DeleteDataAttachment deletes an attachment of an item
+func (c *Client) DeleteDataAttachment(ctx context.Context, id, attachmentID string) error {
+	path := "/api/v1/data/" + url.PathEscape(id) + "/attachments/" + url.PathEscape(attachmentID)
+	return c.doJSON(ctx, http.MethodDelete, path, nil, nil, http.StatusNoContent)
+}
+
+// Attach encrypts the file at path and attaches it to an item by ID or unique
+// ID prefix. The file name, type and size are encrypted separately from the
+// content, so attachments can be listed without downloading them.
+func (s *ClientSession) Attach(ctx context.Context, id, path string) (*ItemAttachment, error) {
+	if err := s.checkAttachmentSupport(ctx); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > MaxAttachmentBytes {
+		return nil, fmt.Errorf("file is larger than %d MiB, store it with 'create binary' instead", MaxAttachmentBytes>>20)
+	}
+
+	id, err = s.resolveID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	var content bytes.Buffer
+	size, err := s.cryptoManager.EncryptStream(&content, io.LimitReader(file, MaxAttachmentBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt attachment: %w", err)
+	}
+	description := models.BinaryData{
+		FileName: info.Name(),
+		MimeType: getMimeType(filepath.Ext(info.Name())),
+		Size:     size,
+	}
+	plainInfo, err := json.Marshal(description)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal attachment description: %w", err)
+	}
+
+	req := models.DataAttachmentRequest{Content: content.Bytes()}
+	if req.Info, err = s.cryptoManager.Encrypt(plainInfo); err != nil {
+		return nil, fmt.Errorf("failed to encrypt attachment: %w", err)
+	}
+
+	attachment, err := s.cli.AddDataAttachment(ctx, id, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach file: %w", err)
+	}
+	return &ItemAttachment{ID: attachment.ID.String(), FileName: description.FileName, MimeType: description.MimeType,
+		Size: description.Size, CreatedAt: attachment.CreatedAt}, nil
+}
+
+// Attachments decrypts the descriptions of the attachments of an item by ID
+// or unique ID prefix, oldest first
+func (s *ClientSession) Attachments(ctx context.Context, id string) ([]ItemAttachment, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.cli.GetDataAttachments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := make([]ItemAttachment, 0, len(encrypted))
+	for _, attachment := range encrypted {
+		plainInfo, err := s.cryptoManager.Decrypt(attachment.Info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt attachment: %w", err)
+		}
+		var description models.BinaryData
+		if err := json.Unmarshal(plainInfo, &description); err != nil {
+			return nil, fmt.Errorf("invalid attachment description: %w", err)
+		}
+		attachments = append(attachments, ItemAttachment{ID: attachment.ID.String(), FileName: description.FileName,
+			MimeType: description.MimeType, Size: description.Size, CreatedAt: attachment.CreatedAt})
+	}
+	return attachments, nil
+}
+
+// findAttachment returns the attachment of an item referred to by its ID, a
+// unique ID prefix or its file name
+func (s *ClientSession) findAttachment(ctx context.Context, id, ref string) (string, *ItemAttachment, error) {
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	attachments, err := s.Attachments(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var matches []*ItemAttachment
+	for i := range attachments {
+		if attachments[i].ID == strings.ToLower(ref) || attachments[i].FileName == ref {
+			return id, &attachments[i], nil
+		}
+		if len(ref) >= minShortIDLength && strings.HasPrefix(attachments[i].ID, strings.ToLower(ref)) {
+			matches = append(matches, &attachments[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", nil, fmt.Errorf("the item has no attachment %q", ref)
+	case 1:
+		return id, matches[0], nil
+	default:
+		return "", nil, fmt.Errorf("attachment ID prefix %q is ambiguous", ref)
+	}
+}
+
+// DownloadAttachment decrypts an attachment of an item into outputPath, or
+// into a file of the attachment's name in the current directory. The file
+// only replaces outputPath once it is complete.
+func (s *ClientSession) DownloadAttachment(ctx context.Context, id, ref, outputPath string) (*ItemAttachment, string, error) {
+	if !s.IsAuthenticated() {
+		return nil, "", ErrNotAuthenticated
+	}
+	id, attachment, err := s.findAttachment(ctx, id, ref)
+	if err != nil {
+		return nil, "", err
+	}
+	if outputPath == "" {
+		outputPath = attachmentPath(attachment.FileName)
+	}
+
+	encrypted, err := s.cli.GetDataAttachment(ctx, id, attachment.ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download attachment: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".*")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	written, err := s.decryptContent(tmp, encrypted.Content)
+	if err != nil {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": id})
+		return nil, "", fmt.Errorf("failed to decrypt attachment: %w", err)
+	}
+	if written != attachment.Size {
+		return nil, "", fmt.Errorf("attachment is %d bytes, expected %d", written, attachment.Size)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		return nil, "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), outputPath); err != nil {
+		return nil, "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	s.recordEvent(EventExport, map[string]string{"data_id": id, "attachment_id": attachment.ID, "path": outputPath})
+	return attachment, outputPath, nil
+}
+
+// attachmentPath returns the file in the current directory an attachment is
+// saved to by default. The name comes from the vault and must not pick a path
+// outside the directory.
+func attachmentPath(fileName string) string {
+	name := filepath.Base(filepath.Clean("/" + fileName))
+	if name == string(filepath.Separator) {
+		return "attachment"
+	}
+	return name
+}
+
+// RemoveAttachment deletes an attachment of an item referred to by its ID, a
+// unique ID prefix or its file name
+func (s *ClientSession) RemoveAttachment(ctx context.Context, id, ref string) (*ItemAttachment, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	id, attachment, err := s.findAttachment(ctx, id, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cli.DeleteDataAttachment(ctx, id, attachment.ID); err != nil {
+		return nil, fmt.Errorf("failed to remove attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// checkAttachmentSupport fails for servers that do not store attachments
+func (s *ClientSession) checkAttachmentSupport(ctx context.Context) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil || !hasFeature(status, "attachments") {
+		return fmt.Errorf("the server does not store attachments")
+	}
+	return nil
+}
+
+// writeAttachments lists the attachments of an item below it. Failures only
+// skip the list, e.g. with servers that predate attachments.
+func (s *ClientSession) writeAttachments(ctx context.Context, w io.Writer, id string) {
+	attachments, err := s.Attachments(ctx, id)
+	if err != nil || len(attachments) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Attachments:")
+	for _, attachment := range attachments {
+		fmt.Fprintf(w, "  %s  %s (%d bytes)\n", attachment.ID[:minShortIDLength], attachment.FileName, attachment.Size)
+	}
+}
+
+// AttachCommand attaches a file to an item
+func (s *ClientSession) AttachCommand(ctx context.Context, id, path string) error {
+	attachment, err := s.Attach(ctx, id, path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Attached %s (%d bytes) with ID: %s\n", attachment.FileName, attachment.Size, attachment.ID)
+	return nil
+}
+
+// AttachmentsCommand lists the attachments of an item
+func (s *ClientSession) AttachmentsCommand(ctx context.Context, id string) error {
+	attachments, err := s.Attachments(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(attachments) == 0 {
+		fmt.Println("The item has no attachments")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tFILE\tTYPE\tSIZE\tADDED")
+	for _, attachment := range attachments {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", attachment.ID, attachment.FileName, attachment.MimeType, attachment.Size,
+			attachment.CreatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return tw.Flush()
+}
+
+// DownloadCommand saves a decrypted attachment of an item to a file, asking
+// before replacing an existing one
+func (s *ClientSession) DownloadCommand(ctx context.Context, id, ref, outputPath string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	if outputPath == "" {
+		_, attachment, err := s.findAttachment(ctx, id, ref)
+		if err != nil {
+			return err
+		}
+		outputPath = attachmentPath(attachment.FileName)
+	}
+
+	if _, err := os.Stat(outputPath); err == nil {
+		fmt.Printf("File %s already exists. Overwrite? (y/N): ", outputPath)
+		scanner := bufio.NewScanner(os.Stdin)
+		if !scanner.Scan() {
+			return fmt.Errorf("failed to read overwrite confirmation")
+		}
+		confirmation := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if confirmation != "y" && confirmation != "yes" {
+			fmt.Println("Download cancelled")
+			return nil
+		}
+	}
+
+	attachment, path, err := s.DownloadAttachment(ctx, id, ref, outputPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved %s (%d bytes) to: %s\n", attachment.FileName, attachment.Size, path)
+	return nil
+}
+
+// DetachCommand removes an attachment from an item
+func (s *ClientSession) DetachCommand(ctx context.Context, id, ref string) error {
+	attachment, err := s.RemoveAttachment(ctx, id, ref)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed attachment %s\n", attachment.FileName)
+	return nil
+}
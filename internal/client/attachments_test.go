@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestAttachmentPath(t *testing.T) {
+	tests := map[string]string{
+		"scan.pdf":         "scan.pdf",
+		"../../etc/passwd": "passwd",
+		"/tmp/notes.txt":   "notes.txt",
+		"..":               "attachment",
+	}
+	for name, want := range tests {
+		if got := attachmentPath(name); got != want {
+			t.Errorf("attachmentPath(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestClientSession_Attachments(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	id := demoItemID(t, session, "Demo Email")
+
+	dir := t.TempDir()
+	codes := filepath.Join(dir, "recovery-codes.txt")
+	content := []byte("1234-5678\n8765-4321\n")
+	if err := os.WriteFile(codes, content, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	attached, err := session.Attach(ctx, id, codes)
+	if err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+	if attached.FileName != "recovery-codes.txt" || attached.Size != int64(len(content)) || attached.MimeType != "text/plain" {
+		t.Errorf("Unexpected attachment %+v", attached)
+	}
+
+	encrypted, err := session.cli.GetDataAttachment(ctx, id, attached.ID)
+	if err != nil {
+		t.Fatalf("GetDataAttachment() error = %v", err)
+	}
+	if bytes.Contains(encrypted.Content, content) || bytes.Contains(encrypted.Info, []byte("recovery-codes")) {
+		t.Error("Expected the attachment and its name to be stored encrypted")
+	}
+	if !crypto.IsStream(encrypted.Content) {
+		t.Error("Expected the attachment to be encrypted as a stream")
+	}
+
+	large := filepath.Join(dir, "large.bin")
+	if err := os.WriteFile(large, make([]byte, MaxAttachmentBytes+1), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := session.Attach(ctx, id, large); err == nil || !strings.Contains(err.Error(), "binary") {
+		t.Errorf("Expected files over the limit to be refused, got %v", err)
+	}
+
+	attachments, err := session.Attachments(ctx, id)
+	if err != nil {
+		t.Fatalf("Attachments() error = %v", err)
+	}
+	if len(attachments) != 1 || attachments[0].ID != attached.ID || attachments[0].FileName != "recovery-codes.txt" {
+		t.Errorf("Unexpected attachments %+v", attachments)
+	}
+
+	// by file name, ID prefix and full ID
+	for i, ref := range []string{"recovery-codes.txt", attached.ID[:8], attached.ID} {
+		output := filepath.Join(dir, "downloaded-"+string(rune('a'+i)))
+		if _, path, err := session.DownloadAttachment(ctx, id, ref, output); err != nil || path != output {
+			t.Fatalf("DownloadAttachment(%q) = %q, %v", ref, path, err)
+		}
+		if got, _ := os.ReadFile(output); !bytes.Equal(got, content) {
+			t.Errorf("DownloadAttachment(%q) wrote %q, want %q", ref, got, content)
+		}
+	}
+	if _, _, err := session.DownloadAttachment(ctx, id, "missing.txt", filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for an unknown attachment")
+	}
+
+	// attachments encrypted as one record before streams stay readable
+	var legacy models.DataAttachmentRequest
+	if legacy.Info, err = session.cryptoManager.Encrypt([]byte(`{"file_name":"legacy.txt","size":6}`)); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if legacy.Content, err = session.cryptoManager.Encrypt([]byte("legacy")); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if _, err := session.cli.AddDataAttachment(ctx, id, legacy); err != nil {
+		t.Fatalf("AddDataAttachment() error = %v", err)
+	}
+	legacyPath := filepath.Join(dir, "legacy")
+	if _, _, err := session.DownloadAttachment(ctx, id, "legacy.txt", legacyPath); err != nil {
+		t.Fatalf("DownloadAttachment() error = %v", err)
+	}
+	if got, _ := os.ReadFile(legacyPath); string(got) != "legacy" {
+		t.Errorf("DownloadAttachment() wrote %q, want %q", got, "legacy")
+	}
+	if _, err := session.RemoveAttachment(ctx, id, "legacy.txt"); err != nil {
+		t.Fatalf("RemoveAttachment() error = %v", err)
+	}
+
+	if _, err := session.RemoveAttachment(ctx, id, "recovery-codes.txt"); err != nil {
+		t.Fatalf("RemoveAttachment() error = %v", err)
+	}
+	if attachments, err := session.Attachments(ctx, id); err != nil || len(attachments) != 0 {
+		t.Errorf("Expected no attachments left, got %+v, %v", attachments, err)
+	}
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// defaultAuditEntries is the number of audit events shown by the audit command
+const defaultAuditEntries = 50
+
+// AuditEntry is an audit event with its sealed details opened
+type AuditEntry struct {
+	models.AuditEvent
+	models.AuditDetails
+	// Sealed reports whether the server recorded details for the event
+	Sealed bool
+}
+
+// SetAuditKey publishes the public key the server seals audit details to
+func (c *Client) SetAuditKey(ctx context.Context, publicKey []byte) error {
+	return c.doJSON(ctx, http.MethodPut, "/api/v1/audit/key", models.AuditKeyRequest{PublicKey: publicKey}, nil, http.StatusNoContent)
+}
+
+// GetAuditEvents gets up to limit of the latest audit events, newest first
+func (c *Client) GetAuditEvents(ctx context.Context, limit int) ([]models.AuditEvent, error) {
+	var resp models.AuditEventsResponse
+	path := "/api/v1/audit?limit=" + strconv.Itoa(limit)
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// GetAuditEventsBetween gets up to limit of the latest audit events recorded
+// at or after since and before until, newest first
+func (c *Client) GetAuditEventsBetween(ctx context.Context, since, until time.Time, limit int) ([]models.AuditEvent, error) {
+	query := url.Values{}
+	query.Set("since", since.UTC().Format(time.RFC3339Nano))
+	query.Set("until", until.UTC().Format(time.RFC3339Nano))
+	query.Set("limit", strconv.Itoa(limit))
+
+	var resp models.AuditEventsResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/audit?"+query.Encode(), nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// publishAuditKey lets the server seal item names in audit events to the vault
+// key. Servers without an audit log reject it, which only costs the names.
+func (s *ClientSession) publishAuditKey(ctx context.Context) {
+	publicKey, _, err := crypto.AuditKeyPair(s.cryptoManager.Key())
+	if err == nil {
+		err = s.cli.SetAuditKey(ctx, publicKey)
+	}
+	if err != nil {
+		logger.Log.Warn("Failed to publish audit key", zap.Error(err))
+	}
+}
+
+// AuditLog gets the latest audit events and opens their sealed details
+func (s *ClientSession) AuditLog(ctx context.Context, limit int) ([]AuditEntry, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+
+	events, err := s.cli.GetAuditEvents(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	return s.openAuditEvents(events)
+}
+
+// openAuditEvents opens the sealed details of audit events
+func (s *ClientSession) openAuditEvents(events []models.AuditEvent) ([]AuditEntry, error) {
+	_, privateKey, err := crypto.AuditKeyPair(s.cryptoManager.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]AuditEntry, 0, len(events))
+	for _, event := range events {
+		entry := AuditEntry{AuditEvent: event}
+		if len(event.Sealed) > 0 {
+			details, err := crypto.OpenAuditDetails(privateKey, event.Sealed)
+			if err != nil {
+				// sealed to the audit key of the vault key before a master password rotation
+				logger.Log.Debug("Failed to open audit details", zap.Error(err))
+				entries = append(entries, entry)
+				continue
+			}
+			if err := json.Unmarshal(details, &entry.AuditDetails); err != nil {
+				return nil, fmt.Errorf("failed to parse audit details: %w", err)
+			}
+			entry.Sealed = true
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// WriteAuditLog renders audit entries as a table; items without recorded
+// details are shown by ID, events without an item such as logins by a dash
+func WriteAuditLog(w io.Writer, entries []AuditEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No audit events")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tACTION\tITEM\tADDRESS")
+	for _, entry := range entries {
+		item := entry.ItemName
+		if !entry.Sealed && entry.DataID != nil {
+			item = entry.DataID.String()
+		}
+		if item == "" {
+			item = "-"
+		}
+		address := entry.IP
+		if address == "" {
+			address = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", entry.CreatedAt.Local().Format("2006-01-02 15:04"), entry.Action, item, address)
+	}
+	if err := tw.Flush(); err != nil {
+		logger.Log.Error("Failed to write audit log", zap.Error(err))
+	}
+}
+
+// AuditCommand shows the latest changes to the vault. Item names and addresses
+// are decrypted locally; the server only stores them sealed to the vault key.
+func (s *ClientSession) AuditCommand(ctx context.Context, limit int) error {
+	if limit <= 0 {
+		limit = defaultAuditEntries
+	}
+
+	entries, err := s.AuditLog(ctx, limit)
+	if err != nil {
+		return err
+	}
+	WriteAuditLog(os.Stdout, entries)
+	return nil
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestClientSession_AuditLog(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	id := demoItemID(t, session, "Demo Email")
+	if err := session.Delete(ctx, id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	entries, err := session.AuditLog(ctx, 2)
+	if err != nil {
+		t.Fatalf("AuditLog() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(entries))
+	}
+	latest := entries[0]
+	if latest.Action != "data.delete" || latest.DataID == nil || latest.DataID.String() != id {
+		t.Errorf("Expected the deletion first, got %+v", latest)
+	}
+	if !latest.Sealed || latest.ItemName != "Demo Email" {
+		t.Errorf("Expected the item name to be opened locally, got %+v", latest)
+	}
+	if entries[1].Action != "data.create" || !entries[1].Sealed {
+		t.Errorf("Expected the seeded items to be audited, got %+v", entries[1])
+	}
+}
+
+func TestWriteAuditLog(t *testing.T) {
+	var empty bytes.Buffer
+	WriteAuditLog(&empty, nil)
+	if empty.String() != "No audit events\n" {
+		t.Errorf("Unexpected output for an empty log: %q", empty.String())
+	}
+
+	dataID := uuid.New()
+	entries := []AuditEntry{
+		{
+			AuditEvent:   models.AuditEvent{Action: "data.update", DataID: &dataID, CreatedAt: time.Now()},
+			AuditDetails: models.AuditDetails{ItemName: "Bank login", IP: "203.0.113.7"},
+			Sealed:       true,
+		},
+		{AuditEvent: models.AuditEvent{Action: "data.create", DataID: &dataID, CreatedAt: time.Now()}},
+	}
+	var out bytes.Buffer
+	WriteAuditLog(&out, entries)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "TIME") {
+		t.Fatalf("Expected a header and two rows, got %q", out.String())
+	}
+	if !strings.Contains(lines[1], "Bank login") || !strings.Contains(lines[1], "203.0.113.7") {
+		t.Errorf("Expected the opened details, got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], dataID.String()) || !strings.HasSuffix(lines[2], "-") {
+		t.Errorf("Expected the item ID without an address, got %q", lines[2])
+	}
+}
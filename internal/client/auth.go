@@ -7,18 +7,31 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"runtime"
 
 	"github.com/a2sh3r/gophkeeper/internal/logger"
 	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/pkg/version"
 	"go.uber.org/zap"
 )
 
 // Register registers new user
 func (c *Client) Register(ctx context.Context, username, password, masterPassword string) (*models.AuthResponse, error) {
+	return c.RegisterWithIterations(ctx, username, password, masterPassword, 0)
+}
+
+// RegisterWithIterations registers new user whose vault key is derived with
+// iterations of PBKDF2; 0 means the default count
+func (c *Client) RegisterWithIterations(ctx context.Context, username, password, masterPassword string, iterations int) (*models.AuthResponse, error) {
 	req := models.UserRequest{
 		Username:       username,
 		Password:       password,
 		MasterPassword: masterPassword,
+		KDFIterations:  iterations,
+	}
+	if c.grpc != nil {
+		return c.grpcRegister(ctx, req)
 	}
 
 	return c.authRequest(ctx, "/api/v1/register", req)
@@ -30,10 +43,25 @@ func (c *Client) Login(ctx context.Context, username, password string) (*models.
 		Username: username,
 		Password: password,
 	}
+	if c.grpc != nil {
+		return c.grpcLogin(ctx, req)
+	}
 
 	return c.authRequest(ctx, "/api/v1/login", req)
 }
 
+// deviceHeader carries the name of this machine, see server.DeviceHeader
+const deviceHeader = "X-Device-Name"
+
+// setDevice describes this machine and client build on a login, so the
+// session can be told apart from others
+func setDevice(req *http.Request) {
+	if hostname, err := os.Hostname(); err == nil {
+		req.Header.Set(deviceHeader, hostname)
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("gophkeeper/%s (%s/%s)", version.Version, runtime.GOOS, runtime.GOARCH))
+}
+
 // authRequest performs authentication request
 func (c *Client) authRequest(ctx context.Context, endpoint string, req interface{}) (*models.AuthResponse, error) {
 	jsonData, err := json.Marshal(req)
@@ -48,11 +76,12 @@ func (c *Client) authRequest(ctx context.Context, endpoint string, req interface
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	setDevice(httpReq)
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
 		logger.Log.Error("Auth request failed", zap.Error(err), zap.String("endpoint", endpoint))
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, requestFailed(err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -66,15 +95,7 @@ func (c *Client) authRequest(ctx context.Context, endpoint string, req interface
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		var errResp models.ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil {
-			logger.Log.Warn("Auth request failed with server error", zap.String("endpoint", endpoint),
-				zap.Int("status_code", resp.StatusCode), zap.String("error", errResp.Error))
-			return nil, fmt.Errorf("server error: %s", errResp.Error)
-		}
-		logger.Log.Warn("Auth request failed with unknown error", zap.String("endpoint", endpoint),
-			zap.Int("status_code", resp.StatusCode), zap.String("response", string(body)))
-		return nil, fmt.Errorf("server error: %s", string(body))
+		return nil, statusError(resp, body)
 	}
 
 	var authResp models.AuthResponse
@@ -86,3 +107,81 @@ func (c *Client) authRequest(ctx context.Context, endpoint string, req interface
 	return &authResp, nil
 }
 
+// GetSalt gets the key derivation salt stored on the server for the current user
+func (c *Client) GetSalt(ctx context.Context) (string, error) {
+	saltResp, err := c.GetKeyDerivation(ctx)
+	if err != nil {
+		return "", err
+	}
+	return saltResp.Salt, nil
+}
+
+// GetKeyDerivation gets the key derivation salt stored on the server for the
+// current user with the iteration count of PBKDF2
+func (c *Client) GetKeyDerivation(ctx context.Context) (*models.SaltResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v1/salt", nil)
+	if err != nil {
+		logger.Log.Error("Failed to create GET salt request", zap.Error(err))
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.authorize(req)
+
+	return c.saltRequest(req)
+}
+
+// SetSalt registers the key derivation salt for an account that has none yet
+func (c *Client) SetSalt(ctx context.Context, salt string) (string, error) {
+	jsonData, err := json.Marshal(models.SaltRequest{Salt: salt})
+	if err != nil {
+		logger.Log.Error("Failed to marshal salt request", zap.Error(err))
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.baseURL+"/api/v1/salt", bytes.NewBuffer(jsonData))
+	if err != nil {
+		logger.Log.Error("Failed to create PUT salt request", zap.Error(err))
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(req)
+
+	saltResp, err := c.saltRequest(req)
+	if err != nil {
+		return "", err
+	}
+	return saltResp.Salt, nil
+}
+
+// saltRequest performs salt request
+func (c *Client) saltRequest(req *http.Request) (*models.SaltResponse, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Salt request failed", zap.Error(err), zap.String("method", req.Method))
+		return nil, requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Log.Error("Failed to read salt response", zap.Error(err))
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, body)
+	}
+
+	var saltResp models.SaltResponse
+	if err := json.Unmarshal(body, &saltResp); err != nil {
+		logger.Log.Error("Failed to unmarshal salt response", zap.Error(err))
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &saltResp, nil
+}
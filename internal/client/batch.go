@@ -0,0 +1,170 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// importBatchSize is the number of records sent in one batch by imports to
+// servers without streaming import
+const importBatchSize = 100
+
+// BatchRefusedError is returned when the server refuses a batch; nothing of it was applied
+type BatchRefusedError struct {
+	Status  int
+	Results []models.BatchResult
+}
+
+// Error implements error, naming the first refused operation
+func (e *BatchRefusedError) Error() string {
+	for i, result := range e.Results {
+		if result.Error != "" {
+			return fmt.Sprintf("batch refused at operation %d (%s): %s", i+1, result.Op, result.Error)
+		}
+	}
+	return fmt.Sprintf("batch refused with status %d", e.Status)
+}
+
+// BatchData applies creates, updates and deletes in one transaction: all of
+// them or, with a *BatchRefusedError or ErrConflict, none
+func (c *Client) BatchData(ctx context.Context, ops []models.BatchOperation) ([]models.BatchResult, error) {
+	jsonData, err := json.Marshal(models.BatchRequest{Operations: ops})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/data/batch", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	var batchResp models.BatchResponse
+	if resp.Header.Get("Content-Type") == "application/json" {
+		if err := json.Unmarshal(body, &batchResp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK && batchResp.Applied:
+		return batchResp.Results, nil
+	case len(batchResp.Results) > 0:
+		return nil, &BatchRefusedError{Status: resp.StatusCode, Results: batchResp.Results}
+	case resp.StatusCode == http.StatusConflict:
+		return nil, ErrConflict
+	default:
+		return nil, statusError(resp, body)
+	}
+}
+
+// DeleteMany deletes items by ID or unique ID prefix, all of them or none on
+// servers with batches and one after the other elsewhere
+func (s *ClientSession) DeleteMany(ctx context.Context, refs []string) ([]string, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		id, err := s.resolveID(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil || !hasFeature(status, "batch") {
+		for i, id := range ids {
+			if err := s.cli.DeleteData(ctx, id); err != nil {
+				return ids[:i], fmt.Errorf("failed to delete %s: %w", id, err)
+			}
+		}
+		return ids, nil
+	}
+
+	ops := make([]models.BatchOperation, 0, len(ids))
+	for _, id := range ids {
+		op := models.BatchOperation{Op: models.BatchDelete}
+		if err := op.ID.UnmarshalText([]byte(id)); err != nil {
+			return nil, fmt.Errorf("invalid data ID %q: %w", id, err)
+		}
+		ops = append(ops, op)
+	}
+	if _, err := s.cli.BatchData(ctx, ops); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// importInBatches creates the items of an import in batches, for servers
+// without streaming import. A record a batch refuses is reported as failed and
+// the records around it are sent again, keeping the results in order.
+func (s *ClientSession) importInBatches(ctx context.Context, records []models.ImportRecord, onResult func(models.ImportResult)) error {
+	queue := records
+	limit := importBatchSize
+	for len(queue) > 0 {
+		batch := queue[:min(limit, len(queue))]
+		limit = importBatchSize
+		ops := make([]models.BatchOperation, len(batch))
+		for i := range batch {
+			ops[i] = models.BatchOperation{Op: models.BatchCreate, Data: &batch[i].Data}
+		}
+
+		results, err := s.cli.BatchData(ctx, ops)
+		var refused *BatchRefusedError
+		if errors.As(err, &refused) && len(refused.Results) == len(batch) {
+			first := -1
+			for i, result := range refused.Results {
+				if result.Error != "" {
+					first = i
+					break
+				}
+			}
+			switch first {
+			case -1:
+				return refused
+			case 0:
+				onResult(models.ImportResult{Seq: batch[0].Seq, Error: refused.Results[0].Error})
+				queue = queue[1:]
+			default:
+				// the records before the refused one go on their own first
+				limit = first
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for i, result := range results {
+			id := result.ID
+			onResult(models.ImportResult{Seq: batch[i].Seq, ID: &id})
+		}
+		queue = queue[len(batch):]
+	}
+	return nil
+}
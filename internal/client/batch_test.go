@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+func newBatchTestSession(t *testing.T) *ClientSession {
+	t.Helper()
+	router, err := newDemoRouter()
+	if err != nil {
+		t.Fatalf("newDemoRouter() error = %v", err)
+	}
+	cli := NewClient(demoServerURL)
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+	resp, err := cli.Register(context.Background(), "batcher", "login-password", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	cli.SetToken(resp.Token)
+	session := NewClientSession(cli)
+	if err := session.Unlock("master-password", resp.Salt); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	return session
+}
+
+func TestClientSession_DeleteMany(t *testing.T) {
+	ctx := context.Background()
+	session := newBatchTestSession(t)
+
+	var refs []string
+	for _, name := range []string{"one", "two", "three"} {
+		data, err := session.cli.CreateData(ctx, models.DataRequest{Type: models.DataTypeText, Name: name, Data: []byte("x")})
+		if err != nil {
+			t.Fatalf("CreateData() error = %v", err)
+		}
+		refs = append(refs, data.ID.String())
+	}
+
+	missing := append([]string{refs[0]}, uuid.New().String())
+	if _, err := session.DeleteMany(ctx, missing); err == nil {
+		t.Fatal("Expected an error for a missing item")
+	}
+	if items, _ := session.cli.GetData(ctx); len(items) != 3 {
+		t.Fatalf("Expected nothing deleted, got %d items left", len(items))
+	}
+
+	deleted, err := session.DeleteMany(ctx, refs[:2])
+	if err != nil || len(deleted) != 2 {
+		t.Fatalf("DeleteMany() = %v, %v", deleted, err)
+	}
+	items, _ := session.cli.GetData(ctx)
+	if len(items) != 1 || items[0].ID.String() != refs[2] {
+		t.Errorf("Expected only the third item left, got %+v", items)
+	}
+}
+
+func TestClient_BatchDataRefused(t *testing.T) {
+	ctx := context.Background()
+	session := newBatchTestSession(t)
+
+	_, err := session.cli.BatchData(ctx, []models.BatchOperation{
+		{Op: models.BatchCreate, Data: &models.DataRequest{Type: models.DataTypeText, Name: "ok", Data: []byte("x")}},
+		{Op: models.BatchDelete, ID: uuid.New()},
+	})
+	var refused *BatchRefusedError
+	if !errors.As(err, &refused) {
+		t.Fatalf("Expected *BatchRefusedError, got %v", err)
+	}
+	if refused.Status != http.StatusNotFound || len(refused.Results) != 2 || refused.Results[1].Error == "" {
+		t.Errorf("Unexpected refusal %+v", refused)
+	}
+	if items, _ := session.cli.GetData(ctx); len(items) != 0 {
+		t.Errorf("Expected nothing created, got %d items", len(items))
+	}
+}
+
+func TestClientSession_ImportInBatches(t *testing.T) {
+	ctx := context.Background()
+	session := newBatchTestSession(t)
+
+	var records []models.ImportRecord
+	for seq := 1; seq <= 5; seq++ {
+		dataType := models.DataTypeText
+		if seq == 3 {
+			dataType = "unknown"
+		}
+		records = append(records, models.ImportRecord{Seq: seq, Data: models.DataRequest{Type: dataType, Name: "item", Data: []byte("x")}})
+	}
+
+	var results []models.ImportResult
+	if err := session.importInBatches(ctx, records, func(result models.ImportResult) {
+		results = append(results, result)
+	}); err != nil {
+		t.Fatalf("importInBatches() error = %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("Expected a result per record, got %+v", results)
+	}
+	for i, result := range results {
+		if result.Seq != i+1 {
+			t.Errorf("Expected results in order, got seq %d at %d", result.Seq, i)
+		}
+		if failed := result.Error != ""; failed != (result.Seq == 3) {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	}
+	if items, _ := session.cli.GetData(ctx); len(items) != 4 {
+		t.Errorf("Expected 4 items imported, got %d", len(items))
+	}
+}
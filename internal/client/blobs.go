@@ -0,0 +1,166 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// ErrChunksOnServer is returned when presigning the downloads of an item
+// whose chunks are stored on the server rather than in object storage
+var ErrChunksOnServer = errors.New("chunks are stored on the server")
+
+// PresignDataChunkUpload reserves the content chunk of a binary item at index
+// and returns the URL uploading its size bytes of ciphertext to object storage
+func (c *Client) PresignDataChunkUpload(ctx context.Context, id string, index int, size int) (*models.PresignedChunk, error) {
+	endpoint := c.baseURL + "/api/v1/data/" + id + "/chunks/presign?index=" + strconv.Itoa(index) + "&size=" + strconv.Itoa(size)
+	presigned, err := c.presignChunks(ctx, http.MethodPost, endpoint, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+	if len(presigned.Chunks) != 1 {
+		return nil, fmt.Errorf("expected 1 presigned chunk, got %d", len(presigned.Chunks))
+	}
+	return &presigned.Chunks[0], nil
+}
+
+// PresignDataChunkDownloads returns the URLs downloading the content chunks
+// of a binary item from object storage, in order, or ErrChunksOnServer if
+// they are downloaded with DownloadDataChunks instead
+func (c *Client) PresignDataChunkDownloads(ctx context.Context, id string) ([]models.PresignedChunk, error) {
+	presigned, err := c.presignChunks(ctx, http.MethodGet, c.baseURL+"/api/v1/data/"+id+"/chunks/presign", http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return presigned.Chunks, nil
+}
+
+// presignChunks calls a presign route expecting status
+func (c *Client) presignChunks(ctx context.Context, method, endpoint string, status int) (*models.PresignedChunksResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Request failed", zap.Error(err), zap.String("path", req.URL.Path))
+		return nil, requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if method == http.MethodGet && resp.StatusCode == http.StatusConflict {
+		return nil, ErrChunksOnServer
+	}
+	if resp.StatusCode != status {
+		return nil, statusError(resp, body)
+	}
+
+	var presigned models.PresignedChunksResponse
+	if err := json.Unmarshal(body, &presigned); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &presigned, nil
+}
+
+// PutPresignedChunk uploads an encrypted chunk to a presigned URL. The URL
+// carries its own authorization, so the session token is not sent.
+func (c *Client) PutPresignedChunk(ctx context.Context, url string, chunk []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(chunk))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = int64(len(chunk))
+	_, err = c.doPresigned(req)
+	return err
+}
+
+// GetPresignedChunk downloads an encrypted chunk from a presigned URL
+func (c *Client) GetPresignedChunk(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return c.doPresigned(req)
+}
+
+// doPresigned sends a request to object storage and returns the response
+// body, which is at most one chunk
+func (c *Client) doPresigned(req *http.Request) ([]byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Object storage request failed", zap.Error(err), zap.String("host", req.URL.Host))
+		return nil, requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxChunkFrame+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		// object storage answers in XML, not in the errors of the server
+		return nil, fmt.Errorf("object storage returned %s", resp.Status)
+	}
+	if len(body) > maxChunkFrame {
+		return nil, fmt.Errorf("chunk is too large")
+	}
+	return body, nil
+}
+
+// uploadChunkPresigned uploads one encrypted chunk straight to object storage
+func (s *ClientSession) uploadChunkPresigned(ctx context.Context, id string, index int, chunk []byte) error {
+	presigned, err := s.cli.PresignDataChunkUpload(ctx, id, index, len(chunk))
+	if err != nil {
+		return err
+	}
+	return s.cli.PutPresignedChunk(ctx, presigned.URL, chunk)
+}
+
+// downloadChunksPresigned passes the encrypted chunks of a binary item to fn
+// in order, downloading them straight from object storage
+func (s *ClientSession) downloadChunksPresigned(ctx context.Context, id string, fn func(index int, chunk []byte) error) error {
+	chunks, err := s.cli.PresignDataChunkDownloads(ctx, id)
+	if err != nil {
+		return err
+	}
+	for index, presigned := range chunks {
+		chunk, err := s.cli.GetPresignedChunk(ctx, presigned.URL)
+		if err != nil {
+			return fmt.Errorf("download ended after %d of %d chunks: %w", index, len(chunks), err)
+		}
+		if err := fn(index, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// presignedChunks reports whether the server hands out presigned URLs for
+// chunks, so their content bypasses it
+func (s *ClientSession) presignedChunks(ctx context.Context) bool {
+	status, err := s.cli.GetStatus(ctx)
+	return err == nil && hasFeature(status, "presigned_chunks")
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// presigningServer wraps the demo server with presigned chunk routes backed
+// by a bucket in memory at bucket.test
+type presigningServer struct {
+	router  http.Handler
+	mutex   sync.Mutex
+	objects map[string][]byte
+	chunks  map[string][]string
+	// inline makes the presigned downloads answer that chunks are on the server
+	inline bool
+	// tokens are the Authorization headers sent to the bucket
+	tokens []string
+}
+
+func (p *presigningServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if r.URL.Host == "bucket.test" {
+		p.tokens = append(p.tokens, r.Header.Get("Authorization"))
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			if int64(len(body)) != r.ContentLength {
+				http.Error(w, "length mismatch", http.StatusForbidden)
+				return
+			}
+			p.objects[r.URL.Path] = body
+		case http.MethodGet:
+			object, ok := p.objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "no such key", http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(object)
+		}
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/api/v1/status":
+		rec := httptest.NewRecorder()
+		p.router.ServeHTTP(rec, r)
+		var status models.StatusResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &status)
+		status.Features = append(status.Features, "presigned_chunks")
+		_ = json.NewEncoder(w).Encode(status)
+	case strings.HasSuffix(r.URL.Path, "/chunks/presign") && r.Method == http.MethodPost:
+		id := strings.Split(r.URL.Path, "/")[4]
+		key := fmt.Sprintf("/%s/%s", id, r.URL.Query().Get("index"))
+		p.chunks[id] = append(p.chunks[id], key)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(models.PresignedChunksResponse{
+			Chunks: []models.PresignedChunk{{Method: http.MethodPut, URL: "http://bucket.test" + key}},
+		})
+	case strings.HasSuffix(r.URL.Path, "/chunks/presign"):
+		if p.inline {
+			http.Error(w, "stored on the server", http.StatusConflict)
+			return
+		}
+		var response models.PresignedChunksResponse
+		for index, key := range p.chunks[strings.Split(r.URL.Path, "/")[4]] {
+			response.Chunks = append(response.Chunks, models.PresignedChunk{Index: index, Method: http.MethodGet, URL: "http://bucket.test" + key})
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	default:
+		p.router.ServeHTTP(w, r)
+	}
+}
+
+func TestClientSession_PresignedChunks(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	transport := session.cli.httpClient.Transport.(*handlerTransport)
+	server := &presigningServer{router: transport.handler, objects: make(map[string][]byte), chunks: make(map[string][]string)}
+	transport.handler = server
+
+	content := bytes.Repeat([]byte("0123456789abcdefghijklmnopqrstuvwxy"), 72)
+	path := filepath.Join(t.TempDir(), "backup.tar")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, err := session.UploadBinary(ctx, path, "", models.DataRequest{Name: "Backup"}, 1000)
+	if err != nil {
+		t.Fatalf("UploadBinary() error = %v", err)
+	}
+	if len(server.objects) != 3 {
+		t.Fatalf("Expected 3 chunks in the bucket, got %d", len(server.objects))
+	}
+	for key, object := range server.objects {
+		if bytes.Contains(object, content[:100]) {
+			t.Errorf("Expected the object %s to be encrypted", key)
+		}
+	}
+
+	binaryData := models.BinaryData{FileName: "backup.tar", Size: int64(len(content)), Chunks: 3}
+	outputPath := filepath.Join(t.TempDir(), "restored.tar")
+	if err := session.downloadBinary(ctx, data, binaryData, outputPath); err != nil {
+		t.Fatalf("downloadBinary() error = %v", err)
+	}
+	restored, err := os.ReadFile(outputPath)
+	if err != nil || !bytes.Equal(restored, content) {
+		t.Errorf("Expected the file to round-trip through the bucket, got %d bytes, %v", len(restored), err)
+	}
+	for _, token := range server.tokens {
+		if token != "" {
+			t.Errorf("Expected no session token sent to the bucket, got %q", token)
+		}
+	}
+}
+
+func TestClientSession_PresignedChunksFallback(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	content := bytes.Repeat([]byte("x"), 2500)
+	path := filepath.Join(t.TempDir(), "backup.tar")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	// uploaded through the server before object storage was set up
+	data, err := session.UploadBinary(ctx, path, "", models.DataRequest{Name: "Backup"}, 1000)
+	if err != nil {
+		t.Fatalf("UploadBinary() error = %v", err)
+	}
+
+	transport := session.cli.httpClient.Transport.(*handlerTransport)
+	transport.handler = &presigningServer{router: transport.handler, objects: make(map[string][]byte),
+		chunks: make(map[string][]string), inline: true}
+
+	binaryData := models.BinaryData{FileName: "backup.tar", Size: int64(len(content)), Chunks: 3}
+	outputPath := filepath.Join(t.TempDir(), "restored.tar")
+	if err := session.downloadBinary(ctx, data, binaryData, outputPath); err != nil {
+		t.Fatalf("downloadBinary() error = %v", err)
+	}
+	if restored, err := os.ReadFile(outputPath); err != nil || !bytes.Equal(restored, content) {
+		t.Errorf("Expected the file streamed from the server, got %d bytes, %v", len(restored), err)
+	}
+}
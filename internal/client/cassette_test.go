@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/vcr"
+)
+
+// cassetteSession returns a session whose requests are replayed from the named
+// cassette, or recorded against an in-memory server with UPDATE_CASSETTES=1
+func cassetteSession(t *testing.T, name string) *ClientSession {
+	t.Helper()
+	var next http.RoundTripper
+	if vcr.ModeFromEnv() == vcr.ModeRecord {
+		router, err := newDemoRouter()
+		if err != nil {
+			t.Fatalf("newDemoRouter() error = %v", err)
+		}
+		next = &handlerTransport{handler: router}
+	}
+
+	cli := NewClient(demoServerURL)
+	cli.httpClient.Transport = vcr.Start(t, filepath.Join("testdata", "cassettes", name+".json"), next)
+	return NewClientSession(cli)
+}
+
+func TestCassette_ItemLifecycle(t *testing.T) {
+	ctx := context.Background()
+	session := cassetteSession(t, "item_lifecycle")
+	cli := session.cli
+
+	resp, err := cli.Register(ctx, "cassette-user", "cassette-password", DemoMasterPassword)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	cli.SetToken(resp.Token)
+	salt, err := base64.StdEncoding.DecodeString(resp.Salt)
+	if err != nil {
+		t.Fatalf("Failed to decode salt: %v", err)
+	}
+	cryptoManager, err := crypto.NewCryptoManagerWithSalt(DemoMasterPassword, salt)
+	if err != nil {
+		t.Fatalf("NewCryptoManagerWithSalt() error = %v", err)
+	}
+	session.SetCryptoManager(cryptoManager, DemoMasterPassword)
+
+	if _, err := cli.Login(ctx, "cassette-user", "wrong-password"); err == nil {
+		t.Error("Expected login with a wrong password to fail")
+	}
+
+	ciphertext, err := cryptoManager.Encrypt([]byte("recorded note"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	created, err := session.Create(ctx, models.DataRequest{Type: models.DataTypeText, Name: "Cassette note", Data: ciphertext})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// by short ID, which resolves through the item list
+	data, err := session.Get(ctx, created.ID.String()[:8])
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	plaintext, err := cryptoManager.Decrypt(data.Data)
+	if err != nil || string(plaintext) != "recorded note" {
+		t.Errorf("Expected the recorded note to decrypt, got %q, %v", plaintext, err)
+	}
+
+	if err := session.Delete(ctx, created.ID.String()); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := session.Get(ctx, created.ID.String()); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error for the deleted item, got %v", err)
+	}
+	items, err := session.List(ctx)
+	if err != nil || len(items) != 0 {
+		t.Errorf("Expected an empty vault, got %d items, %v", len(items), err)
+	}
+}
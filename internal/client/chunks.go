@@ -0,0 +1,335 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// binaryChunkSize is the plaintext size of one chunk of a large file; files
+// larger than that are uploaded in chunks when the server supports it
+const binaryChunkSize = 4 << 20
+
+// maxChunkFrame caps a chunk read from a download, as a guard against a
+// corrupt stream; the server stores at most 16 MiB per chunk
+const maxChunkFrame = 16 << 20
+
+// UploadDataChunk stores one encrypted content chunk of a binary item at index
+func (c *Client) UploadDataChunk(ctx context.Context, id string, index int, chunk []byte) error {
+	if c.grpc != nil {
+		sent := false
+		return c.grpcUploadChunks(ctx, id, index, func() ([]byte, error) {
+			if sent {
+				return nil, nil
+			}
+			sent = true
+			return chunk, nil
+		})
+	}
+
+	endpoint := c.baseURL + "/api/v1/data/" + id + "/chunks?index=" + strconv.Itoa(index)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(chunk))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Request failed", zap.Error(err), zap.String("path", req.URL.Path))
+		return requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		return statusError(resp, body)
+	}
+	return nil
+}
+
+// UploadDataChunks stores the encrypted content chunks of a binary item
+// returned by next, in order, until it returns a nil chunk. Over gRPC they
+// go in one stream.
+func (c *Client) UploadDataChunks(ctx context.Context, id string, next func() ([]byte, error)) error {
+	if c.grpc != nil {
+		return c.grpcUploadChunks(ctx, id, 0, next)
+	}
+	return uploadEach(ctx, id, next, c.UploadDataChunk)
+}
+
+// uploadEach stores the chunks returned by next with one upload call each
+func uploadEach(ctx context.Context, id string, next func() ([]byte, error),
+	upload func(ctx context.Context, id string, index int, chunk []byte) error) error {
+	for index := 0; ; index++ {
+		chunk, err := next()
+		if err != nil || chunk == nil {
+			return err
+		}
+		if err := upload(ctx, id, index, chunk); err != nil {
+			return fmt.Errorf("failed to upload chunk %d: %w", index, err)
+		}
+	}
+}
+
+// DownloadDataChunks streams the encrypted content chunks of a binary item to
+// fn in order, holding one chunk in memory at a time
+func (c *Client) DownloadDataChunks(ctx context.Context, id string, fn func(index int, chunk []byte) error) error {
+	if c.grpc != nil {
+		return c.grpcDownloadChunks(ctx, id, fn)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/data/"+id+"/chunks", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(req)
+
+	// a large file outlasts the request timeout; ctx bounds it instead
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Request failed", zap.Error(err), zap.String("path", req.URL.Path))
+		return requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		return statusError(resp, body)
+	}
+
+	count, err := strconv.Atoi(resp.Header.Get("X-Chunk-Count"))
+	if err != nil {
+		return fmt.Errorf("invalid chunk count in response")
+	}
+
+	var prefix [4]byte
+	for index := 0; index < count; index++ {
+		if _, err := io.ReadFull(resp.Body, prefix[:]); err != nil {
+			return fmt.Errorf("download ended after %d of %d chunks: %w", index, count, err)
+		}
+		size := binary.BigEndian.Uint32(prefix[:])
+		if size > maxChunkFrame {
+			return fmt.Errorf("chunk %d is too large: %d bytes", index, size)
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(resp.Body, chunk); err != nil {
+			return fmt.Errorf("download ended after %d of %d chunks: %w", index, count, err)
+		}
+		if err := fn(index, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkSizeFor returns the plaintext chunk size for uploading a file of size
+// bytes, or 0 if it is sent whole because it is small or the server cannot
+// store chunks
+func (s *ClientSession) chunkSizeFor(ctx context.Context, size int64) int {
+	chunkSize := binaryChunkSize
+	if size <= int64(chunkSize) {
+		return 0
+	}
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil || !hasFeature(status, "chunked_binary") {
+		return 0
+	}
+	if status.MaxPayloadBytes > 0 {
+		// chunks are encrypted into base64 inside JSON, leave room for that
+		if limit := int((status.MaxPayloadBytes - 1024) / 4 * 3); limit < chunkSize {
+			chunkSize = limit
+		}
+	}
+	if chunkSize <= 0 {
+		return 0
+	}
+	return chunkSize
+}
+
+// UploadBinary stores the file at path as a binary item whose content is
+// encrypted and uploaded chunkSize bytes at a time, so memory use does not grow
+// with the file. The item itself holds the encrypted file description. The
+// item is removed again if the upload fails.
+func (s *ClientSession) UploadBinary(ctx context.Context, path, notes string, dataReq models.DataRequest, chunkSize int) (*models.Data, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	binaryData := models.BinaryData{
+		FileName: info.Name(),
+		MimeType: getMimeType(filepath.Ext(info.Name())),
+		Size:     info.Size(),
+		Notes:    notes,
+		Chunks:   int((info.Size() + int64(chunkSize) - 1) / int64(chunkSize)),
+	}
+	description, err := json.Marshal(binaryData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal binary metadata: %w", err)
+	}
+	if dataReq.Data, err = s.cryptoManager.Encrypt(description); err != nil {
+		return nil, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+	dataReq.Type = models.DataTypeBinary
+	dataReq.Metadata = string(description)
+
+	data, err := s.Create(ctx, dataReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uploadChunks(ctx, data.ID.String(), file, chunkSize); err != nil {
+		if err := s.cli.DeleteData(context.WithoutCancel(ctx), data.ID.String()); err != nil {
+			logger.Log.Warn("Failed to remove partially uploaded item", zap.Error(err), zap.String("data_id", data.ID.String()))
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// createChunkedBinary handles creating a binary item from a large file
+func (s *ClientSession) createChunkedBinary(ctx context.Context, path, notes string, dataReq models.DataRequest, chunkSize int) error {
+	fmt.Println("Large file: uploading in encrypted chunks...")
+	data, err := s.UploadBinary(ctx, path, notes, dataReq, chunkSize)
+	if err != nil {
+		return fmt.Errorf("failed to create data: %w", err)
+	}
+
+	fmt.Printf("Successfully created encrypted data with ID: %s\n", data.ID)
+	return nil
+}
+
+// uploadChunks encrypts and uploads r in chunks of chunkSize bytes, each an
+// encrypted stream, straight to object storage when the server presigns chunk
+// uploads
+func (s *ClientSession) uploadChunks(ctx context.Context, id string, r io.Reader, chunkSize int) error {
+	var chunk bytes.Buffer
+	index, last := 0, false
+	next := func() ([]byte, error) {
+		if last {
+			return nil, nil
+		}
+		chunk.Reset()
+		n, err := s.cryptoManager.EncryptStream(&chunk, io.LimitReader(r, int64(chunkSize)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt chunk %d: %w", index, err)
+		}
+		if n == 0 {
+			return nil, nil
+		}
+		// a short chunk is the last one
+		last = n < int64(chunkSize)
+		index++
+		return chunk.Bytes(), nil
+	}
+
+	if s.presignedChunks(ctx) {
+		return uploadEach(ctx, id, next, s.uploadChunkPresigned)
+	}
+	return s.cli.UploadDataChunks(ctx, id, next)
+}
+
+// decryptContent writes the plaintext of an encrypted file chunk or
+// attachment to w and returns its size. Content is encrypted as a stream;
+// content uploaded before is a single record.
+func (s *ClientSession) decryptContent(w io.Writer, ciphertext []byte) (int64, error) {
+	if crypto.IsStream(ciphertext) {
+		return s.cryptoManager.DecryptStream(w, bytes.NewReader(ciphertext))
+	}
+	plaintext, err := s.cryptoManager.Decrypt(ciphertext)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(plaintext)
+	return int64(n), err
+}
+
+// downloadBinary decrypts the chunks of a binary item into outputPath. The
+// file only replaces outputPath once it is complete and of the recorded size.
+// Chunks in object storage are downloaded from it directly.
+func (s *ClientSession) downloadBinary(ctx context.Context, data *models.Data, binaryData models.BinaryData, outputPath string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var written int64
+	var chunks int
+	write := func(index int, chunk []byte) error {
+		n, err := s.decryptContent(tmp, chunk)
+		written += n
+		chunks++
+		if err != nil {
+			return fmt.Errorf("failed to decrypt chunk %d: %w", index, err)
+		}
+		return nil
+	}
+	err = ErrChunksOnServer
+	if s.presignedChunks(ctx) {
+		err = s.downloadChunksPresigned(ctx, data.ID.String(), write)
+	}
+	if errors.Is(err, ErrChunksOnServer) {
+		// items uploaded before object storage was set up stay on the server
+		err = s.cli.DownloadDataChunks(ctx, data.ID.String(), write)
+	}
+	if err != nil {
+		return err
+	}
+	if chunks != binaryData.Chunks || written != binaryData.Size {
+		return fmt.Errorf("incomplete file on the server: got %d of %d chunks, %d of %d bytes",
+			chunks, binaryData.Chunks, written, binaryData.Size)
+	}
+
+	if err := tmp.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), outputPath); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
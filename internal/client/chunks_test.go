@@ -0,0 +1,99 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestClientSession_ChunkedBinary(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	content := bytes.Repeat([]byte("0123456789abcdefghijklmnopqrstuvwxy"), 72)
+	path := filepath.Join(t.TempDir(), "backup.tar")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, err := session.UploadBinary(ctx, path, "weekly", models.DataRequest{Name: "Backup"}, 1000)
+	if err != nil {
+		t.Fatalf("UploadBinary() error = %v", err)
+	}
+	binaryData := models.BinaryData{FileName: "backup.tar", Size: int64(len(content)), Chunks: 3}
+	if !strings.Contains(data.Metadata, `"chunks":3`) {
+		t.Errorf("Expected the chunk count in the metadata, got %q", data.Metadata)
+	}
+	err = session.cli.DownloadDataChunks(ctx, data.ID.String(), func(index int, chunk []byte) error {
+		if !crypto.IsStream(chunk) {
+			t.Errorf("Expected chunk %d to be encrypted as a stream", index)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DownloadDataChunks() error = %v", err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "restored.tar")
+	if err := session.downloadBinary(ctx, data, binaryData, outputPath); err != nil {
+		t.Fatalf("downloadBinary() error = %v", err)
+	}
+	restored, err := os.ReadFile(outputPath)
+	if err != nil || !bytes.Equal(restored, content) {
+		t.Errorf("Expected the file to round-trip, got %d bytes, %v", len(restored), err)
+	}
+
+	binaryData.Chunks = 4
+	missingPath := filepath.Join(t.TempDir(), "missing.tar")
+	if err := session.downloadBinary(ctx, data, binaryData, missingPath); err == nil || !strings.Contains(err.Error(), "incomplete") {
+		t.Errorf("Expected an incomplete file error, got %v", err)
+	}
+	if _, err := os.Stat(missingPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no file to be left behind, got %v", err)
+	}
+}
+
+func TestClientSession_ChunkedBinaryFailure(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	transport := session.cli.httpClient.Transport.(*handlerTransport)
+	router := transport.handler
+	transport.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/chunks") && r.URL.Query().Get("index") == "1" {
+			http.Error(w, "Failed to store chunk", http.StatusInternalServerError)
+			return
+		}
+		router.ServeHTTP(w, r)
+	})
+
+	path := filepath.Join(t.TempDir(), "backup.tar")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), 2500), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := session.UploadBinary(ctx, path, "", models.DataRequest{Name: "Backup"}, 1000); err == nil || !strings.Contains(err.Error(), "chunk 1") {
+		t.Fatalf("Expected the failed chunk in the error, got %v", err)
+	}
+	items, err := session.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	for _, item := range items {
+		if item.Name == "Backup" {
+			t.Error("Expected the partially uploaded item to be removed")
+		}
+	}
+}
@@ -1,15 +1,29 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
-	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"go.uber.org/zap"
 )
 
+// requestIDHeader carries the ID the server logs a request under
+const requestIDHeader = "X-Request-ID"
+
 // Client represents client for server interaction
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	token      string
+	// vaultLock is the token of the vault lock held by the running operation
+	vaultLock string
+	// grpc sends the calls the gRPC API serves over it when set, see UseGRPC
+	grpc *grpcTransport
 }
 
 // NewClient creates new client
@@ -17,7 +31,8 @@ func NewClient(baseURL string) *Client {
 	return &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: &compressionTransport{},
+			Timeout:   DefaultRequestTimeout,
 		},
 	}
 }
@@ -26,3 +41,71 @@ func NewClient(baseURL string) *Client {
 func (c *Client) SetToken(token string) {
 	c.token = token
 }
+
+// SetBaseURL sets the server base URL
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = baseURL
+	if compressing, ok := c.httpClient.Transport.(*compressionTransport); ok {
+		compressing.reset()
+	}
+}
+
+// authorize adds the credentials, and the vault lock if one is held, to req
+func (c *Client) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if c.vaultLock != "" {
+		req.Header.Set(vaultLockHeader, c.vaultLock)
+	}
+}
+
+// doJSON sends an authenticated request with an optional JSON body and decodes
+// the response into out if given. Any status other than okStatus is an error.
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}, okStatus int) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		logger.Log.Error("Failed to create request", zap.Error(err), zap.String("path", path))
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Request failed", zap.Error(err), zap.String("method", method), zap.String("path", path))
+		return requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Log.Error("Failed to read response", zap.Error(err), zap.String("path", path))
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != okStatus {
+		return statusError(resp, respBody)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			logger.Log.Error("Failed to unmarshal response", zap.Error(err), zap.String("path", path))
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	return nil
+}
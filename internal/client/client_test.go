@@ -270,6 +270,54 @@ func TestClient_GetData(t *testing.T) {
 	}
 }
 
+func TestClient_GetDataFiltered(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    models.DataFilter
+		wantPath  string
+		wantQuery string
+	}{
+		{name: "no filter", wantPath: "/api/v1/data", wantQuery: ""},
+		{
+			name:      "all options",
+			filter:    models.DataFilter{Environment: "prod", Type: models.DataTypeBankCard, Name: "visa & co", Limit: 50, Offset: 100},
+			wantPath:  "/api/v1/data",
+			wantQuery: "environment=prod&limit=50&name=visa+%26+co&offset=100&type=bank_card",
+		},
+		{
+			name:      "search",
+			filter:    models.DataFilter{Query: "example.com", Type: models.DataTypeLoginPassword},
+			wantPath:  "/api/v1/data/search",
+			wantQuery: "q=example.com&type=login_password",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.wantPath {
+					t.Errorf("Expected path %q, got %q", tt.wantPath, r.URL.Path)
+				}
+				if r.URL.RawQuery != tt.wantQuery {
+					t.Errorf("Expected query %q, got %q", tt.wantQuery, r.URL.RawQuery)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				if err := json.NewEncoder(w).Encode(models.DataListResponse{}); err != nil {
+					logger.Log.Error("Failed to encode response", zap.Error(err))
+				}
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.SetToken("test-token")
+
+			if _, err := client.GetDataFiltered(context.Background(), tt.filter); err != nil {
+				t.Errorf("GetDataFiltered() error = %v", err)
+			}
+		})
+	}
+}
+
 func TestClient_CreateData(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -1451,3 +1499,201 @@ func TestClient_CreateData_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_GetSalt(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverCode int
+		serverResp interface{}
+		wantSalt   string
+		wantErr    bool
+	}{
+		{
+			name:       "successful get",
+			serverCode: http.StatusOK,
+			serverResp: models.SaltResponse{Salt: "c2FsdA=="},
+			wantSalt:   "c2FsdA==",
+		},
+		{
+			name:       "server error",
+			serverCode: http.StatusNotFound,
+			serverResp: models.ErrorResponse{Error: "User not found"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/salt" || r.Method != "GET" {
+					t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				if r.Header.Get("Authorization") != "Bearer test-token" {
+					t.Errorf("Expected Authorization header, got %s", r.Header.Get("Authorization"))
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.serverCode)
+				if err := json.NewEncoder(w).Encode(tt.serverResp); err != nil {
+					logger.Log.Error("Failed to encode response", zap.Error(err))
+				}
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.SetToken("test-token")
+			salt, err := client.GetSalt(context.Background())
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetSalt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if salt != tt.wantSalt {
+				t.Errorf("Expected salt %s, got %s", tt.wantSalt, salt)
+			}
+		})
+	}
+}
+
+func TestClient_SetSalt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/salt" || r.Method != "PUT" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var req models.SaltRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+
+		if req.Salt == "conflict" {
+			http.Error(w, "Salt already set", http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.SaltResponse{Salt: req.Salt}); err != nil {
+			logger.Log.Error("Failed to encode response", zap.Error(err))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	salt, err := client.SetSalt(context.Background(), "c2FsdA==")
+	if err != nil {
+		t.Fatalf("SetSalt() error = %v", err)
+	}
+	if salt != "c2FsdA==" {
+		t.Errorf("Expected salt c2FsdA==, got %s", salt)
+	}
+
+	if _, err := client.SetSalt(context.Background(), "conflict"); err == nil {
+		t.Error("Expected error for conflicting salt")
+	}
+}
+
+func TestClient_KDFIterations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/register":
+			var req models.UserRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("Failed to decode request: %v", err)
+			}
+			if err := json.NewEncoder(w).Encode(models.AuthResponse{Token: "test-token", Salt: "c2FsdA==",
+				KDFIterations: req.KDFIterations}); err != nil {
+				logger.Log.Error("Failed to encode response", zap.Error(err))
+			}
+		case "/api/v1/salt":
+			if err := json.NewEncoder(w).Encode(models.SaltResponse{Salt: "c2FsdA==", KDFIterations: 250000}); err != nil {
+				logger.Log.Error("Failed to encode response", zap.Error(err))
+			}
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	resp, err := client.RegisterWithIterations(context.Background(), "testuser", "password123", "masterPassword123!", 250000)
+	if err != nil {
+		t.Fatalf("RegisterWithIterations() error = %v", err)
+	}
+	if resp.KDFIterations != 250000 {
+		t.Errorf("Expected 250000 iterations, got %d", resp.KDFIterations)
+	}
+
+	derivation, err := client.GetKeyDerivation(context.Background())
+	if err != nil {
+		t.Fatalf("GetKeyDerivation() error = %v", err)
+	}
+	if derivation.Salt != "c2FsdA==" || derivation.KDFIterations != 250000 {
+		t.Errorf("Unexpected key derivation %+v", derivation)
+	}
+}
+
+func TestCheckStoredSalt(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *Config
+		username   string
+		serverSalt string
+		wantErr    bool
+	}{
+		{
+			name:       "matching salt",
+			config:     &Config{Username: "alice", Salt: "salt-1"},
+			username:   "alice",
+			serverSalt: "salt-1",
+		},
+		{
+			name:       "mismatching salt",
+			config:     &Config{Username: "alice", Salt: "salt-1"},
+			username:   "alice",
+			serverSalt: "salt-2",
+			wantErr:    true,
+		},
+		{
+			name:       "different user",
+			config:     &Config{Username: "alice", Salt: "salt-1"},
+			username:   "bob",
+			serverSalt: "salt-2",
+		},
+		{
+			name:       "no stored salt",
+			config:     &Config{},
+			username:   "alice",
+			serverSalt: "salt-2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckStoredSalt(tt.config, tt.username, tt.serverSalt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckStoredSalt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && err != ErrSaltMismatch {
+				t.Errorf("Expected ErrSaltMismatch, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStatusError_RequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-42")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"Failed to get data"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL).GetData(context.Background())
+	if err == nil || err.Error() != "server error: Failed to get data (request ID req-42)" {
+		t.Errorf("Expected the error with the request ID, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+const (
+	// DefaultClipboardDuration is how long copy leaves a secret on the clipboard by default
+	DefaultClipboardDuration = 20 * time.Second
+)
+
+// copyDefaultFields is the field copy picks when none is given
+var copyDefaultFields = map[models.DataType]string{
+	models.DataTypeLoginPassword: "password",
+	models.DataTypeBankCard:      "card_number",
+	models.DataTypeText:          "content",
+}
+
+// Clipboard reads and writes the system clipboard
+type Clipboard interface {
+	Read() (string, error)
+	Write(text string) error
+}
+
+// SystemClipboard uses wl-copy, xclip or xsel on Linux, pbcopy on macOS and
+// PowerShell on Windows
+type SystemClipboard struct{}
+
+// clipboardTool is a pair of commands that write and read the clipboard
+type clipboardTool struct {
+	write []string
+	read  []string
+}
+
+// clipboardTools returns the clipboard commands to try on an operating system, in order
+func clipboardTools(goos string) []clipboardTool {
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []clipboardTool{
+			{write: []string{"wl-copy"}, read: []string{"wl-paste", "--no-newline"}},
+			{write: []string{"xclip", "-selection", "clipboard"}, read: []string{"xclip", "-selection", "clipboard", "-o"}},
+			{write: []string{"xsel", "--clipboard", "--input"}, read: []string{"xsel", "--clipboard", "--output"}},
+		}
+	case "darwin":
+		return []clipboardTool{{write: []string{"pbcopy"}, read: []string{"pbpaste"}}}
+	case "windows":
+		return []clipboardTool{{
+			write: []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "Set-Clipboard -Value ([Console]::In.ReadToEnd())"},
+			read:  []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "Get-Clipboard -Raw"},
+		}}
+	default:
+		return nil
+	}
+}
+
+// tool returns the first clipboard tool installed on this system
+func (SystemClipboard) tool() (clipboardTool, error) {
+	for _, tool := range clipboardTools(runtime.GOOS) {
+		if _, err := exec.LookPath(tool.write[0]); err == nil {
+			return tool, nil
+		}
+	}
+	return clipboardTool{}, fmt.Errorf("no clipboard tool found (install wl-clipboard, xclip or xsel)")
+}
+
+// Read implements Clipboard
+func (c SystemClipboard) Read() (string, error) {
+	tool, err := c.tool()
+	if err != nil {
+		return "", err
+	}
+	output, err := exec.Command(tool.read[0], tool.read[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w", tool.read[0], err)
+	}
+	return strings.TrimSuffix(string(output), "\r\n"), nil
+}
+
+// Write implements Clipboard. The text is passed on stdin, never as an
+// argument, so it does not show up in the process list.
+func (c SystemClipboard) Write(text string) error {
+	tool, err := c.tool()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(tool.write[0], tool.write[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", tool.write[0], err, output)
+	}
+	return nil
+}
+
+// SetClipboard sets the clipboard used by copy
+func (s *ClientSession) SetClipboard(clipboard Clipboard) {
+	s.clipboard = clipboard
+}
+
+// CopyCommand copies a field of an item to the clipboard and clears it after
+// duration, or earlier when ctx is cancelled. Without a field name, the
+// password, card number or text content is copied.
+func (s *ClientSession) CopyCommand(ctx context.Context, id, field string, duration time.Duration) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	if len(id) == 0 {
+		return fmt.Errorf("data ID is required")
+	}
+	if s.clipboard == nil {
+		return fmt.Errorf("no clipboard available")
+	}
+
+	data, err := s.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+	if field == "" {
+		field = copyDefaultFields[data.Type]
+		if field == "" {
+			return fmt.Errorf("%s items have no field to copy", data.Type)
+		}
+	}
+
+	fields, err := s.ItemFields(data)
+	if err != nil {
+		return err
+	}
+	value := ""
+	var names []string
+	for _, f := range fields {
+		if f.Value == "" {
+			continue
+		}
+		names = append(names, f.Name)
+		if f.Name == field {
+			value = f.Value
+		}
+	}
+	if value == "" {
+		return fmt.Errorf("item %q has no %s field (available: %s)", CleanQuotes(data.Name), field, strings.Join(names, ", "))
+	}
+
+	if err := s.clipboard.Write(value); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	s.recordUsage(data.ID.String())
+	fmt.Printf("Copied %s of %q to the clipboard (clearing in %s)\n", field, CleanQuotes(data.Name), duration)
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	return s.clearClipboard(value)
+}
+
+// clearClipboard empties the clipboard unless something else was copied meanwhile
+func (s *ClientSession) clearClipboard(value string) error {
+	current, err := s.clipboard.Read()
+	if err == nil && current != value {
+		fmt.Println("Clipboard changed meanwhile, leaving it alone")
+		return nil
+	}
+	if err := s.clipboard.Write(""); err != nil {
+		return fmt.Errorf("failed to clear clipboard: %w", err)
+	}
+	fmt.Println("Clipboard cleared")
+	return nil
+}
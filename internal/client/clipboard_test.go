@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClipboard struct {
+	text   string
+	writes []string
+	// onWrite, if set, runs after each write, e.g. to simulate another copy
+	onWrite func(c *fakeClipboard)
+}
+
+func (c *fakeClipboard) Read() (string, error) {
+	return c.text, nil
+}
+
+func (c *fakeClipboard) Write(text string) error {
+	c.text = text
+	c.writes = append(c.writes, text)
+	if c.onWrite != nil {
+		c.onWrite(c)
+	}
+	return nil
+}
+
+func TestClientSession_Copy(t *testing.T) {
+	session, _, err := NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	visa := demoItemID(t, session, "Demo Visa")
+
+	tests := []struct {
+		name       string
+		field      string
+		wantCopied string
+		wantErr    string
+	}{
+		{name: "default field", wantCopied: "4111111111111111"},
+		{name: "named field", field: "expiry_date", wantCopied: "12/30"},
+		{name: "unknown field", field: "pin", wantErr: "no pin field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clipboard := &fakeClipboard{}
+			session.SetClipboard(clipboard)
+
+			err := session.CopyCommand(context.Background(), visa, tt.field, time.Millisecond)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("CopyCommand() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(clipboard.writes) != 0 {
+					t.Errorf("Expected nothing to be copied, got %q", clipboard.writes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CopyCommand() error = %v", err)
+			}
+			if len(clipboard.writes) != 2 || clipboard.writes[0] != tt.wantCopied || clipboard.text != "" {
+				t.Errorf("Expected %q to be copied and then cleared, got %q", tt.wantCopied, clipboard.writes)
+			}
+		})
+	}
+}
+
+func TestClientSession_Copy_KeepsNewerClipboard(t *testing.T) {
+	session, _, err := NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clipboard := &fakeClipboard{onWrite: func(c *fakeClipboard) {
+		c.text = "copied elsewhere"
+		cancel()
+	}}
+	session.SetClipboard(clipboard)
+
+	if err := session.CopyCommand(ctx, demoItemID(t, session, "Demo Email"), "", time.Hour); err != nil {
+		t.Fatalf("CopyCommand() error = %v", err)
+	}
+	if len(clipboard.writes) != 1 || clipboard.text != "copied elsewhere" {
+		t.Errorf("Expected a newer clipboard to be left alone, got writes %q", clipboard.writes)
+	}
+}
+
+func TestClientSession_Copy_NotAuthenticated(t *testing.T) {
+	session := NewClientSession(NewClient("http://localhost:8080"))
+	if err := session.CopyCommand(context.Background(), "id", "", time.Second); err != ErrNotAuthenticated {
+		t.Errorf("CopyCommand() error = %v, want ErrNotAuthenticated", err)
+	}
+}
+
+func TestClipboardTools(t *testing.T) {
+	if tools := clipboardTools("linux"); len(tools) != 3 || tools[0].write[0] != "wl-copy" {
+		t.Errorf("Expected wl-copy, xclip and xsel on Linux, got %v", tools)
+	}
+	if tools := clipboardTools("plan9"); tools != nil {
+		t.Errorf("Expected no clipboard tools on plan9, got %v", tools)
+	}
+}
@@ -0,0 +1,324 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+// GetCollections gets all collections of the user
+func (c *Client) GetCollections(ctx context.Context) ([]models.Collection, error) {
+	var resp models.CollectionsResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/collections", nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Collections, nil
+}
+
+// CreateCollection creates a collection
+func (c *Client) CreateCollection(ctx context.Context, req models.CollectionRequest) (*models.Collection, error) {
+	var resp models.CollectionResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/collections", req, &resp, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &resp.Collection, nil
+}
+
+// UpdateCollection renames a collection or moves it to another parent
+func (c *Client) UpdateCollection(ctx context.Context, id uuid.UUID, req models.CollectionRequest) (*models.Collection, error) {
+	var resp models.CollectionResponse
+	path := "/api/v1/collections/" + url.PathEscape(id.String())
+	if err := c.doJSON(ctx, http.MethodPut, path, req, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &resp.Collection, nil
+}
+
+// DeleteCollection deletes an empty collection
+func (c *Client) DeleteCollection(ctx context.Context, id uuid.UUID) error {
+	path := "/api/v1/collections/" + url.PathEscape(id.String())
+	return c.doJSON(ctx, http.MethodDelete, path, nil, nil, http.StatusNoContent)
+}
+
+// SetDataCollection files an item in a collection, or at the top level when
+// collectionID is nil
+func (c *Client) SetDataCollection(ctx context.Context, id string, collectionID *uuid.UUID) (*models.Data, error) {
+	var resp models.DataResponse
+	path := "/api/v1/data/" + url.PathEscape(id) + "/collection"
+	if err := c.doJSON(ctx, http.MethodPut, path, models.DataCollectionRequest{CollectionID: collectionID}, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+// collectionTree indexes collections by ID and by parent for path lookups
+type collectionTree struct {
+	byID     map[uuid.UUID]models.Collection
+	children map[uuid.UUID][]models.Collection
+}
+
+func newCollectionTree(collections []models.Collection) *collectionTree {
+	tree := &collectionTree{
+		byID:     make(map[uuid.UUID]models.Collection, len(collections)),
+		children: make(map[uuid.UUID][]models.Collection),
+	}
+	for _, collection := range collections {
+		tree.byID[collection.ID] = collection
+		parent := uuid.Nil
+		if collection.ParentID != nil {
+			parent = *collection.ParentID
+		}
+		tree.children[parent] = append(tree.children[parent], collection)
+	}
+	for _, children := range tree.children {
+		sort.Slice(children, func(i, j int) bool {
+			return strings.ToLower(children[i].Name) < strings.ToLower(children[j].Name)
+		})
+	}
+	return tree
+}
+
+// splitCollectionPath splits a slash separated path into collection names;
+// the root, "/" or "", has none
+func splitCollectionPath(path string) []string {
+	var names []string
+	for _, name := range strings.Split(path, "/") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// child returns the collection named name in parent, uuid.Nil being the top level
+func (t *collectionTree) child(parent uuid.UUID, name string) (models.Collection, bool) {
+	for _, collection := range t.children[parent] {
+		if strings.EqualFold(collection.Name, name) {
+			return collection, true
+		}
+	}
+	return models.Collection{}, false
+}
+
+// find returns the ID of the collection at path, uuid.Nil for the root
+func (t *collectionTree) find(path string) (uuid.UUID, error) {
+	id := uuid.Nil
+	for _, name := range splitCollectionPath(path) {
+		collection, ok := t.child(id, name)
+		if !ok {
+			return uuid.Nil, fmt.Errorf("no collection %q", path)
+		}
+		id = collection.ID
+	}
+	return id, nil
+}
+
+// path returns the full path of a collection, like /Work/Servers
+func (t *collectionTree) path(id uuid.UUID) string {
+	var names []string
+	for collection, ok := t.byID[id]; ok; {
+		names = append([]string{collection.Name}, names...)
+		if collection.ParentID == nil {
+			break
+		}
+		collection, ok = t.byID[*collection.ParentID]
+	}
+	return "/" + strings.Join(names, "/")
+}
+
+func (s *ClientSession) collectionTree(ctx context.Context) (*collectionTree, error) {
+	collections, err := s.cli.GetCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+	return newCollectionTree(collections), nil
+}
+
+// optionalID returns nil for uuid.Nil, which stands for the top level
+func optionalID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+// MakeCollection creates the collection at a slash separated path along with
+// any missing parents, and returns it. Existing collections are kept.
+func (s *ClientSession) MakeCollection(ctx context.Context, path string) (*models.Collection, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	names := splitCollectionPath(path)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("collection path is required")
+	}
+
+	tree, err := s.collectionTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var current models.Collection
+	for _, name := range names {
+		if existing, ok := tree.child(current.ID, name); ok {
+			current = existing
+			continue
+		}
+		created, err := s.cli.CreateCollection(ctx, models.CollectionRequest{Name: name, ParentID: optionalID(current.ID)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection %q: %w", name, err)
+		}
+		current = *created
+	}
+	return &current, nil
+}
+
+// MkdirCommand creates a collection and its missing parents
+func (s *ClientSession) MkdirCommand(ctx context.Context, path string) error {
+	collection, err := s.MakeCollection(ctx, path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Collection %s is ready (ID: %s)\n", "/"+strings.Join(splitCollectionPath(path), "/"), collection.ID)
+	return nil
+}
+
+// RmdirCommand deletes an empty collection
+func (s *ClientSession) RmdirCommand(ctx context.Context, path string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	tree, err := s.collectionTree(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := tree.find(path)
+	if err != nil {
+		return err
+	}
+	if id == uuid.Nil {
+		return fmt.Errorf("the root cannot be deleted")
+	}
+	if err := s.cli.DeleteCollection(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	fmt.Printf("Collection %s deleted\n", tree.path(id))
+	return nil
+}
+
+// MoveCommand files an item by ID or unique ID prefix in the collection at
+// path, "/" being the top level. A source starting with "/" is a collection,
+// which is moved into the target with its contents.
+func (s *ClientSession) MoveCommand(ctx context.Context, source, target string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	tree, err := s.collectionTree(ctx)
+	if err != nil {
+		return err
+	}
+	targetID, err := tree.find(target)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(source, "/") {
+		id, err := tree.find(source)
+		if err != nil {
+			return err
+		}
+		if id == uuid.Nil {
+			return fmt.Errorf("the root cannot be moved")
+		}
+		collection := tree.byID[id]
+		if _, err := s.cli.UpdateCollection(ctx, id, models.CollectionRequest{Name: collection.Name, ParentID: optionalID(targetID)}); err != nil {
+			return fmt.Errorf("failed to move collection: %w", err)
+		}
+		fmt.Printf("Moved %s to %s\n", tree.path(id), tree.path(targetID))
+		return nil
+	}
+
+	id, err := s.resolveID(ctx, source)
+	if err != nil {
+		return err
+	}
+	data, err := s.cli.SetDataCollection(ctx, id, optionalID(targetID))
+	if err != nil {
+		return fmt.Errorf("failed to move data: %w", err)
+	}
+	fmt.Printf("Moved %q to %s\n", CleanQuotes(data.Name), tree.path(targetID))
+	return nil
+}
+
+// TreeCommand lists the collection at path, the whole vault for "/", as a tree
+// of collections and the items filed in them
+func (s *ClientSession) TreeCommand(ctx context.Context, path string) error {
+	return s.writeTree(ctx, os.Stdout, path)
+}
+
+func (s *ClientSession) writeTree(ctx context.Context, w io.Writer, path string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	tree, err := s.collectionTree(ctx)
+	if err != nil {
+		return err
+	}
+	root, err := tree.find(path)
+	if err != nil {
+		return err
+	}
+	all, err := s.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+
+	items := make(map[uuid.UUID][]models.Data)
+	for _, item := range all {
+		parent := uuid.Nil
+		// items of a collection deleted meanwhile show at the top level
+		if item.CollectionID != nil {
+			if _, ok := tree.byID[*item.CollectionID]; ok {
+				parent = *item.CollectionID
+			}
+		}
+		items[parent] = append(items[parent], item)
+	}
+	shortIDs := ShortIDs(all)
+
+	var walk func(id uuid.UUID, indent string)
+	walk = func(id uuid.UUID, indent string) {
+		children := tree.children[id]
+		entries := items[id]
+		sort.SliceStable(entries, func(i, j int) bool {
+			return strings.ToLower(CleanQuotes(entries[i].Name)) < strings.ToLower(CleanQuotes(entries[j].Name))
+		})
+		count := len(children) + len(entries)
+		branch := func(i int) (string, string) {
+			if i == count-1 {
+				return "└── ", "    "
+			}
+			return "├── ", "│   "
+		}
+		for i, child := range children {
+			prefix, next := branch(i)
+			fmt.Fprintf(w, "%s%s%s/\n", indent, prefix, child.Name)
+			walk(child.ID, indent+next)
+		}
+		for i, item := range entries {
+			prefix, _ := branch(len(children) + i)
+			fmt.Fprintf(w, "%s%s%s  %s\n", indent, prefix, shortIDs[item.ID.String()], CleanQuotes(item.Name))
+		}
+	}
+
+	fmt.Fprintln(w, tree.path(root))
+	walk(root, "")
+	return nil
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestClientSession_Collections(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	email := demoItemID(t, session, "Demo Email")
+
+	servers, err := session.MakeCollection(ctx, "Work/Servers")
+	if err != nil {
+		t.Fatalf("MakeCollection() error = %v", err)
+	}
+	again, err := session.MakeCollection(ctx, "/work/servers/")
+	if err != nil || again.ID != servers.ID {
+		t.Errorf("Expected an existing path to be reused, got %v, %v", again, err)
+	}
+	if _, err := session.MakeCollection(ctx, "Personal"); err != nil {
+		t.Fatalf("MakeCollection() error = %v", err)
+	}
+
+	if err := session.MoveCommand(ctx, email, "Work/Servers"); err != nil {
+		t.Fatalf("MoveCommand() error = %v", err)
+	}
+	data, err := session.Get(ctx, email)
+	if err != nil || data.CollectionID == nil || *data.CollectionID != servers.ID {
+		t.Fatalf("Expected the item in Work/Servers, got %+v, %v", data, err)
+	}
+	if err := session.MoveCommand(ctx, email, "Missing"); err == nil {
+		t.Error("Expected moving into a missing collection to fail")
+	}
+
+	var out bytes.Buffer
+	if err := session.writeTree(ctx, &out, "/"); err != nil {
+		t.Fatalf("writeTree() error = %v", err)
+	}
+	tree := out.String()
+	for _, want := range []string{"/\n├── Personal/\n├── Work/\n│   └── Servers/\n│       └── ", "  Demo Email\n", "└── "} {
+		if !strings.Contains(tree, want) {
+			t.Errorf("Expected the tree to contain %q:\n%s", want, tree)
+		}
+	}
+	if strings.Index(tree, "Demo Email") > strings.Index(tree, "Demo Visa") {
+		t.Errorf("Expected collections before top level items:\n%s", tree)
+	}
+
+	if err := session.RmdirCommand(ctx, "Work/Servers"); err == nil {
+		t.Error("Expected deleting a collection with items to fail")
+	}
+	if err := session.MoveCommand(ctx, "/Work/Servers", "Personal"); err != nil {
+		t.Fatalf("MoveCommand() error = %v", err)
+	}
+	if err := session.MoveCommand(ctx, "/Personal", "Personal/Servers"); err == nil {
+		t.Error("Expected moving a collection into itself to fail")
+	}
+
+	out.Reset()
+	if err := session.writeTree(ctx, &out, "Personal"); err != nil {
+		t.Fatalf("writeTree() error = %v", err)
+	}
+	if tree := out.String(); !strings.HasPrefix(tree, "/Personal\n└── Servers/\n    └── ") || strings.Contains(tree, "Demo Visa") {
+		t.Errorf("Expected only the Personal subtree:\n%s", tree)
+	}
+	if err := session.RmdirCommand(ctx, "Work"); err != nil {
+		t.Errorf("RmdirCommand() error = %v", err)
+	}
+}
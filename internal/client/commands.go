@@ -5,32 +5,57 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
+	"github.com/a2sh3r/gophkeeper/internal/client/localstore"
 	"github.com/a2sh3r/gophkeeper/internal/crypto"
 	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
 )
 
 // ErrNotAuthenticated is returned when session is not authenticated
 var ErrNotAuthenticated = fmt.Errorf("session not authenticated - please login first")
 
+// ErrSaltMismatch is returned when the server salt differs from the one stored locally
+var ErrSaltMismatch = fmt.Errorf("server salt does not match locally stored salt - refusing to continue")
+
 // RegisterCommand handles user registration
 func (s *ClientSession) RegisterCommand(ctx context.Context, username, password string, config *Config) error {
 	if len(username) == 0 || len(password) == 0 {
 		return fmt.Errorf("username and password are required")
 	}
 
-	fmt.Print("Enter master password for data encryption (min 8 characters): ")
-	scanner := bufio.NewScanner(os.Stdin)
-	if !scanner.Scan() {
+	if err := s.checkRegistrationOpen(ctx); err != nil {
+		return err
+	}
+	if err := s.checkCredentials(ctx, username, password); err != nil {
+		return err
+	}
+	if err := s.checkCredentialsSize(ctx, routeRegister, username, password); err != nil {
+		return err
+	}
+
+	masterPassword, ok := readSecret(bufio.NewScanner(os.Stdin), "Enter master password for data encryption (min 8 characters): ")
+	if !ok {
 		return fmt.Errorf("failed to read master password")
 	}
-	masterPassword := scanner.Text()
 
-	if len(masterPassword) < 8 {
-		return fmt.Errorf("master password must be at least 8 characters long")
+	if err := s.registerWithMasterPassword(ctx, username, password, masterPassword, config); err != nil {
+		return err
+	}
+
+	fmt.Println("Master password set for data encryption")
+	return nil
+}
+
+// registerWithMasterPassword registers the user and unlocks the session with the master password
+func (s *ClientSession) registerWithMasterPassword(ctx context.Context, username, password, masterPassword string, config *Config) error {
+	if len(masterPassword) < minMasterPasswordLength {
+		return fmt.Errorf("master password must be at least %d characters long", minMasterPasswordLength)
 	}
 
 	resp, err := s.Register(ctx, username, password, masterPassword)
@@ -43,22 +68,28 @@ func (s *ClientSession) RegisterCommand(ctx context.Context, username, password
 		return fmt.Errorf("failed to decode salt: %w", err)
 	}
 
-	cryptoManager, err := crypto.NewCryptoManagerWithSalt(masterPassword, saltBytes)
+	cryptoManager, err := s.deriveCryptoManager(masterPassword, saltBytes, resp.KDFIterations)
 	if err != nil {
 		return fmt.Errorf("failed to initialize encryption: %w", err)
 	}
 
 	s.SetCryptoManager(cryptoManager, masterPassword)
 
+	config.Username = username
 	config.Token = resp.Token
 	config.Salt = resp.Salt
+	config.SaltIterations = cryptoManager.Iterations()
 	if err := SaveConfig(config); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 	s.cli.SetToken(resp.Token)
+	s.publishVerifier(ctx, cryptoManager)
+
+	s.recordEvent(EventUnlock, map[string]string{"username": username, "action": "register"})
+	s.publishAuditKey(ctx)
+	s.publishShareKey(ctx)
 
 	fmt.Printf("Successfully registered user: %s\n", resp.User.Username)
-	fmt.Println("Master password set for data encryption")
 	return nil
 }
 
@@ -68,81 +99,230 @@ func (s *ClientSession) LoginCommand(ctx context.Context, username, password str
 		return fmt.Errorf("username and password are required")
 	}
 
+	if err := s.checkCredentialsSize(ctx, routeLogin, username, password); err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
 	resp, err := s.Login(ctx, username, password)
 	if err != nil {
 		return fmt.Errorf("login failed: %w", err)
 	}
+	return s.finishLogin(ctx, resp, username, config)
+}
+
+// finishLogin unlocks the vault of a login with the master password and
+// saves the session
+func (s *ClientSession) finishLogin(ctx context.Context, resp *models.AuthResponse, username string, config *Config) error {
+	s.cli.SetToken(resp.Token)
+
+	salt, err := s.resolveSalt(ctx, resp.Salt)
+	if err != nil {
+		return err
+	}
 
-	fmt.Print("Enter master password for data decryption: ")
 	scanner := bufio.NewScanner(os.Stdin)
-	if !scanner.Scan() {
-		return fmt.Errorf("failed to read master password")
+	if err := CheckStoredSalt(config, username, salt); err != nil {
+		fmt.Println("The server has a new salt for your vault, as after 'rotate-master' on another device.")
+		fmt.Print("Did you change your master password? (y/N): ")
+		if !scanner.Scan() {
+			return err
+		}
+		if answer := strings.ToLower(strings.TrimSpace(scanner.Text())); answer != "y" && answer != "yes" {
+			return err
+		}
 	}
-	masterPassword := scanner.Text()
 
-	saltBytes, err := base64.StdEncoding.DecodeString(resp.Salt)
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
 	if err != nil {
 		return fmt.Errorf("failed to decode salt: %w", err)
 	}
 
-	cryptoManager, err := crypto.NewCryptoManagerWithSalt(masterPassword, saltBytes)
-	if err != nil {
-		return fmt.Errorf("failed to initialize encryption: %w", err)
-	}
+	for attempt := 1; ; attempt++ {
+		masterPassword, ok := readSecret(scanner, "Enter master password for data decryption: ")
+		if !ok {
+			return fmt.Errorf("failed to read master password")
+		}
 
-	s.SetCryptoManager(cryptoManager, masterPassword)
+		cryptoManager, err := s.deriveCryptoManager(masterPassword, saltBytes, resp.KDFIterations)
+		if err != nil {
+			return fmt.Errorf("failed to initialize encryption: %w", err)
+		}
+
+		err = s.verifyMasterPassword(ctx, cryptoManager)
+		if err == nil {
+			s.SetCryptoManager(cryptoManager, masterPassword)
+			break
+		}
+		if !errors.Is(err, ErrWrongMasterPassword) {
+			return err
+		}
 
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"username": username, "action": "login"})
+		if attempt >= maxUnlockAttempts {
+			return fmt.Errorf("too many failed master password attempts")
+		}
+		fmt.Println("Wrong master password, please try again")
+		if attempt >= hintAfterFailures {
+			s.showPasswordHint(ctx)
+		}
+	}
+
+	config.Username = username
 	config.Token = resp.Token
-	config.Salt = resp.Salt
+	config.Salt = salt
+	config.SaltIterations = resp.KDFIterations
 	if err := SaveConfig(config); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
-	s.cli.SetToken(resp.Token)
+
+	s.recordEvent(EventUnlock, map[string]string{"username": username, "action": "login"})
+	s.publishAuditKey(ctx)
+	s.publishShareKey(ctx)
 
 	fmt.Printf("Successfully logged in as: %s\n", resp.User.Username)
 	fmt.Println("Master password verified for data decryption")
 	return nil
 }
 
-// ListCommand handles listing all data
-func (s *ClientSession) ListCommand(ctx context.Context) error {
-	data, err := s.List(ctx)
+// resolveSalt returns the account salt, registering a fresh one for accounts
+// created before salts were issued. If another device won the race to register
+// it, the salt already stored on the server is used instead.
+func (s *ClientSession) resolveSalt(ctx context.Context, loginSalt string) (string, error) {
+	if loginSalt != "" {
+		return loginSalt, nil
+	}
+
+	saltBytes, err := crypto.GenerateSalt()
+	if err != nil {
+		return "", err
+	}
+
+	salt, err := s.cli.SetSalt(ctx, base64.StdEncoding.EncodeToString(saltBytes))
+	if err == nil {
+		return salt, nil
+	}
+
+	salt, getErr := s.cli.GetSalt(ctx)
+	if getErr != nil || salt == "" {
+		return "", fmt.Errorf("failed to register salt: %w", err)
+	}
+	return salt, nil
+}
+
+// CheckStoredSalt refuses to continue when the salt cached for the same user
+// differs from the one the server returned
+func CheckStoredSalt(config *Config, username, serverSalt string) error {
+	if config.Username != username || config.Salt == "" {
+		return nil
+	}
+	if config.Salt != serverSalt {
+		return ErrSaltMismatch
+	}
+	return nil
+}
+
+// ListCommand handles listing data, optionally limited to one environment.
+// Items are grouped by type with short IDs unless flat is set.
+func (s *ClientSession) ListCommand(ctx context.Context, environment string, flat bool) error {
+	data, note, err := s.listForDisplay(ctx, environment)
 	if err != nil {
 		return fmt.Errorf("failed to get data: %w", err)
 	}
+	if note != "" {
+		fmt.Println(note)
+	}
 
 	if len(data) == 0 {
+		if environment != "" {
+			fmt.Printf("No data found in environment %s\n", environment)
+			return nil
+		}
 		fmt.Println("No data found")
 		return nil
 	}
 
-	fmt.Printf("Found %d items:\n", len(data))
+	if environment != "" {
+		fmt.Printf("Found %d items in environment %s:\n", len(data), environment)
+	} else {
+		fmt.Printf("Found %d items:\n", len(data))
+	}
+
+	usage := s.loadUsage()
+	if !flat {
+		// short IDs must be unique across the vault, not just this environment
+		all := data
+		if environment != "" {
+			if all, _, err = s.listForDisplay(ctx, ""); err != nil {
+				return fmt.Errorf("failed to get data: %w", err)
+			}
+		}
+		return WriteGroupedList(os.Stdout, data, ShortIDs(all), usage, environment == "")
+	}
 	for _, item := range data {
 		fmt.Printf("  %s [%s] - %s", item.ID.String(), item.Type, CleanQuotes(item.Name))
+		if item.Environment != "" && environment == "" {
+			fmt.Printf(" (%s)", item.Environment)
+		}
 		if item.Description != "" {
 			fmt.Printf(" - %s", CleanQuotes(item.Description))
 		}
+		if used, ok := usage[item.ID.String()]; ok {
+			fmt.Printf(" (last used %s)", used.Local().Format("2006-01-02"))
+		}
 		fmt.Printf("\n")
 	}
 	return nil
 }
 
-// GetCommand handles getting data by ID
-func (s *ClientSession) GetCommand(ctx context.Context, id string) error {
+// SearchCommand lists the items whose name, description or metadata contain
+// query, searched on the server rather than in a full listing
+func (s *ClientSession) SearchCommand(ctx context.Context, query string) error {
+	data, err := s.Search(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to search data: %w", err)
+	}
+	if len(data) == 0 {
+		fmt.Printf("No items match %q\n", query)
+		return nil
+	}
+
+	// short IDs must be unique across the vault, not just the matches
+	all, _, err := s.listForDisplay(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+	fmt.Printf("Found %d items matching %q:\n", len(data), query)
+	return WriteGroupedList(os.Stdout, data, ShortIDs(all), s.loadUsage(), true)
+}
+
+// GetCommand handles getting data by ID. Secrets are masked unless reveal is set.
+func (s *ClientSession) GetCommand(ctx context.Context, id string, reveal bool) error {
 	if len(id) == 0 {
 		return fmt.Errorf("data ID is required")
 	}
 
-	data, err := s.Get(ctx, id)
+	data, note, err := s.getForDisplay(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get data: %w", err)
 	}
+	if note != "" {
+		fmt.Println(note)
+	}
 
-	return DisplayStructuredData(data, s.cryptoManager)
+	if err := WriteStructuredData(os.Stdout, data, s.cryptoManager, reveal); err != nil {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": data.ID.String()})
+		return err
+	}
+	if !reveal && hasSecrets(data.Type) {
+		fmt.Printf("(secrets hidden, use 'get %s --reveal' to show them)\n", id)
+	}
+	s.writeComments(ctx, os.Stdout, data.ID.String())
+	s.writeAttachments(ctx, os.Stdout, data.ID.String())
+	s.recordUsage(data.ID.String())
+	return nil
 }
 
-// CreateCommand handles creating new data
-func (s *ClientSession) CreateCommand(ctx context.Context, dataType, name, description string) error {
+// CreateCommand handles creating new data in the given environment (empty for none)
+func (s *ClientSession) CreateCommand(ctx context.Context, dataType, name, description, environment string) error {
 	if !s.IsAuthenticated() {
 		return ErrNotAuthenticated
 	}
@@ -157,13 +337,28 @@ func (s *ClientSession) CreateCommand(ctx context.Context, dataType, name, descr
 
 	switch dataType {
 	case "login_password":
-		dataContent, metadata, err = CreateLoginPasswordData()
+		if dataContent, metadata, err = CreateLoginPasswordData(); err == nil {
+			s.warnIfPwned(ctx, dataContent)
+		}
 	case "text":
 		dataContent, metadata, err = CreateTextData()
 	case "binary":
-		dataContent, metadata, err = CreateBinaryData()
+		var path, notes string
+		if path, notes, err = promptBinaryFile(); err != nil {
+			break
+		}
+		if info, statErr := os.Stat(path); statErr == nil {
+			if chunkSize := s.chunkSizeFor(ctx, info.Size()); chunkSize > 0 {
+				return s.createChunkedBinary(ctx, path, notes, models.DataRequest{
+					Name: name, Description: description, Environment: environment,
+				}, chunkSize)
+			}
+		}
+		dataContent, metadata, err = readBinaryData(path, notes)
 	case "bank_card":
 		dataContent, metadata, err = CreateBankCardData()
+	case "otp":
+		dataContent, metadata, err = CreateOTPData()
 	default:
 		return fmt.Errorf("unknown data type: %s", dataType)
 	}
@@ -177,15 +372,28 @@ func (s *ClientSession) CreateCommand(ctx context.Context, dataType, name, descr
 		return fmt.Errorf("failed to encrypt data: %w", err)
 	}
 
+	if err := s.checkPayloadSize(ctx, routeCreateData, len(encryptedData)+len(metadata)); err != nil {
+		return err
+	}
+
 	dataReq := models.DataRequest{
 		Type:        models.DataType(dataType),
 		Name:        name,
 		Description: description,
 		Data:        encryptedData,
 		Metadata:    metadata,
+		Environment: environment,
 	}
 
 	data, err := s.Create(ctx, dataReq)
+	if isOffline(err) && s.localStore != nil {
+		change := localstore.Change{Op: localstore.OpCreate, ID: uuid.New(), Request: dataReq}
+		if err := s.queueOffline(change); err != nil {
+			return fmt.Errorf("server unreachable and saving locally failed: %w", err)
+		}
+		fmt.Printf("Server unreachable: item saved locally with ID %s; run 'sync' when back online\n", change.ID)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create data: %w", err)
 	}
@@ -194,8 +402,11 @@ func (s *ClientSession) CreateCommand(ctx context.Context, dataType, name, descr
 	return nil
 }
 
-// UpdateCommand handles updating existing data
-func (s *ClientSession) UpdateCommand(ctx context.Context, id string) error {
+// UpdateRawCommand replaces the whole payload of an item with one typed line.
+//
+// Deprecated: structured items lose their fields this way; UpdateCommand edits
+// them field by field. Kept behind "update --raw" for a transition period.
+func (s *ClientSession) UpdateRawCommand(ctx context.Context, id string) error {
 	if !s.IsAuthenticated() {
 		return ErrNotAuthenticated
 	}
@@ -204,16 +415,18 @@ func (s *ClientSession) UpdateCommand(ctx context.Context, id string) error {
 		return fmt.Errorf("data ID is required")
 	}
 
-	data, err := s.Get(ctx, id)
+	data, _, err := s.getForDisplay(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get data: %w", err)
 	}
 
 	decryptedData, err := s.cryptoManager.Decrypt(data.Data)
 	if err != nil {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": data.ID.String()})
 		return fmt.Errorf("failed to decrypt current data: %w", err)
 	}
 
+	fmt.Fprintln(os.Stderr, rawUpdateWarning)
 	fmt.Printf("Current data: %s\n", string(decryptedData))
 	fmt.Print("Enter new data content: ")
 	scanner := bufio.NewScanner(os.Stdin)
@@ -228,19 +441,40 @@ func (s *ClientSession) UpdateCommand(ctx context.Context, id string) error {
 	}
 
 	dataReq := models.DataRequest{
-		Type:        data.Type,
-		Name:        data.Name,
-		Description: data.Description,
-		Data:        encryptedContent,
-		Metadata:    data.Metadata,
-	}
+		Type:          data.Type,
+		Name:          data.Name,
+		Description:   data.Description,
+		Data:          encryptedContent,
+		Metadata:      data.Metadata,
+		Environment:   data.Environment,
+		BaseUpdatedAt: &data.UpdatedAt,
+		BaseRevision:  baseRevision(data),
+	}
+	return s.saveUpdate(ctx, data, dataReq, scanner, os.Stdout)
+}
 
-	updatedData, err := s.Update(ctx, id, dataReq)
+// saveUpdate stores an edit. If the item was changed on another device since
+// it was read, the user is asked via in and out whether to see both versions,
+// overwrite the other change, keep the edit as a conflict copy or discard it.
+// Without an answer, e.g. when in is nil, the edit is kept as a conflict copy.
+func (s *ClientSession) saveUpdate(ctx context.Context, data *models.Data, dataReq models.DataRequest, in *bufio.Scanner, out io.Writer) error {
+	updatedData, err := s.Update(ctx, data.ID.String(), dataReq)
+	if isOffline(err) && s.localStore != nil {
+		change := localstore.Change{Op: localstore.OpUpdate, ID: data.ID, Request: dataReq, BaseUpdatedAt: data.UpdatedAt}
+		if err := s.queueOffline(change); err != nil {
+			return fmt.Errorf("server unreachable and saving locally failed: %w", err)
+		}
+		fmt.Fprintln(out, "Server unreachable: edit saved locally; run 'sync' when back online")
+		return nil
+	}
+	if errors.Is(err, ErrConflict) {
+		return s.resolveUpdateConflict(ctx, data, dataReq, in, out)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update data: %w", err)
 	}
 
-	fmt.Printf("Successfully updated encrypted data: %s\n", updatedData.ID)
+	fmt.Fprintf(out, "Successfully updated encrypted data: %s\n", updatedData.ID)
 	return nil
 }
 
@@ -250,6 +484,11 @@ func (s *ClientSession) DeleteCommand(ctx context.Context, id string) error {
 		return fmt.Errorf("data ID is required")
 	}
 
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Are you sure you want to delete data with ID %s? (y/N): ", id)
 	scanner := bufio.NewScanner(os.Stdin)
 	if !scanner.Scan() {
@@ -262,15 +501,50 @@ func (s *ClientSession) DeleteCommand(ctx context.Context, id string) error {
 		return nil
 	}
 
-	err := s.Delete(ctx, id)
+	err = s.Delete(ctx, id)
+	if isOffline(err) && s.localStore != nil {
+		if err := s.queueOfflineDelete(id); err != nil {
+			return fmt.Errorf("server unreachable and saving locally failed: %w", err)
+		}
+		fmt.Printf("Server unreachable: deletion of %s saved locally; run 'sync' when back online\n", id)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete data: %w", err)
 	}
 
+	s.recordEvent(EventWipe, map[string]string{"data_id": id})
+
 	fmt.Printf("Successfully deleted data: %s\n", id)
 	return nil
 }
 
+// DeleteManyCommand handles deleting several items at once, all of them or none
+func (s *ClientSession) DeleteManyCommand(ctx context.Context, refs []string) error {
+	fmt.Printf("Are you sure you want to delete %d items? (y/N): ", len(refs))
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		return fmt.Errorf("failed to read confirmation")
+	}
+	confirmation := strings.ToLower(strings.TrimSpace(scanner.Text()))
+
+	if confirmation != "y" && confirmation != "yes" {
+		fmt.Println("Deletion cancelled")
+		return nil
+	}
+
+	ids, err := s.DeleteMany(ctx, refs)
+	for _, id := range ids {
+		s.recordEvent(EventWipe, map[string]string{"data_id": id})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete data: %w", err)
+	}
+
+	fmt.Printf("Successfully deleted %d items\n", len(ids))
+	return nil
+}
+
 // SaveCommand handles saving binary data to file
 func (s *ClientSession) SaveCommand(ctx context.Context, id, outputPath string) error {
 	if !s.IsAuthenticated() {
@@ -312,8 +586,32 @@ func (s *ClientSession) SaveCommand(ctx context.Context, id, outputPath string)
 		}
 	}
 
+	if binaryData.Chunks > 0 {
+		if err := s.downloadBinary(ctx, data, binaryData, outputPath); err != nil {
+			return err
+		}
+	} else if err := s.saveWholeBinary(data, outputPath); err != nil {
+		return err
+	}
+
+	s.recordEvent(EventExport, map[string]string{"data_id": data.ID.String(), "path": outputPath})
+	s.recordUsage(data.ID.String())
+
+	fmt.Printf("Successfully saved decrypted binary data to: %s\n", outputPath)
+	fmt.Printf("File: %s\n", binaryData.FileName)
+	fmt.Printf("Size: %d bytes\n", binaryData.Size)
+	fmt.Printf("MIME Type: %s\n", binaryData.MimeType)
+	if binaryData.Notes != "" {
+		fmt.Printf("Notes: %s\n", binaryData.Notes)
+	}
+	return nil
+}
+
+// saveWholeBinary writes the content of a binary item stored in the item itself
+func (s *ClientSession) saveWholeBinary(data *models.Data, outputPath string) error {
 	decryptedData, err := s.cryptoManager.Decrypt(data.Data)
 	if err != nil {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": data.ID.String()})
 		return fmt.Errorf("failed to decrypt binary data: %w", err)
 	}
 
@@ -322,17 +620,8 @@ func (s *ClientSession) SaveCommand(ctx context.Context, id, outputPath string)
 		return fmt.Errorf("failed to decode base64 data: %w", err)
 	}
 
-	err = os.WriteFile(outputPath, fileData, 0644)
-	if err != nil {
+	if err := os.WriteFile(outputPath, fileData, 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
-
-	fmt.Printf("Successfully saved decrypted binary data to: %s\n", outputPath)
-	fmt.Printf("File: %s\n", binaryData.FileName)
-	fmt.Printf("Size: %d bytes\n", binaryData.Size)
-	fmt.Printf("MIME Type: %s\n", binaryData.MimeType)
-	if binaryData.Notes != "" {
-		fmt.Printf("Notes: %s\n", binaryData.Notes)
-	}
 	return nil
 }
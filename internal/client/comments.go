@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// maxCommentLength is the maximum comment length in characters
+const maxCommentLength = 500
+
+// ItemComment is a decrypted item comment
+type ItemComment struct {
+	CreatedAt time.Time
+	Text      string
+}
+
+// AddDataComment appends an encrypted comment to an item
+func (c *Client) AddDataComment(ctx context.Context, id string, ciphertext []byte) (*models.DataComment, error) {
+	var resp models.DataCommentResponse
+	path := "/api/v1/data/" + url.PathEscape(id) + "/comments"
+	if err := c.doJSON(ctx, http.MethodPost, path, models.DataCommentRequest{Ciphertext: ciphertext}, &resp, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &resp.Comment, nil
+}
+
+// GetDataComments gets the encrypted comments of an item, oldest first
+func (c *Client) GetDataComments(ctx context.Context, id string) ([]models.DataComment, error) {
+	var resp models.DataCommentsResponse
+	path := "/api/v1/data/" + url.PathEscape(id) + "/comments"
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Comments, nil
+}
+
+// AddComment encrypts a comment and appends it to an item by ID or unique ID prefix
+func (s *ClientSession) AddComment(ctx context.Context, id, text string) (*ItemComment, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > maxCommentLength {
+		return nil, fmt.Errorf("comment must be between 1 and %d characters", maxCommentLength)
+	}
+
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := s.cryptoManager.Encrypt([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt comment: %w", err)
+	}
+
+	comment, err := s.cli.AddDataComment(ctx, id, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add comment: %w", err)
+	}
+	return &ItemComment{CreatedAt: comment.CreatedAt, Text: text}, nil
+}
+
+// Comments decrypts the comments of an item in chronological order
+func (s *ClientSession) Comments(ctx context.Context, id string) ([]ItemComment, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+
+	encrypted, err := s.cli.GetDataComments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]ItemComment, 0, len(encrypted))
+	for _, comment := range encrypted {
+		text, err := s.cryptoManager.Decrypt(comment.Ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt comment: %w", err)
+		}
+		comments = append(comments, ItemComment{CreatedAt: comment.CreatedAt, Text: string(text)})
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+	return comments, nil
+}
+
+// writeComments renders comments below an item. Failures only skip the comments,
+// e.g. with servers that predate them.
+func (s *ClientSession) writeComments(ctx context.Context, w io.Writer, id string) {
+	comments, err := s.Comments(ctx, id)
+	if err != nil {
+		logger.Log.Warn("Failed to load comments", zap.Error(err), zap.String("data_id", id))
+		return
+	}
+	if len(comments) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Comments:")
+	for _, comment := range comments {
+		fmt.Fprintf(w, "  %s  %s\n", comment.CreatedAt.Local().Format("2006-01-02 15:04"), comment.Text)
+	}
+}
+
+// CommentCommand appends a comment such as "rotated after breach" to an item
+func (s *ClientSession) CommentCommand(ctx context.Context, id, text string) error {
+	if len(id) == 0 {
+		return fmt.Errorf("data ID is required")
+	}
+
+	comment, err := s.AddComment(ctx, id, text)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Comment added on %s\n", comment.CreatedAt.Local().Format("2006-01-02 15:04"))
+	return nil
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestClientSession_Comments(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	items, err := session.List(ctx)
+	if err != nil || len(items) == 0 {
+		t.Fatalf("List() = %d items, %v", len(items), err)
+	}
+	id := items[0].ID.String()
+
+	var out bytes.Buffer
+	session.writeComments(ctx, &out, id)
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing for an item without comments, got %q", out.String())
+	}
+
+	if _, err := session.AddComment(ctx, id, "  "); err == nil {
+		t.Error("Expected empty comment to be rejected")
+	}
+	if _, err := session.AddComment(ctx, id, strings.Repeat("x", maxCommentLength+1)); err == nil {
+		t.Error("Expected too long comment to be rejected")
+	}
+
+	if _, err := session.AddComment(ctx, id, "rotated after breach"); err != nil {
+		t.Fatalf("AddComment() error = %v", err)
+	}
+	if _, err := session.AddComment(ctx, ShortIDs(items)[id], "moved to the team vault"); err != nil {
+		t.Fatalf("AddComment() by short ID error = %v", err)
+	}
+
+	comments, err := session.Comments(ctx, id)
+	if err != nil {
+		t.Fatalf("Comments() error = %v", err)
+	}
+	if len(comments) != 2 || comments[0].Text != "rotated after breach" || comments[1].Text != "moved to the team vault" {
+		t.Errorf("Expected both comments in order, got %+v", comments)
+	}
+
+	encrypted, err := session.GetClient().GetDataComments(ctx, id)
+	if err != nil {
+		t.Fatalf("GetDataComments() error = %v", err)
+	}
+	if bytes.Contains(encrypted[0].Ciphertext, []byte("breach")) {
+		t.Error("Comments must be encrypted before upload")
+	}
+
+	session.writeComments(ctx, &out, id)
+	if !strings.Contains(out.String(), "Comments:") || strings.Index(out.String(), "breach") > strings.Index(out.String(), "team vault") {
+		t.Errorf("Expected comments listed oldest first, got %q", out.String())
+	}
+}
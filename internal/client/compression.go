@@ -0,0 +1,116 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+)
+
+// minCompressedRequest is the smallest request body worth compressing
+const minCompressedRequest = 1024
+
+// compressionTransport asks for compressed responses and decompresses them,
+// and compresses large request bodies once the server is known to accept
+// them. Older servers reject compressed bodies, so it waits for a response
+// advertising gzip in its Accept-Encoding header first.
+type compressionTransport struct {
+	next http.RoundTripper
+	// accepted is set once the server advertised compressed request bodies
+	accepted atomic.Bool
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *compressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" {
+		return next.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	if req.ContentLength >= minCompressedRequest && req.GetBody != nil && req.Header.Get("Content-Encoding") == "" &&
+		t.accepted.Load() {
+		if err := compressRequest(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(strings.ToLower(resp.Header.Get("Accept-Encoding")), "gzip") {
+		t.accepted.Store(true)
+	}
+	if encoding := strings.ToLower(resp.Header.Get("Content-Encoding")); encoding == "gzip" || encoding == "deflate" {
+		resp.Body = middleware.DecompressBody(resp.Body, encoding)
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+	return resp, nil
+}
+
+// reset forgets what the server supports, as after switching servers
+func (t *compressionTransport) reset() {
+	t.accepted.Store(false)
+}
+
+// compressRequest gzips the body of req if that makes it smaller. The
+// compressed body can be replayed for retries.
+func compressRequest(req *http.Request) error {
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := io.Copy(writer, body); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if int64(compressed.Len()) >= req.ContentLength {
+		return nil
+	}
+
+	payload := compressed.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(payload)), nil
+	}
+	req.ContentLength = int64(len(payload))
+	req.Header.Set("Content-Encoding", "gzip")
+	return nil
+}
+
+// innerTransport returns the transport below compression
+func (c *Client) innerTransport() http.RoundTripper {
+	if compressing, ok := c.httpClient.Transport.(*compressionTransport); ok {
+		return compressing.next
+	}
+	return c.httpClient.Transport
+}
+
+// setInnerTransport replaces the transport below compression
+func (c *Client) setInnerTransport(transport http.RoundTripper) {
+	if compressing, ok := c.httpClient.Transport.(*compressionTransport); ok {
+		compressing.next = transport
+		return
+	}
+	c.httpClient.Transport = transport
+}
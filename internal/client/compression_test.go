@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// encodingRecorder records the Content-Encoding of requests and responses
+type encodingRecorder struct {
+	next      http.RoundTripper
+	requests  []string
+	responses []string
+}
+
+func (r *encodingRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req.Header.Get("Content-Encoding"))
+	resp, err := r.next.RoundTrip(req)
+	if err == nil {
+		r.responses = append(r.responses, resp.Header.Get("Content-Encoding"))
+	}
+	return resp, err
+}
+
+func TestClient_Compression(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	router := mux.NewRouter()
+	server.RegisterRoutes(router, store, store, auth.NewJWTManager("test-secret", time.Hour), server.Options{})
+	recorder := &encodingRecorder{next: &handlerTransport{
+		handler: middleware.Compression(middleware.DefaultCompressionMinSize)(router),
+	}}
+
+	cli := NewClient(demoServerURL)
+	cli.setInnerTransport(recorder)
+	resp, err := cli.Register(ctx, "compressed", "password123", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	cli.SetToken(resp.Token)
+
+	payload := bytes.Repeat([]byte("compressible "), 500)
+	created, err := cli.CreateData(ctx, models.DataRequest{Type: "text", Name: "Large", Data: payload})
+	if err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	if recorder.requests[0] != "" || recorder.requests[1] != "gzip" {
+		t.Errorf("Expected only requests after the server advertised support to be compressed, got %q", recorder.requests)
+	}
+
+	got, err := cli.GetDataByID(ctx, created.ID.String())
+	if err != nil {
+		t.Fatalf("GetDataByID() error = %v", err)
+	}
+	if !bytes.Equal(got.Data, payload) {
+		t.Error("Expected the data to survive compression")
+	}
+	if last := recorder.responses[len(recorder.responses)-1]; last != "gzip" {
+		t.Errorf("Expected a compressed response, got %q", last)
+	}
+
+	cli.SetBaseURL(demoServerURL)
+	if _, err := cli.CreateData(ctx, models.DataRequest{Type: "text", Name: "Other", Data: payload}); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	if last := recorder.requests[len(recorder.requests)-1]; last != "" {
+		t.Errorf("Expected support to be forgotten after switching servers, got %q", last)
+	}
+}
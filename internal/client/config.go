@@ -16,8 +16,69 @@ const (
 // Config represents client configuration
 type Config struct {
 	ServerURL string `json:"server_url"`
+	Username  string `json:"username,omitempty"`
 	Token     string `json:"token"`
 	Salt      string `json:"salt"`
+	// SaltIterations is the PBKDF2 iteration count of the vault key under
+	// Salt, as the server has it; 0 means the default count
+	SaltIterations int `json:"salt_iterations,omitempty"`
+	// KDFIterations is the PBKDF2 iteration count of the vault keys of new
+	// accounts and master passwords; 0 means the default count. More
+	// iterations slow down guessing the master password, and every unlock.
+	KDFIterations int `json:"kdf_iterations,omitempty"`
+	// TokenStore is "keychain" when the token is kept in the OS keychain
+	// instead of this file
+	TokenStore string `json:"token_store,omitempty"`
+	// SessionKeyWrap seals the vault key remembered in the OS keychain
+	SessionKeyWrap []byte `json:"session_key_wrap,omitempty"`
+	// DefaultEnvironment is applied to new items and list filtering when no --env is given
+	DefaultEnvironment string `json:"default_environment,omitempty"`
+	// CACertFile is a PEM bundle of CA certificates trusted for the server in
+	// addition to the system roots, e.g. for a private CA
+	CACertFile string `json:"ca_cert_file,omitempty"`
+	// Notifications shows desktop notifications when long operations finish
+	Notifications bool `json:"notifications,omitempty"`
+	// BreachCheck checks new passwords against Have I Been Pwned
+	BreachCheck bool `json:"breach_check,omitempty"`
+	// FetchIcons fetches the favicons of the sites of new logins
+	FetchIcons bool `json:"fetch_icons,omitempty"`
+	// AutoLockMinutes locks the vault after that many minutes without input;
+	// 0 means DefaultAutoLockMinutes and a negative value turns auto-lock off
+	AutoLockMinutes int `json:"auto_lock_minutes,omitempty"`
+	// RetryAttempts is how many times a request is tried on transient network
+	// and server failures; 0 means DefaultRetryAttempts and a negative value
+	// turns retries off
+	RetryAttempts int `json:"retry_attempts,omitempty"`
+	// RetryBackoffMillis is the delay before the first retry, doubled for each
+	// further one up to RetryMaxBackoffMillis; 0 means the defaults
+	RetryBackoffMillis    int `json:"retry_backoff_millis,omitempty"`
+	RetryMaxBackoffMillis int `json:"retry_max_backoff_millis,omitempty"`
+	// RetryNoJitter waits the exact backoff instead of a random part of it
+	RetryNoJitter bool `json:"retry_no_jitter,omitempty"`
+	// TimeoutSeconds bounds a whole request, ConnectTimeoutSeconds establishing
+	// a connection and ReadTimeoutSeconds waiting for the response; 0 means the
+	// defaults and a negative value no limit
+	TimeoutSeconds        int `json:"timeout_seconds,omitempty"`
+	ConnectTimeoutSeconds int `json:"connect_timeout_seconds,omitempty"`
+	ReadTimeoutSeconds    int `json:"read_timeout_seconds,omitempty"`
+	// KeepAliveSeconds is the interval of TCP keep-alive probes; a negative
+	// value disables keep-alive and connection reuse
+	KeepAliveSeconds int `json:"keep_alive_seconds,omitempty"`
+	// IdleConnTimeoutSeconds is how long an unused connection is kept for reuse
+	IdleConnTimeoutSeconds int `json:"idle_conn_timeout_seconds,omitempty"`
+	// Proxy is the URL of the proxy to the server, "direct" for none; when
+	// empty HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honored
+	Proxy string `json:"proxy,omitempty"`
+	// Protocol is the API the vault calls are sent over: ProtocolGRPC, or the
+	// REST API when empty or ProtocolHTTP
+	Protocol string `json:"protocol,omitempty"`
+	// GRPCAddress is the host:port of the gRPC API; empty means the host of
+	// ServerURL on DefaultGRPCPort
+	GRPCAddress string `json:"grpc_address,omitempty"`
+	// InsecureSkipVerify accepts any server certificate; it is never saved
+	InsecureSkipVerify bool `json:"-"`
+	// Ephemeral disables persisting the config, e.g. in demo mode
+	Ephemeral bool `json:"-"`
 }
 
 // LoadConfig loads configuration from file
@@ -41,11 +102,17 @@ func NewConfig() *Config {
 		logger.Log.Error("Failed to unmarshal config", zap.Error(err))
 		return config
 	}
+	loadToken(config)
 	return config
 }
 
-// SaveConfig saves configuration to file
+// SaveConfig saves configuration to file, keeping the session token in the OS
+// keychain instead when one is available
 func SaveConfig(config *Config) error {
+	if config.Ephemeral {
+		return nil
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		logger.Log.Error("Failed to get home directory", zap.Error(err))
@@ -53,7 +120,14 @@ func SaveConfig(config *Config) error {
 	}
 
 	configPath := fmt.Sprintf("%s/%s", homeDir, configFile)
-	data, err := json.Marshal(config)
+	saved := *config
+	saved.TokenStore = ""
+	if storeToken(config) {
+		saved.Token = ""
+		saved.TokenStore = TokenStoreKeychain
+	}
+	config.TokenStore = saved.TokenStore
+	data, err := json.Marshal(saved)
 	if err != nil {
 		logger.Log.Error("Failed to marshal config", zap.Error(err))
 		return err
@@ -0,0 +1,283 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// ErrConflict is returned when an item was changed by another device since it was read
+var ErrConflict = errors.New("item was modified by another device")
+
+var conflictCopyPattern = regexp.MustCompile(`^(.*) \(conflict copy from (.+), (\d{4}-\d{2}-\d{2})\)$`)
+
+// Conflict resolution choices
+const (
+	ResolveKeepMine   = "mine"
+	ResolveKeepTheirs = "theirs"
+	ResolveKeepBoth   = "both"
+)
+
+// ConflictCopy is an item holding an edit that lost a concurrent update
+type ConflictCopy struct {
+	Copy         models.Data
+	OriginalName string
+	Device       string
+	Date         string
+	// Original is the item the copy was made from, nil if it no longer exists
+	Original *models.Data
+}
+
+// ConflictCopyName names the copy of an item edited concurrently on device
+func ConflictCopyName(name, device string, at time.Time) string {
+	return fmt.Sprintf("%s (conflict copy from %s, %s)", name, device, at.Format("2006-01-02"))
+}
+
+// DeviceName returns the name identifying this device in conflict copies
+func DeviceName() string {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return "unknown device"
+}
+
+// SaveConflictCopy stores a local edit that could not be applied to original
+// because another device changed it first, so neither version is lost
+func (s *ClientSession) SaveConflictCopy(ctx context.Context, original *models.Data, dataReq models.DataRequest) (*models.Data, error) {
+	dataReq.Name = ConflictCopyName(original.Name, DeviceName(), time.Now())
+	dataReq.BaseUpdatedAt = nil
+	dataReq.BaseRevision = nil
+	return s.Create(ctx, dataReq)
+}
+
+// baseRevision returns the revision an update of data is conditional on, or
+// nil for items read from servers that predate revisions
+func baseRevision(data *models.Data) *int {
+	if data.Revision == 0 {
+		return nil
+	}
+	revision := data.Revision
+	return &revision
+}
+
+// Answers to an update conflict
+const (
+	conflictShow      = "s"
+	conflictOverwrite = "o"
+	conflictCopy      = "c"
+	conflictDiscard   = "d"
+)
+
+// resolveUpdateConflict asks what to do with an edit of data that another
+// device changed first, instead of silently overwriting either change
+func (s *ClientSession) resolveUpdateConflict(ctx context.Context, data *models.Data, dataReq models.DataRequest, in *bufio.Scanner, out io.Writer) error {
+	fmt.Fprintf(out, "%q was changed on another device since you opened it.\n", CleanQuotes(data.Name))
+	for in != nil {
+		fmt.Fprintf(out, "[%s]how both versions, [%s]verwrite the other change, keep yours as a [%s]onflict copy or [%s]iscard it? [%s]: ",
+			conflictShow, conflictOverwrite, conflictCopy, conflictDiscard, conflictCopy)
+		if !in.Scan() {
+			break
+		}
+
+		switch strings.ToLower(strings.TrimSpace(in.Text())) {
+		case conflictShow:
+			current, err := s.cli.GetDataByID(ctx, data.ID.String())
+			if err != nil {
+				return fmt.Errorf("failed to get the other version: %w", err)
+			}
+			fmt.Fprintf(out, "--- Other device (%s) ---\n", current.UpdatedAt.Local().Format("2006-01-02 15:04"))
+			if err := WriteStructuredData(out, current, s.cryptoManager, true); err != nil {
+				return err
+			}
+			fmt.Fprintln(out, "--- Your edit ---")
+			edit := &models.Data{ID: data.ID, Type: dataReq.Type, Name: dataReq.Name, Description: dataReq.Description,
+				Data: dataReq.Data, Metadata: dataReq.Metadata, Environment: dataReq.Environment}
+			if err := WriteStructuredData(out, edit, s.cryptoManager, true); err != nil {
+				return err
+			}
+		case conflictOverwrite:
+			current, err := s.cli.GetDataByID(ctx, data.ID.String())
+			if err != nil {
+				return fmt.Errorf("failed to get the other version: %w", err)
+			}
+			dataReq.BaseUpdatedAt = &current.UpdatedAt
+			dataReq.BaseRevision = baseRevision(current)
+			updated, err := s.Update(ctx, data.ID.String(), dataReq)
+			if errors.Is(err, ErrConflict) {
+				fmt.Fprintln(out, "It was changed again meanwhile.")
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update data: %w", err)
+			}
+			fmt.Fprintf(out, "Overwrote the other change; the previous version is kept in 'history %s'\n", updated.ID)
+			return nil
+		case conflictDiscard:
+			fmt.Fprintln(out, "Your edit was discarded")
+			return nil
+		case conflictCopy, "":
+			return s.keepConflictCopy(ctx, data, dataReq, out)
+		default:
+			fmt.Fprintln(out, "Please answer s, o, c or d")
+		}
+	}
+	return s.keepConflictCopy(ctx, data, dataReq, out)
+}
+
+// keepConflictCopy saves an edit that lost a concurrent update as a conflict copy
+func (s *ClientSession) keepConflictCopy(ctx context.Context, data *models.Data, dataReq models.DataRequest, out io.Writer) error {
+	conflictCopy, err := s.SaveConflictCopy(ctx, data, dataReq)
+	if err != nil {
+		return fmt.Errorf("item was changed on another device and saving a conflict copy failed: %w", err)
+	}
+	fmt.Fprintf(out, "Your edit was saved as %q (%s)\n", conflictCopy.Name, conflictCopy.ID)
+	fmt.Fprintln(out, "Run 'conflicts' to review and merge.")
+	return nil
+}
+
+// Conflicts lists conflict copies together with the items they were copied from
+func (s *ClientSession) Conflicts(ctx context.Context) ([]ConflictCopy, error) {
+	items, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []ConflictCopy
+	for _, item := range items {
+		match := conflictCopyPattern.FindStringSubmatch(item.Name)
+		if match == nil {
+			continue
+		}
+		conflict := ConflictCopy{Copy: item, OriginalName: match[1], Device: match[2], Date: match[3]}
+		for i := range items {
+			if items[i].Name == conflict.OriginalName && items[i].Type == item.Type {
+				conflict.Original = &items[i]
+				break
+			}
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, nil
+}
+
+// ResolveConflict merges a conflict copy: "mine" overwrites the original with
+// the copy, "theirs" discards the copy and "both" keeps the copy under a plain name
+func (s *ClientSession) ResolveConflict(ctx context.Context, conflict ConflictCopy, choice string) error {
+	copyID := conflict.Copy.ID.String()
+
+	switch choice {
+	case ResolveKeepMine:
+		if conflict.Original == nil {
+			return fmt.Errorf("original item %q no longer exists, keep both instead", conflict.OriginalName)
+		}
+		baseUpdatedAt := conflict.Original.UpdatedAt
+		if _, err := s.Update(ctx, conflict.Original.ID.String(), models.DataRequest{
+			Type:          conflict.Copy.Type,
+			Name:          conflict.Original.Name,
+			Description:   conflict.Copy.Description,
+			Data:          conflict.Copy.Data,
+			Metadata:      conflict.Copy.Metadata,
+			Environment:   conflict.Original.Environment,
+			BaseUpdatedAt: &baseUpdatedAt,
+			BaseRevision:  baseRevision(conflict.Original),
+		}); err != nil {
+			return fmt.Errorf("failed to update original: %w", err)
+		}
+		return s.Delete(ctx, copyID)
+	case ResolveKeepTheirs:
+		return s.Delete(ctx, copyID)
+	case ResolveKeepBoth:
+		_, err := s.Update(ctx, copyID, models.DataRequest{
+			Type:          conflict.Copy.Type,
+			Name:          fmt.Sprintf("%s (from %s)", conflict.OriginalName, conflict.Device),
+			Description:   conflict.Copy.Description,
+			Data:          conflict.Copy.Data,
+			Metadata:      conflict.Copy.Metadata,
+			Environment:   conflict.Copy.Environment,
+			BaseUpdatedAt: &conflict.Copy.UpdatedAt,
+			BaseRevision:  baseRevision(&conflict.Copy),
+		})
+		return err
+	default:
+		return fmt.Errorf("unknown resolution: %s (use %s, %s or %s)", choice, ResolveKeepMine, ResolveKeepTheirs, ResolveKeepBoth)
+	}
+}
+
+// ConflictsCommand lists conflict copies and walks through merging them
+func (s *ClientSession) ConflictsCommand(ctx context.Context, args []string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+
+	conflicts, err := s.Conflicts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list conflicts: %w", err)
+	}
+	if len(conflicts) == 0 {
+		fmt.Println("No conflicts")
+		return nil
+	}
+
+	if len(args) == 0 {
+		fmt.Printf("Found %d conflict copy(ies):\n", len(conflicts))
+		for _, conflict := range conflicts {
+			original := "original missing"
+			if conflict.Original != nil {
+				original = "original " + conflict.Original.ID.String()
+			}
+			fmt.Printf("- %s: %q from %s on %s (%s)\n", conflict.Copy.ID, conflict.OriginalName,
+				conflict.Device, conflict.Date, original)
+		}
+		fmt.Println("Run 'conflicts resolve' to merge them.")
+		return nil
+	}
+
+	if args[0] != "resolve" {
+		return fmt.Errorf("unknown conflicts action: %s (use resolve)", args[0])
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for _, conflict := range conflicts {
+		if len(args) > 1 && args[1] != conflict.Copy.ID.String() {
+			continue
+		}
+
+		fmt.Printf("\n=== Conflict for %q ===\n", conflict.OriginalName)
+		if conflict.Original != nil {
+			fmt.Printf("--- Current version (%s) ---\n", conflict.Original.UpdatedAt.Local().Format("2006-01-02 15:04"))
+			if err := WriteStructuredData(os.Stdout, conflict.Original, s.cryptoManager, true); err != nil {
+				return err
+			}
+		} else {
+			fmt.Println("--- Current version: deleted ---")
+		}
+		fmt.Printf("--- Conflict copy from %s ---\n", conflict.Device)
+		if err := WriteStructuredData(os.Stdout, &conflict.Copy, s.cryptoManager, true); err != nil {
+			return err
+		}
+
+		fmt.Printf("Keep [%s] conflict copy, [%s] current version, [%s] or [s]kip: ",
+			ResolveKeepMine, ResolveKeepTheirs, ResolveKeepBoth)
+		if !scanner.Scan() {
+			return nil
+		}
+		choice := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if choice == "" || choice == "s" || choice == "skip" {
+			fmt.Println("Skipped")
+			continue
+		}
+		if err := s.ResolveConflict(ctx, conflict, choice); err != nil {
+			return err
+		}
+		fmt.Println("Resolved")
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestConflictCopyName(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	name := ConflictCopyName("Email", "laptop", at)
+	if name != "Email (conflict copy from laptop, 2024-05-01)" {
+		t.Fatalf("ConflictCopyName() = %q", name)
+	}
+
+	match := conflictCopyPattern.FindStringSubmatch(name)
+	if match == nil || match[1] != "Email" || match[2] != "laptop" || match[3] != "2024-05-01" {
+		t.Errorf("Conflict copy name not parsed back: %v", match)
+	}
+}
+
+func TestConflicts_DetectAndResolve(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		choice      string
+		wantContent string
+		wantItems   int
+	}{
+		{choice: ResolveKeepMine, wantContent: "mine", wantItems: 1},
+		{choice: ResolveKeepTheirs, wantContent: "theirs", wantItems: 1},
+		{choice: ResolveKeepBoth, wantContent: "theirs", wantItems: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.choice, func(t *testing.T) {
+			session, _, err := NewDemoSession(ctx)
+			if err != nil {
+				t.Fatalf("NewDemoSession() error = %v", err)
+			}
+			demoItems, _ := session.List(ctx)
+
+			encrypt := func(content string) []byte {
+				encrypted, err := session.GetCryptoManager().Encrypt([]byte(content))
+				if err != nil {
+					t.Fatalf("Encrypt() error = %v", err)
+				}
+				return encrypted
+			}
+
+			original, err := session.Create(ctx, models.DataRequest{Type: models.DataTypeText, Name: "Wiki", Data: encrypt("base")})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			stale := original.UpdatedAt
+
+			// Another device updates the item first
+			time.Sleep(time.Millisecond)
+			if _, err := session.Update(ctx, original.ID.String(), models.DataRequest{Type: models.DataTypeText, Name: "Wiki",
+				Data: encrypt("theirs"), BaseUpdatedAt: &stale}); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			mine := models.DataRequest{Type: models.DataTypeText, Name: "Wiki", Data: encrypt("mine"), BaseUpdatedAt: &stale}
+			if _, err := session.Update(ctx, original.ID.String(), mine); !errors.Is(err, ErrConflict) {
+				t.Fatalf("Expected ErrConflict for stale update, got %v", err)
+			}
+			if _, err := session.SaveConflictCopy(ctx, original, mine); err != nil {
+				t.Fatalf("SaveConflictCopy() error = %v", err)
+			}
+
+			conflicts, err := session.Conflicts(ctx)
+			if err != nil {
+				t.Fatalf("Conflicts() error = %v", err)
+			}
+			if len(conflicts) != 1 || conflicts[0].Original == nil || conflicts[0].Original.ID != original.ID {
+				t.Fatalf("Expected one conflict linked to the original, got %+v", conflicts)
+			}
+			if conflicts[0].Device != DeviceName() {
+				t.Errorf("Conflict device = %q, want %q", conflicts[0].Device, DeviceName())
+			}
+
+			if err := session.ResolveConflict(ctx, conflicts[0], tt.choice); err != nil {
+				t.Fatalf("ResolveConflict() error = %v", err)
+			}
+
+			resolved, err := session.Get(ctx, original.ID.String())
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			content, _ := session.GetCryptoManager().Decrypt(resolved.Data)
+			if string(content) != tt.wantContent {
+				t.Errorf("Original content = %q, want %q", content, tt.wantContent)
+			}
+
+			items, _ := session.List(ctx)
+			if len(items)-len(demoItems) != tt.wantItems {
+				t.Errorf("Expected %d items after resolving, got %d", tt.wantItems, len(items)-len(demoItems))
+			}
+			if remaining, _ := session.Conflicts(ctx); len(remaining) != 0 {
+				t.Errorf("Expected no conflicts left, got %d", len(remaining))
+			}
+		})
+	}
+}
+
+func TestClientSession_SaveUpdateConflict(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		input       string
+		wantContent string
+		wantItems   int
+		wantOutput  string
+	}{
+		{name: "show then overwrite", input: "s\no\n", wantContent: "mine", wantItems: 1, wantOutput: "--- Other device"},
+		{name: "discard", input: "d\n", wantContent: "theirs", wantItems: 1, wantOutput: "discarded"},
+		{name: "conflict copy by default", input: "\n", wantContent: "theirs", wantItems: 2, wantOutput: "conflict copy from"},
+		{name: "unknown answer", input: "x\nc\n", wantContent: "theirs", wantItems: 2, wantOutput: "Please answer"},
+		{name: "no input", input: "", wantContent: "theirs", wantItems: 2, wantOutput: "Run 'conflicts'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, _, err := NewDemoSession(ctx)
+			if err != nil {
+				t.Fatalf("NewDemoSession() error = %v", err)
+			}
+			demoItems, _ := session.List(ctx)
+
+			encrypt := func(content string) []byte {
+				encrypted, err := session.GetCryptoManager().Encrypt([]byte(content))
+				if err != nil {
+					t.Fatalf("Encrypt() error = %v", err)
+				}
+				return encrypted
+			}
+
+			original, err := session.Create(ctx, models.DataRequest{Type: models.DataTypeText, Name: "Wiki", Data: encrypt("base")})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if _, err := session.Update(ctx, original.ID.String(), models.DataRequest{Type: models.DataTypeText, Name: "Wiki",
+				Data: encrypt("theirs"), BaseRevision: baseRevision(original)}); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			var out bytes.Buffer
+			mine := models.DataRequest{Type: models.DataTypeText, Name: "Wiki", Data: encrypt("mine"), BaseRevision: baseRevision(original)}
+			if err := session.saveUpdate(ctx, original, mine, bufio.NewScanner(strings.NewReader(tt.input)), &out); err != nil {
+				t.Fatalf("saveUpdate() error = %v", err)
+			}
+			if !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("Expected %q in the output:\n%s", tt.wantOutput, out.String())
+			}
+
+			current, err := session.Get(ctx, original.ID.String())
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			content, _ := session.GetCryptoManager().Decrypt(current.Data)
+			if string(content) != tt.wantContent {
+				t.Errorf("Content = %q, want %q", content, tt.wantContent)
+			}
+			if items, _ := session.List(ctx); len(items)-len(demoItems) != tt.wantItems {
+				t.Errorf("Expected %d items, got %d", tt.wantItems, len(items)-len(demoItems))
+			}
+		})
+	}
+}
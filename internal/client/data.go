@@ -4,10 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/a2sh3r/gophkeeper/internal/grpcserver"
+	pb "github.com/a2sh3r/gophkeeper/internal/grpcserver/proto"
 	"github.com/a2sh3r/gophkeeper/internal/logger"
 	"github.com/a2sh3r/gophkeeper/internal/models"
 	"go.uber.org/zap"
@@ -15,18 +21,65 @@ import (
 
 // GetData gets all user data
 func (c *Client) GetData(ctx context.Context) ([]models.Data, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v1/data", nil)
+	return c.GetDataFiltered(ctx, models.DataFilter{})
+}
+
+// GetDataFiltered gets user data matching the filter, one page of it if the
+// filter sets a limit. A filter with a query is sent to the search endpoint.
+func (c *Client) GetDataFiltered(ctx context.Context, filter models.DataFilter) ([]models.Data, error) {
+	if c.grpc != nil {
+		return c.grpcListData(ctx, filter)
+	}
+
+	query := url.Values{}
+	if filter.Environment != "" {
+		query.Set("environment", filter.Environment)
+	}
+	if filter.Type != "" {
+		query.Set("type", string(filter.Type))
+	}
+	if filter.Name != "" {
+		query.Set("name", filter.Name)
+	}
+	if filter.Tag != "" {
+		query.Set("tag", filter.Tag)
+	}
+	if !filter.ExpiringBefore.IsZero() {
+		// the server takes a period; one already past still asks for the expired items
+		within := time.Until(filter.ExpiringBefore).Round(time.Second)
+		if within < time.Second {
+			within = time.Second
+		}
+		query.Set("expiring", within.String())
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		query.Set("offset", strconv.Itoa(filter.Offset))
+	}
+
+	endpoint := c.baseURL + "/api/v1/data"
+	if filter.Query != "" {
+		query.Set("q", filter.Query)
+		endpoint += "/search"
+	}
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		logger.Log.Error("Failed to create GET data request", zap.Error(err))
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	c.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		logger.Log.Error("GET data request failed", zap.Error(err))
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, requestFailed(err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -41,15 +94,7 @@ func (c *Client) GetData(ctx context.Context) ([]models.Data, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp models.ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil {
-			logger.Log.Warn("GET data failed with server error", zap.Int("status_code", resp.StatusCode),
-				zap.String("error", errResp.Error))
-			return nil, fmt.Errorf("server error: %s", errResp.Error)
-		}
-		logger.Log.Warn("GET data failed with unknown error", zap.Int("status_code", resp.StatusCode),
-			zap.String("response", string(body)))
-		return nil, fmt.Errorf("server error: %s", string(body))
+		return nil, statusError(resp, body)
 	}
 
 	var dataResp models.DataListResponse
@@ -63,6 +108,12 @@ func (c *Client) GetData(ctx context.Context) ([]models.Data, error) {
 
 // CreateData creates new data
 func (c *Client) CreateData(ctx context.Context, dataReq models.DataRequest) (*models.Data, error) {
+	if c.grpc != nil {
+		return c.grpcItem(ctx, func(ctx context.Context) (*pb.DataResponse, error) {
+			return c.grpc.data.CreateData(ctx, grpcserver.DataRequestFromModel(dataReq))
+		})
+	}
+
 	jsonData, err := json.Marshal(dataReq)
 	if err != nil {
 		logger.Log.Error("Failed to marshal create data request", zap.Error(err))
@@ -76,12 +127,12 @@ func (c *Client) CreateData(ctx context.Context, dataReq models.DataRequest) (*m
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	c.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		logger.Log.Error("POST data request failed", zap.Error(err))
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, requestFailed(err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -96,15 +147,7 @@ func (c *Client) CreateData(ctx context.Context, dataReq models.DataRequest) (*m
 	}
 
 	if resp.StatusCode != http.StatusCreated {
-		var errResp models.ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil {
-			logger.Log.Warn("POST data failed with server error", zap.Int("status_code", resp.StatusCode),
-				zap.String("error", errResp.Error))
-			return nil, fmt.Errorf("server error: %s", errResp.Error)
-		}
-		logger.Log.Warn("POST data failed with unknown error", zap.Int("status_code", resp.StatusCode),
-			zap.String("response", string(body)))
-		return nil, fmt.Errorf("server error: %s", string(body))
+		return nil, statusError(resp, body)
 	}
 
 	var dataResp models.DataResponse
@@ -118,16 +161,22 @@ func (c *Client) CreateData(ctx context.Context, dataReq models.DataRequest) (*m
 
 // GetDataByID gets data by ID
 func (c *Client) GetDataByID(ctx context.Context, id string) (*models.Data, error) {
+	if c.grpc != nil {
+		return c.grpcItem(ctx, func(ctx context.Context) (*pb.DataResponse, error) {
+			return c.grpc.data.GetData(ctx, &pb.GetDataRequest{Id: id})
+		})
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v1/data/"+id, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	c.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, requestFailed(err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -141,11 +190,7 @@ func (c *Client) GetDataByID(ctx context.Context, id string) (*models.Data, erro
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp models.ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil {
-			return nil, fmt.Errorf("server error: %s", errResp.Error)
-		}
-		return nil, fmt.Errorf("server error: %s", string(body))
+		return nil, statusError(resp, body)
 	}
 
 	var dataResp models.DataResponse
@@ -158,6 +203,17 @@ func (c *Client) GetDataByID(ctx context.Context, id string) (*models.Data, erro
 
 // UpdateData updates data
 func (c *Client) UpdateData(ctx context.Context, id string, dataReq models.DataRequest) (*models.Data, error) {
+	if c.grpc != nil {
+		data, err := c.grpcItem(ctx, func(ctx context.Context) (*pb.DataResponse, error) {
+			return c.grpc.data.UpdateData(ctx, &pb.UpdateDataRequest{Id: id, Data: grpcserver.DataRequestFromModel(dataReq)})
+		})
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusConflict {
+			return nil, ErrConflict
+		}
+		return data, err
+	}
+
 	jsonData, err := json.Marshal(dataReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -169,11 +225,14 @@ func (c *Client) UpdateData(ctx context.Context, id string, dataReq models.DataR
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	if dataReq.BaseRevision != nil {
+		req.Header.Set("If-Match", fmt.Sprintf("%q", strconv.Itoa(*dataReq.BaseRevision)))
+	}
+	c.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, requestFailed(err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -186,12 +245,12 @@ func (c *Client) UpdateData(ctx context.Context, id string, dataReq models.DataR
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusConflict {
+		return nil, ErrConflict
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		var errResp models.ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil {
-			return nil, fmt.Errorf("server error: %s", errResp.Error)
-		}
-		return nil, fmt.Errorf("server error: %s", string(body))
+		return nil, statusError(resp, body)
 	}
 
 	var dataResp models.DataResponse
@@ -204,18 +263,22 @@ func (c *Client) UpdateData(ctx context.Context, id string, dataReq models.DataR
 
 // DeleteData deletes data
 func (c *Client) DeleteData(ctx context.Context, id string) error {
+	if c.grpc != nil {
+		return c.grpcDeleteData(ctx, id)
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "DELETE", c.baseURL+"/api/v1/data/"+id, nil)
 	if err != nil {
 		logger.Log.Error("Failed to create DELETE data request", zap.Error(err), zap.String("data_id", id))
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	c.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		logger.Log.Error("DELETE data request failed", zap.Error(err), zap.String("data_id", id))
-		return fmt.Errorf("request failed: %w", err)
+		return requestFailed(err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -230,15 +293,7 @@ func (c *Client) DeleteData(ctx context.Context, id string) error {
 			return fmt.Errorf("failed to read response: %w", err)
 		}
 
-		var errResp models.ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil {
-			logger.Log.Warn("DELETE data failed with server error", zap.Int("status_code", resp.StatusCode),
-				zap.String("data_id", id), zap.String("error", errResp.Error))
-			return fmt.Errorf("server error: %s", errResp.Error)
-		}
-		logger.Log.Warn("DELETE data failed with unknown error", zap.Int("status_code", resp.StatusCode),
-			zap.String("data_id", id), zap.String("response", string(body)))
-		return fmt.Errorf("server error: %s", string(body))
+		return statusError(resp, body)
 	}
 
 	return nil
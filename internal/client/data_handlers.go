@@ -22,11 +22,18 @@ func CreateLoginPasswordData() ([]byte, string, error) {
 	}
 	login := strings.TrimSpace(scanner.Text())
 
-	fmt.Print("Enter password: ")
-	if !scanner.Scan() {
+	password, ok := readSecret(scanner, "Enter password: ")
+	if !ok {
 		return nil, "", fmt.Errorf("failed to read password")
 	}
-	password := strings.TrimSpace(scanner.Text())
+	password = strings.TrimSpace(password)
+	if password != "" {
+		analysis := AnalyzePassword(password)
+		fmt.Printf("Strength: %s\n", EntropyMeter(analysis.Bits))
+		for _, warning := range analysis.Warnings {
+			fmt.Printf("Warning: %s\n", warning)
+		}
+	}
 
 	fmt.Print("Enter URL (optional): ")
 	if !scanner.Scan() {
@@ -88,38 +95,46 @@ func CreateTextData() ([]byte, string, error) {
 
 // CreateBinaryData creates binary data from file
 func CreateBinaryData() ([]byte, string, error) {
+	filePath, notes, err := promptBinaryFile()
+	if err != nil {
+		return nil, "", err
+	}
+	return readBinaryData(filePath, notes)
+}
+
+// promptBinaryFile asks for the path and notes of a file to store
+func promptBinaryFile() (string, string, error) {
 	scanner := bufio.NewScanner(os.Stdin)
 
 	fmt.Print("Enter file path: ")
 	if !scanner.Scan() {
-		return nil, "", fmt.Errorf("failed to read file path")
+		return "", "", fmt.Errorf("failed to read file path")
 	}
 	filePath := strings.TrimSpace(scanner.Text())
 
-	fileData, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to read file: %w", err)
-	}
-
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to get file info: %w", err)
+	if _, err := os.Stat(filePath); err != nil {
+		return "", "", fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	fileName := fileInfo.Name()
-	fileExt := filepath.Ext(fileName)
-	mimeType := getMimeType(fileExt)
-
 	fmt.Print("Enter notes (optional): ")
 	if !scanner.Scan() {
-		return nil, "", fmt.Errorf("failed to read notes")
+		return "", "", fmt.Errorf("failed to read notes")
 	}
-	notes := strings.TrimSpace(scanner.Text())
+	return filePath, strings.TrimSpace(scanner.Text()), nil
+}
 
+// readBinaryData reads a whole file into the content and metadata of a binary item
+func readBinaryData(filePath, notes string) ([]byte, string, error) {
+	fileData, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	fileName := filepath.Base(filePath)
 	binaryData := models.BinaryData{
 		FileName: fileName,
 		Size:     int64(len(fileData)),
-		MimeType: mimeType,
+		MimeType: getMimeType(filepath.Ext(fileName)),
 		Notes:    notes,
 	}
 
@@ -136,11 +151,11 @@ func CreateBinaryData() ([]byte, string, error) {
 func CreateBankCardData() ([]byte, string, error) {
 	scanner := bufio.NewScanner(os.Stdin)
 
-	fmt.Print("Enter card number: ")
-	if !scanner.Scan() {
+	cardNumber, ok := readSecret(scanner, "Enter card number: ")
+	if !ok {
 		return nil, "", fmt.Errorf("failed to read card number")
 	}
-	cardNumber := strings.TrimSpace(scanner.Text())
+	cardNumber = strings.TrimSpace(cardNumber)
 
 	fmt.Print("Enter expiry date (MM/YY): ")
 	if !scanner.Scan() {
@@ -148,11 +163,11 @@ func CreateBankCardData() ([]byte, string, error) {
 	}
 	expiryDate := strings.TrimSpace(scanner.Text())
 
-	fmt.Print("Enter CVV: ")
-	if !scanner.Scan() {
+	cvv, ok := readSecret(scanner, "Enter CVV: ")
+	if !ok {
 		return nil, "", fmt.Errorf("failed to read CVV")
 	}
-	cvv := strings.TrimSpace(scanner.Text())
+	cvv = strings.TrimSpace(cvv)
 
 	fmt.Print("Enter cardholder name: ")
 	if !scanner.Scan() {
@@ -223,3 +238,54 @@ func getMimeType(ext string) string {
 		return "application/octet-stream"
 	}
 }
+
+// CreateOTPData creates TOTP secret data from user input. The secret may be
+// given as an otpauth:// URI, which also carries the issuer and account.
+func CreateOTPData() ([]byte, string, error) {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	secret, ok := readSecret(scanner, "Enter secret (base32) or otpauth:// URI: ")
+	if !ok {
+		return nil, "", fmt.Errorf("failed to read secret")
+	}
+	secret = strings.TrimSpace(secret)
+
+	var otpData models.OTPData
+	var err error
+	if strings.HasPrefix(secret, "otpauth:") {
+		if otpData, err = ParseOTPAuthURI(secret); err != nil {
+			return nil, "", err
+		}
+	} else {
+		otpData.Secret = secret
+		if otpData, _, err = normalizeOTP(otpData); err != nil {
+			return nil, "", err
+		}
+
+		fmt.Print("Enter issuer (optional): ")
+		if !scanner.Scan() {
+			return nil, "", fmt.Errorf("failed to read issuer")
+		}
+		otpData.Issuer = strings.TrimSpace(scanner.Text())
+
+		fmt.Print("Enter account (optional): ")
+		if !scanner.Scan() {
+			return nil, "", fmt.Errorf("failed to read account")
+		}
+		otpData.Account = strings.TrimSpace(scanner.Text())
+	}
+
+	fmt.Print("Enter notes (optional): ")
+	if !scanner.Scan() {
+		return nil, "", fmt.Errorf("failed to read notes")
+	}
+	otpData.Notes = strings.TrimSpace(scanner.Text())
+
+	data, err := json.Marshal(otpData)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal otp data: %w", err)
+	}
+
+	metadata := fmt.Sprintf("Issuer: %s, Account: %s", otpData.Issuer, otpData.Account)
+	return data, metadata, nil
+}
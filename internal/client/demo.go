@@ -0,0 +1,289 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// DemoUsername is the account used by demo mode
+	DemoUsername = "demo"
+	// DemoMasterPassword is the master password of the demo vault
+	DemoMasterPassword = "demo-master-password"
+	demoServerURL      = "http://demo.invalid"
+)
+
+// handlerTransport serves HTTP requests in-process without opening sockets.
+// The response is returned once the handler writes its header and the body is
+// streamed, so handlers may answer while still reading the request, like the import.
+// Cancelling the request context aborts the exchange as with a real connection.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	body, writer := io.Pipe()
+	w := &pipeResponseWriter{header: make(http.Header), body: writer, ready: make(chan struct{})}
+	stop := context.AfterFunc(ctx, func() {
+		body.CloseWithError(ctx.Err())
+	})
+	go func() {
+		defer stop()
+		defer writer.Close()
+		t.handler.ServeHTTP(w, req)
+		w.WriteHeader(http.StatusOK)
+	}()
+
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+		StatusCode: w.status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     w.sent,
+		Body:       body,
+		Request:    req,
+	}, nil
+}
+
+// pipeResponseWriter is the http.ResponseWriter of handlerTransport
+type pipeResponseWriter struct {
+	header http.Header
+	sent   http.Header
+	status int
+	body   *io.PipeWriter
+	ready  chan struct{}
+}
+
+// Header implements http.ResponseWriter
+func (w *pipeResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements http.ResponseWriter; only the first call has an effect
+func (w *pipeResponseWriter) WriteHeader(status int) {
+	if w.sent != nil {
+		return
+	}
+	w.status = status
+	w.sent = w.header.Clone()
+	close(w.ready)
+}
+
+// Write implements http.ResponseWriter
+func (w *pipeResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// Flush implements http.Flusher; writes are never buffered
+func (w *pipeResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+// demoItem describes a sample item of the demo vault
+type demoItem struct {
+	dataType    models.DataType
+	name        string
+	description string
+	payload     interface{}
+	metadata    string
+}
+
+// newDemoRouter creates the routes of an empty in-memory server
+func newDemoRouter() (http.Handler, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate demo secret: %w", err)
+	}
+
+	store := storage.NewMemoryStorage()
+	router := mux.NewRouter()
+	opts := server.Options{}
+	router.NotFoundHandler = apierror.NotFoundHandler()
+	router.MethodNotAllowedHandler = apierror.MethodNotAllowedHandler()
+	jwtManager := auth.NewJWTManager(hex.EncodeToString(secret), 24*time.Hour)
+	sessions := server.NewSessions(store, opts)
+	jwtManager.SetSessions(sessions)
+	server.RegisterAuditRoutes(router, store, jwtManager, server.AuditOptions{})
+	server.RegisterVaultLockRoutes(router, server.NewVaultLocks(opts), jwtManager)
+	events := server.NewEventHub()
+	server.RegisterEventRoutes(router, events, jwtManager)
+	server.RegisterStatusRoutes(router, server.StatusOptions{
+		RegistrationOpen: true,
+		Features: []string{server.FeatureComments, server.FeatureStreamingImport, server.FeatureAuditLog, server.FeatureVaultLock,
+			server.FeatureChunkedBinary, server.FeatureVersions, server.FeatureSearch, server.FeatureCollections,
+			server.FeatureVaultRotation, server.FeatureSharing, server.FeatureChangeEvents, server.FeatureBatch,
+			server.FeatureMetadataPatch, server.FeatureSessions, server.FeatureDomainIndex, server.FeatureIcons,
+			server.FeatureAttachments, server.FeatureExpiry},
+	})
+	audited := server.NewNotifyingDataStorage(server.NewAuditedDataStorage(store, store, server.AuditOptions{}), events, opts)
+	server.RegisterRoutes(router, store, audited, jwtManager, opts)
+	server.RegisterCommentRoutes(router, store, audited, jwtManager, opts)
+	server.RegisterChunkRoutes(router, store, audited, jwtManager)
+	server.RegisterAttachmentRoutes(router, store, audited, jwtManager, opts)
+	server.RegisterVersionRoutes(router, store, audited, jwtManager, opts)
+	server.RegisterCollectionRoutes(router, store, audited, jwtManager, opts)
+	server.RegisterRotationRoutes(router, store, jwtManager)
+	server.RegisterVerifierRoutes(router, store, jwtManager)
+	server.RegisterShareRoutes(router, store, store, audited, jwtManager, opts)
+	server.RegisterSessionRoutes(router, sessions, jwtManager)
+	return middleware.RequestID(router), nil
+}
+
+// NewDemoSession creates an unlocked session backed by an ephemeral in-memory
+// vault pre-populated with sample items
+func NewDemoSession(ctx context.Context) (*ClientSession, *Config, error) {
+	router, err := newDemoRouter()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cli := NewClient(demoServerURL)
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+
+	resp, err := cli.Register(ctx, DemoUsername, DemoMasterPassword, DemoMasterPassword)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create demo account: %w", err)
+	}
+	cli.SetToken(resp.Token)
+
+	saltBytes, err := base64.StdEncoding.DecodeString(resp.Salt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode salt: %w", err)
+	}
+	cryptoManager, err := crypto.NewCryptoManagerWithSalt(DemoMasterPassword, saltBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+
+	session := NewClientSession(cli)
+	session.SetCryptoManager(cryptoManager, DemoMasterPassword)
+	session.publishAuditKey(ctx)
+	session.publishShareKey(ctx)
+	session.publishVerifier(ctx, cryptoManager)
+
+	if err := seedDemoVault(ctx, session); err != nil {
+		return nil, nil, err
+	}
+
+	config := &Config{
+		ServerURL: demoServerURL,
+		Username:  DemoUsername,
+		Token:     resp.Token,
+		Salt:      resp.Salt,
+		Ephemeral: true,
+	}
+	return session, config, nil
+}
+
+// seedDemoVault creates the sample items of the demo vault
+func seedDemoVault(ctx context.Context, session *ClientSession) error {
+	demoFile := []byte("Hello from the GophKeeper demo vault!\n")
+	binaryMetadata, err := json.Marshal(models.BinaryData{
+		FileName: "hello.txt",
+		MimeType: "text/plain",
+		Size:     int64(len(demoFile)),
+		Notes:    "Try: save <id> ./hello.txt",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal demo metadata: %w", err)
+	}
+
+	items := []demoItem{
+		{
+			dataType:    models.DataTypeLoginPassword,
+			name:        "Demo Email",
+			description: "Sample webmail login",
+			payload: models.LoginPasswordData{
+				Login:    "demo@example.com",
+				Password: "correct-horse-battery-staple",
+				URL:      "https://mail.example.com",
+				Notes:    "Not a real account",
+			},
+			metadata: "Login: demo@example.com, URL: https://mail.example.com",
+		},
+		{
+			dataType:    models.DataTypeText,
+			name:        "Wi-Fi Notes",
+			description: "Sample secure note",
+			payload: models.TextData{
+				Content: "Network: demo-net, passphrase: purple-elephant-42",
+			},
+			metadata: "Length: 49 characters",
+		},
+		{
+			dataType:    models.DataTypeBankCard,
+			name:        "Demo Visa",
+			description: "Sample test card",
+			payload: models.BankCardData{
+				CardNumber: "4111111111111111",
+				ExpiryDate: "12/30",
+				CVV:        "123",
+				Cardholder: "DEMO USER",
+				Bank:       "Example Bank",
+			},
+			metadata: "Card: 4111111111111111, Bank: Example Bank",
+		},
+	}
+
+	for _, item := range items {
+		content, err := json.Marshal(item.payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal demo item: %w", err)
+		}
+		if err := createDemoItem(ctx, session, item.dataType, item.name, item.description, content, item.metadata); err != nil {
+			return err
+		}
+	}
+
+	encoded := []byte(base64.StdEncoding.EncodeToString(demoFile))
+	return createDemoItem(ctx, session, models.DataTypeBinary, "hello.txt", "Sample file", encoded, string(binaryMetadata))
+}
+
+// createDemoItem encrypts and stores a single demo item
+func createDemoItem(ctx context.Context, session *ClientSession, dataType models.DataType, name, description string, content []byte, metadata string) error {
+	encrypted, err := session.cryptoManager.Encrypt(content)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt demo item: %w", err)
+	}
+
+	_, err = session.Create(ctx, models.DataRequest{
+		Type:        dataType,
+		Name:        name,
+		Description: description,
+		Data:        encrypted,
+		Metadata:    metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create demo item %q: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestNewDemoSession(t *testing.T) {
+	session, config, err := NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	if !session.IsAuthenticated() {
+		t.Error("Demo session should be unlocked")
+	}
+	if !config.Ephemeral {
+		t.Error("Demo config should be ephemeral")
+	}
+
+	items, err := session.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("Expected 4 demo items, got %d", len(items))
+	}
+
+	for _, item := range items {
+		if item.Type != models.DataTypeLoginPassword {
+			continue
+		}
+		decrypted, err := session.GetCryptoManager().Decrypt(item.Data)
+		if err != nil {
+			t.Fatalf("Failed to decrypt demo item: %v", err)
+		}
+		var login models.LoginPasswordData
+		if err := json.Unmarshal(decrypted, &login); err != nil {
+			t.Fatalf("Failed to unmarshal demo login: %v", err)
+		}
+		if login.Login != "demo@example.com" {
+			t.Errorf("Unexpected demo login %s", login.Login)
+		}
+	}
+}
+
+func TestSaveConfig_Ephemeral(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := SaveConfig(&Config{Token: "demo", Ephemeral: true}); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	if config := NewConfig(); config.Token != "" {
+		t.Error("Ephemeral config should not be persisted")
+	}
+}
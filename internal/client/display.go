@@ -3,87 +3,241 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/a2sh3r/gophkeeper/internal/crypto"
 	"github.com/a2sh3r/gophkeeper/internal/models"
 )
 
-// DisplayStructuredData displays structured data in a user-friendly format
+// secretMask replaces a hidden secret; it has a fixed length so that the
+// length of the secret is not shown either
+const secretMask = "••••••••"
+
+// StdoutIsTerminal reports whether standard output is an interactive terminal
+// rather than a pipe or file, i.e. whether secrets would end up on a screen
+func StdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// DisplayStructuredData displays structured data in a user-friendly format with
+// passwords, card numbers, CVVs and OTP secrets masked
 func DisplayStructuredData(data *models.Data, cryptoManager *crypto.CryptoManager) error {
+	return WriteStructuredData(os.Stdout, data, cryptoManager, false)
+}
+
+// WriteStructuredData writes structured data in a user-friendly format to w.
+// Secrets are masked unless reveal is set.
+func WriteStructuredData(w io.Writer, data *models.Data, cryptoManager *crypto.CryptoManager, reveal bool) error {
 	decryptedData, err := cryptoManager.Decrypt(data.Data)
 	if err != nil {
 		return fmt.Errorf("failed to decrypt data: %w", err)
 	}
+	writeDecryptedData(w, data, decryptedData, reveal)
+	return nil
+}
+
+// maskSecret returns value, or its mask followed by the last visible characters
+// unless reveal is set
+func maskSecret(value string, visible int, reveal bool) string {
+	if reveal || value == "" {
+		return value
+	}
+	if visible > 0 {
+		digits := strings.ReplaceAll(value, " ", "")
+		if len(digits) > 2*visible {
+			return strings.Repeat("•", 4) + digits[len(digits)-visible:]
+		}
+	}
+	return secretMask
+}
+
+// hasSecrets reports whether items of the type have fields that are masked
+func hasSecrets(dataType models.DataType) bool {
+	switch dataType {
+	case models.DataTypeLoginPassword, models.DataTypeBankCard, models.DataTypeOTP:
+		return true
+	}
+	return false
+}
 
-	fmt.Printf("ID: %s\n", data.ID.String())
-	fmt.Printf("Type: %s\n", data.Type)
-	fmt.Printf("Name: %s\n", CleanQuotes(data.Name))
+// writeDecryptedData writes data whose payload is already decrypted to w, with
+// secrets masked unless reveal is set
+func writeDecryptedData(w io.Writer, data *models.Data, decryptedData []byte, reveal bool) {
+	fmt.Fprintf(w, "ID: %s\n", data.ID.String())
+	fmt.Fprintf(w, "Type: %s\n", data.Type)
+	fmt.Fprintf(w, "Name: %s\n", CleanQuotes(data.Name))
 	if data.Description != "" {
-		fmt.Printf("Description: %s\n", CleanQuotes(data.Description))
+		fmt.Fprintf(w, "Description: %s\n", CleanQuotes(data.Description))
+	}
+	if data.Environment != "" {
+		fmt.Fprintf(w, "Environment: %s\n", data.Environment)
+	}
+	if len(data.Tags) > 0 {
+		fmt.Fprintf(w, "Tags: %s\n", strings.Join(data.Tags, ", "))
 	}
-	fmt.Printf("Created: %s\n", data.CreatedAt.Format("2006-01-02 15:04:05"))
-	fmt.Printf("Updated: %s\n", data.UpdatedAt.Format("2006-01-02 15:04:05"))
-	fmt.Println("---")
+	if data.ExpiresAt != nil {
+		fmt.Fprintf(w, "Expires: %s\n", describeExpiry(*data.ExpiresAt, time.Now()))
+	}
+	fmt.Fprintf(w, "Created: %s\n", data.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "Updated: %s\n", data.UpdatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintln(w, "---")
 
 	switch data.Type {
 	case "login_password":
 		var loginPasswordData models.LoginPasswordData
 		if err := json.Unmarshal(decryptedData, &loginPasswordData); err == nil {
-			fmt.Printf("Login: %s\n", loginPasswordData.Login)
-			fmt.Printf("Password: %s\n", loginPasswordData.Password)
+			fmt.Fprintf(w, "Login: %s\n", loginPasswordData.Login)
+			fmt.Fprintf(w, "Password: %s\n", maskSecret(loginPasswordData.Password, 0, reveal))
 			if loginPasswordData.URL != "" {
-				fmt.Printf("URL: %s\n", loginPasswordData.URL)
+				fmt.Fprintf(w, "URL: %s\n", loginPasswordData.URL)
 			}
 			if loginPasswordData.Notes != "" {
-				fmt.Printf("Notes: %s\n", loginPasswordData.Notes)
+				fmt.Fprintf(w, "Notes: %s\n", loginPasswordData.Notes)
 			}
 		} else {
-			fmt.Printf("Data: %s\n", string(decryptedData))
+			fmt.Fprintf(w, "Data: %s\n", maskSecret(string(decryptedData), 0, reveal))
 		}
 	case "text":
 		var textData models.TextData
 		if err := json.Unmarshal(decryptedData, &textData); err == nil {
-			fmt.Printf("Content: %s\n", textData.Content)
+			fmt.Fprintf(w, "Content: %s\n", textData.Content)
 			if textData.Notes != "" {
-				fmt.Printf("Notes: %s\n", textData.Notes)
+				fmt.Fprintf(w, "Notes: %s\n", textData.Notes)
 			}
 		} else {
-			fmt.Printf("Data: %s\n", string(decryptedData))
+			fmt.Fprintf(w, "Data: %s\n", string(decryptedData))
 		}
 	case "binary":
 		var binaryData models.BinaryData
 		if err := json.Unmarshal(decryptedData, &binaryData); err == nil {
-			fmt.Printf("File: %s\n", binaryData.FileName)
-			fmt.Printf("Size: %d bytes\n", binaryData.Size)
-			fmt.Printf("MIME Type: %s\n", binaryData.MimeType)
+			fmt.Fprintf(w, "File: %s\n", binaryData.FileName)
+			fmt.Fprintf(w, "Size: %d bytes\n", binaryData.Size)
+			fmt.Fprintf(w, "MIME Type: %s\n", binaryData.MimeType)
 			if binaryData.Notes != "" {
-				fmt.Printf("Notes: %s\n", binaryData.Notes)
+				fmt.Fprintf(w, "Notes: %s\n", binaryData.Notes)
 			}
 		} else {
-			fmt.Printf("Data: %s\n", string(decryptedData))
+			fmt.Fprintf(w, "Data: %s\n", string(decryptedData))
 		}
 	case "bank_card":
 		var bankCardData models.BankCardData
 		if err := json.Unmarshal(decryptedData, &bankCardData); err == nil {
-			fmt.Printf("Card Number: %s\n", bankCardData.CardNumber)
-			fmt.Printf("Expiry Date: %s\n", bankCardData.ExpiryDate)
-			fmt.Printf("CVV: %s\n", bankCardData.CVV)
-			fmt.Printf("Cardholder: %s\n", bankCardData.Cardholder)
+			fmt.Fprintf(w, "Card Number: %s\n", maskSecret(bankCardData.CardNumber, 4, reveal))
+			fmt.Fprintf(w, "Expiry Date: %s\n", bankCardData.ExpiryDate)
+			fmt.Fprintf(w, "CVV: %s\n", maskSecret(bankCardData.CVV, 0, reveal))
+			fmt.Fprintf(w, "Cardholder: %s\n", bankCardData.Cardholder)
 			if bankCardData.Bank != "" {
-				fmt.Printf("Bank: %s\n", bankCardData.Bank)
+				fmt.Fprintf(w, "Bank: %s\n", bankCardData.Bank)
 			}
 			if bankCardData.Notes != "" {
-				fmt.Printf("Notes: %s\n", bankCardData.Notes)
+				fmt.Fprintf(w, "Notes: %s\n", bankCardData.Notes)
 			}
 		} else {
-			fmt.Printf("Data: %s\n", string(decryptedData))
+			fmt.Fprintf(w, "Data: %s\n", maskSecret(string(decryptedData), 0, reveal))
+		}
+	case "otp":
+		var otpData models.OTPData
+		if err := json.Unmarshal(decryptedData, &otpData); err == nil {
+			if otpData.Issuer != "" {
+				fmt.Fprintf(w, "Issuer: %s\n", otpData.Issuer)
+			}
+			if otpData.Account != "" {
+				fmt.Fprintf(w, "Account: %s\n", otpData.Account)
+			}
+			fmt.Fprintf(w, "Secret: %s\n", maskSecret(otpData.Secret, 0, reveal))
+			if code, remaining, err := GenerateTOTP(otpData, time.Now()); err == nil {
+				fmt.Fprintf(w, "Current Code: %s (valid for %ds)\n", code, int(remaining.Round(time.Second).Seconds()))
+			}
+			if otpData.Notes != "" {
+				fmt.Fprintf(w, "Notes: %s\n", otpData.Notes)
+			}
+		} else {
+			fmt.Fprintf(w, "Data: %s\n", maskSecret(string(decryptedData), 0, reveal))
 		}
 	default:
-		fmt.Printf("Data: %s\n", string(decryptedData))
+		fmt.Fprintf(w, "Data: %s\n", string(decryptedData))
 	}
+}
 
-	return nil
+// listGroups are the type headers of grouped list output, in display order
+var listGroups = []struct {
+	dataType models.DataType
+	title    string
+}{
+	{models.DataTypeLoginPassword, "Logins"},
+	{models.DataTypeBankCard, "Cards"},
+	{models.DataTypeOTP, "One-time codes"},
+	{models.DataTypeText, "Notes"},
+	{models.DataTypeBinary, "Files"},
+}
+
+// WriteGroupedList writes items grouped under type headers with per-group counts
+// and aligned columns, sorted by name within each group. Environments are shown
+// when showEnvironment is set, i.e. when the list is not limited to one. IDs are
+// replaced by their entry in shortIDs, if any.
+func WriteGroupedList(w io.Writer, items []models.Data, shortIDs map[string]string, usage map[string]time.Time, showEnvironment bool) error {
+	groups := make(map[models.DataType][]models.Data)
+	for _, item := range items {
+		groups[item.Type] = append(groups[item.Type], item)
+	}
+
+	titles := make(map[models.DataType]string, len(listGroups))
+	order := make([]models.DataType, 0, len(groups))
+	for _, group := range listGroups {
+		titles[group.dataType] = group.title
+		order = append(order, group.dataType)
+	}
+	var other []models.DataType
+	for dataType := range groups {
+		if _, known := titles[dataType]; !known {
+			other = append(other, dataType)
+		}
+	}
+	sort.Slice(other, func(i, j int) bool { return other[i] < other[j] })
+	order = append(order, other...)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, dataType := range order {
+		group := groups[dataType]
+		if len(group) == 0 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			return strings.ToLower(CleanQuotes(group[i].Name)) < strings.ToLower(CleanQuotes(group[j].Name))
+		})
+
+		title := titles[dataType]
+		if title == "" {
+			title = string(dataType)
+		}
+		fmt.Fprintf(tw, "\n%s (%d)\n", title, len(group))
+		for _, item := range group {
+			id := item.ID.String()
+			if short, ok := shortIDs[id]; ok {
+				id = short
+			}
+			cells := []string{id, CleanQuotes(item.Name)}
+			if showEnvironment {
+				cells = append(cells, item.Environment)
+			}
+			lastUsed := ""
+			if used, ok := usage[item.ID.String()]; ok {
+				lastUsed = "last used " + used.Local().Format("2006-01-02")
+			}
+			cells = append(cells, CleanQuotes(item.Description), lastUsed)
+			for len(cells) > 0 && cells[len(cells)-1] == "" {
+				cells = cells[:len(cells)-1]
+			}
+			fmt.Fprintf(tw, "  %s\n", strings.Join(cells, "\t"))
+		}
+	}
+	return tw.Flush()
 }
 
 // CleanQuotes removes quotes from string
@@ -2,6 +2,7 @@ package client
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -209,6 +210,65 @@ func TestDisplayStructuredData_BankCard(t *testing.T) {
 	}
 }
 
+func TestWriteStructuredData_MasksSecrets(t *testing.T) {
+	cryptoManager, err := crypto.NewCryptoManager("testpassword123")
+	if err != nil {
+		t.Fatalf("Failed to create crypto manager: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		dataType models.DataType
+		payload  interface{}
+		masked   []string
+		revealed []string
+	}{
+		{name: "login", dataType: models.DataTypeLoginPassword,
+			payload:  models.LoginPasswordData{Login: "alice", Password: "s3cret"},
+			masked:   []string{"Login: alice", "Password: ••••••••"},
+			revealed: []string{"Password: s3cret"}},
+		{name: "bank card", dataType: models.DataTypeBankCard,
+			payload:  models.BankCardData{CardNumber: "4111 1111 1111 1234", ExpiryDate: "12/25", CVV: "123", Cardholder: "John Doe"},
+			masked:   []string{"Card Number: ••••1234", "CVV: ••••••••", "Expiry Date: 12/25"},
+			revealed: []string{"Card Number: 4111 1111 1111 1234", "CVV: 123"}},
+		{name: "otp", dataType: models.DataTypeOTP,
+			payload:  models.OTPData{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Example"},
+			masked:   []string{"Secret: ••••••••", "Current Code: "},
+			revealed: []string{"Secret: JBSWY3DPEHPK3PXP"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext, _ := json.Marshal(tt.payload)
+			encrypted, err := cryptoManager.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("Failed to encrypt data: %v", err)
+			}
+			data := &models.Data{ID: uuid.New(), Type: tt.dataType, Name: "Item", Data: encrypted}
+
+			var masked, revealed strings.Builder
+			if err := WriteStructuredData(&masked, data, cryptoManager, false); err != nil {
+				t.Fatalf("WriteStructuredData() error = %v", err)
+			}
+			if err := WriteStructuredData(&revealed, data, cryptoManager, true); err != nil {
+				t.Fatalf("WriteStructuredData() error = %v", err)
+			}
+			for _, want := range tt.masked {
+				if !strings.Contains(masked.String(), want) {
+					t.Errorf("Expected masked output to contain %q, got:\n%s", want, masked.String())
+				}
+			}
+			for _, secret := range tt.revealed {
+				if strings.Contains(masked.String(), secret) {
+					t.Errorf("Expected masked output not to contain %q", secret)
+				}
+				if !strings.Contains(revealed.String(), secret) {
+					t.Errorf("Expected revealed output to contain %q, got:\n%s", secret, revealed.String())
+				}
+			}
+		})
+	}
+}
+
 func TestDisplayStructuredData_UnknownType(t *testing.T) {
 	// Create crypto manager
 	cryptoManager, err := crypto.NewCryptoManager("testpassword123")
@@ -265,3 +325,66 @@ func TestDisplayStructuredData_DecryptionError(t *testing.T) {
 		t.Error("Expected error for invalid encrypted data")
 	}
 }
+
+func TestWriteGroupedList(t *testing.T) {
+	cardID := uuid.New()
+	githubID := uuid.New()
+	awsID := uuid.New()
+	noteID := uuid.New()
+	items := []models.Data{
+		{ID: githubID, Type: models.DataTypeLoginPassword, Name: "github", Description: "work account", Environment: "prod"},
+		{ID: noteID, Type: models.DataTypeText, Name: "wifi"},
+		{ID: cardID, Type: models.DataTypeBankCard, Name: "Visa", Environment: "personal"},
+		{ID: awsID, Type: models.DataTypeLoginPassword, Name: "AWS", Environment: "staging"},
+	}
+	usage := map[string]time.Time{githubID.String(): time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)}
+
+	var buf strings.Builder
+	if err := WriteGroupedList(&buf, items, nil, usage, true); err != nil {
+		t.Fatalf("WriteGroupedList() error = %v", err)
+	}
+	output := buf.String()
+
+	order := []string{"Logins (2)", awsID.String(), githubID.String(), "Cards (1)", cardID.String(), "Notes (1)", noteID.String()}
+	last := -1
+	for _, want := range order {
+		index := strings.Index(output, want)
+		if index < 0 {
+			t.Fatalf("Expected %q in output:\n%s", want, output)
+		}
+		if index < last {
+			t.Errorf("Expected %q after the previous entries in output:\n%s", want, output)
+		}
+		last = index
+	}
+	if strings.Contains(output, "Files") {
+		t.Errorf("Expected empty groups to be omitted, got:\n%s", output)
+	}
+
+	var awsLine, githubLine string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, awsID.String()) {
+			awsLine = line
+		}
+		if strings.Contains(line, githubID.String()) {
+			githubLine = line
+		}
+		if line != strings.TrimRight(line, " ") {
+			t.Errorf("Expected no trailing spaces, got %q", line)
+		}
+	}
+	if strings.Index(awsLine, "staging") != strings.Index(githubLine, "prod") {
+		t.Errorf("Expected aligned environment columns, got:\n%s\n%s", awsLine, githubLine)
+	}
+	if !strings.Contains(githubLine, "work account") || !strings.Contains(githubLine, "last used 2024-03-01") {
+		t.Errorf("Expected description and last use, got %q", githubLine)
+	}
+
+	buf.Reset()
+	if err := WriteGroupedList(&buf, items, nil, nil, false); err != nil {
+		t.Fatalf("WriteGroupedList() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "staging") {
+		t.Errorf("Expected no environment column, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,354 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// rawUpdateWarning is printed by the deprecated whole-payload update
+const rawUpdateWarning = "WARNING: 'update --raw' is deprecated and will be removed in a future release.\n" +
+	"It replaces the whole encrypted payload with what you type, so logins, cards and notes lose their fields.\n" +
+	"Use 'update <id>' to edit fields one by one instead."
+
+// pipedUpdateWarning is printed when a script pipes the new content into 'update'
+const pipedUpdateWarning = "WARNING: piping new content into 'update <id>' is deprecated; 'update' now edits fields one by one.\n" +
+	"Change your script to 'update --raw <id>' to keep the old behavior during the transition period."
+
+// clearFieldInput clears an optional field in the structured editor
+const clearFieldInput = "-"
+
+// editableField is a payload field offered by the structured editor
+type editableField struct {
+	name     string
+	secret   bool
+	required bool
+}
+
+// editableFields lists the payload fields of each structured item type in prompt order
+var editableFields = map[models.DataType][]editableField{
+	models.DataTypeLoginPassword: {
+		{name: "login", required: true},
+		{name: "password", secret: true, required: true},
+		{name: "url"},
+		{name: "notes"},
+	},
+	models.DataTypeBankCard: {
+		{name: "card_number", secret: true, required: true},
+		{name: "expiry_date", required: true},
+		{name: "cvv", secret: true, required: true},
+		{name: "cardholder", required: true},
+		{name: "bank"},
+		{name: "notes"},
+	},
+	models.DataTypeText: {
+		{name: "content", required: true},
+		{name: "notes"},
+	},
+}
+
+// StdinIsTerminal reports whether standard input is an interactive terminal rather
+// than a pipe or file, i.e. whether prompts are answered by a person
+func StdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// UpdateCommand edits the name, description and payload fields of an item one by one.
+// Enter keeps a value and "-" clears an optional one. Scripts piping the new content
+// in still get the whole-payload update, with a warning, during the transition period.
+func (s *ClientSession) UpdateCommand(ctx context.Context, id string) error {
+	if !StdinIsTerminal() {
+		fmt.Fprintln(os.Stderr, pipedUpdateWarning)
+		return s.UpdateRawCommand(ctx, id)
+	}
+	return s.editItem(ctx, id, nil, bufio.NewScanner(os.Stdin), os.Stdout)
+}
+
+// UpdateFieldsCommand edits only the named fields of an item, which may include
+// name and description. Unlike UpdateCommand it also reads piped answers, so a
+// script can change a single field: echo "$NEW" | update <id> --field password
+func (s *ClientSession) UpdateFieldsCommand(ctx context.Context, id string, names []string) error {
+	return s.editItem(ctx, id, names, bufio.NewScanner(os.Stdin), os.Stdout)
+}
+
+// editItem runs the structured editor with prompts written to out and answers
+// read from in. It prompts for the fields in only, or for all of them if empty.
+func (s *ClientSession) editItem(ctx context.Context, id string, only []string, in *bufio.Scanner, out io.Writer) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+
+	if len(id) == 0 {
+		return fmt.Errorf("data ID is required")
+	}
+
+	data, _, err := s.getForDisplay(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+
+	fields, ok := editableFields[data.Type]
+	if !ok {
+		return fmt.Errorf("%s items have no editable fields; re-create it, or use 'update --raw %s' (deprecated)", data.Type, id)
+	}
+
+	decrypted, err := s.cryptoManager.Decrypt(data.Data)
+	if err != nil {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": data.ID.String()})
+		return fmt.Errorf("failed to decrypt current data: %w", err)
+	}
+
+	// unknown keys, e.g. written by newer clients, are kept as they are
+	var payload map[string]interface{}
+	if err := json.Unmarshal(decrypted, &payload); err != nil {
+		return fmt.Errorf("item %q has no structured fields; use 'update --raw %s' (deprecated) to replace it", data.Name, id)
+	}
+
+	selected := make(map[string]bool, len(only))
+	for _, name := range only {
+		if name != "name" && name != "description" && !hasEditableField(fields, name) {
+			return fmt.Errorf("unknown field %s for %s items (fields: %s)", name, data.Type, editableFieldNames(fields))
+		}
+		selected[name] = true
+	}
+	edits := func(name string) bool {
+		return len(selected) == 0 || selected[name]
+	}
+
+	fmt.Fprintf(out, "Editing %q (Enter keeps a value, %q clears an optional one)\n", CleanQuotes(data.Name), clearFieldInput)
+
+	changed := false
+	prompt := func(field editableField, current string) (string, error) {
+		shown := current
+		if field.secret && current != "" {
+			shown = "hidden"
+		}
+		fmt.Fprintf(out, "%s [%s]: ", field.name, shown)
+		var answer string
+		if field.secret && stdinIsTerminal() {
+			// secrets are typed without echo
+			secret, err := readHidden()
+			fmt.Fprintln(out)
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s", field.name)
+			}
+			answer = strings.TrimSpace(string(secret))
+		} else {
+			if !in.Scan() {
+				return "", fmt.Errorf("failed to read %s", field.name)
+			}
+			answer = strings.TrimSpace(in.Text())
+		}
+		switch {
+		case answer == "":
+			return current, nil
+		case answer == clearFieldInput && field.required:
+			return "", fmt.Errorf("%s is required and cannot be cleared", field.name)
+		case answer == clearFieldInput:
+			answer = ""
+		}
+		if answer != current {
+			changed = true
+		}
+		return answer, nil
+	}
+
+	name, description := CleanQuotes(data.Name), CleanQuotes(data.Description)
+	if edits("name") {
+		if name, err = prompt(editableField{name: "name", required: true}, name); err != nil {
+			return err
+		}
+	}
+	if edits("description") {
+		if description, err = prompt(editableField{name: "description"}, description); err != nil {
+			return err
+		}
+	}
+	for _, field := range fields {
+		if !edits(field.name) {
+			continue
+		}
+		current := ""
+		if value, ok := payload[field.name]; ok && value != nil {
+			current = fmt.Sprint(value)
+		}
+		value, err := prompt(field, current)
+		if err != nil {
+			return err
+		}
+		if value == "" && !field.required {
+			delete(payload, field.name)
+		} else {
+			payload[field.name] = value
+		}
+	}
+
+	if !changed {
+		fmt.Fprintln(out, "No changes")
+		return nil
+	}
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %w", err)
+	}
+	encryptedContent, err := s.cryptoManager.Encrypt(content)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt new data: %w", err)
+	}
+
+	dataReq := models.DataRequest{
+		Type:          data.Type,
+		Name:          name,
+		Description:   description,
+		Data:          encryptedContent,
+		Metadata:      ItemMetadata(data.Type, payload, data.Metadata),
+		Environment:   data.Environment,
+		BaseUpdatedAt: &data.UpdatedAt,
+		BaseRevision:  baseRevision(data),
+	}
+	return s.saveUpdate(ctx, data, dataReq, in, out)
+}
+
+// PatchData changes the name, description or metadata of an item and keeps its
+// encrypted payload
+func (c *Client) PatchData(ctx context.Context, id string, patch models.DataPatchRequest) (*models.Data, error) {
+	var resp models.DataResponse
+	if err := c.doJSON(ctx, http.MethodPatch, "/api/v1/data/"+url.PathEscape(id), patch, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+// PatchItem changes the name, description or metadata of an item by ID or
+// unique ID prefix without decrypting or re-encrypting its payload. Servers
+// without PATCH support get the unchanged ciphertext back in a full update.
+func (s *ClientSession) PatchItem(ctx context.Context, id string, patch models.DataPatchRequest) (*models.Data, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := s.cli.GetStatus(ctx)
+	if err == nil && hasFeature(status, "metadata_patch") {
+		return s.cli.PatchData(ctx, id, patch)
+	}
+
+	data, err := s.cli.GetDataByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	dataReq := models.DataRequest{
+		Type:          data.Type,
+		Name:          data.Name,
+		Description:   data.Description,
+		Data:          data.Data,
+		Metadata:      data.Metadata,
+		Environment:   data.Environment,
+		BaseUpdatedAt: &data.UpdatedAt,
+		BaseRevision:  baseRevision(data),
+		Checksum:      data.Checksum,
+	}
+	if patch.Name != nil {
+		dataReq.Name = *patch.Name
+	}
+	if patch.Description != nil {
+		dataReq.Description = *patch.Description
+	}
+	if patch.Metadata != nil {
+		dataReq.Metadata = *patch.Metadata
+	}
+	return s.cli.UpdateData(ctx, id, dataReq)
+}
+
+// RenameCommand renames an item, keeping its encrypted payload
+func (s *ClientSession) RenameCommand(ctx context.Context, id, name string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	name = CleanQuotes(name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	data, err := s.PatchItem(ctx, id, models.DataPatchRequest{Name: &name})
+	if err != nil {
+		return fmt.Errorf("failed to rename data: %w", err)
+	}
+	fmt.Printf("Renamed %s to %q\n", data.ID, CleanQuotes(data.Name))
+	return nil
+}
+
+// DescribeCommand sets the description of an item, or clears it if empty,
+// keeping its encrypted payload
+func (s *ClientSession) DescribeCommand(ctx context.Context, id, description string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	description = CleanQuotes(description)
+	data, err := s.PatchItem(ctx, id, models.DataPatchRequest{Description: &description})
+	if err != nil {
+		return fmt.Errorf("failed to update description: %w", err)
+	}
+	if description == "" {
+		fmt.Printf("Cleared the description of %q\n", CleanQuotes(data.Name))
+	} else {
+		fmt.Printf("Updated the description of %q\n", CleanQuotes(data.Name))
+	}
+	return nil
+}
+
+// hasEditableField reports whether fields include one called name
+func hasEditableField(fields []editableField, name string) bool {
+	for _, field := range fields {
+		if field.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// editableFieldNames lists the names of fields, including name and description
+func editableFieldNames(fields []editableField) string {
+	names := []string{"name", "description"}
+	for _, field := range fields {
+		names = append(names, field.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ItemMetadata rebuilds the plaintext summary stored next to the encrypted
+// payload of an item of dataType, so it does not go stale after an edit. Types
+// without a summary keep current.
+func ItemMetadata(dataType models.DataType, payload map[string]interface{}, current string) string {
+	field := func(name string) string {
+		if value, ok := payload[name]; ok && value != nil {
+			return fmt.Sprint(value)
+		}
+		return ""
+	}
+
+	switch dataType {
+	case models.DataTypeLoginPassword:
+		return fmt.Sprintf("Login: %s, URL: %s", field("login"), field("url"))
+	case models.DataTypeBankCard:
+		return fmt.Sprintf("Card: %s, Bank: %s", field("card_number"), field("bank"))
+	case models.DataTypeText:
+		return fmt.Sprintf("Length: %d characters", len(field("content")))
+	case models.DataTypeOTP:
+		return fmt.Sprintf("Issuer: %s, Account: %s", field("issuer"), field("account"))
+	default:
+		return current
+	}
+}
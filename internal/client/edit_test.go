@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func demoItemID(t *testing.T, session *ClientSession, name string) string {
+	t.Helper()
+	items, err := session.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	for _, item := range items {
+		if item.Name == name {
+			return item.ID.String()
+		}
+	}
+	t.Fatalf("Demo item %q not found", name)
+	return ""
+}
+
+func TestClientSession_EditItem(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	id := demoItemID(t, session, "Demo Email")
+
+	// keep name, description and login, change the password, clear the URL
+	var out bytes.Buffer
+	in := bufio.NewScanner(strings.NewReader("\n\n\nnew-password\n-\n\n"))
+	if err := session.editItem(ctx, id, nil, in, &out); err != nil {
+		t.Fatalf("editItem() error = %v", err)
+	}
+	if strings.Contains(out.String(), "correct-horse-battery-staple") {
+		t.Error("Secret fields must not be shown in prompts")
+	}
+
+	data, err := session.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	decrypted, err := session.cryptoManager.Decrypt(data.Data)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	var payload models.LoginPasswordData
+	if err := json.Unmarshal(decrypted, &payload); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := models.LoginPasswordData{Login: "demo@example.com", Password: "new-password", Notes: "Not a real account"}
+	if payload != want {
+		t.Errorf("Expected payload %+v, got %+v", want, payload)
+	}
+	if data.Name != "Demo Email" || data.Description != "Sample webmail login" {
+		t.Errorf("Expected name and description to be kept, got %q, %q", data.Name, data.Description)
+	}
+	if data.Metadata != "Login: demo@example.com, URL: " {
+		t.Errorf("Expected metadata to follow the edit, got %q", data.Metadata)
+	}
+}
+
+func TestClientSession_EditItemFields(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	id := demoItemID(t, session, "Demo Email")
+
+	var out bytes.Buffer
+	if err := session.editItem(ctx, id, []string{"password"}, bufio.NewScanner(strings.NewReader("n3w-password\n")), &out); err != nil {
+		t.Fatalf("editItem() error = %v", err)
+	}
+	if strings.Contains(out.String(), "login [") || strings.Contains(out.String(), "name [") {
+		t.Errorf("Expected a prompt for the password only, got %q", out.String())
+	}
+
+	data, err := session.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	fields, err := session.ItemFields(data)
+	if err != nil {
+		t.Fatalf("ItemFields() error = %v", err)
+	}
+	if fieldValue(fields, "password") != "n3w-password" || fieldValue(fields, "login") != "demo@example.com" || data.Name != "Demo Email" {
+		t.Errorf("Expected only the password to change, got %+v", fields)
+	}
+
+	err = session.editItem(ctx, id, []string{"pin"}, bufio.NewScanner(strings.NewReader("1234\n")), &out)
+	if err == nil || !strings.Contains(err.Error(), "unknown field pin") {
+		t.Errorf("Expected an unknown field error, got %v", err)
+	}
+}
+
+func TestClientSession_EditItemRejected(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		item    string
+		input   string
+		wantErr string
+		wantOut string
+	}{
+		{name: "no changes", item: "Wi-Fi Notes", input: "\n\n\n\n", wantOut: "No changes"},
+		{name: "clear required field", item: "Demo Visa", input: "\n\n-\n", wantErr: "card_number is required"},
+		{name: "binary item", item: "hello.txt", wantErr: "update --raw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := demoItemID(t, session, tt.item)
+			before, err := session.Get(ctx, id)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			var out bytes.Buffer
+			err = session.editItem(ctx, id, nil, bufio.NewScanner(strings.NewReader(tt.input)), &out)
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("editItem() error = %v", err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("Expected output containing %q, got %q", tt.wantOut, out.String())
+			}
+
+			after, err := session.Get(ctx, id)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if !after.UpdatedAt.Equal(before.UpdatedAt) {
+				t.Error("Item must not be saved")
+			}
+		})
+	}
+}
+
+func TestClientSession_RenameAndDescribe(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	id := demoItemID(t, session, "Demo Email")
+	before, err := session.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if err := session.RenameCommand(ctx, id[:8], `"Work mail"`); err != nil {
+		t.Fatalf("RenameCommand() error = %v", err)
+	}
+	if err := session.DescribeCommand(ctx, id, ""); err != nil {
+		t.Fatalf("DescribeCommand() error = %v", err)
+	}
+	if err := session.RenameCommand(ctx, id, ""); err == nil {
+		t.Error("Expected an empty name to be rejected")
+	}
+
+	after, err := session.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if after.Name != "Work mail" || after.Description != "" {
+		t.Errorf("Expected the item renamed and its description cleared, got %q, %q", after.Name, after.Description)
+	}
+	if !bytes.Equal(after.Data, before.Data) || after.Metadata != before.Metadata {
+		t.Error("Expected the encrypted payload and metadata to be kept")
+	}
+}
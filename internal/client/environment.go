@@ -0,0 +1,59 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxEnvironmentLength matches the environment column size on the server
+const maxEnvironmentLength = 32
+
+// ErrInvalidEnvironment is returned for environment names that cannot be stored
+var ErrInvalidEnvironment = fmt.Errorf("environment must be 1-%d characters of a-z, 0-9, '-' or '_'", maxEnvironmentLength)
+
+// NormalizeEnvironment lowercases and validates an environment name such as dev, staging or prod
+func NormalizeEnvironment(environment string) (string, error) {
+	environment = strings.ToLower(strings.TrimSpace(environment))
+	if environment == "" || len(environment) > maxEnvironmentLength {
+		return "", ErrInvalidEnvironment
+	}
+	for _, r := range environment {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return "", ErrInvalidEnvironment
+		}
+	}
+	return environment, nil
+}
+
+// EnvCommand shows, sets or clears the default environment stored in the client config
+func EnvCommand(config *Config, args []string) error {
+	if len(args) == 0 {
+		if config.DefaultEnvironment == "" {
+			fmt.Println("No default environment set")
+		} else {
+			fmt.Printf("Default environment: %s\n", config.DefaultEnvironment)
+		}
+		return nil
+	}
+
+	if args[0] == "clear" {
+		config.DefaultEnvironment = ""
+		if err := SaveConfig(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Println("Default environment cleared")
+		return nil
+	}
+
+	environment, err := NormalizeEnvironment(args[0])
+	if err != nil {
+		return err
+	}
+
+	config.DefaultEnvironment = environment
+	if err := SaveConfig(config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("Default environment set to %s\n", environment)
+	return nil
+}
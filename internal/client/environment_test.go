@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestNormalizeEnvironment(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "lowercase", input: "prod", want: "prod"},
+		{name: "uppercase and spaces", input: "  Staging ", want: "staging"},
+		{name: "dash and digits", input: "dev-2", want: "dev-2"},
+		{name: "empty", input: "", wantErr: true},
+		{name: "invalid characters", input: "prod env", wantErr: true},
+		{name: "too long", input: "abcdefghijklmnopqrstuvwxyz0123456", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeEnvironment(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeEnvironment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeEnvironment() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvCommand(t *testing.T) {
+	config := &Config{Ephemeral: true}
+
+	if err := EnvCommand(config, []string{"PROD"}); err != nil {
+		t.Fatalf("EnvCommand() error = %v", err)
+	}
+	if config.DefaultEnvironment != "prod" {
+		t.Errorf("Expected default environment prod, got %q", config.DefaultEnvironment)
+	}
+
+	if err := EnvCommand(config, []string{"bad env!"}); err == nil {
+		t.Error("Expected error for invalid environment")
+	}
+	if config.DefaultEnvironment != "prod" {
+		t.Errorf("Invalid environment should not change the default, got %q", config.DefaultEnvironment)
+	}
+
+	if err := EnvCommand(config, []string{"clear"}); err != nil {
+		t.Fatalf("EnvCommand() error = %v", err)
+	}
+	if config.DefaultEnvironment != "" {
+		t.Errorf("Expected default environment to be cleared, got %q", config.DefaultEnvironment)
+	}
+}
+
+func TestClientSession_ListFiltered_Environment(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	encrypted, err := session.GetCryptoManager().Encrypt([]byte("sk_live_123"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	for _, env := range []string{"prod", "staging"} {
+		_, err := session.Create(ctx, models.DataRequest{
+			Type:        models.DataTypeText,
+			Name:        "API_KEY",
+			Data:        encrypted,
+			Environment: env,
+		})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	items, err := session.ListFiltered(ctx, models.DataFilter{Environment: "prod"})
+	if err != nil {
+		t.Fatalf("ListFiltered() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Environment != "prod" {
+		t.Errorf("Expected one prod item, got %+v", items)
+	}
+
+	all, err := session.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(all) != 6 {
+		t.Errorf("Expected 6 items without filter, got %d", len(all))
+	}
+}
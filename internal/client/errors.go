@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// Error kinds to branch on with errors.Is instead of matching messages
+var (
+	// ErrNotFound matches API errors for items, users and routes the server does not have
+	ErrNotFound = errors.New("not found")
+	// ErrAccessDenied matches API errors for requests the account may not make
+	ErrAccessDenied = errors.New("access denied")
+	// ErrUnauthorized matches API errors for missing, invalid or expired credentials
+	ErrUnauthorized = errors.New("not authorized - please login again")
+	// ErrQuotaExceeded matches API errors for requests over a server limit,
+	// such as the payload size or the rate of attempts
+	ErrQuotaExceeded = errors.New("server limit exceeded")
+	// ErrServerUnavailable matches failures to reach the server, gateway
+	// errors, an overloaded server and maintenance mode
+	ErrServerUnavailable = errors.New("server unavailable")
+)
+
+// APIError is an error response of the server
+type APIError struct {
+	Status int
+	// Code is one of the models.ErrorCode constants; for servers without
+	// codes it is derived from Status
+	Code string
+	// Message is the detail of the error when given and its short description otherwise
+	Message string
+	// RequestID finds the server logs of the failed request
+	RequestID string
+	// Fields are the invalid fields of a rejected request
+	Fields []models.FieldError
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	if e.RequestID == "" {
+		return fmt.Sprintf("server error: %s", e.Message)
+	}
+	return fmt.Sprintf("server error: %s (request ID %s)", e.Message, e.RequestID)
+}
+
+// Is makes errors.Is match the error with the error kind of its code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Code == models.ErrorCodeNotFound
+	case ErrAccessDenied:
+		return e.Code == models.ErrorCodeForbidden
+	case ErrUnauthorized:
+		return e.Code == models.ErrorCodeUnauthorized
+	case ErrConflict:
+		return e.Code == models.ErrorCodeConflict
+	case ErrQuotaExceeded:
+		return e.Code == models.ErrorCodePayloadTooLarge || e.Code == models.ErrorCodeRateLimited
+	case ErrServerUnavailable:
+		return e.Code == models.ErrorCodeUnavailable || e.Code == models.ErrorCodeOverloaded ||
+			e.Code == models.ErrorCodeMaintenance
+	case ErrRegistrationClosed:
+		return e.Code == models.ErrorCodeRegistrationClosed
+	default:
+		return false
+	}
+}
+
+// requestError is a request that got no response from the server
+type requestError struct {
+	err error
+}
+
+// requestFailed wraps a failure to get a response, which matches
+// ErrServerUnavailable unless the request was cancelled
+func requestFailed(err error) error {
+	return &requestError{err: err}
+}
+
+// Error implements error
+func (e *requestError) Error() string {
+	return "request failed: " + e.err.Error()
+}
+
+// Unwrap returns the cause of the failure
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// Is makes errors.Is match the error with ErrServerUnavailable
+func (e *requestError) Is(target error) bool {
+	return target == ErrServerUnavailable && !errors.Is(e.err, context.Canceled)
+}
+
+// parseAPIError turns an error response into an *APIError, whether the body
+// is a models.ErrorResponse or plain text from an older server or a proxy
+func parseAPIError(resp *http.Response, respBody []byte) *APIError {
+	apiErr := &APIError{
+		Status:    resp.StatusCode,
+		Message:   strings.TrimSpace(string(respBody)),
+		RequestID: resp.Header.Get(requestIDHeader),
+	}
+	var errResp models.ErrorResponse
+	if err := json.Unmarshal(respBody, &errResp); err == nil {
+		apiErr.Code = errResp.Code
+		apiErr.Fields = errResp.Fields
+		apiErr.Message = errResp.Error
+		if errResp.Message != "" {
+			apiErr.Message = errResp.Message
+		}
+	}
+	if apiErr.Code == "" {
+		apiErr.Code = models.ErrorCodeForStatus(resp.StatusCode)
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// statusError converts an unexpected response into an error with the server's
+// message: a rejection error such as *MaintenanceError, or an *APIError
+func statusError(resp *http.Response, respBody []byte) error {
+	if err := rejectionError(resp, respBody); err != nil {
+		return err
+	}
+
+	apiErr := parseAPIError(resp, respBody)
+	logger.Log.Warn("Request failed", zap.Int("status_code", apiErr.Status), zap.String("code", apiErr.Code),
+		zap.String("error", apiErr.Message), zap.String("request_id", apiErr.RequestID))
+	return apiErr
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestStatusError_APIError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    APIError
+		matches error
+	}{
+		{
+			name:    "structured",
+			status:  http.StatusNotFound,
+			body:    `{"error":"Data not found","code":"not_found"}`,
+			want:    APIError{Status: http.StatusNotFound, Code: models.ErrorCodeNotFound, Message: "Data not found"},
+			matches: ErrNotFound,
+		},
+		{
+			name:    "specific code",
+			status:  http.StatusForbidden,
+			body:    `{"error":"Registration is closed","code":"registration_closed"}`,
+			want:    APIError{Status: http.StatusForbidden, Code: models.ErrorCodeRegistrationClosed, Message: "Registration is closed"},
+			matches: ErrRegistrationClosed,
+		},
+		{
+			name:    "plain text from an older server",
+			status:  http.StatusForbidden,
+			body:    "Access denied\n",
+			want:    APIError{Status: http.StatusForbidden, Code: models.ErrorCodeForbidden, Message: "Access denied"},
+			matches: ErrAccessDenied,
+		},
+		{
+			name:    "empty body",
+			status:  http.StatusUnauthorized,
+			want:    APIError{Status: http.StatusUnauthorized, Code: models.ErrorCodeUnauthorized, Message: "Unauthorized"},
+			matches: ErrUnauthorized,
+		},
+		{
+			name:    "conflict",
+			status:  http.StatusConflict,
+			body:    `{"error":"Data was modified by another device","code":"conflict"}`,
+			want:    APIError{Status: http.StatusConflict, Code: models.ErrorCodeConflict, Message: "Data was modified by another device"},
+			matches: ErrConflict,
+		},
+		{
+			name:    "payload too large",
+			status:  http.StatusRequestEntityTooLarge,
+			body:    "Request body too large",
+			want:    APIError{Status: http.StatusRequestEntityTooLarge, Code: models.ErrorCodePayloadTooLarge, Message: "Request body too large"},
+			matches: ErrQuotaExceeded,
+		},
+		{
+			name:    "bad gateway",
+			status:  http.StatusBadGateway,
+			body:    "<html>502 Bad Gateway</html>",
+			want:    APIError{Status: http.StatusBadGateway, Code: models.ErrorCodeUnavailable, Message: "<html>502 Bad Gateway</html>"},
+			matches: ErrServerUnavailable,
+		},
+		{
+			name:    "overloaded",
+			status:  http.StatusServiceUnavailable,
+			body:    `{"error":"overloaded","message":"The server is busy, please retry shortly.","code":"overloaded"}`,
+			want:    APIError{Status: http.StatusServiceUnavailable, Code: models.ErrorCodeOverloaded, Message: "The server is busy, please retry shortly."},
+			matches: ErrServerUnavailable,
+		},
+		{
+			name:   "invalid fields",
+			status: http.StatusBadRequest,
+			body:   `{"error":"Invalid request","message":"name is required","code":"invalid_request","fields":[{"field":"name","rule":"required","message":"name is required"}]}`,
+			want: APIError{Status: http.StatusBadRequest, Code: models.ErrorCodeInvalidRequest, Message: "name is required",
+				Fields: []models.FieldError{{Field: "name", Rule: "required", Message: "name is required"}}},
+		},
+		{
+			name:   "detail",
+			status: http.StatusTooManyRequests,
+			body:   `{"error":"rate_limited","message":"Too many attempts, please retry later.","code":"rate_limited"}`,
+			want:   APIError{Status: http.StatusTooManyRequests, Code: models.ErrorCodeRateLimited, Message: "Too many attempts, please retry later."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: make(http.Header)}
+			err := statusError(resp, []byte(tt.body))
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || !reflect.DeepEqual(*apiErr, tt.want) {
+				t.Fatalf("statusError() = %#v, want %+v", err, tt.want)
+			}
+			if tt.matches != nil && !errors.Is(err, tt.matches) {
+				t.Errorf("Expected the error to match %v", tt.matches)
+			}
+			if tt.matches != ErrNotFound && errors.Is(err, ErrNotFound) {
+				t.Error("Expected no match with unrelated errors")
+			}
+		})
+	}
+}
+
+func TestErrServerUnavailable(t *testing.T) {
+	maintenance := statusError(&http.Response{StatusCode: http.StatusServiceUnavailable, Header: make(http.Header)},
+		[]byte(`{"error":"maintenance","message":"Nightly backup","code":"maintenance"}`))
+	if !IsMaintenance(maintenance) || !errors.Is(maintenance, ErrServerUnavailable) {
+		t.Errorf("Expected maintenance to be a server unavailable error, got %v", maintenance)
+	}
+
+	cli := NewClient("http://127.0.0.1:1")
+	_, err := cli.GetData(context.Background())
+	if !errors.Is(err, ErrServerUnavailable) || !isOffline(err) || !strings.HasPrefix(err.Error(), "request failed: ") {
+		t.Errorf("Expected an unreachable server to be unavailable, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cli.GetData(ctx); errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("Expected a cancelled request not to be unavailable, got %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// escrowConsentPhrase must be typed to consent to key escrow
+const escrowConsentPhrase = "I consent"
+
+// RecoveredItem is a decrypted item recovered from key escrow
+type RecoveredItem struct {
+	ID          string          `json:"id"`
+	Type        models.DataType `json:"type"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Environment string          `json:"environment,omitempty"`
+	Metadata    string          `json:"metadata,omitempty"`
+	// Content is the decrypted payload; binary files are base64 encoded
+	Content string `json:"content"`
+	Error   string `json:"error,omitempty"`
+}
+
+// GetRecoveryKey gets the organization recovery public key
+func (c *Client) GetRecoveryKey(ctx context.Context) (*models.RecoveryKeyResponse, error) {
+	var resp models.RecoveryKeyResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/escrow/recovery-key", nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetEscrowStatus gets the user's key escrow status
+func (c *Client) GetEscrowStatus(ctx context.Context) (*models.KeyEscrowStatusResponse, error) {
+	var resp models.KeyEscrowStatusResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/escrow", nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SetEscrow stores the wrapped vault key with the user's consent
+func (c *Client) SetEscrow(ctx context.Context, req models.KeyEscrowRequest) error {
+	return c.doJSON(ctx, http.MethodPut, "/api/v1/escrow", req, nil, http.StatusNoContent)
+}
+
+// DeleteEscrow withdraws key escrow consent
+func (c *Client) DeleteEscrow(ctx context.Context) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/v1/escrow", nil, nil, http.StatusNoContent)
+}
+
+// GetEscrowRecovery gets a consenting user's escrowed vault using the admin token
+func (c *Client) GetEscrowRecovery(ctx context.Context, adminToken, username string) (*models.EscrowRecoveryResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v1/admin/escrow/"+url.PathEscape(username), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Admin-Token", adminToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Escrow recovery request failed", zap.Error(err))
+		return nil, requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, body)
+	}
+
+	var recovery models.EscrowRecoveryResponse
+	if err := json.Unmarshal(body, &recovery); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &recovery, nil
+}
+
+// EnableEscrow wraps the vault key under the organization recovery key and stores it.
+// The caller is responsible for obtaining the user's explicit consent first.
+func (s *ClientSession) EnableEscrow(ctx context.Context) (string, error) {
+	if !s.IsAuthenticated() {
+		return "", ErrNotAuthenticated
+	}
+
+	recoveryKey, err := s.cli.GetRecoveryKey(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get recovery key: %w", err)
+	}
+	if crypto.RecoveryKeyID(recoveryKey.PublicKey) != recoveryKey.KeyID {
+		return "", fmt.Errorf("recovery key ID does not match the key")
+	}
+
+	wrapped, err := crypto.WrapKey(recoveryKey.PublicKey, s.cryptoManager.Key())
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.cli.SetEscrow(ctx, models.KeyEscrowRequest{
+		Consent:       true,
+		WrappedKey:    wrapped,
+		RecoveryKeyID: recoveryKey.KeyID,
+	}); err != nil {
+		return "", fmt.Errorf("failed to store escrow: %w", err)
+	}
+
+	s.recordEvent(EventExport, map[string]string{"via": "key-escrow", "recovery_key_id": recoveryKey.KeyID})
+	return recoveryKey.KeyID, nil
+}
+
+// EscrowCommand shows, enables or disables key escrow
+func (s *ClientSession) EscrowCommand(ctx context.Context, args []string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "status":
+		status, err := s.cli.GetEscrowStatus(ctx)
+		if err != nil {
+			return err
+		}
+		if !status.Enabled {
+			fmt.Println("Key escrow: disabled")
+		} else {
+			fmt.Printf("Key escrow: enabled since %s (recovery key %s)\n", status.ConsentedAt, status.RecoveryKeyID)
+			if status.CurrentKeyID != "" && status.CurrentKeyID != status.RecoveryKeyID {
+				fmt.Println("The organization recovery key has changed; run 'escrow enable' again to renew.")
+			}
+		}
+		if status.CurrentKeyID == "" {
+			fmt.Println("This server does not offer key escrow.")
+		}
+		return nil
+	case "enable":
+		fmt.Println("Key escrow lets your organization's admins decrypt ALL items in this vault")
+		fmt.Println("using their recovery key, e.g. after you leave. Your master password is not shared.")
+		fmt.Printf("Type '%s' to continue: ", escrowConsentPhrase)
+		scanner := bufio.NewScanner(os.Stdin)
+		if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != escrowConsentPhrase {
+			fmt.Println("Key escrow not enabled")
+			return nil
+		}
+		keyID, err := s.EnableEscrow(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Key escrow enabled under recovery key %s\n", keyID)
+		return nil
+	case "disable":
+		if err := s.cli.DeleteEscrow(ctx); err != nil {
+			return err
+		}
+		fmt.Println("Key escrow disabled; your wrapped key was deleted from the server")
+		return nil
+	default:
+		return fmt.Errorf("unknown escrow action: %s (use status, enable or disable)", action)
+	}
+}
+
+// RecoverEscrow decrypts a consenting user's vault with the organization recovery private key
+func RecoverEscrow(recovery *models.EscrowRecoveryResponse, recoveryPrivateKey []byte) ([]RecoveredItem, error) {
+	vaultKey, err := crypto.UnwrapKey(recoveryPrivateKey, recovery.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap vault key: %w", err)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(recovery.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user salt: %w", err)
+	}
+
+	cryptoManager, err := crypto.NewCryptoManagerWithKey(vaultKey, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]RecoveredItem, len(recovery.Data))
+	for i, data := range recovery.Data {
+		items[i] = RecoveredItem{
+			ID:          data.ID.String(),
+			Type:        data.Type,
+			Name:        data.Name,
+			Description: data.Description,
+			Environment: data.Environment,
+			Metadata:    data.Metadata,
+		}
+
+		decrypted, err := cryptoManager.Decrypt(data.Data)
+		if err != nil {
+			items[i].Error = err.Error()
+			continue
+		}
+		if data.Type == models.DataTypeBinary {
+			items[i].Content = base64.StdEncoding.EncodeToString(decrypted)
+		} else {
+			items[i].Content = string(decrypted)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+func TestKeyEscrow_EnableAndRecover(t *testing.T) {
+	ctx := context.Background()
+	publicKey, privateKey, err := crypto.GenerateRecoveryKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryKeyPair() error = %v", err)
+	}
+
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := mux.NewRouter()
+	server.RegisterRoutes(router, store, store, jwtManager, server.Options{})
+	server.RegisterEscrowRoutes(router, store, store, store, jwtManager,
+		server.EscrowOptions{RecoveryPublicKey: publicKey, AdminToken: "admin-secret"})
+
+	cli := NewClient("http://escrow.invalid")
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+
+	resp, err := cli.Register(ctx, "leaver", "password", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	cli.SetToken(resp.Token)
+
+	session := NewClientSession(cli)
+	if err := session.Unlock("master-password", resp.Salt); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	encrypted, err := session.GetCryptoManager().Encrypt([]byte(`{"content":"team runbook"}`))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if _, err := session.Create(ctx, models.DataRequest{Type: models.DataTypeText, Name: "Runbook", Data: encrypted}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	admin := NewClient("http://escrow.invalid")
+	admin.httpClient = cli.httpClient
+	if _, err := admin.GetEscrowRecovery(ctx, "admin-secret", "leaver"); err == nil {
+		t.Fatal("Recovery should fail before the user consents")
+	}
+
+	keyID, err := session.EnableEscrow(ctx)
+	if err != nil {
+		t.Fatalf("EnableEscrow() error = %v", err)
+	}
+	if keyID != crypto.RecoveryKeyID(publicKey) {
+		t.Errorf("Expected key ID %s, got %s", crypto.RecoveryKeyID(publicKey), keyID)
+	}
+
+	recovery, err := admin.GetEscrowRecovery(ctx, "admin-secret", "leaver")
+	if err != nil {
+		t.Fatalf("GetEscrowRecovery() error = %v", err)
+	}
+	items, err := RecoverEscrow(recovery, privateKey)
+	if err != nil {
+		t.Fatalf("RecoverEscrow() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Content != `{"content":"team runbook"}` || items[0].Error != "" {
+		t.Errorf("Unexpected recovered items: %+v", items)
+	}
+
+	_, otherPrivateKey, _ := crypto.GenerateRecoveryKeyPair()
+	if _, err := RecoverEscrow(recovery, otherPrivateKey); err == nil {
+		t.Error("Expected error when recovering with a different private key")
+	}
+
+	if _, err := admin.GetEscrowRecovery(ctx, "wrong", "leaver"); err == nil {
+		t.Error("Expected error with a wrong admin token")
+	}
+
+	if err := session.EscrowCommand(ctx, []string{"disable"}); err != nil {
+		t.Fatalf("EscrowCommand(disable) error = %v", err)
+	}
+	if _, err := admin.GetEscrowRecovery(ctx, "admin-secret", "leaver"); err == nil {
+		t.Error("Recovery should fail after consent is withdrawn")
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(recovery.Salt); err != nil {
+		t.Errorf("Recovery salt should be base64: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// eventIdleTimeout drops an event stream that stays silent for several server
+// heartbeats, so a dead connection is noticed and opened again
+var eventIdleTimeout = 90 * time.Second
+
+// Delays between attempts to open the event stream again
+var (
+	minEventRetryDelay = time.Second
+	maxEventRetryDelay = time.Minute
+)
+
+// ErrEventsUnsupported is returned by servers that do not stream change events
+var ErrEventsUnsupported = errors.New("the server does not stream change events")
+
+// EventStream reads the server-sent change events of the user's items
+type EventStream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	cancel context.CancelFunc
+	idle   *time.Timer
+}
+
+func newEventStream(body io.ReadCloser, cancel context.CancelFunc) *EventStream {
+	return &EventStream{body: body, reader: bufio.NewReader(body), cancel: cancel, idle: time.AfterFunc(eventIdleTimeout, cancel)}
+}
+
+// OpenEvents opens the stream of changes to the user's items. It stays open
+// until ctx is done, the connection drops or the stream is closed.
+func (c *Client) OpenEvents(ctx context.Context) (*EventStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/events", nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.authorize(req)
+
+	// the stream outlives the timeout of ordinary requests
+	httpClient := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, requestFailed(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer cancel()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrEventsUnsupported
+		}
+		return nil, statusError(resp, body)
+	}
+	return newEventStream(resp.Body, cancel), nil
+}
+
+// Next blocks until the next change event arrives
+func (s *EventStream) Next() (models.ChangeEvent, error) {
+	var eventType, data string
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return models.ChangeEvent{}, err
+		}
+		s.idle.Reset(eventIdleTimeout)
+
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if data != "" && (eventType == "" || eventType == "change") {
+				var event models.ChangeEvent
+				if err := json.Unmarshal([]byte(data), &event); err != nil {
+					return models.ChangeEvent{}, fmt.Errorf("invalid change event: %w", err)
+				}
+				return event, nil
+			}
+			eventType, data = "", ""
+		case strings.HasPrefix(line, ":"):
+			// a comment, sent as a heartbeat
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				eventType = value
+			case "data":
+				if data != "" {
+					data += "\n"
+				}
+				data += value
+			}
+		}
+	}
+}
+
+// Close closes the stream
+func (s *EventStream) Close() error {
+	s.idle.Stop()
+	s.cancel()
+	return s.body.Close()
+}
+
+// WatchChanges follows the change events of the user's items until ctx is
+// done and calls onChange after the vault changed, so the local cache can be
+// refreshed without polling. Changes arriving while onChange runs are
+// coalesced into one more call, as is any change missed while reconnecting.
+// lock is held while the stream is opened, as commands may replace the token
+// meanwhile; a locked vault is not watched. Watching stops for good on
+// servers without change events.
+func (s *ClientSession) WatchChanges(ctx context.Context, lock sync.Locker, onChange func(ctx context.Context)) {
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+				onChange(ctx)
+			}
+		}
+	}()
+
+	go func() {
+		delay := minEventRetryDelay
+		connected := false
+		for {
+			lock.Lock()
+			var stream *EventStream
+			err := ErrNotAuthenticated
+			if s.IsAuthenticated() {
+				stream, err = s.cli.OpenEvents(ctx)
+			}
+			lock.Unlock()
+
+			switch {
+			case errors.Is(err, ErrEventsUnsupported):
+				return
+			case errors.Is(err, ErrNotAuthenticated):
+				delay = minEventRetryDelay
+			case err == nil:
+				if connected {
+					notify()
+				}
+				connected = true
+				delay = minEventRetryDelay
+				err = followEvents(stream, notify)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, ErrNotAuthenticated) {
+				logger.Log.Debug("Change events interrupted", zap.Error(err), zap.Duration("retry_in", delay))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > maxEventRetryDelay {
+				delay = maxEventRetryDelay
+			}
+		}
+	}()
+}
+
+// followEvents calls notify for every event of the stream until it ends
+func followEvents(stream *EventStream, notify func()) error {
+	defer func() {
+		if err := stream.Close(); err != nil {
+			logger.Log.Debug("Failed to close event stream", zap.Error(err))
+		}
+	}()
+	for {
+		if _, err := stream.Next(); err != nil {
+			return err
+		}
+		notify()
+	}
+}
+
+// RefreshCache fetches the item list again to bring the local index and the
+// offline cache up to date
+func (s *ClientSession) RefreshCache(ctx context.Context) {
+	if _, err := s.List(ctx); err != nil && !errors.Is(err, ErrNotAuthenticated) {
+		logger.Log.Warn("Failed to refresh local cache", zap.Error(err))
+	}
+}
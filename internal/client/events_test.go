@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/gorilla/mux"
+)
+
+func TestEventStream_Next(t *testing.T) {
+	body := ": connected\n\n" +
+		"event: change\ndata: {\"type\":\"created\",\"data_id\":\"00000000-0000-0000-0000-000000000001\",\"revision\":1}\n\n" +
+		": ping\n\n" +
+		"event: other\ndata: {\"type\":\"ignored\"}\n\n" +
+		"data: {\"type\":\"deleted\",\r\ndata: \"revision\":2}\r\n\r\n"
+	stream := newEventStream(io.NopCloser(strings.NewReader(body)), func() {})
+	defer stream.Close()
+
+	event, err := stream.Next()
+	if err != nil || event.Type != models.ChangeCreated || event.Revision != 1 || event.DataID.String() != "00000000-0000-0000-0000-000000000001" {
+		t.Fatalf("Next() = %+v, %v", event, err)
+	}
+	event, err = stream.Next()
+	if err != nil || event.Type != models.ChangeDeleted || event.Revision != 2 {
+		t.Fatalf("Expected a multi-line event after skipping others, got %+v, %v", event, err)
+	}
+	if _, err := stream.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF at the end of the stream, got %v", err)
+	}
+}
+
+func TestClientSession_WatchChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	router, err := newDemoRouter()
+	if err != nil {
+		t.Fatalf("newDemoRouter() error = %v", err)
+	}
+	cli := NewClient(demoServerURL)
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+	resp, err := cli.Register(ctx, "watcher", "login-password", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	cli.SetToken(resp.Token)
+	session := NewClientSession(cli)
+	if err := session.Unlock("master-password", resp.Salt); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	// another device of the same user
+	device := NewClient(demoServerURL)
+	device.httpClient.Transport = &handlerTransport{handler: router}
+	device.SetToken(resp.Token)
+
+	changed := make(chan struct{}, 10)
+	var mutex sync.Mutex
+	session.WatchChanges(ctx, &mutex, func(context.Context) {
+		changed <- struct{}{}
+	})
+
+	// the watcher connects in the background, so create items until one is noticed
+	deadline := time.After(5 * time.Second)
+	for noticed := false; !noticed; {
+		if _, err := device.CreateData(ctx, models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x")}); err != nil {
+			t.Fatalf("CreateData() error = %v", err)
+		}
+		select {
+		case <-changed:
+			noticed = true
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("Expected the change on the other device to be noticed")
+		}
+	}
+}
+
+func TestClient_OpenEventsUnsupported(t *testing.T) {
+	cli := NewClient(demoServerURL)
+	cli.httpClient.Transport = &handlerTransport{handler: mux.NewRouter()}
+	if _, err := cli.OpenEvents(context.Background()); !errors.Is(err, ErrEventsUnsupported) {
+		t.Errorf("Expected ErrEventsUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// DefaultExpiryWindow is how far ahead the expiring command looks by default
+const DefaultExpiryWindow = "30d"
+
+// cardExpiresAt returns when a card with an expiry date such as 12/30 or
+// 12/2030 expires: the end of that month
+func cardExpiresAt(expiry string) (time.Time, bool) {
+	month, year, ok := strings.Cut(strings.TrimSpace(expiry), "/")
+	if !ok {
+		return time.Time{}, false
+	}
+	m, err := strconv.Atoi(strings.TrimSpace(month))
+	if err != nil || m < 1 || m > 12 {
+		return time.Time{}, false
+	}
+	y, err := strconv.Atoi(strings.TrimSpace(year))
+	if err != nil || y < 0 {
+		return time.Time{}, false
+	}
+	if y < 100 {
+		y += 2000
+	}
+	return time.Date(y, time.Month(m)+1, 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// withCardExpiry sets the expiry of a bank card item from the expiry date in
+// its plaintext, so the server can flag it without reading the card. Other
+// items and requests that set an expiry are left alone.
+func (s *ClientSession) withCardExpiry(dataReq *models.DataRequest) {
+	if dataReq.Type != models.DataTypeBankCard || dataReq.ExpiresAt != nil {
+		return
+	}
+	plaintext, err := s.cryptoManager.Decrypt(dataReq.Data)
+	if err != nil {
+		return
+	}
+	var card models.BankCardData
+	if err := json.Unmarshal(plaintext, &card); err != nil {
+		return
+	}
+	if expiresAt, ok := cardExpiresAt(card.ExpiryDate); ok {
+		dataReq.ExpiresAt = &expiresAt
+	}
+}
+
+// ParseExpiry parses the expiry of an item: a date such as 2030-12-31, a
+// month such as 12/30 meaning its end, or never, which gives the zero time
+func ParseExpiry(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "never" {
+		return time.Time{}, nil
+	}
+	if expiresAt, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return expiresAt, nil
+	}
+	if expiresAt, ok := cardExpiresAt(value); ok {
+		return expiresAt, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q (use e.g. 2030-12-31, 12/30 or never)", value)
+}
+
+// SetExpiry sets when an item by ID or unique ID prefix expires; the zero
+// time clears it
+func (s *ClientSession) SetExpiry(ctx context.Context, id string, expiresAt time.Time) (*models.Data, error) {
+	if err := s.checkExpirySupport(ctx); err != nil {
+		return nil, err
+	}
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.cli.PatchData(ctx, id, models.DataPatchRequest{ExpiresAt: &expiresAt})
+}
+
+// Expiring returns the items expiring within the given period, including the
+// expired ones, soonest first
+func (s *ClientSession) Expiring(ctx context.Context, within time.Duration) ([]models.Data, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	filter := models.DataFilter{ExpiringBefore: time.Now().Add(within)}
+	items, err := s.ListFiltered(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// servers without expiry ignore the filter, so it is applied here as well
+	var expiring []models.Data
+	for i := range items {
+		if filter.Matches(&items[i]) {
+			expiring = append(expiring, items[i])
+		}
+	}
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].ExpiresAt.Before(*expiring[j].ExpiresAt)
+	})
+	return expiring, nil
+}
+
+// ExpiringCommand prints the items expiring within a period such as 30d, as
+// a table or as JSON
+func (s *ClientSession) ExpiringCommand(ctx context.Context, w io.Writer, window string, output string) error {
+	within, err := ParseAge(window)
+	if err != nil {
+		return err
+	}
+	items, err := s.Expiring(ctx, within)
+	if err != nil {
+		return err
+	}
+	if output == OutputJSON {
+		outputs := make([]ItemOutput, 0, len(items))
+		for i := range items {
+			outputs = append(outputs, NewItemOutput(&items[i]))
+		}
+		return WriteJSON(w, outputs)
+	}
+	if len(items) == 0 {
+		fmt.Fprintf(w, "No items expire within %s\n", window)
+		return nil
+	}
+
+	fmt.Fprintf(w, "%d item(s) expired or expiring within %s:\n", len(items), window)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ID\tTYPE\tNAME\tEXPIRES")
+	for i := range items {
+		item := &items[i]
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", item.ID, item.Type, CleanQuotes(item.Name), describeExpiry(*item.ExpiresAt, time.Now()))
+	}
+	return tw.Flush()
+}
+
+// describeExpiry renders when an item expires relative to now
+func describeExpiry(expiresAt, now time.Time) string {
+	date := expiresAt.Local().Format("2006-01-02")
+	days := int(math.Ceil(expiresAt.Sub(now).Hours() / 24))
+	switch {
+	case !expiresAt.After(now):
+		return date + " (expired)"
+	case days == 1:
+		return date + " (in 1 day)"
+	default:
+		return fmt.Sprintf("%s (in %d days)", date, days)
+	}
+}
+
+// ExpireCommand sets or clears when an item expires
+func (s *ClientSession) ExpireCommand(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: expire <id> <date>|never, e.g. 2030-12-31 or 12/30")
+	}
+	expiresAt, err := ParseExpiry(args[1])
+	if err != nil {
+		return err
+	}
+	data, err := s.SetExpiry(ctx, args[0], expiresAt)
+	if err != nil {
+		return err
+	}
+	if data.ExpiresAt == nil {
+		fmt.Printf("%q no longer expires\n", CleanQuotes(data.Name))
+		return nil
+	}
+	fmt.Printf("%q expires %s\n", CleanQuotes(data.Name), describeExpiry(*data.ExpiresAt, time.Now()))
+	return nil
+}
+
+// checkExpirySupport fails for servers that do not store expiry, which would
+// silently drop it
+func (s *ClientSession) checkExpirySupport(ctx context.Context) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil || !hasFeature(status, "expiry") {
+		return fmt.Errorf("the server does not store expiry dates")
+	}
+	return nil
+}
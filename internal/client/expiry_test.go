@@ -0,0 +1,96 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestParseExpiry(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2030-12-31", want: time.Date(2030, 12, 31, 0, 0, 0, 0, time.Local)},
+		{value: "12/30", want: time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "03/2027", want: time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "never", want: time.Time{}},
+		{value: "13/30", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseExpiry(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExpiry(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseExpiry(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientSession_Expiring(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	// cards take their expiry from the card's expiry date
+	nextMonth := time.Now().AddDate(0, 1, 0)
+	payload, _ := json.Marshal(models.BankCardData{CardNumber: "4111111111111111", ExpiryDate: nextMonth.Format("01/06")})
+	encrypted, err := session.cryptoManager.Encrypt(payload)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	card, err := session.Create(ctx, models.DataRequest{Type: models.DataTypeBankCard, Name: "Expiring card", Data: encrypted})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if card.ExpiresAt == nil || card.ExpiresAt.Month() != nextMonth.AddDate(0, 1, 0).Month() {
+		t.Fatalf("Expected the card to expire at the end of next month, got %v", card.ExpiresAt)
+	}
+
+	login := demoItemID(t, session, "Demo Email")
+	if _, err := session.SetExpiry(ctx, login, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("SetExpiry() error = %v", err)
+	}
+
+	items, err := session.Expiring(ctx, 90*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Expiring() error = %v", err)
+	}
+	if len(items) != 2 || items[0].ID.String() != login || items[1].ID != card.ID {
+		t.Fatalf("Expiring() = %d items, want the expired login then the card", len(items))
+	}
+	if items[0].Expiry != models.ExpiryExpired {
+		t.Errorf("Expected the login flagged %q, got %q", models.ExpiryExpired, items[0].Expiry)
+	}
+
+	var out bytes.Buffer
+	if err := session.ExpiringCommand(ctx, &out, "90d", OutputText); err != nil {
+		t.Fatalf("ExpiringCommand() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "(expired)") || !strings.Contains(out.String(), "Expiring card") {
+		t.Errorf("Unexpected expiring output:\n%s", out.String())
+	}
+
+	if _, err := session.SetExpiry(ctx, login, time.Time{}); err != nil {
+		t.Fatalf("SetExpiry() error = %v", err)
+	}
+	items, err = session.Expiring(ctx, 90*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Expiring() error = %v", err)
+	}
+	if len(items) != 1 || items[0].ID != card.ID {
+		t.Errorf("Expected only the card after clearing the login's expiry, got %d items", len(items))
+	}
+}
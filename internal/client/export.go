@@ -0,0 +1,286 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// ExportFormat identifies encrypted vault exports
+	ExportFormat = "gophkeeper-export"
+	// ExportVersion is the version of the export layout written
+	ExportVersion = 1
+
+	// minExportPasswordLength keeps exports, which leave the vault, from being
+	// protected by trivially guessable passwords
+	minExportPasswordLength = 8
+)
+
+// ExportArchive is the file an export is written to: the export, gzipped and
+// encrypted with a key derived from the export password, in a small envelope
+// that tells what the file is
+type ExportArchive struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	Cipher  string `json:"cipher"`
+	KDF     string `json:"kdf"`
+	// Sealed holds the salt and nonce along with the ciphertext
+	Sealed []byte `json:"sealed"`
+}
+
+// VaultExport is the decrypted content of an export
+type VaultExport struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	Items      []ExportedItem `json:"items"`
+}
+
+// ExportedItem is a decrypted vault item. Structured payloads are exported as
+// fields, file contents as content and any other payload as data.
+type ExportedItem struct {
+	ID          uuid.UUID              `json:"id"`
+	Type        models.DataType        `json:"type"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Collection  string                 `json:"collection,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Metadata    string                 `json:"metadata,omitempty"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`
+	Content     []byte                 `json:"content,omitempty"`
+	Data        string                 `json:"data,omitempty"`
+	Revision    int                    `json:"revision,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// Export decrypts all items of the vault and writes them to w as an archive
+// encrypted with password. It returns the number of items exported.
+func (s *ClientSession) Export(ctx context.Context, w io.Writer, password string) (int, error) {
+	if !s.IsAuthenticated() {
+		return 0, ErrNotAuthenticated
+	}
+	if len(password) < minExportPasswordLength {
+		return 0, fmt.Errorf("export password must be at least %d characters", minExportPasswordLength)
+	}
+
+	items, err := s.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get data: %w", err)
+	}
+
+	// collections are only looked up when items are filed in them
+	var tree *collectionTree
+	for _, item := range items {
+		if item.CollectionID != nil {
+			if tree, err = s.collectionTree(ctx); err != nil {
+				return 0, err
+			}
+			break
+		}
+	}
+
+	export := VaultExport{ExportedAt: time.Now().UTC(), Items: make([]ExportedItem, 0, len(items))}
+	for i := range items {
+		exported, err := s.exportItem(ctx, &items[i])
+		if err != nil {
+			return 0, fmt.Errorf("failed to export %q: %w", CleanQuotes(items[i].Name), err)
+		}
+		if items[i].CollectionID != nil {
+			exported.Collection = tree.path(*items[i].CollectionID)
+		}
+		export.Items = append(export.Items, exported)
+	}
+
+	archive, err := SealExport(export, password)
+	if err != nil {
+		return 0, err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(archive); err != nil {
+		return 0, fmt.Errorf("failed to write export: %w", err)
+	}
+	return len(export.Items), nil
+}
+
+// exportItem decrypts the payload of an item, downloading the chunks of
+// chunked files
+func (s *ClientSession) exportItem(ctx context.Context, data *models.Data) (ExportedItem, error) {
+	item := ExportedItem{
+		ID:          data.ID,
+		Type:        data.Type,
+		Name:        data.Name,
+		Description: data.Description,
+		Environment: data.Environment,
+		Tags:        data.Tags,
+		Metadata:    data.Metadata,
+		Revision:    data.Revision,
+		CreatedAt:   data.CreatedAt,
+		UpdatedAt:   data.UpdatedAt,
+	}
+
+	if data.Type == models.DataTypeBinary {
+		var binaryData models.BinaryData
+		if err := json.Unmarshal([]byte(data.Metadata), &binaryData); err == nil && binaryData.Chunks > 0 {
+			var content bytes.Buffer
+			err := s.cli.DownloadDataChunks(ctx, data.ID.String(), func(index int, chunk []byte) error {
+				if _, err := s.decryptContent(&content, chunk); err != nil {
+					return fmt.Errorf("failed to decrypt chunk %d: %w", index, err)
+				}
+				return nil
+			})
+			if err != nil {
+				return item, err
+			}
+			item.Content = content.Bytes()
+			return item, nil
+		}
+	}
+
+	decrypted, err := s.cryptoManager.Decrypt(data.Data)
+	if err != nil {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": data.ID.String()})
+		return item, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	if data.Type == models.DataTypeBinary {
+		if content, err := base64.StdEncoding.DecodeString(string(decrypted)); err == nil {
+			item.Content = content
+			return item, nil
+		}
+	}
+	if err := json.Unmarshal(decrypted, &item.Fields); err != nil {
+		item.Data = string(decrypted)
+	}
+	return item, nil
+}
+
+// SealExport gzips and encrypts an export with a key derived from password
+func SealExport(export VaultExport, password string) (*ExportArchive, error) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if err := json.NewEncoder(gz).Encode(export); err != nil {
+		return nil, fmt.Errorf("failed to encode export: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress export: %w", err)
+	}
+
+	cryptoManager, err := crypto.NewCryptoManager(password)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := cryptoManager.Encrypt(compressed.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt export: %w", err)
+	}
+	return &ExportArchive{
+		Format:  ExportFormat,
+		Version: ExportVersion,
+		Cipher:  "aes-256-gcm",
+		KDF:     "pbkdf2-sha256",
+		Sealed:  sealed,
+	}, nil
+}
+
+// OpenExport reads an export archive from r and decrypts it with password
+func OpenExport(r io.Reader, password string) (*VaultExport, error) {
+	var archive ExportArchive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil || archive.Format != ExportFormat {
+		return nil, fmt.Errorf("not a GophKeeper export")
+	}
+	if archive.Version > ExportVersion {
+		return nil, fmt.Errorf("export version %d is newer than this client supports (%d)", archive.Version, ExportVersion)
+	}
+
+	sealed, err := crypto.ParseEncryptedData(archive.Sealed)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt export: %w", err)
+	}
+	cryptoManager, err := crypto.NewCryptoManagerWithSalt(password, sealed.Salt)
+	if err != nil {
+		return nil, err
+	}
+	compressed, err := cryptoManager.Decrypt(archive.Sealed)
+	if err != nil {
+		return nil, fmt.Errorf("wrong export password or corrupt export")
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("corrupt export: %w", err)
+	}
+	var export VaultExport
+	if err := json.NewDecoder(gz).Decode(&export); err != nil {
+		return nil, fmt.Errorf("corrupt export: %w", err)
+	}
+	return &export, nil
+}
+
+// defaultExportPath names an export after the day it was made
+func defaultExportPath(now time.Time) string {
+	return "gophkeeper-export-" + now.Format("2006-01-02") + ".gkx"
+}
+
+// ExportCommand prompts for an export password and writes the whole vault,
+// decrypted and then encrypted with that password, to path
+func (s *ClientSession) ExportCommand(ctx context.Context, path string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	if path == "" {
+		path = defaultExportPath(time.Now())
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	password, ok := readSecret(scanner, "Export password: ")
+	if !ok {
+		return fmt.Errorf("failed to read export password")
+	}
+	confirmation, ok := readSecret(scanner, "Repeat export password: ")
+	if !ok {
+		return fmt.Errorf("failed to read export password")
+	}
+	if password != confirmation {
+		return fmt.Errorf("export passwords do not match")
+	}
+
+	// written next to path and renamed, so a failed export leaves no partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	count, err := s.Export(ctx, tmp, password)
+	if err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	s.recordEvent(EventExport, map[string]string{"via": "export", "path": path, "items": fmt.Sprint(count)})
+	fmt.Printf("Exported %d items to %s\n", count, path)
+	fmt.Println("Keep the export password safe: the export cannot be opened without it")
+	return nil
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestClientSession_Export(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	if _, err := session.MakeCollection(ctx, "Finance"); err != nil {
+		t.Fatalf("MakeCollection() error = %v", err)
+	}
+	if err := session.MoveCommand(ctx, demoItemID(t, session, "Demo Visa"), "Finance"); err != nil {
+		t.Fatalf("MoveCommand() error = %v", err)
+	}
+
+	if _, err := session.Export(ctx, &bytes.Buffer{}, "short"); err == nil {
+		t.Error("Expected a short export password to be rejected")
+	}
+
+	var out bytes.Buffer
+	count, err := session.Export(ctx, &out, "export-password")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Expected 4 items exported, got %d", count)
+	}
+	if strings.Contains(out.String(), "4111111111111111") || strings.Contains(out.String(), "Demo Visa") {
+		t.Error("Expected the export to be encrypted")
+	}
+
+	if _, err := OpenExport(bytes.NewReader(out.Bytes()), "wrong-password"); err == nil {
+		t.Error("Expected a wrong export password to fail")
+	}
+	export, err := OpenExport(bytes.NewReader(out.Bytes()), "export-password")
+	if err != nil {
+		t.Fatalf("OpenExport() error = %v", err)
+	}
+
+	items := make(map[string]ExportedItem)
+	for _, item := range export.Items {
+		items[item.Name] = item
+	}
+	visa := items["Demo Visa"]
+	if visa.Type != models.DataTypeBankCard || visa.Fields["card_number"] != "4111111111111111" || visa.Collection != "/Finance" {
+		t.Errorf("Expected the decrypted card in /Finance, got %+v", visa)
+	}
+	if visa.CreatedAt.IsZero() || visa.UpdatedAt.IsZero() {
+		t.Errorf("Expected timestamps to be exported, got %+v", visa)
+	}
+	if file := items["hello.txt"]; string(file.Content) != "Hello from the GophKeeper demo vault!\n" || file.Fields != nil {
+		t.Errorf("Expected the file content, got %+v", file)
+	}
+
+	if _, err := OpenExport(strings.NewReader(`{"items":[]}`), "export-password"); err == nil {
+		t.Error("Expected a file that is not an export to be rejected")
+	}
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// PublishedField holds what a machine consumer needs to fetch one field value
+type PublishedField struct {
+	DataID    string
+	Field     string
+	Token     string
+	DataKey   string
+	ExpiresAt string
+}
+
+// SetDataField publishes a field ciphertext
+func (c *Client) SetDataField(ctx context.Context, id, field string, ciphertext []byte) error {
+	path := "/api/v1/data/" + url.PathEscape(id) + "/field/" + url.PathEscape(field)
+	return c.doJSON(ctx, http.MethodPut, path, models.DataFieldRequest{Ciphertext: ciphertext}, nil, http.StatusNoContent)
+}
+
+// GetDataField gets a published field ciphertext
+func (c *Client) GetDataField(ctx context.Context, id, field string) (*models.DataFieldResponse, error) {
+	path := "/api/v1/data/" + url.PathEscape(id) + "/field/" + url.PathEscape(field)
+	var resp models.DataFieldResponse
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateScopedToken requests a token that can only read one published field
+func (c *Client) CreateScopedToken(ctx context.Context, req models.ScopedTokenRequest) (*models.ScopedTokenResponse, error) {
+	var resp models.ScopedTokenResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/tokens", req, &resp, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PublishField encrypts one field of an item under a fresh data key, uploads the
+// ciphertext and issues a token scoped to it. Neither the server nor the consumer
+// learns the master password; the consumer only ever sees this single value.
+func (s *ClientSession) PublishField(ctx context.Context, id, field string, ttl time.Duration) (*PublishedField, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+
+	item, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+
+	value, err := s.itemField(item, field)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, fmt.Errorf("field %q of %q is empty", field, item.Name)
+	}
+
+	dataKey, err := crypto.GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := crypto.SealWithKey(dataKey, []byte(value))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt field: %w", err)
+	}
+
+	if err := s.cli.SetDataField(ctx, item.ID.String(), field, sealed); err != nil {
+		return nil, fmt.Errorf("failed to publish field: %w", err)
+	}
+
+	tokenResp, err := s.cli.CreateScopedToken(ctx, models.ScopedTokenRequest{
+		DataID:     item.ID,
+		Field:      field,
+		TTLSeconds: int64(ttl / time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scoped token: %w", err)
+	}
+
+	s.recordEvent(EventExport, map[string]string{"data_id": item.ID.String(), "field": field, "via": "publish-field"})
+
+	return &PublishedField{
+		DataID:    item.ID.String(),
+		Field:     field,
+		Token:     tokenResp.Token,
+		DataKey:   base64.StdEncoding.EncodeToString(dataKey),
+		ExpiresAt: tokenResp.ExpiresAt,
+	}, nil
+}
+
+// PublishFieldCommand publishes a field and prints the consumer credentials
+func (s *ClientSession) PublishFieldCommand(ctx context.Context, id, field string, ttl time.Duration) error {
+	published, err := s.PublishField(ctx, id, field, ttl)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Published field %q of %s (token expires %s)\n", published.Field, published.DataID, published.ExpiresAt)
+	fmt.Println("Give the consumer these values; they are shown only once:")
+	fmt.Printf("  GOPHKEEPER_FIELD_TOKEN=%s\n", published.Token)
+	fmt.Printf("  GOPHKEEPER_DATA_KEY=%s\n", published.DataKey)
+	fmt.Printf("Fetch with: gophkeeper-client fetch-field %s %s\n", published.DataID, published.Field)
+	fmt.Println("Re-run publish-field after changing the item to refresh the published value.")
+	return nil
+}
+
+// FetchField fetches a published field with a scoped token and decrypts it with the data key
+func FetchField(ctx context.Context, cli *Client, id, field, dataKey string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(dataKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid data key: %w", err)
+	}
+
+	resp, err := cli.GetDataField(ctx, id, field)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := crypto.OpenWithKey(key, resp.Ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
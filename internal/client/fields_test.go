@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestClientSession_PublishField(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	payload, _ := json.Marshal(models.LoginPasswordData{Login: "app", Password: "s3cret"})
+	encrypted, err := session.GetCryptoManager().Encrypt(payload)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	item, err := session.Create(ctx, models.DataRequest{Type: models.DataTypeLoginPassword, Name: "DB", Data: encrypted})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	published, err := session.PublishField(ctx, item.ID.String(), "password", time.Hour)
+	if err != nil {
+		t.Fatalf("PublishField() error = %v", err)
+	}
+
+	consumer := NewClient(demoServerURL)
+	consumer.httpClient = session.cli.httpClient
+	consumer.SetToken(published.Token)
+
+	value, err := FetchField(ctx, consumer, published.DataID, "password", published.DataKey)
+	if err != nil {
+		t.Fatalf("FetchField() error = %v", err)
+	}
+	if value != "s3cret" {
+		t.Errorf("FetchField() = %q, want %q", value, "s3cret")
+	}
+
+	if _, err := FetchField(ctx, consumer, published.DataID, "login", published.DataKey); err == nil {
+		t.Error("Scoped token should not fetch other fields")
+	}
+	if _, err := consumer.GetData(ctx); err == nil {
+		t.Error("Scoped token should not list the vault")
+	}
+
+	wrongKey := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	if _, err := FetchField(ctx, consumer, published.DataID, "password", wrongKey); err == nil {
+		t.Error("Expected error when decrypting with the wrong data key")
+	}
+
+	if _, err := session.PublishField(ctx, item.ID.String(), "missing", time.Hour); err == nil {
+		t.Error("Expected error for a missing field")
+	}
+}
@@ -0,0 +1,293 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+	"golang.org/x/net/publicsuffix"
+)
+
+// MatchRule decides which stored login URLs match a target URL
+type MatchRule string
+
+// Match rules, from the loosest to the strictest
+const (
+	// MatchDomain matches any host under the same registrable domain, so a
+	// login for accounts.example.com is offered on mail.example.com
+	MatchDomain MatchRule = "domain"
+	// MatchHost matches the stored host and its subdomains, so a login for
+	// example.com is offered on mail.example.com but not the other way around
+	MatchHost MatchRule = "host"
+	// MatchExact matches the same scheme, host and port, and a path under the
+	// stored one
+	MatchExact MatchRule = "exact"
+)
+
+// ParseMatchRule parses the name of a match rule; an empty name is MatchDomain
+func ParseMatchRule(name string) (MatchRule, error) {
+	switch rule := MatchRule(strings.ToLower(name)); rule {
+	case "":
+		return MatchDomain, nil
+	case MatchDomain, MatchHost, MatchExact:
+		return rule, nil
+	}
+	return "", fmt.Errorf("match rule must be %s, %s or %s", MatchDomain, MatchHost, MatchExact)
+}
+
+// LoginMatch is a login item whose URL matches a lookup
+type LoginMatch struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Login string `json:"login"`
+	URL   string `json:"url"`
+	// Rule is the strictest rule the URL matches under
+	Rule MatchRule `json:"match"`
+}
+
+// parseLoginURL parses a URL as stored in a login or typed by a user, who
+// may leave out the scheme
+func parseLoginURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("empty URL")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if loginHost(u) == "" {
+		return nil, fmt.Errorf("URL %q has no host", raw)
+	}
+	return u, nil
+}
+
+// loginHost returns the lowercased host name of u without a trailing dot
+func loginHost(u *url.URL) string {
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// RegistrableDomain returns the domain a host name is registered under, such
+// as example.co.uk for mail.example.co.uk. IP addresses and names without a
+// public suffix, such as localhost, are their own domain.
+func RegistrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// URLDomains returns the domain index entries of a login URL: its registrable
+// domain, or none if it has no host or is an IPv6 address
+func URLDomains(raw string) []string {
+	u, err := parseLoginURL(raw)
+	if err != nil || strings.Contains(loginHost(u), ":") {
+		return []string{}
+	}
+	return []string{RegistrableDomain(loginHost(u))}
+}
+
+// MatchLoginURL reports the strictest rule under which the stored login URL
+// matches target, and whether it matches under rule at all
+func MatchLoginURL(stored, target *url.URL, rule MatchRule) (MatchRule, bool) {
+	storedHost, targetHost := loginHost(stored), loginHost(target)
+	if RegistrableDomain(storedHost) != RegistrableDomain(targetHost) {
+		return "", false
+	}
+	best := MatchDomain
+	if targetHost == storedHost || strings.HasSuffix(targetHost, "."+storedHost) {
+		best = MatchHost
+		if targetHost == storedHost && strings.EqualFold(stored.Scheme, target.Scheme) &&
+			urlPort(stored) == urlPort(target) && pathUnder(target.Path, stored.Path) {
+			best = MatchExact
+		}
+	}
+	return best, matchRank(best) >= matchRank(rule)
+}
+
+// urlPort returns the port of u, or the default one of its scheme
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// pathUnder reports whether path is base or below it, segment by segment
+func pathUnder(path, base string) bool {
+	base = strings.TrimSuffix(base, "/")
+	return base == "" || path == base || strings.HasPrefix(path, base+"/")
+}
+
+func matchRank(rule MatchRule) int {
+	switch rule {
+	case MatchExact:
+		return 2
+	case MatchHost:
+		return 1
+	}
+	return 0
+}
+
+// withDomains sets the domain index of a login item from the URL in its
+// plaintext, so it can be looked up by site without decrypting every login.
+// Other items are left alone.
+func (s *ClientSession) withDomains(dataReq *models.DataRequest) error {
+	if dataReq.Type != models.DataTypeLoginPassword {
+		return nil
+	}
+	plaintext, err := s.cryptoManager.Decrypt(dataReq.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	var login models.LoginPasswordData
+	if err := json.Unmarshal(plaintext, &login); err != nil {
+		return nil
+	}
+	domains := URLDomains(login.URL)
+	dataReq.Domains = &domains
+	return nil
+}
+
+// FindLogins returns the login items whose URL matches target under rule,
+// the closest matches first. Servers with a domain index are asked for the
+// logins of the target's domain only. When that finds nothing, the logins
+// saved before the index are decrypted and matched instead, and indexed on
+// the way so the next lookup finds them.
+func (s *ClientSession) FindLogins(ctx context.Context, target string, rule MatchRule) ([]LoginMatch, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	targetURL, err := parseLoginURL(target)
+	if err != nil {
+		return nil, err
+	}
+	domain := RegistrableDomain(loginHost(targetURL))
+
+	status, err := s.cli.GetStatus(ctx)
+	indexed := err == nil && hasFeature(status, "domain_index")
+	if indexed {
+		items, err := s.ListFiltered(ctx, models.DataFilter{Type: models.DataTypeLoginPassword, Domain: domain})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get logins: %w", err)
+		}
+		if matches := s.matchLogins(items, targetURL, rule); len(matches) > 0 {
+			return matches, nil
+		}
+	}
+
+	items, err := s.ListFiltered(ctx, models.DataFilter{Type: models.DataTypeLoginPassword})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logins: %w", err)
+	}
+	if indexed {
+		items = s.indexLogins(ctx, items)
+	}
+	return s.matchLogins(items, targetURL, rule), nil
+}
+
+// indexLogins sets the domain index of the logins among items that have
+// none and returns those with a URL. Failures to index are only logged; the
+// lookup works without the index.
+func (s *ClientSession) indexLogins(ctx context.Context, items []models.Data) []models.Data {
+	var unindexed []models.Data
+	for _, item := range items {
+		if len(item.Domains) > 0 {
+			continue
+		}
+		dataReq := models.DataRequest{Type: item.Type, Data: item.Data}
+		if err := s.withDomains(&dataReq); err != nil || dataReq.Domains == nil || len(*dataReq.Domains) == 0 {
+			continue
+		}
+		unindexed = append(unindexed, item)
+		if _, err := s.cli.PatchData(ctx, item.ID.String(), models.DataPatchRequest{Domains: dataReq.Domains}); err != nil {
+			logger.Log.Warn("Failed to index login", zap.String("data_id", item.ID.String()), zap.Error(err))
+		}
+	}
+	return unindexed
+}
+
+// matchLogins decrypts the login items and returns those matching target
+// under rule, sorted by how closely they match and then by name. Items that
+// cannot be decrypted are skipped.
+func (s *ClientSession) matchLogins(items []models.Data, target *url.URL, rule MatchRule) []LoginMatch {
+	var matches []LoginMatch
+	for _, item := range items {
+		if item.Type != models.DataTypeLoginPassword {
+			continue
+		}
+		plaintext, err := s.cryptoManager.Decrypt(item.Data)
+		if err != nil {
+			logger.Log.Warn("Failed to decrypt login", zap.String("data_id", item.ID.String()), zap.Error(err))
+			continue
+		}
+		var login models.LoginPasswordData
+		if err := json.Unmarshal(plaintext, &login); err != nil {
+			continue
+		}
+		stored, err := parseLoginURL(login.URL)
+		if err != nil {
+			continue
+		}
+		if best, ok := MatchLoginURL(stored, target, rule); ok {
+			matches = append(matches, LoginMatch{ID: item.ID.String(), Name: CleanQuotes(item.Name),
+				Login: login.Login, URL: login.URL, Rule: best})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if ri, rj := matchRank(matches[i].Rule), matchRank(matches[j].Rule); ri != rj {
+			return ri > rj
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	return matches
+}
+
+// FindLoginCommand prints the logins matching target, as a table or as JSON
+func (s *ClientSession) FindLoginCommand(ctx context.Context, w io.Writer, target string, rule MatchRule, output string) error {
+	matches, err := s.FindLogins(ctx, target, rule)
+	if err != nil {
+		return err
+	}
+	if output == OutputJSON {
+		if matches == nil {
+			matches = []LoginMatch{}
+		}
+		return WriteJSON(w, matches)
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(w, "No logins match %s\n", target)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Found %d logins for %s:\n", len(matches), target)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ID\tNAME\tLOGIN\tURL\tMATCH")
+	for _, m := range matches {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", m.ID, m.Name, m.Login, m.URL, m.Rule)
+	}
+	return tw.Flush()
+}
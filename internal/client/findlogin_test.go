@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestMatchLoginURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   string
+		target   string
+		rule     MatchRule
+		wantBest MatchRule
+		wantOK   bool
+	}{
+		{name: "sibling subdomain", stored: "https://accounts.example.com", target: "https://mail.example.com",
+			rule: MatchDomain, wantBest: MatchDomain, wantOK: true},
+		{name: "sibling subdomain by host", stored: "https://accounts.example.com", target: "https://mail.example.com",
+			rule: MatchHost, wantBest: MatchDomain},
+		{name: "subdomain of the stored host", stored: "example.com", target: "https://login.example.com/",
+			rule: MatchHost, wantBest: MatchHost, wantOK: true},
+		{name: "public suffix", stored: "https://shop.example.co.uk", target: "https://other.co.uk",
+			rule: MatchDomain},
+		{name: "lookalike", stored: "https://example.com", target: "https://example.com.evil.net",
+			rule: MatchDomain},
+		{name: "exact with default port", stored: "https://example.com/app", target: "https://EXAMPLE.com:443/app/login",
+			rule: MatchExact, wantBest: MatchExact, wantOK: true},
+		{name: "path prefix is per segment", stored: "https://example.com/app", target: "https://example.com/apple",
+			rule: MatchExact, wantBest: MatchHost},
+		{name: "other scheme", stored: "https://example.com", target: "http://example.com",
+			rule: MatchExact, wantBest: MatchHost},
+		{name: "ip address", stored: "http://192.168.1.1:8080", target: "http://192.168.1.1:8080/admin",
+			rule: MatchExact, wantBest: MatchExact, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored, err := parseLoginURL(tt.stored)
+			if err != nil {
+				t.Fatalf("parseLoginURL(%q) error = %v", tt.stored, err)
+			}
+			target, err := parseLoginURL(tt.target)
+			if err != nil {
+				t.Fatalf("parseLoginURL(%q) error = %v", tt.target, err)
+			}
+			best, ok := MatchLoginURL(stored, target, tt.rule)
+			if best != tt.wantBest || ok != tt.wantOK {
+				t.Errorf("MatchLoginURL() = %q, %v, want %q, %v", best, ok, tt.wantBest, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestURLDomains(t *testing.T) {
+	tests := map[string][]string{
+		"https://mail.example.co.uk/inbox": {"example.co.uk"},
+		"Login.GitHub.com":                 {"github.com"},
+		"http://localhost:8080":            {"localhost"},
+		"https://[::1]/":                   {},
+		"":                                 {},
+	}
+	for raw, want := range tests {
+		if got := URLDomains(raw); !reflect.DeepEqual(got, want) {
+			t.Errorf("URLDomains(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestClientSession_FindLogins(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	indexed, err := session.Get(ctx, demoItemID(t, session, "Demo Email"))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(indexed.Domains, []string{"example.com"}) {
+		t.Errorf("Expected the login indexed under its domain, got %q", indexed.Domains)
+	}
+
+	// a login saved by a client that does not index domains
+	payload, _ := json.Marshal(models.LoginPasswordData{Login: "octocat", Password: "x", URL: "https://github.com/login"})
+	encrypted, err := session.cryptoManager.Encrypt(payload)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	legacy, err := session.cli.CreateData(ctx, models.DataRequest{Type: models.DataTypeLoginPassword, Name: "GitHub", Data: encrypted})
+	if err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		rule   MatchRule
+		want   []string
+	}{
+		{name: "same domain", target: "https://example.com/", rule: MatchDomain, want: []string{"Demo Email"}},
+		{name: "parent of the saved host", target: "example.com", rule: MatchHost},
+		{name: "subdomain of the saved host", target: "https://inbox.mail.example.com", rule: MatchHost, want: []string{"Demo Email"}},
+		{name: "unindexed login", target: "https://gist.github.com", rule: MatchDomain, want: []string{"GitHub"}},
+		{name: "no logins", target: "https://example.org", rule: MatchDomain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := session.FindLogins(ctx, tt.target, tt.rule)
+			if err != nil {
+				t.Fatalf("FindLogins() error = %v", err)
+			}
+			var names []string
+			for _, m := range matches {
+				names = append(names, m.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("FindLogins() = %q, want %q", names, tt.want)
+			}
+		})
+	}
+
+	backfilled, err := session.Get(ctx, legacy.ID.String())
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(backfilled.Domains, []string{"github.com"}) {
+		t.Errorf("Expected the lookup to index the older login, got %q", backfilled.Domains)
+	}
+
+	var out bytes.Buffer
+	if err := session.FindLoginCommand(ctx, &out, "mail.example.com", MatchExact, OutputJSON); err != nil {
+		t.Fatalf("FindLoginCommand() error = %v", err)
+	}
+	var matches []LoginMatch
+	if err := json.Unmarshal(out.Bytes(), &matches); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", out.String(), err)
+	}
+	if len(matches) != 1 || matches[0].Login != "demo@example.com" || matches[0].Rule != MatchExact {
+		t.Errorf("Unexpected matches %+v", matches)
+	}
+
+	if _, err := session.FindLogins(ctx, "https://", MatchDomain); err == nil || !strings.Contains(err.Error(), "no host") {
+		t.Errorf("Expected an error for a URL without a host, got %v", err)
+	}
+}
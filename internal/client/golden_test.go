@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/fixtures"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// goldenServerOnly lists endpoints the client does not call, e.g. operator routes used with curl
+var goldenServerOnly = map[string]bool{
+	"maintenance.set": true,
+	"maintenance.get": true,
+}
+
+// TestGoldenRequests checks that the client sends the golden requests and
+// understands the golden responses of every endpoint
+func TestGoldenRequests(t *testing.T) {
+	ctx := context.Background()
+	cli := NewClient("http://golden.invalid")
+	cli.SetToken(fixtures.Token)
+
+	calls := map[string]func() error{
+		"status": func() error { _, err := cli.GetStatus(ctx); return err },
+		"register": func() error {
+			_, err := cli.Register(ctx, fixtures.Username, fixtures.Password, fixtures.MasterPassword)
+			return err
+		},
+		"login":       func() error { _, err := cli.Login(ctx, fixtures.Username, fixtures.Password); return err },
+		"salt.get":    func() error { _, err := cli.GetSalt(ctx); return err },
+		"salt.set":    func() error { _, err := cli.SetSalt(ctx, fixtures.Salt); return err },
+		"data.create": func() error { _, err := cli.CreateData(ctx, fixtures.CreateDataRequest()); return err },
+		"data.list":   func() error { _, err := cli.GetData(ctx); return err },
+		"data.get":    func() error { _, err := cli.GetDataByID(ctx, fixtures.DataID); return err },
+		"data.update": func() error {
+			_, err := cli.UpdateData(ctx, fixtures.DataID, fixtures.UpdateDataRequest())
+			return err
+		},
+		"field.set": func() error {
+			return cli.SetDataField(ctx, fixtures.DataID, fixtures.FieldName, fixtures.FieldCiphertext)
+		},
+		"token.create": func() error { _, err := cli.CreateScopedToken(ctx, fixtures.ScopedTokenRequest()); return err },
+		"field.get": func() error {
+			_, err := cli.GetDataField(ctx, fixtures.DataID, fixtures.FieldName)
+			return err
+		},
+		"comment.add": func() error {
+			_, err := cli.AddDataComment(ctx, fixtures.DataID, fixtures.CommentCiphertext)
+			return err
+		},
+		"comment.list": func() error { _, err := cli.GetDataComments(ctx, fixtures.DataID); return err },
+		"data.import": func() error {
+			return cli.ImportData(ctx, fixtures.ImportRecords(), importWindow, func(models.ImportResult) {})
+		},
+		"hint.set":            func() error { return cli.SetPasswordHint(ctx, fixtures.Hint) },
+		"hint.get":            func() error { _, err := cli.GetPasswordHint(ctx); return err },
+		"hint.delete":         func() error { return cli.DeletePasswordHint(ctx) },
+		"escrow.recovery-key": func() error { _, err := cli.GetRecoveryKey(ctx); return err },
+		"escrow.set": func() error {
+			return cli.SetEscrow(ctx, models.KeyEscrowRequest{
+				Consent:       true,
+				WrappedKey:    fixtures.WrappedKey,
+				RecoveryKeyID: crypto.RecoveryKeyID(fixtures.RecoveryPublicKey),
+			})
+		},
+		"escrow.status": func() error { _, err := cli.GetEscrowStatus(ctx); return err },
+		"escrow.recovery": func() error {
+			_, err := cli.GetEscrowRecovery(ctx, fixtures.AdminToken, fixtures.Username)
+			return err
+		},
+		"escrow.delete": func() error { return cli.DeleteEscrow(ctx) },
+		"audit.key.set": func() error { return cli.SetAuditKey(ctx, fixtures.AuditPublicKey) },
+		"audit.list":    func() error { _, err := cli.GetAuditEvents(ctx, defaultAuditEntries); return err },
+		"vault.lock":    func() error { _, err := cli.LockVault(ctx, "rotation", vaultLockTTL); return err },
+		"vault.unlock":  func() error { return cli.UnlockVault(ctx, fixtures.Token) },
+		"data.delete":   func() error { return cli.DeleteData(ctx, fixtures.DataID) },
+	}
+
+	for _, endpoint := range fixtures.Endpoints {
+		t.Run(endpoint.Name, func(t *testing.T) {
+			call, ok := calls[endpoint.Name]
+			if !ok {
+				if !goldenServerOnly[endpoint.Name] {
+					t.Fatalf("No client call for endpoint %s", endpoint.Name)
+				}
+				t.Skip("server-only endpoint")
+			}
+
+			var requestBody []byte
+			cli.httpClient.Transport = &handlerTransport{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != endpoint.Method || r.URL.Path != endpoint.Target(fixtures.DataID) {
+					t.Errorf("Expected %s %s, got %s %s", endpoint.Method, endpoint.Target(fixtures.DataID), r.Method, r.URL.Path)
+				}
+				if r.Body != nil {
+					requestBody, _ = io.ReadAll(r.Body)
+				}
+
+				var response []byte
+				if endpoint.HasResponse {
+					golden, err := fixtures.Response(endpoint.Name)
+					if err != nil {
+						t.Errorf("Failed to read golden response: %v", err)
+					}
+					response = golden
+					w.Header().Set("Content-Type", "application/json")
+				}
+				w.WriteHeader(endpoint.Status)
+				_, _ = w.Write(response)
+			})}
+
+			err := call()
+
+			if endpoint.HasRequest {
+				fixtures.CheckRequest(t, endpoint.Name, requestBody, nil)
+			} else if len(requestBody) > 0 {
+				t.Errorf("Expected no request body, got %s", requestBody)
+			}
+			if err != nil {
+				t.Errorf("Client call error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/grpcserver"
+	pb "github.com/a2sh3r/gophkeeper/internal/grpcserver/proto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/pkg/version"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Protocols of the API the vault calls are sent over, see Config.Protocol
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+)
+
+// DefaultGRPCPort is the port of the gRPC API when Config.GRPCAddress is empty
+const DefaultGRPCPort = "9090"
+
+// GRPCTarget returns the host:port of the gRPC API and whether it is served
+// over TLS, which it is when the REST API is
+func (c *Config) GRPCTarget() (string, bool, error) {
+	serverURL, err := url.Parse(c.ServerURL)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid server URL: %w", err)
+	}
+	secure := serverURL.Scheme == "https"
+	if c.GRPCAddress != "" {
+		return c.GRPCAddress, secure, nil
+	}
+	if serverURL.Hostname() == "" {
+		return "", false, fmt.Errorf("no host in server URL %q", c.ServerURL)
+	}
+	return net.JoinHostPort(serverURL.Hostname(), DefaultGRPCPort), secure, nil
+}
+
+// grpcTransport is the connection to the gRPC API of a client using it
+type grpcTransport struct {
+	conn *grpc.ClientConn
+	auth pb.AuthClient
+	data pb.DataClient
+}
+
+// UseGRPC sends registration, login, item and chunk calls to the gRPC API at
+// target, a host:port, instead of the REST API; other calls keep using the
+// base URL. tlsConfig secures the connection, nil connects in plain text.
+func (c *Client) UseGRPC(target string, tlsConfig *tls.Config) error {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.Dial(target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUserAgent(fmt.Sprintf("gophkeeper/%s (%s/%s)", version.Version, runtime.GOOS, runtime.GOARCH)),
+		// responses are as large as the REST API returns them
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(math.MaxInt32)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to connect to gRPC server: %w", err)
+	}
+	if err := c.Close(); err != nil {
+		return err
+	}
+	c.grpc = &grpcTransport{conn: conn, auth: pb.NewAuthClient(conn), data: pb.NewDataClient(conn)}
+	return nil
+}
+
+// Close closes the connection to the gRPC API, if any
+func (c *Client) Close() error {
+	if c.grpc == nil {
+		return nil
+	}
+	err := c.grpc.conn.Close()
+	c.grpc = nil
+	return err
+}
+
+// grpcContext returns the context of a gRPC call, bounded by the request
+// timeout of the client and carrying the token and vault lock like authorize
+func (c *Client) grpcContext(ctx context.Context, timeout bool) (context.Context, context.CancelFunc) {
+	md := metadata.MD{}
+	if c.token != "" {
+		md.Set("authorization", "Bearer "+c.token)
+	}
+	if c.vaultLock != "" {
+		md.Set(vaultLockHeader, c.vaultLock)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	if timeout && c.httpClient.Timeout > 0 {
+		return context.WithTimeout(ctx, c.httpClient.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// grpcError converts the error of a gRPC call like statusError converts an
+// unexpected response, so callers get the same errors from either API
+func grpcError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return requestFailed(ctx.Err())
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return requestFailed(err)
+	}
+	for _, detail := range st.Details() {
+		if apiErr, ok := detail.(*pb.Error); ok {
+			return apiStatusError(apiErr)
+		}
+	}
+
+	// without details the call did not reach the API
+	httpStatus := http.StatusInternalServerError
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return requestFailed(err)
+	case codes.Unauthenticated:
+		httpStatus = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		httpStatus = http.StatusForbidden
+	case codes.NotFound:
+		httpStatus = http.StatusNotFound
+	case codes.ResourceExhausted:
+		httpStatus = http.StatusRequestEntityTooLarge
+	case codes.Unimplemented:
+		httpStatus = http.StatusNotImplemented
+	}
+	return &APIError{Status: httpStatus, Code: models.ErrorCodeForStatus(httpStatus), Message: st.Message()}
+}
+
+// apiStatusError converts the REST error response attached to a gRPC error
+func apiStatusError(apiErr *pb.Error) error {
+	resp := &http.Response{StatusCode: int(apiErr.GetHttpStatus()), Header: make(http.Header)}
+	if apiErr.GetRequestId() != "" {
+		resp.Header.Set(requestIDHeader, apiErr.GetRequestId())
+	}
+	if retryAfter := apiErr.GetRetryAfter(); retryAfter != nil {
+		resp.Header.Set("Retry-After", strconv.Itoa(int(retryAfter.AsDuration()/time.Second)))
+	}
+	errResp := models.ErrorResponse{Error: apiErr.GetError(), Message: apiErr.GetMessage(), Code: apiErr.GetCode()}
+	for _, field := range apiErr.GetFields() {
+		errResp.Fields = append(errResp.Fields, models.FieldError{Field: field.GetField(), Rule: field.GetRule(), Message: field.GetMessage()})
+	}
+	body, err := json.Marshal(errResp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal error: %w", err)
+	}
+	return statusError(resp, body)
+}
+
+// grpcAuth sends a register or login call, describing this machine like setDevice
+func (c *Client) grpcAuth(ctx context.Context, call func(ctx context.Context) (*pb.AuthResponse, error)) (*models.AuthResponse, error) {
+	ctx, cancel := c.grpcContext(ctx, true)
+	defer cancel()
+	if hostname, err := os.Hostname(); err == nil {
+		ctx = metadata.AppendToOutgoingContext(ctx, deviceHeader, hostname)
+	}
+
+	resp, err := call(ctx)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	authResp, err := grpcserver.AuthResponseToModel(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert response: %w", err)
+	}
+	return &authResp, nil
+}
+
+// grpcRegister registers a user over gRPC
+func (c *Client) grpcRegister(ctx context.Context, req models.UserRequest) (*models.AuthResponse, error) {
+	return c.grpcAuth(ctx, func(ctx context.Context) (*pb.AuthResponse, error) {
+		return c.grpc.auth.Register(ctx, &pb.RegisterRequest{
+			Username:       req.Username,
+			Password:       req.Password,
+			MasterPassword: req.MasterPassword,
+			KdfIterations:  int32(req.KDFIterations),
+		})
+	})
+}
+
+// grpcLogin authenticates over gRPC
+func (c *Client) grpcLogin(ctx context.Context, req models.LoginRequest) (*models.AuthResponse, error) {
+	return c.grpcAuth(ctx, func(ctx context.Context) (*pb.AuthResponse, error) {
+		return c.grpc.auth.Login(ctx, &pb.LoginRequest{Username: req.Username, Password: req.Password})
+	})
+}
+
+// grpcItem sends a call returning an item
+func (c *Client) grpcItem(ctx context.Context, call func(ctx context.Context) (*pb.DataResponse, error)) (*models.Data, error) {
+	ctx, cancel := c.grpcContext(ctx, true)
+	defer cancel()
+
+	resp, err := call(ctx)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	data, err := grpcserver.ItemToModel(resp.GetData())
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert response: %w", err)
+	}
+	return &data, nil
+}
+
+// grpcListData lists the items matching filter over gRPC
+func (c *Client) grpcListData(ctx context.Context, filter models.DataFilter) ([]models.Data, error) {
+	req := &pb.ListDataRequest{
+		Environment: filter.Environment,
+		Type:        grpcserver.DataTypeFromModel(filter.Type),
+		Name:        filter.Name,
+		Tag:         filter.Tag,
+		Domain:      filter.Domain,
+		Query:       filter.Query,
+		Limit:       int32(filter.Limit),
+		Offset:      int32(filter.Offset),
+	}
+	if !filter.ExpiringBefore.IsZero() {
+		// like over REST, a time already past still asks for the expired items
+		within := time.Until(filter.ExpiringBefore).Round(time.Second)
+		if within < time.Second {
+			within = time.Second
+		}
+		req.ExpiringWithin = durationpb.New(within)
+	}
+
+	ctx, cancel := c.grpcContext(ctx, true)
+	defer cancel()
+	resp, err := c.grpc.data.ListData(ctx, req)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	list := make([]models.Data, 0, len(resp.GetData()))
+	for _, item := range resp.GetData() {
+		data, err := grpcserver.ItemToModel(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert response: %w", err)
+		}
+		list = append(list, data)
+	}
+	return list, nil
+}
+
+// grpcDeleteData deletes an item over gRPC
+func (c *Client) grpcDeleteData(ctx context.Context, id string) error {
+	ctx, cancel := c.grpcContext(ctx, true)
+	defer cancel()
+	if _, err := c.grpc.data.DeleteData(ctx, &pb.DeleteDataRequest{Id: id}); err != nil {
+		return grpcError(ctx, err)
+	}
+	return nil
+}
+
+// grpcUploadChunks stores the content chunks returned by next over one gRPC
+// stream, the first at index first
+func (c *Client) grpcUploadChunks(ctx context.Context, id string, first int, next func() ([]byte, error)) error {
+	// a large file outlasts the request timeout; ctx bounds it instead
+	ctx, cancel := c.grpcContext(ctx, false)
+	defer cancel()
+	stream, err := c.grpc.data.UploadChunks(ctx)
+	if err != nil {
+		return grpcError(ctx, err)
+	}
+
+	sent := 0
+	for ; ; sent++ {
+		chunk, err := next()
+		if err != nil {
+			return err
+		}
+		if chunk == nil {
+			break
+		}
+		req := &pb.UploadChunkRequest{DataId: id, Index: int32(first + sent), Payload: chunk}
+		if err := stream.Send(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				// the server ended the call; CloseAndRecv returns why
+				break
+			}
+			return grpcError(ctx, err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("failed to upload chunks: %w", grpcError(ctx, err))
+	}
+	if int(resp.GetCount()) != sent {
+		return fmt.Errorf("server stored %d of %d chunks", resp.GetCount(), sent)
+	}
+	return nil
+}
+
+// grpcDownloadChunks streams the content chunks of an item to fn over gRPC
+func (c *Client) grpcDownloadChunks(ctx context.Context, id string, fn func(index int, chunk []byte) error) error {
+	// a large file outlasts the request timeout; ctx bounds it instead
+	ctx, cancel := c.grpcContext(ctx, false)
+	defer cancel()
+	stream, err := c.grpc.data.DownloadChunks(ctx, &pb.DownloadChunksRequest{DataId: id})
+	if err != nil {
+		return grpcError(ctx, err)
+	}
+
+	count := 0
+	for index := 0; ; index++ {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			if index < count {
+				return fmt.Errorf("download ended after %d of %d chunks", index, count)
+			}
+			return nil
+		}
+		if err != nil {
+			return grpcError(ctx, err)
+		}
+		if int(chunk.GetIndex()) != index {
+			return fmt.Errorf("got chunk %d instead of %d", chunk.GetIndex(), index)
+		}
+		count = int(chunk.GetCount())
+		if err := fn(index, chunk.GetPayload()); err != nil {
+			return err
+		}
+	}
+}
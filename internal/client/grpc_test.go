@@ -0,0 +1,213 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/grpcserver"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// newGRPCClient returns a client sending the calls of the gRPC API to a
+// server over an in-memory store, and the rest to the REST routes on the same
+// store, which fail the test for the calls gRPC should have carried
+func newGRPCClient(t *testing.T, maintenance *middleware.Maintenance) *Client {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := mux.NewRouter()
+	server.RegisterRoutes(router, store, store, jwtManager, server.Options{})
+	server.RegisterChunkRoutes(router, store, store, jwtManager)
+	handler := maintenance.Handler(router)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	srv := grpcserver.New(store, store, store, jwtManager, grpcserver.Options{Maintenance: maintenance})
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	cli := NewClient("http://grpc.invalid")
+	cli.httpClient.Transport = &handlerTransport{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/register", r.URL.Path == "/api/v1/login",
+			r.URL.Path == "/api/v1/data", strings.HasPrefix(r.URL.Path, "/api/v1/data/") && !strings.Contains(r.URL.Path[len("/api/v1/data/"):], "/"),
+			strings.HasSuffix(r.URL.Path, "/chunks"):
+			t.Errorf("%s %s was sent over REST", r.Method, r.URL.Path)
+		}
+		handler.ServeHTTP(w, r)
+	})}
+	if err := cli.UseGRPC(listener.Addr().String(), nil); err != nil {
+		t.Fatalf("UseGRPC() error = %v", err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return cli
+}
+
+func TestClient_GRPC(t *testing.T) {
+	ctx := context.Background()
+	cli := newGRPCClient(t, middleware.NewMaintenance(false, ""))
+
+	resp, err := cli.Register(ctx, "grpc-user", "password", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	var apiErr *APIError
+	_, err = cli.Register(ctx, "grpc-user", "password", "master-password")
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusConflict || apiErr.Code != models.ErrorCodeUserExists {
+		t.Errorf("Register() of a taken name error = %v, want %s", err, models.ErrorCodeUserExists)
+	}
+	_, err = cli.Login(ctx, "grpc-user", "wrong")
+	if !errors.As(err, &apiErr) || apiErr.Code != models.ErrorCodeInvalidCredentials {
+		t.Errorf("Login() with a wrong password error = %v, want %s", err, models.ErrorCodeInvalidCredentials)
+	}
+	if _, err := cli.GetData(ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("GetData() without a token error = %v, want %v", err, ErrUnauthorized)
+	}
+	cli.SetToken(resp.Token)
+
+	session := NewClientSession(cli)
+	if err := session.Unlock("master-password", resp.Salt); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	sealed, err := session.cryptoManager.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	tags := []string{"work"}
+	data, err := session.Create(ctx, models.DataRequest{
+		Type: models.DataTypeText, Name: "note", Data: sealed, Tags: &tags, ExpiresAt: &expires,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := cli.GetDataByID(ctx, data.ID.String())
+	if err != nil {
+		t.Fatalf("GetDataByID() error = %v", err)
+	}
+	if got.Revision != 1 || len(got.Tags) != 1 || got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) {
+		t.Errorf("GetDataByID() = %+v", got)
+	}
+	plaintext, err := session.cryptoManager.Decrypt(got.Data)
+	if err != nil || string(plaintext) != "secret" {
+		t.Errorf("Expected the item to decrypt, got %q, %v", plaintext, err)
+	}
+
+	list, err := cli.GetDataFiltered(ctx, models.DataFilter{Tag: "work", ExpiringBefore: time.Now().Add(2 * time.Hour)})
+	if err != nil || len(list) != 1 {
+		t.Errorf("GetDataFiltered() = %d items, %v", len(list), err)
+	}
+
+	revision := got.Revision
+	update := models.DataRequest{Type: models.DataTypeText, Name: "renamed", Data: got.Data, BaseRevision: &revision}
+	if _, err := cli.UpdateData(ctx, data.ID.String(), update); err != nil {
+		t.Fatalf("UpdateData() error = %v", err)
+	}
+	if _, err := cli.UpdateData(ctx, data.ID.String(), update); !errors.Is(err, ErrConflict) {
+		t.Errorf("UpdateData() of a stale revision error = %v, want %v", err, ErrConflict)
+	}
+
+	if err := cli.DeleteData(ctx, data.ID.String()); err != nil {
+		t.Fatalf("DeleteData() error = %v", err)
+	}
+	if _, err := cli.GetDataByID(ctx, data.ID.String()); !errors.Is(err, ErrNotFound) || !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+		t.Errorf("GetDataByID() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestClient_GRPCChunkedBinary(t *testing.T) {
+	ctx := context.Background()
+	cli := newGRPCClient(t, middleware.NewMaintenance(false, ""))
+	resp, err := cli.Register(ctx, "grpc-files", "password", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	cli.SetToken(resp.Token)
+	session := NewClientSession(cli)
+	if err := session.Unlock("master-password", resp.Salt); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	content := bytes.Repeat([]byte("0123456789abcdefghijklmnopqrstuvwxy"), 72)
+	path := filepath.Join(t.TempDir(), "backup.tar")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	data, err := session.UploadBinary(ctx, path, "", models.DataRequest{Name: "Backup"}, 1000)
+	if err != nil {
+		t.Fatalf("UploadBinary() error = %v", err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "restored.tar")
+	binaryData := models.BinaryData{FileName: "backup.tar", Size: int64(len(content)), Chunks: 3}
+	if err := session.downloadBinary(ctx, data, binaryData, outputPath); err != nil {
+		t.Fatalf("downloadBinary() error = %v", err)
+	}
+	restored, err := os.ReadFile(outputPath)
+	if err != nil || !bytes.Equal(restored, content) {
+		t.Errorf("Expected the file to round-trip, got %d bytes, %v", len(restored), err)
+	}
+}
+
+func TestClient_GRPCMaintenance(t *testing.T) {
+	ctx := context.Background()
+	maintenance := middleware.NewMaintenance(false, "", server.MaintenanceExemptPaths...)
+	cli := newGRPCClient(t, maintenance)
+	resp, err := cli.Register(ctx, "grpc-reader", "password", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	cli.SetToken(resp.Token)
+
+	maintenance.Enable("nightly backup", 5*time.Minute)
+	_, err = cli.CreateData(ctx, models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x")})
+	want := "server is in maintenance mode: nightly backup; please retry in 5m0s"
+	if !IsMaintenance(err) || err.Error() != want {
+		t.Errorf("CreateData() during maintenance error = %v, want %q", err, want)
+	}
+}
+
+func TestConfig_GRPCTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     Config
+		wantTarget string
+		wantSecure bool
+		wantErr    bool
+	}{
+		{name: "host of the server URL", config: Config{ServerURL: "http://vault.example.com:8080"}, wantTarget: "vault.example.com:9090"},
+		{name: "HTTPS server", config: Config{ServerURL: "https://vault.example.com"}, wantTarget: "vault.example.com:9090", wantSecure: true},
+		{name: "configured address", config: Config{ServerURL: "https://vault.example.com", GRPCAddress: "grpc.example.com:443"},
+			wantTarget: "grpc.example.com:443", wantSecure: true},
+		{name: "no host", config: Config{ServerURL: "/relative"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, secure, err := tt.config.GRPCTarget()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GRPCTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if target != tt.wantTarget || secure != tt.wantSecure {
+				t.Errorf("GRPCTarget() = %q, %v, want %q, %v", target, secure, tt.wantTarget, tt.wantSecure)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// Unlock attempt limits for login
+const (
+	maxUnlockAttempts = 5
+	// hintAfterFailures is the number of failed unlocks after which the hint is shown
+	hintAfterFailures = 2
+)
+
+// ErrWrongMasterPassword is returned when the master password does not decrypt the vault
+var ErrWrongMasterPassword = errors.New("master password does not decrypt your vault")
+
+// GetPasswordHint gets the master password hint, empty if none is set
+func (c *Client) GetPasswordHint(ctx context.Context) (string, error) {
+	var resp models.PasswordHintResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/hint", nil, &resp, http.StatusOK); err != nil {
+		return "", err
+	}
+	return resp.Hint, nil
+}
+
+// SetPasswordHint stores a master password hint as plaintext on the server
+func (c *Client) SetPasswordHint(ctx context.Context, hint string) error {
+	return c.doJSON(ctx, http.MethodPut, "/api/v1/hint", models.PasswordHintRequest{Hint: hint}, nil, http.StatusNoContent)
+}
+
+// DeletePasswordHint removes the master password hint from the server
+func (c *Client) DeletePasswordHint(ctx context.Context) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/v1/hint", nil, nil, http.StatusNoContent)
+}
+
+// showPasswordHint prints the stored hint, if any, after repeated failed unlocks
+func (s *ClientSession) showPasswordHint(ctx context.Context) {
+	hint, err := s.cli.GetPasswordHint(ctx)
+	if err != nil || hint == "" {
+		return
+	}
+	fmt.Printf("Your hint: %s\n", hint)
+}
+
+// HintCommand shows, sets or removes the master password hint
+func (s *ClientSession) HintCommand(ctx context.Context, args []string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+
+	action := "show"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "show":
+		hint, err := s.cli.GetPasswordHint(ctx)
+		if err != nil {
+			return err
+		}
+		if hint == "" {
+			fmt.Println("No master password hint is set")
+		} else {
+			fmt.Printf("Master password hint: %s\n", hint)
+		}
+		return nil
+	case "set":
+		fmt.Println("WARNING: the hint is stored on the server as PLAINTEXT, unlike your items.")
+		fmt.Println("Anyone with access to the server or your account can read it.")
+		fmt.Println("Never include your password or any part of it.")
+		fmt.Print("Store a hint anyway? (y/N): ")
+		scanner := bufio.NewScanner(os.Stdin)
+		if !scanner.Scan() {
+			return fmt.Errorf("failed to read confirmation")
+		}
+		if answer := strings.ToLower(strings.TrimSpace(scanner.Text())); answer != "y" && answer != "yes" {
+			fmt.Println("Hint not stored")
+			return nil
+		}
+
+		fmt.Print("Enter hint (max 200 characters): ")
+		if !scanner.Scan() {
+			return fmt.Errorf("failed to read hint")
+		}
+		hint := strings.TrimSpace(scanner.Text())
+		if err := s.checkPasswordHint(hint); err != nil {
+			return err
+		}
+		if err := s.cli.SetPasswordHint(ctx, hint); err != nil {
+			return fmt.Errorf("failed to store hint: %w", err)
+		}
+		fmt.Println("Hint stored. Run 'hint remove' to delete it at any time.")
+		return nil
+	case "remove":
+		if err := s.cli.DeletePasswordHint(ctx); err != nil {
+			return fmt.Errorf("failed to remove hint: %w", err)
+		}
+		fmt.Println("Hint removed from the server")
+		return nil
+	default:
+		return fmt.Errorf("unknown hint action: %s (use show, set or remove)", action)
+	}
+}
+
+// checkPasswordHint rejects empty hints and hints revealing the master password
+func (s *ClientSession) checkPasswordHint(hint string) error {
+	if hint == "" {
+		return fmt.Errorf("hint must not be empty")
+	}
+	if s.masterPassword != "" && strings.Contains(strings.ToLower(hint), strings.ToLower(s.masterPassword)) {
+		return fmt.Errorf("hint must not contain your master password")
+	}
+	return nil
+}
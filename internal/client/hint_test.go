@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+func TestClientSession_PasswordHint(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := mux.NewRouter()
+	server.RegisterRoutes(router, store, store, jwtManager, server.Options{})
+	server.RegisterHintRoutes(router, store, store, jwtManager)
+
+	cli := NewClient("http://hint.invalid")
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+	resp, err := cli.Register(ctx, "hinted", "password", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	cli.SetToken(resp.Token)
+
+	session := NewClientSession(cli)
+	if err := session.Unlock("master-password", resp.Salt); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	if err := session.checkPasswordHint("my Master-Password backwards"); err == nil {
+		t.Error("Expected hint containing the master password to be rejected")
+	}
+	if err := session.checkPasswordHint("favourite band"); err != nil {
+		t.Errorf("checkPasswordHint() error = %v", err)
+	}
+
+	if err := cli.SetPasswordHint(ctx, "favourite band"); err != nil {
+		t.Fatalf("SetPasswordHint() error = %v", err)
+	}
+	if hint, err := cli.GetPasswordHint(ctx); err != nil || hint != "favourite band" {
+		t.Errorf("GetPasswordHint() = %q, %v", hint, err)
+	}
+	if err := cli.DeletePasswordHint(ctx); err != nil {
+		t.Fatalf("DeletePasswordHint() error = %v", err)
+	}
+	if hint, err := cli.GetPasswordHint(ctx); err != nil || hint != "" {
+		t.Errorf("GetPasswordHint() after removal = %q, %v", hint, err)
+	}
+}
+
+func TestClientSession_VerifyMasterPassword(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	salt, _ := base64.StdEncoding.DecodeString(session.GetCryptoManager().GetSaltBase64())
+	wrong, err := crypto.NewCryptoManagerWithSalt("not-the-password", salt)
+	if err != nil {
+		t.Fatalf("NewCryptoManagerWithSalt() error = %v", err)
+	}
+
+	if err := session.verifyMasterPassword(ctx, session.GetCryptoManager()); err != nil {
+		t.Errorf("verifyMasterPassword() with correct key error = %v", err)
+	}
+	if err := session.verifyMasterPassword(ctx, wrong); !errors.Is(err, ErrWrongMasterPassword) {
+		t.Errorf("verifyMasterPassword() with wrong key error = %v, want %v", err, ErrWrongMasterPassword)
+	}
+
+	for _, item := range mustList(t, session) {
+		if err := session.Delete(ctx, item.ID.String()); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+	}
+	// the verifier stored at registration checks the key without any item
+	if err := session.verifyMasterPassword(ctx, wrong); !errors.Is(err, ErrWrongMasterPassword) {
+		t.Errorf("verifyMasterPassword() on an empty vault error = %v, want %v", err, ErrWrongMasterPassword)
+	}
+	if err := session.verifyMasterPassword(ctx, session.GetCryptoManager()); err != nil {
+		t.Errorf("verifyMasterPassword() on an empty vault with correct key error = %v", err)
+	}
+}
+
+func TestClientSession_VerifyMasterPasswordWithoutVerifier(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	// an account registered before verifiers, with an empty vault
+	resp, err := session.cli.Register(ctx, "legacy", "password", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	session.cli.SetToken(resp.Token)
+	salt, _ := base64.StdEncoding.DecodeString(resp.Salt)
+	right, _ := crypto.NewCryptoManagerWithSalt("master-password", salt)
+	wrong, _ := crypto.NewCryptoManagerWithSalt("not-the-password", salt)
+
+	if err := session.verifyMasterPassword(ctx, wrong); err != nil {
+		t.Errorf("Empty vault without a verifier cannot be verified and should be accepted, got %v", err)
+	}
+
+	session.SetCryptoManager(right, "master-password")
+	encrypted, _ := right.Encrypt([]byte("text"))
+	if _, err := session.Create(ctx, models.DataRequest{Type: models.DataTypeText, Name: "note", Data: encrypted}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := session.verifyMasterPassword(ctx, wrong); !errors.Is(err, ErrWrongMasterPassword) {
+		t.Errorf("verifyMasterPassword() with wrong key error = %v, want %v", err, ErrWrongMasterPassword)
+	}
+	if err := session.verifyMasterPassword(ctx, right); err != nil {
+		t.Fatalf("verifyMasterPassword() with correct key error = %v", err)
+	}
+	verifier, err := session.cli.GetMasterVerifier(ctx)
+	if err != nil || !right.CheckVerifier(verifier) {
+		t.Errorf("Expected a verifier to be stored after a successful check, got %v", err)
+	}
+}
+
+func mustList(t *testing.T, session *ClientSession) []models.Data {
+	t.Helper()
+	items, err := session.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	return items
+}
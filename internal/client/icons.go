@@ -0,0 +1,254 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// MaxIconBytes is the largest favicon that is stored; the encrypted icon must
+// fit models.MaxIconSize
+const MaxIconBytes = 16 << 10
+
+// IconFetcher downloads the favicons of the sites of login items
+type IconFetcher struct {
+	httpClient *http.Client
+}
+
+// NewIconFetcher creates a fetcher with a short timeout, so a slow site does
+// not hold up saving a login
+func NewIconFetcher() *IconFetcher {
+	return &IconFetcher{httpClient: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Fetch downloads /favicon.ico of the site of a login URL. Only images of up
+// to MaxIconBytes are accepted.
+func (f *IconFetcher) Fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	site, err := parseLoginURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if site.Scheme != "http" && site.Scheme != "https" {
+		return nil, fmt.Errorf("no icon for %s URLs", site.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, site.Scheme+"://"+site.Host+"/favicon.ico", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("icon fetch failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("icon fetch failed: %s", resp.Status)
+	}
+
+	icon, err := io.ReadAll(io.LimitReader(resp.Body, MaxIconBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read icon: %w", err)
+	}
+	if len(icon) > MaxIconBytes {
+		return nil, fmt.Errorf("icon is larger than %d bytes", MaxIconBytes)
+	}
+	// sniffed rather than trusting the header; SVG is refused as it can carry scripts
+	if contentType := http.DetectContentType(icon); !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("icon is not an image (%s)", contentType)
+	}
+	return icon, nil
+}
+
+// SetIconFetcher turns on fetching the icons of new logins, nil turning it off
+func (s *ClientSession) SetIconFetcher(fetcher *IconFetcher) {
+	s.icons = fetcher
+}
+
+// loginIcon fetches and encrypts the favicon of the site in a login payload
+func (s *ClientSession) loginIcon(ctx context.Context, fetcher *IconFetcher, plaintext []byte) ([]byte, error) {
+	var login models.LoginPasswordData
+	if err := json.Unmarshal(plaintext, &login); err != nil || login.URL == "" {
+		return nil, fmt.Errorf("the login has no URL")
+	}
+	icon, err := fetcher.Fetch(ctx, login.URL)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.cryptoManager.Encrypt(icon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt icon: %w", err)
+	}
+	return encrypted, nil
+}
+
+// withIcon sets the icon of a new login item when icon fetching is on. A
+// failed fetch is only logged; it never blocks saving.
+func (s *ClientSession) withIcon(ctx context.Context, dataReq *models.DataRequest) {
+	if s.icons == nil || dataReq.Type != models.DataTypeLoginPassword || dataReq.Icon != nil {
+		return
+	}
+	plaintext, err := s.cryptoManager.Decrypt(dataReq.Data)
+	if err != nil {
+		return
+	}
+	icon, err := s.loginIcon(ctx, s.icons, plaintext)
+	if err != nil {
+		logger.Log.Debug("No icon for login", zap.Error(err))
+		return
+	}
+	dataReq.Icon = &icon
+}
+
+// FetchIcon fetches the favicon of the site of a login item by ID or unique ID
+// prefix and stores it encrypted with the item
+func (s *ClientSession) FetchIcon(ctx context.Context, id string) (*models.Data, error) {
+	if err := s.checkIconSupport(ctx); err != nil {
+		return nil, err
+	}
+	data, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	return s.fetchIcon(ctx, data)
+}
+
+func (s *ClientSession) fetchIcon(ctx context.Context, data *models.Data) (*models.Data, error) {
+	if data.Type != models.DataTypeLoginPassword {
+		return nil, fmt.Errorf("only login items have icons")
+	}
+	plaintext, err := s.cryptoManager.Decrypt(data.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	fetcher := s.icons
+	if fetcher == nil {
+		fetcher = NewIconFetcher()
+	}
+	icon, err := s.loginIcon(ctx, fetcher, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return s.cli.PatchData(ctx, data.ID.String(), models.DataPatchRequest{Icon: &icon})
+}
+
+// FetchIcons fetches the icons of the login items that have none and returns
+// how many were stored and how many could not be fetched
+func (s *ClientSession) FetchIcons(ctx context.Context) (int, int, error) {
+	if err := s.checkIconSupport(ctx); err != nil {
+		return 0, 0, err
+	}
+	items, err := s.ListFiltered(ctx, models.DataFilter{Type: models.DataTypeLoginPassword})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get logins: %w", err)
+	}
+	fetched, failed := 0, 0
+	for i := range items {
+		if len(items[i].Icon) > 0 {
+			continue
+		}
+		if _, err := s.fetchIcon(ctx, &items[i]); err != nil {
+			logger.Log.Debug("No icon for login", zap.String("data_id", items[i].ID.String()), zap.Error(err))
+			failed++
+			continue
+		}
+		fetched++
+	}
+	return fetched, failed, nil
+}
+
+// RemoveIcon removes the icon of an item by ID or unique ID prefix
+func (s *ClientSession) RemoveIcon(ctx context.Context, id string) (*models.Data, error) {
+	if err := s.checkIconSupport(ctx); err != nil {
+		return nil, err
+	}
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.cli.PatchData(ctx, id, models.DataPatchRequest{Icon: &[]byte{}})
+}
+
+// ItemIcon returns the decrypted icon of an item, nil if it has none
+func (s *ClientSession) ItemIcon(data *models.Data) ([]byte, error) {
+	if len(data.Icon) == 0 {
+		return nil, nil
+	}
+	return s.cryptoManager.Decrypt(data.Icon)
+}
+
+// checkIconSupport fails for servers that do not store icons, which would
+// silently drop them
+func (s *ClientSession) checkIconSupport(ctx context.Context) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil || !hasFeature(status, "icons") {
+		return fmt.Errorf("the server does not store icons")
+	}
+	return nil
+}
+
+// IconCommand shows or sets whether the icons of new logins are fetched, or
+// fetches or removes the icons of existing items
+func (s *ClientSession) IconCommand(ctx context.Context, config *Config, args []string) error {
+	if len(args) == 0 {
+		if config.FetchIcons {
+			fmt.Println("Icons of new logins: fetched")
+		} else {
+			fmt.Println("Icons of new logins: not fetched")
+		}
+		return nil
+	}
+
+	switch {
+	case (args[0] == "on" || args[0] == "off") && len(args) == 1:
+		config.FetchIcons = args[0] == "on"
+		if err := SaveConfig(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		if config.FetchIcons {
+			s.SetIconFetcher(NewIconFetcher())
+			fmt.Println("Icons of new logins will be fetched from their sites")
+		} else {
+			s.SetIconFetcher(nil)
+			fmt.Println("Icons of new logins will not be fetched")
+		}
+		return nil
+	case args[0] == "fetch" && len(args) == 2 && args[1] == "--all":
+		fetched, failed, err := s.FetchIcons(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Fetched %d icons", fetched)
+		if failed > 0 {
+			fmt.Printf(", %d sites had none", failed)
+		}
+		fmt.Println()
+		return nil
+	case args[0] == "fetch" && len(args) == 2:
+		data, err := s.FetchIcon(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Stored the icon of %q\n", CleanQuotes(data.Name))
+		return nil
+	case args[0] == "remove" && len(args) == 2:
+		data, err := s.RemoveIcon(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed the icon of %q\n", CleanQuotes(data.Name))
+		return nil
+	default:
+		return fmt.Errorf("usage: icon [on|off] | fetch <id>|--all | remove <id>")
+	}
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// pngIcon is the start of a PNG file, enough for content sniffing
+var pngIcon = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x10")
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestIconFetcher_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/favicon.ico" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(pngIcon)
+	}))
+	defer srv.Close()
+
+	fetcher := NewIconFetcher()
+	icon, err := fetcher.Fetch(context.Background(), srv.URL+"/login?next=/inbox")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if !bytes.Equal(icon, pngIcon) {
+		t.Errorf("Fetch() = %q, want the favicon", icon)
+	}
+
+	tests := []struct {
+		name    string
+		body    []byte
+		status  int
+		wantErr string
+	}{
+		{name: "missing", status: http.StatusNotFound, wantErr: "404"},
+		{name: "html", body: []byte("<!DOCTYPE html><html></html>"), status: http.StatusOK, wantErr: "not an image"},
+		{name: "svg", body: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`),
+			status: http.StatusOK, wantErr: "not an image"},
+		{name: "too large", body: append(append([]byte{}, pngIcon...), make([]byte, MaxIconBytes)...),
+			status: http.StatusOK, wantErr: "larger than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write(tt.body)
+			}))
+			defer srv.Close()
+			if _, err := fetcher.Fetch(context.Background(), srv.URL); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Fetch() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := fetcher.Fetch(context.Background(), "ftp://example.com"); err == nil {
+		t.Error("Expected an error for a URL other than http or https")
+	}
+}
+
+func TestClientSession_Icons(t *testing.T) {
+	ctx := context.Background()
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/favicon.ico" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(pngIcon)
+	}))
+	defer site.Close()
+	siteHost := strings.TrimPrefix(site.URL, "http://")
+	// other sites, such as the one of the demo login, are unreachable
+	fetcher := NewIconFetcher()
+	fetcher.httpClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host != siteHost {
+			return nil, errors.New("unreachable")
+		}
+		return http.DefaultTransport.RoundTrip(r)
+	})
+
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	createLogin := func(name string) *models.Data {
+		t.Helper()
+		payload, _ := json.Marshal(models.LoginPasswordData{Login: "alice", Password: "x", URL: site.URL + "/login"})
+		encrypted, err := session.cryptoManager.Encrypt(payload)
+		if err != nil {
+			t.Fatalf("Encrypt() error = %v", err)
+		}
+		data, err := session.Create(ctx, models.DataRequest{Type: models.DataTypeLoginPassword, Name: name, Data: encrypted})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		return data
+	}
+	iconOf := func(id string) []byte {
+		t.Helper()
+		data, err := session.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		icon, err := session.ItemIcon(data)
+		if err != nil {
+			t.Fatalf("ItemIcon() error = %v", err)
+		}
+		return icon
+	}
+
+	// off by default
+	manual := createLogin("Manual")
+	if icon := iconOf(manual.ID.String()); icon != nil {
+		t.Errorf("Expected no icon while fetching is off, got %q", icon)
+	}
+	if _, err := session.FetchIcon(ctx, manual.ID.String()); err != nil {
+		t.Fatalf("FetchIcon() error = %v", err)
+	}
+	if icon := iconOf(manual.ID.String()); !bytes.Equal(icon, pngIcon) {
+		t.Errorf("Expected the fetched icon, got %q", icon)
+	}
+
+	session.SetIconFetcher(fetcher)
+	automatic := createLogin("Automatic")
+	if !bytes.Equal(iconOf(automatic.ID.String()), pngIcon) {
+		t.Error("Expected the icon of a new login to be fetched")
+	}
+	if bytes.Contains(automatic.Icon, pngIcon) {
+		t.Error("Expected the icon to be stored encrypted")
+	}
+
+	if _, err := session.FetchIcon(ctx, demoItemID(t, session, "Demo Visa")); err == nil {
+		t.Error("Expected an error for an item other than a login")
+	}
+
+	// the demo login points at a site that cannot be reached
+	fetched, failed, err := session.FetchIcons(ctx)
+	if err != nil {
+		t.Fatalf("FetchIcons() error = %v", err)
+	}
+	if fetched != 0 || failed != 1 {
+		t.Errorf("FetchIcons() = %d fetched, %d failed, want 0 and 1", fetched, failed)
+	}
+
+	if _, err := session.RemoveIcon(ctx, manual.ID.String()); err != nil {
+		t.Fatalf("RemoveIcon() error = %v", err)
+	}
+	if icon := iconOf(manual.ID.String()); icon != nil {
+		t.Errorf("Expected the icon removed, got %q", icon)
+	}
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+)
+
+// LockReasonIdle is passed to the lock callback after a period without input
+const LockReasonIdle = "idle"
+
+const (
+	// DefaultAutoLockMinutes is the idle time after which the vault locks
+	// unless configured otherwise
+	DefaultAutoLockMinutes = 15
+	// idleCheckInterval is how often the time since the last input is checked
+	idleCheckInterval = 5 * time.Second
+)
+
+// IdleTimeout returns how long the vault may stay unlocked without input,
+// or 0 when auto-lock is off
+func (c *Config) IdleTimeout() time.Duration {
+	switch {
+	case c.AutoLockMinutes < 0:
+		return 0
+	case c.AutoLockMinutes == 0:
+		return DefaultAutoLockMinutes * time.Minute
+	default:
+		return time.Duration(c.AutoLockMinutes) * time.Minute
+	}
+}
+
+// IdleWatcher tracks user input and reports when none came for a timeout
+type IdleWatcher struct {
+	clock clock.Clock
+
+	mutex   sync.Mutex
+	timeout time.Duration
+	last    time.Time
+}
+
+// NewIdleWatcher creates a watcher for timeout, counting from now
+func NewIdleWatcher(timeout time.Duration, c clock.Clock) *IdleWatcher {
+	return &IdleWatcher{clock: c, timeout: timeout, last: c.Now()}
+}
+
+// Touch records user input
+func (w *IdleWatcher) Touch() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.last = w.clock.Now()
+}
+
+// SetTimeout changes the timeout, 0 turning auto-lock off
+func (w *IdleWatcher) SetTimeout(timeout time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.timeout = timeout
+}
+
+// idle reports whether the timeout passed since the last input and, if so,
+// starts counting again so the next idle period is reported once more
+func (w *IdleWatcher) idle() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	now := w.clock.Now()
+	if w.timeout <= 0 || now.Sub(w.last) < w.timeout {
+		return false
+	}
+	w.last = now
+	return true
+}
+
+// Watch calls onLock with LockReasonIdle whenever no input was recorded for
+// the timeout, until ctx is cancelled. A zero timeout never locks.
+func (w *IdleWatcher) Watch(ctx context.Context, onLock func(reason string)) {
+	go func() {
+		ticker := time.NewTicker(idleCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if w.idle() {
+					onLock(LockReasonIdle)
+				}
+			}
+		}
+	}()
+}
+
+// AutoLockCommand shows or sets the idle time after which the vault locks
+func AutoLockCommand(config *Config, args []string) error {
+	if len(args) == 0 {
+		if timeout := config.IdleTimeout(); timeout > 0 {
+			fmt.Printf("Auto-lock after %d minutes without input\n", int(timeout.Minutes()))
+		} else {
+			fmt.Println("Auto-lock: off")
+		}
+		return nil
+	}
+
+	if args[0] == "off" {
+		config.AutoLockMinutes = -1
+	} else {
+		minutes, err := strconv.Atoi(args[0])
+		if err != nil || minutes < 1 {
+			return fmt.Errorf("invalid auto-lock time %q: use a number of minutes or off", args[0])
+		}
+		config.AutoLockMinutes = minutes
+	}
+	if err := SaveConfig(config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if config.AutoLockMinutes < 0 {
+		fmt.Println("Auto-lock turned off")
+	} else {
+		fmt.Printf("The vault will lock after %d minutes without input\n", config.AutoLockMinutes)
+	}
+	return nil
+}
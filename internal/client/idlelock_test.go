@@ -0,0 +1,63 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+)
+
+func TestIdleWatcher(t *testing.T) {
+	manual := clock.NewManual(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC))
+	watcher := NewIdleWatcher(10*time.Minute, manual)
+
+	manual.Advance(9 * time.Minute)
+	if watcher.idle() {
+		t.Error("Expected no lock before the timeout")
+	}
+	watcher.Touch()
+	manual.Advance(9 * time.Minute)
+	if watcher.idle() {
+		t.Error("Expected input to postpone the lock")
+	}
+	manual.Advance(time.Minute)
+	if !watcher.idle() {
+		t.Fatal("Expected a lock after the timeout without input")
+	}
+	if watcher.idle() {
+		t.Error("Expected an idle period to be reported once")
+	}
+
+	watcher.SetTimeout(0)
+	manual.Advance(time.Hour)
+	if watcher.idle() {
+		t.Error("Expected no lock with auto-lock off")
+	}
+}
+
+func TestAutoLockCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default", args: nil, want: DefaultAutoLockMinutes * time.Minute},
+		{name: "minutes", args: []string{"5"}, want: 5 * time.Minute},
+		{name: "off", args: []string{"off"}, want: 0},
+		{name: "zero", args: []string{"0"}, want: DefaultAutoLockMinutes * time.Minute, wantErr: true},
+		{name: "garbage", args: []string{"soon"}, want: DefaultAutoLockMinutes * time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Ephemeral: true}
+			err := AutoLockCommand(config, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AutoLockCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := config.IdleTimeout(); got != tt.want {
+				t.Errorf("IdleTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
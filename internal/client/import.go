@@ -0,0 +1,376 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// importWindow is the number of records sent ahead of the server's results
+	importWindow = 32
+	// importProgressSuffix names the resume file kept next to an import source
+	importProgressSuffix = ".progress"
+	// importProgressEvery is how often import progress is reported, in records
+	importProgressEvery = 100
+)
+
+// ErrImportFailures is returned by ImportCommand when some lines were not imported
+var ErrImportFailures = errors.New("lines not imported")
+
+// importItem is one line of an import file: a plaintext item whose fields are
+// encrypted before they leave the client
+type importItem struct {
+	Type        models.DataType        `json:"type"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Environment string                 `json:"environment"`
+	Fields      map[string]interface{} `json:"fields"`
+}
+
+// ImportFailure is an import file line that could not be imported
+type ImportFailure struct {
+	Line  int    `json:"line"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// ImportReport summarizes an import run
+type ImportReport struct {
+	Imported int
+	// Resumed is the last line acknowledged by an earlier, interrupted run
+	Resumed  int
+	Failures []ImportFailure
+	// Types counts the items imported by this run by type
+	Types map[models.DataType]int
+}
+
+// importProgress is saved after each result so an interrupted import resumes
+// after the last acknowledged line of an unchanged source
+type importProgress struct {
+	SourceSHA256 string          `json:"source_sha256"`
+	Acknowledged int             `json:"acknowledged"`
+	Imported     int             `json:"imported"`
+	Failures     []ImportFailure `json:"failures,omitempty"`
+}
+
+// ImportData streams import records to the server as NDJSON and calls onResult
+// with each result as it arrives. At most window records are sent ahead of the
+// results, so a slow server throttles the client instead of buffering the stream.
+func (c *Client) ImportData(ctx context.Context, records []models.ImportRecord, window int, onResult func(models.ImportResult)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	slots := make(chan struct{}, window)
+	body, writer := io.Pipe()
+	defer body.Close()
+	go func() {
+		encoder := json.NewEncoder(writer)
+		for _, record := range records {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				writer.CloseWithError(ctx.Err())
+				return
+			}
+			if err := encoder.Encode(record); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		writer.Close()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/data/import", body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	c.authorize(req)
+
+	// a large import outlasts the request timeout; ctx bounds it instead
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Request failed", zap.Error(err), zap.String("path", req.URL.Path))
+		return requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		return statusError(resp, respBody)
+	}
+
+	acknowledged := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var result models.ImportResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			return fmt.Errorf("failed to unmarshal import result: %w", err)
+		}
+		if result.Seq == 0 {
+			return fmt.Errorf("server aborted the import: %s", result.Error)
+		}
+
+		<-slots
+		acknowledged++
+		onResult(result)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("import stream interrupted: %w", err)
+	}
+	if acknowledged < len(records) {
+		return fmt.Errorf("import stream ended after %d of %d records", acknowledged, len(records))
+	}
+	return nil
+}
+
+// importProgressPath returns the resume file of an import source
+func importProgressPath(path string) string {
+	return path + importProgressSuffix
+}
+
+// loadImportProgress returns the saved progress of the source, or empty progress
+// if there is none or the source changed since
+func loadImportProgress(path, sum string) importProgress {
+	fresh := importProgress{SourceSHA256: sum}
+	raw, err := os.ReadFile(importProgressPath(path))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Log.Warn("Failed to read import progress", zap.Error(err))
+		}
+		return fresh
+	}
+
+	var progress importProgress
+	if err := json.Unmarshal(raw, &progress); err != nil || progress.SourceSHA256 != sum {
+		return fresh
+	}
+	return progress
+}
+
+// saveImportProgress records the lines up to progress.Acknowledged as done
+func saveImportProgress(path string, progress importProgress) {
+	raw, err := json.Marshal(progress)
+	if err != nil {
+		logger.Log.Warn("Failed to marshal import progress", zap.Error(err))
+		return
+	}
+	if err := os.WriteFile(importProgressPath(path), raw, 0600); err != nil {
+		logger.Log.Warn("Failed to save import progress", zap.Error(err))
+	}
+}
+
+// importRecord validates an import file line and encrypts it into a record
+func (s *ClientSession) importRecord(line int, item importItem) (models.ImportRecord, error) {
+	if item.Name == "" {
+		return models.ImportRecord{}, fmt.Errorf("name is required")
+	}
+	fields, ok := editableFields[item.Type]
+	if !ok {
+		return models.ImportRecord{}, fmt.Errorf("type %q cannot be imported", item.Type)
+	}
+	for _, field := range fields {
+		if value, ok := item.Fields[field.name]; field.required && (!ok || value == nil || value == "") {
+			return models.ImportRecord{}, fmt.Errorf("%s is required", field.name)
+		}
+	}
+
+	content, err := json.Marshal(item.Fields)
+	if err != nil {
+		return models.ImportRecord{}, fmt.Errorf("failed to marshal data: %w", err)
+	}
+	encrypted, err := s.cryptoManager.Encrypt(content)
+	if err != nil {
+		return models.ImportRecord{}, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+
+	record := models.ImportRecord{
+		Seq: line,
+		Data: models.DataRequest{
+			Type:        item.Type,
+			Name:        item.Name,
+			Description: item.Description,
+			Data:        encrypted,
+			Metadata:    ItemMetadata(item.Type, item.Fields, ""),
+			Environment: item.Environment,
+		},
+	}
+	if err := s.withChecksum(&record.Data); err != nil {
+		return models.ImportRecord{}, err
+	}
+	if err := s.withDomains(&record.Data); err != nil {
+		return models.ImportRecord{}, err
+	}
+	s.withCardExpiry(&record.Data)
+	return record, nil
+}
+
+// Import encrypts the items of an NDJSON file and streams them to the server.
+// Progress is kept next to the file, so running it again after an interruption
+// resumes after the last acknowledged line.
+func (s *ClientSession) Import(ctx context.Context, path string, progressOut io.Writer) (*ImportReport, error) {
+	return s.ImportFrom(ctx, ImportFormatNDJSON, path, progressOut)
+}
+
+// ImportFrom imports a file in one of the ImportFormats, such as a Bitwarden
+// or KeePass export, like Import does an NDJSON file. Entries are mapped onto
+// the item types and encrypted before they leave the client.
+func (s *ClientSession) ImportFrom(ctx context.Context, format, path string, progressOut io.Writer) (*ImportReport, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	items, err := parseImportSource(format, source)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(source)
+	progress := loadImportProgress(path, hex.EncodeToString(sum[:]))
+	report := &ImportReport{Resumed: progress.Acknowledged, Types: make(map[models.DataType]int)}
+
+	var records []models.ImportRecord
+	names := make(map[int]string)
+	types := make(map[int]models.DataType)
+	var localFailures []ImportFailure
+	for _, entry := range items {
+		seq := entry.seq
+		if seq <= progress.Acknowledged {
+			continue
+		}
+		if entry.err != nil {
+			localFailures = append(localFailures, ImportFailure{Line: seq, Name: entry.item.Name, Error: entry.err.Error()})
+			continue
+		}
+		record, err := s.importRecord(seq, entry.item)
+		if err != nil {
+			localFailures = append(localFailures, ImportFailure{Line: seq, Name: entry.item.Name, Error: err.Error()})
+			continue
+		}
+		records = append(records, record)
+		names[seq] = entry.item.Name
+		types[seq] = entry.item.Type
+	}
+
+	// failures of skipped lines are saved once a later line is acknowledged
+	pending := localFailures
+	onResult := func(result models.ImportResult) {
+		for len(pending) > 0 && pending[0].Line < result.Seq {
+			progress.Failures = append(progress.Failures, pending[0])
+			pending = pending[1:]
+		}
+		if result.Error != "" {
+			progress.Failures = append(progress.Failures, ImportFailure{Line: result.Seq, Name: names[result.Seq], Error: result.Error})
+		} else {
+			progress.Imported++
+			report.Types[types[result.Seq]]++
+		}
+		progress.Acknowledged = result.Seq
+		saveImportProgress(path, progress)
+
+		done := progress.Imported + len(progress.Failures)
+		if progressOut != nil && done%importProgressEvery == 0 {
+			fmt.Fprintf(progressOut, "Processed %d records...\n", done)
+		}
+	}
+
+	var importErr error
+	if len(records) > 0 {
+		importErr = s.withVaultLock(ctx, "import", func() error {
+			status, err := s.cli.GetStatus(ctx)
+			if err == nil && !hasFeature(status, "streaming_import") && hasFeature(status, "batch") {
+				return s.importInBatches(ctx, records, onResult)
+			}
+			return s.cli.ImportData(ctx, records, importWindow, onResult)
+		})
+	}
+
+	report.Imported = progress.Imported
+	if importErr != nil && ctx.Err() != nil {
+		// cancelled, however the stream happened to break off
+		importErr = ctx.Err()
+	}
+	if importErr != nil {
+		report.Failures = append(progress.Failures, pending...)
+		return report, fmt.Errorf("import interrupted after line %d: %w", progress.Acknowledged, importErr)
+	}
+
+	report.Failures = append(progress.Failures, pending...)
+	sort.Slice(report.Failures, func(i, j int) bool {
+		return report.Failures[i].Line < report.Failures[j].Line
+	})
+	if err := os.Remove(importProgressPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Log.Warn("Failed to remove import progress", zap.Error(err))
+	}
+	return report, nil
+}
+
+// ImportCommand imports an NDJSON file of items, one per line such as
+// {"type":"text","name":"Notes","fields":{"content":"..."}}, and reports each failed line
+func (s *ClientSession) ImportCommand(ctx context.Context, path string) error {
+	return s.ImportFromCommand(ctx, ImportFormatNDJSON, path)
+}
+
+// ImportFromCommand imports a file in one of the ImportFormats and reports
+// the items imported by type and each failed line or entry
+func (s *ClientSession) ImportFromCommand(ctx context.Context, format, path string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("import file is required")
+	}
+
+	report, err := s.ImportFrom(ctx, format, path, os.Stdout)
+	if report != nil {
+		unit := importUnit(format)
+		if report.Resumed > 0 {
+			fmt.Printf("Resumed after %s %d of an earlier import\n", unit, report.Resumed)
+		}
+		fmt.Printf("Imported %d items, %d failed\n", report.Imported, len(report.Failures))
+		for _, group := range listGroups {
+			if count := report.Types[group.dataType]; count > 0 {
+				fmt.Printf("  %s: %d\n", group.title, count)
+			}
+		}
+		for _, failure := range report.Failures {
+			name := ""
+			if failure.Name != "" {
+				name = fmt.Sprintf(" (%s)", failure.Name)
+			}
+			fmt.Printf("  %s %d%s: %s\n", unit, failure.Line, name, failure.Error)
+		}
+	}
+	if err != nil {
+		if report != nil {
+			fmt.Println("Run the same import again to resume.")
+		}
+		return err
+	}
+	if len(report.Failures) > 0 {
+		return fmt.Errorf("%w: %d", ErrImportFailures, len(report.Failures))
+	}
+	return nil
+}
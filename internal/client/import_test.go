@@ -0,0 +1,235 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeImportFile writes count valid text items, followed by the extra lines
+func writeImportFile(t *testing.T, count int, extra ...string) string {
+	t.Helper()
+	var lines []string
+	for i := 1; i <= count; i++ {
+		line, _ := json.Marshal(importItem{
+			Type:   "text",
+			Name:   fmt.Sprintf("Imported %d", i),
+			Fields: map[string]interface{}{"content": fmt.Sprintf("note %d", i)},
+		})
+		lines = append(lines, string(line))
+	}
+	lines = append(lines, extra...)
+
+	path := filepath.Join(t.TempDir(), "vault.ndjson")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+// countImported returns the number of items named like the import file items
+func countImported(t *testing.T, session *ClientSession) int {
+	t.Helper()
+	items, err := session.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	count := 0
+	for _, item := range items {
+		if strings.HasPrefix(item.Name, "Imported ") {
+			count++
+		}
+	}
+	return count
+}
+
+func TestClientSession_Import(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	// more records than the window, so the client has to wait for results
+	count := importWindow*3 + 5
+	path := writeImportFile(t, count,
+		`{"type":"bank_card","name":"Card","fields":{"card_number":"4111111111111111"}}`,
+		`{"type":"binary","name":"File","fields":{}}`,
+		`not json`,
+		`{"type":"login_password","name":"Mail","fields":{"login":"me","password":"secret","url":"https://mail.example.com"}}`,
+	)
+
+	report, err := session.Import(ctx, path, io.Discard)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Imported != count+1 || report.Resumed != 0 {
+		t.Errorf("Expected %d imported items, got %+v", count+1, report)
+	}
+
+	want := []ImportFailure{
+		{Line: count + 1, Name: "Card", Error: "expiry_date is required"},
+		{Line: count + 2, Name: "File", Error: `type "binary" cannot be imported`},
+		{Line: count + 3, Error: "invalid JSON"},
+	}
+	if len(report.Failures) != len(want) {
+		t.Fatalf("Expected failures %+v, got %+v", want, report.Failures)
+	}
+	for i := range want {
+		if report.Failures[i] != want[i] {
+			t.Errorf("Expected failure %+v, got %+v", want[i], report.Failures[i])
+		}
+	}
+
+	if got := countImported(t, session); got != count {
+		t.Errorf("Expected %d imported notes, got %d", count, got)
+	}
+	if _, err := os.Stat(importProgressPath(path)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the progress file to be removed after a complete import, got %v", err)
+	}
+}
+
+func TestClientSession_ImportResume(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	count := 50
+	path := writeImportFile(t, count)
+
+	// the connection drops after the first ten records reached the server
+	transport := session.cli.httpClient.Transport.(*handlerTransport)
+	router := transport.handler
+	transport.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = io.NopCloser(&failingReader{r: r.Body, records: 10})
+		router.ServeHTTP(w, r)
+	})
+
+	report, err := session.Import(ctx, path, io.Discard)
+	if err == nil {
+		t.Fatal("Expected the interrupted import to fail")
+	}
+	if report == nil || report.Imported != 10 {
+		t.Fatalf("Expected 10 acknowledged records before the interruption, got %+v", report)
+	}
+	if _, err := os.Stat(importProgressPath(path)); err != nil {
+		t.Fatalf("Expected the progress to be saved: %v", err)
+	}
+
+	transport.handler = router
+	report, err = session.Import(ctx, path, io.Discard)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Resumed != 10 || report.Imported != count || len(report.Failures) != 0 {
+		t.Errorf("Expected to resume after line 10 and import %d in total, got %+v", count, report)
+	}
+	if got := countImported(t, session); got != count {
+		t.Errorf("Expected every line imported exactly once, got %d of %d", got, count)
+	}
+}
+
+// failingReader passes the first records lines through and then fails
+type failingReader struct {
+	r       io.Reader
+	records int
+	buf     []byte
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	for len(f.buf) == 0 {
+		if f.records == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		chunk := make([]byte, 4096)
+		n, err := f.r.Read(chunk)
+		f.buf = append(f.buf, chunk[:n]...)
+		if err != nil && n == 0 {
+			return 0, err
+		}
+	}
+
+	n := 0
+	for n < len(p) && n < len(f.buf) {
+		p[n] = f.buf[n]
+		n++
+		if p[n-1] == '\n' {
+			f.records--
+			if f.records == 0 {
+				break
+			}
+		}
+	}
+	f.buf = f.buf[n:]
+	if f.records == 0 {
+		f.buf = nil
+	}
+	return n, nil
+}
+
+func TestClientSession_ImportCancelled(t *testing.T) {
+	session, _, err := NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	count := 50
+	path := writeImportFile(t, count)
+
+	// Ctrl+C once the server has seen ten records
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := session.cli.httpClient.Transport.(*handlerTransport)
+	router := transport.handler
+	transport.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/data/import" {
+			r.Body = io.NopCloser(&cancellingReader{r: r.Body, records: 10, cancel: cancel})
+		}
+		router.ServeHTTP(w, r)
+	})
+
+	if _, err := session.Import(ctx, path, io.Discard); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the import to be cancelled, got %v", err)
+	}
+	if _, err := os.Stat(importProgressPath(path)); err != nil {
+		t.Fatalf("Expected the progress to be kept for resuming: %v", err)
+	}
+
+	transport.handler = router
+	report, err := session.Import(context.Background(), path, io.Discard)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Resumed == 0 || report.Imported != count {
+		t.Errorf("Expected to resume and import %d in total, got %+v", count, report)
+	}
+	if got := countImported(t, session); got != count {
+		t.Errorf("Expected every line imported exactly once, got %d of %d", got, count)
+	}
+}
+
+// cancellingReader calls cancel once the first records lines have been read
+type cancellingReader struct {
+	r       io.Reader
+	records int
+	cancel  func()
+}
+
+func (c *cancellingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	for _, b := range p[:n] {
+		if b == '\n' {
+			c.records--
+			if c.records == 0 {
+				c.cancel()
+			}
+		}
+	}
+	return n, err
+}
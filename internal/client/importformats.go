@@ -0,0 +1,422 @@
+package client
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// Import formats: GophKeeper's own NDJSON and the exports of other password managers
+const (
+	ImportFormatNDJSON     = "ndjson"
+	ImportFormatBitwarden  = "bitwarden"
+	ImportFormatKeePassXML = "keepass-xml"
+	ImportFormatKeePassCSV = "keepass-csv"
+	ImportFormatCSV        = "csv"
+)
+
+// ImportFormats lists the accepted import formats
+var ImportFormats = []string{ImportFormatNDJSON, ImportFormatBitwarden, ImportFormatKeePassXML, ImportFormatKeePassCSV, ImportFormatCSV}
+
+// sourceItem is an item read from an import source with the line or entry
+// number it is acknowledged and reported under, or the reason it was unreadable
+type sourceItem struct {
+	seq  int
+	item importItem
+	err  error
+}
+
+// importUnit names what the numbers of failures of a format count
+func importUnit(format string) string {
+	switch format {
+	case ImportFormatBitwarden, ImportFormatKeePassXML:
+		return "entry"
+	default:
+		return "line"
+	}
+}
+
+// parseImportSource reads the items of an import source in the given format
+func parseImportSource(format string, source []byte) ([]sourceItem, error) {
+	switch format {
+	case ImportFormatNDJSON:
+		return parseNDJSON(source), nil
+	case ImportFormatBitwarden:
+		return parseBitwarden(source)
+	case ImportFormatKeePassXML:
+		return parseKeePassXML(source)
+	case ImportFormatKeePassCSV, ImportFormatCSV:
+		return parseCSV(source)
+	default:
+		return nil, fmt.Errorf("unknown import format %q, expected one of: %s", format, strings.Join(ImportFormats, ", "))
+	}
+}
+
+// parseNDJSON reads one item per line, numbered by line
+func parseNDJSON(source []byte) []sourceItem {
+	var items []sourceItem
+	for i, line := range bytes.Split(source, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var item importItem
+		if err := json.Unmarshal(line, &item); err != nil {
+			items = append(items, sourceItem{seq: i + 1, err: errors.New("invalid JSON")})
+			continue
+		}
+		items = append(items, sourceItem{seq: i + 1, item: item})
+	}
+	return items
+}
+
+// foreignItem maps the fields of an entry from another password manager onto
+// an item of dataType. Entries lacking a field the type requires, like a login
+// without a username, become a text item listing all of their fields, so
+// nothing is lost.
+func foreignItem(dataType models.DataType, name, description string, values map[string]string) importItem {
+	if dataType == models.DataTypeText && values["content"] == "" {
+		values["content"], values["notes"] = values["notes"], ""
+	}
+
+	fields := make(map[string]interface{})
+	for key, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			fields[key] = value
+		}
+	}
+	if name == "" {
+		for _, fallback := range []string{"url", "login"} {
+			if value, ok := fields[fallback].(string); ok {
+				name = value
+				break
+			}
+		}
+	}
+	item := importItem{Type: dataType, Name: strings.TrimSpace(name), Description: strings.TrimSpace(description), Fields: fields}
+
+	complete := true
+	for _, field := range editableFields[dataType] {
+		if _, ok := fields[field.name]; field.required && !ok {
+			complete = false
+		}
+	}
+	if complete || len(fields) == 0 {
+		return item
+	}
+	return fieldListing(item, editableFields[dataType])
+}
+
+// fieldListing turns an item into a text item whose content lists its fields:
+// those in order first, then the others by name
+func fieldListing(item importItem, order []editableField) importItem {
+	var keys []string
+	known := make(map[string]bool)
+	for _, field := range order {
+		known[field.name] = true
+		if _, ok := item.Fields[field.name]; ok {
+			keys = append(keys, field.name)
+		}
+	}
+	var others []string
+	for key := range item.Fields {
+		if !known[key] {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+
+	lines := make([]string, 0, len(item.Fields))
+	for _, key := range append(keys, others...) {
+		lines = append(lines, fmt.Sprintf("%s: %s", key, item.Fields[key]))
+	}
+	item.Type = models.DataTypeText
+	item.Fields = map[string]interface{}{"content": strings.Join(lines, "\n")}
+	return item
+}
+
+// bitwardenExport is the unencrypted JSON export of Bitwarden
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Items     []struct {
+		Type  int    `json:"type"`
+		Name  string `json:"name"`
+		Notes string `json:"notes"`
+		Login *struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			TOTP     string `json:"totp"`
+			URIs     []struct {
+				URI string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+		Card *struct {
+			CardholderName string `json:"cardholderName"`
+			Brand          string `json:"brand"`
+			Number         string `json:"number"`
+			ExpMonth       string `json:"expMonth"`
+			ExpYear        string `json:"expYear"`
+			Code           string `json:"code"`
+		} `json:"card"`
+		Identity map[string]interface{} `json:"identity"`
+		Fields   []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"fields"`
+	} `json:"items"`
+}
+
+// Bitwarden item types
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+)
+
+// parseBitwarden reads logins, cards, secure notes and identities, numbered by
+// entry. Custom fields are kept as extra fields of the item.
+func parseBitwarden(source []byte) ([]sourceItem, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(source, &export); err != nil {
+		return nil, fmt.Errorf("not a Bitwarden JSON export: %w", err)
+	}
+	if export.Encrypted {
+		return nil, fmt.Errorf("encrypted Bitwarden exports cannot be imported, export the vault as unencrypted JSON")
+	}
+
+	items := make([]sourceItem, 0, len(export.Items))
+	for i, entry := range export.Items {
+		values := map[string]string{"notes": entry.Notes}
+		dataType := models.DataTypeText
+		switch {
+		case entry.Type == bitwardenLogin && entry.Login != nil:
+			dataType = models.DataTypeLoginPassword
+			values["login"] = entry.Login.Username
+			values["password"] = entry.Login.Password
+			values["totp"] = entry.Login.TOTP
+			if len(entry.Login.URIs) > 0 {
+				values["url"] = entry.Login.URIs[0].URI
+			}
+		case entry.Type == bitwardenCard && entry.Card != nil:
+			dataType = models.DataTypeBankCard
+			values["cardholder"] = entry.Card.CardholderName
+			values["card_number"] = entry.Card.Number
+			values["cvv"] = entry.Card.Code
+			values["bank"] = entry.Card.Brand
+			if entry.Card.ExpMonth != "" && entry.Card.ExpYear != "" {
+				values["expiry_date"] = fmt.Sprintf("%02s/%s", entry.Card.ExpMonth, lastDigits(entry.Card.ExpYear, 2))
+			}
+		case entry.Type == bitwardenIdentity:
+			// an identity has no content of its own, so its fields are listed
+			fields := make(map[string]interface{})
+			for key, value := range entry.Identity {
+				if value != nil && strings.TrimSpace(fmt.Sprint(value)) != "" {
+					fields[key] = strings.TrimSpace(fmt.Sprint(value))
+				}
+			}
+			if notes := strings.TrimSpace(entry.Notes); notes != "" {
+				fields["notes"] = notes
+			}
+			items = append(items, sourceItem{seq: i + 1, item: fieldListing(importItem{Name: entry.Name, Fields: fields}, nil)})
+			continue
+		case entry.Type != bitwardenSecureNote:
+			items = append(items, sourceItem{seq: i + 1, item: importItem{Name: entry.Name}, err: fmt.Errorf("unsupported Bitwarden item type %d", entry.Type)})
+			continue
+		}
+		for _, field := range entry.Fields {
+			if _, taken := values[field.Name]; field.Name != "" && !taken && field.Value != nil {
+				values[field.Name] = fmt.Sprint(field.Value)
+			}
+		}
+		items = append(items, sourceItem{seq: i + 1, item: foreignItem(dataType, entry.Name, "", values)})
+	}
+	return items, nil
+}
+
+// lastDigits returns the last n characters of s, turning years like 2030 into 30
+func lastDigits(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
+
+// keePassFile is the XML export of KeePass 2 and KeePassXC
+type keePassFile struct {
+	Meta struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+// keePassEntry is an entry; its History element, holding earlier versions of
+// the entry, is deliberately not read
+type keePassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+}
+
+// keePassFields maps the standard KeePass entry fields onto item fields
+var keePassFields = map[string]string{"UserName": "login", "Password": "password", "URL": "url", "Notes": "notes"}
+
+// parseKeePassXML reads the entries of all groups but the recycle bin, numbered
+// in document order
+func parseKeePassXML(source []byte) ([]sourceItem, error) {
+	var file keePassFile
+	if err := xml.Unmarshal(source, &file); err != nil {
+		return nil, fmt.Errorf("not a KeePass XML export: %w", err)
+	}
+
+	var items []sourceItem
+	var walk func(groups []keePassGroup)
+	walk = func(groups []keePassGroup) {
+		for _, group := range groups {
+			if group.UUID != "" && group.UUID == file.Meta.RecycleBinUUID {
+				continue
+			}
+			for _, entry := range group.Entries {
+				title := ""
+				values := make(map[string]string)
+				for _, field := range entry.Strings {
+					switch name, known := keePassFields[field.Key]; {
+					case field.Key == "Title":
+						title = field.Value
+					case known:
+						values[name] = field.Value
+					default:
+						values[field.Key] = field.Value
+					}
+				}
+				items = append(items, sourceItem{seq: len(items) + 1, item: foreignItem(entryType(values), title, "", values)})
+			}
+			walk(group.Groups)
+		}
+	}
+	walk(file.Root.Groups)
+	return items, nil
+}
+
+// entryType guesses the type of an entry from the fields it has
+func entryType(values map[string]string) models.DataType {
+	switch {
+	case values["card_number"] != "":
+		return models.DataTypeBankCard
+	case values["login"] != "" || values["password"] != "":
+		return models.DataTypeLoginPassword
+	default:
+		return models.DataTypeText
+	}
+}
+
+// csvColumns maps CSV headers, lowercased without spaces, dashes and
+// underscores, onto item fields. They cover the CSV exports of KeePass,
+// KeePassXC, Bitwarden and most other password managers.
+var csvColumns = map[string]string{
+	"title": "name", "name": "name", "account": "name",
+	"description": "description",
+	"type":        "type",
+	"username":    "login", "user": "login", "login": "login", "loginname": "login", "loginusername": "login", "email": "login",
+	"password": "password", "loginpassword": "password",
+	"url": "url", "website": "url", "uri": "url", "loginuri": "url",
+	"notes": "notes", "note": "notes", "comments": "notes", "comment": "notes", "extra": "notes",
+	"totp": "totp", "logintotp": "totp",
+	"content":    "content",
+	"cardnumber": "card_number", "expirydate": "expiry_date", "expiry": "expiry_date", "cvv": "cvv", "cvc": "cvv",
+	"cardholder": "cardholder", "cardholdername": "cardholder", "bank": "bank",
+}
+
+// csvIgnored are columns of password manager exports that are not item content
+var csvIgnored = map[string]bool{
+	"group": true, "folder": true, "favorite": true, "reprompt": true, "fields": true,
+	"lastmodified": true, "created": true, "icon": true,
+}
+
+// csvTypes are the values accepted in a type column
+var csvTypes = map[string]models.DataType{
+	"login": models.DataTypeLoginPassword, "login_password": models.DataTypeLoginPassword,
+	"card": models.DataTypeBankCard, "bank_card": models.DataTypeBankCard,
+	"note": models.DataTypeText, "securenote": models.DataTypeText, "text": models.DataTypeText,
+}
+
+// parseCSV reads a CSV file with a header row, numbered by line. The type of
+// each row comes from a type column, if any, or from the fields it has;
+// unknown columns are kept as extra fields.
+func parseCSV(source []byte) ([]sourceItem, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(source, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make([]string, len(header))
+	for i, title := range header {
+		key := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(title)))
+		switch {
+		case csvColumns[key] != "":
+			columns[i] = csvColumns[key]
+		case !csvIgnored[key] && strings.TrimSpace(title) != "":
+			columns[i] = strings.TrimSpace(title)
+		}
+	}
+
+	var items []sourceItem
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("failed to read CSV: %w", err)
+			}
+			items = append(items, sourceItem{seq: parseErr.StartLine, err: errors.New("invalid CSV")})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		values := make(map[string]string)
+		for i, value := range record {
+			if i < len(columns) && columns[i] != "" && values[columns[i]] == "" {
+				values[columns[i]] = value
+			}
+		}
+		name, description, typeName := values["name"], values["description"], strings.ToLower(strings.TrimSpace(values["type"]))
+		delete(values, "name")
+		delete(values, "description")
+		delete(values, "type")
+
+		dataType, ok := csvTypes[typeName]
+		if typeName == "" {
+			dataType, ok = entryType(values), true
+		}
+		if !ok {
+			items = append(items, sourceItem{seq: line, item: importItem{Name: name}, err: fmt.Errorf("unknown type %q", typeName)})
+			continue
+		}
+		items = append(items, sourceItem{seq: line, item: foreignItem(dataType, name, description, values)})
+	}
+	return items, nil
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+const bitwardenSample = `{
+  "encrypted": false,
+  "folders": [{"id": "f1", "name": "Work"}],
+  "items": [
+    {"type": 1, "name": "GitHub", "notes": "2FA on", "folderId": "f1",
+     "login": {"username": "octo", "password": "hunter2", "totp": "JBSWY3DPEHPK3PXP", "uris": [{"uri": "https://github.com"}]},
+     "fields": [{"name": "recovery", "value": "abc-def", "type": 1}]},
+    {"type": 2, "name": "Door code", "notes": "4711", "secureNote": {"type": 0}},
+    {"type": 3, "name": "Visa", "card": {"cardholderName": "Jane Doe", "brand": "Visa", "number": "4111111111111111", "expMonth": "3", "expYear": "2030", "code": "123"}},
+    {"type": 3, "name": "Partial card", "card": {"number": "5500000000000004"}},
+    {"type": 4, "name": "Me", "identity": {"firstName": "Jane", "lastName": "Doe", "phone": null}},
+    {"type": 9, "name": "Future"}
+  ]
+}`
+
+const keePassSample = `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+  <Meta><RecycleBinUUID>bin</RecycleBinUUID></Meta>
+  <Root>
+    <Group>
+      <UUID>root</UUID><Name>Database</Name>
+      <Entry>
+        <String><Key>Title</Key><Value>Router</Value></String>
+        <String><Key>UserName</Key><Value>admin</Value></String>
+        <String><Key>Password</Key><Value ProtectInMemory="True">s3cret</Value></String>
+        <String><Key>URL</Key><Value>http://192.168.0.1</Value></String>
+        <String><Key>Serial</Key><Value>RT-42</Value></String>
+        <History>
+          <Entry><String><Key>Title</Key><Value>Old router</Value></String></Entry>
+        </History>
+      </Entry>
+      <Group>
+        <UUID>notes</UUID><Name>Notes</Name>
+        <Entry>
+          <String><Key>Title</Key><Value>Wi-Fi</Value></String>
+          <String><Key>Notes</Key><Value>guest / welcome</Value></String>
+        </Entry>
+      </Group>
+      <Group>
+        <UUID>bin</UUID><Name>Recycle Bin</Name>
+        <Entry><String><Key>Title</Key><Value>Deleted</Value></String></Entry>
+      </Group>
+    </Group>
+  </Root>
+</KeePassFile>`
+
+func TestParseBitwarden(t *testing.T) {
+	items, err := parseBitwarden([]byte(bitwardenSample))
+	if err != nil {
+		t.Fatalf("parseBitwarden() error = %v", err)
+	}
+	if len(items) != 6 {
+		t.Fatalf("Expected 6 entries, got %d", len(items))
+	}
+
+	github := items[0].item
+	wantFields := map[string]interface{}{
+		"login": "octo", "password": "hunter2", "url": "https://github.com", "notes": "2FA on",
+		"totp": "JBSWY3DPEHPK3PXP", "recovery": "abc-def",
+	}
+	if github.Type != models.DataTypeLoginPassword || !reflect.DeepEqual(github.Fields, wantFields) {
+		t.Errorf("Unexpected login %+v", github)
+	}
+	if note := items[1].item; note.Type != models.DataTypeText || note.Fields["content"] != "4711" {
+		t.Errorf("Expected the secure note as text, got %+v", note)
+	}
+	if card := items[2].item; card.Type != models.DataTypeBankCard || card.Fields["expiry_date"] != "03/30" || card.Fields["cardholder"] != "Jane Doe" {
+		t.Errorf("Unexpected card %+v", card)
+	}
+	if partial := items[3].item; partial.Type != models.DataTypeText || partial.Fields["content"] != "card_number: 5500000000000004" {
+		t.Errorf("Expected an incomplete card to be listed in a note, got %+v", partial)
+	}
+	if identity := items[4].item; identity.Type != models.DataTypeText || identity.Fields["content"] != "firstName: Jane\nlastName: Doe" {
+		t.Errorf("Expected the identity fields listed in a note, got %+v", identity)
+	}
+	if items[5].err == nil || items[5].seq != 6 {
+		t.Errorf("Expected the unknown type to fail as entry 6, got %+v", items[5])
+	}
+
+	if _, err := parseBitwarden([]byte(`{"encrypted": true, "items": []}`)); err == nil {
+		t.Error("Expected encrypted exports to be rejected")
+	}
+}
+
+func TestParseKeePassXML(t *testing.T) {
+	items, err := parseKeePassXML([]byte(keePassSample))
+	if err != nil {
+		t.Fatalf("parseKeePassXML() error = %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected the entries without history and recycle bin, got %+v", items)
+	}
+	router := items[0].item
+	if router.Name != "Router" || router.Type != models.DataTypeLoginPassword || router.Fields["password"] != "s3cret" || router.Fields["Serial"] != "RT-42" {
+		t.Errorf("Unexpected entry %+v", router)
+	}
+	if wifi := items[1].item; wifi.Type != models.DataTypeText || wifi.Fields["content"] != "guest / welcome" || items[1].seq != 2 {
+		t.Errorf("Expected the note as entry 2, got %+v", items[1])
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []importItem
+	}{
+		{
+			name:   "KeePass",
+			source: "\"Account\",\"Login Name\",\"Password\",\"Web Site\",\"Comments\"\n\"Mail\",\"me\",\"pw\",\"https://mail.example.com\",\"multi\nline\"\n",
+			want: []importItem{{Type: models.DataTypeLoginPassword, Name: "Mail", Fields: map[string]interface{}{
+				"login": "me", "password": "pw", "url": "https://mail.example.com", "notes": "multi\nline"}}},
+		},
+		{
+			name:   "KeePassXC with ignored columns",
+			source: "\"Group\",\"Title\",\"Username\",\"Password\",\"URL\",\"Notes\",\"TOTP\",\"Icon\",\"Last Modified\",\"Created\"\n\"Root\",\"Bank\",\"jane\",\"pw\",\"\",\"\",\"\",\"0\",\"2024-01-01\",\"2024-01-01\"\n",
+			want:   []importItem{{Type: models.DataTypeLoginPassword, Name: "Bank", Fields: map[string]interface{}{"login": "jane", "password": "pw"}}},
+		},
+		{
+			name:   "generic with types",
+			source: "type,name,card_number,expiry_date,cvv,cardholder,content\ncard,Visa,4111111111111111,12/30,123,Jane,\nnote,Todo,,,,,buy milk\n",
+			want: []importItem{
+				{Type: models.DataTypeBankCard, Name: "Visa", Fields: map[string]interface{}{
+					"card_number": "4111111111111111", "expiry_date": "12/30", "cvv": "123", "cardholder": "Jane"}},
+				{Type: models.DataTypeText, Name: "Todo", Fields: map[string]interface{}{"content": "buy milk"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := parseCSV([]byte(tt.source))
+			if err != nil {
+				t.Fatalf("parseCSV() error = %v", err)
+			}
+			var got []importItem
+			for _, item := range items {
+				if item.err != nil {
+					t.Fatalf("Unexpected failure %v", item.err)
+				}
+				got = append(got, item.item)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCSV() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	items, err := parseCSV([]byte("type,name\nwidget,Thing\n"))
+	if err != nil || len(items) != 1 || items[0].err == nil || items[0].seq != 2 {
+		t.Errorf("Expected an unknown type to fail on line 2, got %+v, %v", items, err)
+	}
+}
+
+func TestClientSession_ImportFrom(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "bitwarden.json")
+	if err := os.WriteFile(path, []byte(bitwardenSample), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	report, err := session.ImportFrom(ctx, ImportFormatBitwarden, path, io.Discard)
+	if err != nil {
+		t.Fatalf("ImportFrom() error = %v", err)
+	}
+	if report.Imported != 5 || len(report.Failures) != 1 || report.Failures[0].Line != 6 || report.Failures[0].Name != "Future" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if want := map[models.DataType]int{models.DataTypeLoginPassword: 1, models.DataTypeBankCard: 1, models.DataTypeText: 3}; !reflect.DeepEqual(report.Types, want) {
+		t.Errorf("Types = %v, want %v", report.Types, want)
+	}
+
+	fields, err := session.ItemFields(findItem(t, session, "GitHub"))
+	if err != nil {
+		t.Fatalf("ItemFields() error = %v", err)
+	}
+	if fields[1].Name != "password" || fields[1].Value != "hunter2" {
+		t.Errorf("Expected the imported password, got %+v", fields)
+	}
+
+	if _, err := session.ImportFrom(ctx, "lastpass", path, io.Discard); err == nil || !strings.Contains(err.Error(), "unknown import format") {
+		t.Errorf("Expected an unknown format to be rejected, got %v", err)
+	}
+}
+
+// findItem returns the listed item named name
+func findItem(t *testing.T, session *ClientSession, name string) *models.Data {
+	t.Helper()
+	items, err := session.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	for i := range items {
+		if items[i].Name == name {
+			return &items[i]
+		}
+	}
+	t.Fatalf("No item named %q", name)
+	return nil
+}
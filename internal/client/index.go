@@ -0,0 +1,278 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	indexFile = ".gophkeeper_index"
+)
+
+// localIndex is the item list without contents, cached for instant startup
+type localIndex struct {
+	SavedAt time.Time     `json:"saved_at"`
+	Items   []models.Data `json:"items"`
+}
+
+// GetIndexPath returns the path to the local item index file
+func GetIndexPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return indexFile
+	}
+	return fmt.Sprintf("%s/%s", homeDir, indexFile)
+}
+
+// SetIndexPath enables the local index of item IDs, names, types and update times.
+// It is encrypted with the vault key and holds no item contents. An empty path disables it.
+func (s *ClientSession) SetIndexPath(path string) {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	s.indexPath = path
+}
+
+// loadIndex decrypts the local index; nil means there is none usable
+func (s *ClientSession) loadIndex() *localIndex {
+	s.indexMu.Lock()
+	path := s.indexPath
+	s.indexMu.Unlock()
+	if path == "" || !s.IsAuthenticated() {
+		return nil
+	}
+
+	encrypted, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Log.Warn("Failed to read index file", zap.Error(err))
+		}
+		return nil
+	}
+
+	decrypted, err := s.cryptoManager.Decrypt(encrypted)
+	if err != nil {
+		logger.Log.Warn("Index file was written under another vault key, ignoring it", zap.Error(err))
+		return nil
+	}
+	var index localIndex
+	if err := json.Unmarshal(decrypted, &index); err != nil {
+		logger.Log.Warn("Failed to parse index file", zap.Error(err))
+		return nil
+	}
+	return &index
+}
+
+// saveIndex replaces the local index with the full item list from the server
+func (s *ClientSession) saveIndex(cryptoManager *crypto.CryptoManager, items []models.Data) {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	if s.indexPath == "" {
+		return
+	}
+
+	index := localIndex{SavedAt: time.Now().UTC(), Items: make([]models.Data, len(items))}
+	for i, item := range items {
+		item.Data = nil
+		item.Metadata = ""
+		index.Items[i] = item
+	}
+
+	payload, err := json.Marshal(index)
+	if err != nil {
+		logger.Log.Warn("Failed to marshal index", zap.Error(err))
+		return
+	}
+	encrypted, err := cryptoManager.Encrypt(payload)
+	if err != nil {
+		logger.Log.Warn("Failed to encrypt index", zap.Error(err))
+		return
+	}
+	if err := os.WriteFile(s.indexPath, encrypted, 0600); err != nil {
+		logger.Log.Warn("Failed to write index file", zap.Error(err))
+	}
+}
+
+// checkKeyAgainstIndex reports whether the candidate key opens the local index.
+// checked is false when there is no index to check against.
+func (s *ClientSession) checkKeyAgainstIndex(cryptoManager *crypto.CryptoManager) (checked bool, err error) {
+	s.indexMu.Lock()
+	path := s.indexPath
+	s.indexMu.Unlock()
+	if path == "" {
+		return false, nil
+	}
+
+	encrypted, err := os.ReadFile(path)
+	if err != nil {
+		return false, nil
+	}
+	if _, err := cryptoManager.Decrypt(encrypted); err != nil {
+		return true, ErrWrongMasterPassword
+	}
+	return true, nil
+}
+
+// isIndexRefreshing reports whether a background refresh is still pending
+func (s *ClientSession) isIndexRefreshing() bool {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	return s.indexRefreshing
+}
+
+// RefreshIndexInBackground fetches the item list after authentication without
+// blocking the prompt; until it completes, list uses the local index. The refresh
+// outlives the command that started it, so cancelling that command keeps it going.
+func (s *ClientSession) RefreshIndexInBackground(ctx context.Context) {
+	cryptoManager := s.cryptoManager
+	if cryptoManager == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	s.indexMu.Lock()
+	s.indexRefreshing = true
+	s.indexMu.Unlock()
+
+	go func() {
+		defer func() {
+			s.indexMu.Lock()
+			s.indexRefreshing = false
+			s.indexMu.Unlock()
+		}()
+
+		items, err := s.cli.GetData(ctx)
+		if err != nil {
+			logger.Log.Warn("Failed to refresh local index", zap.Error(err))
+			return
+		}
+		s.saveIndex(cryptoManager, items)
+	}()
+}
+
+// indexedItems returns the items of the local index in the environment (all for
+// an empty one), or nil when there is no index
+func (s *ClientSession) indexedItems(environment string) ([]models.Data, time.Time, bool) {
+	index := s.loadIndex()
+	if index == nil {
+		return nil, time.Time{}, false
+	}
+	if environment == "" {
+		return index.Items, index.SavedAt, true
+	}
+	var items []models.Data
+	for _, item := range index.Items {
+		if item.Environment == environment {
+			items = append(items, item)
+		}
+	}
+	return items, index.SavedAt, true
+}
+
+// listForDisplay lists items from the server, or from the local index while the
+// first refresh is pending. When the server cannot be reached it falls back to
+// the local store, then the index. The note tells the user when the list may be
+// stale or offline changes are pending.
+func (s *ClientSession) listForDisplay(ctx context.Context, environment string) ([]models.Data, string, error) {
+	if s.isIndexRefreshing() {
+		if items, savedAt, ok := s.indexedItems(environment); ok {
+			return items, fmt.Sprintf("(local index from %s, refreshing in the background)", savedAt.Local().Format("2006-01-02 15:04")), nil
+		}
+	}
+
+	items, err := s.ListFiltered(ctx, models.DataFilter{Environment: environment})
+	if err == nil {
+		return items, s.pendingNote(), nil
+	}
+	if errors.Is(err, ErrNotAuthenticated) {
+		return nil, "", err
+	}
+	if cached, snapshot, ok := s.cachedItems(environment); ok {
+		return cached, offlineNote(snapshot, err), nil
+	}
+	if cached, savedAt, ok := s.indexedItems(environment); ok {
+		return cached, fmt.Sprintf("(could not reach the server, showing local index from %s: %v)", savedAt.Local().Format("2006-01-02 15:04"), err), nil
+	}
+	return nil, "", err
+}
+
+// UnlockCommand opens the vault of the saved session with the master password only.
+// The key is checked against the local index when there is one, so the vault opens
+// without waiting for the server; the index is then refreshed in the background.
+func (s *ClientSession) UnlockCommand(ctx context.Context, config *Config) error {
+	if s.IsAuthenticated() {
+		fmt.Println("Vault is already unlocked")
+		return nil
+	}
+	if config.Token == "" || config.Salt == "" {
+		return fmt.Errorf("no saved session - use 'login' first")
+	}
+
+	saltBytes, err := base64.StdEncoding.DecodeString(config.Salt)
+	if err != nil {
+		return fmt.Errorf("failed to decode salt: %w", err)
+	}
+
+	checkKey := func(cryptoManager *crypto.CryptoManager) error {
+		checked, err := s.checkKeyAgainstIndex(cryptoManager)
+		if !checked {
+			err = s.verifyMasterPassword(ctx, cryptoManager)
+		}
+		return err
+	}
+
+	if config.SessionKeyWrap != nil {
+		cryptoManager, err := rememberedVaultKey(config)
+		if err == nil {
+			err = checkKey(cryptoManager)
+		}
+		if err == nil {
+			s.SetCryptoManager(cryptoManager, "")
+			s.recordEvent(EventUnlock, map[string]string{"username": config.Username, "action": "unlock", "key": "keychain"})
+			s.RefreshIndexInBackground(ctx)
+			fmt.Printf("Vault unlocked for %s with the key remembered in the OS keychain\n", config.Username)
+			return nil
+		}
+		// e.g. the master password was changed on another device
+		logger.Log.Warn("Remembered vault key no longer opens the vault", zap.Error(err))
+		if errors.Is(err, ErrWrongMasterPassword) {
+			if err := ForgetVaultKey(config); err != nil {
+				logger.Log.Warn("Failed to forget the remembered vault key", zap.Error(err))
+			}
+		}
+	}
+
+	masterPassword, ok := readSecret(bufio.NewScanner(os.Stdin), "Enter master password: ")
+	if !ok {
+		return fmt.Errorf("failed to read master password")
+	}
+
+	cryptoManager, err := s.deriveCryptoManager(masterPassword, saltBytes, config.SaltIterations)
+	if err != nil {
+		return fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+
+	if err := checkKey(cryptoManager); err != nil {
+		if errors.Is(err, ErrWrongMasterPassword) {
+			s.recordEvent(EventMasterPasswordFailed, map[string]string{"username": config.Username, "action": "unlock"})
+		}
+		return err
+	}
+
+	s.SetCryptoManager(cryptoManager, masterPassword)
+	s.recordEvent(EventUnlock, map[string]string{"username": config.Username, "action": "unlock"})
+	s.RefreshIndexInBackground(ctx)
+
+	fmt.Printf("Vault unlocked for %s\n", config.Username)
+	return nil
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+)
+
+func TestClientSession_LocalIndex(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "index")
+	session.SetIndexPath(path)
+	items, err := session.List(ctx)
+	if err != nil || len(items) == 0 {
+		t.Fatalf("List() = %d items, %v", len(items), err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the full list to be indexed: %v", err)
+	}
+	if bytes.Contains(raw, []byte(items[0].ID.String())) {
+		t.Error("Index file should be encrypted")
+	}
+
+	index := session.loadIndex()
+	if index == nil || len(index.Items) != len(items) {
+		t.Fatalf("Expected %d indexed items, got %+v", len(items), index)
+	}
+	for _, item := range index.Items {
+		if len(item.Data) != 0 || item.Metadata != "" {
+			t.Errorf("Index must not hold item contents, got %+v", item)
+		}
+		if item.Name == "" || item.Type == "" || item.UpdatedAt.IsZero() {
+			t.Errorf("Expected name, type and update time in the index, got %+v", item)
+		}
+	}
+
+	// the server is unreachable from here on
+	session.cli.httpClient.Transport = &handlerTransport{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})}
+	cached, note, err := session.listForDisplay(ctx, "")
+	if err != nil {
+		t.Fatalf("listForDisplay() error = %v", err)
+	}
+	if len(cached) != len(items) || !strings.Contains(note, "local index") {
+		t.Errorf("Expected %d items from the local index, got %d with note %q", len(items), len(cached), note)
+	}
+}
+
+func TestClientSession_CheckKeyAgainstIndex(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	session.SetIndexPath(filepath.Join(t.TempDir(), "index"))
+	if checked, _ := session.checkKeyAgainstIndex(session.GetCryptoManager()); checked {
+		t.Error("Without an index file nothing can be checked")
+	}
+
+	if _, err := session.List(ctx); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if checked, err := session.checkKeyAgainstIndex(session.GetCryptoManager()); !checked || err != nil {
+		t.Errorf("Expected the vault key to open the index, got %v, %v", checked, err)
+	}
+
+	wrong, err := crypto.NewCryptoManager("another master password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	if checked, err := session.checkKeyAgainstIndex(wrong); !checked || !errors.Is(err, ErrWrongMasterPassword) {
+		t.Errorf("Expected a wrong key to be rejected, got %v, %v", checked, err)
+	}
+}
+
+func TestClientSession_RefreshIndexOutlivesCommand(t *testing.T) {
+	session, _, err := NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "index")
+	session.SetIndexPath(path)
+
+	// the command that started the refresh is cancelled before it runs
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	session.RefreshIndexInBackground(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for session.isIndexRefreshing() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if index := session.loadIndex(); index == nil || len(index.Items) == 0 {
+		t.Errorf("Expected the refresh to complete after the command was cancelled, got %+v", index)
+	}
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// ItemField is a payload field of an item, for front ends that show and edit
+// items field by field
+type ItemField struct {
+	Name     string
+	Value    string
+	Secret   bool
+	Required bool
+}
+
+// EditableTypes are the item types that can be created and edited field by field
+var EditableTypes = []models.DataType{models.DataTypeLoginPassword, models.DataTypeBankCard, models.DataTypeText}
+
+// NewItemFields returns the empty payload fields of a new item of dataType, or
+// nil if the type cannot be edited field by field
+func NewItemFields(dataType models.DataType) []ItemField {
+	var fields []ItemField
+	for _, field := range editableFields[dataType] {
+		fields = append(fields, ItemField{Name: field.name, Secret: field.secret, Required: field.required})
+	}
+	return fields
+}
+
+// ItemFields decrypts the payload of data into fields: the known fields of its
+// type in prompt order, then any other keys sorted by name. A payload that is
+// not a JSON object is returned as a single "data" field.
+func (s *ClientSession) ItemFields(data *models.Data) ([]ItemField, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+
+	decrypted, err := s.cryptoManager.Decrypt(data.Data)
+	if err != nil {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": data.ID.String()})
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(decrypted, &payload); err != nil {
+		return []ItemField{{Name: "data", Value: string(decrypted)}}, nil
+	}
+
+	var fields []ItemField
+	known := make(map[string]bool)
+	for _, field := range editableFields[data.Type] {
+		known[field.name] = true
+		value := ""
+		if v, ok := payload[field.name]; ok && v != nil {
+			value = fmt.Sprint(v)
+		}
+		fields = append(fields, ItemField{Name: field.name, Value: value, Secret: field.secret, Required: field.required})
+	}
+
+	var others []string
+	for name := range payload {
+		if !known[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	for _, name := range others {
+		if payload[name] != nil {
+			fields = append(fields, ItemField{Name: name, Value: fmt.Sprint(payload[name])})
+		}
+	}
+	return fields, nil
+}
+
+// SaveItem encrypts fields into a new item of dataType, or into an edit of data
+// when it is not nil. Empty optional fields are dropped and keys of the current
+// payload that are not among fields are kept. If data was changed on another
+// device meanwhile, the edit is saved as a conflict copy, which is returned
+// together with an error saying so.
+func (s *ClientSession) SaveItem(ctx context.Context, data *models.Data, dataType models.DataType, name, description string, fields []ItemField) (*models.Data, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	payload := make(map[string]interface{})
+	if data != nil {
+		dataType = data.Type
+		decrypted, err := s.cryptoManager.Decrypt(data.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt current data: %w", err)
+		}
+		if err := json.Unmarshal(decrypted, &payload); err != nil {
+			return nil, fmt.Errorf("item %q has no structured fields", data.Name)
+		}
+	}
+	if _, ok := editableFields[dataType]; !ok {
+		return nil, fmt.Errorf("%s items have no editable fields", dataType)
+	}
+
+	for _, field := range fields {
+		switch {
+		case field.Value == "" && field.Required:
+			return nil, fmt.Errorf("%s is required", field.Name)
+		case field.Value == "":
+			delete(payload, field.Name)
+		default:
+			payload[field.Name] = field.Value
+		}
+	}
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data: %w", err)
+	}
+	encryptedContent, err := s.cryptoManager.Encrypt(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+
+	dataReq := models.DataRequest{
+		Type:        dataType,
+		Name:        name,
+		Description: description,
+		Data:        encryptedContent,
+		Metadata:    ItemMetadata(dataType, payload, ""),
+	}
+	if data == nil {
+		return s.Create(ctx, dataReq)
+	}
+
+	dataReq.Environment = data.Environment
+	dataReq.BaseUpdatedAt = &data.UpdatedAt
+	dataReq.BaseRevision = baseRevision(data)
+	updated, err := s.Update(ctx, data.ID.String(), dataReq)
+	if errors.Is(err, ErrConflict) {
+		conflictCopy, err := s.SaveConflictCopy(ctx, data, dataReq)
+		if err != nil {
+			return nil, fmt.Errorf("item was changed on another device and saving a conflict copy failed: %w", err)
+		}
+		return conflictCopy, fmt.Errorf("item was changed on another device meanwhile; your edit was saved as %q", conflictCopy.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update data: %w", err)
+	}
+	return updated, nil
+}
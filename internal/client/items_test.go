@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestClientSession_SaveItem(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	fields := NewItemFields(models.DataTypeLoginPassword)
+	fields[0].Value, fields[1].Value = "admin", "hunter2"
+	created, err := session.SaveItem(ctx, nil, models.DataTypeLoginPassword, "Router", "", fields)
+	if err != nil {
+		t.Fatalf("SaveItem() error = %v", err)
+	}
+	if created.Metadata != "Login: admin, URL: " {
+		t.Errorf("Expected the login in the metadata, got %q", created.Metadata)
+	}
+
+	// a key written by a newer client survives an edit
+	content := []byte(`{"login":"admin","password":"hunter2","totp":"JBSWY3DP"}`)
+	if created.Data, err = session.cryptoManager.Encrypt(content); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if created, err = session.Update(ctx, created.ID.String(), models.DataRequest{Type: created.Type, Name: created.Name, Data: created.Data, BaseRevision: &created.Revision}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	fields, err = session.ItemFields(created)
+	if err != nil {
+		t.Fatalf("ItemFields() error = %v", err)
+	}
+	if len(fields) != 5 || fields[4].Name != "totp" || !fields[1].Secret {
+		t.Fatalf("Expected the known fields, then the unknown one, got %+v", fields)
+	}
+
+	fields[1].Value = "hunter3"
+	updated, err := session.SaveItem(ctx, created, "", "Router", "Home", fields[:4])
+	if err != nil {
+		t.Fatalf("SaveItem() error = %v", err)
+	}
+	fields, _ = session.ItemFields(updated)
+	if fields[1].Value != "hunter3" || len(fields) != 5 || updated.Description != "Home" {
+		t.Errorf("Expected the edit with the unknown field kept, got %+v", fields)
+	}
+
+	// saving the stale version again becomes a conflict copy
+	conflictCopy, err := session.SaveItem(ctx, created, "", "Router", "", fields[:4])
+	if err == nil || !strings.Contains(err.Error(), "changed on another device") || conflictCopy == nil {
+		t.Errorf("Expected a conflict copy, got %v, %v", conflictCopy, err)
+	}
+
+	fields[0].Value = ""
+	if _, err := session.SaveItem(ctx, updated, "", "Router", "", fields[:4]); err == nil || !strings.Contains(err.Error(), "login is required") {
+		t.Errorf("Expected a required field error, got %v", err)
+	}
+}
@@ -0,0 +1,422 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// In-cluster service account locations
+const (
+	serviceAccountDir       = "/var/run/secrets/kubernetes.io/serviceaccount"
+	k8sManagedByLabel       = "app.kubernetes.io/managed-by"
+	k8sManagedByValue       = "gophkeeper"
+	k8sItemIDAnnotation     = "gophkeeper.io/item-id"
+	k8sItemUpdateAnnotation = "gophkeeper.io/item-updated-at"
+	// DefaultK8sSyncInterval is how often items are synced without a change
+	// event
+	DefaultK8sSyncInterval = 5 * time.Minute
+)
+
+var (
+	invalidSecretNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+	invalidSecretKeyChars  = regexp.MustCompile(`[^-._a-zA-Z0-9]+`)
+)
+
+// K8sSecret is the subset of the Kubernetes Secret object used by the sync
+type K8sSecret struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   K8sObjectMeta     `json:"metadata"`
+	Type       string            `json:"type,omitempty"`
+	Data       map[string][]byte `json:"data"`
+}
+
+// K8sObjectMeta is the subset of Kubernetes object metadata used by the sync
+type K8sObjectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+// K8sClient writes Secrets through the Kubernetes REST API
+type K8sClient struct {
+	apiServer  string
+	token      string
+	namespace  string
+	httpClient *http.Client
+}
+
+// NewK8sClient creates a Kubernetes client for a single namespace
+func NewK8sClient(apiServer, token, namespace string, httpClient *http.Client) *K8sClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &K8sClient{
+		apiServer:  strings.TrimRight(apiServer, "/"),
+		token:      token,
+		namespace:  namespace,
+		httpClient: httpClient,
+	}
+}
+
+// NewInClusterK8sClient creates a Kubernetes client from the pod service account.
+// An empty namespace selects the pod's own namespace.
+func NewInClusterK8sClient(namespace string) (*K8sClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster: KUBERNETES_SERVICE_HOST/PORT not set")
+	}
+
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	if namespace == "" {
+		ns, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service account namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+
+	caCert, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse cluster CA certificate")
+	}
+
+	httpClient := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+	}
+	return NewK8sClient("https://"+host+":"+port, strings.TrimSpace(string(token)), namespace, httpClient), nil
+}
+
+// ApplySecret creates the Secret or replaces the existing one
+func (k *K8sClient) ApplySecret(ctx context.Context, secret *K8sSecret) error {
+	secret.APIVersion = "v1"
+	secret.Kind = "Secret"
+	secret.Metadata.Namespace = k.namespace
+	if secret.Type == "" {
+		secret.Type = "Opaque"
+	}
+
+	collection := k.collection()
+	var existing K8sSecret
+	status, err := k.do(ctx, http.MethodGet, collection+"/"+secret.Metadata.Name, nil, &existing)
+	if err != nil && status != http.StatusNotFound {
+		return err
+	}
+
+	if status == http.StatusNotFound {
+		_, err = k.do(ctx, http.MethodPost, collection, secret, nil)
+		return err
+	}
+
+	if existing.Metadata.Labels[k8sManagedByLabel] != k8sManagedByValue {
+		return fmt.Errorf("secret %s/%s exists and is not managed by gophkeeper", k.namespace, secret.Metadata.Name)
+	}
+	secret.Metadata.ResourceVersion = existing.Metadata.ResourceVersion
+	_, err = k.do(ctx, http.MethodPut, collection+"/"+secret.Metadata.Name, secret, nil)
+	return err
+}
+
+// ListSecrets lists the Secrets of the namespace managed by gophkeeper
+func (k *K8sClient) ListSecrets(ctx context.Context) ([]K8sSecret, error) {
+	var list struct {
+		Items []K8sSecret `json:"items"`
+	}
+	selector := url.QueryEscape(k8sManagedByLabel + "=" + k8sManagedByValue)
+	if _, err := k.do(ctx, http.MethodGet, k.collection()+"?labelSelector="+selector, nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// DeleteSecret deletes a Secret; one that is already gone is not an error
+func (k *K8sClient) DeleteSecret(ctx context.Context, name string) error {
+	status, err := k.do(ctx, http.MethodDelete, k.collection()+"/"+name, nil, nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// collection returns the URL of the Secrets of the namespace
+func (k *K8sClient) collection() string {
+	return fmt.Sprintf("%s/api/v1/namespaces/%s/secrets", k.apiServer, k.namespace)
+}
+
+// do sends a request to the API server and decodes the response into out if given
+func (k *K8sClient) do(ctx context.Context, method, url string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+k.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("kubernetes request failed: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("kubernetes API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// K8sSecretName converts an item name into a valid Kubernetes object name
+func K8sSecretName(name string) string {
+	name = strings.ToLower(CleanQuotes(name))
+	name = strings.ReplaceAll(name, "_", "-")
+	name = invalidSecretNameChars.ReplaceAllString(name, "-")
+	name = strings.Trim(name, "-")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-")
+	}
+	return name
+}
+
+// K8sSyncer maps vault items with one tag to Secrets, re-applying only changed
+// items and deleting the Secrets of items that are gone or lost the tag
+type K8sSyncer struct {
+	session *ClientSession
+	k8s     *K8sClient
+	tag     string
+	synced  map[string]time.Time
+}
+
+// NewK8sSyncer creates a syncer for items with the given tag.
+// The vault is only ever read; all writes go to the cluster.
+func NewK8sSyncer(session *ClientSession, k8s *K8sClient, tag string) *K8sSyncer {
+	return &K8sSyncer{
+		session: session,
+		k8s:     k8s,
+		tag:     strings.ToLower(strings.TrimSpace(tag)),
+		synced:  make(map[string]time.Time),
+	}
+}
+
+// SyncOnce applies all new or changed items, then deletes the managed Secrets
+// no item maps to. An item that fails is logged and skipped, so one bad item
+// does not hold back the others; the failures are returned together with the
+// number of Secrets written.
+func (k *K8sSyncer) SyncOnce(ctx context.Context) (int, error) {
+	items, err := k.session.ListFiltered(ctx, models.DataFilter{Tag: k.tag})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list vault items: %w", err)
+	}
+
+	var errs []error
+	applied := 0
+	wanted := make(map[string]string, len(items))
+	for i := range items {
+		item := &items[i]
+		id := item.ID.String()
+		wanted[id] = K8sSecretName(item.Name)
+		if last, ok := k.synced[id]; ok && !item.UpdatedAt.After(last) {
+			continue
+		}
+
+		if err := k.apply(ctx, item); err != nil {
+			logger.Log.Error("Failed to sync item", zap.Error(err), zap.String("data_id", id))
+			errs = append(errs, err)
+			continue
+		}
+		k.synced[id] = item.UpdatedAt
+		applied++
+	}
+
+	for id := range k.synced {
+		if _, ok := wanted[id]; !ok {
+			delete(k.synced, id)
+		}
+	}
+	if err := k.deleteOrphans(ctx, wanted); err != nil {
+		errs = append(errs, err)
+	}
+	return applied, errors.Join(errs...)
+}
+
+// apply writes the Secret of one item
+func (k *K8sSyncer) apply(ctx context.Context, item *models.Data) error {
+	secret, err := k.secretFromItem(item)
+	if err != nil {
+		return err
+	}
+	if err := k.k8s.ApplySecret(ctx, secret); err != nil {
+		return fmt.Errorf("failed to apply secret %s: %w", secret.Metadata.Name, err)
+	}
+	return nil
+}
+
+// deleteOrphans deletes the managed Secrets whose item is not among wanted,
+// or that an item no longer maps to after it was renamed
+func (k *K8sSyncer) deleteOrphans(ctx context.Context, wanted map[string]string) error {
+	secrets, err := k.k8s.ListSecrets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	var errs []error
+	for _, secret := range secrets {
+		if name, ok := wanted[secret.Metadata.Annotations[k8sItemIDAnnotation]]; ok && name == secret.Metadata.Name {
+			continue
+		}
+		if err := k.k8s.DeleteSecret(ctx, secret.Metadata.Name); err != nil {
+			logger.Log.Error("Failed to delete orphaned secret", zap.Error(err), zap.String("secret", secret.Metadata.Name))
+			errs = append(errs, fmt.Errorf("failed to delete secret %s: %w", secret.Metadata.Name, err))
+			continue
+		}
+		fmt.Printf("Deleted orphaned secret %s\n", secret.Metadata.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// Run syncs immediately, then again whenever the vault changes, until ctx is
+// cancelled. Change events drive the sync; every interval it also runs
+// without one, which covers servers that do not stream change events. Run
+// notifies when syncing starts failing and when it recovers.
+func (k *K8sSyncer) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	changed := make(chan struct{}, 1)
+	k.session.WatchChanges(ctx, &sync.Mutex{}, func(context.Context) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	failing := false
+	for {
+		applied, err := k.SyncOnce(ctx)
+		if err != nil {
+			logger.Log.Error("Kubernetes sync failed", zap.Error(err))
+			fmt.Printf("Sync failed: %v\n", err)
+			if !failing {
+				k.session.NotifyResult("Kubernetes sync", "", err)
+			}
+		} else {
+			if applied > 0 {
+				fmt.Printf("Synced %d secret(s) to namespace %s\n", applied, k.k8s.namespace)
+			}
+			if failing {
+				k.session.NotifyResult("Kubernetes sync", "Sync recovered", nil)
+			}
+		}
+		failing = err != nil
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// secretFromItem decrypts an item into a Secret with one key per field
+func (k *K8sSyncer) secretFromItem(item *models.Data) (*K8sSecret, error) {
+	name := K8sSecretName(item.Name)
+	if name == "" {
+		return nil, fmt.Errorf("item %s has no name usable as a secret name", item.ID)
+	}
+
+	decrypted, err := k.session.cryptoManager.Decrypt(item.Data)
+	if err != nil {
+		if errors.Is(err, crypto.ErrWrongKey) {
+			k.session.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": item.ID.String()})
+		}
+		return nil, fmt.Errorf("failed to decrypt item %s: %w", item.ID, err)
+	}
+
+	data := make(map[string][]byte)
+	if item.Type == models.DataTypeBinary {
+		var binaryData models.BinaryData
+		key := "content"
+		if err := json.Unmarshal([]byte(item.Metadata), &binaryData); err == nil && binaryData.FileName != "" {
+			key = invalidSecretKeyChars.ReplaceAllString(binaryData.FileName, "_")
+		}
+		data[key] = decrypted
+	} else {
+		var payload map[string]interface{}
+		if err := json.Unmarshal(decrypted, &payload); err != nil {
+			data["content"] = decrypted
+		}
+		for field, value := range payload {
+			str := fmt.Sprint(value)
+			if str == "" {
+				continue
+			}
+			data[invalidSecretKeyChars.ReplaceAllString(field, "_")] = []byte(str)
+		}
+	}
+
+	return &K8sSecret{
+		Metadata: K8sObjectMeta{
+			Name:   name,
+			Labels: map[string]string{k8sManagedByLabel: k8sManagedByValue},
+			Annotations: map[string]string{
+				k8sItemIDAnnotation:     item.ID.String(),
+				k8sItemUpdateAnnotation: item.UpdatedAt.UTC().Format(time.RFC3339),
+			},
+		},
+		Data: data,
+	}, nil
+}
@@ -0,0 +1,341 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// fakeK8sAPI is a minimal in-memory Secrets API
+type fakeK8sAPI struct {
+	mutex   sync.Mutex
+	secrets map[string]K8sSecret
+	writes  int
+}
+
+func (f *fakeK8sAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer k8s-token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	const prefix = "/api/v1/namespaces/apps/secrets"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		if name == "" {
+			selector := r.URL.Query().Get("labelSelector")
+			var list struct {
+				Items []K8sSecret `json:"items"`
+			}
+			for _, secret := range f.secrets {
+				if selector == k8sManagedByLabel+"="+secret.Metadata.Labels[k8sManagedByLabel] {
+					list.Items = append(list.Items, secret)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(list)
+			return
+		}
+		secret, ok := f.secrets[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(secret)
+	case http.MethodPost, http.MethodPut:
+		var secret K8sSecret
+		if err := json.NewDecoder(r.Body).Decode(&secret); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		secret.Metadata.ResourceVersion = "1"
+		f.secrets[secret.Metadata.Name] = secret
+		f.writes++
+		_ = json.NewEncoder(w).Encode(secret)
+	case http.MethodDelete:
+		if _, ok := f.secrets[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(f.secrets, name)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestK8sSecretName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "DB_PASSWORD", want: "db-password"},
+		{input: "Demo Email", want: "demo-email"},
+		{input: "\"Stripe API key!\"", want: "stripe-api-key"},
+		{input: "!!!", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := K8sSecretName(tt.input); got != tt.want {
+				t.Errorf("K8sSecretName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// createK8sItem creates a login item with the given tags
+func createK8sItem(t *testing.T, session *ClientSession, name, password string, tags ...string) *models.Data {
+	t.Helper()
+	payload, _ := json.Marshal(models.LoginPasswordData{Login: "app", Password: password})
+	encrypted, err := session.GetCryptoManager().Encrypt(payload)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	item, err := session.Create(context.Background(), models.DataRequest{
+		Type: models.DataTypeLoginPassword,
+		Name: name,
+		Data: encrypted,
+		Tags: &tags,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return item
+}
+
+func TestK8sSyncer_SyncOnce(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	item := createK8sItem(t, session, "DB_PASSWORD", "s3cret", "k8s")
+	createK8sItem(t, session, "OTHER_PASSWORD", "other", "prod")
+
+	managed := map[string]string{k8sManagedByLabel: k8sManagedByValue}
+	api := &fakeK8sAPI{secrets: map[string]K8sSecret{
+		"unmanaged": {Metadata: K8sObjectMeta{Name: "unmanaged"}},
+		"stale": {Metadata: K8sObjectMeta{Name: "stale", Labels: managed,
+			Annotations: map[string]string{k8sItemIDAnnotation: "00000000-0000-0000-0000-000000000001"}}},
+	}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	syncer := NewK8sSyncer(session, NewK8sClient(server.URL, "k8s-token", "apps", server.Client()), "K8S")
+
+	applied, err := syncer.SyncOnce(ctx)
+	if err != nil {
+		t.Fatalf("SyncOnce() error = %v", err)
+	}
+	if applied != 1 {
+		t.Fatalf("Expected 1 secret applied, got %d", applied)
+	}
+
+	secret, ok := api.secrets["db-password"]
+	if !ok {
+		t.Fatal("Expected secret db-password to be created")
+	}
+	if string(secret.Data["password"]) != "s3cret" || string(secret.Data["login"]) != "app" {
+		t.Errorf("Unexpected secret data: %v", secret.Data)
+	}
+	if secret.Metadata.Annotations[k8sItemIDAnnotation] != item.ID.String() {
+		t.Errorf("Expected item ID annotation %s, got %v", item.ID, secret.Metadata.Annotations)
+	}
+	if _, ok := api.secrets["other-password"]; ok {
+		t.Error("Items without the tag should not be synced")
+	}
+	if _, ok := api.secrets["stale"]; ok {
+		t.Error("Expected the orphaned managed secret to be deleted")
+	}
+	if _, ok := api.secrets["unmanaged"]; !ok {
+		t.Error("Secrets not managed by gophkeeper should be kept")
+	}
+
+	applied, err = syncer.SyncOnce(ctx)
+	if err != nil {
+		t.Fatalf("SyncOnce() error = %v", err)
+	}
+	if applied != 0 || api.writes != 1 {
+		t.Errorf("Unchanged items should not be re-applied, applied=%d writes=%d", applied, api.writes)
+	}
+
+	payload, _ := json.Marshal(models.LoginPasswordData{Login: "app", Password: "rotated"})
+	encrypted, _ := session.GetCryptoManager().Encrypt(payload)
+	updated, err := session.Update(ctx, item.ID.String(), models.DataRequest{
+		Type:         models.DataTypeLoginPassword,
+		Name:         item.Name,
+		Data:         encrypted,
+		BaseRevision: &item.Revision,
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	applied, err = syncer.SyncOnce(ctx)
+	if err != nil {
+		t.Fatalf("SyncOnce() error = %v", err)
+	}
+	if applied != 1 || string(api.secrets["db-password"].Data["password"]) != "rotated" {
+		t.Errorf("Expected changed item to be re-applied, applied=%d data=%v", applied, api.secrets["db-password"].Data)
+	}
+
+	untagged := []string{}
+	if _, err := session.Update(ctx, item.ID.String(), models.DataRequest{
+		Type:         models.DataTypeLoginPassword,
+		Name:         item.Name,
+		Data:         encrypted,
+		Tags:         &untagged,
+		BaseRevision: &updated.Revision,
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := syncer.SyncOnce(ctx); err != nil {
+		t.Fatalf("SyncOnce() error = %v", err)
+	}
+	if _, ok := api.secrets["db-password"]; ok {
+		t.Error("Expected the secret of an item that lost the tag to be deleted")
+	}
+}
+
+func TestK8sSyncer_SyncOnce_ItemFailures(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	securityLog, err := NewSecurityLog(filepath.Join(t.TempDir(), "security.log"))
+	if err != nil {
+		t.Fatalf("NewSecurityLog() error = %v", err)
+	}
+	session.SetSecurityLog(securityLog)
+
+	// the items are created as another client would, without the checks of
+	// the session
+	tags := []string{"k8s"}
+	if _, err := session.cli.CreateData(ctx, models.DataRequest{
+		Type: models.DataTypeText, Name: "malformed", Data: []byte("not encrypted"), Tags: &tags,
+	}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	createK8sItem(t, session, "DB_PASSWORD", "s3cret", "k8s")
+
+	api := &fakeK8sAPI{secrets: map[string]K8sSecret{}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	syncer := NewK8sSyncer(session, NewK8sClient(server.URL, "k8s-token", "apps", server.Client()), "k8s")
+
+	applied, err := syncer.SyncOnce(ctx)
+	if err == nil {
+		t.Fatal("Expected the failure of the malformed item to be returned")
+	}
+	if applied != 1 {
+		t.Errorf("Expected the other item to be applied after the failure, applied=%d", applied)
+	}
+	if _, ok := api.secrets["db-password"]; !ok {
+		t.Error("Expected secret db-password to be created")
+	}
+	events, err := ReadSecurityLog(securityLog.path)
+	if err != nil {
+		t.Fatalf("ReadSecurityLog() error = %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Malformed data is not a key failure, got events %+v", events)
+	}
+
+	other, err := crypto.NewCryptoManager("another master password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	foreign, err := other.Encrypt([]byte(`{"password":"x"}`))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if _, err := session.cli.CreateData(ctx, models.DataRequest{
+		Type: models.DataTypeLoginPassword, Name: "foreign", Data: foreign, Tags: &tags,
+	}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := syncer.SyncOnce(ctx); !errors.Is(err, crypto.ErrWrongKey) {
+		t.Fatalf("Expected ErrWrongKey, got %v", err)
+	}
+	events, err = ReadSecurityLog(securityLog.path)
+	if err != nil {
+		t.Fatalf("ReadSecurityLog() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Event != EventMasterPasswordFailed {
+		t.Errorf("Expected one %s event for the wrong key, got %+v", EventMasterPasswordFailed, events)
+	}
+}
+
+func TestK8sSyncer_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	api := &fakeK8sAPI{secrets: map[string]K8sSecret{}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	syncer := NewK8sSyncer(session, NewK8sClient(server.URL, "k8s-token", "apps", server.Client()), "k8s")
+
+	done := make(chan error, 1)
+	go func() { done <- syncer.Run(ctx, time.Hour) }()
+
+	// the syncer follows change events in the background, so keep creating
+	// items until one arrives without waiting for the interval
+	deadline := time.After(5 * time.Second)
+	for synced := false; !synced; {
+		createK8sItem(t, session, "DB_PASSWORD_"+strconv.FormatInt(time.Now().UnixNano(), 10), "s3cret", "k8s")
+		select {
+		case <-deadline:
+			t.Fatal("Expected a change event to sync the new item")
+		case <-time.After(50 * time.Millisecond):
+		}
+		api.mutex.Lock()
+		synced = len(api.secrets) > 0
+		api.mutex.Unlock()
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestK8sClient_ApplySecret_Unmanaged(t *testing.T) {
+	api := &fakeK8sAPI{secrets: map[string]K8sSecret{
+		"db-password": {Metadata: K8sObjectMeta{Name: "db-password"}},
+	}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	k8s := NewK8sClient(server.URL, "k8s-token", "apps", server.Client())
+	err := k8s.ApplySecret(context.Background(), &K8sSecret{Metadata: K8sObjectMeta{Name: "db-password"}})
+	if err == nil {
+		t.Fatal("Expected error when overwriting an unmanaged secret")
+	}
+	if api.writes != 0 {
+		t.Errorf("Unmanaged secret should not be written, writes=%d", api.writes)
+	}
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/zalando/go-keyring"
+	"go.uber.org/zap"
+)
+
+const (
+	// keychainService names the GophKeeper entries in the OS keychain
+	keychainService = "gophkeeper"
+	// keychainEnv turns the OS keychain off when set to "off", e.g. on
+	// headless machines where every access would fail anyway
+	keychainEnv = "GOPHKEEPER_KEYCHAIN"
+
+	// TokenStoreKeychain marks a config whose session token is kept in the
+	// OS keychain (macOS Keychain, Windows Credential Manager or libsecret)
+	TokenStoreKeychain = "keychain"
+
+	keychainTokenEntry    = "token"
+	keychainVaultKeyEntry = "vault-key"
+)
+
+// keychainEnabled reports whether the OS keychain may be used
+func keychainEnabled() bool {
+	return os.Getenv(keychainEnv) != "off"
+}
+
+// keychainAccount names the entry of a kind for the current config file, so
+// separate homes or users never share a session
+func keychainAccount(entry string) string {
+	return entry + ":" + GetConfigPath()
+}
+
+// storeToken moves the session token of config into the OS keychain and
+// reports whether it did; otherwise it stays in the config file
+func storeToken(config *Config) bool {
+	if config.Token == "" {
+		deleteKeychainEntry(keychainTokenEntry)
+		return false
+	}
+	if !keychainEnabled() {
+		return false
+	}
+	if err := keyring.Set(keychainService, keychainAccount(keychainTokenEntry), config.Token); err != nil {
+		logger.Log.Warn("OS keychain unavailable, saving the session token in the config file", zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// loadToken fills in the session token of a config that keeps it in the OS keychain
+func loadToken(config *Config) {
+	if config.TokenStore != TokenStoreKeychain {
+		return
+	}
+	if !keychainEnabled() {
+		logger.Log.Warn("Session token is in the OS keychain, which is turned off; log in again")
+		return
+	}
+	token, err := keyring.Get(keychainService, keychainAccount(keychainTokenEntry))
+	if err != nil {
+		logger.Log.Warn("Failed to read the session token from the OS keychain; log in again", zap.Error(err))
+		return
+	}
+	config.Token = token
+}
+
+// deleteKeychainEntry removes an entry, which may not exist
+func deleteKeychainEntry(entry string) {
+	if !keychainEnabled() {
+		return
+	}
+	if err := keyring.Delete(keychainService, keychainAccount(entry)); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		logger.Log.Warn("Failed to delete OS keychain entry", zap.Error(err), zap.String("entry", entry))
+	}
+}
+
+// RememberVaultKey keeps the open vault key in the OS keychain so 'unlock'
+// needs no master password. The key is stored sealed under a wrapping key
+// kept in the config file, so neither the keychain entry nor the file alone
+// opens the vault.
+func (s *ClientSession) RememberVaultKey(config *Config) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	if !keychainEnabled() {
+		return fmt.Errorf("the OS keychain is turned off (%s=off)", keychainEnv)
+	}
+
+	wrapKey, err := crypto.GenerateDataKey()
+	if err != nil {
+		return err
+	}
+	sealed, err := crypto.SealWithKey(wrapKey, s.cryptoManager.Key())
+	if err != nil {
+		return err
+	}
+	if err := keyring.Set(keychainService, keychainAccount(keychainVaultKeyEntry), base64.StdEncoding.EncodeToString(sealed)); err != nil {
+		return fmt.Errorf("failed to store the vault key in the OS keychain: %w", err)
+	}
+
+	config.SessionKeyWrap = wrapKey
+	if err := SaveConfig(config); err != nil {
+		deleteKeychainEntry(keychainVaultKeyEntry)
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
+
+// ForgetVaultKey removes a remembered vault key
+func ForgetVaultKey(config *Config) error {
+	deleteKeychainEntry(keychainVaultKeyEntry)
+	if config.SessionKeyWrap == nil {
+		return nil
+	}
+	config.SessionKeyWrap = nil
+	return SaveConfig(config)
+}
+
+// rememberedVaultKey opens the vault key remembered by RememberVaultKey
+func rememberedVaultKey(config *Config) (*crypto.CryptoManager, error) {
+	if config.SessionKeyWrap == nil || !keychainEnabled() {
+		return nil, fmt.Errorf("no vault key remembered")
+	}
+	encoded, err := keyring.Get(keychainService, keychainAccount(keychainVaultKeyEntry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the vault key from the OS keychain: %w", err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt vault key in the OS keychain: %w", err)
+	}
+	key, err := crypto.OpenWithKey(config.SessionKeyWrap, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap the remembered vault key: %w", err)
+	}
+	salt, err := base64.StdEncoding.DecodeString(config.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode salt: %w", err)
+	}
+	cryptoManager, err := crypto.NewCryptoManagerWithKey(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	cryptoManager.SetIterations(config.SaltIterations)
+	return cryptoManager, nil
+}
+
+// KeychainCommand shows where the session is kept, or remembers or forgets
+// the vault key in the OS keychain
+func (s *ClientSession) KeychainCommand(config *Config, args []string) error {
+	if len(args) == 0 || args[0] == "status" {
+		switch {
+		case !keychainEnabled():
+			fmt.Printf("OS keychain: off (%s=off), session token in %s\n", keychainEnv, GetConfigPath())
+		case config.TokenStore == TokenStoreKeychain:
+			fmt.Println("OS keychain: session token stored there")
+		default:
+			fmt.Printf("OS keychain: not used, session token in %s\n", GetConfigPath())
+		}
+		if config.SessionKeyWrap != nil {
+			fmt.Println("Vault key: remembered, 'unlock' needs no master password")
+		} else {
+			fmt.Println("Vault key: not remembered")
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "remember":
+		if err := s.RememberVaultKey(config); err != nil {
+			return err
+		}
+		fmt.Println("Vault key remembered in the OS keychain; 'unlock' will not ask for the master password")
+		return nil
+	case "forget":
+		if err := ForgetVaultKey(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Println("Vault key forgotten; 'unlock' asks for the master password again")
+		return nil
+	default:
+		return fmt.Errorf("unknown keychain action: %s (use status, remember or forget)", args[0])
+	}
+}
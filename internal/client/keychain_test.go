@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestSaveConfig_Keychain(t *testing.T) {
+	tests := []struct {
+		name         string
+		setup        func(t *testing.T)
+		wantInFile   bool
+		wantStoreTag string
+	}{
+		{name: "keychain available", setup: func(t *testing.T) { keyring.MockInit() }, wantStoreTag: TokenStoreKeychain},
+		{name: "keychain unavailable", setup: func(t *testing.T) {
+			keyring.MockInitWithError(errors.New("no secret service"))
+		}, wantInFile: true},
+		{name: "keychain turned off", setup: func(t *testing.T) {
+			keyring.MockInit()
+			t.Setenv(keychainEnv, "off")
+		}, wantInFile: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			tt.setup(t)
+
+			config := &Config{ServerURL: "http://localhost:8080", Token: "session-token", Salt: "salt"}
+			if err := SaveConfig(config); err != nil {
+				t.Fatalf("SaveConfig() error = %v", err)
+			}
+			data, err := os.ReadFile(GetConfigPath())
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if got := strings.Contains(string(data), "session-token"); got != tt.wantInFile {
+				t.Errorf("Token in config file = %v, want %v: %s", got, tt.wantInFile, data)
+			}
+			if config.TokenStore != tt.wantStoreTag {
+				t.Errorf("TokenStore = %q, want %q", config.TokenStore, tt.wantStoreTag)
+			}
+
+			if loaded := NewConfig(); loaded.Token != "session-token" {
+				t.Errorf("Expected the token to load again, got %q", loaded.Token)
+			}
+		})
+	}
+}
+
+func TestSaveConfig_KeychainLogout(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keyring.MockInit()
+
+	config := &Config{Token: "session-token"}
+	if err := SaveConfig(config); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+	config.Token = ""
+	if err := SaveConfig(config); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	if _, err := keyring.Get(keychainService, keychainAccount(keychainTokenEntry)); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("Expected the keychain entry to be deleted, got %v", err)
+	}
+	if loaded := NewConfig(); loaded.Token != "" || loaded.TokenStore != "" {
+		t.Errorf("Expected no session, got token %q in %q", loaded.Token, loaded.TokenStore)
+	}
+}
+
+func TestClientSession_RememberVaultKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keyring.MockInit()
+	ctx := context.Background()
+	session, config, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	if err := session.RememberVaultKey(config); err != nil {
+		t.Fatalf("RememberVaultKey() error = %v", err)
+	}
+	stored, err := keyring.Get(keychainService, keychainAccount(keychainVaultKeyEntry))
+	if err != nil {
+		t.Fatalf("Expected the vault key in the keychain, got %v", err)
+	}
+	if strings.Contains(stored, string(session.GetCryptoManager().Key())) {
+		t.Error("Expected the vault key to be stored wrapped")
+	}
+
+	session.Lock()
+	if err := session.UnlockCommand(ctx, config); err != nil {
+		t.Fatalf("UnlockCommand() error = %v", err)
+	}
+	if !session.IsAuthenticated() {
+		t.Fatal("Expected the remembered key to unlock the vault")
+	}
+	if _, err := session.List(ctx); err != nil {
+		t.Errorf("List() error = %v", err)
+	}
+	if !session.isCurrentMasterPassword(DemoMasterPassword) || session.isCurrentMasterPassword("wrong password") {
+		t.Error("Expected the master password to be checked against the remembered key")
+	}
+
+	if err := ForgetVaultKey(config); err != nil {
+		t.Fatalf("ForgetVaultKey() error = %v", err)
+	}
+	if _, err := keyring.Get(keychainService, keychainAccount(keychainVaultKeyEntry)); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("Expected the vault key to be deleted, got %v", err)
+	}
+	if _, err := rememberedVaultKey(config); err == nil {
+		t.Error("Expected no remembered key after forgetting it")
+	}
+}
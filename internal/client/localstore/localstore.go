@@ -0,0 +1,203 @@
+// Package localstore keeps an encrypted copy of the whole vault on the client,
+// together with the changes made while the server could not be reached, so
+// items can be read and edited offline and reconciled later.
+package localstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrWrongKey is returned when the store was written under another vault key
+var ErrWrongKey = errors.New("local store was written under another vault key")
+
+// Kinds of pending changes
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Change is an item change made offline, waiting to be sent to the server
+type Change struct {
+	Op string    `json:"op"`
+	ID uuid.UUID `json:"id"`
+	// Request is the new item content of a create or update
+	Request models.DataRequest `json:"request"`
+	// BaseUpdatedAt is the UpdatedAt of the server version the change was made
+	// on; zero for items created offline
+	BaseUpdatedAt time.Time `json:"base_updated_at,omitempty"`
+	QueuedAt      time.Time `json:"queued_at"`
+}
+
+// Snapshot is the server state as of the last sync plus the pending changes
+type Snapshot struct {
+	SyncedAt time.Time     `json:"synced_at"`
+	Items    []models.Data `json:"items"`
+	Pending  []Change      `json:"pending,omitempty"`
+}
+
+// Store is the encrypted local store file
+type Store struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// New creates a store kept at path
+func New(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the store file path
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Load decrypts the store. A missing file yields an empty snapshot.
+func (s *Store) Load(cryptoManager *crypto.CryptoManager) (*Snapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	encrypted, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local store: %w", err)
+	}
+
+	decrypted, err := cryptoManager.Decrypt(encrypted)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(decrypted, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse local store: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Save encrypts and writes the snapshot, replacing the file atomically
+func (s *Store) Save(cryptoManager *crypto.CryptoManager, snapshot *Snapshot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal local store: %w", err)
+	}
+	encrypted, err := cryptoManager.Encrypt(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt local store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write local store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(encrypted); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write local store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write local store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write local store: %w", err)
+	}
+	return nil
+}
+
+// View returns the items as they look with the pending changes applied,
+// ordered like the server orders them, newest update first
+func (s *Snapshot) View() []models.Data {
+	items := make([]models.Data, len(s.Items))
+	copy(items, s.Items)
+
+	for _, change := range s.Pending {
+		index := -1
+		for i := range items {
+			if items[i].ID == change.ID {
+				index = i
+				break
+			}
+		}
+
+		switch change.Op {
+		case OpCreate:
+			items = append(items, itemFromRequest(change.ID, change.Request, change.QueuedAt, change.QueuedAt))
+		case OpUpdate:
+			if index >= 0 {
+				items[index] = itemFromRequest(change.ID, change.Request, items[index].CreatedAt, change.QueuedAt)
+			}
+		case OpDelete:
+			if index >= 0 {
+				items = append(items[:index], items[index+1:]...)
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].UpdatedAt.After(items[j].UpdatedAt) })
+	return items
+}
+
+// Find returns the item with id as it looks with the pending changes applied
+func (s *Snapshot) Find(id uuid.UUID) (*models.Data, bool) {
+	for _, item := range s.View() {
+		if item.ID == id {
+			return &item, true
+		}
+	}
+	return nil, false
+}
+
+// Queue records a change made offline. Later changes to an item are folded
+// into its pending one, so the server only sees the final state, checked
+// against the version the first change was based on.
+func (s *Snapshot) Queue(change Change) {
+	for i := range s.Pending {
+		pending := &s.Pending[i]
+		if pending.ID != change.ID {
+			continue
+		}
+		if change.Op == OpDelete && pending.Op == OpCreate {
+			s.Pending = append(s.Pending[:i], s.Pending[i+1:]...)
+			return
+		}
+		switch change.Op {
+		case OpDelete:
+			pending.Op = OpDelete
+			pending.Request = models.DataRequest{}
+		default:
+			pending.Request = change.Request
+		}
+		pending.QueuedAt = change.QueuedAt
+		return
+	}
+	s.Pending = append(s.Pending, change)
+}
+
+func itemFromRequest(id uuid.UUID, req models.DataRequest, createdAt, updatedAt time.Time) models.Data {
+	return models.Data{
+		ID:          id,
+		Type:        req.Type,
+		Name:        req.Name,
+		Description: req.Description,
+		Data:        req.Data,
+		Metadata:    req.Metadata,
+		Environment: req.Environment,
+		CreatedAt:   createdAt,
+		UpdatedAt:   updatedAt,
+	}
+}
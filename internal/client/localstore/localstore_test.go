@@ -0,0 +1,83 @@
+package localstore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestStore_LoadSave(t *testing.T) {
+	cryptoManager, err := crypto.NewCryptoManager("master-password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	store := New(filepath.Join(t.TempDir(), "cache"))
+
+	snapshot, err := store.Load(cryptoManager)
+	if err != nil || len(snapshot.Items) != 0 || !snapshot.SyncedAt.IsZero() {
+		t.Fatalf("Expected an empty snapshot without a file, got %+v, %v", snapshot, err)
+	}
+
+	snapshot.Items = []models.Data{{ID: uuid.New(), Name: "Mail", Data: []byte("sealed")}}
+	snapshot.SyncedAt = time.Now().UTC()
+	if err := store.Save(cryptoManager, snapshot); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := store.Load(cryptoManager)
+	if err != nil || len(loaded.Items) != 1 || loaded.Items[0].Name != "Mail" {
+		t.Errorf("Expected the saved item back, got %+v, %v", loaded, err)
+	}
+
+	other, err := crypto.NewCryptoManager("other-password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	if _, err := store.Load(other); err != ErrWrongKey {
+		t.Errorf("Load() with another key error = %v, want %v", err, ErrWrongKey)
+	}
+}
+
+func TestSnapshot_Queue(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	kept := models.Data{ID: uuid.New(), Name: "Kept", CreatedAt: base, UpdatedAt: base}
+	edited := models.Data{ID: uuid.New(), Name: "Edited", CreatedAt: base, UpdatedAt: base}
+	snapshot := &Snapshot{Items: []models.Data{kept, edited}}
+
+	created := uuid.New()
+	later := base.Add(time.Hour)
+	snapshot.Queue(Change{Op: OpCreate, ID: created, Request: models.DataRequest{Name: "New"}, QueuedAt: later})
+	snapshot.Queue(Change{Op: OpUpdate, ID: created, Request: models.DataRequest{Name: "New, renamed"}, QueuedAt: later})
+	snapshot.Queue(Change{Op: OpUpdate, ID: edited.ID, Request: models.DataRequest{Name: "Edited once"}, BaseUpdatedAt: base, QueuedAt: later})
+	snapshot.Queue(Change{Op: OpUpdate, ID: edited.ID, Request: models.DataRequest{Name: "Edited twice"}, BaseUpdatedAt: later, QueuedAt: later})
+
+	if len(snapshot.Pending) != 2 {
+		t.Fatalf("Expected changes to fold into one per item, got %+v", snapshot.Pending)
+	}
+	if change := snapshot.Pending[1]; change.Request.Name != "Edited twice" || !change.BaseUpdatedAt.Equal(base) {
+		t.Errorf("Expected the last edit based on the synced version, got %+v", change)
+	}
+
+	view := snapshot.View()
+	if len(view) != 3 || view[len(view)-1].Name != "Kept" {
+		t.Errorf("Expected changed items first and the untouched one last, got %+v", view)
+	}
+	if item, ok := snapshot.Find(created); !ok || item.Name != "New, renamed" {
+		t.Errorf("Find() = %+v, %v", item, ok)
+	}
+
+	snapshot.Queue(Change{Op: OpDelete, ID: created, QueuedAt: later})
+	snapshot.Queue(Change{Op: OpDelete, ID: kept.ID, BaseUpdatedAt: base, QueuedAt: later})
+	if len(snapshot.Pending) != 2 || snapshot.Pending[1].Op != OpDelete {
+		t.Errorf("Expected an offline item to vanish and a synced one to be deleted, got %+v", snapshot.Pending)
+	}
+	if _, ok := snapshot.Find(kept.ID); ok {
+		t.Error("Deleted item should not be visible")
+	}
+	if len(snapshot.View()) != 1 {
+		t.Errorf("Expected only the edited item left, got %+v", snapshot.View())
+	}
+}
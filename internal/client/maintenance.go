@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// MaintenanceError is returned when the server rejects a change because it is in maintenance mode
+type MaintenanceError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *MaintenanceError) Error() string {
+	msg := "server is in maintenance mode, your data can be read but not changed"
+	if e.Message != "" {
+		msg = "server is in maintenance mode: " + e.Message
+	}
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s; please retry in %s", msg, e.RetryAfter)
+	}
+	return msg + "; please retry later"
+}
+
+// Is makes errors.Is match maintenance mode with ErrServerUnavailable
+func (e *MaintenanceError) Is(target error) bool {
+	return target == ErrServerUnavailable
+}
+
+// IsMaintenance reports whether err was caused by server maintenance mode
+func IsMaintenance(err error) bool {
+	var maintenanceErr *MaintenanceError
+	return errors.As(err, &maintenanceErr)
+}
+
+// maintenanceError returns a *MaintenanceError if the response is a maintenance mode rejection
+func maintenanceError(resp *http.Response, body []byte) error {
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+
+	var errResp models.ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error != "maintenance" {
+		return nil
+	}
+
+	maintenanceErr := &MaintenanceError{Message: errResp.Message}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		maintenanceErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return maintenanceErr
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+func TestClient_MaintenanceMode(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	router := mux.NewRouter()
+	server.RegisterRoutes(router, store, store, auth.NewJWTManager("test-secret", time.Hour), server.Options{})
+	maintenance := middleware.NewMaintenance(false, "", server.MaintenanceExemptPaths...)
+
+	cli := NewClient("http://maintenance.invalid")
+	cli.httpClient.Transport = &handlerTransport{handler: maintenance.Handler(router)}
+
+	resp, err := cli.Register(ctx, "reader", "password", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	cli.SetToken(resp.Token)
+
+	maintenance.Enable("nightly backup", 5*time.Minute)
+
+	if _, err := cli.GetData(ctx); err != nil {
+		t.Errorf("GetData() should work during maintenance, error = %v", err)
+	}
+
+	_, err = cli.CreateData(ctx, models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x")})
+	if !IsMaintenance(err) {
+		t.Fatalf("Expected maintenance error, got %v", err)
+	}
+	want := "server is in maintenance mode: nightly backup; please retry in 5m0s"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	if err := cli.DeleteData(ctx, "00000000-0000-0000-0000-000000000000"); !IsMaintenance(err) {
+		t.Errorf("Expected maintenance error from DeleteData, got %v", err)
+	}
+
+	maintenance.Disable()
+	if _, err := cli.CreateData(ctx, models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x")}); err != nil {
+		t.Errorf("CreateData() after maintenance error = %v", err)
+	}
+}
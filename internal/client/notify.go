@@ -0,0 +1,121 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"go.uber.org/zap"
+)
+
+// notificationTitle prefixes the title of every notification
+const notificationTitle = "GophKeeper"
+
+// windowsToastScript shows a toast with the title and message passed in the environment,
+// so that neither needs escaping for PowerShell
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:GOPHKEEPER_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:GOPHKEEPER_NOTIFY_MESSAGE)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('GophKeeper').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+
+// Notifier reports the outcome of long-running operations, e.g. when the user
+// has switched away from the terminal
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// DesktopNotifier shows native desktop notifications through notify-send on Linux,
+// osascript on macOS and a PowerShell toast on Windows
+type DesktopNotifier struct{}
+
+// Notify implements Notifier
+func (DesktopNotifier) Notify(title, message string) error {
+	cmd := desktopNotifyCommand(runtime.GOOS, title, message)
+	if cmd == nil {
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", cmd.Path, err, output)
+	}
+	return nil
+}
+
+// desktopNotifyCommand builds the notification command for an operating system.
+// Title and message are passed as arguments or environment, never as script source.
+func desktopNotifyCommand(goos, title, message string) *exec.Cmd {
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return exec.Command("notify-send", "--app-name", notificationTitle, title, message)
+	case "darwin":
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message)
+	case "windows":
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+		cmd.Env = append(os.Environ(), "GOPHKEEPER_NOTIFY_TITLE="+title, "GOPHKEEPER_NOTIFY_MESSAGE="+message)
+		return cmd
+	default:
+		return nil
+	}
+}
+
+// SetNotifier sets where the outcome of long-running operations is reported; nil disables it
+func (s *ClientSession) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// NotifyResult reports that a long-running operation completed with summary or failed with err.
+// Notifications never contain secret values.
+func (s *ClientSession) NotifyResult(operation, summary string, err error) {
+	if s.notifier == nil {
+		return
+	}
+
+	title := fmt.Sprintf("%s: %s finished", notificationTitle, operation)
+	message := summary
+	if err != nil {
+		title = fmt.Sprintf("%s: %s failed", notificationTitle, operation)
+		message = err.Error()
+	}
+
+	if err := s.notifier.Notify(title, message); err != nil {
+		logger.Log.Warn("Failed to show notification", zap.Error(err), zap.String("operation", operation))
+	}
+}
+
+// NotifyCommand enables, disables or tests desktop notifications for long operations
+func (s *ClientSession) NotifyCommand(config *Config, args []string) error {
+	if len(args) == 0 {
+		if config.Notifications {
+			fmt.Println("Desktop notifications: on")
+		} else {
+			fmt.Println("Desktop notifications: off")
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "on", "off":
+		config.Notifications = args[0] == "on"
+		if err := SaveConfig(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		if config.Notifications {
+			s.SetNotifier(DesktopNotifier{})
+		} else {
+			s.SetNotifier(nil)
+		}
+		fmt.Printf("Desktop notifications turned %s\n", args[0])
+		return nil
+	case "test":
+		return DesktopNotifier{}.Notify(notificationTitle, "Notifications are working")
+	default:
+		return fmt.Errorf("unknown notify action: %s (use on, off or test)", args[0])
+	}
+}
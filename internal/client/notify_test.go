@@ -0,0 +1,76 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recordingNotifier records notifications instead of showing them
+type recordingNotifier struct {
+	titles   []string
+	messages []string
+}
+
+func (r *recordingNotifier) Notify(title, message string) error {
+	r.titles = append(r.titles, title)
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func TestDesktopNotifyCommand(t *testing.T) {
+	title, message := `Done "now"`, `it's $(rm -rf ~) finished`
+
+	tests := []struct {
+		goos string
+		name string
+	}{
+		{goos: "linux", name: "notify-send"},
+		{goos: "darwin", name: "osascript"},
+		{goos: "windows", name: "powershell"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			cmd := desktopNotifyCommand(tt.goos, title, message)
+			if cmd == nil {
+				t.Fatal("Expected a notification command")
+			}
+			if cmd.Args[0] != tt.name {
+				t.Errorf("Expected %s, got %s", tt.name, cmd.Args[0])
+			}
+
+			// title and message must be passed verbatim, never spliced into a script
+			args := strings.Join(cmd.Args, "\n")
+			env := strings.Join(cmd.Env, "\n")
+			if !strings.Contains(args+env, message) || strings.Contains(windowsToastScript, message) {
+				t.Errorf("Message not passed verbatim: args=%q", cmd.Args)
+			}
+		})
+	}
+
+	if cmd := desktopNotifyCommand("plan9", title, message); cmd != nil {
+		t.Errorf("Expected no command for unsupported systems, got %v", cmd.Args)
+	}
+}
+
+func TestClientSession_NotifyResult(t *testing.T) {
+	session := NewClientSession(NewClient("http://localhost:8080"))
+
+	// without a notifier nothing happens
+	session.NotifyResult("scan", "done", nil)
+
+	notifier := &recordingNotifier{}
+	session.SetNotifier(notifier)
+	session.NotifyResult("scan", "2 stored secret(s) found", nil)
+	session.NotifyResult("Kubernetes sync", "", errors.New("connection refused"))
+
+	if len(notifier.titles) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(notifier.titles))
+	}
+	if notifier.titles[0] != "GophKeeper: scan finished" || notifier.messages[0] != "2 stored secret(s) found" {
+		t.Errorf("Unexpected success notification %q: %q", notifier.titles[0], notifier.messages[0])
+	}
+	if notifier.titles[1] != "GophKeeper: Kubernetes sync failed" || notifier.messages[1] != "connection refused" {
+		t.Errorf("Unexpected failure notification %q: %q", notifier.titles[1], notifier.messages[1])
+	}
+}
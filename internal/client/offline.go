@@ -0,0 +1,355 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/client/localstore"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	localStoreFile = ".gophkeeper_cache"
+)
+
+// GetLocalStorePath returns the path to the local store of item contents
+func GetLocalStorePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return localStoreFile
+	}
+	return fmt.Sprintf("%s/%s", homeDir, localStoreFile)
+}
+
+// SetLocalStorePath enables the local store: a copy of all items, encrypted with
+// the vault key, that list and get fall back to offline, and that keeps changes
+// made offline until 'sync'. An empty path disables it.
+func (s *ClientSession) SetLocalStorePath(path string) {
+	if path == "" {
+		s.localStore = nil
+		return
+	}
+	s.localStore = localstore.New(path)
+}
+
+// isOffline reports whether err means the server could not be reached at all,
+// as opposed to the server rejecting the request
+func isOffline(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && !errors.Is(err, context.Canceled)
+}
+
+// loadSnapshot decrypts the local store; nil means there is none usable
+func (s *ClientSession) loadSnapshot() *localstore.Snapshot {
+	if s.localStore == nil || !s.IsAuthenticated() {
+		return nil
+	}
+	snapshot, err := s.localStore.Load(s.cryptoManager)
+	if err != nil {
+		logger.Log.Warn("Ignoring local store", zap.Error(err))
+		return nil
+	}
+	return snapshot
+}
+
+// saveSnapshot writes the local store; failures only cost offline access
+func (s *ClientSession) saveSnapshot(snapshot *localstore.Snapshot) {
+	if err := s.localStore.Save(s.cryptoManager, snapshot); err != nil {
+		logger.Log.Warn("Failed to save local store", zap.Error(err))
+	}
+}
+
+// cacheItems replaces the server state in the local store with the full item
+// list, keeping the changes not synced yet
+func (s *ClientSession) cacheItems(items []models.Data) {
+	if s.localStore == nil {
+		return
+	}
+	snapshot := s.loadSnapshot()
+	if snapshot == nil {
+		// written under another vault key: its pending changes cannot be sent anyway
+		snapshot = &localstore.Snapshot{}
+	}
+	snapshot.Items = items
+	snapshot.SyncedAt = time.Now().UTC()
+	s.saveSnapshot(snapshot)
+}
+
+// cachedItems returns the items of the local store in the environment (all for
+// an empty one) with offline changes applied, or false when there is no store
+func (s *ClientSession) cachedItems(environment string) ([]models.Data, *localstore.Snapshot, bool) {
+	snapshot := s.loadSnapshot()
+	if snapshot == nil || (snapshot.SyncedAt.IsZero() && len(snapshot.Pending) == 0) {
+		return nil, nil, false
+	}
+	var items []models.Data
+	for _, item := range snapshot.View() {
+		if environment == "" || item.Environment == environment {
+			items = append(items, item)
+		}
+	}
+	return items, snapshot, true
+}
+
+// offlineNote tells the user that items come from the local store
+func offlineNote(snapshot *localstore.Snapshot, err error) string {
+	note := fmt.Sprintf("(could not reach the server, showing local copy from %s", snapshot.SyncedAt.Local().Format("2006-01-02 15:04"))
+	if len(snapshot.Pending) > 0 {
+		note += fmt.Sprintf(" with %d offline changes", len(snapshot.Pending))
+	}
+	return note + fmt.Sprintf(": %v)", err)
+}
+
+// pendingNote reminds the user of offline changes not sent to the server yet
+func (s *ClientSession) pendingNote() string {
+	snapshot := s.loadSnapshot()
+	if snapshot == nil || len(snapshot.Pending) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("(%d offline changes not synced yet, run 'sync' to send them)", len(snapshot.Pending))
+}
+
+// getForDisplay gets an item from the server, or from the local store when the
+// server cannot be reached or the item was created offline and not synced yet
+func (s *ClientSession) getForDisplay(ctx context.Context, id string) (*models.Data, string, error) {
+	data, err := s.Get(ctx, id)
+	if err == nil || errors.Is(err, ErrNotAuthenticated) {
+		return data, "", err
+	}
+
+	fullID, resolveErr := s.resolveID(ctx, id)
+	if resolveErr != nil {
+		return nil, "", err
+	}
+	itemID, parseErr := uuid.Parse(fullID)
+	snapshot := s.loadSnapshot()
+	if parseErr != nil || snapshot == nil {
+		return nil, "", err
+	}
+	cached, ok := snapshot.Find(itemID)
+	if !ok {
+		return nil, "", err
+	}
+	if isOffline(err) {
+		return cached, offlineNote(snapshot, err), nil
+	}
+	for _, change := range snapshot.Pending {
+		if change.Op == localstore.OpCreate && change.ID == itemID {
+			return cached, "(created offline, run 'sync' to send it to the server)", nil
+		}
+	}
+	return nil, "", err
+}
+
+// queueOffline keeps a change made while the server could not be reached
+func (s *ClientSession) queueOffline(change localstore.Change) error {
+	snapshot := s.loadSnapshot()
+	if snapshot == nil {
+		return fmt.Errorf("no local store to keep the change in")
+	}
+	change.QueuedAt = time.Now().UTC()
+	snapshot.Queue(change)
+	if err := s.localStore.Save(s.cryptoManager, snapshot); err != nil {
+		return err
+	}
+	return nil
+}
+
+// queueOfflineDelete keeps the deletion of the item with id made offline
+func (s *ClientSession) queueOfflineDelete(id string) error {
+	itemID, err := uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+	snapshot := s.loadSnapshot()
+	if snapshot == nil {
+		return fmt.Errorf("no local store to keep the change in")
+	}
+	item, ok := snapshot.Find(itemID)
+	if !ok {
+		return fmt.Errorf("item %s is not in the local store", id)
+	}
+	return s.queueOffline(localstore.Change{Op: localstore.OpDelete, ID: itemID, BaseUpdatedAt: item.UpdatedAt})
+}
+
+// SyncResult summarizes a sync
+type SyncResult struct {
+	Sent      int
+	Conflicts []string
+	Failed    []string
+	Cached    int
+}
+
+// Sync sends the changes made offline to the server in order, then refreshes
+// the local store. Edits to items changed on another device meanwhile are kept
+// as conflict copies; deletes of such items are skipped. Failed changes stay
+// pending. If the server cannot be reached, the remaining changes stay pending
+// and the error is returned.
+func (s *ClientSession) Sync(ctx context.Context) (*SyncResult, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	if s.localStore == nil {
+		return nil, fmt.Errorf("local store is disabled")
+	}
+	snapshot, err := s.localStore.Load(s.cryptoManager)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{}
+	var remaining []localstore.Change
+	for i, change := range snapshot.Pending {
+		err := s.pushChange(ctx, snapshot, change)
+		switch {
+		case err == nil:
+			result.Sent++
+		case errors.Is(err, ErrConflict):
+			result.Conflicts = append(result.Conflicts, fmt.Sprintf("%s %s: %v", change.Op, changeName(snapshot, change), err))
+		case isOffline(err) || ctx.Err() != nil:
+			snapshot.Pending = append(remaining, snapshot.Pending[i:]...)
+			s.saveSnapshot(snapshot)
+			return result, fmt.Errorf("sync stopped with %d changes left: %w", len(snapshot.Pending), err)
+		default:
+			remaining = append(remaining, change)
+			result.Failed = append(result.Failed, fmt.Sprintf("%s %s: %v", change.Op, changeName(snapshot, change), err))
+		}
+	}
+	snapshot.Pending = remaining
+
+	items, err := s.cli.GetData(ctx)
+	if err != nil {
+		s.saveSnapshot(snapshot)
+		return result, fmt.Errorf("failed to refresh the local store: %w", err)
+	}
+	snapshot.Items = items
+	snapshot.SyncedAt = time.Now().UTC()
+	if err := s.localStore.Save(s.cryptoManager, snapshot); err != nil {
+		return result, err
+	}
+	s.saveIndex(s.cryptoManager, items)
+	result.Cached = len(items)
+	return result, nil
+}
+
+// pushChange sends one offline change to the server, comparing update times
+// with the version the change was based on
+func (s *ClientSession) pushChange(ctx context.Context, snapshot *localstore.Snapshot, change localstore.Change) error {
+	id := change.ID.String()
+	switch change.Op {
+	case localstore.OpCreate:
+		dataReq := change.Request
+		dataReq.BaseUpdatedAt = nil
+		if err := s.withChecksum(&dataReq); err != nil {
+			return err
+		}
+		if err := s.withDomains(&dataReq); err != nil {
+			return err
+		}
+		s.withCardExpiry(&dataReq)
+		_, err := s.cli.CreateData(ctx, dataReq)
+		return err
+
+	case localstore.OpUpdate:
+		dataReq := change.Request
+		dataReq.BaseUpdatedAt = &change.BaseUpdatedAt
+		if err := s.keepSharedDataKey(ctx, id, &dataReq); err != nil {
+			return err
+		}
+		if err := s.withChecksum(&dataReq); err != nil {
+			return err
+		}
+		if err := s.withDomains(&dataReq); err != nil {
+			return err
+		}
+		s.withCardExpiry(&dataReq)
+		_, err := s.cli.UpdateData(ctx, id, dataReq)
+		if err == nil {
+			return nil
+		}
+		var original *models.Data
+		switch {
+		case errors.Is(err, ErrConflict):
+			if original, err = s.cli.GetDataByID(ctx, id); err != nil {
+				return err
+			}
+		case errors.Is(err, ErrNotFound):
+			original = cachedItem(snapshot, change.ID)
+		default:
+			return err
+		}
+		conflictCopy, err := s.SaveConflictCopy(ctx, original, dataReq)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w, your edit was kept as %q", ErrConflict, conflictCopy.Name)
+
+	case localstore.OpDelete:
+		current, err := s.cli.GetDataByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+		if current.UpdatedAt.After(change.BaseUpdatedAt) {
+			return fmt.Errorf("%w, so it was not deleted", ErrConflict)
+		}
+		return s.cli.DeleteData(ctx, id)
+	}
+	return fmt.Errorf("unknown change %q", change.Op)
+}
+
+// cachedItem returns the server version of an item as of the last sync
+func cachedItem(snapshot *localstore.Snapshot, id uuid.UUID) *models.Data {
+	for i := range snapshot.Items {
+		if snapshot.Items[i].ID == id {
+			return &snapshot.Items[i]
+		}
+	}
+	return &models.Data{ID: id}
+}
+
+// changeName names the item of a change in sync reports
+func changeName(snapshot *localstore.Snapshot, change localstore.Change) string {
+	if change.Request.Name != "" {
+		return fmt.Sprintf("%q", change.Request.Name)
+	}
+	if item := cachedItem(snapshot, change.ID); item.Name != "" {
+		return fmt.Sprintf("%q", item.Name)
+	}
+	return change.ID.String()
+}
+
+// SyncCommand handles sending offline changes and refreshing the local store
+func (s *ClientSession) SyncCommand(ctx context.Context) error {
+	result, err := s.Sync(ctx)
+	if result != nil {
+		for _, conflict := range result.Conflicts {
+			fmt.Printf("  conflict: %s\n", conflict)
+		}
+		for _, failed := range result.Failed {
+			fmt.Printf("  failed: %s\n", failed)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Sync complete: %d changes sent, %d conflicts, %d failed; %d items available offline\n",
+		result.Sent, len(result.Conflicts), len(result.Failed), result.Cached)
+	if len(result.Conflicts) > 0 {
+		fmt.Println("Run 'conflicts' to review and merge.")
+	}
+	if len(result.Failed) > 0 {
+		fmt.Println("Failed changes are kept; run 'sync' again to retry them.")
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/client/localstore"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+// offlineTransport fails every request as if the server were unreachable
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("dial tcp: connection refused")
+}
+
+func TestClientSession_Offline(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cache")
+	session.SetLocalStorePath(path)
+	items, err := session.List(ctx)
+	if err != nil || len(items) < 2 {
+		t.Fatalf("List() = %d items, %v", len(items), err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the items to be stored locally: %v", err)
+	}
+	if strings.Contains(string(raw), items[0].Name) {
+		t.Error("Local store should be encrypted")
+	}
+
+	online := session.cli.httpClient.Transport
+	session.cli.httpClient.Transport = offlineTransport{}
+
+	cached, note, err := session.listForDisplay(ctx, "")
+	if err != nil || len(cached) != len(items) || !strings.Contains(note, "local copy") {
+		t.Fatalf("Expected %d items from the local store, got %d, %q, %v", len(items), len(cached), note, err)
+	}
+	edited, note, err := session.getForDisplay(ctx, items[0].ID.String()[:8])
+	if err != nil || string(edited.Data) != string(items[0].Data) || note == "" {
+		t.Fatalf("Expected the contents from the local store, got %+v, %q, %v", edited, note, err)
+	}
+
+	created := uuid.New()
+	sealed, err := session.cryptoManager.Encrypt([]byte("note"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if err := session.queueOffline(localstore.Change{Op: localstore.OpCreate, ID: created,
+		Request: models.DataRequest{Type: models.DataTypeText, Name: "Written offline", Data: sealed}}); err != nil {
+		t.Fatalf("queueOffline() error = %v", err)
+	}
+	dataReq := models.DataRequest{Type: edited.Type, Name: "Edited offline", Data: edited.Data, Metadata: edited.Metadata,
+		BaseUpdatedAt: &edited.UpdatedAt}
+	if err := session.saveUpdate(ctx, edited, dataReq, nil, io.Discard); err != nil {
+		t.Fatalf("saveUpdate() offline error = %v", err)
+	}
+	if err := session.queueOfflineDelete(items[1].ID.String()); err != nil {
+		t.Fatalf("queueOfflineDelete() error = %v", err)
+	}
+
+	cached, note, _ = session.listForDisplay(ctx, "")
+	if len(cached) != len(items) || !strings.Contains(note, "3 offline changes") {
+		t.Errorf("Expected offline changes in the list, got %d items, %q", len(cached), note)
+	}
+	if _, err := session.Sync(ctx); err == nil || !isOffline(err) {
+		t.Errorf("Expected sync to stop while offline, got %v", err)
+	}
+
+	session.cli.httpClient.Transport = online
+	if _, note, _ := session.listForDisplay(ctx, ""); !strings.Contains(note, "run 'sync'") {
+		t.Errorf("Expected a reminder of pending changes, got %q", note)
+	}
+	result, err := session.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if result.Sent != 3 || len(result.Conflicts) != 0 || len(result.Failed) != 0 || result.Cached != len(items) {
+		t.Errorf("Unexpected sync result %+v", result)
+	}
+
+	names := make(map[string]bool)
+	server, err := session.cli.GetData(ctx)
+	if err != nil {
+		t.Fatalf("GetData() error = %v", err)
+	}
+	for _, item := range server {
+		names[item.Name] = true
+	}
+	if !names["Written offline"] || !names["Edited offline"] || names[items[0].Name] || names[items[1].Name] {
+		t.Errorf("Expected the offline changes on the server, got %v", names)
+	}
+	if note := session.pendingNote(); note != "" {
+		t.Errorf("Expected nothing pending after sync, got %q", note)
+	}
+}
+
+func TestClientSession_SyncConflicts(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	session.SetLocalStorePath(filepath.Join(t.TempDir(), "cache"))
+	items, err := session.List(ctx)
+	if err != nil || len(items) < 2 {
+		t.Fatalf("List() = %d items, %v", len(items), err)
+	}
+
+	online := session.cli.httpClient.Transport
+	session.cli.httpClient.Transport = offlineTransport{}
+	edit := items[0]
+	dataReq := models.DataRequest{Type: edit.Type, Name: "Edited offline", Data: edit.Data, Metadata: edit.Metadata}
+	if err := session.saveUpdate(ctx, &edit, dataReq, nil, io.Discard); err != nil {
+		t.Fatalf("saveUpdate() offline error = %v", err)
+	}
+	if err := session.queueOfflineDelete(items[1].ID.String()); err != nil {
+		t.Fatalf("queueOfflineDelete() error = %v", err)
+	}
+
+	// another device changes both items meanwhile
+	session.cli.httpClient.Transport = online
+	for _, item := range items[:2] {
+		changed := models.DataRequest{Type: item.Type, Name: item.Name, Description: "changed elsewhere", Data: item.Data, Metadata: item.Metadata, BaseRevision: &item.Revision}
+		if _, err := session.cli.UpdateData(ctx, item.ID.String(), changed); err != nil {
+			t.Fatalf("UpdateData() error = %v", err)
+		}
+	}
+
+	result, err := session.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if result.Sent != 0 || len(result.Conflicts) != 2 {
+		t.Fatalf("Expected two conflicts, got %+v", result)
+	}
+
+	server, err := session.cli.GetData(ctx)
+	if err != nil {
+		t.Fatalf("GetData() error = %v", err)
+	}
+	var kept, copied bool
+	for _, item := range server {
+		if item.ID == items[1].ID {
+			kept = true
+		}
+		if strings.HasPrefix(item.Name, items[0].Name+" (conflict copy from ") {
+			copied = true
+		}
+	}
+	if !kept {
+		t.Error("An item changed elsewhere must not be deleted")
+	}
+	if !copied {
+		t.Error("Expected the offline edit to be kept as a conflict copy")
+	}
+}
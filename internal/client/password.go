@@ -0,0 +1,184 @@
+package client
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+const (
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars  = "0123456789"
+	symbolChars = "!@#$%^&*()-_=+[]{};:,.?"
+)
+
+// GeneratePassword generates a random password containing every character class
+func GeneratePassword(length int) (string, error) {
+	classes := []string{lowerChars, upperChars, digitChars, symbolChars}
+	if length < len(classes) {
+		return "", fmt.Errorf("password length must be at least %d", len(classes))
+	}
+
+	alphabet := strings.Join(classes, "")
+	password := make([]byte, length)
+	for i := range password {
+		charset := alphabet
+		if i < len(classes) {
+			charset = classes[i]
+		}
+		c, err := randomChar(charset)
+		if err != nil {
+			return "", err
+		}
+		password[i] = c
+	}
+
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("failed to shuffle password: %w", err)
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+
+	return string(password), nil
+}
+
+// randomChar picks a uniformly random character from charset
+func randomChar(charset string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random character: %w", err)
+	}
+	return charset[n.Int64()], nil
+}
+
+// EstimateEntropy estimates password entropy in bits from its length and character classes
+func EstimateEntropy(password string) float64 {
+	if password == "" {
+		return 0
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	for _, r := range password {
+		switch {
+		case strings.ContainsRune(lowerChars, r):
+			hasLower = true
+		case strings.ContainsRune(upperChars, r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			hasSymbol = true
+		default:
+			hasOther = true
+		}
+	}
+
+	poolSize := 0
+	if hasLower {
+		poolSize += len(lowerChars)
+	}
+	if hasUpper {
+		poolSize += len(upperChars)
+	}
+	if hasDigit {
+		poolSize += len(digitChars)
+	}
+	if hasSymbol {
+		poolSize += 33
+	}
+	if hasOther {
+		poolSize += 100
+	}
+
+	return float64(len([]rune(password))) * math.Log2(float64(poolSize))
+}
+
+// EntropyRating returns a human readable strength rating for entropy bits
+func EntropyRating(bits float64) string {
+	switch {
+	case bits < 40:
+		return "weak"
+	case bits < 60:
+		return "fair"
+	case bits < 80:
+		return "strong"
+	default:
+		return "very strong"
+	}
+}
+
+// EntropyMeter renders entropy as a bar, e.g. "[########--] 82 bits (very strong)"
+func EntropyMeter(bits float64) string {
+	const width = 10
+	filled := int(math.Min(bits/10, width))
+	return fmt.Sprintf("[%s%s] %.0f bits (%s)",
+		strings.Repeat("#", filled), strings.Repeat("-", width-filled), bits, EntropyRating(bits))
+}
+
+// commonPasswords are frequent passwords and words that guessing attacks try first
+var commonPasswords = map[string]bool{
+	"password": true, "passw0rd": true, "123456": true, "12345678": true, "123456789": true, "1234567890": true,
+	"qwerty": true, "qwertyuiop": true, "asdfgh": true, "zxcvbn": true, "abc123": true, "111111": true,
+	"letmein": true, "welcome": true, "monkey": true, "dragon": true, "master": true, "login": true,
+	"admin": true, "administrator": true, "root": true, "secret": true, "iloveyou": true, "sunshine": true,
+	"princess": true, "football": true, "baseball": true, "shadow": true, "superman": true, "trustno1": true,
+	"changeme": true, "default": true, "guest": true, "test": true, "summer": true, "winter": true,
+	"spring": true, "autumn": true, "hello": true, "freedom": true, "whatever": true, "starwars": true,
+}
+
+// PasswordAnalysis is the estimated strength of a password and what weakens it
+type PasswordAnalysis struct {
+	Bits     float64
+	Rating   string
+	Warnings []string
+}
+
+// Weak reports whether the password should be replaced
+func (a PasswordAnalysis) Weak() bool {
+	return a.Rating == "weak"
+}
+
+// AnalyzePassword estimates the entropy of a password like EstimateEntropy but
+// discounts what guessing attacks try first: common passwords with a few
+// characters appended, repeated characters and runs such as "abcd" or "1234"
+func AnalyzePassword(password string) PasswordAnalysis {
+	var warnings []string
+	runes := []rune(password)
+	if len(runes) < 12 {
+		warnings = append(warnings, "shorter than 12 characters")
+	}
+
+	// characters repeating or continuing a sequence add almost nothing
+	predictable := 0
+	for i := 1; i < len(runes); i++ {
+		if step := runes[i] - runes[i-1]; step >= -1 && step <= 1 {
+			predictable++
+		}
+	}
+	if predictable > 0 && predictable*3 >= len(runes) {
+		warnings = append(warnings, "repeated characters or sequences like abc or 123")
+	}
+
+	bits := EstimateEntropy(password)
+	if len(runes) > 0 {
+		bits *= float64(len(runes)-predictable) / float64(len(runes))
+	}
+
+	base := strings.ToLower(strings.TrimRightFunc(password, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+	base = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "@", "a", "$", "s").Replace(base)
+	if commonPasswords[base] || commonPasswords[strings.ToLower(password)] {
+		warnings = append(warnings, "a common password, tried first by attackers")
+		// a dictionary word plus a few guessable characters
+		bits = math.Min(bits, 20+float64(len(runes)-len([]rune(base)))*3)
+	}
+
+	return PasswordAnalysis{Bits: bits, Rating: EntropyRating(bits), Warnings: warnings}
+}
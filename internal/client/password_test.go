@@ -0,0 +1,102 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	password, err := GeneratePassword(20)
+	if err != nil {
+		t.Fatalf("GeneratePassword() error = %v", err)
+	}
+
+	if len(password) != 20 {
+		t.Errorf("Expected length 20, got %d", len(password))
+	}
+
+	for _, charset := range []string{lowerChars, upperChars, digitChars, symbolChars} {
+		if !strings.ContainsAny(password, charset) {
+			t.Errorf("Password %q misses characters from %q", password, charset)
+		}
+	}
+
+	other, err := GeneratePassword(20)
+	if err != nil {
+		t.Fatalf("GeneratePassword() error = %v", err)
+	}
+	if password == other {
+		t.Error("Expected different passwords")
+	}
+
+	if _, err := GeneratePassword(3); err == nil {
+		t.Error("Expected error for too short password")
+	}
+}
+
+func TestEstimateEntropy(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		wantRating string
+	}{
+		{name: "empty", password: "", wantRating: "weak"},
+		{name: "short digits", password: "1234", wantRating: "weak"},
+		{name: "lowercase words", password: "correcthorse", wantRating: "fair"},
+		{name: "mixed", password: "Tr0ub4dor&3", wantRating: "strong"},
+		{name: "long mixed", password: "x7#Kp2!qLm9$Vw4@Zr", wantRating: "very strong"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rating := EntropyRating(EstimateEntropy(tt.password))
+			if rating != tt.wantRating {
+				t.Errorf("EntropyRating(EstimateEntropy(%q)) = %s, want %s", tt.password, rating, tt.wantRating)
+			}
+		})
+	}
+}
+
+func TestEntropyMeter(t *testing.T) {
+	meter := EntropyMeter(45)
+	if meter != "[####------] 45 bits (fair)" {
+		t.Errorf("Unexpected meter %q", meter)
+	}
+
+	if meter := EntropyMeter(250); !strings.HasPrefix(meter, "[##########]") {
+		t.Errorf("Expected full meter, got %q", meter)
+	}
+}
+
+func TestAnalyzePassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		wantWeak   bool
+		wantRating string
+		wantWarn   string
+	}{
+		{name: "common password", password: "password", wantWeak: true, wantWarn: "common password"},
+		{name: "common password with suffix", password: "P@ssw0rd2024!", wantWeak: true, wantWarn: "common password"},
+		{name: "sequence", password: "abcdefgh12345678", wantWeak: true, wantWarn: "sequences"},
+		{name: "repeated", password: "aaaaaaaaaaaaaaaa", wantWeak: true, wantWarn: "repeated"},
+		{name: "short", password: "x7#Kp2", wantWeak: true, wantWarn: "shorter than 12"},
+		{name: "passphrase", password: "correct-horse-battery-staple", wantRating: "very strong"},
+		{name: "random", password: "x7#Kp2!qLm9$Vw4@Zr", wantRating: "very strong"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := AnalyzePassword(tt.password)
+			if analysis.Weak() != tt.wantWeak {
+				t.Errorf("AnalyzePassword(%q).Weak() = %v (%.0f bits), want %v", tt.password, analysis.Weak(), analysis.Bits, tt.wantWeak)
+			}
+			if tt.wantRating != "" && analysis.Rating != tt.wantRating {
+				t.Errorf("AnalyzePassword(%q).Rating = %s, want %s", tt.password, analysis.Rating, tt.wantRating)
+			}
+			if warnings := strings.Join(analysis.Warnings, "; "); !strings.Contains(warnings, tt.wantWarn) {
+				t.Errorf("Expected a warning containing %q, got %q", tt.wantWarn, warnings)
+			}
+		})
+	}
+}
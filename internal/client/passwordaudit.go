@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// PasswordFinding is a login item whose password should be changed
+type PasswordFinding struct {
+	// ID is the shortest unique prefix of the item ID
+	ID     string
+	Item   string
+	Detail string
+}
+
+// PasswordReport is the result of auditing the passwords of a vault
+type PasswordReport struct {
+	Checked int
+	Weak    []PasswordFinding
+	// Reused groups the names of items sharing one password
+	Reused   [][]string
+	Breached []PasswordFinding
+	// BreachCheckFailed is set when some passwords could not be checked
+	BreachCheckFailed bool
+}
+
+// AuditPasswords decrypts every login item and reports weak and reused
+// passwords and, when checker is set, ones found in known breaches
+func (s *ClientSession) AuditPasswords(ctx context.Context, checker *PwnedChecker) (*PasswordReport, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	items, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PasswordReport{}
+	shortIDs := ShortIDs(items)
+	// only hashes are kept to find reuse, not the passwords themselves
+	shared := make(map[[sha256.Size]byte][]string)
+	breaches := make(map[[sha256.Size]byte]int)
+	for i := range items {
+		item := &items[i]
+		if item.Type != models.DataTypeLoginPassword {
+			continue
+		}
+		decrypted, err := s.cryptoManager.Decrypt(item.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %q: %w", item.Name, err)
+		}
+		var login models.LoginPasswordData
+		if err := json.Unmarshal(decrypted, &login); err != nil || login.Password == "" {
+			continue
+		}
+		report.Checked++
+		name := CleanQuotes(item.Name)
+
+		if analysis := AnalyzePassword(login.Password); analysis.Weak() {
+			detail := fmt.Sprintf("%.0f bits", analysis.Bits)
+			if len(analysis.Warnings) > 0 {
+				detail += ", " + strings.Join(analysis.Warnings, ", ")
+			}
+			report.Weak = append(report.Weak, PasswordFinding{ID: shortIDs[item.ID.String()], Item: name, Detail: detail})
+		}
+
+		hash := sha256.Sum256([]byte(login.Password))
+		shared[hash] = append(shared[hash], name)
+
+		if checker == nil || ctx.Err() != nil {
+			continue
+		}
+		count, checked := breaches[hash]
+		if !checked {
+			if count, err = checker.Count(ctx, login.Password); err != nil {
+				report.BreachCheckFailed = true
+				continue
+			}
+			breaches[hash] = count
+		}
+		if count > 0 {
+			report.Breached = append(report.Breached, PasswordFinding{ID: shortIDs[item.ID.String()], Item: name,
+				Detail: fmt.Sprintf("seen %d times", count)})
+		}
+	}
+
+	for _, names := range shared {
+		if len(names) > 1 {
+			sort.Strings(names)
+			report.Reused = append(report.Reused, names)
+		}
+	}
+	sort.Slice(report.Reused, func(i, j int) bool { return report.Reused[i][0] < report.Reused[j][0] })
+	return report, nil
+}
+
+// AuditPasswordsCommand prints a report of weak, reused and, with --breach or
+// breach checks turned on, breached passwords
+func (s *ClientSession) AuditPasswordsCommand(ctx context.Context, args []string) error {
+	checker := s.pwned
+	for _, arg := range args {
+		if arg != "--breach" {
+			return fmt.Errorf("usage: audit-passwords [--breach]")
+		}
+		if checker == nil {
+			checker = NewPwnedChecker()
+		}
+	}
+
+	report, err := s.AuditPasswords(ctx, checker)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Checked %d passwords\n", report.Checked)
+	printFindings := func(title string, findings []PasswordFinding) {
+		if len(findings) == 0 {
+			return
+		}
+		fmt.Printf("\n%s (%d):\n", title, len(findings))
+		for _, finding := range findings {
+			fmt.Printf("  %s  %s - %s\n", finding.ID, finding.Item, finding.Detail)
+		}
+	}
+	printFindings("Weak", report.Weak)
+	if len(report.Reused) > 0 {
+		fmt.Printf("\nReused (%d passwords):\n", len(report.Reused))
+		for _, names := range report.Reused {
+			fmt.Printf("  %s\n", strings.Join(names, ", "))
+		}
+	}
+	printFindings("Found in known breaches", report.Breached)
+
+	switch {
+	case checker == nil:
+		fmt.Println("\nBreaches not checked; run 'audit-passwords --breach' to check them (only a hash prefix is sent)")
+	case report.BreachCheckFailed:
+		fmt.Println("\nNote: some passwords could not be checked against known breaches")
+	}
+	if len(report.Weak) == 0 && len(report.Reused) == 0 && len(report.Breached) == 0 {
+		fmt.Println("No problems found")
+	}
+	return nil
+}
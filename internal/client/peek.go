@@ -0,0 +1,73 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPeekDuration is how long peek shows decrypted content by default
+	DefaultPeekDuration = 10 * time.Second
+)
+
+// PeekCommand shows decrypted data for a limited time, then wipes it from the terminal
+func (s *ClientSession) PeekCommand(ctx context.Context, id string, duration time.Duration) error {
+	return s.peek(ctx, os.Stdout, id, duration)
+}
+
+// peek renders the item to w and clears the rendered lines after duration
+func (s *ClientSession) peek(ctx context.Context, w io.Writer, id string, duration time.Duration) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+
+	if len(id) == 0 {
+		return fmt.Errorf("data ID is required")
+	}
+
+	data, err := s.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+
+	var rendered bytes.Buffer
+	if err := WriteStructuredData(&rendered, data, s.cryptoManager, true); err != nil {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": data.ID.String()})
+		return err
+	}
+	fmt.Fprintf(&rendered, "(hiding in %s)\n", duration)
+	s.recordUsage(data.ID.String())
+
+	if _, err := w.Write(rendered.Bytes()); err != nil {
+		return fmt.Errorf("failed to display data: %w", err)
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	if _, err := io.WriteString(w, ClearLinesSequence(strings.Count(rendered.String(), "\n"))); err != nil {
+		return fmt.Errorf("failed to clear screen: %w", err)
+	}
+	fmt.Fprintln(w, "Content hidden")
+	return nil
+}
+
+// ClearLinesSequence returns the ANSI sequence that erases the last n printed
+// lines and the terminal scrollback buffer
+func ClearLinesSequence(n int) string {
+	var b strings.Builder
+	if n > 0 {
+		fmt.Fprintf(&b, "\033[%dF", n)
+	}
+	b.WriteString("\033[J\033[3J")
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClearLinesSequence(t *testing.T) {
+	if got := ClearLinesSequence(3); got != "\033[3F\033[J\033[3J" {
+		t.Errorf("ClearLinesSequence(3) = %q", got)
+	}
+	if got := ClearLinesSequence(0); got != "\033[J\033[3J" {
+		t.Errorf("ClearLinesSequence(0) = %q", got)
+	}
+}
+
+func TestClientSession_Peek(t *testing.T) {
+	session, _, err := NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	items, err := session.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var id string
+	for _, item := range items {
+		if item.Name == "Demo Visa" {
+			id = item.ID.String()
+		}
+	}
+
+	var out bytes.Buffer
+	if err := session.peek(context.Background(), &out, id, 10*time.Millisecond); err != nil {
+		t.Fatalf("peek() error = %v", err)
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "4111111111111111") {
+		t.Error("Expected decrypted content to be shown")
+	}
+	if !strings.Contains(output, "\033[J\033[3J") {
+		t.Error("Expected content to be cleared")
+	}
+	if !strings.HasSuffix(output, "Content hidden\n") {
+		t.Errorf("Expected hidden notice at the end, got %q", output)
+	}
+}
+
+func TestClientSession_Peek_Cancelled(t *testing.T) {
+	session, _, err := NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	items, err := session.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancel()
+
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- session.peek(ctx, &out, items[0].ID.String(), time.Hour)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("peek() did not return after context cancellation")
+	}
+}
+
+func TestClientSession_Peek_NotAuthenticated(t *testing.T) {
+	session := NewClientSession(NewClient("http://localhost:8080"))
+	if err := session.PeekCommand(context.Background(), "id", time.Second); err != ErrNotAuthenticated {
+		t.Errorf("PeekCommand() error = %v, want ErrNotAuthenticated", err)
+	}
+}
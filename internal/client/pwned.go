@@ -0,0 +1,129 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// DefaultPwnedURL is the Have I Been Pwned range API
+const DefaultPwnedURL = "https://api.pwnedpasswords.com/range/"
+
+// PwnedChecker looks passwords up in the Have I Been Pwned breach corpus
+// using k-anonymity: only the first five hex characters of a password's SHA-1
+// hash are sent, and the matching suffixes are compared locally
+type PwnedChecker struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewPwnedChecker creates a checker against the public Have I Been Pwned API
+func NewPwnedChecker() *PwnedChecker {
+	return &PwnedChecker{
+		baseURL:    DefaultPwnedURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Count returns how many times password appears in known breaches, 0 meaning
+// it was not found
+func (p *PwnedChecker) Count(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+prefix, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	// padded responses hide how many suffixes share the prefix
+	req.Header.Set("Add-Padding", "true")
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("breach check failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("breach check failed: %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		// padding entries have a count of 0
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("invalid breach check response: %w", err)
+		}
+		return n, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read breach check response: %w", err)
+	}
+	return 0, nil
+}
+
+// SetPwnedChecker turns on breach checks of new passwords, nil turning them off
+func (s *ClientSession) SetPwnedChecker(checker *PwnedChecker) {
+	s.pwned = checker
+}
+
+// warnIfPwned prints a warning when the password of new login data appears in
+// known breaches. A failed check only prints a note; it never blocks saving.
+func (s *ClientSession) warnIfPwned(ctx context.Context, loginData []byte) {
+	var login models.LoginPasswordData
+	if s.pwned == nil || json.Unmarshal(loginData, &login) != nil || login.Password == "" {
+		return
+	}
+	count, err := s.pwned.Count(ctx, login.Password)
+	switch {
+	case err != nil:
+		logger.Log.Warn("Breach check failed", zap.Error(err))
+		fmt.Println("Note: could not check the password against known breaches")
+	case count > 0:
+		fmt.Printf("Warning: this password appeared %d times in known data breaches; consider a generated one\n", count)
+	}
+}
+
+// BreachCheckCommand shows or sets whether new passwords are checked against known breaches
+func (s *ClientSession) BreachCheckCommand(config *Config, args []string) error {
+	if len(args) == 0 {
+		if config.BreachCheck {
+			fmt.Println("Breach check of new passwords: on")
+		} else {
+			fmt.Println("Breach check of new passwords: off")
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "on", "off":
+		config.BreachCheck = args[0] == "on"
+		if err := SaveConfig(config); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		if config.BreachCheck {
+			s.SetPwnedChecker(NewPwnedChecker())
+		} else {
+			s.SetPwnedChecker(nil)
+		}
+		fmt.Printf("Breach check of new passwords turned %s\n", args[0])
+		return nil
+	default:
+		return fmt.Errorf("unknown breach-check action: %s (use on or off)", args[0])
+	}
+}
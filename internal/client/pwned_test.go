@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// newFakePwned serves the range API for the given breached passwords and
+// records the requested prefixes
+func newFakePwned(t *testing.T, breached map[string]int) (*PwnedChecker, *[]string) {
+	t.Helper()
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		requested = append(requested, prefix)
+		// a padding entry, as sent with Add-Padding
+		fmt.Fprintf(w, "%s:0\r\n", strings.Repeat("0", 35))
+		for password, count := range breached {
+			sum := sha1.Sum([]byte(password))
+			hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+			if strings.HasPrefix(hash, prefix) {
+				fmt.Fprintf(w, "%s:%d\r\n", hash[5:], count)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &PwnedChecker{baseURL: server.URL + "/range/", httpClient: server.Client()}, &requested
+}
+
+func TestPwnedChecker_Count(t *testing.T) {
+	checker, requested := newFakePwned(t, map[string]int{"hunter2": 17043})
+
+	count, err := checker.Count(context.Background(), "hunter2")
+	if err != nil || count != 17043 {
+		t.Errorf("Count(hunter2) = %d, %v, want 17043", count, err)
+	}
+	if count, err := checker.Count(context.Background(), "x7#Kp2!qLm9$Vw4@Zr"); err != nil || count != 0 {
+		t.Errorf("Count() of an unbreached password = %d, %v, want 0", count, err)
+	}
+	for _, prefix := range *requested {
+		if len(prefix) != 5 {
+			t.Errorf("Expected only 5 hash characters to be sent, got %q", prefix)
+		}
+	}
+
+	failing := &PwnedChecker{baseURL: "http://127.0.0.1:1/range/", httpClient: http.DefaultClient}
+	if _, err := failing.Count(context.Background(), "hunter2"); err == nil {
+		t.Error("Expected an unreachable API to fail the check")
+	}
+}
+
+func TestClientSession_AuditPasswords(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	for _, item := range []struct{ name, password string }{
+		{"Forum", "hunter2"},
+		{"Mail", "x7#Kp2!qLm9$Vw4@Zr"},
+		{"Bank", "x7#Kp2!qLm9$Vw4@Zr"},
+	} {
+		fields := []ItemField{{Name: "login", Value: "me"}, {Name: "password", Value: item.password}}
+		if _, err := session.SaveItem(ctx, nil, models.DataTypeLoginPassword, item.name, "", fields); err != nil {
+			t.Fatalf("SaveItem() error = %v", err)
+		}
+	}
+	checker, requested := newFakePwned(t, map[string]int{"hunter2": 17043})
+
+	report, err := session.AuditPasswords(ctx, checker)
+	if err != nil {
+		t.Fatalf("AuditPasswords() error = %v", err)
+	}
+	if len(report.Weak) != 1 || report.Weak[0].Item != "Forum" {
+		t.Errorf("Expected Forum to be weak, got %+v", report.Weak)
+	}
+	if len(report.Reused) != 1 || strings.Join(report.Reused[0], ",") != "Bank,Mail" {
+		t.Errorf("Expected Bank and Mail to share a password, got %v", report.Reused)
+	}
+	if len(report.Breached) != 1 || report.Breached[0].Item != "Forum" {
+		t.Errorf("Expected Forum to be breached, got %+v", report.Breached)
+	}
+	if len(*requested) != report.Checked-1 {
+		t.Errorf("Expected each distinct password to be checked once, got %d requests for %d passwords",
+			len(*requested), report.Checked)
+	}
+
+	report, err = session.AuditPasswords(ctx, nil)
+	if err != nil || len(report.Breached) != 0 {
+		t.Errorf("Expected no breach check without a checker, got %+v, %v", report, err)
+	}
+}
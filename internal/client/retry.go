@@ -0,0 +1,246 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultRetryAttempts is how many times a request is tried on transient
+	// failures unless configured otherwise
+	DefaultRetryAttempts = 3
+	// DefaultRetryBackoff is the delay before the first retry
+	DefaultRetryBackoff = 200 * time.Millisecond
+	// DefaultRetryMaxBackoff caps the delay between retries
+	DefaultRetryMaxBackoff = 5 * time.Second
+	// maxPeekedErrorBody bounds how much of a 503 response is read to tell
+	// maintenance mode from an overloaded server
+	maxPeekedErrorBody = 4096
+)
+
+// RetryPolicy describes how requests are retried on transient failures:
+// connection errors, timeouts and 502, 503 or 504 responses
+type RetryPolicy struct {
+	// MaxAttempts is how many times a request is tried; 1 or less disables retries
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled for each further one
+	Backoff time.Duration
+	// MaxBackoff caps the delay; a longer Retry-After from the server is not waited for
+	MaxBackoff time.Duration
+	// Jitter waits a random delay between half and all of the backoff so
+	// that clients failing together do not retry together
+	Jitter bool
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultRetryAttempts,
+		Backoff:     DefaultRetryBackoff,
+		MaxBackoff:  DefaultRetryMaxBackoff,
+		Jitter:      true,
+	}
+}
+
+// RetryPolicy returns the retry policy from the config, with defaults for
+// unset options
+func (c *Config) RetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	switch {
+	case c.RetryAttempts < 0:
+		policy.MaxAttempts = 1
+	case c.RetryAttempts > 0:
+		policy.MaxAttempts = c.RetryAttempts
+	}
+	if c.RetryBackoffMillis > 0 {
+		policy.Backoff = time.Duration(c.RetryBackoffMillis) * time.Millisecond
+	}
+	if c.RetryMaxBackoffMillis > 0 {
+		policy.MaxBackoff = time.Duration(c.RetryMaxBackoffMillis) * time.Millisecond
+	}
+	policy.MaxBackoff = max(policy.MaxBackoff, policy.Backoff)
+	policy.Jitter = !c.RetryNoJitter
+	return policy
+}
+
+// delay returns how long to wait before the given retry, counting from 1
+func (p RetryPolicy) delay(retry int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < retry && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxBackoff)
+	if p.Jitter && delay > 1 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	return delay
+}
+
+// SetRetryPolicy makes the client retry requests on transient failures
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	next := c.innerTransport()
+	if retrying, ok := next.(*retryTransport); ok {
+		next = retrying.next
+	}
+	if policy.MaxAttempts <= 1 {
+		c.setInnerTransport(next)
+		return
+	}
+	c.setInnerTransport(&retryTransport{next: next, policy: policy})
+}
+
+// retryTransport retries requests that failed transiently. Requests that may
+// not be repeated safely, such as creating an item, are only retried when
+// they cannot have reached the server: the connection was never established
+// or an overloaded server turned them away.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			attemptReq = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := next.RoundTrip(attemptReq)
+		if attempt >= t.policy.MaxAttempts || !replayable || req.Context().Err() != nil {
+			return resp, err
+		}
+		wait, retry := t.shouldRetry(req, resp, err)
+		if !retry {
+			return resp, err
+		}
+		fields := []zap.Field{zap.String("method", req.Method), zap.String("path", req.URL.Path), zap.Int("attempt", attempt)}
+		if resp != nil {
+			fields = append(fields, zap.Int("status_code", resp.StatusCode), zap.String("request_id", resp.Header.Get(requestIDHeader)))
+			drainBody(resp.Body)
+		}
+
+		delay := max(t.policy.delay(attempt), wait)
+		logger.Log.Debug("Retrying request", append(fields, zap.Duration("delay", delay), zap.Error(err))...)
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry reports whether a failed attempt is worth repeating, and the
+// least time the server asked to wait before doing so
+func (t *retryTransport) shouldRetry(req *http.Request, resp *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		if isDialError(err) {
+			return 0, true
+		}
+		var netErr net.Error
+		return 0, isIdempotent(req) && errors.As(err, &netErr)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusServiceUnavailable:
+		if isMaintenanceResponse(resp) {
+			return 0, false
+		}
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		if !isIdempotent(req) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if parseErr != nil || seconds <= 0 {
+		return 0, true
+	}
+	wait := time.Duration(seconds) * time.Second
+	return wait, wait <= t.policy.MaxBackoff
+}
+
+// isIdempotent reports whether repeating req has the same effect as sending it once
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isDialError reports whether err happened before a connection to the server
+// was established, e.g. connection refused or a DNS failure
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// isMaintenanceResponse reports whether a 503 response is a maintenance mode
+// rejection, which lasts too long to be retried. The body is left readable.
+func isMaintenanceResponse(resp *http.Response) bool {
+	if resp.Body == nil {
+		return false
+	}
+	peeked, _ := io.ReadAll(io.LimitReader(resp.Body, maxPeekedErrorBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), resp.Body), resp.Body}
+
+	var errResp models.ErrorResponse
+	return json.Unmarshal(peeked, &errResp) == nil && errResp.Error == "maintenance"
+}
+
+// drainBody reads the rest of a discarded response so its connection can be reused
+func drainBody(body io.ReadCloser) {
+	if body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, maxPeekedErrorBody))
+	if err := body.Close(); err != nil {
+		logger.Log.Error("Failed to close body", zap.Error(err))
+	}
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
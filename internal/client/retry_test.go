@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedTransport answers each request with the next scripted response or error
+type scriptedTransport struct {
+	replies []func() (*http.Response, error)
+	bodies  []string
+}
+
+func (t *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		payload, _ := io.ReadAll(req.Body)
+		body = string(payload)
+	}
+	t.bodies = append(t.bodies, body)
+	reply := t.replies[0]
+	if len(t.replies) > 1 {
+		t.replies = t.replies[1:]
+	}
+	return reply()
+}
+
+func reply(status int, body string, headers ...string) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		resp := &http.Response{StatusCode: status, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body))}
+		for i := 0; i+1 < len(headers); i += 2 {
+			resp.Header.Set(headers[i], headers[i+1])
+		}
+		return resp, nil
+	}
+}
+
+func fail(err error) func() (*http.Response, error) {
+	return func() (*http.Response, error) { return nil, err }
+}
+
+func TestRetryTransport(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, Jitter: true}
+
+	tests := []struct {
+		name         string
+		method       string
+		replies      []func() (*http.Response, error)
+		wantStatus   int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{name: "connection refused", method: "POST", replies: []func() (*http.Response, error){fail(refused), reply(201, "{}")},
+			wantStatus: 201, wantAttempts: 2},
+		{name: "reset get", method: "GET", replies: []func() (*http.Response, error){fail(reset), reply(200, "{}")},
+			wantStatus: 200, wantAttempts: 2},
+		{name: "reset post not retried", method: "POST", replies: []func() (*http.Response, error){fail(reset)},
+			wantErr: true, wantAttempts: 1},
+		{name: "bad gateway get", method: "GET", replies: []func() (*http.Response, error){reply(502, ""), reply(504, ""), reply(200, "{}")},
+			wantStatus: 200, wantAttempts: 3},
+		{name: "bad gateway post not retried", method: "POST", replies: []func() (*http.Response, error){reply(502, "")},
+			wantStatus: 502, wantAttempts: 1},
+		{name: "overloaded post", method: "POST", replies: []func() (*http.Response, error){reply(503, `{"error":"overloaded"}`), reply(201, "{}")},
+			wantStatus: 201, wantAttempts: 2},
+		{name: "gives up", method: "GET", replies: []func() (*http.Response, error){reply(503, "")},
+			wantStatus: 503, wantAttempts: 3},
+		{name: "maintenance", method: "GET", replies: []func() (*http.Response, error){reply(503, `{"error":"maintenance","message":"backup"}`)},
+			wantStatus: 503, wantAttempts: 1},
+		{name: "long retry after", method: "GET", replies: []func() (*http.Response, error){reply(503, "", "Retry-After", "600")},
+			wantStatus: 503, wantAttempts: 1},
+		{name: "client error", method: "GET", replies: []func() (*http.Response, error){reply(404, "")},
+			wantStatus: 404, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scripted := &scriptedTransport{replies: tt.replies}
+			cli := NewClient(demoServerURL)
+			cli.httpClient.Transport = scripted
+			cli.SetRetryPolicy(policy)
+
+			req, _ := http.NewRequest(tt.method, demoServerURL+"/api/v1/data", strings.NewReader(`{"name":"x"}`))
+			resp, err := cli.httpClient.Do(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				defer resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+				}
+			}
+			if len(scripted.bodies) != tt.wantAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.wantAttempts, len(scripted.bodies))
+			}
+			for _, body := range scripted.bodies {
+				if body != `{"name":"x"}` {
+					t.Errorf("Expected the body on every attempt, got %q", body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransport_KeepsMaintenanceBody(t *testing.T) {
+	cli := NewClient(demoServerURL)
+	cli.httpClient.Transport = &scriptedTransport{replies: []func() (*http.Response, error){
+		reply(503, `{"error":"maintenance","message":"Nightly backup"}`),
+	}}
+	cli.SetRetryPolicy(DefaultRetryPolicy())
+
+	_, err := cli.GetData(context.Background())
+	if !IsMaintenance(err) || !strings.Contains(err.Error(), "Nightly backup") {
+		t.Errorf("Expected the maintenance error, got %v", err)
+	}
+}
+
+func TestRetryTransport_StopsWithContext(t *testing.T) {
+	cli := NewClient(demoServerURL)
+	scripted := &scriptedTransport{replies: []func() (*http.Response, error){reply(503, "")}}
+	cli.httpClient.Transport = scripted
+	cli.SetRetryPolicy(RetryPolicy{MaxAttempts: 5, Backoff: time.Hour, MaxBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := cli.GetData(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to end the retries, got %v", err)
+	}
+	if len(scripted.bodies) != 1 {
+		t.Errorf("Expected a single attempt, got %d", len(scripted.bodies))
+	}
+}
+
+func TestConfig_RetryPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   RetryPolicy
+	}{
+		{name: "defaults", want: DefaultRetryPolicy()},
+		{name: "off", config: Config{RetryAttempts: -1}, want: RetryPolicy{MaxAttempts: 1, Backoff: DefaultRetryBackoff, MaxBackoff: DefaultRetryMaxBackoff, Jitter: true}},
+		{name: "custom", config: Config{RetryAttempts: 5, RetryBackoffMillis: 50, RetryMaxBackoffMillis: 1000, RetryNoJitter: true},
+			want: RetryPolicy{MaxAttempts: 5, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second}},
+		{name: "backoff above cap", config: Config{RetryBackoffMillis: 10000},
+			want: RetryPolicy{MaxAttempts: DefaultRetryAttempts, Backoff: 10 * time.Second, MaxBackoff: 10 * time.Second, Jitter: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.RetryPolicy(); got != tt.want {
+				t.Errorf("RetryPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for retry, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 10: time.Second} {
+		if got := policy.delay(retry); got != want {
+			t.Errorf("delay(%d) = %s, want %s", retry, got, want)
+		}
+	}
+
+	policy.Jitter = true
+	for i := 0; i < 100; i++ {
+		if got := policy.delay(3); got < 200*time.Millisecond || got > 400*time.Millisecond {
+			t.Fatalf("Expected a jittered delay between half and all of the backoff, got %s", got)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// minMasterPasswordLength is the shortest master password accepted
+const minMasterPasswordLength = 8
+
+// RotateVault streams the header with the new salt and verifier and the
+// re-encrypted ciphertexts emitted by produce to the server, which replaces
+// the whole vault with them at once. An error from produce aborts the stream,
+// and the server then keeps the vault as it was.
+func (c *Client) RotateVault(ctx context.Context, header models.RotationHeader,
+	produce func(emit func(models.RotationRecord) error) error) (*models.RotationResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	body, writer := io.Pipe()
+	defer body.Close()
+	produced := make(chan error, 1)
+	go func() {
+		encoder := json.NewEncoder(writer)
+		err := encoder.Encode(header)
+		if err == nil {
+			err = produce(func(record models.RotationRecord) error {
+				return encoder.Encode(record)
+			})
+		}
+		produced <- err
+		writer.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/vault/rotate", body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	c.authorize(req)
+
+	// a large vault outlasts the request timeout; ctx bounds it instead
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		cancel()
+		if produceErr := <-produced; isProduceError(produceErr) {
+			return nil, produceErr
+		}
+		logger.Log.Error("Request failed", zap.Error(err), zap.String("path", req.URL.Path))
+		return nil, requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// the server answers early when it rejects the stream
+		cancel()
+		if produceErr := <-produced; isProduceError(produceErr) {
+			return nil, produceErr
+		}
+		return nil, statusError(resp, respBody)
+	}
+
+	var rotation models.RotationResponse
+	if err := json.Unmarshal(respBody, &rotation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &rotation, nil
+}
+
+// isProduceError reports whether a rotation stream stopped for a reason of its
+// own rather than because the request ended
+func isProduceError(err error) bool {
+	return err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, io.ErrClosedPipe)
+}
+
+// RotateMasterPassword moves the whole vault to a key derived from
+// newPassword and a fresh salt. The data key of every item, comment, previous
+// version, file chunk and attachment is re-wrapped locally, and ciphertexts still in the
+// older format are re-encrypted, then the server swaps them in with the new
+// salt in one step, so a failure part way leaves the vault under the old
+// password. It returns the new salt.
+func (s *ClientSession) RotateMasterPassword(ctx context.Context, newPassword string) (string, *models.RotationResponse, error) {
+	if !s.IsAuthenticated() {
+		return "", nil, ErrNotAuthenticated
+	}
+	if len(newPassword) < minMasterPasswordLength {
+		return "", nil, fmt.Errorf("master password must be at least %d characters long", minMasterPasswordLength)
+	}
+	if newPassword == s.masterPassword {
+		return "", nil, fmt.Errorf("the new master password is the current one")
+	}
+	// offline changes are encrypted with the current key and could not be sent afterwards
+	if snapshot := s.loadSnapshot(); snapshot != nil && len(snapshot.Pending) > 0 {
+		return "", nil, fmt.Errorf("%d offline changes are not synced yet, run 'sync' first", len(snapshot.Pending))
+	}
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	if !hasFeature(status, "vault_rotation") {
+		return "", nil, fmt.Errorf("this server does not support master password rotation")
+	}
+	attachments := hasFeature(status, "attachments")
+
+	escrowed := false
+	if escrow, err := s.cli.GetEscrowStatus(ctx); err == nil {
+		escrowed = escrow.Enabled
+	}
+
+	current := s.cryptoManager
+	nextSalt, err := crypto.GenerateSalt()
+	if err != nil {
+		return "", nil, err
+	}
+	next, err := s.deriveCryptoManager(newPassword, nextSalt, s.kdfIterations)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+	salt := next.GetSaltBase64()
+	verifier, err := next.NewVerifier()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create verifier: %w", err)
+	}
+	usage := s.loadUsage()
+
+	var rotation *models.RotationResponse
+	err = s.withVaultLock(ctx, "rotation", func() error {
+		items, err := s.cli.GetData(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get data: %w", err)
+		}
+		header := models.RotationHeader{Salt: salt, Verifier: verifier, KDFIterations: next.Iterations()}
+		if keys, err := s.cli.GetShareKey(ctx); err == nil && len(keys.PrivateKey) > 0 {
+			if header.ShareKey, err = current.Rewrap(keys.PrivateKey, next); err != nil {
+				return fmt.Errorf("failed to re-encrypt share key: %w", err)
+			}
+		}
+		rotation, err = s.cli.RotateVault(ctx, header, func(emit func(models.RotationRecord) error) error {
+			for i := range items {
+				if err := s.rotateItem(ctx, &items[i], current, next, attachments, emit); err != nil {
+					return fmt.Errorf("failed to re-encrypt %q: %w", CleanQuotes(items[i].Name), err)
+				}
+			}
+			return nil
+		})
+		if err != nil && s.rotationApplied(ctx, salt) {
+			// the response was lost after the server committed the rotation
+			logger.Log.Warn("Rotation response lost, the server has the new salt", zap.Error(err))
+			rotation, err = &models.RotationResponse{}, nil
+		}
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.SetCryptoManager(next, newPassword)
+	s.publishAuditKey(ctx)
+	if escrowed {
+		if _, err := s.EnableEscrow(ctx); err != nil {
+			logger.Log.Warn("Failed to renew key escrow after rotation", zap.Error(err))
+			fmt.Println("Warning: key escrow still holds the old vault key; run 'escrow enable' again")
+		}
+	}
+	s.saveUsage(usage)
+	if _, err := s.List(ctx); err != nil {
+		logger.Log.Warn("Failed to refresh local copy after rotation", zap.Error(err))
+	}
+	s.recordEvent(EventMasterPasswordRotated, map[string]string{"items": fmt.Sprint(rotation.Items)})
+	return salt, rotation, nil
+}
+
+// rotateItem emits the ciphertexts of an item re-wrapped from current to next.
+// Attachments are only looked up on servers that store them.
+func (s *ClientSession) rotateItem(ctx context.Context, data *models.Data, current, next *crypto.CryptoManager,
+	attachments bool, emit func(models.RotationRecord) error) error {
+	reencrypt := func(ciphertext []byte) ([]byte, error) {
+		rewrapped, err := current.Rewrap(ciphertext, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt: %w", err)
+		}
+		return rewrapped, nil
+	}
+
+	ciphertext, err := reencrypt(data.Data)
+	if err != nil {
+		return err
+	}
+	if err := emit(models.RotationRecord{Kind: models.RotationItem, DataID: data.ID, Revision: data.Revision, Data: ciphertext}); err != nil {
+		return err
+	}
+
+	comments, err := s.cli.GetDataComments(ctx, data.ID.String())
+	if err != nil {
+		return fmt.Errorf("failed to get comments: %w", err)
+	}
+	for _, comment := range comments {
+		ciphertext, err := reencrypt(comment.Ciphertext)
+		if err != nil {
+			return fmt.Errorf("comment %s: %w", comment.ID, err)
+		}
+		record := models.RotationRecord{Kind: models.RotationComment, DataID: data.ID, CommentID: comment.ID, Data: ciphertext}
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+
+	versions, err := s.cli.GetDataVersions(ctx, data.ID.String())
+	if err != nil {
+		return fmt.Errorf("failed to get versions: %w", err)
+	}
+	for _, version := range versions {
+		ciphertext, err := reencrypt(version.Data)
+		if err != nil {
+			return fmt.Errorf("version %d: %w", version.Version, err)
+		}
+		record := models.RotationRecord{Kind: models.RotationVersion, DataID: data.ID, Version: version.Version, Data: ciphertext}
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+
+	if attachments {
+		if err := s.rotateAttachments(ctx, data, reencrypt, emit); err != nil {
+			return err
+		}
+	}
+
+	if data.Type != models.DataTypeBinary {
+		return nil
+	}
+	var binaryData models.BinaryData
+	if err := json.Unmarshal([]byte(data.Metadata), &binaryData); err != nil || binaryData.Chunks == 0 {
+		return nil
+	}
+	return s.cli.DownloadDataChunks(ctx, data.ID.String(), func(index int, chunk []byte) error {
+		ciphertext, err := reencrypt(chunk)
+		if err != nil {
+			return fmt.Errorf("chunk %d: %w", index, err)
+		}
+		return emit(models.RotationRecord{Kind: models.RotationChunk, DataID: data.ID, Index: index, Data: ciphertext})
+	})
+}
+
+// rotateAttachments emits the descriptions and content of the attachments of
+// an item re-encrypted with reencrypt, downloading one attachment at a time
+func (s *ClientSession) rotateAttachments(ctx context.Context, data *models.Data, reencrypt func([]byte) ([]byte, error),
+	emit func(models.RotationRecord) error) error {
+	attachments, err := s.cli.GetDataAttachments(ctx, data.ID.String())
+	if err != nil {
+		return fmt.Errorf("failed to get attachments: %w", err)
+	}
+	for _, listed := range attachments {
+		attachment, err := s.cli.GetDataAttachment(ctx, data.ID.String(), listed.ID.String())
+		if err != nil {
+			return fmt.Errorf("failed to get attachment %s: %w", listed.ID, err)
+		}
+		info, err := reencrypt(attachment.Info)
+		if err != nil {
+			return fmt.Errorf("attachment %s: %w", attachment.ID, err)
+		}
+		content, err := reencrypt(attachment.Content)
+		if err != nil {
+			return fmt.Errorf("attachment %s: %w", attachment.ID, err)
+		}
+		record := models.RotationRecord{Kind: models.RotationAttachment, DataID: data.ID, AttachmentID: attachment.ID,
+			Info: info, Data: content}
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isCurrentMasterPassword reports whether password is the one the vault was
+// opened with. After an unlock with a remembered key only the key is known, so
+// the password is checked by deriving it again.
+func (s *ClientSession) isCurrentMasterPassword(password string) bool {
+	if s.masterPassword != "" {
+		return subtle.ConstantTimeCompare([]byte(password), []byte(s.masterPassword)) == 1
+	}
+	cryptoManager, err := s.deriveCryptoManager(password, s.cryptoManager.GetSalt(), s.cryptoManager.Iterations())
+	if err != nil || subtle.ConstantTimeCompare(cryptoManager.Key(), s.cryptoManager.Key()) != 1 {
+		return false
+	}
+	s.SetCryptoManager(cryptoManager, password)
+	return true
+}
+
+// rotationApplied reports whether the server already has the salt of a rotation
+func (s *ClientSession) rotationApplied(ctx context.Context, salt string) bool {
+	current, err := s.cli.GetSalt(context.WithoutCancel(ctx))
+	return err == nil && current == salt
+}
+
+// RotateMasterCommand asks for the current and a new master password,
+// re-encrypts the vault under the new one and saves the new salt
+func (s *ClientSession) RotateMasterCommand(ctx context.Context, config *Config) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	currentPassword, ok := readSecret(scanner, "Current master password: ")
+	if !ok {
+		return fmt.Errorf("failed to read master password")
+	}
+	if !s.isCurrentMasterPassword(currentPassword) {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"action": "rotate-master"})
+		return fmt.Errorf("wrong master password")
+	}
+	newPassword, ok := readSecret(scanner, fmt.Sprintf("New master password (min %d characters): ", minMasterPasswordLength))
+	if !ok {
+		return fmt.Errorf("failed to read master password")
+	}
+	confirmation, ok := readSecret(scanner, "Repeat new master password: ")
+	if !ok {
+		return fmt.Errorf("failed to read master password")
+	}
+	if newPassword != confirmation {
+		return fmt.Errorf("master passwords do not match")
+	}
+
+	fmt.Println("Re-encrypting the vault...")
+	salt, rotation, err := s.RotateMasterPassword(ctx, newPassword)
+	if err != nil {
+		return err
+	}
+
+	config.Salt = salt
+	config.SaltIterations = s.cryptoManager.Iterations()
+	if err := SaveConfig(config); err != nil {
+		return fmt.Errorf("master password changed, but failed to save config: %w", err)
+	}
+	if config.SessionKeyWrap != nil {
+		if err := s.RememberVaultKey(config); err != nil {
+			logger.Log.Warn("Failed to remember the new vault key", zap.Error(err))
+			fmt.Println("Warning: the OS keychain still holds the old vault key; run 'keychain remember' again")
+		}
+	}
+	fmt.Printf("Master password changed: re-encrypted %d items, %d comments, %d versions, %d file chunks and %d attachments\n",
+		rotation.Items, rotation.Comments, rotation.Versions, rotation.Chunks, rotation.Attachments)
+	fmt.Println("Other devices must log in again with the new master password")
+	return nil
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestClientSession_RotateMasterPassword(t *testing.T) {
+	ctx := context.Background()
+	session, config, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.tar")
+	content := bytes.Repeat([]byte("rotate me "), 300)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	file, err := session.UploadBinary(ctx, path, "", models.DataRequest{Name: "Backup"}, 1000)
+	if err != nil {
+		t.Fatalf("UploadBinary() error = %v", err)
+	}
+	if _, err := session.AddComment(ctx, file.ID.String(), "kept offsite"); err != nil {
+		t.Fatalf("AddComment() error = %v", err)
+	}
+	if _, err := session.Attach(ctx, file.ID.String(), path); err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+	items, err := session.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var note models.Data
+	for _, item := range items {
+		if item.Type == models.DataTypeText {
+			note = item
+		}
+	}
+	update := models.DataRequest{Type: note.Type, Name: "Renamed", Data: note.Data, Metadata: note.Metadata,
+		BaseRevision: &note.Revision}
+	if _, err := session.Update(ctx, note.ID.String(), update); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	oldKey := session.GetCryptoManager()
+
+	t.Run("rejected", func(t *testing.T) {
+		if _, _, err := session.RotateMasterPassword(ctx, "short"); err == nil {
+			t.Error("Expected a short password to be rejected")
+		}
+		if _, _, err := session.RotateMasterPassword(ctx, DemoMasterPassword); err == nil {
+			t.Error("Expected the current password to be rejected")
+		}
+	})
+
+	salt, rotation, err := session.RotateMasterPassword(ctx, "a brand new password")
+	if err != nil {
+		t.Fatalf("RotateMasterPassword() error = %v", err)
+	}
+	if salt == config.Salt {
+		t.Error("Expected a new salt")
+	}
+	if rotation.Items != len(items) || rotation.Comments != 1 || rotation.Versions != 1 || rotation.Chunks != 3 ||
+		rotation.Attachments != 1 {
+		t.Errorf("Unexpected rotation counts %+v for %d items", rotation, len(items))
+	}
+
+	// a fresh key from the new password and salt opens everything
+	saltBytes, _ := base64.StdEncoding.DecodeString(salt)
+	fresh, err := crypto.NewCryptoManagerWithSalt("a brand new password", saltBytes)
+	if err != nil {
+		t.Fatalf("NewCryptoManagerWithSalt() error = %v", err)
+	}
+	session.SetCryptoManager(fresh, "a brand new password")
+	if count, err := session.Export(ctx, io.Discard, "export password"); err != nil || count != len(items) {
+		t.Errorf("Expected all %d items to decrypt, got %d, %v", len(items), count, err)
+	}
+	comments, err := session.Comments(ctx, file.ID.String())
+	if err != nil || len(comments) != 1 || comments[0].Text != "kept offsite" {
+		t.Errorf("Expected the comment to decrypt, got %+v, %v", comments, err)
+	}
+	output := filepath.Join(t.TempDir(), "restored.tar")
+	if _, _, err := session.DownloadAttachment(ctx, file.ID.String(), "backup.tar", output); err != nil {
+		t.Errorf("Expected the attachment to decrypt, got %v", err)
+	}
+	versions, err := session.cli.GetDataVersions(ctx, note.ID.String())
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("Expected one version, got %d, %v", len(versions), err)
+	}
+	if _, err := fresh.Decrypt(versions[0].Data); err != nil {
+		t.Errorf("Expected the version to decrypt, got %v", err)
+	}
+	if _, err := oldKey.Decrypt(versions[0].Data); err == nil {
+		t.Error("Expected the old key to no longer decrypt the vault")
+	}
+	if remote, err := session.cli.GetSalt(ctx); err != nil || remote != salt {
+		t.Errorf("Expected the server to have the new salt, got %q, %v", remote, err)
+	}
+	if verifier, err := session.cli.GetMasterVerifier(ctx); err != nil || !fresh.CheckVerifier(verifier) {
+		t.Errorf("Expected the verifier to match the new password, got %v", err)
+	}
+}
+
+func TestClientSession_RotateMasterPasswordFailure(t *testing.T) {
+	ctx := context.Background()
+	session, config, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	// an item this vault key cannot decrypt stops the rotation part way
+	if _, err := session.cli.CreateData(ctx, models.DataRequest{Type: models.DataTypeText, Name: "zz foreign",
+		Data: []byte("not encrypted")}); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	if _, _, err := session.RotateMasterPassword(ctx, "a brand new password"); err == nil ||
+		!strings.Contains(err.Error(), "zz foreign") {
+		t.Fatalf("Expected the rotation to fail on the foreign item, got %v", err)
+	}
+
+	if remote, err := session.cli.GetSalt(ctx); err != nil || remote != config.Salt {
+		t.Errorf("Expected the old salt to be kept, got %q, %v", remote, err)
+	}
+	items, err := session.cli.GetData(ctx)
+	if err != nil {
+		t.Fatalf("GetData() error = %v", err)
+	}
+	for _, item := range items {
+		if item.Name == "zz foreign" {
+			continue
+		}
+		if _, err := session.GetCryptoManager().Decrypt(item.Data); err != nil {
+			t.Errorf("Expected %q to still decrypt with the old key, got %v", item.Name, err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+const (
+	// minScanTokenLength skips values too short to identify a secret
+	minScanTokenLength = 8
+	// maxScanFileSize skips large files such as build artifacts
+	maxScanFileSize = 1 << 20
+)
+
+var (
+	// scanFieldSeparators split assignments such as KEY=value or "key": "value"
+	scanFieldSeparators = "=:,;()[]{}<>"
+	// cardNumberPattern matches card numbers written with spaces or dashes
+	cardNumberPattern = regexp.MustCompile(`\d[\d -]{10,24}\d`)
+)
+
+// ScanFinding is a file line containing a value stored in the vault
+type ScanFinding struct {
+	Path  string
+	Line  int
+	Item  string
+	Field string
+}
+
+// scanSecret identifies the vault field a hashed value came from
+type scanSecret struct {
+	item  string
+	field string
+}
+
+// secretHashes hashes the normalized secret values of all items. Only hashes are
+// kept while scanning, so plaintext secrets are not held for the whole walk.
+func (s *ClientSession) secretHashes(ctx context.Context) (map[[sha256.Size]byte]scanSecret, error) {
+	items, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[[sha256.Size]byte]scanSecret)
+	add := func(item *models.Data, field, value string) {
+		value = normalizeScanToken(value)
+		if len(value) >= minScanTokenLength {
+			hashes[sha256.Sum256([]byte(value))] = scanSecret{item: CleanQuotes(item.Name), field: field}
+		}
+	}
+
+	for i := range items {
+		item := &items[i]
+		decrypted, err := s.cryptoManager.Decrypt(item.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %q: %w", item.Name, err)
+		}
+
+		switch item.Type {
+		case models.DataTypeLoginPassword:
+			var data models.LoginPasswordData
+			if err := json.Unmarshal(decrypted, &data); err == nil {
+				add(item, "password", data.Password)
+			}
+		case models.DataTypeBankCard:
+			var data models.BankCardData
+			if err := json.Unmarshal(decrypted, &data); err == nil {
+				add(item, "card_number", digitsOnly(data.CardNumber))
+			}
+		case models.DataTypeOTP:
+			var data models.OTPData
+			if err := json.Unmarshal(decrypted, &data); err == nil {
+				add(item, "secret", data.Secret)
+			}
+		case models.DataTypeText:
+			var data models.TextData
+			if err := json.Unmarshal(decrypted, &data); err == nil {
+				// only single-token texts such as API keys; prose would match ordinary words
+				if content := strings.TrimSpace(data.Content); !strings.ContainsAny(content, " \t\r\n") {
+					add(item, "content", content)
+				}
+			}
+		}
+	}
+	return hashes, nil
+}
+
+// ScanPaths checks files under the given paths for values of secrets stored in the vault
+func (s *ClientSession) ScanPaths(ctx context.Context, paths []string) ([]ScanFinding, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+
+	hashes, err := s.secretHashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []ScanFinding
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			fileFindings, err := scanFile(path, hashes)
+			if err != nil {
+				return err
+			}
+			findings = append(findings, fileFindings...)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+		}
+	}
+	return findings, nil
+}
+
+// scanFile checks each line of a text file against the secret hashes
+func scanFile(path string, hashes map[[sha256.Size]byte]scanSecret) ([]ScanFinding, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxScanFileSize || bytes.IndexByte(content, 0) >= 0 {
+		return nil, nil
+	}
+
+	var findings []ScanFinding
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScanFileSize)
+	for line := 1; scanner.Scan(); line++ {
+		seen := make(map[scanSecret]bool)
+		for _, token := range scanTokens(scanner.Text()) {
+			secret, ok := hashes[sha256.Sum256([]byte(token))]
+			if !ok || seen[secret] {
+				continue
+			}
+			seen[secret] = true
+			findings = append(findings, ScanFinding{Path: path, Line: line, Item: secret.item, Field: secret.field})
+		}
+	}
+	return findings, scanner.Err()
+}
+
+// scanTokens returns the normalized candidate values of a line: whitespace or quote
+// delimited words, their parts around assignment separators, and card-like digit runs
+func scanTokens(line string) []string {
+	var tokens []string
+	add := func(token string) {
+		if token = normalizeScanToken(token); len(token) >= minScanTokenLength {
+			tokens = append(tokens, token)
+		}
+	}
+
+	words := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '"' || r == '\'' || r == '`'
+	})
+	for _, word := range words {
+		add(word)
+		parts := strings.FieldsFunc(word, func(r rune) bool {
+			return strings.ContainsRune(scanFieldSeparators, r)
+		})
+		if len(parts) > 1 {
+			for _, part := range parts {
+				add(part)
+			}
+		}
+	}
+
+	for _, digits := range cardNumberPattern.FindAllString(line, -1) {
+		add(digitsOnly(digits))
+	}
+	return tokens
+}
+
+// normalizeScanToken trims whitespace, quotes and trailing punctuation
+func normalizeScanToken(token string) string {
+	return strings.TrimRight(strings.Trim(strings.TrimSpace(token), "\"'`"), ",;")
+}
+
+// digitsOnly drops everything but digits, e.g. the spaces of a card number
+func digitsOnly(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// ScanCommand scans paths for stored secrets and returns the process exit code:
+// 0 when nothing was found, 1 for findings and 2 on errors
+func (s *ClientSession) ScanCommand(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: scan <path> [path...]")
+		return AssertExitError
+	}
+
+	findings, err := s.ScanPaths(ctx, args)
+	if err != nil {
+		s.NotifyResult("scan", "", err)
+		fmt.Printf("ERROR: %v\n", err)
+		return AssertExitError
+	}
+	s.NotifyResult("scan", fmt.Sprintf("%d stored secret(s) found", len(findings)), nil)
+
+	for _, finding := range findings {
+		fmt.Printf("%s:%d: contains the %s of %q\n", finding.Path, finding.Line, finding.Field, finding.Item)
+	}
+	if len(findings) > 0 {
+		fmt.Printf("Found %d stored secret(s); remove them before committing\n", len(findings))
+		return AssertExitFailed
+	}
+	fmt.Println("No stored secrets found")
+	return AssertExitPassed
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestScanTokens(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: `DB_PASSWORD="hunter2hunter2"`, want: []string{"DB_PASSWORD=", "hunter2hunter2"}},
+		{line: `  "api_key": "sk_live_abcdef",`, want: []string{"sk_live_abcdef"}},
+		{line: `card: 4111 1111-1111 1111`, want: []string{"1111-1111", "4111111111111111"}},
+		{line: `short x=y`, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got := scanTokens(tt.line)
+			if len(got) != len(tt.want) {
+				t.Fatalf("scanTokens(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("scanTokens(%q) = %q, want %q", tt.line, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestClientSession_ScanPaths(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	create := func(dataType models.DataType, name string, payload interface{}) {
+		data, _ := json.Marshal(payload)
+		encrypted, err := session.GetCryptoManager().Encrypt(data)
+		if err != nil {
+			t.Fatalf("Encrypt() error = %v", err)
+		}
+		if _, err := session.Create(ctx, models.DataRequest{Type: dataType, Name: name, Data: encrypted}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	create(models.DataTypeLoginPassword, "Staging DB", models.LoginPasswordData{Login: "app", Password: "Tr0ub4dor&3-staging"})
+	create(models.DataTypeText, "Stripe key", models.TextData{Content: "sk_test_4eC39HqLyjWDarjtT1zdp7dc\n"})
+	create(models.DataTypeBankCard, "Corporate card", models.BankCardData{CardNumber: "5555 5555 5555 4444", CVV: "123"})
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"config/app.env": "DB_USER=app\nDB_PASSWORD=Tr0ub4dor&3-staging\n",
+		"src/pay.go":     "const key = \"sk_test_4eC39HqLyjWDarjtT1zdp7dc\"\n// test card 5555-5555-5555-4444\n",
+		"README.md":      "Nothing to see here, the password is in the vault.\n",
+		".git/config":    "Tr0ub4dor&3-staging\n",
+		"image.bin":      "\x00Tr0ub4dor&3-staging",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	findings, err := session.ScanPaths(ctx, []string{dir})
+	if err != nil {
+		t.Fatalf("ScanPaths() error = %v", err)
+	}
+
+	want := map[string]string{
+		filepath.Join(dir, "config/app.env") + ":2": "Staging DB",
+		filepath.Join(dir, "src/pay.go") + ":1":     "Stripe key",
+		filepath.Join(dir, "src/pay.go") + ":2":     "Corporate card",
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("Expected %d findings, got %+v", len(want), findings)
+	}
+	for _, finding := range findings {
+		key := fmt.Sprintf("%s:%d", finding.Path, finding.Line)
+		if want[key] != finding.Item {
+			t.Errorf("Unexpected finding %+v", finding)
+		}
+	}
+
+	session.Lock()
+	if _, err := session.ScanPaths(ctx, []string{dir}); err != ErrNotAuthenticated {
+		t.Errorf("Expected ErrNotAuthenticated, got %v", err)
+	}
+}
@@ -0,0 +1,430 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// Output formats of get and list
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// ItemInput is an item given by flags or as JSON, for creating and updating
+// items from scripts without prompts. On update, empty values keep the
+// current ones and an empty field value clears an optional field.
+type ItemInput struct {
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty"`
+	// File is the path of the file a binary item stores
+	File string `json:"file,omitempty"`
+}
+
+// ItemOutput is the JSON form of an item printed by get and list. Fields,
+// the decrypted payload, are only included by get.
+type ItemOutput struct {
+	ID          string            `json:"id"`
+	Type        models.DataType   `json:"type"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Metadata    string            `json:"metadata,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty"`
+	Revision    int               `json:"revision"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// scriptedFields lists the fields accepted for item types that have no
+// structured editor
+var scriptedFields = map[models.DataType][]editableField{
+	models.DataTypeOTP: {
+		{name: "secret", secret: true, required: true},
+		{name: "issuer"},
+		{name: "account"},
+		{name: "notes"},
+	},
+	models.DataTypeBinary: {
+		{name: "notes"},
+	},
+}
+
+// fieldsOf returns the fields accepted for items of dataType
+func fieldsOf(dataType models.DataType) ([]editableField, bool) {
+	if fields, ok := editableFields[dataType]; ok {
+		return fields, true
+	}
+	fields, ok := scriptedFields[dataType]
+	return fields, ok
+}
+
+// ReadItemInput decodes an ItemInput from JSON. Unknown keys are rejected so
+// that typos do not silently drop values.
+func ReadItemInput(r io.Reader) (ItemInput, error) {
+	var input ItemInput
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&input); err != nil {
+		return ItemInput{}, fmt.Errorf("invalid item JSON: %w", err)
+	}
+	return input, nil
+}
+
+// Merge returns in with the values set in other, e.g. flags given next to a
+// JSON file, taking precedence
+func (in ItemInput) Merge(other ItemInput) ItemInput {
+	if other.Name != "" {
+		in.Name = other.Name
+	}
+	if other.Description != "" {
+		in.Description = other.Description
+	}
+	if other.Environment != "" {
+		in.Environment = other.Environment
+	}
+	if other.Tags != nil {
+		in.Tags = other.Tags
+	}
+	if other.File != "" {
+		in.File = other.File
+	}
+	if len(other.Fields) > 0 {
+		fields := make(map[string]string, len(in.Fields)+len(other.Fields))
+		for name, value := range in.Fields {
+			fields[name] = value
+		}
+		for name, value := range other.Fields {
+			fields[name] = value
+		}
+		in.Fields = fields
+	}
+	return in
+}
+
+// normalize checks the environment and tags of the input
+func (in *ItemInput) normalize() error {
+	if in.Environment != "" {
+		environment, err := NormalizeEnvironment(in.Environment)
+		if err != nil {
+			return err
+		}
+		in.Environment = environment
+	}
+	if in.Tags != nil {
+		tags, err := models.NormalizeTags(in.Tags)
+		if err != nil {
+			return err
+		}
+		if tags == nil {
+			tags = []string{}
+		}
+		in.Tags = tags
+	}
+	return nil
+}
+
+// CreateItem creates an item of dataType from input without prompting.
+// Required fields must be given and unknown ones are rejected.
+func (s *ClientSession) CreateItem(ctx context.Context, dataType models.DataType, input ItemInput) (*models.Data, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	if input.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if err := input.normalize(); err != nil {
+		return nil, err
+	}
+	fields, ok := fieldsOf(dataType)
+	if !ok {
+		return nil, fmt.Errorf("unknown data type: %s", dataType)
+	}
+	if err := checkFieldNames(dataType, fields, input.Fields, nil); err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		if field.required && input.Fields[field.name] == "" {
+			return nil, fmt.Errorf("field %s is required", field.name)
+		}
+	}
+
+	dataReq := models.DataRequest{
+		Type:        dataType,
+		Name:        input.Name,
+		Description: input.Description,
+		Environment: input.Environment,
+	}
+	if input.Tags != nil {
+		tags := input.Tags
+		dataReq.Tags = &tags
+	}
+
+	var content []byte
+	var err error
+	switch dataType {
+	case models.DataTypeBinary:
+		if input.File == "" {
+			return nil, fmt.Errorf("binary items need a file")
+		}
+		if info, statErr := os.Stat(input.File); statErr == nil {
+			if chunkSize := s.chunkSizeFor(ctx, info.Size()); chunkSize > 0 {
+				return s.UploadBinary(ctx, input.File, input.Fields["notes"], dataReq, chunkSize)
+			}
+		}
+		content, dataReq.Metadata, err = readBinaryData(input.File, input.Fields["notes"])
+	case models.DataTypeOTP:
+		content, dataReq.Metadata, err = otpContent(input.Fields)
+	default:
+		payload := make(map[string]interface{}, len(input.Fields))
+		for name, value := range input.Fields {
+			if value != "" {
+				payload[name] = value
+			}
+		}
+		dataReq.Metadata = ItemMetadata(dataType, payload, "")
+		content, err = json.Marshal(payload)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data content: %w", err)
+	}
+
+	if dataReq.Data, err = s.cryptoManager.Encrypt(content); err != nil {
+		return nil, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+	if err := s.checkPayloadSize(ctx, routeCreateData, len(dataReq.Data)+len(dataReq.Metadata)); err != nil {
+		return nil, err
+	}
+	return s.Create(ctx, dataReq)
+}
+
+// otpContent builds the payload of an otp item from its fields; the secret
+// may also be an otpauth:// URI
+func otpContent(fields map[string]string) ([]byte, string, error) {
+	var otpData models.OTPData
+	var err error
+	if secret := fields["secret"]; strings.HasPrefix(secret, "otpauth:") {
+		if otpData, err = ParseOTPAuthURI(secret); err != nil {
+			return nil, "", err
+		}
+	} else if otpData, _, err = normalizeOTP(models.OTPData{Secret: secret}); err != nil {
+		return nil, "", err
+	}
+	if fields["issuer"] != "" {
+		otpData.Issuer = fields["issuer"]
+	}
+	if fields["account"] != "" {
+		otpData.Account = fields["account"]
+	}
+	otpData.Notes = fields["notes"]
+
+	content, err := json.Marshal(otpData)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, fmt.Sprintf("Issuer: %s, Account: %s", otpData.Issuer, otpData.Account), nil
+}
+
+// UpdateItem changes an item by ID or unique ID prefix from input without
+// prompting. Fields are merged into the current payload, and the update
+// fails with ErrConflict if the item changes on another device meanwhile.
+func (s *ClientSession) UpdateItem(ctx context.Context, id string, input ItemInput) (*models.Data, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	if input.File != "" {
+		return nil, fmt.Errorf("the file of a binary item cannot be replaced; create a new item instead")
+	}
+	if err := input.normalize(); err != nil {
+		return nil, err
+	}
+	data, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := s.cryptoManager.Decrypt(data.Data)
+	if err != nil {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": data.ID.String()})
+		return nil, fmt.Errorf("failed to decrypt current data: %w", err)
+	}
+	content := decrypted
+	if len(input.Fields) > 0 {
+		var payload map[string]interface{}
+		if err := json.Unmarshal(decrypted, &payload); err != nil || data.Type == models.DataTypeBinary {
+			return nil, fmt.Errorf("item %q has no fields to update", data.Name)
+		}
+		fields, _ := fieldsOf(data.Type)
+		if err := checkFieldNames(data.Type, fields, input.Fields, payload); err != nil {
+			return nil, err
+		}
+		required := make(map[string]bool)
+		for _, field := range fields {
+			required[field.name] = field.required
+		}
+		for name, value := range input.Fields {
+			switch {
+			case value == "" && required[name]:
+				return nil, fmt.Errorf("field %s is required and cannot be cleared", name)
+			case value == "":
+				delete(payload, name)
+			default:
+				payload[name] = value
+			}
+		}
+		if data.Type == models.DataTypeOTP {
+			otpPayload, _ := json.Marshal(payload)
+			var otpData models.OTPData
+			if err := json.Unmarshal(otpPayload, &otpData); err != nil {
+				return nil, fmt.Errorf("invalid otp fields: %w", err)
+			}
+			if _, _, err := normalizeOTP(otpData); err != nil {
+				return nil, err
+			}
+		}
+		if content, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("failed to marshal data: %w", err)
+		}
+		data.Metadata = ItemMetadata(data.Type, payload, data.Metadata)
+	}
+
+	encrypted, err := s.cryptoManager.Encrypt(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+	dataReq := models.DataRequest{
+		Type:          data.Type,
+		Name:          data.Name,
+		Description:   data.Description,
+		Data:          encrypted,
+		Metadata:      data.Metadata,
+		Environment:   data.Environment,
+		BaseUpdatedAt: &data.UpdatedAt,
+		BaseRevision:  baseRevision(data),
+	}
+	if input.Name != "" {
+		dataReq.Name = input.Name
+	}
+	if input.Description != "" {
+		dataReq.Description = input.Description
+	}
+	if input.Environment != "" {
+		dataReq.Environment = input.Environment
+	}
+	if input.Tags != nil {
+		tags := input.Tags
+		dataReq.Tags = &tags
+	}
+	return s.Update(ctx, data.ID.String(), dataReq)
+}
+
+// checkFieldNames rejects fields that items of dataType do not have and that
+// are not already in the current payload
+func checkFieldNames(dataType models.DataType, fields []editableField, given map[string]string, current map[string]interface{}) error {
+	known := make(map[string]bool, len(fields))
+	var names []string
+	for _, field := range fields {
+		known[field.name] = true
+		names = append(names, field.name)
+	}
+	var unknown []string
+	for name := range given {
+		if _, ok := current[name]; !ok && !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	if len(names) == 0 {
+		return fmt.Errorf("%s items have no fields", dataType)
+	}
+	return fmt.Errorf("unknown field %s for %s items (fields: %s)", strings.Join(unknown, ", "), dataType, strings.Join(names, ", "))
+}
+
+// NewItemOutput returns the JSON form of data without its payload
+func NewItemOutput(data *models.Data) ItemOutput {
+	return ItemOutput{
+		ID:          data.ID.String(),
+		Type:        data.Type,
+		Name:        CleanQuotes(data.Name),
+		Description: CleanQuotes(data.Description),
+		Environment: data.Environment,
+		Tags:        data.Tags,
+		Metadata:    data.Metadata,
+		Revision:    data.Revision,
+		ExpiresAt:   data.ExpiresAt,
+		CreatedAt:   data.CreatedAt,
+		UpdatedAt:   data.UpdatedAt,
+	}
+}
+
+// GetJSONCommand writes an item with its decrypted fields as JSON to w
+func (s *ClientSession) GetJSONCommand(ctx context.Context, w io.Writer, id string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	data, note, err := s.getForDisplay(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+	if note != "" {
+		fmt.Fprintln(os.Stderr, note)
+	}
+	fields, err := s.ItemFields(data)
+	if err != nil {
+		return err
+	}
+
+	output := NewItemOutput(data)
+	output.Fields = make(map[string]string, len(fields))
+	for _, field := range fields {
+		if field.Value != "" {
+			output.Fields[field.Name] = field.Value
+		}
+	}
+	s.recordUsage(data.ID.String())
+	return WriteJSON(w, output)
+}
+
+// ListJSONCommand writes the items of environment, or all for none, as a JSON
+// array to w. Payloads are left out so that listing never prints secrets.
+func (s *ClientSession) ListJSONCommand(ctx context.Context, w io.Writer, environment string) error {
+	data, note, err := s.listForDisplay(ctx, environment)
+	if err != nil {
+		if errors.Is(err, ErrNotAuthenticated) {
+			return err
+		}
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+	if note != "" {
+		fmt.Fprintln(os.Stderr, note)
+	}
+	outputs := make([]ItemOutput, 0, len(data))
+	for i := range data {
+		outputs = append(outputs, NewItemOutput(&data[i]))
+	}
+	return WriteJSON(w, outputs)
+}
+
+// WriteJSON writes v as indented JSON followed by a newline
+func WriteJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
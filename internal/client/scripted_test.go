@@ -0,0 +1,171 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestReadItemInput(t *testing.T) {
+	input, err := ReadItemInput(strings.NewReader(`{"name":"Mail","tags":["work"],"fields":{"login":"alice","password":"s3cret"}}`))
+	if err != nil {
+		t.Fatalf("ReadItemInput() error = %v", err)
+	}
+	merged := input.Merge(ItemInput{Name: "Mail (work)", Fields: map[string]string{"password": "n3w"}})
+	if merged.Name != "Mail (work)" || merged.Fields["login"] != "alice" || merged.Fields["password"] != "n3w" || merged.Tags[0] != "work" {
+		t.Errorf("Unexpected merged input %+v", merged)
+	}
+	if input.Fields["password"] != "s3cret" {
+		t.Error("Expected Merge to leave the original fields alone")
+	}
+
+	if _, err := ReadItemInput(strings.NewReader(`{"name":"Mail","feilds":{}}`)); err == nil {
+		t.Error("Expected an unknown key to be rejected")
+	}
+}
+
+func TestClientSession_CreateItem(t *testing.T) {
+	ctx := context.Background()
+	session := newBatchTestSession(t)
+
+	tests := []struct {
+		name     string
+		dataType models.DataType
+		input    ItemInput
+		wantErr  string
+	}{
+		{name: "login", dataType: models.DataTypeLoginPassword,
+			input: ItemInput{Name: "Mail", Environment: "Prod", Tags: []string{"work"}, Fields: map[string]string{"login": "alice", "password": "s3cret"}}},
+		{name: "otp", dataType: models.DataTypeOTP,
+			input: ItemInput{Name: "2FA", Fields: map[string]string{"secret": "JBSW Y3DP EHPK 3PXP", "issuer": "Example"}}},
+		{name: "missing name", dataType: models.DataTypeText, input: ItemInput{Fields: map[string]string{"content": "x"}}, wantErr: "name is required"},
+		{name: "missing field", dataType: models.DataTypeLoginPassword,
+			input: ItemInput{Name: "Mail", Fields: map[string]string{"login": "alice"}}, wantErr: "field password is required"},
+		{name: "unknown field", dataType: models.DataTypeText,
+			input: ItemInput{Name: "Note", Fields: map[string]string{"content": "x", "body": "y"}}, wantErr: "unknown field body"},
+		{name: "bad otp secret", dataType: models.DataTypeOTP,
+			input: ItemInput{Name: "2FA", Fields: map[string]string{"secret": "not base32!"}}, wantErr: "base32"},
+		{name: "unknown type", dataType: "passkey", input: ItemInput{Name: "Key"}, wantErr: "unknown data type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := session.CreateItem(ctx, tt.dataType, tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateItem() error = %v", err)
+			}
+			fields, err := session.ItemFields(data)
+			if err != nil {
+				t.Fatalf("ItemFields() error = %v", err)
+			}
+			for name, want := range tt.input.Fields {
+				if name == "secret" {
+					continue
+				}
+				if got := fieldValue(fields, name); got != want {
+					t.Errorf("Expected field %s = %q, got %q", name, want, got)
+				}
+			}
+			if tt.dataType == models.DataTypeLoginPassword && (data.Environment != "prod" || data.Metadata != "Login: alice, URL: " || len(data.Tags) != 1) {
+				t.Errorf("Unexpected item %+v", data)
+			}
+		})
+	}
+}
+
+func TestClientSession_UpdateItem(t *testing.T) {
+	ctx := context.Background()
+	session := newBatchTestSession(t)
+
+	data, err := session.CreateItem(ctx, models.DataTypeLoginPassword, ItemInput{
+		Name: "Mail", Tags: []string{"work"}, Fields: map[string]string{"login": "alice", "password": "s3cret", "url": "https://mail.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("CreateItem() error = %v", err)
+	}
+
+	updated, err := session.UpdateItem(ctx, data.ID.String()[:8], ItemInput{Fields: map[string]string{"login": "bob", "url": ""}})
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	fields, _ := session.ItemFields(updated)
+	if fieldValue(fields, "login") != "bob" || fieldValue(fields, "password") != "s3cret" || fieldValue(fields, "url") != "" {
+		t.Errorf("Expected login changed, password kept and url cleared, got %+v", fields)
+	}
+	if updated.Name != "Mail" || updated.Metadata != "Login: bob, URL: " || len(updated.Tags) != 1 {
+		t.Errorf("Unexpected updated item %+v", updated)
+	}
+
+	renamed, err := session.UpdateItem(ctx, data.ID.String(), ItemInput{Name: "Work mail", Tags: []string{}})
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+	if renamed.Name != "Work mail" || len(renamed.Tags) != 0 {
+		t.Errorf("Expected the item renamed and its tags cleared, got %+v", renamed)
+	}
+
+	for _, input := range []ItemInput{
+		{Fields: map[string]string{"password": ""}},
+		{Fields: map[string]string{"pin": "1234"}},
+		{File: "photo.png"},
+	} {
+		if _, err := session.UpdateItem(ctx, data.ID.String(), input); err == nil {
+			t.Errorf("Expected UpdateItem(%+v) to fail", input)
+		}
+	}
+}
+
+func TestClientSession_JSONCommands(t *testing.T) {
+	ctx := context.Background()
+	session := newBatchTestSession(t)
+
+	data, err := session.CreateItem(ctx, models.DataTypeText, ItemInput{Name: "Note", Environment: "dev", Fields: map[string]string{"content": "hello"}})
+	if err != nil {
+		t.Fatalf("CreateItem() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := session.GetJSONCommand(ctx, &out, data.ID.String()); err != nil {
+		t.Fatalf("GetJSONCommand() error = %v", err)
+	}
+	var item ItemOutput
+	if err := json.Unmarshal(out.Bytes(), &item); err != nil {
+		t.Fatalf("Failed to decode %q: %v", out.String(), err)
+	}
+	if item.ID != data.ID.String() || item.Fields["content"] != "hello" || item.Environment != "dev" || item.Revision != 1 {
+		t.Errorf("Unexpected item %+v", item)
+	}
+
+	out.Reset()
+	if err := session.ListJSONCommand(ctx, &out, ""); err != nil {
+		t.Fatalf("ListJSONCommand() error = %v", err)
+	}
+	var items []ItemOutput
+	if err := json.Unmarshal(out.Bytes(), &items); err != nil {
+		t.Fatalf("Failed to decode %q: %v", out.String(), err)
+	}
+	if len(items) != 1 || items[0].Name != "Note" || items[0].Fields != nil {
+		t.Errorf("Expected one item without fields, got %+v", items)
+	}
+	if strings.Contains(out.String(), "hello") {
+		t.Error("Expected the listing not to contain decrypted content")
+	}
+}
+
+func fieldValue(fields []ItemField, name string) string {
+	for _, field := range fields {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return ""
+}
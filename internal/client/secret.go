@@ -0,0 +1,36 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// stdinIsTerminal and readHidden are variables so tests can stand in for a terminal
+var (
+	stdinIsTerminal = func() bool { return term.IsTerminal(int(os.Stdin.Fd())) }
+	readHidden      = func() ([]byte, error) { return term.ReadPassword(int(os.Stdin.Fd())) }
+)
+
+// readSecret prints a prompt and reads a secret without echoing it when stdin
+// is a terminal. Piped input is read from scanner like any other answer, so
+// scripts keep working. It reports false if no input could be read.
+func readSecret(scanner *bufio.Scanner, prompt string) (string, bool) {
+	fmt.Print(prompt)
+	if !stdinIsTerminal() {
+		if !scanner.Scan() {
+			return "", false
+		}
+		return scanner.Text(), true
+	}
+
+	secret, err := readHidden()
+	// the newline typed by the user was not echoed either
+	fmt.Println()
+	if err != nil {
+		return "", false
+	}
+	return string(secret), true
+}
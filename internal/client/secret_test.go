@@ -0,0 +1,41 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadSecret(t *testing.T) {
+	origTerminal, origHidden := stdinIsTerminal, readHidden
+	defer func() {
+		stdinIsTerminal, readHidden = origTerminal, origHidden
+	}()
+
+	scanner := bufio.NewScanner(strings.NewReader("piped\nnext\n"))
+
+	stdinIsTerminal = func() bool { return false }
+	if got, ok := readSecret(scanner, "Secret: "); !ok || got != "piped" {
+		t.Errorf("Expected piped input to be read from the scanner, got %q, %v", got, ok)
+	}
+
+	stdinIsTerminal = func() bool { return true }
+	readHidden = func() ([]byte, error) { return []byte("hidden"), nil }
+	if got, ok := readSecret(scanner, "Secret: "); !ok || got != "hidden" {
+		t.Errorf("Expected a terminal to be read without echo, got %q, %v", got, ok)
+	}
+	if !scanner.Scan() || scanner.Text() != "next" {
+		t.Error("Expected the scanner to be left alone when reading from a terminal")
+	}
+
+	readHidden = func() ([]byte, error) { return nil, fmt.Errorf("not a tty") }
+	if _, ok := readSecret(scanner, "Secret: "); ok {
+		t.Error("Expected a failed terminal read to be reported")
+	}
+
+	stdinIsTerminal = func() bool { return false }
+	if _, ok := readSecret(scanner, "Secret: "); ok {
+		t.Error("Expected no input to be reported")
+	}
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	securityLogFile = ".gophkeeper_security.log"
+)
+
+// Security event types recorded in the local audit file
+const (
+	EventUnlock                = "unlock"
+	EventLock                  = "lock"
+	EventMasterPasswordFailed  = "master_password_failed"
+	EventExport                = "export"
+	EventWipe                  = "wipe"
+	EventMasterPasswordRotated = "master_password_rotated"
+)
+
+// SecurityEvent represents a single entry of the local security log
+type SecurityEvent struct {
+	Time     time.Time         `json:"time"`
+	Event    string            `json:"event"`
+	Details  map[string]string `json:"details,omitempty"`
+	PrevHash string            `json:"prev_hash"`
+	Hash     string            `json:"hash"`
+}
+
+// SecurityLog is an append-only, hash-chained log of local security events
+type SecurityLog struct {
+	path     string
+	lastHash string
+	mutex    sync.Mutex
+}
+
+// NewSecurityLog opens the security log at path and restores the hash chain head
+func NewSecurityLog(path string) (*SecurityLog, error) {
+	events, err := ReadSecurityLog(path)
+	if err != nil {
+		return nil, err
+	}
+
+	log := &SecurityLog{path: path}
+	if len(events) > 0 {
+		log.lastHash = events[len(events)-1].Hash
+	}
+	return log, nil
+}
+
+// GetSecurityLogPath returns the path to the security log file
+func GetSecurityLogPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return securityLogFile
+	}
+	return fmt.Sprintf("%s/%s", homeDir, securityLogFile)
+}
+
+// Append records an event, chaining it to the previous entry
+func (l *SecurityLog) Append(event string, details map[string]string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entry := SecurityEvent{
+		Time:     time.Now().UTC(),
+		Event:    event,
+		Details:  details,
+		PrevHash: l.lastHash,
+	}
+	hash, err := hashSecurityEvent(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal security event: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open security log: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write security event: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close security log: %w", err)
+	}
+
+	l.lastHash = hash
+	return nil
+}
+
+// ReadSecurityLog reads all entries of the security log at path
+func ReadSecurityLog(path string) ([]SecurityEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open security log: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var events []SecurityEvent
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		var event SecurityEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("malformed security log entry at line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read security log: %w", err)
+	}
+	return events, nil
+}
+
+// VerifySecurityLog checks the hash chain and returns the index of the first broken entry, or -1
+func VerifySecurityLog(events []SecurityEvent) (int, error) {
+	prevHash := ""
+	for i, event := range events {
+		if event.PrevHash != prevHash {
+			return i, fmt.Errorf("entry %d does not link to the previous entry", i+1)
+		}
+		hash, err := hashSecurityEvent(event)
+		if err != nil {
+			return i, err
+		}
+		if hash != event.Hash {
+			return i, fmt.Errorf("entry %d has been modified", i+1)
+		}
+		prevHash = event.Hash
+	}
+	return -1, nil
+}
+
+// hashSecurityEvent computes the chain hash of an event, excluding its own hash
+func hashSecurityEvent(event SecurityEvent) (string, error) {
+	event.Hash = ""
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal security event: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SecurityLogCommand handles displaying and verifying the local security log
+func (s *ClientSession) SecurityLogCommand(verify bool) error {
+	if s.securityLog == nil {
+		return fmt.Errorf("security log is not enabled")
+	}
+
+	events, err := ReadSecurityLog(s.securityLog.path)
+	if err != nil {
+		return err
+	}
+
+	if verify {
+		if _, err := VerifySecurityLog(events); err != nil {
+			return fmt.Errorf("security log integrity check failed: %w", err)
+		}
+		fmt.Printf("Security log intact: %d entries verified\n", len(events))
+		return nil
+	}
+
+	if len(events) == 0 {
+		fmt.Println("No security events recorded")
+		return nil
+	}
+
+	for _, event := range events {
+		fmt.Printf("  %s %-22s", event.Time.Local().Format("2006-01-02 15:04:05"), event.Event)
+		keys := make([]string, 0, len(event.Details))
+		for key := range event.Details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf(" %s=%s", key, event.Details[key])
+		}
+		fmt.Println()
+	}
+	return nil
+}
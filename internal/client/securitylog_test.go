@@ -0,0 +1,92 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecurityLog_AppendAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "security.log")
+
+	log, err := NewSecurityLog(path)
+	if err != nil {
+		t.Fatalf("NewSecurityLog() error = %v", err)
+	}
+
+	if err := log.Append(EventUnlock, map[string]string{"username": "alice"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := log.Append(EventMasterPasswordFailed, nil); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	reopened, err := NewSecurityLog(path)
+	if err != nil {
+		t.Fatalf("NewSecurityLog() error = %v", err)
+	}
+	if err := reopened.Append(EventLock, nil); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	events, err := ReadSecurityLog(path)
+	if err != nil {
+		t.Fatalf("ReadSecurityLog() error = %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	if events[2].PrevHash != events[1].Hash {
+		t.Error("Reopened log did not continue the hash chain")
+	}
+
+	if idx, err := VerifySecurityLog(events); err != nil || idx != -1 {
+		t.Errorf("VerifySecurityLog() = %d, %v, want -1, nil", idx, err)
+	}
+}
+
+func TestSecurityLog_DetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "security.log")
+
+	log, err := NewSecurityLog(path)
+	if err != nil {
+		t.Fatalf("NewSecurityLog() error = %v", err)
+	}
+	for _, event := range []string{EventUnlock, EventExport, EventLock} {
+		if err := log.Append(event, nil); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	tampered := strings.Replace(string(content), `"event":"export"`, `"event":"unlock"`, 1)
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	events, err := ReadSecurityLog(path)
+	if err != nil {
+		t.Fatalf("ReadSecurityLog() error = %v", err)
+	}
+	idx, err := VerifySecurityLog(events)
+	if err == nil {
+		t.Fatal("Expected tampering to be detected")
+	}
+	if idx != 1 {
+		t.Errorf("Expected broken entry index 1, got %d", idx)
+	}
+}
+
+func TestReadSecurityLog_Missing(t *testing.T) {
+	events, err := ReadSecurityLog(filepath.Join(t.TempDir(), "missing.log"))
+	if err != nil {
+		t.Errorf("ReadSecurityLog() error = %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no events, got %d", len(events))
+	}
+}
@@ -2,9 +2,15 @@ package client
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
 
+	"github.com/a2sh3r/gophkeeper/internal/client/localstore"
 	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
 	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
 )
 
 // ClientSession represents a client session with authentication and encryption
@@ -12,13 +18,49 @@ type ClientSession struct {
 	cli            *Client
 	cryptoManager  *crypto.CryptoManager
 	masterPassword string
+	securityLog    *SecurityLog
+	notifier       Notifier
+	clipboard      Clipboard
+	usagePath      string
+	// pwned checks new passwords against known breaches when set
+	pwned *PwnedChecker
+	// icons fetches the favicons of new logins when set
+	icons *IconFetcher
+	// keys caches the vault keys derived in the session until it locks
+	keys *crypto.KeyCache
+	// kdfIterations is the PBKDF2 iteration count of new vault keys, on
+	// registration and rotation; 0 means the default count
+	kdfIterations int
+
+	indexMu         sync.Mutex
+	indexPath       string
+	indexRefreshing bool
+
+	localStore *localstore.Store
 }
 
 // NewClientSession creates a new client session
 func NewClientSession(cli *Client) *ClientSession {
 	return &ClientSession{
-		cli: cli,
+		cli:  cli,
+		keys: crypto.NewKeyCache(),
+	}
+}
+
+// SetKDFIterations sets the PBKDF2 iteration count of the vault keys of new
+// accounts and master passwords; 0 means the default count
+func (s *ClientSession) SetKDFIterations(iterations int) error {
+	if err := crypto.CheckIterations(iterations); err != nil {
+		return err
 	}
+	s.kdfIterations = iterations
+	return nil
+}
+
+// deriveCryptoManager derives the vault key of masterPassword under salt with
+// iterations of PBKDF2, reusing a key derived before in the session
+func (s *ClientSession) deriveCryptoManager(masterPassword string, salt []byte, iterations int) (*crypto.CryptoManager, error) {
+	return crypto.NewCryptoManagerWithOptions(masterPassword, salt, crypto.KeyOptions{Iterations: iterations, Cache: s.keys})
 }
 
 // SetCryptoManager sets the crypto manager for the session
@@ -27,6 +69,68 @@ func (s *ClientSession) SetCryptoManager(cryptoManager *crypto.CryptoManager, ma
 	s.masterPassword = masterPassword
 }
 
+// Unlock initializes encryption from the stored salt without a new login
+func (s *ClientSession) Unlock(masterPassword, salt string) error {
+	return s.UnlockWithIterations(masterPassword, salt, 0)
+}
+
+// UnlockWithIterations initializes encryption from the stored salt and
+// PBKDF2 iteration count without a new login
+func (s *ClientSession) UnlockWithIterations(masterPassword, salt string, iterations int) error {
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return fmt.Errorf("failed to decode salt: %w", err)
+	}
+
+	cryptoManager, err := s.deriveCryptoManager(masterPassword, saltBytes, iterations)
+	if err != nil {
+		return fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+
+	s.SetCryptoManager(cryptoManager, masterPassword)
+	s.recordEvent(EventUnlock, map[string]string{"action": "unlock"})
+	return nil
+}
+
+// SetSecurityLog sets the local security event log for the session
+func (s *ClientSession) SetSecurityLog(securityLog *SecurityLog) {
+	s.securityLog = securityLog
+}
+
+// Lock drops the crypto manager and master password from memory
+func (s *ClientSession) Lock() {
+	s.lock(nil)
+}
+
+// AutoLock locks the session on a system event such as sleep and reports whether it was unlocked
+func (s *ClientSession) AutoLock(reason string) bool {
+	return s.lock(map[string]string{"reason": reason})
+}
+
+// lock drops the key material and records the lock event if the session was unlocked
+func (s *ClientSession) lock(details map[string]string) bool {
+	if !s.IsAuthenticated() {
+		return false
+	}
+	s.cryptoManager = nil
+	s.masterPassword = ""
+	if s.keys != nil {
+		s.keys.Purge()
+	}
+	s.recordEvent(EventLock, details)
+	return true
+}
+
+// recordEvent appends an event to the security log if one is configured
+func (s *ClientSession) recordEvent(event string, details map[string]string) {
+	if s.securityLog == nil {
+		return
+	}
+	if err := s.securityLog.Append(event, details); err != nil {
+		logger.Log.Error("Failed to record security event", zap.Error(err), zap.String("event", event))
+	}
+}
+
 // IsAuthenticated checks if the session is authenticated with crypto manager
 func (s *ClientSession) IsAuthenticated() bool {
 	return s.cryptoManager != nil
@@ -42,9 +146,10 @@ func (s *ClientSession) GetCryptoManager() *crypto.CryptoManager {
 	return s.cryptoManager
 }
 
-// Register registers a new user
+// Register registers a new user, whose vault key is derived with the
+// iteration count of SetKDFIterations
 func (s *ClientSession) Register(ctx context.Context, username, password, masterPassword string) (*models.AuthResponse, error) {
-	return s.cli.Register(ctx, username, password, masterPassword)
+	return s.cli.RegisterWithIterations(ctx, username, password, masterPassword, s.kdfIterations)
 }
 
 // Login authenticates user
@@ -54,17 +159,36 @@ func (s *ClientSession) Login(ctx context.Context, username, password string) (*
 
 // List gets all user data
 func (s *ClientSession) List(ctx context.Context) ([]models.Data, error) {
+	return s.ListFiltered(ctx, models.DataFilter{})
+}
+
+// ListFiltered gets user data matching the filter
+func (s *ClientSession) ListFiltered(ctx context.Context, filter models.DataFilter) ([]models.Data, error) {
 	if !s.IsAuthenticated() {
 		return nil, ErrNotAuthenticated
 	}
-	return s.cli.GetData(ctx)
+	items, err := s.cli.GetDataFiltered(ctx, filter)
+	if err == nil && filter == (models.DataFilter{}) {
+		s.saveIndex(s.cryptoManager, items)
+		s.cacheItems(items)
+	}
+	return items, err
 }
 
-// Get gets data by ID
+// Search gets user data whose name, description or metadata contain query
+func (s *ClientSession) Search(ctx context.Context, query string) ([]models.Data, error) {
+	return s.ListFiltered(ctx, models.DataFilter{Query: query})
+}
+
+// Get gets data by ID or unique ID prefix
 func (s *ClientSession) Get(ctx context.Context, id string) (*models.Data, error) {
 	if !s.IsAuthenticated() {
 		return nil, ErrNotAuthenticated
 	}
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 	return s.cli.GetDataByID(ctx, id)
 }
 
@@ -73,21 +197,50 @@ func (s *ClientSession) Create(ctx context.Context, dataReq models.DataRequest)
 	if !s.IsAuthenticated() {
 		return nil, ErrNotAuthenticated
 	}
+	if err := s.withChecksum(&dataReq); err != nil {
+		return nil, err
+	}
+	if err := s.withDomains(&dataReq); err != nil {
+		return nil, err
+	}
+	s.withCardExpiry(&dataReq)
+	s.withIcon(ctx, &dataReq)
 	return s.cli.CreateData(ctx, dataReq)
 }
 
-// Update updates data
+// Update updates data by ID or unique ID prefix
 func (s *ClientSession) Update(ctx context.Context, id string, dataReq models.DataRequest) (*models.Data, error) {
 	if !s.IsAuthenticated() {
 		return nil, ErrNotAuthenticated
 	}
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.keepSharedDataKey(ctx, id, &dataReq); err != nil {
+		return nil, err
+	}
+	if err := s.withChecksum(&dataReq); err != nil {
+		return nil, err
+	}
+	if err := s.withDomains(&dataReq); err != nil {
+		return nil, err
+	}
+	s.withCardExpiry(&dataReq)
+	if err := s.checkPayloadSize(ctx, routeUpdateData, len(dataReq.Data)+len(dataReq.Metadata)); err != nil {
+		return nil, err
+	}
 	return s.cli.UpdateData(ctx, id, dataReq)
 }
 
-// Delete deletes data
+// Delete deletes data by ID or unique ID prefix
 func (s *ClientSession) Delete(ctx context.Context, id string) error {
 	if !s.IsAuthenticated() {
 		return ErrNotAuthenticated
 	}
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return err
+	}
 	return s.cli.DeleteData(ctx, id)
 }
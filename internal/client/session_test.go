@@ -1,7 +1,10 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"path/filepath"
 	"testing"
 
 	"github.com/a2sh3r/gophkeeper/internal/crypto"
@@ -187,3 +190,179 @@ func TestClientSession_Delete_NotAuthenticated(t *testing.T) {
 		t.Errorf("Expected ErrNotAuthenticated, got %v", err)
 	}
 }
+
+func TestClientSession_Lock(t *testing.T) {
+	cli := NewClient("http://localhost:8080")
+	session := NewClientSession(cli)
+
+	securityLog, err := NewSecurityLog(filepath.Join(t.TempDir(), "security.log"))
+	if err != nil {
+		t.Fatalf("NewSecurityLog() error = %v", err)
+	}
+	session.SetSecurityLog(securityLog)
+
+	cryptoManager, err := crypto.NewCryptoManager("testpassword123")
+	if err != nil {
+		t.Fatalf("Failed to create crypto manager: %v", err)
+	}
+	session.SetCryptoManager(cryptoManager, "testpassword123")
+
+	session.Lock()
+	session.Lock()
+
+	if session.IsAuthenticated() {
+		t.Error("Session should not be authenticated after lock")
+	}
+	if session.masterPassword != "" {
+		t.Error("MasterPassword should be wiped after lock")
+	}
+
+	events, err := ReadSecurityLog(securityLog.path)
+	if err != nil {
+		t.Fatalf("ReadSecurityLog() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Event != EventLock {
+		t.Errorf("Expected a single lock event, got %v", events)
+	}
+}
+
+func TestClientSession_AutoLock(t *testing.T) {
+	session := NewClientSession(NewClient("http://localhost:8080"))
+	securityLog, err := NewSecurityLog(filepath.Join(t.TempDir(), "security.log"))
+	if err != nil {
+		t.Fatalf("NewSecurityLog() error = %v", err)
+	}
+	session.SetSecurityLog(securityLog)
+
+	if session.AutoLock(LockReasonSleep) {
+		t.Error("AutoLock() should report false for a locked session")
+	}
+
+	cryptoManager, err := crypto.NewCryptoManager("testpassword123")
+	if err != nil {
+		t.Fatalf("Failed to create crypto manager: %v", err)
+	}
+	session.SetCryptoManager(cryptoManager, "testpassword123")
+
+	if !session.AutoLock(LockReasonScreenLock) {
+		t.Error("AutoLock() should report true for an unlocked session")
+	}
+	if session.IsAuthenticated() || session.masterPassword != "" {
+		t.Error("Key material should be dropped after AutoLock()")
+	}
+
+	events, err := ReadSecurityLog(securityLog.path)
+	if err != nil {
+		t.Fatalf("ReadSecurityLog() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Details["reason"] != LockReasonScreenLock {
+		t.Errorf("Expected a single screen-lock event, got %v", events)
+	}
+}
+
+func TestClientSession_Unlock(t *testing.T) {
+	cli := NewClient("http://localhost:8080")
+	session := NewClientSession(cli)
+
+	if err := session.Unlock("testpassword123", "not-base64!"); err == nil {
+		t.Error("Expected error for invalid salt")
+	}
+
+	cryptoManager, err := crypto.NewCryptoManager("testpassword123")
+	if err != nil {
+		t.Fatalf("Failed to create crypto manager: %v", err)
+	}
+
+	if err := session.Unlock("testpassword123", cryptoManager.GetSaltBase64()); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if !session.IsAuthenticated() {
+		t.Error("Session should be authenticated after unlock")
+	}
+}
+
+func TestClientSession_UnlockWithIterations(t *testing.T) {
+	session := NewClientSession(NewClient("http://localhost:8080"))
+
+	if err := session.SetKDFIterations(crypto.MinIterations - 1); err == nil {
+		t.Error("Expected error for too few iterations")
+	}
+	if err := session.SetKDFIterations(200000); err != nil {
+		t.Fatalf("SetKDFIterations() error = %v", err)
+	}
+
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		t.Fatalf("GenerateSalt() error = %v", err)
+	}
+	want, err := crypto.NewCryptoManagerWithOptions("testpassword123", salt, crypto.KeyOptions{Iterations: 200000})
+	if err != nil {
+		t.Fatalf("NewCryptoManagerWithOptions() error = %v", err)
+	}
+	encodedSalt := base64.StdEncoding.EncodeToString(salt)
+
+	for i := 0; i < 2; i++ {
+		if err := session.UnlockWithIterations("testpassword123", encodedSalt, 200000); err != nil {
+			t.Fatalf("UnlockWithIterations() error = %v", err)
+		}
+		if !bytes.Equal(session.GetCryptoManager().Key(), want.Key()) {
+			t.Fatal("Unlock derived a different key")
+		}
+		if session.GetCryptoManager().Iterations() != 200000 {
+			t.Errorf("Expected 200000 iterations, got %d", session.GetCryptoManager().Iterations())
+		}
+		if session.keys.Len() != 1 {
+			t.Errorf("Expected the key to be cached once, got %d keys", session.keys.Len())
+		}
+		session.cryptoManager = nil
+	}
+
+	if err := session.Unlock("testpassword123", encodedSalt); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if bytes.Equal(session.GetCryptoManager().Key(), want.Key()) {
+		t.Error("Keys of different iteration counts should differ")
+	}
+
+	session.Lock()
+	if session.keys.Len() != 0 {
+		t.Errorf("Expected lock to purge the cached keys, got %d", session.keys.Len())
+	}
+}
+
+func TestClientSession_Search(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "example", want: []string{"Demo Visa", "Demo Email"}},
+		{query: "SECURE NOTE", want: []string{"Wi-Fi Notes"}},
+		{query: "purple-elephant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			items, err := session.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			var names []string
+			for _, item := range items {
+				names = append(names, item.Name)
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("Search(%q) = %q, want %q", tt.query, names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Errorf("Search(%q) = %q, want %q", tt.query, names, tt.want)
+				}
+			}
+		})
+	}
+}
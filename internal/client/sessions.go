@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// GetSessions gets the active logins of the user
+func (c *Client) GetSessions(ctx context.Context) ([]models.Session, error) {
+	var resp models.SessionsResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/sessions", nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Sessions, nil
+}
+
+// DeleteSession revokes a login of the user
+func (c *Client) DeleteSession(ctx context.Context, sessionID string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/v1/sessions/"+sessionID, nil, nil, http.StatusNoContent)
+}
+
+// Sessions gets the active logins of the user, most recently seen first
+func (s *ClientSession) Sessions(ctx context.Context) ([]models.Session, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !hasFeature(status, "sessions") {
+		return nil, fmt.Errorf("this server does not track sessions")
+	}
+	return s.cli.GetSessions(ctx)
+}
+
+// RevokeSession logs out the session with a unique prefix of its ID, which
+// may be the current one. It returns the revoked session.
+func (s *ClientSession) RevokeSession(ctx context.Context, ref string) (*models.Session, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if len(ref) < minShortIDLength {
+		return nil, fmt.Errorf("session ID prefix %q is too short, use at least %d characters", ref, minShortIDLength)
+	}
+	sessions, err := s.Sessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var match *models.Session
+	for i := range sessions {
+		if strings.HasPrefix(sessions[i].ID.String(), ref) {
+			if match != nil {
+				return nil, fmt.Errorf("session ID prefix %q is ambiguous", ref)
+			}
+			match = &sessions[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no session has an ID starting with %q", ref)
+	}
+
+	if err := s.cli.DeleteSession(ctx, match.ID.String()); err != nil {
+		return nil, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return match, nil
+}
+
+// SessionsCommand lists the logins of the user, or revokes one:
+// sessions, sessions revoke <id>
+func (s *ClientSession) SessionsCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		sessions, err := s.Sessions(ctx)
+		if err != nil {
+			return err
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No active sessions")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tDEVICE\tCLIENT\tLAST SEEN\tSINCE\t")
+		for _, session := range sessions {
+			current := ""
+			if session.Current {
+				current = "(current)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", session.ID.String()[:minShortIDLength],
+				sessionLabel(session.Device), sessionLabel(session.UserAgent),
+				session.LastSeenAt.Local().Format("2006-01-02 15:04"), session.CreatedAt.Local().Format("2006-01-02 15:04"), current)
+		}
+		return w.Flush()
+	}
+
+	switch args[0] {
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: sessions revoke <id>")
+		}
+		session, err := s.RevokeSession(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if session.Current {
+			fmt.Println("Revoked the current session; log in again to continue")
+			return nil
+		}
+		fmt.Printf("Revoked the session of %s\n", sessionLabel(session.Device))
+		return nil
+	default:
+		return fmt.Errorf("unknown sessions action: %s (use list or revoke)", args[0])
+	}
+}
+
+// sessionLabel prints a device name or user agent reported by a client,
+// which may be missing or hold control characters
+func sessionLabel(label string) string {
+	label = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, label))
+	if label == "" {
+		return "unknown"
+	}
+	return label
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestClientSession_Sessions(t *testing.T) {
+	ctx := context.Background()
+	router, err := newDemoRouter()
+	if err != nil {
+		t.Fatalf("newDemoRouter() error = %v", err)
+	}
+	newSession := func() *ClientSession {
+		cli := NewClient(demoServerURL)
+		cli.httpClient.Transport = &handlerTransport{handler: router}
+		return NewClientSession(cli)
+	}
+
+	first := newSession()
+	resp, err := first.Register(ctx, "traveller", "login-password", "master-password")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	first.GetClient().SetToken(resp.Token)
+	if err := first.Unlock("master-password", resp.Salt); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	second := newSession()
+	resp, err = second.Login(ctx, "traveller", "login-password")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	second.GetClient().SetToken(resp.Token)
+	if err := second.Unlock("master-password", resp.Salt); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	sessions, err := first.Sessions(ctx)
+	if err != nil {
+		t.Fatalf("Sessions() error = %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %+v", sessions)
+	}
+	hostname, _ := os.Hostname()
+	var other string
+	for _, session := range sessions {
+		if session.Device != hostname || !strings.HasPrefix(session.UserAgent, "gophkeeper/") {
+			t.Errorf("Expected the device and client to be recorded, got %+v", session)
+		}
+		if !session.Current {
+			other = session.ID.String()
+		}
+	}
+	if other == "" {
+		t.Fatal("Expected one session to be marked current")
+	}
+
+	if _, err := first.RevokeSession(ctx, "zz"); err == nil {
+		t.Error("Expected a too short session ID prefix to be refused")
+	}
+	if _, err := first.RevokeSession(ctx, "00000000"); err == nil {
+		t.Error("Expected an unknown session ID prefix to be refused")
+	}
+	revoked, err := first.RevokeSession(ctx, other[:minShortIDLength])
+	if err != nil {
+		t.Fatalf("RevokeSession() error = %v", err)
+	}
+	if revoked.ID.String() != other {
+		t.Errorf("Expected session %s to be revoked, got %s", other, revoked.ID)
+	}
+
+	if _, err := second.List(ctx); err == nil || !strings.Contains(err.Error(), "Session revoked") {
+		t.Errorf("Expected the revoked session to be logged out, got %v", err)
+	}
+	if sessions, err := first.Sessions(ctx); err != nil || len(sessions) != 1 || !sessions[0].Current {
+		t.Errorf("Expected only the current session left, got %+v, %v", sessions, err)
+	}
+}
+
+func TestSessionLabel(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{label: "laptop", want: "laptop"},
+		{label: "", want: "unknown"},
+		{label: "evil\x1b[2J\tname", want: "evil [2J name"},
+		{label: "\n", want: "unknown"},
+	}
+	for _, tt := range tests {
+		if got := sessionLabel(tt.label); got != tt.want {
+			t.Errorf("sessionLabel(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
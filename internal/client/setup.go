@@ -0,0 +1,200 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	generatedMasterPasswordLength = 20
+)
+
+// RecoveryKit holds the information needed to regain access to the vault
+type RecoveryKit struct {
+	ServerURL string
+	Username  string
+	Salt      string
+	CreatedAt time.Time
+}
+
+// WriteRecoveryKit writes a printable recovery kit
+func WriteRecoveryKit(w io.Writer, kit RecoveryKit) error {
+	lines := []string{
+		"==================== GophKeeper Recovery Kit ====================",
+		fmt.Sprintf("Created:          %s", kit.CreatedAt.Format("2006-01-02 15:04:05")),
+		fmt.Sprintf("Server:           %s", kit.ServerURL),
+		fmt.Sprintf("Username:         %s", kit.Username),
+		fmt.Sprintf("Encryption salt:  %s", kit.Salt),
+		"Master password:  ______________________________________",
+		"",
+		"Write your master password by hand and keep this sheet offline.",
+		"The server never sees your master password: if it is lost,",
+		"your encrypted data cannot be recovered.",
+		"=================================================================",
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetupCommand walks a new user through server selection, registration,
+// master password creation and the first vault item
+func (s *ClientSession) SetupCommand(ctx context.Context, config *Config) error {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Println("Welcome to GophKeeper! This wizard sets up your encrypted vault.")
+	fmt.Println()
+
+	fmt.Println("Step 1/5: Server")
+	serverURL, err := promptLine(scanner, fmt.Sprintf("Server URL [%s]: ", s.cli.baseURL))
+	if err != nil {
+		return err
+	}
+	if serverURL != "" {
+		s.cli.SetBaseURL(strings.TrimRight(serverURL, "/"))
+	}
+	config.ServerURL = s.cli.baseURL
+
+	fmt.Println()
+	fmt.Println("Step 2/5: Account")
+	username, err := promptLine(scanner, "Choose a username: ")
+	if err != nil {
+		return err
+	}
+	password, err := promptLine(scanner, "Choose an account password: ")
+	if err != nil {
+		return err
+	}
+	if username == "" || password == "" {
+		return fmt.Errorf("username and password are required")
+	}
+	if err := s.checkCredentials(ctx, username, password); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("Step 3/5: Master password")
+	fmt.Println("The master password encrypts your data locally and is never sent in plain form.")
+	masterPassword, err := promptMasterPassword(scanner)
+	if err != nil {
+		return err
+	}
+
+	if err := s.registerWithMasterPassword(ctx, username, password, masterPassword, config); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("Step 4/5: Recovery kit")
+	kit := RecoveryKit{
+		ServerURL: config.ServerURL,
+		Username:  username,
+		Salt:      config.Salt,
+		CreatedAt: time.Now(),
+	}
+	if err := WriteRecoveryKit(os.Stdout, kit); err != nil {
+		return fmt.Errorf("failed to print recovery kit: %w", err)
+	}
+	if _, err := promptLine(scanner, "Press Enter once you have stored the recovery kit safely..."); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("Step 5/5: First item")
+	answer, err := promptLine(scanner, "Create your first item now? (Y/n): ")
+	if err != nil {
+		return err
+	}
+	if answer != "" && strings.ToLower(answer) != "y" && strings.ToLower(answer) != "yes" {
+		fmt.Println("Setup complete. Type 'help' to see what you can do next.")
+		return nil
+	}
+
+	dataType, err := promptLine(scanner, "Type (login_password, text, binary, bank_card, otp) [login_password]: ")
+	if err != nil {
+		return err
+	}
+	if dataType == "" {
+		dataType = "login_password"
+	}
+	name, err := promptLine(scanner, "Name: ")
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("item name is required")
+	}
+
+	if err := s.CreateCommand(ctx, dataType, name, "", config.DefaultEnvironment); err != nil {
+		return err
+	}
+
+	fmt.Println("Setup complete. Type 'list' to see your vault or 'help' for all commands.")
+	return nil
+}
+
+// promptMasterPassword offers a generated master password or reads a custom one,
+// showing its estimated strength
+func promptMasterPassword(scanner *bufio.Scanner) (string, error) {
+	for {
+		generated, err := GeneratePassword(generatedMasterPasswordLength)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Printf("Suggested master password: %s\n", generated)
+		fmt.Printf("Strength: %s\n", EntropyMeter(EstimateEntropy(generated)))
+		answer, err := promptLine(scanner, "Use it (Enter), regenerate (r) or type your own (o)? ")
+		if err != nil {
+			return "", err
+		}
+
+		switch strings.ToLower(answer) {
+		case "":
+			return generated, nil
+		case "r":
+			continue
+		case "o":
+			custom, ok := readSecret(scanner, "Enter master password (min 8 characters): ")
+			if !ok {
+				return "", fmt.Errorf("setup aborted: no input")
+			}
+			custom = strings.TrimSpace(custom)
+			bits := EstimateEntropy(custom)
+			fmt.Printf("Strength: %s\n", EntropyMeter(bits))
+			if len(custom) < 8 {
+				fmt.Println("Master password must be at least 8 characters long")
+				continue
+			}
+			if bits < 60 {
+				confirm, err := promptLine(scanner, "This password is easy to guess. Use it anyway? (y/N): ")
+				if err != nil {
+					return "", err
+				}
+				if strings.ToLower(confirm) != "y" {
+					continue
+				}
+			}
+			return custom, nil
+		default:
+			fmt.Println("Please answer Enter, r or o")
+		}
+	}
+}
+
+// promptLine prints a prompt and reads a trimmed line
+func promptLine(scanner *bufio.Scanner, prompt string) (string, error) {
+	fmt.Print(prompt)
+	if !scanner.Scan() {
+		return "", fmt.Errorf("setup aborted: no input")
+	}
+	return strings.TrimSpace(scanner.Text()), nil
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRecoveryKit(t *testing.T) {
+	var buf bytes.Buffer
+	kit := RecoveryKit{
+		ServerURL: "https://vault.example.com",
+		Username:  "alice",
+		Salt:      "c2FsdA==",
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	if err := WriteRecoveryKit(&buf, kit); err != nil {
+		t.Fatalf("WriteRecoveryKit() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"https://vault.example.com", "alice", "c2FsdA==", "2024-05-01 12:00:00", "Master password:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Recovery kit missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestPromptMasterPassword(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "custom strong password",
+			input: "o\nx7#Kp2!qLm9$Vw4@Zr\n",
+			want:  "x7#Kp2!qLm9$Vw4@Zr",
+		},
+		{
+			name:  "weak password confirmed",
+			input: "o\npassword\ny\n",
+			want:  "password",
+		},
+		{
+			name:  "too short then regenerate and custom",
+			input: "o\nshort\nr\no\nx7#Kp2!qLm9$Vw4@Zr\n",
+			want:  "x7#Kp2!qLm9$Vw4@Zr",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := bufio.NewScanner(strings.NewReader(tt.input))
+			got, err := promptMasterPassword(scanner)
+			if err != nil {
+				t.Fatalf("promptMasterPassword() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("promptMasterPassword() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptMasterPassword_Generated(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("\n"))
+	got, err := promptMasterPassword(scanner)
+	if err != nil {
+		t.Fatalf("promptMasterPassword() error = %v", err)
+	}
+	if len(got) != generatedMasterPasswordLength {
+		t.Errorf("Expected generated password of length %d, got %q", generatedMasterPasswordLength, got)
+	}
+}
+
+func TestPromptLine_NoInput(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader(""))
+	if _, err := promptLine(scanner, "prompt: "); err == nil {
+		t.Error("Expected error on empty input")
+	}
+}
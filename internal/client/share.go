@@ -0,0 +1,590 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// SetShareKey publishes the share key pair items are shared to
+func (c *Client) SetShareKey(ctx context.Context, keys models.ShareKeys) error {
+	return c.doJSON(ctx, http.MethodPut, "/api/v1/share/key", keys, nil, http.StatusNoContent)
+}
+
+// GetShareKey gets the user's share key pair, empty if none is set
+func (c *Client) GetShareKey(ctx context.Context) (*models.ShareKeys, error) {
+	var keys models.ShareKeys
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/share/key", nil, &keys, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &keys, nil
+}
+
+// GetUserShareKey gets the share public key of another user
+func (c *Client) GetUserShareKey(ctx context.Context, username string) ([]byte, error) {
+	var keys models.ShareKeys
+	path := "/api/v1/users/" + url.PathEscape(username) + "/share-key"
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &keys, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return keys.PublicKey, nil
+}
+
+// CreateShare shares an item with a user, or changes their existing share
+func (c *Client) CreateShare(ctx context.Context, dataID string, req models.ShareRequest) (*models.Share, error) {
+	var resp models.ShareResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/data/"+dataID+"/shares", req, &resp, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &resp.Share, nil
+}
+
+// GetShares gets the shares of an item, oldest first
+func (c *Client) GetShares(ctx context.Context, dataID string) ([]models.Share, error) {
+	var resp models.SharesResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/data/"+dataID+"/shares", nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Shares, nil
+}
+
+// DeleteShare revokes a share of an item
+func (c *Client) DeleteShare(ctx context.Context, dataID, shareID string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/v1/data/"+dataID+"/shares/"+shareID, nil, nil, http.StatusNoContent)
+}
+
+// GetSharedItems gets the items other users shared with the user
+func (c *Client) GetSharedItems(ctx context.Context) ([]models.SharedItem, error) {
+	var resp models.SharedItemsResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/shared", nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+// UpdateSharedItem changes an item shared with the user in write mode
+func (c *Client) UpdateSharedItem(ctx context.Context, shareID string, sharedReq models.SharedItemRequest) (*models.Data, error) {
+	jsonData, err := json.Marshal(sharedReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.baseURL+"/api/v1/shared/"+shareID, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusConflict {
+		return nil, ErrConflict
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, body)
+	}
+
+	var dataResp models.DataResponse
+	if err := json.Unmarshal(body, &dataResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &dataResp.Data, nil
+}
+
+// publishShareKey creates the user's share key pair on first use. Servers
+// without sharing reject it, which only means nothing can be shared with them.
+func (s *ClientSession) publishShareKey(ctx context.Context) {
+	keys, err := s.cli.GetShareKey(ctx)
+	if err == nil && len(keys.PublicKey) > 0 {
+		return
+	}
+	if err == nil {
+		var publicKey, privateKey, encrypted []byte
+		publicKey, privateKey, err = crypto.GenerateShareKeyPair()
+		if err == nil {
+			encrypted, err = s.cryptoManager.Encrypt(privateKey)
+		}
+		if err == nil {
+			err = s.cli.SetShareKey(ctx, models.ShareKeys{PublicKey: publicKey, PrivateKey: encrypted})
+		}
+	}
+	if err != nil {
+		logger.Log.Warn("Failed to publish share key", zap.Error(err))
+	}
+}
+
+// sharePrivateKey gets and decrypts the user's share private key
+func (s *ClientSession) sharePrivateKey(ctx context.Context) ([]byte, error) {
+	keys, err := s.cli.GetShareKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share key: %w", err)
+	}
+	if len(keys.PrivateKey) == 0 {
+		return nil, fmt.Errorf("no share key yet; log in again to create one")
+	}
+	privateKey, err := s.cryptoManager.Decrypt(keys.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt share key: %w", err)
+	}
+	return privateKey, nil
+}
+
+// keepSharedDataKey re-encrypts the payload of an update to a shared item
+// under the item's current data key, so its recipients can still read it
+func (s *ClientSession) keepSharedDataKey(ctx context.Context, id string, dataReq *models.DataRequest) error {
+	shares, err := s.cli.GetShares(ctx, id)
+	if err != nil {
+		// servers without sharing have no shares to keep
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get shares: %w", err)
+	}
+	if len(shares) == 0 {
+		return nil
+	}
+
+	current, err := s.cli.GetDataByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+	if bytes.Equal(current.Data, dataReq.Data) {
+		return nil
+	}
+	plaintext, err := s.cryptoManager.Decrypt(dataReq.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt new data: %w", err)
+	}
+	resealed, err := s.cryptoManager.EncryptWithDataKeyOf(current.Data, plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt for the users it is shared with: %w", err)
+	}
+	dataReq.Data = resealed
+	return nil
+}
+
+// ShareItem shares an item with another user in read or write mode by
+// sealing its data key to their share public key. Items still encrypted
+// directly under the vault key are re-encrypted with a data key first. It
+// returns the share and the fingerprint of the recipient's key, which they
+// can compare out of band.
+func (s *ClientSession) ShareItem(ctx context.Context, id, username, mode string) (*models.Share, string, error) {
+	if !s.IsAuthenticated() {
+		return nil, "", ErrNotAuthenticated
+	}
+	if mode != models.ShareModeRead && mode != models.ShareModeWrite {
+		return nil, "", fmt.Errorf("unknown share mode %q (use read or write)", mode)
+	}
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if !hasFeature(status, "sharing") {
+		return nil, "", fmt.Errorf("this server does not support sharing")
+	}
+
+	data, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get data: %w", err)
+	}
+	if data.Type == models.DataTypeBinary {
+		var binaryData models.BinaryData
+		if err := json.Unmarshal([]byte(data.Metadata), &binaryData); err == nil && binaryData.Chunks > 0 {
+			return nil, "", fmt.Errorf("large files uploaded in chunks cannot be shared")
+		}
+	}
+
+	publicKey, err := s.cli.GetUserShareKey(ctx, username)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get the share key of %s: %w", username, err)
+	}
+
+	dataKey, err := s.cryptoManager.DataKey(data.Data)
+	if err != nil {
+		if data, err = s.reencryptItem(ctx, data); err != nil {
+			return nil, "", err
+		}
+		if dataKey, err = s.cryptoManager.DataKey(data.Data); err != nil {
+			return nil, "", err
+		}
+	}
+	sealed, err := crypto.SealDataKey(publicKey, dataKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	share, err := s.cli.CreateShare(ctx, data.ID.String(), models.ShareRequest{Username: username, Mode: mode, SealedKey: sealed})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to share: %w", err)
+	}
+	return share, crypto.RecoveryKeyID(publicKey), nil
+}
+
+// reencryptItem encrypts the payload of an item again under a fresh data key
+func (s *ClientSession) reencryptItem(ctx context.Context, data *models.Data) (*models.Data, error) {
+	plaintext, err := s.cryptoManager.Decrypt(data.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	ciphertext, err := s.cryptoManager.Encrypt(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+	dataReq := models.DataRequest{
+		Type:          data.Type,
+		Name:          data.Name,
+		Description:   data.Description,
+		Data:          ciphertext,
+		Metadata:      data.Metadata,
+		Environment:   data.Environment,
+		BaseUpdatedAt: &data.UpdatedAt,
+		BaseRevision:  baseRevision(data),
+	}
+	if err := s.withChecksum(&dataReq); err != nil {
+		return nil, err
+	}
+	if err := s.withDomains(&dataReq); err != nil {
+		return nil, err
+	}
+	updated, err := s.cli.UpdateData(ctx, data.ID.String(), dataReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-encrypt data: %w", err)
+	}
+	return updated, nil
+}
+
+// ItemShares gets the shares of an item
+func (s *ClientSession) ItemShares(ctx context.Context, id string) ([]models.Share, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.cli.GetShares(ctx, id)
+}
+
+// RevokeShare revokes the share of an item with a user, then re-encrypts the
+// item under a fresh data key and seals that to the remaining recipients, so
+// the data key the user may have kept opens no later change.
+func (s *ClientSession) RevokeShare(ctx context.Context, id, username string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return err
+	}
+	shares, err := s.cli.GetShares(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get shares: %w", err)
+	}
+
+	var revoked *models.Share
+	var remaining []models.Share
+	for i := range shares {
+		if shares[i].Recipient == username {
+			revoked = &shares[i]
+		} else {
+			remaining = append(remaining, shares[i])
+		}
+	}
+	if revoked == nil {
+		return fmt.Errorf("the item is not shared with %s", username)
+	}
+	if err := s.cli.DeleteShare(ctx, id, revoked.ID.String()); err != nil {
+		return fmt.Errorf("failed to revoke share: %w", err)
+	}
+
+	// a recipient with write access may change the item meanwhile
+	const attempts = 3
+	var data *models.Data
+	for attempt := 1; ; attempt++ {
+		current, err := s.cli.GetDataByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("share revoked, but failed to get data to re-key: %w", err)
+		}
+		data, err = s.reencryptItem(ctx, current)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrConflict) || attempt == attempts {
+			return fmt.Errorf("share revoked, but %w", err)
+		}
+	}
+
+	dataKey, err := s.cryptoManager.DataKey(data.Data)
+	if err != nil {
+		return err
+	}
+	for _, share := range remaining {
+		publicKey, err := s.cli.GetUserShareKey(ctx, share.Recipient)
+		if err == nil {
+			var sealed []byte
+			if sealed, err = crypto.SealDataKey(publicKey, dataKey); err == nil {
+				_, err = s.cli.CreateShare(ctx, id, models.ShareRequest{Username: share.Recipient, Mode: share.Mode, SealedKey: sealed})
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("share revoked, but failed to re-share with %s: %w", share.Recipient, err)
+		}
+	}
+	return nil
+}
+
+// SharedEntry is an item shared with the user, with its payload decrypted
+type SharedEntry struct {
+	models.SharedItem
+	Plaintext []byte
+	dataKey   []byte
+}
+
+// SharedItems gets the items other users shared with the user and decrypts
+// them with the data keys sealed to the user's share key
+func (s *ClientSession) SharedItems(ctx context.Context) ([]SharedEntry, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	items, err := s.cli.GetSharedItems(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shared items: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	privateKey, err := s.sharePrivateKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]SharedEntry, 0, len(items))
+	for _, item := range items {
+		dataKey, err := crypto.OpenDataKey(privateKey, item.Share.SealedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the key of %q: %w", CleanQuotes(item.Data.Name), err)
+		}
+		plaintext, err := crypto.DecryptWithDataKey(dataKey, item.Data.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %q: %w", CleanQuotes(item.Data.Name), err)
+		}
+		entries = append(entries, SharedEntry{SharedItem: item, Plaintext: plaintext, dataKey: dataKey})
+	}
+	return entries, nil
+}
+
+// sharedEntry finds an item shared with the user by a unique prefix of its share ID
+func (s *ClientSession) sharedEntry(ctx context.Context, ref string) (*SharedEntry, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if len(ref) < minShortIDLength {
+		return nil, fmt.Errorf("share ID prefix %q is too short, use at least %d characters", ref, minShortIDLength)
+	}
+	entries, err := s.SharedItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var match *SharedEntry
+	for i := range entries {
+		if strings.HasPrefix(entries[i].Share.ID.String(), ref) {
+			if match != nil {
+				return nil, fmt.Errorf("share ID prefix %q is ambiguous", ref)
+			}
+			match = &entries[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no shared item has a share ID starting with %q", ref)
+	}
+	return match, nil
+}
+
+// UpdateSharedItem changes the name, description and payload fields of an
+// item shared with the user in write mode. The payload stays under the
+// item's data key, so its owner and other recipients can still read it.
+func (s *ClientSession) UpdateSharedItem(ctx context.Context, ref string, changes map[string]string) (*models.Data, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+	entry, err := s.sharedEntry(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Share.Mode != models.ShareModeWrite {
+		return nil, fmt.Errorf("%q is shared with you read-only", CleanQuotes(entry.Data.Name))
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(entry.Plaintext, &payload); err != nil {
+		return nil, fmt.Errorf("item %q has no structured fields", entry.Data.Name)
+	}
+	name, description := entry.Data.Name, entry.Data.Description
+	for field, value := range changes {
+		switch field {
+		case "name":
+			name = value
+		case "description":
+			description = value
+		default:
+			if value == "" {
+				delete(payload, field)
+			} else {
+				payload[field] = value
+			}
+		}
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("name cannot be empty")
+	}
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data: %w", err)
+	}
+	ciphertext, err := crypto.ResealWithDataKey(entry.dataKey, entry.Data.Data, content)
+	if err != nil {
+		return nil, err
+	}
+	return s.cli.UpdateSharedItem(ctx, entry.Share.ID.String(), models.SharedItemRequest{
+		Name:         name,
+		Description:  description,
+		Data:         ciphertext,
+		Metadata:     ItemMetadata(entry.Data.Type, payload, entry.Data.Metadata),
+		BaseRevision: entry.Data.Revision,
+		Checksum:     crypto.Checksum(entry.dataKey, content),
+	})
+}
+
+// ShareCommand shares an item with a user: share <id> <username> [read|write]
+func (s *ClientSession) ShareCommand(ctx context.Context, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("usage: share <id> <username> [read|write]")
+	}
+	mode := models.ShareModeRead
+	if len(args) == 3 {
+		mode = args[2]
+	}
+
+	share, fingerprint, err := s.ShareItem(ctx, args[0], args[1], mode)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Shared with %s (%s), share ID %s\n", share.Recipient, share.Mode, share.ID)
+	fmt.Printf("Their share key fingerprint is %s; compare it with them to be sure the server did not swap it\n", fingerprint)
+	return nil
+}
+
+// SharesCommand lists whom an item is shared with
+func (s *ClientSession) SharesCommand(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: shares <id>")
+	}
+	shares, err := s.ItemShares(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if len(shares) == 0 {
+		fmt.Println("The item is not shared")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USER\tMODE\tSINCE\tSHARE ID")
+	for _, share := range shares {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", share.Recipient, share.Mode, share.CreatedAt.Format("2006-01-02 15:04"), share.ID)
+	}
+	return w.Flush()
+}
+
+// UnshareCommand revokes the share of an item with a user
+func (s *ClientSession) UnshareCommand(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: unshare <id> <username>")
+	}
+	if err := s.RevokeShare(ctx, args[0], args[1]); err != nil {
+		return err
+	}
+	fmt.Printf("Stopped sharing with %s; the item was re-encrypted under a new key\n", args[1])
+	return nil
+}
+
+// SharedCommand lists, shows or edits the items shared with the user:
+// shared, shared show <share-id>, shared edit <share-id> <field>=<value>...
+func (s *ClientSession) SharedCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		entries, err := s.SharedItems(ctx)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("Nothing is shared with you")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SHARE ID\tOWNER\tTYPE\tMODE\tNAME")
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.Share.ID.String()[:minShortIDLength], entry.Owner,
+				entry.Data.Type, entry.Share.Mode, CleanQuotes(entry.Data.Name))
+		}
+		return w.Flush()
+	}
+
+	switch args[0] {
+	case "show":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: shared show <share-id>")
+		}
+		entry, err := s.sharedEntry(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Shared by %s (%s)\n", entry.Owner, entry.Share.Mode)
+		writeDecryptedData(os.Stdout, &entry.Data, entry.Plaintext, true)
+		return nil
+	case "edit":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: shared edit <share-id> <field>=<value>...")
+		}
+		changes := make(map[string]string)
+		for _, arg := range args[2:] {
+			field, value, ok := strings.Cut(arg, "=")
+			if !ok || field == "" {
+				return fmt.Errorf("invalid change %q, use <field>=<value>", arg)
+			}
+			changes[field] = value
+		}
+		updated, err := s.UpdateSharedItem(ctx, args[1], changes)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Updated %q\n", CleanQuotes(updated.Name))
+		return nil
+	default:
+		return fmt.Errorf("unknown shared action: %s (use list, show or edit)", args[0])
+	}
+}
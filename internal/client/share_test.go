@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestClientSession_Sharing(t *testing.T) {
+	ctx := context.Background()
+	router, err := newDemoRouter()
+	if err != nil {
+		t.Fatalf("newDemoRouter() error = %v", err)
+	}
+	login := func(username, masterPassword string) *ClientSession {
+		cli := NewClient(demoServerURL)
+		cli.httpClient.Transport = &handlerTransport{handler: router}
+		resp, err := cli.Register(ctx, username, "login-password", masterPassword)
+		if err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+		cli.SetToken(resp.Token)
+		session := NewClientSession(cli)
+		if err := session.Unlock(masterPassword, resp.Salt); err != nil {
+			t.Fatalf("Unlock() error = %v", err)
+		}
+		session.publishShareKey(ctx)
+		return session
+	}
+	owner := login("owner", "owner-master-password")
+	recipient := login("recipient", "recipient-master-password")
+	other := login("other", "other-master-password")
+
+	payload := func(session *ClientSession, content string) []byte {
+		encrypted, err := session.GetCryptoManager().Encrypt([]byte(`{"content":"` + content + `"}`))
+		if err != nil {
+			t.Fatalf("Encrypt() error = %v", err)
+		}
+		return encrypted
+	}
+	data, err := owner.Create(ctx, models.DataRequest{Type: models.DataTypeText, Name: "Runbook", Data: payload(owner, "v1")})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	sharedContent := func(session *ClientSession) []string {
+		t.Helper()
+		entries, err := session.SharedItems(ctx)
+		if err != nil {
+			t.Fatalf("SharedItems() error = %v", err)
+		}
+		var contents []string
+		for _, entry := range entries {
+			var text models.TextData
+			if err := json.Unmarshal(entry.Plaintext, &text); err != nil {
+				t.Fatalf("Unexpected shared payload %q", entry.Plaintext)
+			}
+			contents = append(contents, text.Content)
+		}
+		return contents
+	}
+
+	if _, _, err := owner.ShareItem(ctx, data.ID.String(), "recipient", "admin"); err == nil {
+		t.Error("Expected an unknown mode to be refused")
+	}
+	share, fingerprint, err := owner.ShareItem(ctx, data.ID.String(), "recipient", models.ShareModeRead)
+	if err != nil {
+		t.Fatalf("ShareItem() error = %v", err)
+	}
+	if share.Recipient != "recipient" || len(fingerprint) != 16 {
+		t.Errorf("Unexpected share %+v with fingerprint %q", share, fingerprint)
+	}
+	if got := sharedContent(recipient); len(got) != 1 || got[0] != "v1" {
+		t.Fatalf("Expected the recipient to read the item, got %v", got)
+	}
+	if got := sharedContent(other); len(got) != 0 {
+		t.Errorf("Expected nothing shared with other users, got %v", got)
+	}
+	ref := share.ID.String()[:minShortIDLength]
+	if _, err := recipient.UpdateSharedItem(ctx, ref, map[string]string{"content": "edited"}); err == nil {
+		t.Error("Expected a read-only share to refuse changes")
+	}
+
+	// the owner's own update keeps the data key the recipient holds
+	current, _ := owner.Get(ctx, data.ID.String())
+	if _, err := owner.Update(ctx, data.ID.String(), models.DataRequest{Type: models.DataTypeText, Name: "Runbook",
+		Data: payload(owner, "v2"), BaseRevision: baseRevision(current)}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := sharedContent(recipient); len(got) != 1 || got[0] != "v2" {
+		t.Fatalf("Expected the recipient to read the owner's update, got %v", got)
+	}
+
+	if _, _, err := owner.ShareItem(ctx, data.ID.String(), "recipient", models.ShareModeWrite); err != nil {
+		t.Fatalf("ShareItem() error = %v", err)
+	}
+	if _, err := recipient.UpdateSharedItem(ctx, ref, map[string]string{"content": "v3", "name": "Runbook 2"}); err != nil {
+		t.Fatalf("UpdateSharedItem() error = %v", err)
+	}
+	updated, _ := owner.Get(ctx, data.ID.String())
+	plaintext, err := owner.GetCryptoManager().Decrypt(updated.Data)
+	if err != nil || string(plaintext) != `{"content":"v3"}` || updated.Name != "Runbook 2" {
+		t.Fatalf("Expected the owner to read the recipient's change, got %q %q, %v", updated.Name, plaintext, err)
+	}
+
+	// the share key stays readable after the recipient changes their master password
+	if _, _, err := recipient.RotateMasterPassword(ctx, "new-recipient-master-password"); err != nil {
+		t.Fatalf("RotateMasterPassword() error = %v", err)
+	}
+	if got := sharedContent(recipient); len(got) != 1 || got[0] != "v3" {
+		t.Fatalf("Expected the shared item after rotation, got %v", got)
+	}
+
+	if _, _, err := owner.ShareItem(ctx, data.ID.String(), "other", models.ShareModeRead); err != nil {
+		t.Fatalf("ShareItem() error = %v", err)
+	}
+	before, _ := owner.Get(ctx, data.ID.String())
+	oldKey, _ := owner.GetCryptoManager().DataKey(before.Data)
+	if err := owner.RevokeShare(ctx, data.ID.String(), "recipient"); err != nil {
+		t.Fatalf("RevokeShare() error = %v", err)
+	}
+	if got := sharedContent(recipient); len(got) != 0 {
+		t.Errorf("Expected nothing shared after revoking, got %v", got)
+	}
+	after, _ := owner.Get(ctx, data.ID.String())
+	if newKey, _ := owner.GetCryptoManager().DataKey(after.Data); string(newKey) == string(oldKey) {
+		t.Error("Expected the item to be re-keyed after revoking")
+	}
+	if got := sharedContent(other); len(got) != 1 || got[0] != "v3" {
+		t.Errorf("Expected the remaining recipient to read the re-keyed item, got %v", got)
+	}
+	if err := owner.RevokeShare(ctx, data.ID.String(), "recipient"); err == nil {
+		t.Error("Expected revoking twice to fail")
+	}
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+// minShortIDLength is the shortest ID prefix shown or accepted, as with git
+const minShortIDLength = 4
+
+// ShortIDs returns the shortest prefixes, at least minShortIDLength long, that
+// tell the items apart, keyed by full ID. All prefixes share one length so they
+// line up in list output.
+func ShortIDs(items []models.Data) map[string]string {
+	ids := make([]string, len(items))
+	for i := range items {
+		ids[i] = items[i].ID.String()
+	}
+
+	length := minShortIDLength
+	for ; length < len(uuid.Nil.String()); length++ {
+		seen := make(map[string]bool, len(ids))
+		unique := true
+		for _, id := range ids {
+			if seen[id[:length]] {
+				unique = false
+				break
+			}
+			seen[id[:length]] = true
+		}
+		if unique {
+			break
+		}
+	}
+
+	short := make(map[string]string, len(ids))
+	for _, id := range ids {
+		short[id] = id[:length]
+	}
+	return short
+}
+
+// resolveID turns a full ID or a unique ID prefix into a full ID. Full IDs are
+// returned as is, prefixes are matched against the item list, or the local index
+// while it is being refreshed.
+func (s *ClientSession) resolveID(ctx context.Context, ref string) (string, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if _, err := uuid.Parse(ref); err == nil {
+		return ref, nil
+	}
+	if len(ref) < minShortIDLength {
+		return "", fmt.Errorf("ID prefix %q is too short, use at least %d characters", ref, minShortIDLength)
+	}
+
+	items, _, err := s.listForDisplay(ctx, "")
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for i := range items {
+		if id := items[i].ID.String(); strings.HasPrefix(id, ref) {
+			matches = append(matches, fmt.Sprintf("%s %s", id, CleanQuotes(items[i].Name)))
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no item ID starts with %q", ref)
+	case 1:
+		return strings.Fields(matches[0])[0], nil
+	default:
+		return "", fmt.Errorf("ID prefix %q is ambiguous, it matches:\n  %s", ref, strings.Join(matches, "\n  "))
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestShortIDs(t *testing.T) {
+	items := []models.Data{
+		{ID: uuid.MustParse("5f3a1111-0000-4000-8000-000000000001")},
+		{ID: uuid.MustParse("9c000000-0000-4000-8000-000000000002")},
+	}
+	short := ShortIDs(items)
+	if got := short[items[0].ID.String()]; got != "5f3a" {
+		t.Errorf("Expected 5f3a, got %q", got)
+	}
+
+	items = append(items, models.Data{ID: uuid.MustParse("5f3a2222-0000-4000-8000-000000000003")})
+	short = ShortIDs(items)
+	for _, item := range items {
+		if got := short[item.ID.String()]; len(got) != 5 {
+			t.Errorf("Expected 5 character prefixes once 5f3a is shared, got %q", got)
+		}
+	}
+
+	items = append(items, models.Data{ID: uuid.MustParse("5f3a2222-0000-4000-8000-000000000004")})
+	short = ShortIDs(items)
+	if short[items[2].ID.String()] == short[items[3].ID.String()] {
+		t.Errorf("Expected distinct prefixes, got %v", short)
+	}
+}
+
+func TestClientSession_ResolveID(t *testing.T) {
+	ctx := context.Background()
+	items := []models.Data{
+		{ID: uuid.MustParse("5f3a1111-0000-4000-8000-000000000001"), Name: "github"},
+		{ID: uuid.MustParse("5f3a2222-0000-4000-8000-000000000002"), Name: "gitlab"},
+		{ID: uuid.MustParse("9c000000-0000-4000-8000-000000000003"), Name: "visa"},
+	}
+
+	listed := 0
+	cli := NewClient("http://shortid.invalid")
+	cli.httpClient.Transport = &handlerTransport{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listed++
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(models.DataListResponse{Data: items})
+	})}
+	cryptoManager, err := crypto.NewCryptoManager("testpassword123")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	session := NewClientSession(cli)
+	session.SetCryptoManager(cryptoManager, "testpassword123")
+
+	full := items[2].ID.String()
+	if got, err := session.resolveID(ctx, full); err != nil || got != full {
+		t.Errorf("resolveID(full) = %q, %v", got, err)
+	}
+	if listed != 0 {
+		t.Error("Full IDs should not need the item list")
+	}
+
+	if got, err := session.resolveID(ctx, "9C00"); err != nil || got != full {
+		t.Errorf("resolveID(9C00) = %q, %v", got, err)
+	}
+	if got, err := session.resolveID(ctx, "5f3a2"); err != nil || got != items[1].ID.String() {
+		t.Errorf("resolveID(5f3a2) = %q, %v", got, err)
+	}
+
+	_, err = session.resolveID(ctx, "5f3a")
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") || !strings.Contains(err.Error(), "gitlab") {
+		t.Errorf("Expected ambiguity error listing the matches, got %v", err)
+	}
+	if _, err := session.resolveID(ctx, "abcd"); err == nil {
+		t.Error("Expected error for unknown prefix")
+	}
+	if _, err := session.resolveID(ctx, "5f"); err == nil {
+		t.Error("Expected error for too short prefix")
+	}
+}
+
+func TestClientSession_GetByShortID(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	items, err := session.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	short := ShortIDs(items)
+
+	for _, item := range items {
+		got, err := session.Get(ctx, short[item.ID.String()])
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", short[item.ID.String()], err)
+		}
+		if got.ID != item.ID {
+			t.Errorf("Get(%s) returned %s, want %s", short[item.ID.String()], got.ID, item.ID)
+		}
+	}
+}
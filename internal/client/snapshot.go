@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+// snapshotTimeLayouts lists accepted formats for snapshot timestamps
+var snapshotTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// snapshotAuditPage is the number of audit events read per request by snapshot diffs
+const snapshotAuditPage = 1000
+
+// SnapshotDiff represents changes in the vault between two points in time
+type SnapshotDiff struct {
+	From    time.Time
+	To      time.Time
+	Added   []SnapshotEntry
+	Changed []SnapshotEntry
+	Removed []SnapshotEntry
+}
+
+// SnapshotEntry is an item added, changed or removed between two points in
+// time, with the time of its last such change. Removed items have no type.
+type SnapshotEntry struct {
+	ID   uuid.UUID
+	Type models.DataType
+	Name string
+	At   time.Time
+}
+
+// SnapshotHistory is the recorded history of the vault a snapshot diff is
+// built from besides the current items
+type SnapshotHistory struct {
+	// Versions holds the previous versions of items by item ID
+	Versions map[uuid.UUID][]models.DataVersion
+	// Events holds the audit events of the compared period
+	Events []AuditEntry
+}
+
+// ParseSnapshotTime parses a snapshot timestamp in local time
+func ParseSnapshotTime(value string) (time.Time, error) {
+	for _, layout := range snapshotTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected YYYY-MM-DD, 'YYYY-MM-DD HH:MM:SS' or RFC3339", value)
+}
+
+// DiffSnapshots compares the vault state at from and to. Items created in
+// between are added; items that existed at from are changed when an update
+// to them, current, kept as a previous version or audited, falls in between;
+// items deleted in between are removed when they existed at from.
+func DiffSnapshots(items []models.Data, history SnapshotHistory, from, to time.Time) (*SnapshotDiff, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("start time must be before end time")
+	}
+	within := func(t time.Time) bool { return t.After(from) && !t.After(to) }
+
+	// the latest audited update of each item, and the items created or deleted in between
+	updated := make(map[uuid.UUID]time.Time)
+	created := make(map[uuid.UUID]bool)
+	deleted := make(map[uuid.UUID]AuditEntry)
+	for _, event := range history.Events {
+		if event.DataID == nil || !within(event.CreatedAt) {
+			continue
+		}
+		id := *event.DataID
+		switch event.Action {
+		case models.AuditDataCreate:
+			created[id] = true
+		case models.AuditDataUpdate:
+			if event.CreatedAt.After(updated[id]) {
+				updated[id] = event.CreatedAt
+			}
+		case models.AuditDataDelete:
+			if latest, ok := deleted[id]; !ok || event.CreatedAt.After(latest.CreatedAt) {
+				deleted[id] = event
+			}
+		}
+	}
+
+	diff := &SnapshotDiff{From: from, To: to}
+	current := make(map[uuid.UUID]bool, len(items))
+	for _, item := range items {
+		current[item.ID] = true
+		entry := SnapshotEntry{ID: item.ID, Type: item.Type, Name: item.Name}
+		switch {
+		case within(item.CreatedAt):
+			entry.At = item.CreatedAt
+			diff.Added = append(diff.Added, entry)
+		case !item.CreatedAt.After(from):
+			changes := []time.Time{item.UpdatedAt, updated[item.ID]}
+			for _, version := range history.Versions[item.ID] {
+				changes = append(changes, version.UpdatedAt)
+			}
+			for _, at := range changes {
+				if within(at) && at.After(entry.At) {
+					entry.At = at
+				}
+			}
+			if !entry.At.IsZero() {
+				diff.Changed = append(diff.Changed, entry)
+			}
+		}
+	}
+	for id, event := range deleted {
+		if current[id] || created[id] {
+			continue
+		}
+		name := event.ItemName
+		if !event.Sealed {
+			name = id.String()
+		}
+		diff.Removed = append(diff.Removed, SnapshotEntry{ID: id, Name: name, At: event.CreatedAt})
+	}
+
+	for _, entries := range [][]SnapshotEntry{diff.Added, diff.Changed, diff.Removed} {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].At.Before(entries[j].At) })
+	}
+
+	return diff, nil
+}
+
+// SnapshotDiff compares the vault state at from and to, reading the previous
+// versions of the items that changed since from and the audit log in between
+func (s *ClientSession) SnapshotDiff(ctx context.Context, from, to time.Time) (*SnapshotDiff, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+
+	data, err := s.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+
+	// only items changed after to can have versions from in between that are
+	// not their current one
+	history := SnapshotHistory{Versions: make(map[uuid.UUID][]models.DataVersion)}
+	for _, item := range data {
+		if item.CreatedAt.After(from) || !item.UpdatedAt.After(to) {
+			continue
+		}
+		versions, err := s.cli.GetDataVersions(ctx, item.ID.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get history: %w", err)
+		}
+		history.Versions[item.ID] = versions
+	}
+
+	// events come newest first, so each page ends before the oldest event of
+	// the last one; the end of a page is exclusive, the end of the diff is not
+	var events []models.AuditEvent
+	until := to.Add(time.Nanosecond)
+	for {
+		page, err := s.cli.GetAuditEventsBetween(ctx, from, until, snapshotAuditPage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get audit log: %w", err)
+		}
+		events = append(events, page...)
+		if len(page) < snapshotAuditPage {
+			break
+		}
+		until = page[len(page)-1].CreatedAt
+	}
+	if history.Events, err = s.openAuditEvents(events); err != nil {
+		return nil, err
+	}
+
+	return DiffSnapshots(data, history, from, to)
+}
+
+// SnapshotDiffCommand handles showing vault changes between two timestamps
+func (s *ClientSession) SnapshotDiffCommand(ctx context.Context, from, to string, details bool) error {
+	fromTime, err := ParseSnapshotTime(from)
+	if err != nil {
+		return err
+	}
+	toTime, err := ParseSnapshotTime(to)
+	if err != nil {
+		return err
+	}
+
+	diff, err := s.SnapshotDiff(ctx, fromTime, toTime)
+	if err != nil {
+		return err
+	}
+	writeSnapshotDiff(os.Stdout, diff, details)
+	return nil
+}
+
+// writeSnapshotDiff renders a snapshot diff to w
+func writeSnapshotDiff(w io.Writer, diff *SnapshotDiff, details bool) {
+	fmt.Fprintf(w, "Changes between %s and %s:\n", diff.From.Format("2006-01-02 15:04:05"), diff.To.Format("2006-01-02 15:04:05"))
+	writeSnapshotSection(w, "Added", diff.Added, details)
+	writeSnapshotSection(w, "Changed", diff.Changed, details)
+	writeSnapshotSection(w, "Removed", diff.Removed, details)
+}
+
+// writeSnapshotSection renders a group of snapshot diff entries to w
+func writeSnapshotSection(w io.Writer, title string, entries []SnapshotEntry, details bool) {
+	fmt.Fprintf(w, "%s (%d):\n", title, len(entries))
+	for _, entry := range entries {
+		if !details {
+			fmt.Fprintf(w, "  %s\n", CleanQuotes(entry.Name))
+			continue
+		}
+		kind := ""
+		if entry.Type != "" {
+			kind = " [" + string(entry.Type) + "]"
+		}
+		fmt.Fprintf(w, "  %s%s - %s (%s)\n", entry.ID.String(), kind, CleanQuotes(entry.Name),
+			entry.At.Format("2006-01-02 15:04:05"))
+	}
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestParseSnapshotTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "date", value: "2024-05-01"},
+		{name: "date time", value: "2024-05-01 10:30:00"},
+		{name: "rfc3339", value: "2024-05-01T10:30:00Z"},
+		{name: "invalid", value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSnapshotTime(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSnapshotTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	items := []models.Data{
+		{ID: uuid.New(), Name: "old untouched", CreatedAt: from.AddDate(0, -1, 0), UpdatedAt: from.AddDate(0, -1, 0)},
+		{ID: uuid.New(), Name: "old changed", CreatedAt: from.AddDate(0, -1, 0), UpdatedAt: from.AddDate(0, 0, 2)},
+		{ID: uuid.New(), Name: "new", CreatedAt: from.AddDate(0, 0, 1), UpdatedAt: from.AddDate(0, 0, 3)},
+		{ID: uuid.New(), Name: "future", CreatedAt: to.AddDate(0, 0, 1), UpdatedAt: to.AddDate(0, 0, 1)},
+		{ID: uuid.New(), Name: "changed later", CreatedAt: from.AddDate(0, -1, 0), UpdatedAt: to.AddDate(0, 0, 1)},
+	}
+
+	diff, err := DiffSnapshots(items, SnapshotHistory{}, from, to)
+	if err != nil {
+		t.Fatalf("DiffSnapshots() error = %v", err)
+	}
+
+	if len(diff.Added) != 1 || diff.Added[0].Name != "new" {
+		t.Errorf("Expected only 'new' to be added, got %v", diff.Added)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "old changed" {
+		t.Errorf("Expected only 'old changed' to be changed, got %v", diff.Changed)
+	}
+
+	if len(diff.Removed) != 0 {
+		t.Errorf("Expected nothing removed without audit events, got %v", diff.Removed)
+	}
+
+	if _, err := DiffSnapshots(items, SnapshotHistory{}, to, from); err == nil {
+		t.Error("Expected error for reversed time range")
+	}
+}
+
+func TestDiffSnapshots_History(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	before, inside, after := from.AddDate(0, -1, 0), from.AddDate(0, 0, 4), to.AddDate(0, 0, 1)
+
+	// changed inside the window and again after it
+	editedTwice := models.Data{ID: uuid.New(), Name: "edited twice", CreatedAt: before, UpdatedAt: after}
+	// changed only after the window
+	editedLater := models.Data{ID: uuid.New(), Name: "edited later", CreatedAt: before, UpdatedAt: after}
+	// changed inside the window with its versions pruned, and again after it
+	audited := models.Data{ID: uuid.New(), Name: "audited", CreatedAt: before, UpdatedAt: after}
+	items := []models.Data{editedTwice, editedLater, audited}
+
+	removedID, removedLaterID, transientID := uuid.New(), uuid.New(), uuid.New()
+	unsealedID := uuid.New()
+	event := func(action string, id uuid.UUID, at time.Time, name string) AuditEntry {
+		entry := AuditEntry{AuditEvent: models.AuditEvent{Action: action, DataID: &id, CreatedAt: at}}
+		if name != "" {
+			entry.AuditDetails.ItemName = name
+			entry.Sealed = true
+		}
+		return entry
+	}
+	history := SnapshotHistory{
+		Versions: map[uuid.UUID][]models.DataVersion{
+			editedTwice.ID: {{DataID: editedTwice.ID, Version: 2, UpdatedAt: inside}, {DataID: editedTwice.ID, Version: 1, UpdatedAt: before}},
+			editedLater.ID: {{DataID: editedLater.ID, Version: 1, UpdatedAt: before}},
+		},
+		Events: []AuditEntry{
+			event("data.update", audited.ID, inside, "audited"),
+			event("data.delete", removedID, inside, "removed"),
+			event("data.delete", unsealedID, inside.Add(time.Hour), ""),
+			event("data.delete", removedLaterID, after, "removed later"),
+			event("data.create", transientID, from.AddDate(0, 0, 1), "transient"),
+			event("data.delete", transientID, from.AddDate(0, 0, 2), "transient"),
+		},
+	}
+
+	diff, err := DiffSnapshots(items, history, from, to)
+	if err != nil {
+		t.Fatalf("DiffSnapshots() error = %v", err)
+	}
+
+	if len(diff.Added) != 0 {
+		t.Errorf("Expected nothing added, got %v", diff.Added)
+	}
+	if len(diff.Changed) != 2 || diff.Changed[0].Name != "edited twice" || diff.Changed[1].Name != "audited" {
+		t.Fatalf("Expected 'edited twice' and 'audited' to be changed, got %v", diff.Changed)
+	}
+	for _, entry := range diff.Changed {
+		if !entry.At.Equal(inside) {
+			t.Errorf("Expected %q to be changed at %v, got %v", entry.Name, inside, entry.At)
+		}
+	}
+	if len(diff.Removed) != 2 || diff.Removed[0].Name != "removed" || diff.Removed[1].Name != unsealedID.String() {
+		t.Errorf("Expected 'removed' and the unsealed item to be removed, got %v", diff.Removed)
+	}
+}
+
+func TestClientSession_SnapshotDiff(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	edited := demoItemID(t, session, "Demo Email")
+	removed := demoItemID(t, session, "Demo Visa")
+
+	time.Sleep(10 * time.Millisecond)
+	from := time.Now()
+	data, err := session.Get(ctx, edited)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	fields, err := session.ItemFields(data)
+	if err != nil {
+		t.Fatalf("ItemFields() error = %v", err)
+	}
+	if _, err := session.SaveItem(ctx, data, data.Type, data.Name, "edited", fields); err != nil {
+		t.Fatalf("SaveItem() error = %v", err)
+	}
+	if err := session.Delete(ctx, removed); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	diff, err := session.SnapshotDiff(ctx, from, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("SnapshotDiff() error = %v", err)
+	}
+	if len(diff.Added) != 0 {
+		t.Errorf("Expected nothing added, got %v", diff.Added)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "Demo Email" {
+		t.Errorf("Expected 'Demo Email' to be changed, got %v", diff.Changed)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "Demo Visa" {
+		t.Errorf("Expected 'Demo Visa' to be removed, got %v", diff.Removed)
+	}
+
+	var out bytes.Buffer
+	writeSnapshotDiff(&out, diff, false)
+	if !strings.Contains(out.String(), "Removed (1):\n  Demo Visa\n") {
+		t.Errorf("Expected the removed item in the output, got:\n%s", out.String())
+	}
+}
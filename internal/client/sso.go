@@ -0,0 +1,251 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// ssoPollInterval is how often the client asks whether the user finished
+// logging in at the identity provider
+var ssoPollInterval = 2 * time.Second
+
+// openBrowser opens a URL in the default browser; tests replace it
+var openBrowser = func(rawURL string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", rawURL)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", rawURL)
+	default:
+		cmd = exec.Command("xdg-open", rawURL)
+	}
+	return cmd.Start()
+}
+
+// StartSSO starts a single sign-on login, or with link the linking of the
+// identity to the logged-in account
+func (c *Client) StartSSO(ctx context.Context, link bool) (*models.OIDCStartResponse, error) {
+	path, authorized := "/api/v1/oidc/start", false
+	if link {
+		path, authorized = "/api/v1/oidc/link", true
+	}
+	var start models.OIDCStartResponse
+	status, err := c.ssoRequest(ctx, path, nil, authorized, &start)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", status)
+	}
+	return &start, nil
+}
+
+// PollSSO returns the result of a single sign-on login, or nil while the
+// user has not finished it
+func (c *Client) PollSSO(ctx context.Context, start *models.OIDCStartResponse) (*models.AuthResponse, error) {
+	var resp models.AuthResponse
+	status, err := c.ssoRequest(ctx, "/api/v1/oidc/token",
+		models.OIDCTokenRequest{State: start.State, PollToken: start.PollToken}, false, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusAccepted {
+		return nil, nil
+	}
+	return &resp, nil
+}
+
+// ssoRequest posts body to a single sign-on route, describing this machine,
+// and decodes a 200 response into out. It returns the status of successful
+// responses.
+func (c *Client) ssoRequest(ctx context.Context, path string, body interface{}, authorized bool, out interface{}) (int, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		logger.Log.Error("Failed to create request", zap.Error(err), zap.String("path", path))
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setDevice(req)
+	if authorized {
+		c.authorize(req)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Request failed", zap.Error(err), zap.String("path", path))
+		return 0, requestFailed(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log.Error("Failed to close body", zap.Error(err))
+		}
+	}()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		return resp.StatusCode, nil
+	case http.StatusAccepted:
+		return resp.StatusCode, nil
+	default:
+		return 0, statusError(resp, respBody)
+	}
+}
+
+// waitForSSO shows the user where to log in and polls until they did, the
+// login expired or ctx is cancelled
+func (s *ClientSession) waitForSSO(ctx context.Context, link bool) (*models.AuthResponse, error) {
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !hasFeature(status, "oidc") {
+		return nil, fmt.Errorf("this server does not support single sign-on")
+	}
+
+	start, err := s.cli.StartSSO(ctx, link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start single sign-on: %w", err)
+	}
+	if parsed, err := url.Parse(start.AuthURL); err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return nil, fmt.Errorf("the server sent an invalid login URL")
+	}
+
+	fmt.Println("Log in with your identity provider in the browser:")
+	fmt.Printf("  %s\n", start.AuthURL)
+	if err := openBrowser(start.AuthURL); err != nil {
+		logger.Log.Debug("Failed to open browser", zap.Error(err))
+	}
+	fmt.Println("Waiting for the login to finish (Ctrl+C cancels)...")
+
+	ticker := time.NewTicker(ssoPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		resp, err := s.cli.PollSSO(ctx, start)
+		if err != nil {
+			return nil, err
+		}
+		if resp != nil {
+			return resp, nil
+		}
+		if time.Now().After(start.ExpiresAt) {
+			return nil, fmt.Errorf("the login expired before it was finished")
+		}
+	}
+}
+
+// SSOLoginCommand logs in with single sign-on. The vault is still unlocked
+// with the master password; accounts the login created choose one.
+func (s *ClientSession) SSOLoginCommand(ctx context.Context, config *Config) error {
+	resp, err := s.waitForSSO(ctx, false)
+	if err != nil {
+		return err
+	}
+	if resp.Token == "" {
+		return fmt.Errorf("the server sent no token")
+	}
+	if !resp.Created {
+		return s.finishLogin(ctx, resp, resp.User.Username, config)
+	}
+
+	fmt.Printf("Created the account %s for your identity\n", resp.User.Username)
+	return s.createSSOVault(ctx, resp, config)
+}
+
+// createSSOVault sets the master password of an account created by single
+// sign-on and unlocks it
+func (s *ClientSession) createSSOVault(ctx context.Context, resp *models.AuthResponse, config *Config) error {
+	s.cli.SetToken(resp.Token)
+	salt, err := s.resolveSalt(ctx, resp.Salt)
+	if err != nil {
+		return err
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return fmt.Errorf("failed to decode salt: %w", err)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	masterPassword, ok := readSecret(scanner, fmt.Sprintf("Choose a master password for data encryption (min %d characters): ", minMasterPasswordLength))
+	if !ok {
+		return fmt.Errorf("failed to read master password")
+	}
+	if len(masterPassword) < minMasterPasswordLength {
+		return fmt.Errorf("master password must be at least %d characters long", minMasterPasswordLength)
+	}
+	confirmation, ok := readSecret(scanner, "Repeat the master password: ")
+	if !ok || confirmation != masterPassword {
+		return fmt.Errorf("master passwords do not match")
+	}
+
+	cryptoManager, err := s.deriveCryptoManager(masterPassword, saltBytes, resp.KDFIterations)
+	if err != nil {
+		return fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+	s.SetCryptoManager(cryptoManager, masterPassword)
+
+	config.Username = resp.User.Username
+	config.Token = resp.Token
+	config.Salt = salt
+	config.SaltIterations = cryptoManager.Iterations()
+	if err := SaveConfig(config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	s.publishVerifier(ctx, cryptoManager)
+
+	s.recordEvent(EventUnlock, map[string]string{"username": resp.User.Username, "action": "register"})
+	s.publishAuditKey(ctx)
+	s.publishShareKey(ctx)
+
+	fmt.Printf("Successfully logged in as: %s\n", resp.User.Username)
+	fmt.Println("Master password set for data encryption")
+	return nil
+}
+
+// SSOLinkCommand links an identity of the server's identity provider to the
+// logged-in account, so it can log in with single sign-on
+func (s *ClientSession) SSOLinkCommand(ctx context.Context) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	resp, err := s.waitForSSO(ctx, true)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Linked your identity to %s; use 'sso' to log in\n", resp.User.Username)
+	return nil
+}
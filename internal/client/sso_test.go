@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+// ssoTestProvider logs every browser in as the same identity
+type ssoTestProvider struct{}
+
+func (ssoTestProvider) Issuer() string { return "https://idp.example.com" }
+
+func (ssoTestProvider) AuthCodeURL(state, nonce, verifier string) string {
+	return "https://idp.example.com/authorize?state=" + url.QueryEscape(state)
+}
+
+func (ssoTestProvider) Authenticate(ctx context.Context, code, verifier, nonce string) (*auth.OIDCClaims, error) {
+	if code != "code" {
+		return nil, errors.New("invalid code")
+	}
+	return &auth.OIDCClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "sub"}, PreferredUsername: "traveller"}, nil
+}
+
+func TestClientSession_WaitForSSO(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := mux.NewRouter()
+	server.RegisterStatusRoutes(router, server.StatusOptions{Features: []string{server.FeatureOIDC}})
+	server.RegisterRoutes(router, store, store, jwtManager, server.Options{})
+	server.RegisterOIDCRoutes(router, ssoTestProvider{}, store, store, jwtManager, server.OIDCOptions{AutoCreate: true})
+
+	cli := NewClient(demoServerURL)
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+	session := NewClientSession(cli)
+
+	originalInterval, originalOpen := ssoPollInterval, openBrowser
+	defer func() { ssoPollInterval, openBrowser = originalInterval, originalOpen }()
+	ssoPollInterval = time.Millisecond
+	var opened string
+	openBrowser = func(rawURL string) error {
+		opened = rawURL
+		authURL, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", server.OIDCCallbackPath+"?code=code&state="+
+			url.QueryEscape(authURL.Query().Get("state")), nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected the callback to succeed, got %d: %s", w.Code, w.Body.String())
+		}
+		return nil
+	}
+
+	resp, err := session.waitForSSO(ctx, false)
+	if err != nil {
+		t.Fatalf("waitForSSO() error = %v", err)
+	}
+	if !strings.HasPrefix(opened, "https://idp.example.com/authorize") {
+		t.Errorf("Expected the login URL to be opened, got %q", opened)
+	}
+	if !resp.Created || resp.User.Username != "traveller" || resp.Token == "" {
+		t.Errorf("Expected a created account with a token, got %+v", resp)
+	}
+
+	if err := session.SSOLinkCommand(ctx); err != ErrNotAuthenticated {
+		t.Errorf("SSOLinkCommand() error = %v, want %v", err, ErrNotAuthenticated)
+	}
+}
+
+func TestClientSession_WaitForSSO_Unsupported(t *testing.T) {
+	router, err := newDemoRouter()
+	if err != nil {
+		t.Fatalf("newDemoRouter() error = %v", err)
+	}
+	cli := NewClient(demoServerURL)
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+
+	if _, err := NewClientSession(cli).waitForSSO(context.Background(), false); err == nil ||
+		!strings.Contains(err.Error(), "does not support single sign-on") {
+		t.Errorf("Expected servers without OIDC to be reported, got %v", err)
+	}
+}
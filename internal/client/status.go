@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// ErrRegistrationClosed is returned when the server does not accept new accounts
+var ErrRegistrationClosed = errors.New("registration is closed on this server - ask an administrator for an account")
+
+// GetStatus gets the public, unauthenticated instance status
+func (c *Client) GetStatus(ctx context.Context) (*models.StatusResponse, error) {
+	var resp models.StatusResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/status", nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// checkRegistrationOpen pre-flights registration. Servers without the status
+// endpoint are assumed to be open, leaving the final say to the register call.
+func (s *ClientSession) checkRegistrationOpen(ctx context.Context) error {
+	status, err := s.cli.GetStatus(ctx)
+	if err == nil && !status.RegistrationOpen {
+		return ErrRegistrationClosed
+	}
+	return nil
+}
+
+// checkCredentials pre-flights registration against the credential policy the
+// server publishes, so a rejected username or password is reported before the
+// master password is asked for. Servers without one are left the final say.
+func (s *ClientSession) checkCredentials(ctx context.Context, username, password string) error {
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil || status.CredentialPolicy == nil {
+		return nil
+	}
+	if err := status.CredentialPolicy.CheckUsername(username); err != nil {
+		return err
+	}
+	return status.CredentialPolicy.CheckPassword(password)
+}
+
+// Names of the server routes whose body limits are checked before sending,
+// matching the server's route names
+const (
+	routeRegister   = "auth.register"
+	routeLogin      = "auth.login"
+	routeCreateData = "data.create"
+	routeUpdateData = "data.update"
+)
+
+// payloadLimit returns the body limit of a named route, 0 if unlimited
+func payloadLimit(status *models.StatusResponse, route string) int64 {
+	if limit, ok := status.PayloadLimits[route]; ok {
+		return limit
+	}
+	return status.MaxPayloadBytes
+}
+
+// checkPayloadSize pre-flights an item upload to route against the server body limit
+func (s *ClientSession) checkPayloadSize(ctx context.Context, route string, contentSize int) error {
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil {
+		return nil
+	}
+	limit := payloadLimit(status, route)
+	if limit == 0 {
+		return nil
+	}
+	// Content is sent base64 encoded inside JSON
+	encodedSize := int64(contentSize+2) / 3 * 4
+	if encodedSize > limit {
+		return fmt.Errorf("item is too large for this server: about %d bytes encoded, limit is %d bytes",
+			encodedSize, limit)
+	}
+	return nil
+}
+
+// checkCredentialsSize pre-flights a register or login request, whose body
+// the server limits more tightly than item uploads
+func (s *ClientSession) checkCredentialsSize(ctx context.Context, route, username, password string) error {
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil {
+		return nil
+	}
+	limit := payloadLimit(status, route)
+	body, err := json.Marshal(models.LoginRequest{Username: username, Password: password})
+	if err != nil || limit == 0 || int64(len(body)) <= limit {
+		return nil
+	}
+	return fmt.Errorf("username and password are too long for this server: the request may be at most %d bytes", limit)
+}
+
+// StatusCommand shows what the configured server supports
+func (s *ClientSession) StatusCommand(ctx context.Context) error {
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get server status: %w", err)
+	}
+
+	registration := "open"
+	if !status.RegistrationOpen {
+		registration = "closed"
+	}
+	maxPayload := "unlimited"
+	if limit := payloadLimit(status, routeCreateData); limit > 0 {
+		maxPayload = fmt.Sprintf("%d bytes", limit)
+	}
+	features := "none"
+	if len(status.Features) > 0 {
+		features = strings.Join(status.Features, ", ")
+	}
+
+	fmt.Printf("Server version: %s\n", status.Version)
+	fmt.Printf("Registration:   %s\n", registration)
+	fmt.Printf("Max item size:  %s\n", maxPayload)
+	fmt.Printf("Features:       %s\n", features)
+	return nil
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+func TestClientSession_StatusPreflight(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	router := mux.NewRouter()
+	server.CloseRegistration(router)
+	server.RegisterRoutes(router, store, store, auth.NewJWTManager("test-secret", time.Hour), server.Options{})
+	server.RegisterStatusRoutes(router, server.StatusOptions{MaxPayloadBytes: 1000})
+
+	cli := NewClient("http://status.invalid")
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+	session := NewClientSession(cli)
+
+	status, err := cli.GetStatus(ctx)
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if status.RegistrationOpen || status.MaxPayloadBytes != 1000 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	if err := session.checkRegistrationOpen(ctx); !errors.Is(err, ErrRegistrationClosed) {
+		t.Errorf("Expected ErrRegistrationClosed, got %v", err)
+	}
+	if err := session.checkPayloadSize(ctx, routeCreateData, 600); err != nil {
+		t.Errorf("checkPayloadSize(600) error = %v", err)
+	}
+	if err := session.checkPayloadSize(ctx, routeCreateData, 900); err == nil {
+		t.Error("Expected oversized item to be rejected before upload")
+	}
+}
+
+func TestClientSession_RoutePayloadLimits(t *testing.T) {
+	ctx := context.Background()
+	router := mux.NewRouter()
+	server.RegisterStatusRoutes(router, server.StatusOptions{
+		MaxPayloadBytes: 1000,
+		PayloadLimits:   server.PayloadLimits(100, map[string]int64{server.RouteUpdateData: 0}),
+	})
+
+	cli := NewClient("http://status.invalid")
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+	session := NewClientSession(cli)
+
+	if err := session.checkPayloadSize(ctx, routeCreateData, 900); err == nil {
+		t.Error("Expected the global limit to apply to routes without their own")
+	}
+	if err := session.checkPayloadSize(ctx, routeUpdateData, 1<<20); err != nil {
+		t.Errorf("Expected a route limit of 0 to leave it unlimited, got %v", err)
+	}
+	if err := session.checkCredentialsSize(ctx, routeLogin, "alice", "secret"); err != nil {
+		t.Errorf("checkCredentialsSize() error = %v", err)
+	}
+	if err := session.checkCredentialsSize(ctx, routeRegister, "alice", strings.Repeat("x", 100)); err == nil {
+		t.Error("Expected credentials over the auth limit to be rejected before sending")
+	}
+}
+
+func TestClientSession_StatusPreflight_OldServer(t *testing.T) {
+	ctx := context.Background()
+	router := mux.NewRouter()
+
+	cli := NewClient("http://status.invalid")
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+	session := NewClientSession(cli)
+
+	if err := session.checkRegistrationOpen(ctx); err != nil {
+		t.Errorf("Servers without status endpoint should be assumed open, got %v", err)
+	}
+	if err := session.checkPayloadSize(ctx, routeCreateData, 1<<30); err != nil {
+		t.Errorf("Servers without status endpoint should not limit size, got %v", err)
+	}
+}
+
+func TestClientSession_CheckCredentials(t *testing.T) {
+	ctx := context.Background()
+	policy := models.DefaultCredentialPolicy()
+	policy.PasswordMinClasses = 3
+	router := mux.NewRouter()
+	server.RegisterStatusRoutes(router, server.StatusOptions{RegistrationOpen: true, CredentialPolicy: &policy})
+
+	cli := NewClient("http://status.invalid")
+	cli.httpClient.Transport = &handlerTransport{handler: router}
+	session := NewClientSession(cli)
+
+	tests := []struct {
+		username, password string
+		wantErr            string
+	}{
+		{username: "alice", password: "Secret-pass1"},
+		{username: "al", password: "Secret-pass1", wantErr: "username must be at least 3 characters"},
+		{username: "alice smith", password: "Secret-pass1", wantErr: "username may only contain"},
+		{username: "alice", password: "Sh0rt!", wantErr: "password must be at least 8 characters"},
+		{username: "alice", password: "secretpassword", wantErr: "at least 3 of lowercase"},
+	}
+	for _, tt := range tests {
+		err := session.checkCredentials(ctx, tt.username, tt.password)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("checkCredentials(%q, %q) error = %v", tt.username, tt.password, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("checkCredentials(%q, %q) = %v, want an error containing %q", tt.username, tt.password, err, tt.wantErr)
+		}
+	}
+
+	old := NewClientSession(NewClient("http://status.invalid"))
+	old.cli.httpClient.Transport = &handlerTransport{handler: mux.NewRouter()}
+	if err := old.checkCredentials(ctx, "a", "b"); err != nil {
+		t.Errorf("Servers without a credential policy should be left the final say, got %v", err)
+	}
+}
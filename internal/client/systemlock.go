@@ -0,0 +1,130 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// Reasons passed to the system lock callback
+const (
+	LockReasonSleep      = "sleep"
+	LockReasonScreenLock = "screen-lock"
+)
+
+const (
+	// sleepCheckInterval is how often the wall clock is compared with the monotonic clock
+	sleepCheckInterval = 5 * time.Second
+	// sleepThreshold is how far the wall clock must run ahead to count as a suspend;
+	// it is large enough to ignore ordinary NTP adjustments
+	sleepThreshold = 30 * time.Second
+)
+
+// WatchSystemLock calls onLock whenever the system suspends or the screen is locked,
+// until ctx is cancelled. Suspend is detected on every platform from the wall clock
+// jumping ahead of the monotonic clock, which stops while the machine sleeps; this
+// fires on resume. OS signals additionally report sleep and screen lock as they
+// happen: logind and screensaver D-Bus signals on Linux, session and power
+// notifications on Windows, and the lock state of the console session on macOS.
+func WatchSystemLock(ctx context.Context, onLock func(reason string)) {
+	go watchSleep(ctx, onLock)
+	watchPlatformLock(ctx, onLock)
+}
+
+// watchSleep detects suspend/resume cycles from clock drift
+func watchSleep(ctx context.Context, onLock func(reason string)) {
+	ticker := time.NewTicker(sleepCheckInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if slept(now.Round(0).Sub(last.Round(0)), now.Sub(last)) {
+				onLock(LockReasonSleep)
+			}
+			last = now
+		}
+	}
+}
+
+// slept reports whether the wall clock advanced far beyond the monotonic clock
+func slept(wallElapsed, monotonicElapsed time.Duration) bool {
+	return wallElapsed-monotonicElapsed > sleepThreshold
+}
+
+// Windows messages and their parameters announcing a suspend or a locked session
+const (
+	wmPowerBroadcast   = 0x0218
+	wmWTSSessionChange = 0x02B1
+	pbtAPMSuspend      = 0x4
+	wtsSessionLock     = 0x7
+)
+
+// windowsLockReason returns the lock reason of a window message, or "" for
+// messages not announcing a suspend or a locked session
+func windowsLockReason(message uint32, wParam uintptr) string {
+	switch {
+	case message == wmPowerBroadcast && wParam == pbtAPMSuspend:
+		return LockReasonSleep
+	case message == wmWTSSessionChange && wParam == wtsSessionLock:
+		return LockReasonScreenLock
+	default:
+		return ""
+	}
+}
+
+// ioregScreenLocked reports whether the ioreg property list of the IORegistry
+// root shows a console session with a locked screen
+func ioregScreenLocked(output []byte) bool {
+	const key = "<key>CGSSessionScreenIsLocked</key>"
+	rest := string(output)
+	for {
+		i := strings.Index(rest, key)
+		if i < 0 {
+			return false
+		}
+		rest = strings.TrimLeft(rest[i+len(key):], " \t\r\n")
+		if strings.HasPrefix(rest, "<true/>") {
+			return true
+		}
+	}
+}
+
+// parseDBusMonitor reads dbus-monitor output and calls onLock for signals announcing
+// an imminent suspend or a locked screen
+func parseDBusMonitor(r io.Reader, onLock func(reason string)) error {
+	scanner := bufio.NewScanner(r)
+	member := ""
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "signal ") {
+			member = ""
+			if i := strings.Index(line, "member="); i >= 0 {
+				member = strings.TrimSpace(line[i+len("member="):])
+			}
+			// logind Session.Lock carries no arguments
+			if member == "Lock" {
+				onLock(LockReasonScreenLock)
+			}
+			continue
+		}
+
+		if line != "boolean true" {
+			continue
+		}
+		switch member {
+		case "PrepareForSleep":
+			onLock(LockReasonSleep)
+		case "ActiveChanged":
+			onLock(LockReasonScreenLock)
+		}
+		member = ""
+	}
+	return scanner.Err()
+}
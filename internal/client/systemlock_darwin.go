@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"os/exec"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"go.uber.org/zap"
+)
+
+// screenLockPollInterval is how often the lock state of the console session is read
+const screenLockPollInterval = 2 * time.Second
+
+// watchPlatformLock follows the lock state of the console session through ioreg,
+// which macOS ships with. Sleep locks the screen too when the system asks for the
+// password on wake, and is otherwise caught on resume by watchSleep.
+func watchPlatformLock(ctx context.Context, onLock func(reason string)) {
+	if _, err := exec.LookPath("ioreg"); err != nil {
+		logger.Log.Debug("ioreg not found, screen lock detection disabled")
+		return
+	}
+	go pollScreenLock(ctx, onLock)
+}
+
+// pollScreenLock calls onLock each time the console session becomes locked,
+// until ctx is cancelled
+func pollScreenLock(ctx context.Context, onLock func(reason string)) {
+	ticker := time.NewTicker(screenLockPollInterval)
+	defer ticker.Stop()
+
+	locked := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			output, err := exec.CommandContext(ctx, "ioreg", "-n", "Root", "-d1", "-a").Output()
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Log.Debug("Failed to read the console session from ioreg", zap.Error(err))
+				}
+				continue
+			}
+			now := ioregScreenLocked(output)
+			if now && !locked {
+				onLock(LockReasonScreenLock)
+			}
+			locked = now
+		}
+	}
+}
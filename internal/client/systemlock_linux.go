@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"os/exec"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"go.uber.org/zap"
+)
+
+// D-Bus match rules for suspend and screen lock signals
+var (
+	systemBusRules = []string{
+		"type='signal',interface='org.freedesktop.login1.Manager',member='PrepareForSleep'",
+		"type='signal',interface='org.freedesktop.login1.Session',member='Lock'",
+	}
+	sessionBusRules = []string{
+		"type='signal',interface='org.freedesktop.ScreenSaver',member='ActiveChanged'",
+		"type='signal',interface='org.gnome.ScreenSaver',member='ActiveChanged'",
+	}
+)
+
+// watchPlatformLock follows logind and screensaver signals through dbus-monitor when it is installed
+func watchPlatformLock(ctx context.Context, onLock func(reason string)) {
+	if _, err := exec.LookPath("dbus-monitor"); err != nil {
+		logger.Log.Debug("dbus-monitor not found, screen lock detection disabled")
+		return
+	}
+	go monitorBus(ctx, "--system", systemBusRules, onLock)
+	go monitorBus(ctx, "--session", sessionBusRules, onLock)
+}
+
+// monitorBus runs dbus-monitor on one bus until ctx is cancelled or the bus is unavailable
+func monitorBus(ctx context.Context, bus string, rules []string, onLock func(reason string)) {
+	cmd := exec.CommandContext(ctx, "dbus-monitor", append([]string{bus}, rules...)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		logger.Log.Debug("Failed to attach to dbus-monitor", zap.String("bus", bus), zap.Error(err))
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		logger.Log.Debug("Failed to start dbus-monitor", zap.String("bus", bus), zap.Error(err))
+		return
+	}
+
+	if err := parseDBusMonitor(stdout, onLock); err != nil {
+		logger.Log.Debug("Failed to read dbus-monitor output", zap.String("bus", bus), zap.Error(err))
+	}
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		logger.Log.Debug("dbus-monitor exited", zap.String("bus", bus), zap.Error(err))
+	}
+}
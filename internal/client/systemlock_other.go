@@ -0,0 +1,9 @@
+//go:build !linux && !windows && !darwin
+
+package client
+
+import "context"
+
+// watchPlatformLock is a no-op where no OS lock signals are followed; suspend is
+// still detected from clock drift by watchSleep
+func watchPlatformLock(ctx context.Context, onLock func(reason string)) {}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlept(t *testing.T) {
+	tests := []struct {
+		name      string
+		wall      time.Duration
+		monotonic time.Duration
+		want      bool
+	}{
+		{name: "awake", wall: 5 * time.Second, monotonic: 5 * time.Second, want: false},
+		{name: "clock adjustment", wall: 7 * time.Second, monotonic: 5 * time.Second, want: false},
+		{name: "clock set back", wall: -time.Hour, monotonic: 5 * time.Second, want: false},
+		{name: "suspended", wall: 2 * time.Hour, monotonic: 5 * time.Second, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slept(tt.wall, tt.monotonic); got != tt.want {
+				t.Errorf("slept(%v, %v) = %v, want %v", tt.wall, tt.monotonic, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDBusMonitor(t *testing.T) {
+	output := `signal time=1.0 sender=org.freedesktop.DBus -> destination=:1.42 serial=2 path=/org/freedesktop/DBus; interface=org.freedesktop.DBus; member=NameAcquired
+   string ":1.42"
+signal time=2.0 sender=:1.3 -> destination=(null destination) serial=10 path=/org/freedesktop/login1; interface=org.freedesktop.login1.Manager; member=PrepareForSleep
+   boolean false
+signal time=3.0 sender=:1.3 -> destination=(null destination) serial=11 path=/org/freedesktop/login1; interface=org.freedesktop.login1.Manager; member=PrepareForSleep
+   boolean true
+signal time=4.0 sender=:1.3 -> destination=(null destination) serial=12 path=/org/freedesktop/login1/session/_32; interface=org.freedesktop.login1.Session; member=Lock
+signal time=5.0 sender=:1.9 -> destination=(null destination) serial=13 path=/org/freedesktop/ScreenSaver; interface=org.freedesktop.ScreenSaver; member=ActiveChanged
+   boolean true
+signal time=6.0 sender=:1.9 -> destination=(null destination) serial=14 path=/org/freedesktop/ScreenSaver; interface=org.freedesktop.ScreenSaver; member=ActiveChanged
+   boolean false
+`
+	var reasons []string
+	if err := parseDBusMonitor(strings.NewReader(output), func(reason string) {
+		reasons = append(reasons, reason)
+	}); err != nil {
+		t.Fatalf("parseDBusMonitor() error = %v", err)
+	}
+
+	want := []string{LockReasonSleep, LockReasonScreenLock, LockReasonScreenLock}
+	if strings.Join(reasons, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected lock reasons %v, got %v", want, reasons)
+	}
+}
+
+func TestWindowsLockReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		message uint32
+		wParam  uintptr
+		want    string
+	}{
+		{name: "suspend", message: wmPowerBroadcast, wParam: pbtAPMSuspend, want: LockReasonSleep},
+		{name: "resume", message: wmPowerBroadcast, wParam: 0x7},
+		{name: "session lock", message: wmWTSSessionChange, wParam: wtsSessionLock, want: LockReasonScreenLock},
+		{name: "session unlock", message: wmWTSSessionChange, wParam: 0x8},
+		{name: "other message", message: 0x0010, wParam: wtsSessionLock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := windowsLockReason(tt.message, tt.wParam); got != tt.want {
+				t.Errorf("windowsLockReason(%#x, %#x) = %q, want %q", tt.message, tt.wParam, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIORegScreenLocked(t *testing.T) {
+	session := func(locked string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>IOConsoleUsers</key>
+	<array>
+		<dict>
+			<key>CGSSessionScreenIsLocked</key>
+			` + locked + `
+			<key>kCGSSessionOnConsoleKey</key>
+			<true/>
+		</dict>
+	</array>
+</dict>
+</plist>
+`
+	}
+
+	tests := []struct {
+		name   string
+		output string
+		want   bool
+	}{
+		{name: "locked", output: session("<true/>"), want: true},
+		{name: "unlocked", output: session("<false/>"), want: false},
+		{name: "no console user", output: "<plist version=\"1.0\"><dict></dict></plist>", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ioregScreenLocked([]byte(tt.output)); got != tt.want {
+				t.Errorf("ioregScreenLocked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
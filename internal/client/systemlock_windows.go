@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+	user32   = syscall.NewLazyDLL("user32.dll")
+	wtsapi32 = syscall.NewLazyDLL("wtsapi32.dll")
+
+	procGetModuleHandleW                 = kernel32.NewProc("GetModuleHandleW")
+	procRegisterClassExW                 = user32.NewProc("RegisterClassExW")
+	procCreateWindowExW                  = user32.NewProc("CreateWindowExW")
+	procDefWindowProcW                   = user32.NewProc("DefWindowProcW")
+	procGetMessageW                      = user32.NewProc("GetMessageW")
+	procDispatchMessageW                 = user32.NewProc("DispatchMessageW")
+	procPostMessageW                     = user32.NewProc("PostMessageW")
+	procPostQuitMessage                  = user32.NewProc("PostQuitMessage")
+	procWTSRegisterSessionNotification   = wtsapi32.NewProc("WTSRegisterSessionNotification")
+	procWTSUnRegisterSessionNotification = wtsapi32.NewProc("WTSUnRegisterSessionNotification")
+)
+
+// Window messages of the hidden window and the scope of session notifications
+const (
+	wmDestroy            = 0x0002
+	wmClose              = 0x0010
+	notifyForThisSession = 0
+)
+
+// sessionWindowClass names the window class of the hidden window
+const sessionWindowClass = "GophKeeperSessionWatcher"
+
+// wndClassEx is the WNDCLASSEXW structure
+type wndClassEx struct {
+	Size       uint32
+	Style      uint32
+	WndProc    uintptr
+	ClsExtra   int32
+	WndExtra   int32
+	Instance   syscall.Handle
+	Icon       syscall.Handle
+	Cursor     syscall.Handle
+	Background syscall.Handle
+	MenuName   *uint16
+	ClassName  *uint16
+	IconSm     syscall.Handle
+}
+
+// windowMessage is the MSG structure
+type windowMessage struct {
+	Hwnd    syscall.Handle
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	Pt      struct{ X, Y int32 }
+}
+
+// watchPlatformLock receives WTS session notifications and power broadcasts
+// through a hidden window, whose messages are pumped on a thread of its own
+func watchPlatformLock(ctx context.Context, onLock func(reason string)) {
+	go runSessionWindow(ctx, onLock)
+}
+
+// runSessionWindow creates the hidden window and dispatches its messages until
+// ctx is cancelled or the window cannot be created
+func runSessionWindow(ctx context.Context, onLock func(reason string)) {
+	// a window belongs to the thread that created it and only that thread
+	// receives its messages
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	wndProc := syscall.NewCallback(func(hwnd syscall.Handle, message uint32, wParam, lParam uintptr) uintptr {
+		if reason := windowsLockReason(message, wParam); reason != "" {
+			onLock(reason)
+		}
+		if message == wmDestroy {
+			_, _, _ = procWTSUnRegisterSessionNotification.Call(uintptr(hwnd))
+			_, _, _ = procPostQuitMessage.Call(0)
+			return 0
+		}
+		ret, _, _ := procDefWindowProcW.Call(uintptr(hwnd), uintptr(message), wParam, lParam)
+		return ret
+	})
+
+	className, err := syscall.UTF16PtrFromString(sessionWindowClass)
+	if err != nil {
+		logger.Log.Debug("Invalid window class name", zap.Error(err))
+		return
+	}
+	instance, _, _ := procGetModuleHandleW.Call(0)
+	class := wndClassEx{WndProc: wndProc, Instance: syscall.Handle(instance), ClassName: className}
+	class.Size = uint32(unsafe.Sizeof(class))
+	if atom, _, err := procRegisterClassExW.Call(uintptr(unsafe.Pointer(&class))); atom == 0 {
+		logger.Log.Debug("Failed to register window class, screen lock detection disabled", zap.Error(err))
+		return
+	}
+
+	// a hidden top-level window, as message-only windows miss power broadcasts
+	hwnd, _, err := procCreateWindowExW.Call(0, uintptr(unsafe.Pointer(className)), 0, 0, 0, 0, 0, 0, 0, 0, instance, 0)
+	if hwnd == 0 {
+		logger.Log.Debug("Failed to create window, screen lock detection disabled", zap.Error(err))
+		return
+	}
+	if ok, _, err := procWTSRegisterSessionNotification.Call(hwnd, notifyForThisSession); ok == 0 {
+		logger.Log.Debug("Failed to register for session notifications, screen lock detection disabled", zap.Error(err))
+	}
+
+	go func() {
+		<-ctx.Done()
+		_, _, _ = procPostMessageW.Call(hwnd, wmClose, 0, 0)
+	}()
+
+	var message windowMessage
+	for {
+		ret, _, err := procGetMessageW.Call(uintptr(unsafe.Pointer(&message)), 0, 0, 0)
+		switch int32(ret) {
+		case 0:
+			return
+		case -1:
+			logger.Log.Debug("Failed to get window message", zap.Error(err))
+			return
+		}
+		_, _, _ = procDispatchMessageW.Call(uintptr(unsafe.Pointer(&message)))
+	}
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// AddTags adds tags to an item by ID or unique ID prefix
+func (s *ClientSession) AddTags(ctx context.Context, id string, tags ...string) (*models.Data, error) {
+	return s.updateTags(ctx, id, func(current []string) []string {
+		return append(append([]string{}, current...), tags...)
+	})
+}
+
+// RemoveTags removes tags from an item by ID or unique ID prefix
+func (s *ClientSession) RemoveTags(ctx context.Context, id string, tags ...string) (*models.Data, error) {
+	return s.updateTags(ctx, id, func(current []string) []string {
+		kept := []string{}
+		for _, tag := range current {
+			if !models.HasTag(tags, tag) {
+				kept = append(kept, tag)
+			}
+		}
+		return kept
+	})
+}
+
+// updateTags replaces the tags of an item with change applied to them,
+// conditional on the item not having changed since it was read
+func (s *ClientSession) updateTags(ctx context.Context, id string, change func([]string) []string) (*models.Data, error) {
+	data, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	tags, err := models.NormalizeTags(change(data.Tags))
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return s.Update(ctx, data.ID.String(), models.DataRequest{
+		Type:          data.Type,
+		Name:          data.Name,
+		Description:   data.Description,
+		Data:          data.Data,
+		Metadata:      data.Metadata,
+		Environment:   data.Environment,
+		Tags:          &tags,
+		BaseUpdatedAt: &data.UpdatedAt,
+		BaseRevision:  baseRevision(data),
+	})
+}
+
+// TagCounts returns the number of items with each tag in the vault
+func (s *ClientSession) TagCounts(ctx context.Context) (map[string]int, error) {
+	items, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, item := range items {
+		for _, tag := range item.Tags {
+			counts[tag]++
+		}
+	}
+	return counts, nil
+}
+
+// TagAddCommand adds tags to an item and prints the resulting tags
+func (s *ClientSession) TagAddCommand(ctx context.Context, id string, tags []string) error {
+	data, err := s.AddTags(ctx, id, tags...)
+	if err != nil {
+		return fmt.Errorf("failed to add tags: %w", err)
+	}
+	fmt.Printf("Tags of %q: %s\n", CleanQuotes(data.Name), strings.Join(data.Tags, ", "))
+	return nil
+}
+
+// TagRemoveCommand removes tags from an item and prints the remaining tags
+func (s *ClientSession) TagRemoveCommand(ctx context.Context, id string, tags []string) error {
+	data, err := s.RemoveTags(ctx, id, tags...)
+	if err != nil {
+		return fmt.Errorf("failed to remove tags: %w", err)
+	}
+	if len(data.Tags) == 0 {
+		fmt.Printf("%q has no tags left\n", CleanQuotes(data.Name))
+		return nil
+	}
+	fmt.Printf("Tags of %q: %s\n", CleanQuotes(data.Name), strings.Join(data.Tags, ", "))
+	return nil
+}
+
+// TagListCommand lists the tags in use with their item counts, or the items
+// with tag when it is not empty
+func (s *ClientSession) TagListCommand(ctx context.Context, tag string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	if tag != "" {
+		data, err := s.ListFiltered(ctx, models.DataFilter{Tag: strings.ToLower(tag)})
+		if err != nil {
+			return fmt.Errorf("failed to get data: %w", err)
+		}
+		if len(data) == 0 {
+			fmt.Printf("No items tagged %s\n", tag)
+			return nil
+		}
+		all, _, err := s.listForDisplay(ctx, "")
+		if err != nil {
+			return fmt.Errorf("failed to get data: %w", err)
+		}
+		fmt.Printf("Found %d items tagged %s:\n", len(data), tag)
+		return WriteGroupedList(os.Stdout, data, ShortIDs(all), s.loadUsage(), true)
+	}
+
+	counts, err := s.TagCounts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+	if len(counts) == 0 {
+		fmt.Println("No tags yet; add one with 'tag add <id> <tag>'")
+		return nil
+	}
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, tag := range tags {
+		fmt.Fprintf(tw, "  %s\t%d\n", tag, counts[tag])
+	}
+	return tw.Flush()
+}
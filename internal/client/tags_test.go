@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestClientSession_Tags(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	email := demoItemID(t, session, "Demo Email")
+	visa := demoItemID(t, session, "Demo Visa")
+
+	data, err := session.AddTags(ctx, email, "Work", "personal")
+	if err != nil {
+		t.Fatalf("AddTags() error = %v", err)
+	}
+	if !reflect.DeepEqual(data.Tags, []string{"personal", "work"}) {
+		t.Errorf("Expected normalized tags, got %q", data.Tags)
+	}
+	if _, err := session.AddTags(ctx, visa, "work"); err != nil {
+		t.Fatalf("AddTags() error = %v", err)
+	}
+	if _, err := session.AddTags(ctx, visa, "two words"); err == nil {
+		t.Error("Expected an invalid tag to be rejected")
+	}
+
+	// an edit that does not know about tags keeps them
+	fields, _ := session.ItemFields(data)
+	if _, err := session.SaveItem(ctx, data, data.Type, "Demo Email", data.Description, fields); err != nil {
+		t.Fatalf("SaveItem() error = %v", err)
+	}
+
+	counts, err := session.TagCounts(ctx)
+	if err != nil {
+		t.Fatalf("TagCounts() error = %v", err)
+	}
+	if !reflect.DeepEqual(counts, map[string]int{"work": 2, "personal": 1}) {
+		t.Errorf("TagCounts() = %v", counts)
+	}
+
+	if data, err = session.RemoveTags(ctx, email, "WORK"); err != nil {
+		t.Fatalf("RemoveTags() error = %v", err)
+	}
+	if !reflect.DeepEqual(data.Tags, []string{"personal"}) {
+		t.Errorf("Expected the work tag removed, got %q", data.Tags)
+	}
+	if data, err = session.RemoveTags(ctx, email, "personal"); err != nil || len(data.Tags) != 0 {
+		t.Errorf("Expected all tags removed, got %q, %v", data.Tags, err)
+	}
+
+	tagged, err := session.ListFiltered(ctx, models.DataFilter{Tag: "work"})
+	if err != nil {
+		t.Fatalf("ListFiltered() error = %v", err)
+	}
+	if len(tagged) != 1 || tagged[0].Name != "Demo Visa" {
+		t.Errorf("Expected only Demo Visa tagged work, got %v", tagged)
+	}
+}
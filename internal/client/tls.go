@@ -0,0 +1,44 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// NewTLSConfig builds the TLS settings used to verify the server. Certificates
+// in the PEM bundle caFile, such as a private CA, are trusted in addition to
+// the system roots. insecure disables verification altogether and is meant for
+// local testing with self-signed certificates only.
+func NewTLSConfig(caFile string, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if insecure {
+		config.InsecureSkipVerify = true
+		return config, nil
+	}
+	if caFile == "" {
+		return config, nil
+	}
+
+	bundle, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", caFile)
+	}
+	config.RootCAs = pool
+	return config, nil
+}
+
+// SetTLSConfig makes the client verify the server with config
+func (c *Client) SetTLSConfig(config *tls.Config) {
+	transport := c.baseTransport()
+	transport.TLSClientConfig = config
+	c.setBaseTransport(transport)
+}
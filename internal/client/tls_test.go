@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClient_SetTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version":"test"}`))
+	}))
+	defer server.Close()
+
+	untrusted := NewClient(server.URL)
+	if _, err := untrusted.GetStatus(context.Background()); err == nil {
+		t.Fatal("Expected a self-signed certificate to be rejected")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, bundle, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig, err := NewTLSConfig(caFile, false)
+	if err != nil {
+		t.Fatalf("NewTLSConfig() error = %v", err)
+	}
+	trusted := NewClient(server.URL)
+	trusted.SetTLSConfig(tlsConfig)
+	if _, err := trusted.GetStatus(context.Background()); err != nil {
+		t.Errorf("Expected the CA bundle to be trusted, got %v", err)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTLSConfig(empty, false); err == nil {
+		t.Error("Expected a bundle without certificates to be rejected")
+	}
+}
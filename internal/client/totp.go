@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// RFC 6238 defaults, used by virtually all authenticator apps
+const (
+	defaultOTPAlgorithm = "SHA1"
+	defaultOTPDigits    = 6
+	defaultOTPPeriod    = 30
+)
+
+// otpHashes maps TOTP algorithm names to their HMAC hash
+var otpHashes = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+// normalizeOTP fills in the defaults of otp and checks its parameters
+func normalizeOTP(otp models.OTPData) (models.OTPData, []byte, error) {
+	otp.Algorithm = strings.ToUpper(otp.Algorithm)
+	if otp.Algorithm == "" {
+		otp.Algorithm = defaultOTPAlgorithm
+	}
+	if _, ok := otpHashes[otp.Algorithm]; !ok {
+		return otp, nil, fmt.Errorf("unsupported algorithm %q (use SHA1, SHA256 or SHA512)", otp.Algorithm)
+	}
+	if otp.Digits == 0 {
+		otp.Digits = defaultOTPDigits
+	}
+	if otp.Digits < 6 || otp.Digits > 8 {
+		return otp, nil, fmt.Errorf("digits must be between 6 and 8")
+	}
+	if otp.Period == 0 {
+		otp.Period = defaultOTPPeriod
+	}
+	if otp.Period < 0 {
+		return otp, nil, fmt.Errorf("period must be positive")
+	}
+
+	// secrets are shown in groups and often without padding
+	otp.Secret = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(otp.Secret))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(otp.Secret, "="))
+	if err != nil || len(key) == 0 {
+		return otp, nil, fmt.Errorf("secret must be base32 encoded")
+	}
+	return otp, key, nil
+}
+
+// GenerateTOTP returns the code of otp at time at and how long it stays valid
+func GenerateTOTP(otp models.OTPData, at time.Time) (string, time.Duration, error) {
+	otp, key, err := normalizeOTP(otp)
+	if err != nil {
+		return "", 0, err
+	}
+
+	period := int64(otp.Period)
+	counter := at.Unix() / period
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(otpHashes[otp.Algorithm], key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < otp.Digits; i++ {
+		modulo *= 10
+	}
+
+	remaining := time.Duration((counter+1)*period)*time.Second - time.Duration(at.UnixNano())
+	return fmt.Sprintf("%0*d", otp.Digits, value%modulo), remaining, nil
+}
+
+// ParseOTPAuthURI parses an otpauth://totp/ URI as encoded in the QR codes
+// services show when two-factor authentication is enabled
+func ParseOTPAuthURI(uri string) (models.OTPData, error) {
+	var otp models.OTPData
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "otpauth" {
+		return otp, fmt.Errorf("not an otpauth URI")
+	}
+	if u.Host != "totp" {
+		return otp, fmt.Errorf("only TOTP is supported, got %q", u.Host)
+	}
+
+	// the label is "issuer:account" or just "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		otp.Issuer, otp.Account = strings.TrimSpace(issuer), strings.TrimSpace(account)
+	} else {
+		otp.Account = label
+	}
+
+	query := u.Query()
+	otp.Secret = query.Get("secret")
+	if issuer := query.Get("issuer"); issuer != "" {
+		otp.Issuer = issuer
+	}
+	otp.Algorithm = query.Get("algorithm")
+	for name, target := range map[string]*int{"digits": &otp.Digits, "period": &otp.Period} {
+		if value := query.Get(name); value != "" {
+			if *target, err = strconv.Atoi(value); err != nil {
+				return otp, fmt.Errorf("invalid %s %q", name, value)
+			}
+		}
+	}
+
+	otp, _, err = normalizeOTP(otp)
+	return otp, err
+}
+
+// TOTPCommand prints the current one-time code of an otp item
+func (s *ClientSession) TOTPCommand(ctx context.Context, id string) error {
+	code, remaining, data, err := s.currentTOTP(ctx, id, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (%s, valid for %ds)\n", code, CleanQuotes(data.Name), int(remaining.Round(time.Second).Seconds()))
+	return nil
+}
+
+// currentTOTP decrypts an otp item and returns its code at time at
+func (s *ClientSession) currentTOTP(ctx context.Context, id string, at time.Time) (string, time.Duration, *models.Data, error) {
+	if !s.IsAuthenticated() {
+		return "", 0, nil, ErrNotAuthenticated
+	}
+	if len(id) == 0 {
+		return "", 0, nil, fmt.Errorf("data ID is required")
+	}
+
+	data, err := s.Get(ctx, id)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	if data.Type != models.DataTypeOTP {
+		return "", 0, nil, fmt.Errorf("item %q is a %s item, not otp", CleanQuotes(data.Name), data.Type)
+	}
+
+	decrypted, err := s.cryptoManager.Decrypt(data.Data)
+	if err != nil {
+		s.recordEvent(EventMasterPasswordFailed, map[string]string{"data_id": data.ID.String()})
+		return "", 0, nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	var otp models.OTPData
+	if err := json.Unmarshal(decrypted, &otp); err != nil {
+		return "", 0, nil, fmt.Errorf("failed to parse otp data: %w", err)
+	}
+
+	code, remaining, err := GenerateTOTP(otp, at)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	s.recordUsage(data.ID.String())
+	return code, remaining, data, nil
+}
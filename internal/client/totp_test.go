@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestGenerateTOTP(t *testing.T) {
+	// test vectors from RFC 6238 appendix B, with the ASCII keys base32 encoded
+	const (
+		sha1Key   = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+		sha256Key = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA"
+		sha512Key = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA"
+	)
+	tests := []struct {
+		name          string
+		otp           models.OTPData
+		at            int64
+		wantCode      string
+		wantRemaining time.Duration
+		wantErr       string
+	}{
+		{name: "SHA1", otp: models.OTPData{Secret: sha1Key, Digits: 8}, at: 59, wantCode: "94287082", wantRemaining: time.Second},
+		{name: "SHA256", otp: models.OTPData{Secret: sha256Key, Algorithm: "sha256", Digits: 8}, at: 1111111109, wantCode: "68084774", wantRemaining: time.Second},
+		{name: "SHA512", otp: models.OTPData{Secret: sha512Key, Algorithm: "SHA512", Digits: 8}, at: 2000000000, wantCode: "38618901", wantRemaining: 10 * time.Second},
+		{name: "defaults", otp: models.OTPData{Secret: strings.ToLower(sha1Key[:8]) + " " + sha1Key[8:]}, at: 1234567890, wantCode: "005924", wantRemaining: 30 * time.Second},
+		{name: "invalid secret", otp: models.OTPData{Secret: "not base32!"}, wantErr: "base32"},
+		{name: "unsupported algorithm", otp: models.OTPData{Secret: sha1Key, Algorithm: "MD5"}, wantErr: "unsupported algorithm"},
+		{name: "too many digits", otp: models.OTPData{Secret: sha1Key, Digits: 10}, wantErr: "digits"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, remaining, err := GenerateTOTP(tt.otp, time.Unix(tt.at, 0))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GenerateTOTP() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateTOTP() error = %v", err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("GenerateTOTP() code = %s, want %s", code, tt.wantCode)
+			}
+			if remaining != tt.wantRemaining {
+				t.Errorf("GenerateTOTP() remaining = %s, want %s", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestParseOTPAuthURI(t *testing.T) {
+	otp, err := ParseOTPAuthURI("otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example&digits=8&period=60&algorithm=SHA256")
+	if err != nil {
+		t.Fatalf("ParseOTPAuthURI() error = %v", err)
+	}
+	want := models.OTPData{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Example", Account: "alice@example.com", Algorithm: "SHA256", Digits: 8, Period: 60}
+	if otp != want {
+		t.Errorf("ParseOTPAuthURI() = %+v, want %+v", otp, want)
+	}
+
+	for _, uri := range []string{
+		"https://example.com/?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://hotp/Example?secret=JBSWY3DPEHPK3PXP&counter=1",
+		"otpauth://totp/Example?secret=JBSWY3DPEHPK3PXP&digits=six",
+		"otpauth://totp/Example",
+	} {
+		if _, err := ParseOTPAuthURI(uri); err == nil {
+			t.Errorf("ParseOTPAuthURI(%q) expected an error", uri)
+		}
+	}
+}
+
+func TestClientSession_TOTP(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	payload, err := json.Marshal(models.OTPData{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Issuer: "Example"})
+	if err != nil {
+		t.Fatalf("Failed to marshal otp data: %v", err)
+	}
+	encrypted, err := session.cryptoManager.Encrypt(payload)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	data, err := session.Create(ctx, models.DataRequest{Type: models.DataTypeOTP, Name: "Example 2FA", Data: encrypted})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	code, remaining, _, err := session.currentTOTP(ctx, data.ID.String(), time.Unix(59, 0))
+	if err != nil {
+		t.Fatalf("currentTOTP() error = %v", err)
+	}
+	if code != "287082" || remaining != time.Second {
+		t.Errorf("currentTOTP() = %s valid for %s, want 287082 valid for 1s", code, remaining)
+	}
+
+	var out bytes.Buffer
+	if err := WriteStructuredData(&out, data, session.cryptoManager, false); err != nil {
+		t.Fatalf("WriteStructuredData() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Issuer: Example") || !strings.Contains(out.String(), "Current Code: ") {
+		t.Errorf("Expected the issuer and current code to be shown, got:\n%s", out.String())
+	}
+
+	if _, _, _, err := session.currentTOTP(ctx, demoItemID(t, session, "Demo Email"), time.Now()); err == nil || !strings.Contains(err.Error(), "not otp") {
+		t.Errorf("currentTOTP() error = %v, want a wrong type error", err)
+	}
+}
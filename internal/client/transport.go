@@ -0,0 +1,142 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRequestTimeout bounds a whole request unless configured otherwise;
+	// long transfers such as imports and rotation are not bounded
+	DefaultRequestTimeout = 30 * time.Second
+	// DefaultConnectTimeout bounds establishing a connection, TLS included
+	DefaultConnectTimeout = 10 * time.Second
+	// DefaultReadTimeout bounds waiting for the server to start responding
+	DefaultReadTimeout = 30 * time.Second
+	// DefaultKeepAlive is the interval of TCP keep-alive probes
+	DefaultKeepAlive = 30 * time.Second
+	// DefaultIdleConnTimeout is how long an unused connection is kept for reuse
+	DefaultIdleConnTimeout = 90 * time.Second
+	// ProxyDirect as the configured proxy connects directly, ignoring the environment
+	ProxyDirect = "direct"
+)
+
+// NetworkOptions are the timeouts, keep-alive and proxy settings of the
+// connection to the server. A zero duration means the default and a negative
+// one turns the limit off; a negative KeepAlive also disables reusing
+// connections.
+type NetworkOptions struct {
+	Timeout         time.Duration
+	ConnectTimeout  time.Duration
+	ReadTimeout     time.Duration
+	KeepAlive       time.Duration
+	IdleConnTimeout time.Duration
+	// Proxy is the URL of the proxy to use, ProxyDirect for none, or empty to
+	// honor HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	Proxy string
+}
+
+// NetworkOptions returns the network settings from the config
+func (c *Config) NetworkOptions() NetworkOptions {
+	return NetworkOptions{
+		Timeout:         secondsOption(c.TimeoutSeconds),
+		ConnectTimeout:  secondsOption(c.ConnectTimeoutSeconds),
+		ReadTimeout:     secondsOption(c.ReadTimeoutSeconds),
+		KeepAlive:       secondsOption(c.KeepAliveSeconds),
+		IdleConnTimeout: secondsOption(c.IdleConnTimeoutSeconds),
+		Proxy:           c.Proxy,
+	}
+}
+
+func secondsOption(seconds int) time.Duration {
+	return time.Duration(seconds) * time.Second
+}
+
+// orDefault resolves an option to the duration to use, 0 meaning no limit
+func orDefault(option, fallback time.Duration) time.Duration {
+	switch {
+	case option < 0:
+		return 0
+	case option == 0:
+		return fallback
+	default:
+		return option
+	}
+}
+
+// proxyFunc returns how the transport picks the proxy for a request
+func (o NetworkOptions) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	switch strings.ToLower(o.Proxy) {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case ProxyDirect:
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(o.Proxy)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", o.Proxy)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+		return http.ProxyURL(proxyURL), nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+}
+
+// SetNetworkOptions applies timeouts, keep-alive and proxy settings to the
+// client's connections to the server
+func (c *Client) SetNetworkOptions(options NetworkOptions) error {
+	proxy, err := options.proxyFunc()
+	if err != nil {
+		return err
+	}
+
+	keepAlive := orDefault(options.KeepAlive, DefaultKeepAlive)
+	if keepAlive == 0 {
+		keepAlive = -1
+	}
+	dialer := &net.Dialer{
+		Timeout:   orDefault(options.ConnectTimeout, DefaultConnectTimeout),
+		KeepAlive: keepAlive,
+	}
+
+	transport := c.baseTransport()
+	transport.Proxy = proxy
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = orDefault(options.ConnectTimeout, DefaultConnectTimeout)
+	transport.ResponseHeaderTimeout = orDefault(options.ReadTimeout, DefaultReadTimeout)
+	transport.IdleConnTimeout = orDefault(options.IdleConnTimeout, DefaultIdleConnTimeout)
+	transport.DisableKeepAlives = options.KeepAlive < 0
+	c.setBaseTransport(transport)
+	c.httpClient.Timeout = orDefault(options.Timeout, DefaultRequestTimeout)
+	return nil
+}
+
+// baseTransport returns a copy of the transport that connects to the server,
+// below any retries and compression
+func (c *Client) baseTransport() *http.Transport {
+	next := c.innerTransport()
+	if retrying, ok := next.(*retryTransport); ok {
+		next = retrying.next
+	}
+	if transport, ok := next.(*http.Transport); ok {
+		return transport.Clone()
+	}
+	return http.DefaultTransport.(*http.Transport).Clone()
+}
+
+// setBaseTransport replaces the transport that connects to the server,
+// keeping any retries and compression on top of it
+func (c *Client) setBaseTransport(transport *http.Transport) {
+	if retrying, ok := c.innerTransport().(*retryTransport); ok {
+		retrying.next = transport
+		return
+	}
+	c.setInnerTransport(transport)
+}
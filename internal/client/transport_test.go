@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_SetNetworkOptions(t *testing.T) {
+	cli := NewClient("http://vault.invalid")
+	cli.SetRetryPolicy(DefaultRetryPolicy())
+	config := &Config{TimeoutSeconds: 45, ReadTimeoutSeconds: -1, KeepAliveSeconds: -1, Proxy: "http://proxy.internal:3128"}
+	if err := cli.SetNetworkOptions(config.NetworkOptions()); err != nil {
+		t.Fatalf("SetNetworkOptions() error = %v", err)
+	}
+
+	if cli.httpClient.Timeout != 45*time.Second {
+		t.Errorf("Expected a 45s request timeout, got %s", cli.httpClient.Timeout)
+	}
+	if _, ok := cli.httpClient.Transport.(*compressionTransport); !ok {
+		t.Fatalf("Expected compression to be kept, got %T", cli.httpClient.Transport)
+	}
+	retrying, ok := cli.innerTransport().(*retryTransport)
+	if !ok {
+		t.Fatalf("Expected retries to be kept, got %T", cli.innerTransport())
+	}
+	transport := retrying.next.(*http.Transport)
+	if transport.ResponseHeaderTimeout != 0 || !transport.DisableKeepAlives || transport.TLSHandshakeTimeout != DefaultConnectTimeout {
+		t.Errorf("Unexpected transport settings %+v", transport)
+	}
+	req, _ := http.NewRequest("GET", "http://vault.invalid/api/v1/status", nil)
+	if proxyURL, err := transport.Proxy(req); err != nil || proxyURL.String() != "http://proxy.internal:3128" {
+		t.Errorf("Expected the configured proxy, got %v, %v", proxyURL, err)
+	}
+
+	if err := cli.SetNetworkOptions(NetworkOptions{Proxy: ProxyDirect}); err != nil {
+		t.Fatalf("SetNetworkOptions() error = %v", err)
+	}
+	if cli.httpClient.Timeout != DefaultRequestTimeout || cli.baseTransport().Proxy != nil {
+		t.Errorf("Expected defaults and no proxy, got %s", cli.httpClient.Timeout)
+	}
+
+	for _, proxy := range []string{"proxy.internal:3128", "ftp://proxy.internal", "http://"} {
+		if err := cli.SetNetworkOptions(NetworkOptions{Proxy: proxy}); err == nil {
+			t.Errorf("Expected proxy %q to be rejected", proxy)
+		}
+	}
+}
+
+func TestClient_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version":"test"}`))
+	}))
+	defer proxy.Close()
+
+	cli := NewClient("http://vault.invalid")
+	if err := cli.SetNetworkOptions(NetworkOptions{Proxy: proxy.URL}); err != nil {
+		t.Fatalf("SetNetworkOptions() error = %v", err)
+	}
+	if _, err := cli.GetStatus(context.Background()); err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if proxied != "http://vault.invalid/api/v1/status" {
+		t.Errorf("Expected the request to go through the proxy, got %q", proxied)
+	}
+}
+
+func TestClient_ReadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cli := NewClient(server.URL)
+	if err := cli.SetNetworkOptions(NetworkOptions{ReadTimeout: 50 * time.Millisecond}); err != nil {
+		t.Fatalf("SetNetworkOptions() error = %v", err)
+	}
+	start := time.Now()
+	if _, err := cli.GetStatus(context.Background()); err == nil {
+		t.Fatal("Expected the slow response to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the read timeout to end the request, took %s", elapsed)
+	}
+}
@@ -0,0 +1,50 @@
+package tui
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fuzzyScore reports whether the runes of query appear in s in order, ignoring
+// case, and scores the match. Runes following the previous match and runes
+// starting a word score higher, so "gm" ranks "GMail" and "Google Mail" above
+// "Tagmanager".
+func fuzzyScore(query, s string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+
+	target := []rune(s)
+	lower := []rune(strings.ToLower(s))
+	score := 0
+	last := -2
+	pos := 0
+	for _, q := range strings.ToLower(query) {
+		if unicode.IsSpace(q) {
+			continue
+		}
+		for pos < len(lower) && lower[pos] != q {
+			pos++
+		}
+		if pos == len(lower) {
+			return 0, false
+		}
+
+		score++
+		switch {
+		case pos == last+1:
+			score += 5
+		case pos == 0 || isWordStart(target, pos):
+			score += 3
+		}
+		last = pos
+		pos++
+	}
+	return score, true
+}
+
+// isWordStart reports whether the rune at i starts a word of s
+func isWordStart(s []rune, i int) bool {
+	prev, cur := s[i-1], s[i]
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev) || unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
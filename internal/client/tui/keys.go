@@ -0,0 +1,89 @@
+package tui
+
+import "unicode/utf8"
+
+// keyType is a key the TUI reacts to
+type keyType int
+
+const (
+	keyRune keyType = iota
+	keyEnter
+	keyBackspace
+	keyEscape
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyTab
+	keyShiftTab
+	keyCtrlC
+	keyCtrlR
+	keyCtrlS
+)
+
+// key is one key press; r is set for keyRune
+type key struct {
+	t keyType
+	r rune
+}
+
+// csiKeys maps the final byte of cursor key escape sequences to keys
+var csiKeys = map[byte]keyType{'A': keyUp, 'B': keyDown, 'C': keyRight, 'D': keyLeft, 'Z': keyShiftTab}
+
+// parseKeys decodes the bytes of one terminal read in raw mode into key
+// presses. Unknown control characters and escape sequences are dropped. A
+// lone ESC is the Escape key, since terminals send sequences in one write.
+func parseKeys(buf []byte) []key {
+	var keys []key
+	for len(buf) > 0 {
+		b := buf[0]
+		switch {
+		case b == 0x1b:
+			n, k, ok := parseEscape(buf)
+			if ok {
+				keys = append(keys, k)
+			}
+			buf = buf[n:]
+			continue
+		case b == '\r' || b == '\n':
+			keys = append(keys, key{t: keyEnter})
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, key{t: keyBackspace})
+		case b == '\t':
+			keys = append(keys, key{t: keyTab})
+		case b == 0x03:
+			keys = append(keys, key{t: keyCtrlC})
+		case b == 0x12:
+			keys = append(keys, key{t: keyCtrlR})
+		case b == 0x13:
+			keys = append(keys, key{t: keyCtrlS})
+		case b < 0x20:
+			// other control characters have no binding
+		default:
+			r, size := utf8.DecodeRune(buf)
+			if r != utf8.RuneError {
+				keys = append(keys, key{t: keyRune, r: r})
+			}
+			buf = buf[size:]
+			continue
+		}
+		buf = buf[1:]
+	}
+	return keys
+}
+
+// parseEscape decodes the escape sequence at the start of buf and returns its
+// length and key, with ok false for sequences without a binding
+func parseEscape(buf []byte) (int, key, bool) {
+	if len(buf) == 1 || (buf[1] != '[' && buf[1] != 'O') {
+		return 1, key{t: keyEscape}, true
+	}
+	// CSI and SS3 sequences end with a byte in 0x40-0x7e
+	for i := 2; i < len(buf); i++ {
+		if buf[i] >= 0x40 && buf[i] <= 0x7e {
+			t, ok := csiKeys[buf[i]]
+			return i + 1, key{t: t}, ok && i == 2
+		}
+	}
+	return len(buf), key{}, false
+}
@@ -0,0 +1,546 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/client"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+// Terminal escape sequences used for rendering
+const (
+	reverse = "\x1b[7m"
+	dim     = "\x1b[2m"
+	bold    = "\x1b[1m"
+	reset   = "\x1b[0m"
+)
+
+// secretMask replaces secret values until they are revealed
+const secretMask = "••••••••"
+
+// mode is what keys act on
+type mode int
+
+const (
+	modeList mode = iota
+	modeSearch
+	modeForm
+)
+
+// typeLabels are the short type names of list rows
+var typeLabels = map[models.DataType]string{
+	models.DataTypeLoginPassword: "login",
+	models.DataTypeBankCard:      "card",
+	models.DataTypeText:          "note",
+	models.DataTypeBinary:        "file",
+	models.DataTypeOTP:           "otp",
+}
+
+// model is the state of the TUI. Keys change it through update and view
+// renders it, so both are exercised without a terminal.
+type model struct {
+	ctx   context.Context
+	vault Vault
+
+	items   []models.Data
+	visible []int
+	cursor  int
+	scroll  int
+	query   string
+	mode    mode
+
+	// detail caches the decrypted fields of the selected item
+	detailID  uuid.UUID
+	detail    []client.ItemField
+	detailErr error
+	revealed  bool
+
+	form   *form
+	status string
+	quit   bool
+}
+
+// form is an inline create or edit form. New items start with a type row.
+type form struct {
+	data      *models.Data
+	typeIndex int
+	fields    []client.ItemField
+	focus     int
+	revealed  bool
+}
+
+func newModel(ctx context.Context, vault Vault) *model {
+	return &model{ctx: ctx, vault: vault}
+}
+
+// refresh reloads the items and keeps the selection on selectID if it is listed
+func (m *model) refresh(selectID uuid.UUID) error {
+	items, err := m.vault.List(m.ctx)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+	m.items = items
+	m.detailID = uuid.Nil
+	m.filter()
+	for i, index := range m.visible {
+		if m.items[index].ID == selectID {
+			m.cursor = i
+		}
+	}
+	return nil
+}
+
+// filter recomputes the visible items for the query, best matches first
+func (m *model) filter() {
+	type match struct{ index, score int }
+	var matches []match
+	for i, item := range m.items {
+		if score, ok := fuzzyScore(m.query, item.Name); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	m.visible = m.visible[:0]
+	for _, match := range matches {
+		m.visible = append(m.visible, match.index)
+	}
+	m.cursor = 0
+	m.scroll = 0
+	m.revealed = false
+}
+
+// selected returns the item under the cursor, or nil if none is listed
+func (m *model) selected() *models.Data {
+	if m.cursor >= len(m.visible) {
+		return nil
+	}
+	return &m.items[m.visible[m.cursor]]
+}
+
+// selectedFields returns the decrypted fields of the selected item
+func (m *model) selectedFields() ([]client.ItemField, error) {
+	item := m.selected()
+	if item == nil {
+		return nil, nil
+	}
+	if m.detailID != item.ID {
+		m.detailID = item.ID
+		m.detail, m.detailErr = m.vault.ItemFields(item)
+	}
+	return m.detail, m.detailErr
+}
+
+func (m *model) move(delta int) {
+	cursor := m.cursor + delta
+	if cursor < 0 || cursor >= len(m.visible) {
+		return
+	}
+	m.cursor = cursor
+	m.revealed = false
+}
+
+// update applies one key press
+func (m *model) update(k key) {
+	if k.t == keyCtrlC {
+		m.quit = true
+		return
+	}
+	m.status = ""
+
+	switch m.mode {
+	case modeSearch:
+		m.updateSearch(k)
+	case modeForm:
+		m.updateForm(k)
+	default:
+		m.updateList(k)
+	}
+}
+
+func (m *model) updateList(k key) {
+	switch {
+	case k.t == keyUp || k.t == keyRune && k.r == 'k':
+		m.move(-1)
+	case k.t == keyDown || k.t == keyRune && k.r == 'j':
+		m.move(1)
+	case k.t == keyRune && k.r == '/':
+		m.mode = modeSearch
+	case k.t == keyEscape && m.query != "":
+		m.query = ""
+		m.filter()
+	case k.t == keyRune && k.r == 'r' || k.t == keyEnter:
+		m.revealed = !m.revealed
+	case k.t == keyRune && k.r == 'n':
+		m.openForm(nil)
+	case k.t == keyRune && k.r == 'e':
+		if item := m.selected(); item != nil {
+			m.openForm(item)
+		}
+	case k.t == keyRune && k.r == 'g':
+		if err := m.refresh(m.selectedID()); err != nil {
+			m.status = "Refresh failed: " + err.Error()
+		}
+	case k.t == keyRune && k.r == 'q':
+		m.quit = true
+	}
+}
+
+func (m *model) selectedID() uuid.UUID {
+	if item := m.selected(); item != nil {
+		return item.ID
+	}
+	return uuid.Nil
+}
+
+func (m *model) updateSearch(k key) {
+	switch k.t {
+	case keyRune:
+		m.query += string(k.r)
+		m.filter()
+	case keyBackspace:
+		if query := []rune(m.query); len(query) > 0 {
+			m.query = string(query[:len(query)-1])
+			m.filter()
+		}
+	case keyEscape:
+		m.query = ""
+		m.filter()
+		m.mode = modeList
+	case keyEnter:
+		m.mode = modeList
+	case keyUp:
+		m.move(-1)
+	case keyDown:
+		m.move(1)
+	}
+}
+
+// openForm starts editing data, or creating an item when data is nil
+func (m *model) openForm(data *models.Data) {
+	f := &form{data: data}
+	if data == nil {
+		f.fields = formFields("", "", client.NewItemFields(client.EditableTypes[0]))
+	} else {
+		if client.NewItemFields(data.Type) == nil {
+			m.status = fmt.Sprintf("%s items cannot be edited here", data.Type)
+			return
+		}
+		fields, err := m.vault.ItemFields(data)
+		if err != nil {
+			m.status = err.Error()
+			return
+		}
+		f.fields = formFields(data.Name, data.Description, fields)
+	}
+	m.form = f
+	m.mode = modeForm
+}
+
+// formFields puts the item name and description before its payload fields
+func formFields(name, description string, payload []client.ItemField) []client.ItemField {
+	return append([]client.ItemField{
+		{Name: "name", Value: name, Required: true},
+		{Name: "description", Value: description},
+	}, payload...)
+}
+
+// rows is the number of focusable form rows: the type row, if any, and the fields
+func (f *form) rows() int {
+	if f.data == nil {
+		return len(f.fields) + 1
+	}
+	return len(f.fields)
+}
+
+// field returns the focused field, or nil on the type row
+func (f *form) field() *client.ItemField {
+	index := f.focus
+	if f.data == nil {
+		index--
+	}
+	if index < 0 {
+		return nil
+	}
+	return &f.fields[index]
+}
+
+// setType switches a new item to the next or previous editable type, keeping
+// the name and description
+func (f *form) setType(delta int) {
+	count := len(client.EditableTypes)
+	f.typeIndex = (f.typeIndex + delta + count) % count
+	f.fields = formFields(f.fields[0].Value, f.fields[1].Value, client.NewItemFields(client.EditableTypes[f.typeIndex]))
+}
+
+func (m *model) updateForm(k key) {
+	f := m.form
+	field := f.field()
+	switch {
+	case k.t == keyEscape:
+		m.form = nil
+		m.mode = modeList
+	case k.t == keyTab || k.t == keyDown:
+		f.focus = (f.focus + 1) % f.rows()
+	case k.t == keyShiftTab || k.t == keyUp:
+		f.focus = (f.focus + f.rows() - 1) % f.rows()
+	case k.t == keyEnter && f.focus < f.rows()-1:
+		f.focus++
+	case k.t == keyEnter || k.t == keyCtrlS:
+		m.saveForm()
+	case k.t == keyCtrlR:
+		f.revealed = !f.revealed
+	case field == nil && (k.t == keyLeft || k.t == keyRight):
+		delta := 1
+		if k.t == keyLeft {
+			delta = -1
+		}
+		f.setType(delta)
+	case field != nil && k.t == keyRune:
+		field.Value += string(k.r)
+	case field != nil && k.t == keyBackspace:
+		if value := []rune(field.Value); len(value) > 0 {
+			field.Value = string(value[:len(value)-1])
+		}
+	}
+}
+
+// saveForm stores the form and returns to the list on the saved item
+func (m *model) saveForm() {
+	f := m.form
+	name, description := strings.TrimSpace(f.fields[0].Value), strings.TrimSpace(f.fields[1].Value)
+	payload := make([]client.ItemField, len(f.fields)-2)
+	copy(payload, f.fields[2:])
+	for i := range payload {
+		payload[i].Value = strings.TrimSpace(payload[i].Value)
+	}
+
+	saved, err := m.vault.SaveItem(m.ctx, f.data, client.EditableTypes[f.typeIndex], name, description, payload)
+	if saved == nil {
+		m.status = err.Error()
+		return
+	}
+
+	m.form = nil
+	m.mode = modeList
+	m.query = ""
+	if err := m.refresh(saved.ID); err != nil {
+		m.status = "Saved, but refreshing failed: " + err.Error()
+		return
+	}
+	if err != nil {
+		// saved as a conflict copy
+		m.status = err.Error()
+		return
+	}
+	m.status = fmt.Sprintf("Saved %q", saved.Name)
+}
+
+// view renders the screen as width x height cells, lines separated by CRLF
+// for terminals in raw mode
+func (m *model) view(width, height int) string {
+	if width < 20 || height < 5 {
+		return "Terminal too small"
+	}
+
+	header := fmt.Sprintf("%sGophKeeper%s  %d items", bold, reset, len(m.items))
+	if m.query != "" || m.mode == modeSearch {
+		header += fmt.Sprintf("  search: %s", m.query)
+		if m.mode == modeSearch {
+			header += "_"
+		}
+		header += fmt.Sprintf(" (%d)", len(m.visible))
+	}
+
+	bodyHeight := height - 3
+	listWidth := width * 2 / 5
+	left := m.listLines(listWidth, bodyHeight)
+	var right []string
+	if m.mode == modeForm {
+		right = m.formLines(width - listWidth - 3)
+	} else {
+		right = m.detailLines(width - listWidth - 3)
+	}
+
+	lines := []string{header, strings.Repeat("─", width)}
+	for i := 0; i < bodyHeight; i++ {
+		row := pad(lineAt(left, i), listWidth) + " │ "
+		lines = append(lines, row+fit(lineAt(right, i), width-listWidth-3))
+	}
+
+	footer := m.help()
+	if m.status != "" {
+		footer = m.status
+	}
+	lines = append(lines, fit(footer, width))
+	return strings.Join(lines, "\r\n")
+}
+
+func (m *model) help() string {
+	switch m.mode {
+	case modeSearch:
+		return dim + "type to filter  ↑/↓ move  Enter done  Esc clear" + reset
+	case modeForm:
+		return dim + "Tab/↓ next  Shift+Tab/↑ previous  ←/→ type  Ctrl+R reveal  Ctrl+S save  Esc cancel" + reset
+	default:
+		return dim + "↑/↓ move  / search  Enter reveal  n new  e edit  g refresh  q quit" + reset
+	}
+}
+
+// listLines renders the visible items, scrolled to keep the cursor in view
+func (m *model) listLines(width, height int) []string {
+	if len(m.visible) == 0 {
+		if m.query != "" {
+			return []string{dim + "No matches" + reset}
+		}
+		return []string{dim + "No items yet, press n to create one" + reset}
+	}
+
+	if m.cursor < m.scroll {
+		m.scroll = m.cursor
+	}
+	if m.cursor >= m.scroll+height {
+		m.scroll = m.cursor - height + 1
+	}
+
+	var lines []string
+	for i := m.scroll; i < len(m.visible) && i < m.scroll+height; i++ {
+		item := m.items[m.visible[i]]
+		row := fit(fmt.Sprintf("%-5s %s", typeLabels[item.Type], client.CleanQuotes(item.Name)), width)
+		if i == m.cursor {
+			row = reverse + row + reset
+		}
+		lines = append(lines, row)
+	}
+	return lines
+}
+
+// detailLines renders the selected item with secrets masked unless revealed
+func (m *model) detailLines(width int) []string {
+	item := m.selected()
+	if item == nil {
+		return nil
+	}
+
+	lines := []string{bold + fit(client.CleanQuotes(item.Name), width) + reset}
+	if item.Description != "" {
+		lines = append(lines, wrap(client.CleanQuotes(item.Description), width)...)
+	}
+	lines = append(lines, dim+fmt.Sprintf("%s · updated %s", item.Type, item.UpdatedAt.Format("2006-01-02 15:04"))+reset, "")
+
+	fields, err := m.selectedFields()
+	if err != nil {
+		return append(lines, err.Error())
+	}
+	for _, field := range fields {
+		value := field.Value
+		if field.Secret && !m.revealed && value != "" {
+			value = secretMask
+		}
+		lines = append(lines, wrap(field.Name+": "+value, width)...)
+	}
+	return lines
+}
+
+// formLines renders the form with the focused row highlighted
+func (m *model) formLines(width int) []string {
+	f := m.form
+	title := "New item"
+	if f.data != nil {
+		title = "Edit " + client.CleanQuotes(f.data.Name)
+	}
+	lines := []string{bold + fit(title, width) + reset, ""}
+
+	row := 0
+	if f.data == nil {
+		line := fmt.Sprintf("type: ‹ %s ›", client.EditableTypes[f.typeIndex])
+		if f.focus == row {
+			line = reverse + line + reset
+		}
+		lines = append(lines, line)
+		row++
+	}
+	for _, field := range f.fields {
+		value := field.Value
+		if field.Secret && !f.revealed {
+			value = strings.Repeat("•", len([]rune(value)))
+		}
+		label := field.Name
+		if field.Required {
+			label += "*"
+		}
+		line := fit(label+": "+value, width-1)
+		if f.focus == row {
+			line = reverse + line + "_" + reset
+		}
+		lines = append(lines, line)
+		row++
+	}
+	return lines
+}
+
+func lineAt(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
+
+// visibleLen counts the runes of s that take up cells, skipping escape sequences
+func visibleLen(s string) int {
+	n := 0
+	escape := false
+	for _, r := range s {
+		switch {
+		case r == 0x1b:
+			escape = true
+		case escape:
+			escape = r < 0x40 || r > 0x7e || r == '['
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// fit truncates s to width cells, marking the cut with an ellipsis. Styled
+// strings are left alone, they are kept short by their callers.
+func fit(s string, width int) string {
+	if strings.ContainsRune(s, 0x1b) || len([]rune(s)) <= width {
+		return s
+	}
+	if width < 1 {
+		return ""
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+// pad fits s to width cells and fills the rest with spaces
+func pad(s string, width int) string {
+	s = fit(s, width)
+	if n := visibleLen(s); n < width {
+		s += strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// wrap breaks s into lines of at most width runes
+func wrap(s string, width int) []string {
+	runes := []rune(s)
+	if width < 1 || len(runes) <= width {
+		return []string{s}
+	}
+	var lines []string
+	for len(runes) > width {
+		lines = append(lines, string(runes[:width]))
+		runes = runes[width:]
+	}
+	return append(lines, string(runes))
+}
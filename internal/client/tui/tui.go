@@ -0,0 +1,105 @@
+// Package tui is a full-screen terminal interface to the vault: a list of items
+// with fuzzy search, a detail pane with secrets masked until revealed, and
+// inline forms to create and edit items.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/a2sh3r/gophkeeper/internal/client"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/term"
+)
+
+// Screen control sequences
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	hideCursor     = "\x1b[?25l"
+	showCursor     = "\x1b[?25h"
+	clearScreen    = "\x1b[H\x1b[2J"
+)
+
+// Vault is what the TUI needs of an unlocked client session
+type Vault interface {
+	List(ctx context.Context) ([]models.Data, error)
+	ItemFields(data *models.Data) ([]client.ItemField, error)
+	SaveItem(ctx context.Context, data *models.Data, dataType models.DataType, name, description string, fields []client.ItemField) (*models.Data, error)
+}
+
+// Run shows the TUI on the terminal in until the user quits or ctx is done,
+// e.g. because the vault was locked. onInput, if set, is called on every key
+// press, e.g. to postpone an idle lock.
+func Run(ctx context.Context, vault Vault, in *os.File, out io.Writer, onInput func()) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("the TUI needs an interactive terminal")
+	}
+
+	m := newModel(ctx, vault)
+	if err := m.refresh(uuid.Nil); err != nil {
+		return fmt.Errorf("failed to list items: %w", err)
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to set up terminal: %w", err)
+	}
+	defer func() {
+		_ = term.Restore(fd, state)
+	}()
+	fmt.Fprint(out, enterAltScreen+hideCursor)
+	defer fmt.Fprint(out, clearScreen+showCursor+exitAltScreen)
+
+	keys := make(chan []key)
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			if onInput != nil {
+				onInput()
+			}
+			select {
+			case keys <- parseKeys(buf[:n]):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for !m.quit {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		fmt.Fprint(out, clearScreen+m.view(width, height))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read keys: %w", err)
+		case pressed := <-keys:
+			for _, k := range pressed {
+				m.update(k)
+				if m.quit {
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package tui
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/client"
+	"github.com/google/uuid"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []key
+	}{
+		{name: "runes", input: "aé", want: []key{{t: keyRune, r: 'a'}, {t: keyRune, r: 'é'}}},
+		{name: "arrows", input: "\x1b[A\x1b[B\x1bOC\x1b[D", want: []key{{t: keyUp}, {t: keyDown}, {t: keyRight}, {t: keyLeft}}},
+		{name: "lone escape", input: "\x1b", want: []key{{t: keyEscape}}},
+		{name: "shift tab", input: "\t\x1b[Z", want: []key{{t: keyTab}, {t: keyShiftTab}}},
+		{name: "controls", input: "\r\x7f\x03\x12\x13", want: []key{{t: keyEnter}, {t: keyBackspace}, {t: keyCtrlC}, {t: keyCtrlR}, {t: keyCtrlS}}},
+		{name: "unbound sequences dropped", input: "\x1b[3~\x1b[1;5Ax\x01", want: []key{{t: keyRune, r: 'x'}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseKeys([]byte(tt.input)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseKeys(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	if _, ok := fuzzyScore("gml", "Google Maps"); ok {
+		t.Error("Expected no match without an l")
+	}
+	gmail, _ := fuzzyScore("gm", "GMail")
+	googleMail, _ := fuzzyScore("gm", "Google Mail")
+	tagManager, ok := fuzzyScore("gm", "Tagmanager")
+	if !ok || !(gmail > googleMail && googleMail > tagManager) {
+		t.Errorf("Expected consecutive, then word start matches to rank first, got %d, %d, %d", gmail, googleMail, tagManager)
+	}
+}
+
+func newDemoModel(t *testing.T) (*model, *client.ClientSession) {
+	t.Helper()
+	session, _, err := client.NewDemoSession(context.Background())
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	m := newModel(context.Background(), session)
+	if err := m.refresh(uuid.Nil); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	return m, session
+}
+
+func press(m *model, keys ...key) {
+	for _, k := range keys {
+		m.update(k)
+	}
+}
+
+func typeText(m *model, text string) {
+	for _, r := range text {
+		m.update(key{t: keyRune, r: r})
+	}
+}
+
+func TestModel_SearchAndReveal(t *testing.T) {
+	m, _ := newDemoModel(t)
+
+	press(m, key{t: keyRune, r: '/'})
+	typeText(m, "dvisa")
+	press(m, key{t: keyEnter})
+	if item := m.selected(); item == nil || item.Name != "Demo Visa" {
+		t.Fatalf("Expected the search to select Demo Visa, got %v", item)
+	}
+
+	screen := m.view(100, 20)
+	if !strings.Contains(screen, "card_number: "+secretMask) || strings.Contains(screen, "4111111111111111") {
+		t.Errorf("Expected the card number to be masked:\n%s", screen)
+	}
+	if !strings.Contains(screen, "expiry_date: 12/30") {
+		t.Errorf("Expected other fields to be shown:\n%s", screen)
+	}
+
+	press(m, key{t: keyEnter})
+	if screen := m.view(100, 20); !strings.Contains(screen, "card_number: 4111111111111111") {
+		t.Errorf("Expected the card number after revealing:\n%s", screen)
+	}
+
+	press(m, key{t: keyEscape})
+	if m.query != "" || len(m.visible) != len(m.items) {
+		t.Errorf("Expected Escape to clear the search, got %q with %d of %d items", m.query, len(m.visible), len(m.items))
+	}
+	if screen := m.view(100, 20); strings.Contains(screen, "4111111111111111") {
+		t.Error("Expected secrets to be masked again after the selection changed")
+	}
+}
+
+func TestModel_CreateAndEdit(t *testing.T) {
+	m, session := newDemoModel(t)
+	tab := key{t: keyTab}
+
+	press(m, key{t: keyRune, r: 'n'}, tab)
+	typeText(m, "Router")
+	press(m, key{t: keyCtrlS})
+	if m.mode != modeForm || !strings.Contains(m.status, "login is required") {
+		t.Fatalf("Expected the form to stay open on a missing login, got mode %d, status %q", m.mode, m.status)
+	}
+
+	press(m, tab, tab)
+	typeText(m, "admin")
+	press(m, tab)
+	typeText(m, "hunter2")
+	if screen := m.view(100, 20); strings.Contains(screen, "hunter2") || !strings.Contains(screen, "password*: •••••••") {
+		t.Errorf("Expected the password to be masked while typing:\n%s", screen)
+	}
+	press(m, key{t: keyCtrlS})
+	if m.mode != modeList || m.status != `Saved "Router"` {
+		t.Fatalf("Expected the item to be saved, got mode %d, status %q", m.mode, m.status)
+	}
+	created := m.selected()
+	if created == nil || created.Name != "Router" {
+		t.Fatalf("Expected the new item to be selected, got %v", created)
+	}
+
+	press(m, key{t: keyRune, r: 'e'}, tab, tab, tab, key{t: keyBackspace})
+	typeText(m, "3")
+	press(m, key{t: keyCtrlS})
+	if m.status != `Saved "Router"` {
+		t.Fatalf("Expected the edit to be saved, got %q", m.status)
+	}
+
+	fields, err := session.ItemFields(m.selected())
+	if err != nil {
+		t.Fatalf("ItemFields() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, field := range fields {
+		got[field.Name] = field.Value
+	}
+	if got["login"] != "admin" || got["password"] != "hunter3" {
+		t.Errorf("Expected the edited fields, got %v", got)
+	}
+}
+
+func TestModel_NewItemType(t *testing.T) {
+	m, _ := newDemoModel(t)
+
+	press(m, key{t: keyRune, r: 'n'}, key{t: keyRight})
+	if m.form.typeIndex != 1 || m.form.fields[2].Name != "card_number" {
+		t.Errorf("Expected the card fields after switching type, got %v", m.form.fields)
+	}
+	press(m, key{t: keyLeft}, key{t: keyLeft})
+	if m.form.fields[2].Name != "content" {
+		t.Errorf("Expected the type to wrap around to notes, got %v", m.form.fields)
+	}
+	press(m, key{t: keyEscape})
+	if m.mode != modeList || m.form != nil {
+		t.Error("Expected Escape to cancel the form")
+	}
+}
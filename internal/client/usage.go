@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	usageFile = ".gophkeeper_usage"
+)
+
+// GetUsagePath returns the path to the local item usage file
+func GetUsagePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return usageFile
+	}
+	return fmt.Sprintf("%s/%s", homeDir, usageFile)
+}
+
+// SetUsagePath enables recording when items were last used; the file is encrypted
+// with the vault key and never leaves this machine. An empty path disables it.
+func (s *ClientSession) SetUsagePath(path string) {
+	s.usagePath = path
+}
+
+// loadUsage decrypts the last-used times by item ID. A missing file, or one written
+// under another vault key, yields an empty map.
+func (s *ClientSession) loadUsage() map[string]time.Time {
+	usage := make(map[string]time.Time)
+	if s.usagePath == "" || !s.IsAuthenticated() {
+		return usage
+	}
+
+	encrypted, err := os.ReadFile(s.usagePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Log.Warn("Failed to read usage file", zap.Error(err))
+		}
+		return usage
+	}
+
+	decrypted, err := s.cryptoManager.Decrypt(encrypted)
+	if err != nil {
+		logger.Log.Warn("Usage file was written under another vault key, starting over", zap.Error(err))
+		return usage
+	}
+	if err := json.Unmarshal(decrypted, &usage); err != nil {
+		logger.Log.Warn("Failed to parse usage file", zap.Error(err))
+	}
+	return usage
+}
+
+// recordUsage stores that an item was just accessed
+func (s *ClientSession) recordUsage(id string) {
+	if s.usagePath == "" || !s.IsAuthenticated() {
+		return
+	}
+
+	usage := s.loadUsage()
+	usage[id] = time.Now().UTC()
+	s.saveUsage(usage)
+}
+
+// saveUsage encrypts the last-used times with the vault key and writes them
+func (s *ClientSession) saveUsage(usage map[string]time.Time) {
+	if s.usagePath == "" || !s.IsAuthenticated() {
+		return
+	}
+
+	payload, err := json.Marshal(usage)
+	if err != nil {
+		logger.Log.Warn("Failed to marshal usage", zap.Error(err))
+		return
+	}
+	encrypted, err := s.cryptoManager.Encrypt(payload)
+	if err != nil {
+		logger.Log.Warn("Failed to encrypt usage", zap.Error(err))
+		return
+	}
+	if err := os.WriteFile(s.usagePath, encrypted, 0600); err != nil {
+		logger.Log.Warn("Failed to write usage file", zap.Error(err))
+	}
+}
+
+// lastActivity is when an item was last used, or last changed if it was never
+// used since tracking started
+func lastActivity(item *models.Data, usage map[string]time.Time) time.Time {
+	if used, ok := usage[item.ID.String()]; ok && used.After(item.UpdatedAt) {
+		return used
+	}
+	return item.UpdatedAt
+}
+
+// ParseAge parses ages such as 90d, 2w, 6m or 1y (months are 30 days, years 365)
+// as well as Go durations such as 720h
+func ParseAge(age string) (time.Duration, error) {
+	return models.ParseAge(age)
+}
+
+// UnusedItems returns items not used for at least olderThan, least recently used first
+func (s *ClientSession) UnusedItems(ctx context.Context, olderThan time.Duration) ([]models.Data, map[string]time.Time, error) {
+	if !s.IsAuthenticated() {
+		return nil, nil, ErrNotAuthenticated
+	}
+
+	items, err := s.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	usage := s.loadUsage()
+	cutoff := time.Now().Add(-olderThan)
+	var unused []models.Data
+	for i := range items {
+		if lastActivity(&items[i], usage).Before(cutoff) {
+			unused = append(unused, items[i])
+		}
+	}
+	sort.SliceStable(unused, func(i, j int) bool {
+		return lastActivity(&unused[i], usage).Before(lastActivity(&unused[j], usage))
+	})
+	return unused, usage, nil
+}
+
+// UnusedCommand lists items that were not used for a while so they can be pruned
+func (s *ClientSession) UnusedCommand(ctx context.Context, args []string) error {
+	if len(args) != 2 || args[0] != "--older-than" {
+		return fmt.Errorf("usage: unused --older-than <age>, e.g. 90d, 6m or 1y")
+	}
+	olderThan, err := ParseAge(args[1])
+	if err != nil {
+		return err
+	}
+
+	unused, usage, err := s.UnusedItems(ctx, olderThan)
+	if err != nil {
+		return err
+	}
+
+	if len(unused) == 0 {
+		fmt.Printf("All items were used or changed within %s\n", args[1])
+		return nil
+	}
+
+	fmt.Printf("%d item(s) not used for %s:\n", len(unused), args[1])
+	for i := range unused {
+		item := &unused[i]
+		fmt.Printf("  %s [%s] - %s - %s\n", item.ID, item.Type, CleanQuotes(item.Name), describeLastUse(item, usage))
+	}
+	fmt.Println("Delete the ones you no longer need with 'delete <id>'.")
+	return nil
+}
+
+// describeLastUse renders when an item was last used for list output
+func describeLastUse(item *models.Data, usage map[string]time.Time) string {
+	if used, ok := usage[item.ID.String()]; ok {
+		return "last used " + used.Local().Format("2006-01-02")
+	}
+	return "not used since " + item.UpdatedAt.Local().Format("2006-01-02")
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{age: "90d", want: 90 * 24 * time.Hour},
+		{age: "2w", want: 14 * 24 * time.Hour},
+		{age: "6m", want: 180 * 24 * time.Hour},
+		{age: "1y", want: 365 * 24 * time.Hour},
+		{age: "720h", want: 720 * time.Hour},
+		{age: "0d", wantErr: true},
+		{age: "-1y", wantErr: true},
+		{age: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.age, func(t *testing.T) {
+			got, err := ParseAge(tt.age)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAge(%q) error = %v, wantErr %v", tt.age, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAge(%q) = %v, want %v", tt.age, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastActivity(t *testing.T) {
+	updated := time.Now().Add(-48 * time.Hour)
+	item := &models.Data{ID: uuid.New(), UpdatedAt: updated}
+
+	if got := lastActivity(item, nil); !got.Equal(updated) {
+		t.Errorf("Never used item should fall back to UpdatedAt, got %v", got)
+	}
+
+	used := time.Now().Add(-time.Hour)
+	if got := lastActivity(item, map[string]time.Time{item.ID.String(): used}); !got.Equal(used) {
+		t.Errorf("Expected last use %v, got %v", used, got)
+	}
+
+	stale := updated.Add(-time.Hour)
+	if got := lastActivity(item, map[string]time.Time{item.ID.String(): stale}); !got.Equal(updated) {
+		t.Errorf("A change after the last use counts as activity, got %v", got)
+	}
+}
+
+func TestClientSession_RecordUsage(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	items, err := session.List(ctx)
+	if err != nil || len(items) == 0 {
+		t.Fatalf("List() = %d items, %v", len(items), err)
+	}
+	id := items[0].ID.String()
+
+	// without a usage path nothing is recorded
+	session.recordUsage(id)
+	if usage := session.loadUsage(); len(usage) != 0 {
+		t.Errorf("Expected no usage without a path, got %v", usage)
+	}
+
+	path := filepath.Join(t.TempDir(), "usage")
+	session.SetUsagePath(path)
+	session.recordUsage(id)
+
+	usage := session.loadUsage()
+	if _, ok := usage[id]; !ok || len(usage) != 1 {
+		t.Fatalf("Expected usage of %s, got %v", id, usage)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if bytes.Contains(raw, []byte(id)) {
+		t.Error("Usage file should be encrypted")
+	}
+
+	unused, _, err := session.UnusedItems(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("UnusedItems() error = %v", err)
+	}
+	if len(unused) != 0 {
+		t.Errorf("Fresh items should not be unused, got %d", len(unused))
+	}
+}
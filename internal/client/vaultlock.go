@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// vaultLockHeader carries the token of the held vault lock, see server.VaultLockHeader
+const vaultLockHeader = "X-Vault-Lock"
+
+// vaultLockTTL is the lifetime requested for vault locks; they are renewed at
+// a third of it while the operation runs
+const vaultLockTTL = 2 * time.Minute
+
+// VaultBusyError is returned when a change is rejected because another device
+// holds the vault lock for a maintenance operation
+type VaultBusyError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *VaultBusyError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = "vault busy: another device is changing it"
+	}
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s; please retry in %s", msg, e.RetryAfter)
+	}
+	return msg + "; please retry later"
+}
+
+// IsVaultBusy reports whether err was caused by another device's vault lock
+func IsVaultBusy(err error) bool {
+	var busyErr *VaultBusyError
+	return errors.As(err, &busyErr)
+}
+
+// vaultBusyError returns a *VaultBusyError if the response is a vault lock rejection
+func vaultBusyError(resp *http.Response, body []byte) error {
+	if resp.StatusCode != http.StatusLocked {
+		return nil
+	}
+
+	var errResp models.ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error != "vault_busy" {
+		return nil
+	}
+
+	busyErr := &VaultBusyError{Message: errResp.Message}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		busyErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return busyErr
+}
+
+// rejectionError returns the typed error of a maintenance mode or vault lock
+// rejection, or nil for other responses
+func rejectionError(resp *http.Response, body []byte) error {
+	if err := maintenanceError(resp, body); err != nil {
+		return err
+	}
+	return vaultBusyError(resp, body)
+}
+
+// LockVault locks the vault for operation, or renews the lock the client holds
+func (c *Client) LockVault(ctx context.Context, operation string, ttl time.Duration) (*models.VaultLock, error) {
+	var lock models.VaultLock
+	req := models.VaultLockRequest{Operation: operation, TTLSeconds: int64(ttl / time.Second)}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/vault/lock", req, &lock, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// UnlockVault releases the vault lock with the given token
+func (c *Client) UnlockVault(ctx context.Context, token string) error {
+	return c.doJSON(ctx, http.MethodPost, "/api/v1/vault/unlock", models.VaultUnlockRequest{Token: token}, nil, http.StatusNoContent)
+}
+
+// withVaultLock runs fn holding the vault lock for operation, so other devices
+// get a clear busy error instead of changing the vault halfway through it.
+// Servers that do not advertise vault locks run fn unlocked.
+func (s *ClientSession) withVaultLock(ctx context.Context, operation string, fn func() error) error {
+	status, err := s.cli.GetStatus(ctx)
+	if err != nil || !hasFeature(status, "vault_lock") {
+		return fn()
+	}
+
+	lock, err := s.cli.LockVault(ctx, operation, vaultLockTTL)
+	if err != nil {
+		return err
+	}
+	s.cli.vaultLock = lock.Token
+
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(vaultLockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := s.cli.LockVault(ctx, operation, vaultLockTTL); err != nil {
+					logger.Log.Warn("Failed to renew vault lock", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	defer func() {
+		close(done)
+		<-renewed
+		s.cli.vaultLock = ""
+		// the lock expires on its own if releasing it fails
+		if err := s.cli.UnlockVault(context.WithoutCancel(ctx), lock.Token); err != nil {
+			logger.Log.Warn("Failed to release vault lock", zap.Error(err))
+		}
+	}()
+	return fn()
+}
+
+func hasFeature(status *models.StatusResponse, feature string) bool {
+	for _, f := range status.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
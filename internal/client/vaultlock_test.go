@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestClientSession_VaultBusy(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	// another device of the same account starts a rotation
+	other := NewClient(demoServerURL)
+	other.httpClient.Transport = session.cli.httpClient.Transport
+	other.SetToken(session.cli.token)
+	lock, err := other.LockVault(ctx, "rotation", vaultLockTTL)
+	if err != nil {
+		t.Fatalf("LockVault() error = %v", err)
+	}
+
+	sealed, err := session.cryptoManager.Encrypt([]byte("note"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	_, err = session.Create(ctx, models.DataRequest{Type: models.DataTypeText, Name: "Note", Data: sealed})
+	if !IsVaultBusy(err) {
+		t.Fatalf("Expected a vault busy error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "vault busy: rotation in progress") {
+		t.Errorf("Expected the operation in the error, got %q", err.Error())
+	}
+	if _, err := session.List(ctx); err != nil {
+		t.Errorf("Expected reads to work while locked, got %v", err)
+	}
+
+	if err := other.UnlockVault(ctx, lock.Token); err != nil {
+		t.Fatalf("UnlockVault() error = %v", err)
+	}
+	if _, err := session.Create(ctx, models.DataRequest{Type: models.DataTypeText, Name: "Note", Data: sealed}); err != nil {
+		t.Errorf("Create() after unlocking error = %v", err)
+	}
+}
+
+func TestClientSession_ImportLocksVault(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+
+	other := NewClient(demoServerURL)
+	other.httpClient.Transport = session.cli.httpClient.Transport
+	other.SetToken(session.cli.token)
+
+	transport := session.cli.httpClient.Transport.(*handlerTransport)
+	router := transport.handler
+	var busyErr error
+	transport.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/data/import" {
+			if r.Header.Get(vaultLockHeader) == "" {
+				t.Error("Expected the import to carry the vault lock")
+			}
+			_, busyErr = other.CreateData(ctx, models.DataRequest{Type: models.DataTypeText, Name: "Racing", Data: []byte("sealed")})
+		}
+		router.ServeHTTP(w, r)
+	})
+
+	path := writeImportFile(t, 3)
+	if _, err := session.Import(ctx, path, io.Discard); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if !IsVaultBusy(busyErr) || !strings.Contains(busyErr.Error(), "import in progress") {
+		t.Errorf("Expected other devices to be told an import is running, got %v", busyErr)
+	}
+
+	if session.cli.vaultLock != "" {
+		t.Error("Expected the lock token to be dropped after the import")
+	}
+	if _, err := other.LockVault(ctx, "restore", vaultLockTTL); err != nil {
+		t.Errorf("Expected the vault to be unlocked after the import, got %v", err)
+	}
+}
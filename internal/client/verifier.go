@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// GetMasterVerifier gets the master password verifier, nil if none is set
+func (c *Client) GetMasterVerifier(ctx context.Context) ([]byte, error) {
+	var resp models.VerifierResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/verifier", nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Verifier, nil
+}
+
+// SetMasterVerifier registers the master password verifier of an account that has none yet
+func (c *Client) SetMasterVerifier(ctx context.Context, verifier []byte) error {
+	return c.doJSON(ctx, http.MethodPut, "/api/v1/verifier", models.VerifierRequest{Verifier: verifier}, nil, http.StatusNoContent)
+}
+
+// publishVerifier stores a verifier of the vault key with the account. Servers
+// without verifiers reject it, which only costs the early password check.
+func (s *ClientSession) publishVerifier(ctx context.Context, cryptoManager *crypto.CryptoManager) {
+	verifier, err := cryptoManager.NewVerifier()
+	if err == nil {
+		err = s.cli.SetMasterVerifier(ctx, verifier)
+	}
+	if err != nil {
+		logger.Log.Warn("Failed to publish master password verifier", zap.Error(err))
+	}
+}
+
+// verifyMasterPassword checks the candidate key against the verifier stored
+// at registration. Accounts created before verifiers are checked against the
+// first vault item instead, and get a verifier once that succeeds; an empty
+// vault without a verifier cannot be checked and is accepted.
+func (s *ClientSession) verifyMasterPassword(ctx context.Context, cryptoManager *crypto.CryptoManager) error {
+	if verifier, err := s.cli.GetMasterVerifier(ctx); err == nil && len(verifier) > 0 {
+		if !cryptoManager.CheckVerifier(verifier) {
+			return ErrWrongMasterPassword
+		}
+		return nil
+	}
+
+	items, err := s.cli.GetData(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load vault: %w", err)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	if _, err := cryptoManager.Decrypt(items[0].Data); err != nil {
+		return ErrWrongMasterPassword
+	}
+	s.publishVerifier(ctx, cryptoManager)
+	return nil
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// Outcomes of verifying an item
+const (
+	VerifyOK         = "ok"
+	VerifyNoChecksum = "no checksum"
+	VerifyMismatch   = "checksum mismatch"
+	VerifyDamaged    = "cannot decrypt"
+)
+
+// VerifyResult is the outcome of checking the stored checksum of one item
+type VerifyResult struct {
+	// ID is the shortest unique prefix of the item ID
+	ID     string
+	Item   string
+	Status string
+	// Err is the decryption error of a damaged item
+	Err error
+}
+
+// withChecksum sets the checksum of the plaintext of dataReq, so the item can
+// be verified later. Records in the format older clients wrote get none.
+func (s *ClientSession) withChecksum(dataReq *models.DataRequest) error {
+	checksum, err := s.cryptoManager.Checksum(dataReq.Data)
+	if err != nil {
+		return fmt.Errorf("failed to compute checksum: %w", err)
+	}
+	dataReq.Checksum = checksum
+	return nil
+}
+
+// Verify decrypts the item with the given ID or ID prefix, or every item if
+// id is empty, and checks the plaintext against the stored checksums
+func (s *ClientSession) Verify(ctx context.Context, id string) ([]VerifyResult, error) {
+	if !s.IsAuthenticated() {
+		return nil, ErrNotAuthenticated
+	}
+
+	var items []models.Data
+	if id == "" {
+		var err error
+		if items, err = s.List(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		items = []models.Data{*data}
+	}
+
+	shortIDs := ShortIDs(items)
+	results := make([]VerifyResult, 0, len(items))
+	for i := range items {
+		item := &items[i]
+		result := VerifyResult{ID: shortIDs[item.ID.String()], Item: CleanQuotes(item.Name), Status: VerifyOK}
+		switch err := s.cryptoManager.VerifyChecksum(item.Data, item.Checksum); {
+		case len(item.Checksum) == 0:
+			// still decrypted, so damaged items without a checksum are found too
+			if _, err := s.cryptoManager.Decrypt(item.Data); err != nil {
+				result.Status, result.Err = VerifyDamaged, err
+			} else {
+				result.Status = VerifyNoChecksum
+			}
+		case errors.Is(err, crypto.ErrChecksumMismatch):
+			result.Status = VerifyMismatch
+		case err != nil:
+			result.Status, result.Err = VerifyDamaged, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// VerifyCommand verifies one item, or all of them for "--all", and fails if
+// any item is corrupted
+func (s *ClientSession) VerifyCommand(ctx context.Context, arg string) error {
+	id := arg
+	if arg == "--all" {
+		id = ""
+	}
+	results, err := s.Verify(ctx, id)
+	if err != nil {
+		return err
+	}
+	return writeVerifyResults(os.Stdout, results)
+}
+
+// writeVerifyResults reports the results and returns an error if any item is
+// corrupted
+func writeVerifyResults(w io.Writer, results []VerifyResult) error {
+	var failed, unchecked int
+	for _, result := range results {
+		switch result.Status {
+		case VerifyOK:
+			continue
+		case VerifyNoChecksum:
+			unchecked++
+		default:
+			failed++
+		}
+		if result.Err != nil {
+			fmt.Fprintf(w, "  %s  %s - %s: %v\n", result.ID, result.Item, result.Status, result.Err)
+		} else {
+			fmt.Fprintf(w, "  %s  %s - %s\n", result.ID, result.Item, result.Status)
+		}
+	}
+
+	fmt.Fprintf(w, "Verified %d items: %d ok, %d corrupted, %d without a checksum\n",
+		len(results), len(results)-failed-unchecked, failed, unchecked)
+	if unchecked > 0 {
+		fmt.Fprintln(w, "Items written before checksums were added get one when they are next updated")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d items failed verification: their stored data was corrupted or tampered with", failed)
+	}
+	return nil
+}
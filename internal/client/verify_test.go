@@ -0,0 +1,79 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestClientSession_Verify(t *testing.T) {
+	ctx := context.Background()
+	session := newBatchTestSession(t)
+
+	intact, err := session.CreateItem(ctx, models.DataTypeText, ItemInput{Name: "Intact", Fields: map[string]string{"content": "hello"}})
+	if err != nil {
+		t.Fatalf("CreateItem() error = %v", err)
+	}
+	if len(intact.Checksum) == 0 {
+		t.Fatal("Expected the created item to carry a checksum")
+	}
+
+	// ciphertext swapped at rest, keeping the stored checksum
+	swapped, err := session.CreateItem(ctx, models.DataTypeText, ItemInput{Name: "Swapped", Fields: map[string]string{"content": "original"}})
+	if err != nil {
+		t.Fatalf("CreateItem() error = %v", err)
+	}
+	other, err := session.cryptoManager.Encrypt([]byte(`{"content":"forged"}`))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if _, err := session.cli.UpdateData(ctx, swapped.ID.String(), models.DataRequest{
+		Type: swapped.Type, Name: swapped.Name, Data: other, Checksum: swapped.Checksum, BaseRevision: baseRevision(swapped),
+	}); err != nil {
+		t.Fatalf("UpdateData() error = %v", err)
+	}
+
+	legacy, err := session.cryptoManager.Encrypt([]byte(`{"content":"old"}`))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if _, err := session.cli.CreateData(ctx, models.DataRequest{Type: models.DataTypeText, Name: "Legacy", Data: legacy}); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	if _, err := session.cli.CreateData(ctx, models.DataRequest{Type: models.DataTypeText, Name: "Damaged", Data: []byte("garbage")}); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+
+	results, err := session.Verify(ctx, "")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	want := map[string]string{"Intact": VerifyOK, "Swapped": VerifyMismatch, "Legacy": VerifyNoChecksum, "Damaged": VerifyDamaged}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %+v", len(want), results)
+	}
+	for _, result := range results {
+		if result.Status != want[result.Item] {
+			t.Errorf("Expected %s to be %q, got %q", result.Item, want[result.Item], result.Status)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writeVerifyResults(&out, results); err == nil || !strings.Contains(err.Error(), "2 items failed") {
+		t.Errorf("Expected two failed items, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Verified 4 items: 1 ok, 2 corrupted, 1 without a checksum") {
+		t.Errorf("Unexpected report %q", out.String())
+	}
+
+	single, err := session.Verify(ctx, intact.ID.String()[:8])
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if len(single) != 1 || single[0].Status != VerifyOK {
+		t.Errorf("Expected the intact item alone to verify, got %+v", single)
+	}
+}
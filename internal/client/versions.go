@@ -0,0 +1,161 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// GetDataVersions gets the previous versions of an item, newest first
+func (c *Client) GetDataVersions(ctx context.Context, id string) ([]models.DataVersion, error) {
+	var resp models.DataVersionsResponse
+	path := "/api/v1/data/" + url.PathEscape(id) + "/versions"
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Versions, nil
+}
+
+// RestoreDataVersion makes a previous version of an item the current one
+func (c *Client) RestoreDataVersion(ctx context.Context, id string, version int) (*models.Data, error) {
+	var resp models.DataResponse
+	path := "/api/v1/data/" + url.PathEscape(id) + "/restore/" + strconv.Itoa(version)
+	if err := c.doJSON(ctx, http.MethodPost, path, nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+// HistoryCommand lists the previous versions of an item by ID or unique ID
+// prefix with the fields changed after each one. Values are never shown.
+func (s *ClientSession) HistoryCommand(ctx context.Context, id string) error {
+	return s.writeHistory(ctx, os.Stdout, id)
+}
+
+// writeHistory renders the versions of an item to w, newest first
+func (s *ClientSession) writeHistory(ctx context.Context, w io.Writer, id string) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	if len(id) == 0 {
+		return fmt.Errorf("data ID is required")
+	}
+
+	data, err := s.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+	versions, err := s.cli.GetDataVersions(ctx, data.ID.String())
+	if err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+
+	fmt.Fprintf(w, "History of %q (current version from %s)\n", CleanQuotes(data.Name), formatHistoryTime(data.UpdatedAt))
+	if len(versions) == 0 {
+		fmt.Fprintln(w, "  No previous versions")
+		return nil
+	}
+
+	// each version is compared with the one that replaced it
+	newer := models.DataVersion{Type: data.Type, Name: data.Name, Description: data.Description,
+		Data: data.Data, Environment: data.Environment}
+	for _, version := range versions {
+		changed, err := s.changedFields(version, newer)
+		if err != nil {
+			return err
+		}
+		summary := "no changes"
+		if len(changed) > 0 {
+			summary = "then changed: " + strings.Join(changed, ", ")
+		}
+		fmt.Fprintf(w, "  v%-3d %s  %s\n", version.Version, formatHistoryTime(version.UpdatedAt), summary)
+		newer = version
+	}
+	fmt.Fprintf(w, "Use 'history %s restore <version>' to revert to a version\n", id)
+	return nil
+}
+
+// changedFields returns the names of the item and payload fields that differ
+// between two versions, sorted
+func (s *ClientSession) changedFields(older, newer models.DataVersion) ([]string, error) {
+	var changed []string
+	if older.Name != newer.Name {
+		changed = append(changed, "name")
+	}
+	if older.Description != newer.Description {
+		changed = append(changed, "description")
+	}
+	if older.Environment != newer.Environment {
+		changed = append(changed, "environment")
+	}
+
+	olderPayload, err := s.cryptoManager.Decrypt(older.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt history: %w", err)
+	}
+	newerPayload, err := s.cryptoManager.Decrypt(newer.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt history: %w", err)
+	}
+
+	var olderFields, newerFields map[string]interface{}
+	if json.Unmarshal(olderPayload, &olderFields) != nil || json.Unmarshal(newerPayload, &newerFields) != nil {
+		if !bytes.Equal(olderPayload, newerPayload) {
+			changed = append(changed, "content")
+		}
+		return changed, nil
+	}
+
+	var fields []string
+	for name, value := range olderFields {
+		if fmt.Sprint(value) != fmt.Sprint(newerFields[name]) {
+			fields = append(fields, name)
+		}
+	}
+	for name := range newerFields {
+		if _, ok := olderFields[name]; !ok {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return append(changed, fields...), nil
+}
+
+// formatHistoryTime formats a version time in local time
+func formatHistoryTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// RestoreVersionCommand reverts an item by ID or unique ID prefix to a
+// previous version. The version replaced is kept, so this can be undone.
+func (s *ClientSession) RestoreVersionCommand(ctx context.Context, id string, version int) error {
+	if !s.IsAuthenticated() {
+		return ErrNotAuthenticated
+	}
+	if len(id) == 0 {
+		return fmt.Errorf("data ID is required")
+	}
+
+	id, err := s.resolveID(ctx, id)
+	if err != nil {
+		return err
+	}
+	data, err := s.cli.RestoreDataVersion(ctx, id, version)
+	if err != nil {
+		return fmt.Errorf("failed to restore version %d: %w", version, err)
+	}
+
+	fmt.Printf("Restored version %d of %q\n", version, CleanQuotes(data.Name))
+	return nil
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestClientSession_History(t *testing.T) {
+	ctx := context.Background()
+	session, _, err := NewDemoSession(ctx)
+	if err != nil {
+		t.Fatalf("NewDemoSession() error = %v", err)
+	}
+	id := demoItemID(t, session, "Demo Email")
+
+	var out bytes.Buffer
+	if err := session.writeHistory(ctx, &out, id); err != nil {
+		t.Fatalf("writeHistory() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "No previous versions") {
+		t.Errorf("Expected no versions for an unedited item, got:\n%s", out.String())
+	}
+
+	data, err := session.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	fields, err := session.ItemFields(data)
+	if err != nil {
+		t.Fatalf("ItemFields() error = %v", err)
+	}
+	for i := range fields {
+		if fields[i].Name == "password" {
+			fields[i].Value = "oops-overwritten"
+		}
+	}
+	if _, err := session.SaveItem(ctx, data, data.Type, data.Name, data.Description, fields); err != nil {
+		t.Fatalf("SaveItem() error = %v", err)
+	}
+
+	out.Reset()
+	if err := session.writeHistory(ctx, &out, id); err != nil {
+		t.Fatalf("writeHistory() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "v1") || !strings.Contains(out.String(), "then changed: password") {
+		t.Errorf("Expected version 1 with the changed password field, got:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "oops-overwritten") {
+		t.Error("History must not show field values")
+	}
+
+	if err := session.RestoreVersionCommand(ctx, id, 1); err != nil {
+		t.Fatalf("RestoreVersionCommand() error = %v", err)
+	}
+	restored, err := session.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	fields, err = session.ItemFields(restored)
+	if err != nil {
+		t.Fatalf("ItemFields() error = %v", err)
+	}
+	for _, field := range fields {
+		if field.Name == "password" && field.Value == "oops-overwritten" {
+			t.Error("Expected the password of version 1 after restoring")
+		}
+	}
+
+	if err := session.RestoreVersionCommand(ctx, id, 5); err == nil {
+		t.Error("Expected restoring an unknown version to fail")
+	}
+}
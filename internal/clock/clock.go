@@ -0,0 +1,53 @@
+// Package clock provides the current time to code that stamps or expires
+// records, so tests can control it instead of sleeping.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the wall clock
+type System struct{}
+
+// Now returns the current time
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Manual is a clock that only moves when told to, for deterministic tests
+type Manual struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewManual creates a manual clock stopped at start
+func NewManual(start time.Time) *Manual {
+	return &Manual{now: start}
+}
+
+// Now returns the time the clock is stopped at
+func (m *Manual) Now() time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.now
+}
+
+// Advance moves the clock forward by d
+func (m *Manual) Advance(d time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.now = m.now.Add(d)
+}
+
+// Set stops the clock at t
+func (m *Manual) Set(t time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.now = t
+}
@@ -0,0 +1,36 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManual(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewManual(start)
+
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("Now() = %v, want %v", got, start)
+	}
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("Manual clock moved on its own: %v", got)
+	}
+
+	c.Advance(90 * time.Second)
+	if got := c.Now(); !got.Equal(start.Add(90 * time.Second)) {
+		t.Errorf("Now() after Advance() = %v", got)
+	}
+
+	c.Set(start)
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("Now() after Set() = %v", got)
+	}
+}
+
+func TestSystem(t *testing.T) {
+	before := time.Now()
+	got := System{}.Now()
+	if got.Before(before) || got.After(time.Now()) {
+		t.Errorf("System.Now() = %v, not the current time", got)
+	}
+}
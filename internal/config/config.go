@@ -2,9 +2,11 @@
 package config
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -18,6 +20,74 @@ type ServerConfig struct {
 	Host     string `env:"SERVER_HOST" envDefault:"localhost" json:"host,omitempty"`
 	Port     int    `env:"SERVER_PORT" envDefault:"8080" json:"port,omitempty"`
 	LogLevel string `env:"LOG_LEVEL" envDefault:"info" json:"log_level,omitempty"`
+	// GRPCPort serves the gRPC API on that port of Host, with the TLS settings
+	// of the REST API; 0 disables it
+	GRPCPort int `env:"GRPC_PORT" json:"grpc_port,omitempty"`
+	// IDFormat selects how new data IDs are generated: uuid or ulid (time-ordered)
+	IDFormat string `env:"ID_FORMAT" envDefault:"uuid" json:"id_format,omitempty"`
+	// RegistrationOpen allows anyone reaching the server to create an account
+	RegistrationOpen bool `env:"REGISTRATION_OPEN" envDefault:"true" json:"registration_open,omitempty"`
+	// MaxPayloadBytes limits request body size; 0 disables the limit
+	MaxPayloadBytes int64 `env:"MAX_PAYLOAD_BYTES" envDefault:"33554432" json:"max_payload_bytes,omitempty"`
+	// MaxAuthPayloadBytes limits the bodies of register, login and single
+	// sign-on requests, which only carry credentials; 0 applies MaxPayloadBytes
+	MaxAuthPayloadBytes int64 `env:"MAX_AUTH_PAYLOAD_BYTES" envDefault:"16384" json:"max_auth_payload_bytes,omitempty"`
+	// RoutePayloadLimits limits the bodies of named routes, e.g.
+	// data.create:1048576, overriding the limits above; 0 leaves a route unlimited
+	RoutePayloadLimits map[string]int64 `env:"ROUTE_PAYLOAD_LIMITS" json:"route_payload_limits,omitempty"`
+	// Compression compresses large responses for clients accepting gzip or
+	// deflate, and accepts request bodies compressed with them
+	Compression bool `env:"COMPRESSION" envDefault:"true" json:"compression,omitempty"`
+	// WebUI serves the web interface under /ui for users without the CLI
+	WebUI bool `env:"WEB_UI" envDefault:"true" json:"web_ui,omitempty"`
+	// AllowDegraded starts the server read-only instead of exiting when the storage self-test fails
+	AllowDegraded bool `env:"ALLOW_DEGRADED_START" json:"allow_degraded,omitempty"`
+	// ShutdownTimeout bounds how long in-flight requests are drained on SIGINT or SIGTERM
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s" json:"shutdown_timeout,omitempty"`
+	// ExpiryCheckInterval is how often items are flagged as expiring or
+	// expired; 0 disables the checks
+	ExpiryCheckInterval time.Duration `env:"EXPIRY_CHECK_INTERVAL" envDefault:"1h" json:"expiry_check_interval,omitempty"`
+	// ExpiryWarning is how long before their expiry items are flagged as expiring
+	ExpiryWarning time.Duration `env:"EXPIRY_WARNING" envDefault:"720h" json:"expiry_warning,omitempty"`
+	// TLS serves HTTPS when a certificate or autocert domains are configured
+	TLS TLSConfig `json:"tls,omitempty"`
+}
+
+// TLSConfig holds configuration for serving HTTPS, either with a certificate
+// and key from files or with certificates obtained from Let's Encrypt.
+type TLSConfig struct {
+	CertFile string `env:"TLS_CERT_FILE" json:"cert_file,omitempty"`
+	KeyFile  string `env:"TLS_KEY_FILE" json:"key_file,omitempty"`
+	// AutocertDomains are the host names certificates are requested for
+	AutocertDomains  []string `env:"TLS_AUTOCERT_DOMAINS" envSeparator:"," json:"autocert_domains,omitempty"`
+	AutocertCacheDir string   `env:"TLS_AUTOCERT_CACHE_DIR" envDefault:"autocert-cache" json:"autocert_cache_dir,omitempty"`
+	AutocertEmail    string   `env:"TLS_AUTOCERT_EMAIL" json:"autocert_email,omitempty"`
+	// RedirectAddr, such as :80, serves redirects to HTTPS (and ACME challenges with autocert)
+	RedirectAddr string `env:"TLS_REDIRECT_ADDR" json:"redirect_addr,omitempty"`
+}
+
+// Enabled reports whether the server should serve HTTPS.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != "" || len(t.AutocertDomains) > 0
+}
+
+// Autocert reports whether certificates are obtained from Let's Encrypt.
+func (t TLSConfig) Autocert() bool {
+	return len(t.AutocertDomains) > 0
+}
+
+// Validate checks that exactly one source of certificates is configured.
+func (t TLSConfig) Validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("TLS certificate and key files must be set together")
+	}
+	if t.CertFile != "" && t.Autocert() {
+		return fmt.Errorf("TLS certificate files and autocert domains are mutually exclusive")
+	}
+	if t.RedirectAddr != "" && !t.Enabled() {
+		return fmt.Errorf("HTTPS redirect requires TLS to be enabled")
+	}
+	return nil
 }
 
 // DatabaseConfig holds configuration for the database.
@@ -29,6 +99,19 @@ type DatabaseConfig struct {
 	User     string `env:"DB_USER" envDefault:"postgres" json:"user,omitempty"`
 	Password string `env:"DB_PASSWORD" envDefault:"password" json:"password,omitempty"`
 	SSLMode  string `env:"DB_SSLMODE" envDefault:"disable" json:"ssl_mode,omitempty"`
+	// AutoMigrate applies pending schema migrations on startup
+	AutoMigrate bool `env:"DB_AUTO_MIGRATE" envDefault:"true" json:"auto_migrate,omitempty"`
+	// Pool limits; MaxIdleConns is ignored with NativePool
+	MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" envDefault:"25" json:"max_open_conns,omitempty"`
+	MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" envDefault:"10" json:"max_idle_conns,omitempty"`
+	ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"30m" json:"conn_max_lifetime,omitempty"`
+	ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" envDefault:"5m" json:"conn_max_idle_time,omitempty"`
+	// StatementTimeout aborts statements running longer; 0 disables it
+	StatementTimeout time.Duration `env:"DB_STATEMENT_TIMEOUT" json:"statement_timeout,omitempty"`
+	// NativePool manages connections with pgxpool instead of database/sql
+	NativePool bool `env:"DB_NATIVE_POOL" json:"native_pool,omitempty"`
+	// ReplicaDSNs are PostgreSQL read replicas serving reads of users and items
+	ReplicaDSNs []string `env:"DB_REPLICA_DSNS" envSeparator:"," json:"replica_dsns,omitempty"`
 }
 
 // JWTConfig holds configuration for JWT authentication.
@@ -37,11 +120,315 @@ type JWTConfig struct {
 	TokenExpiry time.Duration `env:"JWT_TOKEN_EXPIRY" envDefault:"24h" json:"token_expiry,omitempty"`
 }
 
+// AdminConfig holds configuration for operator endpoints.
+type AdminConfig struct {
+	Token string `env:"ADMIN_TOKEN" json:"token,omitempty"`
+}
+
+// EscrowConfig holds configuration for organization key escrow.
+type EscrowConfig struct {
+	RecoveryPublicKey string `env:"ESCROW_RECOVERY_PUBLIC_KEY" json:"recovery_public_key,omitempty"`
+}
+
+// MaintenanceConfig holds configuration for maintenance mode.
+type MaintenanceConfig struct {
+	Enabled bool   `env:"MAINTENANCE_MODE" json:"enabled,omitempty"`
+	Message string `env:"MAINTENANCE_MESSAGE" json:"message,omitempty"`
+}
+
+// AuditConfig holds configuration for the per-user audit log.
+type AuditConfig struct {
+	// RecordIP adds the client address to audit events, sealed like item names
+	RecordIP bool `env:"AUDIT_RECORD_IP" json:"record_ip,omitempty"`
+}
+
+// LimitsConfig holds concurrency limits for expensive bulk routes and rate
+// limits for authentication. A zero BulkMaxInFlight disables the limit.
+type LimitsConfig struct {
+	BulkMaxInFlight  int           `env:"BULK_MAX_IN_FLIGHT" envDefault:"32" json:"bulk_max_in_flight,omitempty"`
+	BulkMaxQueue     int           `env:"BULK_MAX_QUEUE" envDefault:"64" json:"bulk_max_queue,omitempty"`
+	BulkQueueTimeout time.Duration `env:"BULK_QUEUE_TIMEOUT" envDefault:"5s" json:"bulk_queue_timeout,omitempty"`
+	// AuthRateLimit is the number of register and login attempts allowed per
+	// client address and per username within AuthRateWindow; 0 disables it
+	AuthRateLimit  int           `env:"AUTH_RATE_LIMIT" envDefault:"10" json:"auth_rate_limit,omitempty"`
+	AuthRateWindow time.Duration `env:"AUTH_RATE_WINDOW" envDefault:"1m" json:"auth_rate_window,omitempty"`
+}
+
+// PolicyConfig holds the rules for the credentials of new users. A zero limit
+// is not checked.
+type PolicyConfig struct {
+	UsernameMinLength int    `env:"USERNAME_MIN_LENGTH" envDefault:"3" json:"username_min_length,omitempty"`
+	UsernameMaxLength int    `env:"USERNAME_MAX_LENGTH" envDefault:"50" json:"username_max_length,omitempty"`
+	UsernameCharset   string `env:"USERNAME_CHARSET" envDefault:"A-Za-z0-9._@-" json:"username_charset,omitempty"`
+	PasswordMinLength int    `env:"PASSWORD_MIN_LENGTH" envDefault:"8" json:"password_min_length,omitempty"`
+	PasswordMaxLength int    `env:"PASSWORD_MAX_LENGTH" envDefault:"128" json:"password_max_length,omitempty"`
+	// PasswordMinClasses is how many of lowercase, uppercase, digits and other
+	// characters passwords must mix
+	PasswordMinClasses int `env:"PASSWORD_MIN_CLASSES" envDefault:"1" json:"password_min_classes,omitempty"`
+}
+
+// AtRestConfig holds configuration for encrypting item data on the server
+// before it is stored. Data keys are wrapped either by a local master key or
+// by a transit key of a HashiCorp Vault or OpenBao KMS.
+type AtRestConfig struct {
+	// Key is a base64 32-byte master key
+	Key        string `env:"AT_REST_KEY" json:"key,omitempty"`
+	VaultAddr  string `env:"AT_REST_VAULT_ADDR" json:"vault_addr,omitempty"`
+	VaultToken string `env:"AT_REST_VAULT_TOKEN" json:"vault_token,omitempty"`
+	VaultKey   string `env:"AT_REST_VAULT_KEY" json:"vault_key,omitempty"`
+}
+
+// Enabled reports whether item data is encrypted at rest.
+func (a AtRestConfig) Enabled() bool {
+	return a.Key != "" || a.VaultAddr != ""
+}
+
+// Validate checks that exactly one complete source of the master key is configured.
+func (a AtRestConfig) Validate() error {
+	if a.Key != "" && a.VaultAddr != "" {
+		return fmt.Errorf("at-rest master key and KMS are mutually exclusive")
+	}
+	if a.Key != "" {
+		key, err := base64.StdEncoding.DecodeString(a.Key)
+		if err != nil {
+			return fmt.Errorf("invalid at-rest master key: %w", err)
+		}
+		if len(key) != 32 {
+			return fmt.Errorf("at-rest master key must be 32 bytes, got %d", len(key))
+		}
+	}
+	if a.VaultAddr != "" && (a.VaultToken == "" || a.VaultKey == "") {
+		return fmt.Errorf("at-rest KMS requires a token and a key name")
+	}
+	return nil
+}
+
+// OIDCConfig holds configuration for single sign-on with an OpenID Connect
+// identity provider. Users still unlock their vault with a master password.
+type OIDCConfig struct {
+	Issuer       string `env:"OIDC_ISSUER" json:"issuer,omitempty"`
+	ClientID     string `env:"OIDC_CLIENT_ID" json:"client_id,omitempty"`
+	ClientSecret string `env:"OIDC_CLIENT_SECRET" json:"client_secret,omitempty"`
+	// RedirectURL is the public URL of the server's /api/v1/oidc/callback
+	RedirectURL string `env:"OIDC_REDIRECT_URL" json:"redirect_url,omitempty"`
+	// Scopes are requested besides openid
+	Scopes []string `env:"OIDC_SCOPES" envDefault:"profile,email" json:"scopes,omitempty"`
+	// UsernameClaim names new accounts: preferred_username, email or sub
+	UsernameClaim string `env:"OIDC_USERNAME_CLAIM" envDefault:"preferred_username" json:"username_claim,omitempty"`
+	// AutoCreate creates accounts for identities not linked to one yet
+	AutoCreate bool `env:"OIDC_AUTO_CREATE" envDefault:"true" json:"auto_create,omitempty"`
+	// Only rejects password logins and registrations
+	Only bool `env:"OIDC_ONLY" json:"only,omitempty"`
+}
+
+// Enabled reports whether single sign-on is configured.
+func (o OIDCConfig) Enabled() bool {
+	return o.Issuer != ""
+}
+
+// Validate checks that an enabled provider is fully configured.
+func (o OIDCConfig) Validate() error {
+	if !o.Enabled() {
+		if o.Only {
+			return fmt.Errorf("OIDC-only login requires an OIDC issuer")
+		}
+		return nil
+	}
+	if o.ClientID == "" || o.RedirectURL == "" {
+		return fmt.Errorf("OIDC requires a client ID and a redirect URL")
+	}
+	if !strings.HasSuffix(o.RedirectURL, "/api/v1/oidc/callback") {
+		return fmt.Errorf("OIDC redirect URL must point to /api/v1/oidc/callback, got %q", o.RedirectURL)
+	}
+	switch o.UsernameClaim {
+	case "preferred_username", "email", "sub":
+	default:
+		return fmt.Errorf("unsupported OIDC username claim %q (use preferred_username, email or sub)", o.UsernameClaim)
+	}
+	return nil
+}
+
+// CORSConfig lets browser clients served from other origins, such as a web UI
+// or a browser extension, call the API. Browsers block cross-origin requests
+// unless the origin is listed.
+type CORSConfig struct {
+	// AllowedOrigins are origins such as https://vault.example.com, or * for any
+	AllowedOrigins []string `env:"CORS_ALLOWED_ORIGINS" envSeparator:"," json:"allowed_origins,omitempty"`
+	AllowedMethods []string `env:"CORS_ALLOWED_METHODS" envSeparator:"," envDefault:"GET,POST,PUT,PATCH,DELETE" json:"allowed_methods,omitempty"`
+	// AllowedHeaders are the request headers browsers may send, or * for any
+	AllowedHeaders []string `env:"CORS_ALLOWED_HEADERS" envSeparator:"," envDefault:"Authorization,Content-Type,If-Match,X-Request-ID,X-Device-Name,X-Vault-Lock" json:"allowed_headers,omitempty"`
+	// ExposedHeaders are the response headers scripts may read
+	ExposedHeaders []string `env:"CORS_EXPOSED_HEADERS" envSeparator:"," envDefault:"ETag,Retry-After,X-Request-ID,X-Chunk-Count" json:"exposed_headers,omitempty"`
+	// AllowCredentials lets browsers send cookies; bearer tokens do not need it
+	AllowCredentials bool          `env:"CORS_ALLOW_CREDENTIALS" json:"allow_credentials,omitempty"`
+	MaxAge           time.Duration `env:"CORS_MAX_AGE" envDefault:"10m" json:"max_age,omitempty"`
+}
+
+// Enabled reports whether any cross-origin requests are allowed.
+func (c CORSConfig) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// Validate checks the allowed origins. Browsers ignore credentials for any
+// origin, so * cannot be combined with them.
+func (c CORSConfig) Validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return fmt.Errorf("CORS credentials cannot be allowed for any origin, list the origins instead")
+			}
+			continue
+		}
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || strings.TrimSuffix(parsed.Path, "/") != "" ||
+			parsed.RawQuery != "" || parsed.Fragment != "" {
+			return fmt.Errorf("CORS origin must be a scheme and host such as https://vault.example.com, got %q", origin)
+		}
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("CORS max age cannot be negative")
+	}
+	return nil
+}
+
+// BackupConfig holds configuration for scheduled backups of the database to
+// a directory or an S3-compatible bucket.
+type BackupConfig struct {
+	// Interval is how often a backup is taken; 0 disables scheduled backups
+	Interval time.Duration `env:"BACKUP_INTERVAL" json:"interval,omitempty"`
+	Dir      string        `env:"BACKUP_DIR" json:"dir,omitempty"`
+	// Retention is how many of the newest backups are kept; 0 keeps all
+	Retention int `env:"BACKUP_RETENTION" envDefault:"7" json:"retention,omitempty"`
+	// Key is a base64 32-byte key encrypting backups; they are only
+	// compressed without one
+	Key         string `env:"BACKUP_KEY" json:"key,omitempty"`
+	S3Endpoint  string `env:"BACKUP_S3_ENDPOINT" json:"s3_endpoint,omitempty"`
+	S3Region    string `env:"BACKUP_S3_REGION" envDefault:"us-east-1" json:"s3_region,omitempty"`
+	S3Bucket    string `env:"BACKUP_S3_BUCKET" json:"s3_bucket,omitempty"`
+	S3AccessKey string `env:"BACKUP_S3_ACCESS_KEY" json:"s3_access_key,omitempty"`
+	S3SecretKey string `env:"BACKUP_S3_SECRET_KEY" json:"s3_secret_key,omitempty"`
+	// S3Prefix is prepended to the names of backups, e.g. gophkeeper/
+	S3Prefix string `env:"BACKUP_S3_PREFIX" json:"s3_prefix,omitempty"`
+	// S3PathStyle puts the bucket in the path, as MinIO expects
+	S3PathStyle bool `env:"BACKUP_S3_PATH_STYLE" json:"s3_path_style,omitempty"`
+}
+
+// Configured reports whether there is a place to store backups.
+func (b BackupConfig) Configured() bool {
+	return b.Dir != "" || b.S3Bucket != ""
+}
+
+// Validate checks that backups go to exactly one place and that the key is
+// usable.
+func (b BackupConfig) Validate() error {
+	if b.Dir != "" && b.S3Bucket != "" {
+		return fmt.Errorf("backup directory and S3 bucket are mutually exclusive")
+	}
+	if b.Interval < 0 || b.Retention < 0 {
+		return fmt.Errorf("backup interval and retention cannot be negative")
+	}
+	if b.Interval > 0 && !b.Configured() {
+		return fmt.Errorf("scheduled backups require a backup directory or S3 bucket")
+	}
+	if b.S3Bucket != "" && (b.S3Endpoint == "" || b.S3AccessKey == "" || b.S3SecretKey == "") {
+		return fmt.Errorf("backups to S3 require an endpoint, an access key and a secret key")
+	}
+	if b.Key != "" {
+		key, err := base64.StdEncoding.DecodeString(b.Key)
+		if err != nil {
+			return fmt.Errorf("invalid backup key: %w", err)
+		}
+		if len(key) != 32 {
+			return fmt.Errorf("backup key must be 32 bytes, got %d", len(key))
+		}
+	}
+	return nil
+}
+
+// BlobConfig holds configuration for storing the content of binary items in
+// an S3-compatible bucket, such as MinIO, instead of the database
+type BlobConfig struct {
+	S3Endpoint string `env:"BLOB_S3_ENDPOINT" json:"s3_endpoint,omitempty"`
+	// S3PublicEndpoint is the endpoint clients reach the bucket at, for
+	// presigned URLs, if it differs from the one the server uses
+	S3PublicEndpoint string `env:"BLOB_S3_PUBLIC_ENDPOINT" json:"s3_public_endpoint,omitempty"`
+	S3Region         string `env:"BLOB_S3_REGION" envDefault:"us-east-1" json:"s3_region,omitempty"`
+	S3Bucket         string `env:"BLOB_S3_BUCKET" json:"s3_bucket,omitempty"`
+	S3AccessKey      string `env:"BLOB_S3_ACCESS_KEY" json:"s3_access_key,omitempty"`
+	S3SecretKey      string `env:"BLOB_S3_SECRET_KEY" json:"s3_secret_key,omitempty"`
+	// S3PathStyle puts the bucket in the path, as MinIO expects
+	S3PathStyle bool `env:"BLOB_S3_PATH_STYLE" json:"s3_path_style,omitempty"`
+	// PresignExpiry is how long presigned upload and download URLs are valid
+	PresignExpiry time.Duration `env:"BLOB_PRESIGN_EXPIRY" envDefault:"15m" json:"presign_expiry,omitempty"`
+	// SweepInterval is how often objects of deleted chunks are deleted
+	SweepInterval time.Duration `env:"BLOB_SWEEP_INTERVAL" envDefault:"10m" json:"sweep_interval,omitempty"`
+}
+
+// Enabled reports whether binary content is stored in a bucket.
+func (b BlobConfig) Enabled() bool {
+	return b.S3Bucket != ""
+}
+
+// Validate checks that an enabled bucket can be reached and presigned for.
+func (b BlobConfig) Validate() error {
+	if !b.Enabled() {
+		return nil
+	}
+	if b.S3Endpoint == "" || b.S3AccessKey == "" || b.S3SecretKey == "" {
+		return fmt.Errorf("blob storage requires an endpoint, an access key and a secret key")
+	}
+	if b.PresignExpiry <= 0 || b.PresignExpiry > 7*24*time.Hour {
+		return fmt.Errorf("presigned URL expiry must be positive and at most 7 days, got %s", b.PresignExpiry)
+	}
+	if b.SweepInterval <= 0 {
+		return fmt.Errorf("blob sweep interval must be positive")
+	}
+	return nil
+}
+
+// CacheConfig holds configuration for the in-memory cache of items and users
+// in front of the database.
+type CacheConfig struct {
+	// Size is how many items and how many users are cached; 0 disables the cache
+	Size int `env:"CACHE_SIZE" json:"size,omitempty"`
+	// TTL is how long a cached item or user is served, which bounds how stale
+	// it can be when other servers write to the same database
+	TTL time.Duration `env:"CACHE_TTL" envDefault:"30s" json:"ttl,omitempty"`
+}
+
+// Enabled reports whether items and users are cached.
+func (c CacheConfig) Enabled() bool {
+	return c.Size > 0
+}
+
+// Validate checks that an enabled cache forgets what it holds.
+func (c CacheConfig) Validate() error {
+	if c.Size < 0 {
+		return fmt.Errorf("cache size cannot be negative")
+	}
+	if c.Enabled() && c.TTL <= 0 {
+		return fmt.Errorf("cache TTL must be positive, got %s", c.TTL)
+	}
+	return nil
+}
+
 // Config represents application configuration.
 type Config struct {
-	Server   ServerConfig   `json:"server,omitempty"`
-	Database DatabaseConfig `json:"database,omitempty"`
-	JWT      JWTConfig      `json:"jwt,omitempty"`
+	Server      ServerConfig      `json:"server,omitempty"`
+	Database    DatabaseConfig    `json:"database,omitempty"`
+	JWT         JWTConfig         `json:"jwt,omitempty"`
+	Admin       AdminConfig       `json:"admin,omitempty"`
+	Escrow      EscrowConfig      `json:"escrow,omitempty"`
+	Maintenance MaintenanceConfig `json:"maintenance,omitempty"`
+	Limits      LimitsConfig      `json:"limits,omitempty"`
+	Audit       AuditConfig       `json:"audit,omitempty"`
+	Policy      PolicyConfig      `json:"policy,omitempty"`
+	AtRest      AtRestConfig      `json:"at_rest,omitempty"`
+	OIDC        OIDCConfig        `json:"oidc,omitempty"`
+	CORS        CORSConfig        `json:"cors,omitempty"`
+	Backup      BackupConfig      `json:"backup,omitempty"`
+	Blob        BlobConfig        `json:"blob,omitempty"`
+	Cache       CacheConfig       `json:"cache,omitempty"`
 }
 
 // NetAddress represents a network address with host and port.
@@ -148,6 +535,18 @@ func (cfg *Config) ParseFlags() {
 		jwtSecret  string
 		jwtExpiry  time.Duration
 		logLevel   string
+		adminToken string
+		escrowKey  string
+		maintain   bool
+		degraded   bool
+		idFormat   string
+		auditIP    bool
+		tlsCert    string
+		tlsKey     string
+		tlsDomains string
+		tlsRedir   string
+		shutdown   time.Duration
+		grpcPort   int
 	)
 
 	fs.Var(addr, "a", "Net address host:port")
@@ -161,6 +560,18 @@ func (cfg *Config) ParseFlags() {
 	fs.StringVar(&jwtSecret, "jwt-secret", "", "JWT secret key")
 	fs.DurationVar(&jwtExpiry, "jwt-expiry", 0, "JWT token expiry")
 	fs.StringVar(&logLevel, "log-level", "", "Log level (debug, info, warn, error)")
+	fs.StringVar(&adminToken, "admin-token", "", "Token for admin endpoints")
+	fs.StringVar(&escrowKey, "escrow-recovery-key", "", "Base64 X25519 organization recovery public key")
+	fs.BoolVar(&maintain, "maintenance", false, "Start in maintenance mode (reads only)")
+	fs.StringVar(&idFormat, "id-format", "", "Data ID format (uuid, ulid)")
+	fs.BoolVar(&auditIP, "audit-record-ip", false, "Record client addresses in the audit log, sealed to each user")
+	fs.StringVar(&tlsCert, "tls-cert", "", "TLS certificate file")
+	fs.StringVar(&tlsKey, "tls-key", "", "TLS private key file")
+	fs.StringVar(&tlsDomains, "tls-autocert-domains", "", "Comma-separated domains to obtain Let's Encrypt certificates for")
+	fs.StringVar(&tlsRedir, "tls-redirect-addr", "", "Address serving redirects to HTTPS, e.g. :80")
+	fs.DurationVar(&shutdown, "shutdown-timeout", 0, "Time to drain in-flight requests on shutdown")
+	fs.IntVar(&grpcPort, "grpc-port", 0, "Port of the gRPC API (0 keeps it off)")
+	fs.BoolVar(&degraded, "allow-degraded", false, "Start read-only instead of exiting if the storage self-test fails")
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return
@@ -210,6 +621,54 @@ func (cfg *Config) ParseFlags() {
 	if logLevel != "" {
 		cfg.Server.LogLevel = logLevel
 	}
+
+	if adminToken != "" {
+		cfg.Admin.Token = adminToken
+	}
+
+	if escrowKey != "" {
+		cfg.Escrow.RecoveryPublicKey = escrowKey
+	}
+
+	if maintain {
+		cfg.Maintenance.Enabled = true
+	}
+
+	if idFormat != "" {
+		cfg.Server.IDFormat = idFormat
+	}
+
+	if degraded {
+		cfg.Server.AllowDegraded = true
+	}
+
+	if auditIP {
+		cfg.Audit.RecordIP = true
+	}
+
+	if shutdown > 0 {
+		cfg.Server.ShutdownTimeout = shutdown
+	}
+
+	if grpcPort > 0 {
+		cfg.Server.GRPCPort = grpcPort
+	}
+
+	if tlsCert != "" {
+		cfg.Server.TLS.CertFile = tlsCert
+	}
+
+	if tlsKey != "" {
+		cfg.Server.TLS.KeyFile = tlsKey
+	}
+
+	if tlsDomains != "" {
+		cfg.Server.TLS.AutocertDomains = strings.Split(tlsDomains, ",")
+	}
+
+	if tlsRedir != "" {
+		cfg.Server.TLS.RedirectAddr = tlsRedir
+	}
 }
 
 // GetDSN returns database connection string.
@@ -232,28 +691,85 @@ func (cfg *Config) GetServerAddr() string {
 	return fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 }
 
+// GetGRPCAddr returns the address of the gRPC API.
+func (cfg *Config) GetGRPCAddr() string {
+	return fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.GRPCPort)
+}
+
 // Load is a compatibility function for backward compatibility.
 func Load() *Config {
 	cfg := &Config{}
 	if err := env.Parse(cfg); err != nil {
 		return &Config{
 			Server: ServerConfig{
-				Host: "localhost",
-				Port: 8080,
+				Host:                "localhost",
+				Port:                8080,
+				IDFormat:            "uuid",
+				RegistrationOpen:    true,
+				MaxPayloadBytes:     32 << 20,
+				MaxAuthPayloadBytes: 16 << 10,
+				Compression:         true,
+				WebUI:               true,
+				ShutdownTimeout:     30 * time.Second,
+				ExpiryCheckInterval: time.Hour,
+				ExpiryWarning:       30 * 24 * time.Hour,
 			},
 			Database: DatabaseConfig{
-				Type:     "postgres",
-				Host:     "localhost",
-				Port:     5432,
-				Name:     "gophkeeper",
-				User:     "postgres",
-				Password: "password",
-				SSLMode:  "disable",
+				Type:            "postgres",
+				Host:            "localhost",
+				Port:            5432,
+				Name:            "gophkeeper",
+				User:            "postgres",
+				Password:        "password",
+				SSLMode:         "disable",
+				AutoMigrate:     true,
+				MaxOpenConns:    25,
+				MaxIdleConns:    10,
+				ConnMaxLifetime: 30 * time.Minute,
+				ConnMaxIdleTime: 5 * time.Minute,
 			},
 			JWT: JWTConfig{
 				Secret:      "your-secret-key",
 				TokenExpiry: 24 * time.Hour,
 			},
+			Limits: LimitsConfig{
+				BulkMaxInFlight:  32,
+				BulkMaxQueue:     64,
+				BulkQueueTimeout: 5 * time.Second,
+				AuthRateLimit:    10,
+				AuthRateWindow:   time.Minute,
+			},
+			Policy: PolicyConfig{
+				UsernameMinLength:  3,
+				UsernameMaxLength:  50,
+				UsernameCharset:    "A-Za-z0-9._@-",
+				PasswordMinLength:  8,
+				PasswordMaxLength:  128,
+				PasswordMinClasses: 1,
+			},
+			OIDC: OIDCConfig{
+				Scopes:        []string{"profile", "email"},
+				UsernameClaim: "preferred_username",
+				AutoCreate:    true,
+			},
+			CORS: CORSConfig{
+				AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+				AllowedHeaders: []string{"Authorization", "Content-Type", "If-Match", "X-Request-ID", "X-Device-Name", "X-Vault-Lock"},
+				ExposedHeaders: []string{"ETag", "Retry-After", "X-Request-ID", "X-Chunk-Count"},
+				MaxAge:         10 * time.Minute,
+			},
+			Backup: BackupConfig{
+				Retention: 7,
+				S3Region:  "us-east-1",
+			},
+			Blob: BlobConfig{
+				S3Region:      "us-east-1",
+				PresignExpiry: 15 * time.Minute,
+				SweepInterval: 10 * time.Minute,
+			},
+			Cache: CacheConfig{
+				TTL: 30 * time.Second,
+			},
 		}
 	}
 
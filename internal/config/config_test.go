@@ -92,6 +92,24 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoad_PayloadLimits(t *testing.T) {
+	t.Setenv("ROUTE_PAYLOAD_LIMITS", "data.create:1048576,data.batch:0")
+
+	config := Load()
+	if config.Server.MaxAuthPayloadBytes != 16<<10 {
+		t.Errorf("Expected the default auth payload limit, got %d", config.Server.MaxAuthPayloadBytes)
+	}
+	want := map[string]int64{"data.create": 1 << 20, "data.batch": 0}
+	if len(config.Server.RoutePayloadLimits) != len(want) {
+		t.Fatalf("Expected route limits %v, got %v", want, config.Server.RoutePayloadLimits)
+	}
+	for route, limit := range want {
+		if got, ok := config.Server.RoutePayloadLimits[route]; !ok || got != limit {
+			t.Errorf("Expected limit %d for %s, got %d", limit, route, got)
+		}
+	}
+}
+
 func TestConfig_GetDSN(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -187,6 +205,19 @@ func TestConfig_GetServerAddr(t *testing.T) {
 	}
 }
 
+func TestConfig_GetGRPCAddr(t *testing.T) {
+	config := Config{
+		Server: ServerConfig{
+			Host:     "0.0.0.0",
+			Port:     8080,
+			GRPCPort: 9090,
+		},
+	}
+	if addr := config.GetGRPCAddr(); addr != "0.0.0.0:9090" {
+		t.Errorf("Expected gRPC address 0.0.0.0:9090, got %s", addr)
+	}
+}
+
 func TestNetAddress_String(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -580,3 +611,220 @@ func TestConfig_ParseFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestTLSConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tls     TLSConfig
+		enabled bool
+		wantErr bool
+	}{
+		{name: "disabled", tls: TLSConfig{}},
+		{name: "certificate files", tls: TLSConfig{CertFile: "server.crt", KeyFile: "server.key"}, enabled: true},
+		{name: "autocert", tls: TLSConfig{AutocertDomains: []string{"vault.example.com"}, RedirectAddr: ":80"}, enabled: true},
+		{name: "certificate without key", tls: TLSConfig{CertFile: "server.crt"}, enabled: true, wantErr: true},
+		{name: "files and autocert", tls: TLSConfig{CertFile: "server.crt", KeyFile: "server.key", AutocertDomains: []string{"vault.example.com"}}, enabled: true, wantErr: true},
+		{name: "redirect without TLS", tls: TLSConfig{RedirectAddr: ":80"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tls.Enabled(); got != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", got, tt.enabled)
+			}
+			if err := tt.tls.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAtRestConfig_Validate(t *testing.T) {
+	key := "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="
+	tests := []struct {
+		name    string
+		atRest  AtRestConfig
+		enabled bool
+		wantErr bool
+	}{
+		{name: "disabled", atRest: AtRestConfig{}},
+		{name: "local key", atRest: AtRestConfig{Key: key}, enabled: true},
+		{name: "vault", atRest: AtRestConfig{VaultAddr: "https://vault:8200", VaultToken: "t", VaultKey: "gophkeeper"}, enabled: true},
+		{name: "invalid base64", atRest: AtRestConfig{Key: "%%%"}, enabled: true, wantErr: true},
+		{name: "short key", atRest: AtRestConfig{Key: "c2hvcnQ="}, enabled: true, wantErr: true},
+		{name: "vault without token", atRest: AtRestConfig{VaultAddr: "https://vault:8200", VaultKey: "gophkeeper"}, enabled: true, wantErr: true},
+		{name: "key and vault", atRest: AtRestConfig{Key: key, VaultAddr: "https://vault:8200", VaultToken: "t", VaultKey: "k"}, enabled: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.atRest.Enabled(); got != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", got, tt.enabled)
+			}
+			if err := tt.atRest.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCConfig_Validate(t *testing.T) {
+	valid := OIDCConfig{Issuer: "https://idp.example.com", ClientID: "gophkeeper",
+		RedirectURL: "https://vault.example.com/api/v1/oidc/callback", UsernameClaim: "preferred_username"}
+	tests := []struct {
+		name    string
+		change  func(o *OIDCConfig)
+		enabled bool
+		wantErr bool
+	}{
+		{name: "disabled", change: func(o *OIDCConfig) { *o = OIDCConfig{} }},
+		{name: "only without issuer", change: func(o *OIDCConfig) { *o = OIDCConfig{Only: true} }, wantErr: true},
+		{name: "valid", change: func(o *OIDCConfig) {}, enabled: true},
+		{name: "email claim", change: func(o *OIDCConfig) { o.UsernameClaim = "email" }, enabled: true},
+		{name: "no client ID", change: func(o *OIDCConfig) { o.ClientID = "" }, enabled: true, wantErr: true},
+		{name: "other redirect", change: func(o *OIDCConfig) { o.RedirectURL = "https://vault.example.com/" }, enabled: true, wantErr: true},
+		{name: "unknown claim", change: func(o *OIDCConfig) { o.UsernameClaim = "name" }, enabled: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oidc := valid
+			tt.change(&oidc)
+			if got := oidc.Enabled(); got != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", got, tt.enabled)
+			}
+			if err := oidc.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCORSConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cors    CORSConfig
+		enabled bool
+		wantErr bool
+	}{
+		{name: "disabled", cors: CORSConfig{}},
+		{name: "origins", cors: CORSConfig{AllowedOrigins: []string{"https://vault.example.com", "http://localhost:3000/"}}, enabled: true},
+		{name: "any origin", cors: CORSConfig{AllowedOrigins: []string{"*"}}, enabled: true},
+		{name: "any origin with credentials", cors: CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, enabled: true, wantErr: true},
+		{name: "no scheme", cors: CORSConfig{AllowedOrigins: []string{"vault.example.com"}}, enabled: true, wantErr: true},
+		{name: "path", cors: CORSConfig{AllowedOrigins: []string{"https://vault.example.com/app"}}, enabled: true, wantErr: true},
+		{name: "negative max age", cors: CORSConfig{AllowedOrigins: []string{"*"}, MaxAge: -time.Second}, enabled: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cors.Enabled(); got != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", got, tt.enabled)
+			}
+			if err := tt.cors.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupConfig_Validate(t *testing.T) {
+	s3 := BackupConfig{S3Endpoint: "http://minio:9000", S3Bucket: "backups", S3AccessKey: "a", S3SecretKey: "s"}
+	tests := []struct {
+		name       string
+		backup     BackupConfig
+		configured bool
+		wantErr    bool
+	}{
+		{name: "disabled", backup: BackupConfig{}},
+		{name: "directory", backup: BackupConfig{Dir: "/backups", Interval: time.Hour}, configured: true},
+		{name: "s3", backup: s3, configured: true},
+		{name: "encrypted", backup: BackupConfig{Dir: "/backups", Key: "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="}, configured: true},
+		{name: "schedule without target", backup: BackupConfig{Interval: time.Hour}, wantErr: true},
+		{name: "directory and s3", backup: BackupConfig{Dir: "/backups", S3Bucket: "backups"}, configured: true, wantErr: true},
+		{name: "s3 without credentials", backup: BackupConfig{S3Endpoint: "http://minio:9000", S3Bucket: "backups"}, configured: true, wantErr: true},
+		{name: "short key", backup: BackupConfig{Dir: "/backups", Key: "c2hvcnQ="}, configured: true, wantErr: true},
+		{name: "negative retention", backup: BackupConfig{Dir: "/backups", Retention: -1}, configured: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.backup.Configured(); got != tt.configured {
+				t.Errorf("Configured() = %v, want %v", got, tt.configured)
+			}
+			if err := tt.backup.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBlobConfig_Validate(t *testing.T) {
+	bucket := BlobConfig{S3Endpoint: "http://minio:9000", S3Bucket: "blobs", S3AccessKey: "a", S3SecretKey: "s",
+		PresignExpiry: 15 * time.Minute, SweepInterval: 10 * time.Minute}
+	tests := []struct {
+		name    string
+		blob    func(b BlobConfig) BlobConfig
+		enabled bool
+		wantErr bool
+	}{
+		{name: "disabled", blob: func(BlobConfig) BlobConfig { return BlobConfig{} }},
+		{name: "bucket", blob: func(b BlobConfig) BlobConfig { return b }, enabled: true},
+		{name: "public endpoint", blob: func(b BlobConfig) BlobConfig { b.S3PublicEndpoint = "https://blobs.example.com"; return b }, enabled: true},
+		{name: "without credentials", blob: func(b BlobConfig) BlobConfig { b.S3SecretKey = ""; return b }, enabled: true, wantErr: true},
+		{name: "without endpoint", blob: func(b BlobConfig) BlobConfig { b.S3Endpoint = ""; return b }, enabled: true, wantErr: true},
+		{name: "no expiry", blob: func(b BlobConfig) BlobConfig { b.PresignExpiry = 0; return b }, enabled: true, wantErr: true},
+		{name: "expiry over a week", blob: func(b BlobConfig) BlobConfig { b.PresignExpiry = 8 * 24 * time.Hour; return b }, enabled: true, wantErr: true},
+		{name: "no sweeps", blob: func(b BlobConfig) BlobConfig { b.SweepInterval = 0; return b }, enabled: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob := tt.blob(bucket)
+			if got := blob.Enabled(); got != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", got, tt.enabled)
+			}
+			if err := blob.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCacheConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cache   CacheConfig
+		enabled bool
+		wantErr bool
+	}{
+		{name: "disabled", cache: CacheConfig{TTL: 30 * time.Second}},
+		{name: "enabled", cache: CacheConfig{Size: 10000, TTL: 30 * time.Second}, enabled: true},
+		{name: "negative size", cache: CacheConfig{Size: -1, TTL: 30 * time.Second}, wantErr: true},
+		{name: "no TTL", cache: CacheConfig{Size: 10000}, enabled: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cache.Enabled(); got != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", got, tt.enabled)
+			}
+			if err := tt.cache.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_ParseFlags_TLS(t *testing.T) {
+	originalArgs := os.Args
+	defer func() {
+		os.Args = originalArgs
+	}()
+	os.Args = []string{"config", "-tls-autocert-domains", "vault.example.com,www.vault.example.com", "-tls-redirect-addr", ":80"}
+
+	config := &Config{}
+	config.ParseFlags()
+
+	if got := config.Server.TLS.AutocertDomains; len(got) != 2 || got[1] != "www.vault.example.com" {
+		t.Errorf("ParseFlags() TLS.AutocertDomains = %v", got)
+	}
+	if config.Server.TLS.RedirectAddr != ":80" {
+		t.Errorf("ParseFlags() TLS.RedirectAddr = %v, want :80", config.Server.TLS.RedirectAddr)
+	}
+}
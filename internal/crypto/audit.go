@@ -0,0 +1,39 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+// auditLabel separates sealed audit details from other sealed data
+const auditLabel = "gophkeeper-audit"
+
+// AuditKeyPair derives the X25519 key pair audit details are sealed to from the
+// vault key, so every device holding the vault key can read the audit log
+// without storing another secret
+func AuditKeyPair(vaultKey []byte) (publicKey, privateKey []byte, err error) {
+	mac := hmac.New(sha256.New, vaultKey)
+	mac.Write([]byte(auditLabel))
+
+	key, err := ecdh.X25519().NewPrivateKey(mac.Sum(nil))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive audit key: %w", err)
+	}
+	return key.PublicKey().Bytes(), key.Bytes(), nil
+}
+
+// SealAuditDetails encrypts audit event details to the user's audit public key
+func SealAuditDetails(auditPublicKey, details []byte) ([]byte, error) {
+	sealed, err := sealTo(auditLabel, auditPublicKey, details)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit public key: %w", err)
+	}
+	return sealed, nil
+}
+
+// OpenAuditDetails decrypts audit event details with the user's audit private key
+func OpenAuditDetails(auditPrivateKey, sealed []byte) ([]byte, error) {
+	return openWith(auditLabel, auditPrivateKey, sealed)
+}
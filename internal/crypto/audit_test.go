@@ -0,0 +1,44 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAuditKeyPair(t *testing.T) {
+	vaultKey, _ := GenerateDataKey()
+	publicKey, privateKey, err := AuditKeyPair(vaultKey)
+	if err != nil {
+		t.Fatalf("AuditKeyPair() error = %v", err)
+	}
+
+	again, _, err := AuditKeyPair(vaultKey)
+	if err != nil || !bytes.Equal(again, publicKey) {
+		t.Errorf("Expected the same key pair from the same vault key, got %v", err)
+	}
+	otherKey, _ := GenerateDataKey()
+	other, otherPrivateKey, _ := AuditKeyPair(otherKey)
+	if bytes.Equal(other, publicKey) {
+		t.Error("Expected another key pair from another vault key")
+	}
+
+	details := []byte(`{"item_name":"Bank login"}`)
+	sealed, err := SealAuditDetails(publicKey, details)
+	if err != nil {
+		t.Fatalf("SealAuditDetails() error = %v", err)
+	}
+	if bytes.Contains(sealed, []byte("Bank login")) {
+		t.Error("Sealed details must not contain the plaintext")
+	}
+
+	opened, err := OpenAuditDetails(privateKey, sealed)
+	if err != nil || !bytes.Equal(opened, details) {
+		t.Errorf("OpenAuditDetails() = %q, %v", opened, err)
+	}
+	if _, err := OpenAuditDetails(otherPrivateKey, sealed); err == nil {
+		t.Error("Expected error when opening with another vault's audit key")
+	}
+	if _, err := UnwrapKey(privateKey, sealed); err == nil {
+		t.Error("Sealed audit details must not open as an escrowed key")
+	}
+}
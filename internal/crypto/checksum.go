@@ -0,0 +1,66 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// checksumLabel separates checksum keys from other keys derived from a data key
+const checksumLabel = "gophkeeper-checksum"
+
+// ChecksumSize is the size of a record checksum
+const ChecksumSize = sha256.Size
+
+// ErrChecksumMismatch is returned when the plaintext of a record does not
+// match the checksum stored with it
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Checksum returns the HMAC-SHA256 of plaintext under a key derived from the
+// data key of its record. The data key moves with the record when the vault
+// key is rotated or the item is shared, so the checksum stays valid.
+func Checksum(dataKey, plaintext []byte) []byte {
+	keyMAC := hmac.New(sha256.New, dataKey)
+	keyMAC.Write([]byte(checksumLabel))
+
+	mac := hmac.New(sha256.New, keyMAC.Sum(nil))
+	mac.Write(plaintext)
+	return mac.Sum(nil)
+}
+
+// Checksum decrypts a record encrypted by Encrypt and returns the checksum of
+// its plaintext. Records encrypted directly under the vault key have no data
+// key to derive it from and get nil.
+func (cm *CryptoManager) Checksum(encryptedData []byte) ([]byte, error) {
+	encData, err := ParseEncryptedData(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+	if encData.FormatVersion() != FormatDataKey {
+		return nil, nil
+	}
+	dataKey, err := cm.DataKey(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := DecryptWithDataKey(dataKey, encryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	return Checksum(dataKey, plaintext), nil
+}
+
+// VerifyChecksum decrypts a record and checks its plaintext against checksum.
+// It returns ErrChecksumMismatch if they differ, or the decryption error if
+// the ciphertext itself was damaged.
+func (cm *CryptoManager) VerifyChecksum(encryptedData, checksum []byte) error {
+	computed, err := cm.Checksum(encryptedData)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(computed, checksum) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
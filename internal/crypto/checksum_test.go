@@ -0,0 +1,69 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCryptoManager_VerifyChecksum(t *testing.T) {
+	cm, err := NewCryptoManager("master-password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	encrypted, err := cm.Encrypt([]byte("s3cret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	checksum, err := cm.Checksum(encrypted)
+	if err != nil {
+		t.Fatalf("Checksum() error = %v", err)
+	}
+	if len(checksum) != ChecksumSize {
+		t.Fatalf("Expected a %d byte checksum, got %d", ChecksumSize, len(checksum))
+	}
+
+	other, err := cm.Encrypt([]byte("s3cret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	rotated, err := NewCryptoManager("next-master-password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	rewrapped, err := cm.Rewrap(encrypted, rotated)
+	if err != nil {
+		t.Fatalf("Rewrap() error = %v", err)
+	}
+	damaged := bytes.Clone(encrypted)
+	damaged[len(damaged)-1] ^= 1
+
+	tests := []struct {
+		name      string
+		cm        *CryptoManager
+		encrypted []byte
+		checksum  []byte
+		wantErr   error
+		// wantDecryptErr expects the damage to be caught by decryption
+		wantDecryptErr bool
+	}{
+		{name: "valid", cm: cm, encrypted: encrypted, checksum: checksum},
+		{name: "after rotation", cm: rotated, encrypted: rewrapped, checksum: checksum},
+		{name: "other record", cm: cm, encrypted: other, checksum: checksum, wantErr: ErrChecksumMismatch},
+		{name: "damaged checksum", cm: cm, encrypted: encrypted, checksum: checksum[1:], wantErr: ErrChecksumMismatch},
+		{name: "damaged ciphertext", cm: cm, encrypted: damaged, checksum: checksum, wantDecryptErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cm.VerifyChecksum(tt.encrypted, tt.checksum)
+			switch {
+			case tt.wantDecryptErr:
+				if err == nil || errors.Is(err, ErrChecksumMismatch) {
+					t.Errorf("Expected a decryption error, got %v", err)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("VerifyChecksum() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
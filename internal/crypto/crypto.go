@@ -1,22 +1,81 @@
 package crypto
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"golang.org/x/crypto/pbkdf2"
 )
 
+// Versions of the EncryptedData format
+const (
+	// FormatVaultKey encrypts data directly under the vault key. Records
+	// without a version use it.
+	FormatVaultKey = 1
+	// FormatDataKey encrypts each record under its own random data key,
+	// stored wrapped by the vault key next to the data
+	FormatDataKey = 2
+)
+
+// Iteration counts of PBKDF2 deriving vault keys from master passwords
+const (
+	DefaultIterations = 100000
+	MinIterations     = 100000
+	MaxIterations     = 10000000
+)
+
+// IterationsOrDefault returns iterations, or DefaultIterations for 0
+func IterationsOrDefault(iterations int) int {
+	if iterations == 0 {
+		return DefaultIterations
+	}
+	return iterations
+}
+
+// CheckIterations checks an iteration count for deriving vault keys; 0 means
+// DefaultIterations
+func CheckIterations(iterations int) error {
+	if iterations != 0 && (iterations < MinIterations || iterations > MaxIterations) {
+		return fmt.Errorf("key derivation iterations must be between %d and %d, got %d", MinIterations, MaxIterations, iterations)
+	}
+	return nil
+}
+
+// ErrWrongKey is returned when data does not authenticate under the key
+// opening it: the key is wrong or the data was altered
+var ErrWrongKey = errors.New("wrong key or altered data")
+
+// deriveKey derives the vault key of a master password and salt
+func deriveKey(masterPassword string, salt []byte, iterations int) []byte {
+	return pbkdf2.Key([]byte(masterPassword), salt, IterationsOrDefault(iterations), 32, sha256.New)
+}
+
 // EncryptedData represents encrypted data with metadata
 type EncryptedData struct {
-	Nonce []byte `json:"nonce"`
-	Salt  []byte `json:"salt"`
-	Data  []byte `json:"data"`
+	Version    int    `json:"version,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Salt       []byte `json:"salt"`
+	WrappedKey []byte `json:"wrapped_key,omitempty"`
+	// Iterations is the PBKDF2 iteration count of the vault key under Salt,
+	// omitted for DefaultIterations
+	Iterations int    `json:"iterations,omitempty"`
+	Data       []byte `json:"data"`
+}
+
+// FormatVersion returns the format version of an encrypted record
+func (e *EncryptedData) FormatVersion() int {
+	if e.Version == 0 {
+		return FormatVaultKey
+	}
+	return e.Version
 }
 
 // CryptoManager handles encryption and decryption operations
@@ -24,6 +83,19 @@ type CryptoManager struct {
 	masterPassword string
 	key            []byte
 	salt           []byte
+	// iterations derived key from masterPassword and salt; 0 means
+	// DefaultIterations, or unknown for managers of NewCryptoManagerWithKey
+	iterations int
+	// keys caches the keys of other salts derived from masterPassword
+	keys *KeyCache
+}
+
+// KeyOptions tune how a crypto manager derives vault keys
+type KeyOptions struct {
+	// Iterations of PBKDF2; 0 means DefaultIterations
+	Iterations int
+	// Cache reuses keys derived before, if set
+	Cache *KeyCache
 }
 
 // NewCryptoManager creates a new crypto manager with master password
@@ -32,91 +104,150 @@ func NewCryptoManager(masterPassword string) (*CryptoManager, error) {
 		return nil, fmt.Errorf("master password cannot be empty")
 	}
 
-	salt := make([]byte, 32)
-	if _, err := rand.Read(salt); err != nil {
-		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	salt, err := GenerateSalt()
+	if err != nil {
+		return nil, err
 	}
 
-	key := pbkdf2.Key([]byte(masterPassword), salt, 100000, 32, sha256.New)
-
 	return &CryptoManager{
 		masterPassword: masterPassword,
-		key:            key,
+		key:            deriveKey(masterPassword, salt, DefaultIterations),
 		salt:           salt,
 	}, nil
 }
 
+// GenerateSalt generates a new random salt for key derivation
+func GenerateSalt() ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return salt, nil
+}
+
 // NewCryptoManagerWithSalt creates a new crypto manager with existing salt
 func NewCryptoManagerWithSalt(masterPassword string, salt []byte) (*CryptoManager, error) {
+	return NewCryptoManagerWithOptions(masterPassword, salt, KeyOptions{})
+}
+
+// NewCryptoManagerWithOptions creates a crypto manager with existing salt,
+// deriving the vault key as opts set
+func NewCryptoManagerWithOptions(masterPassword string, salt []byte, opts KeyOptions) (*CryptoManager, error) {
 	if masterPassword == "" {
 		return nil, fmt.Errorf("master password cannot be empty")
 	}
 	if len(salt) != 32 {
 		return nil, fmt.Errorf("invalid salt length: expected 32 bytes, got %d", len(salt))
 	}
+	if err := CheckIterations(opts.Iterations); err != nil {
+		return nil, err
+	}
 
-	key := pbkdf2.Key([]byte(masterPassword), salt, 100000, 32, sha256.New)
-
-	return &CryptoManager{
+	cm := &CryptoManager{
 		masterPassword: masterPassword,
-		key:            key,
 		salt:           salt,
+		iterations:     opts.Iterations,
+		keys:           opts.Cache,
+	}
+	cm.key = cm.derive(salt, opts.Iterations)
+	return cm, nil
+}
+
+// derive derives the vault key of salt from the master password, from the
+// key cache if there is one
+func (cm *CryptoManager) derive(salt []byte, iterations int) []byte {
+	if cm.keys != nil {
+		return cm.keys.Key(cm.masterPassword, salt, iterations)
+	}
+	return deriveKey(cm.masterPassword, salt, iterations)
+}
+
+// NewCryptoManagerWithKey creates a crypto manager from an already derived vault key,
+// e.g. one recovered from key escrow. It can only decrypt data encrypted under salt.
+func NewCryptoManagerWithKey(key, salt []byte) (*CryptoManager, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid key length: expected 32 bytes, got %d", len(key))
+	}
+	if len(salt) != 32 {
+		return nil, fmt.Errorf("invalid salt length: expected 32 bytes, got %d", len(salt))
+	}
+
+	return &CryptoManager{
+		key:  key,
+		salt: salt,
 	}, nil
 }
 
-// Encrypt encrypts data using AES-256-GCM
+// Key returns the derived vault key
+func (cm *CryptoManager) Key() []byte {
+	return cm.key
+}
+
+// Iterations returns the PBKDF2 iteration count the vault key was derived
+// with; 0 means DefaultIterations
+func (cm *CryptoManager) Iterations() int {
+	if cm.iterations == DefaultIterations {
+		return 0
+	}
+	return cm.iterations
+}
+
+// SetIterations records the PBKDF2 iteration count the key of a manager of
+// NewCryptoManagerWithKey was derived with, so the key can be derived again
+// from the master password
+func (cm *CryptoManager) SetIterations(iterations int) {
+	cm.iterations = IterationsOrDefault(iterations)
+}
+
+// Encrypt encrypts data using AES-256-GCM under a fresh data key, which is
+// stored with the result wrapped by the vault key
 func (cm *CryptoManager) Encrypt(data []byte) ([]byte, error) {
 	if len(data) == 0 {
 		return nil, fmt.Errorf("data cannot be empty")
 	}
 
-	block, err := aes.NewCipher(cm.key)
+	dataKey, err := GenerateDataKey()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
+		return nil, err
 	}
-
-	gcm, err := cipher.NewGCM(block)
+	sealed, err := SealWithKey(dataKey, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
+		return nil, err
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := rand.Read(nonce); err != nil {
-		return nil, fmt.Errorf("failed to generate nonce: %w", err)
-	}
-
-	encryptedData := gcm.Seal(nonce, nonce, data, nil)
-
 	encData := EncryptedData{
-		Nonce: nonce,
-		Salt:  cm.salt,
-		Data:  encryptedData[len(nonce):],
+		Version: FormatDataKey,
+		Nonce:   sealed[:aesGCMNonceSize],
+		Data:    sealed[aesGCMNonceSize:],
 	}
-
-	jsonData, err := json.Marshal(encData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal encrypted data: %w", err)
+	if err := cm.wrapDataKey(&encData, dataKey); err != nil {
+		return nil, err
 	}
 
-	return jsonData, nil
+	return marshalEncryptedData(&encData)
 }
 
-// Decrypt decrypts data using AES-256-GCM
+// Decrypt decrypts data encrypted by Encrypt in any format version
 func (cm *CryptoManager) Decrypt(encryptedData []byte) ([]byte, error) {
-	if len(encryptedData) == 0 {
-		return nil, fmt.Errorf("encrypted data cannot be empty")
+	encData, err := ParseEncryptedData(encryptedData)
+	if err != nil {
+		return nil, err
 	}
 
-	var encData EncryptedData
-	if err := json.Unmarshal(encryptedData, &encData); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal encrypted data: %w", err)
+	key, err := cm.keyFor(encData.Salt, encData.Iterations)
+	if err != nil {
+		return nil, err
 	}
-
-	if len(encData.Salt) != 32 {
-		return nil, fmt.Errorf("invalid salt length in encrypted data")
+	switch encData.FormatVersion() {
+	case FormatVaultKey:
+	case FormatDataKey:
+		if key, err = OpenWithKey(key, encData.WrappedKey); err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encrypted data version %d", encData.Version)
 	}
 
-	key := pbkdf2.Key([]byte(cm.masterPassword), encData.Salt, 100000, 32, sha256.New)
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
@@ -129,12 +260,106 @@ func (cm *CryptoManager) Decrypt(encryptedData []byte) ([]byte, error) {
 
 	decryptedData, err := gcm.Open(nil, encData.Nonce, encData.Data, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+		return nil, fmt.Errorf("failed to decrypt data: %w", ErrWrongKey)
 	}
 
 	return decryptedData, nil
 }
 
+// Rewrap moves encrypted data from this vault key to next. Only the wrapped
+// data key changes, so the data itself is neither decrypted nor copied under
+// a new key. Data in the older format is re-encrypted, which upgrades it, and
+// so are streams, whose chunks authenticate the header holding the key.
+func (cm *CryptoManager) Rewrap(encryptedData []byte, next *CryptoManager) ([]byte, error) {
+	if IsStream(encryptedData) {
+		var plaintext, rewrapped bytes.Buffer
+		if _, err := cm.DecryptStream(&plaintext, bytes.NewReader(encryptedData)); err != nil {
+			return nil, err
+		}
+		if _, err := next.EncryptStream(&rewrapped, &plaintext); err != nil {
+			return nil, err
+		}
+		return rewrapped.Bytes(), nil
+	}
+	encData, err := ParseEncryptedData(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+	if encData.FormatVersion() != FormatDataKey {
+		plaintext, err := cm.Decrypt(encryptedData)
+		if err != nil {
+			return nil, err
+		}
+		return next.Encrypt(plaintext)
+	}
+
+	key, err := cm.keyFor(encData.Salt, encData.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := OpenWithKey(key, encData.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	if err := next.wrapDataKey(encData, dataKey); err != nil {
+		return nil, err
+	}
+	return marshalEncryptedData(encData)
+}
+
+// aesGCMNonceSize is the standard nonce size SealWithKey prefixes
+const aesGCMNonceSize = 12
+
+// wrapDataKey seals dataKey under the vault key into encData
+func (cm *CryptoManager) wrapDataKey(encData *EncryptedData, dataKey []byte) error {
+	wrapped, err := SealWithKey(cm.key, dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	encData.Salt = cm.salt
+	encData.Iterations = cm.Iterations()
+	encData.WrappedKey = wrapped
+	return nil
+}
+
+// keyFor returns the vault key for data encrypted under salt, deriving it
+// again from the master password with iterations when the salt is not the
+// current one
+func (cm *CryptoManager) keyFor(salt []byte, iterations int) ([]byte, error) {
+	if bytes.Equal(salt, cm.salt) {
+		return cm.key, nil
+	}
+	if cm.masterPassword == "" {
+		return nil, fmt.Errorf("data was encrypted under a different salt")
+	}
+	if err := CheckIterations(iterations); err != nil {
+		return nil, err
+	}
+	return cm.derive(salt, iterations), nil
+}
+
+// ParseEncryptedData decodes and checks an encrypted record in a binary
+// envelope or, as written before it, JSON
+func ParseEncryptedData(encryptedData []byte) (*EncryptedData, error) {
+	if len(encryptedData) == 0 {
+		return nil, fmt.Errorf("encrypted data cannot be empty")
+	}
+
+	var encData EncryptedData
+	if isEnvelope(encryptedData) {
+		if err := encData.UnmarshalBinary(encryptedData); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(encryptedData, &encData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal encrypted data: %w", err)
+	}
+
+	if len(encData.Salt) != 32 {
+		return nil, fmt.Errorf("invalid salt length in encrypted data")
+	}
+	return &encData, nil
+}
+
 // EncryptString encrypts a string and returns base64 encoded result
 func (cm *CryptoManager) EncryptString(data string) (string, error) {
 	encrypted, err := cm.Encrypt([]byte(data))
@@ -175,7 +400,7 @@ func VerifyMasterPassword(masterPassword string, salt []byte) bool {
 		return false
 	}
 
-	key := pbkdf2.Key([]byte(masterPassword), salt, 100000, 32, sha256.New)
+	key := deriveKey(masterPassword, salt, DefaultIterations)
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -185,3 +410,23 @@ func VerifyMasterPassword(masterPassword string, salt []byte) bool {
 	_, err = cipher.NewGCM(block)
 	return err == nil
 }
+
+// verifierPlaintext is the known value sealed into master password verifiers
+const verifierPlaintext = "gophkeeper master password verifier v1"
+
+// NewVerifier encrypts a known value under the vault key. Stored with the
+// account, it lets a master password be checked before any item is read.
+func (cm *CryptoManager) NewVerifier() ([]byte, error) {
+	return cm.Encrypt([]byte(verifierPlaintext))
+}
+
+// CheckVerifier reports whether verifier was created under this vault key,
+// i.e. with the same master password and salt
+func (cm *CryptoManager) CheckVerifier(verifier []byte) bool {
+	encData, err := ParseEncryptedData(verifier)
+	if err != nil || !bytes.Equal(encData.Salt, cm.salt) {
+		return false
+	}
+	plaintext, err := cm.Decrypt(verifier)
+	return err == nil && subtle.ConstantTimeCompare(plaintext, []byte(verifierPlaintext)) == 1
+}
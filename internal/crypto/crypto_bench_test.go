@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// benchmarkSizes are the plaintext sizes of the encryption benchmarks
+var benchmarkSizes = []int{1 << 10, 64 << 10, 1 << 20}
+
+func newBenchmarkManager(b *testing.B) *CryptoManager {
+	b.Helper()
+	cm, err := NewCryptoManagerWithSalt("benchmarkPassword123!", bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		b.Fatalf("NewCryptoManagerWithSalt() error = %v", err)
+	}
+	return cm
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	cm := newBenchmarkManager(b)
+	for _, size := range benchmarkSizes {
+		plaintext := bytes.Repeat([]byte("x"), size)
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := cm.Encrypt(plaintext); err != nil {
+					b.Fatalf("Encrypt() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecrypt(b *testing.B) {
+	cm := newBenchmarkManager(b)
+	for _, size := range benchmarkSizes {
+		encrypted, err := cm.Encrypt(bytes.Repeat([]byte("x"), size))
+		if err != nil {
+			b.Fatalf("Encrypt() error = %v", err)
+		}
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := cm.Decrypt(encrypted); err != nil {
+					b.Fatalf("Decrypt() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEncryptStream encrypts 64 MiB with a constant amount of memory,
+// unlike Encrypt, which holds the whole plaintext and ciphertext
+func BenchmarkEncryptStream(b *testing.B) {
+	cm := newBenchmarkManager(b)
+	const size = 64 << 20
+	b.SetBytes(size)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := cm.EncryptStream(io.Discard, io.LimitReader(zeroReader{}, size)); err != nil {
+			b.Fatalf("EncryptStream() error = %v", err)
+		}
+	}
+}
+
+func BenchmarkDecryptStream(b *testing.B) {
+	cm := newBenchmarkManager(b)
+	var encrypted bytes.Buffer
+	if _, err := cm.EncryptStream(&encrypted, io.LimitReader(zeroReader{}, 16<<20)); err != nil {
+		b.Fatalf("EncryptStream() error = %v", err)
+	}
+	b.SetBytes(16 << 20)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cm.DecryptStream(io.Discard, bytes.NewReader(encrypted.Bytes())); err != nil {
+			b.Fatalf("DecryptStream() error = %v", err)
+		}
+	}
+}
+
+// zeroReader reads zeros without end
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func BenchmarkNewCryptoManagerWithSalt(b *testing.B) {
+	salt := bytes.Repeat([]byte{7}, 32)
+	for i := 0; i < b.N; i++ {
+		if _, err := NewCryptoManagerWithSalt("benchmarkPassword123!", salt); err != nil {
+			b.Fatalf("NewCryptoManagerWithSalt() error = %v", err)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package crypto
 
 import (
+	"bytes"
+	"encoding/json"
 	"testing"
 )
 
@@ -293,3 +295,220 @@ func TestEmptyDataHandling(t *testing.T) {
 		t.Error("Decrypt() should return error for empty data")
 	}
 }
+
+func TestGenerateSalt(t *testing.T) {
+	salt1, err := GenerateSalt()
+	if err != nil {
+		t.Fatalf("GenerateSalt() error = %v", err)
+	}
+	if len(salt1) != 32 {
+		t.Errorf("Expected salt length 32, got %d", len(salt1))
+	}
+
+	salt2, err := GenerateSalt()
+	if err != nil {
+		t.Fatalf("GenerateSalt() error = %v", err)
+	}
+	if string(salt1) == string(salt2) {
+		t.Error("Expected different salts")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	cm, err := NewCryptoManager("correct horse battery")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	verifier, err := cm.NewVerifier()
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	same, _ := NewCryptoManagerWithSalt("correct horse battery", cm.GetSalt())
+	wrongPassword, _ := NewCryptoManagerWithSalt("wrong horse battery", cm.GetSalt())
+	otherSalt, _ := NewCryptoManager("correct horse battery")
+	other, _ := otherSalt.NewVerifier()
+
+	tests := []struct {
+		name     string
+		cm       *CryptoManager
+		verifier []byte
+		want     bool
+	}{
+		{name: "same key", cm: same, verifier: verifier, want: true},
+		{name: "wrong password", cm: wrongPassword, verifier: verifier, want: false},
+		{name: "verifier under another salt", cm: same, verifier: other, want: false},
+		{name: "garbage", cm: same, verifier: []byte("not a verifier"), want: false},
+		{name: "encrypted other value", cm: same, verifier: mustEncrypt(t, same, "something else"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cm.CheckVerifier(tt.verifier); got != tt.want {
+				t.Errorf("CheckVerifier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustEncrypt(t *testing.T, cm *CryptoManager, value string) []byte {
+	t.Helper()
+	encrypted, err := cm.Encrypt([]byte(value))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	return encrypted
+}
+
+func TestEncryptDataKeyFormat(t *testing.T) {
+	cm, err := NewCryptoManager("correct horse battery")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	first := mustEncrypt(t, cm, "same value")
+	second := mustEncrypt(t, cm, "same value")
+
+	a, err := ParseEncryptedData(first)
+	if err != nil {
+		t.Fatalf("ParseEncryptedData() error = %v", err)
+	}
+	b, err := ParseEncryptedData(second)
+	if err != nil {
+		t.Fatalf("ParseEncryptedData() error = %v", err)
+	}
+	if a.FormatVersion() != FormatDataKey || len(a.WrappedKey) == 0 {
+		t.Fatalf("Expected a wrapped data key, got version %d", a.Version)
+	}
+	if bytes.Equal(a.WrappedKey, b.WrappedKey) {
+		t.Error("Expected every record to get its own data key")
+	}
+
+	a.WrappedKey = b.WrappedKey
+	swapped, _ := a.MarshalBinary()
+	if _, err := cm.Decrypt(swapped); err == nil {
+		t.Error("Expected another record's data key not to open the data")
+	}
+
+	a.Version = 99
+	future, _ := json.Marshal(a)
+	if _, err := cm.Decrypt(future); err == nil {
+		t.Error("Expected an unknown format version to be rejected")
+	}
+}
+
+func TestDecryptVaultKeyFormat(t *testing.T) {
+	cm, err := NewCryptoManager("correct horse battery")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	legacy := mustEncryptVaultKey(t, cm, "written by an older client")
+
+	decrypted, err := cm.Decrypt(legacy)
+	if err != nil || string(decrypted) != "written by an older client" {
+		t.Errorf("Decrypt() = %q, %v", decrypted, err)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	current, err := NewCryptoManager("old password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	next, err := NewCryptoManager("new password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		encrypted []byte
+		sameData  bool
+	}{
+		{name: "data key format", encrypted: mustEncrypt(t, current, "secret"), sameData: true},
+		{name: "vault key format is upgraded", encrypted: mustEncryptVaultKey(t, current, "secret"), sameData: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rewrapped, err := current.Rewrap(tt.encrypted, next)
+			if err != nil {
+				t.Fatalf("Rewrap() error = %v", err)
+			}
+			if decrypted, err := next.Decrypt(rewrapped); err != nil || string(decrypted) != "secret" {
+				t.Errorf("Expected the next key to decrypt, got %q, %v", decrypted, err)
+			}
+			if _, err := current.Decrypt(rewrapped); err == nil {
+				t.Error("Expected the current key to no longer decrypt")
+			}
+
+			before, _ := ParseEncryptedData(tt.encrypted)
+			after, err := ParseEncryptedData(rewrapped)
+			if err != nil {
+				t.Fatalf("ParseEncryptedData() error = %v", err)
+			}
+			if after.FormatVersion() != FormatDataKey {
+				t.Errorf("Expected the data key format, got %d", after.FormatVersion())
+			}
+			if got := bytes.Equal(before.Data, after.Data); got != tt.sameData {
+				t.Errorf("Data kept = %v, want %v", got, tt.sameData)
+			}
+		})
+	}
+
+	if _, err := current.Rewrap([]byte("not encrypted"), next); err == nil {
+		t.Error("Expected garbage to be rejected")
+	}
+}
+
+// mustEncryptVaultKey encrypts value directly under the vault key, as
+// clients did before per-record data keys
+func mustEncryptVaultKey(t *testing.T, cm *CryptoManager, value string) []byte {
+	t.Helper()
+	sealed, err := SealWithKey(cm.Key(), []byte(value))
+	if err != nil {
+		t.Fatalf("SealWithKey() error = %v", err)
+	}
+	encrypted, err := json.Marshal(EncryptedData{Nonce: sealed[:12], Salt: cm.GetSalt(), Data: sealed[12:]})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	return encrypted
+}
+
+func TestNewCryptoManagerWithOptions(t *testing.T) {
+	salt := bytes.Repeat([]byte{2}, 32)
+	cache := NewKeyCache()
+
+	cm, err := NewCryptoManagerWithOptions("masterPassword123!", salt, KeyOptions{Iterations: MinIterations + 1, Cache: cache})
+	if err != nil {
+		t.Fatalf("NewCryptoManagerWithOptions() error = %v", err)
+	}
+	if cm.Iterations() != MinIterations+1 {
+		t.Errorf("Iterations() = %d, want %d", cm.Iterations(), MinIterations+1)
+	}
+	defaults, _ := NewCryptoManagerWithSalt("masterPassword123!", salt)
+	if bytes.Equal(cm.Key(), defaults.Key()) {
+		t.Error("Expected the iteration count to change the vault key")
+	}
+
+	encrypted := mustEncrypt(t, cm, "secret")
+	if encData, err := ParseEncryptedData(encrypted); err != nil || encData.Iterations != MinIterations+1 {
+		t.Errorf("Expected the iteration count recorded, got %+v, %v", encData, err)
+	}
+	if encData, err := ParseEncryptedData(mustEncrypt(t, defaults, "secret")); err != nil || encData.Iterations != 0 {
+		t.Errorf("Expected the default iteration count recorded as 0, got %+v, %v", encData, err)
+	}
+
+	// a manager of a new salt reads records of the old one with their count
+	next, err := NewCryptoManagerWithOptions("masterPassword123!", bytes.Repeat([]byte{3}, 32), KeyOptions{Cache: cache})
+	if err != nil {
+		t.Fatalf("NewCryptoManagerWithOptions() error = %v", err)
+	}
+	if decrypted, err := next.Decrypt(encrypted); err != nil || string(decrypted) != "secret" {
+		t.Errorf("Expected records of the old salt decrypted, got %q, %v", decrypted, err)
+	}
+
+	for _, iterations := range []int{-1, MinIterations - 1, MaxIterations + 1} {
+		if _, err := NewCryptoManagerWithOptions("masterPassword123!", salt, KeyOptions{Iterations: iterations}); err == nil {
+			t.Errorf("Expected %d iterations to be rejected", iterations)
+		}
+	}
+}
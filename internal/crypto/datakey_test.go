@@ -0,0 +1,55 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSealOpenWithKey(t *testing.T) {
+	key, err := GenerateDataKey()
+	if err != nil {
+		t.Fatalf("GenerateDataKey() error = %v", err)
+	}
+	otherKey, err := GenerateDataKey()
+	if err != nil {
+		t.Fatalf("GenerateDataKey() error = %v", err)
+	}
+
+	sealed, err := SealWithKey(key, []byte("s3cret"))
+	if err != nil {
+		t.Fatalf("SealWithKey() error = %v", err)
+	}
+	if bytes.Contains(sealed, []byte("s3cret")) {
+		t.Error("Sealed data contains the plaintext")
+	}
+
+	tests := []struct {
+		name     string
+		key      []byte
+		sealed   []byte
+		want     string
+		wantErr  bool
+		wrongKey bool
+	}{
+		{name: "correct key", key: key, sealed: sealed, want: "s3cret"},
+		{name: "wrong key", key: otherKey, sealed: sealed, wantErr: true, wrongKey: true},
+		{name: "short key", key: key[:16], sealed: sealed, wantErr: true},
+		{name: "truncated data", key: key, sealed: sealed[:4], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OpenWithKey(tt.key, tt.sealed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OpenWithKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrWrongKey) != tt.wrongKey {
+				t.Errorf("OpenWithKey() error = %v, want ErrWrongKey %v", err, tt.wrongKey)
+			}
+			if string(got) != tt.want {
+				t.Errorf("OpenWithKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
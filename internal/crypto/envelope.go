@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Encrypted records are stored in a binary envelope, which saves the base64
+// and field names of the JSON encoding:
+//
+//	magic "GKE" | version (1) | salt (32) | iterations (uvarint) |
+//	wrapped key length (uvarint) | wrapped key | nonce (12) | ciphertext
+//
+// The version is that of the envelope. Envelopes only hold FormatDataKey
+// records; records in the older format stay JSON until they are encrypted
+// again. JSON records start with '{', so the magic tells the two apart and
+// records written before the envelope still decrypt.
+const envelopeVersion = 1
+
+// envelopeMagic starts every binary envelope
+var envelopeMagic = []byte("GKE")
+
+// errInvalidEnvelope is returned for binary envelopes that cannot be decoded
+var errInvalidEnvelope = errors.New("invalid encrypted data envelope")
+
+// isEnvelope reports whether an encrypted record is a binary envelope
+func isEnvelope(encryptedData []byte) bool {
+	return bytes.HasPrefix(encryptedData, envelopeMagic)
+}
+
+// MarshalBinary encodes a FormatDataKey record as a binary envelope
+func (e *EncryptedData) MarshalBinary() ([]byte, error) {
+	if e.FormatVersion() != FormatDataKey {
+		return nil, fmt.Errorf("only records with a data key fit a binary envelope, got version %d", e.FormatVersion())
+	}
+	if len(e.Salt) != 32 || len(e.Nonce) != aesGCMNonceSize || len(e.WrappedKey) == 0 || e.Iterations < 0 {
+		return nil, errInvalidEnvelope
+	}
+
+	envelope := make([]byte, 0, len(envelopeMagic)+1+len(e.Salt)+2*binary.MaxVarintLen64+
+		len(e.WrappedKey)+len(e.Nonce)+len(e.Data))
+	envelope = append(envelope, envelopeMagic...)
+	envelope = append(envelope, envelopeVersion)
+	envelope = append(envelope, e.Salt...)
+	envelope = binary.AppendUvarint(envelope, uint64(e.Iterations))
+	envelope = binary.AppendUvarint(envelope, uint64(len(e.WrappedKey)))
+	envelope = append(envelope, e.WrappedKey...)
+	envelope = append(envelope, e.Nonce...)
+	envelope = append(envelope, e.Data...)
+	return envelope, nil
+}
+
+// UnmarshalBinary decodes a binary envelope. The fields share memory with data.
+func (e *EncryptedData) UnmarshalBinary(data []byte) error {
+	if !isEnvelope(data) || len(data) < len(envelopeMagic)+1 {
+		return errInvalidEnvelope
+	}
+	rest := data[len(envelopeMagic):]
+	if version := rest[0]; version != envelopeVersion {
+		return fmt.Errorf("unsupported encrypted data envelope version %d", version)
+	}
+	rest = rest[1:]
+
+	if len(rest) < 32 {
+		return errInvalidEnvelope
+	}
+	salt, rest := rest[:32], rest[32:]
+	iterations, n := binary.Uvarint(rest)
+	if n <= 0 || iterations > MaxIterations {
+		return errInvalidEnvelope
+	}
+	rest = rest[n:]
+	wrappedLen, n := binary.Uvarint(rest)
+	if n <= 0 || wrappedLen == 0 || wrappedLen > uint64(len(rest[n:])) {
+		return errInvalidEnvelope
+	}
+	rest = rest[n:]
+	wrappedKey, rest := rest[:wrappedLen], rest[wrappedLen:]
+	if len(rest) < aesGCMNonceSize {
+		return errInvalidEnvelope
+	}
+
+	*e = EncryptedData{
+		Version:    FormatDataKey,
+		Salt:       salt,
+		Iterations: int(iterations),
+		WrappedKey: wrappedKey,
+		Nonce:      rest[:aesGCMNonceSize],
+		Data:       rest[aesGCMNonceSize:],
+	}
+	return nil
+}
+
+// marshalEncryptedData encodes a record as it is stored: records with a data
+// key in a binary envelope, older ones as JSON
+func marshalEncryptedData(encData *EncryptedData) ([]byte, error) {
+	if encData.FormatVersion() == FormatDataKey {
+		return encData.MarshalBinary()
+	}
+	jsonData, err := json.Marshal(encData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal encrypted data: %w", err)
+	}
+	return jsonData, nil
+}
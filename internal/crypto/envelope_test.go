@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestEncryptBinaryEnvelope(t *testing.T) {
+	cm, err := NewCryptoManager("correct horse battery")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	plaintext := bytes.Repeat([]byte("x"), 1024)
+	encrypted, err := cm.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !bytes.HasPrefix(encrypted, envelopeMagic) {
+		t.Fatalf("Expected a binary envelope, got %q", encrypted[:min(len(encrypted), 16)])
+	}
+
+	encData, err := ParseEncryptedData(encrypted)
+	if err != nil {
+		t.Fatalf("ParseEncryptedData() error = %v", err)
+	}
+	legacy, err := json.Marshal(encData)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if len(encrypted) >= len(legacy)*4/5 {
+		t.Errorf("Expected the envelope (%d bytes) well below JSON (%d bytes)", len(encrypted), len(legacy))
+	}
+
+	// records written as JSON before the envelope still decrypt
+	if decrypted, err := cm.Decrypt(legacy); err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Expected the JSON record decrypted, got %v", err)
+	}
+	roundTrip, err := encData.MarshalBinary()
+	if err != nil || !bytes.Equal(roundTrip, encrypted) {
+		t.Errorf("Expected the envelope encoded again unchanged, got %v", err)
+	}
+}
+
+func TestParseEncryptedData_Envelope(t *testing.T) {
+	cm, err := NewCryptoManager("correct horse battery")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	encrypted := mustEncrypt(t, cm, "secret")
+
+	future := bytes.Clone(encrypted)
+	future[len(envelopeMagic)] = envelopeVersion + 1
+
+	tests := []struct {
+		name      string
+		encrypted []byte
+	}{
+		{name: "magic only", encrypted: envelopeMagic},
+		{name: "unknown version", encrypted: future},
+		{name: "cut in the salt", encrypted: encrypted[:len(envelopeMagic)+10]},
+		{name: "cut in the wrapped key", encrypted: encrypted[:len(envelopeMagic)+1+32+2+20]},
+		{name: "cut in the nonce", encrypted: encrypted[:len(envelopeMagic)+1+32+2+60+5]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseEncryptedData(tt.encrypted); err == nil {
+				t.Error("Expected error")
+			}
+			if _, err := cm.Decrypt(tt.encrypted); err == nil {
+				t.Error("Expected Decrypt() to fail")
+			}
+		})
+	}
+
+	legacy := mustEncryptVaultKey(t, cm, "secret")
+	encData, err := ParseEncryptedData(legacy)
+	if err != nil {
+		t.Fatalf("ParseEncryptedData() error = %v", err)
+	}
+	if _, err := encData.MarshalBinary(); err == nil {
+		t.Error("Expected records without a data key to stay JSON")
+	}
+}
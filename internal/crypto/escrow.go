@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateRecoveryKeyPair generates an X25519 key pair for an organization recovery key.
+// The private key stays offline with admins; the public key is configured on the server.
+func GenerateRecoveryKeyPair() (publicKey, privateKey []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate recovery key: %w", err)
+	}
+	return key.PublicKey().Bytes(), key.Bytes(), nil
+}
+
+// RecoveryKeyID returns a short fingerprint identifying a recovery public key
+func RecoveryKeyID(publicKey []byte) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// escrowLabel separates keys wrapped for escrow from other sealed data
+const escrowLabel = "gophkeeper-escrow"
+
+// WrapKey encrypts key for the holder of the recovery private key using an
+// ephemeral X25519 exchange. The result is the ephemeral public key followed
+// by the sealed key.
+func WrapKey(recoveryPublicKey, key []byte) ([]byte, error) {
+	wrapped, err := sealTo(escrowLabel, recoveryPublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recovery public key: %w", err)
+	}
+	return wrapped, nil
+}
+
+// UnwrapKey decrypts a key wrapped by WrapKey with the recovery private key
+func UnwrapKey(recoveryPrivateKey, wrapped []byte) ([]byte, error) {
+	return openWith(escrowLabel, recoveryPrivateKey, wrapped)
+}
+
+// sealTo encrypts data for the holder of the X25519 private key of publicKey
+// using an ephemeral exchange. The result is the ephemeral public key followed
+// by the sealed data; label binds it to one use.
+func sealTo(label string, publicKey, data []byte) ([]byte, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared key: %w", err)
+	}
+
+	sealed, err := SealWithKey(wrappingKey(label, shared, ephemeral.PublicKey().Bytes(), publicKey), data)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ephemeral.PublicKey().Bytes(), sealed...), nil
+}
+
+// openWith decrypts data sealed by sealTo with the same label
+func openWith(label string, privateKey, sealed []byte) ([]byte, error) {
+	private, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	const publicKeySize = 32
+	if len(sealed) <= publicKeySize {
+		return nil, fmt.Errorf("sealed data is too short")
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(sealed[:publicKeySize])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	shared, err := private.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared key: %w", err)
+	}
+
+	return OpenWithKey(wrappingKey(label, shared, sealed[:publicKeySize], private.PublicKey().Bytes()), sealed[publicKeySize:])
+}
+
+// wrappingKey binds the shared secret to the label and both public keys
+func wrappingKey(label string, shared, ephemeralPublicKey, recipientPublicKey []byte) []byte {
+	sum := sha256.Sum256(bytes.Join([][]byte{[]byte(label), shared, ephemeralPublicKey, recipientPublicKey}, nil))
+	return sum[:]
+}
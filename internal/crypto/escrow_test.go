@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrapUnwrapKey(t *testing.T) {
+	publicKey, privateKey, err := GenerateRecoveryKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryKeyPair() error = %v", err)
+	}
+	_, otherPrivateKey, err := GenerateRecoveryKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryKeyPair() error = %v", err)
+	}
+
+	vaultKey, _ := GenerateDataKey()
+	wrapped, err := WrapKey(publicKey, vaultKey)
+	if err != nil {
+		t.Fatalf("WrapKey() error = %v", err)
+	}
+
+	unwrapped, err := UnwrapKey(privateKey, wrapped)
+	if err != nil {
+		t.Fatalf("UnwrapKey() error = %v", err)
+	}
+	if !bytes.Equal(unwrapped, vaultKey) {
+		t.Error("Unwrapped key does not match the original")
+	}
+
+	if _, err := UnwrapKey(otherPrivateKey, wrapped); err == nil {
+		t.Error("Expected error when unwrapping with a different recovery key")
+	}
+	if _, err := UnwrapKey(privateKey, wrapped[:16]); err == nil {
+		t.Error("Expected error for truncated wrapped key")
+	}
+	if _, err := WrapKey([]byte("short"), vaultKey); err == nil {
+		t.Error("Expected error for invalid recovery public key")
+	}
+
+	if RecoveryKeyID(publicKey) == RecoveryKeyID(bytes.Repeat([]byte{1}, 32)) {
+		t.Error("Different keys should have different IDs")
+	}
+}
+
+func TestNewCryptoManagerWithKey(t *testing.T) {
+	cm, err := NewCryptoManager("master-password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	encrypted, err := cm.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	recovered, err := NewCryptoManagerWithKey(cm.Key(), cm.GetSalt())
+	if err != nil {
+		t.Fatalf("NewCryptoManagerWithKey() error = %v", err)
+	}
+	decrypted, err := recovered.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if string(decrypted) != "secret" {
+		t.Errorf("Decrypt() = %q, want %q", decrypted, "secret")
+	}
+
+	other, _ := NewCryptoManager("master-password")
+	otherEncrypted, _ := other.Encrypt([]byte("secret"))
+	if _, err := recovered.Decrypt(otherEncrypted); err == nil {
+		t.Error("Key-only manager should not decrypt data under another salt")
+	}
+
+	if _, err := NewCryptoManagerWithKey([]byte("short"), cm.GetSalt()); err == nil {
+		t.Error("Expected error for invalid key length")
+	}
+}
@@ -0,0 +1,99 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+)
+
+// KeyCache keeps vault keys derived from master passwords, so deriving the
+// key of the same master password, salt and iteration count again, as on
+// every unlock, login retry or rotation check of a session, skips PBKDF2.
+// Parallel derivations of one key run it once. Keys are indexed by a keyed
+// hash of the master password, never the password itself. It is safe for
+// concurrent use.
+type KeyCache struct {
+	mutex sync.Mutex
+	// secret keys the hash indexing keys, so it cannot be looked up without it
+	secret []byte
+	keys   map[[sha256.Size]byte]*derivation
+}
+
+// derivation is a vault key of a KeyCache, done once derived
+type derivation struct {
+	done chan struct{}
+	key  []byte
+}
+
+// NewKeyCache creates an empty key cache
+func NewKeyCache() *KeyCache {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("crypto: failed to generate key cache secret: " + err.Error())
+	}
+	return &KeyCache{secret: secret, keys: make(map[[sha256.Size]byte]*derivation)}
+}
+
+// Key returns the vault key of masterPassword and salt derived with
+// iterations, deriving it unless it is cached. The key is a copy the caller
+// may keep.
+func (c *KeyCache) Key(masterPassword string, salt []byte, iterations int) []byte {
+	index := c.index(masterPassword, salt, iterations)
+
+	c.mutex.Lock()
+	d, ok := c.keys[index]
+	if !ok {
+		d = &derivation{done: make(chan struct{})}
+		c.keys[index] = d
+	}
+	c.mutex.Unlock()
+
+	if !ok {
+		d.key = deriveKey(masterPassword, salt, iterations)
+		close(d.done)
+	}
+	<-d.done
+	return append([]byte(nil), d.key...)
+}
+
+// Purge zeroes and forgets every cached key, e.g. when the vault locks
+func (c *KeyCache) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for index, d := range c.keys {
+		select {
+		case <-d.done:
+			for i := range d.key {
+				d.key[i] = 0
+			}
+		default:
+			// still being derived; the waiters get the key, the cache does not
+		}
+		delete(c.keys, index)
+	}
+}
+
+// Len returns the number of cached keys
+func (c *KeyCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.keys)
+}
+
+// index hashes what a key is derived from with the secret of the cache
+func (c *KeyCache) index(masterPassword string, salt []byte, iterations int) [sha256.Size]byte {
+	mac := hmac.New(sha256.New, c.secret)
+	var lengths [16]byte
+	binary.BigEndian.PutUint64(lengths[:8], uint64(len(masterPassword)))
+	binary.BigEndian.PutUint64(lengths[8:], uint64(IterationsOrDefault(iterations)))
+	mac.Write(lengths[:])
+	mac.Write([]byte(masterPassword))
+	mac.Write(salt)
+
+	var index [sha256.Size]byte
+	copy(index[:], mac.Sum(nil))
+	return index
+}
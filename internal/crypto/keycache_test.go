@@ -0,0 +1,51 @@
+package crypto
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestKeyCache(t *testing.T) {
+	c := NewKeyCache()
+	salt := bytes.Repeat([]byte{1}, 32)
+
+	var wg sync.WaitGroup
+	keys := make([][]byte, 4)
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			keys[i] = c.Key("masterPassword123!", salt, 0)
+		}(i)
+	}
+	wg.Wait()
+
+	want := deriveKey("masterPassword123!", salt, DefaultIterations)
+	for i, key := range keys {
+		if !bytes.Equal(key, want) {
+			t.Errorf("Key() of call %d differs from the derived key", i)
+		}
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected parallel derivations of one key cached once, got %d keys", c.Len())
+	}
+
+	if bytes.Equal(c.Key("masterPassword123!", salt, MinIterations+1), want) {
+		t.Error("Expected another iteration count to derive another key")
+	}
+	if bytes.Equal(c.Key("otherPassword123!", salt, 0), want) {
+		t.Error("Expected another master password to derive another key")
+	}
+	if c.Len() != 3 {
+		t.Errorf("Expected 3 cached keys, got %d", c.Len())
+	}
+
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Expected no keys after Purge, got %d", c.Len())
+	}
+	if !bytes.Equal(keys[0], want) {
+		t.Error("Expected keys handed out to survive Purge")
+	}
+}
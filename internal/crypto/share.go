@@ -0,0 +1,105 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"fmt"
+)
+
+// shareLabel separates data keys sealed for sharing from other sealed data
+const shareLabel = "gophkeeper-share"
+
+// GenerateShareKeyPair generates the X25519 key pair items are shared to. The
+// private key is stored encrypted under the vault key.
+func GenerateShareKeyPair() (publicKey, privateKey []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate share key: %w", err)
+	}
+	return key.PublicKey().Bytes(), key.Bytes(), nil
+}
+
+// SealDataKey encrypts the data key of an item to a recipient's share public key
+func SealDataKey(recipientPublicKey, dataKey []byte) ([]byte, error) {
+	sealed, err := sealTo(shareLabel, recipientPublicKey, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid share public key: %w", err)
+	}
+	return sealed, nil
+}
+
+// OpenDataKey decrypts a data key sealed by SealDataKey with the share private key
+func OpenDataKey(sharePrivateKey, sealed []byte) ([]byte, error) {
+	return openWith(shareLabel, sharePrivateKey, sealed)
+}
+
+// DataKey returns the data key of a record encrypted under this vault key.
+// Records in the older format have none and must be encrypted again first.
+func (cm *CryptoManager) DataKey(encryptedData []byte) ([]byte, error) {
+	encData, err := ParseEncryptedData(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+	if encData.FormatVersion() != FormatDataKey {
+		return nil, fmt.Errorf("data is encrypted directly under the vault key")
+	}
+	key, err := cm.keyFor(encData.Salt, encData.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := OpenWithKey(key, encData.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+// EncryptWithDataKeyOf encrypts data under the data key of current, so
+// whoever an item was shared with can still read it after the change
+func (cm *CryptoManager) EncryptWithDataKeyOf(current, data []byte) ([]byte, error) {
+	dataKey, err := cm.DataKey(current)
+	if err != nil {
+		return nil, err
+	}
+	return ResealWithDataKey(dataKey, current, data)
+}
+
+// DecryptWithDataKey decrypts a record with its data key, without the vault key
+func DecryptWithDataKey(dataKey, encryptedData []byte) ([]byte, error) {
+	encData, err := ParseEncryptedData(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+	if encData.FormatVersion() != FormatDataKey {
+		return nil, fmt.Errorf("data is encrypted directly under the vault key")
+	}
+	return OpenWithKey(dataKey, append(append([]byte{}, encData.Nonce...), encData.Data...))
+}
+
+// ResealWithDataKey replaces the data of record current, encrypted under
+// dataKey, keeping the data key wrapped for the owner. It lets someone an
+// item is shared with change it without the owner's vault key.
+func ResealWithDataKey(dataKey, current, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("data cannot be empty")
+	}
+	encData, err := ParseEncryptedData(current)
+	if err != nil {
+		return nil, err
+	}
+	if encData.FormatVersion() != FormatDataKey {
+		return nil, fmt.Errorf("data is encrypted directly under the vault key")
+	}
+	// the key must be the one the owner's wrapped key opens
+	if _, err := OpenWithKey(dataKey, append(append([]byte{}, encData.Nonce...), encData.Data...)); err != nil {
+		return nil, fmt.Errorf("data key does not match the record: %w", err)
+	}
+
+	sealed, err := SealWithKey(dataKey, data)
+	if err != nil {
+		return nil, err
+	}
+	encData.Nonce = sealed[:aesGCMNonceSize]
+	encData.Data = sealed[aesGCMNonceSize:]
+	return marshalEncryptedData(encData)
+}
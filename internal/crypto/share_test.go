@@ -0,0 +1,69 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestShareDataKey(t *testing.T) {
+	owner, _ := NewCryptoManager("owner-master-password")
+	publicKey, privateKey, err := GenerateShareKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateShareKeyPair() error = %v", err)
+	}
+	_, otherPrivateKey, _ := GenerateShareKeyPair()
+
+	encrypted, err := owner.Encrypt([]byte("shared secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	dataKey, err := owner.DataKey(encrypted)
+	if err != nil {
+		t.Fatalf("DataKey() error = %v", err)
+	}
+	sealed, err := SealDataKey(publicKey, dataKey)
+	if err != nil {
+		t.Fatalf("SealDataKey() error = %v", err)
+	}
+
+	opened, err := OpenDataKey(privateKey, sealed)
+	if err != nil || !bytes.Equal(opened, dataKey) {
+		t.Fatalf("OpenDataKey() = %x, %v", opened, err)
+	}
+	if _, err := OpenDataKey(otherPrivateKey, sealed); err == nil {
+		t.Error("Expected error when opening with another share key")
+	}
+	if _, err := UnwrapKey(privateKey, sealed); err == nil {
+		t.Error("A sealed data key must not open as an escrowed key")
+	}
+	if plaintext, err := DecryptWithDataKey(opened, encrypted); err != nil || string(plaintext) != "shared secret" {
+		t.Errorf("DecryptWithDataKey() = %q, %v", plaintext, err)
+	}
+
+	// a recipient's change stays readable by the owner and keeps the data key
+	changed, err := ResealWithDataKey(opened, encrypted, []byte("changed secret"))
+	if err != nil {
+		t.Fatalf("ResealWithDataKey() error = %v", err)
+	}
+	if plaintext, err := owner.Decrypt(changed); err != nil || string(plaintext) != "changed secret" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+	otherKey, _ := GenerateDataKey()
+	if _, err := ResealWithDataKey(otherKey, encrypted, []byte("forged")); err == nil {
+		t.Error("Expected a data key that does not open the record to be refused")
+	}
+
+	// the owner's change keeps the data key too
+	updated, err := owner.EncryptWithDataKeyOf(changed, []byte("owner update"))
+	if err != nil {
+		t.Fatalf("EncryptWithDataKeyOf() error = %v", err)
+	}
+	if plaintext, err := DecryptWithDataKey(opened, updated); err != nil || string(plaintext) != "owner update" {
+		t.Errorf("DecryptWithDataKey() after the owner's update = %q, %v", plaintext, err)
+	}
+
+	legacy := mustEncryptVaultKey(t, owner, "legacy")
+	if _, err := owner.DataKey(legacy); err == nil {
+		t.Error("Expected no data key for data encrypted directly under the vault key")
+	}
+}
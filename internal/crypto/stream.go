@@ -0,0 +1,314 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Streams encrypt data of any length in chunks, each sealed with AES-256-GCM
+// under one random data key, so neither side holds more than a chunk in
+// memory. The data key is wrapped by the vault key in the stream header like
+// in FormatDataKey records. The header is
+//
+//	magic "GKST" | version (1) | salt (32) | iterations (uint32) |
+//	chunk size (uint32) | nonce prefix (7) | wrapped key length (uint16) | wrapped key
+//
+// followed by the sealed chunks. Every chunk but the last holds exactly chunk
+// size bytes of plaintext; the last holds fewer, possibly none. The nonce of a
+// chunk is the prefix, its big-endian index and a byte marking the last
+// chunk, and every chunk authenticates the header, so chunks cannot be
+// reordered, dropped, truncated or moved between streams unnoticed.
+const (
+	// StreamChunkSize is the plaintext size of the chunks of new streams
+	StreamChunkSize = 64 << 10
+	// maxStreamChunkSize bounds the chunk size accepted from stream headers
+	maxStreamChunkSize = 16 << 20
+
+	streamVersion         = 1
+	streamNoncePrefixSize = aesGCMNonceSize - 5
+	streamTagSize         = 16
+)
+
+// streamMagic starts every encrypted stream
+var streamMagic = []byte("GKST")
+
+// ErrStreamTruncated is returned when an encrypted stream ends before its last chunk
+var ErrStreamTruncated = errors.New("encrypted stream is truncated")
+
+// IsStream reports whether data starts like a stream of EncryptWriter rather
+// than a record of Encrypt
+func IsStream(data []byte) bool {
+	return bytes.HasPrefix(data, streamMagic)
+}
+
+// streamCipher seals or opens the chunks of one stream
+type streamCipher struct {
+	gcm    cipher.AEAD
+	header []byte
+	nonce  []byte
+	index  uint32
+}
+
+func newStreamCipher(dataKey, noncePrefix, header []byte) (*streamCipher, error) {
+	gcm, err := newDataKeyGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aesGCMNonceSize)
+	copy(nonce, noncePrefix)
+	return &streamCipher{gcm: gcm, header: header, nonce: nonce}, nil
+}
+
+// next returns the nonce of the next chunk
+func (c *streamCipher) next(last bool) ([]byte, error) {
+	if c.index == ^uint32(0) {
+		return nil, fmt.Errorf("encrypted stream has too many chunks")
+	}
+	binary.BigEndian.PutUint32(c.nonce[streamNoncePrefixSize:], c.index)
+	c.nonce[aesGCMNonceSize-1] = 0
+	if last {
+		c.nonce[aesGCMNonceSize-1] = 1
+	}
+	c.index++
+	return c.nonce, nil
+}
+
+// EncryptWriter returns a writer encrypting what is written to it into w in
+// StreamChunkSize chunks. The stream header is written right away; Close
+// writes the last chunk and must be called for the stream to be complete. It
+// does not close w.
+func (cm *CryptoManager) EncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	dataKey, err := GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := SealWithKey(cm.key, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	noncePrefix := make([]byte, streamNoncePrefixSize)
+	if _, err := rand.Read(noncePrefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	header := make([]byte, 0, len(streamMagic)+1+len(cm.salt)+8+streamNoncePrefixSize+2+len(wrapped))
+	header = append(header, streamMagic...)
+	header = append(header, streamVersion)
+	header = append(header, cm.salt...)
+	header = binary.BigEndian.AppendUint32(header, uint32(cm.Iterations()))
+	header = binary.BigEndian.AppendUint32(header, StreamChunkSize)
+	header = append(header, noncePrefix...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+
+	sc, err := newStreamCipher(dataKey, noncePrefix, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write stream header: %w", err)
+	}
+	return &streamWriter{
+		w:      w,
+		cipher: sc,
+		buf:    make([]byte, 0, StreamChunkSize+streamTagSize),
+	}, nil
+}
+
+// streamWriter encrypts chunks of what is written to it
+type streamWriter struct {
+	w      io.Writer
+	cipher *streamCipher
+	// buf holds the plaintext of the next chunk, with room for its tag
+	buf []byte
+	err error
+}
+
+// Write buffers p and writes every full chunk. A full chunk is only sealed
+// once more data follows, as the last chunk must be shorter.
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	if sw.err != nil {
+		return 0, sw.err
+	}
+	written := 0
+	for len(p) > 0 {
+		if len(sw.buf) == StreamChunkSize {
+			if sw.err = sw.flush(false); sw.err != nil {
+				return written, sw.err
+			}
+		}
+		n := min(len(p), StreamChunkSize-len(sw.buf))
+		sw.buf = append(sw.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close writes the buffered data as the last chunk, after another, empty
+// chunk when the buffer is full
+func (sw *streamWriter) Close() error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if len(sw.buf) == StreamChunkSize {
+		if sw.err = sw.flush(false); sw.err != nil {
+			return sw.err
+		}
+	}
+	if sw.err = sw.flush(true); sw.err != nil {
+		return sw.err
+	}
+	sw.err = fmt.Errorf("encrypted stream is closed")
+	return nil
+}
+
+// flush seals and writes the buffered chunk
+func (sw *streamWriter) flush(last bool) error {
+	nonce, err := sw.cipher.next(last)
+	if err != nil {
+		return err
+	}
+	sealed := sw.cipher.gcm.Seal(sw.buf[:0], nonce, sw.buf, sw.cipher.header)
+	if _, err := sw.w.Write(sealed); err != nil {
+		return fmt.Errorf("failed to write encrypted chunk: %w", err)
+	}
+	sw.buf = sw.buf[:0]
+	return nil
+}
+
+// DecryptReader reads the header of a stream written by EncryptWriter from r
+// and returns a reader of its plaintext. Each chunk is authenticated before
+// it is returned, but a stream is only known to be complete once the reader
+// returns io.EOF, so data read before an error must not be trusted as whole.
+func (cm *CryptoManager) DecryptReader(r io.Reader) (io.Reader, error) {
+	fixed := make([]byte, len(streamMagic)+1+32+8+streamNoncePrefixSize+2)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, fmt.Errorf("failed to read stream header: %w", err)
+	}
+	if !bytes.Equal(fixed[:len(streamMagic)], streamMagic) {
+		return nil, fmt.Errorf("not an encrypted stream")
+	}
+	if version := fixed[len(streamMagic)]; version != streamVersion {
+		return nil, fmt.Errorf("unsupported encrypted stream version %d", version)
+	}
+	rest := fixed[len(streamMagic)+1:]
+	salt, rest := rest[:32], rest[32:]
+	iterations := int(binary.BigEndian.Uint32(rest))
+	chunkSize := int(binary.BigEndian.Uint32(rest[4:]))
+	noncePrefix := rest[8 : 8+streamNoncePrefixSize]
+	wrappedLen := int(binary.BigEndian.Uint16(rest[8+streamNoncePrefixSize:]))
+	if chunkSize <= 0 || chunkSize > maxStreamChunkSize {
+		return nil, fmt.Errorf("invalid chunk size %d in stream header", chunkSize)
+	}
+
+	header := make([]byte, len(fixed)+wrappedLen)
+	copy(header, fixed)
+	if _, err := io.ReadFull(r, header[len(fixed):]); err != nil {
+		return nil, fmt.Errorf("failed to read stream header: %w", err)
+	}
+
+	key, err := cm.keyFor(salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := OpenWithKey(key, header[len(fixed):])
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	sc, err := newStreamCipher(dataKey, noncePrefix, header)
+	if err != nil {
+		return nil, err
+	}
+	return &streamReader{
+		r:      r,
+		cipher: sc,
+		buf:    make([]byte, chunkSize+streamTagSize),
+	}, nil
+}
+
+// streamReader decrypts the chunks of a stream as they are read
+type streamReader struct {
+	r      io.Reader
+	cipher *streamCipher
+	// buf holds a sealed chunk as read, then its plaintext
+	buf []byte
+	// plaintext is what is left of the last opened chunk
+	plaintext []byte
+	done      bool
+	err       error
+}
+
+// Read returns the plaintext of the chunks in order
+func (sr *streamReader) Read(p []byte) (int, error) {
+	for len(sr.plaintext) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		if sr.done {
+			return 0, io.EOF
+		}
+		sr.err = sr.open()
+	}
+	n := copy(p, sr.plaintext)
+	sr.plaintext = sr.plaintext[n:]
+	return n, nil
+}
+
+// open reads and opens the next chunk. A full chunk is never the last, a
+// shorter one always is.
+func (sr *streamReader) open() error {
+	n, err := io.ReadFull(sr.r, sr.buf)
+	last := false
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	default:
+		return fmt.Errorf("failed to read encrypted chunk: %w", err)
+	}
+	if n < streamTagSize {
+		return ErrStreamTruncated
+	}
+
+	nonce, err := sr.cipher.next(last)
+	if err != nil {
+		return err
+	}
+	plaintext, err := sr.cipher.gcm.Open(sr.buf[:0], nonce, sr.buf[:n], sr.cipher.header)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt chunk %d: %w", sr.cipher.index-1, err)
+	}
+	sr.plaintext = plaintext
+	sr.done = last
+	return nil
+}
+
+// EncryptStream encrypts everything read from src into dst and returns the
+// number of plaintext bytes
+func (cm *CryptoManager) EncryptStream(dst io.Writer, src io.Reader) (int64, error) {
+	w, err := cm.EncryptWriter(dst)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(w, src)
+	if err != nil {
+		return n, err
+	}
+	return n, w.Close()
+}
+
+// DecryptStream decrypts a stream of EncryptStream read from src into dst
+// and returns the number of plaintext bytes
+func (cm *CryptoManager) DecryptStream(dst io.Writer, src io.Reader) (int64, error) {
+	r, err := cm.DecryptReader(src)
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(dst, r)
+}
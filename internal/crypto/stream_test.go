@@ -0,0 +1,189 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestEncryptDecryptStream(t *testing.T) {
+	cm, err := NewCryptoManager("testpassword123")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+
+	sizes := []int{0, 1, StreamChunkSize - 1, StreamChunkSize, StreamChunkSize + 1, 3*StreamChunkSize + 5}
+	for _, size := range sizes {
+		plaintext := make([]byte, size)
+		if _, err := rand.Read(plaintext); err != nil {
+			t.Fatalf("rand.Read() error = %v", err)
+		}
+
+		var encrypted bytes.Buffer
+		n, err := cm.EncryptStream(&encrypted, bytes.NewReader(plaintext))
+		if err != nil {
+			t.Fatalf("EncryptStream(%d bytes) error = %v", size, err)
+		}
+		if n != int64(size) {
+			t.Errorf("EncryptStream() = %d, want %d", n, size)
+		}
+
+		var decrypted bytes.Buffer
+		if _, err := cm.DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes())); err != nil {
+			t.Fatalf("DecryptStream(%d bytes) error = %v", size, err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plaintext) {
+			t.Errorf("DecryptStream(%d bytes) returned different data", size)
+		}
+	}
+}
+
+func TestEncryptWriter_SmallWrites(t *testing.T) {
+	cm, err := NewCryptoManager("testpassword123")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+
+	plaintext := bytes.Repeat([]byte("0123456789"), StreamChunkSize/5)
+	var encrypted bytes.Buffer
+	w, err := cm.EncryptWriter(&encrypted)
+	if err != nil {
+		t.Fatalf("EncryptWriter() error = %v", err)
+	}
+	for i := 0; i < len(plaintext); i += 7 {
+		if _, err := w.Write(plaintext[i:min(i+7, len(plaintext))]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := w.Write([]byte("late")); err == nil {
+		t.Error("Expected error writing to a closed stream")
+	}
+
+	r, err := cm.DecryptReader(&encrypted)
+	if err != nil {
+		t.Fatalf("DecryptReader() error = %v", err)
+	}
+	decrypted, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Error("Decrypted stream differs from the written data")
+	}
+}
+
+func TestDecryptStream_Errors(t *testing.T) {
+	cm, err := NewCryptoManager("testpassword123")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	plaintext := bytes.Repeat([]byte("x"), 2*StreamChunkSize+100)
+	var buf bytes.Buffer
+	if _, err := cm.EncryptStream(&buf, bytes.NewReader(plaintext)); err != nil {
+		t.Fatalf("EncryptStream() error = %v", err)
+	}
+	encrypted := buf.Bytes()
+	headerSize := len(encrypted) - 2*(StreamChunkSize+streamTagSize) - (100 + streamTagSize)
+	chunk := func(i int) []byte {
+		start := headerSize + i*(StreamChunkSize+streamTagSize)
+		return encrypted[start:min(start+StreamChunkSize+streamTagSize, len(encrypted))]
+	}
+
+	other, err := NewCryptoManagerWithSalt("otherpassword123", cm.GetSalt())
+	if err != nil {
+		t.Fatalf("NewCryptoManagerWithSalt() error = %v", err)
+	}
+
+	tampered := bytes.Clone(encrypted)
+	tampered[headerSize+10] ^= 1
+
+	reordered := bytes.Clone(encrypted[:headerSize])
+	reordered = append(reordered, chunk(1)...)
+	reordered = append(reordered, chunk(0)...)
+	reordered = append(reordered, chunk(2)...)
+
+	tests := []struct {
+		name      string
+		cm        *CryptoManager
+		stream    []byte
+		truncated bool
+	}{
+		{name: "not a stream", cm: cm, stream: []byte(`{"version":2}`)},
+		{name: "wrong master password", cm: other, stream: encrypted},
+		{name: "tampered chunk", cm: cm, stream: tampered},
+		{name: "reordered chunks", cm: cm, stream: reordered},
+		{name: "cut after a chunk", cm: cm, stream: encrypted[:headerSize+2*(StreamChunkSize+streamTagSize)], truncated: true},
+		{name: "cut in a chunk", cm: cm, stream: encrypted[:len(encrypted)-10]},
+		{name: "cut in the header", cm: cm, stream: encrypted[:headerSize-1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cm.DecryptStream(io.Discard, bytes.NewReader(tt.stream))
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if tt.truncated && !errors.Is(err, ErrStreamTruncated) {
+				t.Errorf("Expected ErrStreamTruncated, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDecryptStream_OtherSalt(t *testing.T) {
+	oldManager, err := NewCryptoManager("testpassword123")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	var encrypted bytes.Buffer
+	if _, err := oldManager.EncryptStream(&encrypted, bytes.NewReader([]byte("secret file"))); err != nil {
+		t.Fatalf("EncryptStream() error = %v", err)
+	}
+
+	cm, err := NewCryptoManager("testpassword123")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	var decrypted bytes.Buffer
+	if _, err := cm.DecryptStream(&decrypted, &encrypted); err != nil {
+		t.Fatalf("DecryptStream() error = %v", err)
+	}
+	if decrypted.String() != "secret file" {
+		t.Errorf("DecryptStream() = %q, want %q", decrypted.String(), "secret file")
+	}
+}
+
+func TestRewrap_Stream(t *testing.T) {
+	current, err := NewCryptoManager("old password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	next, err := NewCryptoManager("new password")
+	if err != nil {
+		t.Fatalf("NewCryptoManager() error = %v", err)
+	}
+	content := bytes.Repeat([]byte("secret file "), StreamChunkSize/4)
+	var encrypted bytes.Buffer
+	if _, err := current.EncryptStream(&encrypted, bytes.NewReader(content)); err != nil {
+		t.Fatalf("EncryptStream() error = %v", err)
+	}
+	if !IsStream(encrypted.Bytes()) || IsStream(mustEncrypt(t, current, "secret")) {
+		t.Fatal("Expected IsStream to tell streams from records")
+	}
+
+	rewrapped, err := current.Rewrap(encrypted.Bytes(), next)
+	if err != nil {
+		t.Fatalf("Rewrap() error = %v", err)
+	}
+	var decrypted bytes.Buffer
+	if _, err := next.DecryptStream(&decrypted, bytes.NewReader(rewrapped)); err != nil || !bytes.Equal(decrypted.Bytes(), content) {
+		t.Errorf("Expected the next key to decrypt, got %d bytes, %v", decrypted.Len(), err)
+	}
+	if _, err := current.DecryptStream(io.Discard, bytes.NewReader(rewrapped)); err == nil {
+		t.Error("Expected the current key to no longer decrypt")
+	}
+}
@@ -1,42 +1,184 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/a2sh3r/gophkeeper/internal/logger"
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 )
 
+// Options tunes the connection pool. Zero values keep the defaults of
+// database/sql, or of pgxpool when NativePool is set.
+type Options struct {
+	MaxOpenConns int
+	// MaxIdleConns is ignored with NativePool, which closes idle connections
+	// after ConnMaxIdleTime instead
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// StatementTimeout aborts statements running longer on the server
+	StatementTimeout time.Duration
+	// NativePool manages connections with pgxpool rather than database/sql,
+	// which performs better under concurrent load
+	NativePool bool
+	// ReplicaDSNs are read replicas of the database, each with a pool tuned
+	// like the primary's
+	ReplicaDSNs []string
+}
+
 // DB represents database connection and operations
 type DB struct {
 	conn *sql.DB
+	// pool is set with Options.NativePool
+	pool *pgxpool.Pool
+	// replicas are the read replicas; next picks the one of the next read
+	replicas []*DB
+	next     atomic.Uint32
 }
 
 // New creates new database connection
 func New(dsn string) (*DB, error) {
-	conn, err := sql.Open("pgx", dsn)
+	return NewWithOptions(dsn, Options{})
+}
+
+// NewWithOptions creates new database connection with a tuned pool
+func NewWithOptions(dsn string, opts Options) (*DB, error) {
+	db, err := open(dsn, opts)
 	if err != nil {
 		logger.Log.Error("Failed to open database connection", zap.Error(err))
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := conn.Ping(); err != nil {
+	if err := db.conn.Ping(); err != nil {
 		logger.Log.Error("Failed to ping database", zap.Error(err))
+		_ = db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	logger.Log.Info("Database connection established")
+	for i, replicaDSN := range opts.ReplicaDSNs {
+		replica, err := open(replicaDSN, opts)
+		if err != nil {
+			logger.Log.Error("Failed to open read replica connection", zap.Error(err), zap.Int("replica", i))
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to open read replica %d: %w", i, err)
+		}
+		db.replicas = append(db.replicas, replica)
+		if err := replica.conn.Ping(); err != nil {
+			logger.Log.Error("Failed to ping read replica", zap.Error(err), zap.Int("replica", i))
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to ping read replica %d: %w", i, err)
+		}
+	}
+
+	logger.Log.Info("Database connection established", zap.Bool("native_pool", opts.NativePool),
+		zap.Int("max_open_conns", opts.MaxOpenConns), zap.Int("replicas", len(db.replicas)))
+	return db, nil
+}
+
+// open creates the connection pool without connecting
+func open(dsn string, opts Options) (*DB, error) {
+	if opts.NativePool {
+		config, err := poolConfig(dsn, opts)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := pgxpool.NewWithConfig(context.Background(), config)
+		if err != nil {
+			return nil, err
+		}
+		return &DB{conn: stdlib.OpenDBFromPool(pool), pool: pool}, nil
+	}
+
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	setStatementTimeout(config, opts.StatementTimeout)
+	conn := stdlib.OpenDB(*config)
+	if opts.MaxOpenConns > 0 {
+		conn.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		conn.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		conn.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		conn.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
 	return &DB{conn: conn}, nil
 }
 
-// Close closes database connection
+// poolConfig parses dsn into a pgxpool configuration with opts applied
+func poolConfig(dsn string, opts Options) (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	setStatementTimeout(config.ConnConfig, opts.StatementTimeout)
+	if opts.MaxOpenConns > 0 {
+		config.MaxConns = int32(opts.MaxOpenConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		config.MaxConnLifetime = opts.ConnMaxLifetime
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		config.MaxConnIdleTime = opts.ConnMaxIdleTime
+	}
+	return config, nil
+}
+
+// setStatementTimeout sets the statement_timeout of new sessions, unless the
+// DSN already sets one
+func setStatementTimeout(config *pgx.ConnConfig, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	if _, ok := config.RuntimeParams["statement_timeout"]; ok {
+		return
+	}
+	config.RuntimeParams["statement_timeout"] = strconv.FormatInt(timeout.Milliseconds(), 10)
+}
+
+// Close closes database connection and those of the read replicas
 func (db *DB) Close() error {
-	return db.conn.Close()
+	err := db.conn.Close()
+	if db.pool != nil {
+		db.pool.Close()
+	}
+	for _, replica := range db.replicas {
+		if replicaErr := replica.Close(); err == nil {
+			err = replicaErr
+		}
+	}
+	return err
 }
 
 // Conn returns database connection
 func (db *DB) Conn() *sql.DB {
 	return db.conn
 }
+
+// Replica returns the connection of a read replica, taking turns between
+// them, or that of the primary without replicas
+func (db *DB) Replica() *sql.DB {
+	if len(db.replicas) == 0 {
+		return db.conn
+	}
+	return db.replicas[int(db.next.Add(1)-1)%len(db.replicas)].conn
+}
+
+// Pool returns the native pool for direct use of pgx, or nil without
+// Options.NativePool
+func (db *DB) Pool() *pgxpool.Pool {
+	return db.pool
+}
@@ -0,0 +1,5 @@
+-- Remove environment column from data table
+DROP INDEX IF EXISTS idx_data_user_id_environment;
+
+ALTER TABLE data 
+DROP COLUMN IF EXISTS environment;
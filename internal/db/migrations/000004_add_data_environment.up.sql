@@ -0,0 +1,5 @@
+-- Add environment column to data table
+ALTER TABLE data 
+ADD COLUMN environment VARCHAR(32) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_data_user_id_environment ON data(user_id, environment);
@@ -0,0 +1,9 @@
+-- Published item fields, encrypted client-side under a standalone data key
+CREATE TABLE IF NOT EXISTS data_fields (
+    data_id UUID NOT NULL REFERENCES data(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    ciphertext BYTEA NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (data_id, name)
+);
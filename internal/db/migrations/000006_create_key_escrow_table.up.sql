@@ -0,0 +1,7 @@
+-- Vault keys wrapped under the organization recovery key, present only while users consent
+CREATE TABLE IF NOT EXISTS key_escrow (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    wrapped_key BYTEA NOT NULL,
+    recovery_key_id VARCHAR(64) NOT NULL,
+    consented_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- Optional self-written master password hint, stored as plaintext by the user's explicit choice
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hint VARCHAR(200) NOT NULL DEFAULT '';
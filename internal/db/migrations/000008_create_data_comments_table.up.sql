@@ -0,0 +1,9 @@
+-- Timestamped item comments, encrypted client-side with the vault key
+CREATE TABLE IF NOT EXISTS data_comments (
+    id UUID PRIMARY KEY,
+    data_id UUID NOT NULL REFERENCES data(id) ON DELETE CASCADE,
+    ciphertext BYTEA NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_comments_data_id ON data_comments(data_id, created_at);
@@ -0,0 +1,14 @@
+-- Per-user audit log. Item names and client addresses are sealed to the user's
+-- audit public key, so stored events do not reveal vault structure to operators.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS audit_public_key BYTEA;
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action VARCHAR(50) NOT NULL,
+    data_id UUID,
+    sealed BYTEA,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id, created_at);
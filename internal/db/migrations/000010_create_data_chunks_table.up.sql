@@ -0,0 +1,7 @@
+-- Content of large binary items, uploaded in chunks encrypted client-side
+CREATE TABLE IF NOT EXISTS data_chunks (
+    data_id UUID NOT NULL REFERENCES data(id) ON DELETE CASCADE,
+    chunk_index INTEGER NOT NULL,
+    data BYTEA NOT NULL,
+    PRIMARY KEY (data_id, chunk_index)
+);
@@ -0,0 +1,4 @@
+-- TOTP secrets for generating one-time codes
+ALTER TABLE data DROP CONSTRAINT IF EXISTS data_type_check;
+ALTER TABLE data ADD CONSTRAINT data_type_check
+    CHECK (type IN ('login_password', 'text', 'binary', 'bank_card', 'otp'));
@@ -0,0 +1,13 @@
+-- Previous versions of items, kept on every update so edits can be reverted
+CREATE TABLE IF NOT EXISTS data_versions (
+    data_id UUID NOT NULL REFERENCES data(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    data BYTEA NOT NULL,
+    metadata TEXT,
+    environment VARCHAR(32) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (data_id, version)
+);
@@ -0,0 +1,3 @@
+-- Write counter of each item, the ETag that updates are conditional on
+ALTER TABLE data
+ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_data_metadata_trgm;
+DROP INDEX IF EXISTS idx_data_description_trgm;
+DROP INDEX IF EXISTS idx_data_name_trgm;
@@ -0,0 +1,5 @@
+-- Trigram indexes for case-insensitive substring search over items
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_data_name_trgm ON data USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_data_description_trgm ON data USING gin (description gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_data_metadata_trgm ON data USING gin (metadata gin_trgm_ops);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_data_tags;
+ALTER TABLE data DROP COLUMN IF EXISTS tags;
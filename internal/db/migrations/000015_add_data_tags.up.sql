@@ -0,0 +1,4 @@
+-- Tags for organizing items, as a JSONB array of lowercase strings
+ALTER TABLE data ADD COLUMN tags JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS idx_data_tags ON data USING gin (tags);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_data_collection_id;
+ALTER TABLE data DROP COLUMN IF EXISTS collection_id;
+DROP TABLE IF EXISTS collections;
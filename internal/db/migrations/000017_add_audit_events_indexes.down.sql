@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_audit_events_data_id;
+DROP INDEX IF EXISTS idx_audit_events_created_at;
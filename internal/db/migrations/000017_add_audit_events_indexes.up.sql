@@ -0,0 +1,3 @@
+-- Admin audit queries filter across users by time, action or item
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_data_id ON audit_events(data_id, created_at);
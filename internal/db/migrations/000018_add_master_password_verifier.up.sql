@@ -0,0 +1,3 @@
+-- A known value encrypted under the vault key, so clients can check a master
+-- password right after login instead of failing on the first decrypt
+ALTER TABLE users ADD COLUMN IF NOT EXISTS master_verifier BYTEA;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS shares;
+ALTER TABLE users DROP COLUMN IF EXISTS share_private_key;
+ALTER TABLE users DROP COLUMN IF EXISTS share_public_key;
@@ -0,0 +1,19 @@
+-- X25519 key pairs items are shared to; the private key is encrypted under
+-- the owner's vault key
+ALTER TABLE users ADD COLUMN IF NOT EXISTS share_public_key BYTEA;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS share_private_key BYTEA;
+
+-- Items shared with other users, each grant holding the item's data key
+-- sealed to the recipient's share public key
+CREATE TABLE IF NOT EXISTS shares (
+    id UUID PRIMARY KEY,
+    data_id UUID NOT NULL REFERENCES data(id) ON DELETE CASCADE,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mode VARCHAR(10) NOT NULL,
+    sealed_key BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (data_id, recipient_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shares_recipient_id ON shares(recipient_id);
@@ -0,0 +1,2 @@
+ALTER TABLE data_versions DROP COLUMN IF EXISTS checksum;
+ALTER TABLE data DROP COLUMN IF EXISTS checksum;
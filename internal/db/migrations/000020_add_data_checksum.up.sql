@@ -0,0 +1,4 @@
+-- HMAC of the plaintext of each item, computed by the client under a key derived
+-- from the item's data key, so silent corruption or tampering can be detected
+ALTER TABLE data ADD COLUMN IF NOT EXISTS checksum BYTEA;
+ALTER TABLE data_versions ADD COLUMN IF NOT EXISTS checksum BYTEA;
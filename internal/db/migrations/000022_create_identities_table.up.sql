@@ -0,0 +1,11 @@
+-- Accounts of external identity providers (OIDC) linked to users, so single
+-- sign-on logins map the subject of the ID token to a local user
+CREATE TABLE IF NOT EXISTS identities (
+    issuer VARCHAR(512) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_identities_user_id ON identities(user_id);
@@ -0,0 +1,5 @@
+-- Registrable domains of the URLs of login items, as a JSONB array of
+-- lowercase strings, so logins can be looked up by site without decrypting them
+ALTER TABLE data ADD COLUMN domains JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS idx_data_domains ON data USING gin (domains);
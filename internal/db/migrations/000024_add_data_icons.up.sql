@@ -0,0 +1,2 @@
+-- Favicons of login items, encrypted by the client like the item itself
+ALTER TABLE data ADD COLUMN icon BYTEA;
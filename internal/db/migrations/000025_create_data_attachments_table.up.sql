@@ -0,0 +1,11 @@
+-- Files attached to items; their descriptions and content are encrypted client-side with the vault key
+CREATE TABLE IF NOT EXISTS data_attachments (
+    id UUID PRIMARY KEY,
+    data_id UUID NOT NULL REFERENCES data(id) ON DELETE CASCADE,
+    info BYTEA NOT NULL,
+    size BIGINT NOT NULL,
+    content BYTEA NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_attachments_data_id ON data_attachments(data_id, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_data_expires_at;
+ALTER TABLE data DROP COLUMN IF EXISTS expiry;
+ALTER TABLE data DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,6 @@
+-- When an item expires, such as a card or a certificate, and the flag the
+-- server keeps for items that are expiring or expired
+ALTER TABLE data ADD COLUMN expires_at TIMESTAMP;
+ALTER TABLE data ADD COLUMN expiry VARCHAR(16) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_data_expires_at ON data (expires_at) WHERE expires_at IS NOT NULL;
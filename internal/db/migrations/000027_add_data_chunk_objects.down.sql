@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS data_chunks_object_deletion ON data_chunks;
+DROP FUNCTION IF EXISTS queue_chunk_object_deletion();
+DROP TABLE IF EXISTS blob_deletions;
+-- chunks held in the bucket cannot be kept without their object keys
+DELETE FROM data_chunks WHERE object_key IS NOT NULL;
+ALTER TABLE data_chunks DROP CONSTRAINT IF EXISTS data_chunks_content;
+ALTER TABLE data_chunks DROP COLUMN IF EXISTS object_key;
+ALTER TABLE data_chunks ALTER COLUMN data SET NOT NULL;
@@ -0,0 +1,24 @@
+-- Chunks of binary items may be stored as objects of an S3-compatible bucket,
+-- keeping only their object key here
+ALTER TABLE data_chunks ALTER COLUMN data DROP NOT NULL;
+ALTER TABLE data_chunks ADD COLUMN object_key TEXT;
+ALTER TABLE data_chunks ADD CONSTRAINT data_chunks_content CHECK ((data IS NULL) <> (object_key IS NULL));
+
+-- Objects of deleted or replaced chunks, queued in the same transaction and
+-- removed from the bucket by the server afterwards
+CREATE TABLE IF NOT EXISTS blob_deletions (
+    object_key TEXT PRIMARY KEY,
+    queued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE OR REPLACE FUNCTION queue_chunk_object_deletion() RETURNS trigger AS $$
+BEGIN
+    IF OLD.object_key IS NOT NULL AND (TG_OP = 'DELETE' OR NEW.object_key IS DISTINCT FROM OLD.object_key) THEN
+        INSERT INTO blob_deletions (object_key) VALUES (OLD.object_key) ON CONFLICT DO NOTHING;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER data_chunks_object_deletion AFTER UPDATE OR DELETE ON data_chunks
+    FOR EACH ROW EXECUTE FUNCTION queue_chunk_object_deletion();
@@ -0,0 +1,3 @@
+-- The PBKDF2 iteration count clients derive the vault key of a user with,
+-- 0 meaning the default count
+ALTER TABLE users ADD COLUMN kdf_iterations INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,139 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+)
+
+func TestAll(t *testing.T) {
+	migrations, err := All()
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("Expected migration %d to have version %d, got %d (%s)", i, i+1, m.Version, m.Name)
+		}
+		if strings.TrimSpace(m.Up) == "" {
+			t.Errorf("Migration %d_%s is empty", m.Version, m.Name)
+		}
+	}
+	if latest := migrations[len(migrations)-1].Version; latest != storage.SchemaVersion {
+		t.Errorf("Latest migration is %d, but storage.SchemaVersion is %d", latest, storage.SchemaVersion)
+	}
+}
+
+func TestUp(t *testing.T) {
+	migrations, err := All()
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	latest := migrations[len(migrations)-1].Version
+
+	expectApplied := func(mock sqlmock.Sqlmock, m Migration) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(m.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM schema_migrations").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(m.Version).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	tests := []struct {
+		name        string
+		mockSetup   func(sqlmock.Sqlmock)
+		wantVersion int
+		wantErr     string
+	}{
+		{
+			name: "fresh database",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}))
+				for _, m := range migrations {
+					expectApplied(mock, m)
+				}
+			},
+			wantVersion: latest,
+		},
+		{
+			name: "one migration behind",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(latest-1, false))
+				expectApplied(mock, migrations[len(migrations)-1])
+			},
+			wantVersion: latest,
+		},
+		{
+			name: "up to date",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(latest, false))
+			},
+			wantVersion: latest,
+		},
+		{
+			name: "dirty",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(3, true))
+			},
+			wantVersion: 3,
+			wantErr:     "dirty",
+		},
+		{
+			name: "failed migration is rolled back",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(latest-2, false))
+				expectApplied(mock, migrations[len(migrations)-2])
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(migrations[len(migrations)-1].Up)).WillReturnError(fmt.Errorf("syntax error"))
+				mock.ExpectRollback()
+			},
+			wantVersion: latest - 1,
+			wantErr:     "syntax error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Failed to create mock: %v", err)
+			}
+			defer func() {
+				_ = db.Close()
+			}()
+
+			mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+			tt.mockSetup(mock)
+			mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			version, err := Up(context.Background(), db)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Up() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Up() error = %v, want %q", err, tt.wantErr)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("Up() version = %d, want %d", version, tt.wantVersion)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
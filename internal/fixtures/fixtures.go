@@ -0,0 +1,140 @@
+// Package fixtures holds the golden request and response bodies of every API
+// endpoint. Server tests check that responses match the golden files and client
+// tests check that requests do, so any protocol change shows up as a golden diff.
+//
+// Regenerate the files after an intended change with "make golden", which runs
+// the client tests first since the server tests replay the golden requests.
+package fixtures
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+// Fixed values used in golden files in place of generated ones
+const (
+	UserID     = "00000000-0000-4000-8000-000000000001"
+	DataID     = "00000000-0000-4000-8000-000000000002"
+	CommentID  = "00000000-0000-4000-8000-000000000003"
+	ImportID   = "00000000-0000-4000-8000-000000000004"
+	Time       = "2024-01-01T00:00:00Z"
+	Token      = "fixture-token"
+	Version    = "0.0.0-fixture"
+	FieldName  = "password"
+	AdminToken = "fixture-admin-token"
+
+	Username       = "fixture-user"
+	Password       = "fixture-password"
+	MasterPassword = "fixture-master-password"
+	Hint           = "the usual one, twice"
+)
+
+// Fixed binary values sent by the fixture requests
+var (
+	Salt              = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x01}, 32))
+	RecoveryPublicKey = bytes.Repeat([]byte{0x02}, 32)
+	AuditPublicKey    = bytes.Repeat([]byte{0x03}, 32)
+	WrappedKey        = []byte("fixture-wrapped-key")
+	Ciphertext        = []byte("fixture-ciphertext")
+	FieldCiphertext   = []byte("fixture-field-ciphertext")
+	CommentCiphertext = []byte("fixture-comment-ciphertext")
+)
+
+// Endpoint describes one API exchange with golden files named after it
+type Endpoint struct {
+	Name   string
+	Method string
+	// Path may contain the {id}, {name} and {username} route variables
+	Path   string
+	Status int
+	// HasRequest and HasResponse tell whether the exchange has a JSON or NDJSON body
+	HasRequest  bool
+	HasResponse bool
+}
+
+// Endpoints lists every API exchange in an order that works as a scenario:
+// each one only depends on state created by those before it
+var Endpoints = []Endpoint{
+	{Name: "status", Method: http.MethodGet, Path: "/api/v1/status", Status: http.StatusOK, HasResponse: true},
+	{Name: "register", Method: http.MethodPost, Path: "/api/v1/register", Status: http.StatusOK, HasRequest: true, HasResponse: true},
+	{Name: "login", Method: http.MethodPost, Path: "/api/v1/login", Status: http.StatusOK, HasRequest: true, HasResponse: true},
+	{Name: "salt.get", Method: http.MethodGet, Path: "/api/v1/salt", Status: http.StatusOK, HasResponse: true},
+	{Name: "salt.set", Method: http.MethodPut, Path: "/api/v1/salt", Status: http.StatusOK, HasRequest: true, HasResponse: true},
+	{Name: "data.create", Method: http.MethodPost, Path: "/api/v1/data", Status: http.StatusCreated, HasRequest: true, HasResponse: true},
+	{Name: "data.list", Method: http.MethodGet, Path: "/api/v1/data", Status: http.StatusOK, HasResponse: true},
+	{Name: "data.get", Method: http.MethodGet, Path: "/api/v1/data/{id}", Status: http.StatusOK, HasResponse: true},
+	{Name: "data.update", Method: http.MethodPut, Path: "/api/v1/data/{id}", Status: http.StatusOK, HasRequest: true, HasResponse: true},
+	{Name: "field.set", Method: http.MethodPut, Path: "/api/v1/data/{id}/field/{name}", Status: http.StatusNoContent, HasRequest: true},
+	{Name: "token.create", Method: http.MethodPost, Path: "/api/v1/tokens", Status: http.StatusCreated, HasRequest: true, HasResponse: true},
+	{Name: "field.get", Method: http.MethodGet, Path: "/api/v1/data/{id}/field/{name}", Status: http.StatusOK, HasResponse: true},
+	{Name: "comment.add", Method: http.MethodPost, Path: "/api/v1/data/{id}/comments", Status: http.StatusCreated, HasRequest: true, HasResponse: true},
+	{Name: "comment.list", Method: http.MethodGet, Path: "/api/v1/data/{id}/comments", Status: http.StatusOK, HasResponse: true},
+	{Name: "data.import", Method: http.MethodPost, Path: "/api/v1/data/import", Status: http.StatusOK, HasRequest: true, HasResponse: true},
+	{Name: "hint.set", Method: http.MethodPut, Path: "/api/v1/hint", Status: http.StatusNoContent, HasRequest: true},
+	{Name: "hint.get", Method: http.MethodGet, Path: "/api/v1/hint", Status: http.StatusOK, HasResponse: true},
+	{Name: "hint.delete", Method: http.MethodDelete, Path: "/api/v1/hint", Status: http.StatusNoContent},
+	{Name: "escrow.recovery-key", Method: http.MethodGet, Path: "/api/v1/escrow/recovery-key", Status: http.StatusOK, HasResponse: true},
+	{Name: "escrow.set", Method: http.MethodPut, Path: "/api/v1/escrow", Status: http.StatusNoContent, HasRequest: true},
+	{Name: "escrow.status", Method: http.MethodGet, Path: "/api/v1/escrow", Status: http.StatusOK, HasResponse: true},
+	{Name: "escrow.recovery", Method: http.MethodGet, Path: "/api/v1/admin/escrow/{username}", Status: http.StatusOK, HasResponse: true},
+	{Name: "escrow.delete", Method: http.MethodDelete, Path: "/api/v1/escrow", Status: http.StatusNoContent},
+	{Name: "audit.key.set", Method: http.MethodPut, Path: "/api/v1/audit/key", Status: http.StatusNoContent, HasRequest: true},
+	{Name: "audit.list", Method: http.MethodGet, Path: "/api/v1/audit", Status: http.StatusOK, HasResponse: true},
+	{Name: "vault.lock", Method: http.MethodPost, Path: "/api/v1/vault/lock", Status: http.StatusOK, HasRequest: true, HasResponse: true},
+	{Name: "vault.unlock", Method: http.MethodPost, Path: "/api/v1/vault/unlock", Status: http.StatusNoContent, HasRequest: true},
+	{Name: "data.delete", Method: http.MethodDelete, Path: "/api/v1/data/{id}", Status: http.StatusNoContent},
+	{Name: "maintenance.set", Method: http.MethodPut, Path: "/api/v1/admin/maintenance", Status: http.StatusOK, HasRequest: true, HasResponse: true},
+	{Name: "maintenance.get", Method: http.MethodGet, Path: "/api/v1/admin/maintenance", Status: http.StatusOK, HasResponse: true},
+}
+
+// Target returns the endpoint path with route variables filled in
+func (e Endpoint) Target(dataID string) string {
+	return strings.NewReplacer("{id}", dataID, "{name}", FieldName, "{username}", Username).Replace(e.Path)
+}
+
+// CreateDataRequest is the item created by the data.create fixture
+func CreateDataRequest() models.DataRequest {
+	return models.DataRequest{
+		Type:        models.DataTypeLoginPassword,
+		Name:        "Fixture login",
+		Description: "Created by the golden API fixtures",
+		Data:        Ciphertext,
+		Metadata:    `{"url":"https://example.com"}`,
+		Environment: "prod",
+	}
+}
+
+// UpdateDataRequest is the change sent by the data.update fixture
+func UpdateDataRequest() models.DataRequest {
+	req := CreateDataRequest()
+	req.Name = "Fixture login (renamed)"
+	req.Environment = "staging"
+	revision := 1
+	req.BaseRevision = &revision
+	return req
+}
+
+// ImportRecords are the records streamed by the data.import fixture; the second
+// one is rejected, so the response has a result of each kind
+func ImportRecords() []models.ImportRecord {
+	imported := CreateDataRequest()
+	imported.Name = "Fixture import"
+	rejected := CreateDataRequest()
+	rejected.Type = "unknown"
+	return []models.ImportRecord{{Seq: 1, Data: imported}, {Seq: 2, Data: rejected}}
+}
+
+// ScopedTokenRequest is the token requested by the token.create fixture
+func ScopedTokenRequest() models.ScopedTokenRequest {
+	return models.ScopedTokenRequest{
+		DataID:     uuid.MustParse(DataID),
+		Field:      FieldName,
+		TTLSeconds: int64(time.Hour / time.Second),
+	}
+}
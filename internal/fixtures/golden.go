@@ -0,0 +1,143 @@
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// updateEnv regenerates golden files instead of comparing against them when set to 1
+const updateEnv = "UPDATE_GOLDEN"
+
+// volatileKeys maps JSON keys holding generated values to their fixed replacements
+var volatileKeys = map[string]string{
+	"created_at":   Time,
+	"updated_at":   Time,
+	"consented_at": Time,
+	"expires_at":   Time,
+	"token":        Token,
+	"salt":         Salt,
+	"version":      Version,
+}
+
+// goldenDir returns the directory of the golden files in the source tree
+func goldenDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "golden")
+}
+
+// Request returns the golden request body of an endpoint
+func Request(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(goldenDir(), name+".request.json"))
+}
+
+// Response returns the golden response body of an endpoint
+func Response(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(goldenDir(), name+".response.json"))
+}
+
+// CheckRequest compares a request body with the endpoint's golden request
+func CheckRequest(t testing.TB, name string, body []byte, ids map[string]string) {
+	t.Helper()
+	check(t, name+".request.json", body, ids)
+}
+
+// CheckResponse compares a response body with the endpoint's golden response
+func CheckResponse(t testing.TB, name string, body []byte, ids map[string]string) {
+	t.Helper()
+	check(t, name+".response.json", body, ids)
+}
+
+func check(t testing.TB, file string, body []byte, ids map[string]string) {
+	t.Helper()
+
+	got, err := Normalize(body, ids)
+	if err != nil {
+		t.Fatalf("%s: %v", file, err)
+	}
+
+	path := filepath.Join(goldenDir(), file)
+	if os.Getenv(updateEnv) == "1" {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with %s=1 to create it): %v", updateEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match the golden file (run with %s=1 to accept):\ngot:\n%s\nwant:\n%s", file, updateEnv, got, want)
+	}
+}
+
+// Normalize canonicalizes a JSON body for golden comparison: keys are sorted,
+// generated values such as timestamps and tokens are replaced by the fixed values
+// above, and each key of ids occurring in a string is replaced by its value.
+// NDJSON bodies of several values stay one value per line.
+func Normalize(body []byte, ids map[string]string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var values []interface{}
+	for {
+		var value interface{}
+		if err := decoder.Decode(&value); err == io.EOF && len(values) > 0 {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		values = append(values, normalizeValue(value, "", ids))
+	}
+
+	if len(values) == 1 {
+		normalized, err := json.MarshalIndent(values[0], "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(normalized, '\n'), nil
+	}
+
+	var normalized bytes.Buffer
+	for _, value := range values {
+		line, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		normalized.Write(append(line, '\n'))
+	}
+	return normalized.Bytes(), nil
+}
+
+func normalizeValue(value interface{}, key string, ids map[string]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = normalizeValue(item, k, ids)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeValue(item, "", ids)
+		}
+		return v
+	case string:
+		if replacement, ok := volatileKeys[key]; ok && v != "" {
+			return replacement
+		}
+		for id, replacement := range ids {
+			v = strings.ReplaceAll(v, id, replacement)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,239 @@
+package grpcserver
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/a2sh3r/gophkeeper/internal/grpcserver/proto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// dataTypes maps the data types of the models to those of the API
+var dataTypes = map[models.DataType]pb.DataType{
+	models.DataTypeLoginPassword: pb.DataType_DATA_TYPE_LOGIN_PASSWORD,
+	models.DataTypeText:          pb.DataType_DATA_TYPE_TEXT,
+	models.DataTypeBinary:        pb.DataType_DATA_TYPE_BINARY,
+	models.DataTypeBankCard:      pb.DataType_DATA_TYPE_BANK_CARD,
+	models.DataTypeOTP:           pb.DataType_DATA_TYPE_OTP,
+}
+
+// DataTypeFromModel returns the API data type of t, unspecified for an unknown one
+func DataTypeFromModel(t models.DataType) pb.DataType {
+	return dataTypes[t]
+}
+
+// DataTypeToModel returns the model data type of t, empty for an unspecified one
+func DataTypeToModel(t pb.DataType) models.DataType {
+	for modelType, apiType := range dataTypes {
+		if apiType == t {
+			return modelType
+		}
+	}
+	return ""
+}
+
+// timestamp returns t as a timestamp, nil for the zero time
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// timestampPtr returns *t as a timestamp, nil for nil; unlike timestamp it
+// keeps the zero time, which clears fields of requests
+func timestampPtr(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// fromTimestamp returns ts as a time, the zero time for nil
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// fromTimestampPtr returns ts as a time, nil for nil
+func fromTimestampPtr(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// stringList returns list as a StringList, nil for nil
+func stringList(list *[]string) *pb.StringList {
+	if list == nil {
+		return nil
+	}
+	return &pb.StringList{Values: *list}
+}
+
+// fromStringList returns list as a slice pointer, nil for nil and a non-nil
+// empty slice for an empty list
+func fromStringList(list *pb.StringList) *[]string {
+	if list == nil {
+		return nil
+	}
+	values := append([]string{}, list.GetValues()...)
+	return &values
+}
+
+// ItemFromModel converts an item of the REST API
+func ItemFromModel(data models.Data) *pb.Item {
+	item := &pb.Item{
+		Id:          data.ID.String(),
+		UserId:      data.UserID.String(),
+		Type:        DataTypeFromModel(data.Type),
+		Name:        data.Name,
+		Description: data.Description,
+		Data:        data.Data,
+		Metadata:    data.Metadata,
+		Environment: data.Environment,
+		CreatedAt:   timestamp(data.CreatedAt),
+		UpdatedAt:   timestamp(data.UpdatedAt),
+		Tags:        data.Tags,
+		Revision:    int32(data.Revision),
+		Checksum:    data.Checksum,
+		Domains:     data.Domains,
+		Icon:        data.Icon,
+		ExpiresAt:   timestampPtr(data.ExpiresAt),
+		Expiry:      data.Expiry,
+	}
+	if data.CollectionID != nil {
+		item.CollectionId = data.CollectionID.String()
+	}
+	return item
+}
+
+// ItemToModel converts an item of the gRPC API
+func ItemToModel(item *pb.Item) (models.Data, error) {
+	id, err := uuid.Parse(item.GetId())
+	if err != nil {
+		return models.Data{}, fmt.Errorf("invalid item ID: %w", err)
+	}
+	userID, err := uuid.Parse(item.GetUserId())
+	if err != nil {
+		return models.Data{}, fmt.Errorf("invalid user ID: %w", err)
+	}
+	data := models.Data{
+		ID:          id,
+		UserID:      userID,
+		Type:        DataTypeToModel(item.GetType()),
+		Name:        item.GetName(),
+		Description: item.GetDescription(),
+		Data:        item.GetData(),
+		Metadata:    item.GetMetadata(),
+		Environment: item.GetEnvironment(),
+		Tags:        item.GetTags(),
+		CreatedAt:   fromTimestamp(item.GetCreatedAt()),
+		UpdatedAt:   fromTimestamp(item.GetUpdatedAt()),
+		Revision:    int(item.GetRevision()),
+		Checksum:    item.GetChecksum(),
+		Domains:     item.GetDomains(),
+		Icon:        item.GetIcon(),
+		ExpiresAt:   fromTimestampPtr(item.GetExpiresAt()),
+		Expiry:      item.GetExpiry(),
+	}
+	if item.GetCollectionId() != "" {
+		collectionID, err := uuid.Parse(item.GetCollectionId())
+		if err != nil {
+			return models.Data{}, fmt.Errorf("invalid collection ID: %w", err)
+		}
+		data.CollectionID = &collectionID
+	}
+	return data, nil
+}
+
+// DataRequestFromModel converts a create or update request of the REST API
+func DataRequestFromModel(req models.DataRequest) *pb.DataRequest {
+	apiReq := &pb.DataRequest{
+		Type:          DataTypeFromModel(req.Type),
+		Name:          req.Name,
+		Description:   req.Description,
+		Data:          req.Data,
+		Metadata:      req.Metadata,
+		Environment:   req.Environment,
+		BaseUpdatedAt: timestampPtr(req.BaseUpdatedAt),
+		Tags:          stringList(req.Tags),
+		Domains:       stringList(req.Domains),
+		ExpiresAt:     timestampPtr(req.ExpiresAt),
+		Checksum:      req.Checksum,
+	}
+	if req.Icon != nil {
+		// a present empty icon removes it, so it must not be sent as unset
+		apiReq.Icon = append([]byte{}, *req.Icon...)
+	}
+	if req.BaseRevision != nil {
+		revision := int32(*req.BaseRevision)
+		apiReq.BaseRevision = &revision
+	}
+	return apiReq
+}
+
+// DataRequestToModel converts a create or update request of the gRPC API
+func DataRequestToModel(req *pb.DataRequest) models.DataRequest {
+	modelReq := models.DataRequest{
+		Type:          DataTypeToModel(req.GetType()),
+		Name:          req.GetName(),
+		Description:   req.GetDescription(),
+		Data:          req.GetData(),
+		Metadata:      req.GetMetadata(),
+		Environment:   req.GetEnvironment(),
+		BaseUpdatedAt: fromTimestampPtr(req.GetBaseUpdatedAt()),
+		Tags:          fromStringList(req.GetTags()),
+		Domains:       fromStringList(req.GetDomains()),
+		ExpiresAt:     fromTimestampPtr(req.GetExpiresAt()),
+		Checksum:      req.GetChecksum(),
+	}
+	if req.GetIcon() != nil {
+		icon := append([]byte{}, req.GetIcon()...)
+		modelReq.Icon = &icon
+	}
+	if req != nil && req.BaseRevision != nil {
+		revision := int(req.GetBaseRevision())
+		modelReq.BaseRevision = &revision
+	}
+	return modelReq
+}
+
+// AuthResponseFromModel converts a register or login response of the REST API
+func AuthResponseFromModel(resp models.AuthResponse) *pb.AuthResponse {
+	return &pb.AuthResponse{
+		Token: resp.Token,
+		User: &pb.User{
+			Id:        resp.User.ID.String(),
+			Username:  resp.User.Username,
+			CreatedAt: timestamp(resp.User.CreatedAt),
+			UpdatedAt: timestamp(resp.User.UpdatedAt),
+		},
+		Salt:          resp.Salt,
+		KdfIterations: int32(resp.KDFIterations),
+	}
+}
+
+// AuthResponseToModel converts a register or login response of the gRPC API
+func AuthResponseToModel(resp *pb.AuthResponse) (models.AuthResponse, error) {
+	userID, err := uuid.Parse(resp.GetUser().GetId())
+	if err != nil {
+		return models.AuthResponse{}, fmt.Errorf("invalid user ID: %w", err)
+	}
+	return models.AuthResponse{
+		Token: resp.GetToken(),
+		User: models.User{
+			ID:        userID,
+			Username:  resp.GetUser().GetUsername(),
+			CreatedAt: fromTimestamp(resp.GetUser().GetCreatedAt()),
+			UpdatedAt: fromTimestamp(resp.GetUser().GetUpdatedAt()),
+		},
+		Salt:          resp.GetSalt(),
+		KDFIterations: int(resp.GetKdfIterations()),
+	}, nil
+}
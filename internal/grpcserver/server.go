@@ -0,0 +1,388 @@
+// Package grpcserver serves the gRPC API defined in proto/gophkeeper.proto.
+//
+// The services run the operations of the REST handlers of package server on
+// the same storage, and the interceptors apply the policies of the REST
+// middleware: request IDs, maintenance mode, rate, payload and concurrency
+// limits, auth and vault locks. Both transports therefore check requests the
+// same way and answer with the same errors, carried here as a pb.Error.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	pb "github.com/a2sh3r/gophkeeper/internal/grpcserver/proto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// requestIDKey carries the ID of a call, like middleware.RequestIDHeader
+	requestIDKey = "x-request-id"
+	// authorizationKey carries the JWT of a call, like the Authorization header
+	authorizationKey = "authorization"
+	// vaultLockKey carries the vault lock token, like server.VaultLockHeader
+	vaultLockKey = "x-vault-lock"
+	// deviceKey carries the device name of a login, like server.DeviceHeader
+	deviceKey = "x-device-name"
+	// maxChunkMessage is the size of an UploadChunks message carrying the
+	// largest chunk the server stores
+	maxChunkMessage = 16<<20 + 1<<10
+)
+
+// MaxMessageSize returns the size of the largest call to accept from clients:
+// anything the payload limit of the REST API lets through, and at least a
+// full content chunk. 0 means no payload limit.
+func MaxMessageSize(maxPayloadBytes int64) int {
+	if maxPayloadBytes <= 0 || maxPayloadBytes > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return max(int(maxPayloadBytes), maxChunkMessage)
+}
+
+// Options are the policies of the API, set like the middleware of the REST
+// API. Nil limiters, locks and maintenance leave their policy off.
+type Options struct {
+	// Routes are the options of the REST routes
+	Routes server.Options
+	// Maintenance rejects changes while maintenance mode is on
+	Maintenance *middleware.Maintenance
+	// VaultLocks rejects changes to a vault locked by another holder
+	VaultLocks *server.VaultLocks
+	// AuthLimiter rate limits Register and Login, like the server.AuthRoutes
+	AuthLimiter *middleware.RateLimiter
+	// BulkLimiter bounds the ListData calls in flight, like the server.BulkRoutes
+	BulkLimiter *middleware.ConcurrencyLimiter
+	// MaxPayloadBytes caps the size of a message; 0 leaves it unlimited
+	MaxPayloadBytes int64
+	// PayloadLimits override MaxPayloadBytes by route name, see server.PayloadLimits
+	PayloadLimits map[string]int64
+	// RegistrationClosed rejects Register, like server.CloseRegistration
+	RegistrationClosed bool
+	// PasswordLoginDisabled rejects Register and Login, like server.DisablePasswordLogin
+	PasswordLoginDisabled bool
+}
+
+// Server holds the storage and policies the services run on
+type Server struct {
+	users      server.UserStorage
+	data       server.DataStorage
+	chunks     server.ChunkStorage
+	jwtManager *auth.JWTManager
+	opts       Options
+}
+
+// New returns a gRPC server serving the API on the given storage, which should
+// be wrapped like the storage of the REST routes so calls are audited and
+// announced the same way
+func New(users server.UserStorage, data server.DataStorage, chunks server.ChunkStorage, jwtManager *auth.JWTManager,
+	opts Options, serverOpts ...grpc.ServerOption) *grpc.Server {
+	if opts.Routes.Clock == nil {
+		opts.Routes.Clock = clock.System{}
+	}
+	s := &Server{users: users, data: data, chunks: chunks, jwtManager: jwtManager, opts: opts}
+	serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(s.unary), grpc.ChainStreamInterceptor(s.stream))
+	srv := grpc.NewServer(serverOpts...)
+	pb.RegisterAuthServer(srv, &authServer{Server: s})
+	pb.RegisterDataServer(srv, &dataServer{Server: s})
+	return srv
+}
+
+// policy says which policies apply to a method
+type policy struct {
+	// route is the name of the matching REST route, for its payload limit
+	route string
+	// write methods change data: they read from the primary and are rejected
+	// during maintenance unless exempt
+	write             bool
+	maintenanceExempt bool
+	// rateLimited methods take credentials and go through AuthLimiter
+	rateLimited bool
+	// authenticated methods need a JWT; scoped tokens are not accepted
+	authenticated bool
+	// vaultLocked methods change the vault and are rejected while another
+	// holder locks it
+	vaultLocked bool
+	// bulk methods go through BulkLimiter
+	bulk bool
+}
+
+// policies are the policies of the methods, by full method name
+var policies = map[string]policy{
+	pb.Auth_Register_FullMethodName:       {route: server.RouteRegister, write: true, rateLimited: true},
+	pb.Auth_Login_FullMethodName:          {route: server.RouteLogin, write: true, maintenanceExempt: true, rateLimited: true},
+	pb.Data_ListData_FullMethodName:       {route: server.RouteListData, authenticated: true, bulk: true},
+	pb.Data_GetData_FullMethodName:        {authenticated: true},
+	pb.Data_CreateData_FullMethodName:     {route: server.RouteCreateData, write: true, authenticated: true, vaultLocked: true},
+	pb.Data_UpdateData_FullMethodName:     {route: server.RouteUpdateData, write: true, authenticated: true, vaultLocked: true},
+	pb.Data_DeleteData_FullMethodName:     {write: true, authenticated: true, vaultLocked: true},
+	pb.Data_UploadChunks_FullMethodName:   {write: true, authenticated: true, vaultLocked: true},
+	pb.Data_DownloadChunks_FullMethodName: {authenticated: true},
+}
+
+// userIDKey is the context key of the ID of the authenticated user of a call
+type userIDKey struct{}
+
+// userID returns the ID of the authenticated user of a call
+func userID(ctx context.Context) uuid.UUID {
+	id, _ := ctx.Value(userIDKey{}).(uuid.UUID)
+	return id
+}
+
+// metadataValue returns the first value of key in the metadata of a call
+func metadataValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// usernameRequest is a request carrying credentials
+type usernameRequest interface {
+	GetUsername() string
+}
+
+// unary applies the policies of the method to a unary call
+func (s *Server) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx, release, err := s.begin(ctx, info.FullMethod, req)
+	if err == nil {
+		var resp interface{}
+		resp, err = handler(ctx, req)
+		release()
+		if err == nil {
+			logCall(ctx, info.FullMethod, codes.OK, start)
+			return resp, nil
+		}
+	}
+	err = statusError(ctx, err)
+	logCall(ctx, info.FullMethod, status.Code(err), start)
+	return nil, err
+}
+
+// stream applies the policies of the method to a streaming call, checking
+// the size of each message it receives
+func (s *Server) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, release, err := s.begin(ss.Context(), info.FullMethod, nil)
+	if err == nil {
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx, limit: s.payloadLimit(info.FullMethod)})
+		release()
+		if err == nil {
+			logCall(ctx, info.FullMethod, codes.OK, start)
+			return nil
+		}
+	}
+	err = statusError(ctx, err)
+	logCall(ctx, info.FullMethod, status.Code(err), start)
+	return err
+}
+
+// begin applies the policies of a call in the order of the REST middleware,
+// returning the context to serve it with and the function to call once it is
+// served. req is nil for streaming calls, whose messages are checked as they
+// are received.
+func (s *Server) begin(ctx context.Context, method string, req interface{}) (context.Context, func(), error) {
+	ctx, id := middleware.WithRequestID(ctx, metadataValue(ctx, requestIDKey))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	ip := peerIP(ctx)
+	ctx = server.WithClientIP(ctx, ip)
+	release := func() {}
+
+	p, ok := policies[method]
+	if !ok {
+		return ctx, release, nil
+	}
+	if p.write && !p.maintenanceExempt && s.opts.Maintenance != nil {
+		if err := s.opts.Maintenance.Check(); err != nil {
+			return ctx, release, err
+		}
+	}
+	if req != nil {
+		if limit := s.payloadLimit(method); limit > 0 && int64(proto.Size(req.(proto.Message))) > limit {
+			return ctx, release, middleware.TooLarge(limit)
+		}
+	}
+	if p.rateLimited && s.opts.AuthLimiter != nil {
+		var username string
+		if withUsername, ok := req.(usernameRequest); ok {
+			username = withUsername.GetUsername()
+		}
+		if err := s.opts.AuthLimiter.Check(ctx, ip, username); err != nil {
+			return ctx, release, err
+		}
+	}
+	if p.authenticated {
+		claims, err := auth.Authenticate(ctx, s.jwtManager, metadataValue(ctx, authorizationKey), false)
+		if err != nil {
+			return ctx, release, err
+		}
+		ctx = context.WithValue(ctx, userIDKey{}, claims.UserID)
+		if p.vaultLocked && s.opts.VaultLocks != nil {
+			if err := s.opts.VaultLocks.Guard(claims.UserID, metadataValue(ctx, vaultLockKey)); err != nil {
+				return ctx, release, err
+			}
+		}
+	}
+	if p.write {
+		ctx = storage.ReadPrimary(ctx)
+	}
+	if p.bulk && s.opts.BulkLimiter != nil {
+		done, err := s.opts.BulkLimiter.Acquire(ctx)
+		if err != nil {
+			return ctx, release, err
+		}
+		release = done
+	}
+	return ctx, release, nil
+}
+
+// payloadLimit returns the largest message the method accepts, 0 for any
+func (s *Server) payloadLimit(method string) int64 {
+	if route := policies[method].route; route != "" {
+		if limit, ok := s.opts.PayloadLimits[route]; ok {
+			return limit
+		}
+	}
+	return s.opts.MaxPayloadBytes
+}
+
+// peerIP returns the address of the client of a call
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	ip, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return ip
+}
+
+// logCall logs a served call like the access log of the REST API
+func logCall(ctx context.Context, method string, code codes.Code, start time.Time) {
+	logger.FromContext(ctx).Info("gRPC call", zap.String("method", method), zap.String("code", code.String()),
+		zap.Duration("duration", time.Since(start)))
+}
+
+// serverStream is a streaming call with the context of its policies, whose
+// received messages are limited to limit bytes
+type serverStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	limit int64
+}
+
+// Context implements grpc.ServerStream
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// RecvMsg implements grpc.ServerStream
+func (s *serverStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.limit > 0 && int64(proto.Size(m.(proto.Message))) > s.limit {
+		return middleware.TooLarge(s.limit)
+	}
+	return nil
+}
+
+// statusError returns the status of a failed call. An *apierror.Problem is
+// attached as a pb.Error; other errors, unless already a status, are logged
+// and fail the call like a 500 of the REST API.
+func statusError(ctx context.Context, err error) error {
+	var problem *apierror.Problem
+	if !errors.As(err, &problem) {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return status.FromContextError(err).Err()
+		}
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		logger.FromContext(ctx).Error("gRPC call failed", zap.Error(err))
+		problem = apierror.New("Internal server error", http.StatusInternalServerError)
+	}
+
+	resp := problem.Response
+	apiErr := &pb.Error{
+		HttpStatus: int32(problem.Status),
+		Error:      resp.Error,
+		Message:    resp.Message,
+		Code:       resp.Code,
+		RequestId:  middleware.RequestIDFromContext(ctx),
+	}
+	for _, field := range resp.Fields {
+		apiErr.Fields = append(apiErr.Fields, &pb.FieldError{Field: field.Field, Rule: field.Rule, Message: field.Message})
+	}
+	if apiErr.Code == "" {
+		apiErr.Code = models.ErrorCodeForStatus(problem.Status)
+	}
+	if seconds, err := strconv.Atoi(problem.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = durationpb.New(time.Duration(seconds) * time.Second)
+	}
+
+	message := apiErr.Message
+	if message == "" {
+		message = apiErr.Error
+	}
+	if message == "" {
+		message = http.StatusText(problem.Status)
+	}
+	st := status.New(grpcCode(problem.Status, apiErr.Code), message)
+	if withDetails, err := st.WithDetails(apiErr); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// grpcCode returns the gRPC status code of a REST error response
+func grpcCode(httpStatus int, code string) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		if code == models.ErrorCodeUserExists {
+			return codes.AlreadyExists
+		}
+		return codes.Aborted
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusLocked:
+		return codes.FailedPrecondition
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return codes.Unimplemented
+	default:
+		return codes.Internal
+	}
+}
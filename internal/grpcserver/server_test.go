@@ -0,0 +1,352 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	pb "github.com/a2sh3r/gophkeeper/internal/grpcserver/proto"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestConn serves the API on an in-memory store with opts and returns a
+// connection to it
+func newTestConn(t *testing.T, opts Options) *grpc.ClientConn {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	listener := bufconn.Listen(1 << 20)
+	srv := New(store, store, store, jwtManager, opts)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// apiError returns the code and the REST error attached to err
+func apiError(t *testing.T, err error) (codes.Code, *pb.Error) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("Expected a gRPC status, got %v", err)
+	}
+	for _, detail := range st.Details() {
+		if apiErr, ok := detail.(*pb.Error); ok {
+			return st.Code(), apiErr
+		}
+	}
+	t.Fatalf("Expected an Error detail in %v", err)
+	return 0, nil
+}
+
+func TestServer_DataLifecycle(t *testing.T) {
+	conn := newTestConn(t, Options{})
+	authClient := pb.NewAuthClient(conn)
+	dataClient := pb.NewDataClient(conn)
+	ctx := context.Background()
+
+	registered, err := authClient.Register(ctx, &pb.RegisterRequest{Username: "alice", Password: "password", MasterPassword: "master-password"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if registered.GetToken() == "" || registered.GetSalt() == "" || registered.GetUser().GetUsername() != "alice" {
+		t.Errorf("Register() = %v", registered)
+	}
+	_, err = authClient.Register(ctx, &pb.RegisterRequest{Username: "alice", Password: "password", MasterPassword: "master-password"})
+	if code, apiErr := apiError(t, err); code != codes.AlreadyExists || apiErr.GetCode() != models.ErrorCodeUserExists {
+		t.Errorf("Register() of a taken name = %v, %v", code, apiErr)
+	}
+
+	if _, err := dataClient.ListData(ctx, &pb.ListDataRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("ListData() without a token code = %v, want %v", status.Code(err), codes.Unauthenticated)
+	}
+
+	login, err := authClient.Login(ctx, &pb.LoginRequest{Username: "alice", Password: "password"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+login.GetToken())
+
+	tags := &pb.StringList{Values: []string{"work"}}
+	created, err := dataClient.CreateData(ctx, &pb.DataRequest{
+		Type: pb.DataType_DATA_TYPE_OTP, Name: "vpn", Data: []byte("sealed"), Tags: tags, Environment: "prod",
+	})
+	if err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	item := created.GetData()
+	if item.GetType() != pb.DataType_DATA_TYPE_OTP || string(item.GetData()) != "sealed" || item.GetRevision() != 1 ||
+		len(item.GetTags()) != 1 || item.GetUserId() != registered.GetUser().GetId() {
+		t.Errorf("CreateData() = %v", item)
+	}
+
+	_, err = dataClient.CreateData(ctx, &pb.DataRequest{Type: pb.DataType_DATA_TYPE_TEXT, Data: []byte("x")})
+	if code, apiErr := apiError(t, err); code != codes.InvalidArgument || len(apiErr.GetFields()) == 0 {
+		t.Errorf("CreateData() without a name = %v, %v", code, apiErr)
+	}
+
+	got, err := dataClient.GetData(ctx, &pb.GetDataRequest{Id: item.GetId()})
+	if err != nil || got.GetData().GetName() != "vpn" {
+		t.Fatalf("GetData() = %v, %v", got, err)
+	}
+
+	list, err := dataClient.ListData(ctx, &pb.ListDataRequest{Environment: "prod", Tag: "work"})
+	if err != nil || len(list.GetData()) != 1 {
+		t.Fatalf("ListData() = %v, %v", list, err)
+	}
+	if list, err := dataClient.ListData(ctx, &pb.ListDataRequest{Environment: "dev"}); err != nil || len(list.GetData()) != 0 {
+		t.Errorf("ListData() of another environment = %v, %v", list, err)
+	}
+	if list, err := dataClient.ListData(ctx, &pb.ListDataRequest{Query: "vp"}); err != nil || len(list.GetData()) != 1 {
+		t.Errorf("ListData() with a query = %v, %v", list, err)
+	}
+
+	_, err = dataClient.UpdateData(ctx, &pb.UpdateDataRequest{Id: item.GetId(), Data: &pb.DataRequest{
+		Type: pb.DataType_DATA_TYPE_OTP, Name: "vpn", Data: []byte("sealed again"),
+	}})
+	if code, apiErr := apiError(t, err); code != codes.FailedPrecondition || apiErr.GetHttpStatus() != http.StatusPreconditionRequired {
+		t.Errorf("UpdateData() without a base revision = %v, %v", code, apiErr)
+	}
+
+	revision := item.GetRevision()
+	updated, err := dataClient.UpdateData(ctx, &pb.UpdateDataRequest{Id: item.GetId(), Data: &pb.DataRequest{
+		Type: pb.DataType_DATA_TYPE_OTP, Name: "vpn", Data: []byte("sealed again"), BaseRevision: &revision,
+	}})
+	if err != nil {
+		t.Fatalf("UpdateData() error = %v", err)
+	}
+	if updated.GetData().GetRevision() != 2 || len(updated.GetData().GetTags()) != 1 {
+		t.Errorf("UpdateData() = %v, want revision 2 with the tags kept", updated.GetData())
+	}
+
+	_, err = dataClient.UpdateData(ctx, &pb.UpdateDataRequest{Id: item.GetId(), Data: &pb.DataRequest{
+		Type: pb.DataType_DATA_TYPE_OTP, Name: "vpn", Data: []byte("stale"), BaseRevision: &revision,
+	}})
+	if code, apiErr := apiError(t, err); code != codes.Aborted || apiErr.GetCode() != models.ErrorCodeConflict {
+		t.Errorf("UpdateData() of a stale revision = %v, %v", code, apiErr)
+	}
+
+	if _, err := dataClient.DeleteData(ctx, &pb.DeleteDataRequest{Id: item.GetId()}); err != nil {
+		t.Fatalf("DeleteData() error = %v", err)
+	}
+	_, err = dataClient.GetData(ctx, &pb.GetDataRequest{Id: item.GetId()})
+	if code, apiErr := apiError(t, err); code != codes.NotFound || apiErr.GetRequestId() == "" {
+		t.Errorf("GetData() after delete = %v, %v", code, apiErr)
+	}
+}
+
+func TestServer_Chunks(t *testing.T) {
+	conn := newTestConn(t, Options{})
+	ctx := context.Background()
+	login, err := pb.NewAuthClient(conn).Register(ctx, &pb.RegisterRequest{Username: "bob", Password: "password", MasterPassword: "master-password"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+login.GetToken())
+	dataClient := pb.NewDataClient(conn)
+
+	created, err := dataClient.CreateData(ctx, &pb.DataRequest{Type: pb.DataType_DATA_TYPE_BINARY, Name: "disk.img", Data: []byte("description")})
+	if err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	id := created.GetData().GetId()
+
+	chunks := [][]byte{[]byte("first chunk"), make([]byte, 300<<10), []byte("last")}
+	upload, err := dataClient.UploadChunks(ctx)
+	if err != nil {
+		t.Fatalf("UploadChunks() error = %v", err)
+	}
+	for index, chunk := range chunks {
+		req := &pb.UploadChunkRequest{Index: int32(index), Payload: chunk}
+		if index == 0 {
+			req.DataId = id
+		}
+		if err := upload.Send(req); err != nil {
+			t.Fatalf("Send(%d) error = %v", index, err)
+		}
+	}
+	resp, err := upload.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv() error = %v", err)
+	}
+	if resp.GetCount() != int32(len(chunks)) || resp.GetSize() != int64(len(chunks[0])+len(chunks[1])+len(chunks[2])) {
+		t.Errorf("UploadChunks() = %v", resp)
+	}
+
+	failed := []struct {
+		name string
+		reqs []*pb.UploadChunkRequest
+		want codes.Code
+	}{
+		{name: "empty chunk", reqs: []*pb.UploadChunkRequest{{DataId: id, Index: 0}}, want: codes.InvalidArgument},
+		{name: "no data ID", reqs: []*pb.UploadChunkRequest{{Index: 0, Payload: []byte("x")}}, want: codes.InvalidArgument},
+		{name: "out of order", reqs: []*pb.UploadChunkRequest{{DataId: id, Index: 5, Payload: []byte("x")}}, want: codes.Aborted},
+		{name: "another item", reqs: []*pb.UploadChunkRequest{
+			{DataId: id, Index: 0, Payload: chunks[0]},
+			{DataId: "00000000-0000-0000-0000-000000000000", Index: 1, Payload: []byte("y")},
+		}, want: codes.InvalidArgument},
+	}
+	for _, tt := range failed {
+		upload, err := dataClient.UploadChunks(ctx)
+		if err != nil {
+			t.Fatalf("UploadChunks() error = %v", err)
+		}
+		for _, req := range tt.reqs {
+			if err := upload.Send(req); err != nil {
+				break
+			}
+		}
+		if _, err := upload.CloseAndRecv(); status.Code(err) != tt.want {
+			t.Errorf("UploadChunks() of %s code = %v, want %v", tt.name, status.Code(err), tt.want)
+		}
+	}
+
+	stream, err := dataClient.DownloadChunks(ctx, &pb.DownloadChunksRequest{DataId: id})
+	if err != nil {
+		t.Fatalf("DownloadChunks() error = %v", err)
+	}
+	for index := 0; ; index++ {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			if index != len(chunks) {
+				t.Errorf("DownloadChunks() sent %d chunks, want %d", index, len(chunks))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		if chunk.GetIndex() != int32(index) || chunk.GetCount() != int32(len(chunks)) || string(chunk.GetPayload()) != string(chunks[index]) {
+			t.Errorf("chunk %d = index %d of %d, %d bytes", index, chunk.GetIndex(), chunk.GetCount(), len(chunk.GetPayload()))
+		}
+	}
+
+	stream, err = dataClient.DownloadChunks(ctx, &pb.DownloadChunksRequest{DataId: "00000000-0000-0000-0000-000000000000"})
+	if err != nil {
+		t.Fatalf("DownloadChunks() error = %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Errorf("DownloadChunks() of an unknown item code = %v, want %v", status.Code(err), codes.NotFound)
+	}
+}
+
+func TestServer_Maintenance(t *testing.T) {
+	maintenance := middleware.NewMaintenance(false, "", server.MaintenanceExemptPaths...)
+	conn := newTestConn(t, Options{Maintenance: maintenance})
+	ctx := context.Background()
+	login, err := pb.NewAuthClient(conn).Register(ctx, &pb.RegisterRequest{Username: "carol", Password: "password", MasterPassword: "master-password"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+login.GetToken())
+
+	maintenance.Enable("nightly backup", 5*time.Minute)
+	_, err = pb.NewDataClient(conn).CreateData(ctx, &pb.DataRequest{Type: pb.DataType_DATA_TYPE_TEXT, Name: "note", Data: []byte("x")})
+	code, apiErr := apiError(t, err)
+	if code != codes.Unavailable || apiErr.GetCode() != models.ErrorCodeMaintenance || apiErr.GetMessage() != "nightly backup" {
+		t.Errorf("CreateData() during maintenance = %v, %v", code, apiErr)
+	}
+	if apiErr.GetRetryAfter().AsDuration() != 5*time.Minute {
+		t.Errorf("RetryAfter = %v, want %v", apiErr.GetRetryAfter().AsDuration(), 5*time.Minute)
+	}
+}
+
+func TestServer_RegistrationClosed(t *testing.T) {
+	conn := newTestConn(t, Options{RegistrationClosed: true})
+	ctx := context.Background()
+	authClient := pb.NewAuthClient(conn)
+
+	_, err := authClient.Register(ctx, &pb.RegisterRequest{Username: "dave", Password: "password", MasterPassword: "master-password"})
+	if code, apiErr := apiError(t, err); code != codes.PermissionDenied || apiErr.GetCode() != models.ErrorCodeRegistrationClosed {
+		t.Fatalf("Register() with registration closed = %v, %v", code, apiErr)
+	}
+}
+
+func TestServer_VaultLockAndPayloadLimit(t *testing.T) {
+	locks := server.NewVaultLocks(server.Options{})
+	conn := newTestConn(t, Options{
+		VaultLocks:      locks,
+		MaxPayloadBytes: 1 << 10,
+		PayloadLimits:   map[string]int64{server.RouteCreateData: 128},
+	})
+	ctx := context.Background()
+	login, err := pb.NewAuthClient(conn).Register(ctx, &pb.RegisterRequest{Username: "erin", Password: "password", MasterPassword: "master-password"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+login.GetToken())
+	dataClient := pb.NewDataClient(conn)
+
+	_, err = dataClient.CreateData(ctx, &pb.DataRequest{Type: pb.DataType_DATA_TYPE_TEXT, Name: "note", Data: make([]byte, 256)})
+	if code, apiErr := apiError(t, err); code != codes.ResourceExhausted || apiErr.GetHttpStatus() != http.StatusRequestEntityTooLarge {
+		t.Errorf("CreateData() over the route limit = %v, %v", code, apiErr)
+	}
+
+	userID, err := uuid.Parse(login.GetUser().GetId())
+	if err != nil {
+		t.Fatalf("invalid user ID: %v", err)
+	}
+	lock, ok := locks.Acquire(userID, "rotation", "", time.Minute)
+	if !ok {
+		t.Fatal("Acquire() failed")
+	}
+	note := &pb.DataRequest{Type: pb.DataType_DATA_TYPE_TEXT, Name: "note", Data: []byte("x")}
+	_, err = dataClient.CreateData(ctx, note)
+	if code, apiErr := apiError(t, err); code != codes.FailedPrecondition || apiErr.GetHttpStatus() != http.StatusLocked {
+		t.Errorf("CreateData() to a locked vault = %v, %v", code, apiErr)
+	}
+	if _, err := dataClient.ListData(ctx, &pb.ListDataRequest{}); err != nil {
+		t.Errorf("ListData() of a locked vault error = %v", err)
+	}
+	locked := metadata.AppendToOutgoingContext(ctx, "x-vault-lock", lock.Token)
+	if _, err := dataClient.CreateData(locked, note); err != nil {
+		t.Errorf("CreateData() with the lock token error = %v", err)
+	}
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxPayload int64
+		want       int
+	}{
+		{name: "no payload limit", maxPayload: 0, want: math.MaxInt32},
+		{name: "limit above a chunk", maxPayload: 32 << 20, want: 32 << 20},
+		{name: "limit below a chunk", maxPayload: 1 << 20, want: maxChunkMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaxMessageSize(tt.maxPayload); got != tt.want {
+				t.Errorf("MaxMessageSize(%d) = %d, want %d", tt.maxPayload, got, tt.want)
+			}
+		})
+	}
+}
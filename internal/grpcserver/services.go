@@ -0,0 +1,232 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	pb "github.com/a2sh3r/gophkeeper/internal/grpcserver/proto"
+	"github.com/a2sh3r/gophkeeper/internal/idgen"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// authServer serves the Auth service
+type authServer struct {
+	pb.UnimplementedAuthServer
+	*Server
+}
+
+// checkPasswordLogin returns the problem of a password login or registration
+// the server does not allow
+func (s *authServer) checkPasswordLogin() error {
+	if s.opts.PasswordLoginDisabled {
+		return apierror.NewWithCode(http.StatusForbidden, models.ErrorCodePasswordLoginDisabled,
+			"This server only allows single sign-on")
+	}
+	return nil
+}
+
+// Register creates an account like POST /api/v1/register
+func (s *authServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.AuthResponse, error) {
+	if err := s.checkPasswordLogin(); err != nil {
+		return nil, err
+	}
+	if s.opts.RegistrationClosed {
+		return nil, apierror.NewWithCode(http.StatusForbidden, models.ErrorCodeRegistrationClosed, "Registration is closed")
+	}
+
+	resp, err := server.Register(ctx, s.users, s.jwtManager, s.opts.Routes, models.UserRequest{
+		Username:       req.GetUsername(),
+		Password:       req.GetPassword(),
+		MasterPassword: req.GetMasterPassword(),
+		KDFIterations:  int(req.GetKdfIterations()),
+	}, metadataValue(ctx, deviceKey), metadataValue(ctx, "user-agent"))
+	if err != nil {
+		return nil, err
+	}
+	return AuthResponseFromModel(*resp), nil
+}
+
+// Login authenticates like POST /api/v1/login
+func (s *authServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.AuthResponse, error) {
+	if err := s.checkPasswordLogin(); err != nil {
+		return nil, err
+	}
+
+	resp, err := server.Login(ctx, s.users, s.jwtManager, models.LoginRequest{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+	}, metadataValue(ctx, deviceKey), metadataValue(ctx, "user-agent"))
+	if err != nil {
+		return nil, err
+	}
+	return AuthResponseFromModel(*resp), nil
+}
+
+// dataServer serves the Data service
+type dataServer struct {
+	pb.UnimplementedDataServer
+	*Server
+}
+
+// parseDataID parses the ID of an item named by a call
+func parseDataID(id string) (uuid.UUID, error) {
+	dataID, err := idgen.Parse(id)
+	if err != nil {
+		return uuid.Nil, apierror.New("Invalid data ID", http.StatusBadRequest)
+	}
+	return dataID, nil
+}
+
+// dataResponse returns the response of a call returning data
+func dataResponse(data *models.Data, err error) (*pb.DataResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DataResponse{Data: ItemFromModel(*data)}, nil
+}
+
+// CreateData creates an item like POST /api/v1/data
+func (s *dataServer) CreateData(ctx context.Context, req *pb.DataRequest) (*pb.DataResponse, error) {
+	return dataResponse(server.CreateData(ctx, s.data, s.opts.Routes, userID(ctx), DataRequestToModel(req)))
+}
+
+// GetData returns an item like GET /api/v1/data/{id}
+func (s *dataServer) GetData(ctx context.Context, req *pb.GetDataRequest) (*pb.DataResponse, error) {
+	dataID, err := parseDataID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return dataResponse(server.GetData(ctx, s.data, userID(ctx), dataID))
+}
+
+// ListData lists items like GET /api/v1/data, or GET /api/v1/data/search
+// for a request with a query
+func (s *dataServer) ListData(ctx context.Context, req *pb.ListDataRequest) (*pb.ListDataResponse, error) {
+	filter := models.DataFilter{
+		Environment: req.GetEnvironment(),
+		Type:        DataTypeToModel(req.GetType()),
+		Name:        req.GetName(),
+		Query:       strings.TrimSpace(req.GetQuery()),
+		Tag:         strings.ToLower(req.GetTag()),
+		Domain:      strings.ToLower(req.GetDomain()),
+		Limit:       int(req.GetLimit()),
+		Offset:      int(req.GetOffset()),
+	}
+	if req.GetExpiringWithin() != nil {
+		within := req.GetExpiringWithin().AsDuration()
+		if within < 0 {
+			return nil, apierror.New("Invalid expiring", http.StatusBadRequest)
+		}
+		filter.ExpiringBefore = s.opts.Routes.Clock.Now().Add(within)
+	}
+
+	data, err := server.ListData(ctx, s.data, userID(ctx), filter)
+	if err != nil {
+		return nil, err
+	}
+	list := &pb.ListDataResponse{Data: make([]*pb.Item, 0, len(data))}
+	for _, d := range data {
+		list.Data = append(list.Data, ItemFromModel(d))
+	}
+	return list, nil
+}
+
+// UpdateData replaces an item like PUT /api/v1/data/{id}. The update names
+// the version it is based on in its base revision or base update time.
+func (s *dataServer) UpdateData(ctx context.Context, req *pb.UpdateDataRequest) (*pb.DataResponse, error) {
+	dataID, err := parseDataID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return dataResponse(server.UpdateData(ctx, s.data, s.opts.Routes, userID(ctx), dataID, "", DataRequestToModel(req.GetData())))
+}
+
+// DeleteData deletes an item like DELETE /api/v1/data/{id}
+func (s *dataServer) DeleteData(ctx context.Context, req *pb.DeleteDataRequest) (*pb.DeleteDataResponse, error) {
+	dataID, err := parseDataID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := server.DeleteData(ctx, s.data, userID(ctx), dataID); err != nil {
+		return nil, err
+	}
+	return &pb.DeleteDataResponse{}, nil
+}
+
+// UploadChunks stores the content chunks of an item as they arrive, like a
+// POST /api/v1/data/{id}/chunks for each
+func (s *dataServer) UploadChunks(stream pb.Data_UploadChunksServer) error {
+	ctx := stream.Context()
+	var data *models.Data
+	resp := &pb.UploadChunksResponse{}
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+
+		if data == nil {
+			dataID, err := parseDataID(req.GetDataId())
+			if err != nil {
+				return err
+			}
+			if data, err = server.OwnedData(ctx, s.data, userID(ctx), dataID); err != nil {
+				return err
+			}
+		} else if req.GetDataId() != "" {
+			if dataID, err := idgen.Parse(req.GetDataId()); err != nil || dataID != data.ID {
+				return apierror.New("Chunks of one upload must belong to one item", http.StatusBadRequest)
+			}
+		}
+
+		if err := server.PutDataChunk(ctx, s.chunks, data, int(req.GetIndex()), req.GetPayload()); err != nil {
+			return err
+		}
+		resp.Count++
+		resp.Size += int64(len(req.GetPayload()))
+	}
+}
+
+// DownloadChunks streams the content chunks of an item like
+// GET /api/v1/data/{id}/chunks, holding one chunk in memory at a time
+func (s *dataServer) DownloadChunks(req *pb.DownloadChunksRequest, stream pb.Data_DownloadChunksServer) error {
+	ctx := stream.Context()
+	dataID, err := parseDataID(req.GetDataId())
+	if err != nil {
+		return err
+	}
+	data, err := server.OwnedData(ctx, s.data, userID(ctx), dataID)
+	if err != nil {
+		return err
+	}
+	count, err := server.CountDataChunks(ctx, s.chunks, data)
+	if err != nil {
+		return err
+	}
+
+	for index := 0; index < count; index++ {
+		chunk, err := s.chunks.GetDataChunk(ctx, data.ID, index)
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to get chunk", zap.Error(err), zap.String("data_id", data.ID.String()),
+				zap.Int("index", index))
+			return status.Errorf(codes.DataLoss, "failed to get chunk %d of %d", index, count)
+		}
+		if err := stream.Send(&pb.DataChunk{Index: int32(index), Payload: chunk, Count: int32(count)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Package idgen generates record identifiers. Identifiers are always stored as
+// uuid.UUID; ULIDs fit the same 128 bits and sort by creation time.
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/google/uuid"
+)
+
+// Supported ID formats
+const (
+	FormatUUID = "uuid"
+	FormatULID = "ulid"
+)
+
+// crockford is the Crockford base32 alphabet used by the ULID text form
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidLength is the length of the ULID text form
+const ulidLength = 26
+
+// Generator creates new identifiers
+type Generator interface {
+	NewID() uuid.UUID
+}
+
+// New returns the generator for the given format
+func New(format string) (Generator, error) {
+	switch strings.ToLower(format) {
+	case "", FormatUUID:
+		return UUID{}, nil
+	case FormatULID:
+		return NewULID(), nil
+	default:
+		return nil, fmt.Errorf("unsupported ID format: %s (use uuid or ulid)", format)
+	}
+}
+
+// UUID generates random version 4 UUIDs
+type UUID struct{}
+
+// NewID returns a random UUID
+func (UUID) NewID() uuid.UUID {
+	return uuid.New()
+}
+
+// Sequence generates predictable IDs counting up from
+// 00000000-0000-4000-8000-000000000001, for deterministic tests
+type Sequence struct {
+	mutex sync.Mutex
+	next  uint64
+}
+
+// NewSequence creates a sequence generator
+func NewSequence() *Sequence {
+	return &Sequence{}
+}
+
+// NewID returns the next ID of the sequence
+func (g *Sequence) NewID() uuid.UUID {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.next++
+	var id uuid.UUID
+	id[6] = 0x40
+	binary.BigEndian.PutUint64(id[8:16], 0x8000000000000000|g.next)
+	return id
+}
+
+// ULID generates time-ordered ULIDs: a 48-bit millisecond timestamp followed by
+// 80 random bits. IDs created within the same millisecond are monotonic.
+type ULID struct {
+	mutex    sync.Mutex
+	clock    clock.Clock
+	lastMs   uint64
+	lastRand [10]byte
+}
+
+// NewULID creates a ULID generator
+func NewULID() *ULID {
+	return NewULIDWithClock(clock.System{})
+}
+
+// NewULIDWithClock creates a ULID generator taking timestamps from c
+func NewULIDWithClock(c clock.Clock) *ULID {
+	return &ULID{clock: c}
+}
+
+// NewID returns a ULID greater than any previously returned by this generator
+func (g *ULID) NewID() uuid.UUID {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	ms := uint64(g.clock.Now().UnixMilli())
+	if ms > g.lastMs {
+		g.lastMs = ms
+		if _, err := rand.Read(g.lastRand[:]); err != nil {
+			panic(fmt.Sprintf("idgen: failed to read random bytes: %v", err))
+		}
+	} else if !increment(g.lastRand[:]) {
+		// Random part overflowed within one millisecond; borrow the next one
+		g.lastMs++
+	}
+
+	var id uuid.UUID
+	binary.BigEndian.PutUint16(id[0:2], uint16(g.lastMs>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(g.lastMs))
+	copy(id[6:], g.lastRand[:])
+	return id
+}
+
+// increment adds one to a big-endian number, returning false on overflow
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ULIDString formats an ID in the 26 character ULID text form
+func ULIDString(id uuid.UUID) string {
+	out := make([]byte, ulidLength)
+	// 130 bits of output for 128 bits of input: the first character holds 3 bits
+	hi := binary.BigEndian.Uint64(id[0:8])
+	lo := binary.BigEndian.Uint64(id[8:16])
+	for i := ulidLength - 1; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// ParseULID parses the 26 character ULID text form
+func ParseULID(s string) (uuid.UUID, error) {
+	var id uuid.UUID
+	if len(s) != ulidLength {
+		return id, fmt.Errorf("invalid ULID length: %d", len(s))
+	}
+
+	var hi, lo uint64
+	for i, c := range strings.ToUpper(s) {
+		v := strings.IndexRune(crockford, c)
+		if v < 0 {
+			return id, fmt.Errorf("invalid ULID character: %q", c)
+		}
+		if i == 0 && v > 7 {
+			return id, fmt.Errorf("ULID overflows 128 bits")
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+
+	binary.BigEndian.PutUint64(id[0:8], hi)
+	binary.BigEndian.PutUint64(id[8:16], lo)
+	return id, nil
+}
+
+// Parse parses an ID in either UUID or ULID text form
+func Parse(s string) (uuid.UUID, error) {
+	if len(s) == ulidLength {
+		return ParseULID(s)
+	}
+	return uuid.Parse(s)
+}
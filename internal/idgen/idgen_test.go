@@ -0,0 +1,121 @@
+package idgen
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/google/uuid"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{format: ""},
+		{format: "uuid"},
+		{format: "ULID"},
+		{format: "snowflake", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			gen, err := New(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && gen.NewID() == uuid.Nil {
+				t.Error("NewID() returned nil ID")
+			}
+		})
+	}
+}
+
+func TestULID_NewID_Ordered(t *testing.T) {
+	now := clock.NewManual(time.UnixMilli(1700000000000))
+	gen := NewULIDWithClock(now)
+
+	prev := gen.NewID()
+	for i := 0; i < 1000; i++ {
+		if i%100 == 0 {
+			now.Advance(time.Millisecond)
+		}
+		id := gen.NewID()
+		if bytes.Compare(id[:], prev[:]) <= 0 {
+			t.Fatalf("IDs not increasing: %s after %s", ULIDString(id), ULIDString(prev))
+		}
+		if ULIDString(id) <= ULIDString(prev) {
+			t.Fatalf("Text form not increasing: %s after %s", ULIDString(id), ULIDString(prev))
+		}
+		prev = id
+	}
+}
+
+func TestSequence_NewID(t *testing.T) {
+	gen := NewSequence()
+	want := []string{
+		"00000000-0000-4000-8000-000000000001",
+		"00000000-0000-4000-8000-000000000002",
+		"00000000-0000-4000-8000-000000000003",
+	}
+	for _, w := range want {
+		id := gen.NewID()
+		if id.String() != w {
+			t.Errorf("NewID() = %s, want %s", id, w)
+		}
+		if id.Version() != 4 || id.Variant() != uuid.RFC4122 {
+			t.Errorf("NewID() = %s is not a valid version 4 UUID", id)
+		}
+	}
+}
+
+func TestULIDString_RoundTrip(t *testing.T) {
+	gen := NewULID()
+	for i := 0; i < 100; i++ {
+		id := gen.NewID()
+		text := ULIDString(id)
+		if len(text) != 26 {
+			t.Fatalf("ULIDString() length = %d, want 26", len(text))
+		}
+		parsed, err := Parse(text)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", text, err)
+		}
+		if parsed != id {
+			t.Fatalf("Parse(ULIDString(id)) = %s, want %s", parsed, id)
+		}
+	}
+
+	// Known vector: the maximum ULID is 128 set bits
+	maxID, err := ParseULID("7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+	if err != nil {
+		t.Fatalf("ParseULID() error = %v", err)
+	}
+	if maxID.String() != "ffffffff-ffff-ffff-ffff-ffffffffffff" {
+		t.Errorf("ParseULID(max) = %s", maxID)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{input: "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{input: "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{input: "01arz3ndektsv4rrffq69g5fav"},
+		{input: "8ZZZZZZZZZZZZZZZZZZZZZZZZZ", wantErr: true},
+		{input: "01ARZ3NDEKTSV4RRFFQ69G5FAU", wantErr: true},
+		{input: "not-an-id", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if _, err := Parse(tt.input); (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
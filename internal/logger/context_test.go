@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromContext(t *testing.T) {
+	core, recorded := observer.New(zapcore.InfoLevel)
+	Log = zap.New(core)
+
+	FromContext(context.Background()).Info("global")
+	ctx := NewContext(context.Background(), Log.With(zap.String("request_id", "abc")))
+	FromContext(ctx).Info("scoped")
+
+	logs := recorded.All()
+	if len(logs) != 2 {
+		t.Fatalf("Expected 2 log messages, got %d", len(logs))
+	}
+	if len(logs[0].Context) != 0 {
+		t.Errorf("Expected the global logger without fields, got %v", logs[0].Context)
+	}
+	if fields := logs[1].ContextMap(); fields["request_id"] != "abc" {
+		t.Errorf("Expected the logger from the context, got %v", fields)
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// ErrRecordTooLarge is returned when reading a line of a streamed body longer than the limit
+var ErrRecordTooLarge = errors.New("request record too large")
+
+// MaxBodySize rejects request bodies larger than limit bytes. Bodies without a
+// declared length are cut off at the limit, failing the handler's decoding.
+// Bodies of the streaming paths carry one record per line and may be of any
+// length, so the limit applies to each line instead.
+func MaxBodySize(limit int64, streaming ...string) func(http.Handler) http.Handler {
+	return MaxBodySizeByRoute(limit, nil, streaming...)
+}
+
+// MaxBodySizeByRoute is MaxBodySize with the limits of named routes taking
+// precedence over limit. A limit of 0 leaves bodies unlimited.
+func MaxBodySizeByRoute(limit int64, routes map[string]int64, streaming ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := limit
+			if route := mux.CurrentRoute(r); route != nil {
+				if routeLimit, ok := routes[route.GetName()]; ok {
+					limit = routeLimit
+				}
+			}
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for _, path := range streaming {
+				if r.URL.Path == path {
+					r.Body = &lineLimitReader{ReadCloser: r.Body, limit: limit}
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			if r.ContentLength > limit {
+				BodyTooLarge(w, limit)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BodyTooLarge replies that the request body exceeds limit bytes, for
+// handlers that find out while reading it
+func BodyTooLarge(w http.ResponseWriter, limit int64) {
+	apierror.Reply(w, TooLarge(limit))
+}
+
+// TooLarge returns the 413 problem of a request larger than limit bytes
+func TooLarge(limit int64) *apierror.Problem {
+	return &apierror.Problem{Status: http.StatusRequestEntityTooLarge, Response: models.ErrorResponse{
+		Error:   "Request body too large",
+		Message: fmt.Sprintf("This request accepts at most %d bytes; split the data or store large files as chunked binaries", limit),
+	}}
+}
+
+// lineLimitReader fails reads once a line grows longer than limit bytes. The
+// bytes before the offending one are returned first, so complete lines are
+// not lost to the error.
+type lineLimitReader struct {
+	io.ReadCloser
+	limit int64
+	line  int64
+	err   error
+}
+
+func (l *lineLimitReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	n, err := l.ReadCloser.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			l.line = 0
+			continue
+		}
+		l.line++
+		if l.line > l.limit {
+			l.err = ErrRecordTooLarge
+			return i, nil
+		}
+	}
+	return n, err
+}
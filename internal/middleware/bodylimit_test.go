@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/gorilla/mux"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	handler := MaxBodySize(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name          string
+		body          string
+		unknownLength bool
+		want          int
+	}{
+		{name: "within limit", body: "12345678", want: http.StatusOK},
+		{name: "declared too large", body: "123456789", want: http.StatusRequestEntityTooLarge},
+		{name: "streamed too large", body: "123456789", unknownLength: true, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/data", strings.NewReader(tt.body))
+			if tt.unknownLength {
+				req.ContentLength = -1
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestMaxBodySize_Streaming(t *testing.T) {
+	handler := MaxBodySize(8, "/api/v1/data/import")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "lines within limit", body: "12345678\n12345678\n12345678\n", want: http.StatusOK},
+		{name: "line too large", body: "12345678\n123456789\n", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/data/import", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestMaxBodySizeByRoute(t *testing.T) {
+	router := mux.NewRouter()
+	read := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	router.HandleFunc("/api/v1/login", read).Name("auth.login")
+	router.HandleFunc("/api/v1/data", read).Name("data.create")
+	router.HandleFunc("/api/v1/data/batch", read)
+	router.Use(MaxBodySizeByRoute(8, map[string]int64{"auth.login": 4, "data.create": 0}))
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{name: "route limit", path: "/api/v1/login", body: "12345", want: http.StatusRequestEntityTooLarge},
+		{name: "within route limit", path: "/api/v1/login", body: "1234", want: http.StatusOK},
+		{name: "unlimited route", path: "/api/v1/data", body: strings.Repeat("x", 100), want: http.StatusOK},
+		{name: "global limit", path: "/api/v1/data/batch", body: "123456789", want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body)))
+			if rr.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/login", strings.NewReader("12345")))
+	var resp models.ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Code != models.ErrorCodePayloadTooLarge || !strings.Contains(resp.Message, "at most 4 bytes") {
+		t.Errorf("Expected the limit in the error, got %+v", resp)
+	}
+}
@@ -0,0 +1,256 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+)
+
+// DefaultCompressionMinSize is the smallest response worth compressing; the
+// headers of smaller ones outweigh the savings
+const DefaultCompressionMinSize = 1024
+
+// Compression decompresses gzip and deflate request bodies and compresses
+// responses of at least minSize bytes for clients accepting it. Event streams
+// are left alone so events are not held back. Request bodies are decompressed
+// before they reach body size limits, which then apply to the decompressed size.
+// Responses carry Accept-Encoding to tell clients compressed bodies are
+// accepted, as in RFC 7694.
+func Compression(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if encoding := r.Header.Get("Content-Encoding"); encoding != "" && !strings.EqualFold(encoding, "identity") {
+				if !isSupportedEncoding(encoding) {
+					apierror.Error(w, "Unsupported Content-Encoding "+encoding+", use gzip or deflate", http.StatusUnsupportedMediaType)
+					return
+				}
+				r.Body = DecompressBody(r.Body, encoding)
+				r.Header.Del("Content-Encoding")
+				r.Header.Del("Content-Length")
+				r.ContentLength = -1
+			}
+
+			w.Header().Set("Accept-Encoding", "gzip, deflate")
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize, status: http.StatusOK}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// DecompressBody wraps a body in the given Content-Encoding, gzip or deflate.
+// The decompressor is created on the first read, so streamed bodies are not
+// waited for.
+func DecompressBody(body io.ReadCloser, encoding string) io.ReadCloser {
+	return &decompressingReader{body: body, encoding: strings.ToLower(encoding)}
+}
+
+func isSupportedEncoding(encoding string) bool {
+	switch strings.ToLower(encoding) {
+	case "gzip", "x-gzip", "deflate":
+		return true
+	}
+	return false
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring gzip, or returns "" if neither is acceptable
+func negotiateEncoding(accept string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		quality := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				quality = q
+			}
+		}
+		accepted[name] = quality > 0
+	}
+	for _, encoding := range []string{"gzip", "deflate"} {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// isCompressible reports whether a response of contentType gains from
+// compression; already compressed formats do not
+func isCompressible(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json", mediaType == "application/x-ndjson",
+		mediaType == "application/xml", strings.HasSuffix(mediaType, "+json"):
+		return true
+	}
+	return false
+}
+
+// compressWriter buffers the start of a response until it is known to be
+// large and compressible enough, then compresses the rest as it is written
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+
+	buf         []byte
+	decided     bool
+	compressor  io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader || cw.decided {
+		return
+	}
+	cw.status = status
+	cw.wroteHeader = true
+	// Responses without a body, and informational ones, pass through as they are
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.passThrough()
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.decided {
+		header := cw.Header()
+		if header.Get("Content-Encoding") != "" || !isCompressible(header.Get("Content-Type")) {
+			cw.passThrough()
+		} else {
+			cw.buf = append(cw.buf, p...)
+			if len(cw.buf) >= cw.minSize {
+				if err := cw.startCompression(); err != nil {
+					return 0, err
+				}
+			}
+			return len(p), nil
+		}
+	}
+	if cw.compressor != nil {
+		return cw.compressor.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends what was written so far. A response flushed early is likely
+// streamed and long, so it is compressed if it may be.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.Header().Get("Content-Encoding") == "" && isCompressible(cw.Header().Get("Content-Type")) {
+			if err := cw.startCompression(); err != nil {
+				return
+			}
+		} else {
+			cw.passThrough()
+		}
+	}
+	if flusher, ok := cw.compressor.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// passThrough sends the response uncompressed, with what was buffered so far
+func (cw *compressWriter) passThrough() {
+	cw.decided = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) > 0 {
+		_, _ = cw.ResponseWriter.Write(cw.buf)
+		cw.buf = nil
+	}
+}
+
+func (cw *compressWriter) startCompression() error {
+	cw.decided = true
+	header := cw.Header()
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		// Sniffed from the compressed bytes it would be wrong
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", cw.encoding)
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if cw.encoding == "gzip" {
+		cw.compressor = gzip.NewWriter(cw.ResponseWriter)
+	} else {
+		compressor, err := zlib.NewWriterLevel(cw.ResponseWriter, flate.DefaultCompression)
+		if err != nil {
+			return err
+		}
+		cw.compressor = compressor
+	}
+	buffered := cw.buf
+	cw.buf = nil
+	_, err := cw.compressor.Write(buffered)
+	return err
+}
+
+// close finishes the response: small ones are sent as they are
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		cw.passThrough()
+	}
+	if cw.compressor != nil {
+		_ = cw.compressor.Close()
+	}
+}
+
+// decompressingReader decompresses a body on the fly
+type decompressingReader struct {
+	body     io.ReadCloser
+	encoding string
+	reader   io.Reader
+}
+
+func (d *decompressingReader) Read(p []byte) (int, error) {
+	if d.reader == nil {
+		var err error
+		switch d.encoding {
+		case "deflate":
+			d.reader, err = zlib.NewReader(d.body)
+		default:
+			d.reader, err = gzip.NewReader(d.body)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return d.reader.Read(p)
+}
+
+func (d *decompressingReader) Close() error {
+	return d.body.Close()
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompression_Responses(t *testing.T) {
+	large := strings.Repeat(`{"name":"item"},`, 200)
+	handler := Compression(DefaultCompressionMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte(large))
+		case "/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(large))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			// written in pieces, so compression starts after buffering
+			for i := 0; i < len(large); i += 100 {
+				_, _ = w.Write([]byte(large[i:min(i+100, len(large))]))
+			}
+		}
+	}))
+
+	tests := []struct {
+		name         string
+		path         string
+		accept       string
+		wantEncoding string
+		wantStatus   int
+	}{
+		{name: "gzip", path: "/data", accept: "gzip, deflate", wantEncoding: "gzip", wantStatus: http.StatusCreated},
+		{name: "deflate", path: "/data", accept: "deflate", wantEncoding: "deflate", wantStatus: http.StatusCreated},
+		{name: "gzip refused", path: "/data", accept: "gzip;q=0, deflate;q=0.5", wantEncoding: "deflate", wantStatus: http.StatusCreated},
+		{name: "not accepted", path: "/data", accept: "", wantStatus: http.StatusCreated},
+		{name: "small", path: "/small", accept: "gzip", wantStatus: http.StatusOK},
+		{name: "compressed type", path: "/image", accept: "gzip", wantStatus: http.StatusOK},
+		{name: "event stream", path: "/events", accept: "gzip", wantStatus: http.StatusOK},
+		{name: "no content", path: "/empty", accept: "gzip", wantStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+			if w.Header().Get("Vary") != "Accept-Encoding" || w.Header().Get("Accept-Encoding") == "" {
+				t.Errorf("Expected Vary and Accept-Encoding headers, got %v", w.Header())
+			}
+			if tt.wantEncoding == "" {
+				return
+			}
+			if w.Body.Len() >= len(large) {
+				t.Errorf("Expected a compressed body, got %d of %d bytes", w.Body.Len(), len(large))
+			}
+			body, err := io.ReadAll(DecompressBody(io.NopCloser(w.Body), tt.wantEncoding))
+			if err != nil {
+				t.Fatalf("Failed to decompress the response: %v", err)
+			}
+			if string(body) != large {
+				t.Error("Expected the decompressed body to match")
+			}
+			if w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Expected the content type to be kept, got %q", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestCompression_Requests(t *testing.T) {
+	payload := strings.Repeat("secret data ", 100)
+	handler := Compression(DefaultCompressionMinSize)(MaxBodySize(int64(len(payload)))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if r.Header.Get("Content-Encoding") != "" || string(body) != strings.TrimSuffix(payload, "!") {
+				http.Error(w, "Unexpected body", http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})))
+
+	var gzipped, deflated, bomb bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, _ = gw.Write([]byte(payload))
+	_ = gw.Close()
+	zw := zlib.NewWriter(&deflated)
+	_, _ = zw.Write([]byte(payload))
+	_ = zw.Close()
+	gw = gzip.NewWriter(&bomb)
+	_, _ = gw.Write([]byte(payload + "!"))
+	_ = gw.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		want     int
+	}{
+		{name: "gzip", encoding: "gzip", body: gzipped.Bytes(), want: http.StatusOK},
+		{name: "deflate", encoding: "deflate", body: deflated.Bytes(), want: http.StatusOK},
+		{name: "plain", body: []byte(payload), want: http.StatusOK},
+		{name: "corrupt", encoding: "gzip", body: []byte(payload), want: http.StatusBadRequest},
+		{name: "unsupported", encoding: "br", body: gzipped.Bytes(), want: http.StatusUnsupportedMediaType},
+		{name: "over the limit once decompressed", encoding: "gzip", body: bomb.Bytes(), want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/data", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
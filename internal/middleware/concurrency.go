@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// OverloadedError is the error code returned when a request is shed
+const OverloadedError = models.ErrorCodeOverloaded
+
+// ConcurrencyLimiter bounds the number of in-flight requests. Requests beyond
+// the limit wait in a bounded queue; when the queue is full or the wait times
+// out they are shed with 503 instead of piling up on the database.
+type ConcurrencyLimiter struct {
+	name         string
+	slots        chan struct{}
+	queue        chan struct{}
+	queueTimeout time.Duration
+}
+
+// NewConcurrencyLimiter creates a limiter allowing maxInFlight concurrent
+// requests and up to maxQueue waiting requests for at most queueTimeout
+func NewConcurrencyLimiter(name string, maxInFlight, maxQueue int, queueTimeout time.Duration) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		name:         name,
+		slots:        make(chan struct{}, maxInFlight),
+		queue:        make(chan struct{}, maxQueue),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// InFlight returns the number of requests currently being served
+func (l *ConcurrencyLimiter) InFlight() int {
+	return len(l.slots)
+}
+
+// Queued returns the number of requests waiting for a slot
+func (l *ConcurrencyLimiter) Queued() int {
+	return len(l.queue)
+}
+
+// Handler wraps next with the concurrency limit
+func (l *ConcurrencyLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, err := l.Acquire(r.Context())
+		if err != nil {
+			if r.Context().Err() == nil {
+				apierror.Reply(w, err)
+			}
+			return
+		}
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Acquire takes a slot, queueing if none is free, and returns the function
+// giving it back. A request that is shed gets the 503 problem to reply with.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) (func(), error) {
+	if !l.acquire(ctx) {
+		return nil, l.shed(ctx)
+	}
+	return func() { <-l.slots }, nil
+}
+
+// acquire takes a slot, queueing if none is free. It returns false if the request was shed.
+func (l *ConcurrencyLimiter) acquire(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-l.queue }()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *ConcurrencyLimiter) shed(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	logger.FromContext(ctx).Warn("Request shed by concurrency limit", zap.String("group", l.name),
+		zap.Int("in_flight", l.InFlight()))
+
+	problem := &apierror.Problem{
+		Status:   http.StatusServiceUnavailable,
+		Response: models.ErrorResponse{Error: OverloadedError, Message: "The server is busy, please retry shortly.", Code: OverloadedError},
+	}
+	return problem.WithHeader("Retry-After", "1")
+}
+
+// LimitRoutes returns router middleware applying a limiter to the named routes.
+// Several route names may share a limiter to form a route group.
+func LimitRoutes(limiters map[string]*ConcurrencyLimiter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		limited := make(map[*ConcurrencyLimiter]http.Handler)
+		for _, limiter := range limiters {
+			if _, ok := limited[limiter]; !ok {
+				limited[limiter] = limiter.Handler(next)
+			}
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil {
+				if limiter, ok := limiters[route.GetName()]; ok {
+					limited[limiter].ServeHTTP(w, r)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
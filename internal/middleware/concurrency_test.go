@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestConcurrencyLimiter_QueueAndShed(t *testing.T) {
+	limiter := NewConcurrencyLimiter("bulk", 1, 1, time.Second)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	handler := limiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	codes := make(chan int, 2)
+	var wg sync.WaitGroup
+	serve := func() {
+		defer wg.Done()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/data", nil))
+		codes <- rr.Code
+	}
+
+	wg.Add(1)
+	go serve()
+	<-started
+
+	wg.Add(1)
+	go serve()
+	for limiter.Queued() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Slot and queue are full, so the third request is shed immediately
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/data", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 for shed request, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header on shed request")
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected running and queued requests to succeed, got %d", code)
+		}
+	}
+	if limiter.InFlight() != 0 || limiter.Queued() != 0 {
+		t.Errorf("Expected limiter to be drained, in flight %d queued %d", limiter.InFlight(), limiter.Queued())
+	}
+}
+
+func TestConcurrencyLimiter_QueueTimeout(t *testing.T) {
+	limiter := NewConcurrencyLimiter("bulk", 1, 1, 10*time.Millisecond)
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	handler := limiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	<-started
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected queued request to time out with 503, got %d", rr.Code)
+	}
+}
+
+func TestLimitRoutes(t *testing.T) {
+	limiter := NewConcurrencyLimiter("bulk", 1, 0, time.Millisecond)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	router := mux.NewRouter()
+	router.Use(LimitRoutes(map[string]*ConcurrencyLimiter{"data.list": limiter}))
+	router.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}).Methods("GET").Name("data.list")
+	router.HandleFunc("/salt", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/data", nil))
+		close(done)
+	}()
+	<-started
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/data", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected limited route to shed, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/salt", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected unlimited route to be served, got %d", rr.Code)
+	}
+
+	close(release)
+	<-done
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	options := CORSOptions{
+		AllowedOrigins: []string{"https://vault.example.com", "chrome-extension://abcdef"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	tests := []struct {
+		name        string
+		options     CORSOptions
+		method      string
+		origin      string
+		preflight   bool
+		wantStatus  int
+		wantOrigin  string
+		wantHeaders map[string]string
+	}{
+		{name: "same origin", options: options, method: "GET", wantStatus: http.StatusOK},
+		{name: "allowed origin", options: options, method: "GET", origin: "https://vault.example.com",
+			wantStatus: http.StatusOK, wantOrigin: "https://vault.example.com",
+			wantHeaders: map[string]string{"Access-Control-Expose-Headers": "ETag"}},
+		{name: "extension", options: options, method: "POST", origin: "chrome-extension://abcdef",
+			wantStatus: http.StatusOK, wantOrigin: "chrome-extension://abcdef"},
+		{name: "other origin", options: options, method: "GET", origin: "https://evil.example.com", wantStatus: http.StatusOK},
+		{name: "preflight", options: options, method: "OPTIONS", origin: "https://vault.example.com", preflight: true,
+			wantStatus: http.StatusNoContent, wantOrigin: "https://vault.example.com", wantHeaders: map[string]string{
+				"Access-Control-Allow-Methods": "GET, POST",
+				"Access-Control-Allow-Headers": "Authorization, Content-Type",
+				"Access-Control-Max-Age":       "600",
+			}},
+		{name: "preflight from other origin", options: options, method: "OPTIONS", origin: "https://evil.example.com",
+			preflight: true, wantStatus: http.StatusForbidden},
+		{name: "any origin", options: CORSOptions{AllowedOrigins: []string{"*"}}, method: "GET",
+			origin: "https://anything.example.com", wantStatus: http.StatusOK, wantOrigin: "*"},
+		{name: "credentials", options: CORSOptions{AllowedOrigins: []string{"https://vault.example.com"}, AllowCredentials: true,
+			AllowedHeaders: []string{"*"}}, method: "OPTIONS", origin: "https://vault.example.com", preflight: true,
+			wantStatus: http.StatusNoContent, wantOrigin: "https://vault.example.com", wantHeaders: map[string]string{
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Headers":     "X-Custom",
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/data", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+				req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+			}
+			w := httptest.NewRecorder()
+			CORS(tt.options)(next).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Expected allowed origin %q, got %q", tt.wantOrigin, got)
+			}
+			for name, want := range tt.wantHeaders {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("Expected %s %q, got %q", name, want, got)
+				}
+			}
+			if w.Header().Get("Vary") != "Origin" {
+				t.Errorf("Expected responses to vary by origin, got %v", w.Header().Values("Vary"))
+			}
+		})
+	}
+}
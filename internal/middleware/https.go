@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+)
+
+// hstsMaxAge is one year, the value browsers and preload lists expect
+const hstsMaxAge = "max-age=31536000"
+
+// RedirectToHTTPS answers plain HTTP requests with a permanent redirect to the
+// same URL on HTTPS. httpsPort is appended to the host unless it is empty or
+// the default 443.
+func RedirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
+
+// HSTS tells clients that reached the server over HTTPS to never fall back to
+// plain HTTP for it
+func HSTS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", hstsMaxAge)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
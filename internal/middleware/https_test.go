@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      string
+		httpsPort string
+		want      string
+	}{
+		{name: "default port", host: "vault.example.com", httpsPort: "443", want: "https://vault.example.com/api/v1/data?env=prod"},
+		{name: "strips HTTP port", host: "vault.example.com:80", httpsPort: "", want: "https://vault.example.com/api/v1/data?env=prod"},
+		{name: "custom port", host: "localhost:8080", httpsPort: "8443", want: "https://localhost:8443/api/v1/data?env=prod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://"+tt.host+"/api/v1/data?env=prod", nil)
+			rr := httptest.NewRecorder()
+			RedirectToHTTPS(tt.httpsPort).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusMovedPermanently {
+				t.Errorf("Expected status %d, got %d", http.StatusMovedPermanently, rr.Code)
+			}
+			if got := rr.Header().Get("Location"); got != tt.want {
+				t.Errorf("Expected redirect to %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	handler := HSTS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	plain := httptest.NewRecorder()
+	handler.ServeHTTP(plain, httptest.NewRequest("GET", "/api/v1/status", nil))
+	if got := plain.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("Expected no HSTS header over plain HTTP, got %q", got)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/status", nil)
+	req.TLS = &tls.ConnectionState{}
+	secure := httptest.NewRecorder()
+	handler.ServeHTTP(secure, req)
+	if got := secure.Header().Get("Strict-Transport-Security"); got != hstsMaxAge {
+		t.Errorf("Expected HSTS header %q, got %q", hstsMaxAge, got)
+	}
+}
@@ -0,0 +1,125 @@
+// Package middleware provides HTTP middleware shared by the server routes.
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// MaintenanceError is the error code returned while maintenance mode is on
+const MaintenanceError = models.ErrorCodeMaintenance
+
+// DefaultMaintenanceMessage is shown to clients when no message is configured
+const DefaultMaintenanceMessage = "The server is undergoing maintenance. Your data is safe and can still be read."
+
+// Maintenance rejects mutating requests with 503 while enabled, letting
+// operators run migrations or backups while clients keep read access
+type Maintenance struct {
+	mutex      sync.RWMutex
+	enabled    bool
+	message    string
+	retryAfter time.Duration
+	exempt     []string
+}
+
+// NewMaintenance creates maintenance mode state. Requests whose path starts
+// with one of the exempt prefixes are never blocked.
+func NewMaintenance(enabled bool, message string, exempt ...string) *Maintenance {
+	m := &Maintenance{exempt: exempt}
+	if enabled {
+		m.Enable(message, 0)
+	}
+	return m
+}
+
+// Enable turns maintenance mode on with a message and an optional retry hint
+func (m *Maintenance) Enable(message string, retryAfter time.Duration) {
+	if message == "" {
+		message = DefaultMaintenanceMessage
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.enabled = true
+	m.message = message
+	m.retryAfter = retryAfter
+	logger.Log.Warn("Maintenance mode enabled", zap.String("message", message))
+}
+
+// Disable turns maintenance mode off
+func (m *Maintenance) Disable() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.enabled = false
+	m.message = ""
+	m.retryAfter = 0
+	logger.Log.Info("Maintenance mode disabled")
+}
+
+// Status returns the current maintenance state
+func (m *Maintenance) Status() models.MaintenanceStatus {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return models.MaintenanceStatus{
+		Enabled:           m.enabled,
+		Message:           m.message,
+		RetryAfterSeconds: int64(m.retryAfter / time.Second),
+	}
+}
+
+// Handler wraps next, answering mutating requests with 503 while maintenance is on
+func (m *Maintenance) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isReadOnly(r.Method) || m.isExempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := m.Check(); err != nil {
+			apierror.Reply(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Check returns the 503 problem to reject a mutating request with while
+// maintenance is on, and nil otherwise
+func (m *Maintenance) Check() error {
+	status := m.Status()
+	if !status.Enabled {
+		return nil
+	}
+
+	problem := &apierror.Problem{
+		Status:   http.StatusServiceUnavailable,
+		Response: models.ErrorResponse{Error: MaintenanceError, Message: status.Message, Code: MaintenanceError},
+	}
+	if status.RetryAfterSeconds > 0 {
+		problem.WithHeader("Retry-After", strconv.FormatInt(status.RetryAfterSeconds, 10))
+	}
+	return problem
+}
+
+func (m *Maintenance) isExempt(path string) bool {
+	for _, prefix := range m.exempt {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isReadOnly(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
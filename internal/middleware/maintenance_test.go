@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestMaintenance_Handler(t *testing.T) {
+	maintenance := NewMaintenance(false, "", "/api/v1/login")
+	handler := maintenance.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	if rr := serve("POST", "/api/v1/data"); rr.Code != http.StatusOK {
+		t.Fatalf("Disabled maintenance should allow writes, got %d", rr.Code)
+	}
+
+	maintenance.Enable("backup running", 2*time.Minute)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{method: "GET", path: "/api/v1/data", want: http.StatusOK},
+		{method: "HEAD", path: "/api/v1/data", want: http.StatusOK},
+		{method: "POST", path: "/api/v1/login", want: http.StatusOK},
+		{method: "POST", path: "/api/v1/data", want: http.StatusServiceUnavailable},
+		{method: "PUT", path: "/api/v1/data/1", want: http.StatusServiceUnavailable},
+		{method: "DELETE", path: "/api/v1/data/1", want: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if rr := serve(tt.method, tt.path); rr.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+
+	rr := serve("POST", "/api/v1/data")
+	var errResp models.ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if errResp.Error != MaintenanceError || errResp.Message != "backup running" {
+		t.Errorf("Unexpected maintenance response: %+v", errResp)
+	}
+	if rr.Header().Get("Retry-After") != "120" {
+		t.Errorf("Expected Retry-After 120, got %q", rr.Header().Get("Retry-After"))
+	}
+
+	maintenance.Disable()
+	if rr := serve("POST", "/api/v1/data"); rr.Code != http.StatusOK {
+		t.Errorf("Expected writes to be allowed after disabling, got %d", rr.Code)
+	}
+}
+
+func TestNewMaintenance_DefaultMessage(t *testing.T) {
+	status := NewMaintenance(true, "").Status()
+	if !status.Enabled || status.Message != DefaultMaintenanceMessage {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
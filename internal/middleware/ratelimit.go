@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// RateLimitedError is the error code returned when a request exceeds its rate limit
+const RateLimitedError = models.ErrorCodeRateLimited
+
+// maxUsernameBody bounds how much of a body is read to find the username
+const maxUsernameBody = 64 << 10
+
+// RateLimit allows Burst requests at once, refilled evenly over Per
+type RateLimit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// TokenBucket is the state of one rate limited key
+type TokenBucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Take refills the bucket up to now and takes a token from it. When the
+// bucket is empty it returns false and how long until a token is available.
+func (b *TokenBucket) Take(limit RateLimit, now time.Time) (bool, time.Duration) {
+	rate := float64(limit.Burst) / limit.Per.Seconds()
+	if b.Updated.IsZero() {
+		b.Tokens = float64(limit.Burst)
+	} else if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed*rate)
+	}
+	b.Updated = now
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.Tokens) / rate * float64(time.Second))
+}
+
+// RateLimitStore keeps the token buckets of rate limited keys. The in-memory
+// store suits a single server; instances behind a load balancer need a shared
+// store so each does not grant the full limit.
+type RateLimitStore interface {
+	Take(key string, limit RateLimit, now time.Time) (bool, time.Duration)
+}
+
+// MemoryRateLimitStore keeps token buckets in memory. Buckets that have
+// refilled completely are dropped, so memory follows the active keys only.
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*TokenBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*TokenBucket)}
+}
+
+// Take takes a token from the bucket of key
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) (bool, time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if now.Sub(s.lastSweep) > limit.Per {
+		for k, bucket := range s.buckets {
+			if now.Sub(bucket.Updated) > limit.Per {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &TokenBucket{}
+		s.buckets[key] = bucket
+	}
+	return bucket.Take(limit, now)
+}
+
+// Len returns the number of keys with a bucket
+func (s *MemoryRateLimitStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.buckets)
+}
+
+// RateLimiter limits requests per client address and per username given in
+// the JSON body, so credential stuffing is slowed down both from one address
+// and against one account from many addresses
+type RateLimiter struct {
+	name  string
+	limit RateLimit
+	store RateLimitStore
+	now   func() time.Time
+}
+
+// NewRateLimiter creates a limiter allowing each address and each username
+// the requests of limit, with buckets kept in store
+func NewRateLimiter(name string, limit RateLimit, store RateLimitStore) *RateLimiter {
+	return &RateLimiter{name: name, limit: limit, store: store, now: time.Now}
+}
+
+// Handler wraps next with the rate limit
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := l.Check(r.Context(), clientIP(r), peekUsername(r)); err != nil {
+			apierror.Reply(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Check takes a token for the address and, if not empty, the username of a
+// request and returns the 429 problem to reject it with once either runs out
+func (l *RateLimiter) Check(ctx context.Context, ip, username string) error {
+	keys := []string{l.name + ":ip:" + ip}
+	if username != "" {
+		keys = append(keys, l.name+":user:"+strings.ToLower(username))
+	}
+
+	now := l.now()
+	var wait time.Duration
+	for _, key := range keys {
+		if ok, retryAfter := l.store.Take(key, l.limit, now); !ok && retryAfter > wait {
+			wait = retryAfter
+		}
+	}
+	if wait == 0 {
+		return nil
+	}
+
+	logger.FromContext(ctx).Warn("Request rejected by rate limit", zap.String("group", l.name), zap.String("ip", ip))
+	problem := &apierror.Problem{
+		Status:   http.StatusTooManyRequests,
+		Response: models.ErrorResponse{Error: RateLimitedError, Message: "Too many attempts, please retry later.", Code: RateLimitedError},
+	}
+	return problem.WithHeader("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
+
+// Routes returns router middleware applying the limiter to the named routes
+func (l *RateLimiter) Routes(names ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		limited := l.Handler(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil {
+				for _, name := range names {
+					if route.GetName() == name {
+						limited.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the address the request came from
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// peekUsername reads the username of a JSON body, leaving the body intact
+// for the handler
+func peekUsername(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxUsernameBody))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil {
+		return ""
+	}
+
+	var credentials struct {
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal(body, &credentials); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(credentials.Username)
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTokenBucket_Take(t *testing.T) {
+	limit := RateLimit{Burst: 2, Per: 10 * time.Second}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var bucket TokenBucket
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := bucket.Take(limit, start); !ok {
+			t.Fatalf("Expected request %d within the burst to be allowed", i+1)
+		}
+	}
+	ok, wait := bucket.Take(limit, start)
+	if ok || wait != 5*time.Second {
+		t.Errorf("Expected an empty bucket to wait 5s, got %v, %v", ok, wait)
+	}
+	if ok, _ := bucket.Take(limit, start.Add(5*time.Second)); !ok {
+		t.Error("Expected a token to be refilled after 5s")
+	}
+}
+
+func TestRateLimiter_Handler(t *testing.T) {
+	var body string
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusOK)
+	}).Name("auth.login")
+	router.HandleFunc("/api/v1/data", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	limiter := NewRateLimiter("auth", RateLimit{Burst: 2, Per: time.Minute}, NewMemoryRateLimitStore())
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	router.Use(limiter.Routes("auth.login"))
+
+	login := func(addr, username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/login", strings.NewReader(`{"username":"`+username+`","password":"x"}`))
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		if rr := login("10.0.0.1:5000", "alice"); rr.Code != http.StatusOK {
+			t.Fatalf("Expected attempt %d to be allowed, got %d", i+1, rr.Code)
+		}
+	}
+	if !strings.Contains(body, `"username":"alice"`) {
+		t.Errorf("Expected the handler to receive the whole body, got %q", body)
+	}
+
+	rr := login("10.0.0.1:5001", "bob")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the address to be limited, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Expected Retry-After 30, got %q", got)
+	}
+	if rr := login("10.0.0.2:5000", "Alice"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the username to be limited from another address, got %d", rr.Code)
+	}
+	if rr := login("10.0.0.3:5000", "carol"); rr.Code != http.StatusOK {
+		t.Errorf("Expected another address and username to be allowed, got %d", rr.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/data", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	other := httptest.NewRecorder()
+	router.ServeHTTP(other, req)
+	if other.Code != http.StatusOK {
+		t.Errorf("Expected unnamed routes not to be limited, got %d", other.Code)
+	}
+
+	now = now.Add(time.Minute)
+	if rr := login("10.0.0.1:5000", "alice"); rr.Code != http.StatusOK {
+		t.Errorf("Expected the limit to be lifted after the window, got %d", rr.Code)
+	}
+}
+
+func TestMemoryRateLimitStore_Sweep(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Burst: 1, Per: time.Second}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	store.Take("a", limit, start)
+	store.Take("b", limit, start)
+	store.Take("c", limit, start.Add(2*time.Second))
+	if store.Len() != 1 {
+		t.Errorf("Expected refilled buckets to be dropped, %d left", store.Len())
+	}
+}
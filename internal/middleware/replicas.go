@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+)
+
+// PrimaryReadsForWrites sends the reads of requests that change anything to
+// the primary database, so ownership and revision checks before a write never
+// act on what a lagging read replica or a cache returns. GET and HEAD requests
+// read from the replicas and caches.
+func PrimaryReadsForWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			r = r.WithContext(storage.ReadPrimary(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+)
+
+// replica is storage.Replicas with one replica
+type replica struct {
+	db *sql.DB
+}
+
+func (r replica) Replica() *sql.DB {
+	return r.db
+}
+
+func TestPrimaryReadsForWrites(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer primary.Close()
+	replicaDB, replicaMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer replicaDB.Close()
+
+	store := storage.NewPostgresStorage(primary)
+	store.SetReplicas(replica{db: replicaDB})
+	handler := PrimaryReadsForWrites(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := store.GetUserByUsername(r.Context(), "alice"); err != nil {
+			t.Errorf("GetUserByUsername() error = %v", err)
+		}
+	}))
+
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "username", "password", "master_password", "salt", "kdf_iterations", "created_at", "updated_at"}).
+			AddRow(uuid.New(), "alice", "hash", "master", "salt", 0, time.Now(), time.Now())
+	}
+	replicaMock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(rows())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/data", nil))
+	primaryMock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(rows())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/api/v1/data/1", nil))
+
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expected the GET read from the replica: %v", err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expected the PUT read from the primary: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestID(t *testing.T) {
+	core, recorded := observer.New(zapcore.InfoLevel)
+	previous := logger.Log
+	logger.Log = zap.New(core)
+	defer func() { logger.Log = previous }()
+
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		logger.FromContext(r.Context()).Info("handled")
+	}))
+
+	tests := []struct {
+		name string
+		sent string
+		keep bool
+	}{
+		{name: "honors the sent ID", sent: "client-7f3a.42:1", keep: true},
+		{name: "assigns one when missing"},
+		{name: "replaces a forged log line", sent: "abc\nlevel=error"},
+		{name: "replaces an overlong ID", sent: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorded.TakeAll()
+			req := httptest.NewRequest("GET", "/api/v1/data", nil)
+			if tt.sent != "" {
+				req.Header.Set(RequestIDHeader, tt.sent)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			id := rr.Header().Get(RequestIDHeader)
+			if id == "" || id != seen || req.Header.Get(RequestIDHeader) != id {
+				t.Fatalf("Expected the same ID in the response, context and request, got %q, %q, %q",
+					id, seen, req.Header.Get(RequestIDHeader))
+			}
+			if (id == tt.sent) != tt.keep {
+				t.Errorf("Sent %q, got %q", tt.sent, id)
+			}
+			logs := recorded.All()
+			if len(logs) != 1 || logs[0].ContextMap()["request_id"] != id {
+				t.Errorf("Expected the handler log to carry the request ID, got %+v", logs)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +18,7 @@ const (
 	DataTypeText          DataType = "text"
 	DataTypeBinary        DataType = "binary"
 	DataTypeBankCard      DataType = "bank_card"
+	DataTypeOTP           DataType = "otp"
 )
 
 // Data represents user's private data
@@ -25,17 +30,253 @@ type Data struct {
 	Description string    `json:"description" db:"description"`
 	Data        []byte    `json:"data" db:"data"`
 	Metadata    string    `json:"metadata" db:"metadata"`
+	Environment string    `json:"environment,omitempty" db:"environment"`
+	Tags        []string  `json:"tags,omitempty" db:"tags"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	// Revision counts the writes of an item, starting at 1. It is the ETag
+	// that updates are conditional on.
+	Revision int `json:"revision" db:"revision"`
+	// CollectionID is the collection the item is filed in, nil at the top level
+	CollectionID *uuid.UUID `json:"collection_id,omitempty" db:"collection_id"`
+	// Checksum is the client-computed HMAC of the plaintext of Data, nil for
+	// items written by clients that do not compute one
+	Checksum []byte `json:"checksum,omitempty" db:"checksum"`
+	// Domains are the registrable domains of the URLs in a login item, set by
+	// the client since the server cannot read them, for lookups by site
+	Domains []string `json:"domains,omitempty" db:"domains"`
+	// Icon is the favicon of a login item, encrypted by the client, for
+	// clients to show next to the item; nil if none was fetched
+	Icon []byte `json:"icon,omitempty" db:"icon"`
+	// ExpiresAt is when the secret expires, e.g. a card's expiry date or a
+	// certificate's renewal date; nil if it does not expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	// Expiry flags an item that is expiring or expired; it is kept up to date
+	// by the server, empty for an item that is not due
+	Expiry string `json:"expiry,omitempty" db:"expiry"`
 }
 
 // DataRequest represents create/update data request
 type DataRequest struct {
-	Type        DataType `json:"type" validate:"required,oneof=login_password text binary bank_card"`
+	Type        DataType `json:"type" validate:"required,oneof=login_password text binary bank_card otp"`
 	Name        string   `json:"name" validate:"required,max=255"`
 	Description string   `json:"description" validate:"max=1000"`
 	Data        []byte   `json:"data" validate:"required"`
 	Metadata    string   `json:"metadata" validate:"max=2000"`
+	Environment string   `json:"environment,omitempty" validate:"max=32"`
+	// Tags replace the tags of an item; nil keeps them on update, so clients
+	// unaware of tags do not drop them, and an empty list clears them
+	Tags *[]string `json:"tags,omitempty" validate:"omitempty,max=32,dive,max=64"`
+	// Domains replace the domain index of an item; nil keeps it on update
+	Domains *[]string `json:"domains,omitempty" validate:"omitempty,max=16,dive,max=253"`
+	// Icon replaces the encrypted favicon of an item; nil keeps it on update
+	// and an empty one removes it
+	Icon *[]byte `json:"icon,omitempty" validate:"omitempty,max=24576"`
+	// ExpiresAt replaces the expiry of an item; nil keeps it on update and
+	// the zero time clears it
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// BaseUpdatedAt is the UpdatedAt of the version an update was based on;
+	// when set, the update is rejected if the item changed since
+	BaseUpdatedAt *time.Time `json:"base_updated_at,omitempty"`
+	// BaseRevision is the Revision an update was based on, for clients that
+	// cannot send an If-Match header
+	BaseRevision *int `json:"base_revision,omitempty"`
+	// Checksum is the HMAC of the plaintext of Data; an update without one
+	// clears the stored checksum, which no longer matches
+	Checksum []byte `json:"checksum,omitempty" validate:"omitempty,len=32"`
+}
+
+// DataPatchRequest changes the plaintext fields of an item without its
+// encrypted payload; nil fields are kept
+type DataPatchRequest struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,max=255"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=1000"`
+	Metadata    *string `json:"metadata,omitempty" validate:"omitempty,max=2000"`
+	// Domains replace the domain index, e.g. to index items stored before it
+	Domains *[]string `json:"domains,omitempty" validate:"omitempty,max=16,dive,max=253"`
+	// Icon replaces the encrypted favicon; an empty one removes it
+	Icon *[]byte `json:"icon,omitempty" validate:"omitempty,max=24576"`
+	// ExpiresAt replaces the expiry; the zero time clears it
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// BaseRevision, when set, rejects the change if the item changed since
+	BaseRevision *int `json:"base_revision,omitempty"`
+}
+
+// DataFilter represents data listing filter options. Name matches a
+// case-insensitive substring of the item name and Query one of the name,
+// description or metadata. Tag selects items with that tag and Domain the
+// items indexed under that registrable domain. ExpiringBefore selects the
+// items expiring by then, including expired ones. Limit and Offset
+// select a page of the matching items; a zero Limit means all of them.
+type DataFilter struct {
+	Environment    string    `json:"environment,omitempty"`
+	Type           DataType  `json:"type,omitempty"`
+	Name           string    `json:"name,omitempty"`
+	Query          string    `json:"query,omitempty"`
+	Tag            string    `json:"tag,omitempty"`
+	Domain         string    `json:"domain,omitempty"`
+	ExpiringBefore time.Time `json:"expiring_before,omitempty"`
+	Limit          int       `json:"limit,omitempty"`
+	Offset         int       `json:"offset,omitempty"`
+}
+
+// Matches reports whether data satisfies the filter, ignoring the page
+func (f DataFilter) Matches(data *Data) bool {
+	if f.Environment != "" && data.Environment != f.Environment {
+		return false
+	}
+	if f.Type != "" && data.Type != f.Type {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(strings.ToLower(data.Name), strings.ToLower(f.Name)) {
+		return false
+	}
+	if f.Query != "" {
+		query := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(data.Name), query) &&
+			!strings.Contains(strings.ToLower(data.Description), query) &&
+			!strings.Contains(strings.ToLower(data.Metadata), query) {
+			return false
+		}
+	}
+	if f.Tag != "" && !HasTag(data.Tags, f.Tag) {
+		return false
+	}
+	if f.Domain != "" && !HasTag(data.Domains, f.Domain) {
+		return false
+	}
+	if !f.ExpiringBefore.IsZero() && (data.ExpiresAt == nil || data.ExpiresAt.After(f.ExpiringBefore)) {
+		return false
+	}
+	return true
+}
+
+// Expiry flags of items
+const (
+	ExpiryExpiring = "expiring"
+	ExpiryExpired  = "expired"
+)
+
+// ExpiryStatus returns the expiry flag of an item expiring at expiresAt:
+// expired once that passed, expiring within warning of it and empty otherwise
+func ExpiryStatus(expiresAt *time.Time, now time.Time, warning time.Duration) string {
+	switch {
+	case expiresAt == nil:
+		return ""
+	case !expiresAt.After(now):
+		return ExpiryExpired
+	case !expiresAt.After(now.Add(warning)):
+		return ExpiryExpiring
+	default:
+		return ""
+	}
+}
+
+// ParseAge parses ages such as 90d, 2w, 6m or 1y (months are 30 days, years 365)
+// as well as Go durations such as 720h
+func ParseAge(age string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"m": 30 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+
+	age = strings.TrimSpace(age)
+	if len(age) > 1 {
+		if unit, ok := units[age[len(age)-1:]]; ok {
+			n, err := strconv.Atoi(age[:len(age)-1])
+			if err == nil && n > 0 {
+				return time.Duration(n) * unit, nil
+			}
+		}
+	}
+
+	duration, err := time.ParseDuration(age)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 90d, 6m or 1y)", age)
+	}
+	return duration, nil
+}
+
+// Limits on the tags of an item
+const (
+	MaxTags      = 32
+	MaxTagLength = 64
+)
+
+// NormalizeTags trims and lowercases tags, drops empty and repeated ones and
+// sorts the rest
+func NormalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		switch {
+		case tag == "" || HasTag(normalized, tag):
+			continue
+		case len(tag) > MaxTagLength:
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		case strings.ContainsAny(tag, ", \t\n"):
+			return nil, fmt.Errorf("tag %q contains a comma or whitespace", tag)
+		}
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxTags {
+		return nil, fmt.Errorf("an item can have at most %d tags", MaxTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// MaxIconSize is the largest encrypted favicon an item can have, in bytes
+const MaxIconSize = 24 << 10
+
+// Limits on the domain index of an item
+const (
+	MaxDomains      = 16
+	MaxDomainLength = 253
+)
+
+// NormalizeDomains trims and lowercases domains, drops empty and repeated ones
+// and sorts the rest
+func NormalizeDomains(domains []string) ([]string, error) {
+	var normalized []string
+	for _, domain := range domains {
+		domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		switch {
+		case domain == "" || HasTag(normalized, domain):
+			continue
+		case len(domain) > MaxDomainLength:
+			return nil, fmt.Errorf("domain %q is longer than %d characters", domain, MaxDomainLength)
+		case strings.ContainsAny(domain, "/:@?#, \t\n"):
+			return nil, fmt.Errorf("domain %q is not a host name", domain)
+		}
+		normalized = append(normalized, domain)
+	}
+	if len(normalized) > MaxDomains {
+		return nil, fmt.Errorf("an item can have at most %d domains", MaxDomains)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// HasTag reports whether tags contain tag, ignoring case
+func HasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidDataType reports whether t is one of the known data types
+func IsValidDataType(t DataType) bool {
+	switch t {
+	case DataTypeLoginPassword, DataTypeText, DataTypeBinary, DataTypeBankCard, DataTypeOTP:
+		return true
+	}
+	return false
 }
 
 // LoginPasswordData represents login/password data
@@ -56,6 +297,18 @@ type BankCardData struct {
 	Notes      string `json:"notes,omitempty"`
 }
 
+// OTPData represents a TOTP secret. Zero Algorithm, Digits and Period mean
+// the RFC 6238 defaults SHA1, 6 and 30 seconds.
+type OTPData struct {
+	Secret    string `json:"secret"`
+	Issuer    string `json:"issuer,omitempty"`
+	Account   string `json:"account,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Digits    int    `json:"digits,omitempty"`
+	Period    int    `json:"period,omitempty"`
+	Notes     string `json:"notes,omitempty"`
+}
+
 // TextData represents arbitrary text data
 type TextData struct {
 	Content string `json:"content"`
@@ -68,4 +321,316 @@ type BinaryData struct {
 	MimeType string `json:"mime_type"`
 	Size     int64  `json:"size"`
 	Notes    string `json:"notes,omitempty"`
+	// Chunks is the number of separately encrypted chunks the content was
+	// uploaded in; zero when it is stored in the item itself
+	Chunks int `json:"chunks,omitempty"`
+}
+
+// DataField represents a single published item field, encrypted client-side
+// under a standalone data key held by machine consumers
+type DataField struct {
+	DataID     uuid.UUID `json:"data_id" db:"data_id"`
+	Name       string    `json:"name" db:"name"`
+	Ciphertext []byte    `json:"ciphertext" db:"ciphertext"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DataFieldRequest represents a request to publish a field
+type DataFieldRequest struct {
+	Ciphertext []byte `json:"ciphertext" validate:"required"`
+}
+
+// DataComment represents a timestamped note on an item, encrypted client-side
+// with the vault key
+type DataComment struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	DataID     uuid.UUID `json:"data_id" db:"data_id"`
+	Ciphertext []byte    `json:"ciphertext" db:"ciphertext"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// DataAttachment represents a file attached to an item. Info, the file's
+// BinaryData description, and Content are encrypted client-side with the
+// vault key; Size is the size of the encrypted content. Listings leave
+// Content out.
+type DataAttachment struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	DataID    uuid.UUID `json:"data_id" db:"data_id"`
+	Info      []byte    `json:"info" db:"info"`
+	Size      int64     `json:"size" db:"size"`
+	Content   []byte    `json:"content,omitempty" db:"content"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// DataVersion represents a previous version of an item, kept when the item
+// was updated. UpdatedAt is when that version had been written.
+type DataVersion struct {
+	DataID      uuid.UUID `json:"data_id" db:"data_id"`
+	Version     int       `json:"version" db:"version"`
+	Type        DataType  `json:"type" db:"type"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Data        []byte    `json:"data" db:"data"`
+	Metadata    string    `json:"metadata" db:"metadata"`
+	Environment string    `json:"environment,omitempty" db:"environment"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	Checksum    []byte    `json:"checksum,omitempty" db:"checksum"`
+}
+
+// Collection represents a folder of items. Collections nest through ParentID,
+// which is nil for collections at the top level.
+type Collection struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	Name      string     `json:"name" db:"name"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CollectionRequest represents a request to create, rename or move a collection
+type CollectionRequest struct {
+	Name     string     `json:"name" validate:"required,max=255"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+}
+
+// DataCollectionRequest represents a request to file an item in a collection,
+// or at the top level when CollectionID is nil
+type DataCollectionRequest struct {
+	CollectionID *uuid.UUID `json:"collection_id"`
+}
+
+// DataAttachmentRequest represents a request to attach a file to an item
+type DataAttachmentRequest struct {
+	Info    []byte `json:"info" validate:"required"`
+	Content []byte `json:"content" validate:"required"`
+}
+
+// DataCommentRequest represents a request to append a comment to an item
+type DataCommentRequest struct {
+	Ciphertext []byte `json:"ciphertext" validate:"required"`
+}
+
+// Audit actions recorded for data access and logins
+const (
+	AuditDataCreate  = "data.create"
+	AuditDataRead    = "data.read"
+	AuditDataUpdate  = "data.update"
+	AuditDataDelete  = "data.delete"
+	AuditLogin       = "auth.login"
+	AuditLoginFailed = "auth.login_failed"
+)
+
+// AuditEvent represents a change recorded in a user's audit log. Sealed holds
+// the AuditDetails encrypted to the user's audit public key; without a key the
+// details are not recorded at all.
+type AuditEvent struct {
+	ID        uuid.UUID  `json:"-" db:"id"`
+	UserID    uuid.UUID  `json:"-" db:"user_id"`
+	Action    string     `json:"action" db:"action"`
+	DataID    *uuid.UUID `json:"data_id,omitempty" db:"data_id"`
+	Sealed    []byte     `json:"sealed,omitempty" db:"sealed"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AuditFilter selects audit events; zero fields match every event. Events are
+// returned newest first, at most Limit of them.
+type AuditFilter struct {
+	UserID *uuid.UUID
+	Action string
+	DataID *uuid.UUID
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// Matches reports whether event is selected by the filter, ignoring Limit
+func (f AuditFilter) Matches(event *AuditEvent) bool {
+	switch {
+	case f.UserID != nil && event.UserID != *f.UserID:
+		return false
+	case f.Action != "" && event.Action != f.Action:
+		return false
+	case f.DataID != nil && (event.DataID == nil || *event.DataID != *f.DataID):
+		return false
+	case !f.Since.IsZero() && event.CreatedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !event.CreatedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// AuditDetails are the sensitive fields of an audit event
+type AuditDetails struct {
+	ItemName string `json:"item_name,omitempty"`
+	IP       string `json:"ip,omitempty"`
+}
+
+// AuditKeyRequest represents the X25519 public key audit details are sealed to
+type AuditKeyRequest struct {
+	PublicKey []byte `json:"public_key" validate:"required,len=32"`
+}
+
+// VaultLockRequest represents a request to lock the vault for a maintenance
+// operation such as rotation, import or restore
+type VaultLockRequest struct {
+	Operation  string `json:"operation" validate:"required"`
+	TTLSeconds int64  `json:"ttl_seconds,omitempty"`
+}
+
+// VaultUnlockRequest represents a request to release a vault lock
+type VaultUnlockRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ImportRecord represents one line of a streamed import. Seq is the record's
+// position in the import source and increases from 1; results refer to it.
+type ImportRecord struct {
+	Seq  int         `json:"seq" validate:"required,min=1"`
+	Data DataRequest `json:"data"`
+}
+
+// Batch operations
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOperation is one change of a batch request. Creates carry Data, updates
+// carry ID and Data with a base revision, and deletes carry ID.
+type BatchOperation struct {
+	Op   string       `json:"op" validate:"required,oneof=create update delete"`
+	ID   uuid.UUID    `json:"id"`
+	Data *DataRequest `json:"data,omitempty"`
+}
+
+// BatchRequest represents changes to apply together: all of them or none
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations" validate:"required,min=1"`
+}
+
+// DataChange is one change of a batch as storage applies it. Updates fail the
+// whole batch unless the item is still at BaseRevision; deletes carry the
+// item being deleted.
+type DataChange struct {
+	Op           string
+	Data         *Data
+	BaseRevision int
+}
+
+// Kinds of vault rotation records, one for each kind of ciphertext under the vault key
+const (
+	RotationItem       = "item"
+	RotationComment    = "comment"
+	RotationVersion    = "version"
+	RotationChunk      = "chunk"
+	RotationAttachment = "attachment"
+)
+
+// RotationHeader is the first line of a vault rotation stream: the salt the
+// new vault key is derived with and the master password verifier under it
+type RotationHeader struct {
+	Salt     string `json:"salt" validate:"required"`
+	Verifier []byte `json:"verifier,omitempty"`
+	// KDFIterations is the PBKDF2 iteration count of the new vault key; 0
+	// means the default count
+	KDFIterations int `json:"kdf_iterations,omitempty"`
+	// ShareKey is the share private key encrypted under the new vault key
+	ShareKey []byte `json:"share_key,omitempty"`
+}
+
+// RotationRecord is one line of a vault rotation stream after the header: a
+// ciphertext of the vault re-encrypted under the new key. Items name the
+// revision that was re-encrypted, comments their ID, versions their number,
+// chunks their index and attachments their ID. An attachment carries its
+// content in Data and its description in Info.
+type RotationRecord struct {
+	Kind         string    `json:"kind" validate:"required"`
+	DataID       uuid.UUID `json:"data_id" validate:"required"`
+	Revision     int       `json:"revision,omitempty"`
+	CommentID    uuid.UUID `json:"comment_id,omitempty"`
+	Version      int       `json:"version,omitempty"`
+	Index        int       `json:"index,omitempty"`
+	AttachmentID uuid.UUID `json:"attachment_id,omitempty"`
+	Info         []byte    `json:"info,omitempty"`
+	Data         []byte    `json:"data" validate:"required"`
+}
+
+// Share modes
+const (
+	ShareModeRead  = "read"
+	ShareModeWrite = "write"
+)
+
+// Share grants another user access to an item. SealedKey is the item's data
+// key sealed to the recipient's share public key, so the server never sees it.
+type Share struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	DataID      uuid.UUID `json:"data_id" db:"data_id"`
+	OwnerID     uuid.UUID `json:"owner_id" db:"owner_id"`
+	RecipientID uuid.UUID `json:"recipient_id" db:"recipient_id"`
+	// Recipient is the recipient's username
+	Recipient string    `json:"recipient" db:"recipient"`
+	Mode      string    `json:"mode" db:"mode"`
+	SealedKey []byte    `json:"sealed_key" db:"sealed_key"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ShareRequest represents a request to share an item with a user, or to
+// change an existing share with them
+type ShareRequest struct {
+	Username  string `json:"username" validate:"required"`
+	Mode      string `json:"mode" validate:"required,oneof=read write"`
+	SealedKey []byte `json:"sealed_key" validate:"required"`
+}
+
+// SharedItem is an item shared with the requesting user, with its owner's username
+type SharedItem struct {
+	Share Share  `json:"share"`
+	Owner string `json:"owner"`
+	Data  Data   `json:"data"`
+}
+
+// SharedItemRequest represents a change of a shared item by a recipient with
+// write access. Data must stay encrypted under the shared data key.
+type SharedItemRequest struct {
+	Name         string `json:"name" validate:"required,max=255"`
+	Description  string `json:"description" validate:"max=1000"`
+	Data         []byte `json:"data" validate:"required"`
+	Metadata     string `json:"metadata" validate:"max=2000"`
+	BaseRevision int    `json:"base_revision"`
+	Checksum     []byte `json:"checksum,omitempty" validate:"omitempty,len=32"`
+}
+
+// ShareKeys is a user's X25519 share key pair; the private key is encrypted
+// under the user's vault key
+type ShareKeys struct {
+	PublicKey  []byte `json:"public_key" validate:"required"`
+	PrivateKey []byte `json:"private_key,omitempty"`
+}
+
+// Change event types
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// ChangeEvent tells a user's other clients that one of their items changed.
+// It carries no item content, so clients fetch the item to see the change.
+type ChangeEvent struct {
+	Type     string    `json:"type"`
+	DataID   uuid.UUID `json:"data_id"`
+	Revision int       `json:"revision,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// ScopedTokenRequest represents a request for a token limited to one published field
+type ScopedTokenRequest struct {
+	DataID     uuid.UUID `json:"data_id" validate:"required"`
+	Field      string    `json:"field" validate:"required"`
+	TTLSeconds int64     `json:"ttl_seconds,omitempty"`
 }
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -573,4 +576,127 @@ func TestDataResponse(t *testing.T) {
 	}
 }
 
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", tags: []string{}, want: nil},
+		{name: "trimmed, lowercased, deduplicated and sorted", tags: []string{" Work", "infra", "work", ""}, want: []string{"infra", "work"}},
+		{name: "comma", tags: []string{"a,b"}, wantErr: true},
+		{name: "whitespace", tags: []string{"two words"}, wantErr: true},
+		{name: "too long", tags: []string{strings.Repeat("x", MaxTagLength+1)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeTags(tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeTags() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	tooMany := make([]string, MaxTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag%d", i)
+	}
+	if _, err := NormalizeTags(tooMany); err == nil {
+		t.Error("Expected an error for too many tags")
+	}
+}
+
+func TestNormalizeDomains(t *testing.T) {
+	tests := []struct {
+		name    string
+		domains []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", domains: []string{}, want: nil},
+		{name: "normalized", domains: []string{" Example.COM.", "github.com", "example.com", ""}, want: []string{"example.com", "github.com"}},
+		{name: "url", domains: []string{"https://example.com"}, wantErr: true},
+		{name: "port", domains: []string{"example.com:8443"}, wantErr: true},
+		{name: "too long", domains: []string{strings.Repeat("x", MaxDomainLength+1)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeDomains(tt.domains)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeDomains() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeDomains() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
 
+func TestExpiryStatus(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		want      string
+	}{
+		{name: "no expiry", expiresAt: nil, want: ""},
+		{name: "expired", expiresAt: at(-time.Hour), want: ExpiryExpired},
+		{name: "expiring now", expiresAt: at(0), want: ExpiryExpired},
+		{name: "expiring", expiresAt: at(10 * 24 * time.Hour), want: ExpiryExpiring},
+		{name: "not due", expiresAt: at(60 * 24 * time.Hour), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpiryStatus(tt.expiresAt, now, 30*24*time.Hour); got != tt.want {
+				t.Errorf("ExpiryStatus() = %q, want %q", got, tt.want)
+			}
+			filter := DataFilter{ExpiringBefore: now.Add(30 * 24 * time.Hour)}
+			if got := filter.Matches(&Data{ExpiresAt: tt.expiresAt}); got != (tt.want != "") {
+				t.Errorf("Matches() = %v for an item that is %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialPolicy(t *testing.T) {
+	policy := DefaultCredentialPolicy()
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("Default policy is invalid: %v", err)
+	}
+	if errs := policy.Check("alice.smith@example.com", "password123"); errs != nil {
+		t.Errorf("Expected valid credentials, got %+v", errs)
+	}
+	if err := policy.CheckUsername("ålice"); err == nil {
+		t.Error("Expected a username outside the charset to be rejected")
+	}
+	if err := policy.CheckPassword(strings.Repeat("p", 129)); err == nil {
+		t.Error("Expected an overlong password to be rejected")
+	}
+
+	policy.PasswordMinClasses = 4
+	if err := policy.CheckPassword("Password123"); err == nil {
+		t.Error("Expected a password of three character classes to be rejected")
+	}
+	if err := policy.CheckPassword("Password123!"); err != nil {
+		t.Errorf("CheckPassword() error = %v", err)
+	}
+
+	for _, invalid := range []CredentialPolicy{
+		{UsernameMinLength: 10, UsernameMaxLength: 5},
+		{PasswordMinLength: 10, PasswordMaxLength: 5},
+		{PasswordMinClasses: 5},
+		{UsernameCharset: "z-a"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected policy %+v to be invalid", invalid)
+		}
+	}
+}
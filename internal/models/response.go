@@ -1,9 +1,87 @@
 package models
 
-// ErrorResponse represents error response
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrorResponse represents error response. Error is a short description,
+// Message an optional detail and Code one of the ErrorCode constants for
+// clients to tell errors apart without parsing text. Fields lists the
+// invalid fields of a rejected request.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+	Error   string       `json:"error"`
+	Message string       `json:"message,omitempty"`
+	Code    string       `json:"code,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError is a request field that breaks a validation rule. Field is its
+// JSON key, with the index for list elements such as tags[2].
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error codes
+const (
+	ErrorCodeInvalidRequest       = "invalid_request"
+	ErrorCodeUnauthorized         = "unauthorized"
+	ErrorCodeForbidden            = "forbidden"
+	ErrorCodeNotFound             = "not_found"
+	ErrorCodeMethodNotAllowed     = "method_not_allowed"
+	ErrorCodeConflict             = "conflict"
+	ErrorCodePayloadTooLarge      = "payload_too_large"
+	ErrorCodeLocked               = "locked"
+	ErrorCodePreconditionRequired = "precondition_required"
+	ErrorCodeRateLimited          = "rate_limited"
+	ErrorCodeInternal             = "internal"
+	ErrorCodeUnavailable          = "unavailable"
+	ErrorCodeUserExists           = "user_exists"
+	ErrorCodeInvalidCredentials   = "invalid_credentials"
+	ErrorCodeRegistrationClosed   = "registration_closed"
+	ErrorCodeMaintenance          = "maintenance"
+	ErrorCodeOverloaded           = "overloaded"
+	ErrorCodeVaultBusy            = "vault_busy"
+	// ErrorCodePasswordLoginDisabled is returned for password logins and
+	// registrations on servers that only allow single sign-on
+	ErrorCodePasswordLoginDisabled = "password_login_disabled"
+)
+
+// ErrorCodeForStatus returns the error code of a response status when no
+// more specific one applies
+func ErrorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrorCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrorCodeForbidden
+	case http.StatusNotFound:
+		return ErrorCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrorCodeMethodNotAllowed
+	case http.StatusConflict:
+		return ErrorCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrorCodePayloadTooLarge
+	case http.StatusLocked:
+		return ErrorCodeLocked
+	case http.StatusPreconditionRequired:
+		return ErrorCodePreconditionRequired
+	case http.StatusTooManyRequests:
+		return ErrorCodeRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrorCodeUnavailable
+	}
+	if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		return ErrorCodeInvalidRequest
+	}
+	return ErrorCodeInternal
 }
 
 // SuccessResponse represents success response
@@ -20,3 +98,239 @@ type DataListResponse struct {
 type DataResponse struct {
 	Data Data `json:"data"`
 }
+
+// SaltResponse represents the user's key derivation salt
+type SaltResponse struct {
+	Salt string `json:"salt"`
+	// KDFIterations is the PBKDF2 iteration count of the vault key under
+	// Salt; 0 means the default count
+	KDFIterations int `json:"kdf_iterations,omitempty"`
+}
+
+// VerifierResponse represents the user's master password verifier, empty if none is set
+type VerifierResponse struct {
+	Verifier []byte `json:"verifier,omitempty"`
+}
+
+// DataFieldResponse represents a published field ciphertext
+type DataFieldResponse struct {
+	DataID     string `json:"data_id"`
+	Name       string `json:"name"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// DataCommentResponse represents a newly added comment
+type DataCommentResponse struct {
+	Comment DataComment `json:"comment"`
+}
+
+// DataCommentsResponse represents the comments of an item, oldest first
+type DataCommentsResponse struct {
+	Comments []DataComment `json:"comments"`
+}
+
+// DataAttachmentResponse represents an attachment, with its content when downloaded
+type DataAttachmentResponse struct {
+	Attachment DataAttachment `json:"attachment"`
+}
+
+// DataAttachmentsResponse represents the attachments of an item without their
+// content, oldest first
+type DataAttachmentsResponse struct {
+	Attachments []DataAttachment `json:"attachments"`
+}
+
+// ShareResponse represents a created or changed share
+type ShareResponse struct {
+	Share Share `json:"share"`
+}
+
+// SharesResponse represents the shares of an item
+type SharesResponse struct {
+	Shares []Share `json:"shares"`
+}
+
+// SessionsResponse represents the active sessions of the user, newest first
+type SessionsResponse struct {
+	Sessions []Session `json:"sessions"`
+}
+
+// OIDCStartResponse starts a single sign-on login. The user opens AuthURL in
+// a browser while the client polls for the result with State and PollToken.
+type OIDCStartResponse struct {
+	AuthURL   string    `json:"auth_url"`
+	State     string    `json:"state"`
+	PollToken string    `json:"poll_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SharedItemsResponse represents the items shared with the user
+type SharedItemsResponse struct {
+	Items []SharedItem `json:"items"`
+}
+
+// CollectionResponse represents a created or changed collection
+type CollectionResponse struct {
+	Collection Collection `json:"collection"`
+}
+
+// CollectionsResponse represents all collections of a user, by name
+type CollectionsResponse struct {
+	Collections []Collection `json:"collections"`
+}
+
+// DataVersionsResponse represents the previous versions of an item, newest first
+type DataVersionsResponse struct {
+	Versions []DataVersion `json:"versions"`
+}
+
+// DataChunkResponse represents a stored content chunk of a binary item
+type DataChunkResponse struct {
+	Index int `json:"index"`
+	Size  int `json:"size"`
+}
+
+// PresignedChunk is a URL that uploads or downloads a content chunk of a
+// binary item directly from object storage, without credentials
+type PresignedChunk struct {
+	Index  int    `json:"index"`
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// PresignedChunksResponse represents the URLs of chunks, valid until ExpiresAt
+type PresignedChunksResponse struct {
+	Chunks    []PresignedChunk `json:"chunks"`
+	ExpiresAt time.Time        `json:"expires_at"`
+}
+
+// AuditEventsResponse represents a page of the user's audit log, newest first
+type AuditEventsResponse struct {
+	Events []AuditEvent `json:"events"`
+}
+
+// AdminAuditEvent is an audit event as shown to admins, with the user it
+// belongs to. Its details stay sealed to that user.
+type AdminAuditEvent struct {
+	AuditEvent
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// AdminAuditEventsResponse represents audit events of all users matching an admin filter, newest first
+type AdminAuditEventsResponse struct {
+	Events []AdminAuditEvent `json:"events"`
+}
+
+// RotationResponse reports how many ciphertexts of each kind a vault rotation replaced
+type RotationResponse struct {
+	Items       int `json:"items"`
+	Comments    int `json:"comments"`
+	Versions    int `json:"versions"`
+	Chunks      int `json:"chunks"`
+	Attachments int `json:"attachments"`
+}
+
+// ImportResult acknowledges one streamed import record with the ID of the created
+// item or an error. Seq 0 means the stream itself was aborted.
+type ImportResult struct {
+	Seq   int        `json:"seq"`
+	ID    *uuid.UUID `json:"id,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+// BatchResult reports one operation of a batch, in request order, with the
+// item's ID and revision once applied or why the batch was refused
+type BatchResult struct {
+	Op       string    `json:"op"`
+	ID       uuid.UUID `json:"id"`
+	Revision int       `json:"revision,omitempty"`
+	Status   int       `json:"status"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// BatchResponse represents the outcome of a batch; nothing was applied unless Applied
+type BatchResponse struct {
+	Applied bool          `json:"applied"`
+	Results []BatchResult `json:"results"`
+}
+
+// ScopedTokenResponse represents a newly issued scoped token
+type ScopedTokenResponse struct {
+	Token     string `json:"token"`
+	Scope     string `json:"scope"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// RecoveryKeyResponse represents the organization recovery public key
+type RecoveryKeyResponse struct {
+	PublicKey []byte `json:"public_key"`
+	KeyID     string `json:"key_id"`
+}
+
+// KeyEscrowStatusResponse represents the user's key escrow status
+type KeyEscrowStatusResponse struct {
+	Enabled       bool   `json:"enabled"`
+	RecoveryKeyID string `json:"recovery_key_id,omitempty"`
+	ConsentedAt   string `json:"consented_at,omitempty"`
+	// CurrentKeyID is the key ID currently configured on the server; escrow
+	// under a different ID should be renewed
+	CurrentKeyID string `json:"current_key_id,omitempty"`
+}
+
+// EscrowRecoveryResponse represents everything an admin needs to recover a user's vault
+type EscrowRecoveryResponse struct {
+	Username      string `json:"username"`
+	Salt          string `json:"salt"`
+	WrappedKey    []byte `json:"wrapped_key"`
+	RecoveryKeyID string `json:"recovery_key_id"`
+	ConsentedAt   string `json:"consented_at"`
+	Data          []Data `json:"data"`
+}
+
+// MaintenanceStatus represents the server maintenance mode state
+type MaintenanceStatus struct {
+	Enabled           bool   `json:"enabled"`
+	Message           string `json:"message,omitempty"`
+	RetryAfterSeconds int64  `json:"retry_after_seconds,omitempty"`
+}
+
+// VaultLock represents an advisory lock on a user's vault. Only requests
+// carrying the token may change the vault until the lock expires.
+type VaultLock struct {
+	Token     string    `json:"token,omitempty"`
+	Operation string    `json:"operation"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// StatusResponse represents anonymous instance information for capability pre-flight
+type StatusResponse struct {
+	Version          string   `json:"version"`
+	RegistrationOpen bool     `json:"registration_open"`
+	Features         []string `json:"features"`
+	// MaxPayloadBytes is the largest accepted request body, 0 if unlimited
+	MaxPayloadBytes int64 `json:"max_payload_bytes"`
+	// PayloadLimits are the body limits of named routes, such as auth.login
+	// or data.create, that differ from MaxPayloadBytes; 0 if unlimited
+	PayloadLimits map[string]int64 `json:"payload_limits,omitempty"`
+	// CredentialPolicy is enforced on registration; nil for older servers
+	CredentialPolicy *CredentialPolicy `json:"credential_policy,omitempty"`
+}
+
+// PasswordHintResponse represents the user's master password hint, empty if none is set
+type PasswordHintResponse struct {
+	Hint string `json:"hint"`
+}
+
+// DependencyHealth represents the health of one subsystem the server depends on
+type DependencyHealth struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ReadinessResponse represents the per-dependency readiness of the server
+type ReadinessResponse struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyHealth `json:"dependencies"`
+}
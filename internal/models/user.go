@@ -1,7 +1,11 @@
 package models
 
 import (
+	"fmt"
+	"regexp"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -13,15 +17,169 @@ type User struct {
 	Password       string    `json:"-" db:"password"`
 	MasterPassword string    `json:"-" db:"master_password"`
 	Salt           string    `json:"-" db:"salt"`
-	CreatedAt      time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	// KDFIterations is the PBKDF2 iteration count of the vault key under Salt;
+	// 0 means the default count
+	KDFIterations int       `json:"-" db:"kdf_iterations"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // UserRequest represents user registration request
 type UserRequest struct {
-	Username       string `json:"username" validate:"required,min=3,max=50"`
-	Password       string `json:"password" validate:"required,min=6"`
+	// Username and Password are checked against the server's CredentialPolicy
+	Username       string `json:"username" validate:"required"`
+	Password       string `json:"password" validate:"required"`
 	MasterPassword string `json:"master_password" validate:"required,min=8"`
+	// KDFIterations is the PBKDF2 iteration count the client derives the
+	// vault key with; 0 means the default count
+	KDFIterations int `json:"kdf_iterations,omitempty"`
+}
+
+// Identity links the account of an external identity provider to a user. The
+// subject is unique per issuer.
+type Identity struct {
+	Issuer    string    `json:"issuer" db:"issuer"`
+	Subject   string    `json:"subject" db:"subject"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Session is a login of a user, tracked so it can be listed and revoked. The
+// tokens issued at login carry its ID.
+type Session struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"-" db:"user_id"`
+	// Device is the name the client gave for its machine
+	Device     string    `json:"device,omitempty" db:"device"`
+	UserAgent  string    `json:"user_agent,omitempty" db:"user_agent"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	// Current marks the session of the listing request
+	Current bool `json:"current,omitempty" db:"-"`
+}
+
+// CredentialPolicy holds the rules for the usernames and account passwords of
+// new users. The server enforces it on registration and publishes it in its
+// status, so clients can check credentials before sending them. Zero limits
+// are not checked.
+type CredentialPolicy struct {
+	UsernameMinLength int `json:"username_min_length,omitempty"`
+	UsernameMaxLength int `json:"username_max_length,omitempty"`
+	// UsernameCharset lists the characters allowed in usernames in the form
+	// of a regular expression character class without brackets, e.g. a-z0-9._-
+	UsernameCharset   string `json:"username_charset,omitempty"`
+	PasswordMinLength int    `json:"password_min_length,omitempty"`
+	PasswordMaxLength int    `json:"password_max_length,omitempty"`
+	// PasswordMinClasses is how many of lowercase letters, uppercase letters,
+	// digits and other characters a password must mix
+	PasswordMinClasses int `json:"password_min_classes,omitempty"`
+}
+
+// DefaultCredentialPolicy returns the policy of a server that configures none
+func DefaultCredentialPolicy() CredentialPolicy {
+	return CredentialPolicy{
+		UsernameMinLength:  3,
+		UsernameMaxLength:  50,
+		UsernameCharset:    "A-Za-z0-9._@-",
+		PasswordMinLength:  8,
+		PasswordMaxLength:  128,
+		PasswordMinClasses: 1,
+	}
+}
+
+// Validate checks that the policy can be satisfied
+func (p CredentialPolicy) Validate() error {
+	if p.UsernameMaxLength > 0 && p.UsernameMinLength > p.UsernameMaxLength {
+		return fmt.Errorf("username minimum length %d exceeds the maximum %d", p.UsernameMinLength, p.UsernameMaxLength)
+	}
+	if p.PasswordMaxLength > 0 && p.PasswordMinLength > p.PasswordMaxLength {
+		return fmt.Errorf("password minimum length %d exceeds the maximum %d", p.PasswordMinLength, p.PasswordMaxLength)
+	}
+	if p.PasswordMinClasses < 0 || p.PasswordMinClasses > 4 {
+		return fmt.Errorf("password character classes must be between 0 and 4, got %d", p.PasswordMinClasses)
+	}
+	if p.UsernameCharset != "" {
+		if _, err := regexp.Compile("^[" + p.UsernameCharset + "]*$"); err != nil {
+			return fmt.Errorf("invalid username charset %q: %w", p.UsernameCharset, err)
+		}
+	}
+	return nil
+}
+
+// CheckUsername returns why username breaks the policy, or nil
+func (p CredentialPolicy) CheckUsername(username string) error {
+	if err := checkLength("username", username, p.UsernameMinLength, p.UsernameMaxLength); err != nil {
+		return err
+	}
+	if p.UsernameCharset != "" {
+		charset, err := regexp.Compile("^[" + p.UsernameCharset + "]*$")
+		if err != nil {
+			return fmt.Errorf("invalid username charset %q: %w", p.UsernameCharset, err)
+		}
+		if !charset.MatchString(username) {
+			return fmt.Errorf("username may only contain the characters %s", p.UsernameCharset)
+		}
+	}
+	return nil
+}
+
+// CheckPassword returns why an account password breaks the policy, or nil
+func (p CredentialPolicy) CheckPassword(password string) error {
+	if err := checkLength("password", password, p.PasswordMinLength, p.PasswordMaxLength); err != nil {
+		return err
+	}
+	if classes := characterClasses(password); classes < p.PasswordMinClasses {
+		return fmt.Errorf("password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.PasswordMinClasses)
+	}
+	return nil
+}
+
+// Check returns the credentials that break the policy as field errors
+func (p CredentialPolicy) Check(username, password string) []FieldError {
+	var errs []FieldError
+	if err := p.CheckUsername(username); err != nil {
+		errs = append(errs, FieldError{Field: "username", Rule: "policy", Message: err.Error()})
+	}
+	if err := p.CheckPassword(password); err != nil {
+		errs = append(errs, FieldError{Field: "password", Rule: "policy", Message: err.Error()})
+	}
+	return errs
+}
+
+func checkLength(name, value string, minLength, maxLength int) error {
+	length := utf8.RuneCountInString(value)
+	if length < minLength {
+		return fmt.Errorf("%s must be at least %d characters", name, minLength)
+	}
+	if maxLength > 0 && length > maxLength {
+		return fmt.Errorf("%s must be at most %d characters", name, maxLength)
+	}
+	return nil
+}
+
+// characterClasses counts the classes of characters s mixes
+func characterClasses(s string) int {
+	var lower, upper, digit, other bool
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	count := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			count++
+		}
+	}
+	return count
 }
 
 // LoginRequest represents authentication request
@@ -30,9 +188,52 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// OIDCTokenRequest polls for the result of a single sign-on login
+type OIDCTokenRequest struct {
+	State     string `json:"state" validate:"required"`
+	PollToken string `json:"poll_token" validate:"required"`
+}
+
 // AuthResponse represents authentication response with token
 type AuthResponse struct {
 	Token string `json:"token"`
 	User  User   `json:"user"`
 	Salt  string `json:"salt,omitempty"`
+	// KDFIterations is the PBKDF2 iteration count of the vault key under
+	// Salt; 0 means the default count
+	KDFIterations int `json:"kdf_iterations,omitempty"`
+	// Created is set when a single sign-on login created the account, whose
+	// master password is chosen by the client
+	Created bool `json:"created,omitempty"`
+}
+
+// SaltRequest represents one-time salt registration request
+type SaltRequest struct {
+	Salt string `json:"salt" validate:"required"`
+}
+
+// VerifierRequest represents one-time master password verifier registration request
+type VerifierRequest struct {
+	Verifier []byte `json:"verifier" validate:"required"`
+}
+
+// KeyEscrow represents a user's vault key wrapped under the organization recovery key.
+// A record only exists while the user consents to escrow.
+type KeyEscrow struct {
+	UserID        uuid.UUID `json:"user_id" db:"user_id"`
+	WrappedKey    []byte    `json:"wrapped_key" db:"wrapped_key"`
+	RecoveryKeyID string    `json:"recovery_key_id" db:"recovery_key_id"`
+	ConsentedAt   time.Time `json:"consented_at" db:"consented_at"`
+}
+
+// KeyEscrowRequest represents a request to enable key escrow
+type KeyEscrowRequest struct {
+	Consent       bool   `json:"consent"`
+	WrappedKey    []byte `json:"wrapped_key" validate:"required"`
+	RecoveryKeyID string `json:"recovery_key_id" validate:"required"`
+}
+
+// PasswordHintRequest represents a request to set a master password hint
+type PasswordHintRequest struct {
+	Hint string `json:"hint" validate:"required,max=200"`
 }
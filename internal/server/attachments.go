@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/idgen"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// maxAttachmentCiphertext caps the size of one encrypted attachment; larger
+// files are stored as chunked binary items
+const maxAttachmentCiphertext = 16 << 20
+
+// maxAttachmentInfo caps the size of the encrypted description of an attachment
+const maxAttachmentInfo = 2 << 10
+
+type AttachmentStorage interface {
+	AddDataAttachment(ctx context.Context, attachment *models.DataAttachment) error
+	GetDataAttachments(ctx context.Context, dataID uuid.UUID) ([]*models.DataAttachment, error)
+	GetDataAttachment(ctx context.Context, dataID, attachmentID uuid.UUID) (*models.DataAttachment, error)
+	DeleteDataAttachment(ctx context.Context, dataID, attachmentID uuid.UUID) error
+}
+
+// RegisterAttachmentRoutes registers the routes storing files attached to items
+func RegisterAttachmentRoutes(r *mux.Router, attachmentStorage AttachmentStorage, dataStorage DataStorage, jwtManager *auth.JWTManager, opts Options) {
+	opts = opts.withDefaults()
+	attachments := r.PathPrefix("/api/v1/data/{id}/attachments").Subrouter()
+	attachments.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	attachments.HandleFunc("", handleGetDataAttachments(attachmentStorage, dataStorage)).Methods("GET")
+	attachments.HandleFunc("", handleAddDataAttachment(attachmentStorage, dataStorage, opts)).Methods("POST")
+	attachments.HandleFunc("/{attachmentID}", handleGetDataAttachment(attachmentStorage, dataStorage)).Methods("GET")
+	attachments.HandleFunc("/{attachmentID}", handleDeleteDataAttachment(attachmentStorage, dataStorage)).Methods("DELETE")
+}
+
+// handleGetDataAttachments returns the attachments of an item without their
+// content, oldest first
+func handleGetDataAttachments(attachmentStorage AttachmentStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		attachments, err := attachmentStorage.GetDataAttachments(r.Context(), data.ID)
+		if err != nil {
+			apierror.Error(w, "Failed to get attachments", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.DataAttachmentsResponse{Attachments: make([]models.DataAttachment, 0, len(attachments))}
+		for _, attachment := range attachments {
+			response.Attachments = append(response.Attachments, *attachment)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleAddDataAttachment attaches an encrypted file to an item. The server
+// assigns the ID and timestamp; the file's description and content are never
+// visible to it.
+func handleAddDataAttachment(attachmentStorage AttachmentStorage, dataStorage DataStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.DataAttachmentRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		if len(req.Info) > maxAttachmentInfo {
+			apierror.Error(w, "Attachment description too long", http.StatusBadRequest)
+			return
+		}
+		if len(req.Content) > maxAttachmentCiphertext {
+			apierror.Error(w, "Attachment too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		attachment := &models.DataAttachment{
+			ID:        opts.RecordIDs.NewID(),
+			DataID:    data.ID,
+			Info:      req.Info,
+			Size:      int64(len(req.Content)),
+			Content:   req.Content,
+			CreatedAt: opts.Clock.Now(),
+		}
+		if err := attachmentStorage.AddDataAttachment(r.Context(), attachment); err != nil {
+			if err.Error() == "data not found" {
+				apierror.Error(w, "Data not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to add attachment", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Attachment added", zap.String("data_id", data.ID.String()),
+			zap.String("attachment_id", attachment.ID.String()), zap.Int64("size", attachment.Size))
+
+		added := *attachment
+		added.Content = nil
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(models.DataAttachmentResponse{Attachment: added}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleGetDataAttachment returns an attachment of an item with its content
+func handleGetDataAttachment(attachmentStorage AttachmentStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+		attachmentID, ok := attachmentIDVar(w, r)
+		if !ok {
+			return
+		}
+
+		attachment, err := attachmentStorage.GetDataAttachment(r.Context(), data.ID, attachmentID)
+		if err != nil {
+			if err.Error() == "attachment not found" {
+				apierror.Error(w, "Attachment not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get attachment", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.DataAttachmentResponse{Attachment: *attachment}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleDeleteDataAttachment deletes an attachment of an item
+func handleDeleteDataAttachment(attachmentStorage AttachmentStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+		attachmentID, ok := attachmentIDVar(w, r)
+		if !ok {
+			return
+		}
+
+		if err := attachmentStorage.DeleteDataAttachment(r.Context(), data.ID, attachmentID); err != nil {
+			if err.Error() == "attachment not found" {
+				apierror.Error(w, "Attachment not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Attachment deleted", zap.String("data_id", data.ID.String()),
+			zap.String("attachment_id", attachmentID.String()))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// attachmentIDVar parses the attachment ID of the route. It writes the error
+// response and returns false if it is invalid.
+func attachmentIDVar(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	attachmentID, err := idgen.Parse(mux.Vars(r)["attachmentID"])
+	if err != nil {
+		apierror.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return attachmentID, true
+}
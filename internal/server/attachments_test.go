@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_DataAttachments(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterAttachmentRoutes(router, store, store, jwtManager, Options{})
+
+	register := func(username string) string {
+		w := doRequest(router, "POST", "/api/v1/register", "", models.UserRequest{Username: username, Password: "login-pass", MasterPassword: "master-password"})
+		var authResp models.AuthResponse
+		if err := json.NewDecoder(w.Body).Decode(&authResp); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+		return authResp.Token
+	}
+
+	owner := register("owner")
+	other := register("other")
+
+	w := doRequest(router, "POST", "/api/v1/data", owner, models.DataRequest{Type: models.DataTypeText, Name: "contract", Data: []byte("sealed")})
+	var dataResp models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&dataResp); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+	path := "/api/v1/data/" + dataResp.Data.ID.String() + "/attachments"
+
+	tests := []struct {
+		name           string
+		path           string
+		token          string
+		request        models.DataAttachmentRequest
+		expectedStatus int
+	}{
+		{name: "unauthenticated", path: path, request: models.DataAttachmentRequest{Info: []byte("n"), Content: []byte("c")},
+			expectedStatus: http.StatusUnauthorized},
+		{name: "other user", path: path, token: other, request: models.DataAttachmentRequest{Info: []byte("n"), Content: []byte("c")},
+			expectedStatus: http.StatusForbidden},
+		{name: "unknown item", path: "/api/v1/data/" + uuid.New().String() + "/attachments", token: owner,
+			request: models.DataAttachmentRequest{Info: []byte("n"), Content: []byte("c")}, expectedStatus: http.StatusNotFound},
+		{name: "no content", path: path, token: owner, request: models.DataAttachmentRequest{Info: []byte("n")},
+			expectedStatus: http.StatusBadRequest},
+		{name: "description too long", path: path, token: owner,
+			request:        models.DataAttachmentRequest{Info: bytes.Repeat([]byte("x"), maxAttachmentInfo+1), Content: []byte("c")},
+			expectedStatus: http.StatusBadRequest},
+		{name: "too large", path: path, token: owner,
+			request:        models.DataAttachmentRequest{Info: []byte("n"), Content: make([]byte, maxAttachmentCiphertext+1)},
+			expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "first", path: path, token: owner, request: models.DataAttachmentRequest{Info: []byte("scan"), Content: []byte("pdf")},
+			expectedStatus: http.StatusCreated},
+		{name: "second", path: path, token: owner, request: models.DataAttachmentRequest{Info: []byte("notes"), Content: []byte("text")},
+			expectedStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := doRequest(router, "POST", tt.path, tt.token, tt.request); w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if w := doRequest(router, "GET", path, other, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected other users to be denied, got %d", w.Code)
+	}
+
+	var listed models.DataAttachmentsResponse
+	if err := json.NewDecoder(doRequest(router, "GET", path, owner, nil).Body).Decode(&listed); err != nil {
+		t.Fatalf("Failed to decode attachments: %v", err)
+	}
+	if len(listed.Attachments) != 2 || string(listed.Attachments[0].Info) != "scan" || string(listed.Attachments[1].Info) != "notes" {
+		t.Fatalf("Expected both attachments oldest first, got %+v", listed.Attachments)
+	}
+	if listed.Attachments[0].Content != nil || listed.Attachments[0].Size != 3 {
+		t.Errorf("Expected the listing to carry sizes but no content, got %+v", listed.Attachments[0])
+	}
+
+	first := path + "/" + listed.Attachments[0].ID.String()
+	if w := doRequest(router, "GET", first, other, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected other users to be denied, got %d", w.Code)
+	}
+	var downloaded models.DataAttachmentResponse
+	if err := json.NewDecoder(doRequest(router, "GET", first, owner, nil).Body).Decode(&downloaded); err != nil {
+		t.Fatalf("Failed to decode attachment: %v", err)
+	}
+	if string(downloaded.Attachment.Content) != "pdf" {
+		t.Errorf("Expected the attachment content, got %q", downloaded.Attachment.Content)
+	}
+	if w := doRequest(router, "GET", path+"/not-an-id", owner, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid attachment ID to be rejected, got %d", w.Code)
+	}
+
+	if w := doRequest(router, "DELETE", first, other, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected other users to be denied, got %d", w.Code)
+	}
+	if w := doRequest(router, "DELETE", first, owner, nil); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if w := doRequest(router, "GET", first, owner, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected a deleted attachment to be gone, got %d", w.Code)
+	}
+	if w := doRequest(router, "DELETE", first, owner, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,404 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/idgen"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Page sizes of the audit log endpoint
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+type AuditStorage interface {
+	SetAuditKey(ctx context.Context, userID uuid.UUID, publicKey []byte) error
+	GetAuditKey(ctx context.Context, userID uuid.UUID) ([]byte, error)
+	AddAuditEvent(ctx context.Context, event *models.AuditEvent) error
+	FindAuditEvents(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, error)
+}
+
+// AuditOptions configures the audit log
+type AuditOptions struct {
+	// RecordIP adds the client address to the sealed details of each event
+	RecordIP bool
+	// AdminToken authorizes the admin audit route across users; it is disabled when empty
+	AdminToken string
+	// Clock stamps events; nil means the system clock
+	Clock clock.Clock
+	// RecordIDs generates the IDs of events; nil means random UUIDs
+	RecordIDs idgen.Generator
+}
+
+// withDefaults returns opts with its nil clock and ID generator set to their defaults
+func (opts AuditOptions) withDefaults() AuditOptions {
+	if opts.Clock == nil {
+		opts.Clock = clock.System{}
+	}
+	if opts.RecordIDs == nil {
+		opts.RecordIDs = idgen.UUID{}
+	}
+	return opts
+}
+
+// clientIPKey is the context key of the client address recorded in audit events
+type clientIPKey struct{}
+
+// WithClientIP returns ctx carrying the address of the client of a request,
+// recorded in the audit events it causes when AuditOptions.RecordIP is set
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// RegisterAuditRoutes registers the audit log routes. With RecordIP it also keeps
+// the client address of every request for the audit events it causes.
+func RegisterAuditRoutes(r *mux.Router, auditStorage AuditStorage, jwtManager *auth.JWTManager, opts AuditOptions) {
+	if opts.RecordIP {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ip, _, err := net.SplitHostPort(r.RemoteAddr)
+				if err != nil {
+					ip = r.RemoteAddr
+				}
+				next.ServeHTTP(w, r.WithContext(WithClientIP(r.Context(), ip)))
+			})
+		})
+	}
+
+	audit := r.PathPrefix("/api/v1/audit").Subrouter()
+	audit.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	audit.HandleFunc("", handleGetAuditEvents(auditStorage)).Methods("GET")
+	audit.HandleFunc("/key", handleSetAuditKey(auditStorage)).Methods("PUT")
+
+	if opts.AdminToken == "" {
+		return
+	}
+	admin := r.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(requireAdminToken(opts.AdminToken))
+	admin.HandleFunc("/audit", handleAdminAuditEvents(auditStorage)).Methods("GET")
+}
+
+// parseAuditFilter reads the action, data_id, since, until and limit query
+// parameters; times are RFC 3339
+func parseAuditFilter(r *http.Request) (models.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := models.AuditFilter{Action: query.Get("action"), Limit: defaultAuditLimit}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			return filter, errors.New("invalid limit")
+		}
+		filter.Limit = limit
+	}
+	if raw := query.Get("data_id"); raw != "" {
+		dataID, err := idgen.Parse(raw)
+		if err != nil {
+			return filter, errors.New("invalid data ID")
+		}
+		filter.DataID = &dataID
+	}
+	for _, bound := range []struct {
+		name string
+		time *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		if raw := query.Get(bound.name); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s, expected an RFC 3339 time", bound.name)
+			}
+			*bound.time = parsed
+		}
+	}
+	return filter, nil
+}
+
+// handleGetAuditEvents returns the latest audit events of the user, newest first
+func handleGetAuditEvents(auditStorage AuditStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			apierror.Error(w, "Invalid query: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.UserID = &userID
+
+		events, err := auditStorage.FindAuditEvents(r.Context(), filter)
+		if err != nil {
+			apierror.Error(w, "Failed to get audit events", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.AuditEventsResponse{Events: make([]models.AuditEvent, 0, len(events))}
+		for _, event := range events {
+			response.Events = append(response.Events, *event)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleAdminAuditEvents returns the latest audit events of all users, or of
+// the user_id given, newest first. Item names and addresses stay sealed to
+// each user.
+func handleAdminAuditEvents(auditStorage AuditStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			apierror.Error(w, "Invalid query: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if raw := r.URL.Query().Get("user_id"); raw != "" {
+			userID, err := uuid.Parse(raw)
+			if err != nil {
+				apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+			filter.UserID = &userID
+		}
+
+		events, err := auditStorage.FindAuditEvents(r.Context(), filter)
+		if err != nil {
+			apierror.Error(w, "Failed to get audit events", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.AdminAuditEventsResponse{Events: make([]models.AdminAuditEvent, 0, len(events))}
+		for _, event := range events {
+			response.Events = append(response.Events, models.AdminAuditEvent{AuditEvent: *event, UserID: event.UserID})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleSetAuditKey stores the public key later audit details are sealed to
+func handleSetAuditKey(auditStorage AuditStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.AuditKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.PublicKey) != 32 {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := auditStorage.SetAuditKey(r.Context(), userID, req.PublicKey); err != nil {
+			if err.Error() == "user not found" {
+				apierror.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to set audit key", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// auditRecorder adds events to users' audit logs
+type auditRecorder struct {
+	audit AuditStorage
+	opts  AuditOptions
+}
+
+// record adds an audit event of the user, about data if it is not nil.
+// Failures are logged; events of changes return them too, so the change is
+// rolled back with its event.
+func (s auditRecorder) record(ctx context.Context, action string, userID uuid.UUID, data *models.Data) error {
+	event := &models.AuditEvent{
+		ID:        s.opts.RecordIDs.NewID(),
+		UserID:    userID,
+		Action:    action,
+		CreatedAt: s.opts.Clock.Now(),
+	}
+	details := models.AuditDetails{}
+	if data != nil {
+		dataID := data.ID
+		event.DataID = &dataID
+		details.ItemName = data.Name
+	}
+	if ip, ok := ctx.Value(clientIPKey{}).(string); ok && s.opts.RecordIP {
+		details.IP = ip
+	}
+
+	publicKey, err := s.audit.GetAuditKey(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get audit key", zap.Error(err), zap.String("user_id", userID.String()))
+	}
+	if len(publicKey) > 0 && details != (models.AuditDetails{}) {
+		plaintext, err := json.Marshal(details)
+		if err == nil {
+			event.Sealed, err = crypto.SealAuditDetails(publicKey, plaintext)
+		}
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to seal audit details", zap.Error(err), zap.String("user_id", userID.String()))
+		}
+	}
+
+	if err := s.audit.AddAuditEvent(ctx, event); err != nil {
+		logger.FromContext(ctx).Error("Failed to record audit event", zap.Error(err),
+			zap.String("action", action), zap.String("user_id", userID.String()))
+		return err
+	}
+	return nil
+}
+
+// readRecorder is implemented by data storages that record reads of items
+type readRecorder interface {
+	RecordRead(ctx context.Context, data *models.Data)
+}
+
+// recordRead records that the owner read data, if dataStorage keeps an audit log
+func recordRead(ctx context.Context, dataStorage DataStorage, data *models.Data) {
+	if recorder, ok := dataStorage.(readRecorder); ok {
+		recorder.RecordRead(ctx, data)
+	}
+}
+
+// loginRecorder is implemented by user storages that record login attempts
+type loginRecorder interface {
+	RecordLogin(ctx context.Context, user *models.User, succeeded bool)
+}
+
+// recordLogin records a login attempt on an existing account, if userStorage keeps an audit log
+func recordLogin(ctx context.Context, userStorage UserStorage, user *models.User, succeeded bool) {
+	if recorder, ok := userStorage.(loginRecorder); ok {
+		recorder.RecordLogin(ctx, user, succeeded)
+	}
+}
+
+// auditedDataStorage records data access in the owner's audit log
+type auditedDataStorage struct {
+	DataStorage
+	auditRecorder
+}
+
+// NewAuditedDataStorage wraps dataStorage so that creating, reading, updating
+// and deleting items is recorded in the owner's audit log. Item names, and
+// client addresses with RecordIP, are sealed to the owner's audit key and
+// dropped if there is none, so stored events never show them to the server
+// operator. Reads are those of single items through the API; listing and
+// internal lookups are not recorded. If dataStorage is a Transactor, each
+// change is stored in one transaction with its event, so auditStorage must
+// use the same database.
+func NewAuditedDataStorage(dataStorage DataStorage, auditStorage AuditStorage, opts AuditOptions) DataStorage {
+	return &auditedDataStorage{DataStorage: dataStorage, auditRecorder: auditRecorder{audit: auditStorage, opts: opts.withDefaults()}}
+}
+
+// CreateData creates data and records it
+func (s *auditedDataStorage) CreateData(ctx context.Context, data *models.Data) error {
+	return inTx(ctx, s.DataStorage, func(ctx context.Context) error {
+		if err := s.DataStorage.CreateData(ctx, data); err != nil {
+			return err
+		}
+		return s.record(ctx, models.AuditDataCreate, data.UserID, data)
+	})
+}
+
+// RecordRead records that the owner read data
+func (s *auditedDataStorage) RecordRead(ctx context.Context, data *models.Data) {
+	_ = s.record(ctx, models.AuditDataRead, data.UserID, data)
+}
+
+// UpdateData updates data and records it
+func (s *auditedDataStorage) UpdateData(ctx context.Context, data *models.Data) error {
+	return inTx(ctx, s.DataStorage, func(ctx context.Context) error {
+		if err := s.DataStorage.UpdateData(ctx, data); err != nil {
+			return err
+		}
+		return s.record(ctx, models.AuditDataUpdate, data.UserID, data)
+	})
+}
+
+// DeleteData deletes data and records it
+func (s *auditedDataStorage) DeleteData(ctx context.Context, dataID uuid.UUID) error {
+	return inTx(ctx, s.DataStorage, func(ctx context.Context) error {
+		data, err := s.DataStorage.GetDataByID(ctx, dataID)
+		if err != nil {
+			return err
+		}
+		if err := s.DataStorage.DeleteData(ctx, dataID); err != nil {
+			return err
+		}
+		return s.record(ctx, models.AuditDataDelete, data.UserID, data)
+	})
+}
+
+// ApplyDataBatch applies a batch and records each of its changes
+func (s *auditedDataStorage) ApplyDataBatch(ctx context.Context, changes []models.DataChange) error {
+	actions := map[string]string{
+		models.BatchCreate: models.AuditDataCreate,
+		models.BatchUpdate: models.AuditDataUpdate,
+		models.BatchDelete: models.AuditDataDelete,
+	}
+	return inTx(ctx, s.DataStorage, func(ctx context.Context) error {
+		if err := s.DataStorage.ApplyDataBatch(ctx, changes); err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if err := s.record(ctx, actions[change.Op], change.Data.UserID, change.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// auditedUserStorage records login attempts in the user's audit log
+type auditedUserStorage struct {
+	UserStorage
+	auditRecorder
+}
+
+// NewAuditedUserStorage wraps userStorage so that successful and failed logins
+// to existing accounts are recorded in their audit log
+func NewAuditedUserStorage(userStorage UserStorage, auditStorage AuditStorage, opts AuditOptions) UserStorage {
+	return &auditedUserStorage{UserStorage: userStorage, auditRecorder: auditRecorder{audit: auditStorage, opts: opts.withDefaults()}}
+}
+
+// RecordLogin records a login attempt on the user's account
+func (s *auditedUserStorage) RecordLogin(ctx context.Context, user *models.User, succeeded bool) {
+	action := models.AuditLogin
+	if !succeeded {
+		action = models.AuditLoginFailed
+	}
+	_ = s.record(ctx, action, user.ID, nil)
+}
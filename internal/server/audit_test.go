@@ -0,0 +1,243 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestServer_AuditLog(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	opts := AuditOptions{RecordIP: true}
+
+	router := mux.NewRouter()
+	RegisterAuditRoutes(router, store, jwtManager, opts)
+	RegisterRoutes(router, store, NewAuditedDataStorage(store, store, opts), jwtManager, Options{})
+
+	userID := uuid.New()
+	if err := store.CreateUser(context.Background(), &models.User{ID: userID, Username: "audited"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	token, err := jwtManager.GenerateToken(userID, "audited")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		req := newTestRequest(method, path, token, body)
+		req.RemoteAddr = "203.0.113.7:52100"
+		return serveRequest(router, req)
+	}
+	create := func(name string) uuid.UUID {
+		w := do("POST", "/api/v1/data", models.DataRequest{Type: models.DataTypeText, Name: name, Data: []byte("sealed")})
+		var resp models.DataResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to create data: %v", err)
+		}
+		return resp.Data.ID
+	}
+	events := func(query string) []models.AuditEvent {
+		w := do("GET", "/api/v1/audit"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp models.AuditEventsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode audit log: %v", err)
+		}
+		return resp.Events
+	}
+
+	// without an audit key the item name is not recorded at all
+	unsealedID := create("Before the key")
+
+	vaultKey, _ := crypto.GenerateDataKey()
+	publicKey, privateKey, err := crypto.AuditKeyPair(vaultKey)
+	if err != nil {
+		t.Fatalf("AuditKeyPair() error = %v", err)
+	}
+	if w := do("PUT", "/api/v1/audit/key", models.AuditKeyRequest{PublicKey: []byte("short")}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid key, got %d", w.Code)
+	}
+	if w := do("PUT", "/api/v1/audit/key", models.AuditKeyRequest{PublicKey: publicKey}); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	sealedID := create("Bank login")
+	if w := do("DELETE", "/api/v1/data/"+sealedID.String(), nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+
+	log := events("")
+	if len(log) != 3 {
+		t.Fatalf("Expected 3 audit events, got %+v", log)
+	}
+	want := []struct {
+		action string
+		dataID uuid.UUID
+		sealed bool
+	}{
+		{action: models.AuditDataDelete, dataID: sealedID, sealed: true},
+		{action: models.AuditDataCreate, dataID: sealedID, sealed: true},
+		{action: models.AuditDataCreate, dataID: unsealedID},
+	}
+	for i, w := range want {
+		event := log[i]
+		if event.Action != w.action || event.DataID == nil || *event.DataID != w.dataID || (len(event.Sealed) > 0) != w.sealed {
+			t.Errorf("Event %d: expected %+v, got %+v", i, w, event)
+		}
+		if bytes.Contains(event.Sealed, []byte("Bank login")) || bytes.Contains(event.Sealed, []byte("203.0.113.7")) {
+			t.Errorf("Event %d leaks sealed details in plaintext", i)
+		}
+	}
+
+	opened, err := crypto.OpenAuditDetails(privateKey, log[0].Sealed)
+	if err != nil {
+		t.Fatalf("OpenAuditDetails() error = %v", err)
+	}
+	var details models.AuditDetails
+	if err := json.Unmarshal(opened, &details); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if details.ItemName != "Bank login" || details.IP != "203.0.113.7" {
+		t.Errorf("Expected the item name and address in the sealed details, got %+v", details)
+	}
+
+	if got := events("?limit=1"); len(got) != 1 || got[0].Action != models.AuditDataDelete {
+		t.Errorf("Expected only the latest event, got %+v", got)
+	}
+	for _, query := range []string{"?limit=0", "?limit=abc", "?limit=1001"} {
+		if w := do("GET", "/api/v1/audit"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestServer_AuditReadsLoginsAndAdmin(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	opts := AuditOptions{AdminToken: "admin-secret"}
+
+	router := mux.NewRouter()
+	RegisterAuditRoutes(router, store, jwtManager, opts)
+	RegisterRoutes(router, NewAuditedUserStorage(store, store, opts), NewAuditedDataStorage(store, store, opts), jwtManager, Options{})
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("right"), bcrypt.MinCost)
+	userID := uuid.New()
+	if err := store.CreateUser(context.Background(), &models.User{ID: userID, Username: "reader", Password: string(hashed)}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	token, _ := jwtManager.GenerateToken(userID, "reader")
+
+	do := func(method, path string, body interface{}, header, value string) *httptest.ResponseRecorder {
+		req := newTestRequest(method, path, "", body)
+		req.Header.Set(header, value)
+		return serveRequest(router, req)
+	}
+
+	for _, password := range []string{"wrong", "right"} {
+		do("POST", "/api/v1/login", models.LoginRequest{Username: "reader", Password: password}, "", "")
+	}
+	w := do("POST", "/api/v1/data", models.DataRequest{Type: models.DataTypeText, Name: "Notes", Data: []byte("sealed")}, "Authorization", "Bearer "+token)
+	var created models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+	if w := do("GET", "/api/v1/data/"+created.Data.ID.String(), nil, "Authorization", "Bearer "+token); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var own models.AuditEventsResponse
+	w = do("GET", "/api/v1/audit", nil, "Authorization", "Bearer "+token)
+	if err := json.NewDecoder(w.Body).Decode(&own); err != nil {
+		t.Fatalf("Failed to decode audit log: %v", err)
+	}
+	var actions []string
+	for _, event := range own.Events {
+		actions = append(actions, event.Action)
+	}
+	want := []string{models.AuditDataRead, models.AuditDataCreate, models.AuditLogin, models.AuditLoginFailed}
+	if strings.Join(actions, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected events %v, got %v", want, actions)
+	}
+
+	w = do("GET", "/api/v1/audit?action=data.read&data_id="+created.Data.ID.String(), nil, "Authorization", "Bearer "+token)
+	if err := json.NewDecoder(w.Body).Decode(&own); err != nil || len(own.Events) != 1 {
+		t.Errorf("Expected one read of the item, got %+v, %v", own.Events, err)
+	}
+	if w := do("GET", "/api/v1/audit?since=yesterday", nil, "Authorization", "Bearer "+token); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid time, got %d", w.Code)
+	}
+
+	if w := do("GET", "/api/v1/admin/audit", nil, "X-Admin-Token", "wrong"); w.Code != http.StatusUnauthorized && w.Code != http.StatusForbidden {
+		t.Errorf("Expected the admin route to require the admin token, got %d", w.Code)
+	}
+	var admin models.AdminAuditEventsResponse
+	w = do("GET", "/api/v1/admin/audit?action=auth.login_failed&user_id="+userID.String(), nil, "X-Admin-Token", "admin-secret")
+	if err := json.NewDecoder(w.Body).Decode(&admin); err != nil {
+		t.Fatalf("Failed to decode admin audit log: %v", err)
+	}
+	if len(admin.Events) != 1 || admin.Events[0].UserID != userID {
+		t.Errorf("Expected the failed login of the user, got %+v", admin.Events)
+	}
+	if w := do("GET", "/api/v1/admin/audit?since="+time.Now().Add(time.Hour).Format(time.RFC3339), nil, "X-Admin-Token", "admin-secret"); !strings.Contains(w.Body.String(), `"events":[]`) {
+		t.Errorf("Expected no events after the given time, got %s", w.Body.String())
+	}
+}
+
+// txDataStorage runs transactions by noting whether they failed
+type txDataStorage struct {
+	DataStorage
+	failed []error
+}
+
+func (s *txDataStorage) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := fn(ctx)
+	s.failed = append(s.failed, err)
+	return err
+}
+
+// failingAuditStorage cannot add events
+type failingAuditStorage struct {
+	AuditStorage
+}
+
+func (failingAuditStorage) AddAuditEvent(context.Context, *models.AuditEvent) error {
+	return errors.New("audit log unavailable")
+}
+
+func TestAuditedDataStorage_Transaction(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	tx := &txDataStorage{DataStorage: store}
+	userID := uuid.New()
+	if err := store.CreateUser(ctx, &models.User{ID: userID, Username: "audited"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	data := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "n", Data: []byte("d")}
+
+	if err := NewAuditedDataStorage(tx, store, AuditOptions{}).CreateData(ctx, data); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	if err := NewAuditedDataStorage(tx, failingAuditStorage{store}, AuditOptions{}).UpdateData(ctx, data); err == nil {
+		t.Fatal("Expected the update to fail with its audit event")
+	}
+	if len(tx.failed) != 2 || tx.failed[0] != nil || tx.failed[1] == nil {
+		t.Errorf("Expected a committed and a rolled back transaction, got %v", tx.failed)
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxBatchOperations bounds the operations of one batch
+const maxBatchOperations = 1000
+
+// handleBatchData applies creates, updates and deletes of the user's items in
+// one transaction. Every operation is checked first; if one is refused, none
+// is applied and the response names it with its status.
+func handleBatchData(dataStorage DataStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.BatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if !replyBodyTooLarge(w, err) {
+				apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			}
+			return
+		}
+		if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+			apierror.Error(w, fmt.Sprintf("A batch needs between 1 and %d operations", maxBatchOperations), http.StatusBadRequest)
+			return
+		}
+
+		now := opts.Clock.Now()
+		changes := make([]models.DataChange, 0, len(req.Operations))
+		results := make([]models.BatchResult, len(req.Operations))
+		touched := make(map[uuid.UUID]bool)
+		refused := 0
+		for i, op := range req.Operations {
+			change, status, reason := checkBatchOperation(r, dataStorage, userID, op, now, touched, opts)
+			results[i] = models.BatchResult{Op: op.Op, ID: op.ID, Status: status, Error: reason}
+			if status != http.StatusOK {
+				if refused == 0 {
+					refused = status
+				}
+				continue
+			}
+			changes = append(changes, change)
+		}
+		if refused != 0 {
+			writeBatchResponse(w, refused, models.BatchResponse{Results: results})
+			return
+		}
+
+		if err := dataStorage.ApplyDataBatch(r.Context(), changes); err != nil {
+			if err.Error() == "data changed during batch" {
+				apierror.Error(w, "Data was modified by another device", http.StatusConflict)
+				return
+			}
+			apierror.Error(w, "Failed to apply batch", http.StatusInternalServerError)
+			return
+		}
+
+		for i, change := range changes {
+			results[i].ID = change.Data.ID
+			if change.Op != models.BatchDelete {
+				results[i].Revision = change.Data.Revision
+			}
+		}
+		logger.FromContext(r.Context()).Info("Batch applied", zap.String("user_id", userID.String()), zap.Int("operations", len(changes)))
+		writeBatchResponse(w, http.StatusOK, models.BatchResponse{Applied: true, Results: results})
+	}
+}
+
+// checkBatchOperation turns one operation of a batch into the change to
+// apply. It returns the status to refuse the batch with and why, or 200.
+func checkBatchOperation(r *http.Request, dataStorage DataStorage, userID uuid.UUID, op models.BatchOperation,
+	now time.Time, touched map[uuid.UUID]bool, opts Options) (models.DataChange, int, string) {
+	change := models.DataChange{Op: op.Op}
+	var tags, domains []string
+	if op.Op == models.BatchCreate || op.Op == models.BatchUpdate {
+		if op.Data == nil {
+			return change, http.StatusBadRequest, "data is required"
+		}
+		if err := validateDataRequest(*op.Data); err != nil {
+			return change, http.StatusBadRequest, err.Error()
+		}
+		var err error
+		if tags, err = requestTags(*op.Data); err != nil {
+			return change, http.StatusBadRequest, "Invalid tags: " + err.Error()
+		}
+		if domains, err = requestDomains(op.Data.Domains); err != nil {
+			return change, http.StatusBadRequest, "Invalid domains: " + err.Error()
+		}
+	}
+
+	if op.Op == models.BatchCreate {
+		change.Data = &models.Data{
+			ID:          opts.DataIDs.NewID(),
+			UserID:      userID,
+			Type:        op.Data.Type,
+			Name:        op.Data.Name,
+			Description: op.Data.Description,
+			Data:        op.Data.Data,
+			Metadata:    op.Data.Metadata,
+			Environment: op.Data.Environment,
+			Tags:        tags,
+			Domains:     domains,
+			Icon:        requestIcon(op.Data.Icon),
+			Checksum:    op.Data.Checksum,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		setExpiry(change.Data, op.Data.ExpiresAt, opts)
+		return change, http.StatusOK, ""
+	}
+	if op.Op != models.BatchUpdate && op.Op != models.BatchDelete {
+		return change, http.StatusBadRequest, fmt.Sprintf("unknown operation %q", op.Op)
+	}
+
+	if touched[op.ID] {
+		return change, http.StatusBadRequest, "the item is changed twice in the batch"
+	}
+	touched[op.ID] = true
+	data, err := dataStorage.GetDataByID(r.Context(), op.ID)
+	if err != nil {
+		if err.Error() == "data not found" {
+			return change, http.StatusNotFound, "Data not found"
+		}
+		return change, http.StatusInternalServerError, "Failed to get data"
+	}
+	if data.UserID != userID {
+		return change, http.StatusForbidden, "Access denied"
+	}
+	change.Data = data
+	if op.Op == models.BatchDelete {
+		return change, http.StatusOK, ""
+	}
+
+	if status, reason := checkUpdatePrecondition("", *op.Data, data); status != 0 {
+		return change, status, reason
+	}
+	change.BaseRevision = data.Revision
+	data.Type = op.Data.Type
+	data.Name = op.Data.Name
+	data.Description = op.Data.Description
+	data.Data = op.Data.Data
+	data.Metadata = op.Data.Metadata
+	data.Environment = op.Data.Environment
+	if op.Data.Tags != nil {
+		data.Tags = tags
+	}
+	if op.Data.Domains != nil {
+		data.Domains = domains
+	}
+	if op.Data.Icon != nil {
+		data.Icon = requestIcon(op.Data.Icon)
+	}
+	setExpiry(data, op.Data.ExpiresAt, opts)
+	data.Checksum = op.Data.Checksum
+	data.UpdatedAt = now
+	return change, http.StatusOK, ""
+}
+
+func writeBatchResponse(w http.ResponseWriter, status int, response models.BatchResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Log.Error("Failed to encode response", zap.Error(err))
+	}
+}
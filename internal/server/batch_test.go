@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_BatchData(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	hub := NewEventHub()
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, NewNotifyingDataStorage(NewAuditedDataStorage(store, store, AuditOptions{}), hub, Options{}), jwtManager, Options{})
+
+	userID, otherID := uuid.New(), uuid.New()
+	if err := store.CreateUser(ctx, &models.User{ID: userID, Username: "owner"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	token, _ := jwtManager.GenerateToken(userID, "owner")
+	events, cancel := hub.Subscribe(userID)
+	defer cancel()
+
+	existing := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "existing", Data: []byte("v1")}
+	doomed := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "doomed", Data: []byte("x")}
+	foreign := &models.Data{ID: uuid.New(), UserID: otherID, Type: models.DataTypeText, Name: "foreign", Data: []byte("x")}
+	for _, data := range []*models.Data{existing, doomed, foreign} {
+		if err := store.CreateData(ctx, data); err != nil {
+			t.Fatalf("CreateData() error = %v", err)
+		}
+	}
+
+	batch := func(ops ...models.BatchOperation) (int, models.BatchResponse) {
+		body, _ := json.Marshal(models.BatchRequest{Operations: ops})
+		req := httptest.NewRequest("POST", "/api/v1/data/batch", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp models.BatchResponse
+		if w.Header().Get("Content-Type") == "application/json" {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return w.Code, resp
+	}
+	revision := func(n int) *int { return &n }
+	create := models.BatchOperation{Op: models.BatchCreate, Data: &models.DataRequest{Type: models.DataTypeText, Name: "new", Data: []byte("n")}}
+	update := models.BatchOperation{Op: models.BatchUpdate, ID: existing.ID,
+		Data: &models.DataRequest{Type: models.DataTypeText, Name: "renamed", Data: []byte("v2"), BaseRevision: revision(1)}}
+	remove := models.BatchOperation{Op: models.BatchDelete, ID: doomed.ID}
+	badTags, badDomains := []string{"two words"}, []string{"not a host"}
+
+	refused := []struct {
+		name string
+		ops  []models.BatchOperation
+		want int
+	}{
+		{name: "empty", want: http.StatusBadRequest},
+		{name: "unknown operation", ops: []models.BatchOperation{create, {Op: "merge", ID: existing.ID}}, want: http.StatusBadRequest},
+		{name: "invalid data", ops: []models.BatchOperation{create, {Op: models.BatchCreate, Data: &models.DataRequest{Type: "nope", Name: "x", Data: []byte("x")}}}, want: http.StatusBadRequest},
+		{name: "invalid tags", ops: []models.BatchOperation{create, {Op: models.BatchCreate,
+			Data: &models.DataRequest{Type: models.DataTypeText, Name: "x", Data: []byte("x"), Tags: &badTags}}}, want: http.StatusBadRequest},
+		{name: "invalid domains", ops: []models.BatchOperation{{Op: models.BatchUpdate, ID: existing.ID,
+			Data: &models.DataRequest{Type: models.DataTypeText, Name: "x", Data: []byte("x"), Domains: &badDomains, BaseRevision: revision(1)}}}, want: http.StatusBadRequest},
+		{name: "same item twice", ops: []models.BatchOperation{update, {Op: models.BatchDelete, ID: existing.ID}}, want: http.StatusBadRequest},
+		{name: "another user's item", ops: []models.BatchOperation{create, {Op: models.BatchDelete, ID: foreign.ID}}, want: http.StatusForbidden},
+		{name: "missing item", ops: []models.BatchOperation{create, {Op: models.BatchDelete, ID: uuid.New()}}, want: http.StatusNotFound},
+		{name: "stale revision", ops: []models.BatchOperation{create, {Op: models.BatchUpdate, ID: existing.ID,
+			Data: &models.DataRequest{Type: models.DataTypeText, Name: "stale", Data: []byte("x"), BaseRevision: revision(7)}}}, want: http.StatusConflict},
+		{name: "no base revision", ops: []models.BatchOperation{{Op: models.BatchUpdate, ID: existing.ID,
+			Data: &models.DataRequest{Type: models.DataTypeText, Name: "blind", Data: []byte("x")}}}, want: http.StatusPreconditionRequired},
+	}
+	for _, tt := range refused {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := batch(tt.ops...)
+			if code != tt.want || resp.Applied {
+				t.Errorf("Expected status %d and nothing applied, got %d %+v", tt.want, code, resp)
+			}
+			if len(tt.ops) > 0 && len(resp.Results) != len(tt.ops) {
+				t.Errorf("Expected a result for each operation, got %+v", resp.Results)
+			}
+		})
+	}
+	if items, _ := store.GetDataByUserID(ctx, userID); len(items) != 2 {
+		t.Fatalf("Expected refused batches to change nothing, got %d items", len(items))
+	}
+
+	code, resp := batch(create, update, remove)
+	if code != http.StatusOK || !resp.Applied || len(resp.Results) != 3 {
+		t.Fatalf("Expected the batch to be applied, got %d %+v", code, resp)
+	}
+	if resp.Results[0].Revision != 1 || resp.Results[1].Revision != 2 || resp.Results[2].ID != doomed.ID {
+		t.Errorf("Unexpected results %+v", resp.Results)
+	}
+	if data, err := store.GetDataByID(ctx, resp.Results[0].ID); err != nil || data.Name != "new" || data.UserID != userID {
+		t.Errorf("Expected the created item, got %+v, %v", data, err)
+	}
+	if data, _ := store.GetDataByID(ctx, existing.ID); data.Name != "renamed" || data.Revision != 2 {
+		t.Errorf("Expected the updated item, got %+v", data)
+	}
+	if _, err := store.GetDataByID(ctx, doomed.ID); err == nil {
+		t.Error("Expected the deleted item to be gone")
+	}
+
+	if len(events) != 3 {
+		t.Errorf("Expected an event for each change, got %d", len(events))
+	}
+	auditEvents, _ := store.GetAuditEvents(ctx, userID, 100)
+	if len(auditEvents) != 3 {
+		t.Errorf("Expected an audit event for each change, got %d", len(auditEvents))
+	}
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// blobSweepBatch is how many objects of deleted chunks one sweep deletes at most
+const blobSweepBatch = 500
+
+// BlobChunkStorage stores the content chunks of binary items as objects that
+// clients transfer directly
+type BlobChunkStorage interface {
+	ReserveDataChunk(ctx context.Context, dataID uuid.UUID, index int) (string, error)
+	GetDataChunkKeys(ctx context.Context, dataID uuid.UUID) ([]string, error)
+}
+
+// Presigner creates URLs that transfer objects without credentials until they
+// expire
+type Presigner interface {
+	PresignPut(key string, size int64, expires time.Duration) (string, error)
+	PresignGet(key string, expires time.Duration) (string, error)
+}
+
+// BlobOptions configure the presigned chunk routes
+type BlobOptions struct {
+	// Expiry is how long presigned URLs are valid
+	Expiry time.Duration
+	// Clock computes the expiry times of presigned URLs; nil means the system clock
+	Clock clock.Clock
+}
+
+// RegisterBlobRoutes registers the routes handing out presigned URLs of the
+// chunks of binary items, so their content goes to and from object storage
+// without passing through the server
+func RegisterBlobRoutes(r *mux.Router, blobStorage BlobChunkStorage, presigner Presigner, dataStorage DataStorage,
+	jwtManager *auth.JWTManager, opts BlobOptions) {
+	if opts.Clock == nil {
+		opts.Clock = clock.System{}
+	}
+	presign := r.Path("/api/v1/data/{id}/chunks/presign").Subrouter()
+	presign.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	presign.Methods("POST").HandlerFunc(handlePresignChunkUpload(blobStorage, presigner, dataStorage, opts))
+	presign.Methods("GET").HandlerFunc(handlePresignChunkDownloads(blobStorage, presigner, dataStorage, opts))
+}
+
+// handlePresignChunkUpload reserves the chunk at the index given in the query
+// and returns a URL uploading exactly size bytes of ciphertext to it. Chunks
+// go in order as with uploads through the server.
+func handlePresignChunkUpload(blobStorage BlobChunkStorage, presigner Presigner, dataStorage DataStorage, opts BlobOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		index, err := strconv.Atoi(r.URL.Query().Get("index"))
+		if err != nil || index < 0 {
+			apierror.Error(w, "Invalid chunk index", http.StatusBadRequest)
+			return
+		}
+		size, err := strconv.Atoi(r.URL.Query().Get("size"))
+		if err != nil || size <= 0 {
+			apierror.Error(w, "Invalid chunk size", http.StatusBadRequest)
+			return
+		}
+		if size > maxChunkCiphertext {
+			apierror.Error(w, "Chunk too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+		if data.Type != models.DataTypeBinary {
+			apierror.Error(w, "Only binary data is stored in chunks", http.StatusBadRequest)
+			return
+		}
+
+		key, err := blobStorage.ReserveDataChunk(r.Context(), data.ID, index)
+		if err != nil {
+			switch err.Error() {
+			case "data not found":
+				apierror.Error(w, "Data not found", http.StatusNotFound)
+			case "chunk out of order":
+				apierror.Error(w, "Chunk out of order", http.StatusConflict)
+			default:
+				apierror.Error(w, "Failed to reserve chunk", http.StatusInternalServerError)
+			}
+			return
+		}
+		expiresAt := opts.Clock.Now().Add(opts.Expiry)
+		url, err := presigner.PresignPut(key, int64(size), opts.Expiry)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Failed to presign chunk upload", zap.Error(err))
+			apierror.Error(w, "Failed to presign chunk upload", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		response := models.PresignedChunksResponse{
+			Chunks:    []models.PresignedChunk{{Index: index, Method: http.MethodPut, URL: url}},
+			ExpiresAt: expiresAt,
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handlePresignChunkDownloads returns a URL downloading each chunk of an item,
+// in order. Items with chunks stored in the database get 409 and are
+// downloaded through the chunks route instead.
+func handlePresignChunkDownloads(blobStorage BlobChunkStorage, presigner Presigner, dataStorage DataStorage, opts BlobOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		keys, err := blobStorage.GetDataChunkKeys(r.Context(), data.ID)
+		if err != nil {
+			if err.Error() == "chunk is stored in the database" {
+				apierror.Error(w, "Chunks are stored on the server, download them from /chunks", http.StatusConflict)
+				return
+			}
+			apierror.Error(w, "Failed to get chunks", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.PresignedChunksResponse{
+			Chunks:    make([]models.PresignedChunk, 0, len(keys)),
+			ExpiresAt: opts.Clock.Now().Add(opts.Expiry),
+		}
+		for index, key := range keys {
+			url, err := presigner.PresignGet(key, opts.Expiry)
+			if err != nil {
+				logger.FromContext(r.Context()).Error("Failed to presign chunk download", zap.Error(err))
+				apierror.Error(w, "Failed to presign chunk download", http.StatusInternalServerError)
+				return
+			}
+			response.Chunks = append(response.Chunks, models.PresignedChunk{Index: index, Method: http.MethodGet, URL: url})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// BlobSweeper deletes the objects of chunks that were deleted or replaced
+type BlobSweeper interface {
+	SweepBlobs(ctx context.Context, limit int) (int, error)
+}
+
+// RunBlobSweeps deletes the objects of deleted and replaced chunks now and
+// then every interval until ctx is done
+func RunBlobSweeps(ctx context.Context, sweeper BlobSweeper, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sweepBlobs(ctx, sweeper)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepBlobs deletes queued objects in batches until none are left and
+// returns how many it deleted
+func sweepBlobs(ctx context.Context, sweeper BlobSweeper) int {
+	total := 0
+	for ctx.Err() == nil {
+		deleted, err := sweeper.SweepBlobs(ctx, blobSweepBatch)
+		total += deleted
+		if err != nil {
+			logger.Log.Error("Failed to delete objects of deleted chunks", zap.Error(err))
+			break
+		}
+		if deleted < blobSweepBatch {
+			break
+		}
+	}
+	if total > 0 {
+		logger.Log.Info("Deleted objects of deleted chunks", zap.Int("count", total))
+	}
+	return total
+}
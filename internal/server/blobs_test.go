@@ -0,0 +1,227 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// memoryChunkKeys is a BlobChunkStorage keeping the reserved keys in memory
+type memoryChunkKeys struct {
+	mutex sync.Mutex
+	keys  map[uuid.UUID][]string
+}
+
+func (m *memoryChunkKeys) ReserveDataChunk(_ context.Context, dataID uuid.UUID, index int) (string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	keys := m.keys[dataID]
+	if index > len(keys) {
+		return "", storage.ErrChunkOutOfOrder
+	}
+	key := fmt.Sprintf("chunks/%s/%d", dataID, index)
+	if index == len(keys) {
+		m.keys[dataID] = append(keys, key)
+	}
+	return key, nil
+}
+
+func (m *memoryChunkKeys) GetDataChunkKeys(_ context.Context, dataID uuid.UUID) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.keys[dataID], nil
+}
+
+// fakePresigner returns URLs naming the method, key and size
+type fakePresigner struct{}
+
+func (fakePresigner) PresignPut(key string, size int64, _ time.Duration) (string, error) {
+	return fmt.Sprintf("https://bucket.example/%s?size=%d", key, size), nil
+}
+
+func (fakePresigner) PresignGet(key string, _ time.Duration) (string, error) {
+	return "https://bucket.example/" + key, nil
+}
+
+func TestServer_PresignedChunks(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	keys := &memoryChunkKeys{keys: make(map[uuid.UUID][]string)}
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterChunkRoutes(router, store, store, jwtManager)
+	RegisterBlobRoutes(router, keys, fakePresigner{}, store, jwtManager, BlobOptions{Expiry: 15 * time.Minute})
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		return doRequest(router, method, path, token, nil)
+	}
+	register := func(username string) string {
+		payload, _ := json.Marshal(models.UserRequest{Username: username, Password: "login-pass", MasterPassword: "master-password"})
+		req := httptest.NewRequest("POST", "/api/v1/register", bytes.NewReader(payload))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var authResp models.AuthResponse
+		if err := json.NewDecoder(w.Body).Decode(&authResp); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+		return authResp.Token
+	}
+	create := func(token string, dataType models.DataType) (uuid.UUID, string) {
+		payload, _ := json.Marshal(models.DataRequest{Type: dataType, Name: "item", Data: []byte("sealed")})
+		req := httptest.NewRequest("POST", "/api/v1/data", bytes.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var dataResp models.DataResponse
+		if err := json.NewDecoder(w.Body).Decode(&dataResp); err != nil {
+			t.Fatalf("Failed to create data: %v", err)
+		}
+		return dataResp.Data.ID, "/api/v1/data/" + dataResp.Data.ID.String() + "/chunks/presign"
+	}
+
+	owner := register("owner")
+	other := register("other")
+	dataID, path := create(owner, models.DataTypeBinary)
+	_, textPath := create(owner, models.DataTypeText)
+
+	tests := []struct {
+		name           string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "unauthenticated", path: path + "?index=0&size=10", expectedStatus: http.StatusUnauthorized},
+		{name: "other user", path: path + "?index=0&size=10", token: other, expectedStatus: http.StatusForbidden},
+		{name: "bad index", path: path + "?index=-1&size=10", token: owner, expectedStatus: http.StatusBadRequest},
+		{name: "no size", path: path + "?index=0", token: owner, expectedStatus: http.StatusBadRequest},
+		{name: "too large", path: path + fmt.Sprintf("?index=0&size=%d", maxChunkCiphertext+1), token: owner,
+			expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "not binary", path: textPath + "?index=0&size=10", token: owner, expectedStatus: http.StatusBadRequest},
+		{name: "out of order", path: path + "?index=1&size=10", token: owner, expectedStatus: http.StatusConflict},
+		{name: "first", path: path + "?index=0&size=10", token: owner, expectedStatus: http.StatusCreated},
+		{name: "second", path: path + "?index=1&size=20", token: owner, expectedStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do("POST", tt.path, tt.token); w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := do("POST", path+"?index=2&size=30", owner)
+	var upload models.PresignedChunksResponse
+	if err := json.NewDecoder(w.Body).Decode(&upload); err != nil {
+		t.Fatalf("Failed to decode upload: %v", err)
+	}
+	want := fmt.Sprintf("https://bucket.example/chunks/%s/2?size=30", dataID)
+	if len(upload.Chunks) != 1 || upload.Chunks[0].Method != http.MethodPut || upload.Chunks[0].URL != want {
+		t.Errorf("Unexpected presigned upload %+v", upload.Chunks)
+	}
+	if upload.ExpiresAt.IsZero() {
+		t.Error("Expected the expiry of the URL")
+	}
+
+	if w := do("GET", path, other); w.Code != http.StatusForbidden {
+		t.Errorf("Expected other users to be denied the chunks, got %d", w.Code)
+	}
+	w = do("GET", path, owner)
+	var download models.PresignedChunksResponse
+	if err := json.NewDecoder(w.Body).Decode(&download); err != nil {
+		t.Fatalf("Failed to decode downloads: %v", err)
+	}
+	if len(download.Chunks) != 3 || download.Chunks[2].Index != 2 || download.Chunks[2].Method != http.MethodGet ||
+		download.Chunks[2].URL != fmt.Sprintf("https://bucket.example/chunks/%s/2", dataID) {
+		t.Errorf("Unexpected presigned downloads %+v", download.Chunks)
+	}
+
+	// the chunk routes still serve the same prefix
+	if w := do("GET", "/api/v1/data/"+dataID.String()+"/chunks", owner); w.Code != http.StatusOK {
+		t.Errorf("Expected the chunks route to keep working, got %d", w.Code)
+	}
+}
+
+// inlineChunkKeys is a BlobChunkStorage whose chunks are stored in the database
+type inlineChunkKeys struct {
+	memoryChunkKeys
+}
+
+func (*inlineChunkKeys) GetDataChunkKeys(context.Context, uuid.UUID) ([]string, error) {
+	return nil, storage.ErrChunkInline
+}
+
+func TestServer_PresignedChunks_Inline(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterBlobRoutes(router, &inlineChunkKeys{}, fakePresigner{}, store, jwtManager, BlobOptions{Expiry: time.Minute})
+
+	payload, _ := json.Marshal(models.UserRequest{Username: "owner", Password: "login-pass", MasterPassword: "master-password"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/register", bytes.NewReader(payload)))
+	var authResp models.AuthResponse
+	if err := json.NewDecoder(w.Body).Decode(&authResp); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	payload, _ = json.Marshal(models.DataRequest{Type: models.DataTypeBinary, Name: "file", Data: []byte("sealed")})
+	req := httptest.NewRequest("POST", "/api/v1/data", bytes.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+authResp.Token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var dataResp models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&dataResp); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/data/"+dataResp.Data.ID.String()+"/chunks/presign", nil)
+	req.Header.Set("Authorization", "Bearer "+authResp.Token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for chunks stored in the database, got %d", w.Code)
+	}
+}
+
+// countingSweeper deletes from a queue of size objects
+type countingSweeper struct {
+	queued int
+	err    error
+}
+
+func (s *countingSweeper) SweepBlobs(_ context.Context, limit int) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	deleted := min(limit, s.queued)
+	s.queued -= deleted
+	return deleted, nil
+}
+
+func TestSweepBlobs(t *testing.T) {
+	sweeper := &countingSweeper{queued: 2*blobSweepBatch + 3}
+	if deleted := sweepBlobs(context.Background(), sweeper); deleted != 2*blobSweepBatch+3 {
+		t.Errorf("Expected every queued object deleted, got %d", deleted)
+	}
+	if sweeper.queued != 0 {
+		t.Errorf("Expected an empty queue, %d left", sweeper.queued)
+	}
+
+	if deleted := sweepBlobs(context.Background(), &countingSweeper{queued: 5, err: errors.New("unavailable")}); deleted != 0 {
+		t.Errorf("Expected no deletions on errors, got %d", deleted)
+	}
+}
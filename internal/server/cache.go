@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/cache"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+)
+
+// StorageCache holds items read by ID and users read by username for the
+// caching storages, which forget what their writes change. Every store
+// writing items or users must be wrapped with it, or cached values outlive
+// the writes of the unwrapped ones until their TTL.
+type StorageCache struct {
+	data  *cache.LRU[uuid.UUID, *models.Data]
+	users *cache.LRU[string, *models.User]
+	// generation counts forgets, so a value read before one is not cached
+	// after it
+	generation atomic.Uint64
+}
+
+// NewStorageCache creates a cache of at most size items and size users, each
+// kept for ttl
+func NewStorageCache(size int, ttl time.Duration) *StorageCache {
+	return &StorageCache{
+		data:  cache.NewLRU[uuid.UUID, *models.Data](size, ttl),
+		users: cache.NewLRU[string, *models.User](size, ttl),
+	}
+}
+
+// cacheTxKey is the context key of the items written in a transaction
+type cacheTxKey struct{}
+
+// cacheTx collects the items written in a transaction, to forget them again
+// once it ends, as reads in between see them as they were
+type cacheTx struct {
+	mutex sync.Mutex
+	ids   []uuid.UUID
+	all   bool
+}
+
+// forgetData forgets items, after the transaction of ctx ends as well
+func (c *StorageCache) forgetData(ctx context.Context, ids ...uuid.UUID) {
+	c.generation.Add(1)
+	for _, id := range ids {
+		c.data.Remove(id)
+	}
+	if tx, ok := ctx.Value(cacheTxKey{}).(*cacheTx); ok {
+		tx.mutex.Lock()
+		tx.ids = append(tx.ids, ids...)
+		tx.mutex.Unlock()
+	}
+}
+
+// forgetAllData forgets every item, after the transaction of ctx ends as well
+func (c *StorageCache) forgetAllData(ctx context.Context) {
+	c.generation.Add(1)
+	c.data.Purge()
+	if tx, ok := ctx.Value(cacheTxKey{}).(*cacheTx); ok {
+		tx.mutex.Lock()
+		tx.all = true
+		tx.mutex.Unlock()
+	}
+}
+
+// forgetUser forgets a user and all of their items
+func (c *StorageCache) forgetUser(userID uuid.UUID) {
+	c.generation.Add(1)
+	c.users.RemoveFunc(func(_ string, user *models.User) bool {
+		return user.ID == userID
+	})
+	c.data.RemoveFunc(func(_ uuid.UUID, data *models.Data) bool {
+		return data.UserID == userID
+	})
+}
+
+// inTx runs fn in a transaction of store like inTx and forgets the items
+// written in it once it ends. A transaction joined by ctx is left to its owner.
+func (c *StorageCache) inTx(ctx context.Context, store interface{}, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(cacheTxKey{}).(*cacheTx); ok {
+		return inTx(ctx, store, fn)
+	}
+	tx := &cacheTx{}
+	err := inTx(context.WithValue(ctx, cacheTxKey{}, tx), store, fn)
+	if tx.all {
+		c.forgetAllData(ctx)
+	} else if len(tx.ids) > 0 {
+		c.forgetData(ctx, tx.ids...)
+	}
+	return err
+}
+
+// cloneData copies data, so callers changing what they read do not change
+// the cache
+func cloneData(data *models.Data) *models.Data {
+	clone := *data
+	clone.Data = slices.Clone(data.Data)
+	clone.Tags = slices.Clone(data.Tags)
+	clone.Checksum = slices.Clone(data.Checksum)
+	clone.Domains = slices.Clone(data.Domains)
+	clone.Icon = slices.Clone(data.Icon)
+	return &clone
+}
+
+// cachingDataStorage serves items read by ID from a StorageCache
+type cachingDataStorage struct {
+	DataStorage
+	cache *StorageCache
+}
+
+// NewCachingDataStorage wraps dataStorage so that items read by ID are
+// served from c until they are written or expire. Reads of contexts of
+// storage.ReadPrimary skip the cache, so writes never act on a cached item.
+// Wrap it in the storages of NewAuditedDataStorage and
+// NewNotifyingDataStorage, not the other way round, so their transactions
+// cover it.
+func NewCachingDataStorage(dataStorage DataStorage, c *StorageCache) DataStorage {
+	return &cachingDataStorage{DataStorage: dataStorage, cache: c}
+}
+
+// InTx runs fn in a transaction of the wrapped storage, if it has them
+func (s *cachingDataStorage) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.cache.inTx(ctx, s.DataStorage, fn)
+}
+
+// RecordRead records the read in the audit log of the wrapped storage, if any
+func (s *cachingDataStorage) RecordRead(ctx context.Context, data *models.Data) {
+	recordRead(ctx, s.DataStorage, data)
+}
+
+// GetDataByID gets data from the cache, or from the wrapped storage
+func (s *cachingDataStorage) GetDataByID(ctx context.Context, dataID uuid.UUID) (*models.Data, error) {
+	if storage.ReadsPrimary(ctx) {
+		return s.DataStorage.GetDataByID(ctx, dataID)
+	}
+	if data, ok := s.cache.data.Get(dataID); ok {
+		return cloneData(data), nil
+	}
+
+	generation := s.cache.generation.Load()
+	data, err := s.DataStorage.GetDataByID(ctx, dataID)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache.generation.Load() == generation {
+		s.cache.data.Add(dataID, cloneData(data))
+	}
+	return data, nil
+}
+
+// UpdateData updates data and forgets it
+func (s *cachingDataStorage) UpdateData(ctx context.Context, data *models.Data) error {
+	defer s.cache.forgetData(ctx, data.ID)
+	return s.DataStorage.UpdateData(ctx, data)
+}
+
+// DeleteData deletes data and forgets it
+func (s *cachingDataStorage) DeleteData(ctx context.Context, dataID uuid.UUID) error {
+	defer s.cache.forgetData(ctx, dataID)
+	return s.DataStorage.DeleteData(ctx, dataID)
+}
+
+// ApplyDataBatch applies a batch and forgets the items it changes
+func (s *cachingDataStorage) ApplyDataBatch(ctx context.Context, changes []models.DataChange) error {
+	ids := make([]uuid.UUID, 0, len(changes))
+	for _, change := range changes {
+		ids = append(ids, change.Data.ID)
+	}
+	defer s.cache.forgetData(ctx, ids...)
+	return s.DataStorage.ApplyDataBatch(ctx, changes)
+}
+
+// cachingUserStorage serves users read by username from a StorageCache
+type cachingUserStorage struct {
+	UserStorage
+	cache *StorageCache
+}
+
+// NewCachingUserStorage wraps userStorage so that users read by username,
+// as on every login, are served from c until they are written or expire.
+// Wrap it in the storage of NewAuditedUserStorage, not the other way round.
+func NewCachingUserStorage(userStorage UserStorage, c *StorageCache) UserStorage {
+	return &cachingUserStorage{UserStorage: userStorage, cache: c}
+}
+
+// RecordLogin records the login in the audit log of the wrapped storage, if any
+func (s *cachingUserStorage) RecordLogin(ctx context.Context, user *models.User, succeeded bool) {
+	recordLogin(ctx, s.UserStorage, user, succeeded)
+}
+
+// GetUserByUsername gets a user from the cache, or from the wrapped storage
+func (s *cachingUserStorage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if user, ok := s.cache.users.Get(username); ok {
+		clone := *user
+		return &clone, nil
+	}
+
+	generation := s.cache.generation.Load()
+	user, err := s.UserStorage.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache.generation.Load() == generation {
+		clone := *user
+		s.cache.users.Add(username, &clone)
+	}
+	return user, nil
+}
+
+// SetUserSalt sets the salt of a user and forgets them
+func (s *cachingUserStorage) SetUserSalt(ctx context.Context, userID uuid.UUID, salt string) error {
+	defer s.cache.forgetUser(userID)
+	return s.UserStorage.SetUserSalt(ctx, userID, salt)
+}
+
+// cachingRotationStorage forgets rotated users and items
+type cachingRotationStorage struct {
+	RotationStorage
+	cache *StorageCache
+}
+
+// NewCachingRotationStorage wraps rotationStorage so that a rotation forgets
+// the user and all of their items in c
+func NewCachingRotationStorage(rotationStorage RotationStorage, c *StorageCache) RotationStorage {
+	return &cachingRotationStorage{RotationStorage: rotationStorage, cache: c}
+}
+
+// RotateVault rotates the vault of a user and forgets the user and their items
+func (s *cachingRotationStorage) RotateVault(ctx context.Context, userID uuid.UUID, header models.RotationHeader,
+	next func() (*models.RotationRecord, error)) (*models.RotationResponse, error) {
+	defer s.cache.forgetUser(userID)
+	return s.RotationStorage.RotateVault(ctx, userID, header, next)
+}
+
+// cachingCollectionStorage forgets items moved between collections
+type cachingCollectionStorage struct {
+	CollectionStorage
+	cache *StorageCache
+}
+
+// NewCachingCollectionStorage wraps collectionStorage so that filing an item
+// forgets it in c, and deleting a collection, which moves its items, forgets
+// every item
+func NewCachingCollectionStorage(collectionStorage CollectionStorage, c *StorageCache) CollectionStorage {
+	return &cachingCollectionStorage{CollectionStorage: collectionStorage, cache: c}
+}
+
+// SetDataCollection files an item in a collection and forgets it
+func (s *cachingCollectionStorage) SetDataCollection(ctx context.Context, dataID uuid.UUID, collectionID *uuid.UUID) error {
+	defer s.cache.forgetData(ctx, dataID)
+	return s.CollectionStorage.SetDataCollection(ctx, dataID, collectionID)
+}
+
+// DeleteCollection deletes a collection and forgets every item
+func (s *cachingCollectionStorage) DeleteCollection(ctx context.Context, collectionID uuid.UUID) error {
+	defer s.cache.forgetAllData(ctx)
+	return s.CollectionStorage.DeleteCollection(ctx, collectionID)
+}
+
+// cachingExpiryStorage forgets items flagged as expiring
+type cachingExpiryStorage struct {
+	ExpiryStorage
+	cache *StorageCache
+}
+
+// NewCachingExpiryStorage wraps expiryStorage so that flagged items are
+// forgotten in c
+func NewCachingExpiryStorage(expiryStorage ExpiryStorage, c *StorageCache) ExpiryStorage {
+	return &cachingExpiryStorage{ExpiryStorage: expiryStorage, cache: c}
+}
+
+// FlagExpiringData flags expiring and expired items and forgets them
+func (s *cachingExpiryStorage) FlagExpiringData(ctx context.Context, now time.Time, warning time.Duration) ([]*models.Data, error) {
+	flagged, err := s.ExpiryStorage.FlagExpiringData(ctx, now, warning)
+	ids := make([]uuid.UUID, 0, len(flagged))
+	for _, data := range flagged {
+		ids = append(ids, data.ID)
+	}
+	s.cache.forgetData(ctx, ids...)
+	return flagged, err
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+)
+
+// countingStorage counts the reads that reach the storage
+type countingStorage struct {
+	*storage.MemoryStorage
+	dataReads atomic.Int32
+	userReads atomic.Int32
+}
+
+func (s *countingStorage) GetDataByID(ctx context.Context, dataID uuid.UUID) (*models.Data, error) {
+	s.dataReads.Add(1)
+	return s.MemoryStorage.GetDataByID(ctx, dataID)
+}
+
+func (s *countingStorage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	s.userReads.Add(1)
+	return s.MemoryStorage.GetUserByUsername(ctx, username)
+}
+
+func newCachedStore(t *testing.T) (*countingStorage, *StorageCache, *models.User, *models.Data) {
+	t.Helper()
+	store := &countingStorage{MemoryStorage: storage.NewMemoryStorage()}
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "alice", Password: "hash", Salt: "salt"}
+	if err := store.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	data := &models.Data{ID: uuid.New(), UserID: user.ID, Type: models.DataTypeText, Name: "note", Data: []byte("sealed"),
+		Tags: []string{"work"}}
+	if err := store.CreateData(ctx, data); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	return store, NewStorageCache(10, time.Minute), user, data
+}
+
+func TestCachingDataStorage(t *testing.T) {
+	ctx := context.Background()
+	store, c, _, data := newCachedStore(t)
+	cached := NewCachingDataStorage(store, c)
+
+	first, err := cached.GetDataByID(ctx, data.ID)
+	if err != nil {
+		t.Fatalf("GetDataByID() error = %v", err)
+	}
+	first.Name = "changed by the caller"
+	first.Tags[0] = "changed"
+	second, err := cached.GetDataByID(ctx, data.ID)
+	if err != nil {
+		t.Fatalf("GetDataByID() error = %v", err)
+	}
+	if got := store.dataReads.Load(); got != 1 {
+		t.Errorf("Expected 1 storage read for 2 reads, got %d", got)
+	}
+	if second.Name != "note" || second.Tags[0] != "work" {
+		t.Errorf("Expected the cache unaffected by callers, got %q %v", second.Name, second.Tags)
+	}
+
+	if _, err := cached.GetDataByID(storage.ReadPrimary(ctx), data.ID); err != nil {
+		t.Fatalf("GetDataByID() error = %v", err)
+	}
+	if got := store.dataReads.Load(); got != 2 {
+		t.Errorf("Expected reads for writes to skip the cache, got %d storage reads", got)
+	}
+
+	second.Name = "renamed"
+	if err := cached.UpdateData(ctx, second); err != nil {
+		t.Fatalf("UpdateData() error = %v", err)
+	}
+	third, err := cached.GetDataByID(ctx, data.ID)
+	if err != nil || third.Name != "renamed" {
+		t.Fatalf("Expected the update read back, got %+v, %v", third, err)
+	}
+
+	if err := cached.DeleteData(ctx, data.ID); err != nil {
+		t.Fatalf("DeleteData() error = %v", err)
+	}
+	if _, err := cached.GetDataByID(ctx, data.ID); err == nil {
+		t.Error("Expected the deleted item forgotten")
+	}
+}
+
+func TestCachingDataStorage_Tx(t *testing.T) {
+	ctx := context.Background()
+	store, c, _, data := newCachedStore(t)
+	cached := NewCachingDataStorage(store, c)
+
+	err := inTx(ctx, cached, func(ctx context.Context) error {
+		current, err := cached.GetDataByID(ctx, data.ID)
+		if err != nil {
+			return err
+		}
+		current.Name = "renamed"
+		if err := cached.UpdateData(ctx, current); err != nil {
+			return err
+		}
+		// a concurrent reader caching the item before the commit
+		c.data.Add(data.ID, cloneData(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("inTx() error = %v", err)
+	}
+	if got, err := cached.GetDataByID(ctx, data.ID); err != nil || got.Name != "renamed" {
+		t.Errorf("Expected the item forgotten once the transaction ended, got %+v, %v", got, err)
+	}
+}
+
+func TestCachingUserStorage(t *testing.T) {
+	ctx := context.Background()
+	store, c, user, data := newCachedStore(t)
+	users := NewCachingUserStorage(store, c)
+	cached := NewCachingDataStorage(store, c)
+
+	for i := 0; i < 3; i++ {
+		if _, err := users.GetUserByUsername(ctx, "alice"); err != nil {
+			t.Fatalf("GetUserByUsername() error = %v", err)
+		}
+	}
+	if got := store.userReads.Load(); got != 1 {
+		t.Errorf("Expected 1 storage read for 3 reads, got %d", got)
+	}
+	if _, err := users.GetUserByUsername(ctx, "bob"); err == nil {
+		t.Error("Expected unknown users not found")
+	}
+
+	if _, err := cached.GetDataByID(ctx, data.ID); err != nil {
+		t.Fatalf("GetDataByID() error = %v", err)
+	}
+	rotation := NewCachingRotationStorage(store, c)
+	_, _ = rotation.RotateVault(ctx, user.ID, models.RotationHeader{}, func() (*models.RotationRecord, error) {
+		return nil, context.Canceled
+	})
+	if c.users.Len() != 0 || c.data.Len() != 0 {
+		t.Errorf("Expected a rotation to forget the user and their items, %d users and %d items left",
+			c.users.Len(), c.data.Len())
+	}
+}
+
+func TestCachingCollectionAndExpiryStorage(t *testing.T) {
+	ctx := context.Background()
+	store, c, user, data := newCachedStore(t)
+	cached := NewCachingDataStorage(store, c)
+	collections := NewCachingCollectionStorage(store, c)
+
+	collection := &models.Collection{ID: uuid.New(), UserID: user.ID, Name: "Work"}
+	if err := collections.CreateCollection(ctx, collection); err != nil {
+		t.Fatalf("CreateCollection() error = %v", err)
+	}
+	if _, err := cached.GetDataByID(ctx, data.ID); err != nil {
+		t.Fatalf("GetDataByID() error = %v", err)
+	}
+	if err := collections.SetDataCollection(ctx, data.ID, &collection.ID); err != nil {
+		t.Fatalf("SetDataCollection() error = %v", err)
+	}
+	if got, err := cached.GetDataByID(ctx, data.ID); err != nil || got.CollectionID == nil {
+		t.Errorf("Expected the filed item read back, got %+v, %v", got, err)
+	}
+	if err := collections.DeleteCollection(ctx, collection.ID); err != nil {
+		t.Fatalf("DeleteCollection() error = %v", err)
+	}
+	if c.data.Len() != 0 {
+		t.Error("Expected deleting a collection to forget the items")
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	item, _ := cached.GetDataByID(ctx, data.ID)
+	item.ExpiresAt = &expiresAt
+	if err := cached.UpdateData(ctx, item); err != nil {
+		t.Fatalf("UpdateData() error = %v", err)
+	}
+	if _, err := cached.GetDataByID(ctx, data.ID); err != nil {
+		t.Fatalf("GetDataByID() error = %v", err)
+	}
+	flagged, err := NewCachingExpiryStorage(store, c).FlagExpiringData(ctx, time.Now(), 24*time.Hour)
+	if err != nil || len(flagged) != 1 {
+		t.Fatalf("FlagExpiringData() = %d items, %v", len(flagged), err)
+	}
+	if got, _ := cached.GetDataByID(ctx, data.ID); got.Expiry == "" {
+		t.Error("Expected the flagged item read back with its flag")
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// maxChunkCiphertext caps the size of one encrypted content chunk, so uploads
+// and downloads hold at most one chunk in memory
+const maxChunkCiphertext = 16 << 20
+
+// ChunkCountHeader carries the number of chunks in a chunk download
+const ChunkCountHeader = "X-Chunk-Count"
+
+type ChunkStorage interface {
+	PutDataChunk(ctx context.Context, dataID uuid.UUID, index int, chunk []byte) error
+	GetDataChunk(ctx context.Context, dataID uuid.UUID, index int) ([]byte, error)
+	CountDataChunks(ctx context.Context, dataID uuid.UUID) (int, error)
+}
+
+// RegisterChunkRoutes registers the routes storing the content of large binary
+// items in chunks
+func RegisterChunkRoutes(r *mux.Router, chunkStorage ChunkStorage, dataStorage DataStorage, jwtManager *auth.JWTManager) {
+	chunks := r.PathPrefix("/api/v1/data/{id}/chunks").Subrouter()
+	chunks.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	chunks.HandleFunc("", handleUploadDataChunk(chunkStorage, dataStorage)).Methods("POST")
+	chunks.HandleFunc("", handleDownloadDataChunks(chunkStorage, dataStorage)).Methods("GET")
+}
+
+// handleUploadDataChunk stores one encrypted chunk, sent as the raw body, at the
+// index given in the query. Chunks go in order; sending one again replaces it,
+// so an interrupted upload can be retried from the failed chunk.
+func handleUploadDataChunk(chunkStorage ChunkStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		index, err := strconv.Atoi(r.URL.Query().Get("index"))
+		if err != nil || index < 0 {
+			apierror.Error(w, "Invalid chunk index", http.StatusBadRequest)
+			return
+		}
+
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		chunk, err := io.ReadAll(io.LimitReader(r.Body, maxChunkCiphertext+1))
+		if err != nil {
+			apierror.Error(w, "Failed to read chunk", http.StatusBadRequest)
+			return
+		}
+		if err := PutDataChunk(r.Context(), chunkStorage, data, index, chunk); err != nil {
+			apierror.Reply(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(models.DataChunkResponse{Index: index, Size: len(chunk)}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleDownloadDataChunks streams the encrypted chunks of an item in order,
+// each prefixed with its length as a 4-byte big-endian integer
+func handleDownloadDataChunks(chunkStorage ChunkStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		count, err := CountDataChunks(r.Context(), chunkStorage, data)
+		if err != nil {
+			apierror.Reply(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set(ChunkCountHeader, strconv.Itoa(count))
+		flusher, _ := w.(http.Flusher)
+		var prefix [4]byte
+		for index := 0; index < count; index++ {
+			chunk, err := chunkStorage.GetDataChunk(r.Context(), data.ID, index)
+			if err != nil {
+				// the status is sent already; the short stream tells the client
+				logger.FromContext(r.Context()).Error("Failed to get chunk", zap.Error(err), zap.String("data_id", data.ID.String()),
+					zap.Int("index", index))
+				return
+			}
+			binary.BigEndian.PutUint32(prefix[:], uint32(len(chunk)))
+			if _, err := w.Write(prefix[:]); err != nil {
+				return
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
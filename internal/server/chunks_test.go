@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_DataChunks(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterChunkRoutes(router, store, store, jwtManager)
+
+	register := func(username string) string {
+		payload, _ := json.Marshal(models.UserRequest{Username: username, Password: "login-pass", MasterPassword: "master-password"})
+		w := doRequest(router, "POST", "/api/v1/register", "", payload)
+		var authResp models.AuthResponse
+		if err := json.NewDecoder(w.Body).Decode(&authResp); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+		return authResp.Token
+	}
+	create := func(token string, dataType models.DataType) string {
+		payload, _ := json.Marshal(models.DataRequest{Type: dataType, Name: "item", Data: []byte("sealed")})
+		w := doRequest(router, "POST", "/api/v1/data", token, payload)
+		var dataResp models.DataResponse
+		if err := json.NewDecoder(w.Body).Decode(&dataResp); err != nil {
+			t.Fatalf("Failed to create data: %v", err)
+		}
+		return "/api/v1/data/" + dataResp.Data.ID.String() + "/chunks"
+	}
+
+	owner := register("owner")
+	other := register("other")
+	path := create(owner, models.DataTypeBinary)
+	textPath := create(owner, models.DataTypeText)
+
+	tests := []struct {
+		name           string
+		path           string
+		token          string
+		chunk          []byte
+		expectedStatus int
+	}{
+		{name: "unauthenticated", path: path + "?index=0", chunk: []byte("c"), expectedStatus: http.StatusUnauthorized},
+		{name: "other user", path: path + "?index=0", token: other, chunk: []byte("c"), expectedStatus: http.StatusForbidden},
+		{name: "bad index", path: path + "?index=-1", token: owner, chunk: []byte("c"), expectedStatus: http.StatusBadRequest},
+		{name: "not binary", path: textPath + "?index=0", token: owner, chunk: []byte("c"), expectedStatus: http.StatusBadRequest},
+		{name: "empty", path: path + "?index=0", token: owner, expectedStatus: http.StatusBadRequest},
+		{name: "out of order", path: path + "?index=1", token: owner, chunk: []byte("c"), expectedStatus: http.StatusConflict},
+		{name: "first", path: path + "?index=0", token: owner, chunk: []byte("first"), expectedStatus: http.StatusCreated},
+		{name: "second", path: path + "?index=1", token: owner, chunk: []byte("second"), expectedStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := doRequest(router, "POST", tt.path, tt.token, tt.chunk); w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if w := doRequest(router, "GET", path, other, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected other users to be denied the chunks, got %d", w.Code)
+	}
+
+	w := doRequest(router, "GET", path, owner, nil)
+	if w.Code != http.StatusOK || w.Header().Get(ChunkCountHeader) != "2" {
+		t.Fatalf("Expected 2 chunks, got %d with count %q", w.Code, w.Header().Get(ChunkCountHeader))
+	}
+	var chunks []string
+	for {
+		var prefix [4]byte
+		if _, err := io.ReadFull(w.Body, prefix[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Failed to read frame: %v", err)
+		}
+		chunk := make([]byte, binary.BigEndian.Uint32(prefix[:]))
+		if _, err := io.ReadFull(w.Body, chunk); err != nil {
+			t.Fatalf("Failed to read chunk: %v", err)
+		}
+		chunks = append(chunks, string(chunk))
+	}
+	if len(chunks) != 2 || chunks[0] != "first" || chunks[1] != "second" {
+		t.Errorf("Expected the chunks in order, got %q", chunks)
+	}
+}
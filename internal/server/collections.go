@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type CollectionStorage interface {
+	CreateCollection(ctx context.Context, collection *models.Collection) error
+	GetCollectionsByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Collection, error)
+	UpdateCollection(ctx context.Context, collection *models.Collection) error
+	DeleteCollection(ctx context.Context, collectionID uuid.UUID) error
+	SetDataCollection(ctx context.Context, dataID uuid.UUID, collectionID *uuid.UUID) error
+}
+
+// RegisterCollectionRoutes registers the routes organizing items in collections
+func RegisterCollectionRoutes(r *mux.Router, collectionStorage CollectionStorage, dataStorage DataStorage, jwtManager *auth.JWTManager, opts Options) {
+	opts = opts.withDefaults()
+	protected := r.PathPrefix("/api/v1").Subrouter()
+	protected.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	protected.HandleFunc("/collections", handleGetCollections(collectionStorage)).Methods("GET")
+	protected.HandleFunc("/collections", handleCreateCollection(collectionStorage, opts)).Methods("POST")
+	protected.HandleFunc("/collections/{collection}", handleUpdateCollection(collectionStorage, opts)).Methods("PUT")
+	protected.HandleFunc("/collections/{collection}", handleDeleteCollection(collectionStorage, dataStorage)).Methods("DELETE")
+	protected.HandleFunc("/data/{id}/collection", handleSetDataCollection(collectionStorage, dataStorage)).Methods("PUT")
+}
+
+// userCollections loads the collections of the caller by ID. It writes the
+// error response and returns nil if that fails.
+func userCollections(w http.ResponseWriter, r *http.Request, collectionStorage CollectionStorage) (uuid.UUID, map[uuid.UUID]*models.Collection) {
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return uuid.Nil, nil
+	}
+
+	collections, err := collectionStorage.GetCollectionsByUserID(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, "Failed to get collections", http.StatusInternalServerError)
+		return uuid.Nil, nil
+	}
+	byID := make(map[uuid.UUID]*models.Collection, len(collections))
+	for _, collection := range collections {
+		byID[collection.ID] = collection
+	}
+	return userID, byID
+}
+
+// validateCollection checks the name and parent of a collection with the given
+// ID among the other collections of its owner and returns the reason it is
+// invalid, with the status to reject it with
+func validateCollection(id uuid.UUID, req models.CollectionRequest, collections map[uuid.UUID]*models.Collection) (int, string) {
+	name := strings.TrimSpace(req.Name)
+	switch {
+	case name == "" || len(name) > 255:
+		return http.StatusBadRequest, "Collection name must be between 1 and 255 characters"
+	case strings.Contains(name, "/"):
+		return http.StatusBadRequest, "Collection name cannot contain '/'"
+	}
+
+	// walking up from the parent must neither leave the user's collections
+	// nor come back to the collection itself
+	for parent := req.ParentID; parent != nil; parent = collections[*parent].ParentID {
+		if *parent == id {
+			return http.StatusBadRequest, "A collection cannot be moved into itself"
+		}
+		if collections[*parent] == nil {
+			return http.StatusNotFound, "Parent collection not found"
+		}
+	}
+
+	for _, sibling := range collections {
+		if sibling.ID != id && sameParent(sibling.ParentID, req.ParentID) && strings.EqualFold(sibling.Name, name) {
+			return http.StatusConflict, "A collection with this name already exists there"
+		}
+	}
+	return 0, ""
+}
+
+// sameParent reports whether two parent IDs are equal, nil meaning the top level
+func sameParent(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// handleGetCollections returns all collections of the user, by name
+func handleGetCollections(collectionStorage CollectionStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		collections, err := collectionStorage.GetCollectionsByUserID(r.Context(), userID)
+		if err != nil {
+			apierror.Error(w, "Failed to get collections", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.CollectionsResponse{Collections: make([]models.Collection, 0, len(collections))}
+		for _, collection := range collections {
+			response.Collections = append(response.Collections, *collection)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleCreateCollection creates a collection at the top level or in a parent
+func handleCreateCollection(collectionStorage CollectionStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.CollectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		userID, collections := userCollections(w, r, collectionStorage)
+		if collections == nil {
+			return
+		}
+
+		collection := &models.Collection{
+			ID:        opts.RecordIDs.NewID(),
+			UserID:    userID,
+			Name:      strings.TrimSpace(req.Name),
+			ParentID:  req.ParentID,
+			CreatedAt: opts.Clock.Now(),
+			UpdatedAt: opts.Clock.Now(),
+		}
+		if status, reason := validateCollection(collection.ID, req, collections); status != 0 {
+			apierror.Error(w, reason, status)
+			return
+		}
+
+		if err := collectionStorage.CreateCollection(r.Context(), collection); err != nil {
+			apierror.Error(w, "Failed to create collection", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(models.CollectionResponse{Collection: *collection}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleUpdateCollection renames a collection or moves it to another parent
+func handleUpdateCollection(collectionStorage CollectionStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collectionID, err := uuid.Parse(mux.Vars(r)["collection"])
+		if err != nil {
+			apierror.Error(w, "Invalid collection ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.CollectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		_, collections := userCollections(w, r, collectionStorage)
+		if collections == nil {
+			return
+		}
+		collection, ok := collections[collectionID]
+		if !ok {
+			apierror.Error(w, "Collection not found", http.StatusNotFound)
+			return
+		}
+		if status, reason := validateCollection(collectionID, req, collections); status != 0 {
+			apierror.Error(w, reason, status)
+			return
+		}
+
+		collection.Name = strings.TrimSpace(req.Name)
+		collection.ParentID = req.ParentID
+		collection.UpdatedAt = opts.Clock.Now()
+		if err := collectionStorage.UpdateCollection(r.Context(), collection); err != nil {
+			if err.Error() == "collection not found" {
+				apierror.Error(w, "Collection not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to update collection", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.CollectionResponse{Collection: *collection}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleDeleteCollection deletes an empty collection, so deleting one never
+// loses track of items or nested collections
+func handleDeleteCollection(collectionStorage CollectionStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collectionID, err := uuid.Parse(mux.Vars(r)["collection"])
+		if err != nil {
+			apierror.Error(w, "Invalid collection ID", http.StatusBadRequest)
+			return
+		}
+
+		userID, collections := userCollections(w, r, collectionStorage)
+		if collections == nil {
+			return
+		}
+		if _, ok := collections[collectionID]; !ok {
+			apierror.Error(w, "Collection not found", http.StatusNotFound)
+			return
+		}
+		for _, collection := range collections {
+			if collection.ParentID != nil && *collection.ParentID == collectionID {
+				apierror.Error(w, "Collection is not empty", http.StatusConflict)
+				return
+			}
+		}
+		data, err := dataStorage.GetDataByUserID(r.Context(), userID)
+		if err != nil {
+			apierror.Error(w, "Failed to get data", http.StatusInternalServerError)
+			return
+		}
+		for _, d := range data {
+			if d.CollectionID != nil && *d.CollectionID == collectionID {
+				apierror.Error(w, "Collection is not empty", http.StatusConflict)
+				return
+			}
+		}
+
+		if err := collectionStorage.DeleteCollection(r.Context(), collectionID); err != nil {
+			if err.Error() == "collection not found" {
+				apierror.Error(w, "Collection not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to delete collection", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleSetDataCollection files an item in one of the user's collections, or
+// at the top level
+func handleSetDataCollection(collectionStorage CollectionStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.DataCollectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+		if req.CollectionID != nil {
+			_, collections := userCollections(w, r, collectionStorage)
+			if collections == nil {
+				return
+			}
+			if _, ok := collections[*req.CollectionID]; !ok {
+				apierror.Error(w, "Collection not found", http.StatusNotFound)
+				return
+			}
+		}
+
+		if err := collectionStorage.SetDataCollection(r.Context(), data.ID, req.CollectionID); err != nil {
+			switch err.Error() {
+			case "data not found":
+				apierror.Error(w, "Data not found", http.StatusNotFound)
+			case "collection not found":
+				apierror.Error(w, "Collection not found", http.StatusNotFound)
+			default:
+				apierror.Error(w, "Failed to move data", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Data moved", zap.String("data_id", data.ID.String()))
+		data.CollectionID = req.CollectionID
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.DataResponse{Data: *data}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_Collections(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterCollectionRoutes(router, store, store, jwtManager, Options{})
+
+	register := func(username string) string {
+		w := doRequest(router, "POST", "/api/v1/register", "", models.UserRequest{Username: username, Password: "login-pass", MasterPassword: "master-password"})
+		var authResp models.AuthResponse
+		if err := json.NewDecoder(w.Body).Decode(&authResp); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+		return authResp.Token
+	}
+	create := func(token, name string, parentID *uuid.UUID) models.Collection {
+		w := doRequest(router, "POST", "/api/v1/collections", token, models.CollectionRequest{Name: name, ParentID: parentID})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201 creating %q, got %d: %s", name, w.Code, w.Body.String())
+		}
+		var resp models.CollectionResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode collection: %v", err)
+		}
+		return resp.Collection
+	}
+
+	owner := register("owner")
+	other := register("other")
+
+	unknown := uuid.New()
+	work := create(owner, "Work", nil)
+	servers := create(owner, "Servers", &work.ID)
+	if servers.ParentID == nil || *servers.ParentID != work.ID {
+		t.Fatalf("Expected Servers to be nested in Work, got %+v", servers)
+	}
+
+	invalid := []struct {
+		name string
+		req  models.CollectionRequest
+		want int
+	}{
+		{name: "empty name", req: models.CollectionRequest{Name: " "}, want: http.StatusBadRequest},
+		{name: "slash in name", req: models.CollectionRequest{Name: "a/b"}, want: http.StatusBadRequest},
+		{name: "duplicate sibling", req: models.CollectionRequest{Name: "work"}, want: http.StatusConflict},
+		{name: "unknown parent", req: models.CollectionRequest{Name: "x", ParentID: &unknown}, want: http.StatusNotFound},
+	}
+	for _, tt := range invalid {
+		if w := doRequest(router, "POST", "/api/v1/collections", owner, tt.req); w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+	if w := doRequest(router, "POST", "/api/v1/collections", other, models.CollectionRequest{Name: "x", ParentID: &work.ID}); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another user's parent to be not found, got %d", w.Code)
+	}
+
+	if w := doRequest(router, "PUT", "/api/v1/collections/"+work.ID.String(), owner, models.CollectionRequest{Name: "Work", ParentID: &servers.ID}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected moving a collection into its child to be rejected, got %d", w.Code)
+	}
+	if w := doRequest(router, "PUT", "/api/v1/collections/"+servers.ID.String(), owner, models.CollectionRequest{Name: "Hosts"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 moving Servers to the top level, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := doRequest(router, "POST", "/api/v1/data", owner, models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x")})
+	var created models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+	dataPath := "/api/v1/data/" + created.Data.ID.String()
+
+	if w := doRequest(router, "PUT", dataPath+"/collection", other, models.DataCollectionRequest{CollectionID: &work.ID}); w.Code != http.StatusForbidden {
+		t.Errorf("Expected another user to be denied moving the item, got %d", w.Code)
+	}
+	if w := doRequest(router, "PUT", dataPath+"/collection", owner, models.DataCollectionRequest{CollectionID: &work.ID}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 moving the item, got %d: %s", w.Code, w.Body.String())
+	}
+	stored, _ := store.GetDataByID(context.Background(), created.Data.ID)
+	if stored.CollectionID == nil || *stored.CollectionID != work.ID || stored.Revision != created.Data.Revision {
+		t.Errorf("Expected the item in Work at the same revision, got %+v", stored)
+	}
+
+	if w := doRequest(router, "DELETE", "/api/v1/collections/"+work.ID.String(), owner, nil); w.Code != http.StatusConflict {
+		t.Errorf("Expected deleting a collection with items to conflict, got %d", w.Code)
+	}
+	if w := doRequest(router, "PUT", dataPath+"/collection", owner, models.DataCollectionRequest{}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 moving the item to the top level, got %d", w.Code)
+	}
+	if w := doRequest(router, "DELETE", "/api/v1/collections/"+work.ID.String(), other, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another user's collection to be not found, got %d", w.Code)
+	}
+	if w := doRequest(router, "DELETE", "/api/v1/collections/"+work.ID.String(), owner, nil); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 deleting an empty collection, got %d", w.Code)
+	}
+
+	w = doRequest(router, "GET", "/api/v1/collections", owner, nil)
+	var list models.CollectionsResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode collections: %v", err)
+	}
+	if len(list.Collections) != 1 || list.Collections[0].Name != "Hosts" || list.Collections[0].ParentID != nil {
+		t.Errorf("Expected only the top level Hosts collection, got %+v", list.Collections)
+	}
+}
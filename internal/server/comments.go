@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// maxCommentCiphertext caps the size of one encrypted comment
+const maxCommentCiphertext = 8 << 10
+
+type CommentStorage interface {
+	AddDataComment(ctx context.Context, comment *models.DataComment) error
+	GetDataComments(ctx context.Context, dataID uuid.UUID) ([]*models.DataComment, error)
+}
+
+// RegisterCommentRoutes registers the item comment routes
+func RegisterCommentRoutes(r *mux.Router, commentStorage CommentStorage, dataStorage DataStorage, jwtManager *auth.JWTManager, opts Options) {
+	opts = opts.withDefaults()
+	comments := r.PathPrefix("/api/v1/data/{id}/comments").Subrouter()
+	comments.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	comments.HandleFunc("", handleGetDataComments(commentStorage, dataStorage)).Methods("GET")
+	comments.HandleFunc("", handleAddDataComment(commentStorage, dataStorage, opts)).Methods("POST")
+}
+
+// ownedData loads the item addressed by the route and checks that the caller owns it.
+// It writes the error response and returns nil if not.
+func ownedData(w http.ResponseWriter, r *http.Request, dataStorage DataStorage) *models.Data {
+	userID, dataID, ok := dataRoute(w, r)
+	if !ok {
+		return nil
+	}
+
+	data, err := OwnedData(r.Context(), dataStorage, userID, dataID)
+	if err != nil {
+		apierror.Reply(w, err)
+		return nil
+	}
+	return data
+}
+
+// handleGetDataComments returns the encrypted comments of an item, oldest first
+func handleGetDataComments(commentStorage CommentStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		comments, err := commentStorage.GetDataComments(r.Context(), data.ID)
+		if err != nil {
+			apierror.Error(w, "Failed to get comments", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.DataCommentsResponse{Comments: make([]models.DataComment, 0, len(comments))}
+		for _, comment := range comments {
+			response.Comments = append(response.Comments, *comment)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleAddDataComment appends an encrypted comment to an item. The server only
+// assigns the timestamp; the text is never visible to it.
+func handleAddDataComment(commentStorage CommentStorage, dataStorage DataStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.DataCommentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Ciphertext) == 0 {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.Ciphertext) > maxCommentCiphertext {
+			apierror.Error(w, "Comment too long", http.StatusBadRequest)
+			return
+		}
+
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		comment := &models.DataComment{
+			ID:         opts.RecordIDs.NewID(),
+			DataID:     data.ID,
+			Ciphertext: req.Ciphertext,
+			CreatedAt:  opts.Clock.Now(),
+		}
+		if err := commentStorage.AddDataComment(r.Context(), comment); err != nil {
+			if err.Error() == "data not found" {
+				apierror.Error(w, "Data not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to add comment", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Comment added", zap.String("data_id", data.ID.String()))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(models.DataCommentResponse{Comment: *comment}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
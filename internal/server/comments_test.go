@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_DataComments(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterCommentRoutes(router, store, store, jwtManager, Options{})
+
+	register := func(username string) string {
+		w := doRequest(router, "POST", "/api/v1/register", "", models.UserRequest{Username: username, Password: "login-pass", MasterPassword: "master-password"})
+		var authResp models.AuthResponse
+		if err := json.NewDecoder(w.Body).Decode(&authResp); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+		return authResp.Token
+	}
+
+	owner := register("owner")
+	other := register("other")
+
+	w := doRequest(router, "POST", "/api/v1/data", owner, models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("sealed")})
+	var dataResp models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&dataResp); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+	path := "/api/v1/data/" + dataResp.Data.ID.String() + "/comments"
+
+	tests := []struct {
+		name           string
+		path           string
+		token          string
+		ciphertext     []byte
+		expectedStatus int
+	}{
+		{name: "unauthenticated", path: path, ciphertext: []byte("c"), expectedStatus: http.StatusUnauthorized},
+		{name: "other user", path: path, token: other, ciphertext: []byte("c"), expectedStatus: http.StatusForbidden},
+		{name: "unknown item", path: "/api/v1/data/" + uuid.New().String() + "/comments", token: owner, ciphertext: []byte("c"), expectedStatus: http.StatusNotFound},
+		{name: "empty", path: path, token: owner, expectedStatus: http.StatusBadRequest},
+		{name: "too long", path: path, token: owner, ciphertext: bytes.Repeat([]byte("x"), maxCommentCiphertext+1), expectedStatus: http.StatusBadRequest},
+		{name: "first", path: path, token: owner, ciphertext: []byte("first"), expectedStatus: http.StatusCreated},
+		{name: "second", path: path, token: owner, ciphertext: []byte("second"), expectedStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := doRequest(router, "POST", tt.path, tt.token, models.DataCommentRequest{Ciphertext: tt.ciphertext}); w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if w := doRequest(router, "GET", path, other, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected other users to be denied, got %d", w.Code)
+	}
+
+	var comments models.DataCommentsResponse
+	if err := json.NewDecoder(doRequest(router, "GET", path, owner, nil).Body).Decode(&comments); err != nil {
+		t.Fatalf("Failed to decode comments: %v", err)
+	}
+	if len(comments.Comments) != 2 || string(comments.Comments[0].Ciphertext) != "first" || string(comments.Comments[1].Ciphertext) != "second" {
+		t.Errorf("Expected both comments oldest first, got %+v", comments.Comments)
+	}
+}
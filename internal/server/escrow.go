@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type EscrowStorage interface {
+	SetKeyEscrow(ctx context.Context, escrow *models.KeyEscrow) error
+	GetKeyEscrow(ctx context.Context, userID uuid.UUID) (*models.KeyEscrow, error)
+	DeleteKeyEscrow(ctx context.Context, userID uuid.UUID) error
+}
+
+// EscrowOptions configures organization key escrow
+type EscrowOptions struct {
+	// RecoveryPublicKey is the organization X25519 recovery public key; escrow is disabled when empty
+	RecoveryPublicKey []byte
+	// AdminToken authorizes admin recovery requests; admin routes are disabled when empty
+	AdminToken string
+	// Clock stamps escrow consents; nil means the system clock
+	Clock clock.Clock
+}
+
+// RegisterEscrowRoutes registers the opt-in key escrow routes for organization deployments
+func RegisterEscrowRoutes(r *mux.Router, escrowStorage EscrowStorage, userStorage UserStorage, dataStorage DataStorage,
+	jwtManager *auth.JWTManager, opts EscrowOptions) {
+	if opts.Clock == nil {
+		opts.Clock = clock.System{}
+	}
+	escrow := r.PathPrefix("/api/v1/escrow").Subrouter()
+	escrow.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	escrow.HandleFunc("/recovery-key", handleGetRecoveryKey(opts.RecoveryPublicKey)).Methods("GET")
+	escrow.HandleFunc("", handleGetEscrowStatus(escrowStorage, opts.RecoveryPublicKey)).Methods("GET")
+	escrow.HandleFunc("", handleSetEscrow(escrowStorage, opts.RecoveryPublicKey, opts.Clock)).Methods("PUT")
+	escrow.HandleFunc("", handleDeleteEscrow(escrowStorage)).Methods("DELETE")
+
+	if opts.AdminToken == "" {
+		return
+	}
+	admin := r.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(requireAdminToken(opts.AdminToken))
+	admin.HandleFunc("/escrow/{username}", handleEscrowRecovery(escrowStorage, userStorage, dataStorage)).Methods("GET").
+		Name(RouteEscrowRecovery)
+}
+
+// requireAdminToken authorizes requests carrying the configured X-Admin-Token
+func requireAdminToken(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(token)) != 1 {
+				logger.FromContext(r.Context()).Warn("Rejected admin request", zap.String("path", r.URL.Path))
+				apierror.Error(w, "Admin token required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func handleGetRecoveryKey(recoveryPublicKey []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(recoveryPublicKey) == 0 {
+			apierror.Error(w, "Key escrow not enabled", http.StatusNotFound)
+			return
+		}
+
+		response := models.RecoveryKeyResponse{
+			PublicKey: recoveryPublicKey,
+			KeyID:     crypto.RecoveryKeyID(recoveryPublicKey),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+func handleGetEscrowStatus(escrowStorage EscrowStorage, recoveryPublicKey []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		response := models.KeyEscrowStatusResponse{}
+		if len(recoveryPublicKey) > 0 {
+			response.CurrentKeyID = crypto.RecoveryKeyID(recoveryPublicKey)
+		}
+
+		escrow, err := escrowStorage.GetKeyEscrow(r.Context(), userID)
+		switch {
+		case err == nil:
+			response.Enabled = true
+			response.RecoveryKeyID = escrow.RecoveryKeyID
+			response.ConsentedAt = escrow.ConsentedAt.UTC().Format(time.RFC3339)
+		case err.Error() != "escrow not found":
+			apierror.Error(w, "Failed to get escrow", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleSetEscrow stores the user's wrapped vault key. The client wraps the key
+// itself, so the server never sees it; explicit consent is required.
+func handleSetEscrow(escrowStorage EscrowStorage, recoveryPublicKey []byte, clk clock.Clock) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(recoveryPublicKey) == 0 {
+			apierror.Error(w, "Key escrow not enabled", http.StatusNotFound)
+			return
+		}
+
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.KeyEscrowRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.WrappedKey) == 0 {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if !req.Consent {
+			apierror.Error(w, "Explicit consent is required for key escrow", http.StatusBadRequest)
+			return
+		}
+
+		if req.RecoveryKeyID != crypto.RecoveryKeyID(recoveryPublicKey) {
+			apierror.Error(w, "Recovery key has changed, fetch it again", http.StatusConflict)
+			return
+		}
+
+		escrow := &models.KeyEscrow{
+			UserID:        userID,
+			WrappedKey:    req.WrappedKey,
+			RecoveryKeyID: req.RecoveryKeyID,
+			ConsentedAt:   clk.Now(),
+		}
+		if err := escrowStorage.SetKeyEscrow(r.Context(), escrow); err != nil {
+			apierror.Error(w, "Failed to store escrow", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Key escrow enabled", zap.String("user_id", userID.String()),
+			zap.String("recovery_key_id", req.RecoveryKeyID))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleDeleteEscrow withdraws consent and removes the wrapped key
+func handleDeleteEscrow(escrowStorage EscrowStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := escrowStorage.DeleteKeyEscrow(r.Context(), userID); err != nil {
+			if err.Error() == "escrow not found" {
+				apierror.Error(w, "Key escrow not enabled", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to delete escrow", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Key escrow disabled", zap.String("user_id", userID.String()))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleEscrowRecovery returns a consenting user's wrapped key, salt and encrypted
+// items so admins can decrypt them offline with the recovery private key
+func handleEscrowRecovery(escrowStorage EscrowStorage, userStorage UserStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := mux.Vars(r)["username"]
+
+		user, err := userStorage.GetUserByUsername(r.Context(), username)
+		if err != nil {
+			if err.Error() == "user not found" {
+				apierror.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+
+		escrow, err := escrowStorage.GetKeyEscrow(r.Context(), user.ID)
+		if err != nil {
+			if err.Error() == "escrow not found" {
+				apierror.Error(w, "User has not consented to key escrow", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get escrow", http.StatusInternalServerError)
+			return
+		}
+
+		data, err := dataStorage.GetDataByUserID(r.Context(), user.ID)
+		if err != nil {
+			apierror.Error(w, "Failed to get data", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Warn("Escrowed vault released for recovery", zap.String("username", username),
+			zap.String("recovery_key_id", escrow.RecoveryKeyID))
+
+		response := models.EscrowRecoveryResponse{
+			Username:      user.Username,
+			Salt:          user.Salt,
+			WrappedKey:    escrow.WrappedKey,
+			RecoveryKeyID: escrow.RecoveryKeyID,
+			ConsentedAt:   escrow.ConsentedAt.UTC().Format(time.RFC3339),
+			Data:          make([]models.Data, len(data)),
+		}
+		for i, d := range data {
+			response.Data[i] = *d
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_KeyEscrow(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	publicKey, _, err := crypto.GenerateRecoveryKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryKeyPair() error = %v", err)
+	}
+	keyID := crypto.RecoveryKeyID(publicKey)
+
+	user := &models.User{ID: uuid.New(), Username: "leaver", Salt: "c2FsdA==", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := store.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := store.CreateData(context.Background(), &models.Data{ID: uuid.New(), UserID: user.ID, Type: models.DataTypeText,
+		Name: "shared", Data: []byte("encrypted"), CreatedAt: time.Now(), UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+	token, _ := jwtManager.GenerateToken(user.ID, user.Username)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterEscrowRoutes(router, store, store, store, jwtManager, EscrowOptions{RecoveryPublicKey: publicKey, AdminToken: "admin-secret"})
+
+	do := func(method, path string, headers map[string]string, body interface{}) *httptest.ResponseRecorder {
+		req := newTestRequest(method, path, "", body)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return serveRequest(router, req)
+	}
+	userAuth := map[string]string{"Authorization": "Bearer " + token}
+	adminAuth := map[string]string{"X-Admin-Token": "admin-secret"}
+
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		headers        map[string]string
+		body           interface{}
+		expectedStatus int
+	}{
+		{name: "recovery key", method: "GET", path: "/api/v1/escrow/recovery-key", headers: userAuth, expectedStatus: http.StatusOK},
+		{name: "recovery key requires auth", method: "GET", path: "/api/v1/escrow/recovery-key", expectedStatus: http.StatusUnauthorized},
+		{name: "admin before consent", method: "GET", path: "/api/v1/admin/escrow/leaver", headers: adminAuth, expectedStatus: http.StatusNotFound},
+		{name: "enable without consent", method: "PUT", path: "/api/v1/escrow", headers: userAuth,
+			body: models.KeyEscrowRequest{WrappedKey: []byte("wrapped"), RecoveryKeyID: keyID}, expectedStatus: http.StatusBadRequest},
+		{name: "enable with stale key", method: "PUT", path: "/api/v1/escrow", headers: userAuth,
+			body: models.KeyEscrowRequest{Consent: true, WrappedKey: []byte("wrapped"), RecoveryKeyID: "old"}, expectedStatus: http.StatusConflict},
+		{name: "enable", method: "PUT", path: "/api/v1/escrow", headers: userAuth,
+			body: models.KeyEscrowRequest{Consent: true, WrappedKey: []byte("wrapped"), RecoveryKeyID: keyID}, expectedStatus: http.StatusNoContent},
+		{name: "status", method: "GET", path: "/api/v1/escrow", headers: userAuth, expectedStatus: http.StatusOK},
+		{name: "admin wrong token", method: "GET", path: "/api/v1/admin/escrow/leaver", headers: map[string]string{"X-Admin-Token": "nope"},
+			expectedStatus: http.StatusUnauthorized},
+		{name: "admin user token", method: "GET", path: "/api/v1/admin/escrow/leaver", headers: userAuth, expectedStatus: http.StatusUnauthorized},
+		{name: "admin recovery", method: "GET", path: "/api/v1/admin/escrow/leaver", headers: adminAuth, expectedStatus: http.StatusOK},
+		{name: "admin unknown user", method: "GET", path: "/api/v1/admin/escrow/nobody", headers: adminAuth, expectedStatus: http.StatusNotFound},
+		{name: "withdraw consent", method: "DELETE", path: "/api/v1/escrow", headers: userAuth, expectedStatus: http.StatusNoContent},
+		{name: "admin after withdrawal", method: "GET", path: "/api/v1/admin/escrow/leaver", headers: adminAuth, expectedStatus: http.StatusNotFound},
+		{name: "withdraw twice", method: "DELETE", path: "/api/v1/escrow", headers: userAuth, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		w := do(step.method, step.path, step.headers, step.body)
+		if w.Code != step.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d (%s)", step.name, step.expectedStatus, w.Code, w.Body.String())
+		}
+
+		switch step.name {
+		case "status":
+			var status models.KeyEscrowStatusResponse
+			if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if !status.Enabled || status.RecoveryKeyID != keyID || status.CurrentKeyID != keyID {
+				t.Errorf("Unexpected escrow status: %+v", status)
+			}
+		case "admin recovery":
+			var recovery models.EscrowRecoveryResponse
+			if err := json.NewDecoder(w.Body).Decode(&recovery); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if string(recovery.WrappedKey) != "wrapped" || recovery.Salt != user.Salt || len(recovery.Data) != 1 {
+				t.Errorf("Unexpected recovery response: %+v", recovery)
+			}
+		}
+	}
+}
+
+func TestServer_KeyEscrow_Disabled(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	token, _ := jwtManager.GenerateToken(uuid.New(), "testuser")
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterEscrowRoutes(router, store, store, store, jwtManager, EscrowOptions{})
+
+	for _, path := range []string{"/api/v1/escrow/recovery-key", "/api/v1/admin/escrow/testuser"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Admin-Token", "")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusNotFound, w.Code)
+		}
+	}
+}
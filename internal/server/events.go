@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// EventsPath is the server-sent events stream of item changes
+const EventsPath = "/api/v1/events"
+
+// eventBuffer is the number of events queued for a subscriber; events beyond
+// it are dropped for that subscriber rather than slowing down writers
+const eventBuffer = 64
+
+// eventHeartbeat is how often an idle stream gets a comment line, so proxies
+// keep it open and clients notice a dead connection
+var eventHeartbeat = 25 * time.Second
+
+// EventHub fans item changes out to the event streams of their owner. It is
+// kept in memory, so it reaches the clients connected to one server instance.
+type EventHub struct {
+	mutex       sync.Mutex
+	subscribers map[uuid.UUID]map[chan models.ChangeEvent]struct{}
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewEventHub creates an event hub without subscribers
+func NewEventHub() *EventHub {
+	return &EventHub{
+		subscribers: make(map[uuid.UUID]map[chan models.ChangeEvent]struct{}),
+		closed:      make(chan struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the changes of the user's items and a
+// function that ends the subscription
+func (h *EventHub) Subscribe(userID uuid.UUID) (<-chan models.ChangeEvent, func()) {
+	events := make(chan models.ChangeEvent, eventBuffer)
+
+	h.mutex.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan models.ChangeEvent]struct{})
+	}
+	h.subscribers[userID][events] = struct{}{}
+	h.mutex.Unlock()
+
+	return events, func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		delete(h.subscribers[userID], events)
+		if len(h.subscribers[userID]) == 0 {
+			delete(h.subscribers, userID)
+		}
+	}
+}
+
+// Publish sends a change to every stream of the user without blocking
+func (h *EventHub) Publish(userID uuid.UUID, event models.ChangeEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for events := range h.subscribers[userID] {
+		select {
+		case events <- event:
+		default:
+			logger.Log.Warn("Dropping change event for a slow subscriber", zap.String("user_id", userID.String()))
+		}
+	}
+}
+
+// Close ends every open stream, so that a graceful shutdown does not wait on them
+func (h *EventHub) Close() {
+	h.closeOnce.Do(func() {
+		close(h.closed)
+	})
+}
+
+// notifyingDataStorage publishes item changes to the owner's event streams
+type notifyingDataStorage struct {
+	DataStorage
+	hub   *EventHub
+	clock clock.Clock
+}
+
+// NewNotifyingDataStorage wraps dataStorage so that creating, updating and
+// deleting items is published to the owner's event streams. Wrap the storage
+// of NewAuditedDataStorage in it, not the other way round, so changes are
+// published only once they are stored with their audit events.
+func NewNotifyingDataStorage(dataStorage DataStorage, hub *EventHub, opts Options) DataStorage {
+	return &notifyingDataStorage{DataStorage: dataStorage, hub: hub, clock: opts.withDefaults().Clock}
+}
+
+// RecordRead records the read in the audit log of the wrapped storage, if any
+func (s *notifyingDataStorage) RecordRead(ctx context.Context, data *models.Data) {
+	recordRead(ctx, s.DataStorage, data)
+}
+
+// CreateData creates data and publishes the change
+func (s *notifyingDataStorage) CreateData(ctx context.Context, data *models.Data) error {
+	if err := s.DataStorage.CreateData(ctx, data); err != nil {
+		return err
+	}
+	s.publish(models.ChangeCreated, data)
+	return nil
+}
+
+// UpdateData updates data and publishes the change
+func (s *notifyingDataStorage) UpdateData(ctx context.Context, data *models.Data) error {
+	if err := s.DataStorage.UpdateData(ctx, data); err != nil {
+		return err
+	}
+	s.publish(models.ChangeUpdated, data)
+	return nil
+}
+
+// DeleteData deletes data and publishes the change
+func (s *notifyingDataStorage) DeleteData(ctx context.Context, dataID uuid.UUID) error {
+	data, err := s.DataStorage.GetDataByID(ctx, dataID)
+	if err != nil {
+		return err
+	}
+	if err := s.DataStorage.DeleteData(ctx, dataID); err != nil {
+		return err
+	}
+	s.publish(models.ChangeDeleted, data)
+	return nil
+}
+
+// ApplyDataBatch applies a batch and publishes each of its changes
+func (s *notifyingDataStorage) ApplyDataBatch(ctx context.Context, changes []models.DataChange) error {
+	if err := s.DataStorage.ApplyDataBatch(ctx, changes); err != nil {
+		return err
+	}
+	types := map[string]string{
+		models.BatchCreate: models.ChangeCreated,
+		models.BatchUpdate: models.ChangeUpdated,
+		models.BatchDelete: models.ChangeDeleted,
+	}
+	for _, change := range changes {
+		s.publish(types[change.Op], change.Data)
+	}
+	return nil
+}
+
+func (s *notifyingDataStorage) publish(changeType string, data *models.Data) {
+	s.hub.Publish(data.UserID, models.ChangeEvent{
+		Type:     changeType,
+		DataID:   data.ID,
+		Revision: data.Revision,
+		At:       s.clock.Now(),
+	})
+}
+
+// RegisterEventRoutes registers the stream of the user's item changes
+func RegisterEventRoutes(r *mux.Router, hub *EventHub, jwtManager *auth.JWTManager) {
+	events := r.PathPrefix(EventsPath).Subrouter()
+	events.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	events.HandleFunc("", handleEvents(hub)).Methods("GET")
+}
+
+// handleEvents streams the user's item changes as server-sent events until
+// the client disconnects or the server shuts down
+func handleEvents(hub *EventHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			apierror.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		events, cancel := hub.Subscribe(userID)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if _, err := fmt.Fprint(w, ": connected\n\n"); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(eventHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case event := <-events:
+				payload, err := json.Marshal(event)
+				if err != nil {
+					logger.FromContext(r.Context()).Error("Failed to encode change event", zap.Error(err))
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: change\ndata: %s\n\n", payload); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			case <-hub.closed:
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestEventHub(t *testing.T) {
+	hub := NewEventHub()
+	owner, other := uuid.New(), uuid.New()
+	events, cancel := hub.Subscribe(owner)
+	otherEvents, cancelOther := hub.Subscribe(other)
+	defer cancelOther()
+
+	hub.Publish(owner, models.ChangeEvent{Type: models.ChangeCreated})
+	select {
+	case event := <-events:
+		if event.Type != models.ChangeCreated {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	default:
+		t.Fatal("Expected the owner's subscriber to receive the event")
+	}
+	select {
+	case event := <-otherEvents:
+		t.Errorf("Expected no event for another user, got %+v", event)
+	default:
+	}
+
+	// a subscriber that stops reading never blocks publishing
+	for i := 0; i < eventBuffer+10; i++ {
+		hub.Publish(owner, models.ChangeEvent{Type: models.ChangeUpdated})
+	}
+	if len(events) != eventBuffer {
+		t.Errorf("Expected %d queued events, got %d", eventBuffer, len(events))
+	}
+
+	cancel()
+	hub.Publish(owner, models.ChangeEvent{Type: models.ChangeDeleted})
+	if len(events) != eventBuffer {
+		t.Error("Expected no events after the subscription ended")
+	}
+}
+
+func TestServer_ChangeEvents(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	hub := NewEventHub()
+
+	router := mux.NewRouter()
+	RegisterEventRoutes(router, hub, jwtManager)
+	RegisterRoutes(router, store, NewNotifyingDataStorage(store, hub, Options{}), jwtManager, Options{})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	request := func(method, path, token string, body interface{}) *http.Response {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, srv.URL+path, bytes.NewReader(payload))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+	register := func(username string) string {
+		resp := request("POST", "/api/v1/register", "", models.UserRequest{Username: username, Password: "login-pass", MasterPassword: "master-password"})
+		defer resp.Body.Close()
+		var authResp models.AuthResponse
+		if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+		return authResp.Token
+	}
+	owner := register("owner")
+	other := register("other")
+
+	resp := request("GET", EventsPath, "", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a token, got %d", resp.StatusCode)
+	}
+
+	stream := request("GET", EventsPath, owner, nil)
+	defer stream.Body.Close()
+	if stream.StatusCode != http.StatusOK || stream.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", stream.StatusCode, stream.Header.Get("Content-Type"))
+	}
+	lines := bufio.NewReader(stream.Body)
+	next := func() models.ChangeEvent {
+		t.Helper()
+		for {
+			line, err := lines.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Failed to read the stream: %v", err)
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+				var event models.ChangeEvent
+				if err := json.Unmarshal([]byte(data), &event); err != nil {
+					t.Fatalf("Invalid event %q: %v", data, err)
+				}
+				return event
+			}
+		}
+	}
+	// the stream is subscribed once its first comment arrives
+	if line, err := lines.ReadString('\n'); err != nil || !strings.HasPrefix(line, ":") {
+		t.Fatalf("Expected a comment opening the stream, got %q, %v", line, err)
+	}
+
+	created := request("POST", "/api/v1/data", other, models.DataRequest{Type: models.DataTypeText, Name: "other", Data: []byte("x")})
+	created.Body.Close()
+	created = request("POST", "/api/v1/data", owner, models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x")})
+	var data models.DataResponse
+	if err := json.NewDecoder(created.Body).Decode(&data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+	created.Body.Close()
+	if event := next(); event.Type != models.ChangeCreated || event.DataID != data.Data.ID || event.Revision != 1 {
+		t.Errorf("Expected the owner's item to be created, got %+v", event)
+	}
+
+	deleted := request("DELETE", "/api/v1/data/"+data.Data.ID.String(), owner, nil)
+	deleted.Body.Close()
+	if event := next(); event.Type != models.ChangeDeleted || event.DataID != data.Data.ID {
+		t.Errorf("Expected the item to be deleted, got %+v", event)
+	}
+
+	// closing the hub ends open streams
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = lines.ReadString(0)
+	}()
+	hub.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the stream to end when the hub closes")
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"go.uber.org/zap"
+)
+
+// ExpiryStorage flags the items that are expiring or expired
+type ExpiryStorage interface {
+	FlagExpiringData(ctx context.Context, now time.Time, warning time.Duration) ([]*models.Data, error)
+}
+
+// RunExpiryChecks flags expiring and expired items now and then every
+// interval until ctx is done. Each item whose flag changed is published to
+// hub, if not nil, so the owner's connected clients refresh it.
+func RunExpiryChecks(ctx context.Context, storage ExpiryStorage, hub *EventHub, interval time.Duration, opts Options) {
+	opts = opts.withDefaults()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkExpiry(ctx, storage, hub, opts)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkExpiry runs one expiry check and returns the number of items flagged
+func checkExpiry(ctx context.Context, storage ExpiryStorage, hub *EventHub, opts Options) int {
+	flagged, err := storage.FlagExpiringData(ctx, opts.Clock.Now(), opts.ExpiryWarning)
+	if err != nil {
+		logger.Log.Error("Failed to flag expiring items", zap.Error(err))
+		return 0
+	}
+	if len(flagged) == 0 {
+		return 0
+	}
+
+	for _, data := range flagged {
+		if hub != nil {
+			hub.Publish(data.UserID, models.ChangeEvent{
+				Type:     models.ChangeUpdated,
+				DataID:   data.ID,
+				Revision: data.Revision,
+				At:       opts.Clock.Now(),
+			})
+		}
+	}
+	logger.Log.Info("Flagged expiring items", zap.Int("count", len(flagged)))
+	return len(flagged)
+}
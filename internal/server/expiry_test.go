@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+)
+
+func TestCheckExpiry(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	manual := clock.NewManual(start)
+
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	owner := uuid.New()
+	expiresAt := start.Add(45 * 24 * time.Hour)
+	data := &models.Data{ID: uuid.New(), UserID: owner, Type: models.DataTypeBankCard, Name: "Visa",
+		Data: []byte("x"), ExpiresAt: &expiresAt, CreatedAt: start, UpdatedAt: start}
+	if err := store.CreateData(ctx, data); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+
+	opts := Options{Clock: manual}.withDefaults()
+	hub := NewEventHub()
+	events, cancel := hub.Subscribe(owner)
+	defer cancel()
+
+	if flagged := checkExpiry(ctx, store, hub, opts); flagged != 0 {
+		t.Errorf("checkExpiry() flagged %d items before the warning period, want 0", flagged)
+	}
+
+	manual.Advance(20 * 24 * time.Hour)
+	if flagged := checkExpiry(ctx, store, hub, opts); flagged != 1 {
+		t.Fatalf("checkExpiry() flagged %d items, want 1", flagged)
+	}
+	select {
+	case event := <-events:
+		if event.Type != models.ChangeUpdated || event.DataID != data.ID {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	default:
+		t.Fatal("Expected the owner to be told about the flagged item")
+	}
+	stored, _ := store.GetDataByID(ctx, data.ID)
+	if stored.Expiry != models.ExpiryExpiring {
+		t.Errorf("Expected the item flagged %q, got %q", models.ExpiryExpiring, stored.Expiry)
+	}
+
+	// flagged items are not reported again until their flag changes
+	if flagged := checkExpiry(ctx, store, hub, opts); flagged != 0 {
+		t.Errorf("checkExpiry() flagged %d items again, want 0", flagged)
+	}
+	manual.Advance(30 * 24 * time.Hour)
+	if flagged := checkExpiry(ctx, store, nil, opts); flagged != 1 {
+		t.Errorf("checkExpiry() flagged %d expired items, want 1", flagged)
+	}
+	stored, _ = store.GetDataByID(ctx, data.ID)
+	if stored.Expiry != models.ExpiryExpired {
+		t.Errorf("Expected the item flagged %q, got %q", models.ExpiryExpired, stored.Expiry)
+	}
+}
+
+func TestCheckExpiry_Warning(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	expiresAt := time.Now().Add(45 * 24 * time.Hour)
+	data := &models.Data{ID: uuid.New(), UserID: uuid.New(), Type: models.DataTypeBankCard, Name: "Visa",
+		Data: []byte("x"), ExpiresAt: &expiresAt, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := store.CreateData(ctx, data); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+
+	if flagged := checkExpiry(ctx, store, nil, Options{ExpiryWarning: 60 * 24 * time.Hour}.withDefaults()); flagged != 1 {
+		t.Errorf("checkExpiry() flagged %d items within a 60 day warning, want 1", flagged)
+	}
+}
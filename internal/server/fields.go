@@ -0,0 +1,209 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/idgen"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultScopedTokenTTL is the lifetime of scoped tokens when none is requested
+	defaultScopedTokenTTL = 30 * 24 * time.Hour
+	// maxScopedTokenTTL caps the lifetime of scoped tokens
+	maxScopedTokenTTL = 365 * 24 * time.Hour
+)
+
+var fieldNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// handleGetDataField returns the ciphertext of one published field. It accepts full
+// tokens and tokens scoped to exactly this field; the caller decrypts with its data key.
+func handleGetDataField(dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		dataID, err := idgen.Parse(vars["id"])
+		if err != nil {
+			apierror.Error(w, "Invalid data ID", http.StatusBadRequest)
+			return
+		}
+		name := vars["name"]
+
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if scope := r.Header.Get("X-Token-Scope"); scope != "" && scope != auth.FieldScope(dataID, name) {
+			apierror.Error(w, "Token scope does not allow this request", http.StatusForbidden)
+			return
+		}
+
+		data, err := dataStorage.GetDataByID(r.Context(), dataID)
+		if err != nil {
+			if err.Error() == "data not found" {
+				apierror.Error(w, "Data not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get data", http.StatusInternalServerError)
+			return
+		}
+
+		if data.UserID != userID {
+			apierror.Error(w, "Access denied", http.StatusForbidden)
+			return
+		}
+
+		field, err := dataStorage.GetDataField(r.Context(), dataID, name)
+		if err != nil {
+			if err.Error() == "field not found" {
+				apierror.Error(w, "Field not published", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get field", http.StatusInternalServerError)
+			return
+		}
+
+		recordRead(r.Context(), dataStorage, data)
+		response := models.DataFieldResponse{DataID: dataID.String(), Name: field.Name, Ciphertext: field.Ciphertext}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleSetDataField publishes a field ciphertext for machine consumers
+func handleSetDataField(dataStorage DataStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		dataID, err := idgen.Parse(vars["id"])
+		if err != nil {
+			apierror.Error(w, "Invalid data ID", http.StatusBadRequest)
+			return
+		}
+		name := vars["name"]
+		if !fieldNamePattern.MatchString(name) {
+			apierror.Error(w, "Invalid field name", http.StatusBadRequest)
+			return
+		}
+
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.DataFieldRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Ciphertext) == 0 {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		data, err := dataStorage.GetDataByID(r.Context(), dataID)
+		if err != nil {
+			if err.Error() == "data not found" {
+				apierror.Error(w, "Data not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get data", http.StatusInternalServerError)
+			return
+		}
+
+		if data.UserID != userID {
+			apierror.Error(w, "Access denied", http.StatusForbidden)
+			return
+		}
+
+		field := &models.DataField{
+			DataID:     dataID,
+			Name:       name,
+			Ciphertext: req.Ciphertext,
+			CreatedAt:  opts.Clock.Now(),
+			UpdatedAt:  opts.Clock.Now(),
+		}
+		if err := dataStorage.SetDataField(r.Context(), field); err != nil {
+			apierror.Error(w, "Failed to publish field", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleCreateScopedToken issues a token that can only read one published field
+func handleCreateScopedToken(dataStorage DataStorage, jwtManager *auth.JWTManager, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.ScopedTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !fieldNamePattern.MatchString(req.Field) {
+			apierror.Error(w, "Invalid field name", http.StatusBadRequest)
+			return
+		}
+
+		ttl := defaultScopedTokenTTL
+		if req.TTLSeconds > 0 {
+			ttl = time.Duration(req.TTLSeconds) * time.Second
+		}
+		if ttl > maxScopedTokenTTL {
+			apierror.Error(w, "Token lifetime too long", http.StatusBadRequest)
+			return
+		}
+
+		data, err := dataStorage.GetDataByID(r.Context(), req.DataID)
+		if err != nil {
+			if err.Error() == "data not found" {
+				apierror.Error(w, "Data not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get data", http.StatusInternalServerError)
+			return
+		}
+
+		if data.UserID != userID {
+			apierror.Error(w, "Access denied", http.StatusForbidden)
+			return
+		}
+
+		scope := auth.FieldScope(req.DataID, req.Field)
+		// revoking the session that issued the token revokes the token too
+		token, err := jwtManager.GenerateScopedToken(userID, r.Header.Get("X-Username"), scope, r.Header.Get("X-Session-ID"), ttl)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Failed to generate scoped token", zap.Error(err))
+			apierror.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Scoped token issued", zap.String("user_id", userID.String()), zap.String("scope", scope))
+
+		response := models.ScopedTokenResponse{
+			Token:     token,
+			Scope:     scope,
+			ExpiresAt: opts.Clock.Now().Add(ttl).UTC().Format(time.RFC3339),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_DataFields(t *testing.T) {
+	userStorage := storage.NewMemoryStorage()
+	dataStorage := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	userID := uuid.New()
+	token, _ := jwtManager.GenerateToken(userID, "testuser")
+	otherToken, _ := jwtManager.GenerateToken(uuid.New(), "otheruser")
+
+	data := &models.Data{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Type:      models.DataTypeLoginPassword,
+		Name:      "DB_PASSWORD",
+		Data:      []byte("encrypted"),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	otherData := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "other",
+		Data: []byte("encrypted"), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	for _, d := range []*models.Data{data, otherData} {
+		if err := dataStorage.CreateData(context.Background(), d); err != nil {
+			t.Fatalf("Failed to create data: %v", err)
+		}
+	}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
+
+	fieldPath := "/api/v1/data/" + data.ID.String() + "/field/password"
+
+	if w := doRequest(router, "PUT", fieldPath, otherToken, models.DataFieldRequest{Ciphertext: []byte("sealed")}); w.Code != http.StatusForbidden {
+		t.Errorf("Publishing another user's field: expected %d, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := doRequest(router, "PUT", fieldPath, token, models.DataFieldRequest{Ciphertext: []byte("sealed")}); w.Code != http.StatusNoContent {
+		t.Fatalf("Publishing field: expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	w := doRequest(router, "POST", "/api/v1/tokens", token, models.ScopedTokenRequest{DataID: data.ID, Field: "password", TTLSeconds: 3600})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Creating scoped token: expected %d, got %d", http.StatusCreated, w.Code)
+	}
+	var tokenResp models.ScopedTokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&tokenResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		body           interface{}
+		expectedStatus int
+	}{
+		{name: "scoped token reads its field", method: "GET", path: fieldPath, token: tokenResp.Token, expectedStatus: http.StatusOK},
+		{name: "full token reads field", method: "GET", path: fieldPath, token: token, expectedStatus: http.StatusOK},
+		{name: "scoped token other field", method: "GET", path: "/api/v1/data/" + data.ID.String() + "/field/login",
+			token: tokenResp.Token, expectedStatus: http.StatusForbidden},
+		{name: "scoped token other item", method: "GET", path: "/api/v1/data/" + otherData.ID.String() + "/field/password",
+			token: tokenResp.Token, expectedStatus: http.StatusForbidden},
+		{name: "scoped token lists data", method: "GET", path: "/api/v1/data", token: tokenResp.Token, expectedStatus: http.StatusForbidden},
+		{name: "scoped token reads item", method: "GET", path: "/api/v1/data/" + data.ID.String(), token: tokenResp.Token,
+			expectedStatus: http.StatusForbidden},
+		{name: "scoped token publishes field", method: "PUT", path: fieldPath, token: tokenResp.Token,
+			body: models.DataFieldRequest{Ciphertext: []byte("x")}, expectedStatus: http.StatusForbidden},
+		{name: "other user reads field", method: "GET", path: fieldPath, token: otherToken, expectedStatus: http.StatusForbidden},
+		{name: "unpublished field", method: "GET", path: "/api/v1/data/" + data.ID.String() + "/field/login", token: token,
+			expectedStatus: http.StatusNotFound},
+		{name: "token for other user's item", method: "POST", path: "/api/v1/tokens", token: otherToken,
+			body: models.ScopedTokenRequest{DataID: data.ID, Field: "password"}, expectedStatus: http.StatusForbidden},
+		{name: "token with invalid field", method: "POST", path: "/api/v1/tokens", token: token,
+			body: models.ScopedTokenRequest{DataID: data.ID, Field: "bad field"}, expectedStatus: http.StatusBadRequest},
+		{name: "token lifetime too long", method: "POST", path: "/api/v1/tokens", token: token,
+			body:           models.ScopedTokenRequest{DataID: data.ID, Field: "password", TTLSeconds: 10 * 365 * 24 * 3600},
+			expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(router, tt.method, tt.path, tt.token, tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				var response models.DataFieldResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if string(response.Ciphertext) != "sealed" {
+					t.Errorf("Expected ciphertext sealed, got %s", response.Ciphertext)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_ScopedTokenSession(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	clk := clock.NewManual(time.Now())
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	jwtManager.SetClock(clk)
+	sessions := NewSessions(store, Options{Clock: clk})
+	jwtManager.SetSessions(sessions)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{Clock: clk})
+
+	userID := uuid.New()
+	if err := store.CreateUser(context.Background(), &models.User{ID: userID, Username: "testuser"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	token, err := jwtManager.IssueToken(context.Background(), userID, "testuser", "laptop", "gophkeeper-test")
+	if err != nil {
+		t.Fatalf("IssueToken() error = %v", err)
+	}
+	data := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeLoginPassword, Name: "DB_PASSWORD",
+		Data: []byte("encrypted"), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := store.CreateData(context.Background(), data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	fieldPath := "/api/v1/data/" + data.ID.String() + "/field/password"
+	if w := doRequest(router, "PUT", fieldPath, token, models.DataFieldRequest{Ciphertext: []byte("sealed")}); w.Code != http.StatusNoContent {
+		t.Fatalf("Publishing field: expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+	w := doRequest(router, "POST", "/api/v1/tokens", token, models.ScopedTokenRequest{DataID: data.ID, Field: "password"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Creating scoped token: expected %d, got %d", http.StatusCreated, w.Code)
+	}
+	var tokenResp models.ScopedTokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&tokenResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if w := doRequest(router, "GET", fieldPath, tokenResp.Token, nil); w.Code != http.StatusOK {
+		t.Fatalf("Reading field: expected %d, got %d", http.StatusOK, w.Code)
+	}
+
+	claims, err := jwtManager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+
+	// the login expires long before the token
+	clk.Advance(2 * time.Hour)
+	if w := doRequest(router, "GET", "/api/v1/data", token, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Listing data after the session expired: expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := doRequest(router, "GET", fieldPath, tokenResp.Token, nil); w.Code != http.StatusOK {
+		t.Fatalf("Reading field after the session expired: expected %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if err := store.DeleteSession(context.Background(), uuid.MustParse(claims.ID)); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+	if w := doRequest(router, "GET", fieldPath, tokenResp.Token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Reading field after the session was revoked: expected %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
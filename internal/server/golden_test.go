@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/fixtures"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// TestGoldenResponses replays every golden request against the server and
+// compares the responses with the golden files
+func TestGoldenResponses(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterStatusRoutes(router, StatusOptions{
+		RegistrationOpen: true,
+		Features:         []string{FeatureEnvironments, FeatureFieldPublishing, FeatureKeyEscrow, FeatureAdmin},
+		MaxPayloadBytes:  32 << 20,
+	})
+	RegisterAuditRoutes(router, store, jwtManager, AuditOptions{})
+	RegisterVaultLockRoutes(router, NewVaultLocks(Options{}), jwtManager)
+	RegisterRoutes(router, store, NewAuditedDataStorage(store, store, AuditOptions{}), jwtManager, Options{})
+	RegisterHintRoutes(router, store, store, jwtManager)
+	RegisterCommentRoutes(router, store, store, jwtManager, Options{})
+	RegisterEscrowRoutes(router, store, store, store, jwtManager, EscrowOptions{
+		RecoveryPublicKey: fixtures.RecoveryPublicKey,
+		AdminToken:        fixtures.AdminToken,
+	})
+	RegisterMaintenanceRoutes(router, middleware.NewMaintenance(false, ""), fixtures.AdminToken)
+
+	// salt.set needs an account created before salts were stored
+	legacyUser := &models.User{ID: uuid.New(), Username: "fixture-legacy-user"}
+	if err := store.CreateUser(context.Background(), legacyUser); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	legacyToken, err := jwtManager.GenerateToken(legacyUser.ID, legacyUser.Username)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	var userToken, scopedToken, lockToken string
+	ids := make(map[string]string)
+	dataID := fixtures.DataID
+
+	for _, endpoint := range fixtures.Endpoints {
+		var body []byte
+		if endpoint.HasRequest {
+			golden, err := fixtures.Request(endpoint.Name)
+			if err != nil {
+				t.Fatalf("%s: %v", endpoint.Name, err)
+			}
+			body = bytes.ReplaceAll(golden, []byte(fixtures.DataID), []byte(dataID))
+			if endpoint.Name == "vault.unlock" {
+				body = bytes.ReplaceAll(body, []byte(fixtures.Token), []byte(lockToken))
+			}
+		}
+
+		req := httptest.NewRequest(endpoint.Method, endpoint.Target(dataID), bytes.NewReader(body))
+		switch endpoint.Name {
+		case "status", "register", "login":
+		case "salt.set":
+			req.Header.Set("Authorization", "Bearer "+legacyToken)
+		case "field.get":
+			req.Header.Set("Authorization", "Bearer "+scopedToken)
+		case "escrow.recovery", "maintenance.set", "maintenance.get":
+			req.Header.Set("X-Admin-Token", fixtures.AdminToken)
+		default:
+			req.Header.Set("Authorization", "Bearer "+userToken)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != endpoint.Status {
+			t.Fatalf("%s: expected status %d, got %d: %s", endpoint.Name, endpoint.Status, w.Code, w.Body.String())
+		}
+
+		switch endpoint.Name {
+		case "register":
+			var resp models.AuthResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+			userToken = resp.Token
+			ids[resp.User.ID.String()] = fixtures.UserID
+		case "data.create":
+			var resp models.DataResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("data.create: %v", err)
+			}
+			dataID = resp.Data.ID.String()
+			ids[dataID] = fixtures.DataID
+		case "comment.add":
+			var resp models.DataCommentResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("comment.add: %v", err)
+			}
+			ids[resp.Comment.ID.String()] = fixtures.CommentID
+		case "data.import":
+			var resp models.ImportResult
+			if err := json.NewDecoder(bytes.NewReader(w.Body.Bytes())).Decode(&resp); err != nil || resp.ID == nil {
+				t.Fatalf("data.import: %+v, %v", resp, err)
+			}
+			ids[resp.ID.String()] = fixtures.ImportID
+		case "vault.lock":
+			var resp models.VaultLock
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("vault.lock: %v", err)
+			}
+			lockToken = resp.Token
+		case "token.create":
+			var resp models.ScopedTokenResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("token.create: %v", err)
+			}
+			scopedToken = resp.Token
+		}
+
+		if endpoint.HasResponse {
+			fixtures.CheckResponse(t, endpoint.Name, w.Body.Bytes(), ids)
+		} else if w.Body.Len() > 0 {
+			t.Errorf("%s: expected an empty body, got %s", endpoint.Name, w.Body.String())
+		}
+	}
+}
@@ -2,36 +2,173 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
 	"github.com/a2sh3r/gophkeeper/internal/auth"
-	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/idgen"
 	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
 	"github.com/a2sh3r/gophkeeper/internal/models"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserStorage interface {
 	CreateUser(ctx context.Context, user *models.User) error
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error)
+	SetUserSalt(ctx context.Context, userID uuid.UUID, salt string) error
 }
 
 type DataStorage interface {
 	GetDataByID(ctx context.Context, dataID uuid.UUID) (*models.Data, error)
 	GetDataByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Data, error)
+	GetDataByUserIDFiltered(ctx context.Context, userID uuid.UUID, filter models.DataFilter) ([]*models.Data, error)
 	CreateData(ctx context.Context, data *models.Data) error
 	UpdateData(ctx context.Context, data *models.Data) error
 	DeleteData(ctx context.Context, dataID uuid.UUID) error
+	SetDataField(ctx context.Context, field *models.DataField) error
+	GetDataField(ctx context.Context, dataID uuid.UUID, name string) (*models.DataField, error)
+	ApplyDataBatch(ctx context.Context, changes []models.DataChange) error
 }
 
-func RegisterRoutes(r *mux.Router, userStorage UserStorage, dataStorage DataStorage, jwtManager *auth.JWTManager) {
-	r.HandleFunc("/api/v1/register", handleRegister(userStorage, jwtManager)).Methods("POST")
-	r.HandleFunc("/api/v1/login", handleLogin(userStorage, jwtManager)).Methods("POST")
+// Transactor is implemented by storages that run multi-step operations
+// atomically. Storage calls made with the context passed to fn take part in
+// the transaction.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// inTx runs fn in a transaction of store if it has them, and directly otherwise
+func inTx(ctx context.Context, store interface{}, fn func(ctx context.Context) error) error {
+	if transactor, ok := store.(Transactor); ok {
+		return transactor.InTx(ctx, fn)
+	}
+	return fn(ctx)
+}
+
+// maxDataLimit bounds the page size of data listings
+const maxDataLimit = 1000
+
+// Route names used to attach per-route middleware such as concurrency and
+// body size limits
+const (
+	RouteRegister       = "auth.register"
+	RouteLogin          = "auth.login"
+	RouteListData       = "data.list"
+	RouteCreateData     = "data.create"
+	RouteUpdateData     = "data.update"
+	RouteBatchData      = "data.batch"
+	RouteEscrowRecovery = "escrow.recovery"
+)
+
+// AuthRoutes take credentials and are rate limited against credential stuffing
+var AuthRoutes = []string{RouteRegister, RouteLogin, RouteOIDCStart}
+
+// BulkRoutes are expensive routes returning a whole vault, e.g. a full client sync
+var BulkRoutes = []string{RouteListData, RouteEscrowRecovery}
+
+// PayloadLimits returns the body size limits of named routes: authLimit for
+// the AuthRoutes, which only take credentials, overridden by routes. A limit
+// of 0 in routes leaves the route unlimited; authLimit 0 leaves the auth
+// routes at the global limit.
+func PayloadLimits(authLimit int64, routes map[string]int64) map[string]int64 {
+	limits := make(map[string]int64, len(AuthRoutes)+len(routes))
+	if authLimit > 0 {
+		for _, name := range AuthRoutes {
+			limits[name] = authLimit
+		}
+	}
+	for name, limit := range routes {
+		limits[name] = limit
+	}
+	return limits
+}
+
+// CheckPayloadLimits checks that the limits name routes registered on r and
+// are not negative, so a mistyped route name is not silently ignored
+func CheckPayloadLimits(r *mux.Router, limits map[string]int64) error {
+	for name, limit := range limits {
+		if limit < 0 {
+			return fmt.Errorf("payload limit of route %s must not be negative", name)
+		}
+		if r.Get(name) == nil {
+			return fmt.Errorf("unknown route %q", name)
+		}
+	}
+	return nil
+}
+
+// defaultExpiryWarning is how long before their expiry items are flagged as
+// expiring by default
+const defaultExpiryWarning = 30 * 24 * time.Hour
+
+// Options configures the routes, storage wrappers and jobs that check
+// credentials, flag expiring items or stamp records; zero fields take their
+// defaults
+type Options struct {
+	// CredentialPolicy is checked against the credentials of new users; nil
+	// means models.DefaultCredentialPolicy
+	CredentialPolicy *models.CredentialPolicy
+	// ExpiryWarning is how long before their expiry items are flagged as
+	// expiring; zero means 30 days
+	ExpiryWarning time.Duration
+	// Clock stamps records and computes expiry times; nil means the system
+	// clock. The JWT manager and storage take their own.
+	Clock clock.Clock
+	// DataIDs generates the IDs of new data items; nil means random UUIDs
+	DataIDs idgen.Generator
+	// RecordIDs generates the IDs of new users, sessions and other records
+	// besides data items; nil means random UUIDs
+	RecordIDs idgen.Generator
+}
+
+// withDefaults returns opts with its zero fields set to their defaults
+func (opts Options) withDefaults() Options {
+	if opts.CredentialPolicy == nil {
+		policy := models.DefaultCredentialPolicy()
+		opts.CredentialPolicy = &policy
+	}
+	if opts.ExpiryWarning == 0 {
+		opts.ExpiryWarning = defaultExpiryWarning
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.System{}
+	}
+	if opts.DataIDs == nil {
+		opts.DataIDs = idgen.UUID{}
+	}
+	if opts.RecordIDs == nil {
+		opts.RecordIDs = idgen.UUID{}
+	}
+	return opts
+}
+
+func RegisterRoutes(r *mux.Router, userStorage UserStorage, dataStorage DataStorage, jwtManager *auth.JWTManager, opts Options) {
+	opts = opts.withDefaults()
+	r.HandleFunc("/api/v1/register", handleRegister(userStorage, jwtManager, opts)).Methods("POST").Name(RouteRegister)
+	r.HandleFunc("/api/v1/login", handleLogin(userStorage, jwtManager)).Methods("POST").Name(RouteLogin)
+
+	// Published fields accept scoped tokens for machine consumers, so they are
+	// registered before the full-access subrouter that rejects them
+	fields := r.PathPrefix("/api/v1/data/{id}/field").Subrouter()
+	fields.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.ScopedAuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	fields.HandleFunc("/{name}", handleGetDataField(dataStorage)).Methods("GET")
 
 	protected := r.PathPrefix("/api/v1").Subrouter()
 	protected.Use(func(next http.Handler) http.Handler {
@@ -40,329 +177,522 @@ func RegisterRoutes(r *mux.Router, userStorage UserStorage, dataStorage DataStor
 		})
 	})
 
-	protected.HandleFunc("/data", handleGetData(dataStorage)).Methods("GET")
-	protected.HandleFunc("/data", handleCreateData(dataStorage)).Methods("POST")
+	protected.HandleFunc("/salt", handleGetSalt(userStorage)).Methods("GET")
+	protected.HandleFunc("/salt", handleSetSalt(userStorage)).Methods("PUT")
+	protected.HandleFunc("/data", handleGetData(dataStorage, opts)).Methods("GET").Name(RouteListData)
+	protected.HandleFunc("/data", handleCreateData(dataStorage, opts)).Methods("POST").Name(RouteCreateData)
+	protected.HandleFunc("/data/import", handleImportData(dataStorage, opts)).Methods("POST")
+	protected.HandleFunc("/data/batch", handleBatchData(dataStorage, opts)).Methods("POST").Name(RouteBatchData)
+	protected.HandleFunc("/data/search", handleSearchData(dataStorage, opts)).Methods("GET")
 	protected.HandleFunc("/data/{id}", handleGetDataByID(dataStorage)).Methods("GET")
-	protected.HandleFunc("/data/{id}", handleUpdateData(dataStorage)).Methods("PUT")
+	protected.HandleFunc("/data/{id}", handleUpdateData(dataStorage, opts)).Methods("PUT").Name(RouteUpdateData)
+	protected.HandleFunc("/data/{id}", handlePatchData(dataStorage, opts)).Methods("PATCH")
 	protected.HandleFunc("/data/{id}", handleDeleteData(dataStorage)).Methods("DELETE")
+	protected.HandleFunc("/data/{id}/field/{name}", handleSetDataField(dataStorage, opts)).Methods("PUT")
+	protected.HandleFunc("/tokens", handleCreateScopedToken(dataStorage, jwtManager, opts)).Methods("POST")
 }
 
-func handleRegister(userStorage UserStorage, jwtManager *auth.JWTManager) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var req models.UserRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			logger.Log.Warn("Invalid registration request", zap.Error(err))
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			return
-		}
-
-		logger.Log.Info("User registration attempt", zap.String("username", req.Username))
+// decodeRequest decodes the JSON body of r into req and checks the validate tags
+// of its fields. It replies with the invalid fields and returns false if the
+// request is rejected.
+func decodeRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if !decodeBody(w, r, req) {
+		return false
+	}
+	if err := checkRequest(req); err != nil {
+		apierror.Reply(w, err)
+		return false
+	}
+	return true
+}
 
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-		if err != nil {
-			logger.Log.Error("Failed to hash password", zap.Error(err))
-			http.Error(w, "Failed to hash password", http.StatusInternalServerError)
-			return
+// decodeBody decodes the JSON body of r into req, for handlers whose
+// operation checks the request itself. It replies and returns false if the
+// body cannot be decoded.
+func decodeBody(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		if !replyBodyTooLarge(w, err) {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
 		}
+		return false
+	}
+	return true
+}
 
-		cryptoManager, err := crypto.NewCryptoManager(req.MasterPassword)
-		if err != nil {
-			logger.Log.Error("Failed to create crypto manager", zap.Error(err))
-			http.Error(w, "Failed to initialize encryption", http.StatusInternalServerError)
+// replyBodyTooLarge replies 413 if reading the body failed at the body size
+// limit and reports whether it did
+func replyBodyTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	middleware.BodyTooLarge(w, tooLarge.Limit)
+	return true
+}
+
+func handleRegister(userStorage UserStorage, jwtManager *auth.JWTManager, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.UserRequest
+		if !decodeBody(w, r, &req) {
+			logger.FromContext(r.Context()).Warn("Invalid registration request")
 			return
 		}
 
-		hashedMasterPassword, err := bcrypt.GenerateFromPassword([]byte(req.MasterPassword), bcrypt.DefaultCost)
+		response, err := Register(r.Context(), userStorage, jwtManager, opts, req, r.Header.Get(DeviceHeader), r.UserAgent())
 		if err != nil {
-			logger.Log.Error("Failed to hash master password", zap.Error(err))
-			http.Error(w, "Failed to hash master password", http.StatusInternalServerError)
+			apierror.Reply(w, err)
 			return
 		}
 
-		user := &models.User{
-			ID:             uuid.New(),
-			Username:       req.Username,
-			Password:       string(hashedPassword),
-			MasterPassword: string(hashedMasterPassword),
-			Salt:           cryptoManager.GetSaltBase64(),
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
 		}
+	}
+}
 
-		if err := userStorage.CreateUser(r.Context(), user); err != nil {
-			if err.Error() == "user already exists" {
-				logger.Log.Warn("User already exists", zap.String("username", req.Username))
-				http.Error(w, "User already exists", http.StatusConflict)
-				return
-			}
-			logger.Log.Error("Failed to create user", zap.Error(err), zap.String("username", req.Username))
-			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+func handleLogin(userStorage UserStorage, jwtManager *auth.JWTManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.LoginRequest
+		if !decodeBody(w, r, &req) {
+			logger.FromContext(r.Context()).Warn("Invalid login request")
 			return
 		}
 
-		logger.Log.Info("User registered successfully", zap.String("username", req.Username), zap.String("user_id", user.ID.String()))
-
-		token, err := jwtManager.GenerateToken(user.ID, user.Username)
+		response, err := Login(r.Context(), userStorage, jwtManager, req, r.Header.Get(DeviceHeader), r.UserAgent())
 		if err != nil {
-			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			apierror.Reply(w, err)
 			return
 		}
 
-		response := models.AuthResponse{
-			Token: token,
-			User:  *user,
-			Salt:  user.Salt,
-		}
-
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(response); err != nil {
-			logger.Log.Error("Failed to encode response", zap.Error(err))
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
 		}
 	}
 }
 
-func handleLogin(userStorage UserStorage, jwtManager *auth.JWTManager) http.HandlerFunc {
+func handleGetSalt(userStorage UserStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req models.LoginRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			logger.Log.Warn("Invalid login request", zap.Error(err))
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
-		logger.Log.Info("User login attempt", zap.String("username", req.Username))
-
-		user, err := userStorage.GetUserByUsername(r.Context(), req.Username)
+		user, err := userStorage.GetUserByID(r.Context(), userID)
 		if err != nil {
 			if err.Error() == "user not found" {
-				logger.Log.Warn("Login failed - user not found", zap.String("username", req.Username))
-				http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+				apierror.Error(w, "User not found", http.StatusNotFound)
 				return
 			}
-			logger.Log.Error("Failed to get user", zap.Error(err), zap.String("username", req.Username))
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			apierror.Error(w, "Failed to get user", http.StatusInternalServerError)
 			return
 		}
 
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-			logger.Log.Warn("Login failed - invalid password", zap.String("username", req.Username))
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		response := models.SaltResponse{Salt: user.Salt, KDFIterations: user.KDFIterations}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleSetSalt registers the salt for accounts created without one. The salt is
+// immutable afterwards: replaying the same value succeeds, a different one is rejected.
+func handleSetSalt(userStorage UserStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
-		logger.Log.Info("User logged in successfully", zap.String("username", req.Username), zap.String("user_id", user.ID.String()))
+		var req models.SaltRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
 
-		token, err := jwtManager.GenerateToken(user.ID, user.Username)
-		if err != nil {
-			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		salt, err := base64.StdEncoding.DecodeString(req.Salt)
+		if err != nil || len(salt) != 32 {
+			apierror.Error(w, "Salt must be 32 base64 encoded bytes", http.StatusBadRequest)
 			return
 		}
 
-		response := models.AuthResponse{
-			Token: token,
-			User:  *user,
-			Salt:  user.Salt,
+		if err := userStorage.SetUserSalt(r.Context(), userID, req.Salt); err != nil {
+			switch err.Error() {
+			case "user not found":
+				apierror.Error(w, "User not found", http.StatusNotFound)
+			case "salt already set":
+				logger.FromContext(r.Context()).Warn("Rejected salt overwrite", zap.String("user_id", userID.String()))
+				apierror.Error(w, "Salt already set", http.StatusConflict)
+			default:
+				apierror.Error(w, "Failed to set salt", http.StatusInternalServerError)
+			}
+			return
 		}
 
+		response := models.SaltResponse{Salt: req.Salt}
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(response); err != nil {
-			logger.Log.Error("Failed to encode response", zap.Error(err))
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
 		}
 	}
 }
 
-func handleGetData(dataStorage DataStorage) http.HandlerFunc {
+func handleGetData(dataStorage DataStorage, opts Options) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
-		data, err := dataStorage.GetDataByUserID(r.Context(), userID)
-		if err != nil {
-			http.Error(w, "Failed to get data", http.StatusInternalServerError)
+		filter, invalid := parseDataFilter(r.URL.Query(), opts.Clock.Now())
+		if invalid != "" {
+			apierror.Error(w, "Invalid "+invalid, http.StatusBadRequest)
 			return
 		}
 
-		response := models.DataListResponse{Data: make([]models.Data, len(data))}
-		for i, d := range data {
-			response.Data[i] = *d
+		data, err := ListData(r.Context(), dataStorage, userID, filter)
+		if err != nil {
+			apierror.Reply(w, err)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			logger.Log.Error("Failed to encode response", zap.Error(err))
+		if err := json.NewEncoder(w).Encode(models.DataListResponse{Data: data}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
 		}
 	}
 }
 
-func handleCreateData(dataStorage DataStorage) http.HandlerFunc {
+// handleSearchData lists the items whose name, description or metadata
+// contain the q parameter, narrowed and paged like a listing
+func handleSearchData(dataStorage DataStorage, opts Options) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
-		var req models.DataRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		filter, invalid := parseDataFilter(r.URL.Query(), opts.Clock.Now())
+		if invalid != "" {
+			apierror.Error(w, "Invalid "+invalid, http.StatusBadRequest)
 			return
 		}
-
-		data := &models.Data{
-			ID:          uuid.New(),
-			UserID:      userID,
-			Type:        req.Type,
-			Name:        req.Name,
-			Description: req.Description,
-			Data:        req.Data,
-			Metadata:    req.Metadata,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+		filter.Query = strings.TrimSpace(r.URL.Query().Get("q"))
+		if filter.Query == "" {
+			apierror.Error(w, "Search query is required", http.StatusBadRequest)
+			return
 		}
 
-		if err := dataStorage.CreateData(r.Context(), data); err != nil {
-			http.Error(w, "Failed to create data", http.StatusInternalServerError)
+		data, err := ListData(r.Context(), dataStorage, userID, filter)
+		if err != nil {
+			apierror.Reply(w, err)
 			return
 		}
 
-		response := models.DataResponse{Data: *data}
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			logger.Log.Error("Failed to encode response", zap.Error(err))
+		if err := json.NewEncoder(w).Encode(models.DataListResponse{Data: data}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
 		}
 	}
 }
 
-func handleGetDataByID(dataStorage DataStorage) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		dataID, err := uuid.Parse(vars["id"])
+// parseDataFilter reads the listing filter from the query: environment, type,
+// name (a substring), tag, domain, expiring (items expiring within a period
+// such as 30d, or expired), and limit and offset for paging. It returns the
+// name of the first invalid parameter, if any. Expiry periods start at now.
+func parseDataFilter(query url.Values, now time.Time) (models.DataFilter, string) {
+	filter := models.DataFilter{
+		Environment: query.Get("environment"),
+		Type:        models.DataType(query.Get("type")),
+		Name:        query.Get("name"),
+		Tag:         strings.ToLower(query.Get("tag")),
+		Domain:      strings.ToLower(query.Get("domain")),
+	}
+	if filter.Type != "" && !models.IsValidDataType(filter.Type) {
+		return filter, "type"
+	}
+	if raw := query.Get("expiring"); raw != "" {
+		within, err := models.ParseAge(raw)
 		if err != nil {
-			http.Error(w, "Invalid data ID", http.StatusBadRequest)
-			return
+			return filter, "expiring"
 		}
+		filter.ExpiringBefore = now.Add(within)
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxDataLimit {
+			return filter, "limit"
+		}
+		filter.Limit = limit
+	}
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return filter, "offset"
+		}
+		filter.Offset = offset
+	}
+	return filter, ""
+}
 
+func handleCreateData(dataStorage DataStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
-		data, err := dataStorage.GetDataByID(r.Context(), dataID)
-		if err != nil {
-			if err.Error() == "data not found" {
-				http.Error(w, "Data not found", http.StatusNotFound)
-				return
-			}
-			http.Error(w, "Failed to get data", http.StatusInternalServerError)
+		var req models.DataRequest
+		if !decodeBody(w, r, &req) {
 			return
 		}
 
-		if data.UserID != userID {
-			http.Error(w, "Access denied", http.StatusForbidden)
+		data, err := CreateData(r.Context(), dataStorage, opts, userID, req)
+		if err != nil {
+			apierror.Reply(w, err)
 			return
 		}
 
 		response := models.DataResponse{Data: *data}
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
 		if err := json.NewEncoder(w).Encode(response); err != nil {
-			logger.Log.Error("Failed to encode response", zap.Error(err))
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// requestTags returns the normalized tags of req, or nil if it sets none
+func requestTags(req models.DataRequest) ([]string, error) {
+	if req.Tags == nil {
+		return nil, nil
+	}
+	return models.NormalizeTags(*req.Tags)
+}
+
+// requestDomains returns the normalized domain index set by a request, or nil
+// if it sets none
+func requestDomains(domains *[]string) ([]string, error) {
+	if domains == nil {
+		return nil, nil
+	}
+	return models.NormalizeDomains(*domains)
+}
+
+// requestIcon returns the encrypted favicon set by a request, nil for none
+// or an empty one
+func requestIcon(icon *[]byte) []byte {
+	if icon == nil || len(*icon) == 0 {
+		return nil
+	}
+	return *icon
+}
+
+// setExpiry sets the expiry of an item to the one of a request, nil keeping
+// it and the zero time clearing it, and flags the item as of now
+func setExpiry(data *models.Data, expiresAt *time.Time, opts Options) {
+	if expiresAt != nil {
+		if expiresAt.IsZero() {
+			data.ExpiresAt = nil
+		} else {
+			at := expiresAt.UTC()
+			data.ExpiresAt = &at
 		}
 	}
+	data.Expiry = models.ExpiryStatus(data.ExpiresAt, opts.Clock.Now(), opts.ExpiryWarning)
 }
 
-func handleUpdateData(dataStorage DataStorage) http.HandlerFunc {
+func handleGetDataByID(dataStorage DataStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		dataID, err := uuid.Parse(vars["id"])
-		if err != nil {
-			http.Error(w, "Invalid data ID", http.StatusBadRequest)
+		userID, dataID, ok := dataRoute(w, r)
+		if !ok {
 			return
 		}
 
-		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		data, err := GetData(r.Context(), dataStorage, userID, dataID)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Reply(w, err)
 			return
 		}
 
-		var req models.DataRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			return
+		response := models.DataResponse{Data: *data}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", dataETag(data))
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// dataRoute returns the caller and the item addressed by the route. It
+// writes the error response and returns false if either is invalid.
+func dataRoute(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	dataID, err := idgen.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Error(w, "Invalid data ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+	if err != nil {
+		apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, dataID, true
+}
+
+// dataETag returns the entity tag of an item, its revision
+func dataETag(data *models.Data) string {
+	return `"` + strconv.Itoa(data.Revision) + `"`
+}
+
+// matchesETag reports whether an If-Match header value names etag. Weak tags
+// compare like strong ones, since revisions identify the content exactly.
+func matchesETag(ifMatch, etag string) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
 		}
+	}
+	return false
+}
 
-		data, err := dataStorage.GetDataByID(r.Context(), dataID)
-		if err != nil {
-			if err.Error() == "data not found" {
-				http.Error(w, "Data not found", http.StatusNotFound)
-				return
-			}
-			http.Error(w, "Failed to get data", http.StatusInternalServerError)
+// checkUpdatePrecondition checks that an update is based on the current
+// version of data, named by an If-Match header, by base_revision or, from
+// older clients, by base_updated_at. It returns the status to reject the
+// update with and why, or 0 if it may go ahead.
+func checkUpdatePrecondition(ifMatch string, req models.DataRequest, data *models.Data) (int, string) {
+	modified := "Data was modified by another device"
+	switch {
+	case ifMatch != "":
+		if !matchesETag(ifMatch, dataETag(data)) {
+			return http.StatusConflict, modified
+		}
+	case req.BaseRevision != nil:
+		if *req.BaseRevision != data.Revision {
+			return http.StatusConflict, modified
+		}
+	case req.BaseUpdatedAt != nil:
+		// Storage may keep less than nanosecond precision, so compare at microseconds
+		if !req.BaseUpdatedAt.Truncate(time.Microsecond).Equal(data.UpdatedAt.Truncate(time.Microsecond)) {
+			return http.StatusConflict, modified
+		}
+	default:
+		return http.StatusPreconditionRequired, "If-Match header or base_revision required"
+	}
+	return 0, ""
+}
+
+func handleUpdateData(dataStorage DataStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, dataID, ok := dataRoute(w, r)
+		if !ok {
 			return
 		}
 
-		if data.UserID != userID {
-			http.Error(w, "Access denied", http.StatusForbidden)
+		var req models.DataRequest
+		if !decodeBody(w, r, &req) {
 			return
 		}
 
-		data.Type = req.Type
-		data.Name = req.Name
-		data.Description = req.Description
-		data.Data = req.Data
-		data.Metadata = req.Metadata
-		data.UpdatedAt = time.Now()
-
-		if err := dataStorage.UpdateData(r.Context(), data); err != nil {
-			http.Error(w, "Failed to update data", http.StatusInternalServerError)
+		data, err := UpdateData(r.Context(), dataStorage, opts, userID, dataID, r.Header.Get("If-Match"), req)
+		if err != nil {
+			apierror.Reply(w, err)
 			return
 		}
 
 		response := models.DataResponse{Data: *data}
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", dataETag(data))
 		if err := json.NewEncoder(w).Encode(response); err != nil {
-			logger.Log.Error("Failed to encode response", zap.Error(err))
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
 		}
 	}
 }
 
-func handleDeleteData(dataStorage DataStorage) http.HandlerFunc {
+// handlePatchData changes the name, description, metadata, domain index,
+// icon or expiry of an item and keeps its encrypted payload, so they can be edited
+// without the master password
+func handlePatchData(dataStorage DataStorage, opts Options) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		dataID, err := uuid.Parse(vars["id"])
+		var req models.DataPatchRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+			apierror.Error(w, "Name cannot be empty", http.StatusBadRequest)
+			return
+		}
+		domains, err := requestDomains(req.Domains)
 		if err != nil {
-			http.Error(w, "Invalid data ID", http.StatusBadRequest)
+			apierror.Error(w, "Invalid domains: "+err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
-		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
 			return
 		}
 
-		data, err := dataStorage.GetDataByID(r.Context(), dataID)
-		if err != nil {
-			if err.Error() == "data not found" {
-				http.Error(w, "Data not found", http.StatusNotFound)
+		ifMatch := r.Header.Get("If-Match")
+		if ifMatch != "" || req.BaseRevision != nil {
+			precondition := models.DataRequest{BaseRevision: req.BaseRevision}
+			if status, reason := checkUpdatePrecondition(ifMatch, precondition, data); status != 0 {
+				w.Header().Set("ETag", dataETag(data))
+				apierror.Error(w, reason, status)
 				return
 			}
-			http.Error(w, "Failed to get data", http.StatusInternalServerError)
+		}
+
+		if req.Name != nil {
+			data.Name = *req.Name
+		}
+		if req.Description != nil {
+			data.Description = *req.Description
+		}
+		if req.Metadata != nil {
+			data.Metadata = *req.Metadata
+		}
+		if req.Domains != nil {
+			data.Domains = domains
+		}
+		if req.Icon != nil {
+			data.Icon = requestIcon(req.Icon)
+		}
+		setExpiry(data, req.ExpiresAt, opts)
+		data.UpdatedAt = opts.Clock.Now()
+
+		if err := dataStorage.UpdateData(r.Context(), data); err != nil {
+			apierror.Error(w, "Failed to update data", http.StatusInternalServerError)
 			return
 		}
 
-		if data.UserID != userID {
-			http.Error(w, "Access denied", http.StatusForbidden)
+		recordRead(r.Context(), dataStorage, data)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", dataETag(data))
+		if err := json.NewEncoder(w).Encode(models.DataResponse{Data: *data}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+func handleDeleteData(dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, dataID, ok := dataRoute(w, r)
+		if !ok {
 			return
 		}
 
-		if err := dataStorage.DeleteData(r.Context(), dataID); err != nil {
-			http.Error(w, "Failed to delete data", http.StatusInternalServerError)
+		if err := DeleteData(r.Context(), dataStorage, userID, dataID); err != nil {
+			apierror.Reply(w, err)
 			return
 		}
 
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// benchmarkServer is a router over memory storage with one user and item
+type benchmarkServer struct {
+	router *mux.Router
+	token  string
+	data   *models.Data
+}
+
+func newBenchmarkServer(b *testing.B, wrap func(DataStorage) DataStorage) *benchmarkServer {
+	b.Helper()
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		b.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	user := &models.User{ID: uuid.New(), Username: "testuser", Password: string(hashedPassword)}
+	if err := store.CreateUser(context.Background(), user); err != nil {
+		b.Fatalf("CreateUser() error = %v", err)
+	}
+	data := &models.Data{ID: uuid.New(), UserID: user.ID, Type: models.DataTypeText, Name: "Test Data",
+		Data: make([]byte, 1024), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := store.CreateData(context.Background(), data); err != nil {
+		b.Fatalf("CreateData() error = %v", err)
+	}
+	if data, err = store.GetDataByID(context.Background(), data.ID); err != nil {
+		b.Fatalf("GetDataByID() error = %v", err)
+	}
+	token, err := jwtManager.GenerateToken(user.ID, user.Username)
+	if err != nil {
+		b.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	var dataStorage DataStorage = store
+	if wrap != nil {
+		dataStorage = wrap(dataStorage)
+	}
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, dataStorage, jwtManager, Options{})
+	return &benchmarkServer{router: router, token: token, data: data}
+}
+
+// serve runs one request and fails the benchmark unless it gets status
+func (s *benchmarkServer) serve(b *testing.B, method, path string, body []byte, status int) {
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	if w.Code != status {
+		b.Fatalf("%s %s: expected status %d, got %d: %s", method, path, status, w.Code, w.Body.String())
+	}
+}
+
+func BenchmarkServer_Login(b *testing.B) {
+	s := newBenchmarkServer(b, nil)
+	body, _ := json.Marshal(models.LoginRequest{Username: "testuser", Password: "password123"})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.serve(b, http.MethodPost, "/api/v1/login", body, http.StatusOK)
+	}
+}
+
+func BenchmarkServer_CreateData(b *testing.B) {
+	s := newBenchmarkServer(b, nil)
+	body, _ := json.Marshal(models.DataRequest{Type: models.DataTypeText, Name: "Test Data", Data: make([]byte, 1024)})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.serve(b, http.MethodPost, "/api/v1/data", body, http.StatusCreated)
+	}
+}
+
+func BenchmarkServer_GetDataByID(b *testing.B) {
+	for _, bb := range []struct {
+		name string
+		wrap func(DataStorage) DataStorage
+	}{
+		{name: "uncached"},
+		{name: "cached", wrap: func(dataStorage DataStorage) DataStorage {
+			return NewCachingDataStorage(dataStorage, NewStorageCache(100, time.Minute))
+		}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			s := newBenchmarkServer(b, bb.wrap)
+			path := "/api/v1/data/" + s.data.ID.String()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.serve(b, http.MethodGet, path, nil, http.StatusOK)
+			}
+		})
+	}
+}
+
+func BenchmarkServer_UpdateData(b *testing.B) {
+	s := newBenchmarkServer(b, nil)
+	path := "/api/v1/data/" + s.data.ID.String()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		revision := s.data.Revision + i
+		body, _ := json.Marshal(models.DataRequest{Type: models.DataTypeText, Name: "Renamed", Data: make([]byte, 1024),
+			BaseRevision: &revision})
+		s.serve(b, http.MethodPut, path, body, http.StatusOK)
+	}
+}
+
+func BenchmarkServer_GetData(b *testing.B) {
+	s := newBenchmarkServer(b, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.serve(b, http.MethodGet, "/api/v1/data", nil, http.StatusOK)
+	}
+}
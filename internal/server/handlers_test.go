@@ -3,14 +3,22 @@ package server
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/idgen"
 	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
 	"github.com/a2sh3r/gophkeeper/internal/models"
 	"github.com/a2sh3r/gophkeeper/internal/storage"
 	"github.com/google/uuid"
@@ -43,8 +51,8 @@ func TestServer_Register(t *testing.T) {
 				Password:       "password123",
 				MasterPassword: "masterPassword123!",
 			},
-			expectedStatus: http.StatusOK,
-			wantErr:        false,
+			expectedStatus: http.StatusBadRequest,
+			wantErr:        true,
 		},
 		{
 			name: "empty password",
@@ -53,8 +61,8 @@ func TestServer_Register(t *testing.T) {
 				Password:       "",
 				MasterPassword: "masterPassword123!",
 			},
-			expectedStatus: http.StatusOK,
-			wantErr:        false,
+			expectedStatus: http.StatusBadRequest,
+			wantErr:        true,
 		},
 	}
 
@@ -65,7 +73,7 @@ func TestServer_Register(t *testing.T) {
 			jwtManager := auth.NewJWTManager("test-secret", time.Hour)
 
 			router := mux.NewRouter()
-			RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+			RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 			jsonBody, _ := json.Marshal(tt.req)
 			req := httptest.NewRequest("POST", "/api/v1/register", bytes.NewBuffer(jsonBody))
@@ -96,6 +104,52 @@ func TestServer_Register(t *testing.T) {
 	}
 }
 
+func TestServer_Register_KDFIterations(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+
+	register := func(username string, iterations int) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(models.UserRequest{Username: username, Password: "password123",
+			MasterPassword: "masterPassword123!", KDFIterations: iterations})
+		req := httptest.NewRequest("POST", "/api/v1/register", bytes.NewBuffer(jsonBody))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := register("toofew", 1000); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for too few iterations, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w := register("tuned", 600000)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var registered models.AuthResponse
+	if err := json.NewDecoder(w.Body).Decode(&registered); err != nil || registered.KDFIterations != 600000 {
+		t.Fatalf("Expected the iteration count in the response, got %d, %v", registered.KDFIterations, err)
+	}
+
+	jsonBody, _ := json.Marshal(models.LoginRequest{Username: "tuned", Password: "password123"})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/login", bytes.NewBuffer(jsonBody)))
+	var loggedIn models.AuthResponse
+	if err := json.NewDecoder(w.Body).Decode(&loggedIn); err != nil || loggedIn.KDFIterations != 600000 {
+		t.Errorf("Expected the iteration count on login, got %d, %v", loggedIn.KDFIterations, err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/salt", nil)
+	req.Header.Set("Authorization", "Bearer "+registered.Token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var salt models.SaltResponse
+	if err := json.NewDecoder(w.Body).Decode(&salt); err != nil || salt.Salt != registered.Salt || salt.KDFIterations != 600000 {
+		t.Errorf("Expected the salt with its iteration count, got %+v, %v", salt, err)
+	}
+}
+
 func TestServer_Register_DuplicateUser(t *testing.T) {
 	userStorage := storage.NewMemoryStorage()
 	dataStorage := storage.NewMemoryStorage()
@@ -114,7 +168,7 @@ func TestServer_Register_DuplicateUser(t *testing.T) {
 	}
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	reqBody := models.UserRequest{
 		Username:       "testuser",
@@ -132,6 +186,10 @@ func TestServer_Register_DuplicateUser(t *testing.T) {
 	if w.Code != http.StatusConflict {
 		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
 	}
+	var errResp models.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil || errResp.Code != models.ErrorCodeUserExists {
+		t.Errorf("Expected a JSON error with code %s, got %+v, %v", models.ErrorCodeUserExists, errResp, err)
+	}
 }
 
 func TestServer_Login(t *testing.T) {
@@ -156,7 +214,7 @@ func TestServer_Login(t *testing.T) {
 				Username: "",
 				Password: "password123",
 			},
-			expectedStatus: http.StatusUnauthorized,
+			expectedStatus: http.StatusBadRequest,
 			wantErr:        true,
 		},
 		{
@@ -165,7 +223,7 @@ func TestServer_Login(t *testing.T) {
 				Username: "testuser",
 				Password: "",
 			},
-			expectedStatus: http.StatusUnauthorized,
+			expectedStatus: http.StatusBadRequest,
 			wantErr:        true,
 		},
 	}
@@ -191,7 +249,7 @@ func TestServer_Login(t *testing.T) {
 			}
 
 			router := mux.NewRouter()
-			RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+			RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 			jsonBody, _ := json.Marshal(tt.req)
 			req := httptest.NewRequest("POST", "/api/v1/login", bytes.NewBuffer(jsonBody))
@@ -250,8 +308,8 @@ func TestServer_CreateData(t *testing.T) {
 				Data:        []byte("test content"),
 				Metadata:    "{}",
 			},
-			expectedStatus: http.StatusCreated,
-			wantErr:        false,
+			expectedStatus: http.StatusBadRequest,
+			wantErr:        true,
 		},
 		{
 			name: "empty data",
@@ -262,8 +320,18 @@ func TestServer_CreateData(t *testing.T) {
 				Data:        []byte(""),
 				Metadata:    "{}",
 			},
-			expectedStatus: http.StatusCreated,
-			wantErr:        false,
+			expectedStatus: http.StatusBadRequest,
+			wantErr:        true,
+		},
+		{
+			name: "unknown type",
+			req: models.DataRequest{
+				Type: "secret",
+				Name: "Test Data",
+				Data: []byte("test content"),
+			},
+			expectedStatus: http.StatusBadRequest,
+			wantErr:        true,
 		},
 	}
 
@@ -277,7 +345,7 @@ func TestServer_CreateData(t *testing.T) {
 			token, _ := jwtManager.GenerateToken(userID, "testuser")
 
 			router := mux.NewRouter()
-			RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+			RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 			jsonBody, _ := json.Marshal(tt.req)
 			req := httptest.NewRequest("POST", "/api/v1/data", bytes.NewBuffer(jsonBody))
@@ -304,6 +372,14 @@ func TestServer_CreateData(t *testing.T) {
 				if response.Data.UserID != userID {
 					t.Errorf("Expected UserID %s, got %s", userID, response.Data.UserID)
 				}
+			} else {
+				var response models.ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if len(response.Fields) != 1 || response.Code != models.ErrorCodeInvalidRequest {
+					t.Errorf("Expected one invalid field, got %+v", response)
+				}
 			}
 		})
 	}
@@ -357,7 +433,7 @@ func TestServer_GetData(t *testing.T) {
 			}
 
 			router := mux.NewRouter()
-			RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+			RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 			req := httptest.NewRequest("GET", "/api/v1/data", nil)
 			req.Header.Set("Authorization", "Bearer "+token)
@@ -444,7 +520,7 @@ func TestServer_GetDataByID(t *testing.T) {
 			}
 
 			router := mux.NewRouter()
-			RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+			RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 			req := httptest.NewRequest("GET", "/api/v1/data/"+dataID, nil)
 			req.Header.Set("Authorization", "Bearer "+token)
@@ -553,12 +629,13 @@ func TestServer_UpdateData(t *testing.T) {
 			}
 
 			router := mux.NewRouter()
-			RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+			RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 			jsonBody, _ := json.Marshal(tt.req)
 			req := httptest.NewRequest("PUT", "/api/v1/data/"+dataID, bytes.NewBuffer(jsonBody))
 			req.Header.Set("Content-Type", "application/json")
 			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("If-Match", `"1"`)
 
 			w := httptest.NewRecorder()
 			router.ServeHTTP(w, req)
@@ -642,7 +719,7 @@ func TestServer_DeleteData(t *testing.T) {
 			}
 
 			router := mux.NewRouter()
-			RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+			RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 			req := httptest.NewRequest("DELETE", "/api/v1/data/"+dataID, nil)
 			req.Header.Set("Authorization", "Bearer "+token)
@@ -663,7 +740,7 @@ func TestServer_HandleRegister_InvalidJSON(t *testing.T) {
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	req := httptest.NewRequest("POST", "/api/v1/register", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -682,7 +759,7 @@ func TestServer_HandleLogin_InvalidJSON(t *testing.T) {
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	req := httptest.NewRequest("POST", "/api/v1/login", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -704,7 +781,7 @@ func TestServer_HandleCreateData_InvalidJSON(t *testing.T) {
 	token, _ := jwtManager.GenerateToken(userID, "testuser")
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	req := httptest.NewRequest("POST", "/api/v1/data", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -727,7 +804,7 @@ func TestServer_HandleUpdateData_InvalidJSON(t *testing.T) {
 	token, _ := jwtManager.GenerateToken(userID, "testuser")
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	req := httptest.NewRequest("PUT", "/api/v1/data/550e8400-e29b-41d4-a716-446655440000", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -759,7 +836,7 @@ func TestServer_HandleRegister_StorageError(t *testing.T) {
 	}
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	reqBody := models.UserRequest{
 		Username:       "testuser",
@@ -779,13 +856,66 @@ func TestServer_HandleRegister_StorageError(t *testing.T) {
 	}
 }
 
+func TestServer_HandleRegister_CredentialPolicy(t *testing.T) {
+	policy := models.DefaultCredentialPolicy()
+	policy.PasswordMinClasses = 2
+
+	store := storage.NewMemoryStorage()
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, auth.NewJWTManager("test-secret", time.Hour), Options{CredentialPolicy: &policy})
+
+	tests := []struct {
+		name       string
+		username   string
+		password   string
+		wantFields []string
+	}{
+		{name: "valid", username: "alice", password: "password123"},
+		{name: "short username", username: "al", password: "password123", wantFields: []string{"username"}},
+		{name: "username charset", username: "alice/bob", password: "password123", wantFields: []string{"username"}},
+		{name: "short password", username: "bob", password: "pass1", wantFields: []string{"password"}},
+		{name: "one character class", username: "carol", password: "passwordpassword", wantFields: []string{"password"}},
+		{name: "both", username: "d", password: "p", wantFields: []string{"username", "password"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(models.UserRequest{Username: tt.username, Password: tt.password, MasterPassword: "masterPassword123!"})
+			req := httptest.NewRequest("POST", "/api/v1/register", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if tt.wantFields == nil {
+				if w.Code != http.StatusOK {
+					t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+				}
+				return
+			}
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+			var resp models.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var fields []string
+			for _, field := range resp.Fields {
+				fields = append(fields, field.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("Expected invalid fields %v, got %+v", tt.wantFields, resp.Fields)
+			}
+		})
+	}
+}
+
 func TestServer_HandleLogin_StorageError(t *testing.T) {
 	userStorage := storage.NewMemoryStorage()
 	dataStorage := storage.NewMemoryStorage()
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	reqBody := models.LoginRequest{
 		Username: "nonexistent",
@@ -829,7 +959,7 @@ func TestServer_HandleGetDataByID_AccessDenied(t *testing.T) {
 	}
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	req := httptest.NewRequest("GET", "/api/v1/data/"+data.ID.String(), nil)
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -867,7 +997,7 @@ func TestServer_HandleUpdateData_AccessDenied(t *testing.T) {
 	}
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	reqBody := models.DataRequest{
 		Type: models.DataTypeText,
@@ -913,7 +1043,7 @@ func TestServer_HandleDeleteData_AccessDenied(t *testing.T) {
 	}
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	req := httptest.NewRequest("DELETE", "/api/v1/data/"+data.ID.String(), nil)
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -932,7 +1062,7 @@ func TestServer_HandleRegister_InternalError(t *testing.T) {
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	req := httptest.NewRequest("POST", "/api/v1/register", bytes.NewBufferString("invalid json"))
 	req.Header.Set("Content-Type", "application/json")
@@ -951,7 +1081,7 @@ func TestServer_HandleLogin_InternalError(t *testing.T) {
 	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	req := httptest.NewRequest("POST", "/api/v1/login", bytes.NewBufferString("invalid json"))
 	req.Header.Set("Content-Type", "application/json")
@@ -973,7 +1103,7 @@ func TestServer_HandleGetData_InternalError(t *testing.T) {
 	token, _ := jwtManager.GenerateToken(userID, "testuser")
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	req := httptest.NewRequest("GET", "/api/v1/data", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -995,7 +1125,7 @@ func TestServer_HandleCreateData_InternalError(t *testing.T) {
 	token, _ := jwtManager.GenerateToken(userID, "testuser")
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	reqBody := models.DataRequest{
 		Type: models.DataTypeText,
@@ -1025,7 +1155,7 @@ func TestServer_HandleDeleteData_InternalError(t *testing.T) {
 	token, _ := jwtManager.GenerateToken(userID, "testuser")
 
 	router := mux.NewRouter()
-	RegisterRoutes(router, userStorage, dataStorage, jwtManager)
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
 
 	req := httptest.NewRequest("DELETE", "/api/v1/data/550e8400-e29b-41d4-a716-446655440000", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -1037,3 +1167,1012 @@ func TestServer_HandleDeleteData_InternalError(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
 }
+
+func TestServer_HandleGetSalt(t *testing.T) {
+	userStorage := storage.NewMemoryStorage()
+	dataStorage := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	user := &models.User{
+		ID:        uuid.New(),
+		Username:  "testuser",
+		Salt:      "c2FsdA==",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := userStorage.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
+
+	tests := []struct {
+		name           string
+		userID         uuid.UUID
+		expectedStatus int
+	}{
+		{
+			name:           "existing user",
+			userID:         user.ID,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown user",
+			userID:         uuid.New(),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _ := jwtManager.GenerateToken(tt.userID, "testuser")
+			req := httptest.NewRequest("GET", "/api/v1/salt", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var response models.SaltResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if response.Salt != user.Salt {
+					t.Errorf("Expected salt %s, got %s", user.Salt, response.Salt)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_HandleSetSalt(t *testing.T) {
+	userStorage := storage.NewMemoryStorage()
+	dataStorage := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	user := &models.User{
+		ID:        uuid.New(),
+		Username:  "legacyuser",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := userStorage.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
+	token, _ := jwtManager.GenerateToken(user.ID, user.Username)
+
+	salt := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	otherSalt := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+
+	tests := []struct {
+		name           string
+		salt           string
+		expectedStatus int
+	}{
+		{
+			name:           "invalid salt",
+			salt:           "c2FsdA==",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "first registration",
+			salt:           salt,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "replayed registration",
+			salt:           salt,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "overwrite attempt",
+			salt:           otherSalt,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonBody, _ := json.Marshal(models.SaltRequest{Salt: tt.salt})
+			req := httptest.NewRequest("PUT", "/api/v1/salt", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	storedUser, _ := userStorage.GetUserByID(context.Background(), user.ID)
+	if storedUser.Salt != salt {
+		t.Errorf("Expected salt %s to be kept, got %s", salt, storedUser.Salt)
+	}
+}
+
+func TestServer_HandleGetData_EnvironmentFilter(t *testing.T) {
+	userStorage := storage.NewMemoryStorage()
+	dataStorage := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	userID := uuid.New()
+	token, _ := jwtManager.GenerateToken(userID, "testuser")
+
+	for _, env := range []string{"dev", "prod", "prod", ""} {
+		data := &models.Data{
+			ID:          uuid.New(),
+			UserID:      userID,
+			Type:        models.DataTypeText,
+			Name:        "API key " + env,
+			Data:        []byte("test content"),
+			Environment: env,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		if err := dataStorage.CreateData(context.Background(), data); err != nil {
+			t.Fatalf("Failed to create data: %v", err)
+		}
+	}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCount int
+	}{
+		{name: "no filter", query: "", expectedCount: 4},
+		{name: "prod", query: "?environment=prod", expectedCount: 2},
+		{name: "dev", query: "?environment=dev", expectedCount: 1},
+		{name: "unknown", query: "?environment=staging", expectedCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/data"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+			}
+
+			var response models.DataListResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Data) != tt.expectedCount {
+				t.Errorf("Expected %d data items, got %d", tt.expectedCount, len(response.Data))
+			}
+		})
+	}
+}
+
+func TestServer_HandleSearchData(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	userID := uuid.New()
+	token, _ := jwtManager.GenerateToken(userID, "testuser")
+
+	items := []models.Data{
+		{Type: models.DataTypeLoginPassword, Name: "Mail", Metadata: "Login: me, URL: https://mail.example.com"},
+		{Type: models.DataTypeText, Name: "Router", Description: "Admin login for EXAMPLE.com"},
+		{Type: models.DataTypeBankCard, Name: "Visa", Metadata: "Bank: Example Bank"},
+		{Type: models.DataTypeText, Name: "Notes"},
+	}
+	for i := range items {
+		items[i].ID = uuid.New()
+		items[i].UserID = userID
+		if err := store.CreateData(context.Background(), &items[i]); err != nil {
+			t.Fatalf("Failed to create data: %v", err)
+		}
+	}
+	other := &models.Data{ID: uuid.New(), UserID: uuid.New(), Type: models.DataTypeText, Name: "example.com"}
+	if err := store.CreateData(context.Background(), other); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{name: "metadata and description", query: "?q=example.com", expectedStatus: http.StatusOK, expectedCount: 2},
+		{name: "all fields", query: "?q=example", expectedStatus: http.StatusOK, expectedCount: 3},
+		{name: "narrowed by type", query: "?q=example&type=text", expectedStatus: http.StatusOK, expectedCount: 1},
+		{name: "no match", query: "?q=vpn", expectedStatus: http.StatusOK, expectedCount: 0},
+		{name: "missing query", query: "?q=+", expectedStatus: http.StatusBadRequest},
+		{name: "invalid type", query: "?q=mail&type=secret", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/data/search"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.DataListResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Data) != tt.expectedCount {
+				t.Errorf("Expected %d data items, got %d", tt.expectedCount, len(response.Data))
+			}
+		})
+	}
+}
+
+func TestServer_DataTags(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	token, _ := jwtManager.GenerateToken(uuid.New(), "testuser")
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+
+	do := func(method, path string, body interface{}, revision int) *httptest.ResponseRecorder {
+		req := newTestRequest(method, path, token, body)
+		if revision > 0 {
+			req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, revision))
+		}
+		return serveRequest(router, req)
+	}
+	tagsOf := func(w *httptest.ResponseRecorder) []string {
+		var response models.DataResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response.Data.Tags
+	}
+
+	req := models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x"), Tags: &[]string{"a,b"}}
+	if w := do("POST", "/api/v1/data", req, 0); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid tag, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	req.Tags = &[]string{"Work", "infra", "work"}
+	w := do("POST", "/api/v1/data", req, 0)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var created models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !reflect.DeepEqual(created.Data.Tags, []string{"infra", "work"}) {
+		t.Errorf("Expected normalized tags, got %q", created.Data.Tags)
+	}
+	path := "/api/v1/data/" + created.Data.ID.String()
+
+	req.Tags = nil
+	if got := tagsOf(do("PUT", path, req, 1)); !reflect.DeepEqual(got, []string{"infra", "work"}) {
+		t.Errorf("Expected an update without tags to keep them, got %q", got)
+	}
+	req.Tags = &[]string{}
+	if got := tagsOf(do("PUT", path, req, 2)); len(got) != 0 {
+		t.Errorf("Expected an empty list to clear the tags, got %q", got)
+	}
+
+	for query, want := range map[string]int{"?tag=infra": 0, "?tag=WORK": 0, "": 1} {
+		w := do("GET", "/api/v1/data"+query, nil, 0)
+		var response models.DataListResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(response.Data) != want {
+			t.Errorf("GET /api/v1/data%s returned %d items, want %d", query, len(response.Data), want)
+		}
+	}
+}
+
+func TestServer_DataDomains(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	token, _ := jwtManager.GenerateToken(uuid.New(), "testuser")
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		return doRequest(router, method, path, token, body)
+	}
+	count := func(query string) int {
+		var response models.DataListResponse
+		if err := json.NewDecoder(do("GET", "/api/v1/data"+query, nil).Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return len(response.Data)
+	}
+
+	req := models.DataRequest{Type: models.DataTypeLoginPassword, Name: "mail", Data: []byte("x"),
+		Domains: &[]string{"https://example.com/login"}}
+	if w := do("POST", "/api/v1/data", req); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a URL as a domain, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	req.Domains = &[]string{"Example.com"}
+	w := do("POST", "/api/v1/data", req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var created models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !reflect.DeepEqual(created.Data.Domains, []string{"example.com"}) {
+		t.Errorf("Expected normalized domains, got %q", created.Data.Domains)
+	}
+	if got := count("?type=login_password&domain=EXAMPLE.COM"); got != 1 {
+		t.Errorf("Expected the item under its domain, got %d items", got)
+	}
+	if got := count("?domain=example.org"); got != 0 {
+		t.Errorf("Expected no items under another domain, got %d", got)
+	}
+
+	path := "/api/v1/data/" + created.Data.ID.String()
+	revision := created.Data.Revision
+	req.Domains, req.BaseRevision = nil, &revision
+	if w := do("PUT", path, req); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := count("?domain=example.com"); got != 1 {
+		t.Errorf("Expected an update without domains to keep them, got %d items", got)
+	}
+
+	patch := models.DataPatchRequest{Domains: &[]string{"example.org"}}
+	if w := do("PATCH", path, patch); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := count("?domain=example.org"); got != 1 {
+		t.Errorf("Expected the patched domain index, got %d items", got)
+	}
+}
+
+func TestServer_DataIcons(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	token, _ := jwtManager.GenerateToken(uuid.New(), "testuser")
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		return doRequest(router, method, path, token, body)
+	}
+	listed := func() []byte {
+		var response models.DataListResponse
+		if err := json.NewDecoder(do("GET", "/api/v1/data", nil).Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(response.Data) != 1 {
+			t.Fatalf("Expected 1 item, got %d", len(response.Data))
+		}
+		return response.Data[0].Icon
+	}
+
+	tooLarge := make([]byte, models.MaxIconSize+1)
+	req := models.DataRequest{Type: models.DataTypeLoginPassword, Name: "mail", Data: []byte("x"), Icon: &tooLarge}
+	if w := do("POST", "/api/v1/data", req); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a large icon, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	icon := []byte("encrypted icon")
+	req.Icon = &icon
+	w := do("POST", "/api/v1/data", req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var created models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got := listed(); !bytes.Equal(got, icon) {
+		t.Errorf("Expected the icon in list responses, got %q", got)
+	}
+
+	path := "/api/v1/data/" + created.Data.ID.String()
+	revision := created.Data.Revision
+	req.Icon, req.BaseRevision = nil, &revision
+	if w := do("PUT", path, req); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := listed(); !bytes.Equal(got, icon) {
+		t.Errorf("Expected an update without an icon to keep it, got %q", got)
+	}
+
+	if w := do("PATCH", path, models.DataPatchRequest{Icon: &[]byte{}}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := listed(); got != nil {
+		t.Errorf("Expected an empty icon to remove it, got %q", got)
+	}
+}
+
+func TestServer_DataExpiry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	token, _ := jwtManager.GenerateToken(uuid.New(), "testuser")
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{Clock: clock.NewManual(now)})
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		return doRequest(router, method, path, token, body)
+	}
+	names := func(query string) []string {
+		w := do("GET", "/api/v1/data"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /api/v1/data%s: expected status %d, got %d", query, http.StatusOK, w.Code)
+		}
+		var response models.DataListResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		var names []string
+		for _, data := range response.Data {
+			names = append(names, data.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	create := func(name string, expiresAt *time.Time) models.Data {
+		t.Helper()
+		w := do("POST", "/api/v1/data", models.DataRequest{Type: models.DataTypeBankCard, Name: name, Data: []byte("x"), ExpiresAt: expiresAt})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+		var created models.DataResponse
+		if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return created.Data
+	}
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	expired := create("expired", at(-24*time.Hour))
+	expiring := create("expiring", at(10*24*time.Hour))
+	later := create("later", at(60*24*time.Hour))
+	create("never", nil)
+	if expired.Expiry != models.ExpiryExpired || expiring.Expiry != models.ExpiryExpiring || later.Expiry != "" {
+		t.Errorf("Expected the items flagged on create, got %q, %q and %q", expired.Expiry, expiring.Expiry, later.Expiry)
+	}
+
+	if got := names("?expiring=30d"); !reflect.DeepEqual(got, []string{"expired", "expiring"}) {
+		t.Errorf("GET ?expiring=30d = %q, want the expired and expiring items", got)
+	}
+	if got := names("?expiring=90d"); !reflect.DeepEqual(got, []string{"expired", "expiring", "later"}) {
+		t.Errorf("GET ?expiring=90d = %q, want every item with an expiry", got)
+	}
+	if w := do("GET", "/api/v1/data?expiring=soon", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid period, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	path := "/api/v1/data/" + later.ID.String()
+	if w := do("PATCH", path, models.DataPatchRequest{ExpiresAt: at(5 * 24 * time.Hour)}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := names("?expiring=30d"); len(got) != 3 {
+		t.Errorf("Expected the patched expiry to be listed, got %q", got)
+	}
+
+	// an update without an expiry keeps it, the zero time clears it
+	revision := later.Revision + 1
+	req := models.DataRequest{Type: models.DataTypeBankCard, Name: "later", Data: []byte("y"), BaseRevision: &revision}
+	if w := do("PUT", path, req); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := names("?expiring=30d"); len(got) != 3 {
+		t.Errorf("Expected an update without an expiry to keep it, got %q", got)
+	}
+	w := do("PATCH", path, models.DataPatchRequest{ExpiresAt: &time.Time{}})
+	var patched models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&patched); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if patched.Data.ExpiresAt != nil || patched.Data.Expiry != "" {
+		t.Errorf("Expected the expiry cleared, got %v %q", patched.Data.ExpiresAt, patched.Data.Expiry)
+	}
+}
+
+func TestServer_HandleGetData_Pagination(t *testing.T) {
+	userStorage := storage.NewMemoryStorage()
+	dataStorage := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	userID := uuid.New()
+	token, _ := jwtManager.GenerateToken(userID, "testuser")
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"Mail", "Bank", "Mailbox notes", "VPN", "Wi-Fi"} {
+		dataType := models.DataTypeLoginPassword
+		if name == "Mailbox notes" {
+			dataType = models.DataTypeText
+		}
+		data := &models.Data{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Type:      dataType,
+			Name:      name,
+			Data:      []byte("test content"),
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+			UpdatedAt: base,
+		}
+		if err := dataStorage.CreateData(context.Background(), data); err != nil {
+			t.Fatalf("Failed to create data: %v", err)
+		}
+	}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, userStorage, dataStorage, jwtManager, Options{})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedNames  []string
+	}{
+		{name: "first page", query: "?limit=2", expectedStatus: http.StatusOK, expectedNames: []string{"Wi-Fi", "VPN"}},
+		{name: "last page", query: "?limit=2&offset=4", expectedStatus: http.StatusOK, expectedNames: []string{"Mail"}},
+		{name: "type", query: "?type=text", expectedStatus: http.StatusOK, expectedNames: []string{"Mailbox notes"}},
+		{name: "name", query: "?name=mail", expectedStatus: http.StatusOK, expectedNames: []string{"Mailbox notes", "Mail"}},
+		{name: "type and name", query: "?type=login_password&name=mail", expectedStatus: http.StatusOK, expectedNames: []string{"Mail"}},
+		{name: "unknown type", query: "?type=photo", expectedStatus: http.StatusBadRequest},
+		{name: "zero limit", query: "?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "limit too large", query: "?limit=1001", expectedStatus: http.StatusBadRequest},
+		{name: "negative offset", query: "?offset=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/data"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.DataListResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var names []string
+			for _, data := range response.Data {
+				names = append(names, data.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expectedNames, ",") {
+				t.Errorf("Expected %q, got %q", tt.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestServer_BulkRoutesLimited(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	// A limiter without slots sheds every request it sees
+	closed := middleware.NewConcurrencyLimiter("bulk", 0, 0, time.Millisecond)
+	limiters := make(map[string]*middleware.ConcurrencyLimiter)
+	for _, name := range BulkRoutes {
+		limiters[name] = closed
+	}
+
+	router := mux.NewRouter()
+	router.Use(middleware.LimitRoutes(limiters))
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterEscrowRoutes(router, store, store, store, jwtManager, EscrowOptions{AdminToken: "admin-secret"})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/api/v1/data", want: http.StatusServiceUnavailable},
+		{path: "/api/v1/admin/escrow/someone", want: http.StatusServiceUnavailable},
+		{path: "/api/v1/salt", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+			if rr.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestServer_ULIDDataIDs(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	token, _ := jwtManager.GenerateToken(uuid.New(), "testuser")
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{DataIDs: idgen.NewULID()})
+
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		body, _ := json.Marshal(models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x")})
+		req := httptest.NewRequest("POST", "/api/v1/data", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+
+		var response models.DataResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		ids = append(ids, response.Data.ID)
+	}
+
+	for i := 1; i < len(ids); i++ {
+		if bytes.Compare(ids[i][:], ids[i-1][:]) <= 0 {
+			t.Errorf("Expected time-ordered IDs, got %s after %s", ids[i], ids[i-1])
+		}
+	}
+
+	// Items can be addressed by either text form
+	for _, path := range []string{ids[0].String(), idgen.ULIDString(ids[0])} {
+		req := httptest.NewRequest("GET", "/api/v1/data/"+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: expected status %d, got %d", path, http.StatusOK, w.Code)
+		}
+	}
+}
+
+func TestServer_DeterministicClockAndIDs(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	jwtManager.SetClock(clock.NewManual(start))
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{
+		Clock:     clock.NewManual(start),
+		DataIDs:   idgen.NewSequence(),
+		RecordIDs: idgen.NewSequence(),
+	})
+
+	body, _ := json.Marshal(models.UserRequest{Username: "testuser", Password: "password123", MasterPassword: "master123"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/register", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var registered models.AuthResponse
+	if err := json.NewDecoder(w.Body).Decode(&registered); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if registered.User.ID.String() != "00000000-0000-4000-8000-000000000001" || !registered.User.CreatedAt.Equal(start) {
+		t.Errorf("Expected the first sequence ID created at %v, got %s at %v", start, registered.User.ID, registered.User.CreatedAt)
+	}
+
+	body, _ = json.Marshal(models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x")})
+	req := httptest.NewRequest("POST", "/api/v1/data", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+registered.Token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var response models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data.ID.String() != "00000000-0000-4000-8000-000000000001" || !response.Data.UpdatedAt.Equal(start) {
+		t.Errorf("Expected the first sequence ID updated at %v, got %s at %v", start, response.Data.ID, response.Data.UpdatedAt)
+	}
+}
+
+func TestServer_HandleUpdateData_Conflict(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	userID := uuid.New()
+	token, _ := jwtManager.GenerateToken(userID, "testuser")
+
+	updatedAt := time.Now().Add(-time.Hour)
+	data := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "note",
+		Data: []byte("v1"), CreatedAt: updatedAt, UpdatedAt: updatedAt}
+	if err := store.CreateData(context.Background(), data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+
+	stale := updatedAt.Add(-time.Minute)
+	tests := []struct {
+		name           string
+		base           *time.Time
+		expectedStatus int
+	}{
+		{name: "stale base", base: &stale, expectedStatus: http.StatusConflict},
+		{name: "current base", base: &updatedAt, expectedStatus: http.StatusOK},
+		{name: "base now outdated", base: &updatedAt, expectedStatus: http.StatusConflict},
+		{name: "no base", base: nil, expectedStatus: http.StatusPreconditionRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("v2"),
+				BaseUpdatedAt: tt.base})
+			req := httptest.NewRequest("PUT", "/api/v1/data/"+data.ID.String(), bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestServer_HandleUpdateData_Revision(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	userID := uuid.New()
+	token, _ := jwtManager.GenerateToken(userID, "testuser")
+
+	data := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "note", Data: []byte("v1")}
+	if err := store.CreateData(context.Background(), data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+
+	req := httptest.NewRequest("GET", "/api/v1/data/"+data.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if etag := w.Header().Get("ETag"); etag != `"1"` {
+		t.Fatalf("Expected ETag \"1\", got %q", etag)
+	}
+
+	one, two := 1, 2
+	tests := []struct {
+		name           string
+		ifMatch        string
+		base           *int
+		expectedStatus int
+		expectedETag   string
+	}{
+		{name: "current etag", ifMatch: `"1"`, expectedStatus: http.StatusOK, expectedETag: `"2"`},
+		{name: "stale etag", ifMatch: `"1"`, expectedStatus: http.StatusConflict, expectedETag: `"2"`},
+		{name: "weak etag", ifMatch: `W/"2"`, expectedStatus: http.StatusOK, expectedETag: `"3"`},
+		{name: "stale base revision", base: &two, expectedStatus: http.StatusConflict, expectedETag: `"3"`},
+		{name: "header wins over body", ifMatch: `"3"`, base: &one, expectedStatus: http.StatusOK, expectedETag: `"4"`},
+		{name: "any revision", ifMatch: "*", expectedStatus: http.StatusOK, expectedETag: `"5"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte(tt.name),
+				BaseRevision: tt.base})
+			req := httptest.NewRequest("PUT", "/api/v1/data/"+data.ID.String(), bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if etag := w.Header().Get("ETag"); etag != tt.expectedETag {
+				t.Errorf("Expected ETag %s, got %q", tt.expectedETag, etag)
+			}
+		})
+	}
+}
+
+func TestServer_HandlePatchData(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	userID := uuid.New()
+	token, _ := jwtManager.GenerateToken(userID, "testuser")
+	otherToken, _ := jwtManager.GenerateToken(uuid.New(), "other")
+
+	data := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "note", Description: "old",
+		Data: []byte("sealed"), Metadata: "Length: 6 characters"}
+	if err := store.CreateData(context.Background(), data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+
+	name, empty, stale := "renamed", " ", 1
+	tests := []struct {
+		name           string
+		token          string
+		patch          models.DataPatchRequest
+		expectedStatus int
+	}{
+		{name: "other user", token: otherToken, patch: models.DataPatchRequest{Name: &name}, expectedStatus: http.StatusForbidden},
+		{name: "empty name", token: token, patch: models.DataPatchRequest{Name: &empty}, expectedStatus: http.StatusBadRequest},
+		{name: "rename", token: token, patch: models.DataPatchRequest{Name: &name}, expectedStatus: http.StatusOK},
+		{name: "stale base revision", token: token, patch: models.DataPatchRequest{Description: &name, BaseRevision: &stale},
+			expectedStatus: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.patch)
+			req := httptest.NewRequest("PATCH", "/api/v1/data/"+data.ID.String(), bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	stored, err := store.GetDataByID(context.Background(), data.ID)
+	if err != nil {
+		t.Fatalf("Failed to get data: %v", err)
+	}
+	if stored.Name != "renamed" || stored.Description != "old" || string(stored.Data) != "sealed" || stored.Metadata != "Length: 6 characters" {
+		t.Errorf("Expected only the name to change, got %+v", stored)
+	}
+	if stored.Revision != 2 {
+		t.Errorf("Expected revision 2, got %d", stored.Revision)
+	}
+}
+
+func TestServer_HandleUpdateData_Checksum(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	userID := uuid.New()
+	token, _ := jwtManager.GenerateToken(userID, "testuser")
+
+	checksum := bytes.Repeat([]byte{1}, 32)
+	data := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "note", Data: []byte("sealed"),
+		Checksum: checksum}
+	if err := store.CreateData(context.Background(), data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	router := mux.NewRouter()
+	RegisterVersionRoutes(router, store, store, jwtManager, Options{})
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		return doRequest(router, method, path, token, body)
+	}
+	stored := func() []byte {
+		current, err := store.GetDataByID(context.Background(), data.ID)
+		if err != nil {
+			t.Fatalf("Failed to get data: %v", err)
+		}
+		return current.Checksum
+	}
+
+	revision := 1
+	w := send("PUT", "/api/v1/data/"+data.ID.String(), models.DataRequest{Type: models.DataTypeText, Name: "note",
+		Data: []byte("changed"), BaseRevision: &revision})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if stored() != nil {
+		t.Error("Expected an update without a checksum to clear the stale one")
+	}
+
+	w = send("PUT", "/api/v1/data/"+data.ID.String(), models.DataRequest{Type: models.DataTypeText, Name: "note",
+		Data: []byte("sealed"), Checksum: []byte("short"), BaseRevision: &revision})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a malformed checksum to be rejected, got %d", w.Code)
+	}
+
+	if w := send("POST", "/api/v1/data/"+data.ID.String()+"/restore/1", nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !bytes.Equal(stored(), checksum) {
+		t.Errorf("Expected the restored version to bring back its checksum, got %x", stored())
+	}
+}
+
+func TestServer_PayloadLimits(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	limits := PayloadLimits(64, map[string]int64{RouteCreateData: 128})
+	router.Use(middleware.MaxBodySizeByRoute(1024, limits))
+
+	if limits[RouteLogin] != 64 || limits[RouteRegister] != 64 || limits[RouteCreateData] != 128 {
+		t.Errorf("Unexpected limits %v", limits)
+	}
+	if err := CheckPayloadLimits(router, map[string]int64{RouteCreateData: 128, RouteLogin: 0}); err != nil {
+		t.Errorf("CheckPayloadLimits() error = %v", err)
+	}
+	if err := CheckPayloadLimits(router, map[string]int64{"data.craete": 128}); err == nil {
+		t.Error("Expected an unknown route name to be refused")
+	}
+	if err := CheckPayloadLimits(router, map[string]int64{RouteCreateData: -1}); err == nil {
+		t.Error("Expected a negative limit to be refused")
+	}
+
+	userID := uuid.New()
+	if err := store.CreateUser(context.Background(), &models.User{ID: userID, Username: "limited"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	token, err := jwtManager.GenerateToken(userID, "limited")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		body          interface{}
+		unknownLength bool
+		want          int
+	}{
+		{name: "login over auth limit", path: "/api/v1/login",
+			body: models.LoginRequest{Username: "limited", Password: strings.Repeat("x", 64)}, want: http.StatusRequestEntityTooLarge},
+		{name: "streamed item over route limit", path: "/api/v1/data", unknownLength: true,
+			body: models.DataRequest{Type: models.DataTypeText, Name: "big", Data: bytes.Repeat([]byte("x"), 200)}, want: http.StatusRequestEntityTooLarge},
+		{name: "item within route limit", path: "/api/v1/data",
+			body: models.DataRequest{Type: models.DataTypeText, Name: "small", Data: []byte("x")}, want: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("POST", tt.path, bytes.NewReader(payload))
+			req.Header.Set("Authorization", "Bearer "+token)
+			if tt.unknownLength {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+)
+
+// newTestRequest builds a request for handler tests. A []byte body is sent as
+// is and any other non-nil body as JSON; a non-empty token authorizes the
+// request.
+func newTestRequest(method, path, token string, body interface{}) *http.Request {
+	var payload []byte
+	switch body := body.(type) {
+	case nil:
+	case []byte:
+		payload = body
+	default:
+		payload, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// serveRequest serves req with handler and returns the recorded response
+func serveRequest(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// doRequest serves a request built by newTestRequest with handler
+func doRequest(handler http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	return serveRequest(handler, newTestRequest(method, path, token, body))
+}
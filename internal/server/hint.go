@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPasswordHintLength is the maximum hint length in characters
+const maxPasswordHintLength = 200
+
+type HintStorage interface {
+	SetPasswordHint(ctx context.Context, userID uuid.UUID, hint string) error
+	GetPasswordHint(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+// RegisterHintRoutes registers the optional master password hint routes
+func RegisterHintRoutes(r *mux.Router, hintStorage HintStorage, userStorage UserStorage, jwtManager *auth.JWTManager) {
+	hint := r.PathPrefix("/api/v1/hint").Subrouter()
+	hint.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	hint.HandleFunc("", handleGetPasswordHint(hintStorage)).Methods("GET")
+	hint.HandleFunc("", handleSetPasswordHint(hintStorage, userStorage)).Methods("PUT")
+	hint.HandleFunc("", handleDeletePasswordHint(hintStorage)).Methods("DELETE")
+}
+
+func handleGetPasswordHint(hintStorage HintStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		hint, err := hintStorage.GetPasswordHint(r.Context(), userID)
+		if err != nil {
+			if err.Error() == "user not found" {
+				apierror.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get hint", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(models.PasswordHintResponse{Hint: hint}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleSetPasswordHint stores the hint as plaintext. Hints matching either of
+// the user's passwords are refused so the password itself is never stored.
+func handleSetPasswordHint(hintStorage HintStorage, userStorage UserStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.PasswordHintRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.Hint = strings.TrimSpace(req.Hint)
+		if req.Hint == "" || utf8.RuneCountInString(req.Hint) > maxPasswordHintLength {
+			apierror.Error(w, "Hint must be between 1 and 200 characters", http.StatusBadRequest)
+			return
+		}
+
+		user, err := userStorage.GetUserByID(r.Context(), userID)
+		if err != nil {
+			if err.Error() == "user not found" {
+				apierror.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(user.MasterPassword), []byte(req.Hint)) == nil ||
+			bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Hint)) == nil {
+			apierror.Error(w, "Hint must not be your password", http.StatusBadRequest)
+			return
+		}
+
+		if err := hintStorage.SetPasswordHint(r.Context(), userID, req.Hint); err != nil {
+			apierror.Error(w, "Failed to store hint", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Password hint set", zap.String("user_id", userID.String()))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func handleDeletePasswordHint(hintStorage HintStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := hintStorage.SetPasswordHint(r.Context(), userID, ""); err != nil {
+			if err.Error() == "user not found" {
+				apierror.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to remove hint", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Password hint removed", zap.String("user_id", userID.String()))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
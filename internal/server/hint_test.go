@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_PasswordHint(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterHintRoutes(router, store, store, jwtManager)
+
+	w := doRequest(router, "POST", "/api/v1/register", "", models.UserRequest{Username: "hinted", Password: "login-pass", MasterPassword: "master-password"})
+	var authResp models.AuthResponse
+	if err := json.NewDecoder(w.Body).Decode(&authResp); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	token := authResp.Token
+
+	if w := doRequest(router, "GET", "/api/v1/hint", "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected hint to require authentication, got %d", w.Code)
+	}
+
+	tests := []struct {
+		name           string
+		hint           string
+		expectedStatus int
+	}{
+		{name: "master password", hint: "master-password", expectedStatus: http.StatusBadRequest},
+		{name: "login password", hint: "login-pass", expectedStatus: http.StatusBadRequest},
+		{name: "empty", hint: "  ", expectedStatus: http.StatusBadRequest},
+		{name: "too long", hint: string(bytes.Repeat([]byte("x"), 201)), expectedStatus: http.StatusBadRequest},
+		{name: "valid", hint: "street I grew up on", expectedStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := doRequest(router, "PUT", "/api/v1/hint", token, models.PasswordHintRequest{Hint: tt.hint}); w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	var hint models.PasswordHintResponse
+	if err := json.NewDecoder(doRequest(router, "GET", "/api/v1/hint", token, nil).Body).Decode(&hint); err != nil || hint.Hint != "street I grew up on" {
+		t.Errorf("Unexpected hint %+v (%v)", hint, err)
+	}
+
+	if w := doRequest(router, "DELETE", "/api/v1/hint", token, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	hint = models.PasswordHintResponse{}
+	if err := json.NewDecoder(doRequest(router, "GET", "/api/v1/hint", token, nil).Body).Decode(&hint); err != nil || hint.Hint != "" {
+		t.Errorf("Expected hint to be removed, got %+v (%v)", hint, err)
+	}
+}
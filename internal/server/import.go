@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/validate"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ImportPath is the streaming import endpoint. Its body is NDJSON of any length,
+// so request size limits apply per record there.
+const ImportPath = "/api/v1/data/import"
+
+// maxImportRecord bounds one import record when no body size limit is configured
+const maxImportRecord = 64 << 20
+
+// validateImportRecord checks a record before it is stored; the message is sent back as its result
+func validateImportRecord(record models.ImportRecord, lastSeq int) error {
+	if record.Seq <= lastSeq {
+		return fmt.Errorf("sequence number %d is not after %d", record.Seq, lastSeq)
+	}
+	return validateDataRequest(record.Data)
+}
+
+// validateDataRequest checks the fields of an item created or changed without
+// a request of its own, as in an import or a batch
+func validateDataRequest(req models.DataRequest) error {
+	if !models.IsValidDataType(req.Type) {
+		return fmt.Errorf("invalid type %q", req.Type)
+	}
+	if req.Name == "" || len(req.Name) > 255 {
+		return fmt.Errorf("name must be between 1 and 255 characters")
+	}
+	if len(req.Data) == 0 {
+		return fmt.Errorf("data is required")
+	}
+	if _, err := requestTags(req); err != nil {
+		return err
+	}
+	if _, err := requestDomains(req.Domains); err != nil {
+		return err
+	}
+	if errs := validate.Struct(req); len(errs) > 0 {
+		return errors.New(errs[0].Message)
+	}
+	return nil
+}
+
+// handleImportData creates items from an NDJSON stream of import records and
+// acknowledges each one as an NDJSON result line as soon as it is stored, so
+// clients can limit the records in flight and resume after the last result
+func handleImportData(dataStorage DataStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		// results are written while the client is still sending records
+		if err := http.NewResponseController(w).EnableFullDuplex(); err != nil {
+			logger.FromContext(r.Context()).Debug("Full duplex not supported", zap.Error(err))
+		}
+		flusher, _ := w.(http.Flusher)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		writeResult := func(result models.ImportResult) bool {
+			if err := encoder.Encode(result); err != nil {
+				logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+				return false
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return true
+		}
+
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 0, 64<<10), maxImportRecord)
+		lastSeq, imported, failed := 0, 0, 0
+		for scanner.Scan() {
+			// after a read error the scanner still returns the partial last line
+			if scanner.Err() != nil {
+				break
+			}
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+
+			var record models.ImportRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				writeResult(models.ImportResult{Error: "Invalid import record"})
+				return
+			}
+
+			result := models.ImportResult{Seq: record.Seq}
+			if err := validateImportRecord(record, lastSeq); err != nil {
+				result.Error = err.Error()
+			} else {
+				tags, _ := requestTags(record.Data) // validated above
+				domains, _ := requestDomains(record.Data.Domains)
+				data := &models.Data{
+					ID:          opts.DataIDs.NewID(),
+					UserID:      userID,
+					Type:        record.Data.Type,
+					Name:        record.Data.Name,
+					Description: record.Data.Description,
+					Data:        record.Data.Data,
+					Metadata:    record.Data.Metadata,
+					Environment: record.Data.Environment,
+					Tags:        tags,
+					Domains:     domains,
+					Icon:        requestIcon(record.Data.Icon),
+					Checksum:    record.Data.Checksum,
+					CreatedAt:   opts.Clock.Now(),
+					UpdatedAt:   opts.Clock.Now(),
+				}
+				setExpiry(data, record.Data.ExpiresAt, opts)
+				if err := dataStorage.CreateData(r.Context(), data); err != nil {
+					result.Error = "Failed to create data"
+				} else {
+					result.ID = &data.ID
+				}
+			}
+			if record.Seq > lastSeq {
+				lastSeq = record.Seq
+			}
+
+			if result.Error != "" {
+				failed++
+			} else {
+				imported++
+			}
+			if !writeResult(result) {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			message := "Failed to read import stream"
+			if errors.Is(err, middleware.ErrRecordTooLarge) || errors.Is(err, bufio.ErrTooLong) {
+				message = "Import record too large"
+			}
+			logger.FromContext(r.Context()).Warn("Import stream aborted", zap.Error(err), zap.Int("last_seq", lastSeq))
+			writeResult(models.ImportResult{Error: message})
+		}
+
+		logger.FromContext(r.Context()).Info("Import finished", zap.String("user_id", userID.String()),
+			zap.Int("imported", imported), zap.Int("failed", failed))
+	}
+}
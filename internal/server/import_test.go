@@ -0,0 +1,126 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_ImportData(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	router.Use(middleware.MaxBodySize(256, ImportPath))
+
+	userID := uuid.New()
+	if err := store.CreateUser(context.Background(), &models.User{ID: userID, Username: "importer"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	token, err := jwtManager.GenerateToken(userID, "importer")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	record := func(seq int, dataType models.DataType, name string) string {
+		line, _ := json.Marshal(models.ImportRecord{Seq: seq, Data: models.DataRequest{Type: dataType, Name: name, Data: []byte("sealed")}})
+		return string(line) + "\n"
+	}
+	stream := func(body string) []models.ImportResult {
+		req := httptest.NewRequest("POST", ImportPath, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var results []models.ImportResult
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var result models.ImportResult
+			if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+				t.Fatalf("Invalid result line %q: %v", scanner.Text(), err)
+			}
+			results = append(results, result)
+		}
+		return results
+	}
+
+	// the whole stream is larger than the body limit, each record is not
+	var body strings.Builder
+	body.WriteString(record(1, models.DataTypeText, "first"))
+	body.WriteString(record(3, "unknown", "second"))
+	body.WriteString("\n")
+	body.WriteString(record(4, models.DataTypeBankCard, ""))
+	body.WriteString(record(4, models.DataTypeText, "repeated"))
+	body.WriteString(record(7, models.DataTypeLoginPassword, "last"))
+	if body.Len() <= 256 {
+		t.Fatalf("Expected a stream above the body limit, got %d bytes", body.Len())
+	}
+
+	results := stream(body.String())
+	want := []struct {
+		seq     int
+		created bool
+		err     string
+	}{
+		{seq: 1, created: true},
+		{seq: 3, err: "invalid type"},
+		{seq: 4, err: "name must be"},
+		{seq: 4, err: "is not after 4"},
+		{seq: 7, created: true},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %+v", len(want), results)
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.Seq != w.seq || (got.ID != nil) != w.created || !strings.Contains(got.Error, w.err) {
+			t.Errorf("Result %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+
+	items, err := store.GetDataByUserID(context.Background(), userID)
+	if err != nil || len(items) != 2 {
+		t.Errorf("Expected 2 imported items, got %d, %v", len(items), err)
+	}
+
+	t.Run("record too large", func(t *testing.T) {
+		oversized := record(1, models.DataTypeText, strings.Repeat("x", 300))
+		results := stream(record(1, models.DataTypeText, "ok") + oversized)
+		if len(results) != 2 || results[0].ID == nil || results[1].Seq != 0 || results[1].Error != "Import record too large" {
+			t.Errorf("Expected one result and an abort, got %+v", results)
+		}
+	})
+
+	t.Run("malformed record", func(t *testing.T) {
+		results := stream("{not json}\n" + record(1, models.DataTypeText, "never"))
+		if len(results) != 1 || results[0].Seq != 0 || results[0].Error == "" {
+			t.Errorf("Expected the stream to be aborted, got %+v", results)
+		}
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		req := httptest.NewRequest("POST", ImportPath, bytes.NewReader([]byte(record(1, models.DataTypeText, "x"))))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// MaintenanceExemptPaths are never blocked by maintenance mode: logging in
+// does not change stored data and operators must be able to turn it off
+var MaintenanceExemptPaths = []string{"/api/v1/login", "/api/v1/admin/"}
+
+// RegisterMaintenanceRoutes registers the admin routes that show and toggle maintenance mode
+func RegisterMaintenanceRoutes(r *mux.Router, maintenance *middleware.Maintenance, adminToken string) {
+	if adminToken == "" {
+		return
+	}
+	admin := r.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(requireAdminToken(adminToken))
+	admin.HandleFunc("/maintenance", handleGetMaintenance(maintenance)).Methods("GET")
+	admin.HandleFunc("/maintenance", handleSetMaintenance(maintenance)).Methods("PUT")
+}
+
+func handleGetMaintenance(maintenance *middleware.Maintenance) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(maintenance.Status()); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+func handleSetMaintenance(maintenance *middleware.Maintenance) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.MaintenanceStatus
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RetryAfterSeconds < 0 {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.Enabled {
+			maintenance.Enable(req.Message, time.Duration(req.RetryAfterSeconds)*time.Second)
+		} else {
+			maintenance.Disable()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(maintenance.Status()); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
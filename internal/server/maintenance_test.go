@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_MaintenanceMode(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	maintenance := middleware.NewMaintenance(false, "", MaintenanceExemptPaths...)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterMaintenanceRoutes(router, maintenance, "admin-secret")
+	handler := maintenance.Handler(router)
+
+	do := func(method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+		req := newTestRequest(method, path, "", body)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return serveRequest(handler, req)
+	}
+
+	rr := do("POST", "/api/v1/register", models.UserRequest{Username: "ops", Password: "password", MasterPassword: "master-password"}, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Register failed: %d %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := do("PUT", "/api/v1/admin/maintenance", models.MaintenanceStatus{Enabled: true}, nil); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without admin token, got %d", rr.Code)
+	}
+
+	admin := map[string]string{"X-Admin-Token": "admin-secret"}
+	rr = do("PUT", "/api/v1/admin/maintenance", models.MaintenanceStatus{Enabled: true, Message: "migrating", RetryAfterSeconds: 60}, admin)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Enable maintenance failed: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = do("POST", "/api/v1/login", models.LoginRequest{Username: "ops", Password: "password"}, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Login should be allowed during maintenance, got %d", rr.Code)
+	}
+	var authResp models.AuthResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &authResp); err != nil {
+		t.Fatalf("Failed to decode auth response: %v", err)
+	}
+	bearer := map[string]string{"Authorization": "Bearer " + authResp.Token}
+
+	if rr := do("GET", "/api/v1/data", nil, bearer); rr.Code != http.StatusOK {
+		t.Errorf("Reads should be allowed during maintenance, got %d", rr.Code)
+	}
+	rr = do("POST", "/api/v1/data", models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x")}, bearer)
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected 503 with Retry-After, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+
+	rr = do("GET", "/api/v1/admin/maintenance", nil, admin)
+	var status models.MaintenanceStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil || !status.Enabled || status.Message != "migrating" {
+		t.Errorf("Unexpected maintenance status: %+v (%v)", status, err)
+	}
+
+	if rr := do("PUT", "/api/v1/admin/maintenance", models.MaintenanceStatus{}, admin); rr.Code != http.StatusOK {
+		t.Fatalf("Disable maintenance failed: %d", rr.Code)
+	}
+	rr = do("POST", "/api/v1/data", models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("x")}, bearer)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("Writes should resume after maintenance, got %d %s", rr.Code, rr.Body.String())
+	}
+}
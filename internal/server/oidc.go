@@ -0,0 +1,455 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/idgen"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// OIDC routes; OIDCCallbackPath is where the identity provider returns the
+// browser, so the redirect URL registered with it must end with it
+const (
+	OIDCStartPath    = "/api/v1/oidc/start"
+	OIDCCallbackPath = "/api/v1/oidc/callback"
+	OIDCTokenPath    = "/api/v1/oidc/token"
+)
+
+// RouteOIDCStart names the route starting single sign-on logins, which is
+// rate limited with the other AuthRoutes
+const RouteOIDCStart = "auth.oidc.start"
+
+// Username claims single sign-on accounts can be named after
+const (
+	OIDCUsernamePreferred = "preferred_username"
+	OIDCUsernameEmail     = "email"
+	OIDCUsernameSubject   = "sub"
+)
+
+// oidcLoginTTL is how long a started login waits for the user and the client
+const oidcLoginTTL = 10 * time.Minute
+
+// maxOIDCLogins bounds the logins waiting at once
+const maxOIDCLogins = 10000
+
+// OIDCAuthenticator is the identity provider of single sign-on logins
+type OIDCAuthenticator interface {
+	Issuer() string
+	AuthCodeURL(state, nonce, verifier string) string
+	Authenticate(ctx context.Context, code, verifier, nonce string) (*auth.OIDCClaims, error)
+}
+
+// IdentityStorage links identity provider subjects to users
+type IdentityStorage interface {
+	CreateIdentity(ctx context.Context, identity *models.Identity) error
+	GetIdentity(ctx context.Context, issuer, subject string) (*models.Identity, error)
+}
+
+// OIDCOptions configures single sign-on logins
+type OIDCOptions struct {
+	// UsernameClaim names new accounts, one of the OIDCUsername constants;
+	// empty means OIDCUsernamePreferred
+	UsernameClaim string
+	// AutoCreate creates an account for identities that are not linked to
+	// one. Otherwise users must link their identity from a logged-in session.
+	AutoCreate bool
+	// CredentialPolicy is checked against the usernames of new accounts; nil
+	// means models.DefaultCredentialPolicy
+	CredentialPolicy *models.CredentialPolicy
+	// Clock stamps new accounts and identities and expires started logins;
+	// nil means the system clock
+	Clock clock.Clock
+	// RecordIDs generates the IDs of new accounts; nil means random UUIDs
+	RecordIDs idgen.Generator
+}
+
+// oidcLogin is a single sign-on login waiting for the identity provider to
+// return the browser, then for the client to collect its result
+type oidcLogin struct {
+	pollHash  [sha256.Size]byte
+	nonce     string
+	verifier  string
+	device    string
+	userAgent string
+	// linkUserID is set for logins linking the identity to a logged-in user
+	linkUserID uuid.UUID
+	expiresAt  time.Time
+
+	done     bool
+	response *models.AuthResponse
+	failure  string
+}
+
+// oidcLogins holds the started logins by their state
+type oidcLogins struct {
+	mutex  sync.Mutex
+	logins map[string]*oidcLogin
+	clock  clock.Clock
+}
+
+// add stores a login, dropping expired ones, and reports false when too many are waiting
+func (l *oidcLogins) add(state string, login *oidcLogin) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.clock.Now()
+	for key, existing := range l.logins {
+		if !now.Before(existing.expiresAt) {
+			delete(l.logins, key)
+		}
+	}
+	if len(l.logins) >= maxOIDCLogins {
+		return false
+	}
+	l.logins[state] = login
+	return true
+}
+
+// claim takes a waiting login for the callback, so a state is used once
+func (l *oidcLogins) claim(state string) (*oidcLogin, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	login, ok := l.logins[state]
+	if !ok || login.done || !l.clock.Now().Before(login.expiresAt) {
+		return nil, false
+	}
+	login.done = true
+	return login, true
+}
+
+// finish records the result of a claimed login
+func (l *oidcLogins) finish(login *oidcLogin, response *models.AuthResponse, failure string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	login.response = response
+	login.failure = failure
+}
+
+// poll returns the login of state if pollToken matches. Once it has a result
+// it is removed, so the token is handed out once.
+func (l *oidcLogins) poll(state, pollToken string) (*oidcLogin, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	login, ok := l.logins[state]
+	hash := sha256.Sum256([]byte(pollToken))
+	if !ok || subtle.ConstantTimeCompare(hash[:], login.pollHash[:]) != 1 || !l.clock.Now().Before(login.expiresAt) {
+		return nil, false
+	}
+	result := *login
+	if login.response != nil || login.failure != "" {
+		delete(l.logins, state)
+	}
+	return &result, true
+}
+
+// RegisterOIDCRoutes registers single sign-on login with an OpenID Connect
+// identity provider. Started logins are kept in memory, so the callback must
+// reach the instance that started them.
+func RegisterOIDCRoutes(r *mux.Router, provider OIDCAuthenticator, identityStorage IdentityStorage, userStorage UserStorage,
+	jwtManager *auth.JWTManager, opts OIDCOptions) {
+	if opts.UsernameClaim == "" {
+		opts.UsernameClaim = OIDCUsernamePreferred
+	}
+	if opts.CredentialPolicy == nil {
+		policy := models.DefaultCredentialPolicy()
+		opts.CredentialPolicy = &policy
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.System{}
+	}
+	if opts.RecordIDs == nil {
+		opts.RecordIDs = idgen.UUID{}
+	}
+	logins := &oidcLogins{logins: make(map[string]*oidcLogin), clock: opts.Clock}
+
+	r.HandleFunc(OIDCStartPath, handleOIDCStart(provider, logins, false)).Methods("POST").Name(RouteOIDCStart)
+	r.HandleFunc(OIDCCallbackPath, handleOIDCCallback(provider, logins, identityStorage, userStorage, jwtManager, opts)).Methods("GET")
+	r.HandleFunc(OIDCTokenPath, handleOIDCToken(logins)).Methods("POST")
+
+	protected := r.PathPrefix("/api/v1/oidc/link").Subrouter()
+	protected.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	protected.HandleFunc("", handleOIDCStart(provider, logins, true)).Methods("POST")
+}
+
+// handleOIDCStart starts a login, or with link the linking of an identity to
+// the caller's account
+func handleOIDCStart(provider OIDCAuthenticator, logins *oidcLogins, link bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		login := &oidcLogin{
+			device:    truncateLabel(r.Header.Get(DeviceHeader)),
+			userAgent: truncateLabel(r.UserAgent()),
+			expiresAt: logins.clock.Now().Add(oidcLoginTTL),
+		}
+		if link {
+			userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+			if err != nil {
+				apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+			login.linkUserID = userID
+		}
+
+		secrets := make([]string, 4)
+		for i := range secrets {
+			secret, err := auth.NewOIDCSecret()
+			if err != nil {
+				logger.FromContext(r.Context()).Error("Failed to start OIDC login", zap.Error(err))
+				apierror.Error(w, "Failed to start login", http.StatusInternalServerError)
+				return
+			}
+			secrets[i] = secret
+		}
+		state, pollToken := secrets[0], secrets[1]
+		login.nonce, login.verifier = secrets[2], secrets[3]
+		login.pollHash = sha256.Sum256([]byte(pollToken))
+
+		if !logins.add(state, login) {
+			logger.FromContext(r.Context()).Warn("Too many OIDC logins waiting")
+			apierror.Error(w, "Too many logins in progress, try again later", http.StatusServiceUnavailable)
+			return
+		}
+
+		response := models.OIDCStartResponse{
+			AuthURL:   provider.AuthCodeURL(state, login.nonce, login.verifier),
+			State:     state,
+			PollToken: pollToken,
+			ExpiresAt: login.expiresAt,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleOIDCCallback completes a login when the identity provider returns the
+// browser, and shows the user a page telling them to go back to the client
+func handleOIDCCallback(provider OIDCAuthenticator, logins *oidcLogins, identityStorage IdentityStorage, userStorage UserStorage,
+	jwtManager *auth.JWTManager, opts OIDCOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "no-store")
+
+		query := r.URL.Query()
+		login, ok := logins.claim(query.Get("state"))
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, "This login is unknown or has expired. Start it again from GophKeeper.")
+			return
+		}
+
+		response, failure := completeOIDCLogin(r, provider, login, identityStorage, userStorage, jwtManager, opts)
+		logins.finish(login, response, failure)
+		if failure != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintf(w, "Login failed: %s\n", failure)
+			return
+		}
+		if login.linkUserID != uuid.Nil {
+			fmt.Fprintln(w, "Your account is now linked. You can close this window and return to GophKeeper.")
+			return
+		}
+		fmt.Fprintln(w, "Login complete. You can close this window and return to GophKeeper.")
+	}
+}
+
+// completeOIDCLogin verifies the response of the identity provider and maps
+// its subject to a user. It returns the login result, or why it failed in
+// words that may be shown to the user.
+func completeOIDCLogin(r *http.Request, provider OIDCAuthenticator, login *oidcLogin, identityStorage IdentityStorage,
+	userStorage UserStorage, jwtManager *auth.JWTManager, opts OIDCOptions) (*models.AuthResponse, string) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		logger.FromContext(ctx).Warn("OIDC login refused by the identity provider", zap.String("error", providerErr))
+		return nil, "the identity provider refused the login (" + providerErr + ")"
+	}
+
+	claims, err := provider.Authenticate(ctx, query.Get("code"), login.verifier, login.nonce)
+	if err != nil {
+		logger.FromContext(ctx).Warn("OIDC login failed", zap.Error(err))
+		return nil, "the identity provider response could not be verified"
+	}
+	issuer := provider.Issuer()
+
+	if login.linkUserID != uuid.Nil {
+		return linkOIDCIdentity(ctx, identityStorage, userStorage, issuer, claims.Subject, login.linkUserID, opts)
+	}
+
+	var user *models.User
+	created := false
+	identity, err := identityStorage.GetIdentity(ctx, issuer, claims.Subject)
+	switch {
+	case err == nil:
+		user, err = userStorage.GetUserByID(ctx, identity.UserID)
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to get user of identity", zap.Error(err), zap.String("user_id", identity.UserID.String()))
+			return nil, "internal server error"
+		}
+	case err.Error() == "identity not found":
+		if !opts.AutoCreate {
+			return nil, "no account is linked to this identity; log in with your password and link it first"
+		}
+		var failure string
+		user, failure = createOIDCUser(ctx, identityStorage, userStorage, issuer, claims, opts)
+		if failure != "" {
+			return nil, failure
+		}
+		created = true
+	default:
+		logger.FromContext(ctx).Error("Failed to get identity", zap.Error(err))
+		return nil, "internal server error"
+	}
+
+	logger.FromContext(ctx).Info("User logged in with single sign-on", zap.String("username", user.Username),
+		zap.String("user_id", user.ID.String()), zap.Bool("created", created))
+	recordLogin(ctx, userStorage, user, true)
+
+	token, err := jwtManager.IssueToken(ctx, user.ID, user.Username, login.device, login.userAgent)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to issue token", zap.Error(err), zap.String("user_id", user.ID.String()))
+		return nil, "internal server error"
+	}
+	return &models.AuthResponse{Token: token, User: *user, Salt: user.Salt, KDFIterations: user.KDFIterations, Created: created}, ""
+}
+
+// createOIDCUser creates the account of an identity, named after its
+// username claim. The account has no password, so it can only log in with
+// single sign-on; its salt is registered by the client.
+func createOIDCUser(ctx context.Context, identityStorage IdentityStorage, userStorage UserStorage, issuer string,
+	claims *auth.OIDCClaims, opts OIDCOptions) (*models.User, string) {
+	username := claims.PreferredUsername
+	switch opts.UsernameClaim {
+	case OIDCUsernameEmail:
+		if !claims.EmailVerified {
+			return nil, "the identity provider did not verify your email address"
+		}
+		username = claims.Email
+	case OIDCUsernameSubject:
+		username = claims.Subject
+	}
+	if username == "" {
+		return nil, fmt.Sprintf("the identity provider did not send the %s claim", opts.UsernameClaim)
+	}
+	if err := opts.CredentialPolicy.CheckUsername(username); err != nil {
+		return nil, fmt.Sprintf("your username %q is not allowed here: %v", username, err)
+	}
+
+	user := &models.User{
+		ID:        opts.RecordIDs.NewID(),
+		Username:  username,
+		CreatedAt: opts.Clock.Now(),
+		UpdatedAt: opts.Clock.Now(),
+	}
+	identity := &models.Identity{Issuer: issuer, Subject: claims.Subject, UserID: user.ID, CreatedAt: opts.Clock.Now()}
+	err := inTx(ctx, identityStorage, func(ctx context.Context) error {
+		if err := userStorage.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		return identityStorage.CreateIdentity(ctx, identity)
+	})
+	if err != nil {
+		if err.Error() == "user already exists" {
+			logger.FromContext(ctx).Warn("Single sign-on username taken", zap.String("username", username))
+			return nil, fmt.Sprintf("an account named %q already exists; log in with its password and link your identity to it", username)
+		}
+		logger.FromContext(ctx).Error("Failed to create single sign-on user", zap.Error(err), zap.String("username", username))
+		return nil, "internal server error"
+	}
+	logger.FromContext(ctx).Info("User registered with single sign-on", zap.String("username", username),
+		zap.String("user_id", user.ID.String()))
+	return user, ""
+}
+
+// linkOIDCIdentity links an identity to a logged-in user. Linking does not
+// log in, so no token is returned.
+func linkOIDCIdentity(ctx context.Context, identityStorage IdentityStorage, userStorage UserStorage, issuer, subject string,
+	userID uuid.UUID, opts OIDCOptions) (*models.AuthResponse, string) {
+	user, err := userStorage.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get user to link", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, "internal server error"
+	}
+
+	identity := &models.Identity{Issuer: issuer, Subject: subject, UserID: userID, CreatedAt: opts.Clock.Now()}
+	if err := identityStorage.CreateIdentity(ctx, identity); err != nil {
+		if err.Error() == "identity already linked" {
+			if existing, getErr := identityStorage.GetIdentity(ctx, issuer, subject); getErr == nil && existing.UserID == userID {
+				return &models.AuthResponse{User: *user}, ""
+			}
+			return nil, "this identity is already linked to another account"
+		}
+		logger.FromContext(ctx).Error("Failed to link identity", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, "internal server error"
+	}
+	logger.FromContext(ctx).Info("Identity linked", zap.String("user_id", userID.String()))
+	return &models.AuthResponse{User: *user}, ""
+}
+
+// handleOIDCToken hands a finished login to the client that started it: 202
+// while the user has not come back from the identity provider, then the login
+// result once
+func handleOIDCToken(logins *oidcLogins) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.OIDCTokenRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		login, ok := logins.poll(req.State, req.PollToken)
+		if !ok {
+			apierror.Error(w, "Login not found or expired", http.StatusNotFound)
+			return
+		}
+		if login.failure != "" {
+			apierror.WithCode(w, http.StatusUnauthorized, models.ErrorCodeInvalidCredentials, "Login failed: "+login.failure)
+			return
+		}
+		if login.response == nil {
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(login.response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// DisablePasswordLogin rejects password logins and registrations, so users
+// only sign in with the identity provider. It must be called before
+// RegisterRoutes so that it takes precedence over their routes.
+func DisablePasswordLogin(r *mux.Router) {
+	for _, path := range []string{"/api/v1/register", "/api/v1/login"} {
+		r.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			apierror.WithCode(w, http.StatusForbidden, models.ErrorCodePasswordLoginDisabled,
+				"This server only allows single sign-on")
+		}).Methods("POST")
+	}
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeOIDCProvider accepts the codes it knows, returning their claims
+type fakeOIDCProvider struct {
+	claims map[string]*auth.OIDCClaims
+	nonces map[string]string
+}
+
+func (p *fakeOIDCProvider) Issuer() string { return "https://idp.example.com" }
+
+func (p *fakeOIDCProvider) AuthCodeURL(state, nonce, verifier string) string {
+	p.nonces[state] = nonce
+	return "https://idp.example.com/authorize?state=" + url.QueryEscape(state)
+}
+
+func (p *fakeOIDCProvider) Authenticate(ctx context.Context, code, verifier, nonce string) (*auth.OIDCClaims, error) {
+	claims, ok := p.claims[code]
+	if !ok || verifier == "" || nonce == "" {
+		return nil, errors.New("invalid code")
+	}
+	return claims, nil
+}
+
+func TestServer_OIDC(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	provider := &fakeOIDCProvider{nonces: make(map[string]string), claims: map[string]*auth.OIDCClaims{
+		"alice-code":   {RegisteredClaims: jwt.RegisteredClaims{Subject: "sub-alice"}, PreferredUsername: "alice"},
+		"taken-code":   {RegisteredClaims: jwt.RegisteredClaims{Subject: "sub-bob"}, PreferredUsername: "owner"},
+		"owner-code":   {RegisteredClaims: jwt.RegisteredClaims{Subject: "sub-owner"}, PreferredUsername: "someone"},
+		"invalid-code": {RegisteredClaims: jwt.RegisteredClaims{Subject: "sub-invalid"}, PreferredUsername: "a"},
+	}}
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterOIDCRoutes(router, provider, store, store, jwtManager, OIDCOptions{AutoCreate: true})
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	owner := &models.User{ID: uuid.New(), Username: "owner", Password: string(hashed), Salt: "c2FsdA=="}
+	if err := store.CreateUser(context.Background(), owner); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	start := func(path, token string) models.OIDCStartResponse {
+		w := doRequest(router, "POST", path, token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 on start, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp models.OIDCStartResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.State == "" || resp.PollToken == "" || provider.nonces[resp.State] == "" {
+			t.Fatalf("Expected a state, poll token and nonce, got %+v", resp)
+		}
+		return resp
+	}
+	callback := func(state, code string) int {
+		return doRequest(router, "GET", OIDCCallbackPath+"?state="+url.QueryEscape(state)+"&code="+url.QueryEscape(code), "", nil).Code
+	}
+	poll := func(s models.OIDCStartResponse) *httptest.ResponseRecorder {
+		return doRequest(router, "POST", OIDCTokenPath, "", models.OIDCTokenRequest{State: s.State, PollToken: s.PollToken})
+	}
+	login := func(code string) models.AuthResponse {
+		s := start(OIDCStartPath, "")
+		if w := poll(s); w.Code != http.StatusAccepted {
+			t.Fatalf("Expected status 202 before the callback, got %d", w.Code)
+		}
+		if status := callback(s.State, code); status != http.StatusOK {
+			t.Fatalf("Expected status 200 on callback, got %d", status)
+		}
+		w := poll(s)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 after the callback, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp models.AuthResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if w := poll(s); w.Code != http.StatusNotFound {
+			t.Errorf("Expected the result to be handed out once, got %d", w.Code)
+		}
+		return resp
+	}
+
+	first := login("alice-code")
+	if !first.Created || first.User.Username != "alice" || first.Token == "" || first.User.Password != "" {
+		t.Fatalf("Expected the account alice to be created, got %+v", first)
+	}
+	again := login("alice-code")
+	if again.Created || again.User.ID != first.User.ID {
+		t.Errorf("Expected the linked account to log in again, got %+v", again)
+	}
+	if w := doRequest(router, "GET", "/api/v1/data", again.Token, nil); w.Code != http.StatusOK {
+		t.Errorf("Expected the single sign-on token to authorize requests, got %d", w.Code)
+	}
+
+	t.Run("reused state", func(t *testing.T) {
+		s := start(OIDCStartPath, "")
+		callback(s.State, "alice-code")
+		if status := callback(s.State, "alice-code"); status != http.StatusBadRequest {
+			t.Errorf("Expected a used state to be refused, got %d", status)
+		}
+	})
+
+	t.Run("wrong poll token", func(t *testing.T) {
+		s := start(OIDCStartPath, "")
+		s.PollToken = "guess"
+		if w := poll(s); w.Code != http.StatusNotFound {
+			t.Errorf("Expected a wrong poll token to be refused, got %d", w.Code)
+		}
+	})
+
+	failures := map[string]string{
+		"unknown code":     "bogus",
+		"username taken":   "taken-code",
+		"invalid username": "invalid-code",
+	}
+	for name, code := range failures {
+		t.Run(name, func(t *testing.T) {
+			s := start(OIDCStartPath, "")
+			if status := callback(s.State, code); status != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 on callback, got %d", status)
+			}
+			w := poll(s)
+			var apiErr models.ErrorResponse
+			_ = json.NewDecoder(w.Body).Decode(&apiErr)
+			if w.Code != http.StatusUnauthorized || apiErr.Code != models.ErrorCodeInvalidCredentials {
+				t.Errorf("Expected a failed login, got %d %+v", w.Code, apiErr)
+			}
+		})
+	}
+	if _, err := store.GetIdentity(context.Background(), provider.Issuer(), "sub-bob"); err == nil {
+		t.Error("Expected no identity for a taken username")
+	}
+
+	t.Run("link", func(t *testing.T) {
+		if w := doRequest(router, "POST", "/api/v1/oidc/link", "", nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected linking to need a login, got %d", w.Code)
+		}
+		w := doRequest(router, "POST", "/api/v1/login", "", models.LoginRequest{Username: "owner", Password: "secret"})
+		var resp models.AuthResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		s := start("/api/v1/oidc/link", resp.Token)
+		callback(s.State, "owner-code")
+		w = poll(s)
+		var linked models.AuthResponse
+		_ = json.NewDecoder(w.Body).Decode(&linked)
+		if w.Code != http.StatusOK || linked.Token != "" || linked.User.ID != owner.ID {
+			t.Fatalf("Expected the identity to be linked without a token, got %d %+v", w.Code, linked)
+		}
+
+		loggedIn := login("owner-code")
+		if loggedIn.Created || loggedIn.User.ID != owner.ID || loggedIn.Salt != owner.Salt {
+			t.Errorf("Expected the linked identity to log in as owner, got %+v", loggedIn)
+		}
+
+		s = start("/api/v1/oidc/link", resp.Token)
+		if status := callback(s.State, "alice-code"); status != http.StatusUnauthorized {
+			t.Errorf("Expected an identity linked to another account to be refused, got %d", status)
+		}
+	})
+}
+
+func TestServer_OIDCWithoutAutoCreate(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	provider := &fakeOIDCProvider{nonces: make(map[string]string), claims: map[string]*auth.OIDCClaims{
+		"code": {RegisteredClaims: jwt.RegisteredClaims{Subject: "sub"}, PreferredUsername: "newcomer"},
+	}}
+	router := mux.NewRouter()
+	DisablePasswordLogin(router)
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterOIDCRoutes(router, provider, store, store, jwtManager, OIDCOptions{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", OIDCStartPath, nil))
+	var s models.OIDCStartResponse
+	if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", OIDCCallbackPath+"?state="+url.QueryEscape(s.State)+"&code=code", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected unlinked identities to be refused, got %d", w.Code)
+	}
+	if _, err := store.GetUserByUsername(context.Background(), "newcomer"); err == nil {
+		t.Error("Expected no account to be created")
+	}
+
+	for _, path := range []string{"/api/v1/register", "/api/v1/login"} {
+		body, _ := json.Marshal(models.UserRequest{Username: "someone", Password: "password123"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", path, bytes.NewReader(body)))
+		var apiErr models.ErrorResponse
+		_ = json.NewDecoder(w.Body).Decode(&apiErr)
+		if w.Code != http.StatusForbidden || apiErr.Code != models.ErrorCodePasswordLoginDisabled {
+			t.Errorf("Expected %s to be disabled, got %d %+v", path, w.Code, apiErr)
+		}
+	}
+}
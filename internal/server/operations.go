@@ -0,0 +1,346 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/validate"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The operations below are served by both the REST routes and the gRPC API,
+// so either transport checks requests and fails them the same way. A refused
+// request gets an *apierror.Problem; any other error is a failure to reply
+// with 500.
+
+// checkRequest checks the validate tags of the fields of req
+func checkRequest(req interface{}) error {
+	if errs := validate.Struct(req); len(errs) > 0 {
+		return apierror.NewInvalid(errs)
+	}
+	return nil
+}
+
+// Register creates an account and starts a session for it on the device of
+// the request
+func Register(ctx context.Context, userStorage UserStorage, jwtManager *auth.JWTManager, opts Options,
+	req models.UserRequest, device, userAgent string) (*models.AuthResponse, error) {
+	opts = opts.withDefaults()
+	if err := checkRequest(&req); err != nil {
+		logger.FromContext(ctx).Warn("Invalid registration request", zap.String("username", req.Username))
+		return nil, err
+	}
+	if fields := opts.CredentialPolicy.Check(req.Username, req.Password); len(fields) > 0 {
+		logger.FromContext(ctx).Warn("Registration rejected by credential policy", zap.String("username", req.Username))
+		return nil, apierror.NewInvalid(fields)
+	}
+	if err := crypto.CheckIterations(req.KDFIterations); err != nil {
+		return nil, apierror.NewInvalid([]models.FieldError{{Field: "kdf_iterations", Rule: "range", Message: err.Error()}})
+	}
+
+	logger.FromContext(ctx).Info("User registration attempt", zap.String("username", req.Username))
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to hash password", zap.Error(err))
+		return nil, apierror.New("Failed to hash password", http.StatusInternalServerError)
+	}
+
+	// the client derives the vault key; the server only issues its salt
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to generate salt", zap.Error(err))
+		return nil, apierror.New("Failed to initialize encryption", http.StatusInternalServerError)
+	}
+
+	hashedMasterPassword, err := bcrypt.GenerateFromPassword([]byte(req.MasterPassword), bcrypt.DefaultCost)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to hash master password", zap.Error(err))
+		return nil, apierror.New("Failed to hash master password", http.StatusInternalServerError)
+	}
+
+	user := &models.User{
+		ID:             opts.RecordIDs.NewID(),
+		Username:       req.Username,
+		Password:       string(hashedPassword),
+		MasterPassword: string(hashedMasterPassword),
+		Salt:           base64.StdEncoding.EncodeToString(salt),
+		KDFIterations:  req.KDFIterations,
+		CreatedAt:      opts.Clock.Now(),
+		UpdatedAt:      opts.Clock.Now(),
+	}
+
+	if err := userStorage.CreateUser(ctx, user); err != nil {
+		if err.Error() == "user already exists" {
+			logger.FromContext(ctx).Warn("User already exists", zap.String("username", req.Username))
+			return nil, apierror.NewWithCode(http.StatusConflict, models.ErrorCodeUserExists, "User already exists")
+		}
+		logger.FromContext(ctx).Error("Failed to create user", zap.Error(err), zap.String("username", req.Username))
+		return nil, apierror.New("Failed to create user", http.StatusInternalServerError)
+	}
+
+	logger.FromContext(ctx).Info("User registered successfully", zap.String("username", req.Username), zap.String("user_id", user.ID.String()))
+	return issueToken(ctx, jwtManager, user, device, userAgent)
+}
+
+// Login checks the credentials of an account and starts a session for it on
+// the device of the request
+func Login(ctx context.Context, userStorage UserStorage, jwtManager *auth.JWTManager,
+	req models.LoginRequest, device, userAgent string) (*models.AuthResponse, error) {
+	if err := checkRequest(&req); err != nil {
+		logger.FromContext(ctx).Warn("Invalid login request", zap.String("username", req.Username))
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("User login attempt", zap.String("username", req.Username))
+
+	user, err := userStorage.GetUserByUsername(ctx, req.Username)
+	if err != nil {
+		if err.Error() == "user not found" {
+			logger.FromContext(ctx).Warn("Login failed - user not found", zap.String("username", req.Username))
+			return nil, apierror.NewWithCode(http.StatusUnauthorized, models.ErrorCodeInvalidCredentials, "Invalid credentials")
+		}
+		logger.FromContext(ctx).Error("Failed to get user", zap.Error(err), zap.String("username", req.Username))
+		return nil, apierror.New("Internal server error", http.StatusInternalServerError)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		logger.FromContext(ctx).Warn("Login failed - invalid password", zap.String("username", req.Username))
+		recordLogin(ctx, userStorage, user, false)
+		return nil, apierror.NewWithCode(http.StatusUnauthorized, models.ErrorCodeInvalidCredentials, "Invalid credentials")
+	}
+
+	logger.FromContext(ctx).Info("User logged in successfully", zap.String("username", req.Username), zap.String("user_id", user.ID.String()))
+	recordLogin(ctx, userStorage, user, true)
+	return issueToken(ctx, jwtManager, user, device, userAgent)
+}
+
+// issueToken returns the response of a registration or login of user
+func issueToken(ctx context.Context, jwtManager *auth.JWTManager, user *models.User, device, userAgent string) (*models.AuthResponse, error) {
+	token, err := jwtManager.IssueToken(ctx, user.ID, user.Username, device, userAgent)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to issue token", zap.Error(err), zap.String("user_id", user.ID.String()))
+		return nil, apierror.New("Failed to generate token", http.StatusInternalServerError)
+	}
+	return &models.AuthResponse{
+		Token:         token,
+		User:          *user,
+		Salt:          user.Salt,
+		KDFIterations: user.KDFIterations,
+	}, nil
+}
+
+// ListData lists the items of the user matching filter, searching them when
+// it has a query
+func ListData(ctx context.Context, dataStorage DataStorage, userID uuid.UUID, filter models.DataFilter) ([]models.Data, error) {
+	if invalid := checkDataFilter(filter); invalid != "" {
+		return nil, apierror.New("Invalid "+invalid, http.StatusBadRequest)
+	}
+
+	data, err := dataStorage.GetDataByUserIDFiltered(ctx, userID, filter)
+	if err != nil {
+		if filter.Query != "" {
+			return nil, apierror.New("Failed to search data", http.StatusInternalServerError)
+		}
+		return nil, apierror.New("Failed to get data", http.StatusInternalServerError)
+	}
+
+	list := make([]models.Data, 0, len(data))
+	for _, d := range data {
+		list = append(list, *d)
+	}
+	return list, nil
+}
+
+// checkDataFilter returns the name of the first invalid field of filter, if any
+func checkDataFilter(filter models.DataFilter) string {
+	switch {
+	case filter.Type != "" && !models.IsValidDataType(filter.Type):
+		return "type"
+	case filter.Limit < 0 || filter.Limit > maxDataLimit:
+		return "limit"
+	case filter.Offset < 0:
+		return "offset"
+	}
+	return ""
+}
+
+// CreateData stores a new item of the user
+func CreateData(ctx context.Context, dataStorage DataStorage, opts Options, userID uuid.UUID, req models.DataRequest) (*models.Data, error) {
+	opts = opts.withDefaults()
+	if err := checkRequest(&req); err != nil {
+		return nil, err
+	}
+	tags, err := requestTags(req)
+	if err != nil {
+		return nil, apierror.New("Invalid tags: "+err.Error(), http.StatusBadRequest)
+	}
+	domains, err := requestDomains(req.Domains)
+	if err != nil {
+		return nil, apierror.New("Invalid domains: "+err.Error(), http.StatusBadRequest)
+	}
+
+	data := &models.Data{
+		ID:          opts.DataIDs.NewID(),
+		UserID:      userID,
+		Type:        req.Type,
+		Name:        req.Name,
+		Description: req.Description,
+		Data:        req.Data,
+		Metadata:    req.Metadata,
+		Environment: req.Environment,
+		Tags:        tags,
+		Domains:     domains,
+		Icon:        requestIcon(req.Icon),
+		Checksum:    req.Checksum,
+		CreatedAt:   opts.Clock.Now(),
+		UpdatedAt:   opts.Clock.Now(),
+	}
+	setExpiry(data, req.ExpiresAt, opts)
+
+	if err := dataStorage.CreateData(ctx, data); err != nil {
+		return nil, apierror.New("Failed to create data", http.StatusInternalServerError)
+	}
+	return data, nil
+}
+
+// OwnedData returns the item with dataID once it checked that the user owns it
+func OwnedData(ctx context.Context, dataStorage DataStorage, userID, dataID uuid.UUID) (*models.Data, error) {
+	data, err := dataStorage.GetDataByID(ctx, dataID)
+	if err != nil {
+		if err.Error() == "data not found" {
+			return nil, apierror.New("Data not found", http.StatusNotFound)
+		}
+		return nil, apierror.New("Failed to get data", http.StatusInternalServerError)
+	}
+
+	if data.UserID != userID {
+		return nil, apierror.New("Access denied", http.StatusForbidden)
+	}
+	return data, nil
+}
+
+// GetData returns an item of the user, recording that it was read
+func GetData(ctx context.Context, dataStorage DataStorage, userID, dataID uuid.UUID) (*models.Data, error) {
+	data, err := OwnedData(ctx, dataStorage, userID, dataID)
+	if err != nil {
+		return nil, err
+	}
+	recordRead(ctx, dataStorage, data)
+	return data, nil
+}
+
+// UpdateData replaces an item of the user. The update must be based on its
+// current version, named by ifMatch, the value of an If-Match header, or by
+// the request; a refused update gets the ETag of the current version.
+func UpdateData(ctx context.Context, dataStorage DataStorage, opts Options, userID, dataID uuid.UUID,
+	ifMatch string, req models.DataRequest) (*models.Data, error) {
+	opts = opts.withDefaults()
+	if err := checkRequest(&req); err != nil {
+		return nil, err
+	}
+	tags, err := requestTags(req)
+	if err != nil {
+		return nil, apierror.New("Invalid tags: "+err.Error(), http.StatusBadRequest)
+	}
+	domains, err := requestDomains(req.Domains)
+	if err != nil {
+		return nil, apierror.New("Invalid domains: "+err.Error(), http.StatusBadRequest)
+	}
+
+	data, err := OwnedData(ctx, dataStorage, userID, dataID)
+	if err != nil {
+		return nil, err
+	}
+
+	if status, reason := checkUpdatePrecondition(ifMatch, req, data); status != 0 {
+		return nil, apierror.New(reason, status).WithHeader("ETag", dataETag(data))
+	}
+
+	data.Type = req.Type
+	data.Name = req.Name
+	data.Description = req.Description
+	data.Data = req.Data
+	data.Metadata = req.Metadata
+	data.Environment = req.Environment
+	if req.Tags != nil {
+		data.Tags = tags
+	}
+	if req.Domains != nil {
+		data.Domains = domains
+	}
+	if req.Icon != nil {
+		data.Icon = requestIcon(req.Icon)
+	}
+	setExpiry(data, req.ExpiresAt, opts)
+	data.Checksum = req.Checksum
+	data.UpdatedAt = opts.Clock.Now()
+
+	if err := dataStorage.UpdateData(ctx, data); err != nil {
+		return nil, apierror.New("Failed to update data", http.StatusInternalServerError)
+	}
+
+	recordRead(ctx, dataStorage, data)
+	return data, nil
+}
+
+// DeleteData deletes an item of the user
+func DeleteData(ctx context.Context, dataStorage DataStorage, userID, dataID uuid.UUID) error {
+	if _, err := OwnedData(ctx, dataStorage, userID, dataID); err != nil {
+		return err
+	}
+	if err := dataStorage.DeleteData(ctx, dataID); err != nil {
+		return apierror.New("Failed to delete data", http.StatusInternalServerError)
+	}
+	return nil
+}
+
+// PutDataChunk stores one encrypted content chunk of data, an owned binary
+// item, at index. Chunks go in order; storing one again replaces it, so an
+// interrupted upload can be retried from the failed chunk.
+func PutDataChunk(ctx context.Context, chunkStorage ChunkStorage, data *models.Data, index int, chunk []byte) error {
+	if index < 0 {
+		return apierror.New("Invalid chunk index", http.StatusBadRequest)
+	}
+	if data.Type != models.DataTypeBinary {
+		return apierror.New("Only binary data is stored in chunks", http.StatusBadRequest)
+	}
+	if len(chunk) == 0 {
+		return apierror.New("Empty chunk", http.StatusBadRequest)
+	}
+	if len(chunk) > maxChunkCiphertext {
+		return apierror.New("Chunk too large", http.StatusRequestEntityTooLarge)
+	}
+
+	if err := chunkStorage.PutDataChunk(ctx, data.ID, index, chunk); err != nil {
+		switch err.Error() {
+		case "data not found":
+			return apierror.New("Data not found", http.StatusNotFound)
+		case "chunk out of order":
+			return apierror.New("Chunk out of order", http.StatusConflict)
+		default:
+			return apierror.New("Failed to store chunk", http.StatusInternalServerError)
+		}
+	}
+
+	logger.FromContext(ctx).Debug("Chunk stored", zap.String("data_id", data.ID.String()), zap.Int("index", index),
+		zap.Int("size", len(chunk)))
+	return nil
+}
+
+// CountDataChunks returns the number of content chunks of data, an owned item
+func CountDataChunks(ctx context.Context, chunkStorage ChunkStorage, data *models.Data) (int, error) {
+	count, err := chunkStorage.CountDataChunks(ctx, data.ID)
+	if err != nil {
+		return 0, apierror.New("Failed to get chunks", http.StatusInternalServerError)
+	}
+	return count, nil
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Health states of a dependency and of the server as a whole
+const (
+	HealthOK       = "ok"
+	HealthSlow     = "slow"
+	HealthDown     = "down"
+	HealthDegraded = "degraded"
+)
+
+// defaultReadinessTimeout bounds how long one dependency check may take
+const defaultReadinessTimeout = 2 * time.Second
+
+// DependencyCheck checks one subsystem the server depends on, e.g. the database
+type DependencyCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// ReadinessOptions configures the readiness endpoint
+type ReadinessOptions struct {
+	Checks []DependencyCheck
+	// Timeout bounds each check; zero means defaultReadinessTimeout
+	Timeout time.Duration
+	// SlowLatency marks checks taking longer as slow; zero disables it
+	SlowLatency time.Duration
+}
+
+// RegisterReadinessRoutes registers the unauthenticated readiness probe. It answers
+// 503 when a dependency is down so load balancers stop routing to the instance.
+func RegisterReadinessRoutes(r *mux.Router, opts ReadinessOptions) {
+	r.HandleFunc("/readyz", handleReadiness(opts)).Methods("GET")
+}
+
+// CheckDependencies runs all checks concurrently and reports each one with its latency.
+// The overall status is degraded when any dependency is slow or down.
+func CheckDependencies(ctx context.Context, opts ReadinessOptions) models.ReadinessResponse {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultReadinessTimeout
+	}
+
+	response := models.ReadinessResponse{
+		Status:       HealthOK,
+		Dependencies: make([]models.DependencyHealth, len(opts.Checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range opts.Checks {
+		wg.Add(1)
+		go func(i int, check DependencyCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+			latency := time.Since(start)
+
+			health := models.DependencyHealth{
+				Name:      check.Name,
+				Status:    HealthOK,
+				LatencyMS: float64(latency.Microseconds()) / 1000,
+			}
+			switch {
+			case err != nil:
+				health.Status = HealthDown
+				health.Error = err.Error()
+			case opts.SlowLatency > 0 && latency > opts.SlowLatency:
+				health.Status = HealthSlow
+			}
+			response.Dependencies[i] = health
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, health := range response.Dependencies {
+		if health.Status != HealthOK {
+			response.Status = HealthDegraded
+		}
+	}
+	return response
+}
+
+func handleReadiness(opts ReadinessOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := CheckDependencies(r.Context(), opts)
+
+		status := http.StatusOK
+		for _, health := range response.Dependencies {
+			if health.Status == HealthDown {
+				status = http.StatusServiceUnavailable
+				logger.FromContext(r.Context()).Warn("Dependency is down", zap.String("dependency", health.Name), zap.String("error", health.Error))
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
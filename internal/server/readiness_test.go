@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_Readiness(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	slow := func(ctx context.Context) error { time.Sleep(20 * time.Millisecond); return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+	hang := func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }
+
+	tests := []struct {
+		name           string
+		checks         []DependencyCheck
+		expectedCode   int
+		expectedStatus string
+		expectedHealth []string
+	}{
+		{
+			name:           "all ok",
+			checks:         []DependencyCheck{{Name: "database", Check: ok}},
+			expectedCode:   http.StatusOK,
+			expectedStatus: HealthOK,
+			expectedHealth: []string{HealthOK},
+		},
+		{
+			name:           "slow dependency",
+			checks:         []DependencyCheck{{Name: "database", Check: ok}, {Name: "cache", Check: slow}},
+			expectedCode:   http.StatusOK,
+			expectedStatus: HealthDegraded,
+			expectedHealth: []string{HealthOK, HealthSlow},
+		},
+		{
+			name:           "dependency down",
+			checks:         []DependencyCheck{{Name: "database", Check: down}, {Name: "cache", Check: ok}},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: HealthDegraded,
+			expectedHealth: []string{HealthDown, HealthOK},
+		},
+		{
+			name:           "dependency times out",
+			checks:         []DependencyCheck{{Name: "database", Check: hang}},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: HealthDegraded,
+			expectedHealth: []string{HealthDown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			RegisterReadinessRoutes(router, ReadinessOptions{
+				Checks:      tt.checks,
+				Timeout:     50 * time.Millisecond,
+				SlowLatency: 10 * time.Millisecond,
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectedCode, w.Code)
+			}
+
+			var resp models.ReadinessResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Status != tt.expectedStatus {
+				t.Errorf("Expected status %s, got %s", tt.expectedStatus, resp.Status)
+			}
+			if len(resp.Dependencies) != len(tt.checks) {
+				t.Fatalf("Expected %d dependencies, got %+v", len(tt.checks), resp.Dependencies)
+			}
+			for i, health := range resp.Dependencies {
+				if health.Name != tt.checks[i].Name || health.Status != tt.expectedHealth[i] {
+					t.Errorf("Dependency %d = %+v, want %s %s", i, health, tt.checks[i].Name, tt.expectedHealth[i])
+				}
+				if health.Status == HealthDown && health.Error == "" {
+					t.Errorf("Expected an error for %s", health.Name)
+				}
+			}
+		})
+	}
+}
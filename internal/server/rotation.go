@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/crypto"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/middleware"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type RotationStorage interface {
+	RotateVault(ctx context.Context, userID uuid.UUID, header models.RotationHeader,
+		next func() (*models.RotationRecord, error)) (*models.RotationResponse, error)
+}
+
+// RotatePath is the master password rotation endpoint. Its body is NDJSON of
+// any length, so request size limits apply per record there.
+const RotatePath = "/api/v1/vault/rotate"
+
+// errInvalidRotationRecord marks rotation stream lines that cannot be used
+var errInvalidRotationRecord = errors.New("invalid rotation record")
+
+// RegisterRotationRoutes registers the route replacing the salt and every
+// ciphertext of a vault at once after a master password change
+func RegisterRotationRoutes(r *mux.Router, rotationStorage RotationStorage, jwtManager *auth.JWTManager) {
+	rotate := r.Path(RotatePath).Subrouter()
+	rotate.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	rotate.HandleFunc("", handleRotateVault(rotationStorage)).Methods("POST")
+}
+
+// handleRotateVault reads a rotation header and then the re-encrypted
+// ciphertexts of the whole vault from an NDJSON stream. The storage applies
+// them all or, when the stream is incomplete or the vault changed, none.
+func handleRotateVault(rotationStorage RotationStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 0, 64<<10), maxImportRecord)
+		nextLine := func() ([]byte, error) {
+			for scanner.Scan() {
+				if scanner.Err() != nil {
+					break
+				}
+				if line := scanner.Bytes(); len(line) > 0 {
+					return line, nil
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+
+		line, err := nextLine()
+		if err != nil {
+			apierror.Error(w, "Rotation header required", http.StatusBadRequest)
+			return
+		}
+		var header models.RotationHeader
+		if err := json.Unmarshal(line, &header); err != nil {
+			apierror.Error(w, "Invalid rotation header", http.StatusBadRequest)
+			return
+		}
+		if salt, err := base64.StdEncoding.DecodeString(header.Salt); err != nil || len(salt) != 32 {
+			apierror.Error(w, "Salt must be 32 base64 encoded bytes", http.StatusBadRequest)
+			return
+		}
+		if err := crypto.CheckIterations(header.KDFIterations); err != nil {
+			apierror.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(header.Verifier) > maxVerifierSize {
+			apierror.Error(w, "Verifier too large", http.StatusBadRequest)
+			return
+		}
+
+		next := func() (*models.RotationRecord, error) {
+			line, err := nextLine()
+			if err != nil {
+				return nil, err
+			}
+			var record models.RotationRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				return nil, errInvalidRotationRecord
+			}
+			if record.DataID == uuid.Nil || len(record.Data) == 0 {
+				return nil, fmt.Errorf("%w: data_id and data are required", errInvalidRotationRecord)
+			}
+			return &record, nil
+		}
+
+		response, err := rotationStorage.RotateVault(r.Context(), userID, header, next)
+		if err != nil {
+			switch {
+			case errors.Is(err, errInvalidRotationRecord):
+				apierror.Error(w, "Invalid rotation record", http.StatusBadRequest)
+			case errors.Is(err, middleware.ErrRecordTooLarge) || errors.Is(err, bufio.ErrTooLong):
+				apierror.Error(w, "Rotation record too large", http.StatusRequestEntityTooLarge)
+			case err.Error() == "user not found":
+				apierror.Error(w, "User not found", http.StatusNotFound)
+			case err.Error() == "vault changed during rotation":
+				apierror.Error(w, "Vault changed during rotation, retry", http.StatusConflict)
+			case err.Error() == "rotation does not match the vault":
+				apierror.Error(w, "Rotation does not cover the vault exactly", http.StatusConflict)
+			default:
+				logger.FromContext(r.Context()).Error("Vault rotation failed", zap.Error(err), zap.String("user_id", userID.String()))
+				apierror.Error(w, "Failed to rotate vault", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Vault rotated", zap.String("user_id", userID.String()), zap.Int("items", response.Items),
+			zap.Int("comments", response.Comments), zap.Int("versions", response.Versions), zap.Int("chunks", response.Chunks),
+			zap.Int("attachments", response.Attachments))
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
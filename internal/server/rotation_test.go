@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_RotateVault(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterVaultLockRoutes(router, NewVaultLocks(Options{}), jwtManager)
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterRotationRoutes(router, store, jwtManager)
+
+	userID := uuid.New()
+	oldSalt := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	newSalt := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	if err := store.CreateUser(ctx, &models.User{ID: userID, Username: "rotator", Salt: oldSalt}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := store.SetMasterVerifier(ctx, userID, []byte("old-verifier")); err != nil {
+		t.Fatalf("SetMasterVerifier() error = %v", err)
+	}
+	token, err := jwtManager.GenerateToken(userID, "rotator")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	item := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "note", Data: []byte("old-item")}
+	if err := store.CreateData(ctx, item); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	comment := &models.DataComment{ID: uuid.New(), DataID: item.ID, Ciphertext: []byte("old-comment")}
+	if err := store.AddDataComment(ctx, comment); err != nil {
+		t.Fatalf("AddDataComment() error = %v", err)
+	}
+	// the update keeps the first payload as version 1
+	item.Data = []byte("old-item-2")
+	if err := store.UpdateData(ctx, item); err != nil {
+		t.Fatalf("UpdateData() error = %v", err)
+	}
+	current, _ := store.GetDataByID(ctx, item.ID)
+
+	records := func(revision int) []models.RotationRecord {
+		return []models.RotationRecord{
+			{Kind: models.RotationItem, DataID: item.ID, Revision: revision, Data: []byte("new-item")},
+			{Kind: models.RotationComment, DataID: item.ID, CommentID: comment.ID, Data: []byte("new-comment")},
+			{Kind: models.RotationVersion, DataID: item.ID, Version: 1, Data: []byte("new-version")},
+		}
+	}
+	rotate := func(salt string, records []models.RotationRecord) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		encoder := json.NewEncoder(&body)
+		_ = encoder.Encode(models.RotationHeader{Salt: salt, Verifier: []byte("new-verifier"), KDFIterations: 200000})
+		for _, record := range records {
+			_ = encoder.Encode(record)
+		}
+		req := httptest.NewRequest("POST", RotatePath, &body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	unchanged := func(t *testing.T) {
+		t.Helper()
+		user, _ := store.GetUserByID(ctx, userID)
+		data, _ := store.GetDataByID(ctx, item.ID)
+		comments, _ := store.GetDataComments(ctx, item.ID)
+		verifier, _ := store.GetMasterVerifier(ctx, userID)
+		if user.Salt != oldSalt || string(verifier) != "old-verifier" || string(data.Data) != "old-item-2" || data.Revision != current.Revision ||
+			string(comments[0].Ciphertext) != "old-comment" {
+			t.Errorf("Expected the vault to be left as it was, got salt %q, item %q rev %d, comment %q",
+				user.Salt, data.Data, data.Revision, comments[0].Ciphertext)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		salt     string
+		records  []models.RotationRecord
+		wantCode int
+	}{
+		{name: "invalid salt", salt: "short", records: records(current.Revision), wantCode: http.StatusBadRequest},
+		{name: "stale revision", salt: newSalt, records: records(current.Revision - 1), wantCode: http.StatusConflict},
+		{name: "missing version", salt: newSalt, records: records(current.Revision)[:2], wantCode: http.StatusConflict},
+		{name: "duplicate record", salt: newSalt, records: append(records(current.Revision), records(current.Revision)[1]),
+			wantCode: http.StatusConflict},
+		{name: "unknown item", salt: newSalt, records: append(records(current.Revision),
+			models.RotationRecord{Kind: models.RotationItem, DataID: uuid.New(), Data: []byte("x")}), wantCode: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := rotate(tt.salt, tt.records)
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			unchanged(t)
+		})
+	}
+
+	t.Run("malformed record", func(t *testing.T) {
+		body := `{"salt":"` + newSalt + `"}` + "\n{not json}\n"
+		req := httptest.NewRequest("POST", RotatePath, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400, got %d", w.Code)
+		}
+		unchanged(t)
+	})
+
+	t.Run("iterations out of range", func(t *testing.T) {
+		body := `{"salt":"` + newSalt + `","kdf_iterations":1000}` + "\n"
+		req := httptest.NewRequest("POST", RotatePath, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400, got %d", w.Code)
+		}
+		unchanged(t)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		req := httptest.NewRequest("POST", RotatePath, strings.NewReader(`{"salt":"`+newSalt+`"}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		w := rotate(newSalt, records(current.Revision))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response models.RotationResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response != (models.RotationResponse{Items: 1, Comments: 1, Versions: 1}) {
+			t.Errorf("Unexpected counts %+v", response)
+		}
+
+		user, _ := store.GetUserByID(ctx, userID)
+		data, _ := store.GetDataByID(ctx, item.ID)
+		comments, _ := store.GetDataComments(ctx, item.ID)
+		version, _ := store.GetDataVersion(ctx, item.ID, 1)
+		versions, _ := store.GetDataVersions(ctx, item.ID)
+		if user.Salt != newSalt || user.KDFIterations != 200000 {
+			t.Errorf("Expected the new salt and iteration count, got %q, %d", user.Salt, user.KDFIterations)
+		}
+		if verifier, _ := store.GetMasterVerifier(ctx, userID); string(verifier) != "new-verifier" {
+			t.Errorf("Expected the new verifier, got %q", verifier)
+		}
+		if string(data.Data) != "new-item" || data.Revision != current.Revision+1 {
+			t.Errorf("Expected the new item at the next revision, got %q rev %d", data.Data, data.Revision)
+		}
+		if string(comments[0].Ciphertext) != "new-comment" || string(version.Data) != "new-version" || len(versions) != 1 {
+			t.Errorf("Expected the comment and version replaced, got %q, %q, %d versions",
+				comments[0].Ciphertext, version.Data, len(versions))
+		}
+	})
+}
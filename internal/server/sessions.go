@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// DeviceHeader carries the name of the client's machine on login and registration
+const DeviceHeader = "X-Device-Name"
+
+// maxSessionLabel bounds the stored device name and user agent in characters
+const maxSessionLabel = 200
+
+// sessionTouchInterval is how stale the last-seen time of a session may get,
+// so that not every request writes to the storage
+const sessionTouchInterval = time.Minute
+
+type SessionStorage interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, sessionID uuid.UUID) (*models.Session, error)
+	// GetSessions gets the sessions of the user that have not expired at now
+	GetSessions(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error)
+	TouchSession(ctx context.Context, sessionID uuid.UUID, seenAt time.Time) error
+	DeleteSession(ctx context.Context, sessionID uuid.UUID) error
+	// DeleteExpiredSessions deletes the sessions of the user that expired by now
+	DeleteExpiredSessions(ctx context.Context, userID uuid.UUID, now time.Time) error
+}
+
+// Sessions implements auth.Sessions on a SessionStorage
+type Sessions struct {
+	storage SessionStorage
+	opts    Options
+}
+
+// NewSessions creates the session tracking of the JWT manager, stamping
+// sessions with the clock and IDs of opts
+func NewSessions(sessionStorage SessionStorage, opts Options) *Sessions {
+	return &Sessions{storage: sessionStorage, opts: opts.withDefaults()}
+}
+
+// Start records a new session, dropping the sessions of the user that expired
+// longer ago than the scoped tokens they issued can live
+func (s *Sessions) Start(ctx context.Context, userID uuid.UUID, device, userAgent string, expiresAt time.Time) (uuid.UUID, error) {
+	now := s.opts.Clock.Now()
+	if err := s.storage.DeleteExpiredSessions(ctx, userID, now.Add(-maxScopedTokenTTL)); err != nil {
+		logger.FromContext(ctx).Error("Failed to delete expired sessions", zap.Error(err), zap.String("user_id", userID.String()))
+	}
+
+	session := &models.Session{
+		ID:         s.opts.RecordIDs.NewID(),
+		UserID:     userID,
+		Device:     truncateLabel(device),
+		UserAgent:  truncateLabel(userAgent),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.storage.CreateSession(ctx, session); err != nil {
+		return uuid.Nil, err
+	}
+	return session.ID, nil
+}
+
+// Check returns auth.ErrSessionRevoked if the session of the user was deleted
+// or, unless the token is scoped, expired, and records that it was seen
+func (s *Sessions) Check(ctx context.Context, userID, sessionID uuid.UUID, scoped bool) error {
+	session, err := s.storage.GetSession(ctx, sessionID)
+	if err != nil {
+		if err.Error() == "session not found" {
+			return auth.ErrSessionRevoked
+		}
+		return err
+	}
+
+	now := s.opts.Clock.Now()
+	if session.UserID != userID || !scoped && !now.Before(session.ExpiresAt) {
+		return auth.ErrSessionRevoked
+	}
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.storage.TouchSession(ctx, sessionID, now); err != nil {
+			logger.FromContext(ctx).Error("Failed to record session activity", zap.Error(err),
+				zap.String("session_id", sessionID.String()))
+		}
+	}
+	return nil
+}
+
+// truncateLabel cuts a client-supplied label to maxSessionLabel characters
+func truncateLabel(label string) string {
+	if utf8.RuneCountInString(label) <= maxSessionLabel {
+		return label
+	}
+	return string([]rune(label)[:maxSessionLabel])
+}
+
+// RegisterSessionRoutes registers the routes listing and revoking the
+// sessions of the user
+func RegisterSessionRoutes(r *mux.Router, sessions *Sessions, jwtManager *auth.JWTManager) {
+	protected := r.PathPrefix("/api/v1/sessions").Subrouter()
+	protected.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	protected.HandleFunc("", handleGetSessions(sessions.storage, sessions.opts.Clock)).Methods("GET")
+	protected.HandleFunc("/{id}", handleDeleteSession(sessions.storage)).Methods("DELETE")
+}
+
+// handleGetSessions lists the active sessions of the caller, marking the one
+// of the request
+func handleGetSessions(sessionStorage SessionStorage, clk clock.Clock) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		sessions, err := sessionStorage.GetSessions(r.Context(), userID, clk.Now())
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Failed to get sessions", zap.Error(err), zap.String("user_id", userID.String()))
+			apierror.Error(w, "Failed to get sessions", http.StatusInternalServerError)
+			return
+		}
+
+		current := r.Header.Get("X-Session-ID")
+		response := models.SessionsResponse{Sessions: make([]models.Session, 0, len(sessions))}
+		for _, session := range sessions {
+			session.Current = session.ID.String() == current
+			response.Sessions = append(response.Sessions, *session)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleDeleteSession revokes a session of the caller, including the one of
+// the request, which logs it out
+func handleDeleteSession(sessionStorage SessionStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		sessionID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, "Invalid session ID", http.StatusBadRequest)
+			return
+		}
+
+		// sessions of other users are reported as missing, so their IDs cannot be probed
+		session, err := sessionStorage.GetSession(r.Context(), sessionID)
+		if err != nil || session.UserID != userID {
+			if err == nil || err.Error() == "session not found" {
+				apierror.Error(w, "Session not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get session", http.StatusInternalServerError)
+			return
+		}
+
+		if err := sessionStorage.DeleteSession(r.Context(), sessionID); err != nil {
+			if err.Error() == "session not found" {
+				apierror.Error(w, "Session not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Session revoked", zap.String("user_id", userID.String()),
+			zap.String("session_id", sessionID.String()))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
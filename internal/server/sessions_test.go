@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestServer_Sessions(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	sessions := NewSessions(store, Options{})
+	jwtManager.SetSessions(sessions)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterSessionRoutes(router, sessions, jwtManager)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	for _, name := range []string{"owner", "other"} {
+		if err := store.CreateUser(context.Background(), &models.User{ID: uuid.New(), Username: name, Password: string(hashed)}); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+	}
+
+	do := func(method, path, token string, body interface{}, header http.Header) *httptest.ResponseRecorder {
+		req := newTestRequest(method, path, token, body)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		return serveRequest(router, req)
+	}
+	login := func(username, device string) string {
+		header := http.Header{}
+		header.Set(DeviceHeader, device)
+		header.Set("User-Agent", "gophkeeper-test")
+		w := do("POST", "/api/v1/login", "", models.LoginRequest{Username: username, Password: "secret"}, header)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 on login, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp models.AuthResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp.Token
+	}
+	list := func(token string) []models.Session {
+		w := do("GET", "/api/v1/sessions", token, nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp models.SessionsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp.Sessions
+	}
+
+	laptop := login("owner", "laptop")
+	desktop := login("owner", "desktop")
+	otherToken := login("other", "phone")
+
+	listed := list(laptop)
+	if len(listed) != 2 {
+		t.Fatalf("Expected 2 sessions, got %+v", listed)
+	}
+	var laptopID, desktopID uuid.UUID
+	for _, session := range listed {
+		if session.UserAgent != "gophkeeper-test" {
+			t.Errorf("Expected the user agent to be recorded, got %q", session.UserAgent)
+		}
+		switch session.Device {
+		case "laptop":
+			laptopID = session.ID
+			if !session.Current {
+				t.Error("Expected the session of the request to be marked current")
+			}
+		case "desktop":
+			desktopID = session.ID
+			if session.Current {
+				t.Error("Expected only the session of the request to be marked current")
+			}
+		}
+	}
+	if laptopID == uuid.Nil || desktopID == uuid.Nil {
+		t.Fatalf("Expected both devices to be listed, got %+v", listed)
+	}
+
+	other := list(otherToken)
+	if len(other) != 1 {
+		t.Fatalf("Expected 1 session of the other user, got %+v", other)
+	}
+	if w := do("DELETE", "/api/v1/sessions/"+other[0].ID.String(), laptop, nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 revoking a session of another user, got %d", w.Code)
+	}
+	if w := do("DELETE", "/api/v1/sessions/not-a-uuid", laptop, nil, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid session ID, got %d", w.Code)
+	}
+
+	if w := do("DELETE", "/api/v1/sessions/"+desktopID.String(), laptop, nil, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+	w := do("GET", "/api/v1/sessions", desktop, nil, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for the token of a revoked session, got %d", w.Code)
+	}
+	var apiErr models.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&apiErr); err != nil || apiErr.Error != "Session revoked" {
+		t.Errorf("Expected a session revoked error, got %+v, %v", apiErr, err)
+	}
+	if w := do("DELETE", "/api/v1/sessions/"+desktopID.String(), laptop, nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 revoking a session twice, got %d", w.Code)
+	}
+	if listed := list(laptop); len(listed) != 1 || listed[0].ID != laptopID {
+		t.Errorf("Expected only the laptop session left, got %+v", listed)
+	}
+	if len(list(otherToken)) != 1 {
+		t.Error("Expected the session of the other user to be kept")
+	}
+
+	if w := do("DELETE", "/api/v1/sessions/"+laptopID.String(), laptop, nil, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 revoking the current session, got %d", w.Code)
+	}
+	if w := do("GET", "/api/v1/sessions", laptop, nil, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 after logging out, got %d", w.Code)
+	}
+}
+
+func TestSessions_Check(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	sessions := NewSessions(store, Options{})
+	ctx := context.Background()
+	userID := uuid.New()
+	if err := store.CreateUser(ctx, &models.User{ID: userID, Username: "user"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	sessionID, err := sessions.Start(ctx, userID, "laptop", "agent", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := sessions.Check(ctx, userID, sessionID, false); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if err := sessions.Check(ctx, uuid.New(), sessionID, false); err != auth.ErrSessionRevoked {
+		t.Errorf("Check() of another user error = %v, want %v", err, auth.ErrSessionRevoked)
+	}
+	if err := sessions.Check(ctx, userID, uuid.New(), true); err != auth.ErrSessionRevoked {
+		t.Errorf("Check() of an unknown session error = %v, want %v", err, auth.ErrSessionRevoked)
+	}
+
+	expired, err := sessions.Start(ctx, userID, "old", "agent", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := sessions.Check(ctx, userID, expired, false); err != auth.ErrSessionRevoked {
+		t.Errorf("Check() of an expired session error = %v, want %v", err, auth.ErrSessionRevoked)
+	}
+	if err := sessions.Check(ctx, userID, expired, true); err != nil {
+		t.Errorf("Check() of a scoped token of an expired session error = %v", err)
+	}
+
+	stale, err := sessions.Start(ctx, userID, "stale", "agent", time.Now().Add(-maxScopedTokenTTL-time.Minute))
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := sessions.Start(ctx, userID, "new", "agent", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := store.GetSession(ctx, stale); err != storage.ErrSessionNotFound {
+		t.Errorf("Expected starting a session to drop ones expired longer than scoped tokens live, got %v", err)
+	}
+	if _, err := store.GetSession(ctx, expired); err != nil {
+		t.Errorf("Expected starting a session to keep ones scoped tokens may still use, got %v", err)
+	}
+}
@@ -0,0 +1,398 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// maxSharedKeySize bounds a sealed data key or an encrypted share private key
+const maxSharedKeySize = 1024
+
+type ShareStorage interface {
+	SetShareKey(ctx context.Context, userID uuid.UUID, keys *models.ShareKeys) error
+	GetShareKey(ctx context.Context, userID uuid.UUID) (*models.ShareKeys, error)
+	CreateShare(ctx context.Context, share *models.Share) error
+	GetShare(ctx context.Context, shareID uuid.UUID) (*models.Share, error)
+	GetShares(ctx context.Context, dataID uuid.UUID) ([]*models.Share, error)
+	GetSharedWith(ctx context.Context, recipientID uuid.UUID) ([]*models.Share, error)
+	DeleteShare(ctx context.Context, shareID uuid.UUID) error
+}
+
+// RegisterShareRoutes registers the routes sharing items between users. The
+// owner seals an item's data key to the recipient's share public key, so the
+// server stores grants it cannot open.
+func RegisterShareRoutes(r *mux.Router, shareStorage ShareStorage, userStorage UserStorage, dataStorage DataStorage,
+	jwtManager *auth.JWTManager, opts Options) {
+	opts = opts.withDefaults()
+	protected := r.PathPrefix("/api/v1").Subrouter()
+	protected.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	protected.HandleFunc("/share/key", handleGetShareKey(shareStorage)).Methods("GET")
+	protected.HandleFunc("/share/key", handleSetShareKey(shareStorage)).Methods("PUT")
+	protected.HandleFunc("/users/{username}/share-key", handleGetUserShareKey(shareStorage, userStorage)).Methods("GET")
+	protected.HandleFunc("/data/{id}/shares", handleGetShares(shareStorage, dataStorage)).Methods("GET")
+	protected.HandleFunc("/data/{id}/shares", handleCreateShare(shareStorage, userStorage, dataStorage, opts)).Methods("POST")
+	protected.HandleFunc("/data/{id}/shares/{share}", handleDeleteShare(shareStorage, dataStorage)).Methods("DELETE")
+	protected.HandleFunc("/shared", handleGetSharedItems(shareStorage, userStorage, dataStorage)).Methods("GET")
+	protected.HandleFunc("/shared/{share}", handleUpdateSharedItem(shareStorage, dataStorage, opts)).Methods("PUT")
+}
+
+// handleGetShareKey returns the caller's share key pair, the private key still
+// encrypted under their vault key, or an empty one if none is set
+func handleGetShareKey(shareStorage ShareStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		keys, err := shareStorage.GetShareKey(r.Context(), userID)
+		if err != nil {
+			if err.Error() == "user not found" {
+				apierror.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get share key", http.StatusInternalServerError)
+			return
+		}
+		if keys == nil {
+			keys = &models.ShareKeys{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(keys); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleSetShareKey publishes the caller's share key pair. The public key is
+// set once, as items may already be sealed to it.
+func handleSetShareKey(shareStorage ShareStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.ShareKeys
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.PublicKey) != 32 {
+			apierror.Error(w, "Public key must be 32 bytes", http.StatusBadRequest)
+			return
+		}
+		if len(req.PrivateKey) == 0 || len(req.PrivateKey) > maxSharedKeySize {
+			apierror.Error(w, "Private key must be between 1 and 1024 bytes", http.StatusBadRequest)
+			return
+		}
+
+		if err := shareStorage.SetShareKey(r.Context(), userID, &req); err != nil {
+			switch err.Error() {
+			case "user not found":
+				apierror.Error(w, "User not found", http.StatusNotFound)
+			case "share key already set":
+				logger.FromContext(r.Context()).Warn("Rejected share key overwrite", zap.String("user_id", userID.String()))
+				apierror.Error(w, "Share key already set", http.StatusConflict)
+			default:
+				apierror.Error(w, "Failed to set share key", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleGetUserShareKey returns the share public key of a user to seal items to
+func handleGetUserShareKey(shareStorage ShareStorage, userStorage UserStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := userStorage.GetUserByUsername(r.Context(), mux.Vars(r)["username"])
+		if err != nil {
+			if err.Error() == "user not found" {
+				apierror.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+
+		keys, err := shareStorage.GetShareKey(r.Context(), user.ID)
+		if err != nil {
+			apierror.Error(w, "Failed to get share key", http.StatusInternalServerError)
+			return
+		}
+		if keys == nil {
+			apierror.Error(w, "User has no share key", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.ShareKeys{PublicKey: keys.PublicKey}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleGetShares returns the shares of an item the caller owns, oldest first
+func handleGetShares(shareStorage ShareStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		shares, err := shareStorage.GetShares(r.Context(), data.ID)
+		if err != nil {
+			apierror.Error(w, "Failed to get shares", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.SharesResponse{Shares: make([]models.Share, 0, len(shares))}
+		for _, share := range shares {
+			response.Shares = append(response.Shares, *share)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleCreateShare shares an item the caller owns with another user, or
+// changes the mode and sealed key of the share that user already has
+func handleCreateShare(shareStorage ShareStorage, userStorage UserStorage, dataStorage DataStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.ShareRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Mode != models.ShareModeRead && req.Mode != models.ShareModeWrite {
+			apierror.Error(w, "Mode must be read or write", http.StatusBadRequest)
+			return
+		}
+		if len(req.SealedKey) == 0 || len(req.SealedKey) > maxSharedKeySize {
+			apierror.Error(w, "Sealed key must be between 1 and 1024 bytes", http.StatusBadRequest)
+			return
+		}
+
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		recipient, err := userStorage.GetUserByUsername(r.Context(), req.Username)
+		if err != nil {
+			if err.Error() == "user not found" {
+				apierror.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if recipient.ID == data.UserID {
+			apierror.Error(w, "Cannot share an item with yourself", http.StatusBadRequest)
+			return
+		}
+
+		share := &models.Share{
+			ID:          opts.RecordIDs.NewID(),
+			DataID:      data.ID,
+			OwnerID:     data.UserID,
+			RecipientID: recipient.ID,
+			Recipient:   recipient.Username,
+			Mode:        req.Mode,
+			SealedKey:   req.SealedKey,
+			CreatedAt:   opts.Clock.Now(),
+		}
+		if err := shareStorage.CreateShare(r.Context(), share); err != nil {
+			if err.Error() == "data not found" {
+				apierror.Error(w, "Data not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to share data", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Data shared", zap.String("data_id", data.ID.String()),
+			zap.String("recipient_id", recipient.ID.String()), zap.String("mode", share.Mode))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(models.ShareResponse{Share: *share}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleDeleteShare revokes a share of an item the caller owns. The recipient
+// may still hold the data key, so the client re-keys the item afterwards.
+func handleDeleteShare(shareStorage ShareStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shareID, err := uuid.Parse(mux.Vars(r)["share"])
+		if err != nil {
+			apierror.Error(w, "Invalid share ID", http.StatusBadRequest)
+			return
+		}
+
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		share, err := shareStorage.GetShare(r.Context(), shareID)
+		if err != nil || share.DataID != data.ID {
+			if err == nil || err.Error() == "share not found" {
+				apierror.Error(w, "Share not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get share", http.StatusInternalServerError)
+			return
+		}
+
+		if err := shareStorage.DeleteShare(r.Context(), shareID); err != nil {
+			if err.Error() == "share not found" {
+				apierror.Error(w, "Share not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to revoke share", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Share revoked", zap.String("data_id", data.ID.String()),
+			zap.String("recipient_id", share.RecipientID.String()))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleGetSharedItems returns the items other users shared with the caller,
+// each with its grant and its owner's username
+func handleGetSharedItems(shareStorage ShareStorage, userStorage UserStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		shares, err := shareStorage.GetSharedWith(r.Context(), userID)
+		if err != nil {
+			apierror.Error(w, "Failed to get shared items", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.SharedItemsResponse{Items: make([]models.SharedItem, 0, len(shares))}
+		owners := make(map[uuid.UUID]string)
+		for _, share := range shares {
+			data, err := dataStorage.GetDataByID(r.Context(), share.DataID)
+			if err != nil {
+				// the item was deleted after the shares were listed
+				continue
+			}
+			if _, ok := owners[share.OwnerID]; !ok {
+				owner, err := userStorage.GetUserByID(r.Context(), share.OwnerID)
+				if err != nil {
+					continue
+				}
+				owners[share.OwnerID] = owner.Username
+			}
+			response.Items = append(response.Items, models.SharedItem{Share: *share, Owner: owners[share.OwnerID], Data: *data})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleUpdateSharedItem changes an item shared with the caller in write mode.
+// The ciphertext must stay under the shared data key, which the server cannot
+// check, and the base revision must be the current one.
+func handleUpdateSharedItem(shareStorage ShareStorage, dataStorage DataStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shareID, err := uuid.Parse(mux.Vars(r)["share"])
+		if err != nil {
+			apierror.Error(w, "Invalid share ID", http.StatusBadRequest)
+			return
+		}
+
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.SharedItemRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" || len(req.Data) == 0 {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		share, err := shareStorage.GetShare(r.Context(), shareID)
+		if err != nil || share.RecipientID != userID {
+			if err == nil || err.Error() == "share not found" {
+				apierror.Error(w, "Share not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get share", http.StatusInternalServerError)
+			return
+		}
+		if share.Mode != models.ShareModeWrite {
+			apierror.Error(w, "Item is shared read-only", http.StatusForbidden)
+			return
+		}
+
+		data, err := dataStorage.GetDataByID(r.Context(), share.DataID)
+		if err != nil {
+			if err.Error() == "data not found" {
+				apierror.Error(w, "Data not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get data", http.StatusInternalServerError)
+			return
+		}
+		if req.BaseRevision != data.Revision {
+			w.Header().Set("ETag", dataETag(data))
+			apierror.Error(w, "Data was modified by another device", http.StatusConflict)
+			return
+		}
+
+		data.Name = req.Name
+		data.Description = req.Description
+		data.Data = req.Data
+		data.Metadata = req.Metadata
+		data.Checksum = req.Checksum
+		data.UpdatedAt = opts.Clock.Now()
+
+		if err := dataStorage.UpdateData(r.Context(), data); err != nil {
+			apierror.Error(w, "Failed to update data", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Shared data updated", zap.String("data_id", data.ID.String()),
+			zap.String("recipient_id", userID.String()))
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", dataETag(data))
+		if err := json.NewEncoder(w).Encode(models.DataResponse{Data: *data}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_Sharing(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterShareRoutes(router, store, store, store, jwtManager, Options{})
+
+	register := func(username string) string {
+		w := doRequest(router, "POST", "/api/v1/register", "", models.UserRequest{Username: username, Password: "login-pass", MasterPassword: "master-password"})
+		var authResp models.AuthResponse
+		if err := json.NewDecoder(w.Body).Decode(&authResp); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+		return authResp.Token
+	}
+	shared := func(token string) []models.SharedItem {
+		w := doRequest(router, "GET", "/api/v1/shared", token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp models.SharedItemsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode shared items: %v", err)
+		}
+		return resp.Items
+	}
+
+	owner := register("owner")
+	recipient := register("recipient")
+	other := register("other")
+
+	t.Run("share key", func(t *testing.T) {
+		if w := doRequest(router, "GET", "/api/v1/users/recipient/share-key", owner, nil); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 before the key is set, got %d", w.Code)
+		}
+		if w := doRequest(router, "GET", "/api/v1/share/key", recipient, nil); w.Code != http.StatusOK || w.Body.String() != "{\"public_key\":null}\n" {
+			t.Errorf("Expected no share key yet, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := doRequest(router, "PUT", "/api/v1/share/key", recipient, models.ShareKeys{PublicKey: []byte("short"), PrivateKey: []byte("x")}); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for a short public key, got %d", w.Code)
+		}
+		keys := models.ShareKeys{PublicKey: bytes.Repeat([]byte{1}, 32), PrivateKey: []byte("encrypted")}
+		if w := doRequest(router, "PUT", "/api/v1/share/key", recipient, keys); w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := doRequest(router, "PUT", "/api/v1/share/key", recipient, models.ShareKeys{PublicKey: bytes.Repeat([]byte{2}, 32), PrivateKey: []byte("x")}); w.Code != http.StatusConflict {
+			t.Errorf("Expected status 409 replacing the public key, got %d", w.Code)
+		}
+
+		w := doRequest(router, "GET", "/api/v1/users/recipient/share-key", owner, nil)
+		var public models.ShareKeys
+		if err := json.NewDecoder(w.Body).Decode(&public); err != nil {
+			t.Fatalf("Failed to decode share key: %v", err)
+		}
+		if !bytes.Equal(public.PublicKey, keys.PublicKey) || public.PrivateKey != nil {
+			t.Errorf("Expected only the public key, got %+v", public)
+		}
+		w = doRequest(router, "GET", "/api/v1/share/key", recipient, nil)
+		var own models.ShareKeys
+		if err := json.NewDecoder(w.Body).Decode(&own); err != nil || string(own.PrivateKey) != "encrypted" {
+			t.Errorf("Expected the encrypted private key for its owner, got %+v, %v", own, err)
+		}
+	})
+
+	w := doRequest(router, "POST", "/api/v1/data", owner, models.DataRequest{Type: models.DataTypeText, Name: "note", Data: []byte("sealed")})
+	var created models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+	sharesPath := "/api/v1/data/" + created.Data.ID.String() + "/shares"
+
+	invalid := []struct {
+		name  string
+		token string
+		req   models.ShareRequest
+		want  int
+	}{
+		{name: "bad mode", token: owner, req: models.ShareRequest{Username: "recipient", Mode: "admin", SealedKey: []byte("k")}, want: http.StatusBadRequest},
+		{name: "no sealed key", token: owner, req: models.ShareRequest{Username: "recipient", Mode: models.ShareModeRead}, want: http.StatusBadRequest},
+		{name: "unknown user", token: owner, req: models.ShareRequest{Username: "nobody", Mode: models.ShareModeRead, SealedKey: []byte("k")}, want: http.StatusNotFound},
+		{name: "self", token: owner, req: models.ShareRequest{Username: "owner", Mode: models.ShareModeRead, SealedKey: []byte("k")}, want: http.StatusBadRequest},
+		{name: "not the owner", token: other, req: models.ShareRequest{Username: "recipient", Mode: models.ShareModeRead, SealedKey: []byte("k")}, want: http.StatusForbidden},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := doRequest(router, "POST", sharesPath, tt.token, tt.req); w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w = doRequest(router, "POST", sharesPath, owner, models.ShareRequest{Username: "recipient", Mode: models.ShareModeRead, SealedKey: []byte("sealed-dek")})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var share models.ShareResponse
+	if err := json.NewDecoder(w.Body).Decode(&share); err != nil {
+		t.Fatalf("Failed to decode share: %v", err)
+	}
+	sharedPath := "/api/v1/shared/" + share.Share.ID.String()
+
+	items := shared(recipient)
+	if len(items) != 1 || items[0].Owner != "owner" || string(items[0].Data.Data) != "sealed" || string(items[0].Share.SealedKey) != "sealed-dek" {
+		t.Fatalf("Expected the item shared with the recipient, got %+v", items)
+	}
+	if items := shared(other); len(items) != 0 {
+		t.Errorf("Expected nothing shared with other users, got %+v", items)
+	}
+
+	update := models.SharedItemRequest{Name: "edited", Data: []byte("resealed"), BaseRevision: created.Data.Revision}
+	if w := doRequest(router, "PUT", sharedPath, recipient, update); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 writing a read-only share, got %d", w.Code)
+	}
+
+	// sharing again changes the mode of the same grant
+	w = doRequest(router, "POST", sharesPath, owner, models.ShareRequest{Username: "recipient", Mode: models.ShareModeWrite, SealedKey: []byte("sealed-dek")})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(router, "PUT", sharedPath, other, update); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's share, got %d", w.Code)
+	}
+	if w := doRequest(router, "PUT", sharedPath, recipient, update); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doRequest(router, "PUT", sharedPath, recipient, update); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a stale base revision, got %d", w.Code)
+	}
+	if data, _ := store.GetDataByID(context.Background(), created.Data.ID); data.Name != "edited" || string(data.Data) != "resealed" {
+		t.Errorf("Expected the recipient's change, got %q %q", data.Name, data.Data)
+	}
+
+	w = doRequest(router, "GET", sharesPath, owner, nil)
+	var shares models.SharesResponse
+	if err := json.NewDecoder(w.Body).Decode(&shares); err != nil {
+		t.Fatalf("Failed to decode shares: %v", err)
+	}
+	if len(shares.Shares) != 1 || shares.Shares[0].ID != share.Share.ID || shares.Shares[0].Mode != models.ShareModeWrite {
+		t.Errorf("Expected one write share, got %+v", shares.Shares)
+	}
+
+	if w := doRequest(router, "DELETE", sharesPath+"/"+share.Share.ID.String(), other, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 revoking another user's share, got %d", w.Code)
+	}
+	if w := doRequest(router, "DELETE", sharesPath+"/"+share.Share.ID.String(), owner, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+	if items := shared(recipient); len(items) != 0 {
+		t.Errorf("Expected no shared items after revoking, got %+v", items)
+	}
+	if w := doRequest(router, "DELETE", sharesPath+"/"+share.Share.ID.String(), owner, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 revoking twice, got %d", w.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/pkg/version"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Feature names advertised by the status endpoint
+const (
+	FeatureEnvironments      = "environments"
+	FeatureFieldPublishing   = "field_publishing"
+	FeatureConflictDetection = "conflict_detection"
+	FeatureKeyEscrow         = "key_escrow"
+	FeatureAdmin             = "admin"
+	FeatureULIDs             = "ulid_ids"
+	FeatureComments          = "comments"
+	FeatureStreamingImport   = "streaming_import"
+	FeatureAuditLog          = "audit_log"
+	FeatureVaultLock         = "vault_lock"
+	FeatureChunkedBinary     = "chunked_binary"
+	FeatureVersions          = "versions"
+	FeatureSearch            = "search"
+	FeatureCollections       = "collections"
+	FeatureVaultRotation     = "vault_rotation"
+	FeatureSharing           = "sharing"
+	FeatureChangeEvents      = "change_events"
+	FeatureBatch             = "batch"
+	FeatureMetadataPatch     = "metadata_patch"
+	FeatureSessions          = "sessions"
+	FeatureOIDC              = "oidc"
+	FeatureCompression       = "compression"
+	FeatureWebUI             = "web_ui"
+	FeatureDomainIndex       = "domain_index"
+	FeatureIcons             = "icons"
+	FeatureAttachments       = "attachments"
+	FeatureExpiry            = "expiry"
+	FeaturePresignedChunks   = "presigned_chunks"
+)
+
+// StatusOptions describes the instance for the public status endpoint
+type StatusOptions struct {
+	RegistrationOpen bool
+	Features         []string
+	MaxPayloadBytes  int64
+	// PayloadLimits are the body limits of named routes, as from PayloadLimits
+	PayloadLimits map[string]int64
+	// CredentialPolicy is published so clients can check credentials before
+	// registering; nil leaves it out
+	CredentialPolicy *models.CredentialPolicy
+}
+
+// RegisterStatusRoutes registers the unauthenticated instance status route
+func RegisterStatusRoutes(r *mux.Router, opts StatusOptions) {
+	r.HandleFunc("/api/v1/status", handleStatus(opts)).Methods("GET")
+}
+
+// CloseRegistration rejects new sign-ups. It must be called before RegisterRoutes
+// so that it takes precedence over the registration route.
+func CloseRegistration(r *mux.Router) {
+	r.HandleFunc("/api/v1/register", func(w http.ResponseWriter, r *http.Request) {
+		apierror.WithCode(w, http.StatusForbidden, models.ErrorCodeRegistrationClosed, "Registration is closed")
+	}).Methods("POST")
+}
+
+func handleStatus(opts StatusOptions) http.HandlerFunc {
+	features := opts.Features
+	if features == nil {
+		features = []string{}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := models.StatusResponse{
+			Version:          version.Version,
+			RegistrationOpen: opts.RegistrationOpen,
+			Features:         features,
+			MaxPayloadBytes:  opts.MaxPayloadBytes,
+			PayloadLimits:    opts.PayloadLimits,
+			CredentialPolicy: opts.CredentialPolicy,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/a2sh3r/gophkeeper/pkg/version"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_Status(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	CloseRegistration(router)
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterStatusRoutes(router, StatusOptions{
+		RegistrationOpen: false,
+		Features:         []string{FeatureEnvironments, FeatureKeyEscrow},
+		MaxPayloadBytes:  1024,
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/status", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d without authentication, got %d", http.StatusOK, w.Code)
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		t.Error("Expected status response to be cacheable")
+	}
+
+	var status models.StatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if status.Version != version.Version || status.RegistrationOpen || status.MaxPayloadBytes != 1024 ||
+		len(status.Features) != 2 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	body, _ := json.Marshal(models.UserRequest{Username: "newbie", Password: "password", MasterPassword: "master-password"})
+	req = httptest.NewRequest("POST", "/api/v1/register", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected closed registration to return %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
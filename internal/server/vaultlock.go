@@ -0,0 +1,262 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// VaultBusyError is the error code returned for changes to a locked vault
+const VaultBusyError = models.ErrorCodeVaultBusy
+
+// VaultLockHeader carries the token of the vault lock a request holds
+const VaultLockHeader = "X-Vault-Lock"
+
+// Lifetimes of vault locks; holders renew long operations before expiry
+const (
+	defaultVaultLockTTL = time.Minute
+	maxVaultLockTTL     = 10 * time.Minute
+)
+
+// VaultLockOperations are the maintenance operations that may lock a vault
+var VaultLockOperations = []string{"rotation", "import", "restore"}
+
+// VaultLockedPaths are the path prefixes whose mutating requests are rejected
+// while the vault is locked by another holder
+var VaultLockedPaths = []string{"/api/v1/data", "/api/v1/salt", RotatePath}
+
+// VaultLocks keeps advisory per-user vault locks. Locks are short-lived and
+// kept in memory, so they coordinate the devices using one server instance.
+type VaultLocks struct {
+	mutex sync.Mutex
+	locks map[uuid.UUID]models.VaultLock
+	clock clock.Clock
+}
+
+// NewVaultLocks creates an empty vault lock table expiring locks by the clock of opts
+func NewVaultLocks(opts Options) *VaultLocks {
+	return &VaultLocks{locks: make(map[uuid.UUID]models.VaultLock), clock: opts.withDefaults().Clock}
+}
+
+// Acquire locks the user's vault for operation, or renews the lock if token
+// is the current one. It returns the active lock and false if another holder
+// has it.
+func (l *VaultLocks) Acquire(userID uuid.UUID, operation, token string, ttl time.Duration) (models.VaultLock, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.clock.Now()
+	current, locked := l.active(userID, now)
+	if locked && !tokenMatches(current.Token, token) {
+		return current, false
+	}
+	if !locked {
+		token = newVaultLockToken()
+	}
+
+	lock := models.VaultLock{Token: token, Operation: operation, ExpiresAt: now.Add(ttl)}
+	l.locks[userID] = lock
+	return lock, true
+}
+
+// Release removes the user's vault lock if token is the current one
+func (l *VaultLocks) Release(userID uuid.UUID, token string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	current, locked := l.active(userID, l.clock.Now())
+	if !locked || !tokenMatches(current.Token, token) {
+		return false
+	}
+	delete(l.locks, userID)
+	return true
+}
+
+// Check returns the user's vault lock and false unless it is free or held by token
+func (l *VaultLocks) Check(userID uuid.UUID, token string) (models.VaultLock, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	current, locked := l.active(userID, l.clock.Now())
+	if locked && !tokenMatches(current.Token, token) {
+		return current, false
+	}
+	return current, true
+}
+
+// Guard returns the 423 problem to reject a change to the user's vault with,
+// unless it is free or held by token
+func (l *VaultLocks) Guard(userID uuid.UUID, token string) error {
+	if lock, ok := l.Check(userID, token); !ok {
+		return vaultBusy(lock, l.clock.Now())
+	}
+	return nil
+}
+
+// active returns the unexpired lock of the user, dropping an expired one
+func (l *VaultLocks) active(userID uuid.UUID, now time.Time) (models.VaultLock, bool) {
+	lock, ok := l.locks[userID]
+	if ok && !now.Before(lock.ExpiresAt) {
+		delete(l.locks, userID)
+		return models.VaultLock{}, false
+	}
+	return lock, ok
+}
+
+func tokenMatches(current, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(current), []byte(token)) == 1
+}
+
+func newVaultLockToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		// crypto/rand failing leaves nothing sensible to do
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// RegisterVaultLockRoutes registers the vault lock routes and rejects changes
+// to locked vaults from requests that do not hold the lock
+func RegisterVaultLockRoutes(r *mux.Router, locks *VaultLocks, jwtManager *auth.JWTManager) {
+	r.Use(vaultLockGuard(locks, jwtManager))
+
+	vault := r.PathPrefix("/api/v1/vault").Subrouter()
+	vault.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	vault.HandleFunc("/lock", handleLockVault(locks)).Methods("POST")
+	vault.HandleFunc("/unlock", handleUnlockVault(locks)).Methods("POST")
+}
+
+// vaultLockGuard answers mutating requests to a vault locked by another holder
+// with 423. Requests without a valid token are passed on for the routes to reject.
+func vaultLockGuard(locks *VaultLocks, jwtManager *auth.JWTManager) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || !isVaultLockedPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := jwtManager.ValidateToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err := locks.Guard(claims.UserID, r.Header.Get(VaultLockHeader)); err != nil {
+				apierror.Reply(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isVaultLockedPath(path string) bool {
+	for _, prefix := range VaultLockedPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// vaultBusy returns the problem naming the operation holding the lock and
+// how long after now the lock expires
+func vaultBusy(lock models.VaultLock, now time.Time) *apierror.Problem {
+	retryAfter := int64(lock.ExpiresAt.Sub(now)/time.Second) + 1
+	problem := &apierror.Problem{
+		Status:   http.StatusLocked,
+		Response: models.ErrorResponse{Error: VaultBusyError, Message: "vault busy: " + lock.Operation + " in progress", Code: VaultBusyError},
+	}
+	return problem.WithHeader("Retry-After", strconv.FormatInt(retryAfter, 10))
+}
+
+// handleLockVault locks the vault, or renews the lock given in VaultLockHeader
+func handleLockVault(locks *VaultLocks) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.VaultLockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !isVaultLockOperation(req.Operation) || req.TTLSeconds < 0 {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		ttl := time.Duration(req.TTLSeconds) * time.Second
+		if ttl == 0 {
+			ttl = defaultVaultLockTTL
+		}
+		if ttl > maxVaultLockTTL {
+			ttl = maxVaultLockTTL
+		}
+
+		lock, ok := locks.Acquire(userID, req.Operation, r.Header.Get(VaultLockHeader), ttl)
+		if !ok {
+			apierror.Reply(w, vaultBusy(lock, locks.clock.Now()))
+			return
+		}
+		logger.FromContext(r.Context()).Info("Vault locked", zap.String("user_id", userID.String()),
+			zap.String("operation", lock.Operation), zap.Time("expires_at", lock.ExpiresAt))
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(lock); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleUnlockVault releases the vault lock held with the given token
+func handleUnlockVault(locks *VaultLocks) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.VaultUnlockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if !locks.Release(userID, req.Token) {
+			apierror.Error(w, "Lock not found", http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context()).Info("Vault unlocked", zap.String("user_id", userID.String()))
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func isVaultLockOperation(operation string) bool {
+	for _, allowed := range VaultLockOperations {
+		if operation == allowed {
+			return true
+		}
+	}
+	return false
+}
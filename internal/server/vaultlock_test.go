@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_VaultLock(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	locks := NewVaultLocks(Options{})
+
+	router := mux.NewRouter()
+	RegisterVaultLockRoutes(router, locks, jwtManager)
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+
+	userID := uuid.New()
+	if err := store.CreateUser(context.Background(), &models.User{ID: userID, Username: "locked"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	token, err := jwtManager.GenerateToken(userID, "locked")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	otherToken, err := jwtManager.GenerateToken(uuid.New(), "other")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	do := func(method, path, bearer, lockToken string, body interface{}) *httptest.ResponseRecorder {
+		req := newTestRequest(method, path, bearer, body)
+		if lockToken != "" {
+			req.Header.Set(VaultLockHeader, lockToken)
+		}
+		return serveRequest(router, req)
+	}
+	item := models.DataRequest{Type: models.DataTypeText, Name: "Note", Data: []byte("sealed")}
+
+	if w := do("POST", "/api/v1/vault/lock", token, "", models.VaultLockRequest{Operation: "defrag"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown operation, got %d", w.Code)
+	}
+
+	w := do("POST", "/api/v1/vault/lock", token, "", models.VaultLockRequest{Operation: "rotation", TTLSeconds: 30})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var lock models.VaultLock
+	if err := json.Unmarshal(w.Body.Bytes(), &lock); err != nil || lock.Token == "" {
+		t.Fatalf("Expected a lock token, got %s", w.Body.String())
+	}
+
+	// other devices of the user are turned away with the running operation
+	w = do("POST", "/api/v1/data", token, "", item)
+	if w.Code != http.StatusLocked || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected status 423 with Retry-After, got %d", w.Code)
+	}
+	var errResp models.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil || errResp.Error != VaultBusyError ||
+		errResp.Message != "vault busy: rotation in progress" {
+		t.Errorf("Unexpected busy response: %s", w.Body.String())
+	}
+	if w := do("POST", "/api/v1/vault/lock", token, "", models.VaultLockRequest{Operation: "import"}); w.Code != http.StatusLocked {
+		t.Errorf("Expected a second lock to be refused, got %d", w.Code)
+	}
+	if w := do("GET", "/api/v1/data", token, "", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected reads to work on a locked vault, got %d", w.Code)
+	}
+	if w := do("POST", "/api/v1/data", otherToken, "", item); w.Code != http.StatusCreated {
+		t.Errorf("Expected other users to be unaffected, got %d", w.Code)
+	}
+
+	// the holder keeps writing and renewing
+	if w := do("POST", "/api/v1/data", token, lock.Token, item); w.Code != http.StatusCreated {
+		t.Errorf("Expected the lock holder to write, got %d", w.Code)
+	}
+	if w := do("POST", "/api/v1/vault/lock", token, lock.Token, models.VaultLockRequest{Operation: "rotation"}); w.Code != http.StatusOK {
+		t.Errorf("Expected the holder to renew the lock, got %d", w.Code)
+	}
+
+	if w := do("POST", "/api/v1/vault/unlock", token, "", models.VaultUnlockRequest{Token: "wrong"}); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a wrong token, got %d", w.Code)
+	}
+	if w := do("POST", "/api/v1/vault/unlock", token, "", models.VaultUnlockRequest{Token: lock.Token}); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+	if w := do("POST", "/api/v1/data", token, "", item); w.Code != http.StatusCreated {
+		t.Errorf("Expected writes after unlocking, got %d", w.Code)
+	}
+}
+
+func TestVaultLocks_Expiry(t *testing.T) {
+	manual := clock.NewManual(time.Now())
+	locks := NewVaultLocks(Options{Clock: manual})
+	userID := uuid.New()
+
+	lock, ok := locks.Acquire(userID, "import", "", time.Minute)
+	if !ok {
+		t.Fatal("Acquire() on a free vault failed")
+	}
+	if _, ok := locks.Check(userID, ""); ok {
+		t.Error("Check() without the token should fail while locked")
+	}
+	if _, ok := locks.Check(userID, lock.Token); !ok {
+		t.Error("Check() with the token should pass")
+	}
+
+	manual.Advance(time.Minute)
+	if _, ok := locks.Check(userID, ""); !ok {
+		t.Error("Check() should pass once the lock expired")
+	}
+	if locks.Release(userID, lock.Token) {
+		t.Error("Release() of an expired lock should report false")
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// maxVerifierSize bounds a master password verifier, a short known value encrypted by the client
+const maxVerifierSize = 1024
+
+type VerifierStorage interface {
+	SetMasterVerifier(ctx context.Context, userID uuid.UUID, verifier []byte) error
+	GetMasterVerifier(ctx context.Context, userID uuid.UUID) ([]byte, error)
+}
+
+// RegisterVerifierRoutes registers the master password verifier routes. The
+// verifier is a known value the client encrypted under the vault key at
+// registration, so it can check a master password right after login.
+func RegisterVerifierRoutes(r *mux.Router, verifierStorage VerifierStorage, jwtManager *auth.JWTManager) {
+	verifier := r.PathPrefix("/api/v1/verifier").Subrouter()
+	verifier.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	verifier.HandleFunc("", handleGetVerifier(verifierStorage)).Methods("GET")
+	verifier.HandleFunc("", handleSetVerifier(verifierStorage)).Methods("PUT")
+}
+
+func handleGetVerifier(verifierStorage VerifierStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		verifier, err := verifierStorage.GetMasterVerifier(r.Context(), userID)
+		if err != nil {
+			if err.Error() == "user not found" {
+				apierror.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get verifier", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.VerifierResponse{Verifier: verifier}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleSetVerifier registers the verifier once. Afterwards only a master
+// password rotation replaces it, so a stolen token cannot lock the user out.
+func handleSetVerifier(verifierStorage VerifierStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.Header.Get("X-User-ID"))
+		if err != nil {
+			apierror.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.VerifierRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.Verifier) == 0 || len(req.Verifier) > maxVerifierSize {
+			apierror.Error(w, "Verifier must be between 1 and 1024 bytes", http.StatusBadRequest)
+			return
+		}
+
+		if err := verifierStorage.SetMasterVerifier(r.Context(), userID, req.Verifier); err != nil {
+			switch err.Error() {
+			case "user not found":
+				apierror.Error(w, "User not found", http.StatusNotFound)
+			case "verifier already set":
+				logger.FromContext(r.Context()).Warn("Rejected verifier overwrite", zap.String("user_id", userID.String()))
+				apierror.Error(w, "Verifier already set", http.StatusConflict)
+			default:
+				apierror.Error(w, "Failed to set verifier", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
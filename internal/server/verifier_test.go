@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_MasterVerifier(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := mux.NewRouter()
+	RegisterVerifierRoutes(router, store, jwtManager)
+
+	userID := uuid.New()
+	if err := store.CreateUser(context.Background(), &models.User{ID: userID, Username: "verified"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	token, err := jwtManager.GenerateToken(userID, "verified")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	do := func(method string, body interface{}) *httptest.ResponseRecorder {
+		return doRequest(router, method, "/api/v1/verifier", token, body)
+	}
+	get := func() []byte {
+		w := do("GET", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var resp models.VerifierResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp.Verifier
+	}
+
+	if verifier := get(); verifier != nil {
+		t.Errorf("Expected no verifier yet, got %q", verifier)
+	}
+
+	tests := []struct {
+		name     string
+		verifier []byte
+		wantCode int
+	}{
+		{name: "set", verifier: []byte("sealed"), wantCode: http.StatusNoContent},
+		{name: "same again", verifier: []byte("sealed"), wantCode: http.StatusNoContent},
+		{name: "overwrite", verifier: []byte("other"), wantCode: http.StatusConflict},
+		{name: "empty", verifier: nil, wantCode: http.StatusBadRequest},
+		{name: "too large", verifier: bytes.Repeat([]byte("x"), maxVerifierSize+1), wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do("PUT", models.VerifierRequest{Verifier: tt.verifier}); w.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if verifier := get(); string(verifier) != "sealed" {
+		t.Errorf("Expected the first verifier to be kept, got %q", verifier)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/a2sh3r/gophkeeper/internal/apierror"
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type VersionStorage interface {
+	GetDataVersions(ctx context.Context, dataID uuid.UUID) ([]*models.DataVersion, error)
+	GetDataVersion(ctx context.Context, dataID uuid.UUID, version int) (*models.DataVersion, error)
+}
+
+// RegisterVersionRoutes registers the item history routes. Storage keeps a
+// version whenever an item is updated, so restoring one can be undone too.
+func RegisterVersionRoutes(r *mux.Router, versionStorage VersionStorage, dataStorage DataStorage, jwtManager *auth.JWTManager, opts Options) {
+	opts = opts.withDefaults()
+	versions := r.PathPrefix("/api/v1/data/{id}").Subrouter()
+	versions.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.AuthMiddleware(jwtManager)(w, r, next.ServeHTTP)
+		})
+	})
+	versions.HandleFunc("/versions", handleGetDataVersions(versionStorage, dataStorage)).Methods("GET")
+	versions.HandleFunc("/restore/{version}", handleRestoreDataVersion(versionStorage, dataStorage, opts)).Methods("POST")
+}
+
+// handleGetDataVersions returns the previous versions of an item, newest first
+func handleGetDataVersions(versionStorage VersionStorage, dataStorage DataStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		versions, err := versionStorage.GetDataVersions(r.Context(), data.ID)
+		if err != nil {
+			apierror.Error(w, "Failed to get versions", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.DataVersionsResponse{Versions: make([]models.DataVersion, 0, len(versions))}
+		for _, version := range versions {
+			response.Versions = append(response.Versions, *version)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// handleRestoreDataVersion makes a previous version the current one. The
+// version being replaced is kept like on any other update.
+func handleRestoreDataVersion(versionStorage VersionStorage, dataStorage DataStorage, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		number, err := strconv.Atoi(mux.Vars(r)["version"])
+		if err != nil || number < 1 {
+			apierror.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+
+		data := ownedData(w, r, dataStorage)
+		if data == nil {
+			return
+		}
+
+		version, err := versionStorage.GetDataVersion(r.Context(), data.ID, number)
+		if err != nil {
+			if err.Error() == "version not found" {
+				apierror.Error(w, "Version not found", http.StatusNotFound)
+				return
+			}
+			apierror.Error(w, "Failed to get version", http.StatusInternalServerError)
+			return
+		}
+
+		data.Type = version.Type
+		data.Name = version.Name
+		data.Description = version.Description
+		data.Data = version.Data
+		data.Metadata = version.Metadata
+		data.Environment = version.Environment
+		data.Checksum = version.Checksum
+		data.UpdatedAt = opts.Clock.Now()
+
+		if err := dataStorage.UpdateData(r.Context(), data); err != nil {
+			apierror.Error(w, "Failed to update data", http.StatusInternalServerError)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Version restored", zap.String("data_id", data.ID.String()), zap.Int("version", number))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", dataETag(data))
+		if err := json.NewEncoder(w).Encode(models.DataResponse{Data: *data}); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
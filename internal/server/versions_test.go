@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+func TestServer_DataVersions(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, store, store, jwtManager, Options{})
+	RegisterVersionRoutes(router, store, store, jwtManager, Options{})
+
+	register := func(username string) string {
+		w := doRequest(router, "POST", "/api/v1/register", "", models.UserRequest{Username: username, Password: "login-pass", MasterPassword: "master-password"})
+		var authResp models.AuthResponse
+		if err := json.NewDecoder(w.Body).Decode(&authResp); err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+		return authResp.Token
+	}
+	versions := func(path, token string) []models.DataVersion {
+		w := doRequest(router, "GET", path+"/versions", token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp models.DataVersionsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode versions: %v", err)
+		}
+		return resp.Versions
+	}
+
+	owner := register("owner")
+	other := register("other")
+
+	w := doRequest(router, "POST", "/api/v1/data", owner, models.DataRequest{Type: models.DataTypeText, Name: "v1", Data: []byte("first")})
+	var created models.DataResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+	path := "/api/v1/data/" + created.Data.ID.String()
+
+	if got := versions(path, owner); len(got) != 0 {
+		t.Errorf("Expected no versions before an update, got %d", len(got))
+	}
+	for i, name := range []string{"v2", "v3"} {
+		revision := i + 1
+		if w := doRequest(router, "PUT", path, owner, models.DataRequest{Type: models.DataTypeText, Name: name, Data: []byte(name), BaseRevision: &revision}); w.Code != http.StatusOK {
+			t.Fatalf("Failed to update data: %d", w.Code)
+		}
+	}
+
+	got := versions(path, owner)
+	if len(got) != 2 || got[0].Version != 2 || got[0].Name != "v2" || got[1].Version != 1 || got[1].Name != "v1" {
+		t.Fatalf("Expected the replaced versions newest first, got %+v", got)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "unauthenticated", method: "GET", path: path + "/versions", expectedStatus: http.StatusUnauthorized},
+		{name: "other user", method: "GET", path: path + "/versions", token: other, expectedStatus: http.StatusForbidden},
+		{name: "other user restore", method: "POST", path: path + "/restore/1", token: other, expectedStatus: http.StatusForbidden},
+		{name: "invalid version", method: "POST", path: path + "/restore/zero", token: owner, expectedStatus: http.StatusBadRequest},
+		{name: "unknown version", method: "POST", path: path + "/restore/9", token: owner, expectedStatus: http.StatusNotFound},
+		{name: "unknown data", method: "GET", path: "/api/v1/data/00000000-0000-0000-0000-000000000000/versions", token: owner, expectedStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := doRequest(router, tt.method, tt.path, tt.token, nil); w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w = doRequest(router, "POST", path+"/restore/1", owner, nil)
+	var restored models.DataResponse
+	if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&restored) != nil {
+		t.Fatalf("Expected the restore to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if restored.Data.Name != "v1" || string(restored.Data.Data) != "first" {
+		t.Errorf("Expected version 1 to be current, got %+v", restored.Data)
+	}
+	if got := versions(path, owner); len(got) != 3 || got[0].Name != "v3" {
+		t.Errorf("Expected the restore to keep the replaced version, got %+v", got)
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// WebUIPath is where the web interface is served
+const WebUIPath = "/ui/"
+
+// webUIPolicy only lets the page run its own scripts and talk to this server,
+// so nothing injected into it can read the decrypted vault
+const webUIPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; connect-src 'self'; img-src 'self' blob:; " +
+	"form-action 'none'; base-uri 'none'; frame-ancestors 'none'"
+
+// RegisterWebUIRoutes serves the web interface from files under /ui. Like
+// the command-line client it encrypts in the browser, so the server only ever
+// sees ciphertext.
+func RegisterWebUIRoutes(r *mux.Router, files fs.FS) {
+	r.Handle("/ui", http.RedirectHandler(WebUIPath, http.StatusMovedPermanently)).Methods("GET", "HEAD")
+	fileServer := http.StripPrefix(WebUIPath, http.FileServer(http.FS(files)))
+	r.PathPrefix(WebUIPath).Methods("GET", "HEAD").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("Content-Security-Policy", webUIPolicy)
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "no-referrer")
+		// revalidated on every load, so an upgraded server serves its own scripts
+		header.Set("Cache-Control", "no-cache")
+		fileServer.ServeHTTP(w, r)
+	}))
+}
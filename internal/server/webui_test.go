@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/assets"
+	"github.com/gorilla/mux"
+)
+
+func TestRegisterWebUIRoutes(t *testing.T) {
+	router := mux.NewRouter()
+	RegisterWebUIRoutes(router, assets.WebUI())
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantStatus  int
+		wantType    string
+		wantContent string
+	}{
+		{name: "redirect", method: "GET", path: "/ui", wantStatus: http.StatusMovedPermanently},
+		{name: "index", method: "GET", path: "/ui/", wantStatus: http.StatusOK, wantType: "text/html", wantContent: `src="app.js"`},
+		{name: "script", method: "GET", path: "/ui/app.js", wantStatus: http.StatusOK, wantType: "javascript", wantContent: "PBKDF2"},
+		{name: "stylesheet", method: "GET", path: "/ui/style.css", wantStatus: http.StatusOK, wantType: "text/css"},
+		{name: "missing", method: "GET", path: "/ui/missing.js", wantStatus: http.StatusNotFound},
+		{name: "write", method: "POST", path: "/ui/", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if !strings.Contains(w.Header().Get("Content-Type"), tt.wantType) {
+				t.Errorf("Expected a %s content type, got %q", tt.wantType, w.Header().Get("Content-Type"))
+			}
+			if !strings.Contains(w.Body.String(), tt.wantContent) {
+				t.Errorf("Expected the body to contain %q", tt.wantContent)
+			}
+			if policy := w.Header().Get("Content-Security-Policy"); !strings.Contains(policy, "script-src 'self'") ||
+				!strings.Contains(policy, "frame-ancestors 'none'") {
+				t.Errorf("Expected a strict content security policy, got %q", policy)
+			}
+		})
+	}
+}
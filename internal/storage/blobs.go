@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNoBlobStorage is returned for object operations without a blob store
+	ErrNoBlobStorage = errors.New("blob storage is not configured")
+	// ErrChunkInline is returned when listing the object keys of chunks of
+	// which some are stored in the database
+	ErrChunkInline = errors.New("chunk is stored in the database")
+)
+
+// BlobStore stores large ciphertexts as objects outside the database, such
+// as in an S3-compatible bucket
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// SetBlobs stores the content chunks of binary items written from now on as
+// objects of b, keeping only their keys in the database. Chunks stored before
+// stay readable from the database. Call it before use.
+func (s *PostgresStorage) SetBlobs(b BlobStore) {
+	s.blobs = b
+}
+
+// newChunkObjectKey returns a key no other object has, so a chunk is only
+// replaced once the row referencing its new object is committed
+func newChunkObjectKey(dataID uuid.UUID, index int) string {
+	return fmt.Sprintf("chunks/%s/%d/%s", dataID, index, uuid.NewString())
+}
+
+// putChunkObject stores chunk as a new object, sealed at rest like chunks in
+// the database, and returns its key
+func (s *PostgresStorage) putChunkObject(ctx context.Context, dataID uuid.UUID, index int, chunk []byte) (string, error) {
+	sealed, err := s.seal(ctx, chunk)
+	if err != nil {
+		return "", err
+	}
+	key := newChunkObjectKey(dataID, index)
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(sealed), int64(len(sealed))); err != nil {
+		logger.FromContext(ctx).Error("Failed to store chunk object", zap.Error(err),
+			zap.String("data_id", dataID.String()), zap.Int("index", index))
+		return "", fmt.Errorf("failed to store chunk object: %w", err)
+	}
+	return key, nil
+}
+
+// getChunkObject reads and opens the chunk stored as the object of key
+func (s *PostgresStorage) getChunkObject(ctx context.Context, key string) ([]byte, error) {
+	if s.blobs == nil {
+		return nil, fmt.Errorf("chunk is stored as an object: %w", ErrNoBlobStorage)
+	}
+	body, err := s.blobs.Get(ctx, key)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get chunk object", zap.Error(err), zap.String("key", key))
+		return nil, fmt.Errorf("failed to get chunk object: %w", err)
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	chunk, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk object: %w", err)
+	}
+	return s.open(ctx, chunk)
+}
+
+// discardObjects deletes objects that no committed row references, such as
+// those uploaded for a write that failed
+func (s *PostgresStorage) discardObjects(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := s.blobs.Delete(context.WithoutCancel(ctx), key); err != nil {
+			logger.FromContext(ctx).Warn("Failed to delete unused object", zap.Error(err), zap.String("key", key))
+		}
+	}
+}
+
+// ReserveDataChunk records that the content chunk of existing data at index
+// is the object of a new key, for the client to upload to, and returns the
+// key. The chunk it replaces is deleted. Chunks are stored in order as with
+// PutDataChunk. The client uploads the object itself, so it is not sealed at
+// rest; servers sealing chunks do not offer presigned uploads.
+func (s *PostgresStorage) ReserveDataChunk(ctx context.Context, dataID uuid.UUID, index int) (string, error) {
+	if s.blobs == nil {
+		return "", ErrNoBlobStorage
+	}
+	if err := s.checkChunkData(ctx, dataID); err != nil {
+		return "", err
+	}
+	key := newChunkObjectKey(dataID, index)
+	if err := s.upsertDataChunk(ctx, dataID, index, "object_key", key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// GetDataChunkKeys returns the object keys of the content chunks of data in
+// order, or ErrChunkInline if any of them is stored in the database
+func (s *PostgresStorage) GetDataChunkKeys(ctx context.Context, dataID uuid.UUID) ([]string, error) {
+	rows, err := s.q(ctx).QueryContext(ctx, `SELECT object_key FROM data_chunks WHERE data_id = $1 ORDER BY chunk_index`, dataID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get chunk object keys", zap.Error(err), zap.String("data_id", dataID.String()))
+		return nil, fmt.Errorf("failed to get chunk object keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key sql.NullString
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk object key: %w", err)
+		}
+		if !key.Valid {
+			return nil, ErrChunkInline
+		}
+		keys = append(keys, key.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get chunk object keys: %w", err)
+	}
+	return keys, nil
+}
+
+// SweepBlobs deletes up to limit objects of chunks that were deleted or
+// replaced, which the database queues in the transaction removing them, and
+// returns how many it deleted
+func (s *PostgresStorage) SweepBlobs(ctx context.Context, limit int) (int, error) {
+	if s.blobs == nil {
+		return 0, nil
+	}
+	rows, err := s.q(ctx).QueryContext(ctx, `SELECT object_key FROM blob_deletions ORDER BY queued_at LIMIT $1`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queued object deletions: %w", err)
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan queued object deletion: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get queued object deletions: %w", err)
+	}
+
+	deleted := 0
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			return deleted, fmt.Errorf("failed to delete object: %w", err)
+		}
+		if _, err := s.q(ctx).ExecContext(ctx, `DELETE FROM blob_deletions WHERE object_key = $1`, key); err != nil {
+			return deleted, fmt.Errorf("failed to dequeue object deletion: %w", err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/a2sh3r/gophkeeper/internal/atrest"
+	"github.com/google/uuid"
+)
+
+// memoryBlobs is a BlobStore in memory
+type memoryBlobs struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryBlobs() *memoryBlobs {
+	return &memoryBlobs{objects: make(map[string][]byte)}
+}
+
+func (m *memoryBlobs) Put(_ context.Context, key string, body io.Reader, _ int64) error {
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.objects[key] = content
+	return nil
+}
+
+func (m *memoryBlobs) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	content, ok := m.objects[key]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (m *memoryBlobs) Delete(_ context.Context, key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func TestPostgresStorage_PutDataChunk_Blobs(t *testing.T) {
+	dataID := uuid.New()
+
+	t.Run("stored as an object", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer db.Close()
+		blobs := newMemoryBlobs()
+		storage := NewPostgresStorage(db)
+		storage.SetBlobs(blobs)
+
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(dataID).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO data_chunks \\(data_id, chunk_index, object_key\\).*SET object_key = EXCLUDED.object_key, data = NULL").
+			WithArgs(dataID, 0, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		if err := storage.PutDataChunk(context.Background(), dataID, 0, []byte("sealed")); err != nil {
+			t.Fatalf("PutDataChunk() error = %v", err)
+		}
+		if len(blobs.objects) != 1 {
+			t.Fatalf("Expected 1 object, got %d", len(blobs.objects))
+		}
+		for _, content := range blobs.objects {
+			if string(content) != "sealed" {
+				t.Errorf("Unexpected object content %q", content)
+			}
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("sealed at rest", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer db.Close()
+		wrapper, err := atrest.NewLocalKey(bytes.Repeat([]byte{1}, 32))
+		if err != nil {
+			t.Fatalf("NewLocalKey() error = %v", err)
+		}
+		blobs := newMemoryBlobs()
+		storage := NewPostgresStorage(db)
+		storage.SetEnvelope(atrest.NewEnvelope(wrapper))
+		storage.SetBlobs(blobs)
+
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(dataID).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO data_chunks").WithArgs(dataID, 0, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		if err := storage.PutDataChunk(context.Background(), dataID, 0, []byte("client ciphertext")); err != nil {
+			t.Fatalf("PutDataChunk() error = %v", err)
+		}
+		if len(blobs.objects) != 1 {
+			t.Fatalf("Expected 1 object, got %d", len(blobs.objects))
+		}
+		var key string
+		for k := range blobs.objects {
+			key = k
+		}
+		if object := blobs.objects[key]; !atrest.IsSealed(object) || bytes.Contains(object, []byte("client ciphertext")) {
+			t.Fatalf("Expected the object to be sealed, got %q", object)
+		}
+
+		mock.ExpectQuery("SELECT data, object_key FROM data_chunks").WithArgs(dataID, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"data", "object_key"}).AddRow(nil, key))
+		chunk, err := storage.GetDataChunk(context.Background(), dataID, 0)
+		if err != nil || string(chunk) != "client ciphertext" {
+			t.Errorf("GetDataChunk() = %q, %v", chunk, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("out of order", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer db.Close()
+		blobs := newMemoryBlobs()
+		storage := NewPostgresStorage(db)
+		storage.SetBlobs(blobs)
+
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(dataID).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO data_chunks").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		if err := storage.PutDataChunk(context.Background(), dataID, 3, []byte("sealed")); err != ErrChunkOutOfOrder {
+			t.Fatalf("PutDataChunk() error = %v, want %v", err, ErrChunkOutOfOrder)
+		}
+		if len(blobs.objects) != 0 {
+			t.Errorf("Expected the object of the refused chunk deleted, %d left", len(blobs.objects))
+		}
+	})
+}
+
+func TestPostgresStorage_GetDataChunk_Blobs(t *testing.T) {
+	dataID := uuid.New()
+	db, mock := setupMockDB(t)
+	defer db.Close()
+	blobs := newMemoryBlobs()
+	blobs.objects["chunks/a"] = []byte("from object")
+	storage := NewPostgresStorage(db)
+
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"data", "object_key"}).AddRow(nil, "chunks/a")
+	}
+	mock.ExpectQuery("SELECT data, object_key FROM data_chunks").WithArgs(dataID, 0).WillReturnRows(rows())
+	if _, err := storage.GetDataChunk(context.Background(), dataID, 0); !errors.Is(err, ErrNoBlobStorage) {
+		t.Errorf("GetDataChunk() without blob storage error = %v, want ErrNoBlobStorage", err)
+	}
+
+	storage.SetBlobs(blobs)
+	mock.ExpectQuery("SELECT data, object_key FROM data_chunks").WithArgs(dataID, 0).WillReturnRows(rows())
+	chunk, err := storage.GetDataChunk(context.Background(), dataID, 0)
+	if err != nil || string(chunk) != "from object" {
+		t.Errorf("GetDataChunk() = %q, %v", chunk, err)
+	}
+}
+
+func TestPostgresStorage_ReserveDataChunk(t *testing.T) {
+	dataID := uuid.New()
+	db, mock := setupMockDB(t)
+	defer db.Close()
+	storage := NewPostgresStorage(db)
+
+	if _, err := storage.ReserveDataChunk(context.Background(), dataID, 0); err != ErrNoBlobStorage {
+		t.Fatalf("ReserveDataChunk() without blob storage error = %v, want ErrNoBlobStorage", err)
+	}
+
+	storage.SetBlobs(newMemoryBlobs())
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(dataID).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO data_chunks \\(data_id, chunk_index, object_key\\)").
+		WithArgs(dataID, 1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	key, err := storage.ReserveDataChunk(context.Background(), dataID, 1)
+	if err != nil {
+		t.Fatalf("ReserveDataChunk() error = %v", err)
+	}
+	if !bytes.HasPrefix([]byte(key), []byte("chunks/"+dataID.String()+"/1/")) {
+		t.Errorf("Unexpected object key %s", key)
+	}
+
+	mock.ExpectQuery("SELECT object_key FROM data_chunks").WithArgs(dataID).
+		WillReturnRows(sqlmock.NewRows([]string{"object_key"}).AddRow("chunks/a").AddRow("chunks/b"))
+	keys, err := storage.GetDataChunkKeys(context.Background(), dataID)
+	if err != nil || len(keys) != 2 || keys[1] != "chunks/b" {
+		t.Errorf("GetDataChunkKeys() = %v, %v", keys, err)
+	}
+	mock.ExpectQuery("SELECT object_key FROM data_chunks").WithArgs(dataID).
+		WillReturnRows(sqlmock.NewRows([]string{"object_key"}).AddRow("chunks/a").AddRow(nil))
+	if _, err := storage.GetDataChunkKeys(context.Background(), dataID); err != ErrChunkInline {
+		t.Errorf("GetDataChunkKeys() error = %v, want %v", err, ErrChunkInline)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorage_SweepBlobs(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+	blobs := newMemoryBlobs()
+	blobs.objects["chunks/old"] = []byte("x")
+	blobs.objects["chunks/kept"] = []byte("y")
+	storage := NewPostgresStorage(db)
+	storage.SetBlobs(blobs)
+
+	mock.ExpectQuery("SELECT object_key FROM blob_deletions").WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"object_key"}).AddRow("chunks/old"))
+	mock.ExpectExec("DELETE FROM blob_deletions").WithArgs("chunks/old").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	deleted, err := storage.SweepBlobs(context.Background(), 100)
+	if err != nil || deleted != 1 {
+		t.Fatalf("SweepBlobs() = %d, %v, want 1", deleted, err)
+	}
+	if _, ok := blobs.objects["chunks/old"]; ok {
+		t.Error("Expected the queued object deleted")
+	}
+	if _, ok := blobs.objects["chunks/kept"]; !ok {
+		t.Error("Expected other objects kept")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotEmpty is returned when restoring into a database that holds users
+var ErrNotEmpty = errors.New("database is not empty")
+
+// DumpTables are the tables of a dump, in the order rows are restored so
+// that every row comes after the rows it references
+var DumpTables = []string{
+	"users",
+	"collections",
+	"data",
+	"data_fields",
+	"key_escrow",
+	"data_comments",
+	"audit_events",
+	"data_chunks",
+	"data_versions",
+	"shares",
+	"sessions",
+	"identities",
+	"data_attachments",
+	"blob_deletions",
+}
+
+// dumpQueries select the rows of tables that reference themselves in
+// restore order
+var dumpQueries = map[string]string{
+	// parents before their children
+	"collections": `WITH RECURSIVE depth (id, level) AS (
+		SELECT id, 0 FROM collections WHERE parent_id IS NULL
+		UNION ALL
+		SELECT c.id, d.level + 1 FROM collections c JOIN depth d ON c.parent_id = d.id
+	)
+	SELECT row_to_json(t) FROM collections t JOIN depth d ON d.id = t.id ORDER BY d.level`,
+}
+
+// Dumper is implemented by storages that can copy out and restore every row,
+// e.g. for backups
+type Dumper interface {
+	Dump(ctx context.Context, fn func(table string, row json.RawMessage) error) error
+	Restore(ctx context.Context, replace bool, read func(insert func(table string, row json.RawMessage) error) error) error
+}
+
+// Dump calls fn with every row of DumpTables as JSON, from one consistent
+// snapshot. Item data sealed at rest stays sealed.
+func (s *PostgresStorage) Dump(ctx context.Context, fn func(table string, row json.RawMessage) error) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin dump: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, table := range DumpTables {
+		query, ok := dumpQueries[table]
+		if !ok {
+			query = `SELECT row_to_json(t) FROM ` + table + ` t`
+		}
+		if err := dumpRows(ctx, tx, table, query, fn); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func dumpRows(ctx context.Context, tx *sql.Tx, table, query string, fn func(table string, row json.RawMessage) error) error {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to dump %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return fmt.Errorf("failed to dump %s: %w", table, err)
+		}
+		if err := fn(table, row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to dump %s: %w", table, err)
+	}
+	return nil
+}
+
+// Restore inserts the rows read passes to insert, in one transaction. It
+// fails with ErrNotEmpty if there are users already, unless replace is set,
+// which deletes every row of DumpTables first.
+func (s *PostgresStorage) Restore(ctx context.Context, replace bool, read func(insert func(table string, row json.RawMessage) error) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin restore: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if replace {
+		if _, err := tx.ExecContext(ctx, `TRUNCATE `+strings.Join(DumpTables, ", ")+` CASCADE`); err != nil {
+			return fmt.Errorf("failed to clear tables: %w", err)
+		}
+	} else {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users)`).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check for users: %w", err)
+		}
+		if exists {
+			return ErrNotEmpty
+		}
+	}
+
+	known := make(map[string]bool, len(DumpTables))
+	for _, table := range DumpTables {
+		known[table] = true
+	}
+	err = read(func(table string, row json.RawMessage) error {
+		if !known[table] {
+			return fmt.Errorf("unknown table %q", table)
+		}
+		// the table is its own row type, so the columns line up with SELECT *
+		query := `INSERT INTO ` + table + ` SELECT * FROM json_populate_record(NULL::` + table + `, $1::json)`
+		if _, err := tx.ExecContext(ctx, query, string(row)); err != nil {
+			return fmt.Errorf("failed to restore a row of %s: %w", table, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return nil
+}
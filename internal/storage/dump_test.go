@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/a2sh3r/gophkeeper/internal/db/migrations"
+)
+
+// a table added by a migration but not dumped would be lost on restore
+func TestDumpTables_CoverMigrations(t *testing.T) {
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatalf("migrations.All() error = %v", err)
+	}
+	dumped := make(map[string]bool)
+	for _, table := range DumpTables {
+		dumped[table] = true
+	}
+	createTable := regexp.MustCompile(`(?i)CREATE TABLE (?:IF NOT EXISTS )?(\w+)`)
+	for _, migration := range all {
+		for _, match := range createTable.FindAllStringSubmatch(migration.Up, -1) {
+			if !dumped[match[1]] {
+				t.Errorf("Table %s of migration %d is not in DumpTables", match[1], migration.Version)
+			}
+		}
+	}
+}
+
+func TestPostgresStorage_Dump(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+	s := NewPostgresStorage(db)
+
+	mock.ExpectBegin()
+	for _, table := range DumpTables {
+		rows := sqlmock.NewRows([]string{"row_to_json"})
+		if table == "users" {
+			rows.AddRow(`{"id":"a","username":"alice"}`)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(`row_to_json(t) FROM ` + table + ` t`)).WillReturnRows(rows)
+	}
+	mock.ExpectCommit()
+
+	var dumped []string
+	err := s.Dump(context.Background(), func(table string, row json.RawMessage) error {
+		dumped = append(dumped, table+" "+string(row))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+	if len(dumped) != 1 || dumped[0] != `users {"id":"a","username":"alice"}` {
+		t.Errorf("Dump() rows = %v", dumped)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorage_Restore(t *testing.T) {
+	row := json.RawMessage(`{"id":"a","username":"alice"}`)
+	read := func(insert func(table string, row json.RawMessage) error) error {
+		return insert("users", row)
+	}
+
+	t.Run("empty database", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users SELECT * FROM json_populate_record(NULL::users, $1::json)`)).
+			WithArgs(string(row)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := NewPostgresStorage(db).Restore(context.Background(), false, read); err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("database with users", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		if err := NewPostgresStorage(db).Restore(context.Background(), false, read); !errors.Is(err, ErrNotEmpty) {
+			t.Fatalf("Restore() error = %v, want ErrNotEmpty", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(`TRUNCATE users, collections, data, .* CASCADE`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := NewPostgresStorage(db).Restore(context.Background(), true, read); err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("unknown table", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectRollback()
+
+		err := NewPostgresStorage(db).Restore(context.Background(), false, func(insert func(string, json.RawMessage) error) error {
+			return insert("pg_authid", row)
+		})
+		if err == nil {
+			t.Fatal("Expected an error for a table outside the dump")
+		}
+	})
+}
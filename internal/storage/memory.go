@@ -1,35 +1,109 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/a2sh3r/gophkeeper/internal/clock"
 	"github.com/a2sh3r/gophkeeper/internal/models"
 	"github.com/google/uuid"
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
-	ErrUserExists   = errors.New("user already exists")
-	ErrDataNotFound = errors.New("data not found")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = errors.New("user already exists")
+	ErrDataNotFound       = errors.New("data not found")
+	ErrSaltAlreadySet     = errors.New("salt already set")
+	ErrFieldNotFound      = errors.New("field not found")
+	ErrEscrowNotFound     = errors.New("escrow not found")
+	ErrChunkNotFound      = errors.New("chunk not found")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrVersionNotFound    = errors.New("version not found")
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrShareNotFound      = errors.New("share not found")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrIdentityNotFound   = errors.New("identity not found")
+	// ErrIdentityExists is returned when linking an external account that is already linked
+	ErrIdentityExists = errors.New("identity already linked")
+	// ErrVerifierAlreadySet is returned when replacing the master password verifier outside a rotation
+	ErrVerifierAlreadySet = errors.New("verifier already set")
+	// ErrShareKeyAlreadySet is returned when replacing a share public key items may already be sealed to
+	ErrShareKeyAlreadySet = errors.New("share key already set")
+	// ErrChunkOutOfOrder is returned for a chunk stored past the end of the chunks so far
+	ErrChunkOutOfOrder = errors.New("chunk out of order")
+	// ErrRotationConflict is returned when an item changed since it was re-encrypted for a rotation
+	ErrRotationConflict = errors.New("vault changed during rotation")
+	// ErrRotationMismatch is returned when rotation records name ciphertexts
+	// not in the vault, name one twice or leave one out
+	ErrRotationMismatch = errors.New("rotation does not match the vault")
+	// ErrBatchConflict is returned when an item a batch updates or deletes
+	// changed since the batch was checked; no change of the batch is applied
+	ErrBatchConflict = errors.New("data changed during batch")
 )
 
 // MemoryStorage implements in-memory storage
 type MemoryStorage struct {
-	users map[string]*models.User
-	data  map[uuid.UUID]*models.Data
-	mutex sync.RWMutex
+	users  map[string]*models.User
+	data   map[uuid.UUID]*models.Data
+	fields map[uuid.UUID]map[string]*models.DataField
+	// comments are kept in insertion order, which is chronological
+	comments map[uuid.UUID][]*models.DataComment
+	chunks   map[uuid.UUID][][]byte
+	// attachments are kept in insertion order, which is chronological
+	attachments map[uuid.UUID][]*models.DataAttachment
+	// versions are kept oldest first
+	versions    map[uuid.UUID][]*models.DataVersion
+	escrow      map[uuid.UUID]*models.KeyEscrow
+	collections map[uuid.UUID]*models.Collection
+	hints       map[uuid.UUID]string
+	verifiers   map[uuid.UUID][]byte
+	shareKeys   map[uuid.UUID]*models.ShareKeys
+	shares      map[uuid.UUID]*models.Share
+	sessions    map[uuid.UUID]*models.Session
+	// identities are keyed by issuer and subject, see identityKey
+	identities map[string]*models.Identity
+	// audit events are kept in insertion order, which is chronological
+	audit     map[uuid.UUID][]*models.AuditEvent
+	auditKeys map[uuid.UUID][]byte
+	clock     clock.Clock
+	mutex     sync.RWMutex
 }
 
 // NewMemoryStorage creates new in-memory storage
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		users: make(map[string]*models.User),
-		data:  make(map[uuid.UUID]*models.Data),
+		users:       make(map[string]*models.User),
+		data:        make(map[uuid.UUID]*models.Data),
+		fields:      make(map[uuid.UUID]map[string]*models.DataField),
+		comments:    make(map[uuid.UUID][]*models.DataComment),
+		chunks:      make(map[uuid.UUID][][]byte),
+		attachments: make(map[uuid.UUID][]*models.DataAttachment),
+		versions:    make(map[uuid.UUID][]*models.DataVersion),
+		escrow:      make(map[uuid.UUID]*models.KeyEscrow),
+		collections: make(map[uuid.UUID]*models.Collection),
+		hints:       make(map[uuid.UUID]string),
+		verifiers:   make(map[uuid.UUID][]byte),
+		shareKeys:   make(map[uuid.UUID]*models.ShareKeys),
+		shares:      make(map[uuid.UUID]*models.Share),
+		sessions:    make(map[uuid.UUID]*models.Session),
+		identities:  make(map[string]*models.Identity),
+		audit:       make(map[uuid.UUID][]*models.AuditEvent),
+		auditKeys:   make(map[uuid.UUID][]byte),
+		clock:       clock.System{},
 	}
 }
 
+// SetClock sets the clock stamping update times. Call it before use.
+func (s *MemoryStorage) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
 // CreateUser creates new user
 func (s *MemoryStorage) CreateUser(ctx context.Context, user *models.User) error {
 	s.mutex.Lock()
@@ -70,15 +144,43 @@ func (s *MemoryStorage) GetUserByID(ctx context.Context, userID uuid.UUID) (*mod
 	return nil, ErrUserNotFound
 }
 
-// CreateData creates new data
+// SetUserSalt sets user's salt once; re-sending the same salt is a no-op
+func (s *MemoryStorage) SetUserSalt(ctx context.Context, userID uuid.UUID, salt string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, user := range s.users {
+		if user.ID != userID {
+			continue
+		}
+		if user.Salt != "" {
+			if user.Salt == salt {
+				return nil
+			}
+			return ErrSaltAlreadySet
+		}
+		user.Salt = salt
+		user.UpdatedAt = s.clock.Now()
+		return nil
+	}
+
+	return ErrUserNotFound
+}
+
+// CreateData creates new data at revision 1
 func (s *MemoryStorage) CreateData(ctx context.Context, data *models.Data) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.data[data.ID] = data
+	s.createData(data)
 	return nil
 }
 
+func (s *MemoryStorage) createData(data *models.Data) {
+	data.Revision = 1
+	s.data[data.ID] = data
+}
+
 // GetDataByID gets data by ID
 func (s *MemoryStorage) GetDataByID(ctx context.Context, dataID uuid.UUID) (*models.Data, error) {
 	s.mutex.RLock()
@@ -89,7 +191,9 @@ func (s *MemoryStorage) GetDataByID(ctx context.Context, dataID uuid.UUID) (*mod
 		return nil, ErrDataNotFound
 	}
 
-	return data, nil
+	// a copy, so changes made before UpdateData do not reach the stored version
+	copied := *data
+	return &copied, nil
 }
 
 // GetDataByUserID gets all user data
@@ -104,20 +208,70 @@ func (s *MemoryStorage) GetDataByUserID(ctx context.Context, userID uuid.UUID) (
 		}
 	}
 
+	// Same order as the PostgreSQL storage: newest first, ties broken by ID
+	sort.Slice(userData, func(i, j int) bool {
+		if !userData[i].CreatedAt.Equal(userData[j].CreatedAt) {
+			return userData[i].CreatedAt.After(userData[j].CreatedAt)
+		}
+		return bytes.Compare(userData[i].ID[:], userData[j].ID[:]) > 0
+	})
+
 	return userData, nil
 }
 
-// UpdateData updates data
+// GetDataByUserIDFiltered gets the page of user data matching the filter, in
+// the order of GetDataByUserID
+func (s *MemoryStorage) GetDataByUserIDFiltered(ctx context.Context, userID uuid.UUID, filter models.DataFilter) ([]*models.Data, error) {
+	userData, err := s.GetDataByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []*models.Data
+	for _, data := range userData {
+		if filter.Matches(data) {
+			matching = append(matching, data)
+		}
+	}
+
+	if filter.Offset >= len(matching) {
+		return nil, nil
+	}
+	matching = matching[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(matching) {
+		matching = matching[:filter.Limit]
+	}
+	return matching, nil
+}
+
+// UpdateData updates data, keeping the replaced version, and advances its revision
 func (s *MemoryStorage) UpdateData(ctx context.Context, data *models.Data) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if _, exists := s.data[data.ID]; !exists {
+	previous, exists := s.data[data.ID]
+	if !exists {
 		return ErrDataNotFound
 	}
+	s.updateData(previous, data)
+	return nil
+}
 
+func (s *MemoryStorage) updateData(previous, data *models.Data) {
+	s.versions[data.ID] = append(s.versions[data.ID], &models.DataVersion{
+		DataID:      previous.ID,
+		Version:     len(s.versions[data.ID]) + 1,
+		Type:        previous.Type,
+		Name:        previous.Name,
+		Description: previous.Description,
+		Data:        previous.Data,
+		Metadata:    previous.Metadata,
+		Environment: previous.Environment,
+		UpdatedAt:   previous.UpdatedAt,
+		Checksum:    previous.Checksum,
+	})
+	data.Revision = previous.Revision + 1
 	s.data[data.ID] = data
-	return nil
 }
 
 // DeleteData deletes data
@@ -128,7 +282,934 @@ func (s *MemoryStorage) DeleteData(ctx context.Context, dataID uuid.UUID) error
 	if _, exists := s.data[dataID]; !exists {
 		return ErrDataNotFound
 	}
+	s.deleteData(dataID)
+	return nil
+}
 
+func (s *MemoryStorage) deleteData(dataID uuid.UUID) {
 	delete(s.data, dataID)
+	delete(s.fields, dataID)
+	delete(s.comments, dataID)
+	delete(s.chunks, dataID)
+	delete(s.attachments, dataID)
+	delete(s.versions, dataID)
+	for id, share := range s.shares {
+		if share.DataID == dataID {
+			delete(s.shares, id)
+		}
+	}
+}
+
+// ApplyDataBatch applies the changes of a batch in order, all of them or none
+func (s *MemoryStorage) ApplyDataBatch(ctx context.Context, changes []models.DataChange) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, change := range changes {
+		if change.Op == models.BatchCreate {
+			continue
+		}
+		current, exists := s.data[change.Data.ID]
+		if !exists || (change.Op == models.BatchUpdate && current.Revision != change.BaseRevision) {
+			return ErrBatchConflict
+		}
+	}
+
+	for _, change := range changes {
+		switch change.Op {
+		case models.BatchCreate:
+			s.createData(change.Data)
+		case models.BatchUpdate:
+			s.updateData(s.data[change.Data.ID], change.Data)
+		case models.BatchDelete:
+			s.deleteData(change.Data.ID)
+		}
+	}
+	return nil
+}
+
+// FlagExpiringData updates the expiry flags of the items with an expiry, as
+// of now with a warning period, and returns the items whose flag changed
+func (s *MemoryStorage) FlagExpiringData(ctx context.Context, now time.Time, warning time.Duration) ([]*models.Data, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var flagged []*models.Data
+	for id, data := range s.data {
+		status := models.ExpiryStatus(data.ExpiresAt, now, warning)
+		if data.ExpiresAt == nil || status == data.Expiry {
+			continue
+		}
+		// a copy, as listed items share the stored version
+		changed := *data
+		changed.Expiry = status
+		s.data[id] = &changed
+		copied := changed
+		flagged = append(flagged, &copied)
+	}
+	return flagged, nil
+}
+
+// SetDataField creates or replaces a published field of existing data
+func (s *MemoryStorage) SetDataField(ctx context.Context, field *models.DataField) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.data[field.DataID]; !exists {
+		return ErrDataNotFound
+	}
+
+	if existing, exists := s.fields[field.DataID][field.Name]; exists {
+		field.CreatedAt = existing.CreatedAt
+	}
+	if s.fields[field.DataID] == nil {
+		s.fields[field.DataID] = make(map[string]*models.DataField)
+	}
+	s.fields[field.DataID][field.Name] = field
+	return nil
+}
+
+// GetDataField gets a published field
+func (s *MemoryStorage) GetDataField(ctx context.Context, dataID uuid.UUID, name string) (*models.DataField, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	field, exists := s.fields[dataID][name]
+	if !exists {
+		return nil, ErrFieldNotFound
+	}
+
+	return field, nil
+}
+
+// AddDataComment appends a comment to existing data
+func (s *MemoryStorage) AddDataComment(ctx context.Context, comment *models.DataComment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.data[comment.DataID]; !exists {
+		return ErrDataNotFound
+	}
+
+	s.comments[comment.DataID] = append(s.comments[comment.DataID], comment)
+	return nil
+}
+
+// GetDataComments gets the comments of data, oldest first
+func (s *MemoryStorage) GetDataComments(ctx context.Context, dataID uuid.UUID) ([]*models.DataComment, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	comments := make([]*models.DataComment, len(s.comments[dataID]))
+	copy(comments, s.comments[dataID])
+	return comments, nil
+}
+
+// AddDataAttachment attaches a file to existing data
+func (s *MemoryStorage) AddDataAttachment(ctx context.Context, attachment *models.DataAttachment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.data[attachment.DataID]; !exists {
+		return ErrDataNotFound
+	}
+
+	s.attachments[attachment.DataID] = append(s.attachments[attachment.DataID], attachment)
+	return nil
+}
+
+// GetDataAttachments gets the attachments of data without their content, oldest first
+func (s *MemoryStorage) GetDataAttachments(ctx context.Context, dataID uuid.UUID) ([]*models.DataAttachment, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	attachments := make([]*models.DataAttachment, 0, len(s.attachments[dataID]))
+	for _, attachment := range s.attachments[dataID] {
+		listed := *attachment
+		listed.Content = nil
+		attachments = append(attachments, &listed)
+	}
+	return attachments, nil
+}
+
+// GetDataAttachment gets an attachment of data with its content
+func (s *MemoryStorage) GetDataAttachment(ctx context.Context, dataID, attachmentID uuid.UUID) (*models.DataAttachment, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, attachment := range s.attachments[dataID] {
+		if attachment.ID == attachmentID {
+			return attachment, nil
+		}
+	}
+	return nil, ErrAttachmentNotFound
+}
+
+// DeleteDataAttachment deletes an attachment of data
+func (s *MemoryStorage) DeleteDataAttachment(ctx context.Context, dataID, attachmentID uuid.UUID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attachments := s.attachments[dataID]
+	for i, attachment := range attachments {
+		if attachment.ID == attachmentID {
+			s.attachments[dataID] = append(attachments[:i:i], attachments[i+1:]...)
+			return nil
+		}
+	}
+	return ErrAttachmentNotFound
+}
+
+// GetDataVersions gets the previous versions of data, newest first
+func (s *MemoryStorage) GetDataVersions(ctx context.Context, dataID uuid.UUID) ([]*models.DataVersion, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	versions := make([]*models.DataVersion, 0, len(s.versions[dataID]))
+	for i := len(s.versions[dataID]) - 1; i >= 0; i-- {
+		versions = append(versions, s.versions[dataID][i])
+	}
+	return versions, nil
+}
+
+// GetDataVersion gets a previous version of data
+func (s *MemoryStorage) GetDataVersion(ctx context.Context, dataID uuid.UUID, version int) (*models.DataVersion, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if version < 1 || version > len(s.versions[dataID]) {
+		return nil, ErrVersionNotFound
+	}
+	return s.versions[dataID][version-1], nil
+}
+
+// CreateCollection creates a collection
+func (s *MemoryStorage) CreateCollection(ctx context.Context, collection *models.Collection) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.collections[collection.ID] = collection
+	return nil
+}
+
+// GetCollectionsByUserID gets all collections of a user, sorted by name
+func (s *MemoryStorage) GetCollectionsByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Collection, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var collections []*models.Collection
+	for _, collection := range s.collections {
+		if collection.UserID == userID {
+			copied := *collection
+			collections = append(collections, &copied)
+		}
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		if collections[i].Name != collections[j].Name {
+			return collections[i].Name < collections[j].Name
+		}
+		return bytes.Compare(collections[i].ID[:], collections[j].ID[:]) < 0
+	})
+	return collections, nil
+}
+
+// UpdateCollection renames or moves a collection
+func (s *MemoryStorage) UpdateCollection(ctx context.Context, collection *models.Collection) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.collections[collection.ID]; !exists {
+		return ErrCollectionNotFound
+	}
+	s.collections[collection.ID] = collection
+	return nil
+}
+
+// DeleteCollection deletes a collection; items filed in it move to the top level
+func (s *MemoryStorage) DeleteCollection(ctx context.Context, collectionID uuid.UUID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.collections[collectionID]; !exists {
+		return ErrCollectionNotFound
+	}
+	delete(s.collections, collectionID)
+	for id, data := range s.data {
+		if data.CollectionID != nil && *data.CollectionID == collectionID {
+			moved := *data
+			moved.CollectionID = nil
+			s.data[id] = &moved
+		}
+	}
+	return nil
+}
+
+// SetDataCollection files data in a collection, or at the top level when
+// collectionID is nil
+func (s *MemoryStorage) SetDataCollection(ctx context.Context, dataID uuid.UUID, collectionID *uuid.UUID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, exists := s.data[dataID]
+	if !exists {
+		return ErrDataNotFound
+	}
+	if collectionID != nil {
+		if _, exists := s.collections[*collectionID]; !exists {
+			return ErrCollectionNotFound
+		}
+	}
+	// a copy, as listings hand out the stored items
+	moved := *data
+	moved.CollectionID = collectionID
+	s.data[dataID] = &moved
+	return nil
+}
+
+// PutDataChunk stores the content chunk of existing data at index, replacing
+// one stored before. Chunks are stored in order, so index may be at most the
+// number of chunks so far.
+func (s *MemoryStorage) PutDataChunk(ctx context.Context, dataID uuid.UUID, index int, chunk []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.data[dataID]; !exists {
+		return ErrDataNotFound
+	}
+
+	chunks := s.chunks[dataID]
+	switch {
+	case index < 0 || index > len(chunks):
+		return ErrChunkOutOfOrder
+	case index == len(chunks):
+		s.chunks[dataID] = append(chunks, chunk)
+	default:
+		chunks[index] = chunk
+	}
+	return nil
+}
+
+// GetDataChunk gets the content chunk of data at index
+func (s *MemoryStorage) GetDataChunk(ctx context.Context, dataID uuid.UUID, index int) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	chunks := s.chunks[dataID]
+	if index < 0 || index >= len(chunks) {
+		return nil, ErrChunkNotFound
+	}
+	return chunks[index], nil
+}
+
+// CountDataChunks counts the content chunks of data
+func (s *MemoryStorage) CountDataChunks(ctx context.Context, dataID uuid.UUID) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.chunks[dataID]), nil
+}
+
+// SetKeyEscrow creates or replaces the user's key escrow
+func (s *MemoryStorage) SetKeyEscrow(ctx context.Context, escrow *models.KeyEscrow) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.escrow[escrow.UserID] = escrow
+	return nil
+}
+
+// GetKeyEscrow gets the user's key escrow
+func (s *MemoryStorage) GetKeyEscrow(ctx context.Context, userID uuid.UUID) (*models.KeyEscrow, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	escrow, exists := s.escrow[userID]
+	if !exists {
+		return nil, ErrEscrowNotFound
+	}
+
+	return escrow, nil
+}
+
+// DeleteKeyEscrow deletes the user's key escrow
+func (s *MemoryStorage) DeleteKeyEscrow(ctx context.Context, userID uuid.UUID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.escrow[userID]; !exists {
+		return ErrEscrowNotFound
+	}
+
+	delete(s.escrow, userID)
+	return nil
+}
+
+// SetPasswordHint sets the user's master password hint; an empty hint removes it
+func (s *MemoryStorage) SetPasswordHint(ctx context.Context, userID uuid.UUID, hint string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.userExists(userID) {
+		return ErrUserNotFound
+	}
+
+	if hint == "" {
+		delete(s.hints, userID)
+	} else {
+		s.hints[userID] = hint
+	}
+	return nil
+}
+
+// GetPasswordHint gets the user's master password hint, empty if none is set
+func (s *MemoryStorage) GetPasswordHint(ctx context.Context, userID uuid.UUID) (string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if !s.userExists(userID) {
+		return "", ErrUserNotFound
+	}
+
+	return s.hints[userID], nil
+}
+
+// SetMasterVerifier sets the user's master password verifier once; re-sending
+// the same verifier is a no-op
+func (s *MemoryStorage) SetMasterVerifier(ctx context.Context, userID uuid.UUID, verifier []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.userExists(userID) {
+		return ErrUserNotFound
+	}
+
+	if current, ok := s.verifiers[userID]; ok {
+		if bytes.Equal(current, verifier) {
+			return nil
+		}
+		return ErrVerifierAlreadySet
+	}
+	s.verifiers[userID] = verifier
+	return nil
+}
+
+// GetMasterVerifier gets the user's master password verifier, nil if none is set
+func (s *MemoryStorage) GetMasterVerifier(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if !s.userExists(userID) {
+		return nil, ErrUserNotFound
+	}
+
+	return s.verifiers[userID], nil
+}
+
+// SetAuditKey sets the public key the user's audit details are sealed to
+func (s *MemoryStorage) SetAuditKey(ctx context.Context, userID uuid.UUID, publicKey []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.userExists(userID) {
+		return ErrUserNotFound
+	}
+
+	s.auditKeys[userID] = publicKey
+	return nil
+}
+
+// GetAuditKey gets the user's audit public key, nil if none is set
+func (s *MemoryStorage) GetAuditKey(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if !s.userExists(userID) {
+		return nil, ErrUserNotFound
+	}
+
+	return s.auditKeys[userID], nil
+}
+
+// AddAuditEvent appends an event to the user's audit log
+func (s *MemoryStorage) AddAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.userExists(event.UserID) {
+		return ErrUserNotFound
+	}
+
+	s.audit[event.UserID] = append(s.audit[event.UserID], event)
+	return nil
+}
+
+// GetAuditEvents gets up to limit of the user's latest audit events, newest first
+func (s *MemoryStorage) GetAuditEvents(ctx context.Context, userID uuid.UUID, limit int) ([]*models.AuditEvent, error) {
+	return s.FindAuditEvents(ctx, models.AuditFilter{UserID: &userID, Limit: limit})
+}
+
+// FindAuditEvents gets up to filter.Limit of the latest audit events matching the filter, newest first
+func (s *MemoryStorage) FindAuditEvents(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var all []*models.AuditEvent
+	if filter.UserID != nil {
+		all = s.audit[*filter.UserID]
+	} else {
+		for _, events := range s.audit {
+			all = append(all, events...)
+		}
+		sort.SliceStable(all, func(i, j int) bool { return all[i].CreatedAt.Before(all[j].CreatedAt) })
+	}
+
+	events := make([]*models.AuditEvent, 0, filter.Limit)
+	for i := len(all) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		if filter.Matches(all[i]) {
+			events = append(events, all[i])
+		}
+	}
+	return events, nil
+}
+
+// SetShareKey sets the user's share key pair. The public key is set once, as
+// items may be sealed to it; re-sending it replaces the encrypted private key.
+func (s *MemoryStorage) SetShareKey(ctx context.Context, userID uuid.UUID, keys *models.ShareKeys) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.userExists(userID) {
+		return ErrUserNotFound
+	}
+
+	if current, ok := s.shareKeys[userID]; ok && !bytes.Equal(current.PublicKey, keys.PublicKey) {
+		return ErrShareKeyAlreadySet
+	}
+	s.shareKeys[userID] = keys
+	return nil
+}
+
+// GetShareKey gets the user's share key pair, nil if none is set
+func (s *MemoryStorage) GetShareKey(ctx context.Context, userID uuid.UUID) (*models.ShareKeys, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if !s.userExists(userID) {
+		return nil, ErrUserNotFound
+	}
+
+	return s.shareKeys[userID], nil
+}
+
+// CreateShare shares data with a user, or changes the mode and sealed key of
+// the share the user already has, keeping its ID
+func (s *MemoryStorage) CreateShare(ctx context.Context, share *models.Share) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.data[share.DataID]; !exists {
+		return ErrDataNotFound
+	}
+	if !s.userExists(share.RecipientID) {
+		return ErrUserNotFound
+	}
+
+	for _, existing := range s.shares {
+		if existing.DataID == share.DataID && existing.RecipientID == share.RecipientID {
+			share.ID = existing.ID
+			share.CreatedAt = existing.CreatedAt
+		}
+	}
+	s.shares[share.ID] = share
+	return nil
+}
+
+// GetShare gets a share by ID
+func (s *MemoryStorage) GetShare(ctx context.Context, shareID uuid.UUID) (*models.Share, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	share, exists := s.shares[shareID]
+	if !exists {
+		return nil, ErrShareNotFound
+	}
+
+	return s.withRecipient(share), nil
+}
+
+// GetShares gets the shares of data, oldest first
+func (s *MemoryStorage) GetShares(ctx context.Context, dataID uuid.UUID) ([]*models.Share, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var shares []*models.Share
+	for _, share := range s.shares {
+		if share.DataID == dataID {
+			shares = append(shares, s.withRecipient(share))
+		}
+	}
+	sortShares(shares)
+	return shares, nil
+}
+
+// GetSharedWith gets the shares granted to a user, oldest first
+func (s *MemoryStorage) GetSharedWith(ctx context.Context, recipientID uuid.UUID) ([]*models.Share, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var shares []*models.Share
+	for _, share := range s.shares {
+		if share.RecipientID == recipientID {
+			shares = append(shares, s.withRecipient(share))
+		}
+	}
+	sortShares(shares)
+	return shares, nil
+}
+
+// DeleteShare revokes a share
+func (s *MemoryStorage) DeleteShare(ctx context.Context, shareID uuid.UUID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.shares[shareID]; !exists {
+		return ErrShareNotFound
+	}
+
+	delete(s.shares, shareID)
 	return nil
 }
+
+// withRecipient copies a share with its recipient's username filled in; the
+// caller must hold the mutex
+func (s *MemoryStorage) withRecipient(share *models.Share) *models.Share {
+	filled := *share
+	for _, user := range s.users {
+		if user.ID == share.RecipientID {
+			filled.Recipient = user.Username
+		}
+	}
+	return &filled
+}
+
+// sortShares orders shares oldest first, by ID among equal times
+func sortShares(shares []*models.Share) {
+	sort.Slice(shares, func(i, j int) bool {
+		if !shares[i].CreatedAt.Equal(shares[j].CreatedAt) {
+			return shares[i].CreatedAt.Before(shares[j].CreatedAt)
+		}
+		return shares[i].ID.String() < shares[j].ID.String()
+	})
+}
+
+// CreateIdentity links an external account to a user
+func (s *MemoryStorage) CreateIdentity(ctx context.Context, identity *models.Identity) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.userExists(identity.UserID) {
+		return ErrUserNotFound
+	}
+	key := identityKey(identity.Issuer, identity.Subject)
+	if _, exists := s.identities[key]; exists {
+		return ErrIdentityExists
+	}
+
+	stored := *identity
+	s.identities[key] = &stored
+	return nil
+}
+
+// GetIdentity gets the link of an external account by its issuer and subject
+func (s *MemoryStorage) GetIdentity(ctx context.Context, issuer, subject string) (*models.Identity, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	identity, exists := s.identities[identityKey(issuer, subject)]
+	if !exists || !s.userExists(identity.UserID) {
+		return nil, ErrIdentityNotFound
+	}
+
+	found := *identity
+	return &found, nil
+}
+
+func identityKey(issuer, subject string) string {
+	return issuer + "\x00" + subject
+}
+
+// CreateSession records a login of a user
+func (s *MemoryStorage) CreateSession(ctx context.Context, session *models.Session) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.userExists(session.UserID) {
+		return ErrUserNotFound
+	}
+
+	stored := *session
+	s.sessions[session.ID] = &stored
+	return nil
+}
+
+// GetSession gets a session by ID
+func (s *MemoryStorage) GetSession(ctx context.Context, sessionID uuid.UUID) (*models.Session, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+
+	found := *session
+	return &found, nil
+}
+
+// GetSessions gets the sessions of a user that have not expired at now, most
+// recently seen first
+func (s *MemoryStorage) GetSessions(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var sessions []*models.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID && now.Before(session.ExpiresAt) {
+			found := *session
+			sessions = append(sessions, &found)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].ID.String() < sessions[j].ID.String()
+	})
+	return sessions, nil
+}
+
+// TouchSession records that a session was used at seenAt
+func (s *MemoryStorage) TouchSession(ctx context.Context, sessionID uuid.UUID, seenAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+
+	session.LastSeenAt = seenAt
+	return nil
+}
+
+// DeleteSession revokes a session
+func (s *MemoryStorage) DeleteSession(ctx context.Context, sessionID uuid.UUID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.sessions[sessionID]; !exists {
+		return ErrSessionNotFound
+	}
+
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+// DeleteExpiredSessions deletes the sessions of a user that expired by now
+func (s *MemoryStorage) DeleteExpiredSessions(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, session := range s.sessions {
+		if session.UserID == userID && !now.Before(session.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+// RotateVault replaces the salt, verifier and encrypted share private key of
+// the user and every ciphertext of their vault with the records read from next
+// until io.EOF, all at once or not at all.
+// The records must cover each item, comment, version, chunk and attachment of
+// the user exactly once, with items at their current revision; rotated items
+// advance their revision without keeping a version of the old ciphertext.
+func (s *MemoryStorage) RotateVault(ctx context.Context, userID uuid.UUID, header models.RotationHeader,
+	next func() (*models.RotationRecord, error)) (*models.RotationResponse, error) {
+	// records are read before taking the lock, as reading them waits on the client
+	var records []*models.RotationRecord
+	for {
+		record, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var user *models.User
+	for _, candidate := range s.users {
+		if candidate.ID == userID {
+			user = candidate
+		}
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	// the replacements are staged on copies, so a failure leaves the vault as it was
+	items := make(map[uuid.UUID]*models.Data)
+	comments := make(map[uuid.UUID][]*models.DataComment)
+	versions := make(map[uuid.UUID][]*models.DataVersion)
+	chunks := make(map[uuid.UUID][][]byte)
+	attachments := make(map[uuid.UUID][]*models.DataAttachment)
+	total := 0
+	for id, data := range s.data {
+		if data.UserID != userID {
+			continue
+		}
+		item := *data
+		items[id] = &item
+		comments[id] = append([]*models.DataComment(nil), s.comments[id]...)
+		versions[id] = append([]*models.DataVersion(nil), s.versions[id]...)
+		chunks[id] = append([][]byte(nil), s.chunks[id]...)
+		attachments[id] = append([]*models.DataAttachment(nil), s.attachments[id]...)
+		total += 1 + len(s.comments[id]) + len(s.versions[id]) + len(s.chunks[id]) + len(s.attachments[id])
+	}
+
+	response := &models.RotationResponse{}
+	seen := make(map[string]bool)
+	for _, record := range records {
+		item, ok := items[record.DataID]
+		if !ok {
+			return nil, ErrRotationMismatch
+		}
+		key := fmt.Sprintf("%s/%s/%s/%d/%d/%s", record.Kind, record.DataID, record.CommentID, record.Version, record.Index,
+			record.AttachmentID)
+		if seen[key] {
+			return nil, ErrRotationMismatch
+		}
+		seen[key] = true
+
+		switch record.Kind {
+		case models.RotationItem:
+			if record.Revision != item.Revision {
+				return nil, ErrRotationConflict
+			}
+			item.Data = record.Data
+			response.Items++
+		case models.RotationComment:
+			index := -1
+			for i, comment := range comments[record.DataID] {
+				if comment.ID == record.CommentID {
+					index = i
+				}
+			}
+			if index < 0 {
+				return nil, ErrRotationMismatch
+			}
+			comment := *comments[record.DataID][index]
+			comment.Ciphertext = record.Data
+			comments[record.DataID][index] = &comment
+			response.Comments++
+		case models.RotationVersion:
+			if record.Version < 1 || record.Version > len(versions[record.DataID]) {
+				return nil, ErrRotationMismatch
+			}
+			version := *versions[record.DataID][record.Version-1]
+			version.Data = record.Data
+			versions[record.DataID][record.Version-1] = &version
+			response.Versions++
+		case models.RotationChunk:
+			if record.Index < 0 || record.Index >= len(chunks[record.DataID]) {
+				return nil, ErrRotationMismatch
+			}
+			chunks[record.DataID][record.Index] = record.Data
+			response.Chunks++
+		case models.RotationAttachment:
+			index := -1
+			for i, attachment := range attachments[record.DataID] {
+				if attachment.ID == record.AttachmentID {
+					index = i
+				}
+			}
+			if index < 0 || len(record.Info) == 0 {
+				return nil, ErrRotationMismatch
+			}
+			attachment := *attachments[record.DataID][index]
+			attachment.Info = record.Info
+			attachment.Content = record.Data
+			attachment.Size = int64(len(record.Data))
+			attachments[record.DataID][index] = &attachment
+			response.Attachments++
+		default:
+			return nil, ErrRotationMismatch
+		}
+	}
+	if len(records) != total {
+		return nil, ErrRotationMismatch
+	}
+
+	for id, item := range items {
+		item.Revision++
+		s.data[id] = item
+		s.comments[id] = comments[id]
+		s.versions[id] = versions[id]
+		s.chunks[id] = chunks[id]
+		s.attachments[id] = attachments[id]
+	}
+	rotated := *user
+	rotated.Salt = header.Salt
+	rotated.KDFIterations = header.KDFIterations
+	rotated.MasterPassword = ""
+	rotated.UpdatedAt = s.clock.Now()
+	s.users[user.Username] = &rotated
+	if len(header.Verifier) > 0 {
+		s.verifiers[userID] = header.Verifier
+	} else {
+		delete(s.verifiers, userID)
+	}
+	if keys, ok := s.shareKeys[userID]; ok && len(header.ShareKey) > 0 {
+		s.shareKeys[userID] = &models.ShareKeys{PublicKey: keys.PublicKey, PrivateKey: header.ShareKey}
+	}
+	return response, nil
+}
+
+// userExists reports whether a user with the ID exists; the caller must hold the mutex
+func (s *MemoryStorage) userExists(userID uuid.UUID) bool {
+	for _, user := range s.users {
+		if user.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Ping always succeeds for in-memory storage
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// SelfTest writes, reads and deletes a canary user
+func (s *MemoryStorage) SelfTest(ctx context.Context) (*SelfTestReport, error) {
+	start := time.Now()
+	canary := &models.User{ID: uuid.New(), Username: canaryUsername(), CreatedAt: start, UpdatedAt: start}
+	if err := s.CreateUser(ctx, canary); err != nil {
+		return nil, fmt.Errorf("canary write failed: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if got, ok := s.users[canary.Username]; !ok || got.ID != canary.ID {
+		return nil, fmt.Errorf("canary read failed")
+	}
+	delete(s.users, canary.Username)
+
+	return &SelfTestReport{SchemaVersion: SchemaVersion, Latency: time.Since(start)}, nil
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+)
+
+// seedBenchmarkData creates count items of one user
+func seedBenchmarkData(b *testing.B, s *MemoryStorage, userID uuid.UUID, count int) []*models.Data {
+	b.Helper()
+	items := make([]*models.Data, 0, count)
+	for i := 0; i < count; i++ {
+		data := &models.Data{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Type:      models.DataTypeText,
+			Name:      fmt.Sprintf("item %d", i),
+			Data:      make([]byte, 512),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := s.CreateData(context.Background(), data); err != nil {
+			b.Fatalf("CreateData() error = %v", err)
+		}
+		items = append(items, data)
+	}
+	return items
+}
+
+func BenchmarkMemoryStorage_CreateData(b *testing.B) {
+	s := NewMemoryStorage()
+	userID := uuid.New()
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "item", Data: make([]byte, 512)}
+		if err := s.CreateData(ctx, data); err != nil {
+			b.Fatalf("CreateData() error = %v", err)
+		}
+	}
+}
+
+func BenchmarkMemoryStorage_GetDataByID(b *testing.B) {
+	s := NewMemoryStorage()
+	items := seedBenchmarkData(b, s, uuid.New(), 1000)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, err := s.GetDataByID(ctx, items[i%len(items)].ID); err != nil {
+				b.Errorf("GetDataByID() error = %v", err)
+				return
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkMemoryStorage_GetDataByUserID(b *testing.B) {
+	for _, count := range []int{10, 1000} {
+		b.Run(fmt.Sprintf("%d items", count), func(b *testing.B) {
+			s := NewMemoryStorage()
+			userID := uuid.New()
+			seedBenchmarkData(b, s, userID, count)
+			seedBenchmarkData(b, s, uuid.New(), count)
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.GetDataByUserID(ctx, userID); err != nil {
+					b.Fatalf("GetDataByUserID() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMemoryStorage_UpdateData(b *testing.B) {
+	s := NewMemoryStorage()
+	items := seedBenchmarkData(b, s, uuid.New(), 100)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data := *items[i%len(items)]
+		data.Name = fmt.Sprintf("renamed %d", i)
+		if err := s.UpdateData(ctx, &data); err != nil {
+			b.Fatalf("UpdateData() error = %v", err)
+		}
+	}
+}
@@ -2,9 +2,11 @@ package storage
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/a2sh3r/gophkeeper/internal/clock"
 	"github.com/a2sh3r/gophkeeper/internal/models"
 	"github.com/google/uuid"
 )
@@ -459,3 +461,833 @@ func TestMemoryStorage_DeleteData(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoryStorage_SetUserSalt(t *testing.T) {
+	storage := NewMemoryStorage()
+	saltedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	storage.SetClock(clock.NewManual(saltedAt))
+	user := &models.User{
+		ID:        uuid.New(),
+		Username:  "testuser",
+		Password:  "hashedpassword",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := storage.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := storage.SetUserSalt(context.Background(), user.ID, "salt-1"); err != nil {
+		t.Fatalf("SetUserSalt() error = %v", err)
+	}
+
+	if err := storage.SetUserSalt(context.Background(), user.ID, "salt-1"); err != nil {
+		t.Errorf("SetUserSalt() replay error = %v, want nil", err)
+	}
+
+	if err := storage.SetUserSalt(context.Background(), user.ID, "salt-2"); err != ErrSaltAlreadySet {
+		t.Errorf("SetUserSalt() error = %v, want %v", err, ErrSaltAlreadySet)
+	}
+
+	retrievedUser, err := storage.GetUserByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if retrievedUser.Salt != "salt-1" {
+		t.Errorf("Expected salt salt-1, got %s", retrievedUser.Salt)
+	}
+	if !retrievedUser.UpdatedAt.Equal(saltedAt) {
+		t.Errorf("Expected UpdatedAt %v from the storage clock, got %v", saltedAt, retrievedUser.UpdatedAt)
+	}
+
+	if err := storage.SetUserSalt(context.Background(), uuid.New(), "salt-1"); err != ErrUserNotFound {
+		t.Errorf("SetUserSalt() error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestMemoryStorage_DataFields(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	data := &models.Data{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		Type:      models.DataTypeLoginPassword,
+		Name:      "DB_PASSWORD",
+		Data:      []byte("encrypted"),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := storage.CreateData(ctx, data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	field := &models.DataField{DataID: data.ID, Name: "password", Ciphertext: []byte("v1"),
+		CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := storage.SetDataField(ctx, field); err != nil {
+		t.Fatalf("SetDataField() error = %v", err)
+	}
+	if err := storage.SetDataField(ctx, &models.DataField{DataID: data.ID, Name: "password", Ciphertext: []byte("v2"),
+		CreatedAt: time.Now(), UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("SetDataField() replace error = %v", err)
+	}
+
+	got, err := storage.GetDataField(ctx, data.ID, "password")
+	if err != nil {
+		t.Fatalf("GetDataField() error = %v", err)
+	}
+	if string(got.Ciphertext) != "v2" {
+		t.Errorf("Expected replaced ciphertext v2, got %s", got.Ciphertext)
+	}
+	if !got.CreatedAt.Equal(field.CreatedAt) {
+		t.Errorf("Replacing a field should keep its creation time")
+	}
+
+	if _, err := storage.GetDataField(ctx, data.ID, "login"); err != ErrFieldNotFound {
+		t.Errorf("GetDataField() error = %v, want %v", err, ErrFieldNotFound)
+	}
+	if err := storage.SetDataField(ctx, &models.DataField{DataID: uuid.New(), Name: "password"}); err != ErrDataNotFound {
+		t.Errorf("SetDataField() error = %v, want %v", err, ErrDataNotFound)
+	}
+
+	if err := storage.DeleteData(ctx, data.ID); err != nil {
+		t.Fatalf("DeleteData() error = %v", err)
+	}
+	if _, err := storage.GetDataField(ctx, data.ID, "password"); err != ErrFieldNotFound {
+		t.Errorf("Fields should be deleted with their data, got error %v", err)
+	}
+}
+
+func TestMemoryStorage_KeyEscrow(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	userID := uuid.New()
+
+	if _, err := storage.GetKeyEscrow(ctx, userID); err != ErrEscrowNotFound {
+		t.Errorf("GetKeyEscrow() error = %v, want %v", err, ErrEscrowNotFound)
+	}
+
+	escrow := &models.KeyEscrow{UserID: userID, WrappedKey: []byte("wrapped"), RecoveryKeyID: "abc", ConsentedAt: time.Now()}
+	if err := storage.SetKeyEscrow(ctx, escrow); err != nil {
+		t.Fatalf("SetKeyEscrow() error = %v", err)
+	}
+
+	got, err := storage.GetKeyEscrow(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetKeyEscrow() error = %v", err)
+	}
+	if string(got.WrappedKey) != "wrapped" || got.RecoveryKeyID != "abc" {
+		t.Errorf("Unexpected escrow: %+v", got)
+	}
+
+	if err := storage.DeleteKeyEscrow(ctx, userID); err != nil {
+		t.Fatalf("DeleteKeyEscrow() error = %v", err)
+	}
+	if err := storage.DeleteKeyEscrow(ctx, userID); err != ErrEscrowNotFound {
+		t.Errorf("DeleteKeyEscrow() error = %v, want %v", err, ErrEscrowNotFound)
+	}
+}
+
+func TestMemoryStorage_SelfTest(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	report, err := storage.SelfTest(context.Background())
+	if err != nil {
+		t.Fatalf("SelfTest() error = %v", err)
+	}
+	if report.SchemaVersion != SchemaVersion {
+		t.Errorf("SelfTest() schema version = %d, want %d", report.SchemaVersion, SchemaVersion)
+	}
+	if len(storage.users) != 0 {
+		t.Errorf("Canary user was not deleted, %d users left", len(storage.users))
+	}
+}
+
+func TestMemoryStorage_GetDataByUserID_Order(t *testing.T) {
+	storage := NewMemoryStorage()
+	ctx := context.Background()
+	userID := uuid.New()
+	now := time.Now()
+
+	older := &models.Data{ID: uuid.New(), UserID: userID, CreatedAt: now.Add(-time.Minute)}
+	tieLow := &models.Data{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), UserID: userID, CreatedAt: now}
+	tieHigh := &models.Data{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), UserID: userID, CreatedAt: now}
+	for _, data := range []*models.Data{older, tieLow, tieHigh} {
+		if err := storage.CreateData(ctx, data); err != nil {
+			t.Fatalf("CreateData() error = %v", err)
+		}
+	}
+
+	got, err := storage.GetDataByUserID(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetDataByUserID() error = %v", err)
+	}
+	want := []uuid.UUID{tieHigh.ID, tieLow.ID, older.ID}
+	for i, data := range got {
+		if data.ID != want[i] {
+			t.Errorf("GetDataByUserID()[%d] = %s, want %s", i, data.ID, want[i])
+		}
+	}
+}
+
+func TestMemoryStorage_PasswordHint(t *testing.T) {
+	storage := NewMemoryStorage()
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "hinted"}
+	if err := storage.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	if err := storage.SetPasswordHint(ctx, uuid.New(), "hint"); err != ErrUserNotFound {
+		t.Errorf("SetPasswordHint() for unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+
+	if err := storage.SetPasswordHint(ctx, user.ID, "first pet + year"); err != nil {
+		t.Fatalf("SetPasswordHint() error = %v", err)
+	}
+	if hint, err := storage.GetPasswordHint(ctx, user.ID); err != nil || hint != "first pet + year" {
+		t.Errorf("GetPasswordHint() = %q, %v", hint, err)
+	}
+
+	if err := storage.SetPasswordHint(ctx, user.ID, ""); err != nil {
+		t.Fatalf("SetPasswordHint() remove error = %v", err)
+	}
+	if hint, err := storage.GetPasswordHint(ctx, user.ID); err != nil || hint != "" {
+		t.Errorf("GetPasswordHint() after removal = %q, %v", hint, err)
+	}
+}
+
+func TestMemoryStorage_DataComments(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	data := &models.Data{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		Type:      models.DataTypeLoginPassword,
+		Name:      "DB_PASSWORD",
+		Data:      []byte("encrypted"),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := storage.CreateData(ctx, data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	for _, text := range []string{"first", "second"} {
+		comment := &models.DataComment{ID: uuid.New(), DataID: data.ID, Ciphertext: []byte(text), CreatedAt: time.Now()}
+		if err := storage.AddDataComment(ctx, comment); err != nil {
+			t.Fatalf("AddDataComment() error = %v", err)
+		}
+	}
+
+	comments, err := storage.GetDataComments(ctx, data.ID)
+	if err != nil {
+		t.Fatalf("GetDataComments() error = %v", err)
+	}
+	if len(comments) != 2 || string(comments[0].Ciphertext) != "first" || string(comments[1].Ciphertext) != "second" {
+		t.Errorf("Expected comments in order, got %v", comments)
+	}
+
+	if err := storage.AddDataComment(ctx, &models.DataComment{ID: uuid.New(), DataID: uuid.New()}); err != ErrDataNotFound {
+		t.Errorf("AddDataComment() error = %v, want %v", err, ErrDataNotFound)
+	}
+
+	if err := storage.DeleteData(ctx, data.ID); err != nil {
+		t.Fatalf("DeleteData() error = %v", err)
+	}
+	if comments, _ := storage.GetDataComments(ctx, data.ID); len(comments) != 0 {
+		t.Errorf("Deleting data should delete its comments, got %d", len(comments))
+	}
+}
+
+func TestMemoryStorage_DataAttachments(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	data := &models.Data{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		Type:      models.DataTypeText,
+		Name:      "Contract",
+		Data:      []byte("encrypted"),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := storage.CreateData(ctx, data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	var ids []uuid.UUID
+	for _, name := range []string{"scan.pdf", "notes.txt"} {
+		attachment := &models.DataAttachment{ID: uuid.New(), DataID: data.ID, Info: []byte(name), Size: 7,
+			Content: []byte("content"), CreatedAt: time.Now()}
+		if err := storage.AddDataAttachment(ctx, attachment); err != nil {
+			t.Fatalf("AddDataAttachment() error = %v", err)
+		}
+		ids = append(ids, attachment.ID)
+	}
+
+	attachments, err := storage.GetDataAttachments(ctx, data.ID)
+	if err != nil {
+		t.Fatalf("GetDataAttachments() error = %v", err)
+	}
+	if len(attachments) != 2 || string(attachments[0].Info) != "scan.pdf" || string(attachments[1].Info) != "notes.txt" {
+		t.Errorf("Expected attachments in order, got %v", attachments)
+	}
+	if attachments[0].Content != nil {
+		t.Error("Expected attachments listed without their content")
+	}
+
+	attachment, err := storage.GetDataAttachment(ctx, data.ID, ids[0])
+	if err != nil {
+		t.Fatalf("GetDataAttachment() error = %v", err)
+	}
+	if string(attachment.Content) != "content" {
+		t.Errorf("Expected the content, got %q", attachment.Content)
+	}
+	if _, err := storage.GetDataAttachment(ctx, uuid.New(), ids[0]); err != ErrAttachmentNotFound {
+		t.Errorf("GetDataAttachment() error = %v, want %v", err, ErrAttachmentNotFound)
+	}
+
+	if err := storage.DeleteDataAttachment(ctx, data.ID, ids[0]); err != nil {
+		t.Fatalf("DeleteDataAttachment() error = %v", err)
+	}
+	if err := storage.DeleteDataAttachment(ctx, data.ID, ids[0]); err != ErrAttachmentNotFound {
+		t.Errorf("DeleteDataAttachment() error = %v, want %v", err, ErrAttachmentNotFound)
+	}
+	if attachments, _ := storage.GetDataAttachments(ctx, data.ID); len(attachments) != 1 || attachments[0].ID != ids[1] {
+		t.Errorf("Expected one attachment left, got %v", attachments)
+	}
+
+	if err := storage.AddDataAttachment(ctx, &models.DataAttachment{ID: uuid.New(), DataID: uuid.New()}); err != ErrDataNotFound {
+		t.Errorf("AddDataAttachment() error = %v, want %v", err, ErrDataNotFound)
+	}
+
+	if err := storage.DeleteData(ctx, data.ID); err != nil {
+		t.Fatalf("DeleteData() error = %v", err)
+	}
+	if attachments, _ := storage.GetDataAttachments(ctx, data.ID); len(attachments) != 0 {
+		t.Errorf("Deleting data should delete its attachments, got %d", len(attachments))
+	}
+}
+
+func TestMemoryStorage_FlagExpiringData(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	create := func(name string, expiresIn time.Duration) *models.Data {
+		t.Helper()
+		data := &models.Data{ID: uuid.New(), UserID: uuid.New(), Type: models.DataTypeBankCard, Name: name,
+			Data: []byte("encrypted"), CreatedAt: now, UpdatedAt: now}
+		if expiresIn != 0 {
+			expiresAt := now.Add(expiresIn)
+			data.ExpiresAt = &expiresAt
+		}
+		if err := storage.CreateData(ctx, data); err != nil {
+			t.Fatalf("Failed to create data: %v", err)
+		}
+		return data
+	}
+	expired := create("Expired", -time.Hour)
+	expiring := create("Expiring", 10*24*time.Hour)
+	create("Later", 90*24*time.Hour)
+	create("Never", 0)
+
+	flagged, err := storage.FlagExpiringData(ctx, now, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("FlagExpiringData() error = %v", err)
+	}
+	want := map[uuid.UUID]string{expired.ID: models.ExpiryExpired, expiring.ID: models.ExpiryExpiring}
+	if len(flagged) != len(want) {
+		t.Fatalf("FlagExpiringData() flagged %d items, want %d", len(flagged), len(want))
+	}
+	for _, data := range flagged {
+		if data.Expiry != want[data.ID] {
+			t.Errorf("Item %q flagged %q, want %q", data.Name, data.Expiry, want[data.ID])
+		}
+		stored, _ := storage.GetDataByID(ctx, data.ID)
+		if stored.Expiry != want[data.ID] || stored.Revision != 1 {
+			t.Errorf("Stored item %q at revision %d flagged %q", stored.Name, stored.Revision, stored.Expiry)
+		}
+	}
+
+	// unchanged flags are not reported again
+	if flagged, err := storage.FlagExpiringData(ctx, now, 30*24*time.Hour); err != nil || len(flagged) != 0 {
+		t.Errorf("FlagExpiringData() = %d items, %v; want none", len(flagged), err)
+	}
+	flagged, err = storage.FlagExpiringData(ctx, now.Add(20*24*time.Hour), 30*24*time.Hour)
+	if err != nil || len(flagged) != 1 || flagged[0].ID != expiring.ID || flagged[0].Expiry != models.ExpiryExpired {
+		t.Errorf("Expected the expiring item to be flagged expired, got %d items, %v", len(flagged), err)
+	}
+}
+
+func TestMemoryStorage_DataVersions(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	created := time.Now().Add(-time.Hour)
+	data := &models.Data{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		Type:      models.DataTypeLoginPassword,
+		Name:      "DB_PASSWORD",
+		Data:      []byte("first"),
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	if err := storage.CreateData(ctx, data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	for _, content := range []string{"second", "third"} {
+		current, err := storage.GetDataByID(ctx, data.ID)
+		if err != nil {
+			t.Fatalf("GetDataByID() error = %v", err)
+		}
+		current.Data = []byte(content)
+		current.UpdatedAt = time.Now()
+		if err := storage.UpdateData(ctx, current); err != nil {
+			t.Fatalf("UpdateData() error = %v", err)
+		}
+	}
+
+	if current, _ := storage.GetDataByID(ctx, data.ID); current.Revision != 3 {
+		t.Errorf("Expected revision 3 after two updates, got %d", current.Revision)
+	}
+
+	versions, err := storage.GetDataVersions(ctx, data.ID)
+	if err != nil {
+		t.Fatalf("GetDataVersions() error = %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 2 || string(versions[0].Data) != "second" ||
+		versions[1].Version != 1 || string(versions[1].Data) != "first" || !versions[1].UpdatedAt.Equal(created) {
+		t.Errorf("Expected the replaced versions newest first, got %v", versions)
+	}
+
+	version, err := storage.GetDataVersion(ctx, data.ID, 1)
+	if err != nil || string(version.Data) != "first" {
+		t.Errorf("GetDataVersion(1) = %v, %v", version, err)
+	}
+	for _, number := range []int{0, 3} {
+		if _, err := storage.GetDataVersion(ctx, data.ID, number); err != ErrVersionNotFound {
+			t.Errorf("GetDataVersion(%d) error = %v, want %v", number, err, ErrVersionNotFound)
+		}
+	}
+
+	if err := storage.DeleteData(ctx, data.ID); err != nil {
+		t.Fatalf("DeleteData() error = %v", err)
+	}
+	if versions, _ := storage.GetDataVersions(ctx, data.ID); len(versions) != 0 {
+		t.Errorf("Deleting data should delete its versions, got %d", len(versions))
+	}
+}
+
+func TestMemoryStorage_AuditLog(t *testing.T) {
+	storage := NewMemoryStorage()
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "audited"}
+	if err := storage.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	if err := storage.SetAuditKey(ctx, uuid.New(), []byte("key")); err != ErrUserNotFound {
+		t.Errorf("SetAuditKey() for unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+	if err := storage.AddAuditEvent(ctx, &models.AuditEvent{ID: uuid.New(), UserID: uuid.New()}); err != ErrUserNotFound {
+		t.Errorf("AddAuditEvent() for unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+
+	if key, err := storage.GetAuditKey(ctx, user.ID); err != nil || key != nil {
+		t.Errorf("GetAuditKey() before it is set = %x, %v", key, err)
+	}
+	if err := storage.SetAuditKey(ctx, user.ID, []byte("key")); err != nil {
+		t.Fatalf("SetAuditKey() error = %v", err)
+	}
+	if key, err := storage.GetAuditKey(ctx, user.ID); err != nil || string(key) != "key" {
+		t.Errorf("GetAuditKey() = %x, %v", key, err)
+	}
+
+	now := time.Now()
+	for i, action := range []string{"data.create", "data.update", "data.delete"} {
+		event := &models.AuditEvent{ID: uuid.New(), UserID: user.ID, Action: action, CreatedAt: now.Add(time.Duration(i) * time.Second)}
+		if err := storage.AddAuditEvent(ctx, event); err != nil {
+			t.Fatalf("AddAuditEvent() error = %v", err)
+		}
+	}
+
+	events, err := storage.GetAuditEvents(ctx, user.ID, 2)
+	if err != nil {
+		t.Fatalf("GetAuditEvents() error = %v", err)
+	}
+	if len(events) != 2 || events[0].Action != "data.delete" || events[1].Action != "data.update" {
+		t.Errorf("Expected the two latest events newest first, got %+v", events)
+	}
+
+	other := &models.User{ID: uuid.New(), Username: "other"}
+	if err := storage.CreateUser(ctx, other); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := storage.AddAuditEvent(ctx, &models.AuditEvent{ID: uuid.New(), UserID: other.ID, Action: "auth.login", CreatedAt: now.Add(time.Second / 2)}); err != nil {
+		t.Fatalf("AddAuditEvent() error = %v", err)
+	}
+	events, err = storage.FindAuditEvents(ctx, models.AuditFilter{Since: now.Add(time.Second / 4), Until: now.Add(2 * time.Second), Limit: 10})
+	if err != nil {
+		t.Fatalf("FindAuditEvents() error = %v", err)
+	}
+	if len(events) != 2 || events[0].Action != "data.update" || events[1].UserID != other.ID {
+		t.Errorf("Expected the events of all users in the time range newest first, got %+v", events)
+	}
+}
+
+func TestMemoryStorage_DataChunks(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	data := &models.Data{ID: uuid.New(), UserID: uuid.New(), Type: models.DataTypeBinary, Name: "backup.tar",
+		Data: []byte("encrypted"), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := storage.CreateData(ctx, data); err != nil {
+		t.Fatalf("Failed to create data: %v", err)
+	}
+
+	if err := storage.PutDataChunk(ctx, data.ID, 1, []byte("second")); err != ErrChunkOutOfOrder {
+		t.Errorf("PutDataChunk() past the end error = %v, want %v", err, ErrChunkOutOfOrder)
+	}
+	for i, chunk := range []string{"first", "second", "second again"} {
+		index := i
+		if i == 2 {
+			index = 1
+		}
+		if err := storage.PutDataChunk(ctx, data.ID, index, []byte(chunk)); err != nil {
+			t.Fatalf("PutDataChunk(%d) error = %v", index, err)
+		}
+	}
+
+	if count, err := storage.CountDataChunks(ctx, data.ID); err != nil || count != 2 {
+		t.Errorf("CountDataChunks() = %d, %v, want 2", count, err)
+	}
+	if chunk, err := storage.GetDataChunk(ctx, data.ID, 1); err != nil || string(chunk) != "second again" {
+		t.Errorf("GetDataChunk() = %q, %v, want the replaced chunk", chunk, err)
+	}
+	if _, err := storage.GetDataChunk(ctx, data.ID, 2); err != ErrChunkNotFound {
+		t.Errorf("GetDataChunk() error = %v, want %v", err, ErrChunkNotFound)
+	}
+	if err := storage.PutDataChunk(ctx, uuid.New(), 0, []byte("x")); err != ErrDataNotFound {
+		t.Errorf("PutDataChunk() error = %v, want %v", err, ErrDataNotFound)
+	}
+
+	if err := storage.DeleteData(ctx, data.ID); err != nil {
+		t.Fatalf("DeleteData() error = %v", err)
+	}
+	if count, _ := storage.CountDataChunks(ctx, data.ID); count != 0 {
+		t.Errorf("Deleting data should delete its chunks, got %d", count)
+	}
+}
+
+func TestMemoryStorage_GetDataByUserIDFiltered(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	userID := uuid.New()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	names := []string{"Mail", "Bank card", "mailbox notes", "VPN"}
+	types := []models.DataType{models.DataTypeLoginPassword, models.DataTypeBankCard, models.DataTypeText, models.DataTypeLoginPassword}
+	for i, name := range names {
+		data := &models.Data{ID: uuid.New(), UserID: userID, Type: types[i], Name: name, Data: []byte("sealed"),
+			CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		if name == "VPN" {
+			data.Metadata = "Login: MailAdmin"
+			data.Domains = []string{"example.com"}
+		}
+		if types[i] == models.DataTypeLoginPassword {
+			data.Tags = []string{"work"}
+		}
+		if err := storage.CreateData(ctx, data); err != nil {
+			t.Fatalf("Failed to create data: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter models.DataFilter
+		want   []string
+	}{
+		{name: "no filter", want: []string{"VPN", "mailbox notes", "Bank card", "Mail"}},
+		{name: "type", filter: models.DataFilter{Type: models.DataTypeLoginPassword}, want: []string{"VPN", "Mail"}},
+		{name: "name ignores case", filter: models.DataFilter{Name: "MAIL"}, want: []string{"mailbox notes", "Mail"}},
+		{name: "tag", filter: models.DataFilter{Tag: "work"}, want: []string{"VPN", "Mail"}},
+		{name: "domain", filter: models.DataFilter{Domain: "example.com"}, want: []string{"VPN"}},
+		{name: "query matches metadata", filter: models.DataFilter{Query: "mail"}, want: []string{"VPN", "mailbox notes", "Mail"}},
+		{name: "first page", filter: models.DataFilter{Limit: 3}, want: []string{"VPN", "mailbox notes", "Bank card"}},
+		{name: "second page", filter: models.DataFilter{Limit: 3, Offset: 3}, want: []string{"Mail"}},
+		{name: "past the end", filter: models.DataFilter{Offset: 4}},
+		{name: "filtered page", filter: models.DataFilter{Name: "mail", Limit: 1, Offset: 1}, want: []string{"Mail"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := storage.GetDataByUserIDFiltered(ctx, userID, tt.filter)
+			if err != nil {
+				t.Fatalf("GetDataByUserIDFiltered() error = %v", err)
+			}
+			var gotNames []string
+			for _, data := range got {
+				gotNames = append(gotNames, data.Name)
+			}
+			if strings.Join(gotNames, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("GetDataByUserIDFiltered() = %q, want %q", gotNames, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStorage_Collections(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	userID := uuid.New()
+
+	work := &models.Collection{ID: uuid.New(), UserID: userID, Name: "Work"}
+	archive := &models.Collection{ID: uuid.New(), UserID: userID, Name: "Archive", ParentID: &work.ID}
+	for _, collection := range []*models.Collection{work, archive, {ID: uuid.New(), UserID: uuid.New(), Name: "Other"}} {
+		if err := storage.CreateCollection(ctx, collection); err != nil {
+			t.Fatalf("CreateCollection() error = %v", err)
+		}
+	}
+
+	collections, err := storage.GetCollectionsByUserID(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetCollectionsByUserID() error = %v", err)
+	}
+	if len(collections) != 2 || collections[0].Name != "Archive" || collections[1].Name != "Work" {
+		t.Errorf("Expected the user's collections by name, got %v", collections)
+	}
+
+	data := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "note", Revision: 1}
+	if err := storage.CreateData(ctx, data); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	unknown := uuid.New()
+	if err := storage.SetDataCollection(ctx, data.ID, &unknown); err != ErrCollectionNotFound {
+		t.Errorf("SetDataCollection() error = %v, want %v", err, ErrCollectionNotFound)
+	}
+	if err := storage.SetDataCollection(ctx, uuid.New(), &work.ID); err != ErrDataNotFound {
+		t.Errorf("SetDataCollection() error = %v, want %v", err, ErrDataNotFound)
+	}
+	if err := storage.SetDataCollection(ctx, data.ID, &work.ID); err != nil {
+		t.Fatalf("SetDataCollection() error = %v", err)
+	}
+	if stored, _ := storage.GetDataByID(ctx, data.ID); stored.CollectionID == nil || *stored.CollectionID != work.ID || stored.Revision != 1 {
+		t.Errorf("Expected the data in Work at the same revision, got %+v", stored)
+	}
+	if data.CollectionID != nil {
+		t.Error("Expected the stored data to be a copy")
+	}
+
+	if err := storage.UpdateCollection(ctx, &models.Collection{ID: uuid.New()}); err != ErrCollectionNotFound {
+		t.Errorf("UpdateCollection() error = %v, want %v", err, ErrCollectionNotFound)
+	}
+	if err := storage.DeleteCollection(ctx, work.ID); err != nil {
+		t.Fatalf("DeleteCollection() error = %v", err)
+	}
+	if stored, _ := storage.GetDataByID(ctx, data.ID); stored.CollectionID != nil {
+		t.Errorf("Expected the data at the top level after deleting its collection, got %v", stored.CollectionID)
+	}
+	if err := storage.DeleteCollection(ctx, work.ID); err != ErrCollectionNotFound {
+		t.Errorf("DeleteCollection() error = %v, want %v", err, ErrCollectionNotFound)
+	}
+}
+
+func TestMemoryStorage_Shares(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	owner := &models.User{ID: uuid.New(), Username: "owner"}
+	recipient := &models.User{ID: uuid.New(), Username: "recipient"}
+	for _, user := range []*models.User{owner, recipient} {
+		if err := storage.CreateUser(ctx, user); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+	}
+
+	keys := &models.ShareKeys{PublicKey: []byte("public"), PrivateKey: []byte("private")}
+	if err := storage.SetShareKey(ctx, owner.ID, keys); err != nil {
+		t.Fatalf("SetShareKey() error = %v", err)
+	}
+	if err := storage.SetShareKey(ctx, owner.ID, &models.ShareKeys{PublicKey: []byte("public"), PrivateKey: []byte("rewrapped")}); err != nil {
+		t.Errorf("SetShareKey() with the same public key error = %v", err)
+	}
+	if err := storage.SetShareKey(ctx, owner.ID, &models.ShareKeys{PublicKey: []byte("other")}); err != ErrShareKeyAlreadySet {
+		t.Errorf("SetShareKey() error = %v, want %v", err, ErrShareKeyAlreadySet)
+	}
+	if stored, _ := storage.GetShareKey(ctx, owner.ID); stored == nil || string(stored.PrivateKey) != "rewrapped" {
+		t.Errorf("Expected the rewrapped private key, got %+v", stored)
+	}
+	if stored, err := storage.GetShareKey(ctx, recipient.ID); err != nil || stored != nil {
+		t.Errorf("GetShareKey() = %+v, %v, want no key", stored, err)
+	}
+
+	data := &models.Data{ID: uuid.New(), UserID: owner.ID, Type: models.DataTypeText, Name: "note"}
+	if err := storage.CreateData(ctx, data); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	share := &models.Share{ID: uuid.New(), DataID: data.ID, OwnerID: owner.ID, RecipientID: recipient.ID,
+		Mode: models.ShareModeRead, SealedKey: []byte("sealed")}
+	if err := storage.CreateShare(ctx, share); err != nil {
+		t.Fatalf("CreateShare() error = %v", err)
+	}
+	if err := storage.CreateShare(ctx, &models.Share{ID: uuid.New(), DataID: data.ID, RecipientID: uuid.New()}); err != ErrUserNotFound {
+		t.Errorf("CreateShare() error = %v, want %v", err, ErrUserNotFound)
+	}
+
+	changed := &models.Share{ID: uuid.New(), DataID: data.ID, OwnerID: owner.ID, RecipientID: recipient.ID,
+		Mode: models.ShareModeWrite, SealedKey: []byte("resealed")}
+	if err := storage.CreateShare(ctx, changed); err != nil {
+		t.Fatalf("CreateShare() error = %v", err)
+	}
+	if changed.ID != share.ID {
+		t.Errorf("Expected sharing again to keep the share ID %s, got %s", share.ID, changed.ID)
+	}
+	shares, _ := storage.GetShares(ctx, data.ID)
+	if len(shares) != 1 || shares[0].Mode != models.ShareModeWrite || shares[0].Recipient != "recipient" {
+		t.Errorf("Expected one write share with the recipient's name, got %+v", shares)
+	}
+	if shared, _ := storage.GetSharedWith(ctx, recipient.ID); len(shared) != 1 || string(shared[0].SealedKey) != "resealed" {
+		t.Errorf("Expected the item shared with the recipient, got %+v", shared)
+	}
+
+	if err := storage.DeleteData(ctx, data.ID); err != nil {
+		t.Fatalf("DeleteData() error = %v", err)
+	}
+	if _, err := storage.GetShare(ctx, share.ID); err != ErrShareNotFound {
+		t.Errorf("GetShare() error = %v, want %v", err, ErrShareNotFound)
+	}
+	if err := storage.DeleteShare(ctx, share.ID); err != ErrShareNotFound {
+		t.Errorf("DeleteShare() error = %v, want %v", err, ErrShareNotFound)
+	}
+}
+
+func TestMemoryStorage_Sessions(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	user := &models.User{ID: uuid.New(), Username: "user"}
+	if err := storage.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	older := &models.Session{ID: uuid.New(), UserID: user.ID, Device: "laptop", CreatedAt: now, LastSeenAt: now,
+		ExpiresAt: now.Add(time.Hour)}
+	newer := &models.Session{ID: uuid.New(), UserID: user.ID, Device: "desktop", CreatedAt: now,
+		LastSeenAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)}
+	expired := &models.Session{ID: uuid.New(), UserID: user.ID, CreatedAt: now, LastSeenAt: now, ExpiresAt: now}
+	for _, session := range []*models.Session{older, newer, expired} {
+		if err := storage.CreateSession(ctx, session); err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+	}
+	if err := storage.CreateSession(ctx, &models.Session{ID: uuid.New(), UserID: uuid.New()}); err != ErrUserNotFound {
+		t.Errorf("CreateSession() error = %v, want %v", err, ErrUserNotFound)
+	}
+
+	sessions, err := storage.GetSessions(ctx, user.ID, now)
+	if err != nil || len(sessions) != 2 || sessions[0].ID != newer.ID || sessions[1].ID != older.ID {
+		t.Fatalf("Expected the unexpired sessions most recently seen first, got %+v, %v", sessions, err)
+	}
+
+	if err := storage.TouchSession(ctx, older.ID, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("TouchSession() error = %v", err)
+	}
+	if sessions, _ := storage.GetSessions(ctx, user.ID, now); len(sessions) != 2 || sessions[0].ID != older.ID {
+		t.Errorf("Expected the touched session first, got %+v", sessions)
+	}
+
+	if err := storage.DeleteExpiredSessions(ctx, user.ID, now); err != nil {
+		t.Fatalf("DeleteExpiredSessions() error = %v", err)
+	}
+	if _, err := storage.GetSession(ctx, expired.ID); err != ErrSessionNotFound {
+		t.Errorf("GetSession() error = %v, want %v", err, ErrSessionNotFound)
+	}
+
+	if err := storage.DeleteSession(ctx, newer.ID); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+	if err := storage.DeleteSession(ctx, newer.ID); err != ErrSessionNotFound {
+		t.Errorf("DeleteSession() error = %v, want %v", err, ErrSessionNotFound)
+	}
+	if err := storage.TouchSession(ctx, newer.ID, now); err != ErrSessionNotFound {
+		t.Errorf("TouchSession() error = %v, want %v", err, ErrSessionNotFound)
+	}
+	if session, err := storage.GetSession(ctx, older.ID); err != nil || session.Device != "laptop" {
+		t.Errorf("GetSession() = %+v, %v", session, err)
+	}
+}
+
+func TestMemoryStorage_Identities(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	user := &models.User{ID: uuid.New(), Username: "user"}
+	if err := storage.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	identity := &models.Identity{Issuer: "https://idp.example.com", Subject: "sub", UserID: user.ID, CreatedAt: time.Now()}
+	if err := storage.CreateIdentity(ctx, identity); err != nil {
+		t.Fatalf("CreateIdentity() error = %v", err)
+	}
+	if err := storage.CreateIdentity(ctx, &models.Identity{Issuer: identity.Issuer, Subject: "sub", UserID: user.ID}); err != ErrIdentityExists {
+		t.Errorf("CreateIdentity() error = %v, want %v", err, ErrIdentityExists)
+	}
+	if err := storage.CreateIdentity(ctx, &models.Identity{Issuer: identity.Issuer, Subject: "other", UserID: uuid.New()}); err != ErrUserNotFound {
+		t.Errorf("CreateIdentity() error = %v, want %v", err, ErrUserNotFound)
+	}
+
+	found, err := storage.GetIdentity(ctx, identity.Issuer, "sub")
+	if err != nil || found.UserID != user.ID {
+		t.Fatalf("GetIdentity() = %+v, %v", found, err)
+	}
+	if _, err := storage.GetIdentity(ctx, "https://other.example.com", "sub"); err != ErrIdentityNotFound {
+		t.Errorf("Expected subjects to be qualified by their issuer, got %v", err)
+	}
+}
+
+func TestMemoryStorage_ApplyDataBatch(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	userID := uuid.New()
+	kept := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "kept"}
+	removed := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "removed"}
+	for _, data := range []*models.Data{kept, removed} {
+		if err := storage.CreateData(ctx, data); err != nil {
+			t.Fatalf("CreateData() error = %v", err)
+		}
+	}
+
+	created := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "created"}
+	stale := []models.DataChange{
+		{Op: models.BatchCreate, Data: created},
+		{Op: models.BatchUpdate, Data: &models.Data{ID: kept.ID, UserID: userID, Name: "renamed"}, BaseRevision: 2},
+	}
+	if err := storage.ApplyDataBatch(ctx, stale); err != ErrBatchConflict {
+		t.Fatalf("ApplyDataBatch() error = %v, want %v", err, ErrBatchConflict)
+	}
+	if _, err := storage.GetDataByID(ctx, created.ID); err != ErrDataNotFound {
+		t.Error("Expected nothing of a refused batch to be applied")
+	}
+
+	changes := []models.DataChange{
+		{Op: models.BatchCreate, Data: created},
+		{Op: models.BatchUpdate, Data: &models.Data{ID: kept.ID, UserID: userID, Name: "renamed"}, BaseRevision: 1},
+		{Op: models.BatchDelete, Data: removed},
+	}
+	if err := storage.ApplyDataBatch(ctx, changes); err != nil {
+		t.Fatalf("ApplyDataBatch() error = %v", err)
+	}
+	if data, err := storage.GetDataByID(ctx, created.ID); err != nil || data.Revision != 1 {
+		t.Errorf("Expected the created item at revision 1, got %+v, %v", data, err)
+	}
+	if data, _ := storage.GetDataByID(ctx, kept.ID); data.Name != "renamed" || data.Revision != 2 {
+		t.Errorf("Expected the updated item at revision 2, got %+v", data)
+	}
+	if versions, _ := storage.GetDataVersions(ctx, kept.ID); len(versions) != 1 || versions[0].Name != "kept" {
+		t.Errorf("Expected the replaced version to be kept, got %+v", versions)
+	}
+	if _, err := storage.GetDataByID(ctx, removed.ID); err != ErrDataNotFound {
+		t.Errorf("Expected the deleted item to be gone, got %v", err)
+	}
+	if err := storage.ApplyDataBatch(ctx, []models.DataChange{{Op: models.BatchDelete, Data: removed}}); err != ErrBatchConflict {
+		t.Errorf("ApplyDataBatch() deleting twice error = %v, want %v", err, ErrBatchConflict)
+	}
+}
@@ -3,8 +3,15 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
+	"github.com/a2sh3r/gophkeeper/internal/atrest"
+	"github.com/a2sh3r/gophkeeper/internal/clock"
 	"github.com/a2sh3r/gophkeeper/internal/logger"
 	"github.com/a2sh3r/gophkeeper/internal/models"
 	"github.com/google/uuid"
@@ -12,28 +19,166 @@ import (
 	"go.uber.org/zap"
 )
 
+// dataColumns lists the data table columns in scan order
+const dataColumns = `id, user_id, type, name, description, data, metadata, created_at, updated_at, environment, revision, tags,
+	collection_id, checksum, domains, icon, expires_at, expiry`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanData scans a data row selected with dataColumns
+func scanData(row rowScanner) (*models.Data, error) {
+	data := &models.Data{}
+	err := row.Scan(&data.ID, &data.UserID, &data.Type, &data.Name, &data.Description,
+		&data.Data, &data.Metadata, &data.CreatedAt, &data.UpdatedAt, &data.Environment, &data.Revision,
+		(*jsonStrings)(&data.Tags), &data.CollectionID, &data.Checksum, (*jsonStrings)(&data.Domains),
+		&data.Icon, &data.ExpiresAt, &data.Expiry)
+	return data, err
+}
+
+// jsonStrings stores a list of strings, such as the tags of an item, as a
+// JSONB array
+type jsonStrings []string
+
+// Value implements driver.Valuer
+func (t jsonStrings) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return "[]", nil
+	}
+	encoded, err := json.Marshal([]string(t))
+	return string(encoded), err
+}
+
+// Scan implements sql.Scanner; an empty array scans as nil
+func (t *jsonStrings) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a string list", src)
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return fmt.Errorf("invalid string list: %w", err)
+	}
+	if len(list) == 0 {
+		list = nil
+	}
+	*t = list
+	return nil
+}
+
 // PostgresStorage implements PostgreSQL storage
 type PostgresStorage struct {
-	db *sql.DB
+	db    *sql.DB
+	clock clock.Clock
+	// envelope encrypts the data of items and their versions at rest, if set
+	envelope *atrest.Envelope
+	// blobs stores the content chunks of binary items, if set
+	blobs BlobStore
+	// replicas serve reads of users and items, if set
+	replicas Replicas
 }
 
 // NewPostgresStorage creates new PostgreSQL storage
 func NewPostgresStorage(db *sql.DB) *PostgresStorage {
-	return &PostgresStorage{db: db}
+	return &PostgresStorage{db: db, clock: clock.System{}}
+}
+
+// SetClock sets the clock stamping update times. Call it before use.
+func (s *PostgresStorage) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetEnvelope enables encryption at rest of the data and icons of items, the
+// data of their versions, and of the published fields, comments, attachments
+// and chunks, including chunks stored as objects of the blob store. Values
+// written before stay readable and are sealed when they are next written.
+// Call it before use.
+func (s *PostgresStorage) SetEnvelope(e *atrest.Envelope) {
+	s.envelope = e
+}
+
+// seal encrypts value for storage if encryption at rest is enabled
+func (s *PostgresStorage) seal(ctx context.Context, value []byte) ([]byte, error) {
+	if s.envelope == nil {
+		return value, nil
+	}
+	sealed, err := s.envelope.Seal(ctx, value)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to encrypt data at rest", zap.Error(err))
+		return nil, fmt.Errorf("failed to encrypt data at rest: %w", err)
+	}
+	return sealed, nil
+}
+
+// open decrypts a value read from storage. Sealed values are always opened,
+// so they are refused rather than returned if the envelope is missing.
+func (s *PostgresStorage) open(ctx context.Context, value []byte) ([]byte, error) {
+	if s.envelope == nil {
+		if atrest.IsSealed(value) {
+			return nil, fmt.Errorf("data is encrypted at rest but no at-rest key is configured")
+		}
+		return value, nil
+	}
+	opened, err := s.envelope.Open(ctx, value)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to decrypt data at rest", zap.Error(err))
+		return nil, fmt.Errorf("failed to decrypt data at rest: %w", err)
+	}
+	return opened, nil
+}
+
+// sealData returns the data and icon of an item sealed for storage. An item
+// without an icon keeps none.
+func (s *PostgresStorage) sealData(ctx context.Context, data *models.Data) ([]byte, []byte, error) {
+	sealed, err := s.seal(ctx, data.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data.Icon) == 0 {
+		return sealed, data.Icon, nil
+	}
+	icon, err := s.seal(ctx, data.Icon)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sealed, icon, nil
+}
+
+// openData opens the data and icon of an item read from storage
+func (s *PostgresStorage) openData(ctx context.Context, data *models.Data) error {
+	var err error
+	if data.Data, err = s.open(ctx, data.Data); err != nil {
+		return err
+	}
+	if data.Icon, err = s.open(ctx, data.Icon); err != nil {
+		return err
+	}
+	return nil
 }
 
 // CreateUser creates a new user in PostgreSQL
 func (s *PostgresStorage) CreateUser(ctx context.Context, user *models.User) error {
-	query := `INSERT INTO users (id, username, password, master_password, salt, created_at, updated_at) 
-			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	query := `INSERT INTO users (id, username, password, master_password, salt, kdf_iterations, created_at, updated_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 
-	_, err := s.db.ExecContext(ctx, query, user.ID, user.Username, user.Password, user.MasterPassword, user.Salt, user.CreatedAt, user.UpdatedAt)
+	_, err := s.q(ctx).ExecContext(ctx, query, user.ID, user.Username, user.Password, user.MasterPassword, user.Salt,
+		user.KDFIterations, user.CreatedAt, user.UpdatedAt)
 	if err != nil {
 		if err.Error() == `duplicate key value violates unique constraint "users_username_key"` {
-			logger.Log.Warn("User already exists", zap.String("username", user.Username))
+			logger.FromContext(ctx).Warn("User already exists", zap.String("username", user.Username))
 			return ErrUserExists
 		}
-		logger.Log.Error("Failed to create user in database", zap.Error(err), zap.String("username", user.Username))
+		logger.FromContext(ctx).Error("Failed to create user in database", zap.Error(err), zap.String("username", user.Username))
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 	return nil
@@ -41,18 +186,19 @@ func (s *PostgresStorage) CreateUser(ctx context.Context, user *models.User) err
 
 // GetUserByUsername gets user by username
 func (s *PostgresStorage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
-	query := `SELECT id, username, password, master_password, salt, created_at, updated_at FROM users WHERE username = $1`
+	query := `SELECT id, username, password, master_password, salt, kdf_iterations, created_at, updated_at FROM users WHERE username = $1`
 
-	row := s.db.QueryRowContext(ctx, query, username)
 	user := &models.User{}
-
-	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.MasterPassword, &user.Salt, &user.CreatedAt, &user.UpdatedAt)
+	err := s.readRow(ctx, func(row *sql.Row) error {
+		return row.Scan(&user.ID, &user.Username, &user.Password, &user.MasterPassword, &user.Salt, &user.KDFIterations,
+			&user.CreatedAt, &user.UpdatedAt)
+	}, query, username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			logger.Log.Debug("User not found by username", zap.String("username", username))
+			logger.FromContext(ctx).Debug("User not found by username", zap.String("username", username))
 			return nil, ErrUserNotFound
 		}
-		logger.Log.Error("Failed to get user by username", zap.Error(err), zap.String("username", username))
+		logger.FromContext(ctx).Error("Failed to get user by username", zap.Error(err), zap.String("username", username))
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
@@ -61,33 +207,69 @@ func (s *PostgresStorage) GetUserByUsername(ctx context.Context, username string
 
 // GetUserByID gets user by ID
 func (s *PostgresStorage) GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
-	query := `SELECT id, username, password, master_password, salt, created_at, updated_at FROM users WHERE id = $1`
+	query := `SELECT id, username, password, master_password, salt, kdf_iterations, created_at, updated_at FROM users WHERE id = $1`
 
-	row := s.db.QueryRowContext(ctx, query, userID)
 	user := &models.User{}
-
-	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.MasterPassword, &user.Salt, &user.CreatedAt, &user.UpdatedAt)
+	err := s.readRow(ctx, func(row *sql.Row) error {
+		return row.Scan(&user.ID, &user.Username, &user.Password, &user.MasterPassword, &user.Salt, &user.KDFIterations,
+			&user.CreatedAt, &user.UpdatedAt)
+	}, query, userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			logger.Log.Debug("User not found by ID", zap.String("user_id", userID.String()))
+			logger.FromContext(ctx).Debug("User not found by ID", zap.String("user_id", userID.String()))
 			return nil, ErrUserNotFound
 		}
-		logger.Log.Error("Failed to get user by ID", zap.Error(err), zap.String("user_id", userID.String()))
+		logger.FromContext(ctx).Error("Failed to get user by ID", zap.Error(err), zap.String("user_id", userID.String()))
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	return user, nil
 }
 
-// CreateData creates new data
+// SetUserSalt sets user's salt once; re-sending the same salt is a no-op
+func (s *PostgresStorage) SetUserSalt(ctx context.Context, userID uuid.UUID, salt string) error {
+	query := `UPDATE users SET salt = $2, updated_at = $3 WHERE id = $1 AND (salt = '' OR salt = $2)`
+
+	result, err := s.q(ctx).ExecContext(ctx, query, userID, salt, s.clock.Now())
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to set user salt", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("failed to set salt: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get rows affected for salt update", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		if _, err := s.GetUserByID(ctx, userID); err != nil {
+			return err
+		}
+		logger.FromContext(ctx).Warn("Attempt to overwrite user salt", zap.String("user_id", userID.String()))
+		return ErrSaltAlreadySet
+	}
+
+	return nil
+}
+
+// CreateData creates new data at revision 1
 func (s *PostgresStorage) CreateData(ctx context.Context, data *models.Data) error {
-	query := `INSERT INTO data (id, user_id, type, name, description, data, metadata, created_at, updated_at) 
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	query := `INSERT INTO data (` + dataColumns + `) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`
+
+	sealed, icon, err := s.sealData(ctx, data)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.ExecContext(ctx, query, data.ID, data.UserID, data.Type, data.Name, data.Description,
-		data.Data, data.Metadata, data.CreatedAt, data.UpdatedAt)
+	data.Revision = 1
+	_, err = s.q(ctx).ExecContext(ctx, query, data.ID, data.UserID, data.Type, data.Name, data.Description,
+		sealed, data.Metadata, data.CreatedAt, data.UpdatedAt, data.Environment, data.Revision, jsonStrings(data.Tags),
+		data.CollectionID, data.Checksum, jsonStrings(data.Domains), icon, data.ExpiresAt, data.Expiry)
 	if err != nil {
-		logger.Log.Error("Failed to create data in database", zap.Error(err),
+		logger.FromContext(ctx).Error("Failed to create data in database", zap.Error(err),
 			zap.String("data_id", data.ID.String()), zap.String("user_id", data.UserID.String()))
 		return fmt.Errorf("failed to create data: %w", err)
 	}
@@ -96,87 +278,188 @@ func (s *PostgresStorage) CreateData(ctx context.Context, data *models.Data) err
 
 // GetDataByID gets data by ID
 func (s *PostgresStorage) GetDataByID(ctx context.Context, dataID uuid.UUID) (*models.Data, error) {
-	query := `SELECT id, user_id, type, name, description, data, metadata, created_at, updated_at 
+	query := `SELECT ` + dataColumns + ` 
 			  FROM data WHERE id = $1`
 
-	row := s.db.QueryRowContext(ctx, query, dataID)
-	data := &models.Data{}
-
-	err := row.Scan(&data.ID, &data.UserID, &data.Type, &data.Name, &data.Description,
-		&data.Data, &data.Metadata, &data.CreatedAt, &data.UpdatedAt)
+	var data *models.Data
+	err := s.readRow(ctx, func(row *sql.Row) (err error) {
+		data, err = scanData(row)
+		return err
+	}, query, dataID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			logger.Log.Debug("Data not found by ID", zap.String("data_id", dataID.String()))
+			logger.FromContext(ctx).Debug("Data not found by ID", zap.String("data_id", dataID.String()))
 			return nil, ErrDataNotFound
 		}
-		logger.Log.Error("Failed to get data by ID", zap.Error(err), zap.String("data_id", dataID.String()))
+		logger.FromContext(ctx).Error("Failed to get data by ID", zap.Error(err), zap.String("data_id", dataID.String()))
 		return nil, fmt.Errorf("failed to get data: %w", err)
 	}
+	if err := s.openData(ctx, data); err != nil {
+		return nil, err
+	}
 
 	return data, nil
 }
 
 // GetDataByUserID gets all data for a user
 func (s *PostgresStorage) GetDataByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Data, error) {
-	query := `SELECT id, user_id, type, name, description, data, metadata, created_at, updated_at 
-			  FROM data WHERE user_id = $1 ORDER BY created_at DESC`
+	query := `SELECT ` + dataColumns + ` 
+			  FROM data WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	q, _ := s.r(ctx)
+	rows, err := q.QueryContext(ctx, query, userID)
 	if err != nil {
-		logger.Log.Error("Failed to query user data", zap.Error(err), zap.String("user_id", userID.String()))
+		logger.FromContext(ctx).Error("Failed to query user data", zap.Error(err), zap.String("user_id", userID.String()))
 		return nil, fmt.Errorf("failed to query data: %w", err)
 	}
 	defer func() {
 		if err := rows.Close(); err != nil {
-			logger.Log.Error("Failed to close database", zap.Error(err))
+			logger.FromContext(ctx).Error("Failed to close database", zap.Error(err))
 		}
 	}()
 
 	var dataList []*models.Data
 	for rows.Next() {
-		data := &models.Data{}
-		err := rows.Scan(&data.ID, &data.UserID, &data.Type, &data.Name, &data.Description,
-			&data.Data, &data.Metadata, &data.CreatedAt, &data.UpdatedAt)
+		data, err := scanData(rows)
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to scan data row", zap.Error(err), zap.String("user_id", userID.String()))
+			return nil, fmt.Errorf("failed to scan data: %w", err)
+		}
+		if err := s.openData(ctx, data); err != nil {
+			return nil, err
+		}
+		dataList = append(dataList, data)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("Rows iteration error", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return dataList, nil
+}
+
+// GetDataByUserIDFiltered gets the page of user data matching the filter, in
+// the order of GetDataByUserID
+func (s *PostgresStorage) GetDataByUserIDFiltered(ctx context.Context, userID uuid.UUID, filter models.DataFilter) ([]*models.Data, error) {
+	query := `SELECT ` + dataColumns + ` FROM data WHERE user_id = $1`
+	args := []interface{}{userID}
+	if filter.Environment != "" {
+		args = append(args, filter.Environment)
+		query += fmt.Sprintf(" AND environment = $%d", len(args))
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		query += fmt.Sprintf(" AND type = $%d", len(args))
+	}
+	if filter.Name != "" {
+		// strpos rather than LIKE, so % and _ in the search are literal
+		args = append(args, filter.Name)
+		query += fmt.Sprintf(" AND strpos(lower(name), lower($%d)) > 0", len(args))
+	}
+	if filter.Query != "" {
+		// ILIKE with the wildcards escaped, so the trigram indexes apply
+		args = append(args, "%"+escapeLike(filter.Query)+"%")
+		query += fmt.Sprintf(" AND (name ILIKE $%[1]d OR description ILIKE $%[1]d OR metadata ILIKE $%[1]d)", len(args))
+	}
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		query += fmt.Sprintf(" AND tags ? $%d", len(args))
+	}
+	if filter.Domain != "" {
+		args = append(args, filter.Domain)
+		query += fmt.Sprintf(" AND domains ? $%d", len(args))
+	}
+	if !filter.ExpiringBefore.IsZero() {
+		args = append(args, filter.ExpiringBefore)
+		query += fmt.Sprintf(" AND expires_at <= $%d", len(args))
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	q, _ := s.r(ctx)
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to query user data", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to query data: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("Failed to close database", zap.Error(err))
+		}
+	}()
+
+	var dataList []*models.Data
+	for rows.Next() {
+		data, err := scanData(rows)
 		if err != nil {
-			logger.Log.Error("Failed to scan data row", zap.Error(err), zap.String("user_id", userID.String()))
+			logger.FromContext(ctx).Error("Failed to scan data row", zap.Error(err), zap.String("user_id", userID.String()))
 			return nil, fmt.Errorf("failed to scan data: %w", err)
 		}
+		if err := s.openData(ctx, data); err != nil {
+			return nil, err
+		}
 		dataList = append(dataList, data)
 	}
 
 	if err = rows.Err(); err != nil {
-		logger.Log.Error("Rows iteration error", zap.Error(err), zap.String("user_id", userID.String()))
+		logger.FromContext(ctx).Error("Rows iteration error", zap.Error(err), zap.String("user_id", userID.String()))
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
 
 	return dataList, nil
 }
 
-// UpdateData updates data
+// escapeLike escapes the LIKE wildcards and the escape character in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// UpdateData updates data, keeping the replaced version, and advances its
+// revision. Both statements of the query see the row as it was before the
+// update. data is expected to be at the stored revision, as read before.
 func (s *PostgresStorage) UpdateData(ctx context.Context, data *models.Data) error {
-	query := `UPDATE data SET type = $2, name = $3, description = $4, data = $5, metadata = $6, updated_at = $7 
-			  WHERE id = $1`
+	query := `WITH previous AS (
+			  INSERT INTO data_versions (data_id, version, type, name, description, data, metadata, environment, updated_at, checksum)
+			  SELECT id, COALESCE((SELECT MAX(version) FROM data_versions WHERE data_id = $1), 0) + 1,
+			  type, name, description, data, metadata, environment, updated_at, checksum FROM data WHERE id = $1)
+			  UPDATE data SET type = $2, name = $3, description = $4, data = $5, metadata = $6, updated_at = $7, 
+			  environment = $8, tags = $9, checksum = $10, domains = $11, icon = $12, expires_at = $13, expiry = $14, revision = revision + 1 WHERE id = $1`
+
+	sealed, icon, err := s.sealData(ctx, data)
+	if err != nil {
+		return err
+	}
 
-	result, err := s.db.ExecContext(ctx, query, data.ID, data.Type, data.Name, data.Description,
-		data.Data, data.Metadata, data.UpdatedAt)
+	result, err := s.q(ctx).ExecContext(ctx, query, data.ID, data.Type, data.Name, data.Description,
+		sealed, data.Metadata, data.UpdatedAt, data.Environment, jsonStrings(data.Tags), data.Checksum, jsonStrings(data.Domains), icon,
+		data.ExpiresAt, data.Expiry)
 	if err != nil {
-		logger.Log.Error("Failed to update data in database", zap.Error(err),
+		logger.FromContext(ctx).Error("Failed to update data in database", zap.Error(err),
 			zap.String("data_id", data.ID.String()))
 		return fmt.Errorf("failed to update data: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		logger.Log.Error("Failed to get rows affected for update", zap.Error(err),
+		logger.FromContext(ctx).Error("Failed to get rows affected for update", zap.Error(err),
 			zap.String("data_id", data.ID.String()))
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		logger.Log.Debug("Data not found for update", zap.String("data_id", data.ID.String()))
+		logger.FromContext(ctx).Debug("Data not found for update", zap.String("data_id", data.ID.String()))
 		return ErrDataNotFound
 	}
 
+	data.Revision++
 	return nil
 }
 
@@ -184,24 +467,1492 @@ func (s *PostgresStorage) UpdateData(ctx context.Context, data *models.Data) err
 func (s *PostgresStorage) DeleteData(ctx context.Context, dataID uuid.UUID) error {
 	query := `DELETE FROM data WHERE id = $1`
 
-	result, err := s.db.ExecContext(ctx, query, dataID)
+	result, err := s.q(ctx).ExecContext(ctx, query, dataID)
 	if err != nil {
-		logger.Log.Error("Failed to delete data from database", zap.Error(err),
+		logger.FromContext(ctx).Error("Failed to delete data from database", zap.Error(err),
 			zap.String("data_id", dataID.String()))
 		return fmt.Errorf("failed to delete data: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		logger.Log.Error("Failed to get rows affected for delete", zap.Error(err),
+		logger.FromContext(ctx).Error("Failed to get rows affected for delete", zap.Error(err),
+			zap.String("data_id", dataID.String()))
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		logger.FromContext(ctx).Debug("Data not found for deletion", zap.String("data_id", dataID.String()))
+		return ErrDataNotFound
+	}
+
+	return nil
+}
+
+// ApplyDataBatch applies the changes of a batch in order in one transaction
+func (s *PostgresStorage) ApplyDataBatch(ctx context.Context, changes []models.DataChange) error {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to begin batch", zap.Error(err))
+		return fmt.Errorf("failed to begin batch: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.FromContext(ctx).Error("Failed to roll back batch", zap.Error(err))
+		}
+	}()
+
+	for _, change := range changes {
+		data := change.Data
+		var sealed, icon []byte
+		if change.Op != models.BatchDelete {
+			if sealed, icon, err = s.sealData(ctx, data); err != nil {
+				return err
+			}
+		}
+		var result sql.Result
+		switch change.Op {
+		case models.BatchCreate:
+			data.Revision = 1
+			result, err = tx.ExecContext(ctx, `INSERT INTO data (`+dataColumns+`) 
+					  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
+				data.ID, data.UserID, data.Type, data.Name, data.Description, sealed, data.Metadata,
+				data.CreatedAt, data.UpdatedAt, data.Environment, data.Revision, jsonStrings(data.Tags), data.CollectionID,
+				data.Checksum, jsonStrings(data.Domains), icon, data.ExpiresAt, data.Expiry)
+		case models.BatchUpdate:
+			result, err = tx.ExecContext(ctx, `WITH previous AS (
+					  INSERT INTO data_versions (data_id, version, type, name, description, data, metadata, environment, updated_at, checksum)
+					  SELECT id, COALESCE((SELECT MAX(version) FROM data_versions WHERE data_id = $1), 0) + 1,
+					  type, name, description, data, metadata, environment, updated_at, checksum FROM data WHERE id = $1 AND revision = $10)
+					  UPDATE data SET type = $2, name = $3, description = $4, data = $5, metadata = $6, updated_at = $7, 
+					  environment = $8, tags = $9, checksum = $11, domains = $12, icon = $13, expires_at = $14, expiry = $15, revision = revision + 1 WHERE id = $1 AND revision = $10`,
+				data.ID, data.Type, data.Name, data.Description, sealed, data.Metadata, data.UpdatedAt,
+				data.Environment, jsonStrings(data.Tags), change.BaseRevision, data.Checksum, jsonStrings(data.Domains), icon,
+				data.ExpiresAt, data.Expiry)
+		case models.BatchDelete:
+			result, err = tx.ExecContext(ctx, `DELETE FROM data WHERE id = $1`, data.ID)
+		default:
+			return fmt.Errorf("unknown batch operation %q", change.Op)
+		}
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to apply batch operation", zap.Error(err),
+				zap.String("op", change.Op), zap.String("data_id", data.ID.String()))
+			return fmt.Errorf("failed to %s data: %w", change.Op, err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return ErrBatchConflict
+		}
+		if change.Op == models.BatchUpdate {
+			data.Revision = change.BaseRevision + 1
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.FromContext(ctx).Error("Failed to commit batch", zap.Error(err))
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+	return nil
+}
+
+// FlagExpiringData updates the expiry flags of the items with an expiry, as
+// of now with a warning period, and returns the items whose flag changed with
+// their ID, owner, revision and flag. Flagging does not advance the revision.
+func (s *PostgresStorage) FlagExpiringData(ctx context.Context, now time.Time, warning time.Duration) ([]*models.Data, error) {
+	status := `CASE WHEN expires_at <= $1 THEN '` + models.ExpiryExpired + `'
+			  WHEN expires_at <= $2 THEN '` + models.ExpiryExpiring + `' ELSE '' END`
+	query := `UPDATE data SET expiry = ` + status + `
+			  WHERE expires_at IS NOT NULL AND expiry <> ` + status + `
+			  RETURNING id, user_id, revision, expiry`
+
+	rows, err := s.q(ctx).QueryContext(ctx, query, now, now.Add(warning))
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to flag expiring data", zap.Error(err))
+		return nil, fmt.Errorf("failed to flag expiring data: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("Failed to close database", zap.Error(err))
+		}
+	}()
+
+	var flagged []*models.Data
+	for rows.Next() {
+		data := &models.Data{}
+		if err := rows.Scan(&data.ID, &data.UserID, &data.Revision, &data.Expiry); err != nil {
+			return nil, fmt.Errorf("failed to scan flagged data: %w", err)
+		}
+		flagged = append(flagged, data)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return flagged, nil
+}
+
+// SetDataField creates or replaces a published field of existing data
+func (s *PostgresStorage) SetDataField(ctx context.Context, field *models.DataField) error {
+	query := `INSERT INTO data_fields (data_id, name, ciphertext, created_at, updated_at) 
+			  SELECT $1, $2, $3, $4, $5 WHERE EXISTS (SELECT 1 FROM data WHERE id = $1)
+			  ON CONFLICT (data_id, name) DO UPDATE SET ciphertext = EXCLUDED.ciphertext, updated_at = EXCLUDED.updated_at`
+
+	sealed, err := s.seal(ctx, field.Ciphertext)
+	if err != nil {
+		return err
+	}
+	result, err := s.q(ctx).ExecContext(ctx, query, field.DataID, field.Name, sealed, field.CreatedAt, field.UpdatedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to set data field in database", zap.Error(err),
+			zap.String("data_id", field.DataID.String()), zap.String("field", field.Name))
+		return fmt.Errorf("failed to set data field: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get rows affected for data field", zap.Error(err),
+			zap.String("data_id", field.DataID.String()))
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		logger.FromContext(ctx).Debug("Data not found for field", zap.String("data_id", field.DataID.String()))
+		return ErrDataNotFound
+	}
+
+	return nil
+}
+
+// GetDataField gets a published field
+func (s *PostgresStorage) GetDataField(ctx context.Context, dataID uuid.UUID, name string) (*models.DataField, error) {
+	query := `SELECT data_id, name, ciphertext, created_at, updated_at 
+			  FROM data_fields WHERE data_id = $1 AND name = $2`
+
+	field := &models.DataField{}
+	err := s.readRow(ctx, func(row *sql.Row) error {
+		return row.Scan(&field.DataID, &field.Name, &field.Ciphertext, &field.CreatedAt, &field.UpdatedAt)
+	}, query, dataID, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			logger.FromContext(ctx).Debug("Data field not found", zap.String("data_id", dataID.String()), zap.String("field", name))
+			return nil, ErrFieldNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get data field from database", zap.Error(err),
+			zap.String("data_id", dataID.String()), zap.String("field", name))
+		return nil, fmt.Errorf("failed to get data field: %w", err)
+	}
+	if field.Ciphertext, err = s.open(ctx, field.Ciphertext); err != nil {
+		return nil, err
+	}
+
+	return field, nil
+}
+
+// AddDataComment appends a comment to existing data
+func (s *PostgresStorage) AddDataComment(ctx context.Context, comment *models.DataComment) error {
+	query := `INSERT INTO data_comments (id, data_id, ciphertext, created_at) 
+			  SELECT $1, $2, $3, $4 WHERE EXISTS (SELECT 1 FROM data WHERE id = $2)`
+
+	sealed, err := s.seal(ctx, comment.Ciphertext)
+	if err != nil {
+		return err
+	}
+	result, err := s.q(ctx).ExecContext(ctx, query, comment.ID, comment.DataID, sealed, comment.CreatedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to add data comment to database", zap.Error(err),
+			zap.String("data_id", comment.DataID.String()))
+		return fmt.Errorf("failed to add data comment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get rows affected for data comment", zap.Error(err),
+			zap.String("data_id", comment.DataID.String()))
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		logger.FromContext(ctx).Debug("Data not found for comment", zap.String("data_id", comment.DataID.String()))
+		return ErrDataNotFound
+	}
+
+	return nil
+}
+
+// GetDataComments gets the comments of data, oldest first
+func (s *PostgresStorage) GetDataComments(ctx context.Context, dataID uuid.UUID) ([]*models.DataComment, error) {
+	query := `SELECT id, data_id, ciphertext, created_at 
+			  FROM data_comments WHERE data_id = $1 ORDER BY created_at, id`
+
+	q, _ := s.r(ctx)
+	rows, err := q.QueryContext(ctx, query, dataID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get data comments from database", zap.Error(err),
 			zap.String("data_id", dataID.String()))
+		return nil, fmt.Errorf("failed to get data comments: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("Failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var comments []*models.DataComment
+	for rows.Next() {
+		comment := &models.DataComment{}
+		if err := rows.Scan(&comment.ID, &comment.DataID, &comment.Ciphertext, &comment.CreatedAt); err != nil {
+			logger.FromContext(ctx).Error("Failed to scan data comment", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan data comment: %w", err)
+		}
+		if comment.Ciphertext, err = s.open(ctx, comment.Ciphertext); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("Rows iteration error", zap.Error(err), zap.String("data_id", dataID.String()))
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return comments, nil
+}
+
+// AddDataAttachment attaches a file to existing data
+func (s *PostgresStorage) AddDataAttachment(ctx context.Context, attachment *models.DataAttachment) error {
+	query := `INSERT INTO data_attachments (id, data_id, info, size, content, created_at) 
+			  SELECT $1, $2, $3, $4, $5, $6 WHERE EXISTS (SELECT 1 FROM data WHERE id = $2)`
+
+	info, err := s.seal(ctx, attachment.Info)
+	if err != nil {
+		return err
+	}
+	content, err := s.seal(ctx, attachment.Content)
+	if err != nil {
+		return err
+	}
+	result, err := s.q(ctx).ExecContext(ctx, query, attachment.ID, attachment.DataID, info, attachment.Size,
+		content, attachment.CreatedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to add data attachment to database", zap.Error(err),
+			zap.String("data_id", attachment.DataID.String()))
+		return fmt.Errorf("failed to add data attachment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get rows affected for data attachment", zap.Error(err),
+			zap.String("data_id", attachment.DataID.String()))
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		logger.Log.Debug("Data not found for deletion", zap.String("data_id", dataID.String()))
+		logger.FromContext(ctx).Debug("Data not found for attachment", zap.String("data_id", attachment.DataID.String()))
 		return ErrDataNotFound
 	}
 
 	return nil
 }
+
+// GetDataAttachments gets the attachments of data without their content, oldest first
+func (s *PostgresStorage) GetDataAttachments(ctx context.Context, dataID uuid.UUID) ([]*models.DataAttachment, error) {
+	query := `SELECT id, data_id, info, size, created_at 
+			  FROM data_attachments WHERE data_id = $1 ORDER BY created_at, id`
+
+	q, _ := s.r(ctx)
+	rows, err := q.QueryContext(ctx, query, dataID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get data attachments from database", zap.Error(err),
+			zap.String("data_id", dataID.String()))
+		return nil, fmt.Errorf("failed to get data attachments: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("Failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var attachments []*models.DataAttachment
+	for rows.Next() {
+		attachment := &models.DataAttachment{}
+		if err := rows.Scan(&attachment.ID, &attachment.DataID, &attachment.Info, &attachment.Size, &attachment.CreatedAt); err != nil {
+			logger.FromContext(ctx).Error("Failed to scan data attachment", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan data attachment: %w", err)
+		}
+		if attachment.Info, err = s.open(ctx, attachment.Info); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("Rows iteration error", zap.Error(err), zap.String("data_id", dataID.String()))
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// GetDataAttachment gets an attachment of data with its content
+func (s *PostgresStorage) GetDataAttachment(ctx context.Context, dataID, attachmentID uuid.UUID) (*models.DataAttachment, error) {
+	query := `SELECT id, data_id, info, size, content, created_at 
+			  FROM data_attachments WHERE id = $1 AND data_id = $2`
+
+	attachment := &models.DataAttachment{}
+	err := s.readRow(ctx, func(row *sql.Row) error {
+		return row.Scan(&attachment.ID, &attachment.DataID, &attachment.Info, &attachment.Size, &attachment.Content,
+			&attachment.CreatedAt)
+	}, query, attachmentID, dataID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAttachmentNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get data attachment from database", zap.Error(err),
+			zap.String("data_id", dataID.String()), zap.String("attachment_id", attachmentID.String()))
+		return nil, fmt.Errorf("failed to get data attachment: %w", err)
+	}
+	if attachment.Info, err = s.open(ctx, attachment.Info); err != nil {
+		return nil, err
+	}
+	if attachment.Content, err = s.open(ctx, attachment.Content); err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// DeleteDataAttachment deletes an attachment of data
+func (s *PostgresStorage) DeleteDataAttachment(ctx context.Context, dataID, attachmentID uuid.UUID) error {
+	result, err := s.q(ctx).ExecContext(ctx, `DELETE FROM data_attachments WHERE id = $1 AND data_id = $2`, attachmentID, dataID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to delete data attachment from database", zap.Error(err),
+			zap.String("data_id", dataID.String()), zap.String("attachment_id", attachmentID.String()))
+		return fmt.Errorf("failed to delete data attachment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get rows affected for data attachment", zap.Error(err),
+			zap.String("data_id", dataID.String()))
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrAttachmentNotFound
+	}
+
+	return nil
+}
+
+// versionColumns lists the data_versions table columns in scan order
+const versionColumns = `data_id, version, type, name, description, data, metadata, environment, updated_at, checksum`
+
+// scanVersion scans a data_versions row selected with versionColumns
+func scanVersion(row rowScanner) (*models.DataVersion, error) {
+	version := &models.DataVersion{}
+	err := row.Scan(&version.DataID, &version.Version, &version.Type, &version.Name, &version.Description,
+		&version.Data, &version.Metadata, &version.Environment, &version.UpdatedAt, &version.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// GetDataVersions gets the previous versions of data, newest first
+func (s *PostgresStorage) GetDataVersions(ctx context.Context, dataID uuid.UUID) ([]*models.DataVersion, error) {
+	query := `SELECT ` + versionColumns + ` 
+			  FROM data_versions WHERE data_id = $1 ORDER BY version DESC`
+
+	q, _ := s.r(ctx)
+	rows, err := q.QueryContext(ctx, query, dataID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get data versions from database", zap.Error(err),
+			zap.String("data_id", dataID.String()))
+		return nil, fmt.Errorf("failed to get data versions: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("Failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var versions []*models.DataVersion
+	for rows.Next() {
+		version, err := scanVersion(rows)
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to scan data version", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan data version: %w", err)
+		}
+		if version.Data, err = s.open(ctx, version.Data); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("Rows iteration error", zap.Error(err), zap.String("data_id", dataID.String()))
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetDataVersion gets a previous version of data
+func (s *PostgresStorage) GetDataVersion(ctx context.Context, dataID uuid.UUID, version int) (*models.DataVersion, error) {
+	query := `SELECT ` + versionColumns + ` 
+			  FROM data_versions WHERE data_id = $1 AND version = $2`
+
+	var dataVersion *models.DataVersion
+	err := s.readRow(ctx, func(row *sql.Row) (err error) {
+		dataVersion, err = scanVersion(row)
+		return err
+	}, query, dataID, version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrVersionNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get data version", zap.Error(err), zap.String("data_id", dataID.String()),
+			zap.Int("version", version))
+		return nil, fmt.Errorf("failed to get data version: %w", err)
+	}
+	if dataVersion.Data, err = s.open(ctx, dataVersion.Data); err != nil {
+		return nil, err
+	}
+
+	return dataVersion, nil
+}
+
+// CreateCollection creates a collection
+func (s *PostgresStorage) CreateCollection(ctx context.Context, collection *models.Collection) error {
+	query := `INSERT INTO collections (id, user_id, name, parent_id, created_at, updated_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := s.q(ctx).ExecContext(ctx, query, collection.ID, collection.UserID, collection.Name, collection.ParentID,
+		collection.CreatedAt, collection.UpdatedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to create collection in database", zap.Error(err),
+			zap.String("user_id", collection.UserID.String()))
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	return nil
+}
+
+// GetCollectionsByUserID gets all collections of a user, sorted by name
+func (s *PostgresStorage) GetCollectionsByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Collection, error) {
+	query := `SELECT id, user_id, name, parent_id, created_at, updated_at 
+			  FROM collections WHERE user_id = $1 ORDER BY name, id`
+
+	rows, err := s.q(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get collections from database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("Failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var collections []*models.Collection
+	for rows.Next() {
+		collection := &models.Collection{}
+		if err := rows.Scan(&collection.ID, &collection.UserID, &collection.Name, &collection.ParentID,
+			&collection.CreatedAt, &collection.UpdatedAt); err != nil {
+			logger.FromContext(ctx).Error("Failed to scan collection", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan collection: %w", err)
+		}
+		collections = append(collections, collection)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("Rows iteration error", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return collections, nil
+}
+
+// UpdateCollection renames or moves a collection
+func (s *PostgresStorage) UpdateCollection(ctx context.Context, collection *models.Collection) error {
+	query := `UPDATE collections SET name = $2, parent_id = $3, updated_at = $4 WHERE id = $1`
+
+	result, err := s.q(ctx).ExecContext(ctx, query, collection.ID, collection.Name, collection.ParentID, collection.UpdatedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to update collection in database", zap.Error(err),
+			zap.String("collection_id", collection.ID.String()))
+		return fmt.Errorf("failed to update collection: %w", err)
+	}
+	return collectionAffected(result, collection.ID)
+}
+
+// DeleteCollection deletes a collection; items filed in it move to the top level
+func (s *PostgresStorage) DeleteCollection(ctx context.Context, collectionID uuid.UUID) error {
+	result, err := s.q(ctx).ExecContext(ctx, `DELETE FROM collections WHERE id = $1`, collectionID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to delete collection from database", zap.Error(err),
+			zap.String("collection_id", collectionID.String()))
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return collectionAffected(result, collectionID)
+}
+
+// collectionAffected returns ErrCollectionNotFound if a statement on a
+// collection affected no row
+func collectionAffected(result sql.Result, collectionID uuid.UUID) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Log.Error("Failed to get rows affected for collection", zap.Error(err),
+			zap.String("collection_id", collectionID.String()))
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// SetDataCollection files data in a collection, or at the top level when
+// collectionID is nil
+func (s *PostgresStorage) SetDataCollection(ctx context.Context, dataID uuid.UUID, collectionID *uuid.UUID) error {
+	result, err := s.q(ctx).ExecContext(ctx, `UPDATE data SET collection_id = $2 WHERE id = $1`, dataID, collectionID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to set data collection in database", zap.Error(err),
+			zap.String("data_id", dataID.String()))
+		return fmt.Errorf("failed to set data collection: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get rows affected for data collection", zap.Error(err),
+			zap.String("data_id", dataID.String()))
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrDataNotFound
+	}
+	return nil
+}
+
+// PutDataChunk stores the content chunk of existing data at index, replacing
+// one stored before. Chunks are stored in order, so index may be at most the
+// number of chunks so far. With a blob store the chunk is stored as an object.
+func (s *PostgresStorage) PutDataChunk(ctx context.Context, dataID uuid.UUID, index int, chunk []byte) error {
+	if err := s.checkChunkData(ctx, dataID); err != nil {
+		return err
+	}
+	if s.blobs == nil {
+		sealed, err := s.seal(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		return s.upsertDataChunk(ctx, dataID, index, "data", sealed)
+	}
+
+	key, err := s.putChunkObject(ctx, dataID, index, chunk)
+	if err != nil {
+		return err
+	}
+	if err := s.upsertDataChunk(ctx, dataID, index, "object_key", key); err != nil {
+		s.discardObjects(ctx, key)
+		return err
+	}
+	return nil
+}
+
+// checkChunkData checks that the data a chunk is stored for exists
+func (s *PostgresStorage) checkChunkData(ctx context.Context, dataID uuid.UUID) error {
+	var exists bool
+	if err := s.q(ctx).QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM data WHERE id = $1)`, dataID).Scan(&exists); err != nil {
+		logger.FromContext(ctx).Error("Failed to check data for chunk", zap.Error(err), zap.String("data_id", dataID.String()))
+		return fmt.Errorf("failed to check data: %w", err)
+	}
+	if !exists {
+		return ErrDataNotFound
+	}
+	return nil
+}
+
+// upsertDataChunk stores a chunk at index, as its content in the data column
+// or as the key of its object in the object_key column, clearing the other
+func (s *PostgresStorage) upsertDataChunk(ctx context.Context, dataID uuid.UUID, index int, column string, value interface{}) error {
+	other := "object_key"
+	if column == "object_key" {
+		other = "data"
+	}
+	query := `INSERT INTO data_chunks (data_id, chunk_index, ` + column + `) 
+			  SELECT $1, $2, $3 WHERE $2 <= (SELECT COUNT(*) FROM data_chunks WHERE data_id = $1)
+			  ON CONFLICT (data_id, chunk_index) DO UPDATE SET ` + column + ` = EXCLUDED.` + column + `, ` + other + ` = NULL`
+
+	result, err := s.q(ctx).ExecContext(ctx, query, dataID, index, value)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to store data chunk", zap.Error(err),
+			zap.String("data_id", dataID.String()), zap.Int("index", index))
+		return fmt.Errorf("failed to store data chunk: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get rows affected for data chunk", zap.Error(err),
+			zap.String("data_id", dataID.String()))
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		logger.FromContext(ctx).Debug("Data chunk out of order", zap.String("data_id", dataID.String()), zap.Int("index", index))
+		return ErrChunkOutOfOrder
+	}
+
+	return nil
+}
+
+// GetDataChunk gets the content chunk of data at index, from its object if it
+// is stored as one
+func (s *PostgresStorage) GetDataChunk(ctx context.Context, dataID uuid.UUID, index int) ([]byte, error) {
+	query := `SELECT data, object_key FROM data_chunks WHERE data_id = $1 AND chunk_index = $2`
+
+	var chunk []byte
+	var key sql.NullString
+	err := s.readRow(ctx, func(row *sql.Row) error {
+		return row.Scan(&chunk, &key)
+	}, query, dataID, index)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrChunkNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get data chunk from database", zap.Error(err),
+			zap.String("data_id", dataID.String()), zap.Int("index", index))
+		return nil, fmt.Errorf("failed to get data chunk: %w", err)
+	}
+	if key.Valid {
+		return s.getChunkObject(ctx, key.String)
+	}
+
+	return s.open(ctx, chunk)
+}
+
+// CountDataChunks counts the content chunks of data
+func (s *PostgresStorage) CountDataChunks(ctx context.Context, dataID uuid.UUID) (int, error) {
+	var count int
+	if err := s.q(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM data_chunks WHERE data_id = $1`, dataID).Scan(&count); err != nil {
+		logger.FromContext(ctx).Error("Failed to count data chunks", zap.Error(err), zap.String("data_id", dataID.String()))
+		return 0, fmt.Errorf("failed to count data chunks: %w", err)
+	}
+
+	return count, nil
+}
+
+// RotateVault replaces the salt, verifier and encrypted share private key of
+// the user and every ciphertext of their vault with the records read from next until io.EOF, in one
+// transaction. The
+// records must cover each item, comment, version, chunk and attachment of the user
+// exactly once, with items at their current revision; rotated items advance
+// their revision without keeping a version of the old ciphertext.
+func (s *PostgresStorage) RotateVault(ctx context.Context, userID uuid.UUID, header models.RotationHeader,
+	next func() (*models.RotationRecord, error)) (*models.RotationResponse, error) {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to begin rotation", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to begin rotation: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.FromContext(ctx).Error("Failed to roll back rotation", zap.Error(err), zap.String("user_id", userID.String()))
+		}
+	}()
+
+	// the items are locked first, so no change can slip in between the count and the commit
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM data WHERE user_id = $1 FOR UPDATE`, userID); err != nil {
+		logger.FromContext(ctx).Error("Failed to lock data for rotation", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to lock data: %w", err)
+	}
+	var total int
+	err = tx.QueryRowContext(ctx, `SELECT
+			  (SELECT COUNT(*) FROM data WHERE user_id = $1) +
+			  (SELECT COUNT(*) FROM data_comments c JOIN data d ON d.id = c.data_id WHERE d.user_id = $1) +
+			  (SELECT COUNT(*) FROM data_versions v JOIN data d ON d.id = v.data_id WHERE d.user_id = $1) +
+			  (SELECT COUNT(*) FROM data_chunks k JOIN data d ON d.id = k.data_id WHERE d.user_id = $1) +
+			  (SELECT COUNT(*) FROM data_attachments a JOIN data d ON d.id = a.data_id WHERE d.user_id = $1)`, userID).Scan(&total)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to count ciphertexts for rotation", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to count ciphertexts: %w", err)
+	}
+
+	// objects of rotated chunks are only referenced once the rotation commits
+	var uploaded []string
+	committed := false
+	defer func() {
+		if !committed && len(uploaded) > 0 {
+			s.discardObjects(ctx, uploaded...)
+		}
+	}()
+
+	response := &models.RotationResponse{}
+	seen := make(map[string]bool)
+	for {
+		record, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%s/%s/%s/%d/%d/%s", record.Kind, record.DataID, record.CommentID, record.Version, record.Index,
+			record.AttachmentID)
+		if seen[key] {
+			return nil, ErrRotationMismatch
+		}
+		seen[key] = true
+
+		// chunks stored as objects are fetched by clients directly, so they are not sealed
+		ciphertext := record.Data
+		if record.Kind != models.RotationChunk || s.blobs == nil {
+			if ciphertext, err = s.seal(ctx, record.Data); err != nil {
+				return nil, err
+			}
+		}
+
+		var result sql.Result
+		switch record.Kind {
+		case models.RotationItem:
+			result, err = tx.ExecContext(ctx, `UPDATE data SET data = $3, revision = revision + 1 
+					  WHERE id = $1 AND user_id = $2 AND revision = $4`, record.DataID, userID, ciphertext, record.Revision)
+			response.Items++
+		case models.RotationComment:
+			result, err = tx.ExecContext(ctx, `UPDATE data_comments c SET ciphertext = $4 FROM data d 
+					  WHERE c.id = $1 AND c.data_id = $2 AND d.id = c.data_id AND d.user_id = $3`,
+				record.CommentID, record.DataID, userID, ciphertext)
+			response.Comments++
+		case models.RotationVersion:
+			result, err = tx.ExecContext(ctx, `UPDATE data_versions v SET data = $4 FROM data d 
+					  WHERE v.data_id = $1 AND v.version = $2 AND d.id = v.data_id AND d.user_id = $3`,
+				record.DataID, record.Version, userID, ciphertext)
+			response.Versions++
+		case models.RotationChunk:
+			if s.blobs == nil {
+				result, err = tx.ExecContext(ctx, `UPDATE data_chunks k SET data = $4, object_key = NULL FROM data d 
+						  WHERE k.data_id = $1 AND k.chunk_index = $2 AND d.id = k.data_id AND d.user_id = $3`,
+					record.DataID, record.Index, userID, ciphertext)
+			} else {
+				var key string
+				if key, err = s.putChunkObject(ctx, record.DataID, record.Index, record.Data); err != nil {
+					return nil, err
+				}
+				uploaded = append(uploaded, key)
+				result, err = tx.ExecContext(ctx, `UPDATE data_chunks k SET data = NULL, object_key = $4 FROM data d 
+						  WHERE k.data_id = $1 AND k.chunk_index = $2 AND d.id = k.data_id AND d.user_id = $3`,
+					record.DataID, record.Index, userID, key)
+			}
+			response.Chunks++
+		case models.RotationAttachment:
+			if len(record.Info) == 0 {
+				return nil, ErrRotationMismatch
+			}
+			var info []byte
+			if info, err = s.seal(ctx, record.Info); err != nil {
+				return nil, err
+			}
+			result, err = tx.ExecContext(ctx, `UPDATE data_attachments a SET info = $4, content = $5, size = $6 FROM data d 
+					  WHERE a.id = $1 AND a.data_id = $2 AND d.id = a.data_id AND d.user_id = $3`,
+				record.AttachmentID, record.DataID, userID, info, ciphertext, len(record.Data))
+			response.Attachments++
+		default:
+			return nil, ErrRotationMismatch
+		}
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to rotate ciphertext", zap.Error(err),
+				zap.String("user_id", userID.String()), zap.String("data_id", record.DataID.String()))
+			return nil, fmt.Errorf("failed to rotate ciphertext: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			if record.Kind == models.RotationItem {
+				var exists bool
+				if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM data WHERE id = $1 AND user_id = $2)`,
+					record.DataID, userID).Scan(&exists); err != nil {
+					return nil, fmt.Errorf("failed to check data: %w", err)
+				}
+				if exists {
+					return nil, ErrRotationConflict
+				}
+			}
+			return nil, ErrRotationMismatch
+		}
+	}
+	if len(seen) != total {
+		return nil, ErrRotationMismatch
+	}
+
+	var verifier []byte
+	if len(header.Verifier) > 0 {
+		verifier = header.Verifier
+	}
+	result, err := tx.ExecContext(ctx, `UPDATE users SET salt = $2, master_verifier = $3, master_password = '', updated_at = $4, 
+			  kdf_iterations = $5 WHERE id = $1`, userID, header.Salt, verifier, s.clock.Now(), header.KDFIterations)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to rotate user salt", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to set salt: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return nil, ErrUserNotFound
+	}
+	if len(header.ShareKey) > 0 {
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET share_private_key = $2 
+				  WHERE id = $1 AND share_public_key IS NOT NULL`, userID, header.ShareKey); err != nil {
+			logger.FromContext(ctx).Error("Failed to rotate share key", zap.Error(err), zap.String("user_id", userID.String()))
+			return nil, fmt.Errorf("failed to set share key: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.FromContext(ctx).Error("Failed to commit rotation", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to commit rotation: %w", err)
+	}
+	committed = true
+	return response, nil
+}
+
+// SetKeyEscrow creates or replaces the user's key escrow
+func (s *PostgresStorage) SetKeyEscrow(ctx context.Context, escrow *models.KeyEscrow) error {
+	query := `INSERT INTO key_escrow (user_id, wrapped_key, recovery_key_id, consented_at) 
+			  VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (user_id) DO UPDATE SET wrapped_key = EXCLUDED.wrapped_key, 
+			  recovery_key_id = EXCLUDED.recovery_key_id, consented_at = EXCLUDED.consented_at`
+
+	_, err := s.q(ctx).ExecContext(ctx, query, escrow.UserID, escrow.WrappedKey, escrow.RecoveryKeyID, escrow.ConsentedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to set key escrow in database", zap.Error(err),
+			zap.String("user_id", escrow.UserID.String()))
+		return fmt.Errorf("failed to set key escrow: %w", err)
+	}
+
+	return nil
+}
+
+// GetKeyEscrow gets the user's key escrow
+func (s *PostgresStorage) GetKeyEscrow(ctx context.Context, userID uuid.UUID) (*models.KeyEscrow, error) {
+	query := `SELECT user_id, wrapped_key, recovery_key_id, consented_at FROM key_escrow WHERE user_id = $1`
+
+	escrow := &models.KeyEscrow{}
+	err := s.q(ctx).QueryRowContext(ctx, query, userID).Scan(&escrow.UserID, &escrow.WrappedKey,
+		&escrow.RecoveryKeyID, &escrow.ConsentedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			logger.FromContext(ctx).Debug("Key escrow not found", zap.String("user_id", userID.String()))
+			return nil, ErrEscrowNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get key escrow from database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to get key escrow: %w", err)
+	}
+
+	return escrow, nil
+}
+
+// DeleteKeyEscrow deletes the user's key escrow
+func (s *PostgresStorage) DeleteKeyEscrow(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM key_escrow WHERE user_id = $1`
+
+	result, err := s.q(ctx).ExecContext(ctx, query, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to delete key escrow from database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return fmt.Errorf("failed to delete key escrow: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get rows affected for key escrow delete", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrEscrowNotFound
+	}
+
+	return nil
+}
+
+// SetPasswordHint sets the user's master password hint; an empty hint removes it
+func (s *PostgresStorage) SetPasswordHint(ctx context.Context, userID uuid.UUID, hint string) error {
+	query := `UPDATE users SET password_hint = $2, updated_at = $3 WHERE id = $1`
+
+	result, err := s.q(ctx).ExecContext(ctx, query, userID, hint, s.clock.Now())
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to set password hint in database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return fmt.Errorf("failed to set password hint: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetPasswordHint gets the user's master password hint, empty if none is set
+func (s *PostgresStorage) GetPasswordHint(ctx context.Context, userID uuid.UUID) (string, error) {
+	query := `SELECT password_hint FROM users WHERE id = $1`
+
+	var hint string
+	if err := s.q(ctx).QueryRowContext(ctx, query, userID).Scan(&hint); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrUserNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get password hint from database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return "", fmt.Errorf("failed to get password hint: %w", err)
+	}
+
+	return hint, nil
+}
+
+// SetMasterVerifier sets the user's master password verifier once; re-sending
+// the same verifier is a no-op
+func (s *PostgresStorage) SetMasterVerifier(ctx context.Context, userID uuid.UUID, verifier []byte) error {
+	query := `UPDATE users SET master_verifier = $2, updated_at = $3 
+			  WHERE id = $1 AND (master_verifier IS NULL OR master_verifier = $2)`
+
+	result, err := s.q(ctx).ExecContext(ctx, query, userID, verifier, s.clock.Now())
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to set master verifier", zap.Error(err), zap.String("user_id", userID.String()))
+		return fmt.Errorf("failed to set verifier: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		if _, err := s.GetUserByID(ctx, userID); err != nil {
+			return err
+		}
+		logger.FromContext(ctx).Warn("Attempt to overwrite master verifier", zap.String("user_id", userID.String()))
+		return ErrVerifierAlreadySet
+	}
+
+	return nil
+}
+
+// GetMasterVerifier gets the user's master password verifier, nil if none is set
+func (s *PostgresStorage) GetMasterVerifier(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	query := `SELECT master_verifier FROM users WHERE id = $1`
+
+	var verifier []byte
+	if err := s.q(ctx).QueryRowContext(ctx, query, userID).Scan(&verifier); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get master verifier from database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to get verifier: %w", err)
+	}
+
+	return verifier, nil
+}
+
+// SetAuditKey sets the public key the user's audit details are sealed to
+func (s *PostgresStorage) SetAuditKey(ctx context.Context, userID uuid.UUID, publicKey []byte) error {
+	query := `UPDATE users SET audit_public_key = $2, updated_at = $3 WHERE id = $1`
+
+	result, err := s.q(ctx).ExecContext(ctx, query, userID, publicKey, s.clock.Now())
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to set audit key in database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return fmt.Errorf("failed to set audit key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetAuditKey gets the user's audit public key, nil if none is set
+func (s *PostgresStorage) GetAuditKey(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	query := `SELECT audit_public_key FROM users WHERE id = $1`
+
+	var publicKey []byte
+	if err := s.q(ctx).QueryRowContext(ctx, query, userID).Scan(&publicKey); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get audit key from database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to get audit key: %w", err)
+	}
+
+	return publicKey, nil
+}
+
+// SetShareKey sets the user's share key pair. The public key is set once, as
+// items may be sealed to it; re-sending it replaces the encrypted private key.
+func (s *PostgresStorage) SetShareKey(ctx context.Context, userID uuid.UUID, keys *models.ShareKeys) error {
+	query := `UPDATE users SET share_public_key = $2, share_private_key = $3, updated_at = $4 
+			  WHERE id = $1 AND (share_public_key IS NULL OR share_public_key = $2)`
+
+	result, err := s.q(ctx).ExecContext(ctx, query, userID, keys.PublicKey, keys.PrivateKey, s.clock.Now())
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to set share key in database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return fmt.Errorf("failed to set share key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		if _, err := s.GetUserByID(ctx, userID); err != nil {
+			return err
+		}
+		logger.FromContext(ctx).Warn("Attempt to overwrite share key", zap.String("user_id", userID.String()))
+		return ErrShareKeyAlreadySet
+	}
+
+	return nil
+}
+
+// GetShareKey gets the user's share key pair, nil if none is set
+func (s *PostgresStorage) GetShareKey(ctx context.Context, userID uuid.UUID) (*models.ShareKeys, error) {
+	query := `SELECT share_public_key, share_private_key FROM users WHERE id = $1`
+
+	keys := &models.ShareKeys{}
+	if err := s.q(ctx).QueryRowContext(ctx, query, userID).Scan(&keys.PublicKey, &keys.PrivateKey); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get share key from database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to get share key: %w", err)
+	}
+
+	if keys.PublicKey == nil {
+		return nil, nil
+	}
+	return keys, nil
+}
+
+// shareColumns are the columns of a share with its recipient's username
+const shareColumns = `s.id, s.data_id, s.owner_id, s.recipient_id, u.username, s.mode, s.sealed_key, s.created_at`
+
+// CreateShare shares data with a user, or changes the mode and sealed key of
+// the share the user already has, keeping its ID
+func (s *PostgresStorage) CreateShare(ctx context.Context, share *models.Share) error {
+	query := `INSERT INTO shares (id, data_id, owner_id, recipient_id, mode, sealed_key, created_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  ON CONFLICT (data_id, recipient_id) DO UPDATE SET mode = EXCLUDED.mode, sealed_key = EXCLUDED.sealed_key
+			  RETURNING id, created_at`
+
+	err := s.q(ctx).QueryRowContext(ctx, query, share.ID, share.DataID, share.OwnerID, share.RecipientID, share.Mode,
+		share.SealedKey, share.CreatedAt).Scan(&share.ID, &share.CreatedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to create share in database", zap.Error(err),
+			zap.String("data_id", share.DataID.String()), zap.String("recipient_id", share.RecipientID.String()))
+		return fmt.Errorf("failed to create share: %w", err)
+	}
+	return nil
+}
+
+// GetShare gets a share by ID
+func (s *PostgresStorage) GetShare(ctx context.Context, shareID uuid.UUID) (*models.Share, error) {
+	query := `SELECT ` + shareColumns + ` FROM shares s JOIN users u ON u.id = s.recipient_id WHERE s.id = $1`
+
+	share := &models.Share{}
+	err := s.q(ctx).QueryRowContext(ctx, query, shareID).Scan(&share.ID, &share.DataID, &share.OwnerID,
+		&share.RecipientID, &share.Recipient, &share.Mode, &share.SealedKey, &share.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrShareNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get share from database", zap.Error(err), zap.String("share_id", shareID.String()))
+		return nil, fmt.Errorf("failed to get share: %w", err)
+	}
+	return share, nil
+}
+
+// GetShares gets the shares of data, oldest first
+func (s *PostgresStorage) GetShares(ctx context.Context, dataID uuid.UUID) ([]*models.Share, error) {
+	return s.queryShares(ctx, `SELECT `+shareColumns+` FROM shares s JOIN users u ON u.id = s.recipient_id 
+			  WHERE s.data_id = $1 ORDER BY s.created_at, s.id`, dataID)
+}
+
+// GetSharedWith gets the shares granted to a user, oldest first
+func (s *PostgresStorage) GetSharedWith(ctx context.Context, recipientID uuid.UUID) ([]*models.Share, error) {
+	return s.queryShares(ctx, `SELECT `+shareColumns+` FROM shares s JOIN users u ON u.id = s.recipient_id 
+			  WHERE s.recipient_id = $1 ORDER BY s.created_at, s.id`, recipientID)
+}
+
+func (s *PostgresStorage) queryShares(ctx context.Context, query string, id uuid.UUID) ([]*models.Share, error) {
+	rows, err := s.q(ctx).QueryContext(ctx, query, id)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get shares from database", zap.Error(err), zap.String("id", id.String()))
+		return nil, fmt.Errorf("failed to get shares: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("Failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var shares []*models.Share
+	for rows.Next() {
+		share := &models.Share{}
+		if err := rows.Scan(&share.ID, &share.DataID, &share.OwnerID, &share.RecipientID, &share.Recipient,
+			&share.Mode, &share.SealedKey, &share.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan share: %w", err)
+		}
+		shares = append(shares, share)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate shares: %w", err)
+	}
+	return shares, nil
+}
+
+// DeleteShare revokes a share
+func (s *PostgresStorage) DeleteShare(ctx context.Context, shareID uuid.UUID) error {
+	result, err := s.q(ctx).ExecContext(ctx, `DELETE FROM shares WHERE id = $1`, shareID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to delete share from database", zap.Error(err), zap.String("share_id", shareID.String()))
+		return fmt.Errorf("failed to delete share: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrShareNotFound
+	}
+
+	return nil
+}
+
+// CreateIdentity links an external account to a user
+func (s *PostgresStorage) CreateIdentity(ctx context.Context, identity *models.Identity) error {
+	query := `INSERT INTO identities (issuer, subject, user_id, created_at) VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (issuer, subject) DO NOTHING`
+
+	result, err := s.q(ctx).ExecContext(ctx, query, identity.Issuer, identity.Subject, identity.UserID, identity.CreatedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to create identity in database", zap.Error(err),
+			zap.String("user_id", identity.UserID.String()))
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrIdentityExists
+	}
+
+	return nil
+}
+
+// GetIdentity gets the link of an external account by its issuer and subject
+func (s *PostgresStorage) GetIdentity(ctx context.Context, issuer, subject string) (*models.Identity, error) {
+	query := `SELECT issuer, subject, user_id, created_at FROM identities WHERE issuer = $1 AND subject = $2`
+
+	identity := &models.Identity{}
+	err := s.q(ctx).QueryRowContext(ctx, query, issuer, subject).Scan(&identity.Issuer, &identity.Subject,
+		&identity.UserID, &identity.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrIdentityNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get identity from database", zap.Error(err))
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return identity, nil
+}
+
+// sessionColumns are the columns of a session
+const sessionColumns = `id, user_id, device, user_agent, created_at, last_seen_at, expires_at`
+
+// CreateSession records a login of a user
+func (s *PostgresStorage) CreateSession(ctx context.Context, session *models.Session) error {
+	query := `INSERT INTO sessions (` + sessionColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := s.q(ctx).ExecContext(ctx, query, session.ID, session.UserID, session.Device, session.UserAgent,
+		session.CreatedAt, session.LastSeenAt, session.ExpiresAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to create session in database", zap.Error(err),
+			zap.String("user_id", session.UserID.String()))
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSession gets a session by ID
+func (s *PostgresStorage) GetSession(ctx context.Context, sessionID uuid.UUID) (*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+
+	session := &models.Session{}
+	err := s.q(ctx).QueryRowContext(ctx, query, sessionID).Scan(&session.ID, &session.UserID, &session.Device,
+		&session.UserAgent, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		logger.FromContext(ctx).Error("Failed to get session from database", zap.Error(err),
+			zap.String("session_id", sessionID.String()))
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// GetSessions gets the sessions of a user that have not expired at now, most
+// recently seen first
+func (s *PostgresStorage) GetSessions(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions 
+			  WHERE user_id = $1 AND expires_at > $2 ORDER BY last_seen_at DESC, id`
+
+	rows, err := s.q(ctx).QueryContext(ctx, query, userID, now)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get sessions from database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("Failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session := &models.Session{}
+		if err := rows.Scan(&session.ID, &session.UserID, &session.Device, &session.UserAgent,
+			&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// TouchSession records that a session was used at seenAt
+func (s *PostgresStorage) TouchSession(ctx context.Context, sessionID uuid.UUID, seenAt time.Time) error {
+	result, err := s.q(ctx).ExecContext(ctx, `UPDATE sessions SET last_seen_at = $1 WHERE id = $2`, seenAt, sessionID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to update session in database", zap.Error(err),
+			zap.String("session_id", sessionID.String()))
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// DeleteSession revokes a session
+func (s *PostgresStorage) DeleteSession(ctx context.Context, sessionID uuid.UUID) error {
+	result, err := s.q(ctx).ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, sessionID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to delete session from database", zap.Error(err),
+			zap.String("session_id", sessionID.String()))
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// DeleteExpiredSessions deletes the sessions of a user that expired by now
+func (s *PostgresStorage) DeleteExpiredSessions(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	_, err := s.q(ctx).ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1 AND expires_at <= $2`, userID, now)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to delete expired sessions from database", zap.Error(err),
+			zap.String("user_id", userID.String()))
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return nil
+}
+
+// AddAuditEvent appends an event to the user's audit log
+func (s *PostgresStorage) AddAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	query := `INSERT INTO audit_events (id, user_id, action, data_id, sealed, created_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := s.q(ctx).ExecContext(ctx, query, event.ID, event.UserID, event.Action, event.DataID, event.Sealed, event.CreatedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to add audit event to database", zap.Error(err),
+			zap.String("user_id", event.UserID.String()))
+		return fmt.Errorf("failed to add audit event: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuditEvents gets up to limit of the user's latest audit events, newest first
+func (s *PostgresStorage) GetAuditEvents(ctx context.Context, userID uuid.UUID, limit int) ([]*models.AuditEvent, error) {
+	return s.FindAuditEvents(ctx, models.AuditFilter{UserID: &userID, Limit: limit})
+}
+
+// FindAuditEvents gets up to filter.Limit of the latest audit events matching the filter, newest first
+func (s *PostgresStorage) FindAuditEvents(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.UserID != nil {
+		where("user_id = $%d", *filter.UserID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.DataID != nil {
+		where("data_id = $%d", *filter.DataID)
+	}
+	if !filter.Since.IsZero() {
+		where("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where("created_at < $%d", filter.Until)
+	}
+
+	query := `SELECT id, user_id, action, data_id, sealed, created_at FROM audit_events`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id LIMIT $%d", len(args))
+
+	rows, err := s.q(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get audit events from database", zap.Error(err))
+		return nil, fmt.Errorf("failed to get audit events: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.FromContext(ctx).Error("Failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var events []*models.AuditEvent
+	for rows.Next() {
+		event := &models.AuditEvent{}
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Action, &event.DataID, &event.Sealed, &event.CreatedAt); err != nil {
+			logger.FromContext(ctx).Error("Failed to scan audit event", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("Rows iteration error", zap.Error(err))
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return events, nil
+}
+
+// Ping checks that the database is reachable
+func (s *PostgresStorage) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("database unreachable: %w", err)
+	}
+	return nil
+}
+
+// SelfTest measures round-trip latency, verifies the migration version and
+// writes, reads and deletes a canary user inside a transaction
+func (s *PostgresStorage) SelfTest(ctx context.Context) (*SelfTestReport, error) {
+	start := time.Now()
+	if err := s.db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("database unreachable: %w", err)
+	}
+	report := &SelfTestReport{Latency: time.Since(start)}
+
+	var dirty bool
+	err := s.q(ctx).QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&report.SchemaVersion, &dirty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version, have migrations been applied? %w", err)
+	}
+	if dirty {
+		return nil, fmt.Errorf("schema migration %d is dirty, fix it before starting", report.SchemaVersion)
+	}
+	if report.SchemaVersion < SchemaVersion {
+		return nil, fmt.Errorf("schema version %d is older than required %d, run migrations", report.SchemaVersion, SchemaVersion)
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin canary transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.FromContext(ctx).Error("Failed to roll back canary transaction", zap.Error(err))
+		}
+	}()
+
+	canaryID := uuid.New()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO users (id, username, password, master_password, salt, created_at, updated_at) 
+			  VALUES ($1, $2, '', '', '', $3, $3)`, canaryID, canaryUsername(), start); err != nil {
+		return nil, fmt.Errorf("canary write failed: %w", err)
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE id = $1`, canaryID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("canary read failed: %w", err)
+	}
+	if count != 1 {
+		return nil, fmt.Errorf("canary read failed: written row not found")
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, canaryID); err != nil {
+		return nil, fmt.Errorf("canary delete failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("canary commit failed: %w", err)
+	}
+	return report, nil
+}
@@ -1,13 +1,17 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/a2sh3r/gophkeeper/internal/atrest"
 	"github.com/a2sh3r/gophkeeper/internal/logger"
 	"github.com/a2sh3r/gophkeeper/internal/models"
 	"github.com/google/uuid"
@@ -73,7 +77,7 @@ func TestPostgresStorage_CreateUser(t *testing.T) {
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO users").
-					WithArgs(sqlmock.AnyArg(), "testuser", "hashedpassword", "hashedmasterpassword", "salt123", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs(sqlmock.AnyArg(), "testuser", "hashedpassword", "hashedmasterpassword", "salt123", 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			wantError: false,
@@ -91,7 +95,7 @@ func TestPostgresStorage_CreateUser(t *testing.T) {
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO users").
-					WithArgs(sqlmock.AnyArg(), "existinguser", "hashedpassword", "hashedmasterpassword", "salt123", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs(sqlmock.AnyArg(), "existinguser", "hashedpassword", "hashedmasterpassword", "salt123", 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
 					WillReturnError(fmt.Errorf(`duplicate key value violates unique constraint "users_username_key"`))
 			},
 			wantError: true,
@@ -109,7 +113,7 @@ func TestPostgresStorage_CreateUser(t *testing.T) {
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO users").
-					WithArgs(sqlmock.AnyArg(), "testuser", "hashedpassword", "hashedmasterpassword", "salt123", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs(sqlmock.AnyArg(), "testuser", "hashedpassword", "hashedmasterpassword", "salt123", 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
 					WillReturnError(sql.ErrConnDone)
 			},
 			wantError: true,
@@ -161,9 +165,9 @@ func TestPostgresStorage_GetUserByUsername(t *testing.T) {
 			name:     "successful user retrieval",
 			username: "testuser",
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "username", "password", "master_password", "salt", "created_at", "updated_at"}).
-					AddRow(uuid.New(), "testuser", "hashedpassword", "hashedmasterpassword", "salt123", time.Now(), time.Now())
-				mock.ExpectQuery("SELECT id, username, password, master_password, salt, created_at, updated_at FROM users WHERE username = \\$1").
+				rows := sqlmock.NewRows([]string{"id", "username", "password", "master_password", "salt", "kdf_iterations", "created_at", "updated_at"}).
+					AddRow(uuid.New(), "testuser", "hashedpassword", "hashedmasterpassword", "salt123", 0, time.Now(), time.Now())
+				mock.ExpectQuery("SELECT id, username, password, master_password, salt, kdf_iterations, created_at, updated_at FROM users WHERE username = \\$1").
 					WithArgs("testuser").
 					WillReturnRows(rows)
 			},
@@ -173,7 +177,7 @@ func TestPostgresStorage_GetUserByUsername(t *testing.T) {
 			name:     "user not found",
 			username: "nonexistent",
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, username, password, master_password, salt, created_at, updated_at FROM users WHERE username = \\$1").
+				mock.ExpectQuery("SELECT id, username, password, master_password, salt, kdf_iterations, created_at, updated_at FROM users WHERE username = \\$1").
 					WithArgs("nonexistent").
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -183,7 +187,7 @@ func TestPostgresStorage_GetUserByUsername(t *testing.T) {
 			name:     "database error",
 			username: "testuser",
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, username, password, master_password, salt, created_at, updated_at FROM users WHERE username = \\$1").
+				mock.ExpectQuery("SELECT id, username, password, master_password, salt, kdf_iterations, created_at, updated_at FROM users WHERE username = \\$1").
 					WithArgs("testuser").
 					WillReturnError(sql.ErrConnDone)
 			},
@@ -237,9 +241,9 @@ func TestPostgresStorage_GetUserByID(t *testing.T) {
 			name:   "successful user retrieval",
 			userID: userID,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "username", "password", "master_password", "salt", "created_at", "updated_at"}).
-					AddRow(userID, "testuser", "hashedpassword", "hashedmasterpassword", "salt123", time.Now(), time.Now())
-				mock.ExpectQuery("SELECT id, username, password, master_password, salt, created_at, updated_at FROM users WHERE id = \\$1").
+				rows := sqlmock.NewRows([]string{"id", "username", "password", "master_password", "salt", "kdf_iterations", "created_at", "updated_at"}).
+					AddRow(userID, "testuser", "hashedpassword", "hashedmasterpassword", "salt123", 0, time.Now(), time.Now())
+				mock.ExpectQuery("SELECT id, username, password, master_password, salt, kdf_iterations, created_at, updated_at FROM users WHERE id = \\$1").
 					WithArgs(userID).
 					WillReturnRows(rows)
 			},
@@ -249,7 +253,7 @@ func TestPostgresStorage_GetUserByID(t *testing.T) {
 			name:   "user not found",
 			userID: userID,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, username, password, master_password, salt, created_at, updated_at FROM users WHERE id = \\$1").
+				mock.ExpectQuery("SELECT id, username, password, master_password, salt, kdf_iterations, created_at, updated_at FROM users WHERE id = \\$1").
 					WithArgs(userID).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -259,7 +263,7 @@ func TestPostgresStorage_GetUserByID(t *testing.T) {
 			name:   "database error",
 			userID: userID,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT id, username, password, master_password, salt, created_at, updated_at FROM users WHERE id = \\$1").
+				mock.ExpectQuery("SELECT id, username, password, master_password, salt, kdf_iterations, created_at, updated_at FROM users WHERE id = \\$1").
 					WithArgs(userID).
 					WillReturnError(sql.ErrConnDone)
 			},
@@ -320,7 +324,7 @@ func TestPostgresStorage_CreateData(t *testing.T) {
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO data").
-					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "text", "test data", "test description", []byte("test content"), "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "text", "test data", "test description", []byte("test content"), "", sqlmock.AnyArg(), sqlmock.AnyArg(), "", 1, "[]", nil, []byte(nil), "[]", []byte(nil), nil, "").
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			wantError: false,
@@ -340,7 +344,7 @@ func TestPostgresStorage_CreateData(t *testing.T) {
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("INSERT INTO data").
-					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "login_password", "login data", "login description", []byte("username:password"), "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "login_password", "login data", "login description", []byte("username:password"), "", sqlmock.AnyArg(), sqlmock.AnyArg(), "", 1, "[]", nil, []byte(nil), "[]", []byte(nil), nil, "").
 					WillReturnError(sql.ErrConnDone)
 			},
 			wantError: true,
@@ -386,8 +390,8 @@ func TestPostgresStorage_GetDataByID(t *testing.T) {
 			name:   "successful data retrieval",
 			dataID: dataID,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "user_id", "type", "name", "description", "data", "metadata", "created_at", "updated_at"}).
-					AddRow(dataID, uuid.New(), "text", "test data", "test description", []byte("test content"), "", time.Now(), time.Now())
+				rows := sqlmock.NewRows([]string{"id", "user_id", "type", "name", "description", "data", "metadata", "created_at", "updated_at", "environment", "revision", "tags", "collection_id", "checksum", "domains", "icon", "expires_at", "expiry"}).
+					AddRow(dataID, uuid.New(), "text", "test data", "test description", []byte("test content"), "", time.Now(), time.Now(), "", 1, []byte("[]"), nil, nil, []byte("[]"), nil, nil, "")
 				mock.ExpectQuery("SELECT id, user_id, type, name, description, data, metadata, created_at, updated_at").
 					WithArgs(dataID).
 					WillReturnRows(rows)
@@ -459,9 +463,9 @@ func TestPostgresStorage_GetDataByUserID(t *testing.T) {
 			name:   "successful data list retrieval",
 			userID: userID,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "user_id", "type", "name", "description", "data", "metadata", "created_at", "updated_at"}).
-					AddRow(uuid.New(), userID, "text", "test data 1", "description 1", []byte("content 1"), "", time.Now(), time.Now()).
-					AddRow(uuid.New(), userID, "login_password", "test data 2", "description 2", []byte("content 2"), "", time.Now(), time.Now())
+				rows := sqlmock.NewRows([]string{"id", "user_id", "type", "name", "description", "data", "metadata", "created_at", "updated_at", "environment", "revision", "tags", "collection_id", "checksum", "domains", "icon", "expires_at", "expiry"}).
+					AddRow(uuid.New(), userID, "text", "test data 1", "description 1", []byte("content 1"), "", time.Now(), time.Now(), "", 1, []byte("[]"), nil, nil, []byte("[]"), nil, nil, "").
+					AddRow(uuid.New(), userID, "login_password", "test data 2", "description 2", []byte("content 2"), "", time.Now(), time.Now(), "", 1, []byte("[]"), nil, nil, []byte("[]"), nil, nil, "")
 				mock.ExpectQuery("SELECT id, user_id, type, name, description, data, metadata, created_at, updated_at").
 					WithArgs(userID).
 					WillReturnRows(rows)
@@ -472,7 +476,7 @@ func TestPostgresStorage_GetDataByUserID(t *testing.T) {
 			name:   "no data found",
 			userID: userID,
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "user_id", "type", "name", "description", "data", "metadata", "created_at", "updated_at"})
+				rows := sqlmock.NewRows([]string{"id", "user_id", "type", "name", "description", "data", "metadata", "created_at", "updated_at", "environment", "revision", "tags", "collection_id", "checksum", "domains", "icon", "expires_at", "expiry"})
 				mock.ExpectQuery("SELECT id, user_id, type, name, description, data, metadata, created_at, updated_at").
 					WithArgs(userID).
 					WillReturnRows(rows)
@@ -522,6 +526,112 @@ func TestPostgresStorage_GetDataByUserID(t *testing.T) {
 	}
 }
 
+func TestPostgresStorage_GetDataByUserIDFiltered(t *testing.T) {
+	userID := uuid.New()
+	expiresAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "user_id", "type", "name", "description", "data", "metadata", "created_at", "updated_at", "environment", "revision", "tags", "collection_id", "checksum", "domains", "icon", "expires_at", "expiry"}
+
+	tests := []struct {
+		name      string
+		filter    models.DataFilter
+		query     string
+		args      []driver.Value
+		wantError bool
+	}{
+		{
+			name:  "no filter",
+			query: `FROM data WHERE user_id = \$1 ORDER BY created_at DESC, id DESC$`,
+			args:  []driver.Value{userID},
+		},
+		{
+			name:   "all filters and a page",
+			filter: models.DataFilter{Environment: "prod", Type: models.DataTypeText, Name: "50%", Limit: 20, Offset: 40},
+			query: `WHERE user_id = \$1 AND environment = \$2 AND type = \$3 AND strpos\(lower\(name\), lower\(\$4\)\) > 0 ` +
+				`ORDER BY created_at DESC, id DESC LIMIT \$5 OFFSET \$6$`,
+			args: []driver.Value{userID, "prod", models.DataTypeText, "50%", 20, 40},
+		},
+		{
+			name:   "tag",
+			filter: models.DataFilter{Tag: "work"},
+			query:  `WHERE user_id = \$1 AND tags \? \$2 ORDER BY created_at DESC, id DESC$`,
+			args:   []driver.Value{userID, "work"},
+		},
+		{
+			name:   "domain",
+			filter: models.DataFilter{Type: models.DataTypeLoginPassword, Domain: "example.com"},
+			query:  `WHERE user_id = \$1 AND type = \$2 AND domains \? \$3 ORDER BY created_at DESC, id DESC$`,
+			args:   []driver.Value{userID, models.DataTypeLoginPassword, "example.com"},
+		},
+		{
+			name:   "expiring",
+			filter: models.DataFilter{ExpiringBefore: expiresAt},
+			query:  `WHERE user_id = \$1 AND expires_at <= \$2 ORDER BY created_at DESC, id DESC$`,
+			args:   []driver.Value{userID, expiresAt},
+		},
+		{
+			name:   "search escapes wildcards",
+			filter: models.DataFilter{Query: `50%_off\`},
+			query: `WHERE user_id = \$1 AND \(name ILIKE \$2 OR description ILIKE \$2 OR metadata ILIKE \$2\) ` +
+				`ORDER BY created_at DESC, id DESC$`,
+			args: []driver.Value{userID, `%50\%\_off\\%`},
+		},
+		{
+			name:      "database error",
+			filter:    models.DataFilter{Limit: 10},
+			query:     `LIMIT \$2$`,
+			args:      []driver.Value{userID, 10},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			expect := mock.ExpectQuery(tt.query).WithArgs(tt.args...)
+			if tt.wantError {
+				expect.WillReturnError(sql.ErrConnDone)
+			} else {
+				expect.WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(uuid.New(), userID, "text", "50% off", "", []byte("content"), "", time.Now(), time.Now(), "prod", 1, []byte(`["work"]`), nil, nil, []byte(`["example.com"]`), []byte("icon"),
+						expiresAt, models.ExpiryExpiring))
+			}
+
+			storage := NewPostgresStorage(db)
+			dataList, err := storage.GetDataByUserIDFiltered(context.Background(), userID, tt.filter)
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("GetDataByUserIDFiltered() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError && len(dataList) != 1 {
+				t.Errorf("GetDataByUserIDFiltered() returned %d items, want 1", len(dataList))
+			}
+			if !tt.wantError && len(dataList) == 1 && (len(dataList[0].Tags) != 1 || dataList[0].Tags[0] != "work") {
+				t.Errorf("Expected the scanned tags, got %q", dataList[0].Tags)
+			}
+			if !tt.wantError && len(dataList) == 1 && (len(dataList[0].Domains) != 1 || dataList[0].Domains[0] != "example.com") {
+				t.Errorf("Expected the scanned domains, got %q", dataList[0].Domains)
+			}
+			if !tt.wantError && len(dataList) == 1 && string(dataList[0].Icon) != "icon" {
+				t.Errorf("Expected the scanned icon, got %q", dataList[0].Icon)
+			}
+			if !tt.wantError && len(dataList) == 1 && (dataList[0].ExpiresAt == nil || !dataList[0].ExpiresAt.Equal(expiresAt) ||
+				dataList[0].Expiry != models.ExpiryExpiring) {
+				t.Errorf("Expected the scanned expiry, got %v %q", dataList[0].ExpiresAt, dataList[0].Expiry)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
 func TestPostgresStorage_UpdateData(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -543,8 +653,9 @@ func TestPostgresStorage_UpdateData(t *testing.T) {
 				UpdatedAt:   time.Now(),
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("UPDATE data SET").
-					WithArgs(sqlmock.AnyArg(), "text", "updated data", "updated description", []byte("updated content"), "", sqlmock.AnyArg()).
+				// the replaced version is kept by the same statement
+				mock.ExpectExec(`(?s)INSERT INTO data_versions .* FROM data WHERE id = \$1\).*UPDATE data SET`).
+					WithArgs(sqlmock.AnyArg(), "text", "updated data", "updated description", []byte("updated content"), "", sqlmock.AnyArg(), "", "[]", []byte(nil), "[]", []byte(nil), nil, "").
 					WillReturnResult(sqlmock.NewResult(0, 1))
 			},
 			wantError: false,
@@ -564,7 +675,7 @@ func TestPostgresStorage_UpdateData(t *testing.T) {
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("UPDATE data SET").
-					WithArgs(sqlmock.AnyArg(), "bank_card", "bank card", "credit card", []byte("card number"), "", sqlmock.AnyArg()).
+					WithArgs(sqlmock.AnyArg(), "bank_card", "bank card", "credit card", []byte("card number"), "", sqlmock.AnyArg(), "", "[]", []byte(nil), "[]", []byte(nil), nil, "").
 					WillReturnResult(sqlmock.NewResult(0, 0))
 			},
 			wantError: true,
@@ -584,7 +695,7 @@ func TestPostgresStorage_UpdateData(t *testing.T) {
 			},
 			mockSetup: func(mock sqlmock.Sqlmock) {
 				mock.ExpectExec("UPDATE data SET").
-					WithArgs(sqlmock.AnyArg(), "text", "test data", "test description", []byte("test content"), "", sqlmock.AnyArg()).
+					WithArgs(sqlmock.AnyArg(), "text", "test data", "test description", []byte("test content"), "", sqlmock.AnyArg(), "", "[]", []byte(nil), "[]", []byte(nil), nil, "").
 					WillReturnError(sql.ErrConnDone)
 			},
 			wantError: true,
@@ -684,3 +795,1887 @@ func TestPostgresStorage_DeleteData(t *testing.T) {
 		})
 	}
 }
+
+func TestPostgresStorage_SetUserSalt(t *testing.T) {
+	userID := uuid.New()
+	userQuery := "SELECT id, username, password, master_password, salt, kdf_iterations, created_at, updated_at FROM users WHERE id = \\$1"
+	updateQuery := "UPDATE users SET salt = \\$2, updated_at = \\$3 WHERE id = \\$1 AND \\(salt = '' OR salt = \\$2\\)"
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantErr   error
+		wantError bool
+	}{
+		{
+			name: "salt set",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(updateQuery).
+					WithArgs(userID, "salt123", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "salt already set",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(updateQuery).
+					WithArgs(userID, "salt123", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				rows := sqlmock.NewRows([]string{"id", "username", "password", "master_password", "salt", "kdf_iterations", "created_at", "updated_at"}).
+					AddRow(userID, "testuser", "hashedpassword", "hashedmasterpassword", "other", 0, time.Now(), time.Now())
+				mock.ExpectQuery(userQuery).WithArgs(userID).WillReturnRows(rows)
+			},
+			wantErr:   ErrSaltAlreadySet,
+			wantError: true,
+		},
+		{
+			name: "user not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(updateQuery).
+					WithArgs(userID, "salt123", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(userQuery).WithArgs(userID).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr:   ErrUserNotFound,
+			wantError: true,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(updateQuery).
+					WithArgs(userID, "salt123", sqlmock.AnyArg()).
+					WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			err := storage.SetUserSalt(context.Background(), userID, "salt123")
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("SetUserSalt() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("SetUserSalt() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_SetDataField(t *testing.T) {
+	field := &models.DataField{
+		DataID:     uuid.New(),
+		Name:       "password",
+		Ciphertext: []byte("sealed"),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantErr   error
+		wantError bool
+	}{
+		{
+			name: "field set",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO data_fields").
+					WithArgs(field.DataID, "password", []byte("sealed"), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "data not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO data_fields").
+					WithArgs(field.DataID, "password", []byte("sealed"), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr:   ErrDataNotFound,
+			wantError: true,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO data_fields").
+					WithArgs(field.DataID, "password", []byte("sealed"), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			err := storage.SetDataField(context.Background(), field)
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("SetDataField() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("SetDataField() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_GetDataField(t *testing.T) {
+	dataID := uuid.New()
+	query := "SELECT data_id, name, ciphertext, created_at, updated_at FROM data_fields"
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantErr   error
+		wantError bool
+	}{
+		{
+			name: "field found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"data_id", "name", "ciphertext", "created_at", "updated_at"}).
+					AddRow(dataID, "password", []byte("sealed"), time.Now(), time.Now())
+				mock.ExpectQuery(query).WithArgs(dataID, "password").WillReturnRows(rows)
+			},
+		},
+		{
+			name: "field not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(dataID, "password").WillReturnError(sql.ErrNoRows)
+			},
+			wantErr:   ErrFieldNotFound,
+			wantError: true,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(dataID, "password").WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			field, err := storage.GetDataField(context.Background(), dataID, "password")
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("GetDataField() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("GetDataField() error = %v, want %v", err, tt.wantErr)
+			}
+			if !tt.wantError && string(field.Ciphertext) != "sealed" {
+				t.Errorf("Expected ciphertext sealed, got %s", field.Ciphertext)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_KeyEscrow(t *testing.T) {
+	userID := uuid.New()
+	escrow := &models.KeyEscrow{UserID: userID, WrappedKey: []byte("wrapped"), RecoveryKeyID: "abc", ConsentedAt: time.Now()}
+	selectQuery := "SELECT user_id, wrapped_key, recovery_key_id, consented_at FROM key_escrow WHERE user_id = \\$1"
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		run       func(*PostgresStorage) error
+		wantErr   error
+		wantError bool
+	}{
+		{
+			name: "set escrow",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO key_escrow").
+					WithArgs(userID, []byte("wrapped"), "abc", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			run: func(s *PostgresStorage) error { return s.SetKeyEscrow(context.Background(), escrow) },
+		},
+		{
+			name: "set escrow database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO key_escrow").
+					WithArgs(userID, []byte("wrapped"), "abc", sqlmock.AnyArg()).
+					WillReturnError(sql.ErrConnDone)
+			},
+			run:       func(s *PostgresStorage) error { return s.SetKeyEscrow(context.Background(), escrow) },
+			wantError: true,
+		},
+		{
+			name: "get escrow",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"user_id", "wrapped_key", "recovery_key_id", "consented_at"}).
+					AddRow(userID, []byte("wrapped"), "abc", time.Now())
+				mock.ExpectQuery(selectQuery).WithArgs(userID).WillReturnRows(rows)
+			},
+			run: func(s *PostgresStorage) error {
+				_, err := s.GetKeyEscrow(context.Background(), userID)
+				return err
+			},
+		},
+		{
+			name: "get escrow not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectQuery).WithArgs(userID).WillReturnError(sql.ErrNoRows)
+			},
+			run: func(s *PostgresStorage) error {
+				_, err := s.GetKeyEscrow(context.Background(), userID)
+				return err
+			},
+			wantErr:   ErrEscrowNotFound,
+			wantError: true,
+		},
+		{
+			name: "delete escrow",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM key_escrow WHERE user_id = \\$1").
+					WithArgs(userID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			run: func(s *PostgresStorage) error { return s.DeleteKeyEscrow(context.Background(), userID) },
+		},
+		{
+			name: "delete escrow not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM key_escrow WHERE user_id = \\$1").
+					WithArgs(userID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			run:       func(s *PostgresStorage) error { return s.DeleteKeyEscrow(context.Background(), userID) },
+			wantErr:   ErrEscrowNotFound,
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			err := tt.run(NewPostgresStorage(db))
+			if (err != nil) != tt.wantError {
+				t.Errorf("error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_SelfTest(t *testing.T) {
+	versionRows := func(version int, dirty bool) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"version", "dirty"}).AddRow(version, dirty)
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantError bool
+	}{
+		{
+			name: "healthy",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").WillReturnRows(versionRows(SchemaVersion, false))
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectExec("DELETE FROM users").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "canary not readable",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").WillReturnRows(versionRows(SchemaVersion, false))
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectRollback()
+			},
+			wantError: true,
+		},
+		{
+			name: "migrations not applied",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").WillReturnError(fmt.Errorf("relation does not exist"))
+			},
+			wantError: true,
+		},
+		{
+			name: "dirty migration",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").WillReturnRows(versionRows(SchemaVersion, true))
+			},
+			wantError: true,
+		},
+		{
+			name: "schema too old",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").WillReturnRows(versionRows(SchemaVersion-1, false))
+			},
+			wantError: true,
+		},
+		{
+			name: "canary write fails",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").WillReturnRows(versionRows(SchemaVersion, false))
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO users").WillReturnError(fmt.Errorf("read-only transaction"))
+				mock.ExpectRollback()
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			report, err := storage.SelfTest(context.Background())
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("SelfTest() error = %v, wantError %v", err, tt.wantError)
+			}
+			if err == nil && report.SchemaVersion != SchemaVersion {
+				t.Errorf("SelfTest() schema version = %d, want %d", report.SchemaVersion, SchemaVersion)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_PasswordHint(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("set", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Log.Error("Failed to close database", zap.Error(err))
+			}
+		}()
+
+		mock.ExpectExec("UPDATE users SET password_hint = \\$2").
+			WithArgs(userID, "hint", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE users SET password_hint = \\$2").
+			WithArgs(userID, "hint", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		storage := NewPostgresStorage(db)
+		if err := storage.SetPasswordHint(context.Background(), userID, "hint"); err != nil {
+			t.Errorf("SetPasswordHint() error = %v", err)
+		}
+		if err := storage.SetPasswordHint(context.Background(), userID, "hint"); err != ErrUserNotFound {
+			t.Errorf("SetPasswordHint() error = %v, want %v", err, ErrUserNotFound)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Log.Error("Failed to close database", zap.Error(err))
+			}
+		}()
+
+		mock.ExpectQuery("SELECT password_hint FROM users WHERE id = \\$1").
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"password_hint"}).AddRow("hint"))
+		mock.ExpectQuery("SELECT password_hint FROM users WHERE id = \\$1").
+			WithArgs(userID).
+			WillReturnError(sql.ErrNoRows)
+
+		storage := NewPostgresStorage(db)
+		if hint, err := storage.GetPasswordHint(context.Background(), userID); err != nil || hint != "hint" {
+			t.Errorf("GetPasswordHint() = %q, %v", hint, err)
+		}
+		if _, err := storage.GetPasswordHint(context.Background(), userID); err != ErrUserNotFound {
+			t.Errorf("GetPasswordHint() error = %v, want %v", err, ErrUserNotFound)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestPostgresStorage_AddDataComment(t *testing.T) {
+	comment := &models.DataComment{
+		ID:         uuid.New(),
+		DataID:     uuid.New(),
+		Ciphertext: []byte("sealed"),
+		CreatedAt:  time.Now(),
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantErr   error
+		wantError bool
+	}{
+		{
+			name: "comment added",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO data_comments").
+					WithArgs(comment.ID, comment.DataID, []byte("sealed"), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "data not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO data_comments").
+					WithArgs(comment.ID, comment.DataID, []byte("sealed"), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr:   ErrDataNotFound,
+			wantError: true,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO data_comments").
+					WithArgs(comment.ID, comment.DataID, []byte("sealed"), sqlmock.AnyArg()).
+					WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			err := storage.AddDataComment(context.Background(), comment)
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("AddDataComment() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("AddDataComment() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_GetDataComments(t *testing.T) {
+	dataID := uuid.New()
+	query := "SELECT id, data_id, ciphertext, created_at FROM data_comments"
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantCount int
+		wantError bool
+	}{
+		{
+			name: "comments found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "data_id", "ciphertext", "created_at"}).
+					AddRow(uuid.New(), dataID, []byte("first"), time.Now()).
+					AddRow(uuid.New(), dataID, []byte("second"), time.Now())
+				mock.ExpectQuery(query).WithArgs(dataID).WillReturnRows(rows)
+			},
+			wantCount: 2,
+		},
+		{
+			name: "no comments",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "data_id", "ciphertext", "created_at"})
+				mock.ExpectQuery(query).WithArgs(dataID).WillReturnRows(rows)
+			},
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(dataID).WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			comments, err := storage.GetDataComments(context.Background(), dataID)
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("GetDataComments() error = %v, wantError %v", err, tt.wantError)
+			}
+			if len(comments) != tt.wantCount {
+				t.Errorf("GetDataComments() returned %d comments, want %d", len(comments), tt.wantCount)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_FlagExpiringData(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	dataID, userID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		want      int
+		wantError bool
+	}{
+		{
+			name: "items flagged",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`(?s)UPDATE data SET expiry = CASE .* WHERE expires_at IS NOT NULL AND expiry <> CASE .* RETURNING id, user_id, revision, expiry`).
+					WithArgs(now, now.Add(30*24*time.Hour)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "revision", "expiry"}).
+						AddRow(dataID, userID, 3, models.ExpiryExpiring))
+			},
+			want: 1,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("UPDATE data SET expiry").WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			flagged, err := storage.FlagExpiringData(context.Background(), now, 30*24*time.Hour)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("FlagExpiringData() error = %v, wantError %v", err, tt.wantError)
+			}
+			if len(flagged) != tt.want {
+				t.Fatalf("FlagExpiringData() flagged %d items, want %d", len(flagged), tt.want)
+			}
+			if tt.want > 0 && (flagged[0].ID != dataID || flagged[0].UserID != userID || flagged[0].Revision != 3 ||
+				flagged[0].Expiry != models.ExpiryExpiring) {
+				t.Errorf("FlagExpiringData() = %+v", flagged[0])
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_AddDataAttachment(t *testing.T) {
+	attachment := &models.DataAttachment{
+		ID:        uuid.New(),
+		DataID:    uuid.New(),
+		Info:      []byte("sealed info"),
+		Size:      6,
+		Content:   []byte("sealed"),
+		CreatedAt: time.Now(),
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantErr   error
+		wantError bool
+	}{
+		{
+			name: "attachment added",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO data_attachments").
+					WithArgs(attachment.ID, attachment.DataID, []byte("sealed info"), int64(6), []byte("sealed"), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "data not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO data_attachments").
+					WithArgs(attachment.ID, attachment.DataID, []byte("sealed info"), int64(6), []byte("sealed"), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr:   ErrDataNotFound,
+			wantError: true,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO data_attachments").WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			err := storage.AddDataAttachment(context.Background(), attachment)
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("AddDataAttachment() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("AddDataAttachment() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_GetDataAttachment(t *testing.T) {
+	dataID := uuid.New()
+	attachmentID := uuid.New()
+	query := "SELECT id, data_id, info, size, content, created_at FROM data_attachments"
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantErr   error
+		wantError bool
+	}{
+		{
+			name: "attachment found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "data_id", "info", "size", "content", "created_at"}).
+					AddRow(attachmentID, dataID, []byte("info"), int64(7), []byte("content"), time.Now())
+				mock.ExpectQuery(query).WithArgs(attachmentID, dataID).WillReturnRows(rows)
+			},
+		},
+		{
+			name: "attachment not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(attachmentID, dataID).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr:   ErrAttachmentNotFound,
+			wantError: true,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(attachmentID, dataID).WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			attachment, err := storage.GetDataAttachment(context.Background(), dataID, attachmentID)
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("GetDataAttachment() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("GetDataAttachment() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(attachment.Content) != "content" {
+				t.Errorf("GetDataAttachment() content = %q", attachment.Content)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_DeleteDataAttachment(t *testing.T) {
+	dataID := uuid.New()
+	attachmentID := uuid.New()
+
+	tests := []struct {
+		name    string
+		rows    int64
+		wantErr error
+	}{
+		{name: "attachment deleted", rows: 1},
+		{name: "attachment not found", rows: 0, wantErr: ErrAttachmentNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			mock.ExpectExec("DELETE FROM data_attachments").WithArgs(attachmentID, dataID).
+				WillReturnResult(sqlmock.NewResult(0, tt.rows))
+
+			storage := NewPostgresStorage(db)
+			if err := storage.DeleteDataAttachment(context.Background(), dataID, attachmentID); err != tt.wantErr {
+				t.Errorf("DeleteDataAttachment() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_GetDataVersions(t *testing.T) {
+	dataID := uuid.New()
+	query := "SELECT data_id, version, type, name, description, data, metadata, environment, updated_at, checksum FROM data_versions"
+	columns := []string{"data_id", "version", "type", "name", "description", "data", "metadata", "environment", "updated_at", "checksum"}
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantCount int
+		wantError bool
+	}{
+		{
+			name: "versions found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(dataID, 2, "text", "Notes", "", []byte("second"), "", "", time.Now(), nil).
+					AddRow(dataID, 1, "text", "Notes", "", []byte("first"), "", "", time.Now(), nil)
+				mock.ExpectQuery(query).WithArgs(dataID).WillReturnRows(rows)
+			},
+			wantCount: 2,
+		},
+		{
+			name: "no versions",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(dataID).WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(dataID).WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			versions, err := storage.GetDataVersions(context.Background(), dataID)
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("GetDataVersions() error = %v, wantError %v", err, tt.wantError)
+			}
+			if len(versions) != tt.wantCount {
+				t.Errorf("GetDataVersions() returned %d versions, want %d", len(versions), tt.wantCount)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_GetDataVersion(t *testing.T) {
+	dataID := uuid.New()
+	query := "SELECT data_id, version, type, name, description, data, metadata, environment, updated_at, checksum FROM data_versions"
+	columns := []string{"data_id", "version", "type", "name", "description", "data", "metadata", "environment", "updated_at", "checksum"}
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantErr   error
+		wantError bool
+	}{
+		{
+			name: "version found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).AddRow(dataID, 1, "text", "Notes", "", []byte("first"), "", "", time.Now(), nil)
+				mock.ExpectQuery(query).WithArgs(dataID, 1).WillReturnRows(rows)
+			},
+		},
+		{
+			name: "version not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(dataID, 1).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr:   ErrVersionNotFound,
+			wantError: true,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(dataID, 1).WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			version, err := storage.GetDataVersion(context.Background(), dataID, 1)
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("GetDataVersion() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("GetDataVersion() error = %v, want %v", err, tt.wantErr)
+			}
+			if !tt.wantError && (version == nil || string(version.Data) != "first") {
+				t.Errorf("GetDataVersion() = %v, want version 1", version)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_AuditLog(t *testing.T) {
+	userID := uuid.New()
+	dataID := uuid.New()
+	publicKey := bytes.Repeat([]byte{0x03}, 32)
+
+	t.Run("key", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Log.Error("Failed to close database", zap.Error(err))
+			}
+		}()
+
+		mock.ExpectExec("UPDATE users SET audit_public_key = \\$2").
+			WithArgs(userID, publicKey, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT audit_public_key FROM users WHERE id = \\$1").
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"audit_public_key"}).AddRow(publicKey))
+		mock.ExpectQuery("SELECT audit_public_key FROM users WHERE id = \\$1").
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"audit_public_key"}).AddRow(nil))
+
+		storage := NewPostgresStorage(db)
+		if err := storage.SetAuditKey(context.Background(), userID, publicKey); err != nil {
+			t.Errorf("SetAuditKey() error = %v", err)
+		}
+		if key, err := storage.GetAuditKey(context.Background(), userID); err != nil || !bytes.Equal(key, publicKey) {
+			t.Errorf("GetAuditKey() = %x, %v", key, err)
+		}
+		if key, err := storage.GetAuditKey(context.Background(), userID); err != nil || key != nil {
+			t.Errorf("GetAuditKey() = %x, %v, want no key", key, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("events", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Log.Error("Failed to close database", zap.Error(err))
+			}
+		}()
+
+		event := &models.AuditEvent{ID: uuid.New(), UserID: userID, Action: "data.delete", DataID: &dataID, CreatedAt: time.Now()}
+		mock.ExpectExec("INSERT INTO audit_events").
+			WithArgs(event.ID, userID, "data.delete", event.DataID, sqlmock.AnyArg(), event.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT id, user_id, action, data_id, sealed, created_at\\s+FROM audit_events WHERE user_id = \\$1 ORDER BY created_at DESC, id LIMIT \\$2").
+			WithArgs(userID, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "action", "data_id", "sealed", "created_at"}).
+				AddRow(event.ID, userID, "data.delete", dataID, []byte("sealed"), event.CreatedAt).
+				AddRow(uuid.New(), userID, "data.create", nil, nil, event.CreatedAt))
+
+		storage := NewPostgresStorage(db)
+		if err := storage.AddAuditEvent(context.Background(), event); err != nil {
+			t.Errorf("AddAuditEvent() error = %v", err)
+		}
+		events, err := storage.GetAuditEvents(context.Background(), userID, 10)
+		if err != nil || len(events) != 2 {
+			t.Fatalf("GetAuditEvents() = %d events, %v", len(events), err)
+		}
+		if events[0].DataID == nil || *events[0].DataID != dataID || string(events[0].Sealed) != "sealed" {
+			t.Errorf("Unexpected first event %+v", events[0])
+		}
+		if events[1].DataID != nil || events[1].Sealed != nil {
+			t.Errorf("Expected NULL columns to stay empty, got %+v", events[1])
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestPostgresStorage_PutDataChunk(t *testing.T) {
+	dataID := uuid.New()
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantErr   error
+		wantError bool
+	}{
+		{
+			name: "chunk stored",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT EXISTS").WithArgs(dataID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectExec("INSERT INTO data_chunks").
+					WithArgs(dataID, 0, []byte("sealed")).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "data not found",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT EXISTS").WithArgs(dataID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			wantErr:   ErrDataNotFound,
+			wantError: true,
+		},
+		{
+			name: "out of order",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT EXISTS").WithArgs(dataID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectExec("INSERT INTO data_chunks").
+					WithArgs(dataID, 0, []byte("sealed")).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr:   ErrChunkOutOfOrder,
+			wantError: true,
+		},
+		{
+			name: "database error",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT EXISTS").WithArgs(dataID).WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			err := storage.PutDataChunk(context.Background(), dataID, 0, []byte("sealed"))
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("PutDataChunk() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("PutDataChunk() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_GetDataChunk(t *testing.T) {
+	dataID := uuid.New()
+	db, mock := setupMockDB(t)
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Log.Error("Failed to close database", zap.Error(err))
+		}
+	}()
+
+	mock.ExpectQuery("SELECT COUNT").WithArgs(dataID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT data, object_key FROM data_chunks").WithArgs(dataID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"data", "object_key"}).AddRow([]byte("sealed"), nil))
+	mock.ExpectQuery("SELECT data, object_key FROM data_chunks").WithArgs(dataID, 2).WillReturnError(sql.ErrNoRows)
+
+	storage := NewPostgresStorage(db)
+	if count, err := storage.CountDataChunks(context.Background(), dataID); err != nil || count != 2 {
+		t.Errorf("CountDataChunks() = %d, %v, want 2", count, err)
+	}
+	if chunk, err := storage.GetDataChunk(context.Background(), dataID, 1); err != nil || string(chunk) != "sealed" {
+		t.Errorf("GetDataChunk() = %q, %v", chunk, err)
+	}
+	if _, err := storage.GetDataChunk(context.Background(), dataID, 2); err != ErrChunkNotFound {
+		t.Errorf("GetDataChunk() error = %v, want %v", err, ErrChunkNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorage_SetDataCollection(t *testing.T) {
+	dataID := uuid.New()
+	collectionID := uuid.New()
+
+	tests := []struct {
+		name         string
+		collectionID *uuid.UUID
+		mockSetup    func(sqlmock.Sqlmock)
+		wantErr      error
+		wantError    bool
+	}{
+		{
+			name:         "filed in a collection",
+			collectionID: &collectionID,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE data SET collection_id").WithArgs(dataID, &collectionID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "moved to the top level",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE data SET collection_id").WithArgs(dataID, nil).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:         "data not found",
+			collectionID: &collectionID,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE data SET collection_id").WithArgs(dataID, &collectionID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr:   ErrDataNotFound,
+			wantError: true,
+		},
+		{
+			name:         "database error",
+			collectionID: &collectionID,
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE data SET collection_id").WithArgs(dataID, &collectionID).
+					WillReturnError(sql.ErrConnDone)
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			err := storage.SetDataCollection(context.Background(), dataID, tt.collectionID)
+
+			if (err != nil) != tt.wantError {
+				t.Errorf("SetDataCollection() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("SetDataCollection() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_RotateVault(t *testing.T) {
+	userID := uuid.New()
+	dataID := uuid.New()
+	records := func(records ...models.RotationRecord) func() (*models.RotationRecord, error) {
+		return func() (*models.RotationRecord, error) {
+			if len(records) == 0 {
+				return nil, io.EOF
+			}
+			record := records[0]
+			records = records[1:]
+			return &record, nil
+		}
+	}
+	item := models.RotationRecord{Kind: models.RotationItem, DataID: dataID, Revision: 2, Data: []byte("new")}
+	chunk := models.RotationRecord{Kind: models.RotationChunk, DataID: dataID, Index: 0, Data: []byte("chunk")}
+	attachmentID := uuid.New()
+	attachment := models.RotationRecord{Kind: models.RotationAttachment, DataID: dataID, AttachmentID: attachmentID,
+		Info: []byte("info"), Data: []byte("file")}
+	header := models.RotationHeader{Salt: "salt", Verifier: []byte("verifier")}
+
+	tests := []struct {
+		name      string
+		next      func() (*models.RotationRecord, error)
+		mockSetup func(sqlmock.Sqlmock)
+		wantError error
+	}{
+		{
+			name: "rotated",
+			next: records(item, chunk, attachment),
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("FOR UPDATE").WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT").WithArgs(userID).WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(3))
+				mock.ExpectExec("UPDATE data SET data = \\$3, revision = revision \\+ 1").
+					WithArgs(dataID, userID, []byte("new"), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE data_chunks").
+					WithArgs(dataID, 0, userID, []byte("chunk")).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE data_attachments").
+					WithArgs(attachmentID, dataID, userID, []byte("info"), []byte("file"), 4).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE users SET salt = \\$2, master_verifier = \\$3, master_password = ''").
+					WithArgs(userID, "salt", []byte("verifier"), sqlmock.AnyArg(), 0).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "item changed",
+			next: records(item, chunk),
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(2))
+				mock.ExpectExec("UPDATE data SET").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+			wantError: ErrRotationConflict,
+		},
+		{
+			name: "chunk left out",
+			next: records(item),
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(2))
+				mock.ExpectExec("UPDATE data SET").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectRollback()
+			},
+			wantError: ErrRotationMismatch,
+		},
+		{
+			name: "record repeated",
+			next: records(item, item),
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("FOR UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(2))
+				mock.ExpectExec("UPDATE data SET").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectRollback()
+			},
+			wantError: ErrRotationMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			response, err := storage.RotateVault(context.Background(), userID, header, tt.next)
+			if err != tt.wantError {
+				t.Errorf("RotateVault() error = %v, want %v", err, tt.wantError)
+			}
+			if err == nil && (response.Items != 1 || response.Chunks != 1) {
+				t.Errorf("RotateVault() response = %+v", response)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorage_ApplyDataBatch(t *testing.T) {
+	userID := uuid.New()
+	created := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "created", Data: []byte("new")}
+	updated := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "updated", Data: []byte("changed")}
+	deleted := &models.Data{ID: uuid.New(), UserID: userID}
+	changes := []models.DataChange{
+		{Op: models.BatchCreate, Data: created},
+		{Op: models.BatchUpdate, Data: updated, BaseRevision: 3},
+		{Op: models.BatchDelete, Data: deleted},
+	}
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		wantError error
+	}{
+		{
+			name: "applied",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO data").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE data SET").
+					WithArgs(updated.ID, updated.Type, updated.Name, updated.Description, updated.Data, updated.Metadata,
+						sqlmock.AnyArg(), updated.Environment, sqlmock.AnyArg(), 3, updated.Checksum, sqlmock.AnyArg(), updated.Icon,
+						nil, "").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM data").WithArgs(deleted.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "item changed",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO data").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE data SET").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantError: ErrBatchConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			err := storage.ApplyDataBatch(context.Background(), changes)
+			if err != tt.wantError {
+				t.Errorf("ApplyDataBatch() error = %v, want %v", err, tt.wantError)
+			}
+			if err == nil && (created.Revision != 1 || updated.Revision != 4) {
+				t.Errorf("Unexpected revisions %d and %d", created.Revision, updated.Revision)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+// capturedArg matches any value and keeps the last one it saw
+type capturedArg struct {
+	value []byte
+}
+
+func (c *capturedArg) Match(v driver.Value) bool {
+	b, ok := v.([]byte)
+	c.value = b
+	return ok
+}
+
+func TestPostgresStorage_EncryptionAtRest(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Log.Error("Failed to close database", zap.Error(err))
+		}
+	}()
+
+	wrapper, err := atrest.NewLocalKey(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("NewLocalKey() error = %v", err)
+	}
+	storage := NewPostgresStorage(db)
+	storage.SetEnvelope(atrest.NewEnvelope(wrapper))
+
+	ctx := context.Background()
+	data := &models.Data{ID: uuid.New(), UserID: uuid.New(), Type: models.DataTypeText, Name: "n", Data: []byte(`{"salt":"c2FsdA=="}`),
+		Icon: []byte("favicon")}
+	stored, storedIcon := &capturedArg{}, &capturedArg{}
+	mock.ExpectExec("INSERT INTO data").
+		WithArgs(data.ID, data.UserID, data.Type, data.Name, data.Description, stored, data.Metadata,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), data.Environment, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), storedIcon, nil, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := storage.CreateData(ctx, data); err != nil {
+		t.Fatalf("CreateData() error = %v", err)
+	}
+	if !atrest.IsSealed(stored.value) || bytes.Contains(stored.value, []byte("salt")) {
+		t.Fatalf("Expected the stored data to be sealed, got %q", stored.value)
+	}
+	if !atrest.IsSealed(storedIcon.value) || bytes.Contains(storedIcon.value, []byte("favicon")) {
+		t.Fatalf("Expected the stored icon to be sealed, got %q", storedIcon.value)
+	}
+	if string(data.Data) != `{"salt":"c2FsdA=="}` || string(data.Icon) != "favicon" {
+		t.Errorf("Expected the item to keep its data and icon, got %q, %q", data.Data, data.Icon)
+	}
+
+	columns := []string{"id", "user_id", "type", "name", "description", "data", "metadata", "created_at", "updated_at", "environment", "revision", "tags", "collection_id", "checksum", "domains", "icon", "expires_at", "expiry"}
+	mock.ExpectQuery("SELECT id, user_id").WithArgs(data.ID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(data.ID, data.UserID, "text", "n", "", stored.value, "", time.Now(), time.Now(), "", 1, []byte("[]"), nil, nil, []byte("[]"), storedIcon.value, nil, ""))
+	got, err := storage.GetDataByID(ctx, data.ID)
+	if err != nil {
+		t.Fatalf("GetDataByID() error = %v", err)
+	}
+	if !bytes.Equal(got.Data, data.Data) || !bytes.Equal(got.Icon, data.Icon) {
+		t.Errorf("Expected %q and icon %q, got %q and %q", data.Data, data.Icon, got.Data, got.Icon)
+	}
+
+	// rows written before the envelope was configured are read unchanged
+	mock.ExpectQuery("SELECT id, user_id").WithArgs(data.UserID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(data.ID, data.UserID, "text", "n", "", []byte(`{"legacy":1}`), "", time.Now(), time.Now(), "", 1, []byte("[]"), nil, nil, []byte("[]"), nil, nil, ""))
+	list, err := storage.GetDataByUserID(ctx, data.UserID)
+	if err != nil || len(list) != 1 || string(list[0].Data) != `{"legacy":1}` {
+		t.Errorf("GetDataByUserID() = %v, %v", list, err)
+	}
+
+	// sealed rows are refused without the envelope
+	mock.ExpectQuery("SELECT id, user_id").WithArgs(data.ID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(data.ID, data.UserID, "text", "n", "", stored.value, "", time.Now(), time.Now(), "", 1, []byte("[]"), nil, nil, []byte("[]"), nil, nil, ""))
+	if _, err := NewPostgresStorage(db).GetDataByID(ctx, data.ID); err == nil {
+		t.Error("Expected a sealed row to be refused without an at-rest key")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorage_EncryptionAtRest_FieldsCommentsChunks(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Log.Error("Failed to close database", zap.Error(err))
+		}
+	}()
+
+	wrapper, err := atrest.NewLocalKey(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("NewLocalKey() error = %v", err)
+	}
+	storage := NewPostgresStorage(db)
+	storage.SetEnvelope(atrest.NewEnvelope(wrapper))
+	ctx := context.Background()
+	dataID := uuid.New()
+	plaintext := []byte("client ciphertext")
+
+	sealed := func(name string, stored *capturedArg) {
+		t.Helper()
+		if !atrest.IsSealed(stored.value) || bytes.Contains(stored.value, plaintext) {
+			t.Fatalf("Expected the stored %s to be sealed, got %q", name, stored.value)
+		}
+	}
+
+	field := &capturedArg{}
+	mock.ExpectExec("INSERT INTO data_fields").
+		WithArgs(dataID, "password", field, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := storage.SetDataField(ctx, &models.DataField{DataID: dataID, Name: "password", Ciphertext: plaintext}); err != nil {
+		t.Fatalf("SetDataField() error = %v", err)
+	}
+	sealed("field", field)
+	mock.ExpectQuery("SELECT data_id, name, ciphertext, created_at, updated_at FROM data_fields").WithArgs(dataID, "password").
+		WillReturnRows(sqlmock.NewRows([]string{"data_id", "name", "ciphertext", "created_at", "updated_at"}).
+			AddRow(dataID, "password", field.value, time.Now(), time.Now()))
+	if got, err := storage.GetDataField(ctx, dataID, "password"); err != nil || !bytes.Equal(got.Ciphertext, plaintext) {
+		t.Errorf("GetDataField() = %+v, %v", got, err)
+	}
+
+	comment := &capturedArg{}
+	commentID := uuid.New()
+	mock.ExpectExec("INSERT INTO data_comments").
+		WithArgs(commentID, dataID, comment, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := storage.AddDataComment(ctx, &models.DataComment{ID: commentID, DataID: dataID, Ciphertext: plaintext}); err != nil {
+		t.Fatalf("AddDataComment() error = %v", err)
+	}
+	sealed("comment", comment)
+	mock.ExpectQuery("SELECT id, data_id, ciphertext, created_at FROM data_comments").WithArgs(dataID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "data_id", "ciphertext", "created_at"}).
+			AddRow(commentID, dataID, comment.value, time.Now()).
+			AddRow(uuid.New(), dataID, []byte("legacy"), time.Now()))
+	comments, err := storage.GetDataComments(ctx, dataID)
+	if err != nil || len(comments) != 2 || !bytes.Equal(comments[0].Ciphertext, plaintext) || string(comments[1].Ciphertext) != "legacy" {
+		t.Errorf("GetDataComments() = %+v, %v", comments, err)
+	}
+
+	chunk := &capturedArg{}
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(dataID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO data_chunks").
+		WithArgs(dataID, 0, chunk).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := storage.PutDataChunk(ctx, dataID, 0, plaintext); err != nil {
+		t.Fatalf("PutDataChunk() error = %v", err)
+	}
+	sealed("chunk", chunk)
+	mock.ExpectQuery("SELECT data, object_key FROM data_chunks").WithArgs(dataID, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"data", "object_key"}).AddRow(chunk.value, nil))
+	if got, err := storage.GetDataChunk(ctx, dataID, 0); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("GetDataChunk() = %q, %v", got, err)
+	}
+
+	// sealed chunks are refused without the envelope
+	mock.ExpectQuery("SELECT data, object_key FROM data_chunks").WithArgs(dataID, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"data", "object_key"}).AddRow(chunk.value, nil))
+	if _, err := NewPostgresStorage(db).GetDataChunk(ctx, dataID, 0); err == nil {
+		t.Error("Expected a sealed chunk to be refused without an at-rest key")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorage_EncryptionAtRest_Attachments(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Log.Error("Failed to close database", zap.Error(err))
+		}
+	}()
+
+	wrapper, err := atrest.NewLocalKey(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("NewLocalKey() error = %v", err)
+	}
+	storage := NewPostgresStorage(db)
+	storage.SetEnvelope(atrest.NewEnvelope(wrapper))
+	ctx := context.Background()
+	userID, dataID, attachmentID := uuid.New(), uuid.New(), uuid.New()
+	attachment := &models.DataAttachment{ID: attachmentID, DataID: dataID, Info: []byte("file info"),
+		Size: 12, Content: []byte("file content")}
+
+	sealed := func(name string, stored *capturedArg, plaintext []byte) {
+		t.Helper()
+		if !atrest.IsSealed(stored.value) || bytes.Contains(stored.value, plaintext) {
+			t.Fatalf("Expected the stored %s to be sealed, got %q", name, stored.value)
+		}
+	}
+
+	info, content := &capturedArg{}, &capturedArg{}
+	mock.ExpectExec("INSERT INTO data_attachments").
+		WithArgs(attachmentID, dataID, info, int64(12), content, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := storage.AddDataAttachment(ctx, attachment); err != nil {
+		t.Fatalf("AddDataAttachment() error = %v", err)
+	}
+	sealed("info", info, attachment.Info)
+	sealed("content", content, attachment.Content)
+
+	mock.ExpectQuery("SELECT id, data_id, info, size, created_at FROM data_attachments").WithArgs(dataID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "data_id", "info", "size", "created_at"}).
+			AddRow(attachmentID, dataID, info.value, int64(12), time.Now()))
+	list, err := storage.GetDataAttachments(ctx, dataID)
+	if err != nil || len(list) != 1 || !bytes.Equal(list[0].Info, attachment.Info) {
+		t.Errorf("GetDataAttachments() = %+v, %v", list, err)
+	}
+	mock.ExpectQuery("SELECT id, data_id, info, size, content, created_at FROM data_attachments").WithArgs(attachmentID, dataID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "data_id", "info", "size", "content", "created_at"}).
+			AddRow(attachmentID, dataID, info.value, int64(12), content.value, time.Now()))
+	got, err := storage.GetDataAttachment(ctx, dataID, attachmentID)
+	if err != nil || !bytes.Equal(got.Info, attachment.Info) || !bytes.Equal(got.Content, attachment.Content) {
+		t.Errorf("GetDataAttachment() = %+v, %v", got, err)
+	}
+
+	// rotated attachments are sealed again, keeping the size of their content
+	rotated := models.RotationRecord{Kind: models.RotationAttachment, DataID: dataID, AttachmentID: attachmentID,
+		Info: []byte("new info"), Data: []byte("new content")}
+	rotatedInfo, rotatedContent := &capturedArg{}, &capturedArg{}
+	mock.ExpectBegin()
+	mock.ExpectExec("FOR UPDATE").WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT").WithArgs(userID).WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(1))
+	mock.ExpectExec("UPDATE data_attachments").
+		WithArgs(attachmentID, dataID, userID, rotatedInfo, rotatedContent, len(rotated.Data)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE users SET salt").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	next := func() (*models.RotationRecord, error) {
+		if rotated.Kind == "" {
+			return nil, io.EOF
+		}
+		record := rotated
+		rotated.Kind = ""
+		return &record, nil
+	}
+	if _, err := storage.RotateVault(ctx, userID, models.RotationHeader{Salt: "salt", Verifier: []byte("verifier")}, next); err != nil {
+		t.Fatalf("RotateVault() error = %v", err)
+	}
+	sealed("rotated info", rotatedInfo, []byte("new info"))
+	sealed("rotated content", rotatedContent, []byte("new content"))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorage_Shares(t *testing.T) {
+	ownerID, recipientID, dataID, shareID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	created := time.Now()
+
+	t.Run("share key", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Log.Error("Failed to close database", zap.Error(err))
+			}
+		}()
+
+		mock.ExpectExec("UPDATE users SET share_public_key = \\$2").
+			WithArgs(ownerID, []byte("public"), []byte("private"), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT (.+) FROM users WHERE id = \\$1").
+			WithArgs(ownerID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "master_password", "salt",
+				"kdf_iterations", "created_at", "updated_at"}).
+				AddRow(ownerID, "owner", "hash", "", "salt", 0, created, created))
+		mock.ExpectQuery("SELECT share_public_key, share_private_key FROM users WHERE id = \\$1").
+			WithArgs(recipientID).
+			WillReturnRows(sqlmock.NewRows([]string{"share_public_key", "share_private_key"}).AddRow(nil, nil))
+
+		storage := NewPostgresStorage(db)
+		err := storage.SetShareKey(context.Background(), ownerID, &models.ShareKeys{PublicKey: []byte("public"), PrivateKey: []byte("private")})
+		if err != ErrShareKeyAlreadySet {
+			t.Errorf("SetShareKey() error = %v, want %v", err, ErrShareKeyAlreadySet)
+		}
+		if keys, err := storage.GetShareKey(context.Background(), recipientID); err != nil || keys != nil {
+			t.Errorf("GetShareKey() = %+v, %v, want no key", keys, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("create and list", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Log.Error("Failed to close database", zap.Error(err))
+			}
+		}()
+
+		existingID := uuid.New()
+		mock.ExpectQuery("INSERT INTO shares (.+) ON CONFLICT \\(data_id, recipient_id\\) DO UPDATE").
+			WithArgs(shareID, dataID, ownerID, recipientID, models.ShareModeRead, []byte("sealed"), created).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(existingID, created))
+		mock.ExpectQuery("SELECT (.+) FROM shares s JOIN users u ON u.id = s.recipient_id WHERE s.recipient_id = \\$1").
+			WithArgs(recipientID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data_id", "owner_id", "recipient_id", "username", "mode",
+				"sealed_key", "created_at"}).
+				AddRow(existingID, dataID, ownerID, recipientID, "recipient", models.ShareModeRead, []byte("sealed"), created))
+
+		storage := NewPostgresStorage(db)
+		share := &models.Share{ID: shareID, DataID: dataID, OwnerID: ownerID, RecipientID: recipientID,
+			Mode: models.ShareModeRead, SealedKey: []byte("sealed"), CreatedAt: created}
+		if err := storage.CreateShare(context.Background(), share); err != nil {
+			t.Fatalf("CreateShare() error = %v", err)
+		}
+		if share.ID != existingID {
+			t.Errorf("Expected the existing share ID, got %s", share.ID)
+		}
+		shares, err := storage.GetSharedWith(context.Background(), recipientID)
+		if err != nil || len(shares) != 1 || shares[0].Recipient != "recipient" {
+			t.Errorf("GetSharedWith() = %+v, %v", shares, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Log.Error("Failed to close database", zap.Error(err))
+			}
+		}()
+
+		mock.ExpectExec("DELETE FROM shares WHERE id = \\$1").
+			WithArgs(shareID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		storage := NewPostgresStorage(db)
+		if err := storage.DeleteShare(context.Background(), shareID); err != ErrShareNotFound {
+			t.Errorf("DeleteShare() error = %v, want %v", err, ErrShareNotFound)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestPostgresStorage_Sessions(t *testing.T) {
+	userID, sessionID := uuid.New(), uuid.New()
+	now := time.Now()
+	columns := []string{"id", "user_id", "device", "user_agent", "created_at", "last_seen_at", "expires_at"}
+
+	t.Run("create and list", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Log.Error("Failed to close database", zap.Error(err))
+			}
+		}()
+
+		mock.ExpectExec("INSERT INTO sessions").
+			WithArgs(sessionID, userID, "laptop", "gophkeeper", now, now, now.Add(time.Hour)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT (.+) FROM sessions\\s+WHERE user_id = \\$1 AND expires_at > \\$2").
+			WithArgs(userID, now).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(sessionID, userID, "laptop", "gophkeeper", now, now, now.Add(time.Hour)))
+
+		storage := NewPostgresStorage(db)
+		session := &models.Session{ID: sessionID, UserID: userID, Device: "laptop", UserAgent: "gophkeeper",
+			CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}
+		if err := storage.CreateSession(context.Background(), session); err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+		sessions, err := storage.GetSessions(context.Background(), userID, now)
+		if err != nil || len(sessions) != 1 || sessions[0].Device != "laptop" {
+			t.Errorf("GetSessions() = %+v, %v", sessions, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Log.Error("Failed to close database", zap.Error(err))
+			}
+		}()
+
+		mock.ExpectQuery("SELECT (.+) FROM sessions WHERE id = \\$1").
+			WithArgs(sessionID).
+			WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectExec("UPDATE sessions SET last_seen_at = \\$1 WHERE id = \\$2").
+			WithArgs(now, sessionID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM sessions WHERE id = \\$1").
+			WithArgs(sessionID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		storage := NewPostgresStorage(db)
+		if _, err := storage.GetSession(context.Background(), sessionID); err != ErrSessionNotFound {
+			t.Errorf("GetSession() error = %v, want %v", err, ErrSessionNotFound)
+		}
+		if err := storage.TouchSession(context.Background(), sessionID, now); err != ErrSessionNotFound {
+			t.Errorf("TouchSession() error = %v, want %v", err, ErrSessionNotFound)
+		}
+		if err := storage.DeleteSession(context.Background(), sessionID); err != ErrSessionNotFound {
+			t.Errorf("DeleteSession() error = %v, want %v", err, ErrSessionNotFound)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("delete expired", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		defer func() {
+			if err := db.Close(); err != nil {
+				logger.Log.Error("Failed to close database", zap.Error(err))
+			}
+		}()
+
+		mock.ExpectExec("DELETE FROM sessions WHERE user_id = \\$1 AND expires_at <= \\$2").
+			WithArgs(userID, now).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		storage := NewPostgresStorage(db)
+		if err := storage.DeleteExpiredSessions(context.Background(), userID, now); err != nil {
+			t.Errorf("DeleteExpiredSessions() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestPostgresStorage_Identities(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now()
+	identity := &models.Identity{Issuer: "https://idp.example.com", Subject: "sub", UserID: userID, CreatedAt: now}
+
+	db, mock := setupMockDB(t)
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Log.Error("Failed to close database", zap.Error(err))
+		}
+	}()
+
+	mock.ExpectExec("INSERT INTO identities (.+) ON CONFLICT \\(issuer, subject\\) DO NOTHING").
+		WithArgs(identity.Issuer, identity.Subject, userID, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO identities").
+		WithArgs(identity.Issuer, identity.Subject, userID, now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT (.+) FROM identities WHERE issuer = \\$1 AND subject = \\$2").
+		WithArgs(identity.Issuer, identity.Subject).
+		WillReturnRows(sqlmock.NewRows([]string{"issuer", "subject", "user_id", "created_at"}).
+			AddRow(identity.Issuer, identity.Subject, userID, now))
+	mock.ExpectQuery("SELECT (.+) FROM identities").
+		WithArgs(identity.Issuer, "other").
+		WillReturnRows(sqlmock.NewRows([]string{"issuer", "subject", "user_id", "created_at"}))
+
+	storage := NewPostgresStorage(db)
+	if err := storage.CreateIdentity(context.Background(), identity); err != nil {
+		t.Fatalf("CreateIdentity() error = %v", err)
+	}
+	if err := storage.CreateIdentity(context.Background(), identity); err != ErrIdentityExists {
+		t.Errorf("CreateIdentity() error = %v, want %v", err, ErrIdentityExists)
+	}
+	if found, err := storage.GetIdentity(context.Background(), identity.Issuer, identity.Subject); err != nil || found.UserID != userID {
+		t.Errorf("GetIdentity() = %+v, %v", found, err)
+	}
+	if _, err := storage.GetIdentity(context.Background(), identity.Issuer, "other"); err != ErrIdentityNotFound {
+		t.Errorf("GetIdentity() error = %v, want %v", err, ErrIdentityNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// primaryKey is the context key marking reads that go to the primary
+type primaryKey struct{}
+
+// Replicas hands out connections of read replicas of the database
+type Replicas interface {
+	Replica() *sql.DB
+}
+
+// SetReplicas sends reads of users and items to replicas of the database,
+// outside transactions and contexts of ReadPrimary. Writes stay on the
+// primary. Call it before use.
+func (s *PostgresStorage) SetReplicas(r Replicas) {
+	s.replicas = r
+}
+
+// ReadPrimary returns a context whose reads go to the primary, for requests
+// that write and must not act on what a lagging replica or a cache returns
+func ReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadsPrimary reports whether the reads of ctx go to the primary
+func ReadsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// r returns the querier of a read, and whether it is a replica
+func (s *PostgresStorage) r(ctx context.Context) (querier, bool) {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok || s.replicas == nil {
+		return s.q(ctx), false
+	}
+	if ReadsPrimary(ctx) {
+		return s.db, false
+	}
+	return s.replicas.Replica(), true
+}
+
+// readRow runs a query of one row like r, and again on the primary when a
+// replica does not have the row yet, such as right after it was created
+func (s *PostgresStorage) readRow(ctx context.Context, scan func(row *sql.Row) error, query string, args ...interface{}) error {
+	q, replica := s.r(ctx)
+	err := scan(q.QueryRowContext(ctx, query, args...))
+	if replica && errors.Is(err, sql.ErrNoRows) {
+		err = scan(s.db.QueryRowContext(ctx, query, args...))
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+// fixedReplica is Replicas with one replica
+type fixedReplica struct {
+	db *sql.DB
+}
+
+func (f fixedReplica) Replica() *sql.DB {
+	return f.db
+}
+
+func TestPostgresStorage_Replicas(t *testing.T) {
+	const userQuery = "SELECT id, username, password, master_password, salt, kdf_iterations, created_at, updated_at FROM users WHERE username = \\$1"
+	userRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "username", "password", "master_password", "salt", "kdf_iterations", "created_at", "updated_at"}).
+			AddRow(uuid.New(), "alice", "hash", "master", "salt", 0, time.Now(), time.Now())
+	}
+
+	primary, primaryMock := setupMockDB(t)
+	defer primary.Close()
+	replica, replicaMock := setupMockDB(t)
+	defer replica.Close()
+	storage := NewPostgresStorage(primary)
+	storage.SetReplicas(fixedReplica{db: replica})
+	ctx := context.Background()
+
+	t.Run("read from the replica", func(t *testing.T) {
+		replicaMock.ExpectQuery(userQuery).WithArgs("alice").WillReturnRows(userRows())
+		if _, err := storage.GetUserByUsername(ctx, "alice"); err != nil {
+			t.Fatalf("GetUserByUsername() error = %v", err)
+		}
+	})
+
+	t.Run("missing on the replica", func(t *testing.T) {
+		replicaMock.ExpectQuery(userQuery).WithArgs("alice").WillReturnError(sql.ErrNoRows)
+		primaryMock.ExpectQuery(userQuery).WithArgs("alice").WillReturnRows(userRows())
+		if _, err := storage.GetUserByUsername(ctx, "alice"); err != nil {
+			t.Fatalf("GetUserByUsername() error = %v", err)
+		}
+	})
+
+	t.Run("missing everywhere", func(t *testing.T) {
+		replicaMock.ExpectQuery(userQuery).WithArgs("bob").WillReturnError(sql.ErrNoRows)
+		primaryMock.ExpectQuery(userQuery).WithArgs("bob").WillReturnError(sql.ErrNoRows)
+		if _, err := storage.GetUserByUsername(ctx, "bob"); err != ErrUserNotFound {
+			t.Fatalf("GetUserByUsername() error = %v, want %v", err, ErrUserNotFound)
+		}
+	})
+
+	t.Run("read primary", func(t *testing.T) {
+		primaryMock.ExpectQuery(userQuery).WithArgs("alice").WillReturnRows(userRows())
+		if _, err := storage.GetUserByUsername(ReadPrimary(ctx), "alice"); err != nil {
+			t.Fatalf("GetUserByUsername() error = %v", err)
+		}
+	})
+
+	t.Run("in a transaction", func(t *testing.T) {
+		primaryMock.ExpectBegin()
+		primaryMock.ExpectQuery(userQuery).WithArgs("alice").WillReturnRows(userRows())
+		primaryMock.ExpectCommit()
+		err := storage.InTx(ctx, func(ctx context.Context) error {
+			_, err := storage.GetUserByUsername(ctx, "alice")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("InTx() error = %v", err)
+		}
+	})
+
+	t.Run("writes on the primary", func(t *testing.T) {
+		primaryMock.ExpectExec("UPDATE users SET salt").WillReturnResult(sqlmock.NewResult(0, 1))
+		if err := storage.SetUserSalt(ctx, uuid.New(), "salt"); err != nil {
+			t.Fatalf("SetUserSalt() error = %v", err)
+		}
+	})
+
+	for name, mock := range map[string]sqlmock.Sqlmock{"primary": primaryMock, "replica": replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations of the %s: %v", name, err)
+		}
+	}
+}
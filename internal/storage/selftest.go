@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SchemaVersion is the migration version this build expects the database to be at
+const SchemaVersion = 28
+
+// SlowLatency is the round-trip time above which the storage is reported as slow
+const SlowLatency = 200 * time.Millisecond
+
+// SelfTestReport describes a successful storage self-test
+type SelfTestReport struct {
+	SchemaVersion int
+	Latency       time.Duration
+}
+
+// Slow reports whether the measured latency is above SlowLatency
+func (r *SelfTestReport) Slow() bool {
+	return r.Latency > SlowLatency
+}
+
+// canaryUsername returns a unique username that cannot collide with real users
+func canaryUsername() string {
+	return "__selftest_" + uuid.NewString()[:8]
+}
+
+// SelfTester is implemented by storages that can verify themselves on startup
+type SelfTester interface {
+	SelfTest(ctx context.Context) (*SelfTestReport, error)
+}
+
+// Pinger is implemented by storages that can cheaply check they are reachable,
+// e.g. for readiness probes
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
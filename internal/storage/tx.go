@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"go.uber.org/zap"
+)
+
+// txKey is the context key of the transaction of InTx
+type txKey struct{}
+
+// querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// InTx runs fn in a transaction, or in the transaction of ctx if there is one
+// already. Storage calls made with the context passed to fn take part in it,
+// and fn returning an error rolls all of them back. Stores sharing the
+// transaction must use the same database.
+func (s *PostgresStorage) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.FromContext(ctx).Error("Failed to roll back transaction", zap.Error(err))
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx.Tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.FromContext(ctx).Error("Failed to commit transaction", zap.Error(err))
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// q returns the transaction of ctx, or the database outside one
+func (s *PostgresStorage) q(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return s.db
+}
+
+// storageTx is a transaction begun by a storage method, or the one of the
+// context it joined, which is committed or rolled back by its owner
+type storageTx struct {
+	*sql.Tx
+	joined bool
+}
+
+// begin starts a transaction, or joins the one of ctx
+func (s *PostgresStorage) begin(ctx context.Context) (*storageTx, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return &storageTx{Tx: tx, joined: true}, nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &storageTx{Tx: tx}, nil
+}
+
+// Commit commits a transaction begun here; a joined one is left to its owner
+func (t *storageTx) Commit() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+// Rollback rolls back a transaction begun here; a joined one is rolled back
+// by its owner when the error reaches it
+func (t *storageTx) Rollback() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// InTx runs fn. Changes made before fn fails are kept, as memory storage has
+// no transactions; it only serves development and tests.
+func (s *MemoryStorage) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/a2sh3r/gophkeeper/internal/logger"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestPostgresStorage_InTx(t *testing.T) {
+	userID := uuid.New()
+	data := &models.Data{ID: uuid.New(), UserID: userID, Type: models.DataTypeText, Name: "n", Data: []byte("d")}
+	event := &models.AuditEvent{ID: uuid.New(), UserID: userID, Action: "data.create"}
+	errAudit := errors.New("audit failed")
+
+	tests := []struct {
+		name      string
+		mockSetup func(sqlmock.Sqlmock)
+		fn        func(ctx context.Context, s *PostgresStorage) error
+		wantError error
+	}{
+		{
+			name: "committed",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO data").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO audit_events").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			fn: func(ctx context.Context, s *PostgresStorage) error {
+				if err := s.CreateData(ctx, data); err != nil {
+					return err
+				}
+				return s.AddAuditEvent(ctx, event)
+			},
+		},
+		{
+			name: "rolled back",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO data").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectRollback()
+			},
+			fn: func(ctx context.Context, s *PostgresStorage) error {
+				if err := s.CreateData(ctx, data); err != nil {
+					return err
+				}
+				return errAudit
+			},
+			wantError: errAudit,
+		},
+		{
+			name: "batch joins the transaction",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO data").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO audit_events").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			fn: func(ctx context.Context, s *PostgresStorage) error {
+				if err := s.ApplyDataBatch(ctx, []models.DataChange{{Op: models.BatchCreate, Data: data}}); err != nil {
+					return err
+				}
+				return s.InTx(ctx, func(ctx context.Context) error {
+					return s.AddAuditEvent(ctx, event)
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer func() {
+				if err := db.Close(); err != nil {
+					logger.Log.Error("Failed to close database", zap.Error(err))
+				}
+			}()
+
+			tt.mockSetup(mock)
+
+			storage := NewPostgresStorage(db)
+			err := storage.InTx(context.Background(), func(ctx context.Context) error {
+				return tt.fn(ctx, storage)
+			})
+			if !errors.Is(err, tt.wantError) {
+				t.Errorf("InTx() error = %v, want %v", err, tt.wantError)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Package validate checks request structs against their `validate` tags. It
+// implements the rules of go-playground/validator that the models use:
+// required, omitempty, min, max, len, oneof and dive. Nested structs are
+// checked as well; the elements of a slice only after dive.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Struct returns the fields of v, a struct or a pointer to one, that break
+// their rules, named by their JSON keys. It returns nil if v is valid.
+func Struct(v interface{}) []models.FieldError {
+	value := indirect(reflect.ValueOf(v))
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs []models.FieldError
+	checkStruct(value, "", &errs)
+	return errs
+}
+
+func checkStruct(value reflect.Value, prefix string, errs *[]models.FieldError) {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+		checkValue(value.Field(i), prefix+name, field.Tag.Get("validate"), errs)
+	}
+}
+
+// checkValue applies the comma-separated rules to value, then checks a nested
+// struct, or the elements of a slice with the rules after dive
+func checkValue(value reflect.Value, name, tag string, errs *[]models.FieldError) {
+	rules := strings.Split(tag, ",")
+	var elementRules []string
+	dive := false
+	for i, rule := range rules {
+		if rule == "dive" {
+			rules, elementRules, dive = rules[:i], rules[i+1:], true
+			break
+		}
+	}
+
+	for _, rule := range rules {
+		switch rule {
+		case "":
+			continue
+		case "omitempty":
+			if isEmpty(value) {
+				return
+			}
+			continue
+		}
+		if err, ok := checkRule(value, name, rule); !ok {
+			// the other rules of the field would only repeat the problem
+			*errs = append(*errs, err)
+			return
+		}
+	}
+
+	value = indirect(value)
+	switch {
+	case !value.IsValid():
+	case value.Kind() == reflect.Struct && value.Type() != timeType:
+		checkStruct(value, name+".", errs)
+	case value.Kind() == reflect.Slice && dive:
+		for i := 0; i < value.Len(); i++ {
+			checkValue(value.Index(i), fmt.Sprintf("%s[%d]", name, i), strings.Join(elementRules, ","), errs)
+		}
+	}
+}
+
+// checkRule checks one rule with its parameter, such as max=255
+func checkRule(value reflect.Value, name, rule string) (models.FieldError, bool) {
+	rule, param, _ := strings.Cut(rule, "=")
+	fail := func(format string, args ...interface{}) (models.FieldError, bool) {
+		return models.FieldError{Field: name, Rule: rule, Message: name + " " + fmt.Sprintf(format, args...)}, false
+	}
+
+	if rule == "required" {
+		if isEmpty(value) {
+			return fail("is required")
+		}
+		return models.FieldError{}, true
+	}
+
+	value = indirect(value)
+	if !value.IsValid() {
+		return models.FieldError{}, true
+	}
+	switch rule {
+	case "oneof":
+		options := strings.Fields(param)
+		text := fmt.Sprint(value.Interface())
+		for _, option := range options {
+			if text == option {
+				return models.FieldError{}, true
+			}
+		}
+		return fail("must be one of %s", strings.Join(options, ", "))
+	case "min", "max", "len":
+		limit, err := strconv.Atoi(param)
+		if err != nil {
+			panic(fmt.Sprintf("validate: invalid parameter of %s on %s: %q", rule, name, param))
+		}
+		size, unit := measure(value)
+		switch {
+		case rule == "min" && size < limit:
+			return fail("must be at least %d%s", limit, unit)
+		case rule == "max" && size > limit:
+			return fail("must be at most %d%s", limit, unit)
+		case rule == "len" && size != limit:
+			return fail("must be exactly %d%s", limit, unit)
+		}
+		return models.FieldError{}, true
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %q on %s", rule, name))
+	}
+}
+
+// measure returns what min, max and len compare for value: the characters of
+// a string, the length of a slice or map and the value of a number
+func measure(value reflect.Value) (int, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(value.String()), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Len(), " bytes"
+		}
+		return value.Len(), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(value.Uint()), ""
+	default:
+		return 0, ""
+	}
+}
+
+// isEmpty reports whether value is missing: nil, zero or of length zero
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+// indirect follows pointers, returning an invalid value for nil
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
@@ -0,0 +1,44 @@
+package validate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+func TestStruct(t *testing.T) {
+	tags := []string{"work", strings.Repeat("x", 65)}
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []string
+	}{
+		{name: "valid", value: models.DataRequest{Type: models.DataTypeText, Name: "Note", Data: []byte("x")}},
+		{name: "pointer", value: &models.UserRequest{Username: "alice", Password: "secret", MasterPassword: "master-pass"}},
+		{name: "missing fields", value: models.DataRequest{}, want: []string{"type is required", "name is required", "data is required"}},
+		{name: "rules", value: models.DataRequest{Type: "secret", Name: strings.Repeat("n", 256), Data: []byte("x"), Environment: strings.Repeat("é", 33)},
+			want: []string{"type must be one of login_password, text, binary, bank_card, otp", "name must be at most 255 characters",
+				"environment must be at most 32 characters"}},
+		{name: "dive", value: models.DataRequest{Type: models.DataTypeText, Name: "Note", Data: []byte("x"), Tags: &tags},
+			want: []string{"tags[1] must be at most 64 characters"}},
+		{name: "min", value: models.UserRequest{Username: "al", Password: "secret", MasterPassword: "short"},
+			want: []string{"master_password must be at least 8 characters"}},
+		{name: "len", value: models.AuditKeyRequest{PublicKey: []byte("short")}, want: []string{"public_key must be exactly 32 bytes"}},
+		{name: "nested", value: models.BatchOperation{Op: models.BatchCreate, Data: &models.DataRequest{Type: models.DataTypeText, Data: []byte("x")}},
+			want: []string{"data.name is required"}},
+		{name: "omitempty", value: models.DataPatchRequest{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range Struct(tt.value) {
+				got = append(got, err.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Struct() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+// Package vcr records the HTTP exchanges of the client to cassette files and
+// replays them, so high-level client tests exercise real request and response
+// handling without a running server.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// updateEnv records cassettes against the real transport instead of replaying them when set to 1
+const updateEnv = "UPDATE_CASSETTES"
+
+// Redacted replaces secret values in recorded bodies
+const Redacted = "REDACTED"
+
+// secretKeys are the JSON keys whose string values never reach a cassette
+var secretKeys = map[string]bool{
+	"password":        true,
+	"master_password": true,
+	"token":           true,
+}
+
+// recordedHeaders are the response headers the client acts on; others are dropped
+var recordedHeaders = []string{"Content-Type", "Retry-After"}
+
+// Mode selects whether a Recorder records or replays
+type Mode int
+
+const (
+	// ModeReplay answers requests from the cassette without a network
+	ModeReplay Mode = iota
+	// ModeRecord sends requests through the real transport and records them
+	ModeRecord
+)
+
+// ModeFromEnv returns ModeRecord when UPDATE_CASSETTES=1, ModeReplay otherwise
+func ModeFromEnv() Mode {
+	if os.Getenv(updateEnv) == "1" {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Cassette is the recorded exchanges of one test, in order
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Path includes the query string.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded response
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that records or replays a cassette. Replay
+// matches requests by method and path in recorded order; bodies are not
+// compared because encrypted payloads differ on every run.
+type Recorder struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mutex    sync.Mutex
+	cassette Cassette
+	played   int
+	pending  []*recording
+}
+
+// New creates a recorder for the cassette at path. In ModeReplay the cassette
+// must exist; in ModeRecord requests go through next and Stop writes the cassette.
+func New(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode, next: next}
+	if mode == ModeRecord {
+		if next == nil {
+			return nil, fmt.Errorf("vcr: recording %s needs a transport", path)
+		}
+		return r, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("vcr: failed to read cassette (run with %s=1 to record it): %w", updateEnv, err)
+	}
+	if err := json.Unmarshal(raw, &r.cassette); err != nil {
+		return nil, fmt.Errorf("vcr: invalid cassette %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Start creates a recorder in the mode selected by UPDATE_CASSETTES and stops
+// it when the test ends, failing the test if the cassette was not played in full
+func Start(t testing.TB, path string, next http.RoundTripper) *Recorder {
+	t.Helper()
+	r, err := New(path, ModeFromEnv(), next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := r.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+	return r
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModeRecord {
+		return r.record(req)
+	}
+	return r.replay(req)
+}
+
+// replay answers req with the next interaction of the cassette
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		// a real server consumes the body; streaming clients wait for that
+		go func() {
+			_, _ = io.Copy(io.Discard, req.Body)
+			_ = req.Body.Close()
+		}()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	path := req.URL.RequestURI()
+	if r.played >= len(r.cassette.Interactions) {
+		return nil, fmt.Errorf("vcr: unexpected request %s %s after the end of %s", req.Method, path, r.path)
+	}
+	interaction := r.cassette.Interactions[r.played]
+	if interaction.Request.Method != req.Method || interaction.Request.Path != path {
+		return nil, fmt.Errorf("vcr: request %d is %s %s, but %s recorded %s %s", r.played+1,
+			req.Method, path, r.path, interaction.Request.Method, interaction.Request.Path)
+	}
+	r.played++
+
+	header := make(http.Header)
+	for name, value := range interaction.Response.Headers {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+		StatusCode:    interaction.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+		ContentLength: int64(len(interaction.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// recording captures the bodies of one exchange as the client streams them
+type recording struct {
+	request  Request
+	response Response
+	reqBody  bytes.Buffer
+	respBody bytes.Buffer
+}
+
+// record sends req through the real transport, capturing both bodies as they
+// are read so streaming exchanges keep flowing
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	rec := &recording{request: Request{Method: req.Method, Path: req.URL.RequestURI()}}
+	if req.Body != nil {
+		req.Body = &teeReadCloser{ReadCloser: req.Body, buf: &rec.reqBody, mutex: &r.mutex}
+	}
+
+	r.mutex.Lock()
+	r.pending = append(r.pending, rec)
+	r.mutex.Unlock()
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	rec.response.Status = resp.StatusCode
+	for _, name := range recordedHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			if rec.response.Headers == nil {
+				rec.response.Headers = make(map[string]string)
+			}
+			rec.response.Headers[name] = value
+		}
+	}
+	r.mutex.Unlock()
+
+	resp.Body = &teeReadCloser{ReadCloser: resp.Body, buf: &rec.respBody, mutex: &r.mutex}
+	return resp, nil
+}
+
+// Stop ends the recording or replay. Recording writes the cassette with secrets
+// redacted; replaying fails if recorded requests were never made.
+func (r *Recorder) Stop() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.mode == ModeReplay {
+		if r.played < len(r.cassette.Interactions) {
+			next := r.cassette.Interactions[r.played].Request
+			return fmt.Errorf("vcr: %d of %d requests of %s were not made, next is %s %s",
+				len(r.cassette.Interactions)-r.played, len(r.cassette.Interactions), r.path, next.Method, next.Path)
+		}
+		return nil
+	}
+
+	cassette := Cassette{Interactions: make([]Interaction, 0, len(r.pending))}
+	for _, rec := range r.pending {
+		rec.request.Body = Sanitize(rec.reqBody.String())
+		rec.response.Body = Sanitize(rec.respBody.String())
+		cassette.Interactions = append(cassette.Interactions, Interaction{Request: rec.request, Response: rec.response})
+	}
+	r.pending = nil
+
+	raw, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("vcr: failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("vcr: failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(raw, '\n'), 0644); err != nil {
+		return fmt.Errorf("vcr: failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// teeReadCloser copies what is read into buf
+type teeReadCloser struct {
+	io.ReadCloser
+	buf   *bytes.Buffer
+	mutex *sync.Mutex
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		t.mutex.Lock()
+		t.buf.Write(p[:n])
+		t.mutex.Unlock()
+	}
+	return n, err
+}
+
+// Sanitize redacts the string values of secret keys, such as passwords and
+// tokens, in a JSON or NDJSON body. Other bodies are returned unchanged.
+func Sanitize(body string) string {
+	if strings.TrimSpace(body) == "" {
+		return body
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var lines []string
+	for {
+		var value interface{}
+		if err := decoder.Decode(&value); err == io.EOF {
+			break
+		} else if err != nil {
+			return body
+		}
+		var line bytes.Buffer
+		encoder := json.NewEncoder(&line)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(redact(value)); err != nil {
+			return body
+		}
+		lines = append(lines, strings.TrimSuffix(line.String(), "\n"))
+	}
+
+	sanitized := strings.Join(lines, "\n")
+	if strings.HasSuffix(body, "\n") {
+		sanitized += "\n"
+	}
+	return sanitized
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if s, ok := item.(string); ok && secretKeys[key] && s != "" {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = redact(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return value
+}
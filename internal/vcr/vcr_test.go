@@ -0,0 +1,159 @@
+package vcr
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serverTransport sends requests to an in-process handler
+type serverTransport struct {
+	handler http.Handler
+}
+
+func (s serverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, req)
+	return w.Result(), nil
+}
+
+func send(t *testing.T, client *http.Client, method, path, body string) (int, string, error) {
+	t.Helper()
+	req, err := http.NewRequest(method, "http://vcr.invalid"+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(raw), nil
+}
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "login.json")
+	server := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.URL.Path == "/api/v1/login" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"token":"eyJ.secret.jwt","user":{"username":"alice"}}`)
+			return
+		}
+		w.Header().Set("Retry-After", "30")
+		w.Header().Set("X-Internal", "dropped")
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	})
+
+	recorder, err := New(path, ModeRecord, serverTransport{handler: server})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client := &http.Client{Transport: recorder}
+	if status, body, err := send(t, client, "POST", "/api/v1/login", `{"username":"alice","password":"hunter22"}`); err != nil ||
+		status != http.StatusOK || !strings.Contains(body, "eyJ.secret.jwt") {
+		t.Fatalf("Recording should pass the real response through, got %d %q %v", status, body, err)
+	}
+	if status, _, _ := send(t, client, "GET", "/api/v1/data?limit=1", ""); status != http.StatusServiceUnavailable {
+		t.Fatalf("Expected the error response to be recorded, got %d", status)
+	}
+	if err := recorder.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected a cassette: %v", err)
+	}
+	for _, secret := range []string{"hunter22", "eyJ.secret.jwt", "X-Internal"} {
+		if strings.Contains(string(raw), secret) {
+			t.Errorf("Cassette contains %q:\n%s", secret, raw)
+		}
+	}
+
+	replayer, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client = &http.Client{Transport: replayer}
+	status, body, err := send(t, client, "POST", "/api/v1/login", `{"username":"alice","password":"other"}`)
+	if err != nil || status != http.StatusOK || !strings.Contains(body, `"token":"REDACTED"`) || !strings.Contains(body, "alice") {
+		t.Errorf("Unexpected replayed login: %d %q %v", status, body, err)
+	}
+	req, _ := http.NewRequest("GET", "http://vcr.invalid/api/v1/data?limit=1", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Replay error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "30" {
+		t.Errorf("Expected the recorded error with its Retry-After, got %d %v", resp.StatusCode, resp.Header)
+	}
+	if err := replayer.Stop(); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
+
+func TestRecorder_ReplayMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := `{"interactions":[
+		{"request":{"method":"GET","path":"/api/v1/status"},"response":{"status":200,"body":"{}"}},
+		{"request":{"method":"GET","path":"/api/v1/data"},"response":{"status":200,"body":"[]"}}
+	]}`
+	if err := os.WriteFile(path, []byte(cassette), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	replayer, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client := &http.Client{Transport: replayer}
+	if _, _, err := send(t, client, "DELETE", "/api/v1/status", ""); err == nil || !strings.Contains(err.Error(), "recorded GET /api/v1/status") {
+		t.Errorf("Expected a mismatch error, got %v", err)
+	}
+	if _, _, err := send(t, client, "GET", "/api/v1/status", ""); err != nil {
+		t.Fatalf("Replay error = %v", err)
+	}
+	if err := replayer.Stop(); err == nil || !strings.Contains(err.Error(), "1 of 2 requests") {
+		t.Errorf("Expected the unplayed request to be reported, got %v", err)
+	}
+
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json"), ModeReplay, nil); err == nil || !strings.Contains(err.Error(), updateEnv) {
+		t.Errorf("Expected a hint to record the missing cassette, got %v", err)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "empty", body: "", want: ""},
+		{name: "not JSON", body: "404 page not found\n", want: "404 page not found\n"},
+		{
+			name: "nested secrets",
+			body: `{"username":"u","password":"p","master_password":"m","user":{"token":"t"},"salt":"s"}`,
+			want: `{"master_password":"REDACTED","password":"REDACTED","salt":"s","user":{"token":"REDACTED"},"username":"u"}`,
+		},
+		{name: "empty secret kept", body: `{"token":""}`, want: `{"token":""}`},
+		{
+			name: "NDJSON",
+			body: "{\"seq\":1,\"data\":{\"name\":\"a & b\"}}\n{\"seq\":2,\"token\":\"x\"}\n",
+			want: "{\"data\":{\"name\":\"a & b\"},\"seq\":1}\n{\"seq\":2,\"token\":\"REDACTED\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.body); got != tt.want {
+				t.Errorf("Sanitize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Package gophkeeper is the Go SDK of GophKeeper. It lets programs sign in to
+// a GophKeeper server and read and write vault items, which are encrypted and
+// decrypted locally with the master password exactly as the command-line
+// client does; the server never sees plaintext.
+//
+// A Session is a connection to a server, a Vault the unlocked items of the
+// signed in user and an Item one decrypted entry:
+//
+//	session, err := gophkeeper.NewSession("https://vault.example.com", gophkeeper.Options{})
+//	if err != nil { ... }
+//	if err := session.Login(ctx, "alice", "login-password"); err != nil { ... }
+//	vault, err := session.Unlock(ctx, "master-password")
+//	if err != nil { ... }
+//	defer vault.Lock()
+//	item, err := vault.Get(ctx, "a1b2c3")
+package gophkeeper
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/client"
+)
+
+// Errors returned by the SDK, to be matched with errors.Is
+var (
+	// ErrNotFound means the item or account does not exist
+	ErrNotFound = client.ErrNotFound
+	// ErrAccessDenied means the account may not access the item
+	ErrAccessDenied = client.ErrAccessDenied
+	// ErrUnauthorized means the token is missing, invalid or expired
+	ErrUnauthorized = client.ErrUnauthorized
+	// ErrConflict means the item was changed elsewhere since it was read
+	ErrConflict = client.ErrConflict
+	// ErrQuotaExceeded means a size or rate limit of the server was hit
+	ErrQuotaExceeded = client.ErrQuotaExceeded
+	// ErrServerUnavailable means the server could not be reached or is down
+	// for maintenance; trying again later may succeed
+	ErrServerUnavailable = client.ErrServerUnavailable
+	// ErrLocked means the vault was locked and must be unlocked again
+	ErrLocked = client.ErrNotAuthenticated
+	// ErrNotSignedIn means Unlock was called before Login, Register or SetToken
+	ErrNotSignedIn = errors.New("not signed in")
+)
+
+// Options configure the connection to the server. The zero value uses the
+// defaults of the command-line client.
+type Options struct {
+	// Token is an access token from an earlier Login, to resume a session
+	Token string
+	// TLSConfig overrides the TLS settings, e.g. to trust a private CA
+	TLSConfig *tls.Config
+	// Timeout bounds each request; zero means 30 seconds and a negative
+	// value no limit
+	Timeout time.Duration
+	// Proxy is the URL of an HTTP or SOCKS5 proxy, "direct" for none, or
+	// empty to honor HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	Proxy string
+	// RetryAttempts is how many times a request is tried on transient
+	// failures; zero means 3 and a negative value disables retries
+	RetryAttempts int
+}
+
+// Session is a connection to a GophKeeper server on behalf of one account.
+// It is not safe for concurrent use.
+type Session struct {
+	cli   *client.Client
+	token string
+	salt  string
+	// iterations is the PBKDF2 iteration count of the vault key under salt
+	iterations int
+}
+
+// NewSession connects to the server at serverURL. No request is sent until
+// the session is used.
+func NewSession(serverURL string, options Options) (*Session, error) {
+	parsed, err := url.Parse(serverURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q", serverURL)
+	}
+
+	cli := client.NewClient(serverURL)
+	if err := cli.SetNetworkOptions(client.NetworkOptions{Timeout: options.Timeout, Proxy: options.Proxy}); err != nil {
+		return nil, err
+	}
+	if options.TLSConfig != nil {
+		cli.SetTLSConfig(options.TLSConfig)
+	}
+	config := client.Config{RetryAttempts: options.RetryAttempts}
+	cli.SetRetryPolicy(config.RetryPolicy())
+
+	session := &Session{cli: cli}
+	session.SetToken(options.Token)
+	return session, nil
+}
+
+// Register creates an account and signs in to it. The master password is
+// only used to derive the vault key and never leaves this process.
+func (s *Session) Register(ctx context.Context, username, password, masterPassword string) error {
+	resp, err := s.cli.Register(ctx, username, password, masterPassword)
+	if err != nil {
+		return err
+	}
+	s.SetToken(resp.Token)
+	s.salt = resp.Salt
+	s.iterations = resp.KDFIterations
+	return nil
+}
+
+// Login signs in to an existing account
+func (s *Session) Login(ctx context.Context, username, password string) error {
+	resp, err := s.cli.Login(ctx, username, password)
+	if err != nil {
+		return err
+	}
+	s.SetToken(resp.Token)
+	s.salt = resp.Salt
+	s.iterations = resp.KDFIterations
+	return nil
+}
+
+// SetToken resumes a session with a token from an earlier Login
+func (s *Session) SetToken(token string) {
+	s.token = token
+	s.salt = ""
+	s.iterations = 0
+	s.cli.SetToken(token)
+}
+
+// Token returns the access token of the session, empty before signing in
+func (s *Session) Token() string {
+	return s.token
+}
+
+// Unlock derives the vault key from the master password and returns the
+// vault of the signed in account
+func (s *Session) Unlock(ctx context.Context, masterPassword string) (*Vault, error) {
+	if s.token == "" {
+		return nil, ErrNotSignedIn
+	}
+	salt, iterations := s.salt, s.iterations
+	if salt == "" {
+		derivation, err := s.cli.GetKeyDerivation(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get salt: %w", err)
+		}
+		salt, iterations = derivation.Salt, derivation.KDFIterations
+	}
+
+	session := client.NewClientSession(s.cli)
+	if err := session.UnlockWithIterations(masterPassword, salt, iterations); err != nil {
+		return nil, err
+	}
+	return &Vault{session: session}, nil
+}
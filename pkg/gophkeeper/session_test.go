@@ -0,0 +1,125 @@
+package gophkeeper
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/auth"
+	"github.com/a2sh3r/gophkeeper/internal/server"
+	"github.com/a2sh3r/gophkeeper/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	router := mux.NewRouter()
+	server.RegisterRoutes(router, store, store, auth.NewJWTManager("test-secret", time.Hour), server.Options{})
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVault(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(t)
+
+	session, err := NewSession(srv.URL, Options{RetryAttempts: -1})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	if _, err := session.Unlock(ctx, "master-password"); !errors.Is(err, ErrNotSignedIn) {
+		t.Fatalf("Expected ErrNotSignedIn before signing in, got %v", err)
+	}
+	if err := session.Register(ctx, "sdk-user", "login-password", "master-password"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	vault, err := session.Unlock(ctx, "master-password")
+	if err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	created, err := vault.Create(ctx, Item{
+		Type:   TypeLogin,
+		Name:   "Mail",
+		Fields: map[string]string{"login": "alice", "password": "s3cret"},
+		Tags:   []string{"work"},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.Fields["password"] != "s3cret" || created.Metadata != "Login: alice, URL: " || created.Revision != 1 {
+		t.Errorf("Unexpected created item %+v", created)
+	}
+	note, err := vault.Create(ctx, Item{Type: TypeText, Name: "Raw", Content: []byte("not json")})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	resumed, err := NewSession(srv.URL, Options{Token: session.Token()})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	other, err := resumed.Unlock(ctx, "master-password")
+	if err != nil {
+		t.Fatalf("Unlock() with a token error = %v", err)
+	}
+	item, err := other.Get(ctx, created.ID[:8])
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if item.Name != "Mail" || item.Fields["login"] != "alice" || len(item.Tags) != 1 {
+		t.Errorf("Unexpected item %+v", item)
+	}
+
+	item.Fields["login"] = "bob"
+	updated, err := other.Update(ctx, *item)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Metadata != "Login: bob, URL: " || updated.Tags[0] != "work" {
+		t.Errorf("Unexpected updated item %+v", updated)
+	}
+	if _, err := vault.Update(ctx, *created); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for a stale item, got %v", err)
+	}
+
+	items, err := vault.List(ctx)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("List() = %d items, %v", len(items), err)
+	}
+	if err := vault.Delete(ctx, note.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := vault.Get(ctx, note.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after Delete, got %v", err)
+	}
+
+	vault.Lock()
+	if _, err := vault.List(ctx); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked after Lock, got %v", err)
+	}
+}
+
+func TestSession_Errors(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(t)
+
+	if _, err := NewSession("vault.example.com", Options{}); err == nil {
+		t.Error("Expected an error for a server URL without scheme")
+	}
+	session, err := NewSession(srv.URL, Options{})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	if err := session.Login(ctx, "nobody", "password"); err == nil {
+		t.Error("Expected Login to fail for an unknown account")
+	}
+	session.SetToken("invalid")
+	if _, err := session.Unlock(ctx, "master-password"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized for an invalid token, got %v", err)
+	}
+}
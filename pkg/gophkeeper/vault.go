@@ -0,0 +1,205 @@
+package gophkeeper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/a2sh3r/gophkeeper/internal/client"
+	"github.com/a2sh3r/gophkeeper/internal/models"
+)
+
+// ItemType is the kind of secret an item holds
+type ItemType string
+
+// Item types
+const (
+	TypeLogin    ItemType = ItemType(models.DataTypeLoginPassword)
+	TypeText     ItemType = ItemType(models.DataTypeText)
+	TypeBinary   ItemType = ItemType(models.DataTypeBinary)
+	TypeBankCard ItemType = ItemType(models.DataTypeBankCard)
+	TypeOTP      ItemType = ItemType(models.DataTypeOTP)
+)
+
+// Item is a decrypted vault item
+type Item struct {
+	ID          string
+	Type        ItemType
+	Name        string
+	Description string
+	// Fields are the secret fields of structured items, e.g. "login" and
+	// "password" of a login or "content" of a text note. When saving an
+	// item, Fields take precedence over Content if set.
+	Fields map[string]string
+	// Content is the decrypted payload as stored, for items that are not
+	// made of fields. The content of large files uploaded in chunks is not
+	// included; Content then describes the file.
+	Content []byte
+	// Metadata is the plaintext summary the server keeps for listings; it is
+	// rebuilt from Fields when a login, card, note or OTP item is saved
+	Metadata string
+	// Tags label the item; nil keeps the current tags on Update and an
+	// empty list clears them
+	Tags        []string
+	Environment string
+	// Revision counts the saves of the item; Update fails with ErrConflict
+	// if it changed on the server since the item was read
+	Revision  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Vault is the unlocked vault of the signed in account. Items are decrypted
+// and encrypted in this process with the key derived from the master password.
+type Vault struct {
+	session *client.ClientSession
+}
+
+// Lock drops the vault key from memory; the vault cannot be used afterwards
+func (v *Vault) Lock() {
+	v.session.Lock()
+}
+
+// List returns all items of the vault
+func (v *Vault) List(ctx context.Context) ([]Item, error) {
+	data, err := v.session.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(data))
+	for i := range data {
+		item, err := v.decrypt(&data[i])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	return items, nil
+}
+
+// Get returns the item with the given ID or unique ID prefix
+func (v *Vault) Get(ctx context.Context, id string) (*Item, error) {
+	data, err := v.session.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return v.decrypt(data)
+}
+
+// Create encrypts item and stores it as a new item, which is returned
+func (v *Vault) Create(ctx context.Context, item Item) (*Item, error) {
+	dataReq, err := v.request(item)
+	if err != nil {
+		return nil, err
+	}
+	data, err := v.session.Create(ctx, *dataReq)
+	if err != nil {
+		return nil, err
+	}
+	return v.decrypt(data)
+}
+
+// Update replaces the item with the ID of item, unless it was changed on the
+// server since item was read, and returns the saved item
+func (v *Vault) Update(ctx context.Context, item Item) (*Item, error) {
+	dataReq, err := v.request(item)
+	if err != nil {
+		return nil, err
+	}
+	if item.Revision > 0 {
+		revision := item.Revision
+		dataReq.BaseRevision = &revision
+	}
+	data, err := v.session.Update(ctx, item.ID, *dataReq)
+	if err != nil {
+		return nil, err
+	}
+	return v.decrypt(data)
+}
+
+// Delete removes the item with the given ID or unique ID prefix
+func (v *Vault) Delete(ctx context.Context, id string) error {
+	return v.session.Delete(ctx, id)
+}
+
+// decrypt converts a stored item into an Item
+func (v *Vault) decrypt(data *models.Data) (*Item, error) {
+	cryptoManager := v.session.GetCryptoManager()
+	if cryptoManager == nil {
+		return nil, ErrLocked
+	}
+	content, err := cryptoManager.Decrypt(data.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt item %s: %w", data.ID, err)
+	}
+
+	item := &Item{
+		ID:          data.ID.String(),
+		Type:        ItemType(data.Type),
+		Name:        data.Name,
+		Description: data.Description,
+		Content:     content,
+		Metadata:    data.Metadata,
+		Tags:        data.Tags,
+		Environment: data.Environment,
+		Revision:    data.Revision,
+		CreatedAt:   data.CreatedAt,
+		UpdatedAt:   data.UpdatedAt,
+	}
+	var payload map[string]interface{}
+	if json.Unmarshal(content, &payload) == nil && data.Type != models.DataTypeBinary {
+		item.Fields = make(map[string]string, len(payload))
+		for name, value := range payload {
+			if value != nil {
+				item.Fields[name] = fmt.Sprint(value)
+			}
+		}
+	}
+	return item, nil
+}
+
+// request encrypts item into the request that saves it
+func (v *Vault) request(item Item) (*models.DataRequest, error) {
+	cryptoManager := v.session.GetCryptoManager()
+	if cryptoManager == nil {
+		return nil, ErrLocked
+	}
+	if item.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	content := item.Content
+	payload := make(map[string]interface{}, len(item.Fields))
+	for name, value := range item.Fields {
+		payload[name] = value
+	}
+	if item.Fields != nil {
+		var err error
+		if content, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("failed to marshal fields: %w", err)
+		}
+	}
+	encrypted, err := cryptoManager.Encrypt(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt item: %w", err)
+	}
+
+	metadata := item.Metadata
+	if item.Fields != nil {
+		metadata = client.ItemMetadata(models.DataType(item.Type), payload, metadata)
+	}
+	dataReq := &models.DataRequest{
+		Type:        models.DataType(item.Type),
+		Name:        item.Name,
+		Description: item.Description,
+		Data:        encrypted,
+		Metadata:    metadata,
+		Environment: item.Environment,
+	}
+	if item.Tags != nil {
+		tags := item.Tags
+		dataReq.Tags = &tags
+	}
+	return dataReq, nil
+}